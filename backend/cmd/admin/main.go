@@ -0,0 +1,370 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/internal/automation"
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/internal/counters"
+	"bookmark-sync-service/backend/internal/retention"
+	"bookmark-sync-service/backend/internal/search"
+	"bookmark-sync-service/backend/internal/storagegc"
+	"bookmark-sync-service/backend/internal/user"
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/secrets"
+	"bookmark-sync-service/backend/pkg/storage"
+)
+
+// newKeyEnv holds the key rotate-keys re-encrypts with, so it stays out of
+// the shell history
+const newKeyEnv = "NEW_ENCRYPTION_KEY"
+
+const usage = `Usage: admin <command> [flags]
+
+Commands:
+  recount-stats    Recount bookmark save, like and comment counters
+  purge-user       Delete a user and all their data (-user)
+  reindex-search   Rebuild the search index from the database
+  rotate-keys      Re-encrypt stored credentials with $` + newKeyEnv + `
+  apply-retention  Delete data past its retention period
+  verify-storage   Check stored objects against the rows referencing them
+
+Flags:
+  -dry-run  Report what would change without changing anything
+  -yes      Skip the confirmation prompt
+  -user     User ID for purge-user
+`
+
+// admin runs one housekeeping command
+type admin struct {
+	cfg    *config.Config
+	db     *gorm.DB
+	logger *zap.Logger
+	dryRun bool
+	yes    bool
+	in     *bufio.Reader
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Print(usage)
+		os.Exit(1)
+	}
+	command := os.Args[1]
+
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	var dryRun = flags.Bool("dry-run", false, "Report what would change without changing anything")
+	var yes = flags.Bool("yes", false, "Skip the confirmation prompt")
+	var userID = flags.Uint("user", 0, "User ID for purge-user")
+	flags.Usage = func() { fmt.Print(usage) }
+	flags.Parse(os.Args[2:])
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	// Connect to database
+	db, err := database.NewConnection(cfg.Database)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.Fatalf("Failed to get underlying sql.DB: %v", err)
+	}
+	defer sqlDB.Close()
+
+	// Interrupting stops a command between batches
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	a := &admin{cfg: cfg, db: db, logger: zap.NewNop(), dryRun: *dryRun, yes: *yes, in: bufio.NewReader(os.Stdin)}
+	if a.dryRun {
+		fmt.Println("Dry run, nothing will be changed")
+	}
+
+	switch command {
+	case "recount-stats":
+		err = a.recountStats(ctx)
+	case "purge-user":
+		err = a.purgeUser(ctx, *userID)
+	case "reindex-search":
+		err = a.reindexSearch(ctx)
+	case "rotate-keys":
+		err = a.rotateKeys(ctx)
+	case "apply-retention":
+		err = a.applyRetention(ctx)
+	case "verify-storage":
+		err = a.verifyStorage(ctx)
+	default:
+		fmt.Printf("Unknown command: %s\n\n%s", command, usage)
+		os.Exit(1)
+	}
+	if err != nil {
+		log.Fatalf("%s failed: %v", command, err)
+	}
+}
+
+// confirm asks before a command changes anything. -yes answers for the
+// operator, e.g. in scripts
+func (a *admin) confirm(prompt string) bool {
+	if a.yes {
+		return true
+	}
+	fmt.Printf("%s [y/N] ", prompt)
+	answer, _ := a.in.ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	if answer != "y" && answer != "yes" {
+		fmt.Println("❌ Cancelled")
+		return false
+	}
+	return true
+}
+
+// progress rewrites one line as a long command advances
+func progress(format string, args ...interface{}) {
+	fmt.Printf("\r  "+format, args...)
+}
+
+// recountStats runs the counter reconciler the worker runs, fixing drift
+// unless this is a dry run
+func (a *admin) recountStats(ctx context.Context) error {
+	if !a.dryRun && !a.confirm("Recount the counters of every bookmark and fix the ones that drifted?") {
+		return nil
+	}
+
+	cfg := a.cfg.Counters
+	cfg.Fix = !a.dryRun
+	reconciler := counters.NewReconciler(a.db, cfg, a.logger)
+	reconciler.SetProgress(func(checked int) { progress("checked %d bookmarks", checked) })
+
+	fmt.Println("Recounting bookmark counters...")
+	report, err := reconciler.Reconcile(ctx)
+	fmt.Println()
+	if err != nil {
+		return err
+	}
+	for counter, bookmarks := range report.DriftedBookmarks {
+		fmt.Printf("  %s: %d bookmarks off by %d in total\n", counter, bookmarks, report.Drift[counter])
+	}
+	if report.Fixed {
+		fmt.Printf("✅ Checked %d bookmarks, fixed %d in %s\n", report.Checked, report.Drifted, report.Duration)
+	} else {
+		fmt.Printf("✅ Checked %d bookmarks, %d drifted\n", report.Checked, report.Drifted)
+	}
+	return nil
+}
+
+// purgeUser deletes a user the way account deletion does
+func (a *admin) purgeUser(ctx context.Context, userID uint) error {
+	if userID == 0 {
+		return fmt.Errorf("purge-user needs -user")
+	}
+	service := user.NewService(a.db, nil, a.logger)
+
+	summary, err := service.DeletionSummary(ctx, userID)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("User %d has %d bookmarks, %d collections, %d comments, %d sync events and %d follows\n",
+		userID, summary.Bookmarks, summary.Collections, summary.Comments, summary.SyncEvents, summary.Follows)
+	if a.dryRun || !a.confirm(fmt.Sprintf("Permanently delete user %d and all their data?", userID)) {
+		return nil
+	}
+
+	if err := service.DeleteUser(ctx, userID); err != nil {
+		return err
+	}
+	fmt.Printf("✅ Deleted user %d!\n", userID)
+	return nil
+}
+
+// reindexSearch writes every bookmark and collection to Typesense through
+// the indexer the API queues changes on
+func (a *admin) reindexSearch(ctx context.Context) error {
+	counts, err := search.CountReindex(ctx, a.db)
+	if err != nil {
+		return err
+	}
+	total := counts.Bookmarks + counts.Collections
+	fmt.Printf("The index will be rebuilt from %d bookmarks and %d collections\n", counts.Bookmarks, counts.Collections)
+	if a.dryRun || !a.confirm("Rebuild the search index?") {
+		return nil
+	}
+
+	searchService, err := search.NewService(a.cfg.Search)
+	if err != nil {
+		return fmt.Errorf("failed to create search service: %w", err)
+	}
+	indexer := searchService.NewIndexer(a.logger)
+	indexer.SetStatusRecorder(search.NewIndexStatusTracker(a.db, a.logger))
+
+	fmt.Println("Rebuilding the search index...")
+	done, err := indexer.Reindex(ctx, a.db, func(done int) { progress("indexed %d/%d documents", done, total) })
+	fmt.Println()
+	if err != nil {
+		return err
+	}
+	stats := indexer.Stats()
+	if stats.Pending > 0 {
+		fmt.Printf("⚠️  %d documents were rejected, see the index status of their bookmarks\n", stats.Pending)
+	}
+	fmt.Printf("✅ Indexed %d documents!\n", done)
+	return nil
+}
+
+// rotateKeys re-encrypts stored credentials from the configured key to the
+// one in $NEW_ENCRYPTION_KEY. Every secret is checked to decrypt first
+func (a *admin) rotateKeys(ctx context.Context) error {
+	current := a.cfg.Security.EncryptionKey
+	if current == "" || current == "your-encryption-key" {
+		return fmt.Errorf("no encryption key is configured")
+	}
+	nextKey := os.Getenv(newKeyEnv)
+	if nextKey == "" {
+		return fmt.Errorf("set the new key in $%s", newKeyEnv)
+	}
+	if nextKey == current {
+		return fmt.Errorf("the new key is the configured one")
+	}
+
+	cipher, err := secrets.NewCipher(current)
+	if err != nil {
+		return err
+	}
+	next, err := secrets.NewCipher(nextKey)
+	if err != nil {
+		return err
+	}
+	service := automation.NewService(a.db)
+	service.SetCredentialCipher(cipher)
+
+	count, err := service.RotateCredentialKey(ctx, next, true)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("The credentials of %d backup destinations decrypt with the configured key\n", count)
+	if a.dryRun || count == 0 || !a.confirm("Re-encrypt them with the new key?") {
+		return nil
+	}
+
+	if _, err := service.RotateCredentialKey(ctx, next, false); err != nil {
+		return err
+	}
+	fmt.Printf("✅ Re-encrypted %d credentials! Set SECURITY_ENCRYPTION_KEY to the new key and restart the API\n", count)
+	return nil
+}
+
+// applyRetention enforces the retention policy like the worker's cleanup
+// job, always counting the expired rows first
+func (a *admin) applyRetention(ctx context.Context) error {
+	service := retention.NewService(a.cfg.Retention, a.db, a.logger)
+
+	preview, err := service.Enforce(ctx, true)
+	if err != nil {
+		return err
+	}
+	var expired int64
+	for _, result := range preview.Results {
+		printRetention(result)
+		expired += result.Expired
+	}
+	if a.dryRun || expired == 0 || !a.confirm(fmt.Sprintf("Delete %d expired rows?", expired)) {
+		return nil
+	}
+
+	run, err := service.Enforce(ctx, false)
+	if err != nil {
+		return err
+	}
+	var deleted int64
+	for _, result := range run.Results {
+		printRetention(result)
+		deleted += result.Deleted
+	}
+	if run.Error != "" {
+		return errors.New(run.Error)
+	}
+	fmt.Printf("✅ Deleted %d expired rows!\n", deleted)
+	return nil
+}
+
+func printRetention(result retention.ClassResult) {
+	switch {
+	case result.Error != "":
+		fmt.Printf("  %s: ⚠️  %s\n", result.Class, result.Error)
+	case result.Skipped != "":
+		fmt.Printf("  %s: skipped, %s\n", result.Class, result.Skipped)
+	default:
+		fmt.Printf("  %s: %d rows older than %d days, %d deleted\n", result.Class, result.Expired, result.Days, result.Deleted)
+	}
+}
+
+// verifyStorage compares stored objects with the rows referencing them
+// through the storage garbage collector. Missing objects fail the command;
+// without -dry-run expired orphans are collected as the worker would
+func (a *admin) verifyStorage(ctx context.Context) error {
+	storageClient, err := storage.NewClientFromConfig(a.cfg.Storage)
+	if err != nil {
+		return fmt.Errorf("failed to initialize storage client: %w", err)
+	}
+	service := storagegc.NewService(a.cfg.StorageGC, a.db, storagegc.StoreOf(storageClient), a.logger)
+
+	fmt.Println("Checking stored objects...")
+	check, err := service.Collect(ctx, true)
+	if err != nil {
+		return err
+	}
+	var missing, expired int
+	for _, result := range check.Results {
+		printStorage(result)
+		missing += result.Missing
+		expired += result.Expired
+	}
+
+	if !a.dryRun && expired > 0 && a.confirm(fmt.Sprintf("Delete %d orphans past their grace period?", expired)) {
+		run, err := service.Collect(ctx, false)
+		if err != nil {
+			return err
+		}
+		for _, result := range run.Results {
+			printStorage(result)
+		}
+	}
+
+	if missing > 0 {
+		return fmt.Errorf("%d referenced objects are missing from storage", missing)
+	}
+	if check.Error != "" {
+		return errors.New(check.Error)
+	}
+	fmt.Println("✅ Every referenced object is in storage!")
+	return nil
+}
+
+func printStorage(result storagegc.PrefixResult) {
+	switch {
+	case result.Error != "":
+		fmt.Printf("  %s ⚠️  %s\n", result.Prefix, result.Error)
+	case result.Skipped != "":
+		fmt.Printf("  %s skipped, %s\n", result.Prefix, result.Skipped)
+	default:
+		fmt.Printf("  %s %d objects, %d referenced, %d missing, %d orphans (%d expired, %d deleted)\n",
+			result.Prefix, result.Objects, result.Referenced, result.Missing, result.Orphans, result.Expired, result.Deleted)
+	}
+}
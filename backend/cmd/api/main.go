@@ -8,7 +8,9 @@ import (
 	"syscall"
 	"time"
 
+	"bookmark-sync-service/backend/internal/community"
 	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/internal/metering"
 	"bookmark-sync-service/backend/internal/server"
 	"bookmark-sync-service/backend/pkg/database"
 	"bookmark-sync-service/backend/pkg/logger"
@@ -71,6 +73,12 @@ func main() {
 	if err := database.AutoMigrate(db); err != nil {
 		logger.Fatal("Failed to run database migrations", zap.Error(err))
 	}
+	if err := community.AutoMigrate(db); err != nil {
+		logger.Fatal("Failed to run community migrations", zap.Error(err))
+	}
+	if err := metering.AutoMigrate(db); err != nil {
+		logger.Fatal("Failed to run metering migrations", zap.Error(err))
+	}
 
 	// Initialize server
 	srv := server.NewServer(cfg, db, redisClient, supabaseClient, storageClient, searchClient, logger)
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/internal/loadtest"
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/testfactory"
+)
+
+// defaultBudgets are the p95 latencies the hot endpoints must stay under
+// against the default seeded volume
+const defaultBudgets = "list=150ms,search=300ms,sync=200ms,share=150ms"
+
+// searchTerms come from the seeded titles and descriptions so searches
+// return results
+var searchTerms = []string{"golang", "database", "performance", "sync", "redis cache", "security guide"}
+
+// loadUser is a user requests are sent as
+type loadUser struct {
+	token string
+}
+
+func main() {
+	target := flag.String("target", "http://localhost:8080", "Base URL of the API under test")
+	duration := flag.Duration("duration", 30*time.Second, "How long each endpoint is hit")
+	rate := flag.Int("rate", 20, "Requests per second per endpoint")
+	workers := flag.Int("workers", 50, "Maximum requests in flight per endpoint")
+	timeout := flag.Duration("timeout", 10*time.Second, "Timeout of a single request")
+	budgetSpec := flag.String("budgets", defaultBudgets, "p95 budgets as name=duration pairs; endpoints are list, search, sync and share")
+	maxErrorRate := flag.Float64("max-error-rate", 0.01, "Largest share of failed requests an endpoint may have")
+	seed := flag.Bool("seed", true, "Seed users, bookmarks and shares before the run; otherwise use existing data")
+	users := flag.Int("users", 20, "Users to seed or load")
+	bookmarksPerUser := flag.Int("bookmarks", 500, "Bookmarks seeded per user")
+	collectionsPerUser := flag.Int("collections", 5, "Collections seeded per user")
+	randSeed := flag.Int64("rand-seed", 1, "Seed of the generated data")
+	flag.Parse()
+
+	budgets, err := loadtest.ParseBudgets(*budgetSpec, *maxErrorRate)
+	if err != nil {
+		log.Fatalf("Invalid budgets: %v", err)
+	}
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	// Connect to database
+	db, err := database.NewConnection(cfg.Database)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.Fatalf("Failed to get underlying sql.DB: %v", err)
+	}
+	defer sqlDB.Close()
+
+	var dataset *testfactory.Dataset
+	if *seed {
+		fmt.Printf("Seeding %d users with %d bookmarks each...\n", *users, *bookmarksPerUser)
+		dataset, err = testfactory.Seed(db, testfactory.Volume{
+			Users:              *users,
+			BookmarksPerUser:   *bookmarksPerUser,
+			CollectionsPerUser: *collectionsPerUser,
+			SharedCollections:  0.3,
+			RandSeed:           *randSeed,
+		})
+	} else {
+		dataset, err = loadDataset(db, *users)
+	}
+	if err != nil {
+		log.Fatalf("Failed to prepare data: %v", err)
+	}
+	if len(dataset.Users) == 0 {
+		log.Fatalf("No users to send requests as; run with -seed")
+	}
+
+	// Tokens outlive the run so no request fails on expiry
+	expiry := time.Now().Add(*duration + time.Hour)
+	loadUsers := make([]loadUser, 0, len(dataset.Users))
+	for _, user := range dataset.Users {
+		token, err := mintToken(cfg.JWT.Secret, user, expiry)
+		if err != nil {
+			log.Fatalf("Failed to sign token: %v", err)
+		}
+		loadUsers = append(loadUsers, loadUser{token: token})
+	}
+
+	targets := buildTargets(strings.TrimRight(*target, "/"), loadUsers, dataset.Shares)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	fmt.Printf("Sending %d req/s to each of %d endpoints for %s...\n", *rate, len(targets), *duration)
+	results := loadtest.Run(ctx, loadtest.Options{
+		Rate:     *rate,
+		Duration: *duration,
+		Workers:  *workers,
+		Client:   &http.Client{Timeout: *timeout},
+	}, targets)
+
+	loadtest.WriteReport(os.Stdout, results, budgets)
+	if violations := loadtest.Check(results, budgets); len(violations) > 0 {
+		fmt.Println("❌ Performance budgets exceeded:")
+		for _, violation := range violations {
+			fmt.Println("  " + violation)
+		}
+		os.Exit(1)
+	}
+	fmt.Println("✅ All endpoints within budget!")
+}
+
+// buildTargets spreads requests over the users and shares round robin, so
+// caches do not serve every request of an endpoint
+func buildTargets(baseURL string, users []loadUser, shares []*database.CollectionShare) []loadtest.Target {
+	authed := func(i uint64, path string, query url.Values) (*http.Request, error) {
+		req, err := http.NewRequest(http.MethodGet, baseURL+path+"?"+query.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+users[i%uint64(len(users))].token)
+		return req, nil
+	}
+	lastWeek := time.Now().Add(-7 * 24 * time.Hour).Unix()
+
+	targets := []loadtest.Target{
+		{Name: "list", Request: func(i uint64) (*http.Request, error) {
+			return authed(i, "/api/v1/bookmarks", url.Values{"limit": {"20"}, "offset": {fmt.Sprint(i % 5 * 20)}})
+		}},
+		{Name: "search", Request: func(i uint64) (*http.Request, error) {
+			return authed(i, "/api/v1/search/bookmarks", url.Values{"q": {searchTerms[i%uint64(len(searchTerms))]}})
+		}},
+		{Name: "sync", Request: func(i uint64) (*http.Request, error) {
+			return authed(i, "/api/v1/sync/delta", url.Values{
+				"device_id":      {fmt.Sprintf("loadtest-%d", i%uint64(len(users)))},
+				"last_sync_time": {fmt.Sprint(lastWeek)},
+			})
+		}},
+	}
+	if len(shares) > 0 {
+		targets = append(targets, loadtest.Target{Name: "share", Request: func(i uint64) (*http.Request, error) {
+			share := shares[i%uint64(len(shares))]
+			return http.NewRequest(http.MethodGet, baseURL+"/feeds/collections/"+url.PathEscape(share.ShareToken), nil)
+		}})
+	} else {
+		fmt.Println("⚠️  No public shares found, skipping the share endpoint")
+	}
+	return targets
+}
+
+// loadDataset picks existing users with bookmarks and active public shares
+func loadDataset(db *gorm.DB, limit int) (*testfactory.Dataset, error) {
+	dataset := &testfactory.Dataset{}
+	if err := db.Where("id IN (?)", db.Model(&database.Bookmark{}).Select("user_id")).
+		Limit(limit).Find(&dataset.Users).Error; err != nil {
+		return nil, fmt.Errorf("failed to load users: %w", err)
+	}
+	if err := db.Where("share_type = ? AND is_active = ?", "public", true).
+		Limit(100).Find(&dataset.Shares).Error; err != nil {
+		return nil, fmt.Errorf("failed to load shares: %w", err)
+	}
+	return dataset, nil
+}
+
+// mintToken signs an access token the way the auth service does
+func mintToken(secret string, user *database.User, expiry time.Time) (string, error) {
+	claims := jwt.MapClaims{
+		"user_id":     user.ID,
+		"email":       user.Email,
+		"supabase_id": user.SupabaseID,
+		"sub":         user.SupabaseID,
+		"iat":         time.Now().Unix(),
+		"exp":         expiry.Unix(),
+		"type":        "access",
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+}
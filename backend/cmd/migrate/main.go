@@ -1,17 +1,24 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
 	"os"
 
+	"bookmark-sync-service/backend/internal/auth"
+	"bookmark-sync-service/backend/internal/bookmark"
+	"bookmark-sync-service/backend/internal/community"
 	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/internal/metering"
 	"bookmark-sync-service/backend/pkg/database"
 )
 
 func main() {
-	var direction = flag.String("direction", "up", "Migration direction: up or down")
+	var direction = flag.String("direction", "up", "Migration direction: up, down, seed, backfill-tags, backfill-identities or import-identities")
+	var batchSize = flag.Int("batch-size", 500, "Bookmarks per batch when backfilling")
+	var file = flag.String("file", "", "CSV of supabase_id,oidc,subject rows for import-identities")
 	flag.Parse()
 
 	// Load configuration
@@ -38,6 +45,12 @@ func main() {
 		if err := database.AutoMigrate(db); err != nil {
 			log.Fatalf("Failed to run migrations: %v", err)
 		}
+		if err := community.AutoMigrate(db); err != nil {
+			log.Fatalf("Failed to run community migrations: %v", err)
+		}
+		if err := metering.AutoMigrate(db); err != nil {
+			log.Fatalf("Failed to run metering migrations: %v", err)
+		}
 		fmt.Println("✅ Migrations completed successfully!")
 
 	case "down":
@@ -54,8 +67,40 @@ func main() {
 		}
 		fmt.Println("✅ Database seeded successfully!")
 
+	case "backfill-tags":
+		fmt.Println("Copying bookmark tags into the relational tag tables...")
+		copied, err := bookmark.NewService(db).BackfillRelationalTags(context.Background(), *batchSize)
+		if err != nil {
+			log.Fatalf("Failed to backfill tags after %d bookmarks: %v", copied, err)
+		}
+		fmt.Printf("✅ Backfilled tags of %d bookmarks!\n", copied)
+
+	case "backfill-identities":
+		fmt.Println("Linking users to their Supabase IDs...")
+		linked, err := auth.BackfillIdentities(context.Background(), db)
+		if err != nil {
+			log.Fatalf("Failed to backfill identities after %d users: %v", linked, err)
+		}
+		fmt.Printf("✅ Linked %d users to their Supabase IDs!\n", linked)
+
+	case "import-identities":
+		if *file == "" {
+			log.Fatalf("import-identities needs -file")
+		}
+		f, err := os.Open(*file)
+		if err != nil {
+			log.Fatalf("Failed to open %s: %v", *file, err)
+		}
+		defer f.Close()
+		fmt.Println("Linking users to their identity provider accounts...")
+		linked, err := auth.ImportIdentities(context.Background(), db, f)
+		if err != nil {
+			log.Fatalf("Failed to import identities after %d users: %v", linked, err)
+		}
+		fmt.Printf("✅ Linked %d users to their identity provider accounts!\n", linked)
+
 	default:
-		fmt.Printf("Unknown direction: %s. Use 'up', 'down', or 'seed'\n", *direction)
+		fmt.Printf("Unknown direction: %s. Use 'up', 'down', 'seed', 'backfill-tags', 'backfill-identities' or 'import-identities'\n", *direction)
 		os.Exit(1)
 	}
 }
@@ -5,6 +5,7 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"bookmark-sync-service/backend/internal/config"
 	"bookmark-sync-service/backend/pkg/database"
@@ -48,6 +49,12 @@ func main() {
 
 	// Create WebSocket hub
 	wsHub := websocket.NewHub(redisClient, logger)
+	wsHub.SetBackpressure(websocket.Backpressure{
+		SendBuffer:     cfg.WebSocket.SendBuffer,
+		Policy:         websocket.OverflowPolicy(cfg.WebSocket.OverflowPolicy),
+		CoalesceWindow: time.Duration(cfg.WebSocket.CoalesceWindow) * time.Millisecond,
+	})
+	wsHub.SetLiveQueryLimit(cfg.WebSocket.LiveQueryLimit)
 
 	// Start WebSocket hub
 	ctx, cancel := context.WithCancel(context.Background())
@@ -2,19 +2,48 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"bookmark-sync-service/backend/internal/automation"
+	"bookmark-sync-service/backend/internal/backfill"
+	"bookmark-sync-service/backend/internal/bookmark"
+	"bookmark-sync-service/backend/internal/calendar"
+	"bookmark-sync-service/backend/internal/cleanup"
+	"bookmark-sync-service/backend/internal/community"
+	"bookmark-sync-service/backend/internal/compliance"
 	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/internal/counters"
+	"bookmark-sync-service/backend/internal/demo"
+	"bookmark-sync-service/backend/internal/graph"
+	"bookmark-sync-service/backend/internal/maintenance"
+	"bookmark-sync-service/backend/internal/merge"
+	"bookmark-sync-service/backend/internal/metering"
+	"bookmark-sync-service/backend/internal/monitoring"
+	"bookmark-sync-service/backend/internal/quality"
+	"bookmark-sync-service/backend/internal/retention"
+	"bookmark-sync-service/backend/internal/screenshot"
+	"bookmark-sync-service/backend/internal/search"
+	"bookmark-sync-service/backend/internal/seo"
+	"bookmark-sync-service/backend/internal/sharing"
+	"bookmark-sync-service/backend/internal/storagegc"
 	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/dualwrite"
 	"bookmark-sync-service/backend/pkg/logger"
+	"bookmark-sync-service/backend/pkg/mail"
 	"bookmark-sync-service/backend/pkg/redis"
+	"bookmark-sync-service/backend/pkg/storage"
 	"bookmark-sync-service/backend/pkg/supabase"
+	"bookmark-sync-service/backend/pkg/worker"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
-	"gorm.io/gorm"
 )
 
 func main() {
@@ -47,13 +76,129 @@ func main() {
 		logger.Fatal("Failed to connect to Supabase", zap.Error(err))
 	}
 
+	// Initialize storage client for garbage collection
+	storageClient, err := storage.NewClientFromConfig(cfg.Storage)
+	if err != nil {
+		logger.Fatal("Failed to initialize storage client", zap.Error(err))
+	}
+
 	// Create context with cancellation
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// Scheduled jobs are skipped while maintenance mode is on
+	maintenanceService := maintenance.NewService(cfg.Maintenance, redisClient)
+
+	// Scheduled singleton jobs queued on this pool run under their
+	// distributed lock, so one replica executes each at a time
+	jobPool := worker.NewWorkerPool(config.DefaultWorkerPoolSize, config.DefaultQueueSize, logger)
+	jobPool.SetLocker(redisClient)
+	jobPool.SetPauseCheck(maintenanceService.IsEnabled)
+	jobPool.Start()
+	defer jobPool.Stop()
+
 	// Start background workers
-	go runLinkChecker(ctx, db, logger)
-	go runCleanupJob(ctx, db, redisClient, logger)
+	reconciler := counters.NewReconciler(db, cfg.Counters, logger)
+	go runSingleton(ctx, maintenanceService.IsEnabled, redisClient, "link check", "job:link_check", 24*time.Hour, checkLinks, logger)
+	go runSingleton(ctx, maintenanceService.IsEnabled, redisClient, "data retention cleanup", "job:cleanup", time.Duration(cfg.Retention.Interval)*time.Minute, retention.NewService(cfg.Retention, db, logger).RunCleanup, logger)
+	go runSingleton(ctx, maintenanceService.IsEnabled, redisClient, "storage garbage collection", "job:storage_gc", time.Duration(cfg.StorageGC.Interval)*time.Minute, collectStorage(storagegc.NewService(cfg.StorageGC, db, storagegc.StoreOf(storageClient), logger)), logger)
+	go runSingleton(ctx, maintenanceService.IsEnabled, redisClient, "counter reconciliation", "job:counter_reconciliation", time.Duration(cfg.Counters.ReconcileInterval)*time.Minute, reconciler.ReconcileCounters, logger)
+
+	sharingService := sharing.NewService(db, cfg.Server.BaseURL)
+	sharingService.SetLogger(logger)
+	sharingService.SetMailer(mail.NewSender(cfg.Mail, logger), cfg.Subscriptions)
+	// Subscribers become due at different times, so check more often than
+	// each of them is emailed
+	go runSingleton(ctx, maintenanceService.IsEnabled, redisClient, "subscription emails", "job:subscription_digests", time.Duration(cfg.Subscriptions.DigestInterval)*time.Minute/4, counted(sharingService.SendSubscriptionDigests, "Subscription emails sent", logger), logger)
+
+	webhookService := automation.NewService(db)
+	webhookService.SetWebhookDeliveryLimits(automation.WebhookDeliveryLimits{
+		MaxConcurrent:   cfg.Webhooks.MaxConcurrency,
+		PerEndpoint:     cfg.Webhooks.EndpointConcurrency,
+		BreakerTimeouts: cfg.Webhooks.BreakerTimeouts,
+		BreakerCooldown: time.Duration(cfg.Webhooks.BreakerCooldown) * time.Second,
+	})
+	// Failing endpoints are disabled by the configured policy and their
+	// owners notified on their notification channel
+	webhookService.SetWebhookHealthPolicy(automation.WebhookHealthPolicy{
+		MaxConsecutiveFailures: cfg.Webhooks.DisableAfterFailures,
+		MaxFailingDuration:     time.Duration(cfg.Webhooks.DisableAfterDays) * 24 * time.Hour,
+		Window:                 time.Duration(cfg.Webhooks.HealthWindowDays) * 24 * time.Hour,
+	})
+	webhookService.SetNotificationPublisher(redisClient)
+	// Download links in deliveries must verify on the API, so both sign with the configured key
+	if cfg.Storage.DownloadSigningKey != "" {
+		webhookService.SetDownloadSigner(automation.NewDownloadSigner(cfg.Server.BaseURL, cfg.Storage.DownloadSigningKey, time.Duration(cfg.Storage.DownloadURLTTL)*time.Second))
+	}
+	// Deliveries of threshold alerts and scheduled rules count towards usage
+	meteringService := metering.NewService(db, logger)
+	webhookService.SetUsageMeter(meteringService)
+	go meteringService.Run(ctx)
+	go runSingleton(ctx, maintenanceService.IsEnabled, redisClient, "automation threshold evaluation", "job:automation_thresholds", time.Duration(cfg.Webhooks.ThresholdInterval)*time.Minute, counted(webhookService.EvaluateThresholds, "Automation thresholds reached", logger), logger)
+
+	// Scheduled rules tag bookmarks through the bookmark service, so their
+	// changes sync and reach the relational tags as the API's do
+	bookmarkService := bookmark.NewService(db)
+	bookmarkService.SetLogger(logger)
+	tagsMode, err := dualwrite.ParseMode(cfg.Migrations.TagsMode)
+	if err != nil {
+		logger.Error("Invalid relational tags mode, leaving it off", zap.Error(err))
+	}
+	bookmarkService.SetTagMigration(dualwrite.New(bookmark.TagMigrationName, tagsMode, logger))
+	webhookService.SetBookmarkTagger(bookmarkService)
+	go runSingleton(ctx, maintenanceService.IsEnabled, redisClient, "scheduled automation rules", "job:automation_scheduled_rules", time.Duration(cfg.Webhooks.RuleScheduleInterval)*time.Minute, counted(webhookService.RunScheduledRules, "Scheduled automation rules ran", logger), logger)
+
+	complianceService := compliance.NewService(cfg.Compliance, db, logger)
+	complianceService.SetUploader(webhookService)
+	go runSingleton(ctx, maintenanceService.IsEnabled, redisClient, "compliance reports", "job:compliance_reports", time.Duration(cfg.Compliance.Interval)*time.Minute, counted(complianceService.RunDue, "Compliance reports delivered", logger), logger)
+	go runSingleton(ctx, maintenanceService.IsEnabled, redisClient, "calendar stats aggregation", "job:calendar_stats", time.Duration(cfg.Calendar.Interval)*time.Minute, counted(calendar.NewService(cfg.Calendar, db).Refresh, "Calendar stats aggregated", logger), logger)
+	go runSingleton(ctx, maintenanceService.IsEnabled, redisClient, "bookmark graph refresh", "job:bookmark_graph", time.Duration(cfg.Graph.Interval)*time.Minute, counted(graph.NewService(cfg.Graph, db).Refresh, "Bookmark graph refreshed", logger), logger)
+	go runSingleton(ctx, maintenanceService.IsEnabled, redisClient, "demo sandbox cleanup", "job:demo_cleanup", time.Duration(cfg.Demo.CleanupInterval)*time.Minute, demo.NewService(cfg.Demo, db, nil, logger).RunCleanup, logger)
+	go runSingleton(ctx, maintenanceService.IsEnabled, redisClient, "bookmark quality scoring", "job:quality_scoring", time.Duration(cfg.Quality.Interval)*time.Minute, quality.NewService(cfg.Quality, db, logger).RunScoring, logger)
+	mergeService := merge.NewService(cfg.Merge, db, logger)
+	// Merged bookmarks and collections are re-indexed under their new owner.
+	// The indexer writes in batches and flushes what is pending on shutdown
+	var indexerDone chan struct{}
+	if searchService, err := search.NewService(cfg.Search); err != nil {
+		logger.Error("Failed to create search service, merges won't be re-indexed", zap.Error(err))
+	} else {
+		indexer := searchService.NewIndexer(logger)
+		mergeService.SetIndexer(indexer)
+		indexerDone = make(chan struct{})
+		go func() {
+			defer close(indexerDone)
+			indexer.Run(ctx)
+		}()
+	}
+	go runSingleton(ctx, maintenanceService.IsEnabled, redisClient, "account merges", "job:account_merges", time.Duration(cfg.Merge.Interval)*time.Minute, mergeService.RunQueued, logger)
+
+	// Trending scores are recomputed from recent behaviour for every window
+	trendingService := community.NewTrendingService(community.NewGormAdapter(db), nil, community.NewJSONHelper(), logger)
+	trendingService.SetPrivacy(community.TrendingPrivacy{
+		MinParticipants: cfg.Privacy.TrendingMinParticipants,
+		Noise:           cfg.Privacy.TrendingNoise,
+	})
+	go runTrendingCalculation(ctx, maintenanceService.IsEnabled, jobPool, trendingService, config.TrendingCalculationInterval, logger)
+
+	// The sitemap is rebuilt here and shared with the API through Redis
+	seoService := seo.NewService(cfg.SEO, db, redisClient, cfg.Server.BaseURL, logger)
+	go runSitemapRefresh(ctx, maintenanceService.IsEnabled, jobPool, seoService, time.Duration(cfg.SEO.RefreshInterval)*time.Minute, logger)
+
+	cleanupService := cleanup.NewService(cfg.Cleanup, db, logger)
+	cleanupService.SetNotifier(redisClient)
+	go runSingleton(ctx, maintenanceService.IsEnabled, redisClient, "cleanup reminders", "job:cleanup_reminders", time.Duration(cfg.Cleanup.ReminderInterval)*time.Minute, counted(cleanupService.SendReminders, "Cleanup reminders sent", logger), logger)
+
+	// Screenshot and archive backfills started by admins, in the nightly window
+	archiver := monitoring.NewService(db)
+	archiver.SetLogger(logger)
+	archiver.SetArchiveConfig(cfg.Archive)
+	backfillService := backfill.NewService(cfg.Backfill, db, screenshot.NewService(storageClient), archiver, redisClient, logger)
+	go runSingleton(ctx, maintenanceService.IsEnabled, redisClient, "media backfill", "job:media_backfill", time.Duration(cfg.Backfill.Interval)*time.Minute, counted(backfillService.RunBatch, "Media backfill batch ran", logger), logger)
+
+	// Expose worker metrics such as counter drift
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(counters.NewCollector(reconciler))
+	go serveMetrics(ctx, cfg.Worker.MetricsAddr, registry, logger)
 
 	logger.Info("Worker service started")
 
@@ -64,43 +209,159 @@ func main() {
 
 	logger.Info("Shutting down worker service...")
 	cancel()
+	if indexerDone != nil {
+		<-indexerDone
+	}
 	logger.Info("Worker service exited")
 }
 
-// runLinkChecker periodically checks bookmarked links for validity
-func runLinkChecker(ctx context.Context, db *gorm.DB, logger *zap.Logger) {
-	ticker := time.NewTicker(24 * time.Hour)
+// pauseCheck reports whether maintenance mode holds scheduled jobs back
+type pauseCheck func(ctx context.Context) bool
+
+// errStopJob is returned by a scheduled job that can't run in this
+// deployment, ending its worker
+var errStopJob = errors.New("scheduled job stopped")
+
+// runSingleton runs fn on every tick of interval, on one worker replica at a
+// time and never while maintenance mode is on. A non-positive interval
+// disables the job
+func runSingleton(ctx context.Context, paused pauseCheck, locker redis.Locker, name, lockKey string, interval time.Duration, fn func(ctx context.Context) error, logger *zap.Logger) {
+	logger = logger.With(zap.String("job", name))
+	if interval <= 0 {
+		logger.Info("Scheduled job disabled")
+		return
+	}
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
-	logger.Info("Starting link checker worker")
+	logger.Info("Starting scheduled job worker")
 
 	for {
 		select {
 		case <-ticker.C:
-			logger.Info("Running link check job")
-			// TODO: Implement link checking logic
+			if paused(ctx) {
+				logger.Debug("Maintenance mode on, skipping scheduled run")
+				continue
+			}
+			err := locker.WithLock(ctx, lockKey, config.SingletonJobLockTTL, fn)
+			if errors.Is(err, redis.ErrLockNotAcquired) {
+				logger.Debug("Scheduled job running on another replica")
+			} else if errors.Is(err, errStopJob) {
+				logger.Warn("Scheduled job disabled", zap.Error(err))
+				return
+			} else if err != nil {
+				logger.Error("Scheduled job failed", zap.Error(err))
+			}
 		case <-ctx.Done():
-			logger.Info("Link checker worker stopped")
+			logger.Info("Scheduled job worker stopped")
 			return
 		}
 	}
 }
 
-// runCleanupJob periodically cleans up expired data
-func runCleanupJob(ctx context.Context, db *gorm.DB, redisClient *redis.Client, logger *zap.Logger) {
-	ticker := time.NewTicker(1 * time.Hour)
+// counted adapts a job returning how much it did, logging the count when it
+// did anything
+func counted(fn func(ctx context.Context) (int, error), message string, logger *zap.Logger) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		count, err := fn(ctx)
+		if count > 0 {
+			logger.Info(message, zap.Int("count", count))
+		}
+		return err
+	}
+}
+
+// collectStorage runs storage garbage collection, stopping the job when the
+// deployment has no object store
+func collectStorage(service *storagegc.Service) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		err := service.RunCollect(ctx)
+		if errors.Is(err, storagegc.ErrNoObjectStore) {
+			return fmt.Errorf("%w: %v", errStopJob, err)
+		}
+		return err
+	}
+}
+
+// checkLinks checks bookmarked links for validity
+func checkLinks(ctx context.Context) error {
+	// TODO: Implement link checking logic
+	return nil
+}
+
+// trendingWindows are the time windows trending scores are kept for
+var trendingWindows = []string{"hourly", "daily", "weekly", "monthly"}
+
+// runTrendingCalculation periodically queues a trending calculation for each
+// window. The jobs are singletons, so the pool runs each under its lock
+func runTrendingCalculation(ctx context.Context, paused pauseCheck, pool *worker.WorkerPool, service worker.TrendingCalculationService, interval time.Duration, logger *zap.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	logger.Info("Starting trending calculation worker")
+
+	for {
+		select {
+		case <-ticker.C:
+			if paused(ctx) {
+				logger.Debug("Maintenance mode on, skipping scheduled run")
+				continue
+			}
+			for _, window := range trendingWindows {
+				if err := pool.Submit(worker.NewTrendingCalculationJob(window, service, logger)); err != nil {
+					logger.Warn("Failed to queue trending calculation", zap.String("time_window", window), zap.Error(err))
+				}
+			}
+		case <-ctx.Done():
+			logger.Info("Trending calculation worker stopped")
+			return
+		}
+	}
+}
+
+// runSitemapRefresh queues a sitemap rebuild right away and then on the
+// interval while public indexing is enabled. The job is a singleton, so the
+// pool runs it under its lock
+func runSitemapRefresh(ctx context.Context, paused pauseCheck, pool *worker.WorkerPool, service *seo.Service, interval time.Duration, logger *zap.Logger) {
+	if !service.Enabled() || interval <= 0 {
+		logger.Info("Sitemap refresh disabled")
+		return
+	}
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
-	logger.Info("Starting cleanup worker")
+	logger.Info("Starting sitemap refresh worker")
 
 	for {
+		if paused(ctx) {
+			logger.Debug("Maintenance mode on, skipping scheduled run")
+		} else if err := pool.Submit(worker.NewSitemapRefreshJob(service, logger)); err != nil {
+			logger.Warn("Failed to queue sitemap refresh", zap.Error(err))
+		}
+
 		select {
 		case <-ticker.C:
-			logger.Info("Running cleanup job")
-			// TODO: Implement cleanup logic for expired tokens, temporary data, etc.
 		case <-ctx.Done():
-			logger.Info("Cleanup worker stopped")
+			logger.Info("Sitemap refresh worker stopped")
 			return
 		}
 	}
 }
+
+// serveMetrics serves the worker's Prometheus metrics until ctx is done
+func serveMetrics(ctx context.Context, addr string, registry *prometheus.Registry, logger *zap.Logger) {
+	if addr == "" {
+		return
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	server := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Error("Worker metrics server failed", zap.Error(err))
+	}
+}
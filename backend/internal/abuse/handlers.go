@@ -0,0 +1,95 @@
+package abuse
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"bookmark-sync-service/backend/pkg/utils"
+)
+
+// Handler serves abuse reports to share owners and admins
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new abuse handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes registers the share owner routes on an authenticated group
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/shares/abuse-reports", h.ListOwnReports)
+}
+
+// RegisterAdminRoutes registers abuse routes on an admin-only group
+func (h *Handler) RegisterAdminRoutes(router *gin.RouterGroup) {
+	router.GET("/abuse/reports", h.ListReports)
+	router.DELETE("/abuse/blocks/:ip", h.Unblock)
+}
+
+// ListOwnReports lists clients blocked while hammering the user's shares
+// @Summary List abuse reports for my shares
+// @Tags sharing
+// @Produce json
+// @Param active query bool false "Only blocks still in force"
+// @Param limit query int false "Maximum reports"
+// @Success 200 {array} database.AbuseReport
+// @Router /shares/abuse-reports [get]
+func (h *Handler) ListOwnReports(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+	h.listReports(c, userID)
+}
+
+// ListReports lists every abuse report
+// @Summary List abuse reports
+// @Tags admin
+// @Produce json
+// @Param active query bool false "Only blocks still in force"
+// @Param limit query int false "Maximum reports"
+// @Success 200 {array} database.AbuseReport
+// @Router /admin/abuse/reports [get]
+func (h *Handler) ListReports(c *gin.Context) {
+	h.listReports(c, 0)
+}
+
+func (h *Handler) listReports(c *gin.Context, ownerID uint) {
+	filter := ReportFilter{OwnerID: ownerID}
+	filter.ActiveOnly, _ = strconv.ParseBool(c.Query("active"))
+	filter.Limit, _ = strconv.Atoi(c.Query("limit"))
+
+	reports, err := h.service.ListReports(c.Request.Context(), filter)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "LIST_FAILED", err.Error(), nil)
+		return
+	}
+	utils.SuccessResponse(c, reports, "Abuse reports retrieved")
+}
+
+// Unblock lifts the block on an IP address
+// @Summary Unblock an IP address
+// @Tags admin
+// @Produce json
+// @Param ip path string true "Blocked IP address"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/abuse/blocks/{ip} [delete]
+func (h *Handler) Unblock(c *gin.Context) {
+	err := h.service.Unblock(c.Request.Context(), c.Param("ip"))
+	switch {
+	case errors.Is(err, ErrInvalidIP):
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_IP", err.Error(), nil)
+	case errors.Is(err, ErrNotBlocked):
+		utils.ErrorResponse(c, http.StatusNotFound, "NOT_BLOCKED", err.Error(), nil)
+	case err != nil:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "UNBLOCK_FAILED", err.Error(), nil)
+	default:
+		utils.SuccessResponse(c, gin.H{"ip": c.Param("ip")}, "IP address unblocked")
+	}
+}
@@ -0,0 +1,44 @@
+package abuse
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"bookmark-sync-service/backend/pkg/utils"
+)
+
+// Middleware enforces the per-IP budget on a public share endpoint. Clients
+// over budget are slowed down, then blocked with 429; answering with 404
+// counts toward the token enumeration limit
+func (s *Service) Middleware(endpoint string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !s.cfg.Enabled {
+			c.Next()
+			return
+		}
+
+		ip := c.ClientIP()
+		decision := s.Check(c.Request.Context(), ip, endpoint, c.Param("token"))
+		switch decision.Action {
+		case ActionBlock:
+			retryAfter := int(math.Ceil(decision.RetryAfter.Seconds()))
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			utils.ErrorResponse(c, http.StatusTooManyRequests, "TOO_MANY_REQUESTS", "Too many requests from this address", map[string]interface{}{
+				"retry_after": retryAfter,
+			})
+			c.Abort()
+			return
+		case ActionTarpit:
+			s.Tarpit(c.Request.Context())
+		}
+
+		c.Next()
+
+		if c.Writer.Status() == http.StatusNotFound {
+			s.RecordInvalidToken(c.Request.Context(), ip, endpoint)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package abuse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/internal/sharing"
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+// Reasons an IP gets blocked
+const (
+	ReasonRateLimit        = "rate_limit"
+	ReasonTokenEnumeration = "token_enumeration"
+)
+
+var (
+	// ErrInvalidIP is returned when unblocking something that is not an IP address
+	ErrInvalidIP = errors.New("invalid IP address")
+	// ErrNotBlocked is returned when unblocking an IP that is not blocked
+	ErrNotBlocked = errors.New("IP address is not blocked")
+)
+
+// Store is the subset of Redis the abuse counters and blocks live in
+type Store interface {
+	IncrementWithExpiration(ctx context.Context, key string, expiration time.Duration) (int64, error)
+	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error
+	Get(ctx context.Context, key string) (string, error)
+	Del(ctx context.Context, keys ...string) error
+}
+
+// Action is what happens to a request from a client
+type Action int
+
+const (
+	// ActionAllow serves the request
+	ActionAllow Action = iota
+	// ActionTarpit serves the request after a delay
+	ActionTarpit
+	// ActionBlock rejects the request
+	ActionBlock
+)
+
+// Decision is the outcome of checking a request
+type Decision struct {
+	Action     Action
+	RetryAfter time.Duration // for blocked requests
+}
+
+// Service keeps per-IP request budgets for the login-less share endpoints in
+// Redis, so every replica sees the same counts and blocks
+type Service struct {
+	cfg    config.AbuseConfig
+	db     *gorm.DB
+	store  Store
+	logger *zap.Logger
+	now    func() time.Time
+	sleep  func(ctx context.Context, d time.Duration)
+}
+
+// NewService creates an abuse detection service
+func NewService(cfg config.AbuseConfig, db *gorm.DB, store Store, logger *zap.Logger) *Service {
+	return &Service{
+		cfg:    cfg,
+		db:     db,
+		store:  store,
+		logger: logger,
+		now:    time.Now,
+		sleep:  sleepContext,
+	}
+}
+
+// Check counts a request from ip and decides whether to serve it. Redis
+// errors let the request through rather than taking public shares down
+func (s *Service) Check(ctx context.Context, ip, endpoint, token string) Decision {
+	if !s.cfg.Enabled || s.cfg.RequestsPerMinute <= 0 {
+		return Decision{Action: ActionAllow}
+	}
+
+	if until, blocked := s.blockedUntil(ctx, ip); blocked {
+		return Decision{Action: ActionBlock, RetryAfter: until.Sub(s.now())}
+	}
+
+	window := s.now().Truncate(time.Minute).Unix()
+	count, err := s.store.IncrementWithExpiration(ctx, s.key("rate", ip, window), time.Minute)
+	if err != nil {
+		s.logger.Warn("Failed to count public request", zap.Error(err))
+		return Decision{Action: ActionAllow}
+	}
+
+	limit := int64(s.cfg.RequestsPerMinute)
+	switch {
+	case count > 2*limit:
+		return s.block(ctx, ip, ReasonRateLimit, endpoint, token, count)
+	case count > limit:
+		return Decision{Action: ActionTarpit}
+	default:
+		return Decision{Action: ActionAllow}
+	}
+}
+
+// RecordInvalidToken counts a request for an unknown share token and blocks
+// the IP once it looks like it is guessing tokens
+func (s *Service) RecordInvalidToken(ctx context.Context, ip, endpoint string) {
+	if !s.cfg.Enabled || s.cfg.InvalidTokensPerHour <= 0 {
+		return
+	}
+
+	window := s.now().Truncate(time.Hour).Unix()
+	count, err := s.store.IncrementWithExpiration(ctx, s.key("miss", ip, window), time.Hour)
+	if err != nil {
+		s.logger.Warn("Failed to count invalid share token", zap.Error(err))
+		return
+	}
+	if count >= int64(s.cfg.InvalidTokensPerHour) {
+		s.block(ctx, ip, ReasonTokenEnumeration, endpoint, "", count)
+	}
+}
+
+// Tarpit waits out the configured delay or until the client goes away
+func (s *Service) Tarpit(ctx context.Context) {
+	s.sleep(ctx, time.Duration(s.cfg.TarpitDelay)*time.Millisecond)
+}
+
+// block stores the block in Redis and reports it to admins and, for a
+// specific share, to its owner
+func (s *Service) block(ctx context.Context, ip, reason, endpoint, token string, count int64) Decision {
+	duration := time.Duration(s.cfg.BlockDuration) * time.Minute
+	if duration <= 0 {
+		duration = time.Hour
+	}
+	until := s.now().Add(duration)
+
+	if err := s.store.Set(ctx, s.key("block", ip), until.Unix(), duration); err != nil {
+		s.logger.Warn("Failed to block abusive IP", zap.String("ip", ip), zap.Error(err))
+		return Decision{Action: ActionTarpit}
+	}
+
+	report := &database.AbuseReport{
+		IPAddress:    ip,
+		Reason:       reason,
+		Endpoint:     endpoint,
+		RequestCount: count,
+		BlockedUntil: until.UTC(),
+	}
+	if token != "" {
+		var share database.CollectionShare
+		if err := s.db.WithContext(ctx).Select("id", "user_id").Where("share_token = ?", token).First(&share).Error; err == nil {
+			report.ShareID = &share.ID
+			report.OwnerID = &share.UserID
+		}
+	}
+	if err := s.db.WithContext(ctx).Create(report).Error; err != nil {
+		s.logger.Error("Failed to save abuse report", zap.Error(err))
+	}
+
+	s.logger.Warn("Blocked abusive client",
+		zap.String("ip", ip),
+		zap.String("reason", reason),
+		zap.String("endpoint", endpoint),
+		zap.Int64("count", count),
+		zap.Time("until", until))
+	return Decision{Action: ActionBlock, RetryAfter: duration}
+}
+
+// blockedUntil reports whether ip is blocked and until when
+func (s *Service) blockedUntil(ctx context.Context, ip string) (time.Time, bool) {
+	value, err := s.store.Get(ctx, s.key("block", ip))
+	if err != nil {
+		return time.Time{}, false
+	}
+	unix, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	until := time.Unix(unix, 0)
+	return until, until.After(s.now())
+}
+
+// Unblock lifts the block on an IP, resets its counters so it is not blocked
+// again straight away, and resolves its open reports
+func (s *Service) Unblock(ctx context.Context, ip string) error {
+	if net.ParseIP(ip) == nil {
+		return ErrInvalidIP
+	}
+	if _, blocked := s.blockedUntil(ctx, ip); !blocked {
+		return ErrNotBlocked
+	}
+	now := s.now()
+	keys := []string{
+		s.key("block", ip),
+		s.key("rate", ip, now.Truncate(time.Minute).Unix()),
+		s.key("miss", ip, now.Truncate(time.Hour).Unix()),
+	}
+	if err := s.store.Del(ctx, keys...); err != nil {
+		return fmt.Errorf("failed to unblock IP: %w", err)
+	}
+	if err := s.db.WithContext(ctx).Model(&database.AbuseReport{}).
+		Where("ip_address = ? AND resolved_at IS NULL", ip).
+		Update("resolved_at", now.UTC()).Error; err != nil {
+		return fmt.Errorf("failed to resolve abuse reports: %w", err)
+	}
+	return nil
+}
+
+// ReportFilter narrows a report listing
+type ReportFilter struct {
+	OwnerID    uint // reports about this user's shares; 0 lists all
+	ActiveOnly bool // only blocks still in force
+	Limit      int
+}
+
+// ListReports returns abuse reports, newest first. Share owners only see the
+// network of the offending IP, like in share analytics
+func (s *Service) ListReports(ctx context.Context, filter ReportFilter) ([]database.AbuseReport, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = config.DefaultPageSize
+	}
+	if limit > config.MaxPageSize {
+		limit = config.MaxPageSize
+	}
+
+	query := s.db.WithContext(ctx).Order("created_at DESC, id DESC").Limit(limit)
+	if filter.OwnerID != 0 {
+		query = query.Where("owner_id = ?", filter.OwnerID)
+	}
+	if filter.ActiveOnly {
+		query = query.Where("resolved_at IS NULL AND blocked_until > ?", s.now().UTC())
+	}
+
+	var reports []database.AbuseReport
+	if err := query.Find(&reports).Error; err != nil {
+		return nil, fmt.Errorf("failed to list abuse reports: %w", err)
+	}
+	if filter.OwnerID != 0 {
+		for i := range reports {
+			reports[i].IPAddress = sharing.AnonymizeIP(reports[i].IPAddress)
+		}
+	}
+	return reports, nil
+}
+
+func (s *Service) key(kind, ip string, window ...int64) string {
+	if len(window) > 0 {
+		return fmt.Sprintf("%s:%s:%s:%d", config.AbuseKeyPrefix, kind, ip, window[0])
+	}
+	return fmt.Sprintf("%s:%s:%s", config.AbuseKeyPrefix, kind, ip)
+}
+
+func sleepContext(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
@@ -0,0 +1,208 @@
+package abuse
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/redis"
+)
+
+var testConfig = config.AbuseConfig{
+	Enabled:              true,
+	RequestsPerMinute:    3,
+	TarpitDelay:          2000,
+	InvalidTokensPerHour: 3,
+	BlockDuration:        10,
+}
+
+type testEnv struct {
+	service *Service
+	db      *gorm.DB
+	mr      *miniredis.Miniredis
+	slept   []time.Duration
+}
+
+func setupService(t *testing.T, cfg config.AbuseConfig) *testEnv {
+	mr := miniredis.RunT(t)
+	client, err := redis.NewClient(config.RedisConfig{Host: mr.Host(), Port: mr.Port(), PoolSize: 1})
+	require.NoError(t, err)
+
+	db, err := database.SetupTestDB()
+	require.NoError(t, err)
+	t.Cleanup(func() { database.CleanupTestDB(db) })
+
+	env := &testEnv{db: db, mr: mr}
+	env.service = NewService(cfg, db, client, zap.NewNop())
+	now := time.Date(2026, 3, 1, 12, 0, 30, 0, time.UTC)
+	env.service.now = func() time.Time { return now }
+	env.service.sleep = func(_ context.Context, d time.Duration) { env.slept = append(env.slept, d) }
+	return env
+}
+
+func setupRouter(env *testEnv) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/feeds/collections/:token", env.service.Middleware("feed"), func(c *gin.Context) {
+		if c.Param("token") != "known" {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func get(router *gin.Engine, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/feeds/collections/"+token, nil)
+	req.RemoteAddr = "203.0.113.7:4321"
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestMiddleware_TarpitsThenBlocks(t *testing.T) {
+	env := setupService(t, testConfig)
+	router := setupRouter(env)
+	require.NoError(t, env.db.Create(&database.CollectionShare{CollectionID: 1, UserID: 42, ShareToken: "known"}).Error)
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, get(router, "known").Code)
+	}
+	assert.Empty(t, env.slept)
+
+	// Over budget: served, but slowly
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, get(router, "known").Code)
+	}
+	assert.Equal(t, []time.Duration{2 * time.Second, 2 * time.Second, 2 * time.Second}, env.slept)
+
+	// Twice the budget: blocked
+	w := get(router, "known")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "600", w.Header().Get("Retry-After"))
+	assert.True(t, env.mr.Exists("abuse:block:203.0.113.7"))
+
+	// Blocked requests are not counted again
+	assert.Equal(t, http.StatusTooManyRequests, get(router, "known").Code)
+
+	var reports []database.AbuseReport
+	require.NoError(t, env.db.Find(&reports).Error)
+	require.Len(t, reports, 1)
+	assert.Equal(t, ReasonRateLimit, reports[0].Reason)
+	assert.Equal(t, "feed", reports[0].Endpoint)
+	assert.Equal(t, int64(7), reports[0].RequestCount)
+	require.NotNil(t, reports[0].OwnerID)
+	assert.Equal(t, uint(42), *reports[0].OwnerID)
+}
+
+func TestMiddleware_BlocksTokenEnumeration(t *testing.T) {
+	cfg := testConfig
+	cfg.RequestsPerMinute = 100
+	env := setupService(t, cfg)
+	router := setupRouter(env)
+
+	for _, token := range []string{"aaaa", "aaab", "aaac"} {
+		assert.Equal(t, http.StatusNotFound, get(router, token).Code)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, get(router, "known").Code)
+
+	var report database.AbuseReport
+	require.NoError(t, env.db.First(&report).Error)
+	assert.Equal(t, ReasonTokenEnumeration, report.Reason)
+	assert.Nil(t, report.OwnerID, "guessed tokens belong to nobody")
+}
+
+func TestMiddleware_Disabled(t *testing.T) {
+	cfg := testConfig
+	cfg.Enabled = false
+	env := setupService(t, cfg)
+	router := setupRouter(env)
+
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, http.StatusNotFound, get(router, "guess").Code)
+	}
+	assert.Empty(t, env.mr.Keys())
+}
+
+func TestMiddleware_FailsOpen(t *testing.T) {
+	env := setupService(t, testConfig)
+	router := setupRouter(env)
+	env.mr.Close()
+
+	assert.Equal(t, http.StatusOK, get(router, "known").Code)
+}
+
+func TestService_UnblockAndReports(t *testing.T) {
+	env := setupService(t, testConfig)
+	ctx := context.Background()
+	require.NoError(t, env.db.Create(&database.CollectionShare{CollectionID: 1, UserID: 42, ShareToken: "known"}).Error)
+
+	for i := 0; i < 7; i++ {
+		env.service.Check(ctx, "203.0.113.7", "embed", "known")
+	}
+	for i := 0; i < 3; i++ {
+		env.service.RecordInvalidToken(ctx, "198.51.100.9", "feed")
+	}
+
+	all, err := env.service.ListReports(ctx, ReportFilter{ActiveOnly: true})
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+
+	own, err := env.service.ListReports(ctx, ReportFilter{OwnerID: 42})
+	require.NoError(t, err)
+	require.Len(t, own, 1)
+	assert.Equal(t, "203.0.113.0", own[0].IPAddress, "owners only see the network")
+
+	assert.ErrorIs(t, env.service.Unblock(ctx, "not-an-ip"), ErrInvalidIP)
+	assert.ErrorIs(t, env.service.Unblock(ctx, "192.0.2.1"), ErrNotBlocked)
+	require.NoError(t, env.service.Unblock(ctx, "203.0.113.7"))
+	assert.Equal(t, ActionAllow, env.service.Check(ctx, "203.0.113.7", "embed", "known").Action)
+
+	active, err := env.service.ListReports(ctx, ReportFilter{ActiveOnly: true})
+	require.NoError(t, err)
+	require.Len(t, active, 1)
+	assert.Equal(t, "198.51.100.9", active[0].IPAddress)
+}
+
+func TestHandler_AdminRoutes(t *testing.T) {
+	env := setupService(t, testConfig)
+	for i := 0; i < 3; i++ {
+		env.service.RecordInvalidToken(context.Background(), "198.51.100.9", "feed")
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler := NewHandler(env.service)
+	handler.RegisterAdminRoutes(router.Group("/admin"))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/abuse/reports?active=true", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Data []database.AbuseReport `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Len(t, response.Data, 1)
+	assert.Equal(t, "198.51.100.9", response.Data[0].IPAddress)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/admin/abuse/blocks/198.51.100.9", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/admin/abuse/blocks/198.51.100.9", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
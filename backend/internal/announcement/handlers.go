@@ -0,0 +1,192 @@
+package announcement
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"bookmark-sync-service/backend/pkg/utils"
+)
+
+// Handler serves announcement banners
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new announcement handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterPublicRoutes registers the active announcements route. It is
+// served with optional authentication, so visitors see banners too
+func (h *Handler) RegisterPublicRoutes(router *gin.RouterGroup) {
+	router.GET("/announcements/active", h.GetActive)
+}
+
+// RegisterRoutes registers the announcement routes of signed-in users
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	router.POST("/announcements/:id/dismiss", h.Dismiss)
+}
+
+// RegisterAdminRoutes registers the announcement management routes. The
+// router must already require an admin
+func (h *Handler) RegisterAdminRoutes(router *gin.RouterGroup) {
+	announcements := router.Group("/announcements")
+	announcements.GET("", h.List)
+	announcements.POST("", h.Create)
+	announcements.PUT("/:id", h.Update)
+	announcements.DELETE("/:id", h.Delete)
+}
+
+// GetActive returns the announcements to show the caller now
+// @Summary Get active announcements
+// @Tags announcements
+// @Produce json
+// @Success 200 {array} database.Announcement
+// @Router /announcements/active [get]
+func (h *Handler) GetActive(c *gin.Context) {
+	announcements, err := h.service.Active(c.Request.Context(), utils.GetUserIDFromContext(c))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to get announcements", nil)
+		return
+	}
+	utils.SuccessResponse(c, announcements, "Active announcements retrieved")
+}
+
+// Dismiss hides an announcement from the user
+// @Summary Dismiss an announcement
+// @Tags announcements
+// @Produce json
+// @Param id path int true "Announcement ID"
+// @Success 200 {object} utils.APIResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /announcements/{id}/dismiss [post]
+func (h *Handler) Dismiss(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+	id, ok := announcementID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.Dismiss(c.Request.Context(), userID, id); err != nil {
+		h.error(c, err, "Failed to dismiss announcement")
+		return
+	}
+	utils.SuccessResponse(c, nil, "Announcement dismissed")
+}
+
+// List returns every announcement
+// @Summary List announcements
+// @Tags admin
+// @Produce json
+// @Success 200 {array} database.Announcement
+// @Router /admin/announcements [get]
+func (h *Handler) List(c *gin.Context) {
+	announcements, err := h.service.List(c.Request.Context())
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to list announcements", nil)
+		return
+	}
+	utils.SuccessResponse(c, announcements, "Announcements retrieved")
+}
+
+// Create adds an announcement
+// @Summary Create an announcement
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param request body Request true "Announcement"
+// @Success 201 {object} database.Announcement
+// @Failure 400 {object} utils.ErrorResponse
+// @Router /admin/announcements [post]
+func (h *Handler) Create(c *gin.Context) {
+	var req Request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request format", nil)
+		return
+	}
+
+	announcement, err := h.service.Create(c.Request.Context(), req)
+	if err != nil {
+		h.error(c, err, "Failed to create announcement")
+		return
+	}
+	c.JSON(http.StatusCreated, utils.APIResponse{Success: true, Data: announcement, Message: "Announcement created"})
+}
+
+// Update changes an announcement
+// @Summary Update an announcement
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "Announcement ID"
+// @Param request body Request true "Fields to change"
+// @Success 200 {object} database.Announcement
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /admin/announcements/{id} [put]
+func (h *Handler) Update(c *gin.Context) {
+	id, ok := announcementID(c)
+	if !ok {
+		return
+	}
+	var req Request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request format", nil)
+		return
+	}
+
+	announcement, err := h.service.Update(c.Request.Context(), id, req)
+	if err != nil {
+		h.error(c, err, "Failed to update announcement")
+		return
+	}
+	utils.SuccessResponse(c, announcement, "Announcement updated")
+}
+
+// Delete removes an announcement
+// @Summary Delete an announcement
+// @Tags admin
+// @Produce json
+// @Param id path int true "Announcement ID"
+// @Success 200 {object} utils.APIResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /admin/announcements/{id} [delete]
+func (h *Handler) Delete(c *gin.Context) {
+	id, ok := announcementID(c)
+	if !ok {
+		return
+	}
+	if err := h.service.Delete(c.Request.Context(), id); err != nil {
+		h.error(c, err, "Failed to delete announcement")
+		return
+	}
+	utils.SuccessResponse(c, nil, "Announcement deleted")
+}
+
+func (h *Handler) error(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, ErrAnnouncementNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, "ANNOUNCEMENT_NOT_FOUND", "Announcement not found", nil)
+	case errors.Is(err, ErrInvalidAnnouncement):
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_ANNOUNCEMENT", err.Error(), nil)
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", message, nil)
+	}
+}
+
+func announcementID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_ID", "Invalid announcement ID", nil)
+		return 0, false
+	}
+	return uint(id), true
+}
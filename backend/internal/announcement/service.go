@@ -0,0 +1,371 @@
+package announcement
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/websocket"
+)
+
+// Severities of an announcement, from least to most urgent
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// severityOrder sorts critical announcements before warnings before info
+const severityOrder = "CASE severity WHEN '" + SeverityCritical + "' THEN 0 WHEN '" + SeverityWarning + "' THEN 1 ELSE 2 END"
+
+// Audiences an announcement can be shown to
+const (
+	AudienceAll      = "all"       // everyone, signed in or not
+	AudienceUsers    = "users"     // signed-in users
+	AudienceNewUsers = "new_users" // accounts younger than NewUserAge
+	AudienceAdmins   = "admins"
+)
+
+const (
+	// MessageType is the WebSocket message type of pushed announcements
+	MessageType = "announcement"
+
+	// NewUserAge is how long an account counts as new
+	NewUserAge = 30 * 24 * time.Hour
+
+	// MaxMessageLength caps the length of an announcement message
+	MaxMessageLength = 1000
+
+	// pushInterval is how often announcements that have started since the
+	// last check are pushed to connected clients
+	pushInterval = time.Minute
+)
+
+var (
+	// ErrAnnouncementNotFound is returned for unknown announcements, and for
+	// announcements outside a user's audience
+	ErrAnnouncementNotFound = errors.New("announcement not found")
+	// ErrInvalidAnnouncement is returned for announcements that fail validation
+	ErrInvalidAnnouncement = errors.New("invalid announcement")
+)
+
+// Hub delivers messages to the WebSocket clients connected to this replica
+type Hub interface {
+	ConnectedUsers() []string
+	BroadcastToUser(userID string, message *websocket.Message)
+}
+
+// Request creates or updates an announcement. On update, only the fields
+// that are set change
+type Request struct {
+	Message  string     `json:"message"`
+	Severity string     `json:"severity"`
+	StartsAt *time.Time `json:"starts_at"`
+	EndsAt   *time.Time `json:"ends_at"`
+	Audience string     `json:"audience"`
+}
+
+// Service manages announcement banners
+type Service struct {
+	db     *gorm.DB
+	admins map[string]bool
+	hub    Hub
+	logger *zap.Logger
+	now    func() time.Time
+}
+
+// NewService creates a new announcement service. Admins are recognised by
+// email, as the admin API does
+func NewService(db *gorm.DB, adminEmails []string, logger *zap.Logger) *Service {
+	admins := make(map[string]bool, len(adminEmails))
+	for _, email := range adminEmails {
+		if email = strings.ToLower(strings.TrimSpace(email)); email != "" {
+			admins[email] = true
+		}
+	}
+	return &Service{db: db, admins: admins, logger: logger, now: time.Now}
+}
+
+// SetHub makes the service push announcements to connected clients as they
+// start
+func (s *Service) SetHub(hub Hub) {
+	s.hub = hub
+}
+
+// List returns every announcement, newest first, for the admin API
+func (s *Service) List(ctx context.Context) ([]database.Announcement, error) {
+	var announcements []database.Announcement
+	if err := s.db.WithContext(ctx).Order("starts_at DESC, id DESC").Find(&announcements).Error; err != nil {
+		return nil, fmt.Errorf("failed to list announcements: %w", err)
+	}
+	return announcements, nil
+}
+
+// Create adds an announcement. It starts now unless a start time is given
+func (s *Service) Create(ctx context.Context, req Request) (*database.Announcement, error) {
+	announcement := &database.Announcement{
+		Severity: SeverityInfo,
+		StartsAt: s.now(),
+		Audience: AudienceAll,
+	}
+	apply(announcement, req)
+	if err := validate(announcement); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.WithContext(ctx).Create(announcement).Error; err != nil {
+		return nil, fmt.Errorf("failed to create announcement: %w", err)
+	}
+	s.pushIfActive(ctx, announcement)
+	return announcement, nil
+}
+
+// Update changes an announcement. Clients receive it again if it is active,
+// and replace the banner they show
+func (s *Service) Update(ctx context.Context, id uint, req Request) (*database.Announcement, error) {
+	var announcement database.Announcement
+	if err := s.db.WithContext(ctx).First(&announcement, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAnnouncementNotFound
+		}
+		return nil, fmt.Errorf("failed to get announcement: %w", err)
+	}
+	apply(&announcement, req)
+	if err := validate(&announcement); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.WithContext(ctx).Save(&announcement).Error; err != nil {
+		return nil, fmt.Errorf("failed to update announcement: %w", err)
+	}
+	s.pushIfActive(ctx, &announcement)
+	return &announcement, nil
+}
+
+// Delete removes an announcement and its dismissals
+func (s *Service) Delete(ctx context.Context, id uint) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&database.Announcement{}, id)
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete announcement: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrAnnouncementNotFound
+		}
+		if err := tx.Unscoped().Where("announcement_id = ?", id).Delete(&database.AnnouncementDismissal{}).Error; err != nil {
+			return fmt.Errorf("failed to delete announcement dismissals: %w", err)
+		}
+		return nil
+	})
+}
+
+// Active returns the announcements showing now to a user, most urgent
+// first, leaving out the ones the user dismissed. A zero user ID is an
+// anonymous visitor
+func (s *Service) Active(ctx context.Context, userID uint) ([]database.Announcement, error) {
+	now := s.now()
+	query := s.db.WithContext(ctx).
+		Where("starts_at <= ? AND (ends_at IS NULL OR ends_at > ?)", now, now)
+
+	if userID == 0 {
+		query = query.Where("audience = ?", AudienceAll)
+	} else {
+		user, err := s.user(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("audience IN ?", s.audiences(user)).
+			Where("id NOT IN (?)", s.db.Model(&database.AnnouncementDismissal{}).
+				Select("announcement_id").Where("user_id = ?", userID))
+	}
+
+	var announcements []database.Announcement
+	if err := query.Order(severityOrder + ", starts_at DESC, id DESC").Find(&announcements).Error; err != nil {
+		return nil, fmt.Errorf("failed to list active announcements: %w", err)
+	}
+	return announcements, nil
+}
+
+// Dismiss hides an announcement from a user for good. Dismissing it twice
+// is not an error
+func (s *Service) Dismiss(ctx context.Context, userID, id uint) error {
+	user, err := s.user(ctx, userID)
+	if err != nil {
+		return err
+	}
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&database.Announcement{}).
+		Where("id = ? AND audience IN ?", id, s.audiences(user)).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to get announcement: %w", err)
+	}
+	if count == 0 {
+		return ErrAnnouncementNotFound
+	}
+
+	dismissal := &database.AnnouncementDismissal{AnnouncementID: id, UserID: userID}
+	if err := s.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(dismissal).Error; err != nil {
+		return fmt.Errorf("failed to dismiss announcement: %w", err)
+	}
+	return nil
+}
+
+// Run pushes scheduled announcements to connected clients as they start.
+// Every replica runs it for the clients connected to it
+func (s *Service) Run(ctx context.Context) {
+	if s.hub == nil {
+		return
+	}
+
+	ticker := time.NewTicker(pushInterval)
+	defer ticker.Stop()
+
+	since := s.now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		since = s.pushStarted(ctx, since)
+	}
+}
+
+// pushStarted pushes the announcements that started after since, and
+// returns the time to check from next
+func (s *Service) pushStarted(ctx context.Context, since time.Time) time.Time {
+	now := s.now()
+	var started []database.Announcement
+	if err := s.db.WithContext(ctx).
+		Where("starts_at > ? AND starts_at <= ? AND (ends_at IS NULL OR ends_at > ?)", since, now, now).
+		Find(&started).Error; err != nil {
+		s.logger.Warn("Failed to load started announcements", zap.Error(err))
+		return since
+	}
+	for i := range started {
+		s.push(ctx, &started[i])
+	}
+	return now
+}
+
+// pushIfActive pushes an announcement that is showing now
+func (s *Service) pushIfActive(ctx context.Context, announcement *database.Announcement) {
+	now := s.now()
+	if announcement.StartsAt.After(now) || (announcement.EndsAt != nil && !announcement.EndsAt.After(now)) {
+		return
+	}
+	s.push(ctx, announcement)
+}
+
+// push sends an announcement to the connected users in its audience who
+// have not dismissed it
+func (s *Service) push(ctx context.Context, announcement *database.Announcement) {
+	if s.hub == nil {
+		return
+	}
+	ids := make([]uint, 0)
+	for _, connected := range s.hub.ConnectedUsers() {
+		if id, err := strconv.ParseUint(connected, 10, 32); err == nil {
+			ids = append(ids, uint(id))
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	var users []database.User
+	if err := s.db.WithContext(ctx).Select("id", "email", "created_at").
+		Where("id IN ?", ids).
+		Where("id NOT IN (?)", s.db.Model(&database.AnnouncementDismissal{}).
+			Select("user_id").Where("announcement_id = ?", announcement.ID)).
+		Find(&users).Error; err != nil {
+		s.logger.Warn("Failed to load announcement recipients", zap.Uint("announcement_id", announcement.ID), zap.Error(err))
+		return
+	}
+
+	message := &websocket.Message{Type: MessageType, Data: announcement, Timestamp: s.now()}
+	for i := range users {
+		if s.inAudience(&users[i], announcement.Audience) {
+			s.hub.BroadcastToUser(strconv.FormatUint(uint64(users[i].ID), 10), message)
+		}
+	}
+}
+
+// audiences returns the audiences a user belongs to
+func (s *Service) audiences(user *database.User) []string {
+	audiences := []string{AudienceAll, AudienceUsers}
+	for _, audience := range []string{AudienceNewUsers, AudienceAdmins} {
+		if s.inAudience(user, audience) {
+			audiences = append(audiences, audience)
+		}
+	}
+	return audiences
+}
+
+func (s *Service) inAudience(user *database.User, audience string) bool {
+	switch audience {
+	case AudienceAll, AudienceUsers:
+		return true
+	case AudienceNewUsers:
+		return s.now().Sub(user.CreatedAt) < NewUserAge
+	case AudienceAdmins:
+		return s.admins[strings.ToLower(user.Email)]
+	}
+	return false
+}
+
+func (s *Service) user(ctx context.Context, userID uint) (*database.User, error) {
+	var user database.User
+	if err := s.db.WithContext(ctx).Select("id", "email", "created_at").First(&user, userID).Error; err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return &user, nil
+}
+
+// apply copies the set fields of a request onto an announcement
+func apply(announcement *database.Announcement, req Request) {
+	if message := strings.TrimSpace(req.Message); message != "" {
+		announcement.Message = message
+	}
+	if req.Severity != "" {
+		announcement.Severity = req.Severity
+	}
+	if req.StartsAt != nil {
+		announcement.StartsAt = *req.StartsAt
+	}
+	if req.EndsAt != nil {
+		announcement.EndsAt = req.EndsAt
+	}
+	if req.Audience != "" {
+		announcement.Audience = req.Audience
+	}
+}
+
+func validate(announcement *database.Announcement) error {
+	switch {
+	case announcement.Message == "":
+		return fmt.Errorf("%w: message is required", ErrInvalidAnnouncement)
+	case len(announcement.Message) > MaxMessageLength:
+		return fmt.Errorf("%w: message is longer than %d characters", ErrInvalidAnnouncement, MaxMessageLength)
+	case announcement.EndsAt != nil && !announcement.EndsAt.After(announcement.StartsAt):
+		return fmt.Errorf("%w: ends_at must be after starts_at", ErrInvalidAnnouncement)
+	}
+	switch announcement.Severity {
+	case SeverityInfo, SeverityWarning, SeverityCritical:
+	default:
+		return fmt.Errorf("%w: severity must be info, warning or critical", ErrInvalidAnnouncement)
+	}
+	switch announcement.Audience {
+	case AudienceAll, AudienceUsers, AudienceNewUsers, AudienceAdmins:
+	default:
+		return fmt.Errorf("%w: audience must be all, users, new_users or admins", ErrInvalidAnnouncement)
+	}
+	return nil
+}
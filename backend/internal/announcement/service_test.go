@@ -0,0 +1,134 @@
+package announcement
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/websocket"
+)
+
+type recordingHub struct {
+	connected []string
+	sent      map[string][]*websocket.Message
+}
+
+func (h *recordingHub) ConnectedUsers() []string {
+	return h.connected
+}
+
+func (h *recordingHub) BroadcastToUser(userID string, message *websocket.Message) {
+	h.sent[userID] = append(h.sent[userID], message)
+}
+
+func (h *recordingHub) announcements(userID string) []string {
+	var messages []string
+	for _, message := range h.sent[userID] {
+		messages = append(messages, message.Data.(*database.Announcement).Message)
+	}
+	return messages
+}
+
+func TestAnnouncements(t *testing.T) {
+	db, err := database.SetupTestDB()
+	require.NoError(t, err)
+	t.Cleanup(func() { database.CleanupTestDB(db) })
+
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	admin := database.User{Email: "Admin@example.com", Username: "admin", SupabaseID: "admin"}
+	veteran := database.User{Email: "veteran@example.com", Username: "veteran", SupabaseID: "veteran"}
+	newcomer := database.User{Email: "new@example.com", Username: "new", SupabaseID: "new"}
+	for _, user := range []*database.User{&admin, &veteran, &newcomer} {
+		require.NoError(t, db.Create(user).Error)
+	}
+	require.NoError(t, db.Model(&database.User{}).Where("id IN ?", []uint{admin.ID, veteran.ID}).
+		Update("created_at", now.AddDate(-1, 0, 0)).Error)
+	require.NoError(t, db.Model(&newcomer).Update("created_at", now.AddDate(0, 0, -3)).Error)
+
+	service := NewService(db, []string{"admin@example.com"}, zap.NewNop())
+	service.now = func() time.Time { return now }
+	hub := &recordingHub{connected: []string{"1", "2", "3", "not-a-user"}, sent: map[string][]*websocket.Message{}}
+	service.SetHub(hub)
+	ctx := context.Background()
+
+	_, err = service.Create(ctx, Request{Message: "Maintenance tonight", Severity: SeverityWarning})
+	require.NoError(t, err)
+	_, err = service.Create(ctx, Request{Message: "Welcome aboard", Audience: AudienceNewUsers})
+	require.NoError(t, err)
+	_, err = service.Create(ctx, Request{Message: "Rotate the keys", Audience: AudienceAdmins, Severity: SeverityCritical})
+	require.NoError(t, err)
+	ended := now.Add(-time.Hour)
+	expired, err := service.Create(ctx, Request{Message: "Old news", StartsAt: timePtr(now.Add(-2 * time.Hour)), EndsAt: &ended})
+	require.NoError(t, err)
+	scheduled, err := service.Create(ctx, Request{Message: "New feature", StartsAt: timePtr(now.Add(30 * time.Second)), Audience: AudienceUsers})
+	require.NoError(t, err)
+
+	_, err = service.Create(ctx, Request{Message: "Bad", Severity: "loud"})
+	assert.ErrorIs(t, err, ErrInvalidAnnouncement)
+	_, err = service.Create(ctx, Request{Message: "Backwards", EndsAt: timePtr(now.Add(-time.Minute))})
+	assert.ErrorIs(t, err, ErrInvalidAnnouncement)
+	_, err = service.Update(ctx, 999, Request{Message: "Missing"})
+	assert.ErrorIs(t, err, ErrAnnouncementNotFound)
+
+	// Active announcements were pushed to the connected users in their audience
+	assert.Equal(t, []string{"Maintenance tonight", "Rotate the keys"}, hub.announcements("1"))
+	assert.Equal(t, []string{"Maintenance tonight"}, hub.announcements("2"))
+	assert.Equal(t, []string{"Maintenance tonight", "Welcome aboard"}, hub.announcements("3"))
+	assert.Equal(t, MessageType, hub.sent["1"][0].Type)
+
+	messages := func(userID uint) []string {
+		announcements, err := service.Active(ctx, userID)
+		require.NoError(t, err)
+		result := []string{}
+		for _, announcement := range announcements {
+			result = append(result, announcement.Message)
+		}
+		return result
+	}
+	assert.Equal(t, []string{"Maintenance tonight"}, messages(0))
+	assert.Equal(t, []string{"Rotate the keys", "Maintenance tonight"}, messages(admin.ID))
+	assert.Equal(t, []string{"Maintenance tonight", "Welcome aboard"}, messages(newcomer.ID))
+
+	// Dismissals are per user and hide the announcement from pushes too
+	warning := hub.sent["2"][0].Data.(*database.Announcement)
+	require.NoError(t, service.Dismiss(ctx, veteran.ID, warning.ID))
+	require.NoError(t, service.Dismiss(ctx, veteran.ID, warning.ID))
+	assert.Empty(t, messages(veteran.ID))
+	assert.Equal(t, []string{"Maintenance tonight"}, messages(0))
+	assert.ErrorIs(t, service.Dismiss(ctx, veteran.ID, hub.sent["1"][1].Data.(*database.Announcement).ID), ErrAnnouncementNotFound)
+
+	_, err = service.Update(ctx, warning.ID, Request{Message: "Maintenance moved to Friday"})
+	require.NoError(t, err)
+	assert.Len(t, hub.sent["2"], 1)
+	assert.Equal(t, "Maintenance moved to Friday", hub.announcements("1")[2])
+
+	// Expired announcements are not pushed, scheduled ones once they start
+	_, err = service.Update(ctx, expired.ID, Request{Message: "Still old"})
+	require.NoError(t, err)
+	since := now
+	now = now.Add(time.Minute)
+	since = service.pushStarted(ctx, since)
+	assert.Equal(t, now, since)
+	assert.Equal(t, []string{"Maintenance tonight", "New feature"}, hub.announcements("2"))
+	assert.Equal(t, []string{"Maintenance tonight", "Welcome aboard", "Maintenance moved to Friday", "New feature"}, hub.announcements("3"))
+	service.pushStarted(ctx, since)
+	assert.Len(t, hub.sent["2"], 2)
+	assert.Equal(t, []string{"New feature"}, messages(veteran.ID))
+
+	require.NoError(t, service.Delete(ctx, scheduled.ID))
+	assert.ErrorIs(t, service.Delete(ctx, scheduled.ID), ErrAnnouncementNotFound)
+	assert.Empty(t, messages(veteran.ID))
+
+	all, err := service.List(ctx)
+	require.NoError(t, err)
+	assert.Len(t, all, 4)
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
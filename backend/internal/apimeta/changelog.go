@@ -0,0 +1,46 @@
+package apimeta
+
+import "time"
+
+// releases is the public API changelog, starting with the release that
+// introduced it. Add an entry with every change integration authors could
+// notice, newest at the top
+var releases = []Release{
+	{
+		Date: "2026-10-16",
+		Changes: []Change{
+			{Kind: ChangeAdded, Endpoint: "GET /api/v1/meta/changelog", Description: "This changelog, filterable with ?since=YYYY-MM-DD"},
+			{Kind: ChangeAdded, Endpoint: "GET /api/v1/meta/deprecations", Description: "Upcoming breaking changes; affected endpoints also answer with Deprecation, Sunset and Link headers"},
+			{Kind: ChangeDeprecated, Endpoint: "POST /api/v1/storage/screenshots", Description: "Never implemented; use POST /api/v1/screenshot/capture"},
+			{Kind: ChangeDeprecated, Endpoint: "GET /api/v1/storage/screenshots/:id", Description: "Never implemented; screenshots are served from the bookmark's screenshot URL"},
+			{Kind: ChangeDeprecated, Endpoint: "POST /api/v1/storage/files", Description: "Never implemented and will be removed"},
+			{Kind: ChangeDeprecated, Endpoint: "GET /api/v1/storage/files/:id", Description: "Never implemented and will be removed"},
+			{Kind: ChangeDeprecated, Endpoint: "DELETE /api/v1/storage/files/:id", Description: "Never implemented and will be removed"},
+			{Kind: ChangeChanged, Endpoint: "GET /api/v1/bookmarks", Description: "Bookmarks carry their latest link check as status and last_checked_at, also in search results"},
+		},
+	},
+}
+
+// deprecations are the endpoints that will change or go away. Keep each
+// one until well after its sunset so clients that missed it can still
+// find out why it broke
+var deprecations = []Deprecation{
+	storageDeprecation("POST", "/api/v1/storage/screenshots", "POST /api/v1/screenshot/capture"),
+	storageDeprecation("GET", "/api/v1/storage/screenshots/:id", ""),
+	storageDeprecation("POST", "/api/v1/storage/files", ""),
+	storageDeprecation("GET", "/api/v1/storage/files/:id", ""),
+	storageDeprecation("DELETE", "/api/v1/storage/files/:id", ""),
+}
+
+// storageDeprecation announces the removal of a never implemented
+// /storage route, which answers 501 until then
+func storageDeprecation(method, path, replacement string) Deprecation {
+	return Deprecation{
+		Method:       method,
+		Path:         path,
+		DeprecatedAt: time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC),
+		SunsetAt:     time.Date(2027, 4, 1, 0, 0, 0, 0, time.UTC),
+		Replacement:  replacement,
+		Notice:       "The /storage routes were never implemented and will be removed",
+	}
+}
@@ -0,0 +1,58 @@
+package apimeta
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"bookmark-sync-service/backend/pkg/utils"
+)
+
+// Handler serves the API changelog and deprecations
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new API meta handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes registers the meta routes. They need no authentication
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	meta := router.Group("/meta")
+	meta.GET("/changelog", h.GetChangelog)
+	meta.GET("/deprecations", h.GetDeprecations)
+}
+
+// GetChangelog returns the API changes, newest first
+// @Summary Get the API changelog
+// @Tags meta
+// @Produce json
+// @Param since query string false "Only releases on or after this day (YYYY-MM-DD)"
+// @Success 200 {array} Release
+// @Failure 400 {object} utils.ErrorResponse
+// @Router /meta/changelog [get]
+func (h *Handler) GetChangelog(c *gin.Context) {
+	releases, err := h.service.Changelog(c.Query("since"))
+	if err != nil {
+		if errors.Is(err, ErrInvalidDate) {
+			utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_DATE", err.Error(), nil)
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to get changelog", nil)
+		return
+	}
+	utils.SuccessResponse(c, releases, "Changelog retrieved")
+}
+
+// GetDeprecations returns the endpoints that will change or go away
+// @Summary Get upcoming API deprecations
+// @Tags meta
+// @Produce json
+// @Success 200 {array} Deprecation
+// @Router /meta/deprecations [get]
+func (h *Handler) GetDeprecations(c *gin.Context) {
+	utils.SuccessResponse(c, h.service.Deprecations(), "Deprecations retrieved")
+}
@@ -0,0 +1,118 @@
+package apimeta
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Change kinds of changelog entries
+const (
+	ChangeAdded      = "added"
+	ChangeChanged    = "changed"
+	ChangeDeprecated = "deprecated"
+	ChangeRemoved    = "removed"
+)
+
+// ErrInvalidDate is returned for a malformed since date
+var ErrInvalidDate = errors.New("invalid date")
+
+// Change is one entry of an API release
+type Change struct {
+	Kind        string `json:"kind"`
+	Endpoint    string `json:"endpoint,omitempty"` // e.g. GET /api/v1/bookmarks
+	Description string `json:"description"`
+}
+
+// Release is a dated group of API changes
+type Release struct {
+	Date    string   `json:"date"` // YYYY-MM-DD
+	Changes []Change `json:"changes"`
+}
+
+// Deprecation announces an endpoint that will change or go away. Method and
+// Path match the route pattern, e.g. /api/v1/bookmarks/:id
+type Deprecation struct {
+	Method       string    `json:"method"`
+	Path         string    `json:"path"`
+	DeprecatedAt time.Time `json:"deprecated_at"`
+	SunsetAt     time.Time `json:"sunset_at"` // when the endpoint stops working as before
+	Replacement  string    `json:"replacement,omitempty"`
+	Notice       string    `json:"notice"`
+}
+
+// Service serves the changelog and deprecations compiled into the binary
+type Service struct {
+	releases     []Release
+	deprecations []Deprecation
+	byRoute      map[string]*Deprecation
+}
+
+// NewService creates the service from the built-in data
+func NewService() *Service {
+	return newService(releases, deprecations)
+}
+
+func newService(rels []Release, deps []Deprecation) *Service {
+	s := &Service{
+		releases:     append([]Release(nil), rels...),
+		deprecations: append([]Deprecation(nil), deps...),
+		byRoute:      make(map[string]*Deprecation, len(deps)),
+	}
+	// Newest release first; deprecations by the date they stop working
+	sort.SliceStable(s.releases, func(i, j int) bool { return s.releases[i].Date > s.releases[j].Date })
+	sort.SliceStable(s.deprecations, func(i, j int) bool { return s.deprecations[i].SunsetAt.Before(s.deprecations[j].SunsetAt) })
+	for i := range s.deprecations {
+		d := &s.deprecations[i]
+		s.byRoute[d.Method+" "+d.Path] = d
+	}
+	return s
+}
+
+// Changelog returns the releases newest first. A non-empty since
+// (YYYY-MM-DD) keeps only the releases on or after that day
+func (s *Service) Changelog(since string) ([]Release, error) {
+	if since == "" {
+		return s.releases, nil
+	}
+	if _, err := time.Parse("2006-01-02", since); err != nil {
+		return nil, fmt.Errorf("%w: since must be a YYYY-MM-DD date", ErrInvalidDate)
+	}
+	result := make([]Release, 0, len(s.releases))
+	for _, release := range s.releases {
+		if release.Date >= since {
+			result = append(result, release)
+		}
+	}
+	return result, nil
+}
+
+// Deprecations returns the announced deprecations, soonest sunset first
+func (s *Service) Deprecations() []Deprecation {
+	return s.deprecations
+}
+
+// Lookup returns the deprecation of a route, if any
+func (s *Service) Lookup(method, path string) (*Deprecation, bool) {
+	d, ok := s.byRoute[method+" "+path]
+	return d, ok
+}
+
+// Middleware announces deprecations on the responses of the routes they
+// cover with Deprecation (RFC 9745), Sunset (RFC 8594) and a Link to the
+// deprecations list, so clients can warn before anything breaks. Headers
+// are set before the handler runs so they are sent with any response
+func (s *Service) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if d, ok := s.Lookup(c.Request.Method, c.FullPath()); ok {
+			c.Header("Deprecation", fmt.Sprintf("@%d", d.DeprecatedAt.Unix()))
+			c.Header("Sunset", d.SunsetAt.UTC().Format(http.TimeFormat))
+			c.Header("Link", `</api/v1/meta/deprecations>; rel="deprecation"; type="application/json"`)
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,76 @@
+package apimeta
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuiltInData(t *testing.T) {
+	service := NewService()
+	for _, release := range service.releases {
+		_, err := time.Parse("2006-01-02", release.Date)
+		assert.NoError(t, err, release.Date)
+		assert.NotEmpty(t, release.Changes)
+	}
+	for _, d := range service.Deprecations() {
+		assert.True(t, strings.HasPrefix(d.Path, "/api/v1/"), d.Path)
+		assert.True(t, d.DeprecatedAt.Before(d.SunsetAt), d.Path)
+		assert.NotEmpty(t, d.Notice)
+	}
+}
+
+func TestService_Changelog(t *testing.T) {
+	service := newService([]Release{
+		{Date: "2026-09-01", Changes: []Change{{Kind: ChangeAdded, Description: "older"}}},
+		{Date: "2026-10-01", Changes: []Change{{Kind: ChangeAdded, Description: "newer"}}},
+	}, nil)
+
+	all, err := service.Changelog("")
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	assert.Equal(t, "2026-10-01", all[0].Date, "newest first")
+
+	recent, err := service.Changelog("2026-10-01")
+	require.NoError(t, err)
+	require.Len(t, recent, 1)
+	assert.Equal(t, "newer", recent[0].Changes[0].Description)
+
+	_, err = service.Changelog("last week")
+	assert.ErrorIs(t, err, ErrInvalidDate)
+}
+
+func TestService_Middleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	deprecatedAt := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	service := newService(nil, []Deprecation{{
+		Method:       http.MethodGet,
+		Path:         "/api/v1/old/:id",
+		DeprecatedAt: deprecatedAt,
+		SunsetAt:     time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC),
+		Notice:       "going away",
+	}})
+
+	router := gin.New()
+	router.Use(service.Middleware())
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/api/v1/old/:id", ok)
+	router.DELETE("/api/v1/old/:id", ok)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/old/7", nil))
+	assert.Equal(t, "@1790812800", w.Header().Get("Deprecation"))
+	assert.Equal(t, "Fri, 01 Jan 2027 00:00:00 GMT", w.Header().Get("Sunset"))
+	assert.Contains(t, w.Header().Get("Link"), `rel="deprecation"`)
+
+	// Other methods of the same path are not deprecated
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/v1/old/7", nil))
+	assert.Empty(t, w.Header().Get("Deprecation"))
+}
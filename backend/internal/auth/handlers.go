@@ -2,6 +2,7 @@ package auth
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -21,6 +22,13 @@ type ServiceInterface interface {
 	Logout(ctx context.Context, userID uint) error
 	ResetPassword(ctx context.Context, req *ResetPasswordRequest) error
 	ValidateToken(tokenString string) (*UserInfo, error)
+	ExchangeToken(ctx context.Context, token string) (*AuthResponse, error)
+	ProviderInfo() ProviderInfo
+}
+
+// ExchangeRequest carries a token of the external identity provider
+type ExchangeRequest struct {
+	Token string `json:"token" binding:"required"`
 }
 
 type Handler struct {
@@ -59,6 +67,10 @@ func (h *Handler) Register(c *gin.Context) {
 		}
 
 		// Check for specific error types
+		if errors.Is(err, ErrPasswordAuthDisabled) {
+			utils.ErrorResponse(c, http.StatusBadRequest, "PASSWORD_AUTH_DISABLED", err.Error(), nil)
+			return
+		}
 		if err.Error() == "user with email or username already exists" {
 			utils.ErrorResponse(c, http.StatusConflict, "USER_EXISTS", "User with this email or username already exists", nil)
 			return
@@ -98,6 +110,10 @@ func (h *Handler) Login(c *gin.Context) {
 		}
 
 		// Check for specific error types
+		if errors.Is(err, ErrPasswordAuthDisabled) {
+			utils.ErrorResponse(c, http.StatusBadRequest, "PASSWORD_AUTH_DISABLED", err.Error(), nil)
+			return
+		}
 		if err.Error() == "invalid credentials" || err.Error() == "user not found" {
 			utils.ErrorResponse(c, http.StatusUnauthorized, "INVALID_CREDENTIALS", "Invalid email or password", nil)
 			return
@@ -277,3 +293,50 @@ func (h *Handler) ValidateToken(c *gin.Context) {
 
 	utils.SuccessResponse(c, userInfo, "Token is valid")
 }
+
+// GetProvider tells clients how users sign in
+// @Summary Get the auth provider
+// @Description Whether users sign in with a password or at an OpenID Connect provider, and where
+// @Tags auth
+// @Produce json
+// @Success 200 {object} ProviderInfo
+// @Router /api/v1/auth/provider [get]
+func (h *Handler) GetProvider(c *gin.Context) {
+	utils.SuccessResponse(c, h.service.ProviderInfo(), "Auth provider retrieved")
+}
+
+// ExchangeToken signs in with a token of the external identity provider
+// @Summary Exchange a provider token
+// @Description Trade an OpenID Connect ID or access token for API tokens. The account is created on first sign-in
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body ExchangeRequest true "Provider token"
+// @Success 200 {object} AuthResponse
+// @Failure 400 {object} utils.ErrorResponse "No external provider is configured"
+// @Failure 401 {object} utils.ErrorResponse "Invalid token"
+// @Failure 409 {object} utils.ErrorResponse "The email belongs to an unlinked account"
+// @Router /api/v1/auth/token [post]
+func (h *Handler) ExchangeToken(c *gin.Context) {
+	var req ExchangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, map[string]interface{}{
+			"validation_errors": err.Error(),
+		})
+		return
+	}
+
+	response, err := h.service.ExchangeToken(c.Request.Context(), req.Token)
+	switch {
+	case errors.Is(err, ErrTokenAuthDisabled):
+		utils.ErrorResponse(c, http.StatusBadRequest, "TOKEN_AUTH_DISABLED", err.Error(), nil)
+	case errors.Is(err, ErrInvalidCredentials), errors.Is(err, ErrUnknownIdentity):
+		utils.ErrorResponse(c, http.StatusUnauthorized, "INVALID_TOKEN", "Invalid or expired provider token", nil)
+	case errors.Is(err, ErrIdentityConflict), errors.Is(err, ErrMissingEmail):
+		utils.ErrorResponse(c, http.StatusConflict, "IDENTITY_CONFLICT", err.Error(), nil)
+	case err != nil:
+		utils.InternalErrorResponse(c, "Token exchange failed")
+	default:
+		utils.SuccessResponse(c, response, "Token exchanged successfully")
+	}
+}
@@ -65,6 +65,18 @@ func (m *MockAuthService) ValidateToken(tokenString string) (*UserInfo, error) {
 	return args.Get(0).(*UserInfo), args.Error(1)
 }
 
+func (m *MockAuthService) ExchangeToken(ctx context.Context, token string) (*AuthResponse, error) {
+	args := m.Called(ctx, token)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*AuthResponse), args.Error(1)
+}
+
+func (m *MockAuthService) ProviderInfo() ProviderInfo {
+	return m.Called().Get(0).(ProviderInfo)
+}
+
 // setupTestHandler creates a test handler with mock service
 // setupTestHandler 創建帶有模擬服務的測試處理器
 func setupTestHandler() (*Handler, *MockAuthService) {
@@ -0,0 +1,247 @@
+package auth
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrIdentityConflict is returned when a new provider user's email or
+	// username belongs to an account they aren't linked to
+	ErrIdentityConflict = errors.New("an account with this email already exists and is not linked to this identity")
+	// ErrMissingEmail is returned when a provider shares no email for a new user
+	ErrMissingEmail = errors.New("the identity provider did not share an email address")
+)
+
+var usernameUnsafe = regexp.MustCompile(`[^a-z0-9_.-]+`)
+
+// externalID is stored as the user's SupabaseID. Supabase users keep their
+// ID, others are prefixed with their provider to stay unique
+func externalID(identity *Identity) string {
+	if identity.Provider == config.AuthProviderSupabase {
+		return identity.Subject
+	}
+	return identity.Provider + ":" + identity.Subject
+}
+
+// accountFor finds the account of a provider identity. Supabase users of
+// earlier releases are found by their SupabaseID and, when the provider
+// allows it, other users by their verified email; the link is recorded so
+// later sign-ins find it directly. With provision, identities without an
+// account get a new one
+func (s *Service) accountFor(ctx context.Context, identity *Identity, provision bool) (*database.User, error) {
+	db := s.db.WithContext(ctx)
+
+	var link database.UserIdentity
+	err := db.Where("provider = ? AND subject = ?", identity.Provider, identity.Subject).First(&link).Error
+	if err == nil {
+		var user database.User
+		if err := db.First(&user, link.UserID).Error; err != nil {
+			return nil, fmt.Errorf("failed to get linked user: %w", err)
+		}
+		return &user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to find identity: %w", err)
+	}
+
+	var user database.User
+	switch {
+	case identity.Provider == config.AuthProviderSupabase:
+		err = db.Where("supabase_id = ?", identity.Subject).First(&user).Error
+	case s.linksByEmail() && identity.EmailVerified && identity.Email != "":
+		err = db.Where("LOWER(email) = ?", strings.ToLower(identity.Email)).First(&user).Error
+	default:
+		err = gorm.ErrRecordNotFound
+	}
+	if err == nil {
+		if err := db.Create(&database.UserIdentity{UserID: user.ID, Provider: identity.Provider, Subject: identity.Subject, Email: identity.Email}).Error; err != nil {
+			return nil, fmt.Errorf("failed to link identity: %w", err)
+		}
+		return &user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	if !provision {
+		return nil, ErrUnknownIdentity
+	}
+	return s.provision(ctx, identity)
+}
+
+// linksByEmail reports whether the provider may sign users into existing
+// accounts by email
+func (s *Service) linksByEmail() bool {
+	provider, ok := s.provider.(*OIDCProvider)
+	return ok && provider.cfg.LinkByEmail
+}
+
+// provision creates the account of a provider user signing in for the first
+// time
+func (s *Service) provision(ctx context.Context, identity *Identity) (*database.User, error) {
+	if identity.Email == "" {
+		return nil, ErrMissingEmail
+	}
+
+	db := s.db.WithContext(ctx)
+	var taken int64
+	if err := db.Model(&database.User{}).Where("LOWER(email) = ?", strings.ToLower(identity.Email)).Count(&taken).Error; err != nil {
+		return nil, fmt.Errorf("failed to check email: %w", err)
+	}
+	if taken > 0 {
+		return nil, ErrIdentityConflict
+	}
+	username, err := s.availableUsername(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+
+	displayName := identity.DisplayName
+	if displayName == "" {
+		displayName = username
+	}
+	user := database.User{
+		Email:       identity.Email,
+		Username:    username,
+		DisplayName: displayName,
+		Avatar:      identity.Avatar,
+		SupabaseID:  externalID(identity),
+		Preferences: `{"theme": "light", "gridSize": "medium", "defaultView": "grid"}`,
+	}
+	if err := s.createAccount(ctx, &user, identity); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("User provisioned from identity provider")
+	if s.registration != nil {
+		s.registration.UserRegistered(ctx, user.ID)
+	}
+	return &user, nil
+}
+
+// createAccount stores a new user together with their identity
+func (s *Service) createAccount(ctx context.Context, user *database.User, identity *Identity) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return fmt.Errorf("failed to create user: %w", err)
+		}
+		link := &database.UserIdentity{
+			UserID:       user.ID,
+			Provider:     identity.Provider,
+			Subject:      identity.Subject,
+			Email:        identity.Email,
+			PasswordHash: identity.PasswordHash,
+		}
+		if err := tx.Create(link).Error; err != nil {
+			return fmt.Errorf("failed to create identity: %w", err)
+		}
+		return nil
+	})
+}
+
+// availableUsername derives a free username from the provider's username or
+// the email's local part
+func (s *Service) availableUsername(ctx context.Context, identity *Identity) (string, error) {
+	base := identity.Username
+	if base == "" {
+		base, _, _ = strings.Cut(identity.Email, "@")
+	}
+	base = usernameUnsafe.ReplaceAllString(strings.ToLower(base), "")
+	if len(base) > 40 {
+		base = base[:40]
+	}
+	if len(base) < 3 {
+		base = "user"
+	}
+
+	for n := 1; n <= 100; n++ {
+		candidate := base
+		if n > 1 {
+			candidate = fmt.Sprintf("%s%d", base, n)
+		}
+		var taken int64
+		if err := s.db.WithContext(ctx).Model(&database.User{}).Where("username = ?", candidate).Count(&taken).Error; err != nil {
+			return "", fmt.Errorf("failed to check username: %w", err)
+		}
+		if taken == 0 {
+			return candidate, nil
+		}
+	}
+	return "", ErrIdentityConflict
+}
+
+// BackfillIdentities links every user without a Supabase identity to their
+// SupabaseID, so they keep signing in after another provider is linked.
+// It returns the number of identities created
+func BackfillIdentities(ctx context.Context, db *gorm.DB) (int, error) {
+	linked := db.Model(&database.UserIdentity{}).Select("user_id").Where("provider = ?", config.AuthProviderSupabase)
+	var users []database.User
+	if err := db.WithContext(ctx).Where("id NOT IN (?)", linked).
+		Where("supabase_id NOT LIKE ? AND supabase_id NOT LIKE ?", config.AuthProviderOIDC+":%", config.AuthProviderLocal+":%").
+		Find(&users).Error; err != nil {
+		return 0, fmt.Errorf("failed to find users: %w", err)
+	}
+
+	for n, user := range users {
+		identity := &database.UserIdentity{UserID: user.ID, Provider: config.AuthProviderSupabase, Subject: user.SupabaseID, Email: user.Email}
+		if err := db.WithContext(ctx).Create(identity).Error; err != nil {
+			return n, fmt.Errorf("failed to link user %d: %w", user.ID, err)
+		}
+	}
+	return len(users), nil
+}
+
+// ImportIdentities links existing accounts to their users at a new provider
+// from CSV rows of supabase_id,oidc,subject, e.g. exported from the new
+// provider after importing the Supabase users. A header row is skipped. It
+// returns the number of identities linked; rows of unknown users fail
+func ImportIdentities(ctx context.Context, db *gorm.DB, r io.Reader) (int, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 3
+	reader.TrimLeadingSpace = true
+
+	imported := 0
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return imported, nil
+		}
+		if err != nil {
+			return imported, fmt.Errorf("line %d: %w", line, err)
+		}
+		supabaseID, provider, subject := record[0], record[1], record[2]
+		if line == 1 && supabaseID == "supabase_id" {
+			continue
+		}
+		if provider != config.AuthProviderOIDC || subject == "" {
+			return imported, fmt.Errorf("line %d: expected an oidc identity", line)
+		}
+
+		var user database.User
+		if err := db.WithContext(ctx).Where("supabase_id = ?", supabaseID).First(&user).Error; err != nil {
+			return imported, fmt.Errorf("line %d: no user with supabase id %q", line, supabaseID)
+		}
+		var link database.UserIdentity
+		err = db.WithContext(ctx).
+			Where(database.UserIdentity{Provider: provider, Subject: subject}).
+			Attrs(database.UserIdentity{UserID: user.ID, Email: user.Email}).
+			FirstOrCreate(&link).Error
+		if err != nil {
+			return imported, fmt.Errorf("line %d: failed to link identity: %w", line, err)
+		}
+		if link.UserID != user.ID {
+			return imported, fmt.Errorf("line %d: %s identity %q is linked to another user", line, provider, subject)
+		}
+		imported++
+	}
+}
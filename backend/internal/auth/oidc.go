@@ -0,0 +1,210 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"bookmark-sync-service/backend/internal/config"
+
+	"github.com/golang-jwt/jwt/v4"
+	"go.uber.org/zap"
+)
+
+// OIDCProvider verifies tokens of a generic OpenID Connect provider such as
+// Keycloak or Authentik against the signing keys it publishes, and maps
+// their claims to an identity
+type OIDCProvider struct {
+	cfg        config.OIDCConfig
+	httpClient *http.Client
+	logger     *zap.Logger
+	now        func() time.Time
+
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+// NewOIDCProvider creates a provider for the issuer in cfg. Keys are fetched
+// on first use, so the issuer may start after the API
+func NewOIDCProvider(cfg config.OIDCConfig, logger *zap.Logger) *OIDCProvider {
+	return &OIDCProvider{
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: config.OIDCRequestTimeout},
+		logger:     logger,
+		now:        time.Now,
+	}
+}
+
+// Name returns the provider name
+func (p *OIDCProvider) Name() string {
+	return config.AuthProviderOIDC
+}
+
+// Issuer returns the URL clients sign in at
+func (p *OIDCProvider) Issuer() string {
+	return strings.TrimSuffix(p.cfg.IssuerURL, "/")
+}
+
+// ClientID returns the client clients sign in as
+func (p *OIDCProvider) ClientID() string {
+	return p.cfg.ClientID
+}
+
+// VerifyToken checks the signature, issuer, audience and expiry of an ID or
+// access token and returns the identity in its claims
+func (p *OIDCProvider) VerifyToken(ctx context.Context, raw string) (*Identity, error) {
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(raw, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		kid, _ := token.Header["kid"].(string)
+		return p.key(ctx, kid)
+	})
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidCredentials
+	}
+
+	issuer, _ := claims["iss"].(string)
+	if strings.TrimSuffix(issuer, "/") != p.Issuer() {
+		return nil, ErrInvalidCredentials
+	}
+	// Access tokens of some providers name the client in azp rather than aud
+	if azp, _ := claims["azp"].(string); !claims.VerifyAudience(p.cfg.ClientID, true) && azp != p.cfg.ClientID {
+		return nil, ErrInvalidCredentials
+	}
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	verified, _ := claims["email_verified"].(bool)
+	return &Identity{
+		Provider:      config.AuthProviderOIDC,
+		Subject:       subject,
+		Email:         stringClaim(claims, p.cfg.EmailClaim),
+		EmailVerified: verified,
+		Username:      stringClaim(claims, p.cfg.UsernameClaim),
+		DisplayName:   stringClaim(claims, p.cfg.NameClaim),
+		Avatar:        stringClaim(claims, p.cfg.AvatarClaim),
+	}, nil
+}
+
+func stringClaim(claims jwt.MapClaims, name string) string {
+	if name == "" {
+		return ""
+	}
+	value, _ := claims[name].(string)
+	return value
+}
+
+// key returns the signing key with an ID, refetching the key set when it is
+// stale or the key is unknown, e.g. after the provider rotated its keys
+func (p *OIDCProvider) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	age := p.now().Sub(p.fetchedAt)
+	key, known := p.lookup(kid)
+	if p.keys == nil || age > config.OIDCKeyCacheTTL || (!known && age > config.OIDCKeyRefreshInterval) {
+		keys, err := p.fetchKeys(ctx)
+		if err != nil {
+			p.logger.Warn("Failed to fetch OIDC signing keys", zap.Error(err), zap.String("issuer", p.Issuer()))
+			if !known {
+				return nil, err
+			}
+			return key, nil
+		}
+		p.keys = keys
+		p.fetchedAt = p.now()
+		key, known = p.lookup(kid)
+	}
+	if !known {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+// lookup finds a cached key. Tokens without a key ID may use the only key
+func (p *OIDCProvider) lookup(kid string) (*rsa.PublicKey, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+// jsonWebKey is an entry of a JWKS document; only RSA keys are used
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// fetchKeys discovers the issuer's key set and loads its RSA signing keys
+func (p *OIDCProvider) fetchKeys(ctx context.Context) (map[string]*rsa.PublicKey, error) {
+	var discovery struct {
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := p.getJSON(ctx, p.Issuer()+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, fmt.Errorf("failed to discover provider: %w", err)
+	}
+	if discovery.JWKSURI == "" {
+		return nil, fmt.Errorf("provider publishes no jwks_uri")
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := p.getJSON(ctx, discovery.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("failed to fetch signing keys: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, jwk := range set.Keys {
+		if jwk.Kty != "RSA" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("provider publishes no RSA signing keys")
+	}
+	return keys, nil
+}
+
+func (p *OIDCProvider) getJSON(ctx context.Context, url string, target interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %d", url, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(target)
+}
@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/pkg/database"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidCredentials is returned when a provider rejects a sign-in
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	// ErrPasswordAuthDisabled is returned by password endpoints when users
+	// sign in through an external identity provider
+	ErrPasswordAuthDisabled = errors.New("password sign-in is disabled, sign in with the identity provider")
+	// ErrTokenAuthDisabled is returned when exchanging tokens without an
+	// external identity provider
+	ErrTokenAuthDisabled = errors.New("no external identity provider is configured")
+	// ErrUnknownIdentity is returned for a provider user with no account
+	ErrUnknownIdentity = errors.New("no account is linked to this identity")
+)
+
+// Identity is a user as an auth provider knows them
+type Identity struct {
+	Provider      string
+	Subject       string // stable ID of the user at the provider
+	Email         string
+	EmailVerified bool
+	Username      string
+	DisplayName   string
+	Avatar        string
+	PasswordHash  string // set by the local provider on sign-up
+}
+
+// Provider is an identity backend
+type Provider interface {
+	Name() string
+}
+
+// PasswordProvider signs users up and in with an email and a password
+type PasswordProvider interface {
+	Provider
+	SignUp(ctx context.Context, req *RegisterRequest) (*Identity, error)
+	SignIn(ctx context.Context, req *LoginRequest) (*Identity, error)
+}
+
+// TokenProvider verifies tokens issued by an external identity provider
+type TokenProvider interface {
+	Provider
+	VerifyToken(ctx context.Context, token string) (*Identity, error)
+}
+
+// NewProvider creates the provider selected in cfg
+func NewProvider(cfg config.AuthConfig, db *gorm.DB, logger *zap.Logger) (Provider, error) {
+	switch cfg.Provider {
+	case "", config.AuthProviderSupabase:
+		return &supabaseProvider{db: db}, nil
+	case config.AuthProviderLocal:
+		return &localProvider{db: db}, nil
+	case config.AuthProviderOIDC:
+		return NewOIDCProvider(cfg.OIDC, logger), nil
+	default:
+		return nil, fmt.Errorf("unknown auth provider %q", cfg.Provider)
+	}
+}
+
+// supabaseProvider keeps the sign-in of earlier releases, where accounts are
+// identified by their Supabase ID
+type supabaseProvider struct {
+	db *gorm.DB
+}
+
+func (p *supabaseProvider) Name() string {
+	return config.AuthProviderSupabase
+}
+
+func (p *supabaseProvider) SignUp(ctx context.Context, req *RegisterRequest) (*Identity, error) {
+	// For now, we'll create a simple user without Supabase integration
+	// In a full implementation, you would integrate with Supabase Auth here
+	return &Identity{
+		Provider:    config.AuthProviderSupabase,
+		Subject:     fmt.Sprintf("user_%s_%d", req.Username, time.Now().Unix()),
+		Email:       req.Email,
+		Username:    req.Username,
+		DisplayName: req.DisplayName,
+	}, nil
+}
+
+func (p *supabaseProvider) SignIn(ctx context.Context, req *LoginRequest) (*Identity, error) {
+	// For now, we'll do basic email/password validation
+	// In a full implementation, you would integrate with Supabase Auth here
+	var user database.User
+	if err := p.db.WithContext(ctx).Where("email = ?", req.Email).First(&user).Error; err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	// For demo purposes, we'll accept any password for existing users
+	// In production, you would validate the password hash
+	if req.Password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	return &Identity{Provider: config.AuthProviderSupabase, Subject: user.SupabaseID, Email: user.Email}, nil
+}
+
+// localProvider stores bcrypt password hashes with the user's identity, for
+// self-hosters without an identity provider
+type localProvider struct {
+	db *gorm.DB
+}
+
+func (p *localProvider) Name() string {
+	return config.AuthProviderLocal
+}
+
+func (p *localProvider) SignUp(ctx context.Context, req *RegisterRequest) (*Identity, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+	return &Identity{
+		Provider:      config.AuthProviderLocal,
+		Subject:       uuid.NewString(),
+		Email:         strings.ToLower(req.Email),
+		EmailVerified: false,
+		Username:      req.Username,
+		DisplayName:   req.DisplayName,
+		PasswordHash:  string(hash),
+	}, nil
+}
+
+func (p *localProvider) SignIn(ctx context.Context, req *LoginRequest) (*Identity, error) {
+	var identity database.UserIdentity
+	if err := p.db.WithContext(ctx).
+		Where("provider = ? AND email = ?", config.AuthProviderLocal, strings.ToLower(req.Email)).
+		First(&identity).Error; err != nil {
+		// Hash anyway so unknown emails take as long as wrong passwords
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(req.Password))
+		return nil, ErrInvalidCredentials
+	}
+	if identity.PasswordHash == "" ||
+		bcrypt.CompareHashAndPassword([]byte(identity.PasswordHash), []byte(req.Password)) != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return &Identity{Provider: config.AuthProviderLocal, Subject: identity.Subject, Email: identity.Email}, nil
+}
+
+// dummyHash is compared against when an email has no local account
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("not a password"), bcrypt.DefaultCost)
@@ -0,0 +1,218 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/redis"
+)
+
+func setupProviderService(t *testing.T, cfg config.AuthConfig) (*Service, *gorm.DB) {
+	db, err := database.SetupTestDB()
+	require.NoError(t, err)
+	t.Cleanup(func() { database.CleanupTestDB(db) })
+
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+	client, err := redis.NewClient(config.RedisConfig{Host: mr.Host(), Port: mr.Port(), PoolSize: 1})
+	require.NoError(t, err)
+
+	service := NewService(db, client, nil, &config.JWTConfig{Secret: "secret", ExpiryHour: 1}, zap.NewNop())
+	provider, err := NewProvider(cfg, db, zap.NewNop())
+	require.NoError(t, err)
+	service.SetProvider(provider)
+	return service, db
+}
+
+func TestLocalProvider(t *testing.T) {
+	service, db := setupProviderService(t, config.AuthConfig{Provider: config.AuthProviderLocal})
+	ctx := context.Background()
+
+	registered, err := service.Register(ctx, &RegisterRequest{Email: "Ada@example.com", Password: "correct horse", Username: "ada", DisplayName: "Ada"})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(registered.User.SupabaseID, "local:"))
+
+	var identity database.UserIdentity
+	require.NoError(t, db.Where("user_id = ?", registered.User.ID).First(&identity).Error)
+	assert.Equal(t, config.AuthProviderLocal, identity.Provider)
+	assert.NotContains(t, identity.PasswordHash, "correct horse")
+
+	_, err = service.Login(ctx, &LoginRequest{Email: "ada@example.com", Password: "wrong horse"})
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = service.Login(ctx, &LoginRequest{Email: "nobody@example.com", Password: "correct horse"})
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	loggedIn, err := service.Login(ctx, &LoginRequest{Email: "ada@example.com", Password: "correct horse"})
+	require.NoError(t, err)
+	assert.Equal(t, registered.User.ID, loggedIn.User.ID)
+
+	_, err = service.ExchangeToken(ctx, "token")
+	assert.ErrorIs(t, err, ErrTokenAuthDisabled)
+	assert.Equal(t, ProviderInfo{Provider: config.AuthProviderLocal, PasswordSignIn: true}, service.ProviderInfo())
+}
+
+func TestSupabaseProviderLinksLegacyUsers(t *testing.T) {
+	service, db := setupProviderService(t, config.AuthConfig{Provider: config.AuthProviderSupabase})
+	ctx := context.Background()
+
+	legacy := database.User{Email: "old@example.com", Username: "old", SupabaseID: "user_old_1"}
+	require.NoError(t, db.Create(&legacy).Error)
+
+	response, err := service.Login(ctx, &LoginRequest{Email: "old@example.com", Password: "anything"})
+	require.NoError(t, err)
+	assert.Equal(t, legacy.ID, response.User.ID)
+
+	var links int64
+	require.NoError(t, db.Model(&database.UserIdentity{}).Where("user_id = ? AND subject = ?", legacy.ID, "user_old_1").Count(&links).Error)
+	assert.Equal(t, int64(1), links)
+}
+
+// testIssuer is an OpenID Connect provider signing tokens with one RSA key
+type testIssuer struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	issuer := &testIssuer{key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"issuer": issuer.URL, "jwks_uri": issuer.URL + "/certs"})
+	})
+	mux.HandleFunc("/certs", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "main",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	issuer.Server = httptest.NewServer(mux)
+	t.Cleanup(issuer.Close)
+	return issuer
+}
+
+func (i *testIssuer) token(t *testing.T, claims jwt.MapClaims) string {
+	base := jwt.MapClaims{"iss": i.URL, "aud": "bookmarks", "exp": time.Now().Add(time.Hour).Unix()}
+	for name, value := range claims {
+		base[name] = value
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, base)
+	token.Header["kid"] = "main"
+	signed, err := token.SignedString(i.key)
+	require.NoError(t, err)
+	return signed
+}
+
+func TestOIDCProvider(t *testing.T) {
+	issuer := newTestIssuer(t)
+	service, db := setupProviderService(t, config.AuthConfig{
+		Provider: config.AuthProviderOIDC,
+		OIDC: config.OIDCConfig{
+			IssuerURL: issuer.URL + "/", ClientID: "bookmarks", LinkByEmail: true,
+			EmailClaim: "email", UsernameClaim: "preferred_username", NameClaim: "name",
+		},
+	})
+	ctx := context.Background()
+
+	legacy := database.User{Email: "ada@example.com", Username: "ada", SupabaseID: "user_ada_1"}
+	require.NoError(t, db.Create(&legacy).Error)
+
+	// Existing accounts are linked by verified email
+	principal, err := service.VerifyBearer(ctx, issuer.token(t, jwt.MapClaims{"sub": "kc-ada", "email": "Ada@example.com", "email_verified": true}))
+	require.NoError(t, err)
+	assert.Equal(t, legacy.ID, principal.UserID)
+
+	// New users get an account, with a free username
+	exchanged, err := service.ExchangeToken(ctx, issuer.token(t, jwt.MapClaims{
+		"sub": "kc-grace", "email": "grace@example.com", "preferred_username": "ADA", "name": "Grace",
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, "ada2", exchanged.User.Username)
+	assert.Equal(t, "Grace", exchanged.User.DisplayName)
+	assert.Equal(t, "oidc:kc-grace", exchanged.User.SupabaseID)
+	assert.NotEmpty(t, exchanged.AccessToken)
+
+	info, err := service.ValidateToken(issuer.token(t, jwt.MapClaims{"sub": "kc-grace", "aud": "other", "azp": "bookmarks"}))
+	require.NoError(t, err)
+	assert.Equal(t, exchanged.User.ID, info.ID)
+
+	// Unverified emails of existing accounts are not taken over
+	_, err = service.ExchangeToken(ctx, issuer.token(t, jwt.MapClaims{"sub": "kc-mallory", "email": "ada@example.com"}))
+	assert.ErrorIs(t, err, ErrIdentityConflict)
+
+	for name, claims := range map[string]jwt.MapClaims{
+		"wrong audience": {"sub": "kc-ada", "aud": "other"},
+		"wrong issuer":   {"sub": "kc-ada", "iss": "https://evil.example.com"},
+		"expired":        {"sub": "kc-ada", "exp": time.Now().Add(-time.Minute).Unix()},
+		"no subject":     {"email": "ada@example.com"},
+	} {
+		_, err := service.VerifyBearer(ctx, issuer.token(t, claims))
+		assert.ErrorIs(t, err, ErrInvalidCredentials, name)
+	}
+	_, err = service.VerifyBearer(ctx, "not-a-token")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	_, err = service.Login(ctx, &LoginRequest{Email: "ada@example.com", Password: "anything"})
+	assert.ErrorIs(t, err, ErrPasswordAuthDisabled)
+	assert.Equal(t, ProviderInfo{Provider: config.AuthProviderOIDC, Issuer: issuer.URL, ClientID: "bookmarks"}, service.ProviderInfo())
+}
+
+func TestIdentityMigration(t *testing.T) {
+	db, err := database.SetupTestDB()
+	require.NoError(t, err)
+	t.Cleanup(func() { database.CleanupTestDB(db) })
+	ctx := context.Background()
+
+	ada := database.User{Email: "ada@example.com", Username: "ada", SupabaseID: "sb-ada"}
+	grace := database.User{Email: "grace@example.com", Username: "grace", SupabaseID: "sb-grace"}
+	provisioned := database.User{Email: "new@example.com", Username: "new", SupabaseID: "oidc:kc-new"}
+	for _, user := range []*database.User{&ada, &grace, &provisioned} {
+		require.NoError(t, db.Create(user).Error)
+	}
+
+	linked, err := BackfillIdentities(ctx, db)
+	require.NoError(t, err)
+	assert.Equal(t, 2, linked)
+	linked, err = BackfillIdentities(ctx, db)
+	require.NoError(t, err)
+	assert.Equal(t, 0, linked)
+
+	imported, err := ImportIdentities(ctx, db, strings.NewReader("supabase_id,provider,subject\nsb-ada,oidc,kc-ada\nsb-grace, oidc, kc-grace\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 2, imported)
+
+	var link database.UserIdentity
+	require.NoError(t, db.Where("provider = ? AND subject = ?", config.AuthProviderOIDC, "kc-grace").First(&link).Error)
+	assert.Equal(t, grace.ID, link.UserID)
+
+	_, err = ImportIdentities(ctx, db, strings.NewReader("sb-ada,oidc,kc-grace\n"))
+	assert.ErrorContains(t, err, "linked to another user")
+	_, err = ImportIdentities(ctx, db, strings.NewReader("sb-missing,oidc,kc-missing\n"))
+	assert.ErrorContains(t, err, "no user")
+	_, err = ImportIdentities(ctx, db, strings.NewReader("sb-ada,ldap,uid=ada\n"))
+	assert.Error(t, err)
+}
@@ -7,6 +7,7 @@ import (
 
 	"bookmark-sync-service/backend/internal/config"
 	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/middleware"
 	"bookmark-sync-service/backend/pkg/redis"
 	"bookmark-sync-service/backend/pkg/supabase"
 
@@ -22,6 +23,8 @@ type Service struct {
 	supabaseClient *supabase.Client
 	jwtConfig      *config.JWTConfig
 	logger         *zap.Logger
+	registration   RegistrationHook
+	provider       Provider
 }
 
 // NewService creates a new authentication service
@@ -32,9 +35,15 @@ func NewService(db *gorm.DB, redisClient *redis.Client, supabaseClient *supabase
 		supabaseClient: supabaseClient,
 		jwtConfig:      jwtConfig,
 		logger:         logger,
+		provider:       &supabaseProvider{db: db},
 	}
 }
 
+// SetProvider changes who verifies credentials, Supabase by default
+func (s *Service) SetProvider(provider Provider) {
+	s.provider = provider
+}
+
 // RegisterRequest represents a user registration request
 type RegisterRequest struct {
 	Email       string `json:"email" binding:"required,email"`
@@ -87,22 +96,27 @@ func (s *Service) Register(ctx context.Context, req *RegisterRequest) (*AuthResp
 		return nil, fmt.Errorf("user with email or username already exists")
 	}
 
-	// For now, we'll create a simple user without Supabase integration
-	// In a full implementation, you would integrate with Supabase Auth here
-	supabaseID := fmt.Sprintf("user_%s_%d", req.Username, time.Now().Unix())
+	passwords, ok := s.provider.(PasswordProvider)
+	if !ok {
+		return nil, ErrPasswordAuthDisabled
+	}
+	identity, err := passwords.SignUp(ctx, req)
+	if err != nil {
+		return nil, err
+	}
 
 	// Create user in our database
 	user := database.User{
 		Email:       req.Email,
 		Username:    req.Username,
 		DisplayName: req.DisplayName,
-		SupabaseID:  supabaseID,
+		SupabaseID:  externalID(identity),
 		Preferences: `{"theme": "light", "gridSize": "medium", "defaultView": "grid"}`,
 	}
 
-	if err := s.db.Create(&user).Error; err != nil {
+	if err := s.createAccount(ctx, &user, identity); err != nil {
 		s.logger.Error("Failed to create user in database", zap.Error(err), zap.String("email", req.Email))
-		return nil, fmt.Errorf("failed to create user: %w", err)
+		return nil, err
 	}
 
 	// Generate JWT tokens
@@ -119,6 +133,9 @@ func (s *Service) Register(ctx context.Context, req *RegisterRequest) (*AuthResp
 	}
 
 	s.logger.Info("User registered successfully", zap.Uint("user_id", user.ID), zap.String("email", req.Email))
+	if s.registration != nil {
+		s.registration.UserRegistered(ctx, user.ID)
+	}
 
 	return &AuthResponse{
 		User: &UserInfo{
@@ -139,29 +156,30 @@ func (s *Service) Register(ctx context.Context, req *RegisterRequest) (*AuthResp
 func (s *Service) Login(ctx context.Context, req *LoginRequest) (*AuthResponse, error) {
 	s.logger.Info("User login attempt", zap.String("email", req.Email))
 
-	// For now, we'll do basic email/password validation
-	// In a full implementation, you would integrate with Supabase Auth here
-	var user database.User
-	if err := s.db.Where("email = ?", req.Email).First(&user).Error; err != nil {
-		s.logger.Error("User not found", zap.Error(err), zap.String("email", req.Email))
-		return nil, fmt.Errorf("invalid credentials")
+	passwords, ok := s.provider.(PasswordProvider)
+	if !ok {
+		return nil, ErrPasswordAuthDisabled
 	}
-
-	// For demo purposes, we'll accept any password for existing users
-	// In production, you would validate the password hash
-	if req.Password == "" {
-		return nil, fmt.Errorf("invalid credentials")
+	identity, err := passwords.SignIn(ctx, req)
+	if err != nil {
+		s.logger.Error("Sign-in rejected", zap.Error(err), zap.String("email", req.Email))
+		return nil, ErrInvalidCredentials
+	}
+	user, err := s.accountFor(ctx, identity, false)
+	if err != nil {
+		s.logger.Error("User not found", zap.Error(err), zap.String("email", req.Email))
+		return nil, ErrInvalidCredentials
 	}
 
 	// Update last active timestamp
 	now := time.Now()
 	user.LastActiveAt = &now
-	if err := s.db.Save(&user).Error; err != nil {
+	if err := s.db.Save(user).Error; err != nil {
 		s.logger.Warn("Failed to update last active timestamp", zap.Error(err), zap.Uint("user_id", user.ID))
 	}
 
 	// Generate JWT tokens
-	accessToken, refreshToken, err := s.generateTokens(&user)
+	accessToken, refreshToken, err := s.generateTokens(user)
 	if err != nil {
 		s.logger.Error("Failed to generate tokens", zap.Error(err), zap.Uint("user_id", user.ID))
 		return nil, fmt.Errorf("failed to generate tokens: %w", err)
@@ -293,6 +311,12 @@ func (s *Service) ValidateToken(tokenString string) (*UserInfo, error) {
 	})
 
 	if err != nil || !token.Valid {
+		// Requests may also carry a token of the external identity provider
+		if _, ok := s.provider.(TokenProvider); ok {
+			if user, err := s.tokenUser(context.Background(), tokenString); err == nil {
+				return userInfo(user), nil
+			}
+		}
 		return nil, fmt.Errorf("invalid token")
 	}
 
@@ -326,6 +350,8 @@ func (s *Service) ValidateToken(tokenString string) (*UserInfo, error) {
 // generateTokens generates access and refresh tokens
 func (s *Service) generateTokens(user *database.User) (string, string, error) {
 	now := time.Now()
+	accessExpiry := now.Add(time.Duration(s.jwtConfig.ExpiryHour) * time.Hour)
+	refreshExpiry := now.Add(7 * 24 * time.Hour) // 7 days
 
 	// Access token claims
 	accessClaims := jwt.MapClaims{
@@ -335,7 +361,6 @@ func (s *Service) generateTokens(user *database.User) (string, string, error) {
 		"supabase_id": user.SupabaseID,
 		"sub":         user.SupabaseID,
 		"iat":         now.Unix(),
-		"exp":         now.Add(time.Duration(s.jwtConfig.ExpiryHour) * time.Hour).Unix(),
 		"type":        "access",
 	}
 
@@ -344,10 +369,22 @@ func (s *Service) generateTokens(user *database.User) (string, string, error) {
 		"user_id": user.ID,
 		"sub":     user.SupabaseID,
 		"iat":     now.Unix(),
-		"exp":     now.Add(7 * 24 * time.Hour).Unix(), // 7 days
 		"type":    "refresh",
 	}
 
+	// Tokens of a demo sandbox say so and die with it
+	if user.DemoExpiresAt != nil {
+		accessClaims["demo"] = true
+		if user.DemoExpiresAt.Before(accessExpiry) {
+			accessExpiry = *user.DemoExpiresAt
+		}
+		if user.DemoExpiresAt.Before(refreshExpiry) {
+			refreshExpiry = *user.DemoExpiresAt
+		}
+	}
+	accessClaims["exp"] = accessExpiry.Unix()
+	refreshClaims["exp"] = refreshExpiry.Unix()
+
 	// Generate access token
 	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, accessClaims)
 	accessTokenString, err := accessToken.SignedString([]byte(s.jwtConfig.Secret))
@@ -370,3 +407,117 @@ func (s *Service) storeRefreshToken(ctx context.Context, userID uint, token stri
 	key := fmt.Sprintf("refresh_token:%d", userID)
 	return s.redisClient.SetWithExpiration(ctx, key, token, 7*24*time.Hour)
 }
+
+// ProviderInfo tells clients how users sign in
+type ProviderInfo struct {
+	Provider       string `json:"provider"`
+	PasswordSignIn bool   `json:"password_sign_in"`
+	Issuer         string `json:"issuer,omitempty"`    // OpenID Connect issuer to sign in at
+	ClientID       string `json:"client_id,omitempty"` // OpenID Connect client to sign in as
+}
+
+// ProviderInfo describes the configured provider
+func (s *Service) ProviderInfo() ProviderInfo {
+	info := ProviderInfo{Provider: s.provider.Name()}
+	_, info.PasswordSignIn = s.provider.(PasswordProvider)
+	if oidc, ok := s.provider.(*OIDCProvider); ok {
+		info.Issuer = oidc.Issuer()
+		info.ClientID = oidc.ClientID()
+	}
+	return info
+}
+
+// ExchangeToken signs in with a token of the external identity provider and
+// returns API tokens, creating the account on first sign-in
+func (s *Service) ExchangeToken(ctx context.Context, token string) (*AuthResponse, error) {
+	user, err := s.tokenUser(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, refreshToken, err := s.generateTokens(user)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate tokens: %w", err)
+	}
+	if err := s.storeRefreshToken(ctx, user.ID, refreshToken); err != nil {
+		return nil, fmt.Errorf("failed to store refresh token: %w", err)
+	}
+
+	s.logger.Info("Provider token exchanged", zap.Uint("user_id", user.ID))
+
+	return &AuthResponse{
+		User:         userInfo(user),
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    s.jwtConfig.ExpiryHour * 3600,
+	}, nil
+}
+
+// IssueTokens signs in an account the API created itself, such as a demo
+// sandbox, without credentials
+func (s *Service) IssueTokens(ctx context.Context, user *database.User) (*AuthResponse, error) {
+	accessToken, refreshToken, err := s.generateTokens(user)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate tokens: %w", err)
+	}
+	if err := s.storeRefreshToken(ctx, user.ID, refreshToken); err != nil {
+		return nil, fmt.Errorf("failed to store refresh token: %w", err)
+	}
+
+	expiresIn := s.jwtConfig.ExpiryHour * 3600
+	if user.DemoExpiresAt != nil {
+		if remaining := int(time.Until(*user.DemoExpiresAt).Seconds()); remaining < expiresIn {
+			expiresIn = remaining
+		}
+	}
+	return &AuthResponse{
+		User:         userInfo(user),
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    expiresIn,
+	}, nil
+}
+
+// VerifyBearer authenticates requests carrying a token of the external
+// identity provider instead of an API token
+func (s *Service) VerifyBearer(ctx context.Context, token string) (*middleware.Principal, error) {
+	user, err := s.tokenUser(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	return &middleware.Principal{UserID: user.ID, Email: user.Email, Subject: user.SupabaseID}, nil
+}
+
+// tokenUser returns the account of a provider token's user
+func (s *Service) tokenUser(ctx context.Context, token string) (*database.User, error) {
+	tokens, ok := s.provider.(TokenProvider)
+	if !ok {
+		return nil, ErrTokenAuthDisabled
+	}
+	identity, err := tokens.VerifyToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	return s.accountFor(ctx, identity, true)
+}
+
+func userInfo(user *database.User) *UserInfo {
+	return &UserInfo{
+		ID:          user.ID,
+		Email:       user.Email,
+		Username:    user.Username,
+		DisplayName: user.DisplayName,
+		Avatar:      user.Avatar,
+		SupabaseID:  user.SupabaseID,
+	}
+}
+
+// RegistrationHook prepares new accounts, e.g. with sample content
+type RegistrationHook interface {
+	UserRegistered(ctx context.Context, userID uint)
+}
+
+// SetRegistrationHook makes the service run a hook for every new account
+func (s *Service) SetRegistrationHook(hook RegistrationHook) {
+	s.registration = hook
+}
@@ -0,0 +1,23 @@
+package automation
+
+import "context"
+
+// WorkBudget meters a user's automation work against their rate limit. Wait
+// holds the work until the budget allows it and fails when that would take
+// longer than the queue delay
+type WorkBudget interface {
+	Wait(ctx context.Context, userID string) error
+}
+
+// SetWorkBudget makes bulk operations and backups wait for the user's budget
+func (s *Service) SetWorkBudget(budget WorkBudget) {
+	s.budget = budget
+}
+
+// waitForBudget blocks while the work is queued; it stays pending meanwhile
+func (s *Service) waitForBudget(userID string) error {
+	if s.budget == nil {
+		return nil
+	}
+	return s.budget.Wait(context.Background(), userID)
+}
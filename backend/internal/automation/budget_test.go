@@ -0,0 +1,45 @@
+package automation
+
+import (
+	"context"
+	"errors"
+)
+
+// budgetStub refuses work while exhausted and remembers who asked
+type budgetStub struct {
+	exhausted bool
+	users     []string
+}
+
+func (b *budgetStub) Wait(_ context.Context, userID string) error {
+	b.users = append(b.users, userID)
+	if b.exhausted {
+		return errors.New("rate limit exceeded: try again in 30s")
+	}
+	return nil
+}
+
+func (suite *AutomationServiceTestSuite) TestWorkBudget_FailsWorkOverBudget() {
+	service := suite.GetTestService()
+	service.executor = syncExecutor{}
+	budget := &budgetStub{exhausted: true}
+	service.SetWorkBudget(budget)
+
+	// When: The user is over their automation budget
+	operation, err := service.CreateBulkOperation(suite.GetTestUserID(), BulkOperationRequest{Type: "delete"})
+	suite.Require().NoError(err)
+	job, err := service.CreateBackupJob(suite.GetTestUserID(), BackupRequest{Type: "full"})
+	suite.Require().NoError(err)
+
+	// Then: Neither piece of work starts
+	suite.Equal([]string{suite.GetTestUserID(), suite.GetTestUserID()}, budget.users)
+	suite.Equal("failed", operation.Status)
+	suite.Nil(operation.StartedAt)
+	suite.Contains(operation.Error, "rate limit exceeded")
+	suite.Equal("failed", job.Status)
+	suite.Nil(job.StartedAt)
+
+	var stored BulkOperation
+	suite.Require().NoError(suite.GetTestDB().First(&stored, operation.ID).Error)
+	suite.Equal("failed", stored.Status)
+}
@@ -0,0 +1,116 @@
+package automation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Bounds of the server-side wait for bulk operations created with wait=true
+const (
+	DefaultBulkWait = 10 * time.Second
+	MaxBulkWait     = 25 * time.Second // under the server's default 30s write timeout
+	bulkWaitPoll    = 200 * time.Millisecond
+)
+
+// BulkQueue runs bulk operations on the job queue's workers
+type BulkQueue interface {
+	SubmitBulkOperation(operationID uint) error
+}
+
+// SetBulkQueue runs bulk operations on the job queue instead of their own
+// goroutines, so the number running at once is bounded
+func (s *Service) SetBulkQueue(queue BulkQueue) {
+	s.bulkQueue = queue
+}
+
+// startBulkOperation hands a stored operation to the job queue. Operations
+// the queue has no room for still run, on the executor, since the client
+// was already told they were accepted
+func (s *Service) startBulkOperation(operation *BulkOperation) {
+	if s.bulkQueue != nil {
+		if err := s.bulkQueue.SubmitBulkOperation(operation.ID); err == nil {
+			return
+		}
+	}
+
+	s.executor.Execute(func() {
+		s.processBulkOperation(operation)
+	})
+}
+
+// RunBulkOperation processes a queued bulk operation. Operations cancelled
+// while they were queued are skipped
+func (s *Service) RunBulkOperation(ctx context.Context, operationID uint) error {
+	var operation BulkOperation
+	err := s.db.WithContext(ctx).First(&operation, operationID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load bulk operation: %w", err)
+	}
+	if operation.Status != "pending" {
+		return nil
+	}
+
+	s.processBulkOperation(&operation)
+	return nil
+}
+
+// FailBulkOperation marks a queued bulk operation failed when the job queue
+// gives up on it. Operations that already started are left alone
+func (s *Service) FailBulkOperation(ctx context.Context, operationID uint, reason string) error {
+	completed := time.Now()
+	err := s.db.WithContext(ctx).Model(&BulkOperation{}).
+		Where("id = ? AND status = ?", operationID, "pending").
+		Updates(map[string]interface{}{"status": "failed", "error": reason, "completed_at": &completed}).Error
+	if err != nil {
+		return fmt.Errorf("failed to mark bulk operation failed: %w", err)
+	}
+	return nil
+}
+
+// WaitForBulkOperation waits up to timeout for a bulk operation to finish
+// and returns it as it is then, with whether it finished. Giving up the wait,
+// e.g. when the client disconnects, leaves the operation running
+func (s *Service) WaitForBulkOperation(ctx context.Context, userID string, id uint, timeout time.Duration) (*BulkOperation, bool, error) {
+	if timeout <= 0 {
+		timeout = DefaultBulkWait
+	}
+	if timeout > MaxBulkWait {
+		timeout = MaxBulkWait
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(bulkWaitPoll)
+	defer ticker.Stop()
+
+	for {
+		operation, err := s.GetBulkOperation(userID, id)
+		if err != nil {
+			return nil, false, err
+		}
+		if bulkOperationFinished(operation.Status) {
+			return operation, true, nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return operation, false, nil
+		}
+	}
+}
+
+func bulkOperationFinished(status string) bool {
+	switch status {
+	case "completed", "failed", "cancelled", "undone":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+package automation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// queueStub records submitted operations and, unless it is full, runs them
+// right away on the service
+type queueStub struct {
+	service   *Service
+	full      bool
+	submitted []uint
+}
+
+func (q *queueStub) SubmitBulkOperation(operationID uint) error {
+	q.submitted = append(q.submitted, operationID)
+	if q.full {
+		return errors.New("job queue is full")
+	}
+	if q.service != nil {
+		return q.service.RunBulkOperation(context.Background(), operationID)
+	}
+	return nil
+}
+
+func (suite *AutomationServiceTestSuite) TestBulkQueue_RunsQueuedOperations() {
+	service := suite.GetTestService()
+	queue := &queueStub{service: service}
+	service.SetBulkQueue(queue)
+
+	operation, err := service.CreateBulkOperation(suite.GetTestUserID(), BulkOperationRequest{Type: "delete"})
+	suite.Require().NoError(err)
+	suite.Equal([]uint{operation.ID}, queue.submitted)
+
+	stored, err := service.GetBulkOperation(suite.GetTestUserID(), operation.ID)
+	suite.Require().NoError(err)
+	suite.True(bulkOperationFinished(stored.Status))
+}
+
+func (suite *AutomationServiceTestSuite) TestBulkQueue_FallsBackWhenFull() {
+	service := suite.GetTestService()
+	service.executor = syncExecutor{}
+	queue := &queueStub{full: true}
+	service.SetBulkQueue(queue)
+
+	// The operation was accepted, so it still runs
+	operation, err := service.CreateBulkOperation(suite.GetTestUserID(), BulkOperationRequest{Type: "delete"})
+	suite.Require().NoError(err)
+	suite.Len(queue.submitted, 1)
+	suite.True(bulkOperationFinished(operation.Status))
+}
+
+func (suite *AutomationServiceTestSuite) TestRunBulkOperation_SkipsCancelled() {
+	service := suite.GetTestService()
+	operation, err := service.CreateBulkOperation(suite.GetTestUserID(), BulkOperationRequest{Type: "delete"})
+	suite.Require().NoError(err)
+	suite.Require().NoError(service.db.Model(operation).Update("status", "cancelled").Error)
+
+	suite.NoError(service.RunBulkOperation(context.Background(), operation.ID))
+
+	stored, err := service.GetBulkOperation(suite.GetTestUserID(), operation.ID)
+	suite.Require().NoError(err)
+	suite.Equal("cancelled", stored.Status)
+	suite.Nil(stored.StartedAt)
+
+	// Operations deleted while queued are dropped
+	suite.NoError(service.RunBulkOperation(context.Background(), operation.ID+100))
+}
+
+func (suite *AutomationServiceTestSuite) TestFailBulkOperation_MarksPendingFailed() {
+	service := suite.GetTestService()
+	service.SetBulkQueue(&queueStub{})
+	operation, err := service.CreateBulkOperation(suite.GetTestUserID(), BulkOperationRequest{Type: "export"})
+	suite.Require().NoError(err)
+
+	suite.NoError(service.FailBulkOperation(context.Background(), operation.ID, "worker pool stopped"))
+
+	stored, err := service.GetBulkOperation(suite.GetTestUserID(), operation.ID)
+	suite.Require().NoError(err)
+	suite.Equal("failed", stored.Status)
+	suite.Equal("worker pool stopped", stored.Error)
+	suite.NotNil(stored.CompletedAt)
+
+	// Finished operations keep their outcome
+	suite.Require().NoError(service.db.Model(operation).Updates(map[string]interface{}{"status": "completed", "error": ""}).Error)
+	suite.NoError(service.FailBulkOperation(context.Background(), operation.ID, "late"))
+	stored, err = service.GetBulkOperation(suite.GetTestUserID(), operation.ID)
+	suite.Require().NoError(err)
+	suite.Equal("completed", stored.Status)
+}
+
+func (suite *AutomationHandlerTestSuite) TestCreateBulkOperation_WaitReturnsFinished() {
+	suite.GetTestService().SetBulkQueue(&queueStub{service: suite.GetTestService()})
+
+	w := suite.makeRequest("POST", "/api/v1/automation/bulk?wait=true", BulkOperationRequest{Type: "delete"})
+
+	suite.Equal(http.StatusOK, w.Code)
+	var response BulkOperation
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &response))
+	suite.True(bulkOperationFinished(response.Status))
+	suite.Equal("/api/v1/automation/bulk/"+strconv.FormatUint(uint64(response.ID), 10), w.Header().Get("Location"))
+}
+
+func (suite *AutomationHandlerTestSuite) TestCreateBulkOperation_WaitTimesOut() {
+	// Nothing runs the queued operation
+	suite.GetTestService().SetBulkQueue(&queueStub{})
+
+	w := suite.makeRequest("POST", "/api/v1/automation/bulk?wait=true&timeout=1", BulkOperationRequest{Type: "delete"})
+
+	suite.Equal(http.StatusAccepted, w.Code)
+	var response BulkOperation
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &response))
+	suite.Equal("pending", response.Status)
+	suite.NotEmpty(w.Header().Get("Location"))
+}
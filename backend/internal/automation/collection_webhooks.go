@@ -0,0 +1,101 @@
+package automation
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// DefaultShareViewThreshold is how many share views trigger share.viewed
+// when an endpoint sets no threshold of its own
+const DefaultShareViewThreshold = 100
+
+// CollectionWebhookEvents are the events an endpoint scoped to a collection
+// can subscribe to
+var CollectionWebhookEvents = []WebhookEvent{
+	WebhookEventCollectionBookmarkAdded,
+	WebhookEventCollectionBookmarkRemoved,
+	WebhookEventCollectionCollaboratorJoined,
+	WebhookEventShareViewed,
+}
+
+// IsCollectionWebhookEvent reports whether endpoints scoped to a collection
+// can subscribe to event
+func IsCollectionWebhookEvent(event string) bool {
+	for _, e := range CollectionWebhookEvents {
+		if string(e) == event {
+			return true
+		}
+	}
+	return false
+}
+
+// GetCollectionWebhookEndpoints retrieves the user's endpoints scoped to a collection
+func (s *Service) GetCollectionWebhookEndpoints(userID string, collectionID uint) ([]WebhookEndpoint, error) {
+	var endpoints []WebhookEndpoint
+	if err := s.db.Where("user_id = ? AND collection_id = ?", userID, collectionID).Find(&endpoints).Error; err != nil {
+		return nil, fmt.Errorf("failed to get webhook endpoints: %w", err)
+	}
+	return endpoints, nil
+}
+
+// TriggerCollectionWebhook delivers an event of a collection to the
+// endpoints scoped to it. The owner's account-wide endpoints receive it too
+// when they subscribed to the event explicitly
+func (s *Service) TriggerCollectionWebhook(ctx context.Context, event WebhookEvent, ownerID string, collectionID uint, data interface{}) error {
+	endpoints, err := s.collectionEndpoints(ownerID, collectionID)
+	if err != nil {
+		return err
+	}
+
+	s.dispatch(ctx, endpoints, collectionPayload(event, ownerID, data))
+	return nil
+}
+
+// TriggerShareViewed delivers share.viewed to the endpoints whose view
+// threshold the collection's shares just reached a multiple of, so a
+// receiver hears about every Nth view rather than each one
+func (s *Service) TriggerShareViewed(ctx context.Context, ownerID string, collectionID uint, viewCount int64, data interface{}) error {
+	if viewCount <= 0 {
+		return nil
+	}
+
+	endpoints, err := s.collectionEndpoints(ownerID, collectionID)
+	if err != nil {
+		return err
+	}
+
+	reached := endpoints[:0]
+	for _, endpoint := range endpoints {
+		threshold := int64(endpoint.ViewThreshold)
+		if threshold <= 0 {
+			threshold = DefaultShareViewThreshold
+		}
+		if viewCount%threshold == 0 {
+			reached = append(reached, endpoint)
+		}
+	}
+
+	s.dispatch(ctx, reached, collectionPayload(WebhookEventShareViewed, ownerID, data))
+	return nil
+}
+
+// collectionEndpoints returns the owner's active endpoints that are scoped
+// to the collection or to no collection at all
+func (s *Service) collectionEndpoints(ownerID string, collectionID uint) ([]WebhookEndpoint, error) {
+	var endpoints []WebhookEndpoint
+	if err := s.db.Where("user_id = ? AND active = ? AND (collection_id = ? OR collection_id IS NULL)", ownerID, true, collectionID).
+		Find(&endpoints).Error; err != nil {
+		return nil, fmt.Errorf("failed to get webhook endpoints: %w", err)
+	}
+	return endpoints, nil
+}
+
+func collectionPayload(event WebhookEvent, ownerID string, data interface{}) WebhookPayload {
+	return WebhookPayload{
+		Event:     event,
+		Timestamp: time.Now(),
+		UserID:    ownerID,
+		Data:      data,
+	}
+}
@@ -0,0 +1,90 @@
+package automation
+
+import (
+	"context"
+)
+
+func (suite *AutomationServiceTestSuite) createCollectionEndpoint(collectionID uint, threshold int, events ...string) *WebhookEndpoint {
+	endpoint, err := suite.GetTestService().CreateWebhookEndpoint(suite.GetTestUserID(), WebhookEndpointRequest{
+		Name:          "Collection receiver",
+		URL:           "https://hooks.example.com/collection",
+		Events:        events,
+		Active:        true,
+		CollectionID:  &collectionID,
+		ViewThreshold: threshold,
+	})
+	suite.Require().NoError(err)
+	return endpoint
+}
+
+func (suite *AutomationServiceTestSuite) deliveryCount(endpoint *WebhookEndpoint) int {
+	deliveries, err := suite.GetTestService().GetWebhookDeliveries(suite.GetTestUserID(), endpoint.ID)
+	suite.Require().NoError(err)
+	return len(deliveries)
+}
+
+func (suite *AutomationServiceTestSuite) TestTriggerCollectionWebhook_OnlyReachesThatCollection() {
+	ctx := context.Background()
+	service := suite.GetTestService()
+	scoped := suite.createCollectionEndpoint(7, 0, string(WebhookEventCollectionBookmarkAdded), string(WebhookEventBookmarkCreated))
+	other := suite.createCollectionEndpoint(8, 0, string(WebhookEventCollectionBookmarkAdded))
+	accountWide, err := service.CreateWebhookEndpoint(suite.GetTestUserID(), WebhookEndpointRequest{
+		Name:   "Account",
+		URL:    "https://hooks.example.com/account",
+		Events: []string{string(WebhookEventCollectionBookmarkAdded), string(WebhookEventBookmarkCreated)},
+	})
+	suite.Require().NoError(err)
+
+	// When: A bookmark is added to collection 7
+	suite.NoError(service.TriggerCollectionWebhook(ctx, WebhookEventCollectionBookmarkAdded, suite.GetTestUserID(), 7,
+		map[string]interface{}{"collection_id": 7, "bookmark_id": 1}))
+
+	// Then: The collection's endpoint and the subscribed account-wide one hear about it
+	suite.Equal(1, suite.deliveryCount(scoped))
+	suite.Equal(0, suite.deliveryCount(other))
+	suite.Equal(1, suite.deliveryCount(accountWide))
+
+	// And: Account-wide events skip endpoints scoped to a collection
+	suite.NoError(service.TriggerWebhook(ctx, WebhookEventBookmarkCreated, suite.GetTestUserID(), nil))
+	suite.Equal(1, suite.deliveryCount(scoped))
+	suite.Equal(2, suite.deliveryCount(accountWide))
+}
+
+func (suite *AutomationServiceTestSuite) TestTriggerShareViewed_FiresOnThresholdMultiples() {
+	ctx := context.Background()
+	service := suite.GetTestService()
+	everyTen := suite.createCollectionEndpoint(7, 10, string(WebhookEventShareViewed))
+	byDefault := suite.createCollectionEndpoint(7, 0, string(WebhookEventShareViewed))
+
+	for views := int64(1); views <= 100; views++ {
+		suite.NoError(service.TriggerShareViewed(ctx, suite.GetTestUserID(), 7, views, map[string]interface{}{"view_count": views}))
+	}
+
+	suite.Equal(10, suite.deliveryCount(everyTen))
+	suite.Equal(1, suite.deliveryCount(byDefault))
+}
+
+func (suite *AutomationServiceTestSuite) TestUpdateWebhookEndpoint_KeepsCollectionScope() {
+	endpoint := suite.createCollectionEndpoint(7, 25, string(WebhookEventShareViewed))
+
+	// When: The endpoint is updated through the account-wide API
+	updated, err := suite.GetTestService().UpdateWebhookEndpoint(suite.GetTestUserID(), endpoint.ID, WebhookEndpointRequest{
+		Name:          "Renamed",
+		URL:           endpoint.URL,
+		Events:        []string{string(WebhookEventShareViewed)},
+		Active:        true,
+		ViewThreshold: 50,
+	})
+
+	// Then: It stays scoped to its collection
+	suite.NoError(err)
+	suite.Require().NotNil(updated.CollectionID)
+	suite.Equal(uint(7), *updated.CollectionID)
+	suite.Equal(50, updated.ViewThreshold)
+
+	endpoints, err := suite.GetTestService().GetCollectionWebhookEndpoints(suite.GetTestUserID(), 7)
+	suite.NoError(err)
+	suite.Len(endpoints, 1)
+	suite.True(IsCollectionWebhookEvent("share.viewed"))
+	suite.False(IsCollectionWebhookEvent("bookmark.created"))
+}
@@ -0,0 +1,286 @@
+package automation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/pkg/secrets"
+)
+
+// SetCredentialCipher configures the cipher used to encrypt destination credentials
+func (s *Service) SetCredentialCipher(cipher *secrets.Cipher) {
+	s.credentials = cipher
+}
+
+// SetDestinationClientFactory overrides how destination clients are built
+func (s *Service) SetDestinationClientFactory(factory DestinationClientFactory) {
+	s.destinationClient = factory
+}
+
+// CreateBackupDestination registers an external bucket as a backup destination
+func (s *Service) CreateBackupDestination(userID string, req BackupDestinationRequest) (*BackupDestination, error) {
+	if s.credentials == nil {
+		return nil, ErrCredentialEncryptionDisabled
+	}
+	if req.SecretAccessKey == "" {
+		return nil, fmt.Errorf("%w: secret_access_key is required", ErrInvalidParameters)
+	}
+
+	destination := &BackupDestination{UserID: userID, Type: "s3", UseSSL: true, Active: true}
+	if err := s.applyDestinationRequest(destination, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Create(destination).Error; err != nil {
+		return nil, fmt.Errorf("failed to create backup destination: %w", err)
+	}
+
+	return destination, nil
+}
+
+// GetBackupDestinations retrieves backup destinations for a user
+func (s *Service) GetBackupDestinations(userID string) ([]BackupDestination, error) {
+	var destinations []BackupDestination
+	if err := s.db.Where("user_id = ?", userID).Find(&destinations).Error; err != nil {
+		return nil, fmt.Errorf("failed to get backup destinations: %w", err)
+	}
+	return destinations, nil
+}
+
+// GetBackupDestination retrieves a specific backup destination
+func (s *Service) GetBackupDestination(userID string, id uint) (*BackupDestination, error) {
+	var destination BackupDestination
+	if err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&destination).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBackupDestinationNotFound
+		}
+		return nil, fmt.Errorf("failed to get backup destination: %w", err)
+	}
+	return &destination, nil
+}
+
+// UpdateBackupDestination updates a backup destination. An empty secret keeps
+// the stored one
+func (s *Service) UpdateBackupDestination(userID string, id uint, req BackupDestinationRequest) (*BackupDestination, error) {
+	if s.credentials == nil {
+		return nil, ErrCredentialEncryptionDisabled
+	}
+
+	destination, err := s.GetBackupDestination(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.applyDestinationRequest(destination, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Save(destination).Error; err != nil {
+		return nil, fmt.Errorf("failed to update backup destination: %w", err)
+	}
+
+	return destination, nil
+}
+
+// DeleteBackupDestination deletes a backup destination
+func (s *Service) DeleteBackupDestination(userID string, id uint) error {
+	result := s.db.Where("id = ? AND user_id = ?", id, userID).Delete(&BackupDestination{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete backup destination: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrBackupDestinationNotFound
+	}
+	return nil
+}
+
+// TestBackupDestination checks that the destination accepts writes and records the outcome
+func (s *Service) TestBackupDestination(ctx context.Context, userID string, id uint) error {
+	destination, err := s.GetBackupDestination(userID, id)
+	if err != nil {
+		return err
+	}
+
+	client, err := s.destinationClientFor(destination)
+	if err != nil {
+		return err
+	}
+
+	testErr := client.TestConnection(ctx)
+	s.recordDestinationResult(destination, testErr)
+	return testErr
+}
+
+func (s *Service) applyDestinationRequest(destination *BackupDestination, req BackupDestinationRequest) error {
+	destination.Name = req.Name
+	destination.Endpoint = req.Endpoint
+	destination.Region = req.Region
+	destination.Bucket = req.Bucket
+	destination.Prefix = req.Prefix
+	destination.AccessKeyID = req.AccessKeyID
+	destination.KeepLocalCopy = req.KeepLocalCopy
+	if req.UseSSL != nil {
+		destination.UseSSL = *req.UseSSL
+	}
+	if req.Active != nil {
+		destination.Active = *req.Active
+	}
+
+	if req.SecretAccessKey != "" {
+		encrypted, err := s.credentials.Encrypt(req.SecretAccessKey)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt credentials: %w", err)
+		}
+		destination.SecretAccessKey = encrypted
+	}
+
+	return nil
+}
+
+func (s *Service) destinationClientFor(destination *BackupDestination) (DestinationClient, error) {
+	if s.credentials == nil {
+		return nil, ErrCredentialEncryptionDisabled
+	}
+
+	secret, err := s.credentials.Decrypt(destination.SecretAccessKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt destination credentials: %w", err)
+	}
+
+	return s.destinationClient(destination, secret), nil
+}
+
+func (s *Service) recordDestinationResult(destination *BackupDestination, err error) {
+	now := time.Now()
+	destination.LastTestedAt = &now
+	destination.LastError = ""
+	if err != nil {
+		destination.LastError = err.Error()
+	}
+	s.db.Model(destination).Updates(map[string]interface{}{
+		"last_tested_at": destination.LastTestedAt,
+		"last_error":     destination.LastError,
+	})
+}
+
+// uploadBackup copies a finished backup to the job's destination, removing the
+// local file unless the destination keeps a local copy
+func (s *Service) uploadBackup(ctx context.Context, job *BackupJob) error {
+	destination, err := s.GetBackupDestination(job.UserID, *job.DestinationID)
+	if err != nil {
+		return err
+	}
+	if !destination.Active {
+		return ErrBackupDestinationInactive
+	}
+
+	client, err := s.destinationClientFor(destination)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(job.FilePath)
+	if err != nil {
+		return fmt.Errorf("failed to open backup file: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat backup file: %w", err)
+	}
+
+	key := path.Join(destination.Prefix, filepath.Base(job.FilePath))
+	location, err := client.Upload(ctx, key, file, info.Size())
+	s.recordDestinationResult(destination, err)
+	if err != nil {
+		return err
+	}
+
+	job.RemoteURL = location
+	if !destination.KeepLocalCopy {
+		file.Close()
+		os.Remove(job.FilePath)
+		job.FilePath = ""
+	}
+
+	return nil
+}
+
+// UploadToDestination stores a file under the prefix of one of the user's
+// active backup destinations, for other modules delivering exports there,
+// and returns its location
+func (s *Service) UploadToDestination(ctx context.Context, userID string, destinationID uint, name string, body io.Reader, size int64) (string, error) {
+	destination, err := s.GetBackupDestination(userID, destinationID)
+	if err != nil {
+		return "", err
+	}
+	if !destination.Active {
+		return "", ErrBackupDestinationInactive
+	}
+
+	client, err := s.destinationClientFor(destination)
+	if err != nil {
+		return "", err
+	}
+
+	location, err := client.Upload(ctx, path.Join(destination.Prefix, name), body, size)
+	s.recordDestinationResult(destination, err)
+	return location, err
+}
+
+// RotateCredentialKey re-encrypts the credentials of every backup destination
+// with next, returning how many it covered. Every secret is decrypted before
+// any is rewritten, so a key mismatch leaves all of them untouched; a dry run
+// stops there. Afterwards the service encrypts with next, which must become
+// the configured key before the next restart
+func (s *Service) RotateCredentialKey(ctx context.Context, next *secrets.Cipher, dryRun bool) (int, error) {
+	if s.credentials == nil {
+		return 0, ErrCredentialEncryptionDisabled
+	}
+
+	var destinations []BackupDestination
+	if err := s.db.WithContext(ctx).Unscoped().Find(&destinations).Error; err != nil {
+		return 0, fmt.Errorf("failed to get backup destinations: %w", err)
+	}
+
+	rotated := make(map[uint]string, len(destinations))
+	for _, destination := range destinations {
+		secret, err := s.credentials.Decrypt(destination.SecretAccessKey)
+		if err != nil {
+			return 0, fmt.Errorf("failed to decrypt credentials of backup destination %d: %w", destination.ID, err)
+		}
+		encrypted, err := next.Encrypt(secret)
+		if err != nil {
+			return 0, fmt.Errorf("failed to encrypt credentials: %w", err)
+		}
+		rotated[destination.ID] = encrypted
+	}
+	if dryRun {
+		return len(destinations), nil
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for id, encrypted := range rotated {
+			if err := tx.Unscoped().Model(&BackupDestination{}).Where("id = ?", id).
+				UpdateColumn("secret_access_key", encrypted).Error; err != nil {
+				return fmt.Errorf("failed to update backup destination %d: %w", id, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	s.credentials = next
+	return len(destinations), nil
+}
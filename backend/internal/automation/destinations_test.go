@@ -0,0 +1,214 @@
+package automation
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bookmark-sync-service/backend/pkg/secrets"
+)
+
+// fakeDestinationClient records uploads and returns a configured error
+type fakeDestinationClient struct {
+	err      error
+	uploaded map[string]string
+	secret   string
+}
+
+func (f *fakeDestinationClient) TestConnection(ctx context.Context) error {
+	return f.err
+}
+
+func (f *fakeDestinationClient) Upload(ctx context.Context, key string, body io.Reader, size int64) (string, error) {
+	if f.err != nil {
+		return "", f.err
+	}
+	data, _ := io.ReadAll(body)
+	f.uploaded[key] = string(data)
+	return "s3://bucket/" + key, nil
+}
+
+func (suite *AutomationServiceTestSuite) setupDestinations() *fakeDestinationClient {
+	cipher, err := secrets.NewCipher("test-key")
+	suite.Require().NoError(err)
+
+	fake := &fakeDestinationClient{uploaded: map[string]string{}}
+	suite.GetTestService().SetCredentialCipher(cipher)
+	suite.GetTestService().SetDestinationClientFactory(func(destination *BackupDestination, secretAccessKey string) DestinationClient {
+		fake.secret = secretAccessKey
+		return fake
+	})
+	return fake
+}
+
+func (suite *AutomationServiceTestSuite) createDestination(keepLocal bool) *BackupDestination {
+	destination, err := suite.GetTestService().CreateBackupDestination(suite.GetTestUserID(), BackupDestinationRequest{
+		Name:            "My NAS",
+		Endpoint:        "nas.local:9000",
+		Bucket:          "backups",
+		Prefix:          "bookmarks",
+		AccessKeyID:     "AKIA",
+		SecretAccessKey: "super-secret",
+		KeepLocalCopy:   keepLocal,
+	})
+	suite.Require().NoError(err)
+	return destination
+}
+
+func (suite *AutomationServiceTestSuite) TestCreateBackupDestination_EncryptsSecret() {
+	fake := suite.setupDestinations()
+
+	// When: Registering a destination
+	destination := suite.createDestination(false)
+
+	// Then: The stored secret is encrypted but decrypts for the client
+	var stored BackupDestination
+	suite.Require().NoError(suite.GetTestDB().First(&stored, destination.ID).Error)
+	suite.NotEqual("super-secret", stored.SecretAccessKey)
+	suite.True(stored.UseSSL)
+
+	suite.NoError(suite.GetTestService().TestBackupDestination(context.Background(), suite.GetTestUserID(), destination.ID))
+	suite.Equal("super-secret", fake.secret)
+}
+
+func (suite *AutomationServiceTestSuite) TestCreateBackupDestination_RequiresCipher() {
+	_, err := suite.GetTestService().CreateBackupDestination(suite.GetTestUserID(), BackupDestinationRequest{
+		Name: "NAS", Endpoint: "nas.local", Bucket: "b", AccessKeyID: "a", SecretAccessKey: "s",
+	})
+	suite.ErrorIs(err, ErrCredentialEncryptionDisabled)
+}
+
+func (suite *AutomationServiceTestSuite) TestUpdateBackupDestination_KeepsSecret() {
+	suite.setupDestinations()
+	destination := suite.createDestination(false)
+
+	// When: Updating without a new secret
+	updated, err := suite.GetTestService().UpdateBackupDestination(suite.GetTestUserID(), destination.ID, BackupDestinationRequest{
+		Name: "Renamed", Endpoint: "nas.local:9000", Bucket: "backups", AccessKeyID: "AKIA",
+	})
+
+	// Then: The encrypted secret is kept
+	suite.NoError(err)
+	suite.Equal("Renamed", updated.Name)
+	suite.Equal(destination.SecretAccessKey, updated.SecretAccessKey)
+}
+
+func (suite *AutomationServiceTestSuite) TestTestBackupDestination_RecordsRejection() {
+	fake := suite.setupDestinations()
+	destination := suite.createDestination(false)
+	fake.err = &DestinationError{StatusCode: http.StatusForbidden, Code: "AccessDenied", Message: "Access Denied"}
+
+	// When: Testing a destination that rejects writes
+	err := suite.GetTestService().TestBackupDestination(context.Background(), suite.GetTestUserID(), destination.ID)
+
+	// Then: The rejection is returned and stored on the destination
+	suite.ErrorIs(err, ErrBackupDestinationRejected)
+	reloaded, _ := suite.GetTestService().GetBackupDestination(suite.GetTestUserID(), destination.ID)
+	suite.Contains(reloaded.LastError, "AccessDenied: Access Denied")
+	suite.NotNil(reloaded.LastTestedAt)
+}
+
+func (suite *AutomationServiceTestSuite) TestUploadBackup_MovesFileToDestination() {
+	fake := suite.setupDestinations()
+	destination := suite.createDestination(false)
+
+	filePath := filepath.Join(suite.T().TempDir(), "full_1.tar.gz")
+	suite.Require().NoError(os.WriteFile(filePath, []byte("backup-data"), 0o600))
+
+	job := &BackupJob{UserID: suite.GetTestUserID(), Type: "full", Status: "running", FilePath: filePath, DestinationID: &destination.ID}
+
+	// When: Uploading the finished backup
+	suite.NoError(suite.GetTestService().uploadBackup(context.Background(), job))
+
+	// Then: The file is in the destination and no longer kept locally
+	suite.Equal("backup-data", fake.uploaded["bookmarks/full_1.tar.gz"])
+	suite.Equal("s3://bucket/bookmarks/full_1.tar.gz", job.RemoteURL)
+	suite.Empty(job.FilePath)
+	_, err := os.Stat(filePath)
+	suite.True(os.IsNotExist(err))
+}
+
+func (suite *AutomationServiceTestSuite) TestCreateBackupJob_UnknownDestination() {
+	missing := uint(999)
+	_, err := suite.GetTestService().CreateBackupJob(suite.GetTestUserID(), BackupRequest{Type: "full", DestinationID: &missing})
+	suite.ErrorIs(err, ErrBackupDestinationNotFound)
+}
+
+func TestS3Client_SurfacesDestinationErrors(t *testing.T) {
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`<?xml version="1.0"?><Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`))
+	}))
+	defer server.Close()
+
+	client := NewS3DestinationClient(&BackupDestination{Endpoint: server.URL, Bucket: "backups", AccessKeyID: "AKIA"}, "secret")
+	_, err := client.Upload(context.Background(), "a.tar.gz", strings.NewReader("data"), 4)
+
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrBackupDestinationRejected)
+	assert.Contains(t, err.Error(), "HTTP 403")
+	assert.Contains(t, err.Error(), "AccessDenied: Access Denied")
+	assert.True(t, strings.HasPrefix(authorization, "AWS4-HMAC-SHA256 Credential=AKIA/"))
+}
+
+func TestS3Client_Unreachable(t *testing.T) {
+	client := NewS3DestinationClient(&BackupDestination{Endpoint: "http://127.0.0.1:1", Bucket: "backups"}, "secret")
+	err := client.TestConnection(context.Background())
+	assert.ErrorIs(t, err, ErrBackupDestinationUnreachable)
+}
+
+func TestMapBackupDestinationError(t *testing.T) {
+	mapped := MapBackupDestinationError(&DestinationError{StatusCode: http.StatusForbidden, Code: "AccessDenied"})
+	assert.Equal(t, CodeBackupDestinationRejected, mapped.Code)
+	assert.Contains(t, mapped.Details, "AccessDenied")
+}
+
+func (suite *AutomationServiceTestSuite) TestRotateCredentialKey() {
+	fake := suite.setupDestinations()
+	destination := suite.createDestination(false)
+	var before BackupDestination
+	suite.Require().NoError(suite.GetTestDB().First(&before, destination.ID).Error)
+
+	next, err := secrets.NewCipher("next-key")
+	suite.Require().NoError(err)
+
+	// A dry run only checks every secret decrypts
+	count, err := suite.GetTestService().RotateCredentialKey(context.Background(), next, true)
+	suite.Require().NoError(err)
+	suite.Equal(1, count)
+	var stored BackupDestination
+	suite.Require().NoError(suite.GetTestDB().First(&stored, destination.ID).Error)
+	suite.Equal(before.SecretAccessKey, stored.SecretAccessKey)
+
+	// Rotating rewrites the secret under the new key
+	count, err = suite.GetTestService().RotateCredentialKey(context.Background(), next, false)
+	suite.Require().NoError(err)
+	suite.Equal(1, count)
+	suite.Require().NoError(suite.GetTestDB().First(&stored, destination.ID).Error)
+	secret, err := next.Decrypt(stored.SecretAccessKey)
+	suite.Require().NoError(err)
+	suite.Equal("super-secret", secret)
+
+	suite.NoError(suite.GetTestService().TestBackupDestination(context.Background(), suite.GetTestUserID(), destination.ID))
+	suite.Equal("super-secret", fake.secret)
+
+	// Secrets the current key can't decrypt stop the rotation
+	wrong, err := secrets.NewCipher("wrong-key")
+	suite.Require().NoError(err)
+	suite.GetTestService().SetCredentialCipher(wrong)
+	_, err = suite.GetTestService().RotateCredentialKey(context.Background(), next, false)
+	suite.Error(err)
+	var unchanged BackupDestination
+	suite.Require().NoError(suite.GetTestDB().First(&unchanged, destination.ID).Error)
+	suite.Equal(stored.SecretAccessKey, unchanged.SecretAccessKey)
+}
@@ -0,0 +1,197 @@
+package automation
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultDownloadURLTTL is how long signed artifact download links stay valid
+const DefaultDownloadURLTTL = 15 * time.Minute
+
+// Artifact kinds that can be fetched through signed download links
+const (
+	ArtifactBackup = "backup"
+	ArtifactExport = "export"
+)
+
+// downloadPath is the public route serving signed artifact downloads
+const downloadPath = "/api/v1/downloads"
+
+// DownloadSigner issues and verifies short-lived signed download URLs so that
+// external systems can fetch artifacts without an API token
+type DownloadSigner struct {
+	baseURL string
+	key     []byte
+	ttl     time.Duration
+}
+
+// NewDownloadSigner creates a signer. A non-positive ttl falls back to
+// DefaultDownloadURLTTL
+func NewDownloadSigner(baseURL, key string, ttl time.Duration) *DownloadSigner {
+	if ttl <= 0 {
+		ttl = DefaultDownloadURLTTL
+	}
+	return &DownloadSigner{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		key:     []byte(key),
+		ttl:     ttl,
+	}
+}
+
+// newRandomDownloadSigner creates a signer with a per-process random key, used
+// until the service is configured with a stable one
+func newRandomDownloadSigner() *DownloadSigner {
+	key := make([]byte, 32)
+	rand.Read(key)
+	return NewDownloadSigner("", hex.EncodeToString(key), DefaultDownloadURLTTL)
+}
+
+// TTL returns how long issued links stay valid
+func (d *DownloadSigner) TTL() time.Duration {
+	return d.ttl
+}
+
+// Sign returns a signed download URL for an artifact and its expiry time
+func (d *DownloadSigner) Sign(kind string, id uint, now time.Time) (string, time.Time) {
+	return d.SignVolume(kind, id, 0, now)
+}
+
+// SignVolume returns a signed download URL for one volume of a split
+// artifact, or for the artifact itself when volume is 0
+func (d *DownloadSigner) SignVolume(kind string, id uint, volume int, now time.Time) (string, time.Time) {
+	expiresAt := now.Add(d.ttl).UTC().Truncate(time.Second)
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+
+	query := url.Values{}
+	query.Set("expires", expires)
+	query.Set("signature", d.signature(kind, id, volume, expires))
+	if volume > 0 {
+		query.Set("volume", strconv.Itoa(volume))
+	}
+
+	return fmt.Sprintf("%s%s/%s/%d?%s", d.baseURL, downloadPath, kind, id, query.Encode()), expiresAt
+}
+
+// Verify checks a download link signature and its expiry
+func (d *DownloadSigner) Verify(kind string, id uint, expires, signature string, now time.Time) error {
+	return d.VerifyVolume(kind, id, 0, expires, signature, now)
+}
+
+// VerifyVolume checks the signature and expiry of a link to one volume
+func (d *DownloadSigner) VerifyVolume(kind string, id uint, volume int, expires, signature string, now time.Time) error {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return ErrDownloadLinkInvalid
+	}
+	if !hmac.Equal([]byte(signature), []byte(d.signature(kind, id, volume, expires))) {
+		return ErrDownloadLinkInvalid
+	}
+	if now.Unix() > expiresAt {
+		return ErrDownloadLinkExpired
+	}
+	return nil
+}
+
+func (d *DownloadSigner) signature(kind string, id uint, volume int, expires string) string {
+	h := hmac.New(sha256.New, d.key)
+	if volume > 0 {
+		fmt.Fprintf(h, "%s:%d/%d:%s", kind, id, volume, expires)
+	} else {
+		fmt.Fprintf(h, "%s:%d:%s", kind, id, expires)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// SetDownloadSigner configures how artifact download links are signed
+func (s *Service) SetDownloadSigner(signer *DownloadSigner) {
+	s.downloads = signer
+}
+
+// ResolveDownload verifies a signed download link and returns the artifact's file path
+func (s *Service) ResolveDownload(kind string, id uint, expires, signature string) (string, error) {
+	return s.ResolveVolumeDownload(kind, id, 0, expires, signature)
+}
+
+// ResolveVolumeDownload verifies a signed link to one volume of a split
+// backup, or to the whole artifact when volume is 0, and returns its file path
+func (s *Service) ResolveVolumeDownload(kind string, id uint, volume int, expires, signature string) (string, error) {
+	if err := s.downloads.VerifyVolume(kind, id, volume, expires, signature, time.Now()); err != nil {
+		return "", err
+	}
+
+	switch kind {
+	case ArtifactBackup:
+		var job BackupJob
+		if err := s.db.Where("id = ? AND status = ?", id, "completed").First(&job).Error; err != nil || job.FilePath == "" {
+			return "", ErrBackupFileNotFound
+		}
+		if volume > 0 {
+			return backupVolumePath(&job, volume)
+		}
+		return job.FilePath, nil
+	case ArtifactExport:
+		var operation BulkOperation
+		if err := s.db.Where("id = ? AND type = ? AND status = ?", id, "export", "completed").First(&operation).Error; err != nil {
+			return "", ErrResourceNotFound
+		}
+		filePath, _ := operation.Result["file_path"].(string)
+		if filePath == "" {
+			return "", ErrResourceNotFound
+		}
+		return filePath, nil
+	default:
+		return "", ErrDownloadLinkInvalid
+	}
+}
+
+// notifyBackupFinished emits backup.completed or backup.failed for a finished job
+func (s *Service) notifyBackupFinished(ctx context.Context, job *BackupJob) error {
+	if job.Status != "completed" {
+		return s.TriggerWebhook(ctx, WebhookEventBackupFailed, job.UserID, map[string]interface{}{
+			"backup_id": job.ID,
+			"type":      job.Type,
+			"error":     job.Error,
+		})
+	}
+
+	data := map[string]interface{}{
+		"backup_id": job.ID,
+		"type":      job.Type,
+		"size":      job.Size,
+		"checksum":  job.Checksum,
+	}
+	if job.RemoteURL != "" {
+		data["remote_url"] = job.RemoteURL
+	}
+	// Backups moved to an external destination have no local file to link to
+	if job.FilePath != "" {
+		if download, err := s.backupDownload(job); err == nil {
+			data["download_url"], data["expires_at"] = download.DownloadURL, download.ExpiresAt
+			if download.Manifest != nil {
+				data["volumes"] = download.Manifest.Volumes
+			}
+		} else {
+			data["download_url"], data["expires_at"] = s.downloads.Sign(ArtifactBackup, job.ID, time.Now())
+		}
+	}
+	return s.TriggerWebhook(ctx, WebhookEventBackupCompleted, job.UserID, data)
+}
+
+// notifyExportCompleted emits export.completed for a finished export operation
+func (s *Service) notifyExportCompleted(ctx context.Context, operation *BulkOperation) error {
+	downloadURL, expiresAt := s.downloads.Sign(ArtifactExport, operation.ID, time.Now())
+	return s.TriggerWebhook(ctx, WebhookEventExportCompleted, operation.UserID, map[string]interface{}{
+		"operation_id": operation.ID,
+		"total_items":  operation.TotalItems,
+		"download_url": downloadURL,
+		"expires_at":   expiresAt,
+	})
+}
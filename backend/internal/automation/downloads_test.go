@@ -0,0 +1,175 @@
+package automation
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDownloadSigner_SignAndVerify(t *testing.T) {
+	signer := NewDownloadSigner("https://bookmarks.example.com/", "secret", 10*time.Minute)
+	now := time.Unix(1700000000, 0)
+
+	link, expiresAt := signer.Sign(ArtifactBackup, 42, now)
+	assert.Equal(t, now.Add(10*time.Minute).Unix(), expiresAt.Unix())
+	assert.True(t, strings.HasPrefix(link, "https://bookmarks.example.com/api/v1/downloads/backup/42?"))
+
+	parsed, err := url.Parse(link)
+	require.NoError(t, err)
+	expires, signature := parsed.Query().Get("expires"), parsed.Query().Get("signature")
+
+	assert.NoError(t, signer.Verify(ArtifactBackup, 42, expires, signature, now))
+	assert.ErrorIs(t, signer.Verify(ArtifactBackup, 43, expires, signature, now), ErrDownloadLinkInvalid)
+	assert.ErrorIs(t, signer.Verify(ArtifactExport, 42, expires, signature, now), ErrDownloadLinkInvalid)
+	assert.ErrorIs(t, signer.Verify(ArtifactBackup, 42, "bogus", signature, now), ErrDownloadLinkInvalid)
+	assert.ErrorIs(t, signer.Verify(ArtifactBackup, 42, expires, signature, now.Add(11*time.Minute)), ErrDownloadLinkExpired)
+}
+
+func TestNewDownloadSigner_DefaultTTL(t *testing.T) {
+	assert.Equal(t, DefaultDownloadURLTTL, NewDownloadSigner("", "secret", 0).TTL())
+}
+
+func TestWebhookEventCatalog_ArtifactEvents(t *testing.T) {
+	events := make(map[WebhookEvent]bool)
+	for _, info := range WebhookEventCatalog {
+		assert.NotEmpty(t, info.Description)
+		events[info.Event] = true
+	}
+
+	assert.True(t, events[WebhookEventExportCompleted])
+	assert.True(t, events[WebhookEventBackupCompleted])
+	assert.True(t, events[WebhookEventBackupFailed])
+}
+
+func (suite *AutomationServiceTestSuite) TestNotifyBackupFinished_Completed() {
+	// Given: An endpoint subscribed to backup events and a completed backup
+	endpoint, err := suite.GetTestService().CreateWebhookEndpoint(suite.GetTestUserID(), WebhookEndpointRequest{
+		Name:   "NAS sync",
+		URL:    "https://nas.example.com/hook",
+		Events: []string{"backup.completed", "backup.failed"},
+	})
+	suite.Require().NoError(err)
+
+	job := &BackupJob{UserID: suite.GetTestUserID(), Type: "full", Status: "completed", FilePath: "/tmp/backup.tar.gz"}
+	suite.Require().NoError(suite.GetTestDB().Create(job).Error)
+
+	// When: Notifying about the finished backup
+	suite.NoError(suite.GetTestService().notifyBackupFinished(context.Background(), job))
+
+	// Then: A backup.completed delivery with a resolvable download URL is recorded
+	deliveries, err := suite.GetTestService().GetWebhookDeliveries(suite.GetTestUserID(), endpoint.ID)
+	suite.Require().NoError(err)
+	suite.Require().Len(deliveries, 1)
+	suite.Equal(WebhookEventBackupCompleted, deliveries[0].Event)
+
+	data, ok := deliveries[0].Payload["data"].(map[string]interface{})
+	suite.Require().True(ok)
+	suite.NotEmpty(data["expires_at"])
+
+	link, err := url.Parse(data["download_url"].(string))
+	suite.Require().NoError(err)
+	filePath, err := suite.GetTestService().ResolveDownload(ArtifactBackup, job.ID, link.Query().Get("expires"), link.Query().Get("signature"))
+	suite.NoError(err)
+	suite.Equal(job.FilePath, filePath)
+}
+
+func (suite *AutomationServiceTestSuite) TestNotifyBackupFinished_Failed() {
+	// Given: An endpoint subscribed to backup.failed and a failed backup
+	endpoint, err := suite.GetTestService().CreateWebhookEndpoint(suite.GetTestUserID(), WebhookEndpointRequest{
+		Name:   "Alerts",
+		URL:    "https://alerts.example.com/hook",
+		Events: []string{"backup.failed"},
+	})
+	suite.Require().NoError(err)
+
+	job := &BackupJob{UserID: suite.GetTestUserID(), Type: "full", Status: "failed", Error: "disk full"}
+	suite.Require().NoError(suite.GetTestDB().Create(job).Error)
+
+	// When: Notifying about the finished backup
+	suite.NoError(suite.GetTestService().notifyBackupFinished(context.Background(), job))
+
+	// Then: A backup.failed delivery without a download URL is recorded
+	deliveries, err := suite.GetTestService().GetWebhookDeliveries(suite.GetTestUserID(), endpoint.ID)
+	suite.Require().NoError(err)
+	suite.Require().Len(deliveries, 1)
+	suite.Equal(WebhookEventBackupFailed, deliveries[0].Event)
+
+	data := deliveries[0].Payload["data"].(map[string]interface{})
+	suite.Equal("disk full", data["error"])
+	suite.NotContains(data, "download_url")
+}
+
+func (suite *AutomationServiceTestSuite) TestNotifyExportCompleted() {
+	// Given: An endpoint subscribed to export.completed and a finished export
+	endpoint, err := suite.GetTestService().CreateWebhookEndpoint(suite.GetTestUserID(), WebhookEndpointRequest{
+		Name:   "Exports",
+		URL:    "https://nas.example.com/hook",
+		Events: []string{"export.completed"},
+	})
+	suite.Require().NoError(err)
+
+	operation := &BulkOperation{
+		UserID:     suite.GetTestUserID(),
+		Type:       "export",
+		Status:     "completed",
+		TotalItems: 5,
+		Result:     InterfaceMap{"file_path": "/tmp/export.json"},
+	}
+	suite.Require().NoError(suite.GetTestDB().Create(operation).Error)
+
+	// When: Notifying about the export
+	suite.NoError(suite.GetTestService().notifyExportCompleted(context.Background(), operation))
+
+	// Then: The delivery carries a download URL for the export file
+	deliveries, err := suite.GetTestService().GetWebhookDeliveries(suite.GetTestUserID(), endpoint.ID)
+	suite.Require().NoError(err)
+	suite.Require().Len(deliveries, 1)
+
+	data := deliveries[0].Payload["data"].(map[string]interface{})
+	link, err := url.Parse(data["download_url"].(string))
+	suite.Require().NoError(err)
+	filePath, err := suite.GetTestService().ResolveDownload(ArtifactExport, operation.ID, link.Query().Get("expires"), link.Query().Get("signature"))
+	suite.NoError(err)
+	suite.Equal("/tmp/export.json", filePath)
+}
+
+func (suite *AutomationServiceTestSuite) TestResolveDownload_NotCompleted() {
+	// Given: A backup that is still running and a validly signed link for it
+	job := &BackupJob{UserID: suite.GetTestUserID(), Type: "full", Status: "running"}
+	suite.Require().NoError(suite.GetTestDB().Create(job).Error)
+
+	signer := NewDownloadSigner("", "secret", time.Minute)
+	suite.GetTestService().SetDownloadSigner(signer)
+	link, _ := signer.Sign(ArtifactBackup, job.ID, time.Now())
+	parsed, _ := url.Parse(link)
+
+	// When: Resolving the link
+	_, err := suite.GetTestService().ResolveDownload(ArtifactBackup, job.ID, parsed.Query().Get("expires"), parsed.Query().Get("signature"))
+
+	// Then: The artifact is not available yet
+	suite.ErrorIs(err, ErrBackupFileNotFound)
+}
+
+func (suite *AutomationHandlerTestSuite) TestGetWebhookEvents() {
+	// When: Requesting the event catalog
+	w := suite.makeRequest("GET", "/api/v1/automation/webhooks/events", nil)
+
+	// Then: The catalog includes the artifact events
+	suite.Equal(http.StatusOK, w.Code)
+	suite.Contains(w.Body.String(), `"backup.completed"`)
+	suite.Contains(w.Body.String(), `"export.completed"`)
+}
+
+func (suite *AutomationHandlerTestSuite) TestDownloadArtifact_InvalidSignature() {
+	// When: Requesting a download with a forged signature
+	w := suite.makeRequest("GET", "/api/v1/downloads/backup/1?expires=9999999999&signature=forged", nil)
+
+	// Then: The request is rejected
+	suite.Equal(http.StatusForbidden, w.Code)
+}
@@ -1,6 +1,11 @@
 package automation
 
-import "errors"
+import (
+	"errors"
+	"net/http"
+
+	"bookmark-sync-service/backend/pkg/apperrors"
+)
 
 // Common automation errors
 var (
@@ -12,6 +17,11 @@ var (
 	ErrWebhookTimeout          = errors.New("webhook request timeout")
 	ErrWebhookInvalidURL       = errors.New("invalid webhook URL")
 	ErrWebhookInvalidEvent     = errors.New("invalid webhook event")
+	ErrWebhookTestFailed       = errors.New("webhook test delivery failed")
+	ErrWebhookInvalidTemplate  = errors.New("invalid webhook payload template")
+	ErrWebhookEndpointInactive = errors.New("webhook endpoint is inactive")
+	ErrWebhookCircuitOpen      = errors.New("webhook endpoint circuit is open")
+	ErrWebhookInvalidWindow    = errors.New("invalid stats window, use 1h, 24h, 7d or 30d")
 
 	// RSS Feed errors
 	ErrRSSFeedNotFound         = errors.New("RSS feed not found")
@@ -29,24 +39,54 @@ var (
 	ErrBulkOperationInvalidType   = errors.New("invalid bulk operation type")
 	ErrBulkOperationInvalidParams = errors.New("invalid bulk operation parameters")
 
+	// Import upload errors
+	ErrImportUploadsDisabled  = errors.New("import uploads are not configured")
+	ErrImportUploadNotFound   = errors.New("import upload not found")
+	ErrImportUploadUsed       = errors.New("import upload was already imported")
+	ErrImportUploadIncomplete = errors.New("import file has not been uploaded yet")
+	ErrImportUploadTooLarge   = errors.New("import file is too large")
+	ErrImportUploadSize       = errors.New("import file size is missing or does not match the file")
+	ErrImportObjectNotFound   = errors.New("object not found in storage")
+
+	// Import row errors
+	ErrImportRowsDisabled   = errors.New("row imports are not configured")
+	ErrImportRowsInvalid    = errors.New("invalid import rows")
+	ErrImportNoFailedRows   = errors.New("import has no failed rows to retry")
+	ErrImportAlreadyRetried = errors.New("failed rows of this import were already retried")
+
 	// Backup Job errors
 	ErrBackupJobNotFound    = errors.New("backup job not found")
 	ErrBackupJobInProgress  = errors.New("backup job already in progress")
 	ErrBackupJobCompleted   = errors.New("backup job already completed")
 	ErrBackupJobFailed      = errors.New("backup job failed")
+	ErrBackupJobNotComplete = errors.New("backup job not completed")
 	ErrBackupJobInvalidType = errors.New("invalid backup job type")
 	ErrBackupFileNotFound   = errors.New("backup file not found")
 	ErrBackupFileCorrupted  = errors.New("backup file is corrupted")
+	ErrBackupVolumeSize     = errors.New("backup volume size is below the minimum")
+	ErrBackupVolumeNotFound = errors.New("backup volume not found")
+
+	// Backup destination errors
+	ErrBackupDestinationNotFound    = errors.New("backup destination not found")
+	ErrBackupDestinationInactive    = errors.New("backup destination is inactive")
+	ErrBackupDestinationRejected    = errors.New("backup destination rejected the request")
+	ErrBackupDestinationUnreachable = errors.New("backup destination is unreachable")
+	ErrCredentialEncryptionDisabled = errors.New("credential encryption is not configured")
+
+	// Download link errors
+	ErrDownloadLinkInvalid = errors.New("invalid download link")
+	ErrDownloadLinkExpired = errors.New("download link has expired")
 
 	// API Integration errors
-	ErrAPIIntegrationNotFound    = errors.New("API integration not found")
-	ErrAPIIntegrationExists      = errors.New("API integration already exists")
-	ErrAPIIntegrationInactive    = errors.New("API integration is inactive")
-	ErrAPIIntegrationAuthFailed  = errors.New("API integration authentication failed")
-	ErrAPIIntegrationRateLimit   = errors.New("API integration rate limit exceeded")
-	ErrAPIIntegrationTimeout     = errors.New("API integration request timeout")
-	ErrAPIIntegrationInvalidType = errors.New("invalid API integration type")
-	ErrAPIIntegrationSyncFailed  = errors.New("API integration sync failed")
+	ErrAPIIntegrationNotFound        = errors.New("API integration not found")
+	ErrAPIIntegrationExists          = errors.New("API integration already exists")
+	ErrAPIIntegrationInactive        = errors.New("API integration is inactive")
+	ErrAPIIntegrationAuthFailed      = errors.New("API integration authentication failed")
+	ErrAPIIntegrationRateLimit       = errors.New("API integration rate limit exceeded")
+	ErrAPIIntegrationTimeout         = errors.New("API integration request timeout")
+	ErrAPIIntegrationInvalidType     = errors.New("invalid API integration type")
+	ErrAPIIntegrationSyncFailed      = errors.New("API integration sync failed")
+	ErrAPIIntegrationSyncUnsupported = errors.New("API integration type cannot be synced")
 
 	// Automation Rule errors
 	ErrAutomationRuleNotFound         = errors.New("automation rule not found")
@@ -56,6 +96,7 @@ var (
 	ErrAutomationRuleInvalidCondition = errors.New("invalid automation rule condition")
 	ErrAutomationRuleInvalidAction    = errors.New("invalid automation rule action")
 	ErrAutomationRuleExecutionFailed  = errors.New("automation rule execution failed")
+	ErrAutomationRuleInvalidSchedule  = errors.New("invalid automation rule schedule")
 
 	// General errors
 	ErrUserNotAuthenticated = errors.New("user not authenticated")
@@ -82,6 +123,10 @@ const (
 	CodeWebhookTimeout          ErrorCode = "WEBHOOK_TIMEOUT"
 	CodeWebhookInvalidURL       ErrorCode = "WEBHOOK_INVALID_URL"
 	CodeWebhookInvalidEvent     ErrorCode = "WEBHOOK_INVALID_EVENT"
+	CodeWebhookTestFailed       ErrorCode = "WEBHOOK_TEST_FAILED"
+	CodeWebhookInvalidTemplate  ErrorCode = "WEBHOOK_INVALID_TEMPLATE"
+	CodeWebhookEndpointInactive ErrorCode = "WEBHOOK_ENDPOINT_INACTIVE"
+	CodeWebhookCircuitOpen      ErrorCode = "WEBHOOK_CIRCUIT_OPEN"
 
 	// RSS Feed error codes
 	CodeRSSFeedNotFound         ErrorCode = "RSS_FEED_NOT_FOUND"
@@ -108,15 +153,27 @@ const (
 	CodeBackupFileNotFound   ErrorCode = "BACKUP_FILE_NOT_FOUND"
 	CodeBackupFileCorrupted  ErrorCode = "BACKUP_FILE_CORRUPTED"
 
+	// Backup destination error codes
+	CodeBackupDestinationNotFound    ErrorCode = "BACKUP_DESTINATION_NOT_FOUND"
+	CodeBackupDestinationInactive    ErrorCode = "BACKUP_DESTINATION_INACTIVE"
+	CodeBackupDestinationRejected    ErrorCode = "BACKUP_DESTINATION_REJECTED"
+	CodeBackupDestinationUnreachable ErrorCode = "BACKUP_DESTINATION_UNREACHABLE"
+	CodeCredentialEncryptionDisabled ErrorCode = "CREDENTIAL_ENCRYPTION_DISABLED"
+
+	// Download link error codes
+	CodeDownloadLinkInvalid ErrorCode = "DOWNLOAD_LINK_INVALID"
+	CodeDownloadLinkExpired ErrorCode = "DOWNLOAD_LINK_EXPIRED"
+
 	// API Integration error codes
-	CodeAPIIntegrationNotFound    ErrorCode = "API_INTEGRATION_NOT_FOUND"
-	CodeAPIIntegrationExists      ErrorCode = "API_INTEGRATION_EXISTS"
-	CodeAPIIntegrationInactive    ErrorCode = "API_INTEGRATION_INACTIVE"
-	CodeAPIIntegrationAuthFailed  ErrorCode = "API_INTEGRATION_AUTH_FAILED"
-	CodeAPIIntegrationRateLimit   ErrorCode = "API_INTEGRATION_RATE_LIMIT"
-	CodeAPIIntegrationTimeout     ErrorCode = "API_INTEGRATION_TIMEOUT"
-	CodeAPIIntegrationInvalidType ErrorCode = "API_INTEGRATION_INVALID_TYPE"
-	CodeAPIIntegrationSyncFailed  ErrorCode = "API_INTEGRATION_SYNC_FAILED"
+	CodeAPIIntegrationNotFound        ErrorCode = "API_INTEGRATION_NOT_FOUND"
+	CodeAPIIntegrationExists          ErrorCode = "API_INTEGRATION_EXISTS"
+	CodeAPIIntegrationInactive        ErrorCode = "API_INTEGRATION_INACTIVE"
+	CodeAPIIntegrationAuthFailed      ErrorCode = "API_INTEGRATION_AUTH_FAILED"
+	CodeAPIIntegrationRateLimit       ErrorCode = "API_INTEGRATION_RATE_LIMIT"
+	CodeAPIIntegrationTimeout         ErrorCode = "API_INTEGRATION_TIMEOUT"
+	CodeAPIIntegrationInvalidType     ErrorCode = "API_INTEGRATION_INVALID_TYPE"
+	CodeAPIIntegrationSyncFailed      ErrorCode = "API_INTEGRATION_SYNC_FAILED"
+	CodeAPIIntegrationSyncUnsupported ErrorCode = "API_INTEGRATION_SYNC_UNSUPPORTED"
 
 	// Automation Rule error codes
 	CodeAutomationRuleNotFound         ErrorCode = "AUTOMATION_RULE_NOT_FOUND"
@@ -126,6 +183,7 @@ const (
 	CodeAutomationRuleInvalidCondition ErrorCode = "AUTOMATION_RULE_INVALID_CONDITION"
 	CodeAutomationRuleInvalidAction    ErrorCode = "AUTOMATION_RULE_INVALID_ACTION"
 	CodeAutomationRuleExecutionFailed  ErrorCode = "AUTOMATION_RULE_EXECUTION_FAILED"
+	CodeAutomationRuleInvalidSchedule  ErrorCode = "AUTOMATION_RULE_INVALID_SCHEDULE"
 
 	// General error codes
 	CodeUserNotAuthenticated ErrorCode = "USER_NOT_AUTHENTICATED"
@@ -140,6 +198,111 @@ const (
 	CodeNetworkError         ErrorCode = "NETWORK_ERROR"
 )
 
+// Stable codes and HTTP statuses of the automation errors, answered by
+// respondError
+func init() {
+	for _, entry := range []struct {
+		err    error
+		code   ErrorCode
+		status int
+	}{
+		{ErrWebhookEndpointNotFound, CodeWebhookEndpointNotFound, http.StatusNotFound},
+		{ErrWebhookEndpointExists, CodeWebhookEndpointExists, http.StatusConflict},
+		{ErrWebhookDeliveryFailed, CodeWebhookDeliveryFailed, http.StatusBadGateway},
+		{ErrWebhookInvalidSignature, CodeWebhookInvalidSignature, http.StatusUnauthorized},
+		{ErrWebhookTimeout, CodeWebhookTimeout, http.StatusGatewayTimeout},
+		{ErrWebhookInvalidURL, CodeWebhookInvalidURL, http.StatusBadRequest},
+		{ErrWebhookInvalidEvent, CodeWebhookInvalidEvent, http.StatusBadRequest},
+		{ErrWebhookTestFailed, CodeWebhookTestFailed, http.StatusUnprocessableEntity},
+		{ErrWebhookInvalidTemplate, CodeWebhookInvalidTemplate, http.StatusBadRequest},
+		{ErrWebhookEndpointInactive, CodeWebhookEndpointInactive, http.StatusConflict},
+		{ErrWebhookCircuitOpen, CodeWebhookCircuitOpen, http.StatusConflict},
+		{ErrWebhookInvalidWindow, "WEBHOOK_INVALID_WINDOW", http.StatusBadRequest},
+		{ErrPayloadTooLarge, "WEBHOOK_PAYLOAD_TOO_LARGE", http.StatusBadRequest},
+
+		{ErrRSSFeedNotFound, CodeRSSFeedNotFound, http.StatusNotFound},
+		{ErrRSSFeedExists, CodeRSSFeedExists, http.StatusConflict},
+		{ErrRSSFeedInvalidPublicKey, CodeRSSFeedInvalidPublicKey, http.StatusNotFound},
+		{ErrRSSFeedGenerationFailed, CodeRSSFeedGenerationFailed, http.StatusInternalServerError},
+		{ErrRSSFeedInactive, CodeRSSFeedInactive, http.StatusGone},
+
+		{ErrBulkOperationNotFound, CodeBulkOperationNotFound, http.StatusNotFound},
+		{ErrBulkOperationInProgress, CodeBulkOperationInProgress, http.StatusConflict},
+		{ErrBulkOperationCompleted, CodeBulkOperationCompleted, http.StatusConflict},
+		{ErrBulkOperationCancelled, CodeBulkOperationCancelled, http.StatusConflict},
+		{ErrBulkOperationFailed, CodeBulkOperationFailed, http.StatusInternalServerError},
+		{ErrBulkOperationInvalidType, CodeBulkOperationInvalidType, http.StatusBadRequest},
+		{ErrBulkOperationInvalidParams, CodeBulkOperationInvalidParams, http.StatusBadRequest},
+
+		{ErrImportUploadsDisabled, "IMPORT_UPLOADS_DISABLED", http.StatusServiceUnavailable},
+		{ErrImportUploadNotFound, "IMPORT_UPLOAD_NOT_FOUND", http.StatusNotFound},
+		{ErrImportUploadUsed, "IMPORT_UPLOAD_USED", http.StatusConflict},
+		{ErrImportUploadIncomplete, "IMPORT_UPLOAD_INCOMPLETE", http.StatusConflict},
+		{ErrImportUploadTooLarge, "IMPORT_UPLOAD_TOO_LARGE", http.StatusRequestEntityTooLarge},
+		{ErrImportUploadSize, "IMPORT_UPLOAD_SIZE", http.StatusBadRequest},
+		{ErrImportObjectNotFound, "IMPORT_OBJECT_NOT_FOUND", http.StatusNotFound},
+		{ErrImportRowsDisabled, "IMPORT_ROWS_DISABLED", http.StatusServiceUnavailable},
+		{ErrImportRowsInvalid, "IMPORT_ROWS_INVALID", http.StatusBadRequest},
+		{ErrImportNoFailedRows, "IMPORT_NO_FAILED_ROWS", http.StatusConflict},
+		{ErrImportAlreadyRetried, "IMPORT_ALREADY_RETRIED", http.StatusConflict},
+
+		{ErrBackupJobNotFound, CodeBackupJobNotFound, http.StatusNotFound},
+		{ErrBackupJobInProgress, CodeBackupJobInProgress, http.StatusConflict},
+		{ErrBackupJobCompleted, CodeBackupJobCompleted, http.StatusConflict},
+		{ErrBackupJobFailed, CodeBackupJobFailed, http.StatusInternalServerError},
+		{ErrBackupJobNotComplete, "BACKUP_JOB_NOT_COMPLETE", http.StatusConflict},
+		{ErrBackupJobInvalidType, CodeBackupJobInvalidType, http.StatusBadRequest},
+		{ErrBackupFileNotFound, CodeBackupFileNotFound, http.StatusNotFound},
+		{ErrBackupFileCorrupted, CodeBackupFileCorrupted, http.StatusUnprocessableEntity},
+		{ErrBackupVolumeSize, "BACKUP_VOLUME_SIZE", http.StatusBadRequest},
+		{ErrBackupVolumeNotFound, "BACKUP_VOLUME_NOT_FOUND", http.StatusNotFound},
+
+		{ErrBackupDestinationNotFound, CodeBackupDestinationNotFound, http.StatusNotFound},
+		{ErrBackupDestinationInactive, CodeBackupDestinationInactive, http.StatusBadRequest},
+		{ErrBackupDestinationRejected, CodeBackupDestinationRejected, http.StatusBadGateway},
+		{ErrBackupDestinationUnreachable, CodeBackupDestinationUnreachable, http.StatusBadGateway},
+		{ErrCredentialEncryptionDisabled, CodeCredentialEncryptionDisabled, http.StatusServiceUnavailable},
+
+		{ErrDownloadLinkInvalid, CodeDownloadLinkInvalid, http.StatusForbidden},
+		{ErrDownloadLinkExpired, CodeDownloadLinkExpired, http.StatusForbidden},
+
+		{ErrAPIIntegrationNotFound, CodeAPIIntegrationNotFound, http.StatusNotFound},
+		{ErrAPIIntegrationExists, CodeAPIIntegrationExists, http.StatusConflict},
+		{ErrAPIIntegrationInactive, CodeAPIIntegrationInactive, http.StatusConflict},
+		{ErrAPIIntegrationAuthFailed, CodeAPIIntegrationAuthFailed, http.StatusBadGateway},
+		{ErrAPIIntegrationRateLimit, CodeAPIIntegrationRateLimit, http.StatusTooManyRequests},
+		{ErrAPIIntegrationTimeout, CodeAPIIntegrationTimeout, http.StatusGatewayTimeout},
+		{ErrAPIIntegrationInvalidType, CodeAPIIntegrationInvalidType, http.StatusBadRequest},
+		{ErrAPIIntegrationSyncFailed, CodeAPIIntegrationSyncFailed, http.StatusBadGateway},
+		{ErrAPIIntegrationSyncUnsupported, CodeAPIIntegrationSyncUnsupported, http.StatusNotImplemented},
+
+		{ErrAutomationRuleNotFound, CodeAutomationRuleNotFound, http.StatusNotFound},
+		{ErrAutomationRuleExists, CodeAutomationRuleExists, http.StatusConflict},
+		{ErrAutomationRuleInactive, CodeAutomationRuleInactive, http.StatusConflict},
+		{ErrAutomationRuleInvalidTrigger, CodeAutomationRuleInvalidTrigger, http.StatusBadRequest},
+		{ErrAutomationRuleInvalidCondition, CodeAutomationRuleInvalidCondition, http.StatusBadRequest},
+		{ErrAutomationRuleInvalidAction, CodeAutomationRuleInvalidAction, http.StatusBadRequest},
+		{ErrAutomationRuleExecutionFailed, CodeAutomationRuleExecutionFailed, http.StatusInternalServerError},
+		{ErrAutomationRuleInvalidSchedule, CodeAutomationRuleInvalidSchedule, http.StatusBadRequest},
+
+		{ErrTeamNotMember, "TEAM_NOT_MEMBER", http.StatusForbidden},
+		{ErrTeamRoleRequired, "TEAM_ROLE_REQUIRED", http.StatusForbidden},
+
+		{ErrUserNotAuthenticated, CodeUserNotAuthenticated, http.StatusUnauthorized},
+		{ErrUserNotAuthorized, CodeUserNotAuthorized, http.StatusForbidden},
+		{ErrInvalidRequest, CodeInvalidRequest, http.StatusBadRequest},
+		{ErrInvalidParameters, CodeInvalidParameters, http.StatusBadRequest},
+		{ErrResourceNotFound, CodeResourceNotFound, http.StatusNotFound},
+		{ErrResourceExists, CodeResourceExists, http.StatusConflict},
+		{ErrInternalServerError, CodeInternalServerError, http.StatusInternalServerError},
+		{ErrServiceUnavailable, CodeServiceUnavailable, http.StatusServiceUnavailable},
+		{ErrDatabaseError, CodeDatabaseError, http.StatusInternalServerError},
+		{ErrNetworkError, CodeNetworkError, http.StatusBadGateway},
+	} {
+		apperrors.Register(entry.err, apperrors.Code(entry.code), entry.status)
+	}
+}
+
 // AutomationError represents a structured error for the automation service
 type AutomationError struct {
 	Code    ErrorCode `json:"code"`
@@ -186,6 +349,14 @@ func MapWebhookError(err error) *AutomationError {
 		return NewAutomationError(CodeWebhookInvalidURL, "Invalid webhook URL")
 	case ErrWebhookInvalidEvent:
 		return NewAutomationError(CodeWebhookInvalidEvent, "Invalid webhook event")
+	case ErrWebhookTestFailed:
+		return NewAutomationError(CodeWebhookTestFailed, "Webhook test delivery failed")
+	case ErrWebhookInvalidTemplate:
+		return NewAutomationError(CodeWebhookInvalidTemplate, "Invalid webhook payload template")
+	case ErrWebhookEndpointInactive:
+		return NewAutomationError(CodeWebhookEndpointInactive, "Webhook endpoint is inactive")
+	case ErrWebhookCircuitOpen:
+		return NewAutomationError(CodeWebhookCircuitOpen, "Webhook endpoint circuit is open")
 	default:
 		return NewAutomationError(CodeInternalServerError, "Internal server error", err.Error())
 	}
@@ -253,6 +424,25 @@ func MapBackupJobError(err error) *AutomationError {
 	}
 }
 
+// MapBackupDestinationError maps backup destination errors to structured errors,
+// keeping the destination's own explanation as details
+func MapBackupDestinationError(err error) *AutomationError {
+	switch {
+	case errors.Is(err, ErrBackupDestinationNotFound):
+		return NewAutomationError(CodeBackupDestinationNotFound, "Backup destination not found")
+	case errors.Is(err, ErrBackupDestinationInactive):
+		return NewAutomationError(CodeBackupDestinationInactive, "Backup destination is inactive")
+	case errors.Is(err, ErrBackupDestinationRejected):
+		return NewAutomationError(CodeBackupDestinationRejected, "Backup destination rejected the request", err.Error())
+	case errors.Is(err, ErrBackupDestinationUnreachable):
+		return NewAutomationError(CodeBackupDestinationUnreachable, "Backup destination is unreachable", err.Error())
+	case errors.Is(err, ErrCredentialEncryptionDisabled):
+		return NewAutomationError(CodeCredentialEncryptionDisabled, "Credential encryption is not configured")
+	default:
+		return NewAutomationError(CodeInternalServerError, "Internal server error", err.Error())
+	}
+}
+
 // MapAPIIntegrationError maps API integration errors to structured errors
 func MapAPIIntegrationError(err error) *AutomationError {
 	switch err {
@@ -272,6 +462,8 @@ func MapAPIIntegrationError(err error) *AutomationError {
 		return NewAutomationError(CodeAPIIntegrationInvalidType, "Invalid API integration type")
 	case ErrAPIIntegrationSyncFailed:
 		return NewAutomationError(CodeAPIIntegrationSyncFailed, "API integration sync failed")
+	case ErrAPIIntegrationSyncUnsupported:
+		return NewAutomationError(CodeAPIIntegrationSyncUnsupported, "API integration type cannot be synced")
 	default:
 		return NewAutomationError(CodeInternalServerError, "Internal server error", err.Error())
 	}
@@ -294,6 +486,8 @@ func MapAutomationRuleError(err error) *AutomationError {
 		return NewAutomationError(CodeAutomationRuleInvalidAction, "Invalid automation rule action")
 	case ErrAutomationRuleExecutionFailed:
 		return NewAutomationError(CodeAutomationRuleExecutionFailed, "Automation rule execution failed")
+	case ErrAutomationRuleInvalidSchedule:
+		return NewAutomationError(CodeAutomationRuleInvalidSchedule, "Invalid automation rule schedule")
 	default:
 		return NewAutomationError(CodeInternalServerError, "Internal server error", err.Error())
 	}
@@ -0,0 +1,130 @@
+package automation
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"bookmark-sync-service/backend/pkg/exporter"
+)
+
+// defaultExportFormat is used when an export operation names no format
+const defaultExportFormat = "json"
+
+// SetExportDir sets where export operations write their files. Exports go
+// to the system temporary directory by default
+func (s *Service) SetExportDir(dir string) {
+	s.exportDir = dir
+}
+
+// ExportFormats lists the formats export operations can produce
+func (s *Service) ExportFormats() []exporter.Format {
+	return exporter.Formats()
+}
+
+// exportFormat returns the registered format an export operation asks for
+func exportFormat(parameters map[string]interface{}) (exporter.Format, error) {
+	name := defaultExportFormat
+	if value, ok := parameters["format"]; ok {
+		format, isString := value.(string)
+		if !isString || format == "" {
+			return exporter.Format{}, ErrBulkOperationInvalidParams
+		}
+		name = format
+	}
+
+	format, err := exporter.Lookup(name)
+	if err != nil {
+		return exporter.Format{}, fmt.Errorf("%w: %v", ErrBulkOperationInvalidParams, err)
+	}
+	return format, nil
+}
+
+// exportBookmarkIDs returns the bookmarks an export operation is limited to,
+// e.g. the results of a search. ok is false when it exports everything
+func exportBookmarkIDs(parameters map[string]interface{}) (ids []uint, ok bool, err error) {
+	value, ok := parameters["bookmark_ids"]
+	if !ok {
+		return nil, false, nil
+	}
+
+	switch list := value.(type) {
+	case []uint:
+		return list, true, nil
+	case []interface{}:
+		// Parameters decoded from JSON hold numbers as float64
+		ids = make([]uint, 0, len(list))
+		for _, item := range list {
+			id, isNumber := item.(float64)
+			if !isNumber || id < 1 || id != float64(uint(id)) {
+				return nil, false, ErrBulkOperationInvalidParams
+			}
+			ids = append(ids, uint(id))
+		}
+		return ids, true, nil
+	default:
+		return nil, false, ErrBulkOperationInvalidParams
+	}
+}
+
+// processBulkExport writes the user's bookmarks to a file with the
+// requested exporter, leaving its path in the operation result
+func (s *Service) processBulkExport(operation *BulkOperation) error {
+	format, err := exportFormat(operation.Parameters)
+	if err != nil {
+		return err
+	}
+
+	userID, err := strconv.ParseUint(operation.UserID, 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid user ID %q: %w", operation.UserID, err)
+	}
+
+	ids, selected, err := exportBookmarkIDs(operation.Parameters)
+	if err != nil {
+		return err
+	}
+
+	data, err := exporter.Load(context.Background(), s.db, uint(userID))
+	if err != nil {
+		return err
+	}
+	if selected {
+		data = data.Only(ids)
+	}
+	operation.TotalItems = len(data.Bookmarks)
+
+	root := s.exportDir
+	if root == "" {
+		root = filepath.Join(os.TempDir(), "exports")
+	}
+	dir := filepath.Join(root, operation.UserID)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return fmt.Errorf("failed to create export directory: %w", err)
+	}
+
+	filePath := filepath.Join(dir, fmt.Sprintf("export_%d.%s", operation.ID, format.Extension))
+	file, err := os.Create(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to create export file: %w", err)
+	}
+	if err := format.Exporter.Export(file, data); err != nil {
+		file.Close()
+		os.Remove(filePath)
+		return fmt.Errorf("failed to export %s: %w", format.Name, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write export file: %w", err)
+	}
+
+	operation.ProcessedItems = operation.TotalItems
+	if operation.Result == nil {
+		operation.Result = InterfaceMap{}
+	}
+	operation.Result["file_path"] = filePath
+	operation.Result["format"] = format.Name
+	operation.Result["content_type"] = format.ContentType
+	return nil
+}
@@ -0,0 +1,94 @@
+package automation
+
+import (
+	"net/http"
+	"os"
+	"strings"
+)
+
+func (suite *AutomationServiceTestSuite) TestCreateBulkOperation_UnknownExportFormat() {
+	// Given: An export asking for a format no exporter is registered for
+	req := BulkOperationRequest{Type: "export", Parameters: map[string]interface{}{"format": "pdf"}}
+
+	// When: Creating the operation
+	_, err := suite.GetTestService().CreateBulkOperation(suite.GetTestUserID(), req)
+
+	// Then: It is rejected up front
+	suite.ErrorIs(err, ErrBulkOperationInvalidParams)
+}
+
+func (suite *AutomationServiceTestSuite) TestProcessBulkExport_UsesRegisteredFormat() {
+	// Given: A user with a bookmark in a collection
+	db := suite.GetTestDB()
+	for _, statement := range []string{
+		`CREATE TABLE bookmarks (id INTEGER PRIMARY KEY, user_id INTEGER, url TEXT, title TEXT, description TEXT, favicon TEXT, screenshot TEXT, language TEXT, notes TEXT, tags TEXT, metadata TEXT, status TEXT, created_at DATETIME, updated_at DATETIME, deleted_at DATETIME)`,
+		`CREATE TABLE collections (id INTEGER PRIMARY KEY, user_id INTEGER, name TEXT, description TEXT, parent_id INTEGER, visibility TEXT, created_at DATETIME, updated_at DATETIME, deleted_at DATETIME)`,
+		`CREATE TABLE bookmark_collections (bookmark_id INTEGER, collection_id INTEGER)`,
+		`INSERT INTO bookmarks (id, user_id, url, title, tags, status) VALUES (1, 7, 'https://go.dev', 'Go', '["golang"]', 'active')`,
+		`INSERT INTO collections (id, user_id, name, visibility) VALUES (1, 7, 'Languages', 'private')`,
+		`INSERT INTO bookmark_collections VALUES (1, 1)`,
+	} {
+		suite.Require().NoError(db.Exec(statement).Error)
+	}
+	suite.GetTestService().SetExportDir(suite.T().TempDir())
+
+	// When: Running an Org-mode export
+	operation := &BulkOperation{ID: 3, UserID: "7", Type: "export", Parameters: InterfaceMap{"format": "org"}}
+	suite.Require().NoError(suite.GetTestService().processBulkExport(operation))
+
+	// Then: The file is written by the org exporter
+	suite.Equal("org", operation.Result["format"])
+	suite.Equal(1, operation.TotalItems)
+	filePath := operation.Result["file_path"].(string)
+	suite.True(strings.HasSuffix(filePath, "export_3.org"))
+
+	content, err := os.ReadFile(filePath)
+	suite.Require().NoError(err)
+	suite.Contains(string(content), "* Languages\n** [[https://go.dev][Go]] :golang:")
+}
+
+func (suite *AutomationServiceTestSuite) TestProcessBulkExport_LimitsToBookmarkIDs() {
+	// Given: A user with two bookmarks
+	db := suite.GetTestDB()
+	for _, statement := range []string{
+		`CREATE TABLE bookmarks (id INTEGER PRIMARY KEY, user_id INTEGER, url TEXT, title TEXT, description TEXT, favicon TEXT, screenshot TEXT, language TEXT, notes TEXT, tags TEXT, metadata TEXT, status TEXT, created_at DATETIME, updated_at DATETIME, deleted_at DATETIME)`,
+		`CREATE TABLE collections (id INTEGER PRIMARY KEY, user_id INTEGER, name TEXT, description TEXT, parent_id INTEGER, visibility TEXT, created_at DATETIME, updated_at DATETIME, deleted_at DATETIME)`,
+		`CREATE TABLE bookmark_collections (bookmark_id INTEGER, collection_id INTEGER)`,
+		`INSERT INTO bookmarks (id, user_id, url, title, tags, status) VALUES (1, 7, 'https://go.dev', 'Go', '[]', 'active')`,
+		`INSERT INTO bookmarks (id, user_id, url, title, tags, status) VALUES (2, 7, 'https://rust-lang.org', 'Rust', '[]', 'active')`,
+	} {
+		suite.Require().NoError(db.Exec(statement).Error)
+	}
+	suite.GetTestService().SetExportDir(suite.T().TempDir())
+
+	// When: Exporting only the second one, with IDs as decoded from JSON
+	operation := &BulkOperation{ID: 4, UserID: "7", Type: "export", Parameters: InterfaceMap{
+		"format":       "csv",
+		"bookmark_ids": []interface{}{float64(2)},
+	}}
+	suite.Require().NoError(suite.GetTestService().processBulkExport(operation))
+
+	// Then: The file holds just that bookmark
+	suite.Equal(1, operation.TotalItems)
+	content, err := os.ReadFile(operation.Result["file_path"].(string))
+	suite.Require().NoError(err)
+	suite.Contains(string(content), "https://rust-lang.org")
+	suite.NotContains(string(content), "https://go.dev")
+
+	// And: Malformed IDs are rejected when the operation is created
+	_, err = suite.GetTestService().CreateBulkOperation(suite.GetTestUserID(), BulkOperationRequest{
+		Type: "export", Parameters: map[string]interface{}{"bookmark_ids": "1,2"},
+	})
+	suite.ErrorIs(err, ErrBulkOperationInvalidParams)
+}
+
+func (suite *AutomationHandlerTestSuite) TestGetExportFormats() {
+	// When: Requesting the export formats
+	w := suite.makeRequest("GET", "/api/v1/automation/export-formats", nil)
+
+	// Then: The built-in formats are listed
+	suite.Equal(http.StatusOK, w.Code)
+	for _, name := range []string{`"csv"`, `"json"`, `"html"`, `"markdown"`, `"org"`, `"obsidian"`} {
+		suite.Contains(w.Body.String(), name)
+	}
+}
@@ -1,15 +1,30 @@
 package automation
 
 import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"mime"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+
+	"bookmark-sync-service/backend/pkg/apperrors"
+	"bookmark-sync-service/backend/pkg/middleware"
+	"bookmark-sync-service/backend/pkg/utils"
 )
 
 // Handler handles automation HTTP requests
 type Handler struct {
 	service *Service
+
+	// uploadEventToken authenticates bucket notifications; they are refused without one
+	uploadEventToken string
 }
 
 // NewHandler creates a new automation handler
@@ -19,7 +34,13 @@ func NewHandler(service *Service) *Handler {
 	}
 }
 
-// RegisterRoutes registers automation routes
+// SetUploadEventToken sets the bearer token object storage sends with bucket
+// notifications, e.g. the auth_token of a MinIO webhook target
+func (h *Handler) SetUploadEventToken(token string) {
+	h.uploadEventToken = token
+}
+
+// RegisterRoutes registers the automation routes on an authenticated group
 func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
 	automation := r.Group("/automation")
 	{
@@ -28,9 +49,16 @@ func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
 		{
 			webhooks.POST("", h.CreateWebhookEndpoint)
 			webhooks.GET("", h.GetWebhookEndpoints)
+			webhooks.GET("/events", h.GetWebhookEvents)
+			webhooks.POST("/preview", h.PreviewWebhookPayload)
 			webhooks.PUT("/:id", h.UpdateWebhookEndpoint)
 			webhooks.DELETE("/:id", h.DeleteWebhookEndpoint)
 			webhooks.GET("/:id/deliveries", h.GetWebhookDeliveries)
+			webhooks.GET("/:id/health", h.GetWebhookHealth)
+			webhooks.GET("/:id/stats", h.GetWebhookStats)
+			webhooks.POST("/:id/enable", h.EnableWebhookEndpoint)
+			webhooks.POST("/:id/replay", h.ReplayWebhookDeliveries)
+			webhooks.POST("/:id/promote", h.PromoteWebhookEndpoint)
 		}
 
 		// RSS feeds
@@ -46,11 +74,18 @@ func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
 		bulk := automation.Group("/bulk")
 		{
 			bulk.POST("", h.CreateBulkOperation)
+			bulk.POST("/upload-url", h.CreateImportUploadURL)
+			bulk.POST("/upload", middleware.BodyLimit(maxImportUploadSize+middleware.MultipartOverhead), h.UploadImportFile)
 			bulk.GET("", h.GetBulkOperations)
 			bulk.GET("/:id", h.GetBulkOperation)
 			bulk.DELETE("/:id", h.CancelBulkOperation)
+			bulk.GET("/:id/failed-rows", h.GetFailedImportRows)
+			bulk.POST("/:id/retry-failed", middleware.BodyLimit(maxImportRowsFileSize+middleware.MultipartOverhead), h.RetryFailedImportRows)
 		}
 
+		// Formats bulk export operations can produce
+		automation.GET("/export-formats", h.GetExportFormats)
+
 		// Backup jobs
 		backup := automation.Group("/backup")
 		{
@@ -58,6 +93,18 @@ func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
 			backup.GET("", h.GetBackupJobs)
 			backup.GET("/:id", h.GetBackupJob)
 			backup.GET("/:id/download", h.DownloadBackup)
+			backup.GET("/:id/download-links", h.GetBackupDownloadLinks)
+			backup.GET("/:id/volumes/:volume", h.DownloadBackupVolume)
+
+			// External backup destinations
+			destinations := backup.Group("/destinations")
+			{
+				destinations.POST("", h.CreateBackupDestination)
+				destinations.GET("", h.GetBackupDestinations)
+				destinations.PUT("/:id", h.UpdateBackupDestination)
+				destinations.DELETE("/:id", h.DeleteBackupDestination)
+				destinations.POST("/:id/test", h.TestBackupDestination)
+			}
 		}
 
 		// API integrations
@@ -68,6 +115,7 @@ func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
 			integrations.PUT("/:id", h.UpdateAPIIntegration)
 			integrations.DELETE("/:id", h.DeleteAPIIntegration)
 			integrations.POST("/:id/sync", h.TriggerSync)
+			integrations.GET("/:id/runs", h.ListSyncRuns)
 			integrations.POST("/:id/test", h.TestIntegration)
 		}
 
@@ -79,11 +127,51 @@ func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
 			rules.PUT("/:id", h.UpdateAutomationRule)
 			rules.DELETE("/:id", h.DeleteAutomationRule)
 			rules.POST("/:id/execute", h.ExecuteAutomationRule)
+			rules.POST("/:id/promote", h.PromoteAutomationRule)
+			rules.GET("/:id/runs", h.GetAutomationRuleRuns)
+		}
+
+		// Sandbox: deliveries and rule runs captured instead of performed
+		sandbox := automation.Group("/sandbox")
+		{
+			sandbox.POST("/events", h.ReplaySandboxEvent)
+			sandbox.GET("/captures", h.GetSandboxCaptures)
+			sandbox.DELETE("/captures", h.ClearSandboxCaptures)
+		}
+
+		// Automations owned by a team workspace, managed by role
+		teams := automation.Group("/teams")
+		{
+			teams.GET("", h.GetTeams)
+			teams.GET("/:team_id", h.GetTeamAutomations)
+			teams.GET("/:team_id/activity", h.GetTeamActivity)
+			teams.POST("/:team_id/migrate", h.MigrateToTeam)
+			teams.POST("/:team_id/webhooks", h.CreateTeamWebhookEndpoint)
+			teams.PUT("/:team_id/webhooks/:id", h.UpdateTeamWebhookEndpoint)
+			teams.DELETE("/:team_id/webhooks/:id", h.DeleteTeamWebhookEndpoint)
+			teams.POST("/:team_id/rules", h.CreateTeamAutomationRule)
+			teams.PUT("/:team_id/rules/:id", h.UpdateTeamAutomationRule)
+			teams.DELETE("/:team_id/rules/:id", h.DeleteTeamAutomationRule)
+			teams.POST("/:team_id/rules/:id/execute", h.ExecuteTeamAutomationRule)
+			teams.GET("/:team_id/rules/:id/runs", h.GetTeamAutomationRuleRuns)
+			teams.POST("/:team_id/rss", h.CreateTeamRSSFeed)
+			teams.PUT("/:team_id/rss/:id", h.UpdateTeamRSSFeed)
+			teams.DELETE("/:team_id/rss/:id", h.DeleteTeamRSSFeed)
 		}
 	}
+}
 
+// RegisterPublicRoutes registers the routes reached without a user token:
+// public feeds, signed downloads and bucket notifications
+func (h *Handler) RegisterPublicRoutes(r *gin.RouterGroup) {
 	// Public RSS feed endpoint
 	r.GET("/rss/:publicKey", h.GetPublicRSSFeed)
+
+	// Signed artifact downloads referenced by export and backup webhooks
+	r.GET("/downloads/:kind/:id", h.DownloadArtifact)
+
+	// Bucket notifications for uploaded import files, authenticated by token
+	r.POST("/automation/bulk/upload-events", h.HandleUploadEvent)
 }
 
 // Webhook Endpoints
@@ -104,7 +192,7 @@ func (h *Handler) CreateWebhookEndpoint(c *gin.Context) {
 
 	endpoint, err := h.service.CreateWebhookEndpoint(userID, req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -121,7 +209,7 @@ func (h *Handler) GetWebhookEndpoints(c *gin.Context) {
 
 	endpoints, err := h.service.GetWebhookEndpoints(userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -150,7 +238,7 @@ func (h *Handler) UpdateWebhookEndpoint(c *gin.Context) {
 
 	endpoint, err := h.service.UpdateWebhookEndpoint(userID, uint(id), req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -172,7 +260,7 @@ func (h *Handler) DeleteWebhookEndpoint(c *gin.Context) {
 	}
 
 	if err := h.service.DeleteWebhookEndpoint(userID, uint(id)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -195,13 +283,143 @@ func (h *Handler) GetWebhookDeliveries(c *gin.Context) {
 
 	deliveries, err := h.service.GetWebhookDeliveries(userID, uint(id))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"deliveries": deliveries})
 }
 
+// GetWebhookHealth returns the rolling delivery health of a webhook endpoint
+func (h *Handler) GetWebhookHealth(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid endpoint ID"})
+		return
+	}
+
+	health, err := h.service.GetWebhookHealth(userID, uint(id))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, health)
+}
+
+// GetWebhookStats returns the delivery statistics of a webhook endpoint over
+// the window in the window query parameter
+func (h *Handler) GetWebhookStats(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid endpoint ID"})
+		return
+	}
+
+	stats, err := h.service.GetWebhookStats(userID, uint(id), c.Query("window"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// EnableWebhookEndpoint re-enables a webhook endpoint after a successful test delivery
+func (h *Handler) EnableWebhookEndpoint(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid endpoint ID"})
+		return
+	}
+
+	endpoint, err := h.service.EnableWebhookEndpoint(c.Request.Context(), userID, uint(id))
+	if err != nil {
+		if errors.Is(err, ErrWebhookTestFailed) {
+			// The endpoint stays disabled until a test delivery succeeds
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": MapWebhookError(ErrWebhookTestFailed), "details": err.Error()})
+			return
+		}
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, endpoint)
+}
+
+// ReplayWebhookDeliveries sends the deliveries skipped while an endpoint's circuit was open
+func (h *Handler) ReplayWebhookDeliveries(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid endpoint ID"})
+		return
+	}
+
+	replayed, err := h.service.ReplaySkippedDeliveries(c.Request.Context(), userID, uint(id))
+	if err != nil {
+		if errors.Is(err, ErrWebhookEndpointInactive) || errors.Is(err, ErrWebhookCircuitOpen) {
+			c.JSON(http.StatusConflict, gin.H{"error": MapWebhookError(err)})
+			return
+		}
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"replayed": replayed})
+}
+
+// GetWebhookEvents returns the catalog of events endpoints can subscribe to
+func (h *Handler) GetWebhookEvents(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"events": WebhookEventCatalog})
+}
+
+// PreviewWebhookPayload renders a payload template against sample event data
+func (h *Handler) PreviewWebhookPayload(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req PreviewTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	body, err := h.service.PreviewPayload(userID, req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"event": req.Event, "payload": body})
+}
+
 // RSS Feed Endpoints
 
 // CreateRSSFeed creates a new RSS feed
@@ -220,7 +438,7 @@ func (h *Handler) CreateRSSFeed(c *gin.Context) {
 
 	feed, err := h.service.CreateRSSFeed(userID, req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -237,7 +455,7 @@ func (h *Handler) GetRSSFeeds(c *gin.Context) {
 
 	feeds, err := h.service.GetRSSFeeds(userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -266,7 +484,7 @@ func (h *Handler) UpdateRSSFeed(c *gin.Context) {
 
 	feed, err := h.service.UpdateRSSFeed(userID, uint(id), req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -288,7 +506,7 @@ func (h *Handler) DeleteRSSFeed(c *gin.Context) {
 	}
 
 	if err := h.service.DeleteRSSFeed(userID, uint(id)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -321,7 +539,10 @@ func (h *Handler) GetPublicRSSFeed(c *gin.Context) {
 
 // Bulk Operation Endpoints
 
-// CreateBulkOperation creates a new bulk operation
+// CreateBulkOperation accepts a bulk operation and returns it right away
+// with 202 and its Location, while it runs on the job queue. With wait=true
+// the request waits for the operation to finish, up to timeout seconds
+// (DefaultBulkWait, at most MaxBulkWait), and returns it with 200 if it did
 func (h *Handler) CreateBulkOperation(c *gin.Context) {
 	userID := c.GetString("user_id")
 	if userID == "" {
@@ -337,11 +558,124 @@ func (h *Handler) CreateBulkOperation(c *gin.Context) {
 
 	operation, err := h.service.CreateBulkOperation(userID, req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, operation)
+	c.Header("Location", strings.TrimSuffix(c.Request.URL.Path, "/")+"/"+strconv.FormatUint(uint64(operation.ID), 10))
+
+	if wait, _ := strconv.ParseBool(c.Query("wait")); wait {
+		timeout, _ := strconv.Atoi(c.Query("timeout"))
+		finished, done, err := h.service.WaitForBulkOperation(c.Request.Context(), userID, operation.ID, time.Duration(timeout)*time.Second)
+		if err == nil {
+			operation = finished
+		}
+		if done {
+			c.JSON(http.StatusOK, operation)
+			return
+		}
+	}
+
+	c.JSON(http.StatusAccepted, operation)
+}
+
+// CreateImportUploadURL returns a presigned URL to upload a large import
+// file straight to storage. Its object key is then passed as the object_key
+// parameter of an import operation
+func (h *Handler) CreateImportUploadURL(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req ImportUploadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	upload, err := h.service.CreateImportUploadURL(userID, req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, upload)
+}
+
+// UploadImportFile streams a multipart import file through the API to
+// storage. The size field, with the file's length in bytes, and the
+// optional source and auto_import fields are sent before the file. The
+// returned upload's object key is used like one from an upload URL
+func (h *Handler) UploadImportFile(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	file, err := utils.StreamUpload(c, "file")
+	if err != nil {
+		if middleware.BodyTooLarge(c, err) {
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	size, err := strconv.ParseInt(file.Fields["size"], 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrImportUploadSize.Error()})
+		return
+	}
+	autoImport, _ := strconv.ParseBool(file.Fields["auto_import"])
+	req := ImportUploadRequest{Filename: file.Filename, Source: file.Fields["source"], AutoImport: autoImport}
+
+	upload, err := h.service.UploadImportFile(c.Request.Context(), userID, req, file, size)
+	if err != nil {
+		switch {
+		case middleware.BodyTooLarge(c, err):
+		case errors.Is(err, ErrImportUploadTooLarge):
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error(), "limit_bytes": maxImportUploadSize, "code": "IMPORT_UPLOAD_TOO_LARGE"})
+		default:
+			respondError(c, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, upload)
+}
+
+// HandleUploadEvent receives object storage notifications for uploaded
+// import files
+func (h *Handler) HandleUploadEvent(c *gin.Context) {
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if h.uploadEventToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(h.uploadEventToken)) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid upload event token"})
+		return
+	}
+
+	var event UploadEvent
+	if err := c.ShouldBindJSON(&event); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	started, err := h.service.HandleUploadEvent(c.Request.Context(), event)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"imports_started": started})
+}
+
+// GetExportFormats returns the registered export formats, usable as the
+// format parameter of export operations
+func (h *Handler) GetExportFormats(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"formats": h.service.ExportFormats()})
 }
 
 // GetBulkOperations retrieves bulk operations for the authenticated user
@@ -354,7 +688,7 @@ func (h *Handler) GetBulkOperations(c *gin.Context) {
 
 	operations, err := h.service.GetBulkOperations(userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -377,7 +711,7 @@ func (h *Handler) GetBulkOperation(c *gin.Context) {
 
 	operation, err := h.service.GetBulkOperation(userID, uint(id))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -399,13 +733,98 @@ func (h *Handler) CancelBulkOperation(c *gin.Context) {
 	}
 
 	if err := h.service.CancelBulkOperation(userID, uint(id)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Bulk operation cancelled successfully"})
 }
 
+// GetFailedImportRows downloads the failed rows of an import as a CSV file,
+// each annotated with its error, or as JSON with format=json
+func (h *Handler) GetFailedImportRows(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid operation ID"})
+		return
+	}
+
+	rows, err := h.service.GetFailedImportRows(userID, uint(id))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if c.Query("format") == "json" {
+		c.JSON(http.StatusOK, gin.H{"rows": rows})
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="import_%d_failed_rows.csv"`, id))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Status(http.StatusOK)
+	WriteImportRowsCSV(c.Writer, rows)
+}
+
+// RetryFailedImportRows imports the corrected failed rows file of an import,
+// sent as the multipart file field, as a new import operation. Like
+// CreateBulkOperation it returns 202 with the operation's Location
+func (h *Handler) RetryFailedImportRows(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid operation ID"})
+		return
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		if middleware.BodyTooLarge(c, err) {
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A corrected rows file is required"})
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	rows, err := ReadImportRowsCSV(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	operation, err := h.service.RetryFailedImportRows(userID, uint(id), rows)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.Header("Location", fmt.Sprintf("/api/v1/automation/bulk/%d", operation.ID))
+	c.JSON(http.StatusAccepted, operation)
+}
+
+// respondError answers an error with the status and code it is registered
+// with, so unexpected errors are the only ones answered with 500
+func respondError(c *gin.Context, err error) {
+	coded := apperrors.Resolve(err)
+	c.JSON(coded.Status, gin.H{"error": coded.Message, "code": coded.Code})
+}
+
 // Backup Job Endpoints
 
 // CreateBackupJob creates a new backup job
@@ -424,7 +843,7 @@ func (h *Handler) CreateBackupJob(c *gin.Context) {
 
 	job, err := h.service.CreateBackupJob(userID, req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -441,7 +860,7 @@ func (h *Handler) GetBackupJobs(c *gin.Context) {
 
 	jobs, err := h.service.GetBackupJobs(userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -464,7 +883,7 @@ func (h *Handler) GetBackupJob(c *gin.Context) {
 
 	job, err := h.service.GetBackupJob(userID, uint(id))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -487,11 +906,245 @@ func (h *Handler) DownloadBackup(c *gin.Context) {
 
 	filePath, err := h.service.GetBackupFilePath(userID, uint(id))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
+		return
+	}
+
+	serveArtifact(c, filePath)
+}
+
+// GetBackupDownloadLinks returns fresh signed links to a backup and its
+// volumes, for resuming a download once earlier links expired
+func (h *Handler) GetBackupDownloadLinks(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+		return
+	}
+
+	download, err := h.service.GetBackupDownload(userID, uint(id))
+	if err != nil {
+		if isBackupNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, download)
+}
+
+// DownloadBackupVolume downloads one volume of a split backup
+func (h *Handler) DownloadBackupVolume(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+		return
+	}
+	volume, err := strconv.Atoi(c.Param("volume"))
+	if err != nil || volume < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid volume"})
+		return
+	}
+
+	filePath, err := h.service.GetBackupVolumePath(userID, uint(id), volume)
+	if err != nil {
+		if isBackupNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		respondError(c, err)
+		return
+	}
+
+	serveArtifact(c, filePath)
+}
+
+// DownloadArtifact serves a backup, a backup volume or an export through a
+// signed, expiring link
+func (h *Handler) DownloadArtifact(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid artifact ID"})
+		return
+	}
+	volume := 0
+	if raw := c.Query("volume"); raw != "" {
+		if volume, err = strconv.Atoi(raw); err != nil || volume < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid volume"})
+			return
+		}
+	}
+
+	filePath, err := h.service.ResolveVolumeDownload(c.Param("kind"), uint(id), volume, c.Query("expires"), c.Query("signature"))
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrDownloadLinkInvalid), errors.Is(err, ErrDownloadLinkExpired):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	serveArtifact(c, filePath)
+}
+
+// serveArtifact sends a backup or export file. Range requests let clients
+// resume an interrupted download, and the ETag lets them check with
+// If-Range that the file did not change in between
+func serveArtifact(c *gin.Context, filePath string) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Artifact file not found"})
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil || info.IsDir() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Artifact file not found"})
+		return
+	}
+
+	name := filepath.Base(filePath)
+	c.Header("ETag", fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size()))
+	c.Header("Accept-Ranges", "bytes")
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	http.ServeContent(c.Writer, c.Request, name, info.ModTime(), file)
+}
+
+// Backup Destination Endpoints
+
+// CreateBackupDestination registers an external backup destination
+func (h *Handler) CreateBackupDestination(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req BackupDestinationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	destination, err := h.service.CreateBackupDestination(userID, req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, destination)
+}
+
+// GetBackupDestinations retrieves backup destinations for the authenticated user
+func (h *Handler) GetBackupDestinations(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	destinations, err := h.service.GetBackupDestinations(userID)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
-	c.File(filePath)
+	c.JSON(http.StatusOK, gin.H{"destinations": destinations})
+}
+
+// UpdateBackupDestination updates a backup destination
+func (h *Handler) UpdateBackupDestination(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid destination ID"})
+		return
+	}
+
+	var req BackupDestinationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	destination, err := h.service.UpdateBackupDestination(userID, uint(id), req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, destination)
+}
+
+// DeleteBackupDestination deletes a backup destination
+func (h *Handler) DeleteBackupDestination(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid destination ID"})
+		return
+	}
+
+	if err := h.service.DeleteBackupDestination(userID, uint(id)); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Backup destination deleted successfully"})
+}
+
+// TestBackupDestination checks that a backup destination accepts writes
+func (h *Handler) TestBackupDestination(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid destination ID"})
+		return
+	}
+
+	if err := h.service.TestBackupDestination(c.Request.Context(), userID, uint(id)); err != nil {
+		if errors.Is(err, ErrBackupDestinationRejected) || errors.Is(err, ErrBackupDestinationUnreachable) {
+			// The check ran; report why the destination refused the write
+			c.JSON(http.StatusOK, gin.H{"success": false, "error": MapBackupDestinationError(err)})
+			return
+		}
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
 }
 
 // API Integration Endpoints
@@ -512,7 +1165,7 @@ func (h *Handler) CreateAPIIntegration(c *gin.Context) {
 
 	integration, err := h.service.CreateAPIIntegration(userID, req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -529,7 +1182,7 @@ func (h *Handler) GetAPIIntegrations(c *gin.Context) {
 
 	integrations, err := h.service.GetAPIIntegrations(userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -558,7 +1211,7 @@ func (h *Handler) UpdateAPIIntegration(c *gin.Context) {
 
 	integration, err := h.service.UpdateAPIIntegration(userID, uint(id), req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -580,7 +1233,7 @@ func (h *Handler) DeleteAPIIntegration(c *gin.Context) {
 	}
 
 	if err := h.service.DeleteAPIIntegration(userID, uint(id)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -603,13 +1256,42 @@ func (h *Handler) TriggerSync(c *gin.Context) {
 
 	result, err := h.service.TriggerSync(userID, uint(id))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		if errors.Is(err, ErrAPIIntegrationSyncFailed) {
+			// The failed run is still reported so the caller can inspect it
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error(), "result": result})
+			return
+		}
+		respondError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"result": result})
 }
 
+// ListSyncRuns returns the sync history and health of an API integration
+func (h *Handler) ListSyncRuns(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid integration ID"})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	runs, health, err := h.service.ListSyncRuns(userID, uint(id), limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"runs": runs, "health": health})
+}
+
 // TestIntegration tests an API integration
 func (h *Handler) TestIntegration(c *gin.Context) {
 	userID := c.GetString("user_id")
@@ -626,7 +1308,7 @@ func (h *Handler) TestIntegration(c *gin.Context) {
 
 	result, err := h.service.TestIntegration(userID, uint(id))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -651,7 +1333,7 @@ func (h *Handler) CreateAutomationRule(c *gin.Context) {
 
 	rule, err := h.service.CreateAutomationRule(userID, req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -668,7 +1350,7 @@ func (h *Handler) GetAutomationRules(c *gin.Context) {
 
 	rules, err := h.service.GetAutomationRules(userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -697,7 +1379,7 @@ func (h *Handler) UpdateAutomationRule(c *gin.Context) {
 
 	rule, err := h.service.UpdateAutomationRule(userID, uint(id), req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -719,7 +1401,7 @@ func (h *Handler) DeleteAutomationRule(c *gin.Context) {
 	}
 
 	if err := h.service.DeleteAutomationRule(userID, uint(id)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -742,9 +1424,137 @@ func (h *Handler) ExecuteAutomationRule(c *gin.Context) {
 
 	result, err := h.service.ExecuteAutomationRule(userID, uint(id))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"result": result})
 }
+
+// Sandbox Endpoints
+
+// ReplaySandboxEvent sends a test event through the sandbox endpoints and rules
+func (h *Handler) ReplaySandboxEvent(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req SandboxEventRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	captures, err := h.service.ReplaySandboxEvent(c.Request.Context(), userID, req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"captures": captures})
+}
+
+// GetSandboxCaptures returns the sandbox log, newest first
+func (h *Handler) GetSandboxCaptures(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	captures, err := h.service.GetSandboxCaptures(userID, limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"captures": captures})
+}
+
+// ClearSandboxCaptures empties the sandbox log
+func (h *Handler) ClearSandboxCaptures(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	if err := h.service.ClearSandboxCaptures(userID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Sandbox log cleared"})
+}
+
+// PromoteAutomationRule takes a sandbox rule live
+func (h *Handler) PromoteAutomationRule(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rule ID"})
+		return
+	}
+
+	rule, err := h.service.PromoteAutomationRule(userID, uint(id))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// GetAutomationRuleRuns returns the run history of a scheduled rule
+func (h *Handler) GetAutomationRuleRuns(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rule ID"})
+		return
+	}
+
+	runs, err := h.service.GetRuleRuns(userID, uint(id))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"runs": runs})
+}
+
+// PromoteWebhookEndpoint takes a sandbox webhook endpoint live
+func (h *Handler) PromoteWebhookEndpoint(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid endpoint ID"})
+		return
+	}
+
+	endpoint, err := h.service.PromoteWebhookEndpoint(userID, uint(id))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, endpoint)
+}
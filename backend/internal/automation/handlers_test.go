@@ -31,6 +31,7 @@ func (suite *AutomationHandlerTestSuite) SetupTest() {
 	// Register routes
 	api := suite.router.Group("/api/v1")
 	suite.handler.RegisterRoutes(api)
+	suite.handler.RegisterPublicRoutes(api)
 }
 
 // TearDownTest cleans up after each test
@@ -257,14 +258,15 @@ func (suite *AutomationHandlerTestSuite) TestCreateBulkOperation_Success() {
 	// When: Making a POST request to create bulk operation
 	w := suite.makeRequest("POST", "/api/v1/automation/bulk", reqBody)
 
-	// Then: The response should be successful
-	suite.Equal(http.StatusCreated, w.Code)
+	// Then: The operation is accepted and runs in the background
+	suite.Equal(http.StatusAccepted, w.Code)
 
 	var response BulkOperation
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	suite.NoError(err)
 	suite.Equal(reqBody.Type, response.Type)
 	suite.Equal("pending", response.Status)
+	suite.Equal("/api/v1/automation/bulk/"+strconv.FormatUint(uint64(response.ID), 10), w.Header().Get("Location"))
 }
 
 func (suite *AutomationHandlerTestSuite) TestGetBulkOperations_Success() {
@@ -397,6 +399,8 @@ func (suite *AutomationHandlerTestSuite) TestTriggerSync_Success() {
 	}
 	integration, _ := suite.GetTestService().CreateAPIIntegration(suite.GetTestUserID(), reqBody)
 
+	suite.GetTestService().SetIntegrationSyncer("pocket", &stubSyncer{outcome: SyncOutcome{ItemsAdded: 3}})
+
 	// When: Making a POST request to trigger sync
 	url := "/api/v1/automation/integrations/" + strconv.Itoa(int(integration.ID)) + "/sync"
 	w := suite.makeRequest("POST", url, nil)
@@ -603,6 +607,7 @@ func TestRegisterRoutes(t *testing.T) {
 
 	// When: Registering routes
 	handler.RegisterRoutes(api)
+	handler.RegisterPublicRoutes(api)
 
 	// Then: Routes should be registered (we can test this by checking if routes exist)
 	routes := router.Routes()
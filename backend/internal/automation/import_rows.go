@@ -0,0 +1,306 @@
+package automation
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// maxImportRows caps the rows an import operation carries
+	maxImportRows = 5000
+	// maxImportRowsFileSize caps the corrected rows file of a retry
+	maxImportRowsFileSize = 10 << 20
+	// importRowsSaveEvery is how many rows are imported between progress saves
+	importRowsSaveEvery = 50
+)
+
+// importRowsColumns are the columns of a failed rows file. Corrected files
+// may order them differently; the error column is ignored on upload
+var importRowsColumns = []string{"row", "url", "title", "description", "tags", "error"}
+
+// ImportRow is one bookmark of an import operation. Row numbers the row in
+// the file it came from, so failures can be traced back and retried
+type ImportRow struct {
+	Row         int      `json:"row"`
+	URL         string   `json:"url"`
+	Title       string   `json:"title,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Error       string   `json:"error,omitempty"`
+}
+
+// RowImporter saves the rows of import operations, e.g. the bookmark service.
+// The operation ID tells the bookmarks of one operation apart
+type RowImporter interface {
+	ImportRow(ctx context.Context, userID string, operationID uint, row ImportRow) error
+}
+
+// SetRowImporter lets import operations carry rows. Without an importer,
+// operations with a rows parameter are rejected
+func (s *Service) SetRowImporter(importer RowImporter) {
+	s.rowImporter = importer
+}
+
+// checkImportRows validates the rows parameter of an import operation,
+// numbering rows that have no number of their own
+func (s *Service) checkImportRows(parameters map[string]interface{}) error {
+	value, ok := parameters["rows"]
+	if !ok {
+		return nil
+	}
+	if s.rowImporter == nil {
+		return fmt.Errorf("%w: %w", ErrBulkOperationInvalidParams, ErrImportRowsDisabled)
+	}
+	if _, hasUpload := parameters["object_key"]; hasUpload {
+		return fmt.Errorf("%w: rows and object_key cannot be combined", ErrBulkOperationInvalidParams)
+	}
+
+	rows, err := decodeImportRows(value)
+	if err != nil || len(rows) == 0 {
+		return fmt.Errorf("%w: rows must be a non-empty list of rows", ErrBulkOperationInvalidParams)
+	}
+	if len(rows) > maxImportRows {
+		return fmt.Errorf("%w: at most %d rows can be imported at once", ErrBulkOperationInvalidParams, maxImportRows)
+	}
+	for i := range rows {
+		if rows[i].Row == 0 {
+			rows[i].Row = i + 1
+		}
+		rows[i].Error = ""
+	}
+	parameters["rows"] = rows
+	return nil
+}
+
+// decodeImportRows reads rows from operation parameters or results, which
+// hold them as decoded JSON once they have been stored
+func decodeImportRows(value interface{}) ([]ImportRow, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var rows []ImportRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// processImportRows imports the rows of an operation one at a time. Rows
+// that fail keep their error in the result's failed_rows, so they can be
+// downloaded, corrected and retried without importing the rest again
+func (s *Service) processImportRows(operation *BulkOperation) error {
+	if s.rowImporter == nil {
+		return ErrImportRowsDisabled
+	}
+	rows, err := decodeImportRows(operation.Parameters["rows"])
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBulkOperationInvalidParams, err)
+	}
+
+	ctx := context.Background()
+	failed := []ImportRow{}
+	operation.TotalItems = len(rows)
+	for i, row := range rows {
+		if err := s.rowImporter.ImportRow(ctx, operation.UserID, operation.ID, row); err != nil {
+			row.Error = err.Error()
+			failed = append(failed, row)
+		}
+		operation.ProcessedItems = i + 1
+		operation.FailedItems = len(failed)
+		operation.Progress = (i + 1) * 100 / len(rows)
+		if (i+1)%importRowsSaveEvery == 0 {
+			s.db.Save(operation)
+		}
+	}
+
+	if operation.Result == nil {
+		operation.Result = InterfaceMap{}
+	}
+	operation.Result["imported"] = len(rows) - len(failed)
+	operation.Result["failed_rows"] = failed
+	return nil
+}
+
+// GetFailedImportRows returns the rows of an import operation that failed,
+// each with the error it failed with
+func (s *Service) GetFailedImportRows(userID string, id uint) ([]ImportRow, error) {
+	operation, err := s.importOperation(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	return failedImportRows(operation)
+}
+
+// RetryFailedImportRows imports corrected versions of the failed rows of an
+// import operation as a new operation. Only rows that failed may be sent,
+// and an operation's failures are retried once; failures of the retry are
+// retried from the new operation
+func (s *Service) RetryFailedImportRows(userID string, id uint, rows []ImportRow) (*BulkOperation, error) {
+	operation, err := s.importOperation(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if operation.Status == "pending" || operation.Status == "running" {
+		return nil, ErrBulkOperationInProgress
+	}
+	if _, retried := operation.Result["retried_by"]; retried {
+		return nil, ErrImportAlreadyRetried
+	}
+	failed, err := failedImportRows(operation)
+	if err != nil {
+		return nil, err
+	}
+	if len(failed) == 0 {
+		return nil, ErrImportNoFailedRows
+	}
+
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%w: the file has no rows", ErrImportRowsInvalid)
+	}
+	pending := make(map[int]bool, len(failed))
+	for _, row := range failed {
+		pending[row.Row] = true
+	}
+	for i := range rows {
+		if !pending[rows[i].Row] {
+			return nil, fmt.Errorf("%w: row %d did not fail or is listed twice", ErrImportRowsInvalid, rows[i].Row)
+		}
+		pending[rows[i].Row] = false
+		rows[i].Error = ""
+	}
+
+	retry := &BulkOperation{
+		UserID:     userID,
+		Type:       "import",
+		Status:     "pending",
+		Parameters: InterfaceMap{"rows": rows, "retry_of": operation.ID},
+	}
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(retry).Error; err != nil {
+			return fmt.Errorf("failed to create bulk operation: %w", err)
+		}
+		operation.Result["retried_by"] = retry.ID
+		if err := tx.Model(operation).Update("result", operation.Result).Error; err != nil {
+			return fmt.Errorf("failed to update bulk operation: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	s.startBulkOperation(retry)
+	return retry, nil
+}
+
+// importOperation returns one of the user's import operations
+func (s *Service) importOperation(userID string, id uint) (*BulkOperation, error) {
+	operation, err := s.GetBulkOperation(userID, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBulkOperationNotFound
+		}
+		return nil, err
+	}
+	if operation.Type != "import" {
+		return nil, ErrBulkOperationInvalidType
+	}
+	return operation, nil
+}
+
+func failedImportRows(operation *BulkOperation) ([]ImportRow, error) {
+	value, ok := operation.Result["failed_rows"]
+	if !ok {
+		return []ImportRow{}, nil
+	}
+	rows, err := decodeImportRows(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read failed rows: %w", err)
+	}
+	return rows, nil
+}
+
+// WriteImportRowsCSV writes rows as a CSV file with an error column, for
+// users to correct in a spreadsheet. Tags are comma separated in one cell
+func WriteImportRowsCSV(w io.Writer, rows []ImportRow) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(importRowsColumns); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		record := []string{
+			strconv.Itoa(row.Row),
+			row.URL,
+			row.Title,
+			row.Description,
+			strings.Join(row.Tags, ","),
+			row.Error,
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// ReadImportRowsCSV reads a corrected failed rows file. The header names
+// the columns; row and url are required and the error column is ignored
+func ReadImportRowsCSV(r io.Reader) ([]ImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrImportRowsInvalid, err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		// Spreadsheets may save the file with a byte order mark
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	for _, required := range []string{"row", "url"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("%w: missing %s column", ErrImportRowsInvalid, required)
+		}
+	}
+
+	var rows []ImportRow
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrImportRowsInvalid, err)
+		}
+		cell := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		number, err := strconv.Atoi(cell("row"))
+		if err != nil || number < 1 {
+			return nil, fmt.Errorf("%w: line %d has no row number", ErrImportRowsInvalid, line)
+		}
+		row := ImportRow{Row: number, URL: cell("url"), Title: cell("title"), Description: cell("description")}
+		for _, tag := range strings.Split(cell("tags"), ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				row.Tags = append(row.Tags, tag)
+			}
+		}
+		rows = append(rows, row)
+		if len(rows) > maxImportRows {
+			return nil, fmt.Errorf("%w: at most %d rows can be imported at once", ErrImportRowsInvalid, maxImportRows)
+		}
+	}
+	return rows, nil
+}
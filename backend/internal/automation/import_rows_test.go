@@ -0,0 +1,155 @@
+package automation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rowImporterStub rejects rows without an https URL and records the rest
+type rowImporterStub struct {
+	imported []ImportRow
+}
+
+func (r *rowImporterStub) ImportRow(ctx context.Context, userID string, operationID uint, row ImportRow) error {
+	if !strings.HasPrefix(row.URL, "https://") {
+		return errors.New("invalid URL format")
+	}
+	r.imported = append(r.imported, row)
+	return nil
+}
+
+func (suite *AutomationServiceTestSuite) TestImportRows_RetryFailed() {
+	service := suite.GetTestService()
+	service.executor = syncExecutor{}
+	importer := &rowImporterStub{}
+	service.SetRowImporter(importer)
+	userID := suite.GetTestUserID()
+
+	// Given: An import where two of four rows fail
+	operation, err := service.CreateBulkOperation(userID, BulkOperationRequest{Type: "import", Parameters: map[string]interface{}{
+		"rows": []interface{}{
+			map[string]interface{}{"url": "https://go.dev", "title": "Go"},
+			map[string]interface{}{"url": "go.dev/doc", "title": "Docs", "tags": []interface{}{"go"}},
+			map[string]interface{}{"url": "https://pkg.go.dev"},
+			map[string]interface{}{"url": "ftp://example.com"},
+		},
+	}})
+	suite.Require().NoError(err)
+	suite.Equal("completed", operation.Status)
+	suite.Equal(4, operation.TotalItems)
+	suite.Equal(2, operation.FailedItems)
+
+	// Then: The failed rows are kept with their row numbers and errors
+	failed, err := service.GetFailedImportRows(userID, operation.ID)
+	suite.Require().NoError(err)
+	suite.Require().Len(failed, 2)
+	suite.Equal(2, failed[0].Row)
+	suite.Equal([]string{"go"}, failed[0].Tags)
+	suite.Equal("invalid URL format", failed[0].Error)
+	suite.Equal(4, failed[1].Row)
+
+	// When: Only rows that failed can be retried
+	_, err = service.RetryFailedImportRows(userID, operation.ID, []ImportRow{{Row: 1, URL: "https://go.dev"}})
+	suite.ErrorIs(err, ErrImportRowsInvalid)
+
+	// And: The corrected rows run as a new import, keeping their numbers
+	retry, err := service.RetryFailedImportRows(userID, operation.ID, []ImportRow{
+		{Row: 2, URL: "https://go.dev/doc", Title: "Docs"},
+		{Row: 4, URL: "ftp://still-wrong"},
+	})
+	suite.Require().NoError(err)
+	suite.Equal("completed", retry.Status)
+	suite.EqualValues(operation.ID, retry.Parameters["retry_of"])
+	suite.Len(importer.imported, 3)
+
+	failed, err = service.GetFailedImportRows(userID, retry.ID)
+	suite.Require().NoError(err)
+	suite.Require().Len(failed, 1)
+	suite.Equal(4, failed[0].Row)
+
+	_, err = service.RetryFailedImportRows(userID, operation.ID, []ImportRow{{Row: 4, URL: "https://example.com"}})
+	suite.ErrorIs(err, ErrImportAlreadyRetried, "later attempts retry the newer operation")
+	_, err = service.RetryFailedImportRows("someone-else", retry.ID, []ImportRow{{Row: 4, URL: "https://example.com"}})
+	suite.ErrorIs(err, ErrBulkOperationNotFound)
+}
+
+func (suite *AutomationServiceTestSuite) TestImportRows_RequireImporter() {
+	_, err := suite.GetTestService().CreateBulkOperation(suite.GetTestUserID(), BulkOperationRequest{Type: "import", Parameters: map[string]interface{}{
+		"rows": []interface{}{map[string]interface{}{"url": "https://go.dev"}},
+	}})
+	suite.ErrorIs(err, ErrBulkOperationInvalidParams)
+	suite.ErrorIs(err, ErrImportRowsDisabled)
+}
+
+func (suite *AutomationHandlerTestSuite) TestRetryFailedImportRows() {
+	service := suite.GetTestService()
+	service.executor = syncExecutor{}
+	service.SetRowImporter(&rowImporterStub{})
+
+	w := suite.makeRequest("POST", "/api/v1/automation/bulk", BulkOperationRequest{Type: "import", Parameters: map[string]interface{}{
+		"rows": []interface{}{
+			map[string]interface{}{"url": "https://go.dev"},
+			map[string]interface{}{"url": "go.dev/blog", "title": "Blog, news"},
+		},
+	}})
+	suite.Require().Equal(http.StatusAccepted, w.Code)
+	var operation BulkOperation
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &operation))
+
+	// The failed rows download as an annotated CSV file
+	w = suite.makeRequest("GET", fmt.Sprintf("/api/v1/automation/bulk/%d/failed-rows", operation.ID), nil)
+	suite.Require().Equal(http.StatusOK, w.Code)
+	suite.Contains(w.Header().Get("Content-Disposition"), "failed_rows.csv")
+	suite.Equal("row,url,title,description,tags,error\n2,go.dev/blog,\"Blog, news\",,,invalid URL format\n", w.Body.String())
+
+	retry := func(content string) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		writer := multipart.NewWriter(&buf)
+		part, err := writer.CreateFormFile("file", "failed_rows.csv")
+		suite.Require().NoError(err)
+		_, err = part.Write([]byte(content))
+		suite.Require().NoError(err)
+		suite.Require().NoError(writer.Close())
+
+		req := httptest.NewRequest("POST", fmt.Sprintf("/api/v1/automation/bulk/%d/retry-failed", operation.ID), &buf)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		w := httptest.NewRecorder()
+		suite.router.ServeHTTP(w, req)
+		return w
+	}
+
+	suite.Equal(http.StatusBadRequest, retry("url,title\nhttps://go.dev/blog,Blog\n").Code, "rows need their numbers")
+
+	// The corrected file is imported as a new operation
+	w = retry("row,url,title,description,tags,error\n2,https://go.dev/blog,\"Blog, news\",,,invalid URL format\n")
+	suite.Require().Equal(http.StatusAccepted, w.Code)
+	var retried BulkOperation
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &retried))
+	suite.Equal(fmt.Sprintf("/api/v1/automation/bulk/%d", retried.ID), w.Header().Get("Location"))
+	suite.Equal(0, retried.FailedItems)
+
+	suite.Equal(http.StatusConflict, retry("row,url\n2,https://go.dev/blog\n").Code)
+	suite.Equal(http.StatusNotFound, suite.makeRequest("GET", "/api/v1/automation/bulk/999/failed-rows", nil).Code)
+}
+
+func TestReadImportRowsCSV(t *testing.T) {
+	rows, err := ReadImportRowsCSV(strings.NewReader("\ufeffURL,Row,Tags\nhttps://go.dev, 3 ,\"go, docs\"\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []ImportRow{{Row: 3, URL: "https://go.dev", Tags: []string{"go", "docs"}}}, rows)
+
+	_, err = ReadImportRowsCSV(strings.NewReader("row,url\nthree,https://go.dev\n"))
+	assert.ErrorIs(t, err, ErrImportRowsInvalid)
+	_, err = ReadImportRowsCSV(strings.NewReader(""))
+	assert.ErrorIs(t, err, ErrImportRowsInvalid)
+}
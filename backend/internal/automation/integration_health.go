@@ -0,0 +1,332 @@
+package automation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Notification types sent when integration syncs keep failing
+const (
+	NotificationIntegrationFailing = "integration.sync_failing"
+	NotificationIntegrationPaused  = "integration.paused"
+)
+
+// Sync run triggers
+const (
+	SyncTriggerManual    = "manual"
+	SyncTriggerScheduled = "scheduled"
+)
+
+// Sync run statuses
+const (
+	SyncRunRunning = "running"
+	SyncRunSuccess = "success"
+	SyncRunFailed  = "failed"
+)
+
+// Health statuses reported for API integrations
+const (
+	IntegrationHealthUnknown  = "unknown"
+	IntegrationHealthHealthy  = "healthy"
+	IntegrationHealthDegraded = "degraded"
+	IntegrationHealthFailing  = "failing"
+	IntegrationHealthPaused   = "paused"
+)
+
+// Limits for listing sync runs
+const (
+	defaultSyncRunLimit = 20
+	maxSyncRunLimit     = 100
+)
+
+// IntegrationHealthPolicy controls when failing integrations alert and pause
+type IntegrationHealthPolicy struct {
+	// AlertAfter notifies the owner once this many syncs in a row have failed
+	AlertAfter int
+	// PauseAfter stops scheduled syncs once this many syncs in a row have failed
+	PauseAfter int
+}
+
+// DefaultIntegrationHealthPolicy alerts after 3 failed syncs in a row and pauses after 5
+var DefaultIntegrationHealthPolicy = IntegrationHealthPolicy{
+	AlertAfter: 3,
+	PauseAfter: 5,
+}
+
+// SyncOutcome is what a sync did with the items it fetched
+type SyncOutcome struct {
+	ItemsAdded   int
+	ItemsUpdated int
+	ItemsFailed  int
+	// Errors describes the items that failed
+	Errors []string
+}
+
+// IntegrationSyncer pulls items from an integration's service
+type IntegrationSyncer interface {
+	Sync(ctx context.Context, integration *APIIntegration) (*SyncOutcome, error)
+}
+
+// IntegrationHealth summarizes recent sync runs for an integration
+type IntegrationHealth struct {
+	IntegrationID       uint       `json:"integration_id"`
+	Status              string     `json:"status"`
+	SuccessRate         float64    `json:"success_rate"`
+	Runs                int        `json:"runs"`
+	SuccessfulRuns      int        `json:"successful_runs"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastSync            *time.Time `json:"last_sync,omitempty"`
+	LastSuccessAt       *time.Time `json:"last_success_at,omitempty"`
+	LastFailureAt       *time.Time `json:"last_failure_at,omitempty"`
+	PausedAt            *time.Time `json:"paused_at,omitempty"`
+	PausedReason        string     `json:"paused_reason,omitempty"`
+}
+
+// IntegrationSyncNotification tells the owner an integration keeps failing
+type IntegrationSyncNotification struct {
+	Type                string     `json:"type"`
+	IntegrationID       uint       `json:"integration_id"`
+	Name                string     `json:"name"`
+	IntegrationType     string     `json:"integration_type"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastError           string     `json:"last_error"`
+	RunID               uint       `json:"run_id"`
+	PausedAt            *time.Time `json:"paused_at,omitempty"`
+}
+
+// SetIntegrationHealthPolicy configures when failing integrations alert and pause
+func (s *Service) SetIntegrationHealthPolicy(policy IntegrationHealthPolicy) {
+	s.integrationPolicy = policy
+}
+
+// SetIntegrationSyncer registers the client that syncs integrations of a
+// type. Integrations of types without one cannot be synced
+func (s *Service) SetIntegrationSyncer(integrationType string, syncer IntegrationSyncer) {
+	s.syncers[integrationType] = syncer
+}
+
+// ListSyncRuns returns an integration's most recent sync runs, newest first,
+// with its health over those runs
+func (s *Service) ListSyncRuns(userID string, id uint, limit int) ([]SyncRun, *IntegrationHealth, error) {
+	integration, err := s.getAPIIntegration(userID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if limit <= 0 {
+		limit = defaultSyncRunLimit
+	}
+	if limit > maxSyncRunLimit {
+		limit = maxSyncRunLimit
+	}
+
+	var runs []SyncRun
+	if err := s.db.Where("integration_id = ?", id).Order("started_at DESC, id DESC").Limit(limit).Find(&runs).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to get sync runs: %w", err)
+	}
+
+	health := &IntegrationHealth{
+		IntegrationID:       integration.ID,
+		ConsecutiveFailures: integration.ConsecutiveFailures,
+		LastSync:            integration.LastSync,
+		LastSuccessAt:       integration.LastSuccessAt,
+		LastFailureAt:       integration.LastFailureAt,
+		PausedAt:            integration.PausedAt,
+		PausedReason:        integration.PausedReason,
+	}
+	for _, run := range runs {
+		if run.Status == SyncRunRunning {
+			continue
+		}
+		health.Runs++
+		if run.Status == SyncRunSuccess {
+			health.SuccessfulRuns++
+		}
+	}
+	if health.Runs > 0 {
+		health.SuccessRate = float64(health.SuccessfulRuns) / float64(health.Runs)
+	}
+
+	switch {
+	case integration.PausedAt != nil:
+		health.Status = IntegrationHealthPaused
+	case health.Runs == 0:
+		health.Status = IntegrationHealthUnknown
+	case s.integrationPolicy.AlertAfter > 0 && integration.ConsecutiveFailures >= s.integrationPolicy.AlertAfter:
+		health.Status = IntegrationHealthFailing
+	case integration.ConsecutiveFailures > 0:
+		health.Status = IntegrationHealthDegraded
+	default:
+		health.Status = IntegrationHealthHealthy
+	}
+
+	return runs, health, nil
+}
+
+// RunDueSyncs syncs every enabled integration whose interval has elapsed.
+// Paused integrations are skipped until a manual sync succeeds, and
+// integrations of types without a syncer are not run
+func (s *Service) RunDueSyncs(ctx context.Context) (int, error) {
+	var integrations []APIIntegration
+	if err := s.db.WithContext(ctx).
+		Where("active = ? AND sync_enabled = ? AND paused_at IS NULL", true, true).
+		Find(&integrations).Error; err != nil {
+		return 0, fmt.Errorf("failed to get API integrations: %w", err)
+	}
+
+	now := time.Now()
+	synced := 0
+	for i := range integrations {
+		integration := &integrations[i]
+		interval := time.Duration(integration.SyncInterval) * time.Second
+		if integration.LastSync != nil && now.Sub(*integration.LastSync) < interval {
+			continue
+		}
+		if s.syncers[integration.Type] == nil {
+			continue
+		}
+		// A failure is recorded on its run and does not stop the others
+		s.runSync(ctx, integration, SyncTriggerScheduled)
+		synced++
+	}
+	return synced, nil
+}
+
+// runSync syncs an integration and records the run. Its error is the sync's.
+// No run is recorded for an integration whose type has no syncer
+func (s *Service) runSync(ctx context.Context, integration *APIIntegration, trigger string) (*SyncRun, error) {
+	syncer := s.syncers[integration.Type]
+	if syncer == nil {
+		return nil, fmt.Errorf("%w: %s", ErrAPIIntegrationSyncUnsupported, integration.Type)
+	}
+
+	run := &SyncRun{
+		IntegrationID: integration.ID,
+		UserID:        integration.UserID,
+		Trigger:       trigger,
+		Status:        SyncRunRunning,
+		StartedAt:     time.Now(),
+	}
+	if err := s.db.WithContext(ctx).Create(run).Error; err != nil {
+		return nil, fmt.Errorf("failed to record sync run: %w", err)
+	}
+
+	outcome, syncErr := syncer.Sync(ctx, integration)
+	if outcome == nil {
+		outcome = &SyncOutcome{}
+	}
+
+	finished := time.Now()
+	run.FinishedAt = &finished
+	run.ItemsAdded = outcome.ItemsAdded
+	run.ItemsUpdated = outcome.ItemsUpdated
+	run.ItemsFailed = outcome.ItemsFailed
+	run.ErrorDetails = StringSlice(outcome.Errors)
+	run.Status = SyncRunSuccess
+	if syncErr != nil {
+		run.Status = SyncRunFailed
+		run.Error = syncErr.Error()
+	}
+	if err := s.db.WithContext(ctx).Save(run).Error; err != nil {
+		return nil, fmt.Errorf("failed to record sync run: %w", err)
+	}
+
+	s.recordSyncResult(ctx, integration, run)
+	return run, syncErr
+}
+
+// recordSyncResult updates an integration's health counters, alerting the
+// owner and pausing it as the failure streak grows. A success clears a pause
+func (s *Service) recordSyncResult(ctx context.Context, integration *APIIntegration, run *SyncRun) {
+	now := *run.FinishedAt
+
+	if run.Status == SyncRunSuccess {
+		s.db.Model(&APIIntegration{}).Where("id = ?", integration.ID).Updates(map[string]interface{}{
+			"last_sync":            now,
+			"last_success_at":      now,
+			"consecutive_failures": 0,
+			"paused_at":            nil,
+			"paused_reason":        "",
+		})
+		return
+	}
+
+	var current APIIntegration
+	var alert, paused bool
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Counted in SQL so overlapping runs do not lose increments
+		if err := tx.Model(&APIIntegration{}).Where("id = ?", integration.ID).Updates(map[string]interface{}{
+			"last_sync":            now,
+			"last_failure_at":      now,
+			"consecutive_failures": gorm.Expr("consecutive_failures + 1"),
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.First(&current, integration.ID).Error; err != nil {
+			return err
+		}
+
+		policy := s.integrationPolicy
+		alert = policy.AlertAfter > 0 && current.ConsecutiveFailures == policy.AlertAfter
+		if policy.PauseAfter <= 0 || current.ConsecutiveFailures < policy.PauseAfter || current.PausedAt != nil {
+			return nil
+		}
+
+		// Only the run that pauses the integration sends the notification
+		reason := fmt.Sprintf("%d consecutive failed syncs", current.ConsecutiveFailures)
+		result := tx.Model(&APIIntegration{}).Where("id = ? AND paused_at IS NULL", current.ID).Updates(map[string]interface{}{
+			"paused_at":     now,
+			"paused_reason": reason,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if paused = result.RowsAffected > 0; paused {
+			current.PausedAt = &now
+			current.PausedReason = reason
+		}
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	// The owner is told only once the counters are committed
+	if alert {
+		s.notifyIntegrationSync(ctx, NotificationIntegrationFailing, &current, run)
+	}
+	if paused {
+		s.notifyIntegrationSync(ctx, NotificationIntegrationPaused, &current, run)
+	}
+}
+
+func (s *Service) notifyIntegrationSync(ctx context.Context, notificationType string, integration *APIIntegration, run *SyncRun) {
+	if s.notifications == nil {
+		return
+	}
+
+	s.notifications.PublishNotification(ctx, integration.UserID, &IntegrationSyncNotification{
+		Type:                notificationType,
+		IntegrationID:       integration.ID,
+		Name:                integration.Name,
+		IntegrationType:     integration.Type,
+		ConsecutiveFailures: integration.ConsecutiveFailures,
+		LastError:           run.Error,
+		RunID:               run.ID,
+		PausedAt:            integration.PausedAt,
+	})
+}
+
+func (s *Service) getAPIIntegration(userID string, id uint) (*APIIntegration, error) {
+	var integration APIIntegration
+	if err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&integration).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAPIIntegrationNotFound
+		}
+		return nil, fmt.Errorf("failed to get API integration: %w", err)
+	}
+	return &integration, nil
+}
@@ -0,0 +1,167 @@
+package automation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// stubSyncer returns a fixed outcome, failing while err is set
+type stubSyncer struct {
+	outcome SyncOutcome
+	err     error
+}
+
+func (s *stubSyncer) Sync(ctx context.Context, integration *APIIntegration) (*SyncOutcome, error) {
+	outcome := s.outcome
+	return &outcome, s.err
+}
+
+func createSyncIntegration(service *Service, userID string) (*APIIntegration, error) {
+	return service.CreateAPIIntegration(userID, APIIntegrationRequest{
+		Name:        "Pocket",
+		Type:        "pocket",
+		BaseURL:     "https://api.example.com",
+		APIKey:      "test-key",
+		SyncEnabled: true,
+	})
+}
+
+func (suite *AutomationServiceTestSuite) TestIntegrationHealth_AlertsThenPausesFailingSyncs() {
+	publisher := &recordingPublisher{}
+	syncer := &stubSyncer{
+		outcome: SyncOutcome{ItemsAdded: 1, ItemsFailed: 2, Errors: []string{"item 7: invalid url", "item 9: invalid url"}},
+		err:     errors.New("upstream returned HTTP 503"),
+	}
+	service := suite.GetTestService()
+	service.SetNotificationPublisher(publisher)
+	service.SetIntegrationSyncer("pocket", syncer)
+	service.SetIntegrationHealthPolicy(IntegrationHealthPolicy{AlertAfter: 2, PauseAfter: 3})
+	integration, err := createSyncIntegration(service, suite.GetTestUserID())
+	suite.Require().NoError(err)
+
+	// When: Three syncs fail in a row
+	for i := 0; i < 3; i++ {
+		result, err := service.TriggerSync(suite.GetTestUserID(), integration.ID)
+		suite.ErrorIs(err, ErrAPIIntegrationSyncFailed)
+		suite.Equal(SyncRunFailed, result["status"])
+	}
+
+	// Then: Every run is recorded with its error details
+	runs, health, err := service.ListSyncRuns(suite.GetTestUserID(), integration.ID, 0)
+	suite.Require().NoError(err)
+	suite.Require().Len(runs, 3)
+	suite.Equal(SyncTriggerManual, runs[0].Trigger)
+	suite.Equal("upstream returned HTTP 503", runs[0].Error)
+	suite.Equal(2, runs[0].ItemsFailed)
+	suite.Len(runs[0].ErrorDetails, 2)
+	suite.NotNil(runs[0].FinishedAt)
+	suite.Equal(IntegrationHealthPaused, health.Status)
+	suite.Equal(3, health.ConsecutiveFailures)
+	suite.Equal("3 consecutive failed syncs", health.PausedReason)
+
+	// And: The owner is alerted at the threshold, then told about the pause
+	suite.Require().Len(publisher.notifications, 2)
+	suite.Equal(NotificationIntegrationFailing, publisher.notifications[0].(*IntegrationSyncNotification).Type)
+	paused := publisher.notifications[1].(*IntegrationSyncNotification)
+	suite.Equal(NotificationIntegrationPaused, paused.Type)
+	suite.Equal(runs[0].ID, paused.RunID)
+	suite.NotNil(paused.PausedAt)
+
+	// And: Scheduled syncs skip the paused integration
+	synced, err := service.RunDueSyncs(context.Background())
+	suite.Require().NoError(err)
+	suite.Zero(synced)
+
+	// When: A manual sync succeeds
+	syncer.err = nil
+	_, err = service.TriggerSync(suite.GetTestUserID(), integration.ID)
+	suite.Require().NoError(err)
+
+	// Then: The integration is resumed with a clean streak
+	_, health, err = service.ListSyncRuns(suite.GetTestUserID(), integration.ID, 0)
+	suite.Require().NoError(err)
+	suite.Equal(IntegrationHealthHealthy, health.Status)
+	suite.Nil(health.PausedAt)
+	suite.Equal(0, health.ConsecutiveFailures)
+	suite.Equal(4, health.Runs)
+	suite.Equal(0.25, health.SuccessRate)
+}
+
+func (suite *AutomationServiceTestSuite) TestRunDueSyncs_RespectsInterval() {
+	service := suite.GetTestService()
+	service.SetIntegrationSyncer("pocket", &stubSyncer{})
+	due, err := createSyncIntegration(service, suite.GetTestUserID())
+	suite.Require().NoError(err)
+	recent, err := createSyncIntegration(service, suite.GetTestUserID())
+	suite.Require().NoError(err)
+
+	// Given: One integration synced just now
+	_, err = service.TriggerSync(suite.GetTestUserID(), recent.ID)
+	suite.Require().NoError(err)
+
+	// When: Running scheduled syncs
+	synced, err := service.RunDueSyncs(context.Background())
+
+	// Then: Only the integration that was never synced runs
+	suite.Require().NoError(err)
+	suite.Equal(1, synced)
+	runs, _, err := service.ListSyncRuns(suite.GetTestUserID(), due.ID, 0)
+	suite.Require().NoError(err)
+	suite.Require().Len(runs, 1)
+	suite.Equal(SyncTriggerScheduled, runs[0].Trigger)
+	suite.Equal(SyncRunSuccess, runs[0].Status)
+}
+
+func (suite *AutomationServiceTestSuite) TestSync_WithoutSyncerFails() {
+	service := suite.GetTestService()
+	integration, err := createSyncIntegration(service, suite.GetTestUserID())
+	suite.Require().NoError(err)
+
+	// When: Syncing an integration whose type has no syncer
+	result, err := service.TriggerSync(suite.GetTestUserID(), integration.ID)
+
+	// Then: The sync is refused and nothing is recorded
+	suite.ErrorIs(err, ErrAPIIntegrationSyncUnsupported)
+	suite.Nil(result)
+	synced, err := service.RunDueSyncs(context.Background())
+	suite.Require().NoError(err)
+	suite.Zero(synced)
+	runs, health, err := service.ListSyncRuns(suite.GetTestUserID(), integration.ID, 0)
+	suite.Require().NoError(err)
+	suite.Empty(runs)
+	suite.Equal(IntegrationHealthUnknown, health.Status)
+}
+
+func (suite *AutomationHandlerTestSuite) TestListSyncRuns() {
+	integration, err := createSyncIntegration(suite.GetTestService(), suite.GetTestUserID())
+	suite.Require().NoError(err)
+	base := "/api/v1/automation/integrations/" + strconv.Itoa(int(integration.ID))
+
+	suite.GetTestService().SetIntegrationSyncer("pocket", &stubSyncer{err: errors.New("token expired")})
+	w := suite.makeRequest("POST", base+"/sync", nil)
+	suite.Equal(http.StatusBadGateway, w.Code)
+	suite.Contains(w.Body.String(), `"error":"token expired"`)
+
+	w = suite.makeRequest("GET", base+"/runs?limit=5", nil)
+	suite.Require().Equal(http.StatusOK, w.Code)
+	var response struct {
+		Runs   []SyncRun          `json:"runs"`
+		Health *IntegrationHealth `json:"health"`
+	}
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &response))
+	suite.Require().Len(response.Runs, 1)
+	suite.Equal(SyncRunFailed, response.Runs[0].Status)
+	suite.Equal(IntegrationHealthDegraded, response.Health.Status)
+
+	suite.GetTestService().SetIntegrationSyncer("pocket", nil)
+	w = suite.makeRequest("POST", base+"/sync", nil)
+	suite.Equal(http.StatusNotImplemented, w.Code)
+
+	w = suite.makeRequest("GET", "/api/v1/automation/integrations/999/runs", nil)
+	suite.Equal(http.StatusNotFound, w.Code)
+	w = suite.makeRequest("POST", "/api/v1/automation/integrations/999/sync", nil)
+	suite.Equal(http.StatusNotFound, w.Code)
+}
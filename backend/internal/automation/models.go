@@ -21,8 +21,100 @@ const (
 	WebhookEventCollectionDeleted WebhookEvent = "collection.deleted"
 	WebhookEventUserRegistered    WebhookEvent = "user.registered"
 	WebhookEventUserUpdated       WebhookEvent = "user.updated"
+	WebhookEventExportCompleted   WebhookEvent = "export.completed"
+	WebhookEventBackupCompleted   WebhookEvent = "backup.completed"
+	WebhookEventBackupFailed      WebhookEvent = "backup.failed"
+
+	// Events of a shared collection, delivered to endpoints scoped to it
+	WebhookEventCollectionBookmarkAdded      WebhookEvent = "collection.bookmark_added"
+	WebhookEventCollectionBookmarkRemoved    WebhookEvent = "collection.bookmark_removed"
+	WebhookEventCollectionCollaboratorJoined WebhookEvent = "collection.collaborator_joined"
+	WebhookEventShareViewed                  WebhookEvent = "share.viewed"
+
+	// Link monitoring results, fired when a bookmark's link changes state
+	WebhookEventLinkBroken    WebhookEvent = "link.broken"
+	WebhookEventLinkRecovered WebhookEvent = "link.recovered"
+
+	// Threshold events, fired by the worker evaluating view counts and
+	// visits rather than by a request
+	WebhookEventBookmarkViewsReached WebhookEvent = "bookmark.views_reached"
+	WebhookEventBookmarkUnvisited    WebhookEvent = "bookmark.unvisited"
 )
 
+// WebhookEventInfo documents a webhook event and the fields of its payload data
+type WebhookEventInfo struct {
+	Event       WebhookEvent `json:"event"`
+	Description string       `json:"description"`
+	DataFields  []string     `json:"data_fields,omitempty"`
+}
+
+// WebhookEventCatalog lists every event endpoints can subscribe to
+var WebhookEventCatalog = []WebhookEventInfo{
+	{Event: WebhookEventBookmarkCreated, Description: "New bookmark added"},
+	{Event: WebhookEventBookmarkUpdated, Description: "Bookmark modified"},
+	{Event: WebhookEventBookmarkDeleted, Description: "Bookmark removed"},
+	{Event: WebhookEventCollectionCreated, Description: "New collection created"},
+	{Event: WebhookEventCollectionUpdated, Description: "Collection modified"},
+	{Event: WebhookEventCollectionDeleted, Description: "Collection removed"},
+	{Event: WebhookEventUserRegistered, Description: "New user registered"},
+	{Event: WebhookEventUserUpdated, Description: "User profile updated"},
+	{
+		Event:       WebhookEventExportCompleted,
+		Description: "Bulk export finished; download_url is a signed link that expires at expires_at",
+		DataFields:  []string{"operation_id", "total_items", "download_url", "expires_at"},
+	},
+	{
+		Event:       WebhookEventBackupCompleted,
+		Description: "Backup finished; download_url is a signed link that expires at expires_at",
+		DataFields:  []string{"backup_id", "type", "size", "checksum", "download_url", "expires_at", "remote_url"},
+	},
+	{
+		Event:       WebhookEventBackupFailed,
+		Description: "Backup job failed",
+		DataFields:  []string{"backup_id", "type", "error"},
+	},
+	{
+		Event:       WebhookEventCollectionBookmarkAdded,
+		Description: "Bookmark added to a collection",
+		DataFields:  []string{"collection_id", "bookmark_id", "url", "title"},
+	},
+	{
+		Event:       WebhookEventCollectionBookmarkRemoved,
+		Description: "Bookmark removed from a collection",
+		DataFields:  []string{"collection_id", "bookmark_id"},
+	},
+	{
+		Event:       WebhookEventCollectionCollaboratorJoined,
+		Description: "Invited collaborator accepted and joined a collection",
+		DataFields:  []string{"collection_id", "collaborator_id", "user_id", "permission"},
+	},
+	{
+		Event:       WebhookEventShareViewed,
+		Description: "Collection share reached another multiple of the endpoint's view_threshold views",
+		DataFields:  []string{"collection_id", "share_id", "view_count"},
+	},
+	{
+		Event:       WebhookEventLinkBroken,
+		Description: "Link check found a bookmark's link broken or unreachable after it last worked",
+		DataFields:  []string{"bookmark_id", "check_id", "url", "status", "previous_status", "status_code", "error_message", "checked_at"},
+	},
+	{
+		Event:       WebhookEventLinkRecovered,
+		Description: "Link check found a previously broken link working again",
+		DataFields:  []string{"bookmark_id", "check_id", "url", "status", "previous_status", "status_code", "error_message", "checked_at"},
+	},
+	{
+		Event:       WebhookEventBookmarkViewsReached,
+		Description: "Bookmark in a public or shared collection reached the endpoint's bookmark_view_threshold views; fires once per threshold",
+		DataFields:  []string{"bookmark_id", "url", "title", "tags", "views", "threshold"},
+	},
+	{
+		Event:       WebhookEventBookmarkUnvisited,
+		Description: "Bookmark not visited for the endpoint's unvisited_months months; fires again only after another visit",
+		DataFields:  []string{"bookmark_id", "url", "title", "tags", "last_visited_at", "months_unvisited", "threshold"},
+	},
+}
+
 // StringSlice is a custom type for handling JSON arrays in SQLite
 type StringSlice []string
 
@@ -139,19 +231,61 @@ func (s UintSlice) Value() (driver.Value, error) {
 
 // WebhookEndpoint represents a webhook endpoint configuration
 type WebhookEndpoint struct {
-	ID         uint           `json:"id" gorm:"primaryKey"`
-	UserID     string         `json:"user_id" gorm:"not null;index"`
-	Name       string         `json:"name" gorm:"not null"`
-	URL        string         `json:"url" gorm:"not null"`
-	Secret     string         `json:"-" gorm:"not null"` // Hidden from JSON
-	Events     StringSlice    `json:"events" gorm:"type:text"`
-	Active     bool           `json:"active" gorm:"default:true"`
-	RetryCount int            `json:"retry_count" gorm:"default:3"`
-	Timeout    int            `json:"timeout" gorm:"default:30"` // seconds
-	Headers    StringMap      `json:"headers" gorm:"type:text"`
-	CreatedAt  time.Time      `json:"created_at"`
-	UpdatedAt  time.Time      `json:"updated_at"`
-	DeletedAt  gorm.DeletedAt `json:"-" gorm:"index"`
+	ID         uint        `json:"id" gorm:"primaryKey"`
+	UserID     string      `json:"user_id" gorm:"not null;index"`
+	Name       string      `json:"name" gorm:"not null"`
+	URL        string      `json:"url" gorm:"not null"`
+	Secret     string      `json:"-" gorm:"not null"` // Hidden from JSON
+	Events     StringSlice `json:"events" gorm:"type:text"`
+	Active     bool        `json:"active" gorm:"default:true"`
+	RetryCount int         `json:"retry_count" gorm:"default:3"`
+	Timeout    int         `json:"timeout" gorm:"default:30"` // seconds
+	Headers    StringMap   `json:"headers" gorm:"type:text"`
+
+	// PayloadTemplate is an optional Go template producing the request body,
+	// for receivers that expect their own JSON shape
+	PayloadTemplate string `json:"payload_template,omitempty" gorm:"type:text"`
+
+	// CollectionID scopes the endpoint to one collection: it only receives
+	// that collection's events, never the user's account-wide ones
+	CollectionID *uint `json:"collection_id,omitempty" gorm:"index"`
+	// ViewThreshold fires share.viewed every this many views of the
+	// collection's shares, DefaultShareViewThreshold when zero
+	ViewThreshold int `json:"view_threshold,omitempty"`
+
+	// Thresholds of bookmark.views_reached and bookmark.unvisited,
+	// DefaultBookmarkViewThreshold and DefaultUnvisitedMonths when zero
+	BookmarkViewThreshold int `json:"bookmark_view_threshold,omitempty"`
+	UnvisitedMonths       int `json:"unvisited_months,omitempty"`
+
+	// OAuthAppID is set on subscriptions created by a third-party app with the user's consent
+	OAuthAppID *uint `json:"oauth_app_id,omitempty" gorm:"column:oauth_app_id;index"`
+
+	// Sandbox endpoints receive the same events, but their deliveries are
+	// captured to the sandbox log instead of sent
+	Sandbox bool `json:"sandbox" gorm:"default:false"`
+
+	// TeamOwned marks automations of a team workspace: UserID is the
+	// workspace owner's, and members manage them according to their role
+	TeamOwned bool   `json:"team_owned" gorm:"default:false"`
+	CreatedBy string `json:"created_by,omitempty"` // member who created it or moved it into the team
+
+	// Delivery health, used to auto-disable endpoints that keep failing
+	ConsecutiveFailures int        `json:"consecutive_failures" gorm:"default:0"`
+	FailingSince        *time.Time `json:"failing_since,omitempty"`
+	LastSuccessAt       *time.Time `json:"last_success_at,omitempty"`
+	LastFailureAt       *time.Time `json:"last_failure_at,omitempty"`
+	DisabledAt          *time.Time `json:"disabled_at,omitempty"`
+	DisabledReason      string     `json:"disabled_reason,omitempty"`
+
+	// Circuit breaker: after repeated timeouts deliveries are skipped, not
+	// sent, until CircuitOpenUntil
+	ConsecutiveTimeouts int        `json:"consecutive_timeouts" gorm:"default:0"`
+	CircuitOpenUntil    *time.Time `json:"circuit_open_until,omitempty"`
+
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
 // WebhookDelivery represents a webhook delivery attempt
@@ -160,17 +294,31 @@ type WebhookDelivery struct {
 	EndpointID   uint           `json:"endpoint_id" gorm:"not null;index"`
 	Event        WebhookEvent   `json:"event" gorm:"not null"`
 	Payload      InterfaceMap   `json:"payload" gorm:"type:text"`
-	Status       string         `json:"status" gorm:"not null"` // pending, success, failed
+	Status       string         `json:"status" gorm:"not null"` // pending, running, success, failed, skipped
 	StatusCode   int            `json:"status_code"`
 	Response     string         `json:"response"`
 	Error        string         `json:"error"`
 	AttemptCount int            `json:"attempt_count" gorm:"default:0"`
+	LatencyMS    int64          `json:"latency_ms"` // response time of the last attempt
 	NextRetryAt  *time.Time     `json:"next_retry_at"`
 	CreatedAt    time.Time      `json:"created_at"`
 	UpdatedAt    time.Time      `json:"updated_at"`
 	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
+// WebhookDeliveryStat counts the delivery attempts to an endpoint in one
+// hour with one outcome and latency bucket. Delivery statistics are summed
+// from these rows, a few per hour, instead of from the deliveries
+type WebhookDeliveryStat struct {
+	ID         uint      `json:"-" gorm:"primaryKey"`
+	EndpointID uint      `json:"endpoint_id" gorm:"not null;uniqueIndex:idx_webhook_delivery_stats_bucket"`
+	Hour       time.Time `json:"hour" gorm:"not null;uniqueIndex:idx_webhook_delivery_stats_bucket"`
+	Success    bool      `json:"success" gorm:"not null;uniqueIndex:idx_webhook_delivery_stats_bucket"`
+	Bucket     int       `json:"bucket" gorm:"not null;uniqueIndex:idx_webhook_delivery_stats_bucket"` // index into the latency buckets
+	Attempts   int64     `json:"attempts" gorm:"not null;default:0"`
+	Retries    int64     `json:"retries" gorm:"not null;default:0"` // attempts after the first of their delivery
+}
+
 // RSSFeed represents an RSS feed configuration
 type RSSFeed struct {
 	ID          uint           `json:"id" gorm:"primaryKey"`
@@ -187,6 +335,8 @@ type RSSFeed struct {
 	PublicKey   string         `json:"public_key" gorm:"unique;not null"`
 	Collections UintSlice      `json:"collections" gorm:"type:text"` // Collection IDs
 	Tags        StringSlice    `json:"tags" gorm:"type:text"`
+	TeamOwned   bool           `json:"team_owned" gorm:"default:false"` // see WebhookEndpoint.TeamOwned
+	CreatedBy   string         `json:"created_by,omitempty"`
 	CreatedAt   time.Time      `json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
@@ -196,8 +346,8 @@ type RSSFeed struct {
 type BulkOperation struct {
 	ID             uint           `json:"id" gorm:"primaryKey"`
 	UserID         string         `json:"user_id" gorm:"not null;index"`
-	Type           string         `json:"type" gorm:"not null"`      // import, export, delete, update
-	Status         string         `json:"status" gorm:"not null"`    // pending, running, completed, failed
+	Type           string         `json:"type" gorm:"not null"`      // import, export, delete, update, collection_merge, collection_split, collection_transfer, bookmark_cross_post
+	Status         string         `json:"status" gorm:"not null"`    // pending, running, completed, failed, undone
 	Progress       int            `json:"progress" gorm:"default:0"` // 0-100
 	TotalItems     int            `json:"total_items" gorm:"default:0"`
 	ProcessedItems int            `json:"processed_items" gorm:"default:0"`
@@ -205,8 +355,10 @@ type BulkOperation struct {
 	Parameters     InterfaceMap   `json:"parameters" gorm:"type:text"`
 	Result         InterfaceMap   `json:"result" gorm:"type:text"`
 	Error          string         `json:"error"`
+	UndoData       string         `json:"-" gorm:"type:text"` // Serialized state needed to revert the operation
 	StartedAt      *time.Time     `json:"started_at"`
 	CompletedAt    *time.Time     `json:"completed_at"`
+	UndoneAt       *time.Time     `json:"undone_at,omitempty"`
 	CreatedAt      time.Time      `json:"created_at"`
 	UpdatedAt      time.Time      `json:"updated_at"`
 	DeletedAt      gorm.DeletedAt `json:"-" gorm:"index"`
@@ -224,6 +376,10 @@ type BackupJob struct {
 	Compression   string         `json:"compression" gorm:"default:gzip"`
 	Encrypted     bool           `json:"encrypted" gorm:"default:false"`
 	RetentionDays int            `json:"retention_days" gorm:"default:30"`
+	DestinationID *uint          `json:"destination_id,omitempty" gorm:"index"`
+	RemoteURL     string         `json:"remote_url,omitempty"`  // Location in the external destination
+	VolumeSize    int64          `json:"volume_size,omitempty"` // split the archive into volumes of this many bytes
+	Volumes       int            `json:"volumes,omitempty"`     // volumes written; FilePath is then their manifest
 	Error         string         `json:"error"`
 	StartedAt     *time.Time     `json:"started_at"`
 	CompletedAt   *time.Time     `json:"completed_at"`
@@ -232,27 +388,93 @@ type BackupJob struct {
 	DeletedAt     gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
+// BackupDestination represents a user owned S3 compatible bucket backups are uploaded to
+type BackupDestination struct {
+	ID              uint           `json:"id" gorm:"primaryKey"`
+	UserID          string         `json:"user_id" gorm:"not null;index"`
+	Name            string         `json:"name" gorm:"not null"`
+	Type            string         `json:"type" gorm:"not null;default:s3"`
+	Endpoint        string         `json:"endpoint" gorm:"not null"` // host[:port] or URL
+	Region          string         `json:"region"`
+	Bucket          string         `json:"bucket" gorm:"not null"`
+	Prefix          string         `json:"prefix"`
+	AccessKeyID     string         `json:"access_key_id" gorm:"not null"`
+	SecretAccessKey string         `json:"-" gorm:"not null"` // Encrypted at rest
+	UseSSL          bool           `json:"use_ssl" gorm:"default:true"`
+	KeepLocalCopy   bool           `json:"keep_local_copy" gorm:"default:false"`
+	Active          bool           `json:"active" gorm:"default:true"`
+	LastTestedAt    *time.Time     `json:"last_tested_at"`
+	LastError       string         `json:"last_error"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
 // APIIntegration represents an external API integration
 type APIIntegration struct {
-	ID           uint           `json:"id" gorm:"primaryKey"`
-	UserID       string         `json:"user_id" gorm:"not null;index"`
-	Name         string         `json:"name" gorm:"not null"`
-	Type         string         `json:"type" gorm:"not null"` // pocket, instapaper, raindrop, etc.
-	BaseURL      string         `json:"base_url" gorm:"not null"`
-	APIKey       string         `json:"-" gorm:"not null"` // Hidden from JSON
-	APISecret    string         `json:"-"`                 // Hidden from JSON
-	AccessToken  string         `json:"-"`                 // Hidden from JSON
-	RefreshToken string         `json:"-"`                 // Hidden from JSON
-	TokenExpiry  *time.Time     `json:"-"`
-	Active       bool           `json:"active" gorm:"default:true"`
-	RateLimit    int            `json:"rate_limit" gorm:"default:100"` // requests per hour
-	LastSync     *time.Time     `json:"last_sync"`
-	SyncEnabled  bool           `json:"sync_enabled" gorm:"default:false"`
-	SyncInterval int            `json:"sync_interval" gorm:"default:3600"` // seconds
-	Config       InterfaceMap   `json:"config" gorm:"type:text"`
-	CreatedAt    time.Time      `json:"created_at"`
-	UpdatedAt    time.Time      `json:"updated_at"`
-	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
+	ID           uint         `json:"id" gorm:"primaryKey"`
+	UserID       string       `json:"user_id" gorm:"not null;index"`
+	Name         string       `json:"name" gorm:"not null"`
+	Type         string       `json:"type" gorm:"not null"` // pocket, instapaper, raindrop, etc.
+	BaseURL      string       `json:"base_url" gorm:"not null"`
+	APIKey       string       `json:"-" gorm:"not null"` // Hidden from JSON
+	APISecret    string       `json:"-"`                 // Hidden from JSON
+	AccessToken  string       `json:"-"`                 // Hidden from JSON
+	RefreshToken string       `json:"-"`                 // Hidden from JSON
+	TokenExpiry  *time.Time   `json:"-"`
+	Active       bool         `json:"active" gorm:"default:true"`
+	RateLimit    int          `json:"rate_limit" gorm:"default:100"` // requests per hour
+	LastSync     *time.Time   `json:"last_sync"`
+	SyncEnabled  bool         `json:"sync_enabled" gorm:"default:false"`
+	SyncInterval int          `json:"sync_interval" gorm:"default:3600"` // seconds
+	Config       InterfaceMap `json:"config" gorm:"type:text"`
+
+	// Sync health, used to pause integrations that keep failing
+	ConsecutiveFailures int        `json:"consecutive_failures" gorm:"default:0"`
+	LastSuccessAt       *time.Time `json:"last_success_at,omitempty"`
+	LastFailureAt       *time.Time `json:"last_failure_at,omitempty"`
+	PausedAt            *time.Time `json:"paused_at,omitempty"`
+	PausedReason        string     `json:"paused_reason,omitempty"`
+
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// SyncRun records one sync of an API integration
+type SyncRun struct {
+	ID            uint        `json:"id" gorm:"primaryKey"`
+	IntegrationID uint        `json:"integration_id" gorm:"not null;index"`
+	UserID        string      `json:"user_id" gorm:"not null;index"`
+	Trigger       string      `json:"trigger" gorm:"not null"` // manual, scheduled
+	Status        string      `json:"status" gorm:"not null"`  // running, success, failed
+	StartedAt     time.Time   `json:"started_at"`
+	FinishedAt    *time.Time  `json:"finished_at"`
+	ItemsAdded    int         `json:"items_added"`
+	ItemsUpdated  int         `json:"items_updated"`
+	ItemsFailed   int         `json:"items_failed"`
+	Error         string      `json:"error,omitempty" gorm:"type:text"`
+	ErrorDetails  StringSlice `json:"error_details,omitempty" gorm:"type:text"`
+	CreatedAt     time.Time   `json:"created_at"`
+}
+
+// ImportUpload is an import file uploaded straight to object storage with
+// a presigned URL, later referenced by an import operation
+type ImportUpload struct {
+	ID          uint           `json:"id" gorm:"primaryKey"`
+	UserID      string         `json:"user_id" gorm:"not null;index"`
+	ObjectKey   string         `json:"object_key" gorm:"not null;uniqueIndex"`
+	Filename    string         `json:"filename" gorm:"not null"`
+	Source      string         `json:"source,omitempty"`       // chrome, firefox, safari, ...
+	AutoImport  bool           `json:"auto_import"`            // import once storage reports the upload
+	Status      string         `json:"status" gorm:"not null"` // pending, uploaded, imported
+	Size        int64          `json:"size"`
+	OperationID *uint          `json:"operation_id,omitempty"`
+	ExpiresAt   time.Time      `json:"expires_at"` // when the latest upload URL stops working
+	UploadedAt  *time.Time     `json:"uploaded_at,omitempty"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
 // AutomationRule represents an automation rule
@@ -266,8 +488,13 @@ type AutomationRule struct {
 	Actions        InterfaceMap   `json:"actions" gorm:"type:text"`
 	Active         bool           `json:"active" gorm:"default:true"`
 	Priority       int            `json:"priority" gorm:"default:0"`
+	Sandbox        bool           `json:"sandbox" gorm:"default:false"` // only logs what it would do
 	ExecutionCount int            `json:"execution_count" gorm:"default:0"`
 	LastExecuted   *time.Time     `json:"last_executed"`
+	Schedule       string         `json:"schedule,omitempty"` // cron expression or "every" interval of the schedule trigger
+	NextRunAt      *time.Time     `json:"next_run_at,omitempty" gorm:"index"`
+	TeamOwned      bool           `json:"team_owned" gorm:"default:false"` // see WebhookEndpoint.TeamOwned
+	CreatedBy      string         `json:"created_by,omitempty"`
 	CreatedAt      time.Time      `json:"created_at"`
 	UpdatedAt      time.Time      `json:"updated_at"`
 	DeletedAt      gorm.DeletedAt `json:"-" gorm:"index"`
@@ -300,11 +527,40 @@ type BulkOperationRequest struct {
 	Parameters map[string]interface{} `json:"parameters"`
 }
 
+// ImportUploadRequest asks for a URL to upload an import file to. Sending
+// the object key of an earlier upload issues a fresh URL for it, e.g. to
+// retry an upload whose URL expired
+type ImportUploadRequest struct {
+	Filename   string `json:"filename" binding:"required,max=255"`
+	Source     string `json:"source,omitempty"`
+	AutoImport bool   `json:"auto_import,omitempty"`
+	ObjectKey  string `json:"object_key,omitempty"`
+}
+
 // BackupRequest represents a backup request
 type BackupRequest struct {
 	Type        string `json:"type" binding:"required"` // full, incremental
 	Compression string `json:"compression,omitempty"`   // gzip, zip, none
 	Encrypted   bool   `json:"encrypted,omitempty"`
+	// DestinationID uploads the backup to a registered backup destination
+	DestinationID *uint `json:"destination_id,omitempty"`
+	// VolumeSize splits the archive into volumes of at most this many
+	// bytes, listed in a manifest, for downloads over flaky connections
+	VolumeSize int64 `json:"volume_size,omitempty"`
+}
+
+// BackupDestinationRequest represents a request to register or update a backup destination
+type BackupDestinationRequest struct {
+	Name            string `json:"name" binding:"required"`
+	Endpoint        string `json:"endpoint" binding:"required"`
+	Region          string `json:"region"`
+	Bucket          string `json:"bucket" binding:"required"`
+	Prefix          string `json:"prefix"`
+	AccessKeyID     string `json:"access_key_id" binding:"required"`
+	SecretAccessKey string `json:"secret_access_key"` // Required on create, kept when empty on update
+	UseSSL          *bool  `json:"use_ssl"`
+	KeepLocalCopy   bool   `json:"keep_local_copy"`
+	Active          *bool  `json:"active"`
 }
 
 // APIIntegrationRequest represents an API integration request
@@ -327,4 +583,9 @@ type AutomationRuleRequest struct {
 	Conditions  map[string]interface{} `json:"conditions,omitempty"`
 	Actions     map[string]interface{} `json:"actions" binding:"required"`
 	Priority    int                    `json:"priority,omitempty"`
+	// Sandbox creates the rule in the sandbox; promoting it makes it live
+	Sandbox bool `json:"sandbox,omitempty"`
+	// Schedule is required by the schedule trigger: a cron expression such
+	// as "0 9 * * 0" or an interval such as "every 6 hours"
+	Schedule string `json:"schedule,omitempty"`
 }
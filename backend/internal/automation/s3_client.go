@@ -0,0 +1,149 @@
+package automation
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// DestinationClient writes backup artifacts to an external destination
+type DestinationClient interface {
+	// TestConnection verifies the destination accepts writes
+	TestConnection(ctx context.Context) error
+	// Upload stores an object and returns its location
+	Upload(ctx context.Context, key string, body io.Reader, size int64) (string, error)
+}
+
+// DestinationClientFactory builds a client for a destination with its decrypted secret
+type DestinationClientFactory func(destination *BackupDestination, secretAccessKey string) DestinationClient
+
+// DestinationError describes a destination's refusal of a request
+type DestinationError struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+// Error implements the error interface
+func (e *DestinationError) Error() string {
+	detail := e.Code
+	if e.Message != "" {
+		detail += ": " + e.Message
+	}
+	if detail == "" {
+		detail = http.StatusText(e.StatusCode)
+	}
+	return fmt.Sprintf("destination rejected request (HTTP %d): %s", e.StatusCode, detail)
+}
+
+// Unwrap lets callers match destination errors with errors.Is
+func (e *DestinationError) Unwrap() error {
+	return ErrBackupDestinationRejected
+}
+
+// s3Client stores objects in AWS S3 or a compatible service through the
+// MinIO client, addressing buckets path-style
+type s3Client struct {
+	client *minio.Client
+	bucket string
+	// err is why the client could not be built, returned by every call
+	err error
+}
+
+// NewS3DestinationClient is the DestinationClientFactory for S3 compatible
+// destinations
+func NewS3DestinationClient(destination *BackupDestination, secretAccessKey string) DestinationClient {
+	// The endpoint may be a bare host or a URL whose scheme picks TLS
+	endpoint, secure := destination.Endpoint, destination.UseSSL
+	if parsed, err := url.Parse(destination.Endpoint); err == nil && parsed.Host != "" {
+		endpoint, secure = parsed.Host, parsed.Scheme == "https"
+	}
+
+	region := destination.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:        credentials.NewStaticV4(destination.AccessKeyID, secretAccessKey, ""),
+		Secure:       secure,
+		Region:       region,
+		BucketLookup: minio.BucketLookupPath,
+	})
+	if err != nil {
+		err = fmt.Errorf("%w: %v", ErrBackupDestinationUnreachable, err)
+	}
+	return &s3Client{client: client, bucket: destination.Bucket, err: err}
+}
+
+// TestConnection writes and removes a small marker object
+func (c *s3Client) TestConnection(ctx context.Context) error {
+	key := ".bookmark-sync-connectivity-check"
+	body := []byte(time.Now().UTC().Format(time.RFC3339))
+
+	if _, err := c.Upload(ctx, key, bytes.NewReader(body), int64(len(body))); err != nil {
+		return err
+	}
+	return destinationError(c.client.RemoveObject(ctx, c.bucket, key, minio.RemoveObjectOptions{}))
+}
+
+// Upload stores an object, in parts when it is large
+func (c *s3Client) Upload(ctx context.Context, key string, body io.Reader, size int64) (string, error) {
+	if c.err != nil {
+		return "", c.err
+	}
+	key = strings.TrimLeft(key, "/")
+	if _, err := c.client.PutObject(ctx, c.bucket, key, body, size, minio.PutObjectOptions{}); err != nil {
+		return "", destinationError(err)
+	}
+	return fmt.Sprintf("s3://%s/%s", c.bucket, key), nil
+}
+
+// PresignPut returns a URL that uploads an object with a plain PUT until
+// it expires, so clients can send files without going through the API
+func (c *s3Client) PresignPut(key string, expiry time.Duration) (string, error) {
+	if c.err != nil {
+		return "", c.err
+	}
+	presigned, err := c.client.PresignedPutObject(context.Background(), c.bucket, strings.TrimLeft(key, "/"), expiry)
+	if err != nil {
+		return "", fmt.Errorf("failed to presign upload: %w", err)
+	}
+	return presigned.String(), nil
+}
+
+// Stat returns the size of an object, or ErrImportObjectNotFound
+func (c *s3Client) Stat(ctx context.Context, key string) (int64, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	info, err := c.client.StatObject(ctx, c.bucket, strings.TrimLeft(key, "/"), minio.StatObjectOptions{})
+	if err != nil {
+		if minio.ToErrorResponse(err).StatusCode == http.StatusNotFound {
+			return 0, ErrImportObjectNotFound
+		}
+		return 0, destinationError(err)
+	}
+	return info.Size, nil
+}
+
+// destinationError tells a destination's refusal, carrying its S3 error
+// code, from a destination that could not be reached
+func destinationError(err error) error {
+	if err == nil {
+		return nil
+	}
+	response := minio.ToErrorResponse(err)
+	if response.StatusCode == 0 {
+		return fmt.Errorf("%w: %v", ErrBackupDestinationUnreachable, err)
+	}
+	return &DestinationError{StatusCode: response.StatusCode, Code: response.Code, Message: response.Message}
+}
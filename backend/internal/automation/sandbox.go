@@ -0,0 +1,300 @@
+package automation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// maxSandboxCaptures is how many captures a user's sandbox log keeps; older
+// ones are dropped as new ones come in
+const maxSandboxCaptures = 200
+
+// Kinds of sandbox captures
+const (
+	SandboxCaptureWebhook = "webhook"
+	SandboxCaptureRule    = "rule"
+)
+
+// ruleTriggerEvents maps the rule triggers named after actions to the
+// events firing them; other triggers name the event itself
+var ruleTriggerEvents = map[string]WebhookEvent{
+	"bookmark_added":     WebhookEventBookmarkCreated,
+	"bookmark_updated":   WebhookEventBookmarkUpdated,
+	"bookmark_deleted":   WebhookEventBookmarkDeleted,
+	"collection_created": WebhookEventCollectionCreated,
+	"link_broken":        WebhookEventLinkBroken,
+
+	"bookmark_views_reached": WebhookEventBookmarkViewsReached,
+	"bookmark_unvisited":     WebhookEventBookmarkUnvisited,
+}
+
+// SandboxCapture is an entry of a user's sandbox log: a webhook delivery
+// that was recorded instead of sent, or what a sandbox rule would have done
+// with an event
+type SandboxCapture struct {
+	ID         uint         `json:"id" gorm:"primaryKey"`
+	UserID     string       `json:"user_id" gorm:"not null;index"`
+	Kind       string       `json:"kind" gorm:"size:16;not null"`
+	Event      WebhookEvent `json:"event" gorm:"not null"`
+	EndpointID *uint        `json:"endpoint_id,omitempty" gorm:"index"`
+	RuleID     *uint        `json:"rule_id,omitempty" gorm:"index"`
+	// Replayed captures come from a test event rather than a live one
+	Replayed bool `json:"replayed"`
+
+	// The request a webhook capture would have sent
+	URL     string    `json:"url,omitempty"`
+	Headers StringMap `json:"headers,omitempty" gorm:"type:text"`
+	Body    string    `json:"body,omitempty" gorm:"type:text"`
+
+	// Whether a rule's conditions matched, and the actions it would have run
+	Matched bool         `json:"matched"`
+	Actions InterfaceMap `json:"actions,omitempty" gorm:"type:text"`
+
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SandboxEventRequest is a test event replayed through the sandbox
+type SandboxEventRequest struct {
+	Event string                 `json:"event" binding:"required"`
+	Data  map[string]interface{} `json:"data"`
+}
+
+// captureDelivery records the request a sandbox endpoint would have been sent
+func (s *Service) captureDelivery(endpoint *WebhookEndpoint, payload WebhookPayload, replayed bool) *SandboxCapture {
+	capture := &SandboxCapture{
+		UserID:     endpoint.UserID,
+		Kind:       SandboxCaptureWebhook,
+		Event:      payload.Event,
+		EndpointID: &endpoint.ID,
+		Replayed:   replayed,
+		URL:        endpoint.URL,
+	}
+
+	body, err := RenderPayload(endpoint.PayloadTemplate, payload)
+	if err != nil {
+		capture.Error = fmt.Sprintf("Failed to render payload: %v", err)
+	} else {
+		capture.Body = string(body)
+		capture.Headers = StringMap(s.webhookHeaders(endpoint, body, payload))
+	}
+
+	s.saveCapture(capture)
+	return capture
+}
+
+// captureRule records whether a sandbox rule matched an event and the
+// actions it would have run
+func (s *Service) captureRule(rule *AutomationRule, payload WebhookPayload, matched, replayed bool) *SandboxCapture {
+	capture := &SandboxCapture{
+		UserID:   rule.UserID,
+		Kind:     SandboxCaptureRule,
+		Event:    payload.Event,
+		RuleID:   &rule.ID,
+		Replayed: replayed,
+		Matched:  matched,
+	}
+	if matched {
+		capture.Actions = rule.Actions
+	}
+
+	s.saveCapture(capture)
+	return capture
+}
+
+// saveCapture stores a capture and trims the user's log to its newest entries
+func (s *Service) saveCapture(capture *SandboxCapture) {
+	if err := s.db.Create(capture).Error; err != nil {
+		return
+	}
+
+	var cutoff []uint
+	s.db.Model(&SandboxCapture{}).Where("user_id = ?", capture.UserID).
+		Order("id DESC").Offset(maxSandboxCaptures).Limit(1).Pluck("id", &cutoff)
+	if len(cutoff) > 0 {
+		s.db.Where("user_id = ? AND id <= ?", capture.UserID, cutoff[0]).Delete(&SandboxCapture{})
+	}
+}
+
+// runSandboxRules runs the user's sandbox rules triggered by the event
+// against its shadow copy, capturing their outcome
+func (s *Service) runSandboxRules(userID string, payload WebhookPayload, replayed bool) []SandboxCapture {
+	var rules []AutomationRule
+	if err := s.db.Where("user_id = ? AND active = ? AND sandbox = ?", userID, true, true).
+		Order("priority DESC, created_at DESC").Find(&rules).Error; err != nil {
+		return nil
+	}
+
+	data := s.structToMap(payload)["data"]
+	fields, _ := data.(map[string]interface{})
+
+	captures := []SandboxCapture{}
+	for i := range rules {
+		if ruleEvent(rules[i].Trigger) != payload.Event {
+			continue
+		}
+		matched := conditionsMatch(rules[i].Conditions, fields)
+		captures = append(captures, *s.captureRule(&rules[i], payload, matched, replayed))
+	}
+	return captures
+}
+
+// ruleEvent returns the event firing a rule trigger
+func ruleEvent(trigger string) WebhookEvent {
+	if event, ok := ruleTriggerEvents[trigger]; ok {
+		return event
+	}
+	return WebhookEvent(trigger)
+}
+
+// conditionsMatch evaluates rule conditions against an event's data. They
+// are the conditions bookmark suggestions understand: domain, tag,
+// url_contains and title_contains, plus the numeric thresholds of the
+// threshold events; any other condition never matches
+func conditionsMatch(conditions InterfaceMap, data map[string]interface{}) bool {
+	field := func(name string) string {
+		value, _ := data[name].(string)
+		return strings.ToLower(value)
+	}
+
+	for key, raw := range conditions {
+		if dataField, ok := thresholdConditions[key]; ok {
+			threshold, isNumber := number(raw)
+			actual, hasField := number(data[dataField])
+			if !isNumber || !hasField || actual < threshold {
+				return false
+			}
+			continue
+		}
+
+		value, ok := raw.(string)
+		if !ok || value == "" {
+			return false
+		}
+		value = strings.ToLower(value)
+
+		switch key {
+		case "domain":
+			parsed, err := url.Parse(field("url"))
+			if err != nil {
+				return false
+			}
+			host := strings.TrimPrefix(parsed.Hostname(), "www.")
+			if host != value && !strings.HasSuffix(host, "."+value) {
+				return false
+			}
+		case "tag":
+			tags, _ := data["tags"].([]interface{})
+			found := false
+			for _, tag := range tags {
+				if name, ok := tag.(string); ok && strings.ToLower(name) == value {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		case "url_contains":
+			if !strings.Contains(field("url"), value) {
+				return false
+			}
+		case "title_contains":
+			if !strings.Contains(field("title"), value) {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// ReplaySandboxEvent sends a test event through the user's sandbox
+// endpoints and rules only, returning what they captured
+func (s *Service) ReplaySandboxEvent(ctx context.Context, userID string, req SandboxEventRequest) ([]SandboxCapture, error) {
+	if !knownEvent(WebhookEvent(req.Event)) {
+		return nil, fmt.Errorf("%w: unknown event %s", ErrInvalidParameters, req.Event)
+	}
+
+	payload := WebhookPayload{
+		Event:     WebhookEvent(req.Event),
+		Timestamp: time.Now(),
+		UserID:    userID,
+		Data:      req.Data,
+	}
+
+	var endpoints []WebhookEndpoint
+	if err := s.db.Where("user_id = ? AND active = ? AND sandbox = ?", userID, true, true).Find(&endpoints).Error; err != nil {
+		return nil, fmt.Errorf("failed to get webhook endpoints: %w", err)
+	}
+
+	captures := []SandboxCapture{}
+	for i := range endpoints {
+		if s.isEventSubscribed(endpoints[i].Events, req.Event) {
+			captures = append(captures, *s.captureDelivery(&endpoints[i], payload, true))
+		}
+	}
+	captures = append(captures, s.runSandboxRules(userID, payload, true)...)
+	return captures, nil
+}
+
+// GetSandboxCaptures returns the user's sandbox log, newest first
+func (s *Service) GetSandboxCaptures(userID string, limit int) ([]SandboxCapture, error) {
+	if limit <= 0 || limit > maxSandboxCaptures {
+		limit = 50
+	}
+
+	var captures []SandboxCapture
+	if err := s.db.Where("user_id = ?", userID).Order("id DESC").Limit(limit).Find(&captures).Error; err != nil {
+		return nil, fmt.Errorf("failed to get sandbox captures: %w", err)
+	}
+	return captures, nil
+}
+
+// ClearSandboxCaptures empties the user's sandbox log
+func (s *Service) ClearSandboxCaptures(userID string) error {
+	if err := s.db.Where("user_id = ?", userID).Delete(&SandboxCapture{}).Error; err != nil {
+		return fmt.Errorf("failed to clear sandbox captures: %w", err)
+	}
+	return nil
+}
+
+// PromoteAutomationRule takes a sandbox rule live
+func (s *Service) PromoteAutomationRule(userID string, id uint) (*AutomationRule, error) {
+	var rule AutomationRule
+	if err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&rule).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrResourceNotFound
+		}
+		return nil, fmt.Errorf("failed to get automation rule: %w", err)
+	}
+
+	if err := s.db.Model(&rule).Update("sandbox", false).Error; err != nil {
+		return nil, fmt.Errorf("failed to promote automation rule: %w", err)
+	}
+	return &rule, nil
+}
+
+// PromoteWebhookEndpoint takes a sandbox endpoint live, so its next
+// deliveries are sent
+func (s *Service) PromoteWebhookEndpoint(userID string, id uint) (*WebhookEndpoint, error) {
+	var endpoint WebhookEndpoint
+	if err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&endpoint).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWebhookEndpointNotFound
+		}
+		return nil, fmt.Errorf("failed to get webhook endpoint: %w", err)
+	}
+
+	if err := s.db.Model(&endpoint).Update("sandbox", false).Error; err != nil {
+		return nil, fmt.Errorf("failed to promote webhook endpoint: %w", err)
+	}
+	return &endpoint, nil
+}
@@ -0,0 +1,189 @@
+package automation
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConditionsMatch(t *testing.T) {
+	data := map[string]interface{}{
+		"url":   "https://www.blog.golang.org/generics",
+		"title": "Intro to Generics",
+		"tags":  []interface{}{"Go", "news"},
+		"views": 120,
+	}
+
+	tests := []struct {
+		name       string
+		conditions InterfaceMap
+		matched    bool
+	}{
+		{"no conditions", nil, true},
+		{"domain and subdomains", InterfaceMap{"domain": "golang.org"}, true},
+		{"other domain", InterfaceMap{"domain": "go.dev"}, false},
+		{"tag ignores case", InterfaceMap{"tag": "go"}, true},
+		{"all conditions must match", InterfaceMap{"tag": "go", "title_contains": "rust"}, false},
+		{"url and title", InterfaceMap{"url_contains": "GENERICS", "title_contains": "intro"}, true},
+		{"unknown condition", InterfaceMap{"author": "rsc"}, false},
+		{"non-string value", InterfaceMap{"tag": 3}, false},
+		{"threshold reached", InterfaceMap{"min_views": float64(100)}, true},
+		{"threshold not reached", InterfaceMap{"min_views": float64(200)}, false},
+		{"threshold without data", InterfaceMap{"unvisited_months": float64(6)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.matched, conditionsMatch(tt.conditions, data))
+		})
+	}
+
+	assert.False(t, conditionsMatch(InterfaceMap{"tag": "go"}, nil))
+	assert.Equal(t, WebhookEventBookmarkCreated, ruleEvent("bookmark_added"))
+	assert.Equal(t, WebhookEventShareViewed, ruleEvent("share.viewed"))
+	assert.Equal(t, WebhookEventBookmarkUnvisited, ruleEvent("bookmark_unvisited"))
+}
+
+func (suite *AutomationServiceTestSuite) TestSandbox_CapturesInsteadOfDelivering() {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	service := suite.GetTestService()
+	service.executor = syncExecutor{}
+	userID := suite.GetTestUserID()
+
+	// Given: A sandbox endpoint with custom headers and a sandbox rule
+	endpoint, err := service.CreateWebhookEndpoint(userID, WebhookEndpointRequest{
+		Name:    "Draft",
+		URL:     server.URL,
+		Events:  []string{string(WebhookEventBookmarkCreated)},
+		Headers: map[string]string{"x-team": "infra"},
+		Sandbox: true,
+	})
+	suite.Require().NoError(err)
+	rule, err := service.CreateAutomationRule(userID, AutomationRuleRequest{
+		Name:       "File Go posts",
+		Trigger:    "bookmark_added",
+		Conditions: map[string]interface{}{"tag": "go"},
+		Actions:    map[string]interface{}{"add_to_collection": float64(3)},
+		Sandbox:    true,
+	})
+	suite.Require().NoError(err)
+
+	// When: A live event fires
+	data := map[string]interface{}{"url": "https://go.dev/blog", "tags": []string{"go"}}
+	suite.Require().NoError(service.TriggerWebhook(context.Background(), WebhookEventBookmarkCreated, userID, data))
+
+	// Then: Nothing is sent and both outcomes are in the sandbox log
+	suite.Equal(int32(0), atomic.LoadInt32(&requests))
+	var deliveries int64
+	suite.GetTestDB().Model(&WebhookDelivery{}).Count(&deliveries)
+	suite.Zero(deliveries)
+
+	captures, err := service.GetSandboxCaptures(userID, 0)
+	suite.Require().NoError(err)
+	suite.Require().Len(captures, 2)
+
+	ruleCapture, webhookCapture := captures[0], captures[1]
+	suite.Equal(SandboxCaptureWebhook, webhookCapture.Kind)
+	suite.Equal(endpoint.ID, *webhookCapture.EndpointID)
+	suite.False(webhookCapture.Replayed)
+	suite.Equal(server.URL, webhookCapture.URL)
+	suite.Contains(webhookCapture.Body, `"https://go.dev/blog"`)
+	suite.Equal("infra", webhookCapture.Headers["X-Team"])
+	suite.Equal(service.generateSignature([]byte(webhookCapture.Body), endpoint.Secret), webhookCapture.Headers["X-Webhook-Signature"])
+
+	suite.Equal(SandboxCaptureRule, ruleCapture.Kind)
+	suite.Equal(rule.ID, *ruleCapture.RuleID)
+	suite.True(ruleCapture.Matched)
+	suite.Equal(float64(3), ruleCapture.Actions["add_to_collection"])
+
+	// And: Manual runs of a sandbox rule are captured, not counted
+	result, err := service.ExecuteAutomationRule(userID, rule.ID)
+	suite.Require().NoError(err)
+	suite.Equal("captured", result["status"])
+	var stored AutomationRule
+	suite.Require().NoError(suite.GetTestDB().First(&stored, rule.ID).Error)
+	suite.Zero(stored.ExecutionCount)
+
+	// When: The endpoint is promoted
+	promoted, err := service.PromoteWebhookEndpoint(userID, endpoint.ID)
+	suite.Require().NoError(err)
+	suite.False(promoted.Sandbox)
+	suite.Require().NoError(service.TriggerWebhook(context.Background(), WebhookEventBookmarkCreated, userID, data))
+
+	// Then: Its deliveries go out for real
+	suite.Equal(int32(1), atomic.LoadInt32(&requests))
+
+	_, err = service.PromoteWebhookEndpoint("someone-else", endpoint.ID)
+	suite.ErrorIs(err, ErrWebhookEndpointNotFound)
+}
+
+func (suite *AutomationServiceTestSuite) TestSandbox_LogIsCapped() {
+	service := suite.GetTestService()
+	endpoint := &WebhookEndpoint{ID: 9, UserID: suite.GetTestUserID(), URL: "https://example.com"}
+	for i := 0; i < maxSandboxCaptures+5; i++ {
+		service.captureDelivery(endpoint, WebhookPayload{Event: WebhookEventBookmarkCreated}, true)
+	}
+
+	var count int64
+	suite.GetTestDB().Model(&SandboxCapture{}).Count(&count)
+	suite.Equal(int64(maxSandboxCaptures), count)
+}
+
+func (suite *AutomationHandlerTestSuite) TestSandbox_ReplayAndPromoteRule() {
+	// Given: A sandbox rule that only files bookmarks from go.dev
+	rule, err := suite.GetTestService().CreateAutomationRule(suite.GetTestUserID(), AutomationRuleRequest{
+		Name:       "Go",
+		Trigger:    "bookmark_added",
+		Conditions: map[string]interface{}{"domain": "go.dev"},
+		Actions:    map[string]interface{}{"add_tags": "go"},
+		Sandbox:    true,
+	})
+	suite.Require().NoError(err)
+
+	// When: Replaying test events
+	w := suite.makeRequest("POST", "/api/v1/automation/sandbox/events", SandboxEventRequest{
+		Event: string(WebhookEventBookmarkCreated),
+		Data:  map[string]interface{}{"url": "https://example.com/"},
+	})
+
+	// Then: The rule's miss is recorded
+	suite.Equal(http.StatusOK, w.Code)
+	suite.Contains(w.Body.String(), `"matched":false`)
+	suite.Contains(w.Body.String(), `"replayed":true`)
+
+	w = suite.makeRequest("POST", "/api/v1/automation/sandbox/events", SandboxEventRequest{Event: "bookmark.exploded"})
+	suite.Equal(http.StatusBadRequest, w.Code)
+
+	w = suite.makeRequest("GET", "/api/v1/automation/sandbox/captures", nil)
+	suite.Equal(http.StatusOK, w.Code)
+	suite.Contains(w.Body.String(), fmt.Sprintf(`"rule_id":%d`, rule.ID))
+
+	// When: Promoting the rule
+	w = suite.makeRequest("POST", fmt.Sprintf("/api/v1/automation/rules/%d/promote", rule.ID), nil)
+	suite.Equal(http.StatusOK, w.Code)
+	suite.Contains(w.Body.String(), `"sandbox":false`)
+
+	// Then: It no longer runs in the sandbox
+	w = suite.makeRequest("POST", "/api/v1/automation/sandbox/events", SandboxEventRequest{Event: string(WebhookEventBookmarkCreated)})
+	suite.Equal(http.StatusOK, w.Code)
+	suite.Contains(w.Body.String(), `"captures":[]`)
+
+	w = suite.makeRequest("DELETE", "/api/v1/automation/sandbox/captures", nil)
+	suite.Equal(http.StatusOK, w.Code)
+	captures, err := suite.GetTestService().GetSandboxCaptures(suite.GetTestUserID(), 0)
+	suite.Require().NoError(err)
+	suite.Empty(captures)
+
+	w = suite.makeRequest("POST", "/api/v1/automation/rules/999/promote", nil)
+	suite.Equal(http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,188 @@
+package automation
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// minScheduleInterval is the shortest interval of an "every" schedule. The
+// worker checks for due rules about once a minute, so shorter ones would
+// not run more often anyway
+const minScheduleInterval = 15 * time.Minute
+
+// maxScheduleYears bounds the search for the next time a cron schedule
+// matches. Expressions that can match do so within four years (29 February)
+const maxScheduleYears = 5
+
+// Schedule is when a scheduled rule runs: a five-field cron expression
+// (minute hour day-of-month month day-of-week) evaluated in the owner's time
+// zone, or a fixed interval such as "every 6 hours"
+type Schedule struct {
+	every time.Duration
+	// cron fields, each the set of values it matches
+	minutes, hours, days, months, weekdays map[int]bool
+	// anyDay and anyWeekday record a "*" day field; as in cron, when both
+	// day fields are restricted a time matching either runs
+	anyDay, anyWeekday bool
+}
+
+// scheduleUnits are the interval units of "every" schedules
+var scheduleUnits = map[string]time.Duration{
+	"m": time.Minute, "min": time.Minute, "mins": time.Minute, "minute": time.Minute, "minutes": time.Minute,
+	"h": time.Hour, "hour": time.Hour, "hours": time.Hour,
+	"d": 24 * time.Hour, "day": 24 * time.Hour, "days": 24 * time.Hour,
+}
+
+// cronFields are the bounds of the five cron fields, in order
+var cronFields = []struct {
+	name     string
+	min, max int
+}{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7}, // 0 and 7 are both Sunday
+}
+
+// ParseSchedule parses a cron expression or an "every N minutes|hours|days"
+// interval, also written "every 6h"
+func ParseSchedule(expr string) (*Schedule, error) {
+	expr = strings.ToLower(strings.TrimSpace(expr))
+	if expr == "" {
+		return nil, fmt.Errorf("%w: a schedule is required", ErrAutomationRuleInvalidSchedule)
+	}
+	if rest, ok := strings.CutPrefix(expr, "every "); ok {
+		return parseInterval(strings.TrimSpace(rest))
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("%w: a cron expression has 5 fields", ErrAutomationRuleInvalidSchedule)
+	}
+	schedule := &Schedule{anyDay: fields[2] == "*", anyWeekday: fields[4] == "*"}
+	sets := []*map[int]bool{&schedule.minutes, &schedule.hours, &schedule.days, &schedule.months, &schedule.weekdays}
+	for i, field := range fields {
+		values, err := parseCronField(field, cronFields[i].min, cronFields[i].max)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrAutomationRuleInvalidSchedule, cronFields[i].name, err)
+		}
+		*sets[i] = values
+	}
+	if schedule.weekdays[7] {
+		schedule.weekdays[0] = true
+	}
+	// Days such as 30 February never come
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if !schedule.Next(from, time.UTC).Before(from.AddDate(maxScheduleYears, 0, 0)) {
+		return nil, fmt.Errorf("%w: the schedule never runs", ErrAutomationRuleInvalidSchedule)
+	}
+	return schedule, nil
+}
+
+// parseInterval parses the "N unit" of an "every" schedule
+func parseInterval(expr string) (*Schedule, error) {
+	number := strings.TrimRightFunc(expr, func(r rune) bool { return r < '0' || r > '9' })
+	unit := strings.TrimSpace(expr[len(number):])
+	n, err := strconv.Atoi(number)
+	if err != nil || n <= 0 {
+		return nil, fmt.Errorf("%w: an interval needs a positive number", ErrAutomationRuleInvalidSchedule)
+	}
+	length, ok := scheduleUnits[unit]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown interval unit %q", ErrAutomationRuleInvalidSchedule, unit)
+	}
+	every := time.Duration(n) * length
+	if every < minScheduleInterval {
+		return nil, fmt.Errorf("%w: the interval must be at least %s", ErrAutomationRuleInvalidSchedule, minScheduleInterval)
+	}
+	return &Schedule{every: every}, nil
+}
+
+// parseCronField parses a comma-separated list of *, values, ranges and
+// steps (*/15, 1-5, 0-30/10) into the set of values it matches
+func parseCronField(field string, min, max int) (map[int]bool, error) {
+	values := make(map[int]bool)
+	for _, part := range strings.Split(field, ",") {
+		span, stepText, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepText)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("invalid step %q", stepText)
+			}
+			step = n
+		}
+
+		low, high := min, max
+		if span != "*" {
+			from, to, isRange := strings.Cut(span, "-")
+			var err error
+			if low, err = strconv.Atoi(from); err != nil {
+				return nil, fmt.Errorf("invalid value %q", from)
+			}
+			high = low
+			if isRange {
+				if high, err = strconv.Atoi(to); err != nil {
+					return nil, fmt.Errorf("invalid value %q", to)
+				}
+			} else if hasStep {
+				high = max
+			}
+		}
+		if low < min || high > max || low > high {
+			return nil, fmt.Errorf("%q is out of range %d-%d", part, min, max)
+		}
+		for v := low; v <= high; v += step {
+			values[v] = true
+		}
+	}
+	return values, nil
+}
+
+// Next returns the first time after from the schedule runs. Cron schedules
+// match wall-clock minutes in loc; intervals count from from
+func (s *Schedule) Next(from time.Time, loc *time.Location) time.Time {
+	if s.every > 0 {
+		return from.Add(s.every)
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	t := from.In(loc).Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(maxScheduleYears, 0, 0)
+	for t.Before(limit) {
+		// Skip whole months, days and hours that cannot match
+		switch {
+		case !s.months[int(t.Month())]:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case !s.hours[t.Hour()]:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case !s.minutes[t.Minute()]:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return limit
+}
+
+// dayMatches reports whether the schedule runs on t's day
+func (s *Schedule) dayMatches(t time.Time) bool {
+	day, weekday := s.days[t.Day()], s.weekdays[int(t.Weekday())]
+	switch {
+	case s.anyDay && s.anyWeekday:
+		return true
+	case s.anyDay:
+		return weekday
+	case s.anyWeekday:
+		return day
+	default:
+		return day || weekday
+	}
+}
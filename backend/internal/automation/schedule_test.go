@@ -0,0 +1,81 @@
+package automation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSchedule_Cron(t *testing.T) {
+	// Sunday, 2026-10-11 is followed by Sunday, 2026-10-18
+	from := time.Date(2026, 10, 14, 12, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"0 9 * * 0", time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)},
+		{"0 9 * * 7", time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2026, 10, 14, 12, 45, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)},
+		{"30 8 * * 1-5", time.Date(2026, 10, 15, 8, 30, 0, 0, time.UTC)},
+		{"0 6,18 * * *", time.Date(2026, 10, 14, 18, 0, 0, 0, time.UTC)},
+		// Both day fields restricted: either one matching runs
+		{"0 0 20 * 5", time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		schedule, err := ParseSchedule(tt.expr)
+		require.NoError(t, err, tt.expr)
+		assert.Equal(t, tt.want, schedule.Next(from, time.UTC), tt.expr)
+	}
+}
+
+func TestParseSchedule_TimeZone(t *testing.T) {
+	tokyo := time.FixedZone("JST", 9*60*60)
+	schedule, err := ParseSchedule("0 9 * * 0")
+	require.NoError(t, err)
+
+	// 9:00 on Sunday in Tokyo is midnight UTC
+	next := schedule.Next(time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC), tokyo)
+	assert.Equal(t, time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC), next.UTC())
+}
+
+func TestParseSchedule_Every(t *testing.T) {
+	from := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+
+	for expr, every := range map[string]time.Duration{
+		"every 6 hours":   6 * time.Hour,
+		"Every 6h":        6 * time.Hour,
+		"every 1 day":     24 * time.Hour,
+		"every 30 min":    30 * time.Minute,
+		"every 2 days":    48 * time.Hour,
+		"every 15minutes": 15 * time.Minute,
+	} {
+		schedule, err := ParseSchedule(expr)
+		require.NoError(t, err, expr)
+		assert.Equal(t, from.Add(every), schedule.Next(from, time.UTC), expr)
+	}
+}
+
+func TestParseSchedule_Invalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"every",
+		"every 0 hours",
+		"every 5 minutes",
+		"every 2 weeks",
+		"0 9 * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"0 0 30 2 *",
+		"a b c d e",
+	} {
+		_, err := ParseSchedule(expr)
+		assert.ErrorIs(t, err, ErrAutomationRuleInvalidSchedule, expr)
+	}
+}
@@ -0,0 +1,296 @@
+package automation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RuleTriggerSchedule is the trigger of rules that run on a schedule rather
+// than on an event
+const RuleTriggerSchedule = "schedule"
+
+const (
+	// maxRuleRuns is how many runs a rule's history keeps
+	maxRuleRuns = 50
+	// maxScheduledBookmarks caps the bookmarks one run of a rule selects
+	maxScheduledBookmarks = 5000
+)
+
+// How a rule run was started
+const (
+	RuleRunScheduled = "schedule"
+	RuleRunManual    = "manual"
+)
+
+// Statuses of rule runs
+const (
+	RuleRunSucceeded = "succeeded"
+	RuleRunFailed    = "failed"
+	RuleRunCaptured  = "captured" // a sandbox rule recorded what it would do
+)
+
+// scheduledConditions select the bookmarks of a scheduled rule: saved in the
+// last N days, or without tags. Its other conditions match each bookmark as
+// they match event data
+var scheduledConditions = map[string]bool{
+	"saved_within_days": true,
+	"untagged":          true,
+}
+
+// BookmarkTagger adds tags to bookmarks for scheduled rules, e.g. the
+// bookmark service. It reports whether the bookmark changed
+type BookmarkTagger interface {
+	AddBookmarkTags(ctx context.Context, userID string, bookmarkID uint, tags []string) (bool, error)
+}
+
+// SetBookmarkTagger lets scheduled rules change bookmarks. Without a tagger
+// their runs fail
+func (s *Service) SetBookmarkTagger(tagger BookmarkTagger) {
+	s.bookmarkTagger = tagger
+}
+
+// RuleRun is an entry of a scheduled rule's run history
+type RuleRun struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	RuleID     uint      `json:"rule_id" gorm:"not null;index"`
+	UserID     string    `json:"user_id" gorm:"not null;index"`
+	Trigger    string    `json:"trigger" gorm:"size:16;not null"` // schedule, manual
+	RunBy      string    `json:"run_by,omitempty"`                // user who ran it manually, e.g. a team member
+	Status     string    `json:"status" gorm:"size:16;not null"`
+	Matched    int       `json:"matched"` // bookmarks the rule selected
+	Changed    int       `json:"changed"` // bookmarks its actions changed
+	Error      string    `json:"error,omitempty" gorm:"type:text"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+// checkScheduledRule validates the schedule and actions of a scheduled rule
+// and sets when it first runs
+func (s *Service) checkScheduledRule(rule *AutomationRule, schedule string) error {
+	if rule.Trigger != RuleTriggerSchedule {
+		rule.Schedule = ""
+		rule.NextRunAt = nil
+		return nil
+	}
+
+	parsed, err := ParseSchedule(schedule)
+	if err != nil {
+		return err
+	}
+	if len(ruleTags(rule.Actions)) == 0 {
+		return fmt.Errorf("%w: scheduled rules need an add_tag action", ErrAutomationRuleInvalidAction)
+	}
+	if days, ok := rule.Conditions["saved_within_days"]; ok {
+		if n, isNumber := number(days); !isNumber || n <= 0 {
+			return fmt.Errorf("%w: saved_within_days must be a positive number", ErrAutomationRuleInvalidAction)
+		}
+	}
+
+	if schedule != rule.Schedule || rule.NextRunAt == nil {
+		next := parsed.Next(time.Now(), s.userLocale(context.Background(), rule.UserID).Location)
+		rule.Schedule = schedule
+		rule.NextRunAt = &next
+	}
+	return nil
+}
+
+// ruleTags returns the tags of a rule's add_tag action, a tag or a list
+func ruleTags(actions InterfaceMap) []string {
+	var names []string
+	switch value := actions["add_tag"].(type) {
+	case string:
+		names = []string{value}
+	case []interface{}:
+		for _, item := range value {
+			if name, ok := item.(string); ok {
+				names = append(names, name)
+			}
+		}
+	case []string:
+		names = value
+	}
+
+	result := names[:0]
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name != "" {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+// RunScheduledRules runs the active scheduled rules that are due, then sets
+// when each runs next in its owner's time zone. It returns how many ran
+func (s *Service) RunScheduledRules(ctx context.Context) (int, error) {
+	now := time.Now()
+
+	var rules []AutomationRule
+	if err := s.db.WithContext(ctx).
+		Where("trigger = ? AND active = ? AND next_run_at <= ?", RuleTriggerSchedule, true, now).
+		Order("next_run_at").Find(&rules).Error; err != nil {
+		return 0, fmt.Errorf("failed to get scheduled rules: %w", err)
+	}
+
+	ran := 0
+	for i := range rules {
+		if ctx.Err() != nil {
+			return ran, ctx.Err()
+		}
+		rule := &rules[i]
+		s.runScheduledRule(ctx, rule, RuleRunScheduled, "")
+		ran++
+
+		updates := map[string]interface{}{"next_run_at": nil}
+		if schedule, err := ParseSchedule(rule.Schedule); err == nil {
+			updates["next_run_at"] = schedule.Next(now, s.userLocale(ctx, rule.UserID).Location)
+		}
+		if err := s.db.WithContext(ctx).Model(rule).UpdateColumns(updates).Error; err != nil {
+			return ran, fmt.Errorf("failed to schedule automation rule %d: %w", rule.ID, err)
+		}
+	}
+	return ran, nil
+}
+
+// runScheduledRule selects the rule's bookmarks and tags them, or captures
+// what it would do for a sandbox rule, recording the run in its history
+// with the user who ran it manually
+func (s *Service) runScheduledRule(ctx context.Context, rule *AutomationRule, trigger, runBy string) *RuleRun {
+	run := &RuleRun{RuleID: rule.ID, UserID: rule.UserID, Trigger: trigger, RunBy: runBy, StartedAt: time.Now()}
+	defer s.saveRuleRun(run)
+
+	bookmarkIDs, err := s.scheduledRuleBookmarks(ctx, rule, run.StartedAt)
+	if err != nil {
+		run.Status, run.Error = RuleRunFailed, err.Error()
+		return run
+	}
+	run.Matched = len(bookmarkIDs)
+
+	if rule.Sandbox {
+		payload := WebhookPayload{
+			Event:     ruleEvent(rule.Trigger),
+			Timestamp: run.StartedAt,
+			UserID:    rule.UserID,
+			Data:      map[string]interface{}{"bookmark_ids": bookmarkIDs},
+		}
+		s.captureRule(rule, payload, len(bookmarkIDs) > 0, trigger == RuleRunManual)
+		run.Status = RuleRunCaptured
+		return run
+	}
+	if s.bookmarkTagger == nil {
+		run.Status, run.Error = RuleRunFailed, "bookmark tagging is not available"
+		return run
+	}
+
+	tags := ruleTags(rule.Actions)
+	failed := 0
+	for _, id := range bookmarkIDs {
+		changed, err := s.bookmarkTagger.AddBookmarkTags(ctx, rule.UserID, id, tags)
+		if err != nil {
+			if failed == 0 {
+				run.Error = fmt.Sprintf("bookmark %d: %v", id, err)
+			}
+			failed++
+			continue
+		}
+		if changed {
+			run.Changed++
+		}
+	}
+
+	run.Status = RuleRunSucceeded
+	if failed > 0 {
+		run.Status = RuleRunFailed
+		if failed > 1 {
+			run.Error = fmt.Sprintf("%s (and %d more)", run.Error, failed-1)
+		}
+	}
+
+	s.db.WithContext(ctx).Model(rule).UpdateColumns(map[string]interface{}{
+		"execution_count": gorm.Expr("execution_count + 1"),
+		"last_executed":   run.StartedAt,
+	})
+	return run
+}
+
+// scheduledRuleBookmarks returns the IDs of the owner's bookmarks matching
+// the rule's conditions, oldest first
+func (s *Service) scheduledRuleBookmarks(ctx context.Context, rule *AutomationRule, now time.Time) ([]uint, error) {
+	ownerID, err := strconv.ParseUint(rule.UserID, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("not a user account: %s", rule.UserID)
+	}
+
+	query := s.db.WithContext(ctx).Table("bookmarks").
+		Select("id, url, title, tags, created_at").
+		Where("user_id = ? AND deleted_at IS NULL", ownerID)
+	if days, ok := number(rule.Conditions["saved_within_days"]); ok {
+		query = query.Where("created_at >= ?", now.AddDate(0, 0, -int(days)))
+	}
+	if untagged, _ := rule.Conditions["untagged"].(bool); untagged {
+		query = query.Where("(tags IS NULL OR tags = '' OR tags = '[]' OR tags = 'null')")
+	}
+
+	var bookmarks []thresholdBookmark
+	if err := query.Order("id").Limit(maxScheduledBookmarks).Scan(&bookmarks).Error; err != nil {
+		return nil, fmt.Errorf("failed to get bookmarks: %w", err)
+	}
+
+	conditions := InterfaceMap{}
+	for key, value := range rule.Conditions {
+		if !scheduledConditions[key] {
+			conditions[key] = value
+		}
+	}
+
+	ids := []uint{}
+	for _, bookmark := range bookmarks {
+		tags := []interface{}{}
+		if bookmark.Tags != "" {
+			json.Unmarshal([]byte(bookmark.Tags), &tags)
+		}
+		data := map[string]interface{}{"url": bookmark.URL, "title": bookmark.Title, "tags": tags}
+		if conditionsMatch(conditions, data) {
+			ids = append(ids, bookmark.ID)
+		}
+	}
+	return ids, nil
+}
+
+// saveRuleRun finishes a run and trims the rule's history to its newest runs
+func (s *Service) saveRuleRun(run *RuleRun) {
+	run.FinishedAt = time.Now()
+	if err := s.db.Create(run).Error; err != nil {
+		return
+	}
+
+	var cutoff []uint
+	s.db.Model(&RuleRun{}).Where("rule_id = ?", run.RuleID).
+		Order("id DESC").Offset(maxRuleRuns).Limit(1).Pluck("id", &cutoff)
+	if len(cutoff) > 0 {
+		s.db.Where("rule_id = ? AND id <= ?", run.RuleID, cutoff[0]).Delete(&RuleRun{})
+	}
+}
+
+// GetRuleRuns returns the run history of one of the user's rules, newest first
+func (s *Service) GetRuleRuns(userID string, ruleID uint) ([]RuleRun, error) {
+	var rule AutomationRule
+	if err := s.db.Where("id = ? AND user_id = ?", ruleID, userID).First(&rule).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrResourceNotFound
+		}
+		return nil, fmt.Errorf("failed to get automation rule: %w", err)
+	}
+
+	var runs []RuleRun
+	if err := s.db.Where("rule_id = ?", ruleID).Order("id DESC").Find(&runs).Error; err != nil {
+		return nil, fmt.Errorf("failed to get rule runs: %w", err)
+	}
+	return runs, nil
+}
@@ -0,0 +1,176 @@
+package automation
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// fakeBookmarkTagger records the tags scheduled rules add
+type fakeBookmarkTagger struct {
+	tagged map[uint][]string
+	fail   uint
+}
+
+func (f *fakeBookmarkTagger) AddBookmarkTags(ctx context.Context, userID string, bookmarkID uint, tags []string) (bool, error) {
+	if bookmarkID == f.fail {
+		return false, errors.New("bookmark is locked")
+	}
+	if f.tagged == nil {
+		f.tagged = make(map[uint][]string)
+	}
+	f.tagged[bookmarkID] = append(f.tagged[bookmarkID], tags...)
+	return true, nil
+}
+
+// addScheduledBookmark adds a bookmark of user 7 to the threshold tables
+func (suite *AutomationServiceTestSuite) addScheduledBookmark(id uint, url, tags string, createdAt time.Time) {
+	suite.Require().NoError(suite.GetTestDB().Exec(`INSERT INTO bookmarks (id, user_id, url, title, tags, created_at) VALUES (?, 7, ?, 'Title', ?, ?)`, id, url, tags, createdAt).Error)
+}
+
+func (suite *AutomationServiceTestSuite) createWeeklyReviewRule(sandbox bool) *AutomationRule {
+	rule, err := suite.GetTestService().CreateAutomationRule("7", AutomationRuleRequest{
+		Name:       "Weekly review",
+		Trigger:    RuleTriggerSchedule,
+		Schedule:   "0 9 * * 0",
+		Conditions: map[string]interface{}{"saved_within_days": float64(7), "untagged": true},
+		Actions:    map[string]interface{}{"add_tag": "needs-review"},
+		Sandbox:    sandbox,
+	})
+	suite.Require().NoError(err)
+	return rule
+}
+
+func (suite *AutomationServiceTestSuite) TestCreateAutomationRule_Schedule() {
+	service := suite.GetTestService()
+
+	// When: A scheduled rule is created
+	rule := suite.createWeeklyReviewRule(false)
+
+	// Then: It first runs on the coming Sunday at 9:00
+	suite.Require().NotNil(rule.NextRunAt)
+	suite.Equal(time.Sunday, rule.NextRunAt.Weekday())
+	suite.Equal(9, rule.NextRunAt.Hour())
+	suite.True(rule.NextRunAt.After(time.Now()))
+
+	// And: Invalid schedules and rules without a tag to add are rejected
+	_, err := service.CreateAutomationRule("7", AutomationRuleRequest{
+		Name: "Bad", Trigger: RuleTriggerSchedule, Schedule: "every 5 minutes",
+		Actions: map[string]interface{}{"add_tag": "x"},
+	})
+	suite.ErrorIs(err, ErrAutomationRuleInvalidSchedule)
+	_, err = service.CreateAutomationRule("7", AutomationRuleRequest{
+		Name: "Bad", Trigger: RuleTriggerSchedule, Schedule: "every 6 hours",
+		Actions: map[string]interface{}{"notify": true},
+	})
+	suite.ErrorIs(err, ErrAutomationRuleInvalidAction)
+
+	// And: Moving a rule to an event trigger drops its schedule
+	updated, err := service.UpdateAutomationRule("7", rule.ID, AutomationRuleRequest{
+		Name: "Weekly review", Trigger: "bookmark_added", Actions: map[string]interface{}{"add_tag": "x"},
+	})
+	suite.Require().NoError(err)
+	suite.Empty(updated.Schedule)
+	suite.Nil(updated.NextRunAt)
+}
+
+func (suite *AutomationServiceTestSuite) TestRunScheduledRules() {
+	ctx := context.Background()
+	service := suite.GetTestService()
+	tagger := &fakeBookmarkTagger{}
+	service.SetBookmarkTagger(tagger)
+	suite.createThresholdTables()
+
+	// Given: Untagged bookmarks from this week, a tagged one and an old one
+	now := time.Now()
+	suite.addScheduledBookmark(1, "https://go.dev/a", "[]", now.AddDate(0, 0, -2))
+	suite.addScheduledBookmark(2, "https://go.dev/b", "", now.AddDate(0, 0, -1))
+	suite.addScheduledBookmark(3, "https://go.dev/c", `["go"]`, now.AddDate(0, 0, -1))
+	suite.addScheduledBookmark(4, "https://go.dev/d", "[]", now.AddDate(0, 0, -30))
+	rule := suite.createWeeklyReviewRule(false)
+
+	// When: The rules run before the rule is due, then once it is
+	ran, err := service.RunScheduledRules(ctx)
+	suite.Require().NoError(err)
+	suite.Zero(ran)
+	suite.Require().NoError(suite.GetTestDB().Model(rule).Update("next_run_at", now.Add(-time.Minute)).Error)
+	ran, err = service.RunScheduledRules(ctx)
+	suite.Require().NoError(err)
+
+	// Then: This week's untagged bookmarks are marked for review
+	suite.Equal(1, ran)
+	suite.Equal(map[uint][]string{1: {"needs-review"}, 2: {"needs-review"}}, tagger.tagged)
+
+	// And: The run is recorded and the rule waits for next Sunday
+	runs, err := service.GetRuleRuns("7", rule.ID)
+	suite.Require().NoError(err)
+	suite.Require().Len(runs, 1)
+	suite.Equal(RuleRunScheduled, runs[0].Trigger)
+	suite.Equal(RuleRunSucceeded, runs[0].Status)
+	suite.Equal(2, runs[0].Matched)
+	suite.Equal(2, runs[0].Changed)
+
+	var saved AutomationRule
+	suite.Require().NoError(suite.GetTestDB().First(&saved, rule.ID).Error)
+	suite.Equal(1, saved.ExecutionCount)
+	suite.Require().NotNil(saved.NextRunAt)
+	suite.True(saved.NextRunAt.After(now))
+	suite.Equal(time.Sunday, saved.NextRunAt.UTC().Weekday())
+
+	// And: Other users cannot see the history
+	_, err = service.GetRuleRuns("8", rule.ID)
+	suite.ErrorIs(err, ErrResourceNotFound)
+}
+
+func (suite *AutomationServiceTestSuite) TestExecuteAutomationRule_Scheduled() {
+	service := suite.GetTestService()
+	tagger := &fakeBookmarkTagger{fail: 2}
+	service.SetBookmarkTagger(tagger)
+	suite.createThresholdTables()
+	suite.addScheduledBookmark(1, "https://go.dev/a", "[]", time.Now())
+	suite.addScheduledBookmark(2, "https://go.dev/b", "[]", time.Now())
+	rule := suite.createWeeklyReviewRule(false)
+
+	// When: The rule is run by hand and one bookmark fails
+	result, err := service.ExecuteAutomationRule("7", rule.ID)
+	suite.Require().NoError(err)
+
+	// Then: The run fails with the error, without moving the next run
+	suite.Equal(RuleRunFailed, result["status"])
+	suite.Equal(1, result["changed"])
+	suite.Contains(result["error"], "bookmark 2")
+	runs, err := service.GetRuleRuns("7", rule.ID)
+	suite.Require().NoError(err)
+	suite.Require().Len(runs, 1)
+	suite.Equal(RuleRunManual, runs[0].Trigger)
+
+	var saved AutomationRule
+	suite.Require().NoError(suite.GetTestDB().First(&saved, rule.ID).Error)
+	suite.WithinDuration(*rule.NextRunAt, *saved.NextRunAt, time.Second)
+}
+
+func (suite *AutomationServiceTestSuite) TestRunScheduledRules_Sandbox() {
+	service := suite.GetTestService()
+	tagger := &fakeBookmarkTagger{}
+	service.SetBookmarkTagger(tagger)
+	suite.createThresholdTables()
+	suite.addScheduledBookmark(1, "https://go.dev/a", "[]", time.Now())
+	rule := suite.createWeeklyReviewRule(true)
+	suite.Require().NoError(suite.GetTestDB().Model(rule).Update("next_run_at", time.Now().Add(-time.Minute)).Error)
+
+	// When: A due sandbox rule runs
+	_, err := service.RunScheduledRules(context.Background())
+	suite.Require().NoError(err)
+
+	// Then: It only records what it would have tagged
+	suite.Empty(tagger.tagged)
+	captures, err := service.GetSandboxCaptures("7", 10)
+	suite.Require().NoError(err)
+	suite.Require().Len(captures, 1)
+	suite.True(captures[0].Matched)
+	runs, err := service.GetRuleRuns("7", rule.ID)
+	suite.Require().NoError(err)
+	suite.Require().Len(runs, 1)
+	suite.Equal(RuleRunCaptured, runs[0].Status)
+	suite.Equal(1, runs[0].Matched)
+}
@@ -8,6 +8,7 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -16,6 +17,9 @@ import (
 	"time"
 
 	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/pkg/locale"
+	"bookmark-sync-service/backend/pkg/secrets"
 )
 
 // Service handles automation operations
@@ -42,6 +46,31 @@ type Service struct {
 	db         *gorm.DB
 	httpClient *http.Client
 	executor   AsyncExecutor
+	downloads  *DownloadSigner
+
+	credentials       *secrets.Cipher
+	destinationClient DestinationClientFactory
+
+	healthPolicy  WebhookHealthPolicy
+	notifications NotificationPublisher
+
+	deliveryLimits WebhookDeliveryLimits
+	limiter        *deliveryLimiter
+
+	integrationPolicy IntegrationHealthPolicy
+	syncers           map[string]IntegrationSyncer
+
+	exportDir string
+
+	importUploads ImportUploadStore
+	rowImporter   RowImporter
+
+	bookmarkTagger BookmarkTagger
+
+	budget WorkBudget
+	usage  UsageMeter
+
+	bulkQueue BulkQueue
 }
 
 // NewService creates a new automation service with production async executor
@@ -51,7 +80,14 @@ func NewService(db *gorm.DB) *Service {
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
-		executor: &ProductionExecutor{},
+		executor:          &ProductionExecutor{},
+		downloads:         newRandomDownloadSigner(),
+		destinationClient: NewS3DestinationClient,
+		healthPolicy:      DefaultWebhookHealthPolicy,
+		deliveryLimits:    DefaultWebhookDeliveryLimits,
+		limiter:           newDeliveryLimiter(DefaultWebhookDeliveryLimits),
+		integrationPolicy: DefaultIntegrationHealthPolicy,
+		syncers:           make(map[string]IntegrationSyncer),
 	}
 }
 
@@ -62,7 +98,14 @@ func NewServiceForTesting(db *gorm.DB) *Service {
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
-		executor: &TestExecutor{},
+		executor:          &TestExecutor{},
+		downloads:         newRandomDownloadSigner(),
+		destinationClient: NewS3DestinationClient,
+		healthPolicy:      DefaultWebhookHealthPolicy,
+		deliveryLimits:    DefaultWebhookDeliveryLimits,
+		limiter:           newDeliveryLimiter(DefaultWebhookDeliveryLimits),
+		integrationPolicy: DefaultIntegrationHealthPolicy,
+		syncers:           make(map[string]IntegrationSyncer),
 	}
 }
 
@@ -73,7 +116,14 @@ func NewServiceWithExecutor(db *gorm.DB, executor AsyncExecutor) *Service {
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
-		executor: executor,
+		executor:          executor,
+		downloads:         newRandomDownloadSigner(),
+		destinationClient: NewS3DestinationClient,
+		healthPolicy:      DefaultWebhookHealthPolicy,
+		deliveryLimits:    DefaultWebhookDeliveryLimits,
+		limiter:           newDeliveryLimiter(DefaultWebhookDeliveryLimits),
+		integrationPolicy: DefaultIntegrationHealthPolicy,
+		syncers:           make(map[string]IntegrationSyncer),
 	}
 }
 
@@ -81,6 +131,10 @@ func NewServiceWithExecutor(db *gorm.DB, executor AsyncExecutor) *Service {
 
 // CreateWebhookEndpoint creates a new webhook endpoint
 func (s *Service) CreateWebhookEndpoint(userID string, req WebhookEndpointRequest) (*WebhookEndpoint, error) {
+	if _, err := parsePayloadTemplate(req.PayloadTemplate); err != nil {
+		return nil, err
+	}
+
 	secret, err := s.generateSecret()
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate secret: %w", err)
@@ -96,6 +150,15 @@ func (s *Service) CreateWebhookEndpoint(userID string, req WebhookEndpointReques
 		RetryCount: req.RetryCount,
 		Timeout:    req.Timeout,
 		Headers:    StringMap(req.Headers),
+
+		PayloadTemplate: req.PayloadTemplate,
+
+		CollectionID:  req.CollectionID,
+		ViewThreshold: req.ViewThreshold,
+		Sandbox:       req.Sandbox,
+
+		BookmarkViewThreshold: req.BookmarkViewThreshold,
+		UnvisitedMonths:       req.UnvisitedMonths,
 	}
 
 	if endpoint.RetryCount == 0 {
@@ -123,6 +186,10 @@ func (s *Service) GetWebhookEndpoints(userID string) ([]WebhookEndpoint, error)
 
 // UpdateWebhookEndpoint updates a webhook endpoint
 func (s *Service) UpdateWebhookEndpoint(userID string, id uint, req WebhookEndpointRequest) (*WebhookEndpoint, error) {
+	if _, err := parsePayloadTemplate(req.PayloadTemplate); err != nil {
+		return nil, err
+	}
+
 	var endpoint WebhookEndpoint
 	if err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&endpoint).Error; err != nil {
 		return nil, fmt.Errorf("webhook endpoint not found: %w", err)
@@ -131,10 +198,15 @@ func (s *Service) UpdateWebhookEndpoint(userID string, id uint, req WebhookEndpo
 	endpoint.Name = req.Name
 	endpoint.URL = req.URL
 	endpoint.Events = StringSlice(req.Events)
-	endpoint.Active = req.Active
+	// Auto-disabled endpoints are only reactivated through EnableWebhookEndpoint
+	endpoint.Active = req.Active && endpoint.DisabledAt == nil
 	endpoint.RetryCount = req.RetryCount
 	endpoint.Timeout = req.Timeout
 	endpoint.Headers = StringMap(req.Headers)
+	endpoint.PayloadTemplate = req.PayloadTemplate
+	endpoint.ViewThreshold = req.ViewThreshold
+	endpoint.BookmarkViewThreshold = req.BookmarkViewThreshold
+	endpoint.UnvisitedMonths = req.UnvisitedMonths
 
 	if err := s.db.Save(&endpoint).Error; err != nil {
 		return nil, fmt.Errorf("failed to update webhook endpoint: %w", err)
@@ -150,15 +222,16 @@ func (s *Service) DeleteWebhookEndpoint(userID string, id uint) error {
 		return fmt.Errorf("failed to delete webhook endpoint: %w", result.Error)
 	}
 	if result.RowsAffected == 0 {
-		return fmt.Errorf("webhook endpoint not found")
+		return ErrWebhookEndpointNotFound
 	}
 	return nil
 }
 
-// TriggerWebhook triggers webhooks for a specific event
+// TriggerWebhook triggers webhooks for a specific event. Endpoints scoped
+// to a collection are left out
 func (s *Service) TriggerWebhook(ctx context.Context, event WebhookEvent, userID string, data interface{}) error {
 	var endpoints []WebhookEndpoint
-	if err := s.db.Where("user_id = ? AND active = ?", userID, true).Find(&endpoints).Error; err != nil {
+	if err := s.db.Where("user_id = ? AND active = ? AND collection_id IS NULL", userID, true).Find(&endpoints).Error; err != nil {
 		return fmt.Errorf("failed to get webhook endpoints: %w", err)
 	}
 
@@ -168,17 +241,28 @@ func (s *Service) TriggerWebhook(ctx context.Context, event WebhookEvent, userID
 		UserID:    userID,
 		Data:      data,
 	}
+	s.dispatch(ctx, endpoints, payload)
+	s.runSandboxRules(userID, payload, false)
+	return nil
+}
 
+// dispatch records a delivery for every endpoint subscribed to the
+// payload's event and delivers it through the executor
+func (s *Service) dispatch(ctx context.Context, endpoints []WebhookEndpoint, payload WebhookPayload) {
 	for _, endpoint := range endpoints {
 		// Check if endpoint is subscribed to this event
-		if !s.isEventSubscribed(endpoint.Events, string(event)) {
+		if !s.isEventSubscribed(endpoint.Events, string(payload.Event)) {
+			continue
+		}
+		if endpoint.Sandbox {
+			s.captureDelivery(&endpoint, payload, false)
 			continue
 		}
 
 		// Create delivery record
 		delivery := &WebhookDelivery{
 			EndpointID: endpoint.ID,
-			Event:      event,
+			Event:      payload.Event,
 			Payload:    InterfaceMap(s.structToMap(payload)),
 			Status:     "pending",
 		}
@@ -188,12 +272,8 @@ func (s *Service) TriggerWebhook(ctx context.Context, event WebhookEvent, userID
 		}
 
 		// Deliver webhook asynchronously using the configured executor
-		s.executor.Execute(func() {
-			s.deliverWebhook(ctx, &endpoint, delivery, payload)
-		})
+		s.queueDelivery(ctx, endpoint, delivery, payload)
 	}
-
-	return nil
 }
 
 // RSS Feed Management
@@ -266,6 +346,9 @@ func (s *Service) GenerateRSSContent(feed *RSSFeed) (string, error) {
 		return "", fmt.Errorf("failed to get RSS items: %w", err)
 	}
 
+	// Dates are written in the feed owner's timezone, not the server's
+	settings := s.userLocale(context.Background(), feed.UserID)
+
 	rss := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
 <rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom">
   <channel>
@@ -288,10 +371,10 @@ func (s *Service) GenerateRSSContent(feed *RSSFeed) (string, error) {
 		feed.Copyright,
 		feed.Category,
 		feed.TTL,
-		time.Now().Format(time.RFC1123Z),
+		settings.FormatRFC1123Z(time.Now()),
 		feed.Link,
 		feed.PublicKey,
-		s.formatRSSItems(items),
+		s.formatRSSItems(items, settings),
 	)
 
 	return rss, nil
@@ -301,6 +384,24 @@ func (s *Service) GenerateRSSContent(feed *RSSFeed) (string, error) {
 
 // CreateBulkOperation creates a new bulk operation
 func (s *Service) CreateBulkOperation(userID string, req BulkOperationRequest) (*BulkOperation, error) {
+	// Reject bad parameters now rather than when the operation runs
+	switch req.Type {
+	case "export":
+		if _, err := exportFormat(req.Parameters); err != nil {
+			return nil, err
+		}
+		if _, _, err := exportBookmarkIDs(req.Parameters); err != nil {
+			return nil, err
+		}
+	case "import":
+		if err := s.checkImportUpload(userID, req.Parameters); err != nil {
+			return nil, err
+		}
+		if err := s.checkImportRows(req.Parameters); err != nil {
+			return nil, err
+		}
+	}
+
 	operation := &BulkOperation{
 		UserID:     userID,
 		Type:       req.Type,
@@ -308,14 +409,16 @@ func (s *Service) CreateBulkOperation(userID string, req BulkOperationRequest) (
 		Parameters: InterfaceMap(req.Parameters),
 	}
 
-	if err := s.db.Create(operation).Error; err != nil {
-		return nil, fmt.Errorf("failed to create bulk operation: %w", err)
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(operation).Error; err != nil {
+			return fmt.Errorf("failed to create bulk operation: %w", err)
+		}
+		return claimImportUpload(tx, operation)
+	}); err != nil {
+		return nil, err
 	}
 
-	// Start processing asynchronously using the configured executor
-	s.executor.Execute(func() {
-		s.processBulkOperation(operation)
-	})
+	s.startBulkOperation(operation)
 
 	return operation, nil
 }
@@ -342,6 +445,19 @@ func (s *Service) GetBulkOperation(userID string, id uint) (*BulkOperation, erro
 
 // CreateBackupJob creates a new backup job
 func (s *Service) CreateBackupJob(userID string, req BackupRequest) (*BackupJob, error) {
+	if err := validateVolumeSize(req.VolumeSize); err != nil {
+		return nil, err
+	}
+	if req.DestinationID != nil {
+		destination, err := s.GetBackupDestination(userID, *req.DestinationID)
+		if err != nil {
+			return nil, err
+		}
+		if !destination.Active {
+			return nil, ErrBackupDestinationInactive
+		}
+	}
+
 	job := &BackupJob{
 		UserID:        userID,
 		Type:          req.Type,
@@ -349,6 +465,8 @@ func (s *Service) CreateBackupJob(userID string, req BackupRequest) (*BackupJob,
 		Compression:   req.Compression,
 		Encrypted:     req.Encrypted,
 		RetentionDays: 30,
+		DestinationID: req.DestinationID,
+		VolumeSize:    req.VolumeSize,
 	}
 
 	if job.Compression == "" {
@@ -454,56 +572,56 @@ func (s *Service) deliverWebhook(ctx context.Context, endpoint *WebhookEndpoint,
 	delivery.AttemptCount++
 	s.db.Save(delivery)
 
-	// Prepare request
-	payloadBytes, err := json.Marshal(payload)
+	// Prepare request, shaped by the endpoint's template when it has one
+	payloadBytes, err := RenderPayload(endpoint.PayloadTemplate, payload)
 	if err != nil {
-		s.updateDeliveryError(delivery, "Failed to marshal payload", 0)
+		s.recordAttempt(delivery, false, 0)
+		s.updateDeliveryError(delivery, fmt.Sprintf("Failed to render payload: %v", err), 0)
+		s.recordDeliveryResult(ctx, endpoint, false)
 		return
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "POST", endpoint.URL, bytes.NewBuffer(payloadBytes))
 	if err != nil {
+		s.recordAttempt(delivery, false, 0)
 		s.updateDeliveryError(delivery, "Failed to create request", 0)
 		return
 	}
 
-	// Set headers
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("User-Agent", "BookmarkSync-Webhook/1.0")
-
-	// Add custom headers
-	for key, value := range endpoint.Headers {
+	for key, value := range s.webhookHeaders(endpoint, payloadBytes, payload) {
 		req.Header.Set(key, value)
 	}
 
-	// Add signature
-	signature := s.generateSignature(payloadBytes, endpoint.Secret)
-	req.Header.Set("X-Webhook-Signature", signature)
-	req.Header.Set("X-Webhook-Event", string(payload.Event))
-	req.Header.Set("X-Webhook-Timestamp", strconv.FormatInt(payload.Timestamp.Unix(), 10))
-
 	// Set timeout
 	client := &http.Client{
 		Timeout: time.Duration(endpoint.Timeout) * time.Second,
 	}
 
 	// Send request
+	started := time.Now()
+	s.meterDelivery(endpoint)
 	resp, err := client.Do(req)
 	if err != nil {
+		s.recordAttempt(delivery, false, time.Since(started))
 		s.updateDeliveryError(delivery, err.Error(), 0)
 		s.scheduleRetry(delivery, endpoint)
+		s.recordTimeout(ctx, endpoint, isTimeout(err))
+		s.recordDeliveryResult(ctx, endpoint, false)
 		return
 	}
 	defer resp.Body.Close()
+	s.recordTimeout(ctx, endpoint, false)
 
 	// Read response
 	responseBody, _ := io.ReadAll(resp.Body)
+	success := resp.StatusCode >= 200 && resp.StatusCode < 300
+	s.recordAttempt(delivery, success, time.Since(started))
 
 	// Update delivery
 	delivery.StatusCode = resp.StatusCode
 	delivery.Response = string(responseBody)
 
-	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+	if success {
 		delivery.Status = "success"
 	} else {
 		delivery.Status = "failed"
@@ -511,6 +629,23 @@ func (s *Service) deliverWebhook(ctx context.Context, endpoint *WebhookEndpoint,
 	}
 
 	s.db.Save(delivery)
+	s.recordDeliveryResult(ctx, endpoint, delivery.Status == "success")
+}
+
+// webhookHeaders are the request headers of a delivery: the endpoint's
+// custom headers, then the signature and event headers
+func (s *Service) webhookHeaders(endpoint *WebhookEndpoint, body []byte, payload WebhookPayload) map[string]string {
+	headers := map[string]string{
+		"Content-Type": "application/json",
+		"User-Agent":   "BookmarkSync-Webhook/1.0",
+	}
+	for key, value := range endpoint.Headers {
+		headers[http.CanonicalHeaderKey(key)] = value
+	}
+	headers["X-Webhook-Signature"] = s.generateSignature(body, endpoint.Secret)
+	headers["X-Webhook-Event"] = string(payload.Event)
+	headers["X-Webhook-Timestamp"] = strconv.FormatInt(payload.Timestamp.Unix(), 10)
+	return headers
 }
 
 func (s *Service) generateSignature(payload []byte, secret string) string {
@@ -536,13 +671,22 @@ func (s *Service) scheduleRetry(delivery *WebhookDelivery, endpoint *WebhookEndp
 	}
 }
 
+// userLocale returns how times are presented to a user, UTC if unknown
+func (s *Service) userLocale(ctx context.Context, userID string) locale.Settings {
+	id, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil {
+		return locale.Default()
+	}
+	return locale.ForUser(ctx, s.db, uint(id))
+}
+
 func (s *Service) getRSSItems(feed *RSSFeed) ([]RSSItem, error) {
 	// This would integrate with bookmark service to get actual bookmarks
 	// For now, return empty slice - will be implemented when integrating with bookmark service
 	return []RSSItem{}, nil
 }
 
-func (s *Service) formatRSSItems(items []RSSItem) string {
+func (s *Service) formatRSSItems(items []RSSItem, settings locale.Settings) string {
 	var itemsXML strings.Builder
 
 	for _, item := range items {
@@ -553,13 +697,22 @@ func (s *Service) formatRSSItems(items []RSSItem) string {
       <guid>%s</guid>
       <pubDate>%s</pubDate>
     </item>
-`, item.Title, item.Link, item.Description, item.GUID, item.PubDate.Format(time.RFC1123Z)))
+`, item.Title, item.Link, item.Description, item.GUID, settings.FormatRFC1123Z(item.PubDate)))
 	}
 
 	return itemsXML.String()
 }
 
 func (s *Service) processBulkOperation(operation *BulkOperation) {
+	if err := s.waitForBudget(operation.UserID); err != nil {
+		completed := time.Now()
+		operation.Status = "failed"
+		operation.Error = err.Error()
+		operation.CompletedAt = &completed
+		s.db.Save(operation)
+		return
+	}
+
 	// Update status to running
 	now := time.Now()
 	operation.Status = "running"
@@ -594,9 +747,29 @@ func (s *Service) processBulkOperation(operation *BulkOperation) {
 	}
 
 	s.db.Save(operation)
+
+	if operation.Type == "export" && operation.Status == "completed" {
+		s.notifyExportCompleted(context.Background(), operation)
+	}
 }
 
 func (s *Service) processBulkImport(operation *BulkOperation) error {
+	if _, ok := operation.Parameters["rows"]; ok {
+		return s.processImportRows(operation)
+	}
+
+	// Files uploaded to storage must still be there when the import runs
+	if key, ok := operation.Parameters["object_key"].(string); ok {
+		if s.importUploads == nil {
+			return ErrImportUploadsDisabled
+		}
+		size, err := s.importUploads.Stat(context.Background(), key)
+		if err != nil {
+			return fmt.Errorf("failed to read import upload: %w", err)
+		}
+		operation.Result = InterfaceMap{"object_key": key, "object_size": size}
+	}
+
 	// Implementation would depend on integration with bookmark service
 	// For now, simulate processing
 	operation.TotalItems = 100
@@ -609,19 +782,6 @@ func (s *Service) processBulkImport(operation *BulkOperation) error {
 	return nil
 }
 
-func (s *Service) processBulkExport(operation *BulkOperation) error {
-	// Implementation would depend on integration with bookmark service
-	// For now, simulate processing
-	operation.TotalItems = 50
-	for i := 0; i < 50; i++ {
-		time.Sleep(20 * time.Millisecond) // Simulate work
-		operation.ProcessedItems = i + 1
-		operation.Progress = (i + 1) * 100 / 50
-		s.db.Save(operation)
-	}
-	return nil
-}
-
 func (s *Service) processBulkDelete(operation *BulkOperation) error {
 	// Implementation would depend on integration with bookmark service
 	// For now, simulate processing
@@ -649,6 +809,16 @@ func (s *Service) processBulkUpdate(operation *BulkOperation) error {
 }
 
 func (s *Service) processBackupJob(job *BackupJob) {
+	if err := s.waitForBudget(job.UserID); err != nil {
+		completed := time.Now()
+		job.Status = "failed"
+		job.Error = err.Error()
+		job.CompletedAt = &completed
+		s.db.Save(job)
+		s.notifyBackupFinished(context.Background(), job)
+		return
+	}
+
 	// Update status to running
 	now := time.Now()
 	job.Status = "running"
@@ -666,6 +836,17 @@ func (s *Service) processBackupJob(job *BackupJob) {
 		err = fmt.Errorf("unknown backup type: %s", job.Type)
 	}
 
+	if err == nil && job.DestinationID != nil {
+		if uploadErr := s.uploadBackup(context.Background(), job); uploadErr != nil {
+			err = fmt.Errorf("upload to backup destination failed: %w", uploadErr)
+		}
+	}
+
+	// The destination gets the whole archive; a local copy is split for download
+	if err == nil && job.VolumeSize > 0 && job.FilePath != "" {
+		err = s.splitBackupJob(job)
+	}
+
 	// Update final status
 	completed := time.Now()
 	job.CompletedAt = &completed
@@ -678,6 +859,7 @@ func (s *Service) processBackupJob(job *BackupJob) {
 	}
 
 	s.db.Save(job)
+	s.notifyBackupFinished(context.Background(), job)
 }
 
 func (s *Service) processFullBackup(job *BackupJob) error {
@@ -707,6 +889,19 @@ type WebhookEndpointRequest struct {
 	RetryCount int               `json:"retry_count"`
 	Timeout    int               `json:"timeout"`
 	Headers    map[string]string `json:"headers"`
+
+	PayloadTemplate string `json:"payload_template"`
+
+	// CollectionID is set by the sharing module after checking the user
+	// owns the collection, so it is never read from the request body
+	CollectionID  *uint `json:"-"`
+	ViewThreshold int   `json:"view_threshold"`
+
+	BookmarkViewThreshold int `json:"bookmark_view_threshold"`
+	UnvisitedMonths       int `json:"unvisited_months"`
+
+	// Sandbox creates the endpoint in the sandbox; promoting it makes it live
+	Sandbox bool `json:"sandbox"`
 }
 
 type RSSFeedRequest struct {
@@ -781,7 +976,7 @@ func (s *Service) DeleteRSSFeed(userID string, id uint) error {
 		return fmt.Errorf("failed to delete RSS feed: %w", result.Error)
 	}
 	if result.RowsAffected == 0 {
-		return fmt.Errorf("RSS feed not found")
+		return ErrRSSFeedNotFound
 	}
 	return nil
 }
@@ -794,7 +989,7 @@ func (s *Service) CancelBulkOperation(userID string, id uint) error {
 	}
 
 	if operation.Status == "completed" || operation.Status == "failed" {
-		return fmt.Errorf("cannot cancel completed or failed operation")
+		return fmt.Errorf("%w: cannot cancel completed or failed operation", ErrBulkOperationCompleted)
 	}
 
 	operation.Status = "cancelled"
@@ -825,11 +1020,11 @@ func (s *Service) GetBackupFilePath(userID string, id uint) (string, error) {
 	}
 
 	if job.Status != "completed" {
-		return "", fmt.Errorf("backup job not completed")
+		return "", ErrBackupJobNotComplete
 	}
 
 	if job.FilePath == "" {
-		return "", fmt.Errorf("backup file path not available")
+		return "", ErrBackupFileNotFound
 	}
 
 	return job.FilePath, nil
@@ -869,30 +1064,42 @@ func (s *Service) DeleteAPIIntegration(userID string, id uint) error {
 		return fmt.Errorf("failed to delete API integration: %w", result.Error)
 	}
 	if result.RowsAffected == 0 {
-		return fmt.Errorf("API integration not found")
+		return ErrAPIIntegrationNotFound
 	}
 	return nil
 }
 
-// TriggerSync triggers a manual sync for an API integration
+// TriggerSync triggers a manual sync for an API integration. A successful
+// manual sync also resumes an integration paused after repeated failures
 func (s *Service) TriggerSync(userID string, id uint) (map[string]interface{}, error) {
 	var integration APIIntegration
 	if err := s.db.Where("id = ? AND user_id = ? AND active = ?", id, userID, true).First(&integration).Error; err != nil {
-		return nil, fmt.Errorf("API integration not found or inactive: %w", err)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("API integration not found or inactive: %w", ErrAPIIntegrationNotFound)
+		}
+		return nil, fmt.Errorf("failed to get API integration: %w", err)
 	}
 
-	// Simulate sync process
-	result := map[string]interface{}{
-		"status":       "success",
-		"message":      "Sync triggered successfully",
-		"timestamp":    time.Now(),
-		"items_synced": 42,
+	run, err := s.runSync(context.Background(), &integration, SyncTriggerManual)
+	if run == nil {
+		return nil, err
 	}
 
-	// Update last sync time
-	now := time.Now()
-	integration.LastSync = &now
-	s.db.Save(&integration)
+	result := map[string]interface{}{
+		"status":        run.Status,
+		"message":       "Sync completed successfully",
+		"timestamp":     run.StartedAt,
+		"run_id":        run.ID,
+		"items_synced":  run.ItemsAdded + run.ItemsUpdated,
+		"items_added":   run.ItemsAdded,
+		"items_updated": run.ItemsUpdated,
+		"items_failed":  run.ItemsFailed,
+	}
+	if err != nil {
+		result["message"] = "Sync failed"
+		result["error"] = run.Error
+		return result, fmt.Errorf("%w: %v", ErrAPIIntegrationSyncFailed, err)
+	}
 
 	return result, nil
 }
@@ -927,6 +1134,10 @@ func (s *Service) CreateAutomationRule(userID string, req AutomationRuleRequest)
 		Actions:     InterfaceMap(req.Actions),
 		Active:      true,
 		Priority:    req.Priority,
+		Sandbox:     req.Sandbox,
+	}
+	if err := s.checkScheduledRule(rule, req.Schedule); err != nil {
+		return nil, err
 	}
 
 	if err := s.db.Create(rule).Error; err != nil {
@@ -958,6 +1169,9 @@ func (s *Service) UpdateAutomationRule(userID string, id uint, req AutomationRul
 	rule.Conditions = InterfaceMap(req.Conditions)
 	rule.Actions = InterfaceMap(req.Actions)
 	rule.Priority = req.Priority
+	if err := s.checkScheduledRule(&rule, req.Schedule); err != nil {
+		return nil, err
+	}
 
 	if err := s.db.Save(&rule).Error; err != nil {
 		return nil, fmt.Errorf("failed to update automation rule: %w", err)
@@ -973,7 +1187,7 @@ func (s *Service) DeleteAutomationRule(userID string, id uint) error {
 		return fmt.Errorf("failed to delete automation rule: %w", result.Error)
 	}
 	if result.RowsAffected == 0 {
-		return fmt.Errorf("automation rule not found")
+		return ErrAutomationRuleNotFound
 	}
 	return nil
 }
@@ -984,6 +1198,33 @@ func (s *Service) ExecuteAutomationRule(userID string, id uint) (map[string]inte
 	if err := s.db.Where("id = ? AND user_id = ? AND active = ?", id, userID, true).First(&rule).Error; err != nil {
 		return nil, fmt.Errorf("automation rule not found or inactive: %w", err)
 	}
+	return s.executeRule(&rule, userID), nil
+}
+
+// executeRule runs a rule manually on behalf of a user
+func (s *Service) executeRule(rule *AutomationRule, userID string) map[string]interface{} {
+	if rule.Trigger == RuleTriggerSchedule {
+		// Runs now without moving the next scheduled run
+		run := s.runScheduledRule(context.Background(), rule, RuleRunManual, userID)
+		return map[string]interface{}{
+			"status":    run.Status,
+			"message":   "Scheduled rule ran",
+			"timestamp": run.StartedAt,
+			"run_id":    run.ID,
+			"matched":   run.Matched,
+			"changed":   run.Changed,
+			"error":     run.Error,
+		}
+	}
+	if rule.Sandbox {
+		capture := s.captureRule(rule, WebhookPayload{Event: ruleEvent(rule.Trigger), Timestamp: time.Now(), UserID: rule.UserID}, true, true)
+		return map[string]interface{}{
+			"status":     "captured",
+			"message":    "Sandbox rule recorded what it would do",
+			"timestamp":  capture.CreatedAt,
+			"capture_id": capture.ID,
+		}
+	}
 
 	// Simulate rule execution
 	result := map[string]interface{}{
@@ -997,7 +1238,7 @@ func (s *Service) ExecuteAutomationRule(userID string, id uint) (map[string]inte
 	rule.ExecutionCount++
 	now := time.Now()
 	rule.LastExecuted = &now
-	s.db.Save(&rule)
+	s.db.Save(rule)
 
-	return result, nil
+	return result
 }
@@ -280,6 +280,27 @@ func (suite *AutomationServiceTestSuite) TestGenerateRSSContent_Success() {
 	suite.Contains(content, "<ttl>60</ttl>")
 }
 
+func (suite *AutomationServiceTestSuite) TestGenerateRSSContent_UsesOwnerTimezone() {
+	// Given: A feed whose owner lives in Taipei
+	suite.Require().NoError(suite.db.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, preferences TEXT)").Error)
+	suite.Require().NoError(suite.db.Exec(`INSERT INTO users (id, preferences) VALUES (7, '{"timezone":"Asia/Taipei"}')`).Error)
+	service := suite.GetTestService()
+	feed, err := service.CreateRSSFeed("7", RSSFeedRequest{Title: "Taipei", Link: "https://example.com"})
+	suite.Require().NoError(err)
+
+	// When: Generating RSS content
+	content, err := service.GenerateRSSContent(feed)
+
+	// Then: Dates carry the owner's offset rather than the server's
+	suite.Require().NoError(err)
+	suite.Regexp(`<lastBuildDate>[^<]+ \+0800</lastBuildDate>`, content)
+
+	item := RSSItem{Title: "Go", PubDate: time.Date(2026, 3, 1, 22, 30, 0, 0, time.UTC)}
+	settings := service.userLocale(context.Background(), "7")
+	suite.Contains(service.formatRSSItems([]RSSItem{item}, settings), "<pubDate>Mon, 02 Mar 2026 06:30:00 +0800</pubDate>")
+	suite.Equal("UTC", service.userLocale(context.Background(), suite.GetTestUserID()).Timezone())
+}
+
 // Bulk Operation Tests
 
 func (suite *AutomationServiceTestSuite) TestCreateBulkOperation_Success() {
@@ -464,6 +485,8 @@ func (suite *AutomationServiceTestSuite) TestTriggerSync_Success() {
 	}
 	integration, _ := suite.GetTestService().CreateAPIIntegration(suite.GetTestUserID(), req)
 
+	suite.GetTestService().SetIntegrationSyncer("pocket", &stubSyncer{outcome: SyncOutcome{ItemsAdded: 3}})
+
 	// When: Triggering a sync
 	result, err := suite.GetTestService().TriggerSync(suite.GetTestUserID(), integration.ID)
 
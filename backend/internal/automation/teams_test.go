@@ -0,0 +1,163 @@
+package automation
+
+// setupTeam creates a workspace owned by user 7 with an admin (8), an editor
+// (9) and a viewer (10), each a collaborator on one of its collections
+func (suite *AutomationServiceTestSuite) setupTeam() {
+	db := suite.GetTestDB()
+	for _, statement := range []string{
+		`CREATE TABLE collections (id INTEGER PRIMARY KEY, user_id INTEGER, deleted_at DATETIME)`,
+		`CREATE TABLE collection_collaborators (id INTEGER PRIMARY KEY, collection_id INTEGER, user_id INTEGER, permission TEXT, status TEXT, deleted_at DATETIME)`,
+		`INSERT INTO collections (id, user_id) VALUES (1, 7), (2, 7), (3, 8)`,
+		`INSERT INTO collection_collaborators (collection_id, user_id, permission, status) VALUES
+			(1, 8, 'view', 'accepted'), (2, 8, 'admin', 'accepted'),
+			(1, 9, 'edit', 'accepted'), (1, 10, 'view', 'accepted'), (2, 11, 'admin', 'pending')`,
+	} {
+		suite.Require().NoError(db.Exec(statement).Error)
+	}
+}
+
+func (suite *AutomationServiceTestSuite) TestTeamRoles() {
+	service := suite.GetTestService()
+	suite.setupTeam()
+
+	// The highest permission across the owner's collections counts
+	teams, err := service.GetTeams("8")
+	suite.Require().NoError(err)
+	suite.Equal([]TeamWorkspace{{TeamID: "8", Role: TeamRoleOwner}, {TeamID: "7", Role: TeamRoleAdmin}}, teams)
+
+	for userID, want := range map[string]string{"7": TeamRoleOwner, "9": TeamRoleEditor, "10": TeamRoleViewer} {
+		role, err := service.TeamRole("7", userID)
+		suite.Require().NoError(err)
+		suite.Equal(want, role, userID)
+	}
+	// Pending invitations do not make a member
+	_, err = service.TeamRole("7", "11")
+	suite.ErrorIs(err, ErrTeamNotMember)
+}
+
+func (suite *AutomationServiceTestSuite) TestTeamAutomations_RoleBasedManagement() {
+	service := suite.GetTestService()
+	suite.setupTeam()
+	ruleReq := AutomationRuleRequest{Name: "Tag research", Trigger: "bookmark_added", Actions: map[string]interface{}{"add_tag": "research"}}
+
+	// Given: Only admins create team automations
+	_, err := service.CreateTeamAutomationRule("7", "9", ruleReq)
+	suite.ErrorIs(err, ErrTeamRoleRequired)
+	rule, err := service.CreateTeamAutomationRule("7", "8", ruleReq)
+	suite.Require().NoError(err)
+	suite.Equal("7", rule.UserID)
+	suite.True(rule.TeamOwned)
+	suite.Equal("8", rule.CreatedBy)
+
+	_, err = service.CreateTeamRSSFeed("7", "8", RSSFeedRequest{Title: "Team reading", Link: "https://example.com", Collections: []uint{3}})
+	suite.ErrorIs(err, ErrInvalidRequest, "collection 3 is not the workspace's")
+	feed, err := service.CreateTeamRSSFeed("7", "8", RSSFeedRequest{Title: "Team reading", Link: "https://example.com", Collections: []uint{1}})
+	suite.Require().NoError(err)
+	endpoint, err := service.CreateTeamWebhookEndpoint("7", "7", WebhookEndpointRequest{Name: "Team chat", URL: "https://example.com/hook", Events: []string{"bookmark.created"}})
+	suite.Require().NoError(err)
+
+	// When: Members of each role act on them
+	automations, err := service.GetTeamAutomations("7", "10")
+	suite.Require().NoError(err)
+	suite.Equal(TeamRoleViewer, automations.Role)
+	suite.Len(automations.Rules, 1)
+	suite.Len(automations.Feeds, 1)
+	suite.Len(automations.Webhooks, 1)
+
+	_, err = service.ExecuteTeamAutomationRule("7", "10", rule.ID)
+	suite.ErrorIs(err, ErrTeamRoleRequired)
+	_, err = service.ExecuteTeamAutomationRule("7", "9", rule.ID)
+	suite.Require().NoError(err)
+	suite.ErrorIs(service.DeleteTeamWebhookEndpoint("7", "9", endpoint.ID), ErrTeamRoleRequired)
+	suite.Require().NoError(service.DeleteTeamRSSFeed("7", "8", feed.ID))
+	_, err = service.GetTeamAutomations("7", "12")
+	suite.ErrorIs(err, ErrTeamNotMember)
+
+	// Personal automations of the owner are not the team's
+	personal, err := service.CreateAutomationRule("7", ruleReq)
+	suite.Require().NoError(err)
+	_, err = service.ExecuteTeamAutomationRule("7", "9", personal.ID)
+	suite.ErrorIs(err, ErrResourceNotFound)
+
+	// Then: The activity log attributes each change and run to its member
+	activity, err := service.GetTeamActivity("7", "10")
+	suite.Require().NoError(err)
+	suite.Require().Len(activity, 5)
+	suite.Equal(TeamActionDeleted, activity[0].Action)
+	suite.Equal("8", activity[0].ActorID)
+	suite.Equal(TeamActionExecuted, activity[1].Action)
+	suite.Equal("9", activity[1].ActorID)
+	suite.Equal(TeamResourceRule, activity[1].ResourceType)
+}
+
+func (suite *AutomationServiceTestSuite) TestExecuteTeamAutomationRule_RunAttribution() {
+	service := suite.GetTestService()
+	suite.setupTeam()
+	suite.Require().NoError(suite.GetTestDB().Exec(`CREATE TABLE bookmarks (id INTEGER PRIMARY KEY, user_id INTEGER, url TEXT, title TEXT, tags TEXT, created_at DATETIME, deleted_at DATETIME)`).Error)
+	service.SetBookmarkTagger(&fakeBookmarkTagger{})
+
+	rule, err := service.CreateTeamAutomationRule("7", "8", AutomationRuleRequest{
+		Name: "Weekly review", Trigger: RuleTriggerSchedule, Schedule: "0 9 * * 0",
+		Actions: map[string]interface{}{"add_tag": "needs-review"},
+	})
+	suite.Require().NoError(err)
+
+	_, err = service.ExecuteTeamAutomationRule("7", "9", rule.ID)
+	suite.Require().NoError(err)
+
+	runs, err := service.GetTeamRuleRuns("7", "10", rule.ID)
+	suite.Require().NoError(err)
+	suite.Require().Len(runs, 1)
+	suite.Equal(RuleRunManual, runs[0].Trigger)
+	suite.Equal("9", runs[0].RunBy)
+}
+
+func (suite *AutomationServiceTestSuite) TestMigrateToTeam() {
+	service := suite.GetTestService()
+	suite.setupTeam()
+
+	// Given: The admin's personal automations, some tied to their own account
+	rule, err := service.CreateAutomationRule("8", AutomationRuleRequest{Name: "Mine", Trigger: "bookmark_added"})
+	suite.Require().NoError(err)
+	endpoint, err := service.CreateWebhookEndpoint("8", WebhookEndpointRequest{Name: "Hook", URL: "https://example.com/hook", Events: []string{"bookmark.created"}})
+	suite.Require().NoError(err)
+	appEndpoint, err := service.CreateWebhookEndpoint("8", WebhookEndpointRequest{Name: "App", URL: "https://example.com/app", Events: []string{"bookmark.created"}})
+	suite.Require().NoError(err)
+	appID := uint(4)
+	suite.Require().NoError(suite.GetTestDB().Model(appEndpoint).Update("oauth_app_id", appID).Error)
+	feed, err := service.CreateRSSFeed("8", RSSFeedRequest{Title: "My feed", Link: "https://example.com", Collections: []uint{3}})
+	suite.Require().NoError(err)
+	someoneElses, err := service.CreateAutomationRule("9", AutomationRuleRequest{Name: "Theirs", Trigger: "bookmark_added"})
+	suite.Require().NoError(err)
+
+	// When: Editors cannot move automations in, admins can
+	_, err = service.MigrateToTeam("7", "9", TeamMigrationRequest{RuleIDs: []uint{someoneElses.ID}})
+	suite.ErrorIs(err, ErrTeamRoleRequired)
+	result, err := service.MigrateToTeam("7", "8", TeamMigrationRequest{
+		RuleIDs:    []uint{rule.ID, someoneElses.ID},
+		WebhookIDs: []uint{endpoint.ID, appEndpoint.ID},
+		FeedIDs:    []uint{feed.ID},
+	})
+	suite.Require().NoError(err)
+
+	// Then: Only the admin's own automations that can act for the workspace moved
+	suite.Equal(1, result.Rules)
+	suite.Equal(1, result.Webhooks)
+	suite.Equal(0, result.Feeds)
+	suite.Len(result.Skipped, 2)
+
+	automations, err := service.GetTeamAutomations("7", "8")
+	suite.Require().NoError(err)
+	suite.Require().Len(automations.Rules, 1)
+	suite.Equal(rule.ID, automations.Rules[0].ID)
+	suite.Equal("8", automations.Rules[0].CreatedBy)
+	suite.Require().Len(automations.Webhooks, 1)
+	suite.Equal(endpoint.ID, automations.Webhooks[0].ID)
+
+	mine, err := service.GetAutomationRules("8")
+	suite.Require().NoError(err)
+	suite.Empty(mine)
+
+	_, err = service.MigrateToTeam("8", "8", TeamMigrationRequest{})
+	suite.ErrorIs(err, ErrInvalidRequest)
+}
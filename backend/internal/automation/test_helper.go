@@ -30,8 +30,16 @@ func (base *AutomationTestBase) SetupAutomationTest() {
 		&RSSFeed{},
 		&BulkOperation{},
 		&BackupJob{},
+		&BackupDestination{},
 		&APIIntegration{},
+		&SyncRun{},
+		&ImportUpload{},
 		&AutomationRule{},
+		&RuleRun{},
+		&TeamActivity{},
+		&SandboxCapture{},
+		&ThresholdFiring{},
+		&WebhookDeliveryStat{},
 	)
 	base.Require().NoError(err)
 
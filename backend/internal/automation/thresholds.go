@@ -0,0 +1,398 @@
+package automation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Thresholds of the threshold events for endpoints and rules that set none
+// of their own
+const (
+	DefaultBookmarkViewThreshold = 100
+	DefaultUnvisitedMonths       = 6
+)
+
+// thresholdQueryChunk bounds the bookmark IDs bound to one query, below the
+// SQLite parameter limit
+const thresholdQueryChunk = 500
+
+// thresholdConditions maps the numeric rule conditions to the event data
+// field they must reach. Rules without one use the default threshold
+var thresholdConditions = map[string]string{
+	"min_views":        "views",
+	"unvisited_months": "months_unvisited",
+}
+
+// visitActions are the behaviors counting as the owner visiting a bookmark
+var visitActions = []string{"view", "click"}
+
+// sharedVisibilities are the collection visibilities whose bookmarks others
+// can view
+var sharedVisibilities = []string{"public", "shared"}
+
+// ThresholdFiring records that a threshold event fired for a bookmark, so
+// the evaluation job does not fire it again on every run
+type ThresholdFiring struct {
+	ID     uint   `json:"id" gorm:"primaryKey"`
+	UserID string `json:"user_id" gorm:"not null;index"`
+	// Subscriber is endpoint:<id> or rule:<id>
+	Subscriber string       `json:"subscriber" gorm:"size:32;not null;uniqueIndex:idx_threshold_firing"`
+	Event      WebhookEvent `json:"event" gorm:"size:64;not null;uniqueIndex:idx_threshold_firing"`
+	BookmarkID uint         `json:"bookmark_id" gorm:"not null;uniqueIndex:idx_threshold_firing"`
+	Threshold  int          `json:"threshold"`
+	FiredAt    time.Time    `json:"fired_at"`
+}
+
+// thresholdSubscriber is an endpoint or a rule waiting on a threshold event
+type thresholdSubscriber struct {
+	key       string
+	event     WebhookEvent
+	threshold int
+	endpoint  *WebhookEndpoint
+	rule      *AutomationRule
+}
+
+// thresholdBookmark is a bookmark that may have crossed a threshold
+type thresholdBookmark struct {
+	ID             uint
+	URL            string
+	Title          string
+	Tags           string
+	Views          int
+	CreatedAt      time.Time
+	LastAccessedAt *time.Time
+}
+
+// EvaluateThresholds fires bookmark.views_reached for shared bookmarks
+// whose views reached a subscriber's threshold, and bookmark.unvisited for
+// bookmarks their owner has not visited for a subscriber's number of
+// months. Views come from the social metrics, visits from the owner's view
+// and click behaviors. It returns how many events fired
+func (s *Service) EvaluateThresholds(ctx context.Context) (int, error) {
+	subscribers, err := s.thresholdSubscribers(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	fired := 0
+	for userID, list := range subscribers {
+		n, err := s.evaluateUserThresholds(ctx, userID, list)
+		fired += n
+		if err != nil {
+			return fired, err
+		}
+	}
+	return fired, nil
+}
+
+// thresholdSubscribers returns the active account-wide endpoints and the
+// active rules waiting on a threshold event, by user
+func (s *Service) thresholdSubscribers(ctx context.Context) (map[string][]thresholdSubscriber, error) {
+	db := s.db.WithContext(ctx)
+	subscribers := make(map[string][]thresholdSubscriber)
+
+	var endpoints []WebhookEndpoint
+	if err := db.Where("active = ? AND collection_id IS NULL AND (events LIKE ? OR events LIKE ?)", true,
+		"%"+string(WebhookEventBookmarkViewsReached)+"%", "%"+string(WebhookEventBookmarkUnvisited)+"%").
+		Find(&endpoints).Error; err != nil {
+		return nil, fmt.Errorf("failed to get webhook endpoints: %w", err)
+	}
+	for i := range endpoints {
+		endpoint := &endpoints[i]
+		if s.isEventSubscribed(endpoint.Events, string(WebhookEventBookmarkViewsReached)) {
+			subscribers[endpoint.UserID] = append(subscribers[endpoint.UserID], thresholdSubscriber{
+				key:       fmt.Sprintf("endpoint:%d", endpoint.ID),
+				event:     WebhookEventBookmarkViewsReached,
+				threshold: orDefault(endpoint.BookmarkViewThreshold, DefaultBookmarkViewThreshold),
+				endpoint:  endpoint,
+			})
+		}
+		if s.isEventSubscribed(endpoint.Events, string(WebhookEventBookmarkUnvisited)) {
+			subscribers[endpoint.UserID] = append(subscribers[endpoint.UserID], thresholdSubscriber{
+				key:       fmt.Sprintf("endpoint:%d", endpoint.ID),
+				event:     WebhookEventBookmarkUnvisited,
+				threshold: orDefault(endpoint.UnvisitedMonths, DefaultUnvisitedMonths),
+				endpoint:  endpoint,
+			})
+		}
+	}
+
+	var rules []AutomationRule
+	if err := db.Where("active = ?", true).Order("priority DESC, created_at DESC").Find(&rules).Error; err != nil {
+		return nil, fmt.Errorf("failed to get automation rules: %w", err)
+	}
+	for i := range rules {
+		rule := &rules[i]
+		subscriber := thresholdSubscriber{key: fmt.Sprintf("rule:%d", rule.ID), event: ruleEvent(rule.Trigger), rule: rule}
+		switch subscriber.event {
+		case WebhookEventBookmarkViewsReached:
+			subscriber.threshold = ruleThreshold(rule, "min_views", DefaultBookmarkViewThreshold)
+		case WebhookEventBookmarkUnvisited:
+			subscriber.threshold = ruleThreshold(rule, "unvisited_months", DefaultUnvisitedMonths)
+		default:
+			continue
+		}
+		subscribers[rule.UserID] = append(subscribers[rule.UserID], subscriber)
+	}
+	return subscribers, nil
+}
+
+// evaluateUserThresholds fires the threshold events of one user's bookmarks
+func (s *Service) evaluateUserThresholds(ctx context.Context, userID string, subscribers []thresholdSubscriber) (int, error) {
+	ownerID, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil {
+		return 0, nil // not a user account, so there are no bookmarks to watch
+	}
+
+	now := time.Now()
+	minViews, minMonths := 0, 0
+	for _, subscriber := range subscribers {
+		threshold := &minViews
+		if subscriber.event == WebhookEventBookmarkUnvisited {
+			threshold = &minMonths
+		}
+		if *threshold == 0 || subscriber.threshold < *threshold {
+			*threshold = subscriber.threshold
+		}
+	}
+
+	var viewed, unvisited []thresholdBookmark
+	lastVisits := make(map[uint]time.Time)
+	if minViews > 0 {
+		if viewed, err = s.viewedBookmarks(ctx, uint(ownerID), minViews); err != nil {
+			return 0, err
+		}
+	}
+	if minMonths > 0 {
+		if unvisited, err = s.unvisitedBookmarks(ctx, userID, uint(ownerID), now.AddDate(0, -minMonths, 0), lastVisits); err != nil {
+			return 0, err
+		}
+	}
+	if len(viewed) == 0 && len(unvisited) == 0 {
+		return 0, nil
+	}
+
+	var firings []ThresholdFiring
+	if err := s.db.WithContext(ctx).Where("user_id = ?", userID).Find(&firings).Error; err != nil {
+		return 0, fmt.Errorf("failed to get threshold firings: %w", err)
+	}
+	previous := make(map[string]*ThresholdFiring, len(firings))
+	for i := range firings {
+		previous[firingKey(firings[i].Subscriber, firings[i].Event, firings[i].BookmarkID)] = &firings[i]
+	}
+
+	fired := 0
+	for _, subscriber := range subscribers {
+		bookmarks := viewed
+		if subscriber.event == WebhookEventBookmarkUnvisited {
+			bookmarks = unvisited
+		}
+
+		for _, bookmark := range bookmarks {
+			firing := previous[firingKey(subscriber.key, subscriber.event, bookmark.ID)]
+			data := thresholdData(bookmark, subscriber.threshold)
+
+			if subscriber.event == WebhookEventBookmarkViewsReached {
+				// Raising the threshold arms the event again
+				if bookmark.Views < subscriber.threshold || (firing != nil && firing.Threshold >= subscriber.threshold) {
+					continue
+				}
+				data["views"] = bookmark.Views
+			} else {
+				// A visit after the event fired starts another idle period
+				lastVisit := lastVisits[bookmark.ID]
+				if !lastVisit.Before(now.AddDate(0, -subscriber.threshold, 0)) || (firing != nil && !firing.FiredAt.Before(lastVisit)) {
+					continue
+				}
+				data["last_visited_at"] = lastVisit
+				data["months_unvisited"] = monthsBetween(lastVisit, now)
+			}
+			if subscriber.rule != nil && !conditionsMatch(subscriber.rule.Conditions, data) {
+				continue
+			}
+
+			if err := s.fireThreshold(ctx, userID, subscriber, bookmark.ID, data, firing, now); err != nil {
+				return fired, err
+			}
+			fired++
+		}
+	}
+	return fired, nil
+}
+
+// viewedBookmarks returns the owner's bookmarks in public or shared
+// collections with at least minViews views
+func (s *Service) viewedBookmarks(ctx context.Context, ownerID uint, minViews int) ([]thresholdBookmark, error) {
+	db := s.db.WithContext(ctx)
+	if !db.Migrator().HasTable("social_metrics") {
+		return nil, nil
+	}
+
+	shared := db.Table("bookmark_collections").Select("bookmark_collections.bookmark_id").
+		Joins("JOIN collections ON collections.id = bookmark_collections.collection_id").
+		Where("collections.visibility IN ? AND collections.deleted_at IS NULL", sharedVisibilities)
+
+	var bookmarks []thresholdBookmark
+	if err := db.Table("bookmarks").
+		Select("bookmarks.id, bookmarks.url, bookmarks.title, bookmarks.tags, social_metrics.total_views AS views").
+		Joins("JOIN social_metrics ON social_metrics.bookmark_id = bookmarks.id AND social_metrics.deleted_at IS NULL").
+		Where("bookmarks.user_id = ? AND bookmarks.deleted_at IS NULL AND social_metrics.total_views >= ?", ownerID, minViews).
+		Where("bookmarks.id IN (?)", shared).
+		Scan(&bookmarks).Error; err != nil {
+		return nil, fmt.Errorf("failed to get viewed bookmarks: %w", err)
+	}
+	return bookmarks, nil
+}
+
+// unvisitedBookmarks returns the owner's bookmarks last visited before the
+// cutoff, filling in when each was last visited. A bookmark never visited
+// counts from when it was saved
+func (s *Service) unvisitedBookmarks(ctx context.Context, userID string, ownerID uint, cutoff time.Time, lastVisits map[uint]time.Time) ([]thresholdBookmark, error) {
+	db := s.db.WithContext(ctx)
+
+	var candidates []thresholdBookmark
+	if err := db.Table("bookmarks").
+		Select("id, url, title, tags, created_at, last_accessed_at").
+		Where("user_id = ? AND deleted_at IS NULL AND COALESCE(last_accessed_at, created_at) < ?", ownerID, cutoff).
+		Scan(&candidates).Error; err != nil {
+		return nil, fmt.Errorf("failed to get unvisited bookmarks: %w", err)
+	}
+	for _, bookmark := range candidates {
+		lastVisits[bookmark.ID] = bookmark.CreatedAt
+		if bookmark.LastAccessedAt != nil && bookmark.LastAccessedAt.After(bookmark.CreatedAt) {
+			lastVisits[bookmark.ID] = *bookmark.LastAccessedAt
+		}
+	}
+
+	if len(candidates) > 0 && db.Migrator().HasTable("user_behaviors") {
+		for start := 0; start < len(candidates); start += thresholdQueryChunk {
+			end := start + thresholdQueryChunk
+			if end > len(candidates) {
+				end = len(candidates)
+			}
+			ids := make([]uint, 0, end-start)
+			for _, bookmark := range candidates[start:end] {
+				ids = append(ids, bookmark.ID)
+			}
+
+			var visits []struct {
+				BookmarkID uint
+				CreatedAt  time.Time
+			}
+			if err := db.Table("user_behaviors").Select("bookmark_id, created_at").
+				Where("user_id = ? AND action_type IN ? AND bookmark_id IN ? AND deleted_at IS NULL", userID, visitActions, ids).
+				Scan(&visits).Error; err != nil {
+				return nil, fmt.Errorf("failed to get bookmark visits: %w", err)
+			}
+			for _, visit := range visits {
+				if visit.CreatedAt.After(lastVisits[visit.BookmarkID]) {
+					lastVisits[visit.BookmarkID] = visit.CreatedAt
+				}
+			}
+		}
+	}
+
+	unvisited := candidates[:0]
+	for _, bookmark := range candidates {
+		if lastVisits[bookmark.ID].Before(cutoff) {
+			unvisited = append(unvisited, bookmark)
+		}
+	}
+	return unvisited, nil
+}
+
+// fireThreshold delivers a threshold event to its subscriber and records
+// that it fired. Sandbox endpoints and rules capture it instead; live rules
+// count an execution
+func (s *Service) fireThreshold(ctx context.Context, userID string, subscriber thresholdSubscriber, bookmarkID uint, data map[string]interface{}, firing *ThresholdFiring, now time.Time) error {
+	payload := WebhookPayload{Event: subscriber.event, Timestamp: now, UserID: userID, Data: data}
+
+	switch {
+	case subscriber.endpoint != nil:
+		s.dispatch(ctx, []WebhookEndpoint{*subscriber.endpoint}, payload)
+	case subscriber.rule.Sandbox:
+		s.captureRule(subscriber.rule, payload, true, false)
+	default:
+		if err := s.db.WithContext(ctx).Model(subscriber.rule).UpdateColumns(map[string]interface{}{
+			"execution_count": gorm.Expr("execution_count + 1"),
+			"last_executed":   now,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to update automation rule: %w", err)
+		}
+	}
+
+	if firing == nil {
+		firing = &ThresholdFiring{UserID: userID, Subscriber: subscriber.key, Event: subscriber.event, BookmarkID: bookmarkID}
+	}
+	firing.Threshold = subscriber.threshold
+	firing.FiredAt = now
+	if err := s.db.WithContext(ctx).Save(firing).Error; err != nil {
+		return fmt.Errorf("failed to record threshold firing: %w", err)
+	}
+	return nil
+}
+
+// thresholdData is the event data shared by both threshold events
+func thresholdData(bookmark thresholdBookmark, threshold int) map[string]interface{} {
+	tags := []interface{}{}
+	if bookmark.Tags != "" {
+		json.Unmarshal([]byte(bookmark.Tags), &tags)
+	}
+	return map[string]interface{}{
+		"bookmark_id": bookmark.ID,
+		"url":         bookmark.URL,
+		"title":       bookmark.Title,
+		"tags":        tags,
+		"threshold":   threshold,
+	}
+}
+
+// ruleThreshold returns the rule's numeric condition, or the default when
+// it has none
+func ruleThreshold(rule *AutomationRule, condition string, fallback int) int {
+	if value, ok := number(rule.Conditions[condition]); ok && value > 0 {
+		return int(value)
+	}
+	return fallback
+}
+
+// number reads a number from event data or from conditions decoded from JSON
+func number(value interface{}) (float64, bool) {
+	switch n := value.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	default:
+		return 0, false
+	}
+}
+
+func orDefault(value, fallback int) int {
+	if value > 0 {
+		return value
+	}
+	return fallback
+}
+
+func firingKey(subscriber string, event WebhookEvent, bookmarkID uint) string {
+	return fmt.Sprintf("%s|%s|%d", subscriber, event, bookmarkID)
+}
+
+// monthsBetween counts the whole calendar months from one time to another
+func monthsBetween(from, to time.Time) int {
+	months := (to.Year()-from.Year())*12 + int(to.Month()) - int(from.Month())
+	if to.Day() < from.Day() {
+		months--
+	}
+	return months
+}
@@ -0,0 +1,161 @@
+package automation
+
+import (
+	"context"
+	"time"
+)
+
+// createThresholdTables creates the bookmark, collection and social tables
+// the threshold evaluation reads, with bookmarks of user 7
+func (suite *AutomationServiceTestSuite) createThresholdTables() {
+	db := suite.GetTestDB()
+	for _, statement := range []string{
+		`CREATE TABLE bookmarks (id INTEGER PRIMARY KEY, user_id INTEGER, url TEXT, title TEXT, tags TEXT, created_at DATETIME, last_accessed_at DATETIME, deleted_at DATETIME)`,
+		`CREATE TABLE collections (id INTEGER PRIMARY KEY, user_id INTEGER, visibility TEXT, deleted_at DATETIME)`,
+		`CREATE TABLE bookmark_collections (bookmark_id INTEGER, collection_id INTEGER)`,
+		`CREATE TABLE social_metrics (id INTEGER PRIMARY KEY, bookmark_id INTEGER, total_views INTEGER, deleted_at DATETIME)`,
+		`CREATE TABLE user_behaviors (id INTEGER PRIMARY KEY, user_id TEXT, bookmark_id INTEGER, action_type TEXT, created_at DATETIME, deleted_at DATETIME)`,
+		`INSERT INTO collections (id, user_id, visibility) VALUES (1, 7, 'public'), (2, 7, 'private')`,
+	} {
+		suite.Require().NoError(db.Exec(statement).Error)
+	}
+}
+
+func (suite *AutomationServiceTestSuite) addThresholdBookmark(id uint, url string, createdAt time.Time, collectionID uint, views int) {
+	db := suite.GetTestDB()
+	suite.Require().NoError(db.Exec(`INSERT INTO bookmarks (id, user_id, url, title, tags, created_at) VALUES (?, 7, ?, 'Title', '["go"]', ?)`, id, url, createdAt).Error)
+	suite.Require().NoError(db.Exec(`INSERT INTO bookmark_collections VALUES (?, ?)`, id, collectionID).Error)
+	suite.Require().NoError(db.Exec(`INSERT INTO social_metrics (bookmark_id, total_views) VALUES (?, ?)`, id, views).Error)
+}
+
+func (suite *AutomationServiceTestSuite) createThresholdEndpoint(req WebhookEndpointRequest) *WebhookEndpoint {
+	req.Name = "Thresholds"
+	req.URL = "https://hooks.example.com/thresholds"
+	endpoint, err := suite.GetTestService().CreateWebhookEndpoint("7", req)
+	suite.Require().NoError(err)
+	return endpoint
+}
+
+func (suite *AutomationServiceTestSuite) thresholdDeliveries(endpoint *WebhookEndpoint) []WebhookDelivery {
+	deliveries, err := suite.GetTestService().GetWebhookDeliveries("7", endpoint.ID)
+	suite.Require().NoError(err)
+	return deliveries
+}
+
+func (suite *AutomationServiceTestSuite) TestEvaluateThresholds_SharedBookmarkViews() {
+	ctx := context.Background()
+	service := suite.GetTestService()
+	suite.createThresholdTables()
+	recent := time.Now().Add(-time.Hour)
+
+	// Given: A shared bookmark with 120 views, a shared one with 20 and a
+	// private one with 500
+	suite.addThresholdBookmark(1, "https://go.dev/popular", recent, 1, 120)
+	suite.addThresholdBookmark(2, "https://go.dev/quiet", recent, 1, 20)
+	suite.addThresholdBookmark(3, "https://go.dev/private", recent, 2, 500)
+	byDefault := suite.createThresholdEndpoint(WebhookEndpointRequest{Events: []string{string(WebhookEventBookmarkViewsReached)}})
+	everyTen := suite.createThresholdEndpoint(WebhookEndpointRequest{
+		Events:                []string{string(WebhookEventBookmarkViewsReached)},
+		BookmarkViewThreshold: 10,
+	})
+	sandboxRule, err := service.CreateAutomationRule("7", AutomationRuleRequest{
+		Name:       "Viral Go links",
+		Trigger:    "bookmark_views_reached",
+		Conditions: map[string]interface{}{"min_views": float64(200), "domain": "go.dev"},
+		Actions:    map[string]interface{}{"tag": "viral"},
+		Sandbox:    true,
+	})
+	suite.Require().NoError(err)
+
+	// When: The thresholds are evaluated twice
+	fired, err := service.EvaluateThresholds(ctx)
+	suite.Require().NoError(err)
+	again, err := service.EvaluateThresholds(ctx)
+	suite.Require().NoError(err)
+
+	// Then: Each endpoint hears once about the shared bookmarks past its threshold
+	suite.Equal(3, fired)
+	suite.Zero(again)
+	deliveries := suite.thresholdDeliveries(byDefault)
+	suite.Require().Len(deliveries, 1)
+	data := deliveries[0].Payload["data"].(map[string]interface{})
+	suite.Equal(float64(1), data["bookmark_id"])
+	suite.Equal(float64(120), data["views"])
+	suite.Equal(float64(100), data["threshold"])
+	suite.Len(suite.thresholdDeliveries(everyTen), 2)
+
+	// When: The bookmark reaches the rule's threshold
+	suite.Require().NoError(suite.GetTestDB().Exec(`UPDATE social_metrics SET total_views = 250 WHERE bookmark_id = 1`).Error)
+	fired, err = service.EvaluateThresholds(ctx)
+	suite.Require().NoError(err)
+
+	// Then: Only the sandbox rule fires, capturing what it would do
+	suite.Equal(1, fired)
+	captures, err := service.GetSandboxCaptures("7", 10)
+	suite.Require().NoError(err)
+	suite.Require().Len(captures, 1)
+	suite.Equal(sandboxRule.ID, *captures[0].RuleID)
+	suite.True(captures[0].Matched)
+}
+
+func (suite *AutomationServiceTestSuite) TestEvaluateThresholds_UnvisitedBookmarks() {
+	ctx := context.Background()
+	service := suite.GetTestService()
+	db := suite.GetTestDB()
+	suite.createThresholdTables()
+	now := time.Now()
+	saved := now.AddDate(0, -8, 0)
+
+	// Given: Bookmarks saved eight months ago: one never visited, one viewed
+	// last week and one opened last month
+	suite.addThresholdBookmark(1, "https://example.com/old", saved, 2, 0)
+	suite.addThresholdBookmark(2, "https://example.com/viewed", saved, 2, 0)
+	suite.addThresholdBookmark(3, "https://example.com/opened", saved, 2, 0)
+	suite.Require().NoError(db.Exec(`INSERT INTO user_behaviors (user_id, bookmark_id, action_type, created_at) VALUES ('7', 2, 'view', ?)`, now.AddDate(0, 0, -7)).Error)
+	suite.Require().NoError(db.Exec(`UPDATE bookmarks SET last_accessed_at = ? WHERE id = 3`, now.AddDate(0, -1, 0)).Error)
+	endpoint := suite.createThresholdEndpoint(WebhookEndpointRequest{Events: []string{string(WebhookEventBookmarkUnvisited)}})
+	yearly, err := service.CreateAutomationRule("7", AutomationRuleRequest{
+		Name: "Forgotten for a year", Trigger: "bookmark_unvisited",
+		Conditions: map[string]interface{}{"unvisited_months": float64(12)},
+		Actions:    map[string]interface{}{"archive": true},
+	})
+	suite.Require().NoError(err)
+	old, err := service.CreateAutomationRule("7", AutomationRuleRequest{
+		Name: "Forgotten old links", Trigger: "bookmark_unvisited",
+		Conditions: map[string]interface{}{"url_contains": "old"},
+		Actions:    map[string]interface{}{"archive": true},
+	})
+	suite.Require().NoError(err)
+
+	// When: The thresholds are evaluated twice
+	fired, err := service.EvaluateThresholds(ctx)
+	suite.Require().NoError(err)
+	again, err := service.EvaluateThresholds(ctx)
+	suite.Require().NoError(err)
+
+	// Then: Only the bookmark never visited fires, for the endpoint and the
+	// six month rule
+	suite.Equal(2, fired)
+	suite.Zero(again)
+	deliveries := suite.thresholdDeliveries(endpoint)
+	suite.Require().Len(deliveries, 1)
+	data := deliveries[0].Payload["data"].(map[string]interface{})
+	suite.Equal(float64(1), data["bookmark_id"])
+	suite.InDelta(8, data["months_unvisited"], 1, "calendar months, whatever the day")
+
+	var rules []AutomationRule
+	suite.Require().NoError(db.Order("id").Find(&rules, []uint{yearly.ID, old.ID}).Error)
+	suite.Zero(rules[0].ExecutionCount)
+	suite.Equal(1, rules[1].ExecutionCount)
+	suite.NotNil(rules[1].LastExecuted)
+
+	// When: The bookmark was visited after the event fired, but long enough ago
+	suite.Require().NoError(db.Model(&ThresholdFiring{}).Where("bookmark_id = 1").Update("fired_at", now.AddDate(0, -9, 0)).Error)
+	suite.Require().NoError(db.Exec(`INSERT INTO user_behaviors (user_id, bookmark_id, action_type, created_at) VALUES ('7', 1, 'click', ?)`, now.AddDate(0, -7, 0)).Error)
+	fired, err = service.EvaluateThresholds(ctx)
+	suite.Require().NoError(err)
+
+	// Then: The new idle period fires the event again
+	suite.Equal(2, fired)
+	suite.Len(suite.thresholdDeliveries(endpoint), 2)
+}
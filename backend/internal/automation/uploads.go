@@ -0,0 +1,337 @@
+package automation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/internal/metering"
+)
+
+// Import upload statuses
+const (
+	ImportUploadPending  = "pending"
+	ImportUploadUploaded = "uploaded"
+	ImportUploadImported = "imported"
+)
+
+const (
+	// importUploadPrefix namespaces import files in the bucket
+	importUploadPrefix = "imports/"
+	// importUploadURLExpiry is how long an upload URL accepts the file
+	importUploadURLExpiry = time.Hour
+	// maxImportUploadSize caps import files uploaded to storage
+	maxImportUploadSize = 2 << 30
+)
+
+// unsafeFilenameChars are replaced in object keys built from filenames
+var unsafeFilenameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// ImportUploadStore issues upload URLs for, and inspects, import files
+type ImportUploadStore interface {
+	// PresignPut returns a URL that accepts a PUT of the object until expiry
+	PresignPut(key string, expiry time.Duration) (string, error)
+	// Stat returns the object's size, or ErrImportObjectNotFound
+	Stat(ctx context.Context, key string) (int64, error)
+	// Upload stores size bytes read from body as the object
+	Upload(ctx context.Context, key string, body io.Reader, size int64) (string, error)
+}
+
+// NewImportUploadStore returns a store for import files in an S3 compatible
+// bucket such as the MinIO one used for screenshots
+func NewImportUploadStore(endpoint, accessKeyID, secretAccessKey, bucket string, useSSL bool) ImportUploadStore {
+	return NewS3DestinationClient(&BackupDestination{
+		Endpoint:    endpoint,
+		Bucket:      bucket,
+		AccessKeyID: accessKeyID,
+		UseSSL:      useSSL,
+	}, secretAccessKey).(*s3Client)
+}
+
+// ImportUploadURL is where a client uploads an import file
+type ImportUploadURL struct {
+	Upload    *ImportUpload `json:"upload"`
+	UploadURL string        `json:"upload_url"`
+	Method    string        `json:"method"`
+	ExpiresAt time.Time     `json:"expires_at"`
+}
+
+// UploadEvent is an S3 bucket notification, as posted by MinIO webhook targets
+type UploadEvent struct {
+	EventName string              `json:"EventName"`
+	Key       string              `json:"Key"`
+	Records   []UploadEventRecord `json:"Records"`
+}
+
+// UploadEventRecord describes one object in an S3 bucket notification
+type UploadEventRecord struct {
+	EventName string `json:"eventName"`
+	S3        struct {
+		Object struct {
+			Key  string `json:"key"` // URL encoded
+			Size int64  `json:"size"`
+		} `json:"object"`
+	} `json:"s3"`
+}
+
+// SetImportUploadStore enables direct uploads of import files. Without a
+// store, imports only take files the API can reach itself
+func (s *Service) SetImportUploadStore(store ImportUploadStore) {
+	s.importUploads = store
+}
+
+// CreateImportUploadURL registers an import file and returns a presigned
+// URL to upload it to, bypassing the API's request size limits
+func (s *Service) CreateImportUploadURL(userID string, req ImportUploadRequest) (*ImportUploadURL, error) {
+	if s.importUploads == nil {
+		return nil, ErrImportUploadsDisabled
+	}
+
+	expiresAt := time.Now().Add(importUploadURLExpiry).UTC().Truncate(time.Second)
+	var upload ImportUpload
+	if req.ObjectKey != "" {
+		// A new URL for an earlier upload, e.g. after the first one expired
+		if err := s.db.Where("user_id = ? AND object_key = ?", userID, req.ObjectKey).First(&upload).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrImportUploadNotFound
+			}
+			return nil, fmt.Errorf("failed to get import upload: %w", err)
+		}
+		if upload.Status == ImportUploadImported {
+			return nil, ErrImportUploadUsed
+		}
+		upload.ExpiresAt = expiresAt
+		if err := s.db.Model(&upload).Update("expires_at", expiresAt).Error; err != nil {
+			return nil, fmt.Errorf("failed to update import upload: %w", err)
+		}
+	} else {
+		created, err := s.createImportUpload(userID, req, expiresAt)
+		if err != nil {
+			return nil, err
+		}
+		upload = *created
+	}
+
+	uploadURL, err := s.importUploads.PresignPut(upload.ObjectKey, importUploadURLExpiry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign upload URL: %w", err)
+	}
+	return &ImportUploadURL{Upload: &upload, UploadURL: uploadURL, Method: "PUT", ExpiresAt: expiresAt}, nil
+}
+
+// HandleUploadEvent records import files that storage reports as uploaded
+// and starts the imports asked to run on upload. Objects that are not
+// pending import uploads, whose upload URL expired or that storage does not
+// hold are ignored; the size recorded is the stored object's, not the
+// event's. It returns the imports started
+func (s *Service) HandleUploadEvent(ctx context.Context, event UploadEvent) (int, error) {
+	if s.importUploads == nil {
+		return 0, ErrImportUploadsDisabled
+	}
+	started := 0
+	for _, record := range event.Records {
+		if !strings.Contains(record.EventName, "ObjectCreated:") {
+			continue
+		}
+		key, err := url.QueryUnescape(record.S3.Object.Key)
+		if err != nil || !strings.HasPrefix(key, importUploadPrefix) {
+			continue
+		}
+
+		var upload ImportUpload
+		if err := s.db.WithContext(ctx).Where("object_key = ? AND status = ?", key, ImportUploadPending).First(&upload).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			return started, fmt.Errorf("failed to get import upload: %w", err)
+		}
+		if time.Now().After(upload.ExpiresAt) {
+			continue
+		}
+		size, err := s.importUploads.Stat(ctx, key)
+		if errors.Is(err, ErrImportObjectNotFound) {
+			continue
+		}
+		if err != nil {
+			return started, fmt.Errorf("failed to check import upload: %w", err)
+		}
+
+		imported, err := s.markImportUploaded(ctx, &upload, size)
+		if err != nil {
+			return started, err
+		}
+		if imported {
+			started++
+		}
+	}
+	return started, nil
+}
+
+// UploadImportFile streams an import file through the API to storage, for
+// clients that cannot upload to a presigned URL. Storage needs the size
+// before the upload starts, so it is given up front and must match
+func (s *Service) UploadImportFile(ctx context.Context, userID string, req ImportUploadRequest, body io.Reader, size int64) (*ImportUpload, error) {
+	if s.importUploads == nil {
+		return nil, ErrImportUploadsDisabled
+	}
+	if size <= 0 {
+		return nil, ErrImportUploadSize
+	}
+	if size > maxImportUploadSize {
+		return nil, fmt.Errorf("%w: %d bytes, at most %d allowed", ErrImportUploadTooLarge, size, maxImportUploadSize)
+	}
+
+	upload, err := s.createImportUpload(userID, req, time.Now().Add(importUploadURLExpiry).UTC().Truncate(time.Second))
+	if err != nil {
+		return nil, err
+	}
+
+	// A failed upload leaves the record pending; a new upload URL for its
+	// object key can still complete it
+	if _, err := s.importUploads.Upload(ctx, upload.ObjectKey, io.LimitReader(body, size), size); err != nil {
+		return nil, fmt.Errorf("failed to store import file: %w", err)
+	}
+	var extra [1]byte
+	if n, _ := io.ReadFull(body, extra[:]); n > 0 {
+		return nil, ErrImportUploadSize
+	}
+
+	if _, err := s.markImportUploaded(ctx, upload, size); err != nil {
+		return nil, err
+	}
+	return upload, nil
+}
+
+// createImportUpload registers a pending import file under a new object key
+func (s *Service) createImportUpload(userID string, req ImportUploadRequest, expiresAt time.Time) (*ImportUpload, error) {
+	token, err := s.generatePublicKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate object key: %w", err)
+	}
+	upload := &ImportUpload{
+		UserID:     userID,
+		ObjectKey:  importUploadPrefix + userID + "/" + token + "/" + importObjectName(req.Filename),
+		Filename:   req.Filename,
+		Source:     req.Source,
+		AutoImport: req.AutoImport,
+		Status:     ImportUploadPending,
+		ExpiresAt:  expiresAt,
+	}
+	if err := s.db.Create(upload).Error; err != nil {
+		return nil, fmt.Errorf("failed to create import upload: %w", err)
+	}
+	return upload, nil
+}
+
+// markImportUploaded records an import file as complete in storage and
+// starts its import when asked to. It reports whether an import started
+func (s *Service) markImportUploaded(ctx context.Context, upload *ImportUpload, size int64) (bool, error) {
+	now := time.Now()
+	if err := s.db.WithContext(ctx).Model(upload).Updates(map[string]interface{}{
+		"status":      ImportUploadUploaded,
+		"size":        size,
+		"uploaded_at": now,
+	}).Error; err != nil {
+		return false, fmt.Errorf("failed to update import upload: %w", err)
+	}
+	upload.Status, upload.Size, upload.UploadedAt = ImportUploadUploaded, size, &now
+	s.meter(upload.UserID, metering.MetricStorageBytes, size)
+	if !upload.AutoImport {
+		return false, nil
+	}
+
+	parameters := map[string]interface{}{"object_key": upload.ObjectKey}
+	if upload.Source != "" {
+		parameters["source"] = upload.Source
+	}
+	if _, err := s.CreateBulkOperation(upload.UserID, BulkOperationRequest{Type: "import", Parameters: parameters}); err != nil {
+		// The upload stays importable by hand, e.g. if it is too large
+		return false, nil
+	}
+	return true, nil
+}
+
+// checkImportUpload verifies the upload an import operation references is
+// the user's and is complete in storage, adding its size to the parameters
+func (s *Service) checkImportUpload(userID string, parameters map[string]interface{}) error {
+	value, ok := parameters["object_key"]
+	if !ok {
+		return nil
+	}
+	key, isString := value.(string)
+	if !isString || key == "" {
+		return ErrBulkOperationInvalidParams
+	}
+	if s.importUploads == nil {
+		return fmt.Errorf("%w: %w", ErrBulkOperationInvalidParams, ErrImportUploadsDisabled)
+	}
+
+	var upload ImportUpload
+	if err := s.db.Where("user_id = ? AND object_key = ?", userID, key).First(&upload).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: %w", ErrBulkOperationInvalidParams, ErrImportUploadNotFound)
+		}
+		return fmt.Errorf("failed to get import upload: %w", err)
+	}
+	if upload.Status == ImportUploadImported {
+		return fmt.Errorf("%w: %w", ErrBulkOperationInvalidParams, ErrImportUploadUsed)
+	}
+
+	size, err := s.importUploads.Stat(context.Background(), key)
+	if errors.Is(err, ErrImportObjectNotFound) {
+		return fmt.Errorf("%w: %w", ErrBulkOperationInvalidParams, ErrImportUploadIncomplete)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check import upload: %w", err)
+	}
+	if size > maxImportUploadSize {
+		return fmt.Errorf("%w: %w: %d bytes, at most %d allowed", ErrBulkOperationInvalidParams, ErrImportUploadTooLarge, size, maxImportUploadSize)
+	}
+	parameters["object_size"] = size
+	return nil
+}
+
+// claimImportUpload marks the upload an import operation reads as imported,
+// so one file is not imported twice
+func claimImportUpload(tx *gorm.DB, operation *BulkOperation) error {
+	key, ok := operation.Parameters["object_key"].(string)
+	if operation.Type != "import" || !ok {
+		return nil
+	}
+
+	updates := map[string]interface{}{
+		"status":       ImportUploadImported,
+		"operation_id": operation.ID,
+	}
+	if size, ok := operation.Parameters["object_size"].(int64); ok {
+		updates["size"] = size
+	}
+	result := tx.Model(&ImportUpload{}).
+		Where("user_id = ? AND object_key = ? AND status <> ?", operation.UserID, key, ImportUploadImported).
+		Updates(updates)
+	if result.Error != nil {
+		return fmt.Errorf("failed to claim import upload: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: %w", ErrBulkOperationInvalidParams, ErrImportUploadUsed)
+	}
+	return nil
+}
+
+// importObjectName turns an uploaded filename into a safe object name
+func importObjectName(filename string) string {
+	name := unsafeFilenameChars.ReplaceAllString(path.Base(strings.ReplaceAll(filename, "\\", "/")), "_")
+	name = strings.Trim(name, "._")
+	if name == "" {
+		return "import"
+	}
+	return name
+}
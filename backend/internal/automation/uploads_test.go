@@ -0,0 +1,276 @@
+package automation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryUploadStore holds the sizes of uploaded objects
+type memoryUploadStore struct {
+	objects map[string]int64
+}
+
+func (m *memoryUploadStore) PresignPut(key string, expiry time.Duration) (string, error) {
+	return "https://minio.example.com/bookmarks/" + key + "?X-Amz-Expires=" + expiry.String(), nil
+}
+
+func (m *memoryUploadStore) Stat(ctx context.Context, key string) (int64, error) {
+	size, ok := m.objects[key]
+	if !ok {
+		return 0, ErrImportObjectNotFound
+	}
+	return size, nil
+}
+
+func (m *memoryUploadStore) Upload(ctx context.Context, key string, body io.Reader, size int64) (string, error) {
+	read, err := io.Copy(io.Discard, body)
+	if err != nil {
+		return "", err
+	}
+	if read != size {
+		return "", fmt.Errorf("read %d bytes, expected %d", read, size)
+	}
+	m.objects[key] = size
+	return "s3://bookmarks/" + key, nil
+}
+
+func (suite *AutomationServiceTestSuite) setupImportUploads() *memoryUploadStore {
+	store := &memoryUploadStore{objects: map[string]int64{}}
+	suite.GetTestService().SetImportUploadStore(store)
+	return store
+}
+
+func (suite *AutomationServiceTestSuite) TestImportUpload_ReferencedByImport() {
+	store := suite.setupImportUploads()
+	service := suite.GetTestService()
+	userID := suite.GetTestUserID()
+
+	// Given: An upload URL for a browser export
+	upload, err := service.CreateImportUploadURL(userID, ImportUploadRequest{Filename: `C:\Exports\my bookmarks.html`, Source: "chrome"})
+	suite.Require().NoError(err)
+	key := upload.Upload.ObjectKey
+	suite.True(strings.HasPrefix(key, "imports/"+userID+"/"))
+	suite.True(strings.HasSuffix(key, "/my_bookmarks.html"))
+	suite.Equal(ImportUploadPending, upload.Upload.Status)
+	suite.Equal("PUT", upload.Method)
+	suite.Contains(upload.UploadURL, key)
+
+	// When: Importing before the file reached storage
+	importReq := BulkOperationRequest{Type: "import", Parameters: map[string]interface{}{"object_key": key}}
+	_, err = service.CreateBulkOperation(userID, importReq)
+
+	// Then: The import is refused
+	suite.ErrorIs(err, ErrBulkOperationInvalidParams)
+	suite.ErrorIs(err, ErrImportUploadIncomplete)
+
+	// When: Importing the uploaded file
+	store.objects[key] = 300 << 20
+	operation, err := service.CreateBulkOperation(userID, importReq)
+
+	// Then: The operation reads it and the upload is used up
+	suite.Require().NoError(err)
+	suite.Equal(int64(300<<20), operation.Parameters["object_size"])
+	var stored ImportUpload
+	suite.Require().NoError(suite.db.Where("object_key = ?", key).First(&stored).Error)
+	suite.Equal(ImportUploadImported, stored.Status)
+	suite.Equal(operation.ID, *stored.OperationID)
+
+	_, err = service.CreateBulkOperation(userID, importReq)
+	suite.ErrorIs(err, ErrImportUploadUsed)
+	_, err = service.CreateImportUploadURL(userID, ImportUploadRequest{Filename: "x", ObjectKey: key})
+	suite.ErrorIs(err, ErrImportUploadUsed)
+
+	// And: Other users cannot import it
+	_, err = service.CreateBulkOperation("someone-else", importReq)
+	suite.ErrorIs(err, ErrImportUploadNotFound)
+}
+
+func (suite *AutomationServiceTestSuite) TestImportUpload_Limits() {
+	service := suite.GetTestService()
+	_, err := service.CreateImportUploadURL(suite.GetTestUserID(), ImportUploadRequest{Filename: "a.html"})
+	suite.ErrorIs(err, ErrImportUploadsDisabled)
+
+	store := suite.setupImportUploads()
+	first, err := service.CreateImportUploadURL(suite.GetTestUserID(), ImportUploadRequest{Filename: "../../.."})
+	suite.Require().NoError(err)
+	suite.True(strings.HasSuffix(first.Upload.ObjectKey, "/import"))
+
+	// A retried upload keeps its object key
+	again, err := service.CreateImportUploadURL(suite.GetTestUserID(), ImportUploadRequest{Filename: "a.html", ObjectKey: first.Upload.ObjectKey})
+	suite.Require().NoError(err)
+	suite.Equal(first.Upload.ID, again.Upload.ID)
+	_, err = service.CreateImportUploadURL(suite.GetTestUserID(), ImportUploadRequest{Filename: "a.html", ObjectKey: "imports/other/key"})
+	suite.ErrorIs(err, ErrImportUploadNotFound)
+
+	store.objects[first.Upload.ObjectKey] = maxImportUploadSize + 1
+	_, err = service.CreateBulkOperation(suite.GetTestUserID(), BulkOperationRequest{Type: "import", Parameters: map[string]interface{}{"object_key": first.Upload.ObjectKey}})
+	suite.ErrorIs(err, ErrImportUploadTooLarge)
+}
+
+func (suite *AutomationServiceTestSuite) TestHandleUploadEvent_StartsAutoImports() {
+	store := suite.setupImportUploads()
+	service := suite.GetTestService()
+	auto, err := service.CreateImportUploadURL(suite.GetTestUserID(), ImportUploadRequest{Filename: "firefox.json", Source: "firefox", AutoImport: true})
+	suite.Require().NoError(err)
+	manual, err := service.CreateImportUploadURL(suite.GetTestUserID(), ImportUploadRequest{Filename: "safari.plist"})
+	suite.Require().NoError(err)
+	expired, err := service.CreateImportUploadURL(suite.GetTestUserID(), ImportUploadRequest{Filename: "late.html", AutoImport: true})
+	suite.Require().NoError(err)
+	suite.Require().NoError(suite.db.Model(&ImportUpload{}).Where("id = ?", expired.Upload.ID).Update("expires_at", time.Now().Add(-time.Minute)).Error)
+	missing, err := service.CreateImportUploadURL(suite.GetTestUserID(), ImportUploadRequest{Filename: "forged.html", AutoImport: true})
+	suite.Require().NoError(err)
+
+	// Given: Storage reports the files, an unrelated object, an expired
+	// upload and an object it does not hold
+	var event UploadEvent
+	for _, key := range []string{auto.Upload.ObjectKey, manual.Upload.ObjectKey, "screenshots/1.jpg", expired.Upload.ObjectKey, missing.Upload.ObjectKey} {
+		if key != missing.Upload.ObjectKey {
+			store.objects[key] = 2048
+		}
+		record := UploadEventRecord{EventName: "s3:ObjectCreated:Put"}
+		record.S3.Object.Key = url.QueryEscape(key)
+		record.S3.Object.Size = 1024
+		event.Records = append(event.Records, record)
+	}
+
+	// When: Handling the notification
+	started, err := service.HandleUploadEvent(context.Background(), event)
+
+	// Then: Only the auto import starts, the other upload waits for its import request
+	suite.Require().NoError(err)
+	suite.Equal(1, started)
+	operations, err := service.GetBulkOperations(suite.GetTestUserID())
+	suite.Require().NoError(err)
+	suite.Require().Len(operations, 1)
+	suite.Equal("firefox", operations[0].Parameters["source"])
+
+	var waiting ImportUpload
+	suite.Require().NoError(suite.db.First(&waiting, manual.Upload.ID).Error)
+	suite.Equal(ImportUploadUploaded, waiting.Status)
+	suite.Equal(int64(2048), waiting.Size, "the stored size wins over the event's")
+	suite.NotNil(waiting.UploadedAt)
+
+	// And: Expired and missing uploads stay pending
+	for _, id := range []uint{expired.Upload.ID, missing.Upload.ID} {
+		var pending ImportUpload
+		suite.Require().NoError(suite.db.First(&pending, id).Error)
+		suite.Equal(ImportUploadPending, pending.Status)
+	}
+}
+
+func (suite *AutomationHandlerTestSuite) TestImportUploadRoutes() {
+	suite.GetTestService().SetImportUploadStore(&memoryUploadStore{objects: map[string]int64{}})
+
+	w := suite.makeRequest("POST", "/api/v1/automation/bulk/upload-url", ImportUploadRequest{Filename: "bookmarks.html"})
+	suite.Require().Equal(http.StatusCreated, w.Code)
+	var upload ImportUploadURL
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &upload))
+	suite.NotEmpty(upload.UploadURL)
+
+	w = suite.makeRequest("POST", "/api/v1/automation/bulk/upload-url", map[string]string{})
+	suite.Equal(http.StatusBadRequest, w.Code)
+
+	sendEvent := func(token string) int {
+		req := httptest.NewRequest("POST", "/api/v1/automation/bulk/upload-events", bytes.NewBufferString(`{"Records":[]}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		suite.router.ServeHTTP(w, req)
+		return w.Code
+	}
+	suite.Equal(http.StatusUnauthorized, sendEvent(""), "refused until a token is configured")
+	suite.handler.SetUploadEventToken("minio-token")
+	suite.Equal(http.StatusUnauthorized, sendEvent("wrong"))
+	suite.Equal(http.StatusOK, sendEvent("minio-token"))
+}
+
+func (suite *AutomationHandlerTestSuite) TestUploadImportFile_Streams() {
+	store := &memoryUploadStore{objects: map[string]int64{}}
+	suite.GetTestService().SetImportUploadStore(store)
+
+	upload := func(fields map[string]string, content string) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		writer := multipart.NewWriter(&buf)
+		for _, name := range []string{"size", "source", "auto_import"} {
+			if value, ok := fields[name]; ok {
+				suite.Require().NoError(writer.WriteField(name, value))
+			}
+		}
+		part, err := writer.CreateFormFile("file", "bookmarks.html")
+		suite.Require().NoError(err)
+		_, err = part.Write([]byte(content))
+		suite.Require().NoError(err)
+		suite.Require().NoError(writer.Close())
+
+		req := httptest.NewRequest("POST", "/api/v1/automation/bulk/upload", &buf)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		w := httptest.NewRecorder()
+		suite.router.ServeHTTP(w, req)
+		return w
+	}
+
+	// The file goes to storage and is ready to import
+	content := "<DL><DT><A HREF=\"https://go.dev\">Go</A></DL>"
+	w := upload(map[string]string{"size": strconv.Itoa(len(content)), "source": "firefox"}, content)
+	suite.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+	var created ImportUpload
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &created))
+	suite.Equal(ImportUploadUploaded, created.Status)
+	suite.Equal("firefox", created.Source)
+	suite.Equal(int64(len(content)), store.objects[created.ObjectKey])
+
+	// The size must be sent first and match the file
+	suite.Equal(http.StatusBadRequest, upload(map[string]string{}, content).Code)
+	suite.Equal(http.StatusBadRequest, upload(map[string]string{"size": "4"}, content).Code)
+	w = upload(map[string]string{"size": strconv.Itoa(maxImportUploadSize + 1)}, content)
+	suite.Equal(http.StatusRequestEntityTooLarge, w.Code)
+	suite.Contains(w.Body.String(), "limit_bytes")
+}
+
+func TestS3Client_PresignAndStat(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodHead, r.Method)
+		if r.URL.Path != "/bookmarks/imports/a.html" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Length", "2048")
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		w.Header().Set("ETag", `"d41d8cd98f00b204e9800998ecf8427e"`)
+	}))
+	defer server.Close()
+
+	store := NewImportUploadStore(server.URL, "AKIA", "secret", "bookmarks", false)
+	size, err := store.Stat(context.Background(), "imports/a.html")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2048), size)
+	_, err = store.Stat(context.Background(), "imports/missing.html")
+	assert.ErrorIs(t, err, ErrImportObjectNotFound)
+
+	presigned, err := store.PresignPut("imports/my file.html", time.Hour)
+	require.NoError(t, err)
+	parsed, err := url.Parse(presigned)
+	require.NoError(t, err)
+	assert.Equal(t, "/bookmarks/imports/my file.html", parsed.Path)
+	query := parsed.Query()
+	assert.Equal(t, "3600", query.Get("X-Amz-Expires"))
+	assert.Equal(t, "host", query.Get("X-Amz-SignedHeaders"))
+	assert.True(t, strings.HasPrefix(query.Get("X-Amz-Credential"), "AKIA/"))
+	assert.Len(t, query.Get("X-Amz-Signature"), 64)
+
+	_, err = store.PresignPut("imports/a.html", 8*24*time.Hour)
+	assert.Error(t, err)
+}
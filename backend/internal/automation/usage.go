@@ -0,0 +1,33 @@
+package automation
+
+import (
+	"strconv"
+
+	"bookmark-sync-service/backend/internal/metering"
+)
+
+// UsageMeter counts a user's use of metered features
+type UsageMeter interface {
+	Record(userID uint, metric string, amount int64)
+}
+
+// SetUsageMeter makes webhook deliveries and uploaded import files count
+// towards the owner's usage
+func (s *Service) SetUsageMeter(meter UsageMeter) {
+	s.usage = meter
+}
+
+// meter records usage of the user with a string ID, as automation models
+// keep them
+func (s *Service) meter(userID, metric string, amount int64) {
+	if s.usage == nil {
+		return
+	}
+	if id, err := strconv.ParseUint(userID, 10, 64); err == nil {
+		s.usage.Record(uint(id), metric, amount)
+	}
+}
+
+func (s *Service) meterDelivery(endpoint *WebhookEndpoint) {
+	s.meter(endpoint.UserID, metering.MetricWebhookDeliveries, 1)
+}
@@ -0,0 +1,224 @@
+package automation
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// MinBackupVolumeSize is the smallest volume a backup can be split into
+const MinBackupVolumeSize = 1 << 20
+
+// manifestSuffix is appended to the archive name for the manifest of its volumes
+const manifestSuffix = ".manifest.json"
+
+// BackupManifest lists the volumes a backup archive was split into.
+// Concatenating the volumes in order gives back the archive
+type BackupManifest struct {
+	Archive    string         `json:"archive"`
+	Size       int64          `json:"size"`
+	Checksum   string         `json:"checksum"`
+	VolumeSize int64          `json:"volume_size"`
+	Volumes    []BackupVolume `json:"volumes"`
+}
+
+// BackupVolume is one part of a split backup archive
+type BackupVolume struct {
+	Index    int    `json:"index"` // from 1
+	Name     string `json:"name"`
+	Size     int64  `json:"size"`
+	Checksum string `json:"checksum"`
+	// DownloadURL is a signed link, only set when the manifest is handed out
+	DownloadURL string `json:"download_url,omitempty"`
+}
+
+// BackupDownload holds fresh signed links to a completed backup. Links
+// expire, so clients resuming a download after that fetch new ones
+type BackupDownload struct {
+	BackupID uint `json:"backup_id"`
+	// DownloadURL is the archive, or the manifest of a split backup
+	DownloadURL string          `json:"download_url"`
+	ExpiresAt   time.Time       `json:"expires_at"`
+	Manifest    *BackupManifest `json:"manifest,omitempty"`
+}
+
+// splitBackup splits the archive into volumes of at most volumeSize bytes
+// next to it, writes their manifest and removes the archive. It returns the
+// manifest path
+func splitBackup(archivePath string, volumeSize int64, checksum string) (string, *BackupManifest, error) {
+	archive, err := os.Open(archivePath)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to open backup file: %w", err)
+	}
+	defer archive.Close()
+
+	manifest := &BackupManifest{
+		Archive:    filepath.Base(archivePath),
+		Checksum:   checksum,
+		VolumeSize: volumeSize,
+	}
+	written := []string{}
+	cleanup := func() {
+		for _, path := range written {
+			os.Remove(path)
+		}
+	}
+
+	for index := 1; ; index++ {
+		volume := BackupVolume{Index: index, Name: fmt.Sprintf("%s.%03d", manifest.Archive, index)}
+		path := filepath.Join(filepath.Dir(archivePath), volume.Name)
+
+		size, sum, err := writeVolume(path, io.LimitReader(archive, volumeSize))
+		if err != nil {
+			os.Remove(path)
+			cleanup()
+			return "", nil, err
+		}
+		if size == 0 && index > 1 {
+			// The archive ended with the previous volume
+			os.Remove(path)
+			break
+		}
+		written = append(written, path)
+
+		volume.Size, volume.Checksum = size, sum
+		manifest.Volumes = append(manifest.Volumes, volume)
+		manifest.Size += size
+		if size < volumeSize {
+			break
+		}
+	}
+
+	manifestPath := archivePath + manifestSuffix
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err == nil {
+		err = os.WriteFile(manifestPath, data, 0o644)
+	}
+	if err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to write backup manifest: %w", err)
+	}
+
+	archive.Close()
+	os.Remove(archivePath)
+	return manifestPath, manifest, nil
+}
+
+// writeVolume copies r into a new volume file, returning its size and checksum
+func writeVolume(path string, r io.Reader) (int64, string, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to create backup volume: %w", err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(file, hash), r)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to write backup volume: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return 0, "", fmt.Errorf("failed to write backup volume: %w", err)
+	}
+	return size, "sha256:" + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// readBackupManifest loads the manifest of a split backup
+func readBackupManifest(manifestPath string) (*BackupManifest, error) {
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return nil, ErrBackupFileNotFound
+	}
+	var manifest BackupManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, ErrBackupFileCorrupted
+	}
+	return &manifest, nil
+}
+
+// backupVolumePath returns the file of a volume of a completed, split backup
+func backupVolumePath(job *BackupJob, volume int) (string, error) {
+	if job.Volumes == 0 || job.FilePath == "" {
+		return "", ErrBackupVolumeNotFound
+	}
+	manifest, err := readBackupManifest(job.FilePath)
+	if err != nil {
+		return "", err
+	}
+	if volume < 1 || volume > len(manifest.Volumes) {
+		return "", ErrBackupVolumeNotFound
+	}
+	return filepath.Join(filepath.Dir(job.FilePath), manifest.Volumes[volume-1].Name), nil
+}
+
+// splitBackupJob splits a finished backup's archive into the requested volumes
+func (s *Service) splitBackupJob(job *BackupJob) error {
+	manifestPath, manifest, err := splitBackup(job.FilePath, job.VolumeSize, job.Checksum)
+	if err != nil {
+		return err
+	}
+	job.FilePath = manifestPath
+	job.Volumes = len(manifest.Volumes)
+	return nil
+}
+
+// GetBackupVolumePath retrieves the file of one volume of a split backup
+func (s *Service) GetBackupVolumePath(userID string, id uint, volume int) (string, error) {
+	var job BackupJob
+	if err := s.db.Where("id = ? AND user_id = ? AND status = ?", id, userID, "completed").First(&job).Error; err != nil {
+		return "", ErrBackupJobNotFound
+	}
+	return backupVolumePath(&job, volume)
+}
+
+// GetBackupDownload returns fresh signed links to a completed backup and,
+// for a split backup, to each of its volumes
+func (s *Service) GetBackupDownload(userID string, id uint) (*BackupDownload, error) {
+	var job BackupJob
+	if err := s.db.Where("id = ? AND user_id = ? AND status = ?", id, userID, "completed").First(&job).Error; err != nil {
+		return nil, ErrBackupJobNotFound
+	}
+	if job.FilePath == "" {
+		return nil, ErrBackupFileNotFound
+	}
+	return s.backupDownload(&job)
+}
+
+// backupDownload signs the links to a backup's file and volumes
+func (s *Service) backupDownload(job *BackupJob) (*BackupDownload, error) {
+	now := time.Now()
+	download := &BackupDownload{BackupID: job.ID}
+	download.DownloadURL, download.ExpiresAt = s.downloads.Sign(ArtifactBackup, job.ID, now)
+	if job.Volumes == 0 {
+		return download, nil
+	}
+
+	manifest, err := readBackupManifest(job.FilePath)
+	if err != nil {
+		return nil, err
+	}
+	for i := range manifest.Volumes {
+		manifest.Volumes[i].DownloadURL, _ = s.downloads.SignVolume(ArtifactBackup, job.ID, manifest.Volumes[i].Index, now)
+	}
+	download.Manifest = manifest
+	return download, nil
+}
+
+// validateVolumeSize checks the volume size a backup was requested with
+func validateVolumeSize(size int64) error {
+	if size != 0 && size < MinBackupVolumeSize {
+		return fmt.Errorf("%w of %d bytes", ErrBackupVolumeSize, MinBackupVolumeSize)
+	}
+	return nil
+}
+
+// isBackupNotFound reports whether err means the backup or its files are missing
+func isBackupNotFound(err error) bool {
+	return errors.Is(err, ErrBackupJobNotFound) || errors.Is(err, ErrBackupFileNotFound) || errors.Is(err, ErrBackupVolumeNotFound)
+}
@@ -0,0 +1,142 @@
+package automation
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitBackup(t *testing.T) {
+	tests := []struct {
+		name    string
+		size    int
+		volumes []int64
+	}{
+		{"last volume is short", 25, []int64{10, 10, 5}},
+		{"exact multiple", 20, []int64{10, 10}},
+		{"smaller than a volume", 3, []int64{3}},
+		{"empty archive", 0, []int64{0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			archivePath := filepath.Join(dir, "full_1.tar.gz")
+			content := []byte(strings.Repeat("0123456789", 3)[:tt.size])
+			require.NoError(t, os.WriteFile(archivePath, content, 0o644))
+
+			manifestPath, manifest, err := splitBackup(archivePath, 10, "sha256:whole")
+			require.NoError(t, err)
+			assert.Equal(t, archivePath+manifestSuffix, manifestPath)
+			assert.NoFileExists(t, archivePath)
+
+			stored, err := readBackupManifest(manifestPath)
+			require.NoError(t, err)
+			assert.Equal(t, manifest, stored)
+			assert.Equal(t, "full_1.tar.gz", stored.Archive)
+			assert.Equal(t, int64(tt.size), stored.Size)
+			assert.Equal(t, "sha256:whole", stored.Checksum)
+
+			var joined []byte
+			for i, volume := range stored.Volumes {
+				assert.Equal(t, i+1, volume.Index)
+				assert.Equal(t, tt.volumes[i], volume.Size)
+				assert.True(t, strings.HasPrefix(volume.Checksum, "sha256:"))
+				data, err := os.ReadFile(filepath.Join(dir, volume.Name))
+				require.NoError(t, err)
+				joined = append(joined, data...)
+			}
+			assert.Len(t, stored.Volumes, len(tt.volumes))
+			assert.True(t, bytes.Equal(content, joined), "the volumes add up to the archive")
+		})
+	}
+}
+
+func TestDownloadSigner_SignVolume(t *testing.T) {
+	signer := NewDownloadSigner("", "secret", time.Minute)
+	now := time.Unix(1700000000, 0)
+
+	link, _ := signer.SignVolume(ArtifactBackup, 42, 2, now)
+	parsed, err := url.Parse(link)
+	require.NoError(t, err)
+	assert.Equal(t, "2", parsed.Query().Get("volume"))
+	expires, signature := parsed.Query().Get("expires"), parsed.Query().Get("signature")
+
+	assert.NoError(t, signer.VerifyVolume(ArtifactBackup, 42, 2, expires, signature, now))
+	assert.ErrorIs(t, signer.VerifyVolume(ArtifactBackup, 42, 3, expires, signature, now), ErrDownloadLinkInvalid)
+	assert.ErrorIs(t, signer.Verify(ArtifactBackup, 42, expires, signature, now), ErrDownloadLinkInvalid, "a volume link doesn't open the whole archive")
+}
+
+func (suite *AutomationHandlerTestSuite) TestCreateBackupJob_VolumeSizeTooSmall() {
+	w := suite.makeRequest("POST", "/api/v1/automation/backup", BackupRequest{Type: "full", VolumeSize: 1024})
+	suite.Equal(http.StatusBadRequest, w.Code)
+}
+
+func (suite *AutomationHandlerTestSuite) TestBackupVolumes_ResumableDownload() {
+	// Given: A completed backup split into volumes of 10 bytes
+	dir := suite.T().TempDir()
+	archivePath := filepath.Join(dir, "full_1.tar.gz")
+	suite.Require().NoError(os.WriteFile(archivePath, []byte("abcdefghijklmnopqrstuvwxy"), 0o644))
+	job := &BackupJob{UserID: suite.GetTestUserID(), Type: "full", Status: "completed", FilePath: archivePath, VolumeSize: 10}
+	suite.Require().NoError(suite.GetTestService().splitBackupJob(job))
+	suite.Require().NoError(suite.GetTestDB().Create(job).Error)
+	suite.Equal(3, job.Volumes)
+
+	// When: Fetching fresh download links
+	download, err := suite.GetTestService().GetBackupDownload(suite.GetTestUserID(), job.ID)
+	suite.Require().NoError(err)
+	suite.Require().NotNil(download.Manifest)
+	suite.Require().Len(download.Manifest.Volumes, 3)
+
+	// Then: The second volume resumes from a byte offset through its signed link
+	get := func(link string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, link, nil)
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		w := httptest.NewRecorder()
+		suite.router.ServeHTTP(w, req)
+		return w
+	}
+
+	full := get(download.Manifest.Volumes[1].DownloadURL, nil)
+	suite.Equal(http.StatusOK, full.Code)
+	suite.Equal("klmnopqrst", full.Body.String())
+	suite.Equal("bytes", full.Header().Get("Accept-Ranges"))
+	etag := full.Header().Get("ETag")
+	suite.NotEmpty(etag)
+
+	partial := get(download.Manifest.Volumes[1].DownloadURL, http.Header{"Range": {"bytes=4-"}, "If-Range": {etag}})
+	suite.Equal(http.StatusPartialContent, partial.Code)
+	suite.Equal("opqrst", partial.Body.String())
+	suite.Equal("bytes 4-9/10", partial.Header().Get("Content-Range"))
+
+	// A stale ETag gets the whole volume again instead of a mismatched range
+	stale := get(download.Manifest.Volumes[1].DownloadURL, http.Header{"Range": {"bytes=4-"}, "If-Range": {`"stale"`}})
+	suite.Equal(http.StatusOK, stale.Code)
+
+	// The link to the manifest serves the manifest, and the authenticated route serves volumes too
+	manifest := get(download.DownloadURL, nil)
+	suite.Equal(http.StatusOK, manifest.Code)
+	suite.Contains(manifest.Body.String(), `"full_1.tar.gz.003"`)
+
+	last := suite.makeRequest("GET", "/api/v1/automation/backup/"+fmt.Sprint(job.ID)+"/volumes/3", nil)
+	suite.Equal(http.StatusOK, last.Code)
+	suite.Equal("uvwxy", last.Body.String())
+
+	missing := suite.makeRequest("GET", "/api/v1/automation/backup/"+fmt.Sprint(job.ID)+"/volumes/4", nil)
+	suite.Equal(http.StatusNotFound, missing.Code)
+
+	// A volume link can't be pointed at another volume
+	tampered := strings.Replace(download.Manifest.Volumes[1].DownloadURL, "volume=2", "volume=1", 1)
+	suite.Equal(http.StatusForbidden, get(tampered, nil).Code)
+}
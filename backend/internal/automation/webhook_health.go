@@ -0,0 +1,343 @@
+package automation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WebhookEventTest is sent to verify an endpoint before it is re-enabled
+const WebhookEventTest WebhookEvent = "webhook.test"
+
+// NotificationWebhookDisabled is the notification type sent when an endpoint is auto-disabled
+const NotificationWebhookDisabled = "webhook.disabled"
+
+// Health statuses reported for webhook endpoints
+const (
+	WebhookHealthUnknown  = "unknown"
+	WebhookHealthHealthy  = "healthy"
+	WebhookHealthDegraded = "degraded"
+	WebhookHealthFailing  = "failing"
+	WebhookHealthDisabled = "disabled"
+)
+
+// recentFailureLimit caps the failures included in a disable notification
+const recentFailureLimit = 5
+
+// WebhookHealthPolicy controls when failing endpoints are disabled
+type WebhookHealthPolicy struct {
+	// MaxConsecutiveFailures disables an endpoint after this many failed deliveries in a row
+	MaxConsecutiveFailures int
+	// MaxFailingDuration disables an endpoint that has failed without a success for this long
+	MaxFailingDuration time.Duration
+	// Window is the period the rolling success rate is computed over
+	Window time.Duration
+}
+
+// DefaultWebhookHealthPolicy disables endpoints after 50 consecutive failures or 7 days failing
+var DefaultWebhookHealthPolicy = WebhookHealthPolicy{
+	MaxConsecutiveFailures: 50,
+	MaxFailingDuration:     7 * 24 * time.Hour,
+	Window:                 7 * 24 * time.Hour,
+}
+
+// NotificationPublisher publishes a notification on a user's channel
+type NotificationPublisher interface {
+	PublishNotification(ctx context.Context, userID string, notification interface{}) error
+}
+
+// WebhookHealth summarizes recent delivery results for an endpoint
+type WebhookHealth struct {
+	EndpointID           uint       `json:"endpoint_id"`
+	Status               string     `json:"status"`
+	Active               bool       `json:"active"`
+	SuccessRate          float64    `json:"success_rate"`
+	Deliveries           int64      `json:"deliveries"`
+	SuccessfulDeliveries int64      `json:"successful_deliveries"`
+	WindowDays           int        `json:"window_days"`
+	ConsecutiveFailures  int        `json:"consecutive_failures"`
+	FailingSince         *time.Time `json:"failing_since,omitempty"`
+	LastSuccessAt        *time.Time `json:"last_success_at,omitempty"`
+	LastFailureAt        *time.Time `json:"last_failure_at,omitempty"`
+	DisabledAt           *time.Time `json:"disabled_at,omitempty"`
+	DisabledReason       string     `json:"disabled_reason,omitempty"`
+	CircuitOpenUntil     *time.Time `json:"circuit_open_until,omitempty"`
+	SkippedDeliveries    int64      `json:"skipped_deliveries"` // waiting to be replayed
+}
+
+// WebhookFailureSummary describes one failed delivery
+type WebhookFailureSummary struct {
+	DeliveryID uint         `json:"delivery_id"`
+	Event      WebhookEvent `json:"event"`
+	StatusCode int          `json:"status_code,omitempty"`
+	Error      string       `json:"error,omitempty"`
+	At         time.Time    `json:"at"`
+}
+
+// WebhookDisabledNotification tells the owner an endpoint was disabled
+type WebhookDisabledNotification struct {
+	Type                string                  `json:"type"`
+	EndpointID          uint                    `json:"endpoint_id"`
+	Name                string                  `json:"name"`
+	URL                 string                  `json:"url"`
+	Reason              string                  `json:"reason"`
+	ConsecutiveFailures int                     `json:"consecutive_failures"`
+	FailingSince        *time.Time              `json:"failing_since,omitempty"`
+	DisabledAt          time.Time               `json:"disabled_at"`
+	RecentFailures      []WebhookFailureSummary `json:"recent_failures"`
+}
+
+// WebhookTestError reports why a test delivery failed
+type WebhookTestError struct {
+	StatusCode int
+	Message    string
+}
+
+// Error implements the error interface
+func (e *WebhookTestError) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("%s: endpoint returned HTTP %d", ErrWebhookTestFailed, e.StatusCode)
+	}
+	return fmt.Sprintf("%s: %s", ErrWebhookTestFailed, e.Message)
+}
+
+// Unwrap lets callers match test failures with errors.Is
+func (e *WebhookTestError) Unwrap() error {
+	return ErrWebhookTestFailed
+}
+
+// SetWebhookHealthPolicy configures when failing endpoints are disabled
+func (s *Service) SetWebhookHealthPolicy(policy WebhookHealthPolicy) {
+	s.healthPolicy = policy
+}
+
+// SetNotificationPublisher configures how endpoint owners are notified
+func (s *Service) SetNotificationPublisher(publisher NotificationPublisher) {
+	s.notifications = publisher
+}
+
+// GetWebhookHealth returns the health of an endpoint over the policy window
+func (s *Service) GetWebhookHealth(userID string, id uint) (*WebhookHealth, error) {
+	endpoint, err := s.getWebhookEndpoint(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	since := time.Now().Add(-s.healthPolicy.Window)
+	var total, successful int64
+	if err := s.db.Model(&WebhookDelivery{}).
+		Where("endpoint_id = ? AND created_at >= ? AND status IN ?", id, since, []string{"success", "failed"}).
+		Count(&total).Error; err != nil {
+		return nil, fmt.Errorf("failed to count deliveries: %w", err)
+	}
+	if err := s.db.Model(&WebhookDelivery{}).
+		Where("endpoint_id = ? AND created_at >= ? AND status = ?", id, since, "success").
+		Count(&successful).Error; err != nil {
+		return nil, fmt.Errorf("failed to count deliveries: %w", err)
+	}
+	var skipped int64
+	if err := s.db.Model(&WebhookDelivery{}).
+		Where("endpoint_id = ? AND status = ?", id, "skipped").
+		Count(&skipped).Error; err != nil {
+		return nil, fmt.Errorf("failed to count deliveries: %w", err)
+	}
+
+	health := &WebhookHealth{
+		EndpointID:           endpoint.ID,
+		Active:               endpoint.Active,
+		Deliveries:           total,
+		SuccessfulDeliveries: successful,
+		WindowDays:           int(s.healthPolicy.Window / (24 * time.Hour)),
+		ConsecutiveFailures:  endpoint.ConsecutiveFailures,
+		FailingSince:         endpoint.FailingSince,
+		LastSuccessAt:        endpoint.LastSuccessAt,
+		LastFailureAt:        endpoint.LastFailureAt,
+		DisabledAt:           endpoint.DisabledAt,
+		DisabledReason:       endpoint.DisabledReason,
+		SkippedDeliveries:    skipped,
+	}
+	if circuitOpen(endpoint, time.Now()) {
+		health.CircuitOpenUntil = endpoint.CircuitOpenUntil
+	}
+	if total > 0 {
+		health.SuccessRate = float64(successful) / float64(total)
+	}
+
+	switch {
+	case endpoint.DisabledAt != nil:
+		health.Status = WebhookHealthDisabled
+	case total == 0:
+		health.Status = WebhookHealthUnknown
+	case health.SuccessRate >= 0.95:
+		health.Status = WebhookHealthHealthy
+	case health.SuccessRate >= 0.5:
+		health.Status = WebhookHealthDegraded
+	default:
+		health.Status = WebhookHealthFailing
+	}
+
+	return health, nil
+}
+
+// EnableWebhookEndpoint reactivates an endpoint after a successful test delivery
+func (s *Service) EnableWebhookEndpoint(ctx context.Context, userID string, id uint) (*WebhookEndpoint, error) {
+	endpoint, err := s.getWebhookEndpoint(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := WebhookPayload{
+		Event:     WebhookEventTest,
+		Timestamp: time.Now(),
+		UserID:    userID,
+		Data: map[string]interface{}{
+			"endpoint_id": endpoint.ID,
+			"message":     "Test delivery sent before re-enabling this endpoint",
+		},
+	}
+	delivery := &WebhookDelivery{
+		EndpointID: endpoint.ID,
+		Event:      WebhookEventTest,
+		Payload:    InterfaceMap(s.structToMap(payload)),
+		Status:     "pending",
+	}
+	if err := s.db.Create(delivery).Error; err != nil {
+		return nil, fmt.Errorf("failed to create test delivery: %w", err)
+	}
+
+	// The test runs synchronously so the caller learns the outcome
+	s.deliverWebhook(ctx, endpoint, delivery, payload)
+	if delivery.Status != "success" {
+		return nil, &WebhookTestError{StatusCode: delivery.StatusCode, Message: delivery.Error}
+	}
+
+	if err := s.db.Model(endpoint).Updates(map[string]interface{}{
+		"active":               true,
+		"disabled_at":          nil,
+		"disabled_reason":      "",
+		"consecutive_failures": 0,
+		"failing_since":        nil,
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to enable webhook endpoint: %w", err)
+	}
+
+	return s.getWebhookEndpoint(userID, id)
+}
+
+// recordDeliveryResult updates an endpoint's health counters and disables it
+// once the failure streak exceeds the policy
+func (s *Service) recordDeliveryResult(ctx context.Context, endpoint *WebhookEndpoint, success bool) {
+	now := time.Now()
+
+	if success {
+		s.db.Model(&WebhookEndpoint{}).Where("id = ?", endpoint.ID).Updates(map[string]interface{}{
+			"consecutive_failures": 0,
+			"failing_since":        nil,
+			"last_success_at":      now,
+		})
+		return
+	}
+
+	// The failure is counted and the disable decision made in one transaction,
+	// so the counters and the active flag never disagree. pkg/database imports
+	// this package, so the transaction is opened on gorm directly
+	var current WebhookEndpoint
+	var reason string
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Counters are updated in SQL so concurrent deliveries do not lose increments
+		if err := tx.Model(&WebhookEndpoint{}).Where("id = ?", endpoint.ID).Updates(map[string]interface{}{
+			"consecutive_failures": gorm.Expr("consecutive_failures + 1"),
+			"failing_since":        gorm.Expr("COALESCE(failing_since, ?)", now),
+			"last_failure_at":      now,
+		}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.First(&current, endpoint.ID).Error; err != nil || !current.Active {
+			return err
+		}
+
+		reason = s.disableReason(&current, now)
+		if reason == "" {
+			return nil
+		}
+
+		// Only the delivery that flips the endpoint to inactive sends the notification
+		result := tx.Model(&WebhookEndpoint{}).Where("id = ? AND active = ?", current.ID, true).Updates(map[string]interface{}{
+			"active":          false,
+			"disabled_at":     now,
+			"disabled_reason": reason,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			reason = ""
+		}
+		return nil
+	})
+	if err != nil || reason == "" {
+		return
+	}
+
+	// The owner is told only once the disable is committed
+	s.notifyEndpointDisabled(ctx, &current, reason, now)
+}
+
+func (s *Service) disableReason(endpoint *WebhookEndpoint, now time.Time) string {
+	policy := s.healthPolicy
+	if policy.MaxConsecutiveFailures > 0 && endpoint.ConsecutiveFailures >= policy.MaxConsecutiveFailures {
+		return fmt.Sprintf("%d consecutive failed deliveries", endpoint.ConsecutiveFailures)
+	}
+	if policy.MaxFailingDuration > 0 && endpoint.FailingSince != nil && now.Sub(*endpoint.FailingSince) >= policy.MaxFailingDuration {
+		return fmt.Sprintf("no successful delivery since %s", endpoint.FailingSince.UTC().Format(time.RFC3339))
+	}
+	return ""
+}
+
+func (s *Service) notifyEndpointDisabled(ctx context.Context, endpoint *WebhookEndpoint, reason string, disabledAt time.Time) {
+	if s.notifications == nil {
+		return
+	}
+
+	var deliveries []WebhookDelivery
+	s.db.Where("endpoint_id = ? AND status = ?", endpoint.ID, "failed").
+		Order("created_at DESC").Limit(recentFailureLimit).Find(&deliveries)
+
+	failures := make([]WebhookFailureSummary, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		failures = append(failures, WebhookFailureSummary{
+			DeliveryID: delivery.ID,
+			Event:      delivery.Event,
+			StatusCode: delivery.StatusCode,
+			Error:      delivery.Error,
+			At:         delivery.UpdatedAt,
+		})
+	}
+
+	s.notifications.PublishNotification(ctx, endpoint.UserID, &WebhookDisabledNotification{
+		Type:                NotificationWebhookDisabled,
+		EndpointID:          endpoint.ID,
+		Name:                endpoint.Name,
+		URL:                 endpoint.URL,
+		Reason:              reason,
+		ConsecutiveFailures: endpoint.ConsecutiveFailures,
+		FailingSince:        endpoint.FailingSince,
+		DisabledAt:          disabledAt,
+		RecentFailures:      failures,
+	})
+}
+
+func (s *Service) getWebhookEndpoint(userID string, id uint) (*WebhookEndpoint, error) {
+	var endpoint WebhookEndpoint
+	if err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&endpoint).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWebhookEndpointNotFound
+		}
+		return nil, fmt.Errorf("failed to get webhook endpoint: %w", err)
+	}
+	return &endpoint, nil
+}
@@ -0,0 +1,177 @@
+package automation
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// recordingPublisher captures notifications published to users
+type recordingPublisher struct {
+	notifications []interface{}
+}
+
+func (p *recordingPublisher) PublishNotification(ctx context.Context, userID string, notification interface{}) error {
+	p.notifications = append(p.notifications, notification)
+	return nil
+}
+
+// webhookReceiver returns a test server whose status code can be switched
+func webhookReceiver(status *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(atomic.LoadInt32(status)))
+	}))
+}
+
+func (suite *AutomationServiceTestSuite) createHealthEndpoint(url string) *WebhookEndpoint {
+	endpoint, err := suite.GetTestService().CreateWebhookEndpoint(suite.GetTestUserID(), WebhookEndpointRequest{
+		Name:   "Receiver",
+		URL:    url,
+		Events: []string{"bookmark.created"},
+		Active: true,
+	})
+	suite.Require().NoError(err)
+	return endpoint
+}
+
+func (suite *AutomationServiceTestSuite) deliver(endpoint *WebhookEndpoint) {
+	payload := WebhookPayload{Event: WebhookEventBookmarkCreated, Timestamp: time.Now(), UserID: suite.GetTestUserID()}
+	delivery := &WebhookDelivery{EndpointID: endpoint.ID, Event: payload.Event, Status: "pending"}
+	suite.Require().NoError(suite.GetTestDB().Create(delivery).Error)
+	suite.GetTestService().deliverWebhook(context.Background(), endpoint, delivery, payload)
+}
+
+func (suite *AutomationServiceTestSuite) TestWebhookHealth_AutoDisablesAfterFailureStreak() {
+	status := int32(http.StatusInternalServerError)
+	server := webhookReceiver(&status)
+	defer server.Close()
+
+	publisher := &recordingPublisher{}
+	service := suite.GetTestService()
+	service.SetNotificationPublisher(publisher)
+	service.SetWebhookHealthPolicy(WebhookHealthPolicy{MaxConsecutiveFailures: 3, Window: 24 * time.Hour})
+	endpoint := suite.createHealthEndpoint(server.URL)
+
+	// When: The endpoint fails three deliveries in a row
+	for i := 0; i < 3; i++ {
+		suite.deliver(endpoint)
+	}
+
+	// Then: It is disabled and the owner receives a failure summary
+	health, err := service.GetWebhookHealth(suite.GetTestUserID(), endpoint.ID)
+	suite.Require().NoError(err)
+	suite.Equal(WebhookHealthDisabled, health.Status)
+	suite.False(health.Active)
+	suite.Equal(3, health.ConsecutiveFailures)
+	suite.Equal(int64(3), health.Deliveries)
+	suite.Equal(0.0, health.SuccessRate)
+
+	suite.Require().Len(publisher.notifications, 1)
+	notification := publisher.notifications[0].(*WebhookDisabledNotification)
+	suite.Equal(NotificationWebhookDisabled, notification.Type)
+	suite.Equal("3 consecutive failed deliveries", notification.Reason)
+	suite.Len(notification.RecentFailures, 3)
+	suite.Equal(http.StatusInternalServerError, notification.RecentFailures[0].StatusCode)
+
+	// And: A plain update cannot reactivate it
+	updated, err := service.UpdateWebhookEndpoint(suite.GetTestUserID(), endpoint.ID, WebhookEndpointRequest{
+		Name: "Receiver", URL: server.URL, Events: []string{"bookmark.created"}, Active: true,
+	})
+	suite.Require().NoError(err)
+	suite.False(updated.Active)
+}
+
+func (suite *AutomationServiceTestSuite) TestWebhookHealth_DisablesAfterFailingDuration() {
+	status := int32(http.StatusBadGateway)
+	server := webhookReceiver(&status)
+	defer server.Close()
+
+	service := suite.GetTestService()
+	service.SetWebhookHealthPolicy(WebhookHealthPolicy{MaxConsecutiveFailures: 50, MaxFailingDuration: 7 * 24 * time.Hour, Window: 24 * time.Hour})
+	endpoint := suite.createHealthEndpoint(server.URL)
+
+	failingSince := time.Now().Add(-8 * 24 * time.Hour)
+	suite.Require().NoError(suite.GetTestDB().Model(endpoint).Updates(map[string]interface{}{
+		"failing_since": failingSince, "consecutive_failures": 10,
+	}).Error)
+
+	// When: Another delivery fails after a week of failures
+	suite.deliver(endpoint)
+
+	// Then: The endpoint is disabled
+	reloaded, err := service.getWebhookEndpoint(suite.GetTestUserID(), endpoint.ID)
+	suite.Require().NoError(err)
+	suite.False(reloaded.Active)
+	suite.Contains(reloaded.DisabledReason, "no successful delivery since")
+}
+
+func (suite *AutomationServiceTestSuite) TestWebhookHealth_DisableIsAtomic() {
+	status := int32(http.StatusInternalServerError)
+	server := webhookReceiver(&status)
+	defer server.Close()
+
+	publisher := &recordingPublisher{}
+	service := suite.GetTestService()
+	service.SetNotificationPublisher(publisher)
+	service.SetWebhookHealthPolicy(WebhookHealthPolicy{MaxConsecutiveFailures: 1, Window: 24 * time.Hour})
+	endpoint := suite.createHealthEndpoint(server.URL)
+
+	// Given: Disabling the endpoint fails
+	const callback = "test:fail_disable"
+	db := suite.GetTestDB()
+	suite.Require().NoError(db.Callback().Update().Before("gorm:update").Register(callback, func(tx *gorm.DB) {
+		if updates, ok := tx.Statement.Dest.(map[string]interface{}); ok {
+			if _, disabling := updates["disabled_at"]; disabling {
+				tx.AddError(errors.New("disable rejected"))
+			}
+		}
+	}))
+	defer db.Callback().Update().Remove(callback)
+
+	// When: A delivery fails
+	suite.deliver(endpoint)
+
+	// Then: The failure count is rolled back with it and nobody is notified
+	reloaded, err := service.getWebhookEndpoint(suite.GetTestUserID(), endpoint.ID)
+	suite.Require().NoError(err)
+	suite.True(reloaded.Active)
+	suite.Equal(0, reloaded.ConsecutiveFailures)
+	suite.Nil(reloaded.LastFailureAt)
+	suite.Empty(publisher.notifications)
+}
+
+func (suite *AutomationServiceTestSuite) TestEnableWebhookEndpoint_RequiresSuccessfulTest() {
+	status := int32(http.StatusInternalServerError)
+	server := webhookReceiver(&status)
+	defer server.Close()
+
+	service := suite.GetTestService()
+	service.SetWebhookHealthPolicy(WebhookHealthPolicy{MaxConsecutiveFailures: 1, Window: 24 * time.Hour})
+	endpoint := suite.createHealthEndpoint(server.URL)
+	suite.deliver(endpoint)
+
+	// When: Enabling while the receiver still fails
+	_, err := service.EnableWebhookEndpoint(context.Background(), suite.GetTestUserID(), endpoint.ID)
+
+	// Then: The endpoint stays disabled
+	suite.ErrorIs(err, ErrWebhookTestFailed)
+	suite.Contains(err.Error(), "HTTP 500")
+	reloaded, _ := service.getWebhookEndpoint(suite.GetTestUserID(), endpoint.ID)
+	suite.False(reloaded.Active)
+
+	// When: The receiver recovers
+	atomic.StoreInt32(&status, http.StatusOK)
+	enabled, err := service.EnableWebhookEndpoint(context.Background(), suite.GetTestUserID(), endpoint.ID)
+
+	// Then: The endpoint is active again with a clean streak
+	suite.Require().NoError(err)
+	suite.True(enabled.Active)
+	suite.Nil(enabled.DisabledAt)
+	suite.Equal(0, enabled.ConsecutiveFailures)
+	suite.NotNil(enabled.LastSuccessAt)
+}
@@ -0,0 +1,265 @@
+package automation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WebhookDeliveryLimits bounds outgoing webhook traffic so one account
+// can't flood a slow receiver
+type WebhookDeliveryLimits struct {
+	// MaxConcurrent caps deliveries in flight across all endpoints, 0 for no limit
+	MaxConcurrent int
+	// PerEndpoint caps deliveries in flight to one endpoint, 0 for no limit
+	PerEndpoint int
+	// BreakerTimeouts opens an endpoint's circuit after this many timeouts
+	// in a row, 0 disables the breaker
+	BreakerTimeouts int
+	// BreakerCooldown is how long an open circuit skips deliveries
+	BreakerCooldown time.Duration
+}
+
+// DefaultWebhookDeliveryLimits sends at most 4 deliveries at a time to an
+// endpoint and backs off for 5 minutes after 5 timeouts in a row
+var DefaultWebhookDeliveryLimits = WebhookDeliveryLimits{
+	MaxConcurrent:   50,
+	PerEndpoint:     4,
+	BreakerTimeouts: 5,
+	BreakerCooldown: 5 * time.Minute,
+}
+
+// SetWebhookDeliveryLimits configures delivery concurrency and the circuit
+// breaker. It must be called before deliveries start
+func (s *Service) SetWebhookDeliveryLimits(limits WebhookDeliveryLimits) {
+	s.deliveryLimits = limits
+	s.limiter = newDeliveryLimiter(limits)
+}
+
+// deliveryLimiter hands out delivery slots, globally and per endpoint
+type deliveryLimiter struct {
+	global      chan struct{}
+	perEndpoint int
+
+	mu        sync.Mutex
+	endpoints map[uint]*endpointSlots
+}
+
+// endpointSlots are the slots of one endpoint, dropped once nobody holds or
+// waits for them
+type endpointSlots struct {
+	slots   chan struct{}
+	waiters int
+}
+
+func newDeliveryLimiter(limits WebhookDeliveryLimits) *deliveryLimiter {
+	limiter := &deliveryLimiter{perEndpoint: limits.PerEndpoint, endpoints: map[uint]*endpointSlots{}}
+	if limits.MaxConcurrent > 0 {
+		limiter.global = make(chan struct{}, limits.MaxConcurrent)
+	}
+	return limiter
+}
+
+// acquire blocks until a delivery to the endpoint may start. The endpoint
+// slot is taken first, so deliveries queued behind a slow receiver don't
+// hold global slots other endpoints could use
+func (l *deliveryLimiter) acquire(ctx context.Context, endpointID uint) (func(), error) {
+	releaseEndpoint, err := l.acquireEndpoint(ctx, endpointID)
+	if err != nil {
+		return nil, err
+	}
+
+	if l.global != nil {
+		select {
+		case l.global <- struct{}{}:
+		case <-ctx.Done():
+			releaseEndpoint()
+			return nil, ctx.Err()
+		}
+	}
+
+	return func() {
+		if l.global != nil {
+			<-l.global
+		}
+		releaseEndpoint()
+	}, nil
+}
+
+func (l *deliveryLimiter) acquireEndpoint(ctx context.Context, endpointID uint) (func(), error) {
+	if l.perEndpoint <= 0 {
+		return func() {}, nil
+	}
+
+	l.mu.Lock()
+	endpoint, ok := l.endpoints[endpointID]
+	if !ok {
+		endpoint = &endpointSlots{slots: make(chan struct{}, l.perEndpoint)}
+		l.endpoints[endpointID] = endpoint
+	}
+	endpoint.waiters++
+	l.mu.Unlock()
+
+	done := func() {
+		l.mu.Lock()
+		endpoint.waiters--
+		if endpoint.waiters == 0 {
+			delete(l.endpoints, endpointID)
+		}
+		l.mu.Unlock()
+	}
+
+	select {
+	case endpoint.slots <- struct{}{}:
+		return func() {
+			<-endpoint.slots
+			done()
+		}, nil
+	case <-ctx.Done():
+		done()
+		return nil, ctx.Err()
+	}
+}
+
+// queueDelivery sends a delivery once the concurrency limits allow it, or
+// records it as skipped while the endpoint's circuit is open
+func (s *Service) queueDelivery(ctx context.Context, endpoint WebhookEndpoint, delivery *WebhookDelivery, payload WebhookPayload) {
+	if circuitOpen(&endpoint, time.Now()) {
+		s.skipDelivery(delivery, *endpoint.CircuitOpenUntil)
+		return
+	}
+
+	s.executor.Execute(func() {
+		release, err := s.limiter.acquire(ctx, endpoint.ID)
+		if err != nil {
+			s.updateDeliveryError(delivery, err.Error(), 0)
+			return
+		}
+		defer release()
+
+		// The circuit may have opened while this delivery waited for a slot
+		var current WebhookEndpoint
+		if err := s.db.Select("id", "circuit_open_until").First(&current, endpoint.ID).Error; err == nil && circuitOpen(&current, time.Now()) {
+			s.skipDelivery(delivery, *current.CircuitOpenUntil)
+			return
+		}
+
+		s.deliverWebhook(ctx, &endpoint, delivery, payload)
+	})
+}
+
+// circuitOpen reports whether deliveries to the endpoint are skipped at now
+func circuitOpen(endpoint *WebhookEndpoint, now time.Time) bool {
+	return endpoint.CircuitOpenUntil != nil && now.Before(*endpoint.CircuitOpenUntil)
+}
+
+// skipDelivery records a delivery that wasn't sent; it keeps its payload
+// so ReplaySkippedDeliveries can send it once the circuit closes
+func (s *Service) skipDelivery(delivery *WebhookDelivery, until time.Time) {
+	delivery.Status = "skipped"
+	delivery.Error = fmt.Sprintf("circuit open until %s after repeated timeouts", until.UTC().Format(time.RFC3339))
+	delivery.NextRetryAt = &until
+	s.db.Save(delivery)
+}
+
+// isTimeout reports whether a request failed because the receiver didn't
+// answer in time
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// recordTimeout tracks an endpoint's timeout streak and opens its circuit
+// when the streak reaches the limit. A timeout right after the cool-down
+// reopens it at once, any answer from the receiver closes it
+func (s *Service) recordTimeout(ctx context.Context, endpoint *WebhookEndpoint, timedOut bool) {
+	if !timedOut {
+		s.db.WithContext(ctx).Model(&WebhookEndpoint{}).
+			Where("id = ? AND (consecutive_timeouts > 0 OR circuit_open_until IS NOT NULL)", endpoint.ID).
+			Updates(map[string]interface{}{
+				"consecutive_timeouts": 0,
+				"circuit_open_until":   nil,
+			})
+		return
+	}
+
+	limits := s.deliveryLimits
+	s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&WebhookEndpoint{}).Where("id = ?", endpoint.ID).
+			UpdateColumn("consecutive_timeouts", gorm.Expr("consecutive_timeouts + 1")).Error; err != nil {
+			return err
+		}
+		if limits.BreakerTimeouts <= 0 {
+			return nil
+		}
+
+		var current WebhookEndpoint
+		if err := tx.First(&current, endpoint.ID).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		if current.ConsecutiveTimeouts < limits.BreakerTimeouts || circuitOpen(&current, now) {
+			return nil
+		}
+		return tx.Model(&WebhookEndpoint{}).Where("id = ?", current.ID).
+			UpdateColumn("circuit_open_until", now.Add(limits.BreakerCooldown)).Error
+	})
+}
+
+// ReplaySkippedDeliveries queues the deliveries an endpoint's open circuit
+// skipped, oldest first, and returns how many were queued
+func (s *Service) ReplaySkippedDeliveries(ctx context.Context, userID string, id uint) (int, error) {
+	endpoint, err := s.getWebhookEndpoint(userID, id)
+	if err != nil {
+		return 0, err
+	}
+	if !endpoint.Active {
+		return 0, ErrWebhookEndpointInactive
+	}
+	if circuitOpen(endpoint, time.Now()) {
+		return 0, ErrWebhookCircuitOpen
+	}
+
+	var deliveries []WebhookDelivery
+	if err := s.db.WithContext(ctx).Where("endpoint_id = ? AND status = ?", endpoint.ID, "skipped").
+		Order("created_at ASC").Find(&deliveries).Error; err != nil {
+		return 0, fmt.Errorf("failed to get skipped deliveries: %w", err)
+	}
+
+	// Deliveries outlive the request that replays them
+	ctx = context.WithoutCancel(ctx)
+	queued := 0
+	for i := range deliveries {
+		delivery := &deliveries[i]
+
+		var payload WebhookPayload
+		data, _ := json.Marshal(delivery.Payload)
+		if err := json.Unmarshal(data, &payload); err != nil {
+			s.updateDeliveryError(delivery, "Failed to decode payload", 0)
+			continue
+		}
+
+		// Claiming the delivery first keeps concurrent replays from sending it twice
+		result := s.db.Model(&WebhookDelivery{}).Where("id = ? AND status = ?", delivery.ID, "skipped").
+			Updates(map[string]interface{}{"status": "pending", "error": "", "next_retry_at": nil})
+		if result.Error != nil {
+			return queued, fmt.Errorf("failed to replay delivery: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+		delivery.Status = "pending"
+		delivery.Error = ""
+		delivery.NextRetryAt = nil
+
+		s.queueDelivery(ctx, *endpoint, delivery, payload)
+		queued++
+	}
+	return queued, nil
+}
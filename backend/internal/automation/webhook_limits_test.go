@@ -0,0 +1,153 @@
+package automation
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// syncExecutor runs deliveries inline so tests can inspect their results
+type syncExecutor struct{}
+
+func (syncExecutor) Execute(fn func()) {
+	fn()
+}
+
+func TestDeliveryLimiter_CapsConcurrency(t *testing.T) {
+	limiter := newDeliveryLimiter(WebhookDeliveryLimits{MaxConcurrent: 2, PerEndpoint: 1})
+	blocked := func(endpointID uint) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		_, err := limiter.acquire(ctx, endpointID)
+		return err
+	}
+
+	releaseFirst, err := limiter.acquire(context.Background(), 1)
+	require.NoError(t, err)
+
+	// A second delivery to the same endpoint waits for the first
+	assert.ErrorIs(t, blocked(1), context.DeadlineExceeded)
+
+	// Another endpoint gets the last global slot, a third one has to wait
+	releaseSecond, err := limiter.acquire(context.Background(), 2)
+	require.NoError(t, err)
+	assert.ErrorIs(t, blocked(3), context.DeadlineExceeded)
+
+	releaseFirst()
+	releaseAgain, err := limiter.acquire(context.Background(), 1)
+	require.NoError(t, err)
+
+	releaseAgain()
+	releaseSecond()
+	assert.Empty(t, limiter.endpoints)
+}
+
+func (suite *AutomationServiceTestSuite) TestWebhookDeliveryLimits_TimeoutStreak() {
+	service := suite.GetTestService()
+	service.SetWebhookDeliveryLimits(WebhookDeliveryLimits{BreakerTimeouts: 3, BreakerCooldown: time.Minute})
+	endpoint := suite.createHealthEndpoint("http://receiver.example.com/hook")
+	reload := func() *WebhookEndpoint {
+		reloaded, err := service.getWebhookEndpoint(suite.GetTestUserID(), endpoint.ID)
+		suite.Require().NoError(err)
+		return reloaded
+	}
+
+	// When: The receiver times out twice, below the limit
+	service.recordTimeout(context.Background(), endpoint, true)
+	service.recordTimeout(context.Background(), endpoint, true)
+
+	// Then: The circuit stays closed
+	suite.Equal(2, reload().ConsecutiveTimeouts)
+	suite.Nil(reload().CircuitOpenUntil)
+
+	// When: It answers again
+	service.recordTimeout(context.Background(), endpoint, false)
+
+	// Then: The streak starts over
+	suite.Equal(0, reload().ConsecutiveTimeouts)
+
+	// When: It then times out three times in a row
+	for i := 0; i < 3; i++ {
+		service.recordTimeout(context.Background(), endpoint, true)
+	}
+
+	// Then: The circuit opens for the cool-down
+	opened := reload()
+	suite.Require().NotNil(opened.CircuitOpenUntil)
+	suite.WithinDuration(time.Now().Add(time.Minute), *opened.CircuitOpenUntil, 5*time.Second)
+}
+
+func (suite *AutomationServiceTestSuite) TestWebhookDeliveryLimits_OpenCircuitSkipsAndReplays() {
+	var slow, requests int32 = 1, 0
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if atomic.LoadInt32(&slow) == 1 {
+			select {
+			case <-release:
+			case <-time.After(5 * time.Second):
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	defer close(release)
+
+	service := suite.GetTestService()
+	service.executor = syncExecutor{}
+	service.SetWebhookDeliveryLimits(WebhookDeliveryLimits{PerEndpoint: 1, BreakerTimeouts: 1, BreakerCooldown: time.Minute})
+	endpoint := suite.createHealthEndpoint(server.URL)
+	suite.Require().NoError(suite.GetTestDB().Model(endpoint).Update("timeout", 1).Error)
+
+	// When: A delivery times out
+	suite.Require().NoError(service.TriggerWebhook(context.Background(), WebhookEventBookmarkCreated, suite.GetTestUserID(), nil))
+
+	// Then: The endpoint's circuit opens
+	health, err := service.GetWebhookHealth(suite.GetTestUserID(), endpoint.ID)
+	suite.Require().NoError(err)
+	suite.Require().NotNil(health.CircuitOpenUntil)
+
+	// When: Another event fires while the circuit is open
+	suite.Require().NoError(service.TriggerWebhook(context.Background(), WebhookEventBookmarkCreated, suite.GetTestUserID(), map[string]interface{}{"id": 7}))
+
+	// Then: It is recorded as skipped without contacting the receiver
+	suite.Equal(int32(1), atomic.LoadInt32(&requests))
+	var skipped WebhookDelivery
+	suite.Require().NoError(suite.GetTestDB().Where("endpoint_id = ? AND status = ?", endpoint.ID, "skipped").First(&skipped).Error)
+	suite.Contains(skipped.Error, "circuit open")
+	suite.Require().NotNil(skipped.NextRetryAt)
+
+	health, err = service.GetWebhookHealth(suite.GetTestUserID(), endpoint.ID)
+	suite.Require().NoError(err)
+	suite.Equal(int64(1), health.SkippedDeliveries)
+
+	// And: It can't be replayed before the cool-down ends
+	_, err = service.ReplaySkippedDeliveries(context.Background(), suite.GetTestUserID(), endpoint.ID)
+	suite.ErrorIs(err, ErrWebhookCircuitOpen)
+
+	// When: The cool-down is over and the receiver has recovered
+	atomic.StoreInt32(&slow, 0)
+	suite.Require().NoError(suite.GetTestDB().Model(endpoint).Update("circuit_open_until", time.Now().Add(-time.Second)).Error)
+	replayed, err := service.ReplaySkippedDeliveries(context.Background(), suite.GetTestUserID(), endpoint.ID)
+
+	// Then: The skipped delivery is sent with its original payload and the breaker resets
+	suite.Require().NoError(err)
+	suite.Equal(1, replayed)
+	suite.Equal(int32(2), atomic.LoadInt32(&requests))
+
+	var replayedDelivery WebhookDelivery
+	suite.Require().NoError(suite.GetTestDB().First(&replayedDelivery, skipped.ID).Error)
+	suite.Equal("success", replayedDelivery.Status)
+	suite.Equal(float64(7), replayedDelivery.Payload["data"].(map[string]interface{})["id"])
+
+	reloaded, err := service.getWebhookEndpoint(suite.GetTestUserID(), endpoint.ID)
+	suite.Require().NoError(err)
+	suite.Equal(0, reloaded.ConsecutiveTimeouts)
+	suite.Nil(reloaded.CircuitOpenUntil)
+}
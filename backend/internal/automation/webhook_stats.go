@@ -0,0 +1,167 @@
+package automation
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DefaultWebhookStatsWindow is the window of delivery statistics when none
+// is given
+const DefaultWebhookStatsWindow = "24h"
+
+// webhookStatsWindows are the windows delivery statistics are computed over.
+// Statistics are kept per hour, so a window starts at the top of an hour
+var webhookStatsWindows = map[string]time.Duration{
+	"1h":  time.Hour,
+	"24h": 24 * time.Hour,
+	"7d":  7 * 24 * time.Hour,
+	"30d": 30 * 24 * time.Hour,
+}
+
+// latencyBuckets are the upper bounds in milliseconds of the delivery
+// latency histogram. Slower attempts fall in one more bucket past the last
+var latencyBuckets = []int64{50, 100, 250, 500, 1000, 2500, 5000, 10000, 30000}
+
+// WebhookDeliveryStats are an endpoint's delivery statistics over a window.
+// Latency percentiles are estimated from the histogram buckets
+type WebhookDeliveryStats struct {
+	EndpointID         uint                   `json:"endpoint_id"`
+	Window             string                 `json:"window"`
+	Since              time.Time              `json:"since"`
+	Deliveries         int64                  `json:"deliveries"` // first attempts
+	Attempts           int64                  `json:"attempts"`
+	SuccessfulAttempts int64                  `json:"successful_attempts"`
+	SuccessRate        float64                `json:"success_rate"` // of attempts
+	RetriesPerDelivery float64                `json:"retries_per_delivery"`
+	LatencyP50MS       int64                  `json:"latency_p50_ms"`
+	LatencyP95MS       int64                  `json:"latency_p95_ms"`
+	LastFailure        *WebhookFailureSummary `json:"last_failure,omitempty"`
+}
+
+// GetWebhookStats returns the delivery statistics of an endpoint over one
+// of the stats windows
+func (s *Service) GetWebhookStats(userID string, id uint, window string) (*WebhookDeliveryStats, error) {
+	if window == "" {
+		window = DefaultWebhookStatsWindow
+	}
+	length, ok := webhookStatsWindows[window]
+	if !ok {
+		return nil, ErrWebhookInvalidWindow
+	}
+	if _, err := s.getWebhookEndpoint(userID, id); err != nil {
+		return nil, err
+	}
+
+	since := time.Now().UTC().Truncate(time.Hour).Add(time.Hour - length)
+	var rows []WebhookDeliveryStat
+	if err := s.db.Where("endpoint_id = ? AND hour >= ?", id, since).Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to get delivery stats: %w", err)
+	}
+
+	stats := &WebhookDeliveryStats{EndpointID: id, Window: window, Since: since}
+	histogram := make([]int64, len(latencyBuckets)+1)
+	var retries int64
+	for _, row := range rows {
+		stats.Attempts += row.Attempts
+		retries += row.Retries
+		if row.Success {
+			stats.SuccessfulAttempts += row.Attempts
+		}
+		if row.Bucket >= 0 && row.Bucket < len(histogram) {
+			histogram[row.Bucket] += row.Attempts
+		}
+	}
+	stats.Deliveries = stats.Attempts - retries
+	if stats.Attempts > 0 {
+		stats.SuccessRate = float64(stats.SuccessfulAttempts) / float64(stats.Attempts)
+		stats.LatencyP50MS = latencyPercentile(histogram, 0.5)
+		stats.LatencyP95MS = latencyPercentile(histogram, 0.95)
+	}
+	if stats.Deliveries > 0 {
+		stats.RetriesPerDelivery = float64(retries) / float64(stats.Deliveries)
+	}
+
+	var failed WebhookDelivery
+	err := s.db.Where("endpoint_id = ? AND status = ? AND updated_at >= ?", id, "failed", since).
+		Order("updated_at DESC").First(&failed).Error
+	switch {
+	case err == nil:
+		stats.LastFailure = &WebhookFailureSummary{
+			DeliveryID: failed.ID,
+			Event:      failed.Event,
+			StatusCode: failed.StatusCode,
+			Error:      failed.Error,
+			At:         failed.UpdatedAt,
+		}
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, fmt.Errorf("failed to get last failed delivery: %w", err)
+	}
+	return stats, nil
+}
+
+// recordAttempt counts a delivery attempt in its endpoint's hourly stats.
+// Counters are incremented in SQL so concurrent deliveries do not lose
+// attempts; a failure to count does not fail the delivery
+func (s *Service) recordAttempt(delivery *WebhookDelivery, success bool, latency time.Duration) {
+	delivery.LatencyMS = latency.Milliseconds()
+	var retries int64
+	if delivery.AttemptCount > 1 {
+		retries = 1
+	}
+	stat := WebhookDeliveryStat{
+		EndpointID: delivery.EndpointID,
+		Hour:       time.Now().UTC().Truncate(time.Hour),
+		Success:    success,
+		Bucket:     latencyBucket(delivery.LatencyMS),
+		Attempts:   1,
+		Retries:    retries,
+	}
+	s.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "endpoint_id"}, {Name: "hour"}, {Name: "success"}, {Name: "bucket"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"attempts": gorm.Expr("webhook_delivery_stats.attempts + 1"),
+			"retries":  gorm.Expr("webhook_delivery_stats.retries + ?", retries),
+		}),
+	}).Create(&stat)
+}
+
+func latencyBucket(ms int64) int {
+	for i, bound := range latencyBuckets {
+		if ms <= bound {
+			return i
+		}
+	}
+	return len(latencyBuckets)
+}
+
+// latencyPercentile estimates a percentile of a latency histogram,
+// interpolating within the bucket it falls in. Attempts slower than the
+// last bound count as the last bound
+func latencyPercentile(histogram []int64, percentile float64) int64 {
+	var total int64
+	for _, count := range histogram {
+		total += count
+	}
+	rank := percentile * float64(total)
+	var seen int64
+	for i, count := range histogram {
+		if count == 0 || float64(seen+count) < rank {
+			seen += count
+			continue
+		}
+		if i == len(latencyBuckets) {
+			return latencyBuckets[len(latencyBuckets)-1]
+		}
+		var lower int64
+		if i > 0 {
+			lower = latencyBuckets[i-1]
+		}
+		upper := latencyBuckets[i]
+		return lower + int64(float64(upper-lower)*(rank-float64(seen))/float64(count))
+	}
+	return latencyBuckets[len(latencyBuckets)-1]
+}
@@ -0,0 +1,75 @@
+package automation
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *AutomationServiceTestSuite) TestWebhookStats() {
+	status := int32(http.StatusOK)
+	server := webhookReceiver(&status)
+	defer server.Close()
+
+	service := suite.GetTestService()
+	endpoint := suite.createHealthEndpoint(server.URL)
+
+	// Given: Three deliveries succeed and one fails, then succeeds on retry
+	for i := 0; i < 3; i++ {
+		suite.deliver(endpoint)
+	}
+	atomic.StoreInt32(&status, http.StatusServiceUnavailable)
+	payload := WebhookPayload{Event: WebhookEventBookmarkCreated, Timestamp: time.Now(), UserID: suite.GetTestUserID()}
+	delivery := &WebhookDelivery{EndpointID: endpoint.ID, Event: payload.Event, Status: "pending"}
+	suite.Require().NoError(suite.GetTestDB().Create(delivery).Error)
+	service.deliverWebhook(context.Background(), endpoint, delivery, payload)
+	atomic.StoreInt32(&status, http.StatusOK)
+	service.deliverWebhook(context.Background(), endpoint, delivery, payload)
+
+	// When: The owner reads the day's stats
+	stats, err := service.GetWebhookStats(suite.GetTestUserID(), endpoint.ID, "")
+	suite.Require().NoError(err)
+
+	// Then: Attempts, retries and latency come from the hourly counters
+	suite.Equal(DefaultWebhookStatsWindow, stats.Window)
+	suite.Equal(int64(4), stats.Deliveries)
+	suite.Equal(int64(5), stats.Attempts)
+	suite.Equal(int64(4), stats.SuccessfulAttempts)
+	suite.InDelta(0.8, stats.SuccessRate, 0.001)
+	suite.InDelta(0.25, stats.RetriesPerDelivery, 0.001)
+	suite.LessOrEqual(stats.LatencyP50MS, stats.LatencyP95MS)
+	suite.Nil(stats.LastFailure, "the failed delivery succeeded on retry")
+
+	var rows int64
+	suite.GetTestDB().Model(&WebhookDeliveryStat{}).Count(&rows)
+	suite.LessOrEqual(rows, int64(4), "attempts share rows per hour, outcome and latency bucket")
+
+	// And: A delivery that stays failed is the last failure
+	atomic.StoreInt32(&status, http.StatusBadGateway)
+	suite.deliver(endpoint)
+	stats, err = service.GetWebhookStats(suite.GetTestUserID(), endpoint.ID, "7d")
+	suite.Require().NoError(err)
+	suite.Require().NotNil(stats.LastFailure)
+	suite.Equal(http.StatusBadGateway, stats.LastFailure.StatusCode)
+
+	_, err = service.GetWebhookStats(suite.GetTestUserID(), endpoint.ID, "2w")
+	suite.ErrorIs(err, ErrWebhookInvalidWindow)
+	_, err = service.GetWebhookStats("someone-else", endpoint.ID, "")
+	suite.ErrorIs(err, ErrWebhookEndpointNotFound)
+}
+
+func TestLatencyPercentile(t *testing.T) {
+	histogram := make([]int64, len(latencyBuckets)+1)
+	histogram[latencyBucket(30)] = 50   // 0-50ms
+	histogram[latencyBucket(400)] = 45  // 250-500ms
+	histogram[latencyBucket(60000)] = 5 // past the last bound
+
+	assert.Equal(t, int64(50), latencyPercentile(histogram, 0.5))
+	assert.Equal(t, int64(500), latencyPercentile(histogram, 0.95))
+	assert.Equal(t, int64(30000), latencyPercentile(histogram, 0.99))
+	assert.Equal(t, int64(25), latencyPercentile(histogram, 0.25))
+}
@@ -0,0 +1,376 @@
+package automation
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+)
+
+// Limits on payload templates and what they render
+const (
+	maxPayloadTemplateSize = 16 << 10
+	maxRenderedPayloadSize = 256 << 10
+	// maxPayloadIterations bounds the loop iterations a render may run,
+	// counting nested loops over the longest list in the payload
+	maxPayloadIterations = 100000
+	// payloadRenderTimeout bounds a render whatever the template does
+	payloadRenderTimeout = time.Second
+)
+
+// ErrPayloadTooLarge is returned when a template renders more than the limit
+var ErrPayloadTooLarge = errors.New("rendered payload is too large")
+
+// payloadTemplateFuncs is the function set templates may use on top of the
+// text/template builtins. None of them reach outside the payload
+var payloadTemplateFuncs = template.FuncMap{
+	// json encodes a value, so strings come out quoted and escaped
+	"json": func(value interface{}) (string, error) {
+		data, err := json.Marshal(value)
+		return string(data), err
+	},
+	"upper":    strings.ToUpper,
+	"lower":    strings.ToLower,
+	"trim":     strings.TrimSpace,
+	"replace":  strings.ReplaceAll,
+	"contains": strings.Contains,
+	"join": func(separator string, values interface{}) string {
+		list, _ := values.([]interface{})
+		parts := make([]string, len(list))
+		for n, value := range list {
+			parts[n] = fmt.Sprint(value)
+		}
+		return strings.Join(parts, separator)
+	},
+	"truncate": func(length int, value string) string {
+		if runes := []rune(value); len(runes) > length {
+			return string(runes[:length])
+		}
+		return value
+	},
+	"default": func(fallback, value interface{}) interface{} {
+		if value == nil || value == "" {
+			return fallback
+		}
+		return value
+	},
+	// date reformats an RFC 3339 timestamp with a Go layout
+	"date": func(layout, value string) string {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return value
+		}
+		return t.Format(layout)
+	},
+}
+
+// parsePayloadTemplate checks that a payload template is usable
+func parsePayloadTemplate(text string) (*template.Template, error) {
+	if len(text) > maxPayloadTemplateSize {
+		return nil, fmt.Errorf("%w: payload template is larger than %d bytes", ErrWebhookInvalidTemplate, maxPayloadTemplateSize)
+	}
+
+	tmpl, err := template.New("payload").Funcs(payloadTemplateFuncs).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrWebhookInvalidTemplate, err)
+	}
+	// Templates calling templates can recurse without bound
+	if len(tmpl.Templates()) > 1 {
+		return nil, fmt.Errorf("%w: templates can't define other templates", ErrWebhookInvalidTemplate)
+	}
+	if err := checkPayloadNodes(tmpl.Tree.Root); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// checkPayloadNodes rejects what could run without bound: loops must range
+// over a field of the payload, never over a number or a variable holding one
+func checkPayloadNodes(node parse.Node) error {
+	switch node := node.(type) {
+	case *parse.ListNode:
+		if node == nil {
+			return nil
+		}
+		for _, child := range node.Nodes {
+			if err := checkPayloadNodes(child); err != nil {
+				return err
+			}
+		}
+	case *parse.IfNode:
+		return checkPayloadBranch(&node.BranchNode)
+	case *parse.WithNode:
+		return checkPayloadBranch(&node.BranchNode)
+	case *parse.RangeNode:
+		if !rangesOverField(node.Pipe) {
+			return fmt.Errorf("%w: range must loop over a payload field, e.g. {{range .data.tags}}", ErrWebhookInvalidTemplate)
+		}
+		return checkPayloadBranch(&node.BranchNode)
+	case *parse.TemplateNode:
+		return fmt.Errorf("%w: templates can't call other templates", ErrWebhookInvalidTemplate)
+	}
+	return nil
+}
+
+func checkPayloadBranch(branch *parse.BranchNode) error {
+	if err := checkPayloadNodes(branch.List); err != nil {
+		return err
+	}
+	return checkPayloadNodes(branch.ElseList)
+}
+
+// rangesOverField reports whether a range pipeline is a single field, such
+// as .data.tags or $item.tags
+func rangesOverField(pipe *parse.PipeNode) bool {
+	if len(pipe.Cmds) != 1 || len(pipe.Cmds[0].Args) != 1 {
+		return false
+	}
+	switch arg := pipe.Cmds[0].Args[0].(type) {
+	case *parse.FieldNode:
+		return true
+	case *parse.VariableNode:
+		return len(arg.Ident) > 1
+	}
+	return false
+}
+
+// payloadIterations is the most loop iterations a template can run when no
+// list in the payload is longer than longest
+func payloadIterations(node parse.Node, longest int) int {
+	total := 0
+	switch node := node.(type) {
+	case *parse.ListNode:
+		if node == nil {
+			return 0
+		}
+		for _, child := range node.Nodes {
+			total = saturatingAdd(total, payloadIterations(child, longest))
+		}
+	case *parse.IfNode:
+		total = branchIterations(&node.BranchNode, longest)
+	case *parse.WithNode:
+		total = branchIterations(&node.BranchNode, longest)
+	case *parse.RangeNode:
+		body := saturatingAdd(1, payloadIterations(node.List, longest))
+		total = saturatingAdd(saturatingMul(longest, body), payloadIterations(node.ElseList, longest))
+	}
+	return total
+}
+
+func branchIterations(branch *parse.BranchNode, longest int) int {
+	return saturatingAdd(payloadIterations(branch.List, longest), payloadIterations(branch.ElseList, longest))
+}
+
+func saturatingAdd(a, b int) int {
+	if a > maxPayloadIterations-b {
+		return maxPayloadIterations + 1
+	}
+	return a + b
+}
+
+func saturatingMul(a, b int) int {
+	if a != 0 && b > maxPayloadIterations/a {
+		return maxPayloadIterations + 1
+	}
+	return a * b
+}
+
+// longestList returns the length of the longest list or object in a value
+func longestList(value interface{}) int {
+	longest := 0
+	switch value := value.(type) {
+	case []interface{}:
+		longest = len(value)
+		for _, item := range value {
+			if n := longestList(item); n > longest {
+				longest = n
+			}
+		}
+	case map[string]interface{}:
+		longest = len(value)
+		for _, item := range value {
+			if n := longestList(item); n > longest {
+				longest = n
+			}
+		}
+	}
+	return longest
+}
+
+// limitedBuffer fails writes past its limit or its deadline, stopping
+// runaway templates
+type limitedBuffer struct {
+	bytes.Buffer
+	limit    int
+	deadline time.Time
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.limit {
+		return 0, ErrPayloadTooLarge
+	}
+	if time.Now().After(b.deadline) {
+		return 0, errPayloadRenderTimeout
+	}
+	return b.Buffer.Write(p)
+}
+
+// errPayloadRenderTimeout stops a render that ran past its deadline
+var errPayloadRenderTimeout = fmt.Errorf("%w: template took longer than %s to render", ErrWebhookInvalidTemplate, payloadRenderTimeout)
+
+// RenderPayload renders the body sent to an endpoint. Endpoints without a
+// template get the standard JSON payload. Templates see the payload as it
+// would be sent, e.g. {{.event}} and {{.data.url}}, and must produce JSON
+func RenderPayload(payloadTemplate string, payload WebhookPayload) ([]byte, error) {
+	if strings.TrimSpace(payloadTemplate) == "" {
+		return json.Marshal(payload)
+	}
+
+	tmpl, err := parsePayloadTemplate(payloadTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	// Render against the JSON form so field names match the standard payload
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(encoded, &data); err != nil {
+		return nil, err
+	}
+
+	if payloadIterations(tmpl.Tree.Root, longestList(data)) > maxPayloadIterations {
+		return nil, fmt.Errorf("%w: template loops more than %d times over this payload", ErrWebhookInvalidTemplate, maxPayloadIterations)
+	}
+
+	// The render runs apart so a slow template can't hold up the caller
+	out := &limitedBuffer{limit: maxRenderedPayloadSize, deadline: time.Now().Add(payloadRenderTimeout)}
+	done := make(chan error, 1)
+	go func() { done <- tmpl.Execute(out, data) }()
+
+	var execErr error
+	select {
+	case execErr = <-done:
+	case <-time.After(payloadRenderTimeout):
+		return nil, errPayloadRenderTimeout
+	}
+	if execErr != nil {
+		if errors.Is(execErr, ErrPayloadTooLarge) {
+			return nil, ErrPayloadTooLarge
+		}
+		if errors.Is(execErr, errPayloadRenderTimeout) {
+			return nil, errPayloadRenderTimeout
+		}
+		return nil, fmt.Errorf("%w: %v", ErrWebhookInvalidTemplate, execErr)
+	}
+
+	if !json.Valid(out.Bytes()) {
+		return nil, fmt.Errorf("%w: template did not render valid JSON", ErrWebhookInvalidTemplate)
+	}
+	return out.Bytes(), nil
+}
+
+// PreviewTemplateRequest renders a payload template against sample data
+type PreviewTemplateRequest struct {
+	PayloadTemplate string                 `json:"payload_template"`
+	Event           WebhookEvent           `json:"event" binding:"required"`
+	Data            map[string]interface{} `json:"data,omitempty"` // replaces the sample data
+}
+
+// PreviewPayload renders what an endpoint would receive for an event
+func (s *Service) PreviewPayload(userID string, req PreviewTemplateRequest) (json.RawMessage, error) {
+	if !knownEvent(req.Event) {
+		return nil, ErrWebhookInvalidEvent
+	}
+
+	payload := WebhookPayload{
+		Event:     req.Event,
+		Timestamp: time.Now().UTC(),
+		UserID:    userID,
+		Data:      sampleEventData(req.Event),
+	}
+	if req.Data != nil {
+		payload.Data = req.Data
+	}
+
+	body, err := RenderPayload(req.PayloadTemplate, payload)
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(body), nil
+}
+
+// knownEvent reports whether an event is in the catalog or is the test event
+func knownEvent(event WebhookEvent) bool {
+	if event == WebhookEventTest {
+		return true
+	}
+	for _, info := range WebhookEventCatalog {
+		if info.Event == event {
+			return true
+		}
+	}
+	return false
+}
+
+// sampleEventData returns representative data for previews
+func sampleEventData(event WebhookEvent) map[string]interface{} {
+	switch event {
+	case WebhookEventBookmarkCreated, WebhookEventBookmarkUpdated, WebhookEventBookmarkDeleted:
+		return map[string]interface{}{
+			"id":          42,
+			"url":         "https://example.com/article",
+			"title":       "An example article",
+			"description": "A page worth keeping",
+			"tags":        []string{"reading", "example"},
+		}
+	case WebhookEventCollectionCreated, WebhookEventCollectionUpdated, WebhookEventCollectionDeleted:
+		return map[string]interface{}{
+			"id":          7,
+			"name":        "Reading list",
+			"description": "Things to read later",
+			"visibility":  "private",
+		}
+	case WebhookEventUserRegistered, WebhookEventUserUpdated:
+		return map[string]interface{}{
+			"id":           1,
+			"username":     "reader",
+			"display_name": "Example Reader",
+		}
+	case WebhookEventExportCompleted:
+		return map[string]interface{}{
+			"operation_id": 12,
+			"total_items":  250,
+			"download_url": "https://bookmarks.example.com/api/v1/downloads/export/12",
+			"expires_at":   time.Now().UTC().Add(DefaultDownloadURLTTL).Format(time.RFC3339),
+		}
+	case WebhookEventBackupCompleted:
+		return map[string]interface{}{
+			"backup_id":    5,
+			"type":         "full",
+			"size":         1048576,
+			"checksum":     "sha256:0123456789abcdef",
+			"download_url": "https://bookmarks.example.com/api/v1/downloads/backup/5",
+			"expires_at":   time.Now().UTC().Add(DefaultDownloadURLTTL).Format(time.RFC3339),
+		}
+	case WebhookEventBackupFailed:
+		return map[string]interface{}{
+			"backup_id": 5,
+			"type":      "full",
+			"error":     "backup destination is unreachable",
+		}
+	case WebhookEventTest:
+		return map[string]interface{}{
+			"endpoint_id": 3,
+			"message":     "Test delivery sent before re-enabling this endpoint",
+		}
+	default:
+		return map[string]interface{}{}
+	}
+}
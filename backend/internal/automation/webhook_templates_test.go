@@ -0,0 +1,202 @@
+package automation
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func templatePayload() WebhookPayload {
+	return WebhookPayload{
+		Event:     WebhookEventBookmarkCreated,
+		Timestamp: time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC),
+		UserID:    "test-user-123",
+		Data: map[string]interface{}{
+			"url":   "https://example.com/a",
+			"title": `Say "hi"`,
+			"tags":  []string{"go", "web"},
+		},
+	}
+}
+
+func TestRenderPayload_NoTemplate(t *testing.T) {
+	body, err := RenderPayload("  ", templatePayload())
+	require.NoError(t, err)
+
+	expected, _ := json.Marshal(templatePayload())
+	assert.JSONEq(t, string(expected), string(body))
+}
+
+func TestRenderPayload_Template(t *testing.T) {
+	tmpl := `{"text": {{json (printf "%s: %s" (upper .event) .data.title)}}, "link": {{json .data.url}}, ` +
+		`"tags": {{json (join ", " .data.tags)}}, "day": {{json (date "2006-01-02" .timestamp)}}, ` +
+		`"note": {{json (default "none" .data.notes)}}, "short": {{json (truncate 4 .data.url)}}}`
+
+	body, err := RenderPayload(tmpl, templatePayload())
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"text": "BOOKMARK.CREATED: Say \"hi\"",
+		"link": "https://example.com/a",
+		"tags": "go, web",
+		"day": "2024-05-01",
+		"note": "none",
+		"short": "http"
+	}`, string(body))
+}
+
+func TestRenderPayload_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		err      error
+	}{
+		{"syntax", `{"a": {{.event}`, ErrWebhookInvalidTemplate},
+		{"unknown function", `{"a": {{exec "ls"}}}`, ErrWebhookInvalidTemplate},
+		{"not JSON", `text {{.event}}`, ErrWebhookInvalidTemplate},
+		{"too long", strings.Repeat("a", maxPayloadTemplateSize+1), ErrWebhookInvalidTemplate},
+		{"too large", `["{{printf "%0300000d" 0}}"]`, ErrPayloadTooLarge},
+		{"range over number", `{{range 1000000000000}}{{end}}{}`, ErrWebhookInvalidTemplate},
+		{"range over variable", `{{$n := 1000000000000}}{{range $n}}{{end}}{}`, ErrWebhookInvalidTemplate},
+		{"range over dot", `{{with 1000000000000}}{{range .}}{{end}}{{end}}{}`, ErrWebhookInvalidTemplate},
+		{"nested range", `{{with .data}}{{range .tags}}{{range $.data.tags}}{{end}}{{end}}{{end}}{}`, nil},
+		{"recursive template", `{{define "loop"}}{{template "loop" .}}{{end}}{{template "loop" .}}{}`, ErrWebhookInvalidTemplate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := RenderPayload(tt.template, templatePayload())
+			if tt.err == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestRenderPayload_LoopLimit(t *testing.T) {
+	payload := templatePayload()
+	tags := make([]interface{}, 100)
+	for n := range tags {
+		tags[n] = "tag"
+	}
+	payload.Data = map[string]interface{}{"tags": tags}
+
+	// 100 tags looped over three deep is a million iterations writing nothing
+	tmpl := `{{range .data.tags}}{{range $.data.tags}}{{range $.data.tags}}{{end}}{{end}}{{end}}{}`
+	_, err := RenderPayload(tmpl, payload)
+	assert.ErrorIs(t, err, ErrWebhookInvalidTemplate)
+
+	body, err := RenderPayload(`[{{range $n, $tag := .data.tags}}{{if $n}},{{end}}{{json $tag}}{{end}}]`, payload)
+	require.NoError(t, err)
+	assert.True(t, json.Valid(body))
+}
+
+func (suite *AutomationServiceTestSuite) TestCreateWebhookEndpoint_InvalidTemplate() {
+	// When: Creating an endpoint whose template does not parse
+	_, err := suite.GetTestService().CreateWebhookEndpoint(suite.GetTestUserID(), WebhookEndpointRequest{
+		Name:            "Slack",
+		URL:             "https://hooks.example.com/slack",
+		Events:          []string{"bookmark.created"},
+		PayloadTemplate: `{"text": {{.data.title}`,
+	})
+
+	// Then: It is rejected
+	suite.ErrorIs(err, ErrWebhookInvalidTemplate)
+}
+
+func (suite *AutomationServiceTestSuite) TestDeliverWebhook_UsesTemplate() {
+	// Given: A receiver recording the body and signature it gets
+	var body []byte
+	var signature string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get("X-Webhook-Signature")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	service := suite.GetTestService()
+	endpoint, err := service.CreateWebhookEndpoint(suite.GetTestUserID(), WebhookEndpointRequest{
+		Name:            "Slack",
+		URL:             server.URL,
+		Events:          []string{"bookmark.created"},
+		PayloadTemplate: `{"text": {{json (printf "New bookmark: %s" .data.title)}}}`,
+	})
+	suite.Require().NoError(err)
+
+	// When: A delivery is made
+	payload := templatePayload()
+	delivery := &WebhookDelivery{EndpointID: endpoint.ID, Event: payload.Event, Status: "pending"}
+	suite.Require().NoError(suite.GetTestDB().Create(delivery).Error)
+	service.deliverWebhook(context.Background(), endpoint, delivery, payload)
+
+	// Then: The receiver gets the rendered body, signed as sent
+	suite.Equal("success", delivery.Status)
+	suite.JSONEq(`{"text": "New bookmark: Say \"hi\""}`, string(body))
+	suite.Equal(service.generateSignature(body, endpoint.Secret), signature)
+}
+
+func (suite *AutomationServiceTestSuite) TestDeliverWebhook_TemplateFailure() {
+	// Given: An endpoint whose template renders something that isn't JSON
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	endpoint := suite.createHealthEndpoint(server.URL)
+	endpoint.PayloadTemplate = `{{.data.title}}`
+
+	// When: A delivery is made
+	suite.deliver(endpoint)
+
+	// Then: Nothing is sent and the delivery records why
+	var delivery WebhookDelivery
+	suite.Require().NoError(suite.GetTestDB().Where("endpoint_id = ?", endpoint.ID).First(&delivery).Error)
+	suite.False(called)
+	suite.Equal("failed", delivery.Status)
+	suite.Contains(delivery.Error, "Failed to render payload")
+}
+
+func (suite *AutomationHandlerTestSuite) TestPreviewWebhookPayload() {
+	// Given: A template for a Home Assistant style receiver
+	reqBody := PreviewTemplateRequest{
+		Event:           WebhookEventBookmarkCreated,
+		PayloadTemplate: `{"title": {{json .data.title}}, "tags": {{json .data.tags}}}`,
+	}
+
+	// When: Previewing it
+	w := suite.makeRequest("POST", "/api/v1/automation/webhooks/preview", reqBody)
+
+	// Then: The sample event is rendered through the template
+	suite.Equal(http.StatusOK, w.Code)
+	var response struct {
+		Event   string          `json:"event"`
+		Payload json.RawMessage `json:"payload"`
+	}
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &response))
+	suite.Equal("bookmark.created", response.Event)
+	suite.JSONEq(`{"title": "An example article", "tags": ["reading", "example"]}`, string(response.Payload))
+}
+
+func (suite *AutomationHandlerTestSuite) TestPreviewWebhookPayload_Invalid() {
+	// When: Previewing an unknown event or a broken template
+	unknown := suite.makeRequest("POST", "/api/v1/automation/webhooks/preview", PreviewTemplateRequest{Event: "bookmark.exploded"})
+	broken := suite.makeRequest("POST", "/api/v1/automation/webhooks/preview", PreviewTemplateRequest{
+		Event:           WebhookEventBookmarkCreated,
+		PayloadTemplate: `{{.data.title`,
+	})
+
+	// Then: Both are rejected
+	suite.Equal(http.StatusBadRequest, unknown.Code)
+	suite.Equal(http.StatusBadRequest, broken.Code)
+}
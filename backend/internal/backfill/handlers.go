@@ -0,0 +1,136 @@
+package backfill
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"bookmark-sync-service/backend/pkg/middleware"
+	"bookmark-sync-service/backend/pkg/utils"
+)
+
+// Handler serves media backfills to admins
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new media backfill handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterAdminRoutes registers the backfill routes on an admin-only group
+func (h *Handler) RegisterAdminRoutes(router *gin.RouterGroup) {
+	router.GET("/backfills/projection", h.Project)
+	router.GET("/backfills", h.List)
+	router.POST("/backfills", h.Start)
+	router.POST("/backfills/:id/cancel", h.Cancel)
+}
+
+// Project estimates the remaining work and storage cost of a backfill
+// @Summary Project a media backfill
+// @Description Counts the bookmarks lacking screenshots or archives and estimates the storage they need, its monthly cost and how many nightly windows capturing them takes. With a backfill open, counts from its checkpoint. Without parameters both media are projected
+// @Tags admin
+// @Produce json
+// @Param screenshots query bool false "Project screenshots"
+// @Param archives query bool false "Project archives"
+// @Success 200 {object} Projection
+// @Router /admin/backfills/projection [get]
+func (h *Handler) Project(c *gin.Context) {
+	req := Request{Screenshots: c.Query("screenshots") == "true", Archives: c.Query("archives") == "true"}
+	if !req.Screenshots && !req.Archives {
+		req = Request{Screenshots: true, Archives: true}
+	}
+
+	projection, err := h.service.Project(c.Request.Context(), req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, projection, "Backfill projected")
+}
+
+// List returns the latest backfills with their progress
+// @Summary List media backfills
+// @Tags admin
+// @Produce json
+// @Success 200 {array} database.MediaBackfill
+// @Router /admin/backfills [get]
+func (h *Handler) List(c *gin.Context) {
+	backfills, err := h.service.List(c.Request.Context())
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, backfills, "Backfills retrieved")
+}
+
+// Start queues a backfill; the worker runs it in the nightly window
+// @Summary Start a media backfill
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param request body Request true "Media to capture"
+// @Success 201 {object} database.MediaBackfill
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Router /admin/backfills [post]
+func (h *Handler) Start(c *gin.Context) {
+	var req Request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid request data", nil)
+		return
+	}
+
+	backfill, err := h.service.Start(c.Request.Context(), req, middleware.GetUserEmail(c))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, utils.APIResponse{
+		Success: true,
+		Message: "Backfill queued",
+		Data:    backfill,
+	})
+}
+
+// Cancel stops an open backfill
+// @Summary Cancel a media backfill
+// @Tags admin
+// @Produce json
+// @Param id path int true "Backfill ID"
+// @Success 200 {object} database.MediaBackfill
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Router /admin/backfills/{id}/cancel [post]
+func (h *Handler) Cancel(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_ID", "Invalid backfill ID", nil)
+		return
+	}
+
+	backfill, err := h.service.Cancel(c.Request.Context(), uint(id))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, backfill, "Backfill cancelled")
+}
+
+func (h *Handler) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrNothingToCapture):
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error(), nil)
+	case errors.Is(err, ErrNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, "NOT_FOUND", err.Error(), nil)
+	case errors.Is(err, ErrInProgress):
+		utils.ErrorResponse(c, http.StatusConflict, "BACKFILL_IN_PROGRESS", err.Error(), nil)
+	case errors.Is(err, ErrNotCancellable):
+		utils.ErrorResponse(c, http.StatusConflict, "NOT_CANCELLABLE", err.Error(), nil)
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to process backfill", nil)
+	}
+}
@@ -0,0 +1,440 @@
+// Package backfill captures screenshots and archives for existing bookmarks
+// that lack them. An admin projects the remaining work and its storage cost,
+// then starts a backfill; the worker runs it a batch at a time, only within
+// the nightly window and under global and per-domain rate limits, saving a
+// checkpoint after every batch
+package backfill
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/internal/monitoring"
+	"bookmark-sync-service/backend/internal/screenshot"
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+// openStatuses are the statuses of backfills not over yet
+var openStatuses = []string{database.BackfillStatusQueued, database.BackfillStatusRunning}
+
+// archivedSQL tells whether a bookmark has an archive snapshot
+const archivedSQL = "EXISTS (SELECT 1 FROM archive_snapshots WHERE archive_snapshots.bookmark_id = bookmarks.id AND archive_snapshots.deleted_at IS NULL)"
+
+// activeSQL leaves out deleted bookmarks and those whose link is broken
+const activeSQL = "deleted_at IS NULL AND (status IS NULL OR status <> 'broken')"
+
+var (
+	// ErrNothingToCapture is returned when starting a backfill of neither
+	// screenshots nor archives
+	ErrNothingToCapture = errors.New("a backfill captures screenshots, archives or both")
+	// ErrInProgress is returned when starting a backfill while one is open
+	ErrInProgress = errors.New("a backfill is already in progress")
+	// ErrNotFound is returned for backfills that do not exist
+	ErrNotFound = errors.New("backfill not found")
+	// ErrNotCancellable is returned when cancelling a backfill that is over
+	ErrNotCancellable = errors.New("only open backfills can be cancelled")
+)
+
+// Archiver archives the text of a bookmarked page, e.g. the monitoring service
+type Archiver interface {
+	CaptureSnapshot(ctx context.Context, userID, bookmarkID uint) (*monitoring.ArchiveCapture, error)
+}
+
+// RateCounter counts captures per minute across replicas, e.g. the Redis client
+type RateCounter interface {
+	IncrementWithExpiration(ctx context.Context, key string, expiration time.Duration) (int64, error)
+}
+
+// Request starts a backfill
+type Request struct {
+	Screenshots bool `json:"screenshots"`
+	Archives    bool `json:"archives"`
+}
+
+// Projection estimates the work a backfill has left and the storage it
+// will use, from the open backfill's checkpoint when there is one
+type Projection struct {
+	Screenshots     int64                   `json:"screenshots"` // bookmarks lacking a screenshot
+	Archives        int64                   `json:"archives"`    // bookmarks lacking an archive
+	ScreenshotBytes int64                   `json:"screenshot_bytes"`
+	ArchiveBytes    int64                   `json:"archive_bytes"` // average of the archives stored so far
+	StorageBytes    int64                   `json:"storage_bytes"`
+	MonthlyCost     float64                 `json:"monthly_cost"`     // of the storage, per month
+	CaptureMinutes  int64                   `json:"capture_minutes"`  // at the global limit, 0 when unlimited
+	WindowMinutes   int                     `json:"window_minutes"`   // per day
+	EstimatedNights int64                   `json:"estimated_nights"` // 0 when unlimited
+	WindowOpen      bool                    `json:"window_open"`
+	Backfill        *database.MediaBackfill `json:"backfill,omitempty"`
+}
+
+// candidate is a bookmark the backfill still has to capture
+type candidate struct {
+	ID         uint
+	UserID     uint
+	URL        string
+	Screenshot string
+	Archived   bool
+}
+
+// Service projects, starts and runs media backfills
+type Service struct {
+	cfg      config.BackfillConfig
+	db       *gorm.DB
+	capturer screenshot.Capturer
+	archiver Archiver
+	counter  RateCounter
+	location *time.Location
+	logger   *zap.Logger
+	now      func() time.Time
+}
+
+// NewService creates a media backfill service. A nil counter lifts the rate
+// limits; an unknown timezone falls back to UTC
+func NewService(cfg config.BackfillConfig, db *gorm.DB, capturer screenshot.Capturer, archiver Archiver, counter RateCounter, logger *zap.Logger) *Service {
+	location, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		logger.Warn("Invalid backfill timezone, using UTC", zap.String("timezone", cfg.Timezone), zap.Error(err))
+		location = time.UTC
+	}
+	return &Service{
+		cfg:      cfg,
+		db:       db,
+		capturer: capturer,
+		archiver: archiver,
+		counter:  counter,
+		location: location,
+		logger:   logger,
+		now:      time.Now,
+	}
+}
+
+// Project estimates what a backfill of the requested media has left to do.
+// With a backfill open, its media and checkpoint are used instead
+func (s *Service) Project(ctx context.Context, req Request) (*Projection, error) {
+	projection := &Projection{WindowMinutes: s.windowMinutes(), WindowOpen: s.inWindow(s.now())}
+
+	open, err := s.open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var cursor uint
+	if open != nil {
+		projection.Backfill = open
+		req = Request{Screenshots: open.Screenshots, Archives: open.Archives}
+		cursor = open.Cursor
+	}
+
+	base := func() *gorm.DB {
+		return s.db.WithContext(ctx).Table("bookmarks").Where(activeSQL).Where("id > ?", cursor)
+	}
+	if req.Screenshots {
+		if err := base().Where("(screenshot IS NULL OR screenshot = '')").Count(&projection.Screenshots).Error; err != nil {
+			return nil, fmt.Errorf("failed to count bookmarks without screenshots: %w", err)
+		}
+	}
+	if req.Archives {
+		if err := base().Where("NOT " + archivedSQL).Count(&projection.Archives).Error; err != nil {
+			return nil, fmt.Errorf("failed to count bookmarks without archives: %w", err)
+		}
+	}
+
+	projection.ScreenshotBytes = s.cfg.ScreenshotBytes
+	projection.ArchiveBytes = s.cfg.ArchiveBytes
+	var average *float64
+	if err := s.db.WithContext(ctx).Model(&database.ArchiveSnapshot{}).
+		Select("AVG(LENGTH(content))").Scan(&average).Error; err != nil {
+		return nil, fmt.Errorf("failed to measure archives: %w", err)
+	}
+	if average != nil && *average > 0 {
+		projection.ArchiveBytes = int64(*average)
+	}
+	projection.StorageBytes = projection.Screenshots*projection.ScreenshotBytes + projection.Archives*projection.ArchiveBytes
+	projection.MonthlyCost = math.Round(float64(projection.StorageBytes)/1e9*s.cfg.StorageCostPerGB*100) / 100
+
+	if s.cfg.PerMinute > 0 {
+		// Each bookmark counts once against the limit, whatever it lacks
+		bookmarks := projection.Screenshots
+		if projection.Archives > bookmarks {
+			bookmarks = projection.Archives
+		}
+		projection.CaptureMinutes = int64(math.Ceil(float64(bookmarks) / float64(s.cfg.PerMinute)))
+		projection.EstimatedNights = int64(math.Ceil(float64(projection.CaptureMinutes) / float64(projection.WindowMinutes)))
+	}
+	return projection, nil
+}
+
+// Start queues a backfill of the requested media. One runs at a time
+func (s *Service) Start(ctx context.Context, req Request, requestedBy string) (*database.MediaBackfill, error) {
+	if !req.Screenshots && !req.Archives {
+		return nil, ErrNothingToCapture
+	}
+	open, err := s.open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if open != nil {
+		return nil, ErrInProgress
+	}
+
+	backfill := &database.MediaBackfill{
+		RequestedBy: requestedBy,
+		Screenshots: req.Screenshots,
+		Archives:    req.Archives,
+		Status:      database.BackfillStatusQueued,
+	}
+	if err := s.candidates(ctx, req, 0).Count(&backfill.Total).Error; err != nil {
+		return nil, fmt.Errorf("failed to count bookmarks to backfill: %w", err)
+	}
+	if err := s.db.WithContext(ctx).Create(backfill).Error; err != nil {
+		return nil, fmt.Errorf("failed to create backfill: %w", err)
+	}
+	return backfill, nil
+}
+
+// List returns the latest backfills, newest first
+func (s *Service) List(ctx context.Context) ([]database.MediaBackfill, error) {
+	var backfills []database.MediaBackfill
+	if err := s.db.WithContext(ctx).Order("id DESC").Limit(20).Find(&backfills).Error; err != nil {
+		return nil, fmt.Errorf("failed to list backfills: %w", err)
+	}
+	return backfills, nil
+}
+
+// Cancel stops an open backfill after the batch in flight
+func (s *Service) Cancel(ctx context.Context, id uint) (*database.MediaBackfill, error) {
+	var backfill database.MediaBackfill
+	if err := s.db.WithContext(ctx).First(&backfill, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get backfill: %w", err)
+	}
+	if backfill.Status != database.BackfillStatusQueued && backfill.Status != database.BackfillStatusRunning {
+		return nil, ErrNotCancellable
+	}
+
+	now := s.now()
+	backfill.Status = database.BackfillStatusCancelled
+	backfill.CompletedAt = &now
+	if err := s.db.WithContext(ctx).Model(&backfill).
+		Updates(map[string]interface{}{"status": backfill.Status, "completed_at": &now}).Error; err != nil {
+		return nil, fmt.Errorf("failed to cancel backfill: %w", err)
+	}
+	return &backfill, nil
+}
+
+// RunBatch runs the next batch of the open backfill when the window is
+// open. It backs the worker's backfill job and returns how many bookmarks
+// were processed
+func (s *Service) RunBatch(ctx context.Context) (int, error) {
+	now := s.now()
+	if !s.inWindow(now) {
+		return 0, nil
+	}
+	backfill, err := s.open(ctx)
+	if err != nil || backfill == nil {
+		return 0, err
+	}
+	if backfill.Status == database.BackfillStatusQueued {
+		backfill.Status = database.BackfillStatusRunning
+		backfill.StartedAt = &now
+	}
+
+	batch := s.cfg.BatchSize
+	if batch <= 0 {
+		batch = 50
+	}
+	var candidates []candidate
+	if err := s.candidates(ctx, Request{Screenshots: backfill.Screenshots, Archives: backfill.Archives}, backfill.Cursor).
+		Select("id, user_id, url, COALESCE(screenshot, '') AS screenshot, " + archivedSQL + " AS archived").
+		Order("id").Limit(batch).Scan(&candidates).Error; err != nil {
+		return 0, fmt.Errorf("failed to find bookmarks to backfill: %w", err)
+	}
+	if len(candidates) == 0 {
+		backfill.Status = database.BackfillStatusCompleted
+		backfill.CompletedAt = &now
+		return 0, s.checkpoint(ctx, backfill)
+	}
+
+	processed := 0
+	deferred := false
+	throttled := map[string]bool{}
+	for i := range candidates {
+		if ctx.Err() != nil {
+			break
+		}
+		domain := domainOf(candidates[i].URL)
+		if throttled[domain] {
+			deferred = true
+			continue
+		}
+		allowed, full := s.allow(ctx, domain, s.now())
+		if full {
+			break // the global limit is used up until the next minute
+		}
+		if !allowed {
+			throttled[domain] = true
+			deferred = true
+			continue
+		}
+
+		s.capture(ctx, backfill, &candidates[i])
+		processed++
+		// The checkpoint stops before the first deferred bookmark, so the
+		// next batch comes back to it. Bookmarks after it that failed are
+		// tried once more then
+		if !deferred {
+			backfill.Cursor = candidates[i].ID
+		}
+	}
+	return processed, s.checkpoint(ctx, backfill)
+}
+
+// capture takes what the bookmark lacks. Failures are counted and the
+// bookmark passed over; the last error is kept for admins
+func (s *Service) capture(ctx context.Context, backfill *database.MediaBackfill, bookmark *candidate) {
+	backfill.Processed++
+	failed := false
+
+	if backfill.Screenshots && bookmark.Screenshot == "" && s.capturer != nil {
+		result, err := s.capturer.UpdateBookmarkScreenshot(ctx, strconv.FormatUint(uint64(bookmark.ID), 10), bookmark.URL)
+		if err == nil {
+			err = s.db.WithContext(ctx).Model(&database.Bookmark{}).Where("id = ?", bookmark.ID).
+				Update("screenshot", result.URL).Error
+		}
+		if err != nil {
+			failed = true
+			backfill.LastError = fmt.Sprintf("bookmark %d screenshot: %v", bookmark.ID, err)
+		} else {
+			backfill.ScreenshotsCaptured++
+			backfill.StoredBytes += result.Size
+		}
+	}
+
+	if backfill.Archives && !bookmark.Archived && s.archiver != nil {
+		result, err := s.archiver.CaptureSnapshot(ctx, bookmark.UserID, bookmark.ID)
+		if err != nil {
+			failed = true
+			backfill.LastError = fmt.Sprintf("bookmark %d archive: %v", bookmark.ID, err)
+		} else {
+			backfill.ArchivesCaptured++
+			backfill.StoredBytes += int64(len(result.Snapshot.Content))
+		}
+	}
+
+	if failed {
+		backfill.Failed++
+	}
+}
+
+// checkpoint saves a backfill's progress, unless it was cancelled meanwhile
+func (s *Service) checkpoint(ctx context.Context, backfill *database.MediaBackfill) error {
+	result := s.db.WithContext(ctx).Model(backfill).
+		Where("status IN ?", openStatuses).
+		Select("status", "cursor", "processed", "screenshots_captured", "archives_captured", "failed", "stored_bytes", "last_error", "started_at", "completed_at").
+		Updates(backfill)
+	if result.Error != nil {
+		return fmt.Errorf("failed to save backfill checkpoint: %w", result.Error)
+	}
+	if backfill.Status == database.BackfillStatusCompleted && result.RowsAffected > 0 {
+		s.logger.Info("Media backfill completed",
+			zap.Uint("backfill_id", backfill.ID),
+			zap.Int("screenshots", backfill.ScreenshotsCaptured),
+			zap.Int("archives", backfill.ArchivesCaptured),
+			zap.Int("failed", backfill.Failed))
+	}
+	return nil
+}
+
+// allow counts a capture against the global and the domain's limit for the
+// current minute. full reports the global limit was reached. Redis errors
+// let the capture through
+func (s *Service) allow(ctx context.Context, domain string, now time.Time) (allowed, full bool) {
+	if s.counter == nil {
+		return true, false
+	}
+	window := now.Truncate(time.Minute).Unix()
+
+	if s.cfg.PerMinute > 0 {
+		count, err := s.counter.IncrementWithExpiration(ctx, fmt.Sprintf("%s:all:%d", config.BackfillRatePrefix, window), time.Minute)
+		if err == nil && count > int64(s.cfg.PerMinute) {
+			return false, true
+		}
+	}
+	if s.cfg.DomainPerMinute > 0 {
+		count, err := s.counter.IncrementWithExpiration(ctx, fmt.Sprintf("%s:%s:%d", config.BackfillRatePrefix, domain, window), time.Minute)
+		if err == nil && count > int64(s.cfg.DomainPerMinute) {
+			return false, false
+		}
+	}
+	return true, false
+}
+
+// candidates selects the bookmarks after the cursor lacking any of the
+// requested media. Bookmarks whose link is broken are left out
+func (s *Service) candidates(ctx context.Context, req Request, cursor uint) *gorm.DB {
+	query := s.db.WithContext(ctx).Table("bookmarks").Where(activeSQL).Where("id > ?", cursor)
+
+	var lacking []string
+	if req.Screenshots {
+		lacking = append(lacking, "screenshot IS NULL OR screenshot = ''")
+	}
+	if req.Archives {
+		lacking = append(lacking, "NOT "+archivedSQL)
+	}
+	return query.Where("(" + strings.Join(lacking, ") OR (") + ")")
+}
+
+// open returns the backfill not over yet, if any
+func (s *Service) open(ctx context.Context) (*database.MediaBackfill, error) {
+	var backfill database.MediaBackfill
+	err := s.db.WithContext(ctx).Where("status IN ?", openStatuses).Order("id").First(&backfill).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get open backfill: %w", err)
+	}
+	return &backfill, nil
+}
+
+// inWindow reports whether backfills may run at t
+func (s *Service) inWindow(t time.Time) bool {
+	start, end := s.cfg.WindowStart, s.cfg.WindowEnd
+	if start == end {
+		return true
+	}
+	hour := t.In(s.location).Hour()
+	if start < end {
+		return hour >= start && hour < end
+	}
+	return hour >= start || hour < end
+}
+
+// windowMinutes is how long the window is open per day
+func (s *Service) windowMinutes() int {
+	hours := (s.cfg.WindowEnd - s.cfg.WindowStart + 24) % 24
+	if hours == 0 {
+		hours = 24
+	}
+	return hours * 60
+}
+
+// domainOf returns the lower-cased host of a URL without a leading www.
+func domainOf(pageURL string) string {
+	parsed, err := url.Parse(pageURL)
+	if err != nil || parsed.Hostname() == "" {
+		return pageURL
+	}
+	return strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+}
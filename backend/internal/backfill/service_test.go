@@ -0,0 +1,195 @@
+package backfill
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/internal/monitoring"
+	"bookmark-sync-service/backend/internal/screenshot"
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/testfactory"
+)
+
+// fakeCapturer stores a screenshot for every page but those of failing.example
+type fakeCapturer struct{ captured []string }
+
+func (f *fakeCapturer) UpdateBookmarkScreenshot(ctx context.Context, bookmarkID, pageURL string) (*screenshot.CaptureResult, error) {
+	if strings.Contains(pageURL, "failing.example") {
+		return nil, errors.New("page timed out")
+	}
+	f.captured = append(f.captured, pageURL)
+	return &screenshot.CaptureResult{URL: "https://cdn.example.com/screenshots/" + bookmarkID + ".jpg", Size: 1000}, nil
+}
+
+// fakeArchiver archives every page with a short text
+type fakeArchiver struct{ db *testfactory.Factory }
+
+func (f *fakeArchiver) CaptureSnapshot(ctx context.Context, userID, bookmarkID uint) (*monitoring.ArchiveCapture, error) {
+	snapshot := &monitoring.ArchiveSnapshot{UserID: userID, BookmarkID: bookmarkID, Version: 1, URL: "https://example.com", Content: "0123456789", ContentHash: "hash", CapturedAt: time.Now(), CheckedAt: time.Now()}
+	if err := f.db.DB().Create(snapshot).Error; err != nil {
+		return nil, err
+	}
+	return &monitoring.ArchiveCapture{Snapshot: snapshot, Changed: true}, nil
+}
+
+// fakeCounter counts in memory
+type fakeCounter struct{ counts map[string]int64 }
+
+func (f *fakeCounter) IncrementWithExpiration(ctx context.Context, key string, expiration time.Duration) (int64, error) {
+	f.counts[key]++
+	return f.counts[key], nil
+}
+
+// nightly is 02:30 UTC, within the default window
+var nightly = time.Date(2026, 10, 16, 2, 30, 0, 0, time.UTC)
+
+func setupService(t *testing.T, cfg config.BackfillConfig) (*Service, *testfactory.Factory, *fakeCapturer) {
+	db := testfactory.NewDB(t)
+	factory := testfactory.New(t, db)
+	capturer := &fakeCapturer{}
+	if cfg.WindowStart == 0 && cfg.WindowEnd == 0 {
+		cfg.WindowStart, cfg.WindowEnd = 1, 6
+	}
+	cfg.Timezone = "UTC"
+	cfg.BatchSize = 10
+	service := NewService(cfg, db, capturer, &fakeArchiver{db: factory}, &fakeCounter{counts: map[string]int64{}}, zap.NewNop())
+	service.now = func() time.Time { return nightly }
+	return service, factory, capturer
+}
+
+func TestProject(t *testing.T) {
+	ctx := context.Background()
+	service, factory, _ := setupService(t, config.BackfillConfig{PerMinute: 2, ScreenshotBytes: 1000, ArchiveBytes: 500, StorageCostPerGB: 2000000})
+	user := factory.User()
+	for i := 0; i < 3; i++ {
+		factory.Bookmark(user.ID)
+	}
+	factory.Bookmark(user.ID, func(b *database.Bookmark) { b.Screenshot = "https://cdn.example.com/a.jpg" })
+	factory.Bookmark(user.ID, func(b *database.Bookmark) { b.Status = "broken" })
+
+	projection, err := service.Project(ctx, Request{Screenshots: true, Archives: true})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), projection.Screenshots, "broken links and captured screenshots are left out")
+	assert.Equal(t, int64(4), projection.Archives)
+	assert.Equal(t, int64(3*1000+4*500), projection.StorageBytes)
+	assert.Equal(t, 10.0, projection.MonthlyCost)
+	assert.Equal(t, int64(2), projection.CaptureMinutes)
+	assert.Equal(t, 300, projection.WindowMinutes)
+	assert.Equal(t, int64(1), projection.EstimatedNights)
+	assert.True(t, projection.WindowOpen)
+
+	screenshotsOnly, err := service.Project(ctx, Request{Screenshots: true})
+	require.NoError(t, err)
+	assert.Zero(t, screenshotsOnly.Archives)
+}
+
+func TestStartAndCancel(t *testing.T) {
+	ctx := context.Background()
+	service, factory, _ := setupService(t, config.BackfillConfig{})
+	user := factory.User()
+	factory.Bookmark(user.ID)
+	factory.Bookmark(user.ID, func(b *database.Bookmark) { b.Screenshot = "https://cdn.example.com/a.jpg" })
+
+	_, err := service.Start(ctx, Request{}, "admin@example.com")
+	assert.ErrorIs(t, err, ErrNothingToCapture)
+
+	backfill, err := service.Start(ctx, Request{Screenshots: true}, "admin@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, database.BackfillStatusQueued, backfill.Status)
+	assert.Equal(t, int64(1), backfill.Total)
+	_, err = service.Start(ctx, Request{Archives: true}, "admin@example.com")
+	assert.ErrorIs(t, err, ErrInProgress)
+
+	cancelled, err := service.Cancel(ctx, backfill.ID)
+	require.NoError(t, err)
+	assert.Equal(t, database.BackfillStatusCancelled, cancelled.Status)
+	_, err = service.Cancel(ctx, backfill.ID)
+	assert.ErrorIs(t, err, ErrNotCancellable)
+	_, err = service.Cancel(ctx, 999)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestRunBatch(t *testing.T) {
+	ctx := context.Background()
+	service, factory, capturer := setupService(t, config.BackfillConfig{DomainPerMinute: 2})
+	db := factory.DB()
+	user := factory.User()
+
+	// Given: Three bookmarks of one site, one of another and one failing
+	var ids []uint
+	for i := 0; i < 3; i++ {
+		ids = append(ids, factory.Bookmark(user.ID, func(b *database.Bookmark) { b.URL = fmt.Sprintf("https://busy.example/%d", i) }).ID)
+	}
+	other := factory.Bookmark(user.ID, func(b *database.Bookmark) { b.URL = "https://quiet.example/" })
+	failing := factory.Bookmark(user.ID, func(b *database.Bookmark) { b.URL = "https://failing.example/" })
+	backfill, err := service.Start(ctx, Request{Screenshots: true, Archives: true}, "admin@example.com")
+	require.NoError(t, err)
+
+	// When: A batch runs outside the window
+	service.now = func() time.Time { return nightly.Add(12 * time.Hour) }
+	processed, err := service.RunBatch(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, processed, "backfills only run at night")
+
+	// When: A batch runs in the window
+	service.now = func() time.Time { return nightly }
+	processed, err = service.RunBatch(ctx)
+	require.NoError(t, err)
+
+	// Then: The third bookmark of the busy site waits for the next minute,
+	// and the checkpoint stops before it
+	assert.Equal(t, 4, processed)
+	require.NoError(t, db.First(backfill, backfill.ID).Error)
+	assert.Equal(t, database.BackfillStatusRunning, backfill.Status)
+	assert.Equal(t, ids[1], backfill.Cursor)
+	assert.Equal(t, 3, backfill.ScreenshotsCaptured)
+	assert.Equal(t, 4, backfill.ArchivesCaptured)
+	assert.Equal(t, 1, backfill.Failed)
+	assert.Contains(t, backfill.LastError, "page timed out")
+	assert.Equal(t, int64(3*1000+4*10), backfill.StoredBytes)
+	var saved database.Bookmark
+	require.NoError(t, db.First(&saved, other.ID).Error)
+	assert.NotEmpty(t, saved.Screenshot)
+
+	// When: The next minute's batch runs, then one more
+	service.now = func() time.Time { return nightly.Add(time.Minute) }
+	processed, err = service.RunBatch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, processed, "the deferred bookmark and the failed one after it")
+	processed, err = service.RunBatch(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, processed)
+
+	// Then: The backfill completed without capturing a page twice
+	require.NoError(t, db.First(backfill, backfill.ID).Error)
+	assert.Equal(t, database.BackfillStatusCompleted, backfill.Status)
+	assert.Equal(t, failing.ID, backfill.Cursor)
+	assert.Len(t, capturer.captured, 4)
+	assert.NotNil(t, backfill.CompletedAt)
+}
+
+func TestRunBatch_GlobalLimit(t *testing.T) {
+	ctx := context.Background()
+	service, factory, capturer := setupService(t, config.BackfillConfig{PerMinute: 2, WindowStart: 22, WindowEnd: 3})
+	user := factory.User()
+	for i := 0; i < 3; i++ {
+		factory.Bookmark(user.ID)
+	}
+	_, err := service.Start(ctx, Request{Screenshots: true}, "admin@example.com")
+	require.NoError(t, err)
+
+	// The window wraps past midnight
+	processed, err := service.RunBatch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, processed)
+	assert.Len(t, capturer.captured, 2)
+}
@@ -0,0 +1,75 @@
+package bookmark
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+// Permissions a user may be granted on someone else's bookmark
+const (
+	accessView = "view"
+	accessEdit = "edit"
+)
+
+// AccessChecker reports whether a user was granted a permission on a
+// bookmark, such as through a direct share
+type AccessChecker interface {
+	HasAccess(ctx context.Context, userID uint, resourceType string, resourceID uint, permission string) (bool, error)
+}
+
+// SetAccessChecker lets users read and edit bookmarks shared with them
+func (s *Service) SetAccessChecker(checker AccessChecker) {
+	s.access = checker
+}
+
+// GetAccessible retrieves a bookmark the user owns or may view
+func (s *Service) GetAccessible(bookmarkID, userID uint) (*database.Bookmark, error) {
+	ownerID, err := s.grantedOwner(bookmarkID, userID, accessView)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetByID(bookmarkID, ownerID)
+}
+
+// UpdateAccessible updates a bookmark the user owns or may edit. Edits to a
+// shared bookmark are made as its owner, with the owner's URL rules
+func (s *Service) UpdateAccessible(req UpdateBookmarkRequest) (*database.Bookmark, error) {
+	ownerID, err := s.grantedOwner(req.ID, req.UserID, accessEdit)
+	if err != nil {
+		return nil, err
+	}
+	req.UserID = ownerID
+	return s.Update(req)
+}
+
+// grantedOwner returns the owner of a bookmark the user owns or holds the
+// permission on. Bookmarks the user may not access are not found
+func (s *Service) grantedOwner(bookmarkID, userID uint, permission string) (uint, error) {
+	var bookmark database.Bookmark
+	if err := s.db.Select("id", "user_id").First(&bookmark, bookmarkID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, errors.New("bookmark not found")
+		}
+		return 0, fmt.Errorf("failed to get bookmark: %w", err)
+	}
+	if bookmark.UserID == userID {
+		return userID, nil
+	}
+	if s.access == nil {
+		return 0, errors.New("bookmark not found")
+	}
+
+	allowed, err := s.access.HasAccess(context.Background(), userID, "bookmark", bookmarkID, permission)
+	if err != nil {
+		return 0, fmt.Errorf("failed to check bookmark access: %w", err)
+	}
+	if !allowed {
+		return 0, errors.New("bookmark not found")
+	}
+	return bookmark.UserID, nil
+}
@@ -0,0 +1,48 @@
+package bookmark
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubAccessChecker grants each user permissions on every bookmark
+type stubAccessChecker map[uint]map[string]bool
+
+func (s stubAccessChecker) HasAccess(ctx context.Context, userID uint, resourceType string, resourceID uint, permission string) (bool, error) {
+	return resourceType == "bookmark" && s[userID][permission], nil
+}
+
+func TestBookmarkService_SharedAccess(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+
+	bookmark, err := service.Create(CreateBookmarkRequest{UserID: 1, URL: "https://example.com", Title: "Example"})
+	require.NoError(t, err)
+
+	// Without a checker only the owner reaches the bookmark
+	_, err = service.GetAccessible(bookmark.ID, 2)
+	assert.EqualError(t, err, "bookmark not found")
+
+	service.SetAccessChecker(stubAccessChecker{
+		2: {accessView: true},
+		3: {accessView: true, accessEdit: true},
+	})
+
+	viewed, err := service.GetAccessible(bookmark.ID, 2)
+	require.NoError(t, err)
+	assert.Equal(t, bookmark.ID, viewed.ID)
+
+	_, err = service.UpdateAccessible(UpdateBookmarkRequest{ID: bookmark.ID, UserID: 2, Title: "Viewer"})
+	assert.EqualError(t, err, "bookmark not found")
+
+	updated, err := service.UpdateAccessible(UpdateBookmarkRequest{ID: bookmark.ID, UserID: 3, Title: "Editor"})
+	require.NoError(t, err)
+	assert.Equal(t, "Editor", updated.Title)
+	assert.Equal(t, uint(1), updated.UserID)
+
+	_, err = service.GetAccessible(bookmark.ID, 4)
+	assert.EqualError(t, err, "bookmark not found")
+}
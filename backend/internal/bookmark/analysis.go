@@ -0,0 +1,31 @@
+package bookmark
+
+import (
+	"fmt"
+
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/language"
+)
+
+// SetDetectedLanguage stores the language content analysis detected in a
+// bookmark's page, replacing the guess made from its title when it was
+// saved. Codes that aren't ISO 639 languages leave the bookmark unchanged
+func (s *Service) SetDetectedLanguage(bookmarkID, userID uint, code string) (*database.Bookmark, error) {
+	bookmark, err := s.GetByID(bookmarkID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	code = language.Normalize(code)
+	if code == "" || code == bookmark.Language {
+		return bookmark, nil
+	}
+
+	if err := s.db.Model(bookmark).UpdateColumn("language", code).Error; err != nil {
+		return nil, fmt.Errorf("failed to save language: %w", err)
+	}
+	bookmark.Language = code
+
+	s.index(bookmark)
+	return bookmark, nil
+}
@@ -0,0 +1,34 @@
+package bookmark
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBookmarkService_SetDetectedLanguage(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+
+	bookmark, err := service.Create(CreateBookmarkRequest{UserID: 1, URL: "https://example.de", Title: "Example"})
+	require.NoError(t, err)
+
+	updated, err := service.SetDetectedLanguage(bookmark.ID, 1, "de-DE")
+	require.NoError(t, err)
+	assert.Equal(t, "de", updated.Language)
+
+	stored, err := service.GetByID(bookmark.ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "de", stored.Language)
+
+	// Codes that aren't languages keep the stored one
+	_, err = service.SetDetectedLanguage(bookmark.ID, 1, "unknown")
+	require.NoError(t, err)
+	stored, err = service.GetByID(bookmark.ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "de", stored.Language)
+
+	_, err = service.SetDetectedLanguage(bookmark.ID, 2, "fr")
+	assert.EqualError(t, err, "bookmark not found")
+}
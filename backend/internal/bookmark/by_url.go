@@ -0,0 +1,143 @@
+package bookmark
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/tags"
+	"bookmark-sync-service/backend/pkg/urlnorm"
+)
+
+// UpsertResult is the receipt of a save by URL
+type UpsertResult struct {
+	Bookmark *database.Bookmark `json:"bookmark"`
+	// Created is false when the save was merged into an existing bookmark
+	Created bool `json:"created"`
+	// NormalizedURL is the canonical URL the bookmark was matched by
+	NormalizedURL string `json:"normalized_url"`
+}
+
+// UpsertByURL saves a page by its URL: a new bookmark when the user has none
+// of the page, otherwise the tags are added to the existing bookmark and the
+// metadata sent replaces its own. URLs are matched in their canonical form
+// after the user's cleanup rules, and a save racing another one for the same
+// page merges into the bookmark the other one created
+func (s *Service) UpsertByURL(req CreateBookmarkRequest) (*UpsertResult, error) {
+	if req.URL == "" {
+		return nil, errors.New("URL is required")
+	}
+	if !isValidURL(req.URL) {
+		return nil, errors.New("invalid URL format")
+	}
+	req.URL = s.cleanURL(req.UserID, req.URL)
+	normalized, err := urlnorm.Canonical(req.URL)
+	if err != nil {
+		return nil, errors.New("invalid URL format")
+	}
+	sum := sha256.Sum256([]byte(normalized))
+	key := hex.EncodeToString(sum[:])
+
+	existing, err := s.byURLKey(req.UserID, key, normalized)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		if req.Title == "" {
+			req.Title = req.URL
+		}
+		req.urlKey = key
+		created, err := s.Create(req)
+		if err == nil {
+			return &UpsertResult{Bookmark: created, Created: true, NormalizedURL: normalized}, nil
+		}
+		// A concurrent save of the page claimed the key first
+		if existing, _ = s.byURLKey(req.UserID, key, normalized); existing == nil {
+			return nil, err
+		}
+	}
+
+	merged, err := s.Update(mergeRequest(existing, req))
+	if err != nil {
+		return nil, err
+	}
+	return &UpsertResult{Bookmark: merged, Created: false, NormalizedURL: normalized}, nil
+}
+
+// byURLKey finds the user's bookmark of a canonical URL. A bookmark saved
+// another way is claimed with the key, so later saves find it directly
+func (s *Service) byURLKey(userID uint, key, normalized string) (*database.Bookmark, error) {
+	var bookmark database.Bookmark
+	err := s.db.Where("user_id = ? AND url_key = ?", userID, key).First(&bookmark).Error
+	if err == nil {
+		return &bookmark, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to find bookmark by URL: %w", err)
+	}
+
+	// The key of a deleted bookmark is released so the page can be saved again
+	if err := s.db.Unscoped().Model(&database.Bookmark{}).
+		Where("user_id = ? AND url_key = ? AND deleted_at IS NOT NULL", userID, key).
+		Update("url_key", nil).Error; err != nil {
+		return nil, fmt.Errorf("failed to release URL key: %w", err)
+	}
+
+	u, _ := url.Parse(normalized)
+	var candidates []database.Bookmark
+	if err := s.db.Where("user_id = ? AND url_key IS NULL AND LOWER(url) LIKE ?", userID, "%"+u.Hostname()+"%").
+		Order("id").Find(&candidates).Error; err != nil {
+		return nil, fmt.Errorf("failed to find bookmark by URL: %w", err)
+	}
+	for i := range candidates {
+		if canonical, err := urlnorm.Canonical(candidates[i].URL); err != nil || canonical != normalized {
+			continue
+		}
+		claim := s.db.Model(&database.Bookmark{}).Where("id = ? AND url_key IS NULL", candidates[i].ID).Update("url_key", key)
+		if claim.Error == nil && claim.RowsAffected == 1 {
+			candidates[i].URLKey = &key
+			return &candidates[i], nil
+		}
+		// Another save claimed the key for a different bookmark meanwhile
+		if err := s.db.Where("user_id = ? AND url_key = ?", userID, key).First(&bookmark).Error; err == nil {
+			return &bookmark, nil
+		}
+	}
+	return nil, nil
+}
+
+// mergeRequest turns a save of a page the user already has into an update:
+// tags are added and the metadata sent replaces the stored one
+func mergeRequest(existing *database.Bookmark, req CreateBookmarkRequest) UpdateBookmarkRequest {
+	update := UpdateBookmarkRequest{
+		ID:          existing.ID,
+		UserID:      existing.UserID,
+		Description: req.Description,
+		Favicon:     req.Favicon,
+		Screenshot:  req.Screenshot,
+		Language:    req.Language,
+		ReadingTime: req.ReadingTime,
+	}
+	// Without a title the URL stood in for it, which must not replace a real one
+	if req.Title != req.URL {
+		update.Title = req.Title
+	}
+	if len(req.Tags) > 0 {
+		var current []string
+		_ = json.Unmarshal([]byte(existing.Tags), &current)
+		update.Tags = tags.NormalizeAll(append(current, req.Tags...))
+	}
+	if strings.TrimSpace(req.Notes) != "" {
+		update.Notes = &req.Notes
+		update.NotesEncrypted = req.NotesEncrypted
+		update.NotesKeyHint = req.NotesKeyHint
+	}
+	return update
+}
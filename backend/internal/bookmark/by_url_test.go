@@ -0,0 +1,95 @@
+package bookmark
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+func TestBookmarkService_UpsertByURL(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+
+	first, err := service.UpsertByURL(CreateBookmarkRequest{UserID: 1, URL: "https://example.com/post?b=2&a=1", Tags: []string{"go"}})
+	require.NoError(t, err)
+	assert.True(t, first.Created)
+	assert.Equal(t, "https://example.com/post?a=1&b=2", first.NormalizedURL)
+	assert.Equal(t, "https://example.com/post?b=2&a=1", first.Bookmark.Title, "the URL stands in for a missing title")
+
+	// Another device saves the same page under a different spelling
+	second, err := service.UpsertByURL(CreateBookmarkRequest{UserID: 1, URL: "http://www.example.com/post/?a=1&b=2#intro", Title: "Post", Tags: []string{"news", "go"}})
+	require.NoError(t, err)
+	assert.False(t, second.Created)
+	assert.Equal(t, first.Bookmark.ID, second.Bookmark.ID)
+	assert.Equal(t, "Post", second.Bookmark.Title)
+	assert.JSONEq(t, `["go","news"]`, second.Bookmark.Tags)
+	assert.Equal(t, "https://example.com/post?b=2&a=1", second.Bookmark.URL, "the stored URL is kept")
+
+	var count int64
+	db.Model(&database.Bookmark{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	t.Run("bookmarks saved another way are merged into", func(t *testing.T) {
+		legacy, err := service.Create(CreateBookmarkRequest{UserID: 1, URL: "https://go.dev/doc/", Title: "Docs"})
+		require.NoError(t, err)
+
+		result, err := service.UpsertByURL(CreateBookmarkRequest{UserID: 1, URL: "https://go.dev/doc", Tags: []string{"go"}})
+		require.NoError(t, err)
+		assert.False(t, result.Created)
+		assert.Equal(t, legacy.ID, result.Bookmark.ID)
+		assert.Equal(t, "Docs", result.Bookmark.Title)
+	})
+
+	t.Run("deleted bookmarks release their URL", func(t *testing.T) {
+		require.NoError(t, service.Delete(first.Bookmark.ID, 1))
+		again, err := service.UpsertByURL(CreateBookmarkRequest{UserID: 1, URL: "https://example.com/post?a=1&b=2", Title: "Again"})
+		require.NoError(t, err)
+		assert.True(t, again.Created)
+		assert.NotEqual(t, first.Bookmark.ID, again.Bookmark.ID)
+	})
+
+	t.Run("concurrent saves can't both create the page", func(t *testing.T) {
+		_, err := service.Create(CreateBookmarkRequest{UserID: 1, URL: "https://race.dev", Title: "Winner", urlKey: "race"})
+		require.NoError(t, err)
+		_, err = service.Create(CreateBookmarkRequest{UserID: 1, URL: "https://race.dev", Title: "Loser", urlKey: "race"})
+		assert.Error(t, err)
+	})
+
+	_, err = service.UpsertByURL(CreateBookmarkRequest{UserID: 1, URL: "not a url"})
+	assert.EqualError(t, err, "invalid URL format")
+}
+
+func TestUpsertBookmarkByURLHandler(t *testing.T) {
+	router, _ := setupTestRouter(t)
+	save := func(body CreateBookmarkRequest) (int, UpsertResult) {
+		raw, _ := json.Marshal(body)
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/bookmarks/by-url", bytes.NewReader(raw))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var response struct {
+			Data UpsertResult `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return w.Code, response.Data
+	}
+
+	code, created := save(CreateBookmarkRequest{URL: "https://example.com", Title: "Example"})
+	assert.Equal(t, http.StatusCreated, code)
+	assert.True(t, created.Created)
+
+	code, merged := save(CreateBookmarkRequest{URL: "https://example.com/", Tags: []string{"web"}})
+	assert.Equal(t, http.StatusOK, code)
+	assert.False(t, merged.Created)
+	assert.Equal(t, created.Bookmark.ID, merged.Bookmark.ID)
+
+	code, _ = save(CreateBookmarkRequest{URL: "example"})
+	assert.Equal(t, http.StatusBadRequest, code)
+}
@@ -1,11 +1,17 @@
 package bookmark
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 
+	"bookmark-sync-service/backend/internal/hooks"
+	"bookmark-sync-service/backend/internal/mobile"
+	"bookmark-sync-service/backend/pkg/clientid"
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/notes"
 	"bookmark-sync-service/backend/pkg/utils"
 )
 
@@ -27,9 +33,24 @@ func (h *Handlers) RegisterRoutes(router *gin.RouterGroup) {
 	{
 		bookmarks.POST("", h.CreateBookmark)
 		bookmarks.GET("", h.ListBookmarksHandler)
+		bookmarks.PUT("/by-url", h.UpsertBookmarkByURL)
+		bookmarks.GET("/sends", h.ListSends)
+		bookmarks.GET("/sends/:send_id", h.GetSend)
+		bookmarks.POST("/sends/:send_id/ack", h.AcknowledgeSend)
 		bookmarks.GET("/:id", h.GetBookmark)
 		bookmarks.PUT("/:id", h.UpdateBookmark)
 		bookmarks.DELETE("/:id", h.DeleteBookmark)
+		bookmarks.POST("/:id/summarize", h.SummarizeBookmark)
+		bookmarks.POST("/:id/suggest-collections", h.SuggestCollections)
+		bookmarks.POST("/:id/suggest-collections/feedback", h.SuggestionFeedback)
+		bookmarks.POST("/:id/send", h.SendBookmark)
+	}
+
+	tags := router.Group("/tags")
+	{
+		tags.GET("/tree", h.GetTagTree)
+		tags.POST("/rename", h.RenameTag)
+		tags.POST("/migrate", h.MigrateTags)
 	}
 }
 
@@ -43,17 +64,21 @@ func (h *Handlers) CreateBookmark(c *gin.Context) {
 	}
 
 	// Get user ID from context (set by auth middleware)
-	userID, exists := c.Get("user_id")
-	if !exists {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
 		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
 		return
 	}
 
-	req.UserID = userID.(uint)
+	req.UserID = userID
+	req.Source = requestSource(c)
 
 	bookmark, err := h.service.Create(req)
 	if err != nil {
-		if err.Error() == "URL and title are required" || err.Error() == "invalid URL format" {
+		if hookError(c, err) {
+			return
+		}
+		if err.Error() == "URL and title are required" || err.Error() == "invalid URL format" || err.Error() == "notes are too long" || err.Error() == "invalid encrypted notes" || errors.Is(err, clientid.ErrInvalid) {
 			utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error(), nil)
 			return
 		}
@@ -61,17 +86,96 @@ func (h *Handlers) CreateBookmark(c *gin.Context) {
 			utils.ErrorResponse(c, http.StatusNotFound, "NOT_FOUND", err.Error(), nil)
 			return
 		}
+		if err.Error() == "client_id belongs to a deleted bookmark" {
+			utils.ErrorResponse(c, http.StatusConflict, "CLIENT_ID_CONFLICT", err.Error(), nil)
+			return
+		}
 		utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to create bookmark", nil)
 		return
 	}
 
+	renderNotes(c, bookmark)
+
+	// Suggestions are a convenience; a failure must not fail the save
+	suggestions, err := h.service.SuggestCollections(req.UserID, bookmark.ID, 0)
+	if err != nil {
+		suggestions = []CollectionSuggestion{}
+	}
+
 	c.JSON(http.StatusCreated, utils.APIResponse{
 		Success: true,
 		Message: "Bookmark created successfully",
-		Data:    bookmark,
+		Data: createdBookmark{
+			Bookmark:             bookmark,
+			SuggestedCollections: suggestions,
+		},
 	})
 }
 
+// createdBookmark is a new bookmark with the collections it probably belongs in
+type createdBookmark struct {
+	*database.Bookmark
+	SuggestedCollections []CollectionSuggestion `json:"suggested_collections"`
+}
+
+// UpsertBookmarkByURL saves a page by URL, creating the bookmark or merging
+// into the one the user already has
+// @Summary Save a bookmark by URL
+// @Description Idempotent save keyed by the normalized URL. Creates the bookmark (201) or adds the tags and metadata to the existing one (200); created tells which
+// @Tags bookmarks
+// @Accept json
+// @Produce json
+// @Param request body CreateBookmarkRequest true "Bookmark"
+// @Success 200 {object} UpsertResult
+// @Success 201 {object} UpsertResult
+// @Failure 400 {object} utils.ErrorResponse
+// @Router /bookmarks/by-url [put]
+func (h *Handlers) UpsertBookmarkByURL(c *gin.Context) {
+	var req CreateBookmarkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid request format", nil)
+		return
+	}
+
+	// Get user ID from context (set by auth middleware)
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+	req.UserID = userID
+	req.Source = requestSource(c)
+
+	result, err := h.service.UpsertByURL(req)
+	if err != nil {
+		if hookError(c, err) {
+			return
+		}
+		if err.Error() == "URL is required" || err.Error() == "invalid URL format" || err.Error() == "notes are too long" || err.Error() == "invalid encrypted notes" || errors.Is(err, clientid.ErrInvalid) {
+			utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error(), nil)
+			return
+		}
+		if err.Error() == "user not found" {
+			utils.ErrorResponse(c, http.StatusNotFound, "NOT_FOUND", err.Error(), nil)
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to save bookmark", nil)
+		return
+	}
+
+	renderNotes(c, result.Bookmark)
+
+	if result.Created {
+		c.JSON(http.StatusCreated, utils.APIResponse{
+			Success: true,
+			Message: "Bookmark created successfully",
+			Data:    result,
+		})
+		return
+	}
+	utils.SuccessResponse(c, result, "Bookmark merged successfully")
+}
+
 // GetBookmark retrieves a bookmark by ID
 func (h *Handlers) GetBookmark(c *gin.Context) {
 	bookmarkIDStr := c.Param("id")
@@ -82,13 +186,13 @@ func (h *Handlers) GetBookmark(c *gin.Context) {
 	}
 
 	// Get user ID from context
-	userID, exists := c.Get("user_id")
-	if !exists {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
 		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
 		return
 	}
 
-	bookmark, err := h.service.GetByID(uint(bookmarkID), userID.(uint))
+	bookmark, err := h.service.GetAccessible(uint(bookmarkID), userID)
 	if err != nil {
 		if err.Error() == "bookmark not found" {
 			utils.ErrorResponse(c, http.StatusNotFound, "NOT_FOUND", err.Error(), nil)
@@ -98,7 +202,9 @@ func (h *Handlers) GetBookmark(c *gin.Context) {
 		return
 	}
 
-	utils.SuccessResponse(c, bookmark, "Bookmark retrieved successfully")
+	renderNotes(c, bookmark)
+
+	utils.SparseResponse(c, bookmark, "Bookmark retrieved successfully", "")
 }
 
 // UpdateBookmark updates an existing bookmark
@@ -117,22 +223,22 @@ func (h *Handlers) UpdateBookmark(c *gin.Context) {
 	}
 
 	// Get user ID from context
-	userID, exists := c.Get("user_id")
-	if !exists {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
 		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
 		return
 	}
 
 	req.ID = uint(bookmarkID)
-	req.UserID = userID.(uint)
+	req.UserID = userID
 
-	bookmark, err := h.service.Update(req)
+	bookmark, err := h.service.UpdateAccessible(req)
 	if err != nil {
 		if err.Error() == "bookmark not found" {
 			utils.ErrorResponse(c, http.StatusNotFound, "NOT_FOUND", err.Error(), nil)
 			return
 		}
-		if err.Error() == "invalid URL format" {
+		if err.Error() == "invalid URL format" || err.Error() == "notes are too long" || err.Error() == "invalid encrypted notes" {
 			utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error(), nil)
 			return
 		}
@@ -140,7 +246,9 @@ func (h *Handlers) UpdateBookmark(c *gin.Context) {
 		return
 	}
 
-	utils.SuccessResponse(c, bookmark, "Bookmark updated successfully")
+	renderNotes(c, bookmark)
+
+	utils.SparseResponse(c, bookmark, "Bookmark updated successfully", "")
 }
 
 // DeleteBookmark deletes a bookmark
@@ -153,13 +261,13 @@ func (h *Handlers) DeleteBookmark(c *gin.Context) {
 	}
 
 	// Get user ID from context
-	userID, exists := c.Get("user_id")
-	if !exists {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
 		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
 		return
 	}
 
-	err = h.service.Delete(uint(bookmarkID), userID.(uint))
+	err = h.service.Delete(uint(bookmarkID), userID)
 	if err != nil {
 		if err.Error() == "bookmark not found" {
 			utils.ErrorResponse(c, http.StatusNotFound, "NOT_FOUND", err.Error(), nil)
@@ -175,18 +283,20 @@ func (h *Handlers) DeleteBookmark(c *gin.Context) {
 // ListBookmarksHandler lists bookmarks with filtering and pagination
 func (h *Handlers) ListBookmarksHandler(c *gin.Context) {
 	// Get user ID from context
-	userID, exists := c.Get("user_id")
-	if !exists {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
 		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
 		return
 	}
 
 	// Parse query parameters
 	req := ListBookmarksRequest{
-		UserID: userID.(uint),
+		UserID: userID,
 		Search: c.Query("search"),
 		Tags:   c.Query("tags"),
 		Status: c.Query("status"),
+		Lang:   c.Query("lang"),
+		Source: c.Query("source"),
 	}
 
 	// Parse collection ID
@@ -237,6 +347,111 @@ func (h *Handlers) ListBookmarksHandler(c *gin.Context) {
 		"limit":     req.Limit,
 		"offset":    req.Offset,
 	}
+	if mobile.Requested(c) {
+		response["bookmarks"] = mobile.FromBookmarks(bookmarks)
+	} else {
+		renderNotes(c, bookmarks...)
+	}
+
+	utils.SparseResponse(c, response, "Bookmarks retrieved successfully", "bookmarks")
+}
+
+// hookError responds to a save a hook rejected or could not check, and
+// reports whether it did
+func hookError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, hooks.ErrRejected):
+		utils.ErrorResponse(c, http.StatusUnprocessableEntity, "HOOK_REJECTED", err.Error(), nil)
+	case errors.Is(err, hooks.ErrFailed):
+		utils.ErrorResponse(c, http.StatusServiceUnavailable, "HOOK_UNAVAILABLE", "Bookmark could not be checked, try again later", nil)
+	default:
+		return false
+	}
+	return true
+}
+
+// renderNotes fills in the sanitized HTML of the bookmarks' Markdown notes
+// when the client asks for it with render=html. Encrypted notes are left for
+// the client to decrypt and render
+func renderNotes(c *gin.Context, bookmarks ...*database.Bookmark) {
+	if c.Query("render") != "html" {
+		return
+	}
+	for _, bookmark := range bookmarks {
+		if bookmark.NotesEncrypted {
+			continue
+		}
+		bookmark.NotesHTML = notes.Render(bookmark.Notes)
+	}
+}
+
+// GetTagTree returns the user's tags as a namespace tree
+func (h *Handlers) GetTagTree(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	tree, err := h.service.GetTagTree(userID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to get tags", nil)
+		return
+	}
+
+	utils.SuccessResponse(c, tree, "Tags retrieved successfully")
+}
+
+// RenameTag renames a tag and all of its descendants
+func (h *Handlers) RenameTag(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	var req RenameTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid request format", nil)
+		return
+	}
+
+	result, err := h.service.RenameTag(userID, req)
+	if err != nil {
+		if err.Error() == "tag names are required" || err.Error() == "cannot move a tag into its own descendant" {
+			utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error(), nil)
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to rename tag", nil)
+		return
+	}
+
+	utils.SuccessResponse(c, result, "Tag renamed successfully")
+}
+
+// MigrateTags converts flat tags using a legacy separator into namespaced tags
+func (h *Handlers) MigrateTags(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	var req MigrateTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid request format", nil)
+		return
+	}
+
+	result, err := h.service.MigrateTags(userID, req)
+	if err != nil {
+		if err.Error() == "separator is required" {
+			utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error(), nil)
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to migrate tags", nil)
+		return
+	}
 
-	utils.SuccessResponse(c, response, "Bookmarks retrieved successfully")
+	utils.SuccessResponse(c, result, "Tags migrated successfully")
 }
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/gin-gonic/gin"
@@ -44,7 +45,7 @@ func setupTestRouter(t *testing.T) (*gin.Engine, *gorm.DB) {
 
 	// Mock auth middleware that sets user ID
 	router.Use(func(c *gin.Context) {
-		c.Set("user_id", uint(1))
+		c.Set("user_id", "1")
 		c.Next()
 	})
 
@@ -270,3 +271,76 @@ func TestListBookmarks(t *testing.T) {
 		})
 	}
 }
+
+func TestListBookmarks_MobileProfile(t *testing.T) {
+	router, db := setupTestRouter(t)
+	bookmark := &database.Bookmark{
+		UserID:      1,
+		URL:         "https://example.com",
+		Title:       "Example",
+		Description: strings.Repeat("long description ", 40),
+		Screenshot:  "https://cdn.example.com/screenshots/1.jpg",
+		Notes:       "private notes",
+		Metadata:    `{"og":"large"}`,
+		Tags:        `["dev/go"]`,
+		Status:      "active",
+	}
+	require.NoError(t, db.Create(bookmark).Error)
+
+	for _, mobileRequest := range []func(*http.Request){
+		func(r *http.Request) { r.URL.RawQuery = "profile=mobile" },
+		func(r *http.Request) { r.Header.Set("X-API-Profile", "mobile") },
+	} {
+		req := httptest.NewRequest("GET", "/api/v1/bookmarks", nil)
+		mobileRequest(req)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var response struct {
+			Data struct {
+				Bookmarks []map[string]interface{} `json:"bookmarks"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		require.Len(t, response.Data.Bookmarks, 1)
+		got := response.Data.Bookmarks[0]
+		assert.Equal(t, "https://cdn.example.com/screenshots/1_thumb.jpg", got["thumbnail_url"])
+		assert.Equal(t, []interface{}{"dev/go"}, got["tags"])
+		assert.Equal(t, true, got["has_notes"])
+		assert.LessOrEqual(t, len([]rune(got["description"].(string))), 200)
+		for _, field := range []string{"user", "screenshot", "notes", "metadata", "collections"} {
+			assert.NotContains(t, got, field)
+		}
+	}
+}
+
+func TestListBookmarks_Fields(t *testing.T) {
+	router, db := setupTestRouter(t)
+	require.NoError(t, db.Create(&database.Bookmark{UserID: 1, URL: "https://example.com", Title: "Example", Notes: "private notes"}).Error)
+
+	req := httptest.NewRequest("GET", "/api/v1/bookmarks?fields=url,title,user.username", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Data struct {
+			Bookmarks []map[string]interface{} `json:"bookmarks"`
+			Total     int                      `json:"total"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, 1, response.Data.Total, "the envelope is kept")
+	require.Len(t, response.Data.Bookmarks, 1)
+	assert.Equal(t, map[string]interface{}{
+		"url":   "https://example.com",
+		"title": "Example",
+		"user":  map[string]interface{}{"username": "testuser"},
+	}, response.Data.Bookmarks[0])
+
+	req = httptest.NewRequest("GET", "/api/v1/bookmarks?fields=url,user..username", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
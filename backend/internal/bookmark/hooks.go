@@ -0,0 +1,35 @@
+package bookmark
+
+import (
+	"context"
+
+	"bookmark-sync-service/backend/internal/hooks"
+)
+
+// Hooks calls the configured hooks of a hook point, which may reject the
+// operation or change its request
+type Hooks interface {
+	Run(ctx context.Context, point hooks.Point, userID uint, payload interface{}) error
+}
+
+// SetHooks makes the service call the before_bookmark_create hooks
+func (s *Service) SetHooks(h Hooks) {
+	s.hooks = h
+}
+
+// runCreateHooks lets the hooks check and enrich a bookmark before it is
+// validated, so what they change is validated too. A hook moving the
+// bookmark to another URL drops the URL claimed by UpsertByURL
+func (s *Service) runCreateHooks(req *CreateBookmarkRequest) error {
+	if s.hooks == nil {
+		return nil
+	}
+	url := req.URL
+	if err := s.hooks.Run(context.Background(), hooks.BeforeBookmarkCreate, req.UserID, req); err != nil {
+		return err
+	}
+	if req.URL != url {
+		req.urlKey = ""
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package bookmark
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bookmark-sync-service/backend/internal/hooks"
+)
+
+// stubHooks rejects bookmarks of blocked.example and moves the others to
+// their HTTPS URL
+type stubHooks struct{}
+
+func (stubHooks) Run(_ context.Context, point hooks.Point, _ uint, payload interface{}) error {
+	req := payload.(*CreateBookmarkRequest)
+	if point != hooks.BeforeBookmarkCreate {
+		return fmt.Errorf("unexpected point %s", point)
+	}
+	if req.URL == "https://blocked.example" {
+		return fmt.Errorf("%w: domain is blocked", hooks.ErrRejected)
+	}
+	req.URL = "https://secure.example"
+	return nil
+}
+
+func TestBookmarkService_Hooks(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+	service.SetHooks(stubHooks{})
+
+	_, err := service.Create(CreateBookmarkRequest{UserID: 1, URL: "https://blocked.example", Title: "Blocked"})
+	assert.ErrorIs(t, err, hooks.ErrRejected)
+
+	result, err := service.UpsertByURL(CreateBookmarkRequest{UserID: 1, URL: "http://secure.example", Title: "Secure"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://secure.example", result.Bookmark.URL)
+	assert.Nil(t, result.Bookmark.URLKey, "the URL claimed before the hook ran is dropped")
+}
@@ -0,0 +1,34 @@
+package bookmark
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"bookmark-sync-service/backend/internal/automation"
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+// ImportRow saves a row of an automation import operation, with the same
+// validation as bookmarks created through the API. Rows without a title
+// take their URL. The operation is the bookmark's import batch
+func (s *Service) ImportRow(ctx context.Context, userID string, operationID uint, row automation.ImportRow) error {
+	id, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid user ID %q: %w", userID, err)
+	}
+	title := row.Title
+	if title == "" {
+		title = row.URL
+	}
+	_, err = s.Create(CreateBookmarkRequest{
+		UserID:      uint(id),
+		URL:         row.URL,
+		Title:       title,
+		Description: row.Description,
+		Tags:        row.Tags,
+		Source:      database.BookmarkSourceImport,
+		SourceRef:   fmt.Sprintf("bulk-%d", operationID),
+	})
+	return err
+}
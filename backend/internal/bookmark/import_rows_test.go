@@ -0,0 +1,28 @@
+package bookmark
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bookmark-sync-service/backend/internal/automation"
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+func TestBookmarkService_ImportRow(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+	ctx := context.Background()
+
+	require.NoError(t, service.ImportRow(ctx, "1", 42, automation.ImportRow{Row: 1, URL: "https://go.dev", Tags: []string{"go"}}))
+	assert.Error(t, service.ImportRow(ctx, "1", 42, automation.ImportRow{Row: 2, URL: "not a url"}))
+	assert.Error(t, service.ImportRow(ctx, "anyone", 42, automation.ImportRow{Row: 3, URL: "https://go.dev"}))
+
+	var bookmark database.Bookmark
+	require.NoError(t, db.First(&bookmark).Error)
+	assert.Equal(t, "https://go.dev", bookmark.Title, "rows without a title take their URL")
+	assert.Equal(t, database.BookmarkSourceImport, bookmark.Source)
+	assert.Equal(t, "bulk-42", bookmark.SourceRef)
+}
@@ -0,0 +1,49 @@
+package bookmark
+
+import "bookmark-sync-service/backend/pkg/database"
+
+// SearchIndexer queues bookmark changes for batched search indexing
+type SearchIndexer interface {
+	QueueBookmark(bookmark *database.Bookmark)
+	QueueBookmarkDelete(id uint)
+}
+
+// SetIndexer makes the service queue its changes for search indexing
+func (s *Service) SetIndexer(indexer SearchIndexer) {
+	s.indexer = indexer
+}
+
+// index queues a created or updated bookmark. It is marked pending first,
+// so the indexer's report of it always lands last
+func (s *Service) index(bookmark *database.Bookmark) {
+	if s.indexer == nil {
+		return
+	}
+
+	s.markPending([]uint{bookmark.ID})
+	bookmark.IndexStatus = database.IndexStatusPending
+	s.indexer.QueueBookmark(bookmark)
+}
+
+// reindex queues bookmarks changed in bulk, loading them after the change
+// has been committed
+func (s *Service) reindex(ids []uint) {
+	if s.indexer == nil || len(ids) == 0 {
+		return
+	}
+
+	s.markPending(ids)
+	var bookmarks []database.Bookmark
+	if err := s.db.Where("id IN ?", ids).Find(&bookmarks).Error; err != nil {
+		return
+	}
+	for n := range bookmarks {
+		s.indexer.QueueBookmark(&bookmarks[n])
+	}
+}
+
+// markPending records that bookmarks are not searchable until indexed.
+// The column is updated on its own so updated_at is left alone
+func (s *Service) markPending(ids []uint) {
+	s.db.Model(&database.Bookmark{}).Where("id IN ?", ids).UpdateColumn("index_status", database.IndexStatusPending)
+}
@@ -0,0 +1,35 @@
+package bookmark
+
+import (
+	"strconv"
+
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/websocket"
+)
+
+// LiveQueries pushes created and updated bookmarks to WebSocket clients
+// subscribed to a search or filter they match
+type LiveQueries interface {
+	PublishBookmark(userID, action string, item websocket.LiveItem) int
+}
+
+// SetLiveQueries enables pushing saved bookmarks to live query subscribers
+func (s *Service) SetLiveQueries(live LiveQueries) {
+	s.liveQueries = live
+}
+
+// publishLive offers a created or updated bookmark to the owner's live
+// queries. Matching is done on the hub against each connection's queries
+func (s *Service) publishLive(action string, bookmark *database.Bookmark) {
+	if s.liveQueries == nil {
+		return
+	}
+	s.liveQueries.PublishBookmark(strconv.FormatUint(uint64(bookmark.UserID), 10), action, websocket.LiveItem{
+		ID:          strconv.FormatUint(uint64(bookmark.ID), 10),
+		URL:         bookmark.URL,
+		Title:       bookmark.Title,
+		Description: bookmark.Description,
+		Tags:        decodeTags(bookmark.Tags),
+		Data:        bookmark,
+	})
+}
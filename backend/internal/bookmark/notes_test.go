@@ -0,0 +1,100 @@
+package bookmark
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bookmark-sync-service/backend/pkg/notes"
+)
+
+func TestBookmarkService_Notes(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+
+	bookmark, err := service.Create(CreateBookmarkRequest{
+		UserID: 1,
+		URL:    "https://go.dev/blog",
+		Title:  "Go blog",
+		Notes:  "See the post on **worker pools**",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "See the post on **worker pools**", bookmark.Notes)
+
+	_, err = service.Create(CreateBookmarkRequest{
+		UserID: 1,
+		URL:    "https://example.com",
+		Title:  "Go example",
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		search string
+		count  int64
+	}{
+		{name: "plain search includes notes", search: "worker", count: 1},
+		{name: "note keyword", search: "note:pools", count: 1},
+		{name: "note phrase with text", search: `go note:"worker pools"`, count: 1},
+		{name: "note keyword does not match title", search: "note:example", count: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, total, err := service.List(ListBookmarksRequest{UserID: 1, Search: tt.search})
+			require.NoError(t, err)
+			assert.Equal(t, tt.count, total)
+		})
+	}
+
+	cleared := ""
+	updated, err := service.Update(UpdateBookmarkRequest{ID: bookmark.ID, UserID: 1, Notes: &cleared})
+	require.NoError(t, err)
+	assert.Empty(t, updated.Notes)
+
+	tooLong := strings.Repeat("a", notes.MaxLength+1)
+	_, err = service.Update(UpdateBookmarkRequest{ID: bookmark.ID, UserID: 1, Notes: &tooLong})
+	assert.EqualError(t, err, "notes are too long")
+}
+
+func TestBookmarkService_EncryptedNotes(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+
+	// "d29ya2VyIHBvb2xz" is base64 for "worker pools"
+	bookmark, err := service.Create(CreateBookmarkRequest{
+		UserID:         1,
+		URL:            "https://go.dev/blog",
+		Title:          "Go blog",
+		Notes:          "d29ya2VyIHBvb2xz",
+		NotesEncrypted: true,
+		NotesKeyHint:   "vault-v1",
+	})
+	require.NoError(t, err)
+	assert.True(t, bookmark.NotesEncrypted)
+	assert.Equal(t, "vault-v1", bookmark.NotesKeyHint)
+
+	// Ciphertext never matches searches
+	_, total, err := service.List(ListBookmarksRequest{UserID: 1, Search: "note:d29ya2Vy"})
+	require.NoError(t, err)
+	assert.Zero(t, total)
+	_, total, err = service.List(ListBookmarksRequest{UserID: 1, Search: "d29ya2Vy"})
+	require.NoError(t, err)
+	assert.Zero(t, total)
+
+	_, err = service.Create(CreateBookmarkRequest{
+		UserID: 1, URL: "https://example.com", Title: "Example",
+		Notes: "plain text", NotesEncrypted: true, NotesKeyHint: "vault-v1",
+	})
+	assert.EqualError(t, err, "invalid encrypted notes")
+
+	// Notes sent without the flag replace the ciphertext with plain Markdown
+	plain := "now **public**"
+	updated, err := service.Update(UpdateBookmarkRequest{ID: bookmark.ID, UserID: 1, Notes: &plain})
+	require.NoError(t, err)
+	assert.False(t, updated.NotesEncrypted)
+	assert.Empty(t, updated.NotesKeyHint)
+	assert.Equal(t, plain, updated.Notes)
+}
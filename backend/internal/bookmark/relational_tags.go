@@ -0,0 +1,141 @@
+package bookmark
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/dualwrite"
+)
+
+// TagMigrationName names the move from the bookmarks' JSON tags column to
+// the tags and bookmark_tags tables
+const TagMigrationName = "relational_tags"
+
+// SetTagMigration sets the rollout stage of relational tags. Without it the
+// service only reads and writes the JSON tags column
+func (s *Service) SetTagMigration(migration *dualwrite.Migration) {
+	s.tagMigration = migration
+}
+
+// writeTags saves a bookmark's tags through the tag migration: writeOld
+// saves the JSON column, creating the bookmark if needed, and the relational
+// rows follow once dual writes are on
+func (s *Service) writeTags(bookmark *database.Bookmark, list []string, writeOld func() error) error {
+	return s.tagMigration.Write(context.Background(),
+		func(context.Context) error { return writeOld() },
+		func(ctx context.Context) error {
+			return writeRelationalTags(s.db.WithContext(ctx), bookmark.UserID, bookmark.ID, list)
+		})
+}
+
+// writeRelationalTags replaces a bookmark's rows in bookmark_tags, creating
+// the user's tags that do not exist yet
+func writeRelationalTags(db *gorm.DB, userID, bookmarkID uint, list []string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("bookmark_id = ?", bookmarkID).Delete(&database.BookmarkTag{}).Error; err != nil {
+			return fmt.Errorf("failed to clear bookmark tags: %w", err)
+		}
+
+		for position, name := range list {
+			tag := database.Tag{UserID: userID, Name: name}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&tag).Error; err != nil {
+				return fmt.Errorf("failed to create tag: %w", err)
+			}
+			if tag.ID == 0 {
+				if err := tx.Where("user_id = ? AND name = ?", userID, name).First(&tag).Error; err != nil {
+					return fmt.Errorf("failed to get tag: %w", err)
+				}
+			}
+			link := database.BookmarkTag{BookmarkID: bookmarkID, TagID: tag.ID, Position: position}
+			if err := tx.Create(&link).Error; err != nil {
+				return fmt.Errorf("failed to link tag: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// jsonTagCounts counts the user's bookmarks per tag from the JSON tags column
+func (s *Service) jsonTagCounts(ctx context.Context, userID uint) (map[string]int, error) {
+	var rows []database.Bookmark
+	if err := s.db.WithContext(ctx).Model(&database.Bookmark{}).Select("id, tags").
+		Where("user_id = ?", userID).Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to load tags: %w", err)
+	}
+
+	counts := make(map[string]int)
+	for _, row := range rows {
+		for _, tag := range decodeTags(row.Tags) {
+			counts[tag]++
+		}
+	}
+	return counts, nil
+}
+
+// relationalTagCounts counts the user's bookmarks per tag from bookmark_tags
+func (s *Service) relationalTagCounts(ctx context.Context, userID uint) (map[string]int, error) {
+	var rows []struct {
+		Name  string
+		Count int
+	}
+	if err := s.db.WithContext(ctx).Table("bookmark_tags").
+		Select("tags.name AS name, COUNT(*) AS count").
+		Joins("JOIN tags ON tags.id = bookmark_tags.tag_id").
+		Joins("JOIN bookmarks ON bookmarks.id = bookmark_tags.bookmark_id").
+		Where("tags.user_id = ? AND bookmarks.deleted_at IS NULL", userID).
+		Group("tags.name").Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to load relational tags: %w", err)
+	}
+
+	counts := make(map[string]int, len(rows))
+	for _, row := range rows {
+		counts[row.Name] = row.Count
+	}
+	return counts, nil
+}
+
+// BackfillRelationalTags copies the JSON tags of every bookmark into the
+// relational tables, in batches of batchSize bookmarks. It is safe to rerun
+// and returns the number of bookmarks copied
+func (s *Service) BackfillRelationalTags(ctx context.Context, batchSize int) (int, error) {
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+
+	copied := 0
+	var lastID uint
+	for {
+		var rows []database.Bookmark
+		if err := s.db.WithContext(ctx).Model(&database.Bookmark{}).Select("id, user_id, tags").
+			Where("id > ?", lastID).Order("id").Limit(batchSize).Find(&rows).Error; err != nil {
+			return copied, fmt.Errorf("failed to load bookmarks: %w", err)
+		}
+		if len(rows) == 0 {
+			return copied, nil
+		}
+
+		for _, row := range rows {
+			if err := writeRelationalTags(s.db.WithContext(ctx), row.UserID, row.ID, decodeTags(row.Tags)); err != nil {
+				return copied, fmt.Errorf("failed to backfill bookmark %d: %w", row.ID, err)
+			}
+			copied++
+		}
+		lastID = rows[len(rows)-1].ID
+	}
+}
+
+func equalTagCounts(a, b map[string]int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for tag, count := range a {
+		if b[tag] != count {
+			return false
+		}
+	}
+	return true
+}
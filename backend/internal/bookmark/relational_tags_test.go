@@ -0,0 +1,93 @@
+package bookmark
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/dualwrite"
+)
+
+func relationalTags(t *testing.T, service *Service, bookmarkID uint) []string {
+	var names []string
+	require.NoError(t, service.db.Table("bookmark_tags").Select("tags.name").
+		Joins("JOIN tags ON tags.id = bookmark_tags.tag_id").
+		Where("bookmark_tags.bookmark_id = ?", bookmarkID).
+		Order("bookmark_tags.position").Pluck("tags.name", &names).Error)
+	return names
+}
+
+func TestBookmarkService_DualWritesRelationalTags(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+	migration := dualwrite.New(TagMigrationName, dualwrite.ModeOff, nil)
+	service.SetTagMigration(migration)
+
+	// Off: only the JSON column is written
+	before := createTaggedBookmarks(t, service, []string{"news"})[0]
+	assert.Empty(t, relationalTags(t, service, before.ID))
+
+	migration.SetMode(dualwrite.ModeDualWrite)
+	bookmark := createTaggedBookmarks(t, service, []string{"dev/go", "news"})[0]
+	assert.Equal(t, []string{"dev/go", "news"}, relationalTags(t, service, bookmark.ID))
+
+	_, err := service.Update(UpdateBookmarkRequest{ID: bookmark.ID, UserID: 1, Tags: []string{"news", "dev/rust"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"news", "dev/rust"}, relationalTags(t, service, bookmark.ID))
+
+	// Updates without tags leave them alone
+	_, err = service.Update(UpdateBookmarkRequest{ID: bookmark.ID, UserID: 1, Title: "Renamed"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"news", "dev/rust"}, relationalTags(t, service, bookmark.ID))
+
+	_, err = service.RenameTag(1, RenameTagRequest{From: "dev", To: "code"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"news", "code/rust"}, relationalTags(t, service, bookmark.ID))
+
+	// Tags are shared between the user's bookmarks
+	var count int64
+	require.NoError(t, db.Model(&database.Tag{}).Where("user_id = ? AND name = ?", 1, "news").Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+
+	stats := migration.Stats()
+	assert.Equal(t, uint64(3), stats.NewWrites)
+	assert.Zero(t, stats.NewWriteErrors)
+}
+
+func TestBookmarkService_GetTagTreeShadowRead(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+	createTaggedBookmarks(t, service, []string{"dev/go", "news"}, []string{"dev/go"})
+
+	migration := dualwrite.New(TagMigrationName, dualwrite.ModeShadow, nil)
+	service.SetTagMigration(migration)
+
+	// Given: Bookmarks tagged before dual writes started
+	_, err := service.GetTagTree(1)
+	require.NoError(t, err)
+	migration.Wait()
+
+	// Then: The shadow read diverges until they are backfilled
+	assert.Equal(t, uint64(1), migration.Stats().ShadowDiverged)
+
+	copied, err := service.BackfillRelationalTags(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, 2, copied)
+
+	_, err = service.GetTagTree(1)
+	require.NoError(t, err)
+	migration.Wait()
+	assert.Equal(t, uint64(1), migration.Stats().ShadowMatches)
+
+	// And: After cutover the tree comes from the relational tables
+	migration.SetMode(dualwrite.ModeCutover)
+	tree, err := service.GetTagTree(1)
+	require.NoError(t, err)
+	require.Len(t, tree, 2)
+	assert.Equal(t, "dev", tree[0].Path)
+	assert.Equal(t, 2, tree[0].TotalCount)
+	assert.Equal(t, "news", tree[1].Path)
+}
@@ -0,0 +1,37 @@
+package bookmark
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"bookmark-sync-service/backend/pkg/tags"
+)
+
+// AddBookmarkTags adds tags to a bookmark for a scheduled automation rule,
+// as an update through the API would. It reports whether the bookmark
+// changed, so a rule running again does not count bookmarks twice
+func (s *Service) AddBookmarkTags(ctx context.Context, userID string, bookmarkID uint, add []string) (bool, error) {
+	id, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil {
+		return false, fmt.Errorf("invalid user ID %q: %w", userID, err)
+	}
+	existing, err := s.GetByID(bookmarkID, uint(id))
+	if err != nil {
+		return false, err
+	}
+
+	var current []string
+	_ = json.Unmarshal([]byte(existing.Tags), &current)
+	current = tags.NormalizeAll(current)
+	merged := tags.NormalizeAll(append(current, add...))
+	if len(merged) == len(current) {
+		return false, nil
+	}
+
+	if _, err := s.Update(UpdateBookmarkRequest{ID: bookmarkID, UserID: uint(id), Tags: merged}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
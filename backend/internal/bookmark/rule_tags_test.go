@@ -0,0 +1,35 @@
+package bookmark
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+func TestBookmarkService_AddBookmarkTags(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+	ctx := context.Background()
+
+	created, err := service.Create(CreateBookmarkRequest{UserID: 1, URL: "https://go.dev", Title: "Go", Tags: []string{"go"}})
+	require.NoError(t, err)
+
+	changed, err := service.AddBookmarkTags(ctx, "1", created.ID, []string{"needs-review"})
+	require.NoError(t, err)
+	assert.True(t, changed)
+
+	changed, err = service.AddBookmarkTags(ctx, "1", created.ID, []string{"needs-review", "go"})
+	require.NoError(t, err)
+	assert.False(t, changed, "tags already there change nothing")
+
+	var bookmark database.Bookmark
+	require.NoError(t, db.First(&bookmark, created.ID).Error)
+	assert.JSONEq(t, `["go","needs-review"]`, bookmark.Tags)
+
+	_, err = service.AddBookmarkTags(ctx, "2", created.ID, []string{"x"})
+	assert.Error(t, err, "other users' bookmarks are not found")
+}
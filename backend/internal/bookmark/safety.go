@@ -0,0 +1,36 @@
+package bookmark
+
+import (
+	"context"
+	"time"
+
+	"bookmark-sync-service/backend/internal/safety"
+)
+
+// SafetyChecker reports URLs that point at known-malicious sites
+type SafetyChecker interface {
+	CheckURL(ctx context.Context, rawURL string) (*safety.Verdict, error)
+}
+
+// SetSafetyChecker makes the service check URLs as bookmarks are saved
+func (s *Service) SetSafetyChecker(checker SafetyChecker) {
+	s.safety = checker
+}
+
+// checkSafety returns the threat a URL is flagged for and when it was
+// checked. Unsafe URLs are still saved, flagged; when there is no checker or
+// the check fails the URL is left unchecked
+func (s *Service) checkSafety(rawURL string) (string, *time.Time) {
+	if s.safety == nil {
+		return "", nil
+	}
+	verdict, err := s.safety.CheckURL(context.Background(), rawURL)
+	if err != nil {
+		return "", nil
+	}
+	now := time.Now()
+	if verdict == nil {
+		return "", &now
+	}
+	return verdict.Threat, &now
+}
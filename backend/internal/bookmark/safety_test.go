@@ -0,0 +1,53 @@
+package bookmark
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bookmark-sync-service/backend/internal/safety"
+)
+
+type stubSafetyChecker struct {
+	threats map[string]string
+	err     error
+}
+
+func (s *stubSafetyChecker) CheckURL(_ context.Context, rawURL string) (*safety.Verdict, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	if threat, ok := s.threats[rawURL]; ok {
+		return &safety.Verdict{URL: rawURL, Threat: threat, Source: safety.SourceProvider}, nil
+	}
+	return nil, nil
+}
+
+func TestBookmarkService_SafetyChecks(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+	checker := &stubSafetyChecker{threats: map[string]string{"https://malware.example": safety.ThreatMalware}}
+	service.SetSafetyChecker(checker)
+
+	flagged, err := service.Create(CreateBookmarkRequest{UserID: 1, URL: "https://malware.example", Title: "Free stuff"})
+	require.NoError(t, err, "unsafe URLs are saved, flagged")
+	assert.Equal(t, safety.ThreatMalware, flagged.SafetyThreat)
+	assert.NotNil(t, flagged.SafetyCheckedAt)
+
+	safe, err := service.Create(CreateBookmarkRequest{UserID: 1, URL: "https://go.dev", Title: "Go"})
+	require.NoError(t, err)
+	assert.Empty(t, safe.SafetyThreat)
+	assert.NotNil(t, safe.SafetyCheckedAt)
+
+	updated, err := service.Update(UpdateBookmarkRequest{ID: flagged.ID, UserID: 1, URL: "https://go.dev/doc"})
+	require.NoError(t, err)
+	assert.Empty(t, updated.SafetyThreat, "a new URL is checked again")
+
+	checker.err = errors.New("provider down")
+	updated, err = service.Update(UpdateBookmarkRequest{ID: safe.ID, UserID: 1, URL: "https://go.dev/blog"})
+	require.NoError(t, err)
+	assert.Nil(t, updated.SafetyCheckedAt, "a failed check leaves the URL unchecked")
+}
@@ -0,0 +1,432 @@
+package bookmark
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/utils"
+	"bookmark-sync-service/backend/pkg/websocket"
+)
+
+// Actions the receiving device takes with a sent bookmark
+const (
+	SendActionOpen = "open"
+	SendActionSave = "save"
+)
+
+// Statuses of a bookmark send
+const (
+	SendStatusDelivered    = "delivered"    // pushed to the connected device
+	SendStatusQueued       = "queued"       // the device was offline; waiting in the notification center
+	SendStatusAcknowledged = "acknowledged" // the device opened or saved it
+)
+
+// NotificationSentToDevice is the notification center change type of
+// bookmarks sent to an offline device
+const NotificationSentToDevice = "sent_to_device"
+
+// WebSocket message types of bookmark sends
+const (
+	MessageBookmarkSent         = "bookmark_sent"
+	MessageBookmarkAcknowledged = "bookmark_send_acknowledged"
+)
+
+var (
+	ErrDeviceNotFound    = errors.New("device not found")
+	ErrSendNotFound      = errors.New("send not found")
+	ErrInvalidSendAction = errors.New("action must be open or save")
+	ErrDeviceHubDisabled = errors.New("sending to devices is unavailable")
+)
+
+// DeviceHub pushes messages to the user's connected devices
+type DeviceHub interface {
+	SendToDevice(userID, deviceID string, message *websocket.Message) bool
+	BroadcastToUser(userID string, message *websocket.Message)
+}
+
+// SetDeviceHub enables sending bookmarks to the user's other devices
+func (s *Service) SetDeviceHub(hub DeviceHub) {
+	s.devices = hub
+}
+
+// SendRequest sends a bookmark to one of the user's devices
+type SendRequest struct {
+	DeviceID   string `json:"device_id"`
+	FromDevice string `json:"from_device"`
+	Action     string `json:"action"` // "open" (default) or "save"
+}
+
+// SendToDevice pushes a bookmark to one of the user's devices. A device that
+// is not connected finds it in the notification center and its pending
+// sends when it comes back
+func (s *Service) SendToDevice(ctx context.Context, userID, bookmarkID uint, req SendRequest) (*database.BookmarkSend, error) {
+	if s.devices == nil {
+		return nil, ErrDeviceHubDisabled
+	}
+	if req.Action == "" {
+		req.Action = SendActionOpen
+	}
+	if req.Action != SendActionOpen && req.Action != SendActionSave {
+		return nil, ErrInvalidSendAction
+	}
+
+	bookmark, err := s.GetByID(bookmarkID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	owner := strconv.FormatUint(uint64(userID), 10)
+	known, err := s.knownDevice(ctx, owner, req.DeviceID)
+	if err != nil {
+		return nil, err
+	}
+	if !known {
+		return nil, ErrDeviceNotFound
+	}
+
+	send := &database.BookmarkSend{
+		UserID:     userID,
+		BookmarkID: bookmark.ID,
+		DeviceID:   req.DeviceID,
+		FromDevice: req.FromDevice,
+		Action:     req.Action,
+		Status:     SendStatusQueued,
+	}
+
+	if err := s.db.WithContext(ctx).Create(send).Error; err != nil {
+		return nil, fmt.Errorf("failed to record send: %w", err)
+	}
+
+	// The send is stored before it is pushed, so the device can acknowledge
+	// it straight away
+	delivered := s.devices.SendToDevice(owner, req.DeviceID, &websocket.Message{
+		Type: MessageBookmarkSent,
+		Data: map[string]interface{}{
+			"send_id":     send.ID,
+			"bookmark_id": bookmark.ID,
+			"url":         bookmark.URL,
+			"title":       bookmark.Title,
+			"action":      send.Action,
+			"from_device": send.FromDevice,
+		},
+		Timestamp: time.Now(),
+	})
+	if delivered {
+		now := time.Now()
+		err := s.db.WithContext(ctx).Model(send).Where("status = ?", SendStatusQueued).
+			Updates(map[string]interface{}{"status": SendStatusDelivered, "delivered_at": now}).Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to record delivery: %w", err)
+		}
+		return s.GetSend(ctx, userID, send.ID)
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		notification := &database.LinkChangeNotification{
+			UserID:     userID,
+			BookmarkID: bookmark.ID,
+			ChangeType: NotificationSentToDevice,
+			OldValue:   req.DeviceID,
+			NewValue:   bookmark.URL,
+			Message:    sendMessage(bookmark, send),
+		}
+		if err := tx.Create(notification).Error; err != nil {
+			return err
+		}
+		send.NotificationID = &notification.ID
+		return tx.Model(send).Update("notification_id", notification.ID).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to queue send: %w", err)
+	}
+	return send, nil
+}
+
+// knownDevice reports whether a device has ever synced the user's bookmarks
+func (s *Service) knownDevice(ctx context.Context, userID, deviceID string) (bool, error) {
+	if deviceID == "" {
+		return false, nil
+	}
+
+	var count int64
+	err := s.db.WithContext(ctx).Model(&database.SyncState{}).
+		Where("user_id = ? AND device_id = ?", userID, deviceID).
+		Count(&count).Error
+	if err != nil || count > 0 {
+		return count > 0, err
+	}
+	err = s.db.WithContext(ctx).Model(&database.SyncScope{}).
+		Where("user_id = ? AND device_id = ?", userID, deviceID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func sendMessage(bookmark *database.Bookmark, send *database.BookmarkSend) string {
+	title := bookmark.Title
+	if title == "" {
+		title = bookmark.URL
+	}
+	if send.FromDevice == "" {
+		return fmt.Sprintf("Sent to %s: %s", send.DeviceID, title)
+	}
+	return fmt.Sprintf("Sent from %s to %s: %s", send.FromDevice, send.DeviceID, title)
+}
+
+// PendingSends lists the sends a device has not acknowledged yet, oldest
+// first. Without a device it lists those of all devices
+func (s *Service) PendingSends(ctx context.Context, userID uint, deviceID string) ([]database.BookmarkSend, error) {
+	query := s.db.WithContext(ctx).Where("user_id = ? AND status <> ?", userID, SendStatusAcknowledged)
+	if deviceID != "" {
+		query = query.Where("device_id = ?", deviceID)
+	}
+
+	var sends []database.BookmarkSend
+	if err := query.Order("created_at ASC, id ASC").Find(&sends).Error; err != nil {
+		return nil, fmt.Errorf("failed to list sends: %w", err)
+	}
+	return sends, nil
+}
+
+// GetSend returns one of the user's sends
+func (s *Service) GetSend(ctx context.Context, userID, sendID uint) (*database.BookmarkSend, error) {
+	var send database.BookmarkSend
+	err := s.db.WithContext(ctx).Where("id = ? AND user_id = ?", sendID, userID).First(&send).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSendNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get send: %w", err)
+	}
+	return &send, nil
+}
+
+// AcknowledgeSend records the receiving device opening or saving a sent
+// bookmark, clears its notification and tells the user's other devices.
+// When deviceID is set only that device can acknowledge the send.
+// Acknowledging twice keeps the first acknowledgement
+func (s *Service) AcknowledgeSend(ctx context.Context, userID, sendID uint, deviceID, outcome string) (*database.BookmarkSend, error) {
+	send, err := s.GetSend(ctx, userID, sendID)
+	if err != nil {
+		return nil, err
+	}
+	if deviceID != "" && deviceID != send.DeviceID {
+		return nil, ErrSendNotFound
+	}
+	if send.Status == SendStatusAcknowledged {
+		return send, nil
+	}
+	if outcome == "" {
+		outcome = send.Action
+	}
+	if outcome != SendActionOpen && outcome != SendActionSave {
+		return nil, ErrInvalidSendAction
+	}
+
+	now := time.Now()
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(send).Updates(map[string]interface{}{
+			"status":          SendStatusAcknowledged,
+			"outcome":         outcome,
+			"acknowledged_at": now,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to acknowledge send: %w", err)
+		}
+		if send.NotificationID != nil {
+			return tx.Model(&database.LinkChangeNotification{}).
+				Where("id = ? AND user_id = ?", *send.NotificationID, userID).
+				Update("read", true).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	send.Status = SendStatusAcknowledged
+	send.Outcome = outcome
+	send.AcknowledgedAt = &now
+
+	if s.devices != nil {
+		s.devices.BroadcastToUser(strconv.FormatUint(uint64(userID), 10), &websocket.Message{
+			Type: MessageBookmarkAcknowledged,
+			Data: map[string]interface{}{
+				"send_id":     send.ID,
+				"bookmark_id": send.BookmarkID,
+				"device_id":   send.DeviceID,
+				"outcome":     send.Outcome,
+			},
+			Timestamp: now,
+		})
+	}
+	return send, nil
+}
+
+// AcknowledgeDeviceSend handles a bookmark_send_ack message from a device
+// connected to the WebSocket hub
+func (s *Service) AcknowledgeDeviceSend(ctx context.Context, userID, deviceID string, sendID uint, action string) error {
+	id, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil {
+		return ErrSendNotFound
+	}
+	_, err = s.AcknowledgeSend(ctx, uint(id), sendID, deviceID, action)
+	return err
+}
+
+// SendBookmark sends a bookmark to another of the user's devices
+// @Summary Send bookmark to device
+// @Description Pushes the bookmark to the device over WebSocket so it opens or saves the link. An offline device gets it through the notification center and its pending sends
+// @Tags bookmarks
+// @Produce json
+// @Param id path int true "Bookmark ID"
+// @Param device_id query string true "Receiving device"
+// @Param action query string false "open (default) or save"
+// @Param from_device query string false "Sending device"
+// @Success 200 {object} database.BookmarkSend
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 503 {object} utils.ErrorResponse
+// @Router /api/v1/bookmarks/{id}/send [post]
+func (h *Handlers) SendBookmark(c *gin.Context) {
+	bookmarkID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid bookmark ID", nil)
+		return
+	}
+
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	deviceID := c.Query("device_id")
+	if deviceID == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "device_id is required", nil)
+		return
+	}
+
+	send, err := h.service.SendToDevice(c.Request.Context(), userID, uint(bookmarkID), SendRequest{
+		DeviceID:   deviceID,
+		FromDevice: c.Query("from_device"),
+		Action:     c.Query("action"),
+	})
+	if err != nil {
+		h.sendFailed(c, err, "Failed to send bookmark")
+		return
+	}
+
+	message := "Bookmark sent to device"
+	if send.Status == SendStatusQueued {
+		message = "Device is offline; bookmark queued in its notifications"
+	}
+	utils.SuccessResponse(c, send, message)
+}
+
+// ListSends lists the sends not acknowledged yet
+// @Summary List pending bookmark sends
+// @Description Devices coming back online fetch the bookmarks sent to them while they were offline
+// @Tags bookmarks
+// @Produce json
+// @Param device_id query string false "Receiving device"
+// @Success 200 {array} database.BookmarkSend
+// @Router /api/v1/bookmarks/sends [get]
+func (h *Handlers) ListSends(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	sends, err := h.service.PendingSends(c.Request.Context(), userID, c.Query("device_id"))
+	if err != nil {
+		h.sendFailed(c, err, "Failed to list sends")
+		return
+	}
+	utils.SuccessResponse(c, gin.H{"sends": sends}, "Pending sends retrieved successfully")
+}
+
+// GetSend returns a send and its delivery status
+// @Summary Get bookmark send
+// @Tags bookmarks
+// @Produce json
+// @Param send_id path int true "Send ID"
+// @Success 200 {object} database.BookmarkSend
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /api/v1/bookmarks/sends/{send_id} [get]
+func (h *Handlers) GetSend(c *gin.Context) {
+	userID, sendID, ok := sendParams(c)
+	if !ok {
+		return
+	}
+
+	send, err := h.service.GetSend(c.Request.Context(), userID, sendID)
+	if err != nil {
+		h.sendFailed(c, err, "Failed to get send")
+		return
+	}
+	utils.SuccessResponse(c, send, "Send retrieved successfully")
+}
+
+// AcknowledgeSend records the receiving device opening or saving a send
+// @Summary Acknowledge bookmark send
+// @Description Connected devices can acknowledge with a bookmark_send_ack WebSocket message instead
+// @Tags bookmarks
+// @Produce json
+// @Param send_id path int true "Send ID"
+// @Param device_id query string false "Acknowledging device; must be the receiving device"
+// @Param action query string false "What the device did: open or save; defaults to the requested action"
+// @Success 200 {object} database.BookmarkSend
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /api/v1/bookmarks/sends/{send_id}/ack [post]
+func (h *Handlers) AcknowledgeSend(c *gin.Context) {
+	userID, sendID, ok := sendParams(c)
+	if !ok {
+		return
+	}
+
+	send, err := h.service.AcknowledgeSend(c.Request.Context(), userID, sendID, c.Query("device_id"), c.Query("action"))
+	if err != nil {
+		h.sendFailed(c, err, "Failed to acknowledge send")
+		return
+	}
+	utils.SuccessResponse(c, send, "Send acknowledged")
+}
+
+func sendParams(c *gin.Context) (uint, uint, bool) {
+	sendID, err := strconv.ParseUint(c.Param("send_id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid send ID", nil)
+		return 0, 0, false
+	}
+
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return 0, 0, false
+	}
+	return userID, uint(sendID), true
+}
+
+func (h *Handlers) sendFailed(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, ErrDeviceHubDisabled):
+		utils.ErrorResponse(c, http.StatusServiceUnavailable, "SEND_UNAVAILABLE", err.Error(), nil)
+	case errors.Is(err, ErrInvalidSendAction):
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error(), nil)
+	case errors.Is(err, ErrDeviceNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, "DEVICE_NOT_FOUND", err.Error(), nil)
+	case errors.Is(err, ErrSendNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, "NOT_FOUND", err.Error(), nil)
+	case err.Error() == "bookmark not found":
+		utils.ErrorResponse(c, http.StatusNotFound, "NOT_FOUND", err.Error(), nil)
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", message, nil)
+	}
+}
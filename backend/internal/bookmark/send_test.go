@@ -0,0 +1,176 @@
+package bookmark
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/websocket"
+)
+
+// stubDeviceHub delivers to the devices marked online and records messages
+type stubDeviceHub struct {
+	online    map[string]bool
+	sent      []*websocket.Message
+	broadcast []*websocket.Message
+}
+
+func (h *stubDeviceHub) SendToDevice(userID, deviceID string, message *websocket.Message) bool {
+	if !h.online[userID+"/"+deviceID] {
+		return false
+	}
+	h.sent = append(h.sent, message)
+	return true
+}
+
+func (h *stubDeviceHub) BroadcastToUser(userID string, message *websocket.Message) {
+	h.broadcast = append(h.broadcast, message)
+}
+
+func TestBookmarkService_SendToDevice(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+	ctx := context.Background()
+
+	bookmark, err := service.Create(CreateBookmarkRequest{UserID: 1, URL: "https://go.dev", Title: "Go"})
+	require.NoError(t, err)
+
+	_, err = service.SendToDevice(ctx, 1, bookmark.ID, SendRequest{DeviceID: "phone"})
+	assert.ErrorIs(t, err, ErrDeviceHubDisabled)
+
+	hub := &stubDeviceHub{online: map[string]bool{"1/phone": true}}
+	service.SetDeviceHub(hub)
+	require.NoError(t, db.Create(&database.SyncState{UserID: "1", DeviceID: "phone", LastSyncTime: time.Now()}).Error)
+	require.NoError(t, db.Create(&database.SyncScope{UserID: "1", DeviceID: "tablet", CollectionIDs: "[]"}).Error)
+
+	_, err = service.SendToDevice(ctx, 1, bookmark.ID, SendRequest{DeviceID: "laptop"})
+	assert.ErrorIs(t, err, ErrDeviceNotFound)
+	_, err = service.SendToDevice(ctx, 1, bookmark.ID, SendRequest{DeviceID: "phone", Action: "delete"})
+	assert.ErrorIs(t, err, ErrInvalidSendAction)
+	_, err = service.SendToDevice(ctx, 2, bookmark.ID, SendRequest{DeviceID: "phone"})
+	assert.EqualError(t, err, "bookmark not found")
+
+	// A connected device gets the link pushed
+	delivered, err := service.SendToDevice(ctx, 1, bookmark.ID, SendRequest{DeviceID: "phone", FromDevice: "laptop"})
+	require.NoError(t, err)
+	assert.Equal(t, SendStatusDelivered, delivered.Status)
+	assert.NotNil(t, delivered.DeliveredAt)
+	assert.Nil(t, delivered.NotificationID)
+	require.Len(t, hub.sent, 1)
+	assert.Equal(t, MessageBookmarkSent, hub.sent[0].Type)
+	data := hub.sent[0].Data.(map[string]interface{})
+	assert.Equal(t, "https://go.dev", data["url"])
+	assert.Equal(t, SendActionOpen, data["action"])
+	assert.Equal(t, delivered.ID, data["send_id"])
+
+	// An offline device finds it in the notification center
+	queued, err := service.SendToDevice(ctx, 1, bookmark.ID, SendRequest{DeviceID: "tablet", FromDevice: "laptop", Action: SendActionSave})
+	require.NoError(t, err)
+	assert.Equal(t, SendStatusQueued, queued.Status)
+	require.NotNil(t, queued.NotificationID)
+
+	var notification database.LinkChangeNotification
+	require.NoError(t, db.First(&notification, *queued.NotificationID).Error)
+	assert.Equal(t, NotificationSentToDevice, notification.ChangeType)
+	assert.Equal(t, "https://go.dev", notification.NewValue)
+	assert.Equal(t, "Sent from laptop to tablet: Go", notification.Message)
+	assert.False(t, notification.Read)
+
+	pending, err := service.PendingSends(ctx, 1, "tablet")
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, queued.ID, pending[0].ID)
+
+	// Only the receiving device acknowledges
+	_, err = service.AcknowledgeSend(ctx, 1, queued.ID, "phone", "")
+	assert.ErrorIs(t, err, ErrSendNotFound)
+
+	acknowledged, err := service.AcknowledgeSend(ctx, 1, queued.ID, "tablet", "")
+	require.NoError(t, err)
+	assert.Equal(t, SendStatusAcknowledged, acknowledged.Status)
+	assert.Equal(t, SendActionSave, acknowledged.Outcome)
+	require.NoError(t, db.First(&notification, *queued.NotificationID).Error)
+	assert.True(t, notification.Read)
+	require.Len(t, hub.broadcast, 1)
+	assert.Equal(t, MessageBookmarkAcknowledged, hub.broadcast[0].Type)
+
+	// Acknowledging again keeps the first outcome
+	again, err := service.AcknowledgeSend(ctx, 1, queued.ID, "", SendActionOpen)
+	require.NoError(t, err)
+	assert.Equal(t, SendActionSave, again.Outcome)
+	assert.Len(t, hub.broadcast, 1)
+
+	pending, err = service.PendingSends(ctx, 1, "")
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, delivered.ID, pending[0].ID)
+
+	// Devices acknowledge over the hub with the user ID as a string
+	require.NoError(t, service.AcknowledgeDeviceSend(ctx, "1", "phone", delivered.ID, ""))
+	stored, err := service.GetSend(ctx, 1, delivered.ID)
+	require.NoError(t, err)
+	assert.Equal(t, SendStatusAcknowledged, stored.Status)
+	assert.ErrorIs(t, service.AcknowledgeDeviceSend(ctx, "2", "phone", delivered.ID, ""), ErrSendNotFound)
+}
+
+func TestSendBookmarkHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupTestDB(t)
+	service := NewService(db)
+	service.SetDeviceHub(&stubDeviceHub{})
+	require.NoError(t, db.Create(&database.SyncState{UserID: "1", DeviceID: "phone", LastSyncTime: time.Now()}).Error)
+
+	bookmark, err := service.Create(CreateBookmarkRequest{UserID: 1, URL: "https://go.dev", Title: "Go"})
+	require.NoError(t, err)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", "1")
+		c.Next()
+	})
+	NewHandlers(service).RegisterRoutes(router.Group("/api/v1"))
+
+	do := func(method, path string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		return w, body
+	}
+
+	w, _ := do(http.MethodPost, "/api/v1/bookmarks/1/send")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w, _ = do(http.MethodPost, "/api/v1/bookmarks/1/send?device_id=laptop")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w, body := do(http.MethodPost, "/api/v1/bookmarks/1/send?device_id=phone&action=save")
+	require.Equal(t, http.StatusOK, w.Code)
+	send := body["data"].(map[string]interface{})
+	assert.Equal(t, SendStatusQueued, send["status"])
+	assert.Equal(t, float64(bookmark.ID), send["bookmark_id"])
+
+	w, body = do(http.MethodGet, "/api/v1/bookmarks/sends?device_id=phone")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, body["data"].(map[string]interface{})["sends"], 1)
+
+	w, _ = do(http.MethodGet, "/api/v1/bookmarks/sends/1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w, _ = do(http.MethodGet, "/api/v1/bookmarks/sends/99")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w, body = do(http.MethodPost, "/api/v1/bookmarks/sends/1/ack?device_id=phone")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, SendStatusAcknowledged, body["data"].(map[string]interface{})["status"])
+
+	// The static sends route leaves bookmark IDs alone
+	w, _ = do(http.MethodGet, "/api/v1/bookmarks/1")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -7,32 +7,81 @@ import (
 	"net/url"
 	"strings"
 
+	"go.uber.org/zap"
 	"gorm.io/gorm"
 
+	"bookmark-sync-service/backend/internal/automation"
+	"bookmark-sync-service/backend/pkg/clientid"
 	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/dualwrite"
+	"bookmark-sync-service/backend/pkg/language"
+	"bookmark-sync-service/backend/pkg/notes"
+	"bookmark-sync-service/backend/pkg/summarize"
+	"bookmark-sync-service/backend/pkg/tags"
 )
 
 // Service handles bookmark business logic
 type Service struct {
-	db *gorm.DB
+	db         *gorm.DB
+	indexer    SearchIndexer
+	summarizer summarize.Summarizer
+	safety     SafetyChecker
+	onboarding OnboardingTracker
+	urlRules   URLRules
+	devices    DeviceHub
+	hooks      Hooks
+	webhooks   WebhookTrigger
+
+	liveQueries LiveQueries
+	usage       UsageMeter
+	access      AccessChecker
+
+	// tagMigration rolls out relational tags alongside the JSON tags column
+	tagMigration *dualwrite.Migration
+
+	logger *zap.Logger
 }
 
 // NewService creates a new bookmark service
 func NewService(db *gorm.DB) *Service {
 	return &Service{
-		db: db,
+		db:           db,
+		tagMigration: dualwrite.New(TagMigrationName, dualwrite.ModeOff, nil),
+		logger:       zap.NewNop(),
 	}
 }
 
+// SetLogger logs webhook deliveries that fail without failing a change
+func (s *Service) SetLogger(logger *zap.Logger) {
+	s.logger = logger
+}
+
 // CreateBookmarkRequest represents the request to create a bookmark
 type CreateBookmarkRequest struct {
 	UserID      uint     `json:"user_id"`
+	ClientID    string   `json:"client_id"` // UUID from an offline client; creating it again returns the same bookmark
 	URL         string   `json:"url"`
 	Title       string   `json:"title"`
 	Description string   `json:"description"`
 	Tags        []string `json:"tags"`
 	Favicon     string   `json:"favicon"`
 	Screenshot  string   `json:"screenshot"`
+	Language    string   `json:"language"`     // declared page language; detected from the text when empty
+	ReadingTime int      `json:"reading_time"` // estimated minutes, from content analysis
+	Notes       string   `json:"notes"`        // Markdown
+	// NotesEncrypted sends Notes as base64 ciphertext the client encrypted,
+	// with NotesKeyHint naming the key that decrypts it
+	NotesEncrypted bool   `json:"notes_encrypted"`
+	NotesKeyHint   string `json:"notes_key_hint"`
+
+	// Source and SourceRef attribute the bookmark to the pathway creating
+	// it; they are set by that pathway, never by the client. No source
+	// means the API
+	Source    string `json:"-"`
+	SourceRef string `json:"-"`
+
+	// urlKey is set by UpsertByURL to claim the URL for the new bookmark
+	urlKey string
 }
 
 // UpdateBookmarkRequest represents the request to update a bookmark
@@ -45,15 +94,24 @@ type UpdateBookmarkRequest struct {
 	Tags        []string `json:"tags"`
 	Favicon     string   `json:"favicon"`
 	Screenshot  string   `json:"screenshot"`
+	Language    string   `json:"language"`     // overrides the stored language when set
+	ReadingTime int      `json:"reading_time"` // overrides the stored estimate when set
+	Notes       *string  `json:"notes"`        // Markdown; an empty string clears the notes
+	// NotesEncrypted and NotesKeyHint describe Notes when it is set, so
+	// notes sent without them are stored as plain Markdown
+	NotesEncrypted bool   `json:"notes_encrypted"`
+	NotesKeyHint   string `json:"notes_key_hint"`
 }
 
 // ListBookmarksRequest represents the request to list bookmarks
 type ListBookmarksRequest struct {
 	UserID       uint   `json:"user_id"`
-	Search       string `json:"search"`
-	Tags         string `json:"tags"`
+	Search       string `json:"search"` // note:keyword restricts matches to notes
+	Tags         string `json:"tags"`   // comma separated; "dev" also matches "dev/go", "dev/*" only children
 	CollectionID uint   `json:"collection_id"`
 	Status       string `json:"status"`
+	Lang         string `json:"lang"`   // comma separated language codes, e.g. "en,fr"
+	Source       string `json:"source"` // comma separated bookmark sources, e.g. "import,api"
 	Limit        int    `json:"limit"`
 	Offset       int    `json:"offset"`
 	SortBy       string `json:"sort_by"`    // created_at, updated_at, title, url
@@ -62,6 +120,10 @@ type ListBookmarksRequest struct {
 
 // Create creates a new bookmark
 func (s *Service) Create(req CreateBookmarkRequest) (*database.Bookmark, error) {
+	if err := s.runCreateHooks(&req); err != nil {
+		return nil, err
+	}
+
 	// Validate required fields
 	if req.URL == "" || req.Title == "" {
 		return nil, errors.New("URL and title are required")
@@ -71,6 +133,30 @@ func (s *Service) Create(req CreateBookmarkRequest) (*database.Bookmark, error)
 	if !isValidURL(req.URL) {
 		return nil, errors.New("invalid URL format")
 	}
+	req.URL = s.cleanURL(req.UserID, req.URL)
+
+	if req.ReadingTime < 0 {
+		return nil, errors.New("reading time cannot be negative")
+	}
+
+	if len(req.Notes) > notes.MaxLength {
+		return nil, errors.New("notes are too long")
+	}
+	if req.NotesEncrypted && !notes.ValidEncrypted(req.Notes, req.NotesKeyHint) {
+		return nil, errors.New("invalid encrypted notes")
+	}
+
+	clientID, err := clientid.Normalize(req.ClientID)
+	if err != nil {
+		return nil, err
+	}
+	if clientID != "" {
+		// A retried create returns the bookmark the first attempt made
+		existing, err := s.byClientID(req.UserID, clientID)
+		if err != nil || existing != nil {
+			return existing, err
+		}
+	}
 
 	// Check if user exists
 	var user database.User
@@ -82,31 +168,63 @@ func (s *Service) Create(req CreateBookmarkRequest) (*database.Bookmark, error)
 	}
 
 	// Convert tags to JSON
-	tagsJSON := "[]"
-	if len(req.Tags) > 0 {
-		tagsBytes, err := json.Marshal(req.Tags)
-		if err != nil {
-			return nil, fmt.Errorf("failed to marshal tags: %w", err)
-		}
-		tagsJSON = string(tagsBytes)
+	tagList := tags.NormalizeAll(req.Tags)
+	tagsBytes, err := json.Marshal(tagList)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal tags: %w", err)
 	}
 
 	// Create bookmark
 	bookmark := &database.Bookmark{
 		UserID:      req.UserID,
+		ClientID:    clientid.Ptr(clientID),
 		URL:         req.URL,
 		Title:       req.Title,
 		Description: req.Description,
 		Favicon:     req.Favicon,
 		Screenshot:  req.Screenshot,
-		Tags:        tagsJSON,
+		Language:    language.Resolve(req.Language, req.Title+" "+req.Description),
+		ReadingTime: req.ReadingTime,
+		Notes:       req.Notes,
+		Tags:        string(tagsBytes),
 		Status:      "active",
+
+		NotesEncrypted: req.NotesEncrypted,
+		Source:         req.Source,
+		SourceRef:      req.SourceRef,
+	}
+	if bookmark.Source == "" {
+		bookmark.Source = database.BookmarkSourceAPI
+	}
+	if req.NotesEncrypted {
+		bookmark.NotesKeyHint = req.NotesKeyHint
 	}
+	if req.urlKey != "" {
+		bookmark.URLKey = &req.urlKey
+	}
+	bookmark.SafetyThreat, bookmark.SafetyCheckedAt = s.checkSafety(req.URL)
 
-	if err := s.db.Create(bookmark).Error; err != nil {
-		return nil, fmt.Errorf("failed to create bookmark: %w", err)
+	err = s.writeTags(bookmark, tagList, func() error {
+		if err := s.db.Create(bookmark).Error; err != nil {
+			return fmt.Errorf("failed to create bookmark: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		// A concurrent retry with the same client ID won the race
+		if clientID != "" {
+			if existing, lookupErr := s.byClientID(req.UserID, clientID); lookupErr == nil && existing != nil {
+				return existing, nil
+			}
+		}
+		return nil, err
 	}
 
+	s.index(bookmark)
+	s.publishLive("created", bookmark)
+	s.triggerWebhook(automation.WebhookEventBookmarkCreated, bookmark)
+	s.trackOnboarding(bookmark.UserID)
+	s.meterCreated(bookmark.UserID)
 	return bookmark, nil
 }
 
@@ -129,6 +247,44 @@ func (s *Service) GetByID(bookmarkID, userID uint) (*database.Bookmark, error) {
 	return &bookmark, nil
 }
 
+// GetByURL retrieves the user's bookmark of a URL, after applying the
+// user's URL cleanup rules the way saving it would
+func (s *Service) GetByURL(userID uint, rawURL string) (*database.Bookmark, error) {
+	var bookmark database.Bookmark
+
+	err := s.db.Where("user_id = ? AND url = ?", userID, s.cleanURL(userID, rawURL)).
+		Order("id").
+		Preload("User").
+		Preload("Collections").
+		First(&bookmark).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("bookmark not found")
+		}
+		return nil, fmt.Errorf("failed to get bookmark: %w", err)
+	}
+
+	return &bookmark, nil
+}
+
+// byClientID finds the bookmark a client created with a client ID, or nil.
+// A client ID of a deleted bookmark cannot be reused
+func (s *Service) byClientID(userID uint, clientID string) (*database.Bookmark, error) {
+	var bookmark database.Bookmark
+	err := s.db.Unscoped().Where("user_id = ? AND client_id = ?", userID, clientID).First(&bookmark).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find bookmark by client ID: %w", err)
+	}
+	if bookmark.DeletedAt.Valid {
+		return nil, errors.New("client_id belongs to a deleted bookmark")
+	}
+	return &bookmark, nil
+}
+
 // Update updates an existing bookmark
 func (s *Service) Update(req UpdateBookmarkRequest) (*database.Bookmark, error) {
 	// Get existing bookmark
@@ -141,12 +297,25 @@ func (s *Service) Update(req UpdateBookmarkRequest) (*database.Bookmark, error)
 	if req.URL != "" && !isValidURL(req.URL) {
 		return nil, errors.New("invalid URL format")
 	}
+	if req.URL != "" {
+		req.URL = s.cleanURL(req.UserID, req.URL)
+	}
+
+	if req.Notes != nil && len(*req.Notes) > notes.MaxLength {
+		return nil, errors.New("notes are too long")
+	}
+	if req.Notes != nil && req.NotesEncrypted && !notes.ValidEncrypted(*req.Notes, req.NotesKeyHint) {
+		return nil, errors.New("invalid encrypted notes")
+	}
 
 	// Update fields if provided
 	updates := make(map[string]interface{})
 
 	if req.URL != "" {
 		updates["url"] = req.URL
+		if req.URL != bookmark.URL {
+			updates["safety_threat"], updates["safety_checked_at"] = s.checkSafety(req.URL)
+		}
 	}
 	if req.Title != "" {
 		updates["title"] = req.Title
@@ -160,10 +329,26 @@ func (s *Service) Update(req UpdateBookmarkRequest) (*database.Bookmark, error)
 	if req.Screenshot != "" {
 		updates["screenshot"] = req.Screenshot
 	}
+	if code := language.Normalize(req.Language); code != "" {
+		updates["language"] = code
+	}
+	if req.ReadingTime > 0 {
+		updates["reading_time"] = req.ReadingTime
+	}
+	if req.Notes != nil {
+		updates["notes"] = *req.Notes
+		updates["notes_encrypted"] = req.NotesEncrypted
+		updates["notes_key_hint"] = ""
+		if req.NotesEncrypted {
+			updates["notes_key_hint"] = req.NotesKeyHint
+		}
+	}
 
 	// Handle tags
+	var tagList []string
 	if req.Tags != nil {
-		tagsBytes, err := json.Marshal(req.Tags)
+		tagList = tags.NormalizeAll(req.Tags)
+		tagsBytes, err := json.Marshal(tagList)
 		if err != nil {
 			return nil, fmt.Errorf("failed to marshal tags: %w", err)
 		}
@@ -171,18 +356,37 @@ func (s *Service) Update(req UpdateBookmarkRequest) (*database.Bookmark, error)
 	}
 
 	// Perform update
-	if err := s.db.Model(bookmark).Updates(updates).Error; err != nil {
-		return nil, fmt.Errorf("failed to update bookmark: %w", err)
+	update := func() error {
+		if err := s.db.Model(bookmark).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to update bookmark: %w", err)
+		}
+		return nil
+	}
+	if req.Tags != nil {
+		err = s.writeTags(bookmark, tagList, update)
+	} else {
+		err = update()
+	}
+	if err != nil {
+		return nil, err
 	}
 
 	// Return updated bookmark
-	return s.GetByID(req.ID, req.UserID)
+	updated, err := s.GetByID(req.ID, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.index(updated)
+	s.publishLive("updated", updated)
+	s.triggerWebhook(automation.WebhookEventBookmarkUpdated, updated)
+	return updated, nil
 }
 
 // Delete soft deletes a bookmark
 func (s *Service) Delete(bookmarkID, userID uint) error {
 	// Check if bookmark exists and belongs to user
-	_, err := s.GetByID(bookmarkID, userID)
+	bookmark, err := s.GetByID(bookmarkID, userID)
 	if err != nil {
 		return err
 	}
@@ -192,6 +396,10 @@ func (s *Service) Delete(bookmarkID, userID uint) error {
 		return fmt.Errorf("failed to delete bookmark: %w", err)
 	}
 
+	if s.indexer != nil {
+		s.indexer.QueueBookmarkDelete(bookmarkID)
+	}
+	s.triggerWebhook(automation.WebhookEventBookmarkDeleted, bookmark)
 	return nil
 }
 
@@ -199,11 +407,20 @@ func (s *Service) Delete(bookmarkID, userID uint) error {
 func (s *Service) List(req ListBookmarksRequest) ([]*database.Bookmark, int64, error) {
 	query := s.db.Model(&database.Bookmark{}).Where("user_id = ?", req.UserID)
 
-	// Apply filters
-	if req.Search != "" {
-		searchTerm := "%" + strings.ToLower(req.Search) + "%"
-		query = query.Where("LOWER(title) LIKE ? OR LOWER(description) LIKE ? OR LOWER(url) LIKE ?",
-			searchTerm, searchTerm, searchTerm)
+	// Apply filters; note: and tag: terms are split from the search text
+	search, noteTerms := notes.SplitQuery(req.Search)
+	search, tagPatterns := tags.SplitQuery(search)
+	if search != "" {
+		searchTerm := "%" + database.EscapeLike(strings.ToLower(search)) + "%"
+		query = query.Where(`LOWER(title) LIKE ? ESCAPE '\' OR LOWER(description) LIKE ? ESCAPE '\' OR LOWER(url) LIKE ? ESCAPE '\' OR (notes_encrypted = ? AND LOWER(notes) LIKE ? ESCAPE '\')`,
+			searchTerm, searchTerm, searchTerm, false, searchTerm)
+	}
+	// Encrypted notes are ciphertext, so they never match
+	for _, term := range noteTerms {
+		query = query.Where(`notes_encrypted = ? AND LOWER(notes) LIKE ? ESCAPE '\'`, false, "%"+database.EscapeLike(strings.ToLower(term))+"%")
+	}
+	for _, pattern := range tagPatterns {
+		query = tagFilter(query, pattern)
 	}
 
 	if req.Status != "" {
@@ -211,9 +428,15 @@ func (s *Service) List(req ListBookmarksRequest) ([]*database.Bookmark, int64, e
 	}
 
 	if req.Tags != "" {
-		// Simple tag search - can be enhanced with JSON queries
-		tagTerm := "%" + req.Tags + "%"
-		query = query.Where("tags LIKE ?", tagTerm)
+		query = tagFilter(query, req.Tags)
+	}
+
+	if langs := language.ParseList(req.Lang); len(langs) > 0 {
+		query = query.Where("language IN ?", langs)
+	}
+
+	if sources := database.ParseBookmarkSources(req.Source); len(sources) > 0 {
+		query = sourceFilter(query, sources)
 	}
 
 	if req.CollectionID > 0 {
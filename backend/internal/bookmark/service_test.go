@@ -452,3 +452,46 @@ func TestBookmarkService_List(t *testing.T) {
 		})
 	}
 }
+
+func TestBookmarkService_CreateWithClientID(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+
+	req := CreateBookmarkRequest{
+		UserID:   1,
+		ClientID: "3F2504E0-4F89-41D3-9A0C-0305E82C3301",
+		URL:      "https://example.com/offline",
+		Title:    "Saved offline",
+	}
+	first, err := service.Create(req)
+	require.NoError(t, err)
+	require.NotNil(t, first.ClientID)
+	assert.Equal(t, "3f2504e0-4f89-41d3-9a0c-0305e82c3301", *first.ClientID)
+
+	// A retry returns the same bookmark instead of a duplicate
+	req.Title = "Retried"
+	retried, err := service.Create(req)
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, retried.ID)
+	assert.Equal(t, "Saved offline", retried.Title)
+
+	var count int64
+	require.NoError(t, db.Model(&database.Bookmark{}).Where("user_id = ?", 1).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+
+	// Client IDs are unique per user only
+	other := &database.User{Email: "other@example.com", Username: "other", SupabaseID: "other"}
+	require.NoError(t, db.Create(other).Error)
+	req.UserID = other.ID
+	theirs, err := service.Create(req)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.ID, theirs.ID)
+
+	_, err = service.Create(CreateBookmarkRequest{UserID: 1, ClientID: "offline-1", URL: "https://example.com", Title: "Bad"})
+	assert.Error(t, err)
+
+	require.NoError(t, service.Delete(first.ID, 1))
+	req.UserID = 1
+	_, err = service.Create(req)
+	assert.EqualError(t, err, "client_id belongs to a deleted bookmark")
+}
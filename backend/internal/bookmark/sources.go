@@ -0,0 +1,51 @@
+package bookmark
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+// ClientTypeHeader is sent by the web app and the browser extensions, so
+// the bookmarks they save are attributed to them. Requests without it are
+// API use
+const ClientTypeHeader = "X-Client-Type"
+
+// requestSource attributes a bookmark saved through the REST API to the
+// client that sent the request
+func requestSource(c *gin.Context) string {
+	switch strings.ToLower(strings.TrimSpace(c.GetHeader(ClientTypeHeader))) {
+	case database.BookmarkSourceWeb:
+		return database.BookmarkSourceWeb
+	case database.BookmarkSourceExtension:
+		return database.BookmarkSourceExtension
+	default:
+		return database.BookmarkSourceAPI
+	}
+}
+
+// sourceFilter matches bookmarks saved through any of the sources. The
+// unknown source matches bookmarks saved before sources were recorded
+func sourceFilter(query *gorm.DB, sources []string) *gorm.DB {
+	known := make([]string, 0, len(sources))
+	unknown := false
+	for _, source := range sources {
+		if source == database.BookmarkSourceUnknown {
+			unknown = true
+			continue
+		}
+		known = append(known, source)
+	}
+
+	switch {
+	case unknown && len(known) > 0:
+		return query.Where("(source IN ? OR source = '' OR source IS NULL)", known)
+	case unknown:
+		return query.Where("(source = '' OR source IS NULL)")
+	default:
+		return query.Where("source IN ?", known)
+	}
+}
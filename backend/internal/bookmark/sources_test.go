@@ -0,0 +1,60 @@
+package bookmark
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+func TestBookmarkSources(t *testing.T) {
+	router, db := setupTestRouter(t)
+
+	save := func(url, clientType string) database.Bookmark {
+		raw, _ := json.Marshal(map[string]string{"url": url, "title": url, "source": "email"})
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/bookmarks", bytes.NewReader(raw))
+		req.Header.Set("Content-Type", "application/json")
+		if clientType != "" {
+			req.Header.Set(ClientTypeHeader, clientType)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusCreated, w.Code)
+
+		var response struct {
+			Data database.Bookmark `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response.Data
+	}
+
+	assert.Equal(t, database.BookmarkSourceExtension, save("https://a.example", "Extension").Source)
+	assert.Equal(t, database.BookmarkSourceWeb, save("https://b.example", "web").Source)
+	assert.Equal(t, database.BookmarkSourceAPI, save("https://c.example", "").Source, "clients can't pick their source")
+	assert.Equal(t, database.BookmarkSourceAPI, save("https://d.example", "import").Source)
+	require.NoError(t, db.Create(&database.Bookmark{UserID: 1, URL: "https://e.example", Title: "Older", Status: "active"}).Error)
+
+	service := NewService(db)
+	list := func(source string) []string {
+		bookmarks, total, err := service.List(ListBookmarksRequest{UserID: 1, Source: source, Limit: 10, SortBy: "url", SortOrder: "asc"})
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(bookmarks)), total)
+		urls := make([]string, 0, len(bookmarks))
+		for _, bookmark := range bookmarks {
+			urls = append(urls, bookmark.URL)
+		}
+		return urls
+	}
+
+	assert.Equal(t, []string{"https://c.example", "https://d.example"}, list("api"))
+	assert.Equal(t, []string{"https://a.example", "https://b.example"}, list("extension, WEB"))
+	assert.Equal(t, []string{"https://e.example"}, list("unknown"))
+	assert.Equal(t, []string{"https://a.example", "https://e.example"}, list("unknown,extension"))
+	assert.Len(t, list("bogus"), 5, "unrecognized sources don't filter")
+}
@@ -0,0 +1,455 @@
+package bookmark
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/internal/automation"
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/utils"
+)
+
+// How much each signal contributes to a suggestion's score. A matching
+// automation rule is an explicit user instruction, so it outweighs history
+const (
+	tagSignalWeight      = 0.5
+	domainSignalWeight   = 0.3
+	ruleSignalWeight     = 1.0
+	feedbackSignalWeight = 0.3
+	minSuggestionScore   = 0.1
+)
+
+// Reasons a collection is suggested
+const (
+	ReasonTags     = "tags"
+	ReasonDomain   = "domain"
+	ReasonRule     = "rule"
+	ReasonFeedback = "feedback"
+)
+
+// CollectionSuggestion is a collection a bookmark probably belongs in
+type CollectionSuggestion struct {
+	CollectionID uint     `json:"collection_id"`
+	Name         string   `json:"name"`
+	Score        float64  `json:"score"`
+	Reasons      []string `json:"reasons"`
+}
+
+// SuggestionFeedbackRequest tells whether the user took a suggestion
+type SuggestionFeedbackRequest struct {
+	CollectionID uint `json:"collection_id" binding:"required"`
+	Accepted     bool `json:"accepted"`
+}
+
+// collectionSignals accumulates what the user's filed bookmarks say about a collection
+type collectionSignals struct {
+	bookmarks   int
+	tagCounts   map[string]int
+	domainHits  int
+	ruleMatched bool
+	accepted    int
+	rejected    int
+}
+
+// SuggestCollections ranks the user's collections for a bookmark by how
+// often its tags and domain appear in them, by automation rules filing
+// matching bookmarks there, and by earlier feedback on suggestions
+func (s *Service) SuggestCollections(userID, bookmarkID uint, limit int) ([]CollectionSuggestion, error) {
+	if limit <= 0 {
+		limit = config.CollectionSuggestionLimit
+	}
+	if limit > config.MaxCollectionSuggestions {
+		limit = config.MaxCollectionSuggestions
+	}
+
+	var bookmark database.Bookmark
+	if err := s.db.Where("id = ? AND user_id = ?", bookmarkID, userID).First(&bookmark).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("bookmark not found")
+		}
+		return nil, fmt.Errorf("failed to get bookmark: %w", err)
+	}
+
+	var collections []database.Collection
+	if err := s.db.Where("user_id = ?", userID).Find(&collections).Error; err != nil {
+		return nil, fmt.Errorf("failed to list collections: %w", err)
+	}
+	if len(collections) == 0 {
+		return []CollectionSuggestion{}, nil
+	}
+
+	signals := make(map[uint]*collectionSignals, len(collections))
+	for _, collection := range collections {
+		signals[collection.ID] = &collectionSignals{tagCounts: make(map[string]int)}
+	}
+
+	var filedIn []uint
+	if err := s.db.Table("bookmark_collections").Where("bookmark_id = ?", bookmark.ID).
+		Pluck("collection_id", &filedIn).Error; err != nil {
+		return nil, fmt.Errorf("failed to get bookmark collections: %w", err)
+	}
+	for _, id := range filedIn {
+		delete(signals, id)
+	}
+
+	domain := bookmarkDomain(bookmark.URL)
+	bookmarkTags := decodeTags(bookmark.Tags)
+
+	domainTotal, err := s.collectHistorySignals(userID, bookmark.ID, domain, signals)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.collectRuleSignals(userID, &bookmark, domain, bookmarkTags, signals); err != nil {
+		return nil, err
+	}
+	if err := s.collectFeedbackSignals(userID, domain, signals); err != nil {
+		return nil, err
+	}
+
+	suggestions := make([]CollectionSuggestion, 0, len(signals))
+	for _, collection := range collections {
+		signal, ok := signals[collection.ID]
+		if !ok {
+			continue
+		}
+		score, reasons := signal.score(bookmarkTags, domainTotal)
+		if score < minSuggestionScore {
+			continue
+		}
+		suggestions = append(suggestions, CollectionSuggestion{
+			CollectionID: collection.ID,
+			Name:         collection.Name,
+			Score:        math.Round(score*1000) / 1000,
+			Reasons:      reasons,
+		})
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		if suggestions[i].Score != suggestions[j].Score {
+			return suggestions[i].Score > suggestions[j].Score
+		}
+		return suggestions[i].CollectionID < suggestions[j].CollectionID
+	})
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions, nil
+}
+
+// collectHistorySignals counts the tags and the domain of the user's recently
+// filed bookmarks per collection. It returns how many filed bookmarks share
+// the domain across all collections
+func (s *Service) collectHistorySignals(userID, bookmarkID uint, domain string, signals map[uint]*collectionSignals) (int, error) {
+	var rows []struct {
+		CollectionID uint
+		URL          string
+		Tags         string
+	}
+	if err := s.db.Table("bookmark_collections").
+		Select("bookmark_collections.collection_id, bookmarks.url, bookmarks.tags").
+		Joins("JOIN bookmarks ON bookmarks.id = bookmark_collections.bookmark_id").
+		Where("bookmarks.user_id = ? AND bookmarks.id <> ? AND bookmarks.deleted_at IS NULL", userID, bookmarkID).
+		Order("bookmarks.id DESC").
+		Limit(config.CollectionSuggestionHistory).
+		Scan(&rows).Error; err != nil {
+		return 0, fmt.Errorf("failed to load filed bookmarks: %w", err)
+	}
+
+	domainTotal := 0
+	for _, row := range rows {
+		signal, ok := signals[row.CollectionID]
+		sameDomain := domain != "" && bookmarkDomain(row.URL) == domain
+		if sameDomain {
+			domainTotal++
+		}
+		if !ok {
+			continue
+		}
+		signal.bookmarks++
+		if sameDomain {
+			signal.domainHits++
+		}
+		for _, tag := range decodeTags(row.Tags) {
+			signal.tagCounts[tag]++
+		}
+	}
+	return domainTotal, nil
+}
+
+// collectRuleSignals marks the collections that an active bookmark_added
+// automation rule would file the bookmark in; sandbox rules don't count. A
+// rule names its collection in its add_to_collection action; every
+// condition it sets has to match
+func (s *Service) collectRuleSignals(userID uint, bookmark *database.Bookmark, domain string, bookmarkTags []string, signals map[uint]*collectionSignals) error {
+	var rules []automation.AutomationRule
+	if err := s.db.Where(&automation.AutomationRule{
+		UserID:  strconv.FormatUint(uint64(userID), 10),
+		Trigger: "bookmark_added",
+		Active:  true,
+	}).Where("sandbox = ?", false).Find(&rules).Error; err != nil {
+		return fmt.Errorf("failed to load automation rules: %w", err)
+	}
+
+	for _, rule := range rules {
+		collectionID, ok := ruleCollectionID(rule.Actions)
+		if !ok {
+			continue
+		}
+		signal, ok := signals[collectionID]
+		if !ok || !ruleMatches(rule.Conditions, bookmark, domain, bookmarkTags) {
+			continue
+		}
+		signal.ruleMatched = true
+	}
+	return nil
+}
+
+// collectFeedbackSignals counts accepted and rejected suggestions per
+// collection. Feedback given for bookmarks from the same domain counts double
+func (s *Service) collectFeedbackSignals(userID uint, domain string, signals map[uint]*collectionSignals) error {
+	var feedback []database.CollectionSuggestionFeedback
+	if err := s.db.Where("user_id = ?", userID).Find(&feedback).Error; err != nil {
+		return fmt.Errorf("failed to load suggestion feedback: %w", err)
+	}
+
+	for _, entry := range feedback {
+		signal, ok := signals[entry.CollectionID]
+		if !ok {
+			continue
+		}
+		weight := 1
+		if domain != "" && entry.Domain == domain {
+			weight = 2
+		}
+		if entry.Accepted {
+			signal.accepted += weight
+		} else {
+			signal.rejected += weight
+		}
+	}
+	return nil
+}
+
+// score combines a collection's signals into a score and the reasons behind it
+func (c *collectionSignals) score(bookmarkTags []string, domainTotal int) (float64, []string) {
+	var score float64
+	reasons := []string{}
+
+	if c.bookmarks > 0 && len(bookmarkTags) > 0 {
+		var share float64
+		for _, tag := range bookmarkTags {
+			share += float64(c.tagCounts[tag]) / float64(c.bookmarks)
+		}
+		if share > 0 {
+			score += tagSignalWeight * share / float64(len(bookmarkTags))
+			reasons = append(reasons, ReasonTags)
+		}
+	}
+	if c.domainHits > 0 && domainTotal > 0 {
+		score += domainSignalWeight * float64(c.domainHits) / float64(domainTotal)
+		reasons = append(reasons, ReasonDomain)
+	}
+	if c.ruleMatched {
+		score += ruleSignalWeight
+		reasons = append(reasons, ReasonRule)
+	}
+	if votes := c.accepted + c.rejected; votes > 0 {
+		// Smoothed so a single answer nudges rather than decides
+		score += feedbackSignalWeight * float64(c.accepted-c.rejected) / float64(votes+2)
+		if c.accepted > c.rejected {
+			reasons = append(reasons, ReasonFeedback)
+		}
+	}
+	return score, reasons
+}
+
+// RecordSuggestionFeedback stores whether the user took a suggested
+// collection. Accepting one also files the bookmark there
+func (s *Service) RecordSuggestionFeedback(userID, bookmarkID uint, req SuggestionFeedbackRequest) error {
+	var bookmark database.Bookmark
+	if err := s.db.Where("id = ? AND user_id = ?", bookmarkID, userID).First(&bookmark).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("bookmark not found")
+		}
+		return fmt.Errorf("failed to get bookmark: %w", err)
+	}
+
+	var collection database.Collection
+	if err := s.db.Where("id = ? AND user_id = ?", req.CollectionID, userID).First(&collection).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("collection not found")
+		}
+		return fmt.Errorf("failed to get collection: %w", err)
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&database.CollectionSuggestionFeedback{
+			UserID:       userID,
+			CollectionID: collection.ID,
+			BookmarkID:   bookmark.ID,
+			Domain:       bookmarkDomain(bookmark.URL),
+			Accepted:     req.Accepted,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to record suggestion feedback: %w", err)
+		}
+		if !req.Accepted {
+			return nil
+		}
+		if err := tx.Model(&collection).Association("Bookmarks").Append(&bookmark); err != nil {
+			return fmt.Errorf("failed to add bookmark to collection: %w", err)
+		}
+		return nil
+	})
+}
+
+// ruleCollectionID reads the collection an add_to_collection action files into
+func ruleCollectionID(actions automation.InterfaceMap) (uint, bool) {
+	switch value := actions["add_to_collection"].(type) {
+	case float64:
+		if value > 0 {
+			return uint(value), true
+		}
+	case string:
+		if id, err := strconv.ParseUint(value, 10, 32); err == nil && id > 0 {
+			return uint(id), true
+		}
+	}
+	return 0, false
+}
+
+// ruleMatches checks a rule's domain, tag, url_contains and title_contains
+// conditions. Conditions it does not understand never match
+func ruleMatches(conditions automation.InterfaceMap, bookmark *database.Bookmark, domain string, bookmarkTags []string) bool {
+	for key, raw := range conditions {
+		value, ok := raw.(string)
+		if !ok || value == "" {
+			return false
+		}
+		value = strings.ToLower(value)
+
+		switch key {
+		case "domain":
+			if domain != value && !strings.HasSuffix(domain, "."+value) {
+				return false
+			}
+		case "tag":
+			if !containsTag(bookmarkTags, value) {
+				return false
+			}
+		case "url_contains":
+			if !strings.Contains(strings.ToLower(bookmark.URL), value) {
+				return false
+			}
+		case "title_contains":
+			if !strings.Contains(strings.ToLower(bookmark.Title), value) {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func containsTag(list []string, tag string) bool {
+	for _, candidate := range list {
+		if candidate == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// bookmarkDomain returns the host of a URL without a leading www.
+func bookmarkDomain(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+}
+
+// SuggestCollections suggests collections for a bookmark
+// @Summary Suggest collections for a bookmark
+// @Tags bookmarks
+// @Produce json
+// @Param id path int true "Bookmark ID"
+// @Param limit query int false "Maximum suggestions"
+// @Success 200 {array} CollectionSuggestion
+// @Router /bookmarks/{id}/suggest-collections [post]
+func (h *Handlers) SuggestCollections(c *gin.Context) {
+	bookmarkID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid bookmark ID", nil)
+		return
+	}
+
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	suggestions, err := h.service.SuggestCollections(userID, uint(bookmarkID), limit)
+	if err != nil {
+		if err.Error() == "bookmark not found" {
+			utils.ErrorResponse(c, http.StatusNotFound, "NOT_FOUND", err.Error(), nil)
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to suggest collections", nil)
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{"suggested_collections": suggestions}, "Collection suggestions retrieved successfully")
+}
+
+// SuggestionFeedback records whether a suggested collection was taken
+// @Summary Give feedback on a collection suggestion
+// @Tags bookmarks
+// @Accept json
+// @Produce json
+// @Param id path int true "Bookmark ID"
+// @Param request body SuggestionFeedbackRequest true "Feedback"
+// @Success 200 {object} map[string]interface{}
+// @Router /bookmarks/{id}/suggest-collections/feedback [post]
+func (h *Handlers) SuggestionFeedback(c *gin.Context) {
+	bookmarkID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid bookmark ID", nil)
+		return
+	}
+
+	var req SuggestionFeedbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid request format", nil)
+		return
+	}
+
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	if err := h.service.RecordSuggestionFeedback(userID, uint(bookmarkID), req); err != nil {
+		if err.Error() == "bookmark not found" || err.Error() == "collection not found" {
+			utils.ErrorResponse(c, http.StatusNotFound, "NOT_FOUND", err.Error(), nil)
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to record feedback", nil)
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{"collection_id": req.CollectionID, "accepted": req.Accepted}, "Feedback recorded successfully")
+}
@@ -0,0 +1,161 @@
+package bookmark
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/internal/automation"
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+func fileBookmark(t *testing.T, db *gorm.DB, url, tagsJSON string, collection *database.Collection) *database.Bookmark {
+	bookmark := &database.Bookmark{UserID: 1, URL: url, Title: url, Tags: tagsJSON}
+	require.NoError(t, db.Create(bookmark).Error)
+	if collection != nil {
+		require.NoError(t, db.Model(collection).Association("Bookmarks").Append(bookmark))
+	}
+	return bookmark
+}
+
+func createCollection(t *testing.T, db *gorm.DB, name string) *database.Collection {
+	collection := &database.Collection{UserID: 1, Name: name, ShareLink: "link-" + name}
+	require.NoError(t, db.Create(collection).Error)
+	return collection
+}
+
+func TestService_SuggestCollections(t *testing.T) {
+	_, db := setupTestRouter(t)
+	service := NewService(db)
+
+	golang := createCollection(t, db, "Go")
+	news := createCollection(t, db, "News")
+	recipes := createCollection(t, db, "Recipes")
+
+	fileBookmark(t, db, "https://go.dev/blog/a", `["golang"]`, golang)
+	fileBookmark(t, db, "https://go.dev/blog/b", `["golang","dev"]`, golang)
+	fileBookmark(t, db, "https://news.ycombinator.com/item?id=1", `["dev"]`, news)
+	fileBookmark(t, db, "https://food.example.com/soup", `["cooking"]`, recipes)
+
+	bookmark := fileBookmark(t, db, "https://www.go.dev/doc", `["golang"]`, nil)
+
+	suggestions, err := service.SuggestCollections(1, bookmark.ID, 0)
+	require.NoError(t, err)
+	require.Len(t, suggestions, 1)
+	assert.Equal(t, golang.ID, suggestions[0].CollectionID)
+	assert.Equal(t, []string{ReasonTags, ReasonDomain}, suggestions[0].Reasons)
+	assert.InDelta(t, 0.8, suggestions[0].Score, 0.001)
+
+	// Collections the bookmark is already in are not suggested
+	require.NoError(t, db.Model(golang).Association("Bookmarks").Append(bookmark))
+	suggestions, err = service.SuggestCollections(1, bookmark.ID, 0)
+	require.NoError(t, err)
+	assert.Empty(t, suggestions)
+
+	_, err = service.SuggestCollections(2, bookmark.ID, 0)
+	assert.EqualError(t, err, "bookmark not found")
+}
+
+func TestService_SuggestCollectionsFromRules(t *testing.T) {
+	_, db := setupTestRouter(t)
+	service := NewService(db)
+
+	reading := createCollection(t, db, "Reading")
+	require.NoError(t, db.Create(&automation.AutomationRule{
+		UserID:     "1",
+		Name:       "Papers",
+		Trigger:    "bookmark_added",
+		Conditions: automation.InterfaceMap{"domain": "arxiv.org", "title_contains": "pdf"},
+		Actions:    automation.InterfaceMap{"add_to_collection": float64(reading.ID)},
+		Active:     true,
+	}).Error)
+
+	paper := fileBookmark(t, db, "https://export.arxiv.org/abs/1234.pdf", `[]`, nil)
+	suggestions, err := service.SuggestCollections(1, paper.ID, 0)
+	require.NoError(t, err)
+	require.Len(t, suggestions, 1)
+	assert.Equal(t, reading.ID, suggestions[0].CollectionID)
+	assert.Equal(t, []string{ReasonRule}, suggestions[0].Reasons)
+
+	other := fileBookmark(t, db, "https://arxiv.org/list/cs", `[]`, nil)
+	suggestions, err = service.SuggestCollections(1, other.ID, 0)
+	require.NoError(t, err)
+	assert.Empty(t, suggestions, "every condition has to match")
+}
+
+func TestService_SuggestionFeedback(t *testing.T) {
+	_, db := setupTestRouter(t)
+	service := NewService(db)
+
+	first := createCollection(t, db, "First")
+	second := createCollection(t, db, "Second")
+	fileBookmark(t, db, "https://example.com/a", `["misc"]`, first)
+	fileBookmark(t, db, "https://example.com/b", `["misc"]`, second)
+
+	bookmark := fileBookmark(t, db, "https://example.com/c", `["misc"]`, nil)
+	suggestions, err := service.SuggestCollections(1, bookmark.ID, 0)
+	require.NoError(t, err)
+	require.Len(t, suggestions, 2)
+	assert.Equal(t, first.ID, suggestions[0].CollectionID, "ties are broken by ID")
+
+	// The user keeps filing example.com bookmarks in the second collection
+	require.NoError(t, service.RecordSuggestionFeedback(1, bookmark.ID, SuggestionFeedbackRequest{CollectionID: first.ID, Accepted: false}))
+	require.NoError(t, service.RecordSuggestionFeedback(1, bookmark.ID, SuggestionFeedbackRequest{CollectionID: second.ID, Accepted: true}))
+
+	var count int64
+	require.NoError(t, db.Table("bookmark_collections").Where("bookmark_id = ? AND collection_id = ?", bookmark.ID, second.ID).Count(&count).Error)
+	assert.Equal(t, int64(1), count, "accepting files the bookmark")
+
+	next := fileBookmark(t, db, "https://example.com/d", `["misc"]`, nil)
+	suggestions, err = service.SuggestCollections(1, next.ID, 0)
+	require.NoError(t, err)
+	require.NotEmpty(t, suggestions)
+	assert.Equal(t, second.ID, suggestions[0].CollectionID)
+	assert.Contains(t, suggestions[0].Reasons, ReasonFeedback)
+
+	err = service.RecordSuggestionFeedback(1, next.ID, SuggestionFeedbackRequest{CollectionID: 999, Accepted: true})
+	assert.EqualError(t, err, "collection not found")
+}
+
+func TestHandlers_SuggestCollections(t *testing.T) {
+	router, db := setupTestRouter(t)
+	golang := createCollection(t, db, "Go")
+	fileBookmark(t, db, "https://go.dev/blog", `["golang"]`, golang)
+
+	body, _ := json.Marshal(CreateBookmarkRequest{URL: "https://go.dev/doc", Title: "Docs", Tags: []string{"golang"}})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/bookmarks", bytes.NewReader(body)))
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var created struct {
+		Data struct {
+			ID                   uint                   `json:"id"`
+			URL                  string                 `json:"url"`
+			SuggestedCollections []CollectionSuggestion `json:"suggested_collections"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Equal(t, "https://go.dev/doc", created.Data.URL)
+	require.Len(t, created.Data.SuggestedCollections, 1)
+	assert.Equal(t, golang.ID, created.Data.SuggestedCollections[0].CollectionID)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/bookmarks/999/suggest-collections", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	body, _ = json.Marshal(SuggestionFeedbackRequest{CollectionID: golang.ID, Accepted: true})
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/bookmarks/2/suggest-collections/feedback", bytes.NewReader(body)))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/bookmarks/2/suggest-collections", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"suggested_collections":[]`, "filed collections are not suggested again")
+}
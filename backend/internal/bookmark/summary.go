@@ -0,0 +1,139 @@
+package bookmark
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/summarize"
+	"bookmark-sync-service/backend/pkg/utils"
+)
+
+// SetSummarizer enables on-demand bookmark summaries
+func (s *Service) SetSummarizer(summarizer summarize.Summarizer) {
+	s.summarizer = summarizer
+}
+
+// Summarize asks the configured provider for a summary of the bookmark and
+// stores it in the bookmark metadata, where search indexing picks it up
+func (s *Service) Summarize(ctx context.Context, bookmarkID, userID uint) (*database.Bookmark, error) {
+	if s.summarizer == nil {
+		return nil, summarize.ErrDisabled
+	}
+
+	bookmark, err := s.GetByID(bookmarkID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	summary, err := s.summarizer.Summarize(ctx, summarize.Request{
+		URL:         bookmark.URL,
+		Title:       bookmark.Title,
+		Description: bookmark.Description,
+		Language:    bookmark.Language,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize bookmark: %w", err)
+	}
+
+	return s.saveSummary(bookmark, summary)
+}
+
+// SetSummary stores a summary generated elsewhere, e.g. while the page was
+// analyzed, in the bookmark metadata where search indexing picks it up
+func (s *Service) SetSummary(bookmarkID, userID uint, summary string) (*database.Bookmark, error) {
+	bookmark, err := s.GetByID(bookmarkID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.saveSummary(bookmark, summary)
+}
+
+// saveSummary stores a summary in the bookmark metadata and queues the
+// bookmark for indexing
+func (s *Service) saveSummary(bookmark *database.Bookmark, summary string) (*database.Bookmark, error) {
+	metadata, err := mergeMetadata(bookmark.Metadata, map[string]interface{}{
+		"summary":       summary,
+		"summarized_at": time.Now().UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Model(bookmark).Update("metadata", metadata).Error; err != nil {
+		return nil, fmt.Errorf("failed to save summary: %w", err)
+	}
+	bookmark.Metadata = metadata
+
+	s.index(bookmark)
+	return bookmark, nil
+}
+
+// mergeMetadata sets keys in a bookmark's JSON metadata, keeping the rest.
+// Metadata that isn't a JSON object is replaced
+func mergeMetadata(raw string, values map[string]interface{}) (string, error) {
+	metadata := map[string]interface{}{}
+	if raw != "" {
+		if err := json.Unmarshal([]byte(raw), &metadata); err != nil || metadata == nil {
+			metadata = map[string]interface{}{}
+		}
+	}
+	for key, value := range values {
+		metadata[key] = value
+	}
+
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+	return string(data), nil
+}
+
+// SummarizeBookmark generates and stores a summary of a bookmark
+// @Summary Summarize bookmark
+// @Description Asks the configured summarization provider for a short summary, stored in the bookmark metadata and indexed for search
+// @Tags bookmarks
+// @Produce json
+// @Param id path int true "Bookmark ID"
+// @Success 200 {object} database.Bookmark
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 502 {object} utils.ErrorResponse "Provider failed"
+// @Failure 503 {object} utils.ErrorResponse "No provider configured"
+// @Router /api/v1/bookmarks/{id}/summarize [post]
+func (h *Handlers) SummarizeBookmark(c *gin.Context) {
+	bookmarkID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid bookmark ID", nil)
+		return
+	}
+
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	bookmark, err := h.service.Summarize(c.Request.Context(), uint(bookmarkID), userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, summarize.ErrDisabled):
+			utils.ErrorResponse(c, http.StatusServiceUnavailable, "SUMMARIZER_DISABLED", err.Error(), nil)
+		case err.Error() == "bookmark not found":
+			utils.ErrorResponse(c, http.StatusNotFound, "NOT_FOUND", err.Error(), nil)
+		default:
+			utils.ErrorResponse(c, http.StatusBadGateway, "SUMMARIZER_FAILED", "Failed to summarize bookmark", nil)
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, bookmark, "Bookmark summarized successfully")
+}
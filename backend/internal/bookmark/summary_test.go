@@ -0,0 +1,81 @@
+package bookmark
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bookmark-sync-service/backend/pkg/summarize"
+)
+
+type stubSummarizer struct {
+	summary string
+	err     error
+	request summarize.Request
+}
+
+func (s *stubSummarizer) Summarize(ctx context.Context, req summarize.Request) (string, error) {
+	s.request = req
+	return s.summary, s.err
+}
+
+func TestBookmarkService_Summarize(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+
+	bookmark, err := service.Create(CreateBookmarkRequest{UserID: 1, URL: "https://go.dev", Title: "Go"})
+	require.NoError(t, err)
+	require.NoError(t, db.Model(bookmark).Update("metadata", `{"content_type":"article"}`).Error)
+
+	_, err = service.Summarize(context.Background(), bookmark.ID, 1)
+	assert.ErrorIs(t, err, summarize.ErrDisabled)
+
+	summarizer := &stubSummarizer{summary: "The Go programming language"}
+	service.SetSummarizer(summarizer)
+
+	summarized, err := service.Summarize(context.Background(), bookmark.ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "https://go.dev", summarizer.request.URL)
+
+	stored, err := service.GetByID(bookmark.ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, summarized.Metadata, stored.Metadata)
+
+	var metadata map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(stored.Metadata), &metadata))
+	assert.Equal(t, "The Go programming language", metadata["summary"])
+	assert.Equal(t, "article", metadata["content_type"])
+	assert.NotEmpty(t, metadata["summarized_at"])
+
+	_, err = service.Summarize(context.Background(), bookmark.ID, 2)
+	assert.EqualError(t, err, "bookmark not found")
+
+	summarizer.err = errors.New("provider down")
+	_, err = service.Summarize(context.Background(), bookmark.ID, 1)
+	assert.ErrorContains(t, err, "provider down")
+}
+
+func TestBookmarkService_SetSummary(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+
+	bookmark, err := service.Create(CreateBookmarkRequest{UserID: 1, URL: "https://go.dev", Title: "Go"})
+	require.NoError(t, err)
+
+	_, err = service.SetSummary(bookmark.ID, 1, "A language for building simple software")
+	require.NoError(t, err)
+
+	stored, err := service.GetByID(bookmark.ID, 1)
+	require.NoError(t, err)
+
+	var metadata map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(stored.Metadata), &metadata))
+	assert.Equal(t, "A language for building simple software", metadata["summary"])
+
+	_, err = service.SetSummary(bookmark.ID, 2, "Not theirs")
+	assert.EqualError(t, err, "bookmark not found")
+}
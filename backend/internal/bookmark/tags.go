@@ -0,0 +1,194 @@
+package bookmark
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/dualwrite"
+	"bookmark-sync-service/backend/pkg/tags"
+)
+
+// RenameTagRequest represents a request to rename a tag and its descendants
+type RenameTagRequest struct {
+	From string `json:"from" binding:"required"`
+	To   string `json:"to" binding:"required"`
+}
+
+// MigrateTagsRequest represents a request to convert flat tags that use a
+// legacy separator (e.g. "dev:go") into namespaced tags ("dev/go")
+type MigrateTagsRequest struct {
+	Separator string `json:"separator" binding:"required"`
+}
+
+// TagUpdateResult reports how many bookmarks a tag operation changed
+type TagUpdateResult struct {
+	UpdatedBookmarks int `json:"updated_bookmarks"`
+}
+
+// GetTagTree returns the user's tags as a namespace tree with bookmark counts
+func (s *Service) GetTagTree(userID uint) ([]*tags.Node, error) {
+	counts, err := s.TagCounts(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return tags.BuildTree(counts), nil
+}
+
+// TagCounts returns the number of the user's bookmarks carrying each tag
+func (s *Service) TagCounts(userID uint) (map[string]int, error) {
+	return dualwrite.Read(context.Background(), s.tagMigration,
+		func(ctx context.Context) (map[string]int, error) { return s.jsonTagCounts(ctx, userID) },
+		func(ctx context.Context) (map[string]int, error) { return s.relationalTagCounts(ctx, userID) },
+		equalTagCounts)
+}
+
+// RenameTag renames a tag for all of the user's bookmarks, cascading to every
+// descendant tag ("dev" -> "code" also turns "dev/go" into "code/go")
+func (s *Service) RenameTag(userID uint, req RenameTagRequest) (*TagUpdateResult, error) {
+	from, to := tags.Normalize(req.From), tags.Normalize(req.To)
+	if from == "" || to == "" {
+		return nil, errors.New("tag names are required")
+	}
+	if tags.Matches(to, from+tags.Wildcard) {
+		return nil, errors.New("cannot move a tag into its own descendant")
+	}
+
+	return s.rewriteTags(userID, func(tag string) string {
+		renamed, _ := tags.Rename(tag, from, to)
+		return renamed
+	})
+}
+
+// MigrateTags converts the user's flat tags that use a legacy separator into
+// namespaced tags
+func (s *Service) MigrateTags(userID uint, req MigrateTagsRequest) (*TagUpdateResult, error) {
+	if strings.TrimSpace(req.Separator) == "" {
+		return nil, errors.New("separator is required")
+	}
+
+	return s.rewriteTags(userID, func(tag string) string {
+		return tags.Migrate(tag, req.Separator)
+	})
+}
+
+// rewriteTags applies fn to every tag of the user's bookmarks in a single
+// transaction and saves the bookmarks whose tags changed
+func (s *Service) rewriteTags(userID uint, fn func(string) string) (*TagUpdateResult, error) {
+	result := &TagUpdateResult{}
+	var changed []uint
+	rewritten := make(map[uint][]string)
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var rows []database.Bookmark
+		if err := tx.Model(&database.Bookmark{}).Select("id, tags").
+			Where("user_id = ?", userID).Find(&rows).Error; err != nil {
+			return fmt.Errorf("failed to load tags: %w", err)
+		}
+
+		for _, row := range rows {
+			var current []string
+			if row.Tags != "" {
+				if err := json.Unmarshal([]byte(row.Tags), &current); err != nil {
+					continue // Leave malformed tag data untouched
+				}
+			}
+
+			updated := make([]string, 0, len(current))
+			for _, tag := range current {
+				updated = append(updated, fn(tag))
+			}
+			updated = tags.NormalizeAll(updated)
+
+			if equalTags(current, updated) {
+				continue
+			}
+
+			tagsJSON, err := json.Marshal(updated)
+			if err != nil {
+				return fmt.Errorf("failed to marshal tags: %w", err)
+			}
+			if err := tx.Model(&database.Bookmark{}).Where("id = ?", row.ID).
+				Update("tags", string(tagsJSON)).Error; err != nil {
+				return fmt.Errorf("failed to update tags: %w", err)
+			}
+			changed = append(changed, row.ID)
+			rewritten[row.ID] = updated
+			result.UpdatedBookmarks++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The relational rows follow the committed JSON tags
+	for _, id := range changed {
+		bookmark := &database.Bookmark{UserID: userID}
+		bookmark.ID = id
+		if err := s.writeTags(bookmark, rewritten[id], func() error { return nil }); err != nil {
+			return nil, err
+		}
+	}
+
+	s.reindex(changed)
+	return result, nil
+}
+
+// tagFilter builds a SQL condition matching bookmarks tagged with any of the
+// comma separated patterns. "dev" matches dev and its children, "dev/*" only children
+func tagFilter(query *gorm.DB, patterns string) *gorm.DB {
+	var conditions []string
+	var args []interface{}
+
+	for _, pattern := range strings.Split(patterns, ",") {
+		base, descendantsOnly := tags.ParsePattern(pattern)
+		if base == "" {
+			continue
+		}
+
+		base = database.EscapeLike(base)
+		if !descendantsOnly {
+			conditions = append(conditions, `CAST(tags AS TEXT) LIKE ? ESCAPE '\'`)
+			args = append(args, `%"`+base+`"%`)
+		}
+		conditions = append(conditions, `CAST(tags AS TEXT) LIKE ? ESCAPE '\'`)
+		args = append(args, `%"`+base+tags.Separator+`%`)
+	}
+
+	if len(conditions) == 0 {
+		return query
+	}
+	return query.Where("("+strings.Join(conditions, " OR ")+")", args...)
+}
+
+// decodeTags parses the JSON tag array stored on a bookmark
+func decodeTags(raw string) []string {
+	var list []string
+	if raw == "" {
+		return list
+	}
+	if err := json.Unmarshal([]byte(raw), &list); err != nil {
+		return nil
+	}
+	return tags.NormalizeAll(list)
+}
+
+func equalTags(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,151 @@
+package bookmark
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+func createTaggedBookmarks(t *testing.T, service *Service, tagSets ...[]string) []*database.Bookmark {
+	bookmarks := make([]*database.Bookmark, 0, len(tagSets))
+	for _, tagSet := range tagSets {
+		bookmark, err := service.Create(CreateBookmarkRequest{
+			UserID: 1,
+			URL:    "https://example.com",
+			Title:  "Example",
+			Tags:   tagSet,
+		})
+		require.NoError(t, err)
+		bookmarks = append(bookmarks, bookmark)
+	}
+	return bookmarks
+}
+
+func TestBookmarkService_ListByHierarchicalTag(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+
+	createTaggedBookmarks(t, service,
+		[]string{"dev"},
+		[]string{"dev/go"},
+		[]string{"dev/go/testing"},
+		[]string{"devops"},
+	)
+
+	tests := []struct {
+		name  string
+		tags  string
+		count int64
+	}{
+		{name: "parent matches children", tags: "dev", count: 3},
+		{name: "wildcard matches descendants only", tags: "dev/*", count: 2},
+		{name: "leaf tag", tags: "dev/go/testing", count: 1},
+		{name: "multiple patterns", tags: "dev/go,devops", count: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, total, err := service.List(ListBookmarksRequest{UserID: 1, Tags: tt.tags})
+			require.NoError(t, err)
+			assert.Equal(t, tt.count, total)
+		})
+	}
+}
+
+func TestBookmarkService_ListByTagOperator(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+
+	createTaggedBookmarks(t, service,
+		[]string{"dev/go"},
+		[]string{"dev/go", "news"},
+		[]string{"dev_ops"},
+		[]string{"devXops"},
+	)
+
+	tests := []struct {
+		name   string
+		search string
+		count  int64
+	}{
+		{name: "tag term", search: "tag:dev/*", count: 2},
+		{name: "terms must all match", search: "tag:dev tag:news", count: 1},
+		{name: "with search text", search: "example TAG:news", count: 1},
+		{name: "wildcards in tags match literally", search: "tag:dev_ops", count: 1},
+		{name: "wildcards in search text match literally", search: "exam%le", count: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, total, err := service.List(ListBookmarksRequest{UserID: 1, Search: tt.search})
+			require.NoError(t, err)
+			assert.Equal(t, tt.count, total)
+		})
+	}
+}
+
+func TestBookmarkService_GetTagTree(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+
+	createTaggedBookmarks(t, service,
+		[]string{"dev/go", "news"},
+		[]string{"dev/go", "dev/rust"},
+	)
+
+	tree, err := service.GetTagTree(1)
+	require.NoError(t, err)
+	require.Len(t, tree, 2)
+
+	assert.Equal(t, "dev", tree[0].Path)
+	assert.Equal(t, 3, tree[0].TotalCount)
+	require.Len(t, tree[0].Children, 2)
+	assert.Equal(t, "dev/go", tree[0].Children[0].Path)
+	assert.Equal(t, 2, tree[0].Children[0].Count)
+	assert.Equal(t, "news", tree[1].Path)
+}
+
+func TestBookmarkService_RenameTag(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+
+	bookmarks := createTaggedBookmarks(t, service,
+		[]string{"dev", "dev/go"},
+		[]string{"devops"},
+	)
+
+	result, err := service.RenameTag(1, RenameTagRequest{From: "dev", To: "code"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.UpdatedBookmarks)
+
+	renamed, err := service.GetByID(bookmarks[0].ID, 1)
+	require.NoError(t, err)
+	assert.JSONEq(t, `["code", "code/go"]`, renamed.Tags)
+
+	untouched, err := service.GetByID(bookmarks[1].ID, 1)
+	require.NoError(t, err)
+	assert.JSONEq(t, `["devops"]`, untouched.Tags)
+
+	_, err = service.RenameTag(1, RenameTagRequest{From: "code", To: "code/sub"})
+	assert.EqualError(t, err, "cannot move a tag into its own descendant")
+}
+
+func TestBookmarkService_MigrateTags(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+
+	bookmarks := createTaggedBookmarks(t, service,
+		[]string{"dev:go", "dev/go", "news"},
+	)
+
+	result, err := service.MigrateTags(1, MigrateTagsRequest{Separator: ":"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.UpdatedBookmarks)
+
+	migrated, err := service.GetByID(bookmarks[0].ID, 1)
+	require.NoError(t, err)
+	assert.JSONEq(t, `["dev/go", "news"]`, migrated.Tags)
+}
@@ -0,0 +1,30 @@
+package bookmark
+
+import (
+	"context"
+
+	"bookmark-sync-service/backend/pkg/urlnorm"
+)
+
+// URLRules returns the URL cleanup rules of a user, built-in defaults included
+type URLRules interface {
+	Rules(ctx context.Context, userID uint) ([]urlnorm.Rule, error)
+}
+
+// SetURLRules makes the service clean up URLs as bookmarks are saved
+func (s *Service) SetURLRules(rules URLRules) {
+	s.urlRules = rules
+}
+
+// cleanURL applies the user's rules to a URL about to be saved. Without
+// rules, or when they can't be loaded, the URL is saved as given
+func (s *Service) cleanURL(userID uint, rawURL string) string {
+	if s.urlRules == nil {
+		return rawURL
+	}
+	rules, err := s.urlRules.Rules(context.Background(), userID)
+	if err != nil {
+		return rawURL
+	}
+	return urlnorm.Clean(rawURL, rules)
+}
@@ -0,0 +1,31 @@
+package bookmark
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bookmark-sync-service/backend/pkg/urlnorm"
+)
+
+type stubURLRules []urlnorm.Rule
+
+func (r stubURLRules) Rules(context.Context, uint) ([]urlnorm.Rule, error) {
+	return r, nil
+}
+
+func TestBookmarkService_URLRules(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+	service.SetURLRules(stubURLRules(urlnorm.WithDefaults([]urlnorm.Rule{{Domain: "example.com", Strip: []string{"ref"}}})))
+
+	created, err := service.Create(CreateBookmarkRequest{UserID: 1, URL: "https://example.com/post?id=1&ref=hn&utm_source=x", Title: "Post"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/post?id=1", created.URL)
+
+	updated, err := service.Update(UpdateBookmarkRequest{ID: created.ID, UserID: 1, URL: "http://youtube.com/watch?v=abc&t=30&si=x"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://youtube.com/watch?v=abc&t=30", updated.URL)
+}
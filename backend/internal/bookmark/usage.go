@@ -0,0 +1,19 @@
+package bookmark
+
+import "bookmark-sync-service/backend/internal/metering"
+
+// UsageMeter counts a user's use of metered features
+type UsageMeter interface {
+	Record(userID uint, metric string, amount int64)
+}
+
+// SetUsageMeter makes saving a bookmark count towards the user's usage
+func (s *Service) SetUsageMeter(meter UsageMeter) {
+	s.usage = meter
+}
+
+func (s *Service) meterCreated(userID uint) {
+	if s.usage != nil {
+		s.usage.Record(userID, metering.MetricBookmarksCreated, 1)
+	}
+}
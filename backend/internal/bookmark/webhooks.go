@@ -0,0 +1,42 @@
+package bookmark
+
+import (
+	"context"
+	"strconv"
+
+	"go.uber.org/zap"
+
+	"bookmark-sync-service/backend/internal/automation"
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+// WebhookTrigger delivers events to a user's webhook endpoints, including
+// the subscriptions of the user's OAuth apps
+type WebhookTrigger interface {
+	TriggerWebhook(ctx context.Context, event automation.WebhookEvent, userID string, data interface{}) error
+}
+
+// SetWebhooks makes the service report created, updated and deleted
+// bookmarks to the owner's webhooks
+func (s *Service) SetWebhooks(webhooks WebhookTrigger) {
+	s.webhooks = webhooks
+}
+
+// triggerWebhook reports a bookmark change. Delivery failures never fail
+// the change itself
+func (s *Service) triggerWebhook(event automation.WebhookEvent, bookmark *database.Bookmark) {
+	if s.webhooks == nil {
+		return
+	}
+
+	data := map[string]interface{}{"id": bookmark.ID}
+	if event != automation.WebhookEventBookmarkDeleted {
+		data["url"] = bookmark.URL
+		data["title"] = bookmark.Title
+		data["description"] = bookmark.Description
+		data["tags"] = decodeTags(bookmark.Tags)
+	}
+	if err := s.webhooks.TriggerWebhook(context.Background(), event, strconv.FormatUint(uint64(bookmark.UserID), 10), data); err != nil {
+		s.logger.Warn("Failed to trigger bookmark webhook", zap.String("event", string(event)), zap.Uint("bookmark_id", bookmark.ID), zap.Error(err))
+	}
+}
@@ -0,0 +1,52 @@
+package bookmark
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bookmark-sync-service/backend/internal/automation"
+)
+
+// recordingWebhooks keeps the events sent to the owner's webhooks
+type recordingWebhooks struct {
+	events []automation.WebhookEvent
+	users  []string
+	data   []map[string]interface{}
+}
+
+func (r *recordingWebhooks) TriggerWebhook(ctx context.Context, event automation.WebhookEvent, userID string, data interface{}) error {
+	r.events = append(r.events, event)
+	r.users = append(r.users, userID)
+	r.data = append(r.data, data.(map[string]interface{}))
+	return nil
+}
+
+func TestBookmarkService_TriggersWebhooks(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+	webhooks := &recordingWebhooks{}
+	service.SetWebhooks(webhooks)
+
+	bookmark, err := service.Create(CreateBookmarkRequest{UserID: 1, URL: "https://example.com/article", Title: "Article", Tags: []string{"go"}})
+	require.NoError(t, err)
+	_, err = service.Update(UpdateBookmarkRequest{ID: bookmark.ID, UserID: 1, Title: "Renamed"})
+	require.NoError(t, err)
+	require.NoError(t, service.Delete(bookmark.ID, 1))
+
+	assert.Equal(t, []automation.WebhookEvent{
+		automation.WebhookEventBookmarkCreated,
+		automation.WebhookEventBookmarkUpdated,
+		automation.WebhookEventBookmarkDeleted,
+	}, webhooks.events)
+	assert.Equal(t, []string{"1", "1", "1"}, webhooks.users)
+	assert.Equal(t, []string{"go"}, webhooks.data[0]["tags"])
+	assert.Equal(t, "Renamed", webhooks.data[1]["title"])
+	assert.Equal(t, map[string]interface{}{"id": bookmark.ID}, webhooks.data[2])
+
+	// Failed changes trigger nothing
+	assert.Error(t, service.Delete(bookmark.ID, 1))
+	assert.Len(t, webhooks.events, 3)
+}
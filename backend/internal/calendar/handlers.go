@@ -0,0 +1,65 @@
+package calendar
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"bookmark-sync-service/backend/pkg/utils"
+)
+
+// Handler serves the calendar heat map data
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new calendar handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes registers the calendar routes
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	stats := router.Group("/stats")
+	stats.GET("/calendar", h.GetCalendar)
+}
+
+// GetCalendar returns the bookmarks saved and read per day of a year
+// @Summary Get calendar heat map data
+// @Description Per-day counts in UTC, aggregated by the worker every few minutes; days without activity are left out
+// @Tags stats
+// @Produce json
+// @Param year query int false "Year, defaults to the current one"
+// @Success 200 {object} Calendar
+// @Failure 400 {object} utils.ErrorResponse
+// @Router /stats/calendar [get]
+func (h *Handler) GetCalendar(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	year := h.service.now().UTC().Year()
+	if raw := c.Query("year"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_YEAR", "Invalid year", nil)
+			return
+		}
+		year = parsed
+	}
+
+	calendar, err := h.service.Year(c.Request.Context(), userID, year)
+	if err != nil {
+		if errors.Is(err, ErrInvalidYear) {
+			utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_YEAR", "Invalid year", nil)
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to get calendar", nil)
+		return
+	}
+	utils.SuccessResponse(c, calendar, "Calendar retrieved")
+}
@@ -0,0 +1,269 @@
+// Package calendar serves per-day counts of bookmarks saved and read for a
+// contribution-style heat map. The counts are aggregated into daily stats
+// by the worker, so a year is read from at most 366 rows
+package calendar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+// Sources aggregated into daily stats, named by their table
+const (
+	sourceBookmarks = "bookmarks"
+	// sourceBehaviors holds reading sessions, recorded as view behaviors
+	// with a duration. It belongs to the community feature and may not be
+	// migrated
+	sourceBehaviors = "user_behaviors"
+)
+
+const (
+	readAction       = "view"
+	defaultBatchSize = 1000
+	dayLayout        = "2006-01-02"
+)
+
+// ErrInvalidYear is returned for years outside the calendar's range
+var ErrInvalidYear = errors.New("invalid year")
+
+// Day is the activity of one day with any
+type Day struct {
+	Date  string `json:"date"` // YYYY-MM-DD, UTC
+	Saved int    `json:"saved"`
+	Read  int    `json:"read"`
+}
+
+// Calendar is a year of activity. Days without any are left out
+type Calendar struct {
+	Year       int        `json:"year"`
+	Days       []Day      `json:"days"`
+	TotalSaved int        `json:"total_saved"`
+	TotalRead  int        `json:"total_read"`
+	MaxSaved   int        `json:"max_saved"` // busiest day, to scale the heat map
+	MaxRead    int        `json:"max_read"`
+	AsOf       *time.Time `json:"as_of,omitempty"` // last aggregation; later activity is not counted yet
+}
+
+// Service aggregates and serves daily bookmark activity
+type Service struct {
+	db  *gorm.DB
+	cfg config.CalendarConfig
+	now func() time.Time
+}
+
+// NewService creates a new calendar service
+func NewService(cfg config.CalendarConfig, db *gorm.DB) *Service {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultBatchSize
+	}
+	return &Service{db: db, cfg: cfg, now: time.Now}
+}
+
+// Year returns a user's activity per day of a year
+func (s *Service) Year(ctx context.Context, userID uint, year int) (*Calendar, error) {
+	if year < 1970 || year > s.now().UTC().Year()+1 {
+		return nil, ErrInvalidYear
+	}
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	var stats []database.DailyStat
+	if err := s.db.WithContext(ctx).
+		Where("user_id = ? AND day >= ? AND day < ?", userID, start, start.AddDate(1, 0, 0)).
+		Where("saved > 0 OR read > 0").
+		Order("day").Find(&stats).Error; err != nil {
+		return nil, fmt.Errorf("failed to load daily stats: %w", err)
+	}
+
+	calendar := &Calendar{Year: year, Days: make([]Day, 0, len(stats))}
+	for _, stat := range stats {
+		calendar.Days = append(calendar.Days, Day{Date: stat.Day.UTC().Format(dayLayout), Saved: stat.Saved, Read: stat.Read})
+		calendar.TotalSaved += stat.Saved
+		calendar.TotalRead += stat.Read
+		calendar.MaxSaved = max(calendar.MaxSaved, stat.Saved)
+		calendar.MaxRead = max(calendar.MaxRead, stat.Read)
+	}
+
+	cursor, err := s.cursor(s.db.WithContext(ctx), sourceBookmarks)
+	if err != nil {
+		return nil, err
+	}
+	if !cursor.UpdatedAt.IsZero() {
+		calendar.AsOf = &cursor.UpdatedAt
+	}
+	return calendar, nil
+}
+
+// dayKey is a day of a user's activity to recount
+type dayKey struct {
+	userID uint
+	day    time.Time
+}
+
+// Refresh aggregates the bookmarks and reading sessions added since the
+// last run into daily stats and returns how many days it recounted. Days
+// touched by new rows are recounted from their source rows, so reading the
+// same bookmark twice on a day counts once
+func (s *Service) Refresh(ctx context.Context) (int, error) {
+	behaviors := s.db.Migrator().HasTable(sourceBehaviors)
+
+	recounted := map[dayKey]bool{}
+	for {
+		if err := ctx.Err(); err != nil {
+			return len(recounted), err
+		}
+
+		var more bool
+		err := database.WithTransaction(ctx, s.db, func(tx *gorm.DB) error {
+			dirty := map[dayKey]bool{}
+
+			bookmarksCursor, full, err := s.newBookmarks(tx, dirty)
+			if err != nil {
+				return err
+			}
+			more = full
+			cursors := []database.DailyStatCursor{bookmarksCursor}
+
+			if behaviors {
+				behaviorsCursor, full, err := s.newReads(tx, dirty)
+				if err != nil {
+					return err
+				}
+				more = more || full
+				cursors = append(cursors, behaviorsCursor)
+			}
+
+			for key := range dirty {
+				if err := s.recount(tx, key, behaviors); err != nil {
+					return err
+				}
+				recounted[key] = true
+			}
+			for _, cursor := range cursors {
+				if err := tx.Save(&cursor).Error; err != nil {
+					return fmt.Errorf("failed to save aggregation cursor: %w", err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return len(recounted), err
+		}
+		if !more {
+			return len(recounted), nil
+		}
+	}
+}
+
+// cursor loads how far a source was aggregated
+func (s *Service) cursor(tx *gorm.DB, source string) (database.DailyStatCursor, error) {
+	var cursors []database.DailyStatCursor
+	if err := tx.Where("source = ?", source).Limit(1).Find(&cursors).Error; err != nil {
+		return database.DailyStatCursor{}, fmt.Errorf("failed to load aggregation cursor: %w", err)
+	}
+	if len(cursors) == 0 {
+		return database.DailyStatCursor{Source: source}, nil
+	}
+	return cursors[0], nil
+}
+
+// newBookmarks marks the days of the bookmarks saved since the cursor,
+// including those deleted since, and reports whether the batch was full
+func (s *Service) newBookmarks(tx *gorm.DB, dirty map[dayKey]bool) (database.DailyStatCursor, bool, error) {
+	cursor, err := s.cursor(tx, sourceBookmarks)
+	if err != nil {
+		return cursor, false, err
+	}
+
+	var bookmarks []database.Bookmark
+	if err := tx.Unscoped().Select("id", "user_id", "created_at").
+		Where("id > ?", cursor.LastID).Order("id").Limit(s.cfg.BatchSize).
+		Find(&bookmarks).Error; err != nil {
+		return cursor, false, fmt.Errorf("failed to load new bookmarks: %w", err)
+	}
+	for _, bookmark := range bookmarks {
+		dirty[dayKey{userID: bookmark.UserID, day: truncateDay(bookmark.CreatedAt)}] = true
+		cursor.LastID = bookmark.ID
+	}
+	return cursor, len(bookmarks) == s.cfg.BatchSize, nil
+}
+
+// newReads marks the days of the reading sessions recorded since the
+// cursor and reports whether the batch was full
+func (s *Service) newReads(tx *gorm.DB, dirty map[dayKey]bool) (database.DailyStatCursor, bool, error) {
+	cursor, err := s.cursor(tx, sourceBehaviors)
+	if err != nil {
+		return cursor, false, err
+	}
+
+	var rows []struct {
+		ID         uint
+		UserID     string
+		ActionType string
+		Duration   int
+		CreatedAt  time.Time
+	}
+	if err := tx.Table(sourceBehaviors).Select("id", "user_id", "action_type", "duration", "created_at").
+		Where("id > ?", cursor.LastID).Order("id").Limit(s.cfg.BatchSize).
+		Scan(&rows).Error; err != nil {
+		return cursor, false, fmt.Errorf("failed to load new behaviors: %w", err)
+	}
+	for _, row := range rows {
+		cursor.LastID = row.ID
+		if row.ActionType != readAction || row.Duration <= 0 {
+			continue
+		}
+		userID, err := strconv.ParseUint(row.UserID, 10, 32)
+		if err != nil {
+			continue
+		}
+		dirty[dayKey{userID: uint(userID), day: truncateDay(row.CreatedAt)}] = true
+	}
+	return cursor, len(rows) == s.cfg.BatchSize, nil
+}
+
+// recount stores a user's counts of one day, counted from the source rows
+func (s *Service) recount(tx *gorm.DB, key dayKey, behaviors bool) error {
+	end := key.day.AddDate(0, 0, 1)
+	stat := database.DailyStat{UserID: key.userID, Day: key.day}
+
+	var saved int64
+	if err := tx.Unscoped().Model(&database.Bookmark{}).
+		Where("user_id = ? AND created_at >= ? AND created_at < ?", key.userID, key.day, end).
+		Count(&saved).Error; err != nil {
+		return fmt.Errorf("failed to count saved bookmarks: %w", err)
+	}
+	stat.Saved = int(saved)
+
+	if behaviors {
+		var read int64
+		if err := tx.Table(sourceBehaviors).
+			Where("user_id = ? AND action_type = ? AND duration > 0 AND created_at >= ? AND created_at < ? AND deleted_at IS NULL",
+				strconv.FormatUint(uint64(key.userID), 10), readAction, key.day, end).
+			Distinct("bookmark_id").Count(&read).Error; err != nil {
+			return fmt.Errorf("failed to count read bookmarks: %w", err)
+		}
+		stat.Read = int(read)
+	}
+
+	if err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "day"}},
+		DoUpdates: clause.AssignmentColumns([]string{"saved", "read", "updated_at"}),
+	}).Create(&stat).Error; err != nil {
+		return fmt.Errorf("failed to save daily stats: %w", err)
+	}
+	return nil
+}
+
+func truncateDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,133 @@
+package calendar
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bookmark-sync-service/backend/internal/community"
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+func TestCalendar(t *testing.T) {
+	db, err := database.SetupTestDB()
+	require.NoError(t, err)
+	t.Cleanup(func() { database.CleanupTestDB(db) })
+	require.NoError(t, community.AutoMigrate(db))
+
+	user := database.User{Email: "reader@example.com", Username: "reader", SupabaseID: "reader"}
+	other := database.User{Email: "other@example.com", Username: "other", SupabaseID: "other"}
+	require.NoError(t, db.Create(&user).Error)
+	require.NoError(t, db.Create(&other).Error)
+
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	// Small batches make a refresh take several transactions
+	service := NewService(config.CalendarConfig{BatchSize: 2}, db)
+	service.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	monday := time.Date(2026, 10, 12, 9, 0, 0, 0, time.UTC)
+	tuesday := monday.AddDate(0, 0, 1)
+	save := func(owner database.User, at time.Time) database.Bookmark {
+		bookmark := database.Bookmark{UserID: owner.ID, URL: "https://example.com/" + at.Format(time.RFC3339Nano)}
+		bookmark.CreatedAt = at
+		require.NoError(t, db.Create(&bookmark).Error)
+		return bookmark
+	}
+	read := func(bookmark database.Bookmark, action string, seconds int, at time.Time) {
+		require.NoError(t, db.Create(&community.UserBehavior{
+			UserID:     strconv.FormatUint(uint64(bookmark.UserID), 10),
+			BookmarkID: bookmark.ID,
+			ActionType: action,
+			Duration:   seconds,
+			CreatedAt:  at,
+		}).Error)
+	}
+
+	essay := save(user, monday)
+	paper := save(user, monday.Add(time.Hour))
+	deleted := save(user, monday.Add(2*time.Hour))
+	require.NoError(t, db.Delete(&deleted).Error)
+	save(user, time.Date(2025, 12, 31, 23, 0, 0, 0, time.UTC))
+	save(other, monday)
+	read(essay, "view", 300, monday.Add(time.Hour))
+	read(essay, "view", 120, monday.Add(3*time.Hour)) // the same bookmark again counts once
+	read(paper, "view", 600, tuesday)
+	read(paper, "click", 0, tuesday) // not a reading session
+
+	days, err := service.Refresh(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 4, days)
+
+	calendar, err := service.Year(ctx, user.ID, 2026)
+	require.NoError(t, err)
+	assert.Equal(t, []Day{
+		{Date: "2026-10-12", Saved: 3, Read: 1},
+		{Date: "2026-10-13", Saved: 0, Read: 1},
+	}, calendar.Days)
+	assert.Equal(t, 3, calendar.TotalSaved)
+	assert.Equal(t, 2, calendar.TotalRead)
+	assert.Equal(t, 3, calendar.MaxSaved)
+	assert.Equal(t, 1, calendar.MaxRead)
+	assert.NotNil(t, calendar.AsOf)
+
+	previous, err := service.Year(ctx, user.ID, 2025)
+	require.NoError(t, err)
+	assert.Equal(t, []Day{{Date: "2025-12-31", Saved: 1}}, previous.Days)
+
+	// Only days with new rows are recounted
+	days, err = service.Refresh(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, days)
+
+	later := save(user, tuesday.Add(time.Hour))
+	read(later, "view", 60, tuesday.Add(2*time.Hour))
+	read(essay, "view", 60, monday.Add(5*time.Hour))
+	days, err = service.Refresh(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, days)
+
+	calendar, err = service.Year(ctx, user.ID, 2026)
+	require.NoError(t, err)
+	assert.Equal(t, []Day{
+		{Date: "2026-10-12", Saved: 3, Read: 1},
+		{Date: "2026-10-13", Saved: 1, Read: 2},
+	}, calendar.Days)
+
+	_, err = service.Year(ctx, user.ID, 1900)
+	assert.ErrorIs(t, err, ErrInvalidYear)
+	_, err = service.Year(ctx, user.ID, 2030)
+	assert.ErrorIs(t, err, ErrInvalidYear)
+
+	// The handler defaults to the current year
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", strconv.FormatUint(uint64(user.ID), 10))
+		c.Next()
+	})
+	NewHandler(service).RegisterRoutes(router.Group("/api/v1"))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/stats/calendar", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var body struct {
+		Data Calendar `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, 2026, body.Data.Year)
+	assert.Len(t, body.Data.Days, 2)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/stats/calendar?year=abc", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
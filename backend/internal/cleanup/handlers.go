@@ -0,0 +1,151 @@
+package cleanup
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"bookmark-sync-service/backend/pkg/utils"
+)
+
+// Handler serves the spring-cleaning report and actions
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new cleanup handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes registers the routes on an authenticated group
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	cleaning := router.Group("/maintenance/spring-cleaning")
+	{
+		cleaning.GET("", h.GetReport)
+		cleaning.POST("/actions", h.Apply)
+		cleaning.GET("/reminder", h.GetReminder)
+		cleaning.PUT("/reminder", h.SetReminder)
+	}
+}
+
+// ReminderRequest turns the quarterly reminder on or off
+type ReminderRequest struct {
+	Enabled *bool `json:"enabled" binding:"required"`
+}
+
+// GetReport returns the user's cleanup candidates
+// @Summary Get spring-cleaning candidates
+// @Description Bookmarks worth cleaning up, grouped by reason (broken, duplicate, unread, never_visited) and ranked by a staleness score from 0 to 100. Archived bookmarks are left out
+// @Tags maintenance
+// @Produce json
+// @Param limit query int false "Candidates listed per reason (default 20)"
+// @Success 200 {object} Report
+// @Router /maintenance/spring-cleaning [get]
+func (h *Handler) GetReport(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	report, err := h.service.Report(c.Request.Context(), userID, limit)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, report, "Spring-cleaning candidates retrieved")
+}
+
+// Apply archives or deletes candidates in bulk
+// @Summary Archive or delete cleanup candidates
+// @Description Archiving flags bookmarks archived and takes them off the reading list; deleting moves them to the trash
+// @Tags maintenance
+// @Accept json
+// @Produce json
+// @Param request body ActionRequest true "Action and bookmarks"
+// @Success 200 {object} ActionResult
+// @Failure 400 {object} utils.ErrorResponse
+// @Router /maintenance/spring-cleaning/actions [post]
+func (h *Handler) Apply(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+	var req ActionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid request data", nil)
+		return
+	}
+
+	result, err := h.service.Apply(c.Request.Context(), userID, req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, result, "Cleanup action applied")
+}
+
+// GetReminder returns the user's quarterly reminder setting
+// @Summary Get spring-cleaning reminder
+// @Tags maintenance
+// @Produce json
+// @Success 200 {object} Reminder
+// @Router /maintenance/spring-cleaning/reminder [get]
+func (h *Handler) GetReminder(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	reminder, err := h.service.GetReminder(c.Request.Context(), userID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, reminder, "Cleanup reminder retrieved")
+}
+
+// SetReminder turns the user's quarterly reminder on or off
+// @Summary Set spring-cleaning reminder
+// @Description While enabled, a notification summing up the cleanup candidates is sent at the start of each quarter
+// @Tags maintenance
+// @Accept json
+// @Produce json
+// @Param request body ReminderRequest true "Reminder setting"
+// @Success 200 {object} Reminder
+// @Failure 400 {object} utils.ErrorResponse
+// @Router /maintenance/spring-cleaning/reminder [put]
+func (h *Handler) SetReminder(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+	var req ReminderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid request data", nil)
+		return
+	}
+
+	reminder, err := h.service.SetReminder(c.Request.Context(), userID, *req.Enabled)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, reminder, "Cleanup reminder updated")
+}
+
+func (h *Handler) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidAction), errors.Is(err, ErrInvalidBookmarks):
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error(), nil)
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to process cleanup request", nil)
+	}
+}
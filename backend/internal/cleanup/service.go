@@ -0,0 +1,563 @@
+// Package cleanup scores how stale each of a user's bookmarks is and lists
+// the stalest as spring-cleaning candidates, grouped by why, for the user
+// to archive or delete in bulk. The worker reminds users who opt in at the
+// start of each quarter
+package cleanup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/internal/reading"
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/urlnorm"
+)
+
+// Reasons a bookmark is a cleanup candidate
+const (
+	ReasonBroken       = "broken"        // the latest link check failed
+	ReasonDuplicate    = "duplicate"     // superseded by another bookmark of the same page
+	ReasonUnread       = "unread"        // on the reading list and left unopened
+	ReasonNeverVisited = "never_visited" // never opened since it was saved
+)
+
+// reasonOrder is the order of the groups of a report
+var reasonOrder = []string{ReasonBroken, ReasonDuplicate, ReasonUnread, ReasonNeverVisited}
+
+// reasonWeights are the staleness points each reason adds; time since the
+// last visit adds up to maxIdlePoints more
+var reasonWeights = map[string]int{
+	ReasonBroken:       40,
+	ReasonDuplicate:    30,
+	ReasonUnread:       20,
+	ReasonNeverVisited: 20,
+}
+
+// Bulk actions on candidates
+const (
+	ActionArchive = "archive" // flags the bookmark archived and takes it off the reading list
+	ActionDelete  = "delete"
+)
+
+const (
+	pointsPerIdleYear = 5
+	maxIdlePoints     = 10
+	maxScore          = 100
+
+	defaultLimit = 20
+	// MaxActionBookmarks bounds the bookmarks of one bulk action
+	MaxActionBookmarks = 500
+
+	// ReminderNotification is the type of the reminder notification
+	ReminderNotification = "spring_cleaning_reminder"
+)
+
+// brokenStatuses are the link check results counting as broken
+var brokenStatuses = map[string]bool{
+	string(database.LinkStatusBroken):  true,
+	string(database.LinkStatusTimeout): true,
+}
+
+// visitActions are the behaviors counting as the user opening a bookmark
+var visitActions = []string{"view", "click"}
+
+var (
+	// ErrInvalidAction is returned for actions other than archive and delete
+	ErrInvalidAction = errors.New("action must be archive or delete")
+	// ErrInvalidBookmarks is returned for an empty or too long bookmark list
+	ErrInvalidBookmarks = fmt.Errorf("bookmark_ids must list 1 to %d bookmarks", MaxActionBookmarks)
+)
+
+// Candidate is a bookmark worth cleaning up
+type Candidate struct {
+	BookmarkID    uint       `json:"bookmark_id"`
+	Title         string     `json:"title"`
+	URL           string     `json:"url"`
+	Score         int        `json:"score"` // staleness from 0 to 100
+	Reasons       []string   `json:"reasons"`
+	Status        string     `json:"status,omitempty"` // latest link check
+	SavedAt       time.Time  `json:"saved_at"`
+	LastVisitedAt *time.Time `json:"last_visited_at,omitempty"`
+	SupersededBy  uint       `json:"superseded_by,omitempty"` // the duplicate that is kept
+}
+
+// Group is the candidates sharing a reason, stalest first
+type Group struct {
+	Reason     string      `json:"reason"`
+	Count      int         `json:"count"` // all candidates with the reason, listed or not
+	Candidates []Candidate `json:"candidates"`
+}
+
+// Report is the spring-cleaning candidates of a user. A candidate with
+// several reasons is listed in each of their groups
+type Report struct {
+	Total       int       `json:"total"` // distinct candidates
+	Groups      []Group   `json:"groups"`
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+// ActionRequest archives or deletes candidates
+type ActionRequest struct {
+	Action      string `json:"action" binding:"required"`
+	BookmarkIDs []uint `json:"bookmark_ids" binding:"required"`
+}
+
+// ActionResult is how many of the listed bookmarks an action changed;
+// bookmarks of other users or already deleted are left out
+type ActionResult struct {
+	Action   string `json:"action"`
+	Affected int    `json:"affected"`
+}
+
+// Reminder is a user's quarterly reminder setting
+type Reminder struct {
+	Enabled    bool       `json:"enabled"`
+	LastSentAt *time.Time `json:"last_sent_at,omitempty"`
+	NextAt     *time.Time `json:"next_at,omitempty"` // start of the next quarter while enabled
+}
+
+// ReminderMessage tells a user's clients there is spring cleaning to do
+type ReminderMessage struct {
+	Type    string         `json:"type"`
+	Total   int            `json:"total"`
+	Reasons map[string]int `json:"reasons"`
+}
+
+// SearchIndexer keeps the search index in step with archived and deleted
+// bookmarks
+type SearchIndexer interface {
+	QueueBookmark(bookmark *database.Bookmark)
+	QueueBookmarkDelete(id uint)
+}
+
+// NotificationPublisher publishes a notification on a user's channel
+type NotificationPublisher interface {
+	PublishNotification(ctx context.Context, userID string, notification interface{}) error
+}
+
+// Service finds spring-cleaning candidates and sends the reminders
+type Service struct {
+	cfg           config.CleanupConfig
+	db            *gorm.DB
+	indexer       SearchIndexer
+	notifications NotificationPublisher
+	logger        *zap.Logger
+	now           func() time.Time
+}
+
+// NewService creates a new cleanup service
+func NewService(cfg config.CleanupConfig, db *gorm.DB, logger *zap.Logger) *Service {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &Service{cfg: cfg, db: db, logger: logger, now: time.Now}
+}
+
+// SetIndexer re-indexes archived bookmarks and drops deleted ones
+func (s *Service) SetIndexer(indexer SearchIndexer) {
+	s.indexer = indexer
+}
+
+// SetNotifier sends reminders on the users' notification channels. Without
+// it no reminders are sent
+func (s *Service) SetNotifier(publisher NotificationPublisher) {
+	s.notifications = publisher
+}
+
+// scoredBookmark is what scoring reads of a bookmark
+type scoredBookmark struct {
+	ID             uint
+	Title          string
+	URL            string
+	Status         string
+	Tags           string
+	Metadata       string
+	CreatedAt      time.Time
+	LastAccessedAt *time.Time
+}
+
+// onReadingList tells whether a bookmark is on the reading list, the same
+// way the reading list does
+func (b scoredBookmark) onReadingList() bool {
+	return strings.Contains(b.Tags, `"`+reading.ListTag+`"`) || strings.Contains(b.Metadata, `"unread":true`)
+}
+
+// archived tells whether a bookmark was archived, here or by an import
+func (b scoredBookmark) archived() bool {
+	return strings.Contains(b.Metadata, `"archived":true`)
+}
+
+// Report lists the user's cleanup candidates by reason, at most limit of
+// each, stalest first
+func (s *Service) Report(ctx context.Context, userID uint, limit int) (*Report, error) {
+	if limit <= 0 {
+		limit = defaultLimit
+	}
+	if s.cfg.MaxCandidates > 0 && limit > s.cfg.MaxCandidates {
+		limit = s.cfg.MaxCandidates
+	}
+
+	now := s.now()
+	candidates, err := s.candidates(ctx, userID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{Total: len(candidates), Groups: make([]Group, 0, len(reasonOrder)), GeneratedAt: now}
+	for _, reason := range reasonOrder {
+		group := Group{Reason: reason, Candidates: []Candidate{}}
+		for _, candidate := range candidates {
+			for _, has := range candidate.Reasons {
+				if has != reason {
+					continue
+				}
+				group.Count++
+				if len(group.Candidates) < limit {
+					group.Candidates = append(group.Candidates, candidate)
+				}
+			}
+		}
+		report.Groups = append(report.Groups, group)
+	}
+	return report, nil
+}
+
+// candidates scores the user's bookmarks and returns those with a reason
+// to clean them up, stalest first. Archived bookmarks were looked at
+// already and are left out
+func (s *Service) candidates(ctx context.Context, userID uint, now time.Time) ([]Candidate, error) {
+	var bookmarks []scoredBookmark
+	if err := s.db.WithContext(ctx).Model(&database.Bookmark{}).
+		Select("id", "title", "url", "status", "tags", "metadata", "created_at", "last_accessed_at").
+		Where("user_id = ?", userID).
+		Scan(&bookmarks).Error; err != nil {
+		return nil, fmt.Errorf("failed to load bookmarks: %w", err)
+	}
+
+	lastVisits, err := s.lastVisits(ctx, userID, bookmarks)
+	if err != nil {
+		return nil, err
+	}
+
+	// Of the bookmarks of one page, the one visited last is kept, or the
+	// newest when none was visited
+	kept := make(map[string]scoredBookmark)
+	for _, bookmark := range bookmarks {
+		if bookmark.archived() {
+			continue
+		}
+		key := pageKey(bookmark.URL)
+		current, ok := kept[key]
+		if !ok || newerVisit(bookmark, lastVisits, current) {
+			kept[key] = bookmark
+		}
+	}
+
+	unvisitedBefore := now.AddDate(0, 0, -s.cfg.UnvisitedDays)
+	unreadBefore := now.AddDate(0, 0, -s.cfg.UnreadDays)
+	var candidates []Candidate
+	for _, bookmark := range bookmarks {
+		if bookmark.archived() {
+			continue
+		}
+		candidate := Candidate{
+			BookmarkID: bookmark.ID,
+			Title:      bookmark.Title,
+			URL:        bookmark.URL,
+			SavedAt:    bookmark.CreatedAt,
+		}
+		idleSince := bookmark.CreatedAt
+		if visited, ok := lastVisits[bookmark.ID]; ok {
+			candidate.LastVisitedAt = &visited
+			idleSince = visited
+		}
+
+		if brokenStatuses[bookmark.Status] {
+			candidate.Reasons = append(candidate.Reasons, ReasonBroken)
+			candidate.Status = bookmark.Status
+		}
+		if keeper := kept[pageKey(bookmark.URL)]; keeper.ID != bookmark.ID {
+			candidate.Reasons = append(candidate.Reasons, ReasonDuplicate)
+			candidate.SupersededBy = keeper.ID
+		}
+		if bookmark.onReadingList() && idleSince.Before(unreadBefore) {
+			candidate.Reasons = append(candidate.Reasons, ReasonUnread)
+		}
+		if candidate.LastVisitedAt == nil && bookmark.CreatedAt.Before(unvisitedBefore) {
+			candidate.Reasons = append(candidate.Reasons, ReasonNeverVisited)
+		}
+		if len(candidate.Reasons) == 0 {
+			continue
+		}
+
+		for _, reason := range candidate.Reasons {
+			candidate.Score += reasonWeights[reason]
+		}
+		idleYears := now.Sub(idleSince).Hours() / (24 * 365)
+		candidate.Score += min(max(int(idleYears*pointsPerIdleYear), 0), maxIdlePoints)
+		candidate.Score = min(candidate.Score, maxScore)
+		candidates = append(candidates, candidate)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		return candidates[i].SavedAt.Before(candidates[j].SavedAt)
+	})
+	return candidates, nil
+}
+
+// lastVisits returns when each bookmark was last opened, from its last
+// access and the user's view and click behaviors. Bookmarks never opened
+// are left out
+func (s *Service) lastVisits(ctx context.Context, userID uint, bookmarks []scoredBookmark) (map[uint]time.Time, error) {
+	visits := make(map[uint]time.Time)
+	for _, bookmark := range bookmarks {
+		if bookmark.LastAccessedAt != nil && bookmark.LastAccessedAt.After(bookmark.CreatedAt) {
+			visits[bookmark.ID] = *bookmark.LastAccessedAt
+		}
+	}
+
+	db := s.db.WithContext(ctx)
+	if len(bookmarks) == 0 || !db.Migrator().HasTable("user_behaviors") {
+		return visits, nil
+	}
+	var behaviors []struct {
+		BookmarkID uint
+		CreatedAt  time.Time
+	}
+	if err := db.Table("user_behaviors").Select("bookmark_id, created_at").
+		Where("user_id = ? AND action_type IN ? AND deleted_at IS NULL", strconv.FormatUint(uint64(userID), 10), visitActions).
+		Scan(&behaviors).Error; err != nil {
+		return nil, fmt.Errorf("failed to load bookmark visits: %w", err)
+	}
+	for _, behavior := range behaviors {
+		if behavior.CreatedAt.After(visits[behavior.BookmarkID]) {
+			visits[behavior.BookmarkID] = behavior.CreatedAt
+		}
+	}
+	return visits, nil
+}
+
+// pageKey groups the bookmarks of one page, whatever tracking parameters
+// they were saved with; URLs that can't be parsed only match themselves
+func pageKey(rawURL string) string {
+	if canonical, err := urlnorm.Canonical(urlnorm.Clean(rawURL, urlnorm.Defaults)); err == nil {
+		return canonical
+	}
+	return rawURL
+}
+
+// newerVisit tells whether a bookmark was visited after the one kept so
+// far, or is newer when neither was visited
+func newerVisit(bookmark scoredBookmark, visits map[uint]time.Time, kept scoredBookmark) bool {
+	visited, keptVisited := visits[bookmark.ID], visits[kept.ID]
+	if !visited.Equal(keptVisited) {
+		return visited.After(keptVisited)
+	}
+	if !bookmark.CreatedAt.Equal(kept.CreatedAt) {
+		return bookmark.CreatedAt.After(kept.CreatedAt)
+	}
+	return bookmark.ID > kept.ID
+}
+
+// Apply archives or deletes the user's listed bookmarks
+func (s *Service) Apply(ctx context.Context, userID uint, req ActionRequest) (*ActionResult, error) {
+	if req.Action != ActionArchive && req.Action != ActionDelete {
+		return nil, ErrInvalidAction
+	}
+	if len(req.BookmarkIDs) == 0 || len(req.BookmarkIDs) > MaxActionBookmarks {
+		return nil, ErrInvalidBookmarks
+	}
+
+	var bookmarks []database.Bookmark
+	if err := s.db.WithContext(ctx).Where("id IN ? AND user_id = ?", req.BookmarkIDs, userID).Find(&bookmarks).Error; err != nil {
+		return nil, fmt.Errorf("failed to load bookmarks: %w", err)
+	}
+	result := &ActionResult{Action: req.Action}
+	if len(bookmarks) == 0 {
+		return result, nil
+	}
+
+	if req.Action == ActionDelete {
+		ids := make([]uint, len(bookmarks))
+		for n, bookmark := range bookmarks {
+			ids[n] = bookmark.ID
+		}
+		if err := s.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&database.Bookmark{}, ids).Error; err != nil {
+			return nil, fmt.Errorf("failed to delete bookmarks: %w", err)
+		}
+		for _, id := range ids {
+			if s.indexer != nil {
+				s.indexer.QueueBookmarkDelete(id)
+			}
+		}
+		result.Affected = len(ids)
+		return result, nil
+	}
+
+	archived := make([]*database.Bookmark, 0, len(bookmarks))
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for n := range bookmarks {
+			bookmark := &bookmarks[n]
+			metadata, tags, ok := archiveFields(bookmark)
+			if !ok {
+				s.logger.Warn("Skipped archiving bookmark with invalid metadata", zap.Uint("bookmark_id", bookmark.ID))
+				continue
+			}
+			if err := tx.Model(bookmark).Updates(map[string]interface{}{"metadata": metadata, "tags": tags}).Error; err != nil {
+				return fmt.Errorf("failed to archive bookmark %d: %w", bookmark.ID, err)
+			}
+			archived = append(archived, bookmark)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if s.indexer != nil {
+		for _, bookmark := range archived {
+			s.indexer.QueueBookmark(bookmark)
+		}
+	}
+	result.Affected = len(archived)
+	return result, nil
+}
+
+// archiveFields returns a bookmark's metadata flagged archived and no
+// longer unread, and its tags without the reading list tag. It is false
+// when the stored JSON can't be read, so nothing is overwritten
+func archiveFields(bookmark *database.Bookmark) (string, string, bool) {
+	metadata := make(map[string]interface{})
+	if bookmark.Metadata != "" {
+		if err := json.Unmarshal([]byte(bookmark.Metadata), &metadata); err != nil {
+			return "", "", false
+		}
+	}
+	metadata["archived"] = true
+	delete(metadata, "unread")
+	encodedMetadata, _ := json.Marshal(metadata)
+
+	tags := bookmark.Tags
+	if tags != "" {
+		var list []string
+		if err := json.Unmarshal([]byte(tags), &list); err != nil {
+			return "", "", false
+		}
+		kept := make([]string, 0, len(list))
+		for _, tag := range list {
+			if tag != reading.ListTag {
+				kept = append(kept, tag)
+			}
+		}
+		encodedTags, _ := json.Marshal(kept)
+		tags = string(encodedTags)
+	}
+	return string(encodedMetadata), tags, true
+}
+
+// quarterStart returns the start of the calendar quarter of a time, in UTC
+func quarterStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), (t.Month()-1)/3*3+1, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// GetReminder returns the user's quarterly reminder setting
+func (s *Service) GetReminder(ctx context.Context, userID uint) (*Reminder, error) {
+	var reminder database.CleanupReminder
+	err := s.db.WithContext(ctx).Where("user_id = ?", userID).First(&reminder).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &Reminder{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load reminder: %w", err)
+	}
+	return s.reminderView(&reminder), nil
+}
+
+// SetReminder turns the user's quarterly reminder on or off. Turning it on
+// counts as this quarter's reminder, so the first one comes next quarter
+func (s *Service) SetReminder(ctx context.Context, userID uint, enabled bool) (*Reminder, error) {
+	var reminder database.CleanupReminder
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where(database.CleanupReminder{UserID: userID}).FirstOrInit(&reminder).Error; err != nil {
+			return err
+		}
+		if enabled && !reminder.Enabled {
+			now := s.now()
+			reminder.LastSentAt = &now
+		}
+		reminder.Enabled = enabled
+		return tx.Save(&reminder).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save reminder: %w", err)
+	}
+	return s.reminderView(&reminder), nil
+}
+
+func (s *Service) reminderView(reminder *database.CleanupReminder) *Reminder {
+	view := &Reminder{Enabled: reminder.Enabled, LastSentAt: reminder.LastSentAt}
+	if reminder.Enabled {
+		next := quarterStart(s.now())
+		if reminder.LastSentAt == nil || !reminder.LastSentAt.Before(next) {
+			next = next.AddDate(0, 3, 0)
+		}
+		view.NextAt = &next
+	}
+	return view
+}
+
+// SendReminders notifies the users who opted in and weren't reminded this
+// quarter of their cleanup candidates. Users with nothing to clean up are
+// skipped until next quarter. It returns how many were notified
+func (s *Service) SendReminders(ctx context.Context) (int, error) {
+	if s.notifications == nil {
+		return 0, nil
+	}
+	now := s.now()
+	var due []database.CleanupReminder
+	if err := s.db.WithContext(ctx).
+		Where("enabled = ? AND (last_sent_at IS NULL OR last_sent_at < ?)", true, quarterStart(now)).
+		Find(&due).Error; err != nil {
+		return 0, fmt.Errorf("failed to load due reminders: %w", err)
+	}
+
+	sent := 0
+	for _, reminder := range due {
+		candidates, err := s.candidates(ctx, reminder.UserID, now)
+		if err != nil {
+			return sent, err
+		}
+		if len(candidates) > 0 {
+			message := ReminderMessage{Type: ReminderNotification, Total: len(candidates), Reasons: make(map[string]int)}
+			for _, candidate := range candidates {
+				for _, reason := range candidate.Reasons {
+					message.Reasons[reason]++
+				}
+			}
+			// A missed notification is not retried; the report is still there
+			if err := s.notifications.PublishNotification(ctx, strconv.FormatUint(uint64(reminder.UserID), 10), message); err != nil {
+				s.logger.Warn("Failed to publish cleanup reminder", zap.Uint("user_id", reminder.UserID), zap.Error(err))
+			} else {
+				sent++
+			}
+		}
+		if err := s.db.WithContext(ctx).Model(&database.CleanupReminder{}).
+			Where("user_id = ?", reminder.UserID).Update("last_sent_at", now).Error; err != nil {
+			return sent, fmt.Errorf("failed to record reminder: %w", err)
+		}
+	}
+	return sent, nil
+}
@@ -0,0 +1,258 @@
+package cleanup
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/testfactory"
+)
+
+var testConfig = config.CleanupConfig{UnvisitedDays: 180, UnreadDays: 90, MaxCandidates: 50}
+
+// testNow is in the second quarter of 2026
+var testNow = time.Date(2026, time.April, 10, 12, 0, 0, 0, time.UTC)
+
+type testEnv struct {
+	service *Service
+	factory *testfactory.Factory
+	user    *database.User
+}
+
+func setupService(t *testing.T) *testEnv {
+	db := testfactory.NewDB(t)
+	require.NoError(t, db.AutoMigrate(&testfactory.Behavior{}))
+	factory := testfactory.New(t, db)
+	service := NewService(testConfig, db, zap.NewNop())
+	service.now = func() time.Time { return testNow }
+	return &testEnv{service: service, factory: factory, user: factory.User()}
+}
+
+// bookmark creates a bookmark of the user saved at a time
+func (e *testEnv) bookmark(url string, savedAt time.Time, overrides ...func(*database.Bookmark)) *database.Bookmark {
+	overrides = append(overrides, func(b *database.Bookmark) {
+		b.URL = url
+		b.CreatedAt = savedAt
+	})
+	return e.factory.Bookmark(e.user.ID, overrides...)
+}
+
+func group(t *testing.T, report *Report, reason string) Group {
+	t.Helper()
+	for _, group := range report.Groups {
+		if group.Reason == reason {
+			return group
+		}
+	}
+	t.Fatalf("no %s group", reason)
+	return Group{}
+}
+
+func candidateIDs(group Group) []uint {
+	ids := make([]uint, len(group.Candidates))
+	for n, candidate := range group.Candidates {
+		ids[n] = candidate.BookmarkID
+	}
+	return ids
+}
+
+func TestSpringCleaningReport(t *testing.T) {
+	env := setupService(t)
+	ctx := context.Background()
+	userID := strconv.FormatUint(uint64(env.user.ID), 10)
+	recent := testNow.AddDate(0, 0, -10)
+
+	forgotten := env.bookmark("https://example.com/forgotten", testNow.AddDate(-2, 0, 0))
+	broken := env.bookmark("https://example.com/gone", recent, func(b *database.Bookmark) { b.Status = string(database.LinkStatusBroken) })
+	env.factory.Behavior(userID, broken.ID, "view")
+	oldCopy := env.bookmark("https://go.dev/doc?utm_source=feed", testNow.AddDate(0, -2, 0))
+	visitedCopy := env.bookmark("https://go.dev/doc", testNow.AddDate(0, -1, 0))
+	lastVisit := testNow.AddDate(0, 0, -3)
+	require.NoError(t, env.factory.DB().Model(visitedCopy).Update("last_accessed_at", lastVisit).Error)
+	unread := env.bookmark("https://example.com/long-read", testNow.AddDate(0, 0, -120), func(b *database.Bookmark) { b.Tags = `["read-later"]` })
+	env.bookmark("https://example.com/archived", testNow.AddDate(-3, 0, 0), func(b *database.Bookmark) { b.Metadata = `{"archived":true}` })
+	env.bookmark("https://example.com/fresh", recent)
+	env.factory.Bookmark(env.factory.User().ID, func(b *database.Bookmark) { b.CreatedAt = testNow.AddDate(-2, 0, 0) })
+
+	report, err := env.service.Report(ctx, env.user.ID, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 4, report.Total)
+	require.Len(t, report.Groups, 4)
+	assert.Equal(t, ReasonBroken, report.Groups[0].Reason)
+
+	assert.Equal(t, []uint{broken.ID}, candidateIDs(group(t, report, ReasonBroken)))
+	assert.Equal(t, string(database.LinkStatusBroken), group(t, report, ReasonBroken).Candidates[0].Status)
+	assert.Equal(t, 40, group(t, report, ReasonBroken).Candidates[0].Score)
+
+	duplicates := group(t, report, ReasonDuplicate)
+	require.Equal(t, []uint{oldCopy.ID}, candidateIDs(duplicates), "the copy visited last is kept")
+	assert.Equal(t, visitedCopy.ID, duplicates.Candidates[0].SupersededBy)
+	assert.Equal(t, 30, duplicates.Candidates[0].Score)
+
+	assert.Equal(t, []uint{unread.ID}, candidateIDs(group(t, report, ReasonUnread)))
+	assert.Equal(t, 21, group(t, report, ReasonUnread).Candidates[0].Score)
+
+	neverVisited := group(t, report, ReasonNeverVisited)
+	require.Equal(t, []uint{forgotten.ID}, candidateIDs(neverVisited))
+	assert.Equal(t, 30, neverVisited.Candidates[0].Score, "two idle years add the most points")
+
+	report, err = env.service.Report(ctx, env.user.ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 4, report.Total)
+	assert.Len(t, group(t, report, ReasonDuplicate).Candidates, 1)
+}
+
+func TestApplyAction(t *testing.T) {
+	env := setupService(t)
+	ctx := context.Background()
+	old := testNow.AddDate(-1, 0, 0)
+
+	unread := env.bookmark("https://example.com/long-read", old, func(b *database.Bookmark) {
+		b.Tags = `["go","read-later"]`
+		b.Metadata = `{"unread":true,"source":"rss"}`
+	})
+	forgotten := env.bookmark("https://example.com/forgotten", old)
+	other := env.factory.Bookmark(env.factory.User().ID)
+
+	result, err := env.service.Apply(ctx, env.user.ID, ActionRequest{Action: ActionArchive, BookmarkIDs: []uint{unread.ID, other.ID}})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Affected, "other users' bookmarks are left alone")
+
+	var archived database.Bookmark
+	require.NoError(t, env.factory.DB().First(&archived, unread.ID).Error)
+	assert.JSONEq(t, `["go"]`, archived.Tags)
+	assert.JSONEq(t, `{"archived":true,"source":"rss"}`, archived.Metadata)
+
+	result, err = env.service.Apply(ctx, env.user.ID, ActionRequest{Action: ActionDelete, BookmarkIDs: []uint{forgotten.ID}})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Affected)
+
+	report, err := env.service.Report(ctx, env.user.ID, 0)
+	require.NoError(t, err)
+	assert.Zero(t, report.Total)
+
+	_, err = env.service.Apply(ctx, env.user.ID, ActionRequest{Action: "hide", BookmarkIDs: []uint{unread.ID}})
+	assert.ErrorIs(t, err, ErrInvalidAction)
+	_, err = env.service.Apply(ctx, env.user.ID, ActionRequest{Action: ActionDelete})
+	assert.ErrorIs(t, err, ErrInvalidBookmarks)
+}
+
+type stubPublisher struct {
+	sent map[string][]interface{}
+	err  error
+}
+
+func (p *stubPublisher) PublishNotification(_ context.Context, userID string, notification interface{}) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.sent[userID] = append(p.sent[userID], notification)
+	return nil
+}
+
+func TestQuarterlyReminders(t *testing.T) {
+	env := setupService(t)
+	ctx := context.Background()
+	publisher := &stubPublisher{sent: make(map[string][]interface{})}
+	env.service.SetNotifier(publisher)
+	env.bookmark("https://example.com/forgotten", testNow.AddDate(-1, 0, 0))
+	userID := strconv.FormatUint(uint64(env.user.ID), 10)
+
+	reminder, err := env.service.GetReminder(ctx, env.user.ID)
+	require.NoError(t, err)
+	assert.False(t, reminder.Enabled)
+
+	reminder, err = env.service.SetReminder(ctx, env.user.ID, true)
+	require.NoError(t, err)
+	require.NotNil(t, reminder.NextAt)
+	assert.Equal(t, time.Date(2026, time.July, 1, 0, 0, 0, 0, time.UTC), *reminder.NextAt)
+
+	sent, err := env.service.SendReminders(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, sent, "the first reminder waits for the next quarter")
+
+	env.service.now = func() time.Time { return time.Date(2026, time.July, 2, 9, 0, 0, 0, time.UTC) }
+	sent, err = env.service.SendReminders(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	require.Len(t, publisher.sent[userID], 1)
+	message := publisher.sent[userID][0].(ReminderMessage)
+	assert.Equal(t, ReminderNotification, message.Type)
+	assert.Equal(t, 1, message.Total)
+	assert.Equal(t, map[string]int{ReasonNeverVisited: 1}, message.Reasons)
+
+	sent, err = env.service.SendReminders(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, sent, "one reminder a quarter")
+
+	// A failed publish is not retried within the quarter
+	env.service.now = func() time.Time { return time.Date(2026, time.October, 1, 9, 0, 0, 0, time.UTC) }
+	publisher.err = errors.New("redis down")
+	sent, err = env.service.SendReminders(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, sent)
+	publisher.err = nil
+	sent, err = env.service.SendReminders(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, sent)
+
+	_, err = env.service.SetReminder(ctx, env.user.ID, false)
+	require.NoError(t, err)
+	env.service.now = func() time.Time { return time.Date(2027, time.January, 5, 9, 0, 0, 0, time.UTC) }
+	sent, err = env.service.SendReminders(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, sent)
+	assert.Len(t, publisher.sent[userID], 1)
+}
+
+func TestSpringCleaningHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	env := setupService(t)
+	forgotten := env.bookmark("https://example.com/forgotten", testNow.AddDate(-1, 0, 0))
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", strconv.FormatUint(uint64(env.user.ID), 10))
+		c.Next()
+	})
+	NewHandler(env.service).RegisterRoutes(router.Group("/api/v1"))
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, "/api/v1/maintenance/spring-cleaning"+path, bytes.NewBufferString(body)))
+		return w
+	}
+
+	w := serve(http.MethodGet, "?limit=5", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var body struct {
+		Data Report `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, 1, body.Data.Total)
+	assert.Equal(t, []uint{forgotten.ID}, candidateIDs(group(t, &body.Data, ReasonNeverVisited)))
+
+	id := strconv.FormatUint(uint64(forgotten.ID), 10)
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/actions", `{"action":"archive","bookmark_ids":[`+id+`]}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodPost, "/actions", `{"action":"hide","bookmark_ids":[`+id+`]}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodPost, "/actions", `{}`).Code)
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodPut, "/reminder", `{"enabled":true}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodPut, "/reminder", `{}`).Code)
+	w = serve(http.MethodGet, "/reminder", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"enabled":true`)
+}
@@ -0,0 +1,55 @@
+package collection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+// Permissions a user may be granted on someone else's collection
+const (
+	accessView = "view"
+	accessEdit = "edit"
+)
+
+// ErrUnauthorized is returned when someone the collection was shared with
+// changes a setting only its owner may change
+var ErrUnauthorized = errors.New("only the owner can change a collection's visibility or parent")
+
+// AccessChecker reports whether a user was granted a permission on a
+// collection, such as through a direct share or as a collaborator
+type AccessChecker interface {
+	HasAccess(ctx context.Context, userID uint, resourceType string, resourceID uint, permission string) (bool, error)
+}
+
+// SetAccessChecker lets users read and edit collections shared with them
+func (s *Service) SetAccessChecker(checker AccessChecker) {
+	s.access = checker
+}
+
+// checkAccess allows the owner, anyone for public collections, and users
+// granted the permission. Collections the user may not access are not found
+func (s *Service) checkAccess(userID uint, collection *database.Collection, permission string) error {
+	if collection.UserID == userID || collection.Visibility == "public" {
+		return nil
+	}
+	return s.checkGrant(userID, collection.ID, permission)
+}
+
+// checkGrant allows users granted the permission on a collection
+func (s *Service) checkGrant(userID, collectionID uint, permission string) error {
+	if s.access == nil {
+		return errors.New("collection not found")
+	}
+
+	allowed, err := s.access.HasAccess(context.Background(), userID, "collection", collectionID, permission)
+	if err != nil {
+		return fmt.Errorf("failed to check collection access: %w", err)
+	}
+	if !allowed {
+		return errors.New("collection not found")
+	}
+	return nil
+}
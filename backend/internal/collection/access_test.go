@@ -0,0 +1,66 @@
+package collection
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubAccessChecker grants a single user permissions on every collection
+type stubAccessChecker struct {
+	userID      uint
+	permissions map[string]bool
+}
+
+func (s *stubAccessChecker) HasAccess(ctx context.Context, userID uint, resourceType string, resourceID uint, permission string) (bool, error) {
+	return userID == s.userID && resourceType == "collection" && s.permissions[permission], nil
+}
+
+func TestCollectionService_SharedAccess(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+
+	collection := createBulkTestCollection(t, db, 1, "shared", nil)
+	createBulkTestBookmark(t, db, 1, "https://example.com", `[]`, collection)
+
+	// Without a checker private collections stay with their owner
+	_, err := service.GetByID(2, collection.ID)
+	assert.EqualError(t, err, "collection not found")
+
+	checker := &stubAccessChecker{userID: 2, permissions: map[string]bool{accessView: true}}
+	service.SetAccessChecker(checker)
+
+	viewed, err := service.GetByID(2, collection.ID)
+	require.NoError(t, err)
+	assert.Equal(t, collection.ID, viewed.ID)
+
+	bookmarks, err := service.GetBookmarks(2, collection.ID, GetCollectionBookmarksParams{Page: 1, Limit: 10})
+	require.NoError(t, err)
+	assert.Len(t, bookmarks.Bookmarks, 1)
+
+	name := "renamed"
+	_, err = service.Update(2, collection.ID, UpdateCollectionRequest{Name: &name})
+	assert.EqualError(t, err, "collection not found")
+
+	checker.permissions[accessEdit] = true
+	updated, err := service.Update(2, collection.ID, UpdateCollectionRequest{Name: &name})
+	require.NoError(t, err)
+	assert.Equal(t, "renamed", updated.Name)
+	assert.Equal(t, uint(1), updated.UserID)
+
+	// Visibility and placement stay with the owner
+	public := "public"
+	_, err = service.Update(2, collection.ID, UpdateCollectionRequest{Visibility: &public})
+	assert.ErrorIs(t, err, ErrUnauthorized)
+	parent := createBulkTestCollection(t, db, 1, "parent", nil)
+	_, err = service.Update(2, collection.ID, UpdateCollectionRequest{ParentID: &parent.ID})
+	assert.ErrorIs(t, err, ErrUnauthorized)
+
+	_, err = service.GetByID(3, collection.ID)
+	assert.EqualError(t, err, "collection not found")
+
+	_, err = service.Update(1, collection.ID, UpdateCollectionRequest{Visibility: &public})
+	assert.NoError(t, err)
+}
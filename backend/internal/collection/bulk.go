@@ -0,0 +1,807 @@
+package collection
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/internal/automation"
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/tags"
+)
+
+// Bulk operation types recorded for collection-level tools
+const (
+	BulkTypeMerge    = "collection_merge"
+	BulkTypeSplit    = "collection_split"
+	BulkTypeTransfer = "collection_transfer"
+)
+
+const bulkStatusUndone = "undone"
+
+// bookmarkBatchSize bounds the bookmark IDs bound into one query
+const bookmarkBatchSize = 500
+
+var collectionBulkTypes = []string{BulkTypeMerge, BulkTypeSplit, BulkTypeTransfer, BulkTypeCrossPost}
+
+// Collection bulk operation errors
+var (
+	ErrCollectionNotFound   = errors.New("collection not found")
+	ErrSameCollection       = errors.New("source and target collections must differ")
+	ErrMergeIntoDescendant  = errors.New("cannot merge a collection into its own descendant")
+	ErrSplitFilterRequired  = errors.New("at least one split filter is required")
+	ErrNoMatchingBookmarks  = errors.New("no bookmarks match the split filter")
+	ErrRecipientNotFound    = errors.New("recipient not found")
+	ErrTransferToSelf       = errors.New("cannot transfer a collection to yourself")
+	ErrTransferTarget       = errors.New("either a recipient or a team collection is required")
+	ErrTransferPending      = errors.New("collection already has a pending transfer")
+	ErrTransferNotFound     = errors.New("transfer not found")
+	ErrBulkOperationMissing = errors.New("bulk operation not found")
+	ErrUndoNotAvailable     = errors.New("bulk operation cannot be undone")
+)
+
+// MergeCollectionsRequest merges the source collection into the target
+type MergeCollectionsRequest struct {
+	SourceID uint `json:"source_id" binding:"required"`
+	TargetID uint `json:"target_id" binding:"required"`
+	// TagMapping renames tags on the merged bookmarks, e.g. {"golang": "go"}
+	TagMapping map[string]string `json:"tag_mapping,omitempty"`
+}
+
+// SplitCollectionRequest moves the bookmarks matching a filter into a new collection
+type SplitCollectionRequest struct {
+	Name   string   `json:"name" binding:"required"`
+	Tags   []string `json:"tags,omitempty"`   // "dev" also matches "dev/go", "dev/*" only children
+	Search string   `json:"search,omitempty"` // matched against title, description and URL
+	Domain string   `json:"domain,omitempty"`
+	Copy   bool     `json:"copy,omitempty"` // keep matching bookmarks in the source as well
+}
+
+// TransferCollectionRequest hands a collection and its sub-collections to
+// another user, who must accept it, or into a team collection
+type TransferCollectionRequest struct {
+	Recipient        string `json:"recipient,omitempty"`          // email or username
+	TeamCollectionID uint   `json:"team_collection_id,omitempty"` // shared with the user for editing
+}
+
+// collectionUndo records what a collection bulk operation changed so it can be reverted
+type collectionUndo struct {
+	SourceID        uint            `json:"source_id,omitempty"`
+	TargetID        uint            `json:"target_id,omitempty"`
+	NewCollectionID uint            `json:"new_collection_id,omitempty"`
+	AddedLinks      []uint          `json:"added_links,omitempty"`   // bookmarks linked to the target or new collection
+	RemovedLinks    []uint          `json:"removed_links,omitempty"` // bookmarks unlinked from the source
+	Reparented      []uint          `json:"reparented,omitempty"`
+	PreviousTags    map[uint]string `json:"previous_tags,omitempty"`
+
+	FromUserID   uint          `json:"from_user_id,omitempty"`
+	ToUserID     uint          `json:"to_user_id,omitempty"`
+	Collections  []uint        `json:"collections,omitempty"`
+	RootParentID *uint         `json:"root_parent_id,omitempty"`
+	Reassigned   []uint        `json:"reassigned,omitempty"`
+	Copies       map[uint]uint `json:"copies,omitempty"` // original bookmark ID -> recipient's copy
+
+	CrossPosted []uint `json:"cross_posted,omitempty"` // personal then team bookmark
+}
+
+// MergeCollections adds the source collection's bookmarks and sub-collections
+// to the target, reconciles their tags and deletes the source
+func (s *Service) MergeCollections(userID uint, req MergeCollectionsRequest) (*automation.BulkOperation, error) {
+	if req.SourceID == req.TargetID {
+		return nil, ErrSameCollection
+	}
+
+	params := automation.InterfaceMap{"source_id": req.SourceID, "target_id": req.TargetID}
+	if len(req.TagMapping) > 0 {
+		params["tag_mapping"] = req.TagMapping
+	}
+
+	return s.runBulkOperation(userID, BulkTypeMerge, params, func(tx *gorm.DB, op *automation.BulkOperation, undo *collectionUndo) error {
+		source, err := ownedCollection(tx, userID, req.SourceID)
+		if err != nil {
+			return err
+		}
+		target, err := ownedCollection(tx, userID, req.TargetID)
+		if err != nil {
+			return err
+		}
+
+		descendants, err := subtreeIDs(tx, userID, source.ID)
+		if err != nil {
+			return err
+		}
+		for _, id := range descendants {
+			if id == target.ID {
+				return ErrMergeIntoDescendant
+			}
+		}
+
+		sourceBookmarks, err := linkedBookmarkIDs(tx, source.ID)
+		if err != nil {
+			return err
+		}
+		targetBookmarks, err := linkedBookmarkIDs(tx, target.ID)
+		if err != nil {
+			return err
+		}
+
+		inTarget := make(map[uint]bool, len(targetBookmarks))
+		for _, id := range targetBookmarks {
+			inTarget[id] = true
+		}
+		var added []uint
+		for _, id := range sourceBookmarks {
+			if !inTarget[id] {
+				added = append(added, id)
+			}
+		}
+		if err := linkBookmarks(tx, target.ID, added); err != nil {
+			return err
+		}
+
+		var children []uint
+		if err := tx.Model(&database.Collection{}).Where("parent_id = ?", source.ID).Pluck("id", &children).Error; err != nil {
+			return fmt.Errorf("failed to find sub-collections: %w", err)
+		}
+		if len(children) > 0 {
+			if err := tx.Model(&database.Collection{}).Where("id IN ?", children).Update("parent_id", target.ID).Error; err != nil {
+				return fmt.Errorf("failed to move sub-collections: %w", err)
+			}
+		}
+
+		previousTags, err := reconcileTags(tx, append(targetBookmarks, added...), req.TagMapping)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Delete(source).Error; err != nil {
+			return fmt.Errorf("failed to delete source collection: %w", err)
+		}
+
+		undo.SourceID, undo.TargetID = source.ID, target.ID
+		undo.AddedLinks, undo.Reparented, undo.PreviousTags = added, children, previousTags
+
+		op.TotalItems = len(sourceBookmarks)
+		op.ProcessedItems = len(sourceBookmarks)
+		op.Result = automation.InterfaceMap{
+			"target_id":          target.ID,
+			"bookmarks_added":    len(added),
+			"collections_moved":  len(children),
+			"bookmarks_retagged": len(previousTags),
+		}
+		return nil
+	})
+}
+
+// SplitCollection moves the bookmarks of a collection that match the filter
+// into a new sibling collection
+func (s *Service) SplitCollection(userID, collectionID uint, req SplitCollectionRequest) (*automation.BulkOperation, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, errors.New("name is required")
+	}
+	if len(req.Tags) == 0 && strings.TrimSpace(req.Search) == "" && strings.TrimSpace(req.Domain) == "" {
+		return nil, ErrSplitFilterRequired
+	}
+
+	// Share links are generated up front because they are checked outside the transaction
+	shareLink, err := s.generateShareLink()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate share link: %w", err)
+	}
+
+	params := automation.InterfaceMap{
+		"collection_id": collectionID,
+		"name":          name,
+		"tags":          req.Tags,
+		"search":        req.Search,
+		"domain":        req.Domain,
+		"copy":          req.Copy,
+	}
+
+	return s.runBulkOperation(userID, BulkTypeSplit, params, func(tx *gorm.DB, op *automation.BulkOperation, undo *collectionUndo) error {
+		source, err := ownedCollection(tx, userID, collectionID)
+		if err != nil {
+			return err
+		}
+
+		matched, total, err := matchSplitFilter(tx, source.ID, req)
+		if err != nil {
+			return err
+		}
+		if len(matched) == 0 {
+			return ErrNoMatchingBookmarks
+		}
+
+		split := &database.Collection{
+			UserID:      userID,
+			Name:        name,
+			Description: source.Description,
+			Color:       source.Color,
+			Icon:        source.Icon,
+			ParentID:    source.ParentID,
+			Visibility:  source.Visibility,
+			ShareLink:   shareLink,
+		}
+		if err := tx.Create(split).Error; err != nil {
+			return fmt.Errorf("failed to create collection: %w", err)
+		}
+
+		if err := linkBookmarks(tx, split.ID, matched); err != nil {
+			return err
+		}
+		if !req.Copy {
+			if err := unlinkBookmarks(tx, source.ID, matched); err != nil {
+				return err
+			}
+			undo.RemovedLinks = matched
+		}
+
+		undo.SourceID, undo.NewCollectionID, undo.AddedLinks = source.ID, split.ID, matched
+
+		op.TotalItems = total
+		op.ProcessedItems = total
+		op.Result = automation.InterfaceMap{
+			"collection_id":   split.ID,
+			"bookmarks_moved": len(matched),
+			"copied":          req.Copy,
+		}
+		return nil
+	})
+}
+
+// TransferCollection offers a collection, its sub-collections and their
+// bookmarks to another user, who takes them over once they accept. A team
+// collection the user may edit is a target that needs no acceptance: the
+// subtree moves into it at once and to its owner
+func (s *Service) TransferCollection(userID, collectionID uint, req TransferCollectionRequest) (*database.CollectionTransfer, error) {
+	recipientName := strings.TrimSpace(req.Recipient)
+	if (recipientName == "") == (req.TeamCollectionID == 0) {
+		return nil, ErrTransferTarget
+	}
+	if req.TeamCollectionID != 0 {
+		return s.transferToTeam(userID, collectionID, req.TeamCollectionID)
+	}
+
+	root, err := ownedCollection(s.db, userID, collectionID)
+	if err != nil {
+		return nil, err
+	}
+	recipient, err := findUser(s.db, recipientName)
+	if err != nil {
+		return nil, err
+	}
+	if recipient.ID == userID {
+		return nil, ErrTransferToSelf
+	}
+
+	var pending int64
+	if err := s.db.Model(&database.CollectionTransfer{}).
+		Where("collection_id = ? AND status = ?", root.ID, database.TransferStatusPending).
+		Count(&pending).Error; err != nil {
+		return nil, fmt.Errorf("failed to check pending transfers: %w", err)
+	}
+	if pending > 0 {
+		return nil, ErrTransferPending
+	}
+
+	transfer := &database.CollectionTransfer{
+		CollectionID:   root.ID,
+		CollectionName: root.Name,
+		FromUserID:     userID,
+		ToUserID:       recipient.ID,
+		Status:         database.TransferStatusPending,
+	}
+	if err := s.db.Create(transfer).Error; err != nil {
+		return nil, fmt.Errorf("failed to create transfer: %w", err)
+	}
+	return transfer, nil
+}
+
+// transferToTeam moves a collection subtree into a team collection, handing
+// it to the team collection's owner
+func (s *Service) transferToTeam(userID, collectionID, teamCollectionID uint) (*database.CollectionTransfer, error) {
+	params := automation.InterfaceMap{"collection_id": collectionID, "team_collection_id": teamCollectionID}
+
+	var transfer *database.CollectionTransfer
+	_, err := s.runBulkOperation(userID, BulkTypeTransfer, params, func(tx *gorm.DB, op *automation.BulkOperation, undo *collectionUndo) error {
+		root, err := ownedCollection(tx, userID, collectionID)
+		if err != nil {
+			return err
+		}
+		team, err := teamCollection(tx, userID, teamCollectionID)
+		if err != nil {
+			return err
+		}
+		if team.UserID == userID {
+			return ErrTransferToSelf
+		}
+
+		if err := transferSubtree(tx, op, undo, userID, team.UserID, root, &team.ID); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		transfer = &database.CollectionTransfer{
+			CollectionID:     root.ID,
+			CollectionName:   root.Name,
+			FromUserID:       userID,
+			ToUserID:         team.UserID,
+			TeamCollectionID: &team.ID,
+			Status:           database.TransferStatusAccepted,
+			OperationID:      &op.ID,
+			RespondedAt:      &now,
+		}
+		if err := tx.Create(transfer).Error; err != nil {
+			return fmt.Errorf("failed to record transfer: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return transfer, nil
+}
+
+// transferSubtree hands a collection, its sub-collections and their bookmarks
+// from one user to another, placing the root under parentID, or at the top
+// level when nil. Bookmarks also filed in collections outside the subtree
+// stay with the owner and the recipient receives a copy
+func transferSubtree(tx *gorm.DB, op *automation.BulkOperation, undo *collectionUndo, userID, recipientID uint, root *database.Collection, parentID *uint) error {
+	collectionIDs, err := subtreeIDs(tx, userID, root.ID)
+	if err != nil {
+		return err
+	}
+
+	var bookmarkIDs []uint
+	if err := tx.Table("bookmark_collections").
+		Joins("JOIN bookmarks ON bookmarks.id = bookmark_collections.bookmark_id").
+		Where("bookmark_collections.collection_id IN ? AND bookmarks.user_id = ? AND bookmarks.deleted_at IS NULL", collectionIDs, userID).
+		Distinct().Pluck("bookmark_collections.bookmark_id", &bookmarkIDs).Error; err != nil {
+		return fmt.Errorf("failed to find bookmarks: %w", err)
+	}
+
+	var shared []uint
+	if len(bookmarkIDs) > 0 {
+		if err := tx.Table("bookmark_collections").
+			Where("bookmark_id IN ? AND collection_id NOT IN ?", bookmarkIDs, collectionIDs).
+			Distinct().Pluck("bookmark_id", &shared).Error; err != nil {
+			return fmt.Errorf("failed to find bookmarks filed elsewhere: %w", err)
+		}
+	}
+	isShared := make(map[uint]bool, len(shared))
+	for _, id := range shared {
+		isShared[id] = true
+	}
+
+	var reassigned []uint
+	copies := make(map[uint]uint)
+	for _, id := range bookmarkIDs {
+		if !isShared[id] {
+			reassigned = append(reassigned, id)
+			continue
+		}
+
+		var original database.Bookmark
+		if err := tx.First(&original, id).Error; err != nil {
+			return fmt.Errorf("failed to load bookmark: %w", err)
+		}
+		clone := original
+		clone.BaseModel = database.BaseModel{}
+		clone.UserID = recipientID
+		clone.User = database.User{}
+		clone.Collections = nil
+		clone.SaveCount, clone.LikeCount, clone.CommentCount = 0, 0, 0
+		if err := tx.Omit("User", "Collections", "Comments").Create(&clone).Error; err != nil {
+			return fmt.Errorf("failed to copy bookmark: %w", err)
+		}
+		if err := tx.Table("bookmark_collections").
+			Where("bookmark_id = ? AND collection_id IN ?", id, collectionIDs).
+			Update("bookmark_id", clone.ID).Error; err != nil {
+			return fmt.Errorf("failed to relink bookmark copy: %w", err)
+		}
+		copies[id] = clone.ID
+	}
+
+	if len(reassigned) > 0 {
+		if err := tx.Model(&database.Bookmark{}).Where("id IN ?", reassigned).Update("user_id", recipientID).Error; err != nil {
+			return fmt.Errorf("failed to transfer bookmarks: %w", err)
+		}
+	}
+	if err := tx.Model(&database.Collection{}).Where("id IN ?", collectionIDs).Update("user_id", recipientID).Error; err != nil {
+		return fmt.Errorf("failed to transfer collections: %w", err)
+	}
+	// The recipient does not own the previous parent, so the root becomes
+	// top level or goes into the team collection
+	if root.ParentID != nil || parentID != nil {
+		if err := tx.Model(&database.Collection{}).Where("id = ?", root.ID).Update("parent_id", parentID).Error; err != nil {
+			return fmt.Errorf("failed to detach collection: %w", err)
+		}
+	}
+
+	undo.FromUserID, undo.ToUserID = userID, recipientID
+	undo.Collections, undo.RootParentID = collectionIDs, root.ParentID
+	undo.Reassigned, undo.Copies = reassigned, copies
+
+	op.TotalItems = len(collectionIDs) + len(bookmarkIDs)
+	op.ProcessedItems = op.TotalItems
+	op.Result = automation.InterfaceMap{
+		"recipient_id":          recipientID,
+		"collections":           len(collectionIDs),
+		"bookmarks_transferred": len(reassigned),
+		"bookmarks_copied":      len(copies),
+	}
+	return nil
+}
+
+// ListBulkOperations returns the user's collection bulk operations, newest first
+func (s *Service) ListBulkOperations(userID uint) ([]automation.BulkOperation, error) {
+	var operations []automation.BulkOperation
+	if err := s.db.Where("user_id = ? AND type IN ?", strconv.FormatUint(uint64(userID), 10), collectionBulkTypes).
+		Order("created_at DESC").Find(&operations).Error; err != nil {
+		return nil, fmt.Errorf("failed to get bulk operations: %w", err)
+	}
+	return operations, nil
+}
+
+// UndoBulkOperation reverts a completed collection bulk operation
+func (s *Service) UndoBulkOperation(userID, operationID uint) (*automation.BulkOperation, error) {
+	var op automation.BulkOperation
+	if err := s.db.Where("id = ? AND user_id = ? AND type IN ?", operationID, strconv.FormatUint(uint64(userID), 10), collectionBulkTypes).
+		First(&op).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBulkOperationMissing
+		}
+		return nil, fmt.Errorf("failed to get bulk operation: %w", err)
+	}
+	if op.Status != "completed" || op.UndoData == "" {
+		return nil, ErrUndoNotAvailable
+	}
+
+	var undo collectionUndo
+	if err := json.Unmarshal([]byte(op.UndoData), &undo); err != nil {
+		return nil, fmt.Errorf("failed to decode undo data: %w", err)
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		switch op.Type {
+		case BulkTypeMerge:
+			return undoMerge(tx, &undo)
+		case BulkTypeSplit:
+			return undoSplit(tx, &undo)
+		case BulkTypeTransfer:
+			return undoTransfer(tx, &undo)
+		case BulkTypeCrossPost:
+			return undoCrossPost(tx, op.ID, &undo)
+		default:
+			return ErrUndoNotAvailable
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.recordBulkRevisions(RevisionUndo, &undo)
+	s.indexBulk(&undo)
+	s.markGraphBulk(&undo)
+
+	now := time.Now()
+	op.Status = bulkStatusUndone
+	op.UndoneAt = &now
+	op.UndoData = ""
+	if err := s.db.Save(&op).Error; err != nil {
+		return nil, fmt.Errorf("failed to update bulk operation: %w", err)
+	}
+
+	return &op, nil
+}
+
+// runBulkOperation records a bulk operation and runs fn in a transaction,
+// storing the undo state on success and the error on failure
+func (s *Service) runBulkOperation(userID uint, opType string, params automation.InterfaceMap, fn func(tx *gorm.DB, op *automation.BulkOperation, undo *collectionUndo) error) (*automation.BulkOperation, error) {
+	now := time.Now()
+	op := &automation.BulkOperation{
+		UserID:     strconv.FormatUint(uint64(userID), 10),
+		Type:       opType,
+		Status:     "running",
+		Parameters: params,
+		StartedAt:  &now,
+	}
+	if err := s.db.Create(op).Error; err != nil {
+		return nil, fmt.Errorf("failed to create bulk operation: %w", err)
+	}
+
+	var undo collectionUndo
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		return fn(tx, op, &undo)
+	})
+
+	completed := time.Now()
+	op.CompletedAt = &completed
+	if err != nil {
+		op.Status = "failed"
+		op.Error = err.Error()
+		s.db.Save(op)
+		return nil, err
+	}
+
+	undoData, marshalErr := json.Marshal(undo)
+	if marshalErr == nil {
+		op.UndoData = string(undoData)
+	}
+	op.Status = "completed"
+	op.Progress = 100
+	if err := s.db.Save(op).Error; err != nil {
+		return nil, fmt.Errorf("failed to update bulk operation: %w", err)
+	}
+	s.recordBulkRevisions(opType, &undo)
+	s.indexBulk(&undo)
+	s.markGraphBulk(&undo)
+
+	return op, nil
+}
+
+func undoMerge(tx *gorm.DB, undo *collectionUndo) error {
+	if err := tx.Unscoped().Model(&database.Collection{}).Where("id = ?", undo.SourceID).Update("deleted_at", nil).Error; err != nil {
+		return fmt.Errorf("failed to restore source collection: %w", err)
+	}
+	if len(undo.Reparented) > 0 {
+		if err := tx.Model(&database.Collection{}).Where("id IN ?", undo.Reparented).Update("parent_id", undo.SourceID).Error; err != nil {
+			return fmt.Errorf("failed to restore sub-collections: %w", err)
+		}
+	}
+	if err := unlinkBookmarks(tx, undo.TargetID, undo.AddedLinks); err != nil {
+		return err
+	}
+	for id, previous := range undo.PreviousTags {
+		if err := tx.Model(&database.Bookmark{}).Where("id = ?", id).Update("tags", previous).Error; err != nil {
+			return fmt.Errorf("failed to restore tags: %w", err)
+		}
+	}
+	return nil
+}
+
+func undoSplit(tx *gorm.DB, undo *collectionUndo) error {
+	if err := linkBookmarks(tx, undo.SourceID, undo.RemovedLinks); err != nil {
+		return err
+	}
+	if err := unlinkBookmarks(tx, undo.NewCollectionID, undo.AddedLinks); err != nil {
+		return err
+	}
+	if err := tx.Delete(&database.Collection{}, undo.NewCollectionID).Error; err != nil {
+		return fmt.Errorf("failed to delete split collection: %w", err)
+	}
+	return nil
+}
+
+// undoTransfer returns a transferred subtree, provided the recipient still owns all of it
+func undoTransfer(tx *gorm.DB, undo *collectionUndo) error {
+	var owned int64
+	if err := tx.Model(&database.Collection{}).Where("id IN ? AND user_id = ?", undo.Collections, undo.ToUserID).Count(&owned).Error; err != nil {
+		return fmt.Errorf("failed to check collections: %w", err)
+	}
+	if int(owned) != len(undo.Collections) {
+		return ErrUndoNotAvailable
+	}
+
+	if err := tx.Model(&database.Collection{}).Where("id IN ?", undo.Collections).Update("user_id", undo.FromUserID).Error; err != nil {
+		return fmt.Errorf("failed to return collections: %w", err)
+	}
+	if len(undo.Collections) > 0 {
+		if err := tx.Model(&database.Collection{}).Where("id = ?", undo.Collections[0]).Update("parent_id", undo.RootParentID).Error; err != nil {
+			return fmt.Errorf("failed to reattach collection: %w", err)
+		}
+	}
+	if len(undo.Reassigned) > 0 {
+		if err := tx.Model(&database.Bookmark{}).Where("id IN ? AND user_id = ?", undo.Reassigned, undo.ToUserID).
+			Update("user_id", undo.FromUserID).Error; err != nil {
+			return fmt.Errorf("failed to return bookmarks: %w", err)
+		}
+	}
+	for original, copyID := range undo.Copies {
+		if err := tx.Table("bookmark_collections").Where("bookmark_id = ?", copyID).Update("bookmark_id", original).Error; err != nil {
+			return fmt.Errorf("failed to relink bookmark: %w", err)
+		}
+		if err := tx.Unscoped().Delete(&database.Bookmark{}, copyID).Error; err != nil {
+			return fmt.Errorf("failed to delete bookmark copy: %w", err)
+		}
+	}
+	return nil
+}
+
+// ownedCollection loads a collection owned by the user
+func ownedCollection(tx *gorm.DB, userID, id uint) (*database.Collection, error) {
+	var collection database.Collection
+	if err := tx.Where("id = ? AND user_id = ?", id, userID).First(&collection).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCollectionNotFound
+		}
+		return nil, fmt.Errorf("failed to get collection: %w", err)
+	}
+	return &collection, nil
+}
+
+// subtreeIDs returns a collection and all of its descendants owned by the
+// user, with the root first
+func subtreeIDs(tx *gorm.DB, userID, rootID uint) ([]uint, error) {
+	ids := []uint{rootID}
+	seen := map[uint]bool{rootID: true}
+	frontier := []uint{rootID}
+
+	for len(frontier) > 0 {
+		var children []uint
+		if err := tx.Model(&database.Collection{}).Where("parent_id IN ? AND user_id = ?", frontier, userID).
+			Pluck("id", &children).Error; err != nil {
+			return nil, fmt.Errorf("failed to find sub-collections: %w", err)
+		}
+
+		frontier = frontier[:0]
+		for _, id := range children {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+				frontier = append(frontier, id)
+			}
+		}
+	}
+
+	return ids, nil
+}
+
+func linkedBookmarkIDs(tx *gorm.DB, collectionID uint) ([]uint, error) {
+	var ids []uint
+	if err := tx.Table("bookmark_collections").Where("collection_id = ?", collectionID).
+		Pluck("bookmark_id", &ids).Error; err != nil {
+		return nil, fmt.Errorf("failed to find collection bookmarks: %w", err)
+	}
+	return ids, nil
+}
+
+func linkBookmarks(tx *gorm.DB, collectionID uint, bookmarkIDs []uint) error {
+	if len(bookmarkIDs) == 0 {
+		return nil
+	}
+	rows := make([]map[string]interface{}, len(bookmarkIDs))
+	for i, id := range bookmarkIDs {
+		rows[i] = map[string]interface{}{"collection_id": collectionID, "bookmark_id": id}
+	}
+	if err := tx.Table("bookmark_collections").Create(&rows).Error; err != nil {
+		return fmt.Errorf("failed to add bookmarks to collection: %w", err)
+	}
+	return nil
+}
+
+func unlinkBookmarks(tx *gorm.DB, collectionID uint, bookmarkIDs []uint) error {
+	if len(bookmarkIDs) == 0 {
+		return nil
+	}
+	if err := tx.Exec("DELETE FROM bookmark_collections WHERE collection_id = ? AND bookmark_id IN ?", collectionID, bookmarkIDs).Error; err != nil {
+		return fmt.Errorf("failed to remove bookmarks from collection: %w", err)
+	}
+	return nil
+}
+
+// reconcileTags applies the tag mapping to the bookmarks and normalizes their
+// tags, returning the previous tag JSON of every bookmark it changed
+func reconcileTags(tx *gorm.DB, bookmarkIDs []uint, mapping map[string]string) (map[uint]string, error) {
+	previous := make(map[uint]string)
+	if len(bookmarkIDs) == 0 {
+		return previous, nil
+	}
+
+	var rows []database.Bookmark
+	if err := tx.Model(&database.Bookmark{}).Select("id, tags").Where("id IN ?", bookmarkIDs).Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to load tags: %w", err)
+	}
+
+	for _, row := range rows {
+		var current []string
+		if row.Tags != "" {
+			if err := json.Unmarshal([]byte(row.Tags), &current); err != nil {
+				continue // Leave malformed tag data untouched
+			}
+		}
+
+		updated := make([]string, 0, len(current))
+		for _, tag := range current {
+			for from, to := range mapping {
+				if renamed, ok := tags.Rename(tag, from, to); ok {
+					tag = renamed
+					break
+				}
+			}
+			updated = append(updated, tag)
+		}
+		updated = tags.NormalizeAll(updated)
+
+		if strings.Join(updated, "\x00") == strings.Join(current, "\x00") {
+			continue
+		}
+
+		tagsJSON, err := json.Marshal(updated)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal tags: %w", err)
+		}
+		if err := tx.Model(&database.Bookmark{}).Where("id = ?", row.ID).Update("tags", string(tagsJSON)).Error; err != nil {
+			return nil, fmt.Errorf("failed to update tags: %w", err)
+		}
+		previous[row.ID] = row.Tags
+	}
+
+	return previous, nil
+}
+
+// matchSplitFilter returns the IDs of the collection's bookmarks matching the
+// split filter along with the number of bookmarks examined
+func matchSplitFilter(tx *gorm.DB, collectionID uint, req SplitCollectionRequest) ([]uint, int, error) {
+	query := tx.Model(&database.Bookmark{}).Select("bookmarks.id, bookmarks.tags").
+		Joins("JOIN bookmark_collections ON bookmarks.id = bookmark_collections.bookmark_id").
+		Where("bookmark_collections.collection_id = ?", collectionID)
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count bookmarks: %w", err)
+	}
+
+	if search := strings.TrimSpace(req.Search); search != "" {
+		term := "%" + strings.ToLower(search) + "%"
+		query = query.Where("LOWER(bookmarks.title) LIKE ? OR LOWER(bookmarks.description) LIKE ? OR LOWER(bookmarks.url) LIKE ?",
+			term, term, term)
+	}
+	if domain := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(req.Domain), "www.")); domain != "" {
+		query = query.Where("LOWER(bookmarks.url) LIKE ? OR LOWER(bookmarks.url) LIKE ?", "%://"+domain+"%", "%."+domain+"%")
+	}
+
+	var rows []database.Bookmark
+	if err := query.Find(&rows).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to find bookmarks: %w", err)
+	}
+
+	var matched []uint
+	for _, row := range rows {
+		if len(req.Tags) > 0 && !matchesAnyTag(row.Tags, req.Tags) {
+			continue
+		}
+		matched = append(matched, row.ID)
+	}
+
+	return matched, int(total), nil
+}
+
+func matchesAnyTag(raw string, patterns []string) bool {
+	var list []string
+	if raw == "" || json.Unmarshal([]byte(raw), &list) != nil {
+		return false
+	}
+	for _, tag := range list {
+		for _, pattern := range patterns {
+			if tags.Matches(tag, pattern) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// findUser resolves a user by email or username
+func findUser(tx *gorm.DB, identifier string) (*database.User, error) {
+	identifier = strings.TrimSpace(identifier)
+
+	column := "username"
+	if strings.Contains(identifier, "@") {
+		column = "email"
+	}
+
+	var user database.User
+	if err := tx.First(&user, column+" = ?", identifier).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRecipientNotFound
+		}
+		return nil, fmt.Errorf("failed to find recipient: %w", err)
+	}
+	return &user, nil
+}
@@ -0,0 +1,376 @@
+package collection
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/utils"
+)
+
+// MergeCollections merges one collection into another
+// @Summary Merge collections
+// @Description Add the source collection's bookmarks and sub-collections to the target, reconcile tags and delete the source
+// @Tags collections
+// @Accept json
+// @Produce json
+// @Param request body MergeCollectionsRequest true "Merge request"
+// @Success 200 {object} automation.BulkOperation
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/collections/merge [post]
+func (h *Handler) MergeCollections(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	var req MergeCollectionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid request data", nil)
+		return
+	}
+
+	operation, err := h.service.MergeCollections(userID, req)
+	if err != nil {
+		bulkErrorResponse(c, err, "Failed to merge collections")
+		return
+	}
+
+	utils.SuccessResponse(c, operation, "Collections merged successfully")
+}
+
+// SplitCollection moves matching bookmarks into a new collection
+// @Summary Split a collection
+// @Description Move the bookmarks matching a tag, search or domain filter into a new collection
+// @Tags collections
+// @Accept json
+// @Produce json
+// @Param id path int true "Collection ID"
+// @Param request body SplitCollectionRequest true "Split request"
+// @Success 200 {object} automation.BulkOperation
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/collections/{id}/split [post]
+func (h *Handler) SplitCollection(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid collection ID", nil)
+		return
+	}
+
+	var req SplitCollectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid request data", nil)
+		return
+	}
+
+	operation, err := h.service.SplitCollection(userID, uint(id), req)
+	if err != nil {
+		bulkErrorResponse(c, err, "Failed to split collection")
+		return
+	}
+
+	utils.SuccessResponse(c, operation, "Collection split successfully")
+}
+
+// TransferCollection transfers a collection subtree to another user or a team
+// @Summary Transfer a collection
+// @Description Offer a collection, its sub-collections and their bookmarks to another user, who must accept the transfer, or move them into a team collection you can edit
+// @Tags collections
+// @Accept json
+// @Produce json
+// @Param id path int true "Collection ID"
+// @Param request body TransferCollectionRequest true "Transfer request"
+// @Success 200 {object} database.CollectionTransfer
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/collections/{id}/transfer [post]
+func (h *Handler) TransferCollection(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid collection ID", nil)
+		return
+	}
+
+	var req TransferCollectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid request data", nil)
+		return
+	}
+
+	transfer, err := h.service.TransferCollection(userID, uint(id), req)
+	if err != nil {
+		bulkErrorResponse(c, err, "Failed to transfer collection")
+		return
+	}
+
+	if transfer.Status == database.TransferStatusPending {
+		utils.SuccessResponse(c, transfer, "Collection offered to the recipient")
+		return
+	}
+	utils.SuccessResponse(c, transfer, "Collection transferred successfully")
+}
+
+// ListTransfers lists pending collection transfers
+// @Summary List pending collection transfers
+// @Description List the collection transfers offered to or by the current user that await an answer
+// @Tags collections
+// @Produce json
+// @Success 200 {array} database.CollectionTransfer
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/collections/transfers [get]
+func (h *Handler) ListTransfers(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	transfers, err := h.service.ListTransfers(userID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to list transfers", nil)
+		return
+	}
+
+	utils.SuccessResponse(c, transfers, "Transfers retrieved successfully")
+}
+
+// AcceptTransfer accepts a collection transfer
+// @Summary Accept a collection transfer
+// @Description Take over a collection offered to the current user, with its sub-collections and bookmarks
+// @Tags collections
+// @Produce json
+// @Param transfer_id path int true "Transfer ID"
+// @Success 200 {object} database.CollectionTransfer
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/collections/transfers/{transfer_id}/accept [post]
+func (h *Handler) AcceptTransfer(c *gin.Context) {
+	userID, transferID, ok := transferParams(c)
+	if !ok {
+		return
+	}
+
+	transfer, err := h.service.AcceptTransfer(userID, transferID)
+	if err != nil {
+		bulkErrorResponse(c, err, "Failed to accept transfer")
+		return
+	}
+
+	utils.SuccessResponse(c, transfer, "Collection transferred successfully")
+}
+
+// DeclineTransfer declines a collection transfer
+// @Summary Decline a collection transfer
+// @Description Turn down a collection offered to the current user
+// @Tags collections
+// @Produce json
+// @Param transfer_id path int true "Transfer ID"
+// @Success 200 {object} utils.SuccessResponse
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/collections/transfers/{transfer_id}/decline [post]
+func (h *Handler) DeclineTransfer(c *gin.Context) {
+	userID, transferID, ok := transferParams(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeclineTransfer(userID, transferID); err != nil {
+		bulkErrorResponse(c, err, "Failed to decline transfer")
+		return
+	}
+
+	utils.SuccessResponse(c, nil, "Transfer declined")
+}
+
+// CancelTransfer withdraws a collection transfer
+// @Summary Cancel a collection transfer
+// @Description Withdraw a collection the current user offered before it is accepted
+// @Tags collections
+// @Produce json
+// @Param transfer_id path int true "Transfer ID"
+// @Success 200 {object} utils.SuccessResponse
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/collections/transfers/{transfer_id} [delete]
+func (h *Handler) CancelTransfer(c *gin.Context) {
+	userID, transferID, ok := transferParams(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.CancelTransfer(userID, transferID); err != nil {
+		bulkErrorResponse(c, err, "Failed to cancel transfer")
+		return
+	}
+
+	utils.SuccessResponse(c, nil, "Transfer cancelled")
+}
+
+// transferParams reads the user and transfer ID of a transfer request,
+// responding with an error when either is missing
+func transferParams(c *gin.Context) (uint, uint, bool) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return 0, 0, false
+	}
+
+	id, err := strconv.ParseUint(c.Param("transfer_id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid transfer ID", nil)
+		return 0, 0, false
+	}
+
+	return userID, uint(id), true
+}
+
+// ListBulkOperations lists collection bulk operations
+// @Summary List collection bulk operations
+// @Description List the merge, split and transfer operations run by the current user
+// @Tags collections
+// @Produce json
+// @Success 200 {array} automation.BulkOperation
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/collections/operations [get]
+func (h *Handler) ListBulkOperations(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	operations, err := h.service.ListBulkOperations(userID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to list bulk operations", nil)
+		return
+	}
+
+	utils.SuccessResponse(c, operations, "Bulk operations retrieved successfully")
+}
+
+// CrossPost saves a bookmark to a personal and a team collection at once
+// @Summary Save a bookmark for yourself and your team
+// @Description Save a bookmark to one of your collections and to a team collection you can edit, each copy with its own tags. The saves are one bulk operation, undone together
+// @Tags collections
+// @Accept json
+// @Produce json
+// @Param request body CrossPostRequest true "Cross-post request"
+// @Success 200 {object} automation.BulkOperation
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/collections/cross-post [post]
+func (h *Handler) CrossPost(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	var req CrossPostRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid request data", nil)
+		return
+	}
+
+	operation, err := h.service.CrossPost(userID, req)
+	if err != nil {
+		bulkErrorResponse(c, err, "Failed to save bookmark")
+		return
+	}
+
+	utils.SuccessResponse(c, operation, "Bookmark saved for you and your team")
+}
+
+// UndoBulkOperation reverts a collection bulk operation
+// @Summary Undo a collection bulk operation
+// @Description Revert a completed merge, split, transfer or cross-post
+// @Tags collections
+// @Produce json
+// @Param operation_id path int true "Bulk operation ID"
+// @Success 200 {object} automation.BulkOperation
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/collections/operations/{operation_id}/undo [post]
+func (h *Handler) UndoBulkOperation(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("operation_id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid operation ID", nil)
+		return
+	}
+
+	operation, err := h.service.UndoBulkOperation(userID, uint(id))
+	if err != nil {
+		bulkErrorResponse(c, err, "Failed to undo bulk operation")
+		return
+	}
+
+	utils.SuccessResponse(c, operation, "Bulk operation undone successfully")
+}
+
+// bulkErrorResponse maps collection bulk operation errors to HTTP responses
+func bulkErrorResponse(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, ErrCollectionNotFound), errors.Is(err, ErrRecipientNotFound), errors.Is(err, ErrBulkOperationMissing),
+		errors.Is(err, ErrTransferNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, "NOT_FOUND", err.Error(), nil)
+	case errors.Is(err, ErrTransferPending):
+		utils.ErrorResponse(c, http.StatusConflict, "TRANSFER_PENDING", err.Error(), nil)
+	case errors.Is(err, ErrTeamCollectionDenied):
+		utils.ErrorResponse(c, http.StatusForbidden, "FORBIDDEN", err.Error(), nil)
+	case errors.Is(err, ErrUndoNotAvailable):
+		utils.ErrorResponse(c, http.StatusConflict, "UNDO_NOT_AVAILABLE", err.Error(), nil)
+	case errors.Is(err, ErrSameCollection), errors.Is(err, ErrMergeIntoDescendant), errors.Is(err, ErrSplitFilterRequired),
+		errors.Is(err, ErrNoMatchingBookmarks), errors.Is(err, ErrTransferToSelf), errors.Is(err, ErrTransferTarget),
+		errors.Is(err, ErrInvalidBookmarkURL):
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error(), nil)
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", message, nil)
+	}
+}
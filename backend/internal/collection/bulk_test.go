@@ -0,0 +1,338 @@
+package collection
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+func createBulkTestCollection(t *testing.T, db *gorm.DB, userID uint, name string, parentID *uint) *database.Collection {
+	collection := &database.Collection{
+		UserID:     userID,
+		Name:       name,
+		ParentID:   parentID,
+		Visibility: "private",
+		ShareLink:  fmt.Sprintf("link-%d-%s", userID, name),
+	}
+	require.NoError(t, db.Create(collection).Error)
+	return collection
+}
+
+func createBulkTestBookmark(t *testing.T, db *gorm.DB, userID uint, url, tagsJSON string, collections ...*database.Collection) *database.Bookmark {
+	bookmark := &database.Bookmark{UserID: userID, URL: url, Title: url, Tags: tagsJSON, Status: "active"}
+	require.NoError(t, db.Create(bookmark).Error)
+	for _, collection := range collections {
+		require.NoError(t, db.Exec("INSERT INTO bookmark_collections (collection_id, bookmark_id) VALUES (?, ?)", collection.ID, bookmark.ID).Error)
+	}
+	return bookmark
+}
+
+func collectionBookmarkIDs(t *testing.T, db *gorm.DB, collectionID uint) []uint {
+	ids, err := linkedBookmarkIDs(db, collectionID)
+	require.NoError(t, err)
+	return ids
+}
+
+func TestCollectionService_MergeCollections(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+
+	source := createBulkTestCollection(t, db, 1, "source", nil)
+	target := createBulkTestCollection(t, db, 1, "target", nil)
+	child := createBulkTestCollection(t, db, 1, "child", &source.ID)
+
+	shared := createBulkTestBookmark(t, db, 1, "https://example.com/shared", `["go"]`, source, target)
+	moved := createBulkTestBookmark(t, db, 1, "https://example.com/moved", `["golang/testing"]`, source)
+	createBulkTestBookmark(t, db, 1, "https://example.com/kept", `["Go "]`, target)
+
+	operation, err := service.MergeCollections(1, MergeCollectionsRequest{
+		SourceID:   source.ID,
+		TargetID:   target.ID,
+		TagMapping: map[string]string{"golang": "go"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, BulkTypeMerge, operation.Type)
+	assert.Equal(t, "completed", operation.Status)
+	assert.NotEmpty(t, operation.UndoData)
+
+	assert.ElementsMatch(t, []uint{shared.ID, moved.ID, 3}, collectionBookmarkIDs(t, db, target.ID))
+
+	var reloaded database.Bookmark
+	require.NoError(t, db.First(&reloaded, moved.ID).Error)
+	assert.Equal(t, `["go/testing"]`, reloaded.Tags)
+
+	var movedChild database.Collection
+	require.NoError(t, db.First(&movedChild, child.ID).Error)
+	assert.Equal(t, target.ID, *movedChild.ParentID)
+	assert.ErrorIs(t, db.First(&database.Collection{}, source.ID).Error, gorm.ErrRecordNotFound)
+
+	// Undo restores the source, its sub-collection and the original tags
+	undone, err := service.UndoBulkOperation(1, operation.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "undone", undone.Status)
+	assert.NotNil(t, undone.UndoneAt)
+
+	require.NoError(t, db.First(&database.Collection{}, source.ID).Error)
+	require.NoError(t, db.First(&movedChild, child.ID).Error)
+	assert.Equal(t, source.ID, *movedChild.ParentID)
+	assert.ElementsMatch(t, []uint{shared.ID, 3}, collectionBookmarkIDs(t, db, target.ID))
+	require.NoError(t, db.First(&reloaded, moved.ID).Error)
+	assert.Equal(t, `["golang/testing"]`, reloaded.Tags)
+
+	_, err = service.UndoBulkOperation(1, operation.ID)
+	assert.ErrorIs(t, err, ErrUndoNotAvailable)
+}
+
+func TestCollectionService_MergeCollectionsValidation(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+
+	parent := createBulkTestCollection(t, db, 1, "parent", nil)
+	child := createBulkTestCollection(t, db, 1, "child", &parent.ID)
+
+	_, err := service.MergeCollections(1, MergeCollectionsRequest{SourceID: parent.ID, TargetID: parent.ID})
+	assert.ErrorIs(t, err, ErrSameCollection)
+
+	_, err = service.MergeCollections(1, MergeCollectionsRequest{SourceID: parent.ID, TargetID: child.ID})
+	assert.ErrorIs(t, err, ErrMergeIntoDescendant)
+
+	_, err = service.MergeCollections(2, MergeCollectionsRequest{SourceID: parent.ID, TargetID: child.ID})
+	assert.ErrorIs(t, err, ErrCollectionNotFound)
+
+	// Failed attempts are still tracked
+	operations, err := service.ListBulkOperations(1)
+	require.NoError(t, err)
+	require.Len(t, operations, 1)
+	assert.Equal(t, "failed", operations[0].Status)
+}
+
+func TestCollectionService_SplitCollection(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+
+	source := createBulkTestCollection(t, db, 1, "reading", nil)
+	goBookmark := createBulkTestBookmark(t, db, 1, "https://go.dev/doc", `["dev/go"]`, source)
+	other := createBulkTestBookmark(t, db, 1, "https://example.com/news", `["news"]`, source)
+
+	_, err := service.SplitCollection(1, source.ID, SplitCollectionRequest{Name: "empty filter"})
+	assert.ErrorIs(t, err, ErrSplitFilterRequired)
+
+	operation, err := service.SplitCollection(1, source.ID, SplitCollectionRequest{Name: "Go", Tags: []string{"dev"}})
+	require.NoError(t, err)
+
+	newID := operation.Result["collection_id"].(uint)
+	assert.Equal(t, []uint{goBookmark.ID}, collectionBookmarkIDs(t, db, newID))
+	assert.Equal(t, []uint{other.ID}, collectionBookmarkIDs(t, db, source.ID))
+
+	_, err = service.UndoBulkOperation(1, operation.ID)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uint{goBookmark.ID, other.ID}, collectionBookmarkIDs(t, db, source.ID))
+	assert.ErrorIs(t, db.First(&database.Collection{}, newID).Error, gorm.ErrRecordNotFound)
+}
+
+func TestCollectionService_TransferCollection(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+
+	recipient := &database.User{Email: "friend@example.com", Username: "friend", SupabaseID: "friend-supabase-id"}
+	require.NoError(t, db.Create(recipient).Error)
+
+	parent := createBulkTestCollection(t, db, 1, "parent", nil)
+	root := createBulkTestCollection(t, db, 1, "root", &parent.ID)
+	child := createBulkTestCollection(t, db, 1, "child", &root.ID)
+	elsewhere := createBulkTestCollection(t, db, 1, "elsewhere", nil)
+
+	exclusive := createBulkTestBookmark(t, db, 1, "https://example.com/exclusive", `[]`, child)
+	shared := createBulkTestBookmark(t, db, 1, "https://example.com/shared", `[]`, root, elsewhere)
+
+	_, err := service.TransferCollection(1, root.ID, TransferCollectionRequest{})
+	assert.ErrorIs(t, err, ErrTransferTarget)
+	_, err = service.TransferCollection(1, root.ID, TransferCollectionRequest{Recipient: "testuser"})
+	assert.ErrorIs(t, err, ErrTransferToSelf)
+	_, err = service.TransferCollection(1, root.ID, TransferCollectionRequest{Recipient: "nobody"})
+	assert.ErrorIs(t, err, ErrRecipientNotFound)
+
+	// Nothing changes hands until the recipient accepts
+	transfer, err := service.TransferCollection(1, root.ID, TransferCollectionRequest{Recipient: "friend@example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, database.TransferStatusPending, transfer.Status)
+	_, err = service.TransferCollection(1, root.ID, TransferCollectionRequest{Recipient: "friend"})
+	assert.ErrorIs(t, err, ErrTransferPending)
+
+	var offered database.Collection
+	require.NoError(t, db.First(&offered, root.ID).Error)
+	assert.Equal(t, uint(1), offered.UserID)
+
+	pending, err := service.ListTransfers(recipient.ID)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+
+	_, err = service.AcceptTransfer(1, transfer.ID)
+	assert.ErrorIs(t, err, ErrTransferNotFound)
+	accepted, err := service.AcceptTransfer(recipient.ID, transfer.ID)
+	require.NoError(t, err)
+	assert.Equal(t, database.TransferStatusAccepted, accepted.Status)
+	_, err = service.AcceptTransfer(recipient.ID, transfer.ID)
+	assert.ErrorIs(t, err, ErrTransferNotFound)
+
+	var stored database.CollectionTransfer
+	require.NoError(t, db.First(&stored, transfer.ID).Error)
+	require.NotNil(t, stored.OperationID)
+	operationID := *stored.OperationID
+
+	var transferred database.Collection
+	require.NoError(t, db.First(&transferred, root.ID).Error)
+	assert.Equal(t, recipient.ID, transferred.UserID)
+	assert.Nil(t, transferred.ParentID)
+	var transferredChild database.Collection
+	require.NoError(t, db.First(&transferredChild, child.ID).Error)
+	assert.Equal(t, recipient.ID, transferredChild.UserID)
+
+	var bookmark database.Bookmark
+	require.NoError(t, db.First(&bookmark, exclusive.ID).Error)
+	assert.Equal(t, recipient.ID, bookmark.UserID)
+
+	// The bookmark filed elsewhere stays with the owner; the recipient gets a copy
+	var original database.Bookmark
+	require.NoError(t, db.First(&original, shared.ID).Error)
+	assert.Equal(t, uint(1), original.UserID)
+	rootBookmarks := collectionBookmarkIDs(t, db, root.ID)
+	require.Len(t, rootBookmarks, 1)
+	assert.NotEqual(t, shared.ID, rootBookmarks[0])
+	assert.Equal(t, []uint{shared.ID}, collectionBookmarkIDs(t, db, elsewhere.ID))
+
+	_, err = service.UndoBulkOperation(1, operationID)
+	require.NoError(t, err)
+
+	var restored database.Collection
+	require.NoError(t, db.First(&restored, root.ID).Error)
+	assert.Equal(t, uint(1), restored.UserID)
+	assert.Equal(t, parent.ID, *restored.ParentID)
+	var returned database.Bookmark
+	require.NoError(t, db.First(&returned, exclusive.ID).Error)
+	assert.Equal(t, uint(1), returned.UserID)
+	assert.Equal(t, []uint{shared.ID}, collectionBookmarkIDs(t, db, root.ID))
+}
+
+func TestCollectionService_DeclineAndCancelTransfer(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+
+	recipient := &database.User{Email: "friend@example.com", Username: "friend", SupabaseID: "friend-supabase-id"}
+	require.NoError(t, db.Create(recipient).Error)
+	root := createBulkTestCollection(t, db, 1, "root", nil)
+
+	transfer, err := service.TransferCollection(1, root.ID, TransferCollectionRequest{Recipient: "friend"})
+	require.NoError(t, err)
+	assert.ErrorIs(t, service.CancelTransfer(recipient.ID, transfer.ID), ErrTransferNotFound)
+	require.NoError(t, service.DeclineTransfer(recipient.ID, transfer.ID))
+	_, err = service.AcceptTransfer(recipient.ID, transfer.ID)
+	assert.ErrorIs(t, err, ErrTransferNotFound)
+
+	transfer, err = service.TransferCollection(1, root.ID, TransferCollectionRequest{Recipient: "friend"})
+	require.NoError(t, err)
+	require.NoError(t, service.CancelTransfer(1, transfer.ID))
+	assert.ErrorIs(t, service.DeclineTransfer(recipient.ID, transfer.ID), ErrTransferNotFound)
+
+	var kept database.Collection
+	require.NoError(t, db.First(&kept, root.ID).Error)
+	assert.Equal(t, uint(1), kept.UserID)
+}
+
+func TestCollectionService_TransferToTeam(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+
+	teamOwner := &database.User{Email: "team@example.com", Username: "team", SupabaseID: "team-supabase-id"}
+	require.NoError(t, db.Create(teamOwner).Error)
+	team := createBulkTestCollection(t, db, teamOwner.ID, "team", nil)
+	root := createBulkTestCollection(t, db, 1, "root", nil)
+	bookmark := createBulkTestBookmark(t, db, 1, "https://example.com/team", `[]`, root)
+
+	// Only team collections shared for editing are targets
+	_, err := service.TransferCollection(1, root.ID, TransferCollectionRequest{TeamCollectionID: team.ID})
+	assert.ErrorIs(t, err, ErrTeamCollectionDenied)
+	_, err = service.TransferCollection(1, root.ID, TransferCollectionRequest{Recipient: "team", TeamCollectionID: team.ID})
+	assert.ErrorIs(t, err, ErrTransferTarget)
+
+	require.NoError(t, db.Create(&database.CollectionCollaborator{
+		CollectionID: team.ID, UserID: 1, InviterID: teamOwner.ID, Permission: "edit", Status: "accepted",
+	}).Error)
+
+	transfer, err := service.TransferCollection(1, root.ID, TransferCollectionRequest{TeamCollectionID: team.ID})
+	require.NoError(t, err)
+	assert.Equal(t, database.TransferStatusAccepted, transfer.Status)
+	assert.Equal(t, teamOwner.ID, transfer.ToUserID)
+	require.NotNil(t, transfer.OperationID)
+
+	var moved database.Collection
+	require.NoError(t, db.First(&moved, root.ID).Error)
+	assert.Equal(t, teamOwner.ID, moved.UserID)
+	require.NotNil(t, moved.ParentID)
+	assert.Equal(t, team.ID, *moved.ParentID)
+	var movedBookmark database.Bookmark
+	require.NoError(t, db.First(&movedBookmark, bookmark.ID).Error)
+	assert.Equal(t, teamOwner.ID, movedBookmark.UserID)
+
+	_, err = service.UndoBulkOperation(1, *transfer.OperationID)
+	require.NoError(t, err)
+	var restored database.Collection
+	require.NoError(t, db.First(&restored, root.ID).Error)
+	assert.Equal(t, uint(1), restored.UserID)
+	assert.Nil(t, restored.ParentID)
+}
+
+func TestHandler_CollectionBulkRoutes(t *testing.T) {
+	router, db := setupTestRouter(t)
+
+	source := createBulkTestCollection(t, db, 1, "source", nil)
+	target := createBulkTestCollection(t, db, 1, "target", nil)
+
+	body, _ := json.Marshal(MergeCollectionsRequest{SourceID: source.ID, TargetID: target.ID})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/collections/merge", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/collections/operations", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/collections/operations/1/undo", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/collections/operations/1/undo", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/collections/transfers", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/collections/transfers/99/accept", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	body, _ = json.Marshal(SplitCollectionRequest{Name: "split", Search: "missing"})
+	req = httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/collections/%d/split", source.ID), bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
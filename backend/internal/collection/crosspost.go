@@ -0,0 +1,209 @@
+package collection
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/internal/automation"
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/language"
+	"bookmark-sync-service/backend/pkg/tags"
+)
+
+// BulkTypeCrossPost is a bookmark saved to a personal and a team collection
+// in one call
+const BulkTypeCrossPost = "bookmark_cross_post"
+
+// crossPostDevice marks sync events the server wrote for a cross-post when
+// the client named no device
+const crossPostDevice = "server"
+
+// Cross-posting errors
+var (
+	ErrTeamCollectionDenied = errors.New("team collection is not shared with you for editing")
+	ErrInvalidBookmarkURL   = errors.New("a valid http or https URL is required")
+)
+
+// CrossPostRequest saves a bookmark to one of the user's collections and to
+// a team collection, each copy with its own tags
+type CrossPostRequest struct {
+	URL         string `json:"url" binding:"required"`
+	Title       string `json:"title" binding:"required"`
+	Description string `json:"description"`
+
+	PersonalCollectionID uint     `json:"personal_collection_id" binding:"required"`
+	PersonalTags         []string `json:"personal_tags"`
+	TeamCollectionID     uint     `json:"team_collection_id" binding:"required"`
+	TeamTags             []string `json:"team_tags"`
+
+	// DeviceID is the saving client, so it can skip the sync events it caused
+	DeviceID string `json:"device_id"`
+}
+
+// CrossPost saves a bookmark for the user and for their team at once. The
+// team copy belongs to the team collection's owner, so it stays with the
+// team; team collections are those shared with the user as an accepted
+// collaborator with edit or admin permission. Both saves are one bulk
+// operation, undone together, and their sync events carry its ID so clients
+// can treat them as one change
+func (s *Service) CrossPost(userID uint, req CrossPostRequest) (*automation.BulkOperation, error) {
+	if parsed, err := url.ParseRequestURI(req.URL); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, ErrInvalidBookmarkURL
+	}
+	if req.PersonalCollectionID == req.TeamCollectionID {
+		return nil, ErrSameCollection
+	}
+	device := req.DeviceID
+	if device == "" {
+		device = crossPostDevice
+	}
+
+	params := automation.InterfaceMap{
+		"url":                    req.URL,
+		"personal_collection_id": req.PersonalCollectionID,
+		"team_collection_id":     req.TeamCollectionID,
+	}
+
+	return s.runBulkOperation(userID, BulkTypeCrossPost, params, func(tx *gorm.DB, op *automation.BulkOperation, undo *collectionUndo) error {
+		personal, err := ownedCollection(tx, userID, req.PersonalCollectionID)
+		if err != nil {
+			return err
+		}
+		team, err := teamCollection(tx, userID, req.TeamCollectionID)
+		if err != nil {
+			return err
+		}
+
+		lang := language.Resolve("", req.Title+" "+req.Description)
+		created := make([]uint, 0, 2)
+		for _, target := range []struct {
+			collection *database.Collection
+			tags       []string
+		}{{personal, req.PersonalTags}, {team, req.TeamTags}} {
+			tagJSON, err := json.Marshal(tags.NormalizeAll(target.tags))
+			if err != nil {
+				return fmt.Errorf("failed to marshal tags: %w", err)
+			}
+			bookmark := &database.Bookmark{
+				UserID:      target.collection.UserID,
+				URL:         req.URL,
+				Title:       req.Title,
+				Description: req.Description,
+				Language:    lang,
+				Tags:        string(tagJSON),
+				Status:      "active",
+			}
+			if err := tx.Omit("User", "Collections", "Comments").Create(bookmark).Error; err != nil {
+				return fmt.Errorf("failed to create bookmark: %w", err)
+			}
+			if err := linkBookmarks(tx, target.collection.ID, []uint{bookmark.ID}); err != nil {
+				return err
+			}
+			if err := recordCrossPostEvent(tx, op.ID, bookmark, target.collection.ID, "create", device); err != nil {
+				return err
+			}
+			created = append(created, bookmark.ID)
+		}
+
+		undo.SourceID, undo.TargetID = personal.ID, team.ID
+		undo.CrossPosted = created
+
+		op.TotalItems = len(created)
+		op.ProcessedItems = op.TotalItems
+		op.Result = automation.InterfaceMap{
+			"personal_bookmark_id": created[0],
+			"team_bookmark_id":     created[1],
+		}
+		return nil
+	})
+}
+
+// undoCrossPost deletes both bookmarks of a cross-post and tells the
+// owners' devices, again as one operation
+func undoCrossPost(tx *gorm.DB, operationID uint, undo *collectionUndo) error {
+	collections := []uint{undo.SourceID, undo.TargetID}
+	for i, id := range undo.CrossPosted {
+		var bookmark database.Bookmark
+		if err := tx.First(&bookmark, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue // already deleted by its owner
+			}
+			return fmt.Errorf("failed to find cross-posted bookmark: %w", err)
+		}
+		if err := tx.Exec("DELETE FROM bookmark_collections WHERE bookmark_id = ?", id).Error; err != nil {
+			return fmt.Errorf("failed to remove bookmark from collections: %w", err)
+		}
+		if err := tx.Delete(&bookmark).Error; err != nil {
+			return fmt.Errorf("failed to delete bookmark: %w", err)
+		}
+		if err := recordCrossPostEvent(tx, operationID, &bookmark, collections[i], "delete", crossPostDevice); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// teamCollection loads a collection the user may add bookmarks to for their
+// team: their own, or one they collaborate on with edit or admin permission
+func teamCollection(tx *gorm.DB, userID, id uint) (*database.Collection, error) {
+	var collection database.Collection
+	if err := tx.First(&collection, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCollectionNotFound
+		}
+		return nil, fmt.Errorf("failed to get collection: %w", err)
+	}
+	if collection.UserID == userID {
+		return &collection, nil
+	}
+
+	var collaborators int64
+	if err := tx.Model(&database.CollectionCollaborator{}).
+		Where("collection_id = ? AND user_id = ? AND status = ? AND permission IN ?", id, userID, "accepted", []string{"edit", "admin"}).
+		Count(&collaborators).Error; err != nil {
+		return nil, fmt.Errorf("failed to check collaborator: %w", err)
+	}
+	if collaborators == 0 {
+		return nil, ErrTeamCollectionDenied
+	}
+	return &collection, nil
+}
+
+// recordCrossPostEvent logs a bookmark change of a cross-post for its
+// owner's devices
+func recordCrossPostEvent(tx *gorm.DB, operationID uint, bookmark *database.Bookmark, collectionID uint, action, device string) error {
+	eventType := "bookmark_created"
+	if action == "delete" {
+		eventType = "bookmark_deleted"
+	}
+	data, err := json.Marshal(map[string]interface{}{
+		"operation_id":  operationID,
+		"operation":     BulkTypeCrossPost,
+		"collection_id": collectionID,
+		"url":           bookmark.URL,
+		"title":         bookmark.Title,
+		"tags":          json.RawMessage(bookmark.Tags),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode sync event: %w", err)
+	}
+	event := &database.SyncEvent{
+		Type:       eventType,
+		UserID:     strconv.FormatUint(uint64(bookmark.UserID), 10),
+		ResourceID: strconv.FormatUint(uint64(bookmark.ID), 10),
+		Action:     action,
+		Data:       string(data),
+		DeviceID:   device,
+		Timestamp:  time.Now(),
+	}
+	if err := tx.Create(event).Error; err != nil {
+		return fmt.Errorf("failed to record sync event: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package collection
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+func addTestCollaborator(t *testing.T, db *gorm.DB, collection *database.Collection, userID uint, permission, status string) {
+	collaborator := &database.CollectionCollaborator{
+		CollectionID: collection.ID,
+		UserID:       userID,
+		InviterID:    collection.UserID,
+		Permission:   permission,
+		Status:       status,
+	}
+	require.NoError(t, db.Create(collaborator).Error)
+}
+
+func TestCollectionService_CrossPost(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+
+	personal := createBulkTestCollection(t, db, 1, "reading", nil)
+	team := createBulkTestCollection(t, db, 2, "team-links", nil)
+	addTestCollaborator(t, db, team, 1, "edit", "accepted")
+
+	operation, err := service.CrossPost(1, CrossPostRequest{
+		URL:                  "https://go.dev/blog/loopvar",
+		Title:                "Fixing for loops in Go",
+		PersonalCollectionID: personal.ID,
+		PersonalTags:         []string{"go", "later"},
+		TeamCollectionID:     team.ID,
+		TeamTags:             []string{"release-notes"},
+		DeviceID:             "laptop",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, BulkTypeCrossPost, operation.Type)
+	assert.Equal(t, "completed", operation.Status)
+
+	// Each collection gets its own bookmark with its own tags; the team's
+	// belongs to the team collection's owner
+	personalIDs := collectionBookmarkIDs(t, db, personal.ID)
+	teamIDs := collectionBookmarkIDs(t, db, team.ID)
+	require.Len(t, personalIDs, 1)
+	require.Len(t, teamIDs, 1)
+	var mine, theirs database.Bookmark
+	require.NoError(t, db.First(&mine, personalIDs[0]).Error)
+	require.NoError(t, db.First(&theirs, teamIDs[0]).Error)
+	assert.Equal(t, uint(1), mine.UserID)
+	assert.Equal(t, `["go","later"]`, mine.Tags)
+	assert.Equal(t, uint(2), theirs.UserID)
+	assert.Equal(t, `["release-notes"]`, theirs.Tags)
+
+	// Both owners' devices learn about it as parts of the same operation
+	var events []database.SyncEvent
+	require.NoError(t, db.Order("id").Find(&events).Error)
+	require.Len(t, events, 2)
+	assert.Equal(t, []string{"1", "2"}, []string{events[0].UserID, events[1].UserID})
+	for _, event := range events {
+		assert.Equal(t, "bookmark_created", event.Type)
+		assert.Equal(t, "laptop", event.DeviceID)
+		var data map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(event.Data), &data))
+		assert.Equal(t, float64(operation.ID), data["operation_id"])
+	}
+
+	// Undo removes both saves at once
+	_, err = service.UndoBulkOperation(1, operation.ID)
+	require.NoError(t, err)
+	assert.Empty(t, collectionBookmarkIDs(t, db, personal.ID))
+	assert.Empty(t, collectionBookmarkIDs(t, db, team.ID))
+	var remaining int64
+	require.NoError(t, db.Model(&database.Bookmark{}).Count(&remaining).Error)
+	assert.Zero(t, remaining)
+	var deleted int64
+	require.NoError(t, db.Model(&database.SyncEvent{}).Where("type = ?", "bookmark_deleted").Count(&deleted).Error)
+	assert.Equal(t, int64(2), deleted)
+}
+
+func TestCollectionService_CrossPostRequiresEditAccess(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+
+	personal := createBulkTestCollection(t, db, 1, "reading", nil)
+	viewOnly := createBulkTestCollection(t, db, 2, "view-only", nil)
+	addTestCollaborator(t, db, viewOnly, 1, "view", "accepted")
+	pending := createBulkTestCollection(t, db, 3, "pending", nil)
+	addTestCollaborator(t, db, pending, 1, "edit", "pending")
+
+	request := CrossPostRequest{URL: "https://example.com", Title: "Example", PersonalCollectionID: personal.ID}
+	for _, team := range []*database.Collection{viewOnly, pending} {
+		request.TeamCollectionID = team.ID
+		_, err := service.CrossPost(1, request)
+		assert.ErrorIs(t, err, ErrTeamCollectionDenied, team.Name)
+	}
+
+	// Another user's personal collection is not a target either
+	request.PersonalCollectionID, request.TeamCollectionID = viewOnly.ID, personal.ID
+	_, err := service.CrossPost(1, request)
+	assert.ErrorIs(t, err, ErrCollectionNotFound)
+
+	request.URL = "javascript:alert(1)"
+	_, err = service.CrossPost(1, request)
+	assert.ErrorIs(t, err, ErrInvalidBookmarkURL)
+
+	var bookmarks int64
+	require.NoError(t, db.Model(&database.Bookmark{}).Count(&bookmarks).Error)
+	assert.Zero(t, bookmarks)
+}
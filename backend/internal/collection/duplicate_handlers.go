@@ -0,0 +1,164 @@
+package collection
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"bookmark-sync-service/backend/pkg/utils"
+)
+
+// RegisterAdminRoutes registers the duplicate cluster review routes
+func (h *Handler) RegisterAdminRoutes(router *gin.RouterGroup) {
+	router.GET("/collection-clusters", h.ListClusters)
+	router.POST("/collection-clusters/detect", h.DetectDuplicates)
+	router.PUT("/collection-clusters/:id", h.ReviewCluster)
+}
+
+// DiscoverCollections lists public collections without near-duplicates
+// @Summary Discover public collections
+// @Description List public collections from all users. Near-duplicate collections are collapsed to the most-followed representative of their cluster
+// @Tags collections
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(20)
+// @Param search query string false "Search in name and description"
+// @Success 200 {object} ListCollectionsResult
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/collections/discover [get]
+func (h *Handler) DiscoverCollections(c *gin.Context) {
+	var params DiscoverParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid query parameters", nil)
+		return
+	}
+
+	result, err := h.service.Discover(params)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to discover collections", nil)
+		return
+	}
+
+	utils.SuccessResponse(c, result, "Collections retrieved successfully")
+}
+
+// GetSimilarCollections suggests public collections similar to one of the user's
+// @Summary Get similar collections
+// @Description Suggest other users' public collections whose bookmarks overlap the collection
+// @Tags collections
+// @Produce json
+// @Param id path int true "Collection ID"
+// @Success 200 {array} SimilarCollection
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/collections/{id}/similar [get]
+func (h *Handler) GetSimilarCollections(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid collection ID", nil)
+		return
+	}
+
+	similar, err := h.service.SimilarCollections(userID, uint(id))
+	if err != nil {
+		duplicateErrorResponse(c, err, "Failed to find similar collections")
+		return
+	}
+
+	utils.SuccessResponse(c, similar, "Similar collections retrieved successfully")
+}
+
+// ListClusters lists duplicate collection clusters for review
+// @Summary List duplicate collection clusters
+// @Description List clusters of near-duplicate public collections for admin review
+// @Tags admin
+// @Produce json
+// @Param status query string false "pending, confirmed or dismissed"
+// @Success 200 {array} database.CollectionCluster
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/admin/collection-clusters [get]
+func (h *Handler) ListClusters(c *gin.Context) {
+	clusters, err := h.service.ListClusters(c.Query("status"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to list clusters", nil)
+		return
+	}
+
+	utils.SuccessResponse(c, clusters, "Clusters retrieved successfully")
+}
+
+// DetectDuplicates runs duplicate detection across public collections
+// @Summary Detect duplicate collections
+// @Description Compare the bookmarks of all public collections and cluster near-duplicates
+// @Tags admin
+// @Produce json
+// @Success 200 {object} DetectDuplicatesResult
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/admin/collection-clusters/detect [post]
+func (h *Handler) DetectDuplicates(c *gin.Context) {
+	result, err := h.service.DetectDuplicates()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to detect duplicate collections", nil)
+		return
+	}
+
+	utils.SuccessResponse(c, result, "Duplicate detection completed")
+}
+
+// ReviewCluster confirms or dismisses a duplicate cluster
+// @Summary Review a duplicate collection cluster
+// @Description Confirm a cluster, optionally choosing its representative, or dismiss it so its members show up in discovery again
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "Cluster ID"
+// @Param request body ReviewClusterRequest true "Review decision"
+// @Success 200 {object} database.CollectionCluster
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/admin/collection-clusters/{id} [put]
+func (h *Handler) ReviewCluster(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid cluster ID", nil)
+		return
+	}
+
+	var req ReviewClusterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid request data", nil)
+		return
+	}
+
+	cluster, err := h.service.ReviewCluster(uint(id), req)
+	if err != nil {
+		duplicateErrorResponse(c, err, "Failed to review cluster")
+		return
+	}
+
+	utils.SuccessResponse(c, cluster, "Cluster reviewed successfully")
+}
+
+// duplicateErrorResponse maps duplicate detection errors to HTTP responses
+func duplicateErrorResponse(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, ErrCollectionNotFound), errors.Is(err, ErrClusterNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, "NOT_FOUND", err.Error(), nil)
+	case errors.Is(err, ErrNotClusterMember), errors.Is(err, ErrInvalidReviewState):
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error(), nil)
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", message, nil)
+	}
+}
@@ -0,0 +1,513 @@
+package collection
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+// Duplicate cluster review states
+const (
+	ClusterStatusPending   = "pending"
+	ClusterStatusConfirmed = "confirmed"
+	ClusterStatusDismissed = "dismissed"
+)
+
+const (
+	// duplicateThreshold is the Jaccard overlap at which two public
+	// collections are treated as near-duplicates
+	duplicateThreshold = 0.7
+	// similarThreshold is the lower overlap at which a collection is
+	// suggested to an owner as similar
+	similarThreshold = 0.3
+	// duplicateMinBookmarks keeps tiny collections, which overlap by chance,
+	// out of clustering
+	duplicateMinBookmarks = 3
+	maxSimilarCollections = 20
+)
+
+// Duplicate detection errors
+var (
+	ErrClusterNotFound    = errors.New("collection cluster not found")
+	ErrNotClusterMember   = errors.New("representative must be a member of the cluster")
+	ErrInvalidReviewState = errors.New("status must be confirmed or dismissed")
+)
+
+// DetectDuplicatesResult summarizes a duplicate detection run
+type DetectDuplicatesResult struct {
+	Collections int `json:"collections"` // public collections compared
+	Clusters    int `json:"clusters"`    // clusters found, including known ones
+	New         int `json:"new"`         // clusters added for review
+	Removed     int `json:"removed"`     // stale clusters removed
+}
+
+// ReviewClusterRequest records an admin decision on a cluster
+type ReviewClusterRequest struct {
+	Status           string `json:"status" binding:"required,oneof=confirmed dismissed"`
+	RepresentativeID *uint  `json:"representative_id,omitempty"`
+}
+
+// SimilarCollection is a public collection overlapping one of the user's own
+type SimilarCollection struct {
+	Collection      database.Collection `json:"collection"`
+	Similarity      float64             `json:"similarity"`
+	SharedBookmarks int                 `json:"shared_bookmarks"`
+}
+
+// DiscoverParams represents parameters for browsing public collections
+type DiscoverParams struct {
+	Page   int    `form:"page,default=1" binding:"min=1"`
+	Limit  int    `form:"limit,default=20" binding:"min=1,max=100"`
+	Search string `form:"search"`
+}
+
+// overlapKey reduces a bookmark URL to the form compared between
+// collections, so "https://www.example.com/a/" and "http://example.com/a"
+// count as the same link
+func overlapKey(rawURL string) string {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || u.Host == "" {
+		return strings.ToLower(strings.TrimSpace(rawURL))
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+	return host + strings.TrimSuffix(u.EscapedPath(), "/") + "?" + u.RawQuery
+}
+
+// publicLinkSets loads the overlap keys of every public collection's bookmarks
+func (s *Service) publicLinkSets() (map[uint]map[string]struct{}, error) {
+	var rows []struct {
+		CollectionID uint
+		URL          string
+	}
+	err := s.db.Table("bookmark_collections").
+		Select("bookmark_collections.collection_id, bookmarks.url").
+		Joins("JOIN bookmarks ON bookmarks.id = bookmark_collections.bookmark_id AND bookmarks.deleted_at IS NULL").
+		Joins("JOIN collections ON collections.id = bookmark_collections.collection_id AND collections.deleted_at IS NULL").
+		Where("collections.visibility = ?", "public").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load public collection bookmarks: %w", err)
+	}
+
+	sets := make(map[uint]map[string]struct{})
+	for _, row := range rows {
+		if sets[row.CollectionID] == nil {
+			sets[row.CollectionID] = make(map[string]struct{})
+		}
+		sets[row.CollectionID][overlapKey(row.URL)] = struct{}{}
+	}
+	return sets, nil
+}
+
+// jaccard is the share of the two collections' combined links they have in common
+func jaccard(shared, sizeA, sizeB int) float64 {
+	union := sizeA + sizeB - shared
+	if union == 0 {
+		return 0
+	}
+	return float64(shared) / float64(union)
+}
+
+// sharedCounts counts the links each pair of collections has in common
+func sharedCounts(sets map[uint]map[string]struct{}) map[[2]uint]int {
+	index := make(map[string][]uint)
+	for id, set := range sets {
+		for key := range set {
+			index[key] = append(index[key], id)
+		}
+	}
+
+	shared := make(map[[2]uint]int)
+	for _, ids := range index {
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		for i := 0; i < len(ids); i++ {
+			for j := i + 1; j < len(ids); j++ {
+				shared[[2]uint{ids[i], ids[j]}]++
+			}
+		}
+	}
+	return shared
+}
+
+// duplicateGroup is a cluster found by a detection run
+type duplicateGroup struct {
+	members    []uint
+	similarity float64
+}
+
+// findDuplicateGroups links collections whose overlap reaches the
+// duplicate threshold and returns the connected groups
+func findDuplicateGroups(sets map[uint]map[string]struct{}) []duplicateGroup {
+	parent := make(map[uint]uint)
+	var find func(id uint) uint
+	find = func(id uint) uint {
+		if parent[id] != id {
+			parent[id] = find(parent[id])
+		}
+		return parent[id]
+	}
+
+	weakest := make(map[uint]float64)
+	for pair, shared := range sharedCounts(sets) {
+		a, b := pair[0], pair[1]
+		if len(sets[a]) < duplicateMinBookmarks || len(sets[b]) < duplicateMinBookmarks {
+			continue
+		}
+		similarity := jaccard(shared, len(sets[a]), len(sets[b]))
+		if similarity < duplicateThreshold {
+			continue
+		}
+		for _, id := range pair {
+			if _, ok := parent[id]; !ok {
+				parent[id] = id
+				weakest[id] = 1
+			}
+		}
+		rootA, rootB := find(a), find(b)
+		low := minFloat(similarity, minFloat(weakest[rootA], weakest[rootB]))
+		if rootA != rootB {
+			parent[rootB] = rootA
+		}
+		weakest[rootA] = low
+	}
+
+	byRoot := make(map[uint][]uint)
+	for id := range parent {
+		root := find(id)
+		byRoot[root] = append(byRoot[root], id)
+	}
+
+	groups := make([]duplicateGroup, 0, len(byRoot))
+	for root, members := range byRoot {
+		sort.Slice(members, func(i, j int) bool { return members[i] < members[j] })
+		groups = append(groups, duplicateGroup{members: members, similarity: weakest[root]})
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].members[0] < groups[j].members[0] })
+	return groups
+}
+
+func minFloat(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// clusterFingerprint identifies a cluster by its members so a dismissed
+// cluster is not raised again until its membership changes
+func clusterFingerprint(members []uint) string {
+	parts := make([]string, len(members))
+	for i, id := range members {
+		parts[i] = strconv.FormatUint(uint64(id), 10)
+	}
+	sum := sha256.Sum256([]byte(strings.Join(parts, ",")))
+	return hex.EncodeToString(sum[:])
+}
+
+// pickRepresentative prefers the collection whose owner has the most
+// followers, then the larger collection, then the oldest
+func pickRepresentative(db *gorm.DB, members []uint, sets map[uint]map[string]struct{}) (uint, error) {
+	var owners []struct {
+		ID     uint
+		UserID uint
+	}
+	if err := db.Model(&database.Collection{}).Select("id, user_id").Where("id IN ?", members).Scan(&owners).Error; err != nil {
+		return 0, fmt.Errorf("failed to load collection owners: %w", err)
+	}
+
+	ownerIDs := make([]uint, 0, len(owners))
+	ownerOf := make(map[uint]uint, len(owners))
+	for _, owner := range owners {
+		ownerIDs = append(ownerIDs, owner.UserID)
+		ownerOf[owner.ID] = owner.UserID
+	}
+
+	var counts []struct {
+		FollowingID uint
+		Followers   int64
+	}
+	if err := db.Model(&database.Follow{}).Select("following_id, COUNT(*) AS followers").
+		Where("following_id IN ?", ownerIDs).Group("following_id").Scan(&counts).Error; err != nil {
+		return 0, fmt.Errorf("failed to count followers: %w", err)
+	}
+	followers := make(map[uint]int64, len(counts))
+	for _, count := range counts {
+		followers[count.FollowingID] = count.Followers
+	}
+
+	best := members[0]
+	for _, id := range members[1:] {
+		switch {
+		case followers[ownerOf[id]] != followers[ownerOf[best]]:
+			if followers[ownerOf[id]] > followers[ownerOf[best]] {
+				best = id
+			}
+		case len(sets[id]) > len(sets[best]):
+			best = id
+		}
+	}
+	return best, nil
+}
+
+// DetectDuplicates clusters near-duplicate public collections. Clusters
+// already known keep their review state, dismissed clusters are not raised
+// again and clusters that no longer hold are removed
+func (s *Service) DetectDuplicates() (*DetectDuplicatesResult, error) {
+	sets, err := s.publicLinkSets()
+	if err != nil {
+		return nil, err
+	}
+	groups := findDuplicateGroups(sets)
+	result := &DetectDuplicatesResult{Collections: len(sets), Clusters: len(groups)}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var existing []database.CollectionCluster
+		if err := tx.Find(&existing).Error; err != nil {
+			return fmt.Errorf("failed to load clusters: %w", err)
+		}
+		known := make(map[string]bool, len(existing))
+		for _, cluster := range existing {
+			known[cluster.Fingerprint] = true
+		}
+
+		found := make(map[string]bool, len(groups))
+		for _, group := range groups {
+			fingerprint := clusterFingerprint(group.members)
+			found[fingerprint] = true
+			if known[fingerprint] {
+				continue
+			}
+
+			representative, err := pickRepresentative(tx, group.members, sets)
+			if err != nil {
+				return err
+			}
+			cluster := &database.CollectionCluster{
+				RepresentativeID: representative,
+				Status:           ClusterStatusPending,
+				Similarity:       group.similarity,
+				Fingerprint:      fingerprint,
+			}
+			for _, id := range group.members {
+				cluster.Members = append(cluster.Members, database.CollectionClusterMember{CollectionID: id})
+			}
+			if err := tx.Create(cluster).Error; err != nil {
+				return fmt.Errorf("failed to create cluster: %w", err)
+			}
+			result.New++
+		}
+
+		for _, cluster := range existing {
+			if found[cluster.Fingerprint] || cluster.Status == ClusterStatusDismissed {
+				continue
+			}
+			if err := tx.Where("cluster_id = ?", cluster.ID).Delete(&database.CollectionClusterMember{}).Error; err != nil {
+				return fmt.Errorf("failed to remove cluster members: %w", err)
+			}
+			if err := tx.Delete(&database.CollectionCluster{}, cluster.ID).Error; err != nil {
+				return fmt.Errorf("failed to remove cluster: %w", err)
+			}
+			result.Removed++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// ListClusters lists duplicate clusters for admin review, newest first
+func (s *Service) ListClusters(status string) ([]database.CollectionCluster, error) {
+	query := s.db.Preload("Members.Collection").Order("created_at DESC")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var clusters []database.CollectionCluster
+	if err := query.Find(&clusters).Error; err != nil {
+		return nil, fmt.Errorf("failed to list clusters: %w", err)
+	}
+	return clusters, nil
+}
+
+// ReviewCluster confirms or dismisses a cluster, optionally choosing a
+// different representative
+func (s *Service) ReviewCluster(id uint, req ReviewClusterRequest) (*database.CollectionCluster, error) {
+	if req.Status != ClusterStatusConfirmed && req.Status != ClusterStatusDismissed {
+		return nil, ErrInvalidReviewState
+	}
+
+	var cluster database.CollectionCluster
+	if err := s.db.Preload("Members").First(&cluster, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrClusterNotFound
+		}
+		return nil, fmt.Errorf("failed to get cluster: %w", err)
+	}
+
+	updates := map[string]interface{}{
+		"status":      req.Status,
+		"reviewed_at": time.Now(),
+	}
+	if req.RepresentativeID != nil {
+		member := false
+		for _, m := range cluster.Members {
+			member = member || m.CollectionID == *req.RepresentativeID
+		}
+		if !member {
+			return nil, ErrNotClusterMember
+		}
+		updates["representative_id"] = *req.RepresentativeID
+	}
+
+	if err := s.db.Model(&cluster).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("failed to review cluster: %w", err)
+	}
+
+	if err := s.db.Preload("Members.Collection").First(&cluster, id).Error; err != nil {
+		return nil, fmt.Errorf("failed to get cluster: %w", err)
+	}
+	return &cluster, nil
+}
+
+// Discover lists public collections from all users. Of each duplicate
+// cluster that has not been dismissed only the representative is shown,
+// and collections whose bookmarks are mostly suppressed for low quality
+// are left out
+func (s *Service) Discover(params DiscoverParams) (*ListCollectionsResult, error) {
+	if params.Page < 1 {
+		params.Page = 1
+	}
+	if params.Limit < 1 {
+		params.Limit = 20
+	}
+
+	hidden := s.db.Table("collection_cluster_members").
+		Select("collection_cluster_members.collection_id").
+		Joins("JOIN collection_clusters ON collection_clusters.id = collection_cluster_members.cluster_id AND collection_clusters.deleted_at IS NULL").
+		Where("collection_clusters.status <> ?", ClusterStatusDismissed).
+		Where("collection_cluster_members.collection_id <> collection_clusters.representative_id")
+
+	lowQuality := s.db.Table("bookmark_collections").
+		Select("collection_id").
+		Group("collection_id").
+		Having("SUM(CASE WHEN bookmark_id IN (?) THEN 1 ELSE 0 END) * 2 > COUNT(*)", database.SuppressedBookmarks(s.db))
+
+	query := s.db.Model(&database.Collection{}).
+		Where("visibility = ?", "public").
+		Where("id NOT IN (?)", hidden).
+		Where("id NOT IN (?)", lowQuality)
+
+	if params.Search != "" {
+		searchTerm := "%" + strings.ToLower(params.Search) + "%"
+		query = query.Where("LOWER(name) LIKE ? OR LOWER(description) LIKE ?", searchTerm, searchTerm)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, fmt.Errorf("failed to count collections: %w", err)
+	}
+
+	var collections []database.Collection
+	offset := (params.Page - 1) * params.Limit
+	if err := query.Order("updated_at DESC").Offset(offset).Limit(params.Limit).
+		Preload("User").Find(&collections).Error; err != nil {
+		return nil, fmt.Errorf("failed to discover collections: %w", err)
+	}
+
+	return &ListCollectionsResult{
+		Collections: collections,
+		Total:       total,
+		Page:        params.Page,
+		Limit:       params.Limit,
+		TotalPages:  int((total + int64(params.Limit) - 1) / int64(params.Limit)),
+	}, nil
+}
+
+// SimilarCollections suggests other users' public collections that overlap
+// one of the user's collections, most similar first
+func (s *Service) SimilarCollections(userID, id uint) ([]SimilarCollection, error) {
+	var collection database.Collection
+	if err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&collection).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCollectionNotFound
+		}
+		return nil, fmt.Errorf("failed to get collection: %w", err)
+	}
+
+	var urls []string
+	if err := s.db.Table("bookmark_collections").Select("bookmarks.url").
+		Joins("JOIN bookmarks ON bookmarks.id = bookmark_collections.bookmark_id AND bookmarks.deleted_at IS NULL").
+		Where("bookmark_collections.collection_id = ?", id).Scan(&urls).Error; err != nil {
+		return nil, fmt.Errorf("failed to load collection bookmarks: %w", err)
+	}
+	own := make(map[string]struct{}, len(urls))
+	for _, u := range urls {
+		own[overlapKey(u)] = struct{}{}
+	}
+	if len(own) == 0 {
+		return []SimilarCollection{}, nil
+	}
+
+	sets, err := s.publicLinkSets()
+	if err != nil {
+		return nil, err
+	}
+
+	scores := make(map[uint]SimilarCollection)
+	candidates := make([]uint, 0)
+	for otherID, set := range sets {
+		if otherID == id {
+			continue
+		}
+		shared := 0
+		for key := range set {
+			if _, ok := own[key]; ok {
+				shared++
+			}
+		}
+		similarity := jaccard(shared, len(own), len(set))
+		if shared == 0 || similarity < similarThreshold {
+			continue
+		}
+		scores[otherID] = SimilarCollection{Similarity: similarity, SharedBookmarks: shared}
+		candidates = append(candidates, otherID)
+	}
+	if len(candidates) == 0 {
+		return []SimilarCollection{}, nil
+	}
+
+	var others []database.Collection
+	if err := s.db.Where("id IN ? AND user_id <> ?", candidates, userID).Preload("User").Find(&others).Error; err != nil {
+		return nil, fmt.Errorf("failed to load similar collections: %w", err)
+	}
+
+	similar := make([]SimilarCollection, 0, len(others))
+	for _, other := range others {
+		entry := scores[other.ID]
+		entry.Collection = other
+		similar = append(similar, entry)
+	}
+	sort.Slice(similar, func(i, j int) bool {
+		if similar[i].Similarity != similar[j].Similarity {
+			return similar[i].Similarity > similar[j].Similarity
+		}
+		return similar[i].Collection.ID < similar[j].Collection.ID
+	})
+	if len(similar) > maxSimilarCollections {
+		similar = similar[:maxSimilarCollections]
+	}
+	return similar, nil
+}
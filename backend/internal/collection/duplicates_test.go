@@ -0,0 +1,131 @@
+package collection
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+func createPublicCollection(t *testing.T, db *gorm.DB, userID uint, name string, urls ...string) *database.Collection {
+	collection := &database.Collection{UserID: userID, Name: name, Visibility: "public", ShareLink: name}
+	require.NoError(t, db.Create(collection).Error)
+
+	for _, u := range urls {
+		bookmark := &database.Bookmark{UserID: userID, URL: u, Title: u}
+		require.NoError(t, db.Create(bookmark).Error)
+		require.NoError(t, db.Model(collection).Association("Bookmarks").Append(bookmark))
+	}
+	return collection
+}
+
+func createUsers(t *testing.T, db *gorm.DB, names ...string) []database.User {
+	users := make([]database.User, 0, len(names))
+	for _, name := range names {
+		user := database.User{Email: name + "@example.com", Username: name, SupabaseID: name}
+		require.NoError(t, db.Create(&user).Error)
+		users = append(users, user)
+	}
+	return users
+}
+
+func TestOverlapKey(t *testing.T) {
+	assert.Equal(t, overlapKey("https://www.example.com/a/"), overlapKey("http://example.com/a"))
+	assert.NotEqual(t, overlapKey("https://example.com/a?page=1"), overlapKey("https://example.com/a?page=2"))
+}
+
+func TestCollectionService_DetectDuplicates(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+	users := createUsers(t, db, "alice", "bob", "carol")
+
+	a := createPublicCollection(t, db, users[0].ID, "go-a", "https://go.dev", "https://pkg.go.dev", "https://gobyexample.com", "https://go.dev/blog")
+	b := createPublicCollection(t, db, users[1].ID, "go-b", "https://www.go.dev/", "https://pkg.go.dev", "https://gobyexample.com", "https://go.dev/blog")
+	c := createPublicCollection(t, db, 1, "go-c", "https://go.dev", "https://pkg.go.dev", "https://gobyexample.com", "https://tour.golang.org")
+
+	// bob is the most followed owner, so his collection represents the cluster
+	require.NoError(t, db.Create(&database.Follow{FollowerID: 1, FollowingID: users[1].ID}).Error)
+	require.NoError(t, db.Create(&database.Follow{FollowerID: users[2].ID, FollowingID: users[1].ID}).Error)
+
+	result, err := service.DetectDuplicates()
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Clusters)
+	assert.Equal(t, 1, result.New)
+
+	clusters, err := service.ListClusters(ClusterStatusPending)
+	require.NoError(t, err)
+	require.Len(t, clusters, 1)
+	assert.Equal(t, b.ID, clusters[0].RepresentativeID)
+	assert.Len(t, clusters[0].Members, 2)
+	assert.InDelta(t, 1.0, clusters[0].Similarity, 0.001)
+
+	// Running again keeps the known cluster
+	result, err = service.DetectDuplicates()
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.New)
+
+	// Discovery hides the non-representative member
+	discovered, err := service.Discover(DiscoverParams{Page: 1, Limit: 20})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), discovered.Total)
+	for _, collection := range discovered.Collections {
+		assert.NotEqual(t, a.ID, collection.ID)
+	}
+
+	// A dismissed cluster no longer affects discovery and is not raised again
+	_, err = service.ReviewCluster(clusters[0].ID, ReviewClusterRequest{Status: ClusterStatusDismissed})
+	require.NoError(t, err)
+	discovered, err = service.Discover(DiscoverParams{Page: 1, Limit: 20})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), discovered.Total)
+	result, err = service.DetectDuplicates()
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.New)
+
+	_, err = service.ReviewCluster(clusters[0].ID, ReviewClusterRequest{Status: ClusterStatusConfirmed, RepresentativeID: &c.ID})
+	assert.ErrorIs(t, err, ErrNotClusterMember)
+}
+
+func TestCollectionService_DiscoverHidesLowQuality(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+
+	spam := createPublicCollection(t, db, 1, "spam", "https://bit.ly/a", "https://bit.ly/b", "https://example.com")
+	createPublicCollection(t, db, 2, "reading", "https://go.dev", "https://bit.ly/c")
+
+	var bookmarks []database.Bookmark
+	require.NoError(t, db.Where("url LIKE ?", "https://bit.ly/%").Find(&bookmarks).Error)
+	for _, bookmark := range bookmarks {
+		require.NoError(t, db.Create(&database.BookmarkQuality{BookmarkID: bookmark.ID, UserID: bookmark.UserID, Score: 10, Suppressed: true}).Error)
+	}
+
+	// Most of the spam collection is suppressed, half of the other one
+	discovered, err := service.Discover(DiscoverParams{Page: 1, Limit: 20})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), discovered.Total)
+	require.Len(t, discovered.Collections, 1)
+	assert.NotEqual(t, spam.ID, discovered.Collections[0].ID)
+}
+
+func TestCollectionService_SimilarCollections(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+	users := createUsers(t, db, "alice")
+
+	own := createPublicCollection(t, db, 1, "mine", "https://a.example", "https://b.example", "https://c.example", "https://d.example")
+	other := createPublicCollection(t, db, users[0].ID, "theirs", "https://a.example", "https://b.example", "https://c.example")
+	createPublicCollection(t, db, users[0].ID, "unrelated", "https://z.example", "https://a.example", "https://y.example", "https://x.example")
+
+	similar, err := service.SimilarCollections(1, own.ID)
+	require.NoError(t, err)
+	require.Len(t, similar, 1)
+	assert.Equal(t, other.ID, similar[0].Collection.ID)
+	assert.Equal(t, 3, similar[0].SharedBookmarks)
+	assert.InDelta(t, 0.75, similar[0].Similarity, 0.001)
+
+	_, err = service.SimilarCollections(users[0].ID, own.ID)
+	assert.ErrorIs(t, err, ErrCollectionNotFound)
+}
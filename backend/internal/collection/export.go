@@ -0,0 +1,87 @@
+package collection
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"bookmark-sync-service/backend/pkg/exporter"
+)
+
+// defaultExportFormat is the self-contained archive, the only format that
+// can be imported into another account with its structure
+const defaultExportFormat = "archive"
+
+// ScreenshotFetcher downloads stored screenshots, e.g. the screenshot service
+type ScreenshotFetcher interface {
+	FetchScreenshot(ctx context.Context, screenshotURL string) ([]byte, string, error)
+}
+
+// SetScreenshots lets collection exports include the screenshots of their
+// bookmarks. Without it exports only hold the screenshot URLs
+func (s *Service) SetScreenshots(fetcher ScreenshotFetcher) {
+	s.screenshots = fetcher
+}
+
+// ExportRequest chooses what a collection export holds. Only the archive
+// format holds archived copies and screenshots
+type ExportRequest struct {
+	Format             string `json:"format,omitempty"` // a registered export format, archive by default
+	IncludeDescendants bool   `json:"include_descendants"`
+	IncludeArchives    bool   `json:"include_archives"`
+	IncludeScreenshots bool   `json:"include_screenshots"`
+	IncludeAnnotations bool   `json:"include_annotations"` // the bookmarks' notes
+}
+
+// Export loads a collection of the user, and its descendants when asked,
+// for export and returns the format to write it in
+func (s *Service) Export(ctx context.Context, userID, collectionID uint, req ExportRequest) (*exporter.Data, exporter.Format, error) {
+	name := req.Format
+	if name == "" {
+		name = defaultExportFormat
+	}
+	format, err := exporter.Lookup(name)
+	if err != nil {
+		return nil, exporter.Format{}, err
+	}
+
+	all, err := exporter.Load(ctx, s.db, userID)
+	if err != nil {
+		return nil, format, err
+	}
+	data, ok := all.Subtree(collectionID, req.IncludeDescendants)
+	if !ok {
+		return nil, format, ErrCollectionNotFound
+	}
+
+	if !req.IncludeAnnotations {
+		for n := range data.Bookmarks {
+			data.Bookmarks[n].Notes = ""
+		}
+		for _, collection := range data.Collections {
+			for m := range collection.Bookmarks {
+				collection.Bookmarks[m].Notes = ""
+			}
+		}
+	}
+	if req.IncludeArchives {
+		if err := exporter.LoadArchives(ctx, s.db, data); err != nil {
+			return nil, format, err
+		}
+	}
+	if req.IncludeScreenshots && s.screenshots != nil {
+		for _, bookmark := range data.Bookmarks {
+			if bookmark.Screenshot == "" {
+				continue
+			}
+			// A screenshot that can't be fetched is left as its URL
+			image, contentType, err := s.screenshots.FetchScreenshot(ctx, bookmark.Screenshot)
+			if err != nil {
+				s.logger.Warn("Failed to fetch screenshot", zap.Uint("bookmark_id", bookmark.ID), zap.Error(err))
+				continue
+			}
+			data.AttachScreenshot(bookmark.ID, image, contentType)
+		}
+	}
+	return data, format, nil
+}
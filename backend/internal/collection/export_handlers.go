@@ -0,0 +1,69 @@
+package collection
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"bookmark-sync-service/backend/pkg/exporter"
+	"bookmark-sync-service/backend/pkg/utils"
+)
+
+// ExportCollection downloads a collection in an export format
+// @Summary Export a collection
+// @Description Export one collection, optionally with its sub-collections, archived copies, screenshots and notes. The default archive format is a zip that the archive import recreates in any account
+// @Tags collections
+// @Accept json
+// @Produce application/zip
+// @Param id path int true "Collection ID"
+// @Param request body ExportRequest false "Export options"
+// @Success 200 {file} file
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/collections/{id}/export [post]
+func (h *Handler) ExportCollection(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid collection ID", nil)
+		return
+	}
+
+	// The options are optional, so an empty body exports the collection alone
+	var req ExportRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid request data", nil)
+		return
+	}
+
+	data, format, err := h.service.Export(c.Request.Context(), userID, uint(id), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, exporter.ErrUnknownFormat):
+			utils.ErrorResponse(c, http.StatusBadRequest, "UNKNOWN_FORMAT", err.Error(), nil)
+		case errors.Is(err, ErrCollectionNotFound):
+			utils.ErrorResponse(c, http.StatusNotFound, "NOT_FOUND", "Collection not found", nil)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, "EXPORT_FAILED", "Failed to export collection", nil)
+		}
+		return
+	}
+
+	c.Header("Content-Type", format.ContentType)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=collection_%d.%s", id, format.Extension))
+	if err := format.Exporter.Export(c.Writer, data); err != nil {
+		// The download has started, so the error can't be reported
+		c.Error(err)
+	}
+}
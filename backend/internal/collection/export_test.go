@@ -0,0 +1,90 @@
+package collection
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/exporter"
+)
+
+type stubScreenshotFetcher struct{}
+
+func (stubScreenshotFetcher) FetchScreenshot(_ context.Context, screenshotURL string) ([]byte, string, error) {
+	if screenshotURL == "https://storage.example.com/missing.png" {
+		return nil, "", errors.New("not found")
+	}
+	return []byte("png"), "image/png", nil
+}
+
+func TestCollectionService_Export(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+	service.SetScreenshots(stubScreenshotFetcher{})
+	ctx := context.Background()
+
+	reading := createBulkTestCollection(t, db, 1, "reading", nil)
+	web := createBulkTestCollection(t, db, 1, "web", &reading.ID)
+	createBulkTestCollection(t, db, 1, "other", nil)
+	goDev := createBulkTestBookmark(t, db, 1, "https://go.dev/", `[]`, reading)
+	mdn := createBulkTestBookmark(t, db, 1, "https://developer.mozilla.org/", `[]`, web)
+	require.NoError(t, db.Model(goDev).Updates(map[string]interface{}{"notes": "Start here", "screenshot": "https://storage.example.com/go.png"}).Error)
+	require.NoError(t, db.Model(mdn).Update("screenshot", "https://storage.example.com/missing.png").Error)
+	require.NoError(t, db.Create(&database.ArchiveSnapshot{UserID: 1, BookmarkID: goDev.ID, Version: 1, URL: goDev.URL, Content: "Build simple software", ContentHash: "hash", CapturedAt: time.Now()}).Error)
+
+	data, format, err := service.Export(ctx, 1, reading.ID, ExportRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "archive", format.Name)
+	assert.Len(t, data.Collections, 1)
+	require.Len(t, data.Bookmarks, 1)
+	assert.Empty(t, data.Bookmarks[0].Notes)
+	assert.Nil(t, data.Bookmarks[0].Archive)
+	assert.Empty(t, data.Files)
+
+	data, _, err = service.Export(ctx, 1, reading.ID, ExportRequest{IncludeDescendants: true, IncludeArchives: true, IncludeScreenshots: true, IncludeAnnotations: true})
+	require.NoError(t, err)
+	assert.Len(t, data.Collections, 2)
+	require.Len(t, data.Bookmarks, 2)
+	assert.Equal(t, "Start here", data.Bookmarks[0].Notes)
+	require.NotNil(t, data.Bookmarks[0].Archive)
+	assert.Equal(t, "Build simple software", data.Bookmarks[0].Archive.Content)
+	require.Len(t, data.Files, 1, "screenshots that can't be fetched are left out")
+	assert.Equal(t, data.Bookmarks[0].ScreenshotFile, data.Files[0].Path)
+
+	_, _, err = service.Export(ctx, 2, reading.ID, ExportRequest{})
+	assert.ErrorIs(t, err, ErrCollectionNotFound)
+	_, _, err = service.Export(ctx, 1, reading.ID, ExportRequest{Format: "pdf"})
+	assert.ErrorIs(t, err, exporter.ErrUnknownFormat)
+}
+
+func TestHandler_ExportCollection(t *testing.T) {
+	router, db := setupTestRouter(t)
+	reading := createBulkTestCollection(t, db, 1, "reading", nil)
+	createBulkTestBookmark(t, db, 1, "https://go.dev/", `[]`, reading)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/collections/1/export", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/zip", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "collection_1.zip")
+	archive, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	require.NoError(t, err)
+	assert.Equal(t, exporter.ArchiveManifest, archive.File[0].Name)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/collections/1/export", bytes.NewBufferString(`{"format": "pdf"}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/collections/99/export", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
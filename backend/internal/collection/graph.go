@@ -0,0 +1,42 @@
+package collection
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+// BookmarkGraph precomputes the relationships between a user's bookmarks,
+// e.g. the graph service
+type BookmarkGraph interface {
+	MarkCollection(ctx context.Context, collectionID uint, bookmarkIDs ...uint) error
+}
+
+// SetGraph makes the service queue the bookmarks of a collection for the
+// graph whenever the collection's bookmarks change
+func (s *Service) SetGraph(graph BookmarkGraph) {
+	s.graph = graph
+}
+
+// markGraph queues a collection's bookmarks and those that just left it.
+// Failures never fail the change; the graph is only stale until the
+// bookmarks change again
+func (s *Service) markGraph(id uint, removed ...uint) {
+	if s.graph == nil {
+		return
+	}
+	if err := s.graph.MarkCollection(context.Background(), id, removed...); err != nil {
+		s.logger.Warn("Failed to queue graph changes", zap.Uint("collection_id", id), zap.Error(err))
+	}
+}
+
+// markGraphBulk queues the bookmarks of the collections a bulk operation,
+// or its undo, linked or unlinked bookmarks in. Transferred bookmarks change
+// hands, which the graph picks up from the bookmarks themselves
+func (s *Service) markGraphBulk(undo *collectionUndo) {
+	for _, id := range []uint{undo.SourceID, undo.TargetID, undo.NewCollectionID} {
+		if id != 0 {
+			s.markGraph(id, undo.RemovedLinks...)
+		}
+	}
+}
@@ -1,11 +1,13 @@
 package collection
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 
+	"bookmark-sync-service/backend/pkg/middleware"
 	"bookmark-sync-service/backend/pkg/utils"
 )
 
@@ -25,6 +27,7 @@ func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
 	{
 		collections.POST("", h.CreateCollection)
 		collections.GET("", h.ListCollections)
+		collections.GET("/discover", h.DiscoverCollections)
 		collections.GET("/:id", h.GetCollection)
 		collections.PUT("/:id", h.UpdateCollection)
 		collections.DELETE("/:id", h.DeleteCollection)
@@ -33,6 +36,39 @@ func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
 		collections.POST("/:id/bookmarks/:bookmark_id", h.AddBookmarkToCollection)
 		collections.DELETE("/:id/bookmarks/:bookmark_id", h.RemoveBookmarkFromCollection)
 		collections.GET("/:id/bookmarks", h.GetCollectionBookmarks)
+		collections.GET("/:id/similar", h.GetSimilarCollections)
+
+		// Notes written into bookmarks added to a collection
+		collections.GET("/:id/note-template", h.GetNoteTemplate)
+		collections.PUT("/:id/note-template", h.SetNoteTemplate)
+		collections.DELETE("/:id/note-template", h.DeleteNoteTemplate)
+
+		// Bulk collection tools, tracked as undoable bulk operations
+		collections.POST("/merge", h.MergeCollections)
+		collections.POST("/:id/split", h.SplitCollection)
+		collections.POST("/:id/transfer", h.TransferCollection)
+		collections.GET("/transfers", h.ListTransfers)
+		collections.POST("/transfers/:transfer_id/accept", h.AcceptTransfer)
+		collections.POST("/transfers/:transfer_id/decline", h.DeclineTransfer)
+		collections.DELETE("/transfers/:transfer_id", h.CancelTransfer)
+		collections.POST("/cross-post", h.CrossPost)
+		collections.GET("/operations", h.ListBulkOperations)
+		collections.POST("/operations/:operation_id/undo", h.UndoBulkOperation)
+
+		// Export of one collection, importable into another account
+		collections.POST("/:id/export", h.ExportCollection)
+
+		// Point-in-time history
+		collections.GET("/:id/revisions", h.ListRevisions)
+		collections.POST("/:id/restore", h.RestoreCollection)
+
+		// Template gallery
+		collections.GET("/templates", h.ListTemplates)
+		collections.POST("/templates", h.CreateTemplate)
+		collections.GET("/templates/:template_id", h.GetTemplate)
+		collections.DELETE("/templates/:template_id", h.DeleteTemplate)
+		collections.POST("/templates/:template_id/ratings", h.RateTemplate)
+		collections.POST("/from-template/:id", h.CreateFromTemplate)
 	}
 }
 
@@ -49,8 +85,8 @@ func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
 // @Failure 500 {object} utils.ErrorResponse
 // @Router /api/v1/collections [post]
 func (h *Handler) CreateCollection(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
 		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
 		return
 	}
@@ -60,9 +96,17 @@ func (h *Handler) CreateCollection(c *gin.Context) {
 		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid request data", nil)
 		return
 	}
+	if middleware.IsDemo(c) && req.Visibility != "private" {
+		utils.ErrorResponse(c, http.StatusForbidden, "DEMO_RESTRICTED", "Demo collections stay private", nil)
+		return
+	}
 
-	collection, err := h.service.Create(userID.(uint), req)
+	collection, err := h.service.Create(userID, req)
 	if err != nil {
+		if errors.Is(err, ErrClientIDDeleted) {
+			utils.ErrorResponse(c, http.StatusConflict, "CLIENT_ID_CONFLICT", err.Error(), nil)
+			return
+		}
 		utils.ErrorResponse(c, http.StatusBadRequest, "CREATE_ERROR", "Failed to create collection", nil)
 		return
 	}
@@ -87,14 +131,15 @@ func (h *Handler) CreateCollection(c *gin.Context) {
 // @Param parent_id query int false "Filter by parent collection ID"
 // @Param sort_by query string false "Sort field" default(created_at) Enums(created_at, updated_at, name)
 // @Param sort_order query string false "Sort order" default(desc) Enums(asc, desc)
+// @Param fields query string false "Comma-separated fields to return, dots for nested ones, e.g. id,name"
 // @Success 200 {object} ListCollectionsResult
 // @Failure 400 {object} utils.ErrorResponse
 // @Failure 401 {object} utils.ErrorResponse
 // @Failure 500 {object} utils.ErrorResponse
 // @Router /api/v1/collections [get]
 func (h *Handler) ListCollections(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
 		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
 		return
 	}
@@ -105,13 +150,13 @@ func (h *Handler) ListCollections(c *gin.Context) {
 		return
 	}
 
-	result, err := h.service.List(userID.(uint), params)
+	result, err := h.service.List(userID, params)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to list collections", nil)
 		return
 	}
 
-	utils.SuccessResponse(c, result, "Collections retrieved successfully")
+	utils.SparseResponse(c, result, "Collections retrieved successfully", "collections")
 }
 
 // GetCollection retrieves a collection by ID
@@ -121,6 +166,7 @@ func (h *Handler) ListCollections(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path int true "Collection ID"
+// @Param fields query string false "Comma-separated fields to return, dots for nested ones, e.g. id,name"
 // @Success 200 {object} database.Collection
 // @Failure 400 {object} utils.ErrorResponse
 // @Failure 401 {object} utils.ErrorResponse
@@ -128,8 +174,8 @@ func (h *Handler) ListCollections(c *gin.Context) {
 // @Failure 500 {object} utils.ErrorResponse
 // @Router /api/v1/collections/{id} [get]
 func (h *Handler) GetCollection(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
 		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
 		return
 	}
@@ -141,7 +187,7 @@ func (h *Handler) GetCollection(c *gin.Context) {
 		return
 	}
 
-	collection, err := h.service.GetByID(userID.(uint), uint(id))
+	collection, err := h.service.GetByID(userID, uint(id))
 	if err != nil {
 		if err.Error() == "collection not found" {
 			utils.ErrorResponse(c, http.StatusNotFound, "NOT_FOUND", "Collection not found", nil)
@@ -151,7 +197,7 @@ func (h *Handler) GetCollection(c *gin.Context) {
 		return
 	}
 
-	utils.SuccessResponse(c, collection, "Collection retrieved successfully")
+	utils.SparseResponse(c, collection, "Collection retrieved successfully", "")
 }
 
 // UpdateCollection updates a collection
@@ -169,8 +215,8 @@ func (h *Handler) GetCollection(c *gin.Context) {
 // @Failure 500 {object} utils.ErrorResponse
 // @Router /api/v1/collections/{id} [put]
 func (h *Handler) UpdateCollection(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
 		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
 		return
 	}
@@ -187,18 +233,26 @@ func (h *Handler) UpdateCollection(c *gin.Context) {
 		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid request data", nil)
 		return
 	}
+	if middleware.IsDemo(c) && req.Visibility != nil && *req.Visibility != "private" {
+		utils.ErrorResponse(c, http.StatusForbidden, "DEMO_RESTRICTED", "Demo collections stay private", nil)
+		return
+	}
 
-	collection, err := h.service.Update(userID.(uint), uint(id), req)
+	collection, err := h.service.Update(userID, uint(id), req)
 	if err != nil {
 		if err.Error() == "collection not found" {
 			utils.ErrorResponse(c, http.StatusNotFound, "NOT_FOUND", "Collection not found", nil)
 			return
 		}
+		if errors.Is(err, ErrUnauthorized) {
+			utils.ErrorResponse(c, http.StatusForbidden, "FORBIDDEN", err.Error(), nil)
+			return
+		}
 		utils.ErrorResponse(c, http.StatusBadRequest, "UPDATE_ERROR", "Failed to update collection", nil)
 		return
 	}
 
-	utils.SuccessResponse(c, collection, "Collection updated successfully")
+	utils.SparseResponse(c, collection, "Collection updated successfully", "")
 }
 
 // DeleteCollection deletes a collection
@@ -215,8 +269,8 @@ func (h *Handler) UpdateCollection(c *gin.Context) {
 // @Failure 500 {object} utils.ErrorResponse
 // @Router /api/v1/collections/{id} [delete]
 func (h *Handler) DeleteCollection(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
 		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
 		return
 	}
@@ -228,7 +282,7 @@ func (h *Handler) DeleteCollection(c *gin.Context) {
 		return
 	}
 
-	err = h.service.Delete(userID.(uint), uint(id))
+	err = h.service.Delete(userID, uint(id))
 	if err != nil {
 		if err.Error() == "collection not found" {
 			utils.ErrorResponse(c, http.StatusNotFound, "NOT_FOUND", "Collection not found", nil)
@@ -256,8 +310,8 @@ func (h *Handler) DeleteCollection(c *gin.Context) {
 // @Failure 500 {object} utils.ErrorResponse
 // @Router /api/v1/collections/{id}/bookmarks/{bookmark_id} [post]
 func (h *Handler) AddBookmarkToCollection(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
 		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
 		return
 	}
@@ -276,7 +330,7 @@ func (h *Handler) AddBookmarkToCollection(c *gin.Context) {
 		return
 	}
 
-	err = h.service.AddBookmark(userID.(uint), uint(collectionID), uint(bookmarkID))
+	err = h.service.AddBookmark(userID, uint(collectionID), uint(bookmarkID))
 	if err != nil {
 		if err.Error() == "collection not found" || err.Error() == "bookmark not found" {
 			utils.ErrorResponse(c, http.StatusNotFound, "NOT_FOUND", err.Error(), nil)
@@ -304,8 +358,8 @@ func (h *Handler) AddBookmarkToCollection(c *gin.Context) {
 // @Failure 500 {object} utils.ErrorResponse
 // @Router /api/v1/collections/{id}/bookmarks/{bookmark_id} [delete]
 func (h *Handler) RemoveBookmarkFromCollection(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
 		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
 		return
 	}
@@ -324,7 +378,7 @@ func (h *Handler) RemoveBookmarkFromCollection(c *gin.Context) {
 		return
 	}
 
-	err = h.service.RemoveBookmark(userID.(uint), uint(collectionID), uint(bookmarkID))
+	err = h.service.RemoveBookmark(userID, uint(collectionID), uint(bookmarkID))
 	if err != nil {
 		if err.Error() == "collection not found" {
 			utils.ErrorResponse(c, http.StatusNotFound, "NOT_FOUND", "Collection not found", nil)
@@ -349,6 +403,7 @@ func (h *Handler) RemoveBookmarkFromCollection(c *gin.Context) {
 // @Param search query string false "Search term"
 // @Param sort_by query string false "Sort field" default(created_at) Enums(created_at, updated_at, title, url)
 // @Param sort_order query string false "Sort order" default(desc) Enums(asc, desc)
+// @Param fields query string false "Comma-separated fields to return, dots for nested ones, e.g. url,title"
 // @Success 200 {object} GetCollectionBookmarksResult
 // @Failure 400 {object} utils.ErrorResponse
 // @Failure 401 {object} utils.ErrorResponse
@@ -356,8 +411,8 @@ func (h *Handler) RemoveBookmarkFromCollection(c *gin.Context) {
 // @Failure 500 {object} utils.ErrorResponse
 // @Router /api/v1/collections/{id}/bookmarks [get]
 func (h *Handler) GetCollectionBookmarks(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
 		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
 		return
 	}
@@ -375,7 +430,7 @@ func (h *Handler) GetCollectionBookmarks(c *gin.Context) {
 		return
 	}
 
-	result, err := h.service.GetBookmarks(userID.(uint), uint(collectionID), params)
+	result, err := h.service.GetBookmarks(userID, uint(collectionID), params)
 	if err != nil {
 		if err.Error() == "collection not found" {
 			utils.ErrorResponse(c, http.StatusNotFound, "NOT_FOUND", "Collection not found", nil)
@@ -385,5 +440,5 @@ func (h *Handler) GetCollectionBookmarks(c *gin.Context) {
 		return
 	}
 
-	utils.SuccessResponse(c, result, "Collection bookmarks retrieved successfully")
+	utils.SparseResponse(c, result, "Collection bookmarks retrieved successfully", "bookmarks")
 }
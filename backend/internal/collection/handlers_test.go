@@ -40,7 +40,7 @@ func setupTestRouter(t *testing.T) (*gin.Engine, *gorm.DB) {
 
 	// Mock auth middleware that sets user_id to 1
 	router.Use(func(c *gin.Context) {
-		c.Set("user_id", uint(1))
+		c.Set("user_id", "1")
 		c.Next()
 	})
 
@@ -0,0 +1,397 @@
+package collection
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+// Revision actions recorded by the collection service
+const (
+	RevisionCreate         = "create"
+	RevisionUpdate         = "update"
+	RevisionAddBookmark    = "add_bookmark"
+	RevisionRemoveBookmark = "remove_bookmark"
+	RevisionUndo           = "undo"
+	RevisionRestore        = "restore"
+)
+
+// How restores treat bookmarks that were deleted since the snapshot
+const (
+	DeletedBookmarksSkip     = "skip"
+	DeletedBookmarksUndelete = "undelete"
+)
+
+// Restore conflict reasons
+const (
+	ConflictBookmarkDeleted = "bookmark_deleted" // soft deleted since the snapshot
+	ConflictBookmarkMissing = "bookmark_missing" // permanently deleted or transferred
+	ConflictParentDeleted   = "parent_deleted"
+)
+
+var (
+	// ErrNoRevision is returned when a collection has no history at or before a time
+	ErrNoRevision = errors.New("no collection history at that time")
+	// ErrInvalidRestorePolicy is returned for an unknown deleted bookmark policy
+	ErrInvalidRestorePolicy = errors.New("deleted must be skip or undelete")
+)
+
+// collectionSnapshot is the restorable state of a collection
+type collectionSnapshot struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Color       string `json:"color"`
+	Icon        string `json:"icon"`
+	ParentID    *uint  `json:"parent_id"`
+	Visibility  string `json:"visibility"`
+	BookmarkIDs []uint `json:"bookmark_ids"`
+}
+
+// RevisionSummary describes one entry of a collection's history
+type RevisionSummary struct {
+	ID            uint      `json:"id"`
+	Action        string    `json:"action"`
+	CreatedAt     time.Time `json:"created_at"`
+	Name          string    `json:"name"`
+	BookmarkCount int       `json:"bookmark_count"`
+}
+
+// RestoreOptions controls a point-in-time restore
+type RestoreOptions struct {
+	Preview          bool   // report the changes without applying them
+	DeletedBookmarks string // skip (default) or undelete
+}
+
+// FieldChange is a metadata field a restore changes
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// RestoreConflict is something a restore cannot put back as it was
+type RestoreConflict struct {
+	BookmarkID uint   `json:"bookmark_id,omitempty"`
+	Reason     string `json:"reason"`
+	Resolution string `json:"resolution"` // skipped, undeleted, kept_current
+}
+
+// RestoreResult lists what a restore changes, or changed
+type RestoreResult struct {
+	CollectionID uint              `json:"collection_id"`
+	At           time.Time         `json:"at"`
+	RevisionID   uint              `json:"revision_id"`
+	RevisionAt   time.Time         `json:"revision_at"`
+	Changes      []FieldChange     `json:"changes"`
+	Added        []uint            `json:"added_bookmarks"`
+	Removed      []uint            `json:"removed_bookmarks"`
+	Conflicts    []RestoreConflict `json:"conflicts"`
+	Applied      bool              `json:"applied"`
+}
+
+// recordRevision snapshots a collection after a change. History is best
+// effort, like search indexing: a failure never fails the change itself
+func (s *Service) recordRevision(collectionID uint, action string) {
+	_ = recordRevisionTx(s.db, collectionID, action)
+}
+
+// recordRevisionTx snapshots a collection within tx and prunes its oldest
+// revisions past the retention limit
+func recordRevisionTx(tx *gorm.DB, collectionID uint, action string) error {
+	var collection database.Collection
+	if err := tx.First(&collection, collectionID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to get collection: %w", err)
+	}
+
+	bookmarkIDs, err := linkedBookmarkIDs(tx, collectionID)
+	if err != nil {
+		return err
+	}
+	sort.Slice(bookmarkIDs, func(i, j int) bool { return bookmarkIDs[i] < bookmarkIDs[j] })
+
+	snapshot, err := json.Marshal(collectionSnapshot{
+		Name:        collection.Name,
+		Description: collection.Description,
+		Color:       collection.Color,
+		Icon:        collection.Icon,
+		ParentID:    collection.ParentID,
+		Visibility:  collection.Visibility,
+		BookmarkIDs: bookmarkIDs,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode collection snapshot: %w", err)
+	}
+
+	if err := tx.Create(&database.CollectionRevision{
+		CollectionID: collectionID,
+		UserID:       collection.UserID,
+		Action:       action,
+		Snapshot:     string(snapshot),
+	}).Error; err != nil {
+		return fmt.Errorf("failed to record collection revision: %w", err)
+	}
+
+	var keep []uint
+	if err := tx.Model(&database.CollectionRevision{}).Where("collection_id = ?", collectionID).
+		Order("id DESC").Limit(config.CollectionRevisionLimit).Pluck("id", &keep).Error; err != nil {
+		return fmt.Errorf("failed to prune collection history: %w", err)
+	}
+	if len(keep) == config.CollectionRevisionLimit {
+		if err := tx.Unscoped().Where("collection_id = ? AND id < ?", collectionID, keep[len(keep)-1]).
+			Delete(&database.CollectionRevision{}).Error; err != nil {
+			return fmt.Errorf("failed to prune collection history: %w", err)
+		}
+	}
+	return nil
+}
+
+// recordBulkRevisions snapshots the collections a bulk operation, or its
+// undo, changed
+func (s *Service) recordBulkRevisions(action string, undo *collectionUndo) {
+	ids := append([]uint(nil), undo.Collections...)
+	for _, id := range []uint{undo.SourceID, undo.TargetID, undo.NewCollectionID} {
+		if id != 0 {
+			ids = append(ids, id)
+		}
+	}
+	for _, id := range ids {
+		s.recordRevision(id, action)
+	}
+}
+
+// ListRevisions returns a collection's history, newest first
+func (s *Service) ListRevisions(userID, collectionID uint, limit int) ([]RevisionSummary, error) {
+	if _, err := ownedCollection(s.db, userID, collectionID); err != nil {
+		return nil, err
+	}
+	if limit <= 0 || limit > config.MaxPageSize {
+		limit = config.DefaultPageSize
+	}
+
+	var revisions []database.CollectionRevision
+	if err := s.db.Where("collection_id = ?", collectionID).Order("created_at DESC, id DESC").
+		Limit(limit).Find(&revisions).Error; err != nil {
+		return nil, fmt.Errorf("failed to list collection history: %w", err)
+	}
+
+	summaries := make([]RevisionSummary, 0, len(revisions))
+	for _, revision := range revisions {
+		var snapshot collectionSnapshot
+		if err := json.Unmarshal([]byte(revision.Snapshot), &snapshot); err != nil {
+			continue
+		}
+		summaries = append(summaries, RevisionSummary{
+			ID:            revision.ID,
+			Action:        revision.Action,
+			CreatedAt:     revision.CreatedAt,
+			Name:          snapshot.Name,
+			BookmarkCount: len(snapshot.BookmarkIDs),
+		})
+	}
+	return summaries, nil
+}
+
+// RestoreCollection puts a collection's metadata and bookmarks back to how
+// they were at a point in time. Bookmarks deleted since then are skipped or,
+// when asked, undeleted; a deleted parent keeps the current one
+func (s *Service) RestoreCollection(userID, collectionID uint, at time.Time, opts RestoreOptions) (*RestoreResult, error) {
+	if opts.DeletedBookmarks == "" {
+		opts.DeletedBookmarks = DeletedBookmarksSkip
+	}
+	if opts.DeletedBookmarks != DeletedBookmarksSkip && opts.DeletedBookmarks != DeletedBookmarksUndelete {
+		return nil, ErrInvalidRestorePolicy
+	}
+
+	var result *RestoreResult
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		collection, err := ownedCollection(tx, userID, collectionID)
+		if err != nil {
+			return err
+		}
+
+		var revision database.CollectionRevision
+		if err := tx.Where("collection_id = ? AND created_at <= ?", collectionID, at).
+			Order("created_at DESC, id DESC").First(&revision).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNoRevision
+			}
+			return fmt.Errorf("failed to find collection revision: %w", err)
+		}
+		var snapshot collectionSnapshot
+		if err := json.Unmarshal([]byte(revision.Snapshot), &snapshot); err != nil {
+			return fmt.Errorf("failed to decode collection snapshot: %w", err)
+		}
+
+		result, err = planRestore(tx, collection, &snapshot, opts)
+		if err != nil {
+			return err
+		}
+		result.At = at
+		result.RevisionID = revision.ID
+		result.RevisionAt = revision.CreatedAt
+		if opts.Preview {
+			return nil
+		}
+
+		if err := applyRestore(tx, collection, result); err != nil {
+			return err
+		}
+		result.Applied = true
+		return recordRevisionTx(tx, collectionID, RevisionRestore)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Applied {
+		s.indexTree(collectionID)
+	}
+	return result, nil
+}
+
+// planRestore diffs the current collection against a snapshot
+func planRestore(tx *gorm.DB, collection *database.Collection, snapshot *collectionSnapshot, opts RestoreOptions) (*RestoreResult, error) {
+	result := &RestoreResult{
+		CollectionID: collection.ID,
+		Changes:      []FieldChange{},
+		Added:        []uint{},
+		Removed:      []uint{},
+		Conflicts:    []RestoreConflict{},
+	}
+
+	fields := []struct {
+		name     string
+		from, to string
+	}{
+		{"name", collection.Name, snapshot.Name},
+		{"description", collection.Description, snapshot.Description},
+		{"color", collection.Color, snapshot.Color},
+		{"icon", collection.Icon, snapshot.Icon},
+		{"visibility", collection.Visibility, snapshot.Visibility},
+	}
+	for _, field := range fields {
+		if field.from != field.to {
+			result.Changes = append(result.Changes, FieldChange{Field: field.name, From: field.from, To: field.to})
+		}
+	}
+
+	if !sameParent(collection.ParentID, snapshot.ParentID) {
+		usable := snapshot.ParentID == nil
+		if !usable {
+			var count int64
+			if err := tx.Model(&database.Collection{}).Where("id = ? AND user_id = ?", *snapshot.ParentID, collection.UserID).
+				Count(&count).Error; err != nil {
+				return nil, fmt.Errorf("failed to check parent collection: %w", err)
+			}
+			usable = count > 0 && *snapshot.ParentID != collection.ID
+		}
+		if usable {
+			result.Changes = append(result.Changes, FieldChange{Field: "parent_id", From: collection.ParentID, To: snapshot.ParentID})
+		} else {
+			result.Conflicts = append(result.Conflicts, RestoreConflict{Reason: ConflictParentDeleted, Resolution: "kept_current"})
+		}
+	}
+
+	current, err := linkedBookmarkIDs(tx, collection.ID)
+	if err != nil {
+		return nil, err
+	}
+	inCurrent := make(map[uint]bool, len(current))
+	for _, id := range current {
+		inCurrent[id] = true
+	}
+	inSnapshot := make(map[uint]bool, len(snapshot.BookmarkIDs))
+	for _, id := range snapshot.BookmarkIDs {
+		inSnapshot[id] = true
+	}
+	for _, id := range current {
+		if !inSnapshot[id] {
+			result.Removed = append(result.Removed, id)
+		}
+	}
+	sort.Slice(result.Removed, func(i, j int) bool { return result.Removed[i] < result.Removed[j] })
+
+	if len(snapshot.BookmarkIDs) == 0 {
+		return result, nil
+	}
+
+	// Bookmarks may have been deleted or given away since the snapshot. Soft
+	// deleted bookmarks keep their links, so linked ones are checked too
+	var bookmarks []database.Bookmark
+	if err := tx.Unscoped().Select("id", "deleted_at").Where("id IN ? AND user_id = ?", snapshot.BookmarkIDs, collection.UserID).
+		Find(&bookmarks).Error; err != nil {
+		return nil, fmt.Errorf("failed to check restored bookmarks: %w", err)
+	}
+	deleted := make(map[uint]bool, len(bookmarks))
+	found := make(map[uint]bool, len(bookmarks))
+	for _, bookmark := range bookmarks {
+		found[bookmark.ID] = true
+		deleted[bookmark.ID] = bookmark.DeletedAt.Valid
+	}
+
+	for _, id := range snapshot.BookmarkIDs {
+		switch {
+		case !found[id]:
+			if !inCurrent[id] {
+				result.Conflicts = append(result.Conflicts, RestoreConflict{BookmarkID: id, Reason: ConflictBookmarkMissing, Resolution: "skipped"})
+			}
+			continue
+		case deleted[id] && opts.DeletedBookmarks == DeletedBookmarksSkip:
+			result.Conflicts = append(result.Conflicts, RestoreConflict{BookmarkID: id, Reason: ConflictBookmarkDeleted, Resolution: "skipped"})
+			continue
+		case deleted[id]:
+			result.Conflicts = append(result.Conflicts, RestoreConflict{BookmarkID: id, Reason: ConflictBookmarkDeleted, Resolution: "undeleted"})
+		}
+		if !inCurrent[id] {
+			result.Added = append(result.Added, id)
+		}
+	}
+	return result, nil
+}
+
+// applyRestore writes a planned restore
+func applyRestore(tx *gorm.DB, collection *database.Collection, result *RestoreResult) error {
+	updates := make(map[string]interface{}, len(result.Changes))
+	for _, change := range result.Changes {
+		updates[change.Field] = change.To
+	}
+	if len(updates) > 0 {
+		if err := tx.Model(collection).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to restore collection: %w", err)
+		}
+	}
+
+	var undelete []uint
+	for _, conflict := range result.Conflicts {
+		if conflict.Resolution == "undeleted" {
+			undelete = append(undelete, conflict.BookmarkID)
+		}
+	}
+	if len(undelete) > 0 {
+		if err := tx.Unscoped().Model(&database.Bookmark{}).Where("id IN ?", undelete).Update("deleted_at", nil).Error; err != nil {
+			return fmt.Errorf("failed to undelete bookmarks: %w", err)
+		}
+	}
+
+	if err := unlinkBookmarks(tx, collection.ID, result.Removed); err != nil {
+		return err
+	}
+	return linkBookmarks(tx, collection.ID, result.Added)
+}
+
+func sameParent(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
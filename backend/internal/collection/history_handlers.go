@@ -0,0 +1,111 @@
+package collection
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"bookmark-sync-service/backend/pkg/utils"
+)
+
+// ListRevisions lists a collection's history
+// @Summary List collection history
+// @Description List the recorded revisions of a collection, newest first
+// @Tags collections
+// @Produce json
+// @Param id path int true "Collection ID"
+// @Param limit query int false "Maximum number of revisions"
+// @Success 200 {array} RevisionSummary
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/collections/{id}/revisions [get]
+func (h *Handler) ListRevisions(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid collection ID", nil)
+		return
+	}
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	revisions, err := h.service.ListRevisions(userID, uint(id), limit)
+	if err != nil {
+		restoreErrorResponse(c, err, "Failed to list collection history")
+		return
+	}
+
+	utils.SuccessResponse(c, revisions, "Collection history retrieved successfully")
+}
+
+// RestoreCollection restores a collection to a point in time
+// @Summary Restore a collection
+// @Description Restore a collection's metadata and bookmarks to how they were at a point in time, or preview the changes
+// @Tags collections
+// @Produce json
+// @Param id path int true "Collection ID"
+// @Param at query string true "Point in time (RFC 3339)"
+// @Param preview query bool false "Only report what would change"
+// @Param deleted query string false "Bookmarks deleted since then: skip (default) or undelete"
+// @Success 200 {object} RestoreResult
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/collections/{id}/restore [post]
+func (h *Handler) RestoreCollection(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid collection ID", nil)
+		return
+	}
+
+	at, err := time.Parse(time.RFC3339, c.Query("at"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "at must be an RFC 3339 timestamp", nil)
+		return
+	}
+
+	opts := RestoreOptions{
+		Preview:          c.Query("preview") == "true",
+		DeletedBookmarks: c.Query("deleted"),
+	}
+	result, err := h.service.RestoreCollection(userID, uint(id), at, opts)
+	if err != nil {
+		restoreErrorResponse(c, err, "Failed to restore collection")
+		return
+	}
+
+	if !result.Applied {
+		utils.SuccessResponse(c, result, "Collection restore previewed")
+		return
+	}
+	utils.SuccessResponse(c, result, "Collection restored successfully")
+}
+
+// restoreErrorResponse maps collection history errors to HTTP responses
+func restoreErrorResponse(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, ErrCollectionNotFound), errors.Is(err, ErrNoRevision):
+		utils.ErrorResponse(c, http.StatusNotFound, "NOT_FOUND", err.Error(), nil)
+	case errors.Is(err, ErrInvalidRestorePolicy):
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error(), nil)
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", message, nil)
+	}
+}
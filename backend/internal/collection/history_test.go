@@ -0,0 +1,192 @@
+package collection
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+// backdateRevisions spreads a collection's revisions an hour apart, oldest
+// first, ending at base
+func backdateRevisions(t *testing.T, db *gorm.DB, collectionID uint, base time.Time) []database.CollectionRevision {
+	var revisions []database.CollectionRevision
+	require.NoError(t, db.Where("collection_id = ?", collectionID).Order("id").Find(&revisions).Error)
+	for i := range revisions {
+		revisions[i].CreatedAt = base.Add(time.Duration(i-len(revisions)+1) * time.Hour)
+		require.NoError(t, db.Model(&revisions[i]).UpdateColumn("created_at", revisions[i].CreatedAt).Error)
+	}
+	return revisions
+}
+
+func TestCollectionService_RestoreCollection(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+
+	collection, err := service.Create(1, CreateCollectionRequest{Name: "Reading", Visibility: "private"})
+	require.NoError(t, err)
+	kept := createBulkTestBookmark(t, db, 1, "https://example.com/kept", `[]`)
+	deleted := createBulkTestBookmark(t, db, 1, "https://example.com/deleted", `[]`)
+	purged := createBulkTestBookmark(t, db, 1, "https://example.com/purged", `[]`)
+	for _, bookmark := range []*database.Bookmark{kept, deleted, purged} {
+		require.NoError(t, service.AddBookmark(1, collection.ID, bookmark.ID))
+	}
+
+	// Snapshot to restore: three bookmarks, original name
+	base := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	revisions := backdateRevisions(t, db, collection.ID, base)
+	require.Len(t, revisions, 4)
+	assert.Equal(t, RevisionCreate, revisions[0].Action)
+
+	name := "Archive"
+	_, err = service.Update(1, collection.ID, UpdateCollectionRequest{Name: &name})
+	require.NoError(t, err)
+	require.NoError(t, service.RemoveBookmark(1, collection.ID, kept.ID))
+	added := createBulkTestBookmark(t, db, 1, "https://example.com/added", `[]`)
+	require.NoError(t, service.AddBookmark(1, collection.ID, added.ID))
+	require.NoError(t, db.Delete(deleted).Error)
+	require.NoError(t, db.Exec("DELETE FROM bookmark_collections WHERE bookmark_id = ?", purged.ID).Error)
+	require.NoError(t, db.Unscoped().Delete(purged).Error)
+
+	preview, err := service.RestoreCollection(1, collection.ID, base.Add(time.Minute), RestoreOptions{Preview: true})
+	require.NoError(t, err)
+	assert.False(t, preview.Applied)
+	assert.Equal(t, revisions[3].ID, preview.RevisionID)
+	assert.Equal(t, []FieldChange{{Field: "name", From: "Archive", To: "Reading"}}, preview.Changes)
+	assert.Equal(t, []uint{kept.ID}, preview.Added)
+	assert.Equal(t, []uint{added.ID}, preview.Removed)
+	assert.ElementsMatch(t, []RestoreConflict{
+		{BookmarkID: deleted.ID, Reason: ConflictBookmarkDeleted, Resolution: "skipped"},
+		{BookmarkID: purged.ID, Reason: ConflictBookmarkMissing, Resolution: "skipped"},
+	}, preview.Conflicts)
+	assert.ElementsMatch(t, []uint{deleted.ID, added.ID}, collectionBookmarkIDs(t, db, collection.ID), "previews change nothing")
+	require.Error(t, db.First(&database.Bookmark{}, deleted.ID).Error)
+
+	result, err := service.RestoreCollection(1, collection.ID, base.Add(time.Minute), RestoreOptions{DeletedBookmarks: DeletedBookmarksUndelete})
+	require.NoError(t, err)
+	assert.True(t, result.Applied)
+	assert.Equal(t, []uint{kept.ID}, result.Added, "the deleted bookmark kept its link")
+	assert.Contains(t, result.Conflicts, RestoreConflict{BookmarkID: deleted.ID, Reason: ConflictBookmarkDeleted, Resolution: "undeleted"})
+
+	var restored database.Collection
+	require.NoError(t, db.First(&restored, collection.ID).Error)
+	assert.Equal(t, "Reading", restored.Name)
+	assert.ElementsMatch(t, []uint{kept.ID, deleted.ID}, collectionBookmarkIDs(t, db, collection.ID))
+	require.NoError(t, db.First(&database.Bookmark{}, deleted.ID).Error, "deleted bookmark is back")
+
+	var last database.CollectionRevision
+	require.NoError(t, db.Where("collection_id = ?", collection.ID).Order("id DESC").First(&last).Error)
+	assert.Equal(t, RevisionRestore, last.Action)
+
+	// Before the collection existed there is nothing to restore
+	_, err = service.RestoreCollection(1, collection.ID, base.Add(-24*time.Hour), RestoreOptions{})
+	assert.ErrorIs(t, err, ErrNoRevision)
+	_, err = service.RestoreCollection(2, collection.ID, base, RestoreOptions{})
+	assert.ErrorIs(t, err, ErrCollectionNotFound)
+	_, err = service.RestoreCollection(1, collection.ID, base, RestoreOptions{DeletedBookmarks: "purge"})
+	assert.ErrorIs(t, err, ErrInvalidRestorePolicy)
+}
+
+func TestCollectionService_RestoreKeepsCurrentParentWhenDeleted(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+
+	parent, err := service.Create(1, CreateCollectionRequest{Name: "Parent", Visibility: "private"})
+	require.NoError(t, err)
+	child, err := service.Create(1, CreateCollectionRequest{Name: "Child", Visibility: "private", ParentID: &parent.ID})
+	require.NoError(t, err)
+	base := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	backdateRevisions(t, db, child.ID, base)
+
+	description := "moved out"
+	_, err = service.Update(1, child.ID, UpdateCollectionRequest{Description: &description})
+	require.NoError(t, err)
+	require.NoError(t, db.Model(child).Update("parent_id", nil).Error)
+	require.NoError(t, service.Delete(1, parent.ID))
+
+	result, err := service.RestoreCollection(1, child.ID, base, RestoreOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []FieldChange{{Field: "description", From: "moved out", To: ""}}, result.Changes)
+	assert.Equal(t, []RestoreConflict{{Reason: ConflictParentDeleted, Resolution: "kept_current"}}, result.Conflicts)
+
+	var restored database.Collection
+	require.NoError(t, db.First(&restored, child.ID).Error)
+	assert.Nil(t, restored.ParentID)
+	assert.Empty(t, restored.Description)
+}
+
+func TestCollectionService_BulkOperationsRecordRevisions(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+
+	source := createBulkTestCollection(t, db, 1, "source", nil)
+	target := createBulkTestCollection(t, db, 1, "target", nil)
+	createBulkTestBookmark(t, db, 1, "https://example.com/a", `[]`, source)
+
+	_, err := service.MergeCollections(1, MergeCollectionsRequest{SourceID: source.ID, TargetID: target.ID})
+	require.NoError(t, err)
+
+	revisions, err := service.ListRevisions(1, target.ID, 0)
+	require.NoError(t, err)
+	require.Len(t, revisions, 1)
+	assert.Equal(t, BulkTypeMerge, revisions[0].Action)
+	assert.Equal(t, 1, revisions[0].BookmarkCount)
+}
+
+func TestCollectionHandler_RestoreCollection(t *testing.T) {
+	router, db := setupTestRouter(t)
+	service := NewService(db)
+
+	collection, err := service.Create(1, CreateCollectionRequest{Name: "Reading", Visibility: "private"})
+	require.NoError(t, err)
+	base := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	backdateRevisions(t, db, collection.ID, base)
+	name := "Renamed"
+	_, err = service.Update(1, collection.ID, UpdateCollectionRequest{Name: &name})
+	require.NoError(t, err)
+
+	url := "/api/v1/collections/1/restore?at=" + base.Format(time.RFC3339)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, url+"&preview=true", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Data RestoreResult `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.False(t, response.Data.Applied)
+	require.Len(t, response.Data.Changes, 1)
+	assert.Equal(t, "Reading", response.Data.Changes[0].To)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, url, nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, db.First(collection, collection.ID).Error)
+	assert.Equal(t, "Reading", collection.Name)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/collections/1/revisions", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"action":"restore"`)
+
+	for _, tc := range []struct {
+		url  string
+		code int
+	}{
+		{"/api/v1/collections/1/restore", http.StatusBadRequest},
+		{"/api/v1/collections/1/restore?at=yesterday", http.StatusBadRequest},
+		{"/api/v1/collections/1/restore?at=2020-01-01T00:00:00Z", http.StatusNotFound},
+		{"/api/v1/collections/99/restore?at=2020-01-01T00:00:00Z", http.StatusNotFound},
+		{url + "&deleted=purge", http.StatusBadRequest},
+	} {
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, tc.url, nil))
+		assert.Equal(t, tc.code, w.Code, tc.url)
+	}
+}
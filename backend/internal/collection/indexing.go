@@ -0,0 +1,98 @@
+package collection
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+// SearchIndexer queues collection changes for batched search indexing
+type SearchIndexer interface {
+	QueueCollection(collection *database.Collection)
+	QueueCollectionDelete(id uint)
+}
+
+// SetIndexer makes the service queue its changes for search indexing
+func (s *Service) SetIndexer(indexer SearchIndexer) {
+	s.indexer = indexer
+}
+
+// index queues a collection with its bookmarks and ancestor path loaded,
+// since the search document carries the bookmark count and the path. As
+// every change of a collection passes here, its shares are rendered again
+func (s *Service) index(id uint) {
+	s.refreshShares(id)
+	if s.indexer == nil {
+		return
+	}
+
+	var collection database.Collection
+	if err := s.db.Preload("Bookmarks").First(&collection, id).Error; err != nil {
+		return
+	}
+	collection.Path = s.ancestorPath(&collection)
+	s.indexer.QueueCollection(&collection)
+}
+
+// indexTree queues a collection and its descendants, whose paths change
+// when it is renamed or moved. A collection that no longer exists is
+// removed from the index
+func (s *Service) indexTree(id uint) {
+	if s.indexer == nil {
+		s.refreshShares(id)
+		return
+	}
+
+	var collection database.Collection
+	if err := s.db.Select("id", "user_id").First(&collection, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			s.indexer.QueueCollectionDelete(id)
+			s.refreshShares(id)
+		}
+		return
+	}
+
+	ids, err := subtreeIDs(s.db, collection.UserID, id)
+	if err != nil {
+		return
+	}
+	for _, id := range ids {
+		s.index(id)
+	}
+}
+
+// indexBulk queues the collections a bulk operation, or its undo, changed
+func (s *Service) indexBulk(undo *collectionUndo) {
+	roots := append([]uint(nil), undo.Reparented...)
+	for _, id := range []uint{undo.SourceID, undo.TargetID, undo.NewCollectionID} {
+		if id != 0 {
+			roots = append(roots, id)
+		}
+	}
+	if len(undo.Collections) > 0 {
+		roots = append(roots, undo.Collections[0])
+	}
+
+	for _, id := range roots {
+		s.indexTree(id)
+	}
+}
+
+// ancestorPath returns the names of a collection's ancestors, root first
+func (s *Service) ancestorPath(collection *database.Collection) []string {
+	path := []string{}
+	seen := map[uint]bool{collection.ID: true}
+	for parentID := collection.ParentID; parentID != nil && !seen[*parentID]; {
+		seen[*parentID] = true
+
+		var parent database.Collection
+		if err := s.db.Select("id", "name", "parent_id").First(&parent, *parentID).Error; err != nil {
+			break
+		}
+		path = append([]string{parent.Name}, path...)
+		parentID = parent.ParentID
+	}
+	return path
+}
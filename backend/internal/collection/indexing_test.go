@@ -0,0 +1,96 @@
+package collection
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+// recordingIndexer keeps the latest queued state of each collection
+type recordingIndexer struct {
+	indexed map[uint]database.Collection
+	deleted map[uint]bool
+}
+
+func newRecordingIndexer() *recordingIndexer {
+	return &recordingIndexer{indexed: make(map[uint]database.Collection), deleted: make(map[uint]bool)}
+}
+
+func (r *recordingIndexer) QueueCollection(collection *database.Collection) {
+	r.indexed[collection.ID] = *collection
+	delete(r.deleted, collection.ID)
+}
+
+func (r *recordingIndexer) QueueCollectionDelete(id uint) {
+	r.deleted[id] = true
+	delete(r.indexed, id)
+}
+
+func TestCollectionService_IndexesAncestorPaths(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+	indexer := newRecordingIndexer()
+	service.SetIndexer(indexer)
+
+	work := createBulkTestCollection(t, db, 1, "Work", nil)
+	projects := createBulkTestCollection(t, db, 1, "Projects", &work.ID)
+	golang := createBulkTestCollection(t, db, 1, "Go", &projects.ID)
+	personal := createBulkTestCollection(t, db, 1, "Personal", nil)
+
+	service.index(golang.ID)
+	assert.Equal(t, []string{"Work", "Projects"}, indexer.indexed[golang.ID].Path)
+
+	// Renaming reindexes the descendants under the new name
+	name := "Job"
+	_, err := service.Update(1, work.ID, UpdateCollectionRequest{Name: &name})
+	require.NoError(t, err)
+	assert.Empty(t, indexer.indexed[work.ID].Path)
+	assert.Equal(t, []string{"Job"}, indexer.indexed[projects.ID].Path)
+	assert.Equal(t, []string{"Job", "Projects"}, indexer.indexed[golang.ID].Path)
+
+	// So does moving a collection
+	_, err = service.Update(1, projects.ID, UpdateCollectionRequest{ParentID: &personal.ID})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Personal", "Projects"}, indexer.indexed[golang.ID].Path)
+
+	// Visibility changes reindex the collection itself
+	visibility := "public"
+	_, err = service.Update(1, golang.ID, UpdateCollectionRequest{Visibility: &visibility})
+	require.NoError(t, err)
+	assert.Equal(t, "public", indexer.indexed[golang.ID].Visibility)
+}
+
+func TestCollectionService_IndexesBulkOperations(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+	indexer := newRecordingIndexer()
+	service.SetIndexer(indexer)
+
+	source := createBulkTestCollection(t, db, 1, "source", nil)
+	target := createBulkTestCollection(t, db, 1, "target", nil)
+	child := createBulkTestCollection(t, db, 1, "child", &source.ID)
+
+	operation, err := service.MergeCollections(1, MergeCollectionsRequest{SourceID: source.ID, TargetID: target.ID})
+	require.NoError(t, err)
+	assert.True(t, indexer.deleted[source.ID], "the merged source is removed from the index")
+	assert.Equal(t, []string{"target"}, indexer.indexed[child.ID].Path)
+
+	_, err = service.UndoBulkOperation(1, operation.ID)
+	require.NoError(t, err)
+	assert.Contains(t, indexer.indexed, source.ID)
+	assert.Equal(t, []string{"source"}, indexer.indexed[child.ID].Path)
+}
+
+func TestAncestorPath_StopsOnCycles(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+
+	a := createBulkTestCollection(t, db, 1, "a", nil)
+	b := createBulkTestCollection(t, db, 1, "b", &a.ID)
+	require.NoError(t, db.Model(a).Update("parent_id", b.ID).Error)
+
+	assert.Equal(t, []string{"a"}, service.ancestorPath(b))
+}
@@ -0,0 +1,124 @@
+package collection
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"bookmark-sync-service/backend/pkg/utils"
+)
+
+// GetNoteTemplate returns a collection's note template
+// @Summary Get a collection's note template
+// @Description Get the Markdown template written into the notes of bookmarks added to the collection, with the placeholders it may use
+// @Tags collections
+// @Produce json
+// @Param id path int true "Collection ID"
+// @Success 200 {object} NoteTemplate
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /api/v1/collections/{id}/note-template [get]
+func (h *Handler) GetNoteTemplate(c *gin.Context) {
+	userID, id, ok := noteTemplateParams(c)
+	if !ok {
+		return
+	}
+
+	template, err := h.service.GetNoteTemplate(userID, id)
+	if err != nil {
+		noteTemplateErrorResponse(c, err, "Failed to get note template")
+		return
+	}
+
+	utils.SuccessResponse(c, template, "Note template retrieved successfully")
+}
+
+// SetNoteTemplate sets a collection's note template
+// @Summary Set a collection's note template
+// @Description Set the Markdown template written into the notes of bookmarks added to the collection without notes. Placeholders such as {{url}}, {{title}}, {{tags}} and {{date}} are rendered when a bookmark is added
+// @Tags collections
+// @Accept json
+// @Produce json
+// @Param id path int true "Collection ID"
+// @Param request body NoteTemplateRequest true "Template"
+// @Success 200 {object} NoteTemplate
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /api/v1/collections/{id}/note-template [put]
+func (h *Handler) SetNoteTemplate(c *gin.Context) {
+	userID, id, ok := noteTemplateParams(c)
+	if !ok {
+		return
+	}
+
+	var req NoteTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid request data", nil)
+		return
+	}
+
+	template, err := h.service.SetNoteTemplate(userID, id, req)
+	if err != nil {
+		noteTemplateErrorResponse(c, err, "Failed to set note template")
+		return
+	}
+
+	utils.SuccessResponse(c, template, "Note template saved successfully")
+}
+
+// DeleteNoteTemplate removes a collection's note template
+// @Summary Delete a collection's note template
+// @Tags collections
+// @Produce json
+// @Param id path int true "Collection ID"
+// @Success 200 {object} utils.APIResponse
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /api/v1/collections/{id}/note-template [delete]
+func (h *Handler) DeleteNoteTemplate(c *gin.Context) {
+	userID, id, ok := noteTemplateParams(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteNoteTemplate(userID, id); err != nil {
+		noteTemplateErrorResponse(c, err, "Failed to delete note template")
+		return
+	}
+
+	utils.SuccessResponse(c, nil, "Note template deleted successfully")
+}
+
+// noteTemplateParams reads the user and the collection ID of a note template
+// request, responding itself when either is missing
+func noteTemplateParams(c *gin.Context) (uint, uint, bool) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return 0, 0, false
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid collection ID", nil)
+		return 0, 0, false
+	}
+	return userID, uint(id), true
+}
+
+// noteTemplateErrorResponse maps note template errors to HTTP responses
+func noteTemplateErrorResponse(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, ErrCollectionNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, "NOT_FOUND", err.Error(), nil)
+	case errors.Is(err, ErrInvalidNoteTemplate):
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error(), nil)
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", message, nil)
+	}
+}
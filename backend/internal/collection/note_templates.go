@@ -0,0 +1,175 @@
+package collection
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+// maxNoteTemplateLength bounds a note template, in characters
+const maxNoteTemplateLength = 10000
+
+// ErrInvalidNoteTemplate is returned for note templates that are too long or
+// use placeholders that do not exist
+var ErrInvalidNoteTemplate = errors.New("invalid note template")
+
+// notePlaceholder matches a placeholder such as {{url}} or {{ title }}
+var notePlaceholder = regexp.MustCompile(`\{\{\s*([a-z_]+)\s*\}\}`)
+
+// notePlaceholders render the placeholders of a note template for a bookmark
+// added to a collection at a time
+var notePlaceholders = map[string]func(collection *database.Collection, bookmark *database.Bookmark, now time.Time) string{
+	"url":         func(_ *database.Collection, b *database.Bookmark, _ time.Time) string { return b.URL },
+	"title":       func(_ *database.Collection, b *database.Bookmark, _ time.Time) string { return b.Title },
+	"description": func(_ *database.Collection, b *database.Bookmark, _ time.Time) string { return b.Description },
+	"domain":      func(_ *database.Collection, b *database.Bookmark, _ time.Time) string { return noteDomain(b.URL) },
+	"tags":        func(_ *database.Collection, b *database.Bookmark, _ time.Time) string { return strings.Join(noteTags(b.Tags), ", ") },
+	"collection":  func(c *database.Collection, _ *database.Bookmark, _ time.Time) string { return c.Name },
+	"date":        func(_ *database.Collection, _ *database.Bookmark, now time.Time) string { return now.Format("2006-01-02") },
+	"saved_date":  func(_ *database.Collection, b *database.Bookmark, _ time.Time) string { return b.CreatedAt.UTC().Format("2006-01-02") },
+}
+
+// NoteTemplateRequest sets a collection's note template
+type NoteTemplateRequest struct {
+	Template string `json:"template" binding:"required"`
+}
+
+// NoteTemplate is a collection's note template with the placeholders it may use
+type NoteTemplate struct {
+	CollectionID uint     `json:"collection_id"`
+	Template     string   `json:"template"`
+	Placeholders []string `json:"placeholders"`
+}
+
+// GetNoteTemplate returns the note template of one of the user's collections;
+// the template is empty when none is set
+func (s *Service) GetNoteTemplate(userID, collectionID uint) (*NoteTemplate, error) {
+	collection, err := s.ownCollection(userID, collectionID)
+	if err != nil {
+		return nil, err
+	}
+	return newNoteTemplate(collection), nil
+}
+
+// SetNoteTemplate sets the note template of one of the user's collections.
+// It applies to bookmarks added from then on; notes already written stay
+func (s *Service) SetNoteTemplate(userID, collectionID uint, req NoteTemplateRequest) (*NoteTemplate, error) {
+	if err := validateNoteTemplate(req.Template); err != nil {
+		return nil, err
+	}
+	collection, err := s.ownCollection(userID, collectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Model(collection).Update("note_template", req.Template).Error; err != nil {
+		return nil, fmt.Errorf("failed to save note template: %w", err)
+	}
+	collection.NoteTemplate = req.Template
+	return newNoteTemplate(collection), nil
+}
+
+// DeleteNoteTemplate removes the note template of one of the user's collections
+func (s *Service) DeleteNoteTemplate(userID, collectionID uint) error {
+	collection, err := s.ownCollection(userID, collectionID)
+	if err != nil {
+		return err
+	}
+	if err := s.db.Model(collection).Update("note_template", "").Error; err != nil {
+		return fmt.Errorf("failed to delete note template: %w", err)
+	}
+	return nil
+}
+
+// ownCollection loads one of the user's collections
+func (s *Service) ownCollection(userID, collectionID uint) (*database.Collection, error) {
+	var collection database.Collection
+	if err := s.db.Where("id = ? AND user_id = ?", collectionID, userID).First(&collection).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCollectionNotFound
+		}
+		return nil, fmt.Errorf("failed to get collection: %w", err)
+	}
+	return &collection, nil
+}
+
+// applyNoteTemplate renders the collection's note template into the notes of
+// a bookmark added to it. Bookmarks with notes, including encrypted ones,
+// keep theirs
+func applyNoteTemplate(tx *gorm.DB, collection *database.Collection, bookmark *database.Bookmark, now time.Time) error {
+	if strings.TrimSpace(collection.NoteTemplate) == "" || strings.TrimSpace(bookmark.Notes) != "" || bookmark.NotesEncrypted {
+		return nil
+	}
+
+	notes := renderNoteTemplate(collection.NoteTemplate, collection, bookmark, now)
+	if err := tx.Model(bookmark).Update("notes", notes).Error; err != nil {
+		return fmt.Errorf("failed to apply note template: %w", err)
+	}
+	return nil
+}
+
+// renderNoteTemplate replaces the placeholders of a template. Unknown ones,
+// which validation keeps out of saved templates, are left as written
+func renderNoteTemplate(template string, collection *database.Collection, bookmark *database.Bookmark, now time.Time) string {
+	return notePlaceholder.ReplaceAllStringFunc(template, func(match string) string {
+		render, ok := notePlaceholders[notePlaceholder.FindStringSubmatch(match)[1]]
+		if !ok {
+			return match
+		}
+		return render(collection, bookmark, now)
+	})
+}
+
+// validateNoteTemplate checks a template's length and placeholders, so a typo
+// surfaces when the template is saved rather than in every note
+func validateNoteTemplate(template string) error {
+	if strings.TrimSpace(template) == "" {
+		return fmt.Errorf("%w: the template is empty", ErrInvalidNoteTemplate)
+	}
+	if len([]rune(template)) > maxNoteTemplateLength {
+		return fmt.Errorf("%w: the template is longer than %d characters", ErrInvalidNoteTemplate, maxNoteTemplateLength)
+	}
+	for _, match := range notePlaceholder.FindAllStringSubmatch(template, -1) {
+		if _, ok := notePlaceholders[match[1]]; !ok {
+			return fmt.Errorf("%w: unknown placeholder {{%s}}", ErrInvalidNoteTemplate, match[1])
+		}
+	}
+	return nil
+}
+
+// newNoteTemplate describes a collection's note template
+func newNoteTemplate(collection *database.Collection) *NoteTemplate {
+	names := make([]string, 0, len(notePlaceholders))
+	for name := range notePlaceholders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return &NoteTemplate{CollectionID: collection.ID, Template: collection.NoteTemplate, Placeholders: names}
+}
+
+// noteDomain returns the host of a bookmark's URL without "www."
+func noteDomain(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(parsed.Hostname(), "www.")
+}
+
+// noteTags reads a bookmark's JSON tag list
+func noteTags(raw string) []string {
+	var tags []string
+	if raw != "" {
+		json.Unmarshal([]byte(raw), &tags)
+	}
+	return tags
+}
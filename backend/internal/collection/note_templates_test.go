@@ -0,0 +1,63 @@
+package collection
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+func TestCollectionService_NoteTemplate(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+	research := createBulkTestCollection(t, db, 1, "research", nil)
+
+	// Typos and templates of other users' collections are refused
+	_, err := service.SetNoteTemplate(1, research.ID, NoteTemplateRequest{Template: "Source: {{link}}"})
+	assert.ErrorIs(t, err, ErrInvalidNoteTemplate)
+	_, err = service.SetNoteTemplate(1, research.ID, NoteTemplateRequest{Template: strings.Repeat("x", maxNoteTemplateLength+1)})
+	assert.ErrorIs(t, err, ErrInvalidNoteTemplate)
+	_, err = service.SetNoteTemplate(2, research.ID, NoteTemplateRequest{Template: "{{url}}"})
+	assert.ErrorIs(t, err, ErrCollectionNotFound)
+
+	template, err := service.SetNoteTemplate(1, research.ID, NoteTemplateRequest{
+		Template: "## {{ title }}\n\nSource: {{url}} ({{domain}})\nTags: {{tags}}\nFiled in {{collection}} on {{date}}\n\n### Summary\n",
+	})
+	require.NoError(t, err)
+	assert.Contains(t, template.Placeholders, "url")
+
+	got, err := service.GetNoteTemplate(1, research.ID)
+	require.NoError(t, err)
+	assert.Equal(t, template.Template, got.Template)
+
+	// A bookmark without notes gets the rendered template; one with notes
+	// and an encrypted one keep theirs
+	fresh := createBulkTestBookmark(t, db, 1, "https://www.go.dev/blog/loopvar", `["go","later"]`)
+	noted := createBulkTestBookmark(t, db, 1, "https://example.com/noted", "")
+	require.NoError(t, db.Model(noted).Update("notes", "my own notes").Error)
+	encrypted := createBulkTestBookmark(t, db, 1, "https://example.com/secret", "")
+	require.NoError(t, db.Model(encrypted).Updates(map[string]interface{}{"notes": "", "notes_encrypted": true}).Error)
+
+	for _, bookmark := range []*database.Bookmark{fresh, noted, encrypted} {
+		require.NoError(t, service.AddBookmark(1, research.ID, bookmark.ID))
+	}
+
+	require.NoError(t, db.First(fresh, fresh.ID).Error)
+	today := time.Now().UTC().Format("2006-01-02")
+	assert.Equal(t, "## https://www.go.dev/blog/loopvar\n\nSource: https://www.go.dev/blog/loopvar (go.dev)\nTags: go, later\nFiled in research on "+today+"\n\n### Summary\n", fresh.Notes)
+	require.NoError(t, db.First(noted, noted.ID).Error)
+	assert.Equal(t, "my own notes", noted.Notes)
+	require.NoError(t, db.First(encrypted, encrypted.ID).Error)
+	assert.Empty(t, encrypted.Notes)
+
+	// Without a template bookmarks are added as they are
+	require.NoError(t, service.DeleteNoteTemplate(1, research.ID))
+	other := createBulkTestBookmark(t, db, 1, "https://example.com/other", "")
+	require.NoError(t, service.AddBookmark(1, research.ID, other.ID))
+	require.NoError(t, db.First(other, other.ID).Error)
+	assert.Empty(t, other.Notes)
+}
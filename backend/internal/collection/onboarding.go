@@ -0,0 +1,23 @@
+package collection
+
+import (
+	"context"
+
+	"bookmark-sync-service/backend/internal/onboarding"
+)
+
+// OnboardingTracker completes the onboarding steps waiting for an event
+type OnboardingTracker interface {
+	Track(ctx context.Context, userID uint, event string)
+}
+
+// SetOnboarding makes creating a collection count towards onboarding
+func (s *Service) SetOnboarding(tracker OnboardingTracker) {
+	s.onboarding = tracker
+}
+
+func (s *Service) trackOnboarding(userID uint) {
+	if s.onboarding != nil {
+		s.onboarding.Track(context.Background(), userID, onboarding.EventCollectionCreated)
+	}
+}
@@ -0,0 +1,31 @@
+package collection
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+// BookmarkPublisher announces bookmarks filed in public collections, e.g.
+// to followers on federated instances
+type BookmarkPublisher interface {
+	PublishBookmark(ctx context.Context, userID uint, bookmark *database.Bookmark) error
+}
+
+// SetPublisher makes the service announce bookmarks added to public
+// collections
+func (s *Service) SetPublisher(publisher BookmarkPublisher) {
+	s.publisher = publisher
+}
+
+// publish announces a bookmark. Failures never fail adding it
+func (s *Service) publish(userID uint, bookmark *database.Bookmark) {
+	if s.publisher == nil {
+		return
+	}
+	if err := s.publisher.PublishBookmark(context.Background(), userID, bookmark); err != nil {
+		s.logger.Warn("Failed to publish bookmark", zap.Uint("bookmark_id", bookmark.ID), zap.Error(err))
+	}
+}
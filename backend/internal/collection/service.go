@@ -6,20 +6,45 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
+	"go.uber.org/zap"
 	"gorm.io/gorm"
 
+	"bookmark-sync-service/backend/internal/automation"
+	"bookmark-sync-service/backend/pkg/clientid"
 	"bookmark-sync-service/backend/pkg/database"
 )
 
+// ErrClientIDDeleted is returned when creating a collection with the client
+// ID of one that was deleted
+var ErrClientIDDeleted = errors.New("client_id belongs to a deleted collection")
+
 // Service handles collection business logic
 type Service struct {
-	db *gorm.DB
+	db         *gorm.DB
+	indexer    SearchIndexer
+	webhooks   WebhookTrigger
+	publisher  BookmarkPublisher
+	onboarding OnboardingTracker
+	graph      BookmarkGraph
+
+	shareRenders ShareRenders
+	screenshots  ScreenshotFetcher
+	access       AccessChecker
+
+	logger *zap.Logger
 }
 
 // NewService creates a new collection service
 func NewService(db *gorm.DB) *Service {
-	return &Service{db: db}
+	return &Service{db: db, logger: zap.NewNop()}
+}
+
+// SetLogger logs the follow-up work that fails without failing a change,
+// such as webhook deliveries
+func (s *Service) SetLogger(logger *zap.Logger) {
+	s.logger = logger
 }
 
 // CreateCollectionRequest represents a request to create a collection
@@ -30,6 +55,9 @@ type CreateCollectionRequest struct {
 	Icon        string `json:"icon,omitempty"`
 	ParentID    *uint  `json:"parent_id,omitempty"`
 	Visibility  string `json:"visibility" binding:"required,oneof=private public shared"`
+	// ClientID is a UUID from an offline client; creating it again returns
+	// the same collection
+	ClientID string `json:"client_id,omitempty"`
 }
 
 // UpdateCollectionRequest represents a request to update a collection
@@ -82,6 +110,80 @@ type GetCollectionBookmarksResult struct {
 
 // Create creates a new collection
 func (s *Service) Create(userID uint, req CreateCollectionRequest) (*database.Collection, error) {
+	collection, err := s.newCollection(userID, req)
+	if err != nil {
+		return nil, err
+	}
+	if existing, err := s.replayed(collection); err != nil || existing != nil {
+		return existing, err
+	}
+
+	if err := s.db.Create(collection).Error; err != nil {
+		if existing, lookupErr := s.replayed(collection); lookupErr == nil && existing != nil {
+			return existing, nil
+		}
+		return nil, fmt.Errorf("failed to create collection: %w", err)
+	}
+
+	s.recordRevision(collection.ID, RevisionCreate)
+	s.index(collection.ID)
+	s.trackOnboarding(userID)
+	s.triggerCollectionWebhook(automation.WebhookEventCollectionCreated, collection)
+	return collection, nil
+}
+
+// CreateWithBookmarks creates a collection already holding the given
+// bookmarks, e.g. saved search results. IDs of bookmarks the user does not
+// own, or has deleted, are skipped; the count returned is how many were added
+func (s *Service) CreateWithBookmarks(userID uint, req CreateCollectionRequest, bookmarkIDs []uint) (*database.Collection, int, error) {
+	collection, err := s.newCollection(userID, req)
+	if err != nil {
+		return nil, 0, err
+	}
+	if existing, err := s.replayed(collection); err != nil || existing != nil {
+		return existing, 0, err
+	}
+
+	added := 0
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(collection).Error; err != nil {
+			return fmt.Errorf("failed to create collection: %w", err)
+		}
+
+		for start := 0; start < len(bookmarkIDs); start += bookmarkBatchSize {
+			end := start + bookmarkBatchSize
+			if end > len(bookmarkIDs) {
+				end = len(bookmarkIDs)
+			}
+
+			var owned []uint
+			if err := tx.Model(&database.Bookmark{}).
+				Where("id IN ? AND user_id = ?", bookmarkIDs[start:end], userID).
+				Distinct().Pluck("id", &owned).Error; err != nil {
+				return fmt.Errorf("failed to find bookmarks: %w", err)
+			}
+			if err := linkBookmarks(tx, collection.ID, owned); err != nil {
+				return err
+			}
+			added += len(owned)
+		}
+
+		// One revision for the finished collection rather than one per bookmark
+		return recordRevisionTx(tx, collection.ID, RevisionCreate)
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	s.index(collection.ID)
+	s.markGraph(collection.ID)
+	s.trackOnboarding(userID)
+	s.triggerCollectionWebhook(automation.WebhookEventCollectionCreated, collection)
+	return collection, added, nil
+}
+
+// newCollection validates a create request and builds the collection
+func (s *Service) newCollection(userID uint, req CreateCollectionRequest) (*database.Collection, error) {
 	// Validate request
 	if strings.TrimSpace(req.Name) == "" {
 		return nil, errors.New("name is required")
@@ -102,15 +204,20 @@ func (s *Service) Create(userID uint, req CreateCollectionRequest) (*database.Co
 		}
 	}
 
+	clientID, err := clientid.Normalize(req.ClientID)
+	if err != nil {
+		return nil, err
+	}
+
 	// Generate share link
 	shareLink, err := s.generateShareLink()
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate share link: %w", err)
 	}
 
-	// Create collection
-	collection := &database.Collection{
+	return &database.Collection{
 		UserID:      userID,
+		ClientID:    clientid.Ptr(clientID),
 		Name:        strings.TrimSpace(req.Name),
 		Description: req.Description,
 		Color:       req.Color,
@@ -118,13 +225,28 @@ func (s *Service) Create(userID uint, req CreateCollectionRequest) (*database.Co
 		ParentID:    req.ParentID,
 		Visibility:  req.Visibility,
 		ShareLink:   shareLink,
-	}
+	}, nil
+}
 
-	if err := s.db.Create(collection).Error; err != nil {
-		return nil, fmt.Errorf("failed to create collection: %w", err)
+// replayed returns the collection a retried create already made, found by
+// the client ID of the new one. A client ID of a deleted collection cannot
+// be reused
+func (s *Service) replayed(collection *database.Collection) (*database.Collection, error) {
+	if collection.ClientID == nil {
+		return nil, nil
 	}
-
-	return collection, nil
+	var existing database.Collection
+	err := s.db.Unscoped().Where("user_id = ? AND client_id = ?", collection.UserID, *collection.ClientID).First(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find collection by client ID: %w", err)
+	}
+	if existing.DeletedAt.Valid {
+		return nil, ErrClientIDDeleted
+	}
+	return &existing, nil
 }
 
 // GetByID retrieves a collection by ID
@@ -133,11 +255,6 @@ func (s *Service) GetByID(userID, id uint) (*database.Collection, error) {
 
 	query := s.db.Where("id = ?", id)
 
-	// For private collections, ensure user ownership
-	// For public collections, allow access by anyone
-	// For shared collections, allow access by anyone with the link (handled in handlers)
-	query = query.Where("user_id = ? OR visibility = ?", userID, "public")
-
 	if err := query.Preload("User").Preload("Parent").First(&collection).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("collection not found")
@@ -145,6 +262,13 @@ func (s *Service) GetByID(userID, id uint) (*database.Collection, error) {
 		return nil, fmt.Errorf("failed to get collection: %w", err)
 	}
 
+	// For private collections, ensure user ownership or a granted share
+	// For public collections, allow access by anyone
+	// For shared collections, allow access by anyone with the link (handled in handlers)
+	if err := s.checkAccess(userID, &collection, accessView); err != nil {
+		return nil, err
+	}
+
 	return &collection, nil
 }
 
@@ -215,12 +339,22 @@ func (s *Service) List(userID uint, params ListCollectionsParams) (*ListCollecti
 func (s *Service) Update(userID, id uint, req UpdateCollectionRequest) (*database.Collection, error) {
 	// Get existing collection
 	var collection database.Collection
-	if err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&collection).Error; err != nil {
+	if err := s.db.Where("id = ?", id).First(&collection).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("collection not found")
 		}
 		return nil, fmt.Errorf("failed to get collection: %w", err)
 	}
+	if collection.UserID != userID {
+		if err := s.checkGrant(userID, collection.ID, accessEdit); err != nil {
+			return nil, err
+		}
+		// Editors change the content; where it lives and who sees it stay
+		// with the owner
+		if req.Visibility != nil || req.ParentID != nil {
+			return nil, ErrUnauthorized
+		}
+	}
 
 	// Validate updates
 	if req.Name != nil {
@@ -252,7 +386,7 @@ func (s *Service) Update(userID, id uint, req UpdateCollectionRequest) (*databas
 	if req.ParentID != nil {
 		// Validate parent collection
 		var parent database.Collection
-		if err := s.db.Where("id = ? AND user_id = ?", *req.ParentID, userID).First(&parent).Error; err != nil {
+		if err := s.db.Where("id = ? AND user_id = ?", *req.ParentID, collection.UserID).First(&parent).Error; err != nil {
 			if errors.Is(err, gorm.ErrRecordNotFound) {
 				return nil, errors.New("parent collection not found")
 			}
@@ -266,6 +400,15 @@ func (s *Service) Update(userID, id uint, req UpdateCollectionRequest) (*databas
 		return nil, fmt.Errorf("failed to update collection: %w", err)
 	}
 
+	s.recordRevision(collection.ID, RevisionUpdate)
+
+	// Renames and moves change the indexed paths of sub-collections too
+	if req.Name != nil || req.ParentID != nil {
+		s.indexTree(collection.ID)
+	} else {
+		s.index(collection.ID)
+	}
+	s.triggerCollectionWebhook(automation.WebhookEventCollectionUpdated, &collection)
 	return &collection, nil
 }
 
@@ -285,6 +428,12 @@ func (s *Service) Delete(userID, id uint) error {
 		return fmt.Errorf("failed to delete collection: %w", err)
 	}
 
+	if s.indexer != nil {
+		s.indexer.QueueCollectionDelete(collection.ID)
+	}
+	s.refreshShares(collection.ID)
+	s.markGraph(collection.ID)
+	s.triggerCollectionWebhook(automation.WebhookEventCollectionDeleted, &collection)
 	return nil
 }
 
@@ -308,11 +457,25 @@ func (s *Service) AddBookmark(userID, collectionID, bookmarkID uint) error {
 		return fmt.Errorf("failed to get bookmark: %w", err)
 	}
 
-	// Add bookmark to collection (GORM handles duplicates automatically)
-	if err := s.db.Model(&collection).Association("Bookmarks").Append(&bookmark); err != nil {
-		return fmt.Errorf("failed to add bookmark to collection: %w", err)
+	// Add bookmark to collection (GORM handles duplicates automatically),
+	// writing the collection's note template into its notes
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&collection).Association("Bookmarks").Append(&bookmark); err != nil {
+			return fmt.Errorf("failed to add bookmark to collection: %w", err)
+		}
+		return applyNoteTemplate(tx, &collection, &bookmark, time.Now().UTC())
+	})
+	if err != nil {
+		return err
 	}
 
+	s.recordRevision(collection.ID, RevisionAddBookmark)
+	s.index(collection.ID)
+	s.markGraph(collection.ID)
+	s.triggerBookmarkWebhook(automation.WebhookEventCollectionBookmarkAdded, &collection, &bookmark)
+	if collection.Visibility == "public" {
+		s.publish(userID, &bookmark)
+	}
 	return nil
 }
 
@@ -334,6 +497,10 @@ func (s *Service) RemoveBookmark(userID, collectionID, bookmarkID uint) error {
 		return fmt.Errorf("failed to remove bookmark from collection: %w", err)
 	}
 
+	s.recordRevision(collection.ID, RevisionRemoveBookmark)
+	s.index(collection.ID)
+	s.markGraph(collection.ID, bookmarkID)
+	s.triggerBookmarkWebhook(automation.WebhookEventCollectionBookmarkRemoved, &collection, &bookmark)
 	return nil
 }
 
@@ -349,15 +516,15 @@ func (s *Service) GetBookmarks(userID, collectionID uint, params GetCollectionBo
 
 	// Verify collection exists and user has access
 	var collection database.Collection
-	query := s.db.Where("id = ?", collectionID)
-	query = query.Where("user_id = ? OR visibility = ?", userID, "public")
-
-	if err := query.First(&collection).Error; err != nil {
+	if err := s.db.Where("id = ?", collectionID).First(&collection).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("collection not found")
 		}
 		return nil, fmt.Errorf("failed to get collection: %w", err)
 	}
+	if err := s.checkAccess(userID, &collection, accessView); err != nil {
+		return nil, err
+	}
 
 	var bookmarks []database.Bookmark
 	var total int64
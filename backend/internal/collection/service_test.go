@@ -743,3 +743,58 @@ func TestCollectionService_GetBookmarks(t *testing.T) {
 		})
 	}
 }
+
+func TestCollectionService_CreateWithBookmarks(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+
+	mine := createBulkTestBookmark(t, db, 1, "https://example.com/mine", `[]`)
+	deleted := createBulkTestBookmark(t, db, 1, "https://example.com/deleted", `[]`)
+	theirs := createBulkTestBookmark(t, db, 2, "https://example.com/theirs", `[]`)
+	require.NoError(t, db.Delete(deleted).Error)
+
+	collection, added, err := service.CreateWithBookmarks(1, CreateCollectionRequest{Name: "Results", Visibility: "private"},
+		[]uint{mine.ID, deleted.ID, theirs.ID, mine.ID})
+	require.NoError(t, err)
+	assert.Equal(t, 1, added)
+	assert.Equal(t, []uint{mine.ID}, collectionBookmarkIDs(t, db, collection.ID))
+
+	revisions, err := service.ListRevisions(1, collection.ID, 0)
+	require.NoError(t, err)
+	require.Len(t, revisions, 1)
+	assert.Equal(t, RevisionCreate, revisions[0].Action)
+	assert.Equal(t, 1, revisions[0].BookmarkCount)
+
+	_, _, err = service.CreateWithBookmarks(1, CreateCollectionRequest{Name: " ", Visibility: "private"}, nil)
+	assert.Error(t, err)
+}
+
+func TestCollectionService_CreateWithClientID(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+
+	req := CreateCollectionRequest{Name: "Offline", Visibility: "private", ClientID: "9b2e1c7a-0d4f-4a53-8f0e-6c1d2b3a4f5e"}
+	first, err := service.Create(1, req)
+	require.NoError(t, err)
+	require.NotNil(t, first.ClientID)
+
+	retried, err := service.Create(1, req)
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, retried.ID)
+
+	saved, added, err := service.CreateWithBookmarks(1, req, nil)
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, saved.ID)
+	assert.Zero(t, added)
+
+	var count int64
+	require.NoError(t, db.Model(&database.Collection{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+
+	_, err = service.Create(1, CreateCollectionRequest{Name: "Bad", Visibility: "private", ClientID: "offline"})
+	assert.Error(t, err)
+
+	require.NoError(t, db.Delete(first).Error)
+	_, err = service.Create(1, req)
+	assert.ErrorIs(t, err, ErrClientIDDeleted)
+}
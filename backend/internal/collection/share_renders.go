@@ -0,0 +1,30 @@
+package collection
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+// ShareRenders keeps pre-rendered public share pages of collections up to
+// date, e.g. the sharing service
+type ShareRenders interface {
+	RefreshCollectionRenders(ctx context.Context, collectionID uint) error
+}
+
+// SetShareRenders makes the service render a collection's public shares
+// again whenever it changes
+func (s *Service) SetShareRenders(renders ShareRenders) {
+	s.shareRenders = renders
+}
+
+// refreshShares renders a changed collection's shares again. Failures
+// never fail the change; views render the share themselves instead
+func (s *Service) refreshShares(id uint) {
+	if s.shareRenders == nil {
+		return
+	}
+	if err := s.shareRenders.RefreshCollectionRenders(context.Background(), id); err != nil {
+		s.logger.Warn("Failed to refresh share renders", zap.Uint("collection_id", id), zap.Error(err))
+	}
+}
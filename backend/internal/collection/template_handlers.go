@@ -0,0 +1,249 @@
+package collection
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"bookmark-sync-service/backend/pkg/utils"
+)
+
+// ListTemplates lists the collection template gallery
+// @Summary List collection templates
+// @Description List built-in templates, templates other users published and your own
+// @Tags collections
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(20)
+// @Param category query string false "Filter by category"
+// @Param search query string false "Search names and descriptions"
+// @Param mine query bool false "Only your own templates"
+// @Param sort_by query string false "Sort order" default(rating) Enums(rating, popular, newest)
+// @Success 200 {object} ListTemplatesResult
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/collections/templates [get]
+func (h *Handler) ListTemplates(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	var params ListTemplatesParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid query parameters", nil)
+		return
+	}
+
+	result, err := h.service.ListTemplates(userID, params)
+	if err != nil {
+		templateErrorResponse(c, err, "Failed to list templates")
+		return
+	}
+
+	utils.SuccessResponse(c, result, "Templates retrieved successfully")
+}
+
+// GetTemplate returns a collection template
+// @Summary Get a collection template
+// @Description Get a template with its sub-collections and suggested tags
+// @Tags collections
+// @Produce json
+// @Param template_id path int true "Template ID"
+// @Success 200 {object} Template
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /api/v1/collections/templates/{template_id} [get]
+func (h *Handler) GetTemplate(c *gin.Context) {
+	userID, id, ok := templateParams(c, "template_id")
+	if !ok {
+		return
+	}
+
+	template, err := h.service.GetTemplate(userID, id)
+	if err != nil {
+		templateErrorResponse(c, err, "Failed to get template")
+		return
+	}
+
+	utils.SuccessResponse(c, template, "Template retrieved successfully")
+}
+
+// CreateTemplate contributes a collection template
+// @Summary Create a collection template
+// @Description Contribute a template of at most 3 levels and 50 collections. Public templates appear in everyone's gallery
+// @Tags collections
+// @Accept json
+// @Produce json
+// @Param request body CreateTemplateRequest true "Template"
+// @Success 201 {object} Template
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/collections/templates [post]
+func (h *Handler) CreateTemplate(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	var req CreateTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid request data", nil)
+		return
+	}
+
+	template, err := h.service.CreateTemplate(userID, req)
+	if err != nil {
+		templateErrorResponse(c, err, "Failed to create template")
+		return
+	}
+
+	c.JSON(http.StatusCreated, utils.APIResponse{
+		Success: true,
+		Message: "Template created successfully",
+		Data:    template,
+	})
+}
+
+// DeleteTemplate removes one of your collection templates
+// @Summary Delete a collection template
+// @Description Delete a template you contributed. Collections created from it are kept
+// @Tags collections
+// @Produce json
+// @Param template_id path int true "Template ID"
+// @Success 200 {object} utils.APIResponse
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /api/v1/collections/templates/{template_id} [delete]
+func (h *Handler) DeleteTemplate(c *gin.Context) {
+	userID, id, ok := templateParams(c, "template_id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteTemplate(userID, id); err != nil {
+		templateErrorResponse(c, err, "Failed to delete template")
+		return
+	}
+
+	utils.SuccessResponse(c, nil, "Template deleted successfully")
+}
+
+// RateTemplate rates a collection template
+// @Summary Rate a collection template
+// @Description Rate a built-in or published template from 1 to 5 stars, once
+// @Tags collections
+// @Accept json
+// @Produce json
+// @Param template_id path int true "Template ID"
+// @Param request body RateTemplateRequest true "Rating"
+// @Success 201 {object} database.CollectionTemplateRating
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Router /api/v1/collections/templates/{template_id}/ratings [post]
+func (h *Handler) RateTemplate(c *gin.Context) {
+	userID, id, ok := templateParams(c, "template_id")
+	if !ok {
+		return
+	}
+
+	var req RateTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid request data", nil)
+		return
+	}
+
+	rating, err := h.service.RateTemplate(userID, id, req)
+	if err != nil {
+		templateErrorResponse(c, err, "Failed to rate template")
+		return
+	}
+
+	c.JSON(http.StatusCreated, utils.APIResponse{
+		Success: true,
+		Message: "Template rated successfully",
+		Data:    rating,
+	})
+}
+
+// CreateFromTemplate creates a collection tree from a template
+// @Summary Create collections from a template
+// @Description Create a collection and its sub-collections from a template. Each collection's metadata lists the tags the template suggests for it
+// @Tags collections
+// @Accept json
+// @Produce json
+// @Param id path int true "Template ID"
+// @Param request body FromTemplateRequest false "Overrides"
+// @Success 201 {object} TemplateInstance
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/collections/from-template/{id} [post]
+func (h *Handler) CreateFromTemplate(c *gin.Context) {
+	userID, id, ok := templateParams(c, "id")
+	if !ok {
+		return
+	}
+
+	var req FromTemplateRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid request data", nil)
+			return
+		}
+	}
+
+	instance, err := h.service.CreateFromTemplate(userID, id, req)
+	if err != nil {
+		templateErrorResponse(c, err, "Failed to create collections from template")
+		return
+	}
+
+	c.JSON(http.StatusCreated, utils.APIResponse{
+		Success: true,
+		Message: "Collections created from template",
+		Data:    instance,
+	})
+}
+
+// templateParams reads the user and the template ID of a template request,
+// responding itself when either is missing
+func templateParams(c *gin.Context, param string) (uint, uint, bool) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return 0, 0, false
+	}
+
+	id, err := strconv.ParseUint(c.Param(param), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid template ID", nil)
+		return 0, 0, false
+	}
+	return userID, uint(id), true
+}
+
+// templateErrorResponse maps template errors to HTTP responses
+func templateErrorResponse(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, ErrTemplateNotFound), errors.Is(err, ErrCollectionNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, "NOT_FOUND", err.Error(), nil)
+	case errors.Is(err, ErrTemplateAlreadyRated):
+		utils.ErrorResponse(c, http.StatusConflict, "ALREADY_RATED", err.Error(), nil)
+	case errors.Is(err, ErrInvalidTemplate), errors.Is(err, ErrInvalidTemplateRating), errors.Is(err, ErrRateOwnTemplate):
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error(), nil)
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", message, nil)
+	}
+}
@@ -0,0 +1,528 @@
+package collection
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/tags"
+)
+
+// Template structure limits, so a contributed template cannot create
+// collections without bound
+const (
+	maxTemplateDepth       = 3
+	maxTemplateCollections = 50
+	maxTemplateTags        = 20
+)
+
+// Template sort orders of the gallery
+const (
+	TemplateSortRating  = "rating"
+	TemplateSortPopular = "popular"
+	TemplateSortNewest  = "newest"
+)
+
+// Collection template errors
+var (
+	ErrTemplateNotFound      = errors.New("template not found")
+	ErrInvalidTemplate       = errors.New("template needs a name and at most 3 levels and 50 collections, each with a name")
+	ErrTemplateAlreadyRated  = errors.New("template already rated")
+	ErrRateOwnTemplate       = errors.New("cannot rate your own template")
+	ErrInvalidTemplateRating = errors.New("rating must be between 1 and 5 and the comment at most 500 characters")
+)
+
+// TemplateNode is a collection in a template, with the sub-collections
+// created under it and tags suggested for its bookmarks
+type TemplateNode struct {
+	Name          string         `json:"name"`
+	Description   string         `json:"description,omitempty"`
+	Color         string         `json:"color,omitempty"`
+	Icon          string         `json:"icon,omitempty"`
+	SuggestedTags []string       `json:"suggested_tags,omitempty"`
+	Children      []TemplateNode `json:"children,omitempty"`
+}
+
+// Template is a gallery entry with its structure
+type Template struct {
+	database.CollectionTemplate
+	Builtin   bool         `json:"builtin"`
+	Structure TemplateNode `json:"structure"`
+}
+
+// CreateTemplateRequest contributes a template to the gallery
+type CreateTemplateRequest struct {
+	Name        string       `json:"name" binding:"required,max=100"`
+	Description string       `json:"description" binding:"max=1000"`
+	Category    string       `json:"category" binding:"max=50"`
+	IsPublic    bool         `json:"is_public"`
+	Structure   TemplateNode `json:"structure"`
+}
+
+// ListTemplatesParams filters and orders the template gallery
+type ListTemplatesParams struct {
+	Page     int    `form:"page,default=1" binding:"min=1"`
+	Limit    int    `form:"limit,default=20" binding:"min=1,max=100"`
+	Category string `form:"category"`
+	Search   string `form:"search"`
+	Mine     bool   `form:"mine"` // only the user's own templates
+	SortBy   string `form:"sort_by,default=rating" binding:"oneof=rating popular newest"`
+}
+
+// ListTemplatesResult is a page of the template gallery
+type ListTemplatesResult struct {
+	Templates  []Template `json:"templates"`
+	Total      int64      `json:"total"`
+	Page       int        `json:"page"`
+	Limit      int        `json:"limit"`
+	TotalPages int        `json:"total_pages"`
+}
+
+// RateTemplateRequest rates a template
+type RateTemplateRequest struct {
+	Rating  int    `json:"rating" binding:"required"`
+	Comment string `json:"comment"`
+}
+
+// FromTemplateRequest overrides parts of the collection created from a
+// template. All fields are optional
+type FromTemplateRequest struct {
+	Name       string `json:"name"`
+	ParentID   *uint  `json:"parent_id"`
+	Visibility string `json:"visibility" binding:"omitempty,oneof=private public shared"`
+}
+
+// TemplateInstance is the collection tree created from a template
+type TemplateInstance struct {
+	Collection *database.Collection `json:"collection"`
+	Created    int                  `json:"created"` // collections created, the root included
+	// SuggestedTags are the tags the template suggests anywhere in the tree
+	SuggestedTags []string `json:"suggested_tags"`
+}
+
+// templateMetadata is kept in the metadata of collections created from a
+// template, so clients can offer its suggested tags when filing bookmarks
+type templateMetadata struct {
+	TemplateID    uint     `json:"template_id"`
+	SuggestedTags []string `json:"suggested_tags,omitempty"`
+}
+
+// builtinTemplate is a template shipped with the service
+type builtinTemplate struct {
+	Key         string
+	Category    string
+	Description string
+	Structure   TemplateNode
+}
+
+var builtinTemplates = []builtinTemplate{
+	{
+		Key:         "job-hunt",
+		Category:    "career",
+		Description: "Track openings, companies and interview preparation",
+		Structure: TemplateNode{
+			Name: "Job Hunt", Icon: "briefcase", Color: "#2563eb",
+			SuggestedTags: []string{"job-hunt"},
+			Children: []TemplateNode{
+				{Name: "Openings", SuggestedTags: []string{"job-hunt/opening", "to-apply"}},
+				{Name: "Companies", SuggestedTags: []string{"job-hunt/company"}},
+				{Name: "Interview Prep", SuggestedTags: []string{"job-hunt/interview", "practice"}},
+				{Name: "Resume & Portfolio", SuggestedTags: []string{"job-hunt/resume"}},
+				{Name: "Salary Research", SuggestedTags: []string{"job-hunt/salary"}},
+			},
+		},
+	},
+	{
+		Key:         "trip-planning",
+		Category:    "travel",
+		Description: "Plan transport, stays and things to do for a trip",
+		Structure: TemplateNode{
+			Name: "Trip Planning", Icon: "map", Color: "#16a34a",
+			SuggestedTags: []string{"travel"},
+			Children: []TemplateNode{
+				{Name: "Flights & Transport", SuggestedTags: []string{"travel/transport"}},
+				{Name: "Accommodation", SuggestedTags: []string{"travel/stay"}},
+				{Name: "Things to Do", SuggestedTags: []string{"travel/activity"}},
+				{Name: "Food & Drink", SuggestedTags: []string{"travel/food"}},
+				{Name: "Documents & Visas", SuggestedTags: []string{"travel/documents"}},
+			},
+		},
+	},
+	{
+		Key:         "research-project",
+		Category:    "research",
+		Description: "Organise papers, datasets and notes for a research project",
+		Structure: TemplateNode{
+			Name: "Research Project", Icon: "flask", Color: "#9333ea",
+			SuggestedTags: []string{"research"},
+			Children: []TemplateNode{
+				{
+					Name: "Literature", SuggestedTags: []string{"research/paper"},
+					Children: []TemplateNode{
+						{Name: "To Read", SuggestedTags: []string{"to-read"}},
+						{Name: "Read", SuggestedTags: []string{"research/read"}},
+					},
+				},
+				{Name: "Datasets", SuggestedTags: []string{"research/dataset"}},
+				{Name: "Tools & Methods", SuggestedTags: []string{"research/tool"}},
+				{Name: "Related Work", SuggestedTags: []string{"research/related"}},
+			},
+		},
+	},
+}
+
+// SeedTemplates creates the built-in templates and updates them to the
+// structure shipped with this version
+func (s *Service) SeedTemplates() error {
+	for _, builtin := range builtinTemplates {
+		structure, err := json.Marshal(builtin.Structure)
+		if err != nil {
+			return fmt.Errorf("failed to encode template %s: %w", builtin.Key, err)
+		}
+		key := builtin.Key
+		template := database.CollectionTemplate{BuiltinKey: &key}
+		if err := s.db.Where("builtin_key = ?", key).
+			Assign(map[string]interface{}{
+				"name":        builtin.Structure.Name,
+				"description": builtin.Description,
+				"category":    builtin.Category,
+				"is_public":   true,
+				"structure":   string(structure),
+			}).
+			FirstOrCreate(&template).Error; err != nil {
+			return fmt.Errorf("failed to seed template %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// ListTemplates returns the gallery: built-in templates, templates users
+// made public and the user's own
+func (s *Service) ListTemplates(userID uint, params ListTemplatesParams) (*ListTemplatesResult, error) {
+	query := s.db.Model(&database.CollectionTemplate{})
+	if params.Mine {
+		query = query.Where("creator_id = ?", userID)
+	} else {
+		query = query.Where("is_public = ? OR creator_id = ?", true, userID)
+	}
+	if params.Category != "" {
+		query = query.Where("category = ?", params.Category)
+	}
+	if params.Search != "" {
+		term := "%" + strings.ToLower(params.Search) + "%"
+		query = query.Where("LOWER(name) LIKE ? OR LOWER(description) LIKE ?", term, term)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, fmt.Errorf("failed to count templates: %w", err)
+	}
+
+	switch params.SortBy {
+	case TemplateSortPopular:
+		query = query.Order("use_count DESC")
+	case TemplateSortNewest:
+		query = query.Order("created_at DESC")
+	default:
+		query = query.Order("rating DESC").Order("rating_count DESC")
+	}
+
+	var templates []database.CollectionTemplate
+	if err := query.Order("id").
+		Offset((params.Page - 1) * params.Limit).
+		Limit(params.Limit).
+		Find(&templates).Error; err != nil {
+		return nil, fmt.Errorf("failed to list templates: %w", err)
+	}
+
+	result := &ListTemplatesResult{
+		Templates:  make([]Template, 0, len(templates)),
+		Total:      total,
+		Page:       params.Page,
+		Limit:      params.Limit,
+		TotalPages: int((total + int64(params.Limit) - 1) / int64(params.Limit)),
+	}
+	for i := range templates {
+		result.Templates = append(result.Templates, toTemplate(&templates[i]))
+	}
+	return result, nil
+}
+
+// GetTemplate returns a template the user may see
+func (s *Service) GetTemplate(userID, id uint) (*Template, error) {
+	template, err := s.visibleTemplate(s.db, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	result := toTemplate(template)
+	return &result, nil
+}
+
+// CreateTemplate contributes a user template
+func (s *Service) CreateTemplate(userID uint, req CreateTemplateRequest) (*Template, error) {
+	if strings.TrimSpace(req.Name) == "" || strings.TrimSpace(req.Structure.Name) == "" {
+		return nil, ErrInvalidTemplate
+	}
+	structure := normalizeTemplateNode(req.Structure)
+	if err := validateTemplateNode(structure, 1, new(int)); err != nil {
+		return nil, err
+	}
+	encoded, err := json.Marshal(structure)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode template: %w", err)
+	}
+
+	template := &database.CollectionTemplate{
+		CreatorID:   &userID,
+		Name:        strings.TrimSpace(req.Name),
+		Description: req.Description,
+		Category:    strings.ToLower(strings.TrimSpace(req.Category)),
+		IsPublic:    req.IsPublic,
+		Structure:   string(encoded),
+	}
+	if err := s.db.Create(template).Error; err != nil {
+		return nil, fmt.Errorf("failed to create template: %w", err)
+	}
+
+	result := toTemplate(template)
+	return &result, nil
+}
+
+// DeleteTemplate removes one of the user's own templates
+func (s *Service) DeleteTemplate(userID, id uint) error {
+	result := s.db.Where("id = ? AND creator_id = ?", id, userID).Delete(&database.CollectionTemplate{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete template: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrTemplateNotFound
+	}
+	return nil
+}
+
+// RateTemplate rates a built-in or public template once, the way themes are
+// rated, and updates its average
+func (s *Service) RateTemplate(userID, id uint, req RateTemplateRequest) (*database.CollectionTemplateRating, error) {
+	if req.Rating < 1 || req.Rating > 5 || len(req.Comment) > 500 {
+		return nil, ErrInvalidTemplateRating
+	}
+
+	rating := &database.CollectionTemplateRating{TemplateID: id, UserID: userID, Rating: req.Rating, Comment: req.Comment}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		template, err := s.visibleTemplate(tx, userID, id)
+		if err != nil {
+			return err
+		}
+		if template.CreatorID != nil && *template.CreatorID == userID {
+			return ErrRateOwnTemplate
+		}
+
+		var existing int64
+		if err := tx.Model(&database.CollectionTemplateRating{}).
+			Where("template_id = ? AND user_id = ?", id, userID).
+			Count(&existing).Error; err != nil {
+			return fmt.Errorf("failed to check existing rating: %w", err)
+		}
+		if existing > 0 {
+			return ErrTemplateAlreadyRated
+		}
+		if err := tx.Create(rating).Error; err != nil {
+			return fmt.Errorf("failed to create rating: %w", err)
+		}
+
+		var stats struct {
+			Average float64
+			Count   int
+		}
+		if err := tx.Model(&database.CollectionTemplateRating{}).
+			Select("AVG(rating) AS average, COUNT(*) AS count").
+			Where("template_id = ?", id).
+			Scan(&stats).Error; err != nil {
+			return fmt.Errorf("failed to compute template rating: %w", err)
+		}
+		return tx.Model(template).UpdateColumns(map[string]interface{}{
+			"rating":       stats.Average,
+			"rating_count": stats.Count,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rating, nil
+}
+
+// CreateFromTemplate creates a collection and its sub-collections from a
+// template. Suggested tags are kept in each collection's metadata
+func (s *Service) CreateFromTemplate(userID, id uint, req FromTemplateRequest) (*TemplateInstance, error) {
+	template, err := s.visibleTemplate(s.db, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	structure := toTemplate(template).Structure
+	if name := strings.TrimSpace(req.Name); name != "" {
+		structure.Name = name
+	}
+	visibility := req.Visibility
+	if visibility == "" {
+		visibility = "private"
+	}
+
+	// Share links are checked for uniqueness outside the transaction, as in
+	// CreateWithBookmarks
+	shareLinks := make([]string, countTemplateNodes(structure))
+	for i := range shareLinks {
+		if shareLinks[i], err = s.generateShareLink(); err != nil {
+			return nil, fmt.Errorf("failed to generate share link: %w", err)
+		}
+	}
+
+	instance := &TemplateInstance{SuggestedTags: collectTemplateTags(structure, nil)}
+	var created []uint
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if req.ParentID != nil {
+			var parent database.Collection
+			if err := tx.Where("id = ? AND user_id = ?", *req.ParentID, userID).First(&parent).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return ErrCollectionNotFound
+				}
+				return fmt.Errorf("failed to validate parent collection: %w", err)
+			}
+		}
+
+		root, err := createTemplateNode(tx, userID, template.ID, structure, req.ParentID, visibility, shareLinks, &created)
+		if err != nil {
+			return err
+		}
+		instance.Collection = root
+
+		return tx.Model(template).UpdateColumn("use_count", gorm.Expr("use_count + 1")).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	instance.Created = len(created)
+	for _, collectionID := range created {
+		s.index(collectionID)
+	}
+	return instance, nil
+}
+
+// createTemplateNode creates the collection of a template node and, below
+// it, those of its children, taking share links in the order created
+func createTemplateNode(tx *gorm.DB, userID, templateID uint, node TemplateNode, parentID *uint, visibility string, shareLinks []string, created *[]uint) (*database.Collection, error) {
+	metadata, err := json.Marshal(templateMetadata{TemplateID: templateID, SuggestedTags: node.SuggestedTags})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode collection metadata: %w", err)
+	}
+
+	collection := &database.Collection{
+		UserID:      userID,
+		Name:        node.Name,
+		Description: node.Description,
+		Color:       node.Color,
+		Icon:        node.Icon,
+		ParentID:    parentID,
+		Visibility:  visibility,
+		ShareLink:   shareLinks[len(*created)],
+		Metadata:    string(metadata),
+	}
+	if err := tx.Create(collection).Error; err != nil {
+		return nil, fmt.Errorf("failed to create collection: %w", err)
+	}
+	if err := recordRevisionTx(tx, collection.ID, RevisionCreate); err != nil {
+		return nil, err
+	}
+	*created = append(*created, collection.ID)
+
+	for _, child := range node.Children {
+		childCollection, err := createTemplateNode(tx, userID, templateID, child, &collection.ID, visibility, shareLinks, created)
+		if err != nil {
+			return nil, err
+		}
+		collection.Children = append(collection.Children, *childCollection)
+	}
+	return collection, nil
+}
+
+// visibleTemplate returns a template that is built in, public or the user's
+func (s *Service) visibleTemplate(tx *gorm.DB, userID, id uint) (*database.CollectionTemplate, error) {
+	var template database.CollectionTemplate
+	if err := tx.Where("id = ? AND (is_public = ? OR creator_id = ?)", id, true, userID).
+		First(&template).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTemplateNotFound
+		}
+		return nil, fmt.Errorf("failed to get template: %w", err)
+	}
+	return &template, nil
+}
+
+// toTemplate decodes a stored template for the API
+func toTemplate(template *database.CollectionTemplate) Template {
+	result := Template{CollectionTemplate: *template, Builtin: template.BuiltinKey != nil}
+	_ = json.Unmarshal([]byte(template.Structure), &result.Structure)
+	return result
+}
+
+// normalizeTemplateNode trims names and normalizes suggested tags
+func normalizeTemplateNode(node TemplateNode) TemplateNode {
+	node.Name = strings.TrimSpace(node.Name)
+	node.SuggestedTags = tags.NormalizeAll(node.SuggestedTags)
+	for i := range node.Children {
+		node.Children[i] = normalizeTemplateNode(node.Children[i])
+	}
+	return node
+}
+
+// validateTemplateNode checks a template tree against the structure limits,
+// counting its collections in count
+func validateTemplateNode(node TemplateNode, depth int, count *int) error {
+	*count++
+	if node.Name == "" || len(node.Name) > 100 || depth > maxTemplateDepth ||
+		*count > maxTemplateCollections || len(node.SuggestedTags) > maxTemplateTags {
+		return ErrInvalidTemplate
+	}
+	for _, child := range node.Children {
+		if err := validateTemplateNode(child, depth+1, count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// countTemplateNodes returns how many collections a template tree creates
+func countTemplateNodes(node TemplateNode) int {
+	count := 1
+	for _, child := range node.Children {
+		count += countTemplateNodes(child)
+	}
+	return count
+}
+
+// collectTemplateTags lists the suggested tags of a template tree once each
+func collectTemplateTags(node TemplateNode, seen []string) []string {
+	for _, tag := range node.SuggestedTags {
+		found := false
+		for _, existing := range seen {
+			if existing == tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			seen = append(seen, tag)
+		}
+	}
+	for _, child := range node.Children {
+		seen = collectTemplateTags(child, seen)
+	}
+	if seen == nil {
+		return []string{}
+	}
+	return seen
+}
@@ -0,0 +1,206 @@
+package collection
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+func findBuiltinTemplate(t *testing.T, service *Service, key string) Template {
+	result, err := service.ListTemplates(1, ListTemplatesParams{Page: 1, Limit: 100, SortBy: TemplateSortRating})
+	require.NoError(t, err)
+	for _, template := range result.Templates {
+		if template.BuiltinKey != nil && *template.BuiltinKey == key {
+			return template
+		}
+	}
+	t.Fatalf("built-in template %s not found", key)
+	return Template{}
+}
+
+func TestCollectionService_SeedTemplates(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+
+	require.NoError(t, service.SeedTemplates())
+	require.NoError(t, service.SeedTemplates(), "seeding again updates instead of duplicating")
+
+	var count int64
+	db.Model(&database.CollectionTemplate{}).Count(&count)
+	assert.Equal(t, int64(len(builtinTemplates)), count)
+
+	template := findBuiltinTemplate(t, service, "job-hunt")
+	assert.True(t, template.Builtin)
+	assert.Equal(t, "Job Hunt", template.Name)
+	assert.Len(t, template.Structure.Children, 5)
+}
+
+func TestCollectionService_CreateFromTemplate(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+	require.NoError(t, service.SeedTemplates())
+	research := findBuiltinTemplate(t, service, "research-project")
+
+	parent, err := service.Create(1, CreateCollectionRequest{Name: "Work", Visibility: "private"})
+	require.NoError(t, err)
+
+	instance, err := service.CreateFromTemplate(1, research.ID, FromTemplateRequest{Name: "Thesis", ParentID: &parent.ID})
+	require.NoError(t, err)
+	assert.Equal(t, 7, instance.Created)
+	assert.Equal(t, "Thesis", instance.Collection.Name)
+	assert.Equal(t, parent.ID, *instance.Collection.ParentID)
+	assert.Contains(t, instance.SuggestedTags, "research/dataset")
+	assert.Contains(t, instance.SuggestedTags, "to-read")
+
+	var literature database.Collection
+	require.NoError(t, db.Where("user_id = ? AND name = ?", 1, "Literature").First(&literature).Error)
+	assert.Equal(t, instance.Collection.ID, *literature.ParentID)
+	assert.Equal(t, "private", literature.Visibility)
+	var metadata templateMetadata
+	require.NoError(t, json.Unmarshal([]byte(literature.Metadata), &metadata))
+	assert.Equal(t, research.ID, metadata.TemplateID)
+	assert.Equal(t, []string{"research/paper"}, metadata.SuggestedTags)
+
+	var toRead database.Collection
+	require.NoError(t, db.Where("user_id = ? AND name = ?", 1, "To Read").First(&toRead).Error)
+	assert.Equal(t, literature.ID, *toRead.ParentID)
+
+	var stored database.CollectionTemplate
+	require.NoError(t, db.First(&stored, research.ID).Error)
+	assert.Equal(t, 1, stored.UseCount)
+
+	_, err = service.CreateFromTemplate(2, research.ID, FromTemplateRequest{ParentID: &parent.ID})
+	assert.ErrorIs(t, err, ErrCollectionNotFound, "the parent must be the user's")
+}
+
+func TestCollectionService_UserTemplates(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+
+	structure := TemplateNode{
+		Name:          "Home Renovation",
+		SuggestedTags: []string{" home ", "home"},
+		Children:      []TemplateNode{{Name: "Kitchen"}, {Name: "Garden"}},
+	}
+	private, err := service.CreateTemplate(1, CreateTemplateRequest{Name: "Renovation", Structure: structure})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"home"}, private.Structure.SuggestedTags)
+	public, err := service.CreateTemplate(1, CreateTemplateRequest{Name: "Renovation (shared)", IsPublic: true, Category: "Home", Structure: structure})
+	require.NoError(t, err)
+	assert.Equal(t, "home", public.Category)
+
+	t.Run("Private templates are only visible to their creator", func(t *testing.T) {
+		_, err := service.GetTemplate(2, private.ID)
+		assert.ErrorIs(t, err, ErrTemplateNotFound)
+		_, err = service.CreateFromTemplate(2, private.ID, FromTemplateRequest{})
+		assert.ErrorIs(t, err, ErrTemplateNotFound)
+
+		result, err := service.ListTemplates(2, ListTemplatesParams{Page: 1, Limit: 20, SortBy: TemplateSortNewest})
+		require.NoError(t, err)
+		require.Len(t, result.Templates, 1)
+		assert.Equal(t, public.ID, result.Templates[0].ID)
+
+		mine, err := service.ListTemplates(1, ListTemplatesParams{Page: 1, Limit: 20, Mine: true, SortBy: TemplateSortNewest})
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), mine.Total)
+	})
+
+	t.Run("Structures are bounded", func(t *testing.T) {
+		deep := TemplateNode{Name: "1", Children: []TemplateNode{{Name: "2", Children: []TemplateNode{{Name: "3", Children: []TemplateNode{{Name: "4"}}}}}}}
+		_, err := service.CreateTemplate(1, CreateTemplateRequest{Name: "Deep", Structure: deep})
+		assert.ErrorIs(t, err, ErrInvalidTemplate)
+
+		_, err = service.CreateTemplate(1, CreateTemplateRequest{Name: "Unnamed", Structure: TemplateNode{Name: "Root", Children: []TemplateNode{{Name: " "}}}})
+		assert.ErrorIs(t, err, ErrInvalidTemplate)
+	})
+
+	t.Run("Ratings are averaged once per user", func(t *testing.T) {
+		_, err := service.RateTemplate(1, public.ID, RateTemplateRequest{Rating: 5})
+		assert.ErrorIs(t, err, ErrRateOwnTemplate)
+		_, err = service.RateTemplate(2, public.ID, RateTemplateRequest{Rating: 6})
+		assert.ErrorIs(t, err, ErrInvalidTemplateRating)
+		_, err = service.RateTemplate(2, private.ID, RateTemplateRequest{Rating: 5})
+		assert.ErrorIs(t, err, ErrTemplateNotFound)
+
+		_, err = service.RateTemplate(2, public.ID, RateTemplateRequest{Rating: 5, Comment: "Saved me hours"})
+		require.NoError(t, err)
+		_, err = service.RateTemplate(3, public.ID, RateTemplateRequest{Rating: 2})
+		require.NoError(t, err)
+		_, err = service.RateTemplate(2, public.ID, RateTemplateRequest{Rating: 1})
+		assert.ErrorIs(t, err, ErrTemplateAlreadyRated)
+
+		template, err := service.GetTemplate(2, public.ID)
+		require.NoError(t, err)
+		assert.InDelta(t, 3.5, template.Rating, 0.001)
+		assert.Equal(t, 2, template.RatingCount)
+	})
+
+	t.Run("Only the creator deletes a template", func(t *testing.T) {
+		assert.ErrorIs(t, service.DeleteTemplate(2, public.ID), ErrTemplateNotFound)
+		require.NoError(t, service.DeleteTemplate(1, public.ID))
+		_, err := service.GetTemplate(1, public.ID)
+		assert.ErrorIs(t, err, ErrTemplateNotFound)
+	})
+}
+
+func TestCollectionHandler_Templates(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupTestDB(t)
+	service := NewService(db)
+	require.NoError(t, service.SeedTemplates())
+	trip := findBuiltinTemplate(t, service, "trip-planning")
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", "1")
+		c.Next()
+	})
+	NewHandler(service).RegisterRoutes(router.Group("/api/v1"))
+
+	request := func(method, path string, body interface{}) *httptest.ResponseRecorder {
+		var reader *bytes.Reader
+		if body != nil {
+			encoded, _ := json.Marshal(body)
+			reader = bytes.NewReader(encoded)
+		} else {
+			reader = bytes.NewReader(nil)
+		}
+		req := httptest.NewRequest(method, path, reader)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request(http.MethodGet, "/api/v1/collections/templates?category=travel", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var list struct {
+		Data ListTemplatesResult `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Len(t, list.Data.Templates, 1)
+	assert.Equal(t, "Trip Planning", list.Data.Templates[0].Structure.Name)
+
+	w = request(http.MethodPost, "/api/v1/collections/from-template/"+strconv.Itoa(int(trip.ID)), nil)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var created struct {
+		Data TemplateInstance `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Equal(t, 6, created.Data.Created)
+	assert.Len(t, created.Data.Collection.Children, 5)
+
+	assert.Equal(t, http.StatusNotFound, request(http.MethodPost, "/api/v1/collections/from-template/9999", nil).Code)
+	assert.Equal(t, http.StatusCreated, request(http.MethodPost, "/api/v1/collections/templates/"+strconv.Itoa(int(trip.ID))+"/ratings", RateTemplateRequest{Rating: 4}).Code)
+	assert.Equal(t, http.StatusConflict, request(http.MethodPost, "/api/v1/collections/templates/"+strconv.Itoa(int(trip.ID))+"/ratings", RateTemplateRequest{Rating: 4}).Code)
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodPost, "/api/v1/collections/templates", CreateTemplateRequest{Name: "Empty"}).Code)
+}
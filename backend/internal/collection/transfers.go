@@ -0,0 +1,97 @@
+package collection
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/internal/automation"
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+// ListTransfers returns the user's pending transfers, offered to them or by
+// them, newest first
+func (s *Service) ListTransfers(userID uint) ([]database.CollectionTransfer, error) {
+	var transfers []database.CollectionTransfer
+	if err := s.db.Where("(to_user_id = ? OR from_user_id = ?) AND status = ?", userID, userID, database.TransferStatusPending).
+		Order("created_at DESC").Find(&transfers).Error; err != nil {
+		return nil, fmt.Errorf("failed to get transfers: %w", err)
+	}
+	return transfers, nil
+}
+
+// AcceptTransfer moves a collection offered to the user into their account.
+// It runs as the sender's bulk operation, so the sender can still undo it
+func (s *Service) AcceptTransfer(userID, transferID uint) (*database.CollectionTransfer, error) {
+	var transfer database.CollectionTransfer
+	if err := s.db.Where("id = ? AND to_user_id = ? AND status = ?", transferID, userID, database.TransferStatusPending).
+		First(&transfer).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTransferNotFound
+		}
+		return nil, fmt.Errorf("failed to get transfer: %w", err)
+	}
+
+	params := automation.InterfaceMap{
+		"collection_id": transfer.CollectionID,
+		"recipient_id":  transfer.ToUserID,
+		"transfer_id":   transfer.ID,
+	}
+	now := time.Now()
+	_, err := s.runBulkOperation(transfer.FromUserID, BulkTypeTransfer, params, func(tx *gorm.DB, op *automation.BulkOperation, undo *collectionUndo) error {
+		// Claiming the transfer first keeps a second accept from moving it again
+		result := tx.Model(&database.CollectionTransfer{}).
+			Where("id = ? AND status = ?", transfer.ID, database.TransferStatusPending).
+			Updates(map[string]interface{}{
+				"status":       database.TransferStatusAccepted,
+				"operation_id": op.ID,
+				"responded_at": now,
+			})
+		if result.Error != nil {
+			return fmt.Errorf("failed to accept transfer: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrTransferNotFound
+		}
+
+		root, err := ownedCollection(tx, transfer.FromUserID, transfer.CollectionID)
+		if err != nil {
+			return err
+		}
+		return transferSubtree(tx, op, undo, transfer.FromUserID, transfer.ToUserID, root, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	transfer.Status = database.TransferStatusAccepted
+	transfer.RespondedAt = &now
+	return &transfer, nil
+}
+
+// DeclineTransfer turns down a collection offered to the user
+func (s *Service) DeclineTransfer(userID, transferID uint) error {
+	return s.closeTransfer("id = ? AND to_user_id = ?", transferID, userID, database.TransferStatusDeclined)
+}
+
+// CancelTransfer withdraws a collection the user offered
+func (s *Service) CancelTransfer(userID, transferID uint) error {
+	return s.closeTransfer("id = ? AND from_user_id = ?", transferID, userID, database.TransferStatusCancelled)
+}
+
+// closeTransfer ends a pending transfer without moving anything
+func (s *Service) closeTransfer(condition string, transferID, userID uint, status string) error {
+	result := s.db.Model(&database.CollectionTransfer{}).
+		Where(condition, transferID, userID).
+		Where("status = ?", database.TransferStatusPending).
+		Updates(map[string]interface{}{"status": status, "responded_at": time.Now()})
+	if result.Error != nil {
+		return fmt.Errorf("failed to update transfer: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrTransferNotFound
+	}
+	return nil
+}
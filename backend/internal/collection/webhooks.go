@@ -0,0 +1,66 @@
+package collection
+
+import (
+	"context"
+	"strconv"
+
+	"go.uber.org/zap"
+
+	"bookmark-sync-service/backend/internal/automation"
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+// WebhookTrigger delivers collection events to the owner's webhook
+// endpoints and to the endpoints scoped to a collection
+type WebhookTrigger interface {
+	TriggerWebhook(ctx context.Context, event automation.WebhookEvent, userID string, data interface{}) error
+	TriggerCollectionWebhook(ctx context.Context, event automation.WebhookEvent, ownerID string, collectionID uint, data interface{}) error
+}
+
+// SetWebhooks makes the service report created, updated and deleted
+// collections to the owner's webhooks, and bookmarks added to and removed
+// from collections to their webhooks
+func (s *Service) SetWebhooks(webhooks WebhookTrigger) {
+	s.webhooks = webhooks
+}
+
+// triggerBookmarkWebhook reports a bookmark change of a collection. Delivery
+// failures never fail the change itself
+func (s *Service) triggerBookmarkWebhook(event automation.WebhookEvent, collection *database.Collection, bookmark *database.Bookmark) {
+	if s.webhooks == nil {
+		return
+	}
+
+	data := map[string]interface{}{
+		"collection_id": collection.ID,
+		"bookmark_id":   bookmark.ID,
+	}
+	if event == automation.WebhookEventCollectionBookmarkAdded {
+		data["url"] = bookmark.URL
+		data["title"] = bookmark.Title
+	}
+	ownerID := strconv.FormatUint(uint64(collection.UserID), 10)
+	if err := s.webhooks.TriggerCollectionWebhook(context.Background(), event, ownerID, collection.ID, data); err != nil {
+		s.logger.Warn("Failed to trigger collection webhook", zap.String("event", string(event)), zap.Uint("collection_id", collection.ID), zap.Error(err))
+	}
+}
+
+// triggerCollectionWebhook reports a created, updated or deleted collection
+// to the owner's webhooks. Delivery failures never fail the change itself
+func (s *Service) triggerCollectionWebhook(event automation.WebhookEvent, collection *database.Collection) {
+	if s.webhooks == nil {
+		return
+	}
+
+	data := map[string]interface{}{"id": collection.ID}
+	if event != automation.WebhookEventCollectionDeleted {
+		data["name"] = collection.Name
+		data["description"] = collection.Description
+		data["visibility"] = collection.Visibility
+		data["parent_id"] = collection.ParentID
+	}
+	ownerID := strconv.FormatUint(uint64(collection.UserID), 10)
+	if err := s.webhooks.TriggerWebhook(context.Background(), event, ownerID, data); err != nil {
+		s.logger.Warn("Failed to trigger collection webhook", zap.String("event", string(event)), zap.Uint("collection_id", collection.ID), zap.Error(err))
+	}
+}
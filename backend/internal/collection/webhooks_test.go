@@ -0,0 +1,87 @@
+package collection
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bookmark-sync-service/backend/internal/automation"
+)
+
+// recordingWebhooks keeps the collection events triggered
+type recordingWebhooks struct {
+	events []automation.WebhookEvent
+	owners []string
+	data   []map[string]interface{}
+
+	// userEvents are the events sent to the owner's webhooks
+	userEvents []automation.WebhookEvent
+	userData   []map[string]interface{}
+}
+
+func (r *recordingWebhooks) TriggerWebhook(ctx context.Context, event automation.WebhookEvent, userID string, data interface{}) error {
+	r.userEvents = append(r.userEvents, event)
+	r.userData = append(r.userData, data.(map[string]interface{}))
+	return nil
+}
+
+func (r *recordingWebhooks) TriggerCollectionWebhook(ctx context.Context, event automation.WebhookEvent, ownerID string, collectionID uint, data interface{}) error {
+	r.events = append(r.events, event)
+	r.owners = append(r.owners, ownerID)
+	r.data = append(r.data, data.(map[string]interface{}))
+	return nil
+}
+
+func TestCollectionService_TriggersBookmarkWebhooks(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+	webhooks := &recordingWebhooks{}
+	service.SetWebhooks(webhooks)
+
+	collection := createBulkTestCollection(t, db, 1, "Reading", nil)
+	bookmark := createBulkTestBookmark(t, db, 1, "https://example.com/article", "[]")
+
+	require.NoError(t, service.AddBookmark(1, collection.ID, bookmark.ID))
+	require.NoError(t, service.RemoveBookmark(1, collection.ID, bookmark.ID))
+
+	assert.Equal(t, []automation.WebhookEvent{
+		automation.WebhookEventCollectionBookmarkAdded,
+		automation.WebhookEventCollectionBookmarkRemoved,
+	}, webhooks.events)
+	assert.Equal(t, []string{"1", "1"}, webhooks.owners)
+	assert.Equal(t, "https://example.com/article", webhooks.data[0]["url"])
+	assert.Equal(t, bookmark.ID, webhooks.data[1]["bookmark_id"])
+
+	// Failed changes trigger nothing
+	assert.Error(t, service.AddBookmark(2, collection.ID, bookmark.ID))
+	assert.Len(t, webhooks.events, 2)
+}
+
+func TestCollectionService_TriggersCollectionWebhooks(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+	webhooks := &recordingWebhooks{}
+	service.SetWebhooks(webhooks)
+
+	collection, err := service.Create(1, CreateCollectionRequest{Name: "Reading", Visibility: "private"})
+	require.NoError(t, err)
+	name := "Reading list"
+	_, err = service.Update(1, collection.ID, UpdateCollectionRequest{Name: &name})
+	require.NoError(t, err)
+	require.NoError(t, service.Delete(1, collection.ID))
+
+	assert.Equal(t, []automation.WebhookEvent{
+		automation.WebhookEventCollectionCreated,
+		automation.WebhookEventCollectionUpdated,
+		automation.WebhookEventCollectionDeleted,
+	}, webhooks.userEvents)
+	assert.Equal(t, "Reading list", webhooks.userData[1]["name"])
+	assert.Equal(t, map[string]interface{}{"id": collection.ID}, webhooks.userData[2])
+	assert.Empty(t, webhooks.events, "collection-scoped endpoints only hear about their bookmarks")
+
+	// Failed changes trigger nothing
+	assert.Error(t, service.Delete(2, collection.ID))
+	assert.Len(t, webhooks.userEvents, 3)
+}
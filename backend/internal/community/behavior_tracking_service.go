@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"bookmark-sync-service/backend/pkg/database"
 	"bookmark-sync-service/backend/pkg/worker"
 
 	"go.uber.org/zap"
@@ -51,7 +52,7 @@ func (s *BehaviorTrackingService) TrackUserBehavior(ctx context.Context, req *Be
 		return err
 	}
 
-	if err := s.saveBehaviorRecord(behavior); err != nil {
+	if err := s.saveBehaviorRecord(ctx, behavior); err != nil {
 		return err
 	}
 
@@ -106,11 +107,24 @@ func (s *BehaviorTrackingService) createBehaviorRecord(req *BehaviorTrackingRequ
 }
 
 // saveBehaviorRecord saves the behavior record to database
-func (s *BehaviorTrackingService) saveBehaviorRecord(behavior *UserBehavior) error {
-	if err := s.db.Create(behavior).Error; err != nil {
-		return fmt.Errorf("failed to track user behavior: %w", err)
-	}
-	return nil
+func (s *BehaviorTrackingService) saveBehaviorRecord(ctx context.Context, behavior *UserBehavior) error {
+	return recordBehavior(ctx, s.db, behavior)
+}
+
+// recordBehavior saves a behavior record and bumps the bookmark counter it
+// feeds, e.g. save_count for saves, in one transaction
+func recordBehavior(ctx context.Context, db Database, behavior *UserBehavior) error {
+	return db.Transaction(ctx, func(tx Database) error {
+		if err := tx.Create(behavior).Error; err != nil {
+			return fmt.Errorf("failed to track user behavior: %w", err)
+		}
+		if column := database.CounterForAction(behavior.ActionType); column != "" {
+			if err := tx.AdjustBookmarkCounter(behavior.BookmarkID, column, 1); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
 // processAsyncUpdates handles asynchronous updates via worker pool
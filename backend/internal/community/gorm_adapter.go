@@ -1,6 +1,12 @@
 package community
 
-import "gorm.io/gorm"
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/pkg/database"
+)
 
 // GormAdapter adapts *gorm.DB to implement the Database interface
 type GormAdapter struct {
@@ -47,3 +53,17 @@ func (g *GormAdapter) Limit(limit int) Database {
 func (g *GormAdapter) Offset(offset int) Database {
 	return &GormAdapter{db: g.db.Offset(offset)}
 }
+
+func (g *GormAdapter) ForUpdate() Database {
+	return &GormAdapter{db: database.ForUpdate(g.db)}
+}
+
+func (g *GormAdapter) Transaction(ctx context.Context, fn func(tx Database) error) error {
+	return database.WithTransaction(ctx, g.db, func(tx *gorm.DB) error {
+		return fn(&GormAdapter{db: tx})
+	})
+}
+
+func (g *GormAdapter) AdjustBookmarkCounter(bookmarkID uint, column string, delta int) error {
+	return database.AdjustBookmarkCounter(g.db, bookmarkID, column, delta)
+}
@@ -0,0 +1,122 @@
+package community
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+func setupAdapterDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	// Every connection to :memory: is a new database, so keep to one
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+
+	require.NoError(t, db.AutoMigrate(&UserBehavior{}, &UserFollow{}, &TrendingBookmark{}, &SocialMetrics{}))
+	return db
+}
+
+func TestGormAdapter_Transaction(t *testing.T) {
+	db := setupAdapterDB(t)
+	adapter := NewGormAdapter(db)
+	ctx := context.Background()
+
+	err := adapter.Transaction(ctx, func(tx Database) error {
+		require.NoError(t, tx.Create(&UserFollow{FollowerID: "a", FollowingID: "b"}).Error)
+		return errors.New("abort")
+	})
+	assert.EqualError(t, err, "abort")
+
+	var count int64
+	db.Model(&UserFollow{}).Count(&count)
+	assert.Zero(t, count)
+
+	require.NoError(t, adapter.Transaction(ctx, func(tx Database) error {
+		return tx.Create(&UserFollow{FollowerID: "a", FollowingID: "b"}).Error
+	}))
+	db.Model(&UserFollow{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestTrendingService_CalculateTrendingScoresIsAtomic(t *testing.T) {
+	db := setupAdapterDB(t)
+	service := NewTrendingService(NewGormAdapter(db), nil, NewJSONHelper(), zap.NewNop())
+
+	for _, bookmarkID := range []uint{1, 2, 3} {
+		require.NoError(t, db.Create(&UserBehavior{UserID: "user", BookmarkID: bookmarkID, ActionType: "view"}).Error)
+	}
+
+	// Saving one bookmark's score fails
+	require.NoError(t, db.Callback().Create().Before("gorm:create").Register("test:fail_trending", func(tx *gorm.DB) {
+		if metric, ok := tx.Statement.Dest.(*TrendingBookmark); ok && metric.BookmarkID == 2 {
+			tx.AddError(errors.New("score rejected"))
+		}
+	}))
+
+	err := service.CalculateTrendingScores(context.Background(), "daily")
+	assert.Error(t, err)
+
+	// No score from the failed run is kept
+	var count int64
+	db.Model(&TrendingBookmark{}).Count(&count)
+	assert.Zero(t, count)
+
+	require.NoError(t, db.Callback().Create().Remove("test:fail_trending"))
+	require.NoError(t, service.CalculateTrendingScores(context.Background(), "daily"))
+	db.Model(&TrendingBookmark{}).Count(&count)
+	assert.Equal(t, int64(3), count)
+}
+
+func TestSocialMetricsService_UpdateSocialMetrics(t *testing.T) {
+	db := setupAdapterDB(t)
+	service := NewSocialMetricsService(NewGormAdapter(db), nil, NewJSONHelper(), zap.NewNop())
+	ctx := context.Background()
+
+	for _, action := range []string{"view", "view", "like"} {
+		require.NoError(t, service.UpdateSocialMetrics(ctx, 7, action))
+	}
+
+	var metrics SocialMetrics
+	require.NoError(t, db.First(&metrics, "bookmark_id = ?", 7).Error)
+	assert.Equal(t, 2, metrics.TotalViews)
+	assert.Equal(t, 1, metrics.TotalLikes)
+}
+
+func TestBehaviorTrackingService_CountsActionsWithTheRecord(t *testing.T) {
+	db := setupAdapterDB(t)
+	require.NoError(t, db.AutoMigrate(&database.Bookmark{}))
+	bookmark := &database.Bookmark{UserID: 1, URL: "https://example.com", Title: "Example"}
+	require.NoError(t, db.Create(bookmark).Error)
+	service := NewBehaviorTrackingService(NewGormAdapter(db), nil, nil, nil, nil, zap.NewNop())
+	ctx := context.Background()
+
+	for _, action := range []string{"save", "like", "view"} {
+		require.NoError(t, service.TrackUserBehavior(ctx, &BehaviorTrackingRequest{UserID: "u", BookmarkID: bookmark.ID, ActionType: action}))
+	}
+
+	// A record whose counter update fails is not kept either
+	require.NoError(t, db.Callback().Update().Before("gorm:update").Register("test:fail_counter", func(tx *gorm.DB) {
+		tx.AddError(errors.New("counter locked"))
+	}))
+	assert.Error(t, service.TrackUserBehavior(ctx, &BehaviorTrackingRequest{UserID: "u", BookmarkID: bookmark.ID, ActionType: "save"}))
+	require.NoError(t, db.Callback().Update().Remove("test:fail_counter"))
+
+	var stored database.Bookmark
+	require.NoError(t, db.First(&stored, bookmark.ID).Error)
+	assert.Equal(t, 1, stored.SaveCount)
+	assert.Equal(t, 1, stored.LikeCount)
+	var saves int64
+	db.Model(&UserBehavior{}).Where("action_type = ?", "save").Count(&saves)
+	assert.Equal(t, int64(1), saves)
+}
@@ -6,6 +6,9 @@ import (
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+
+	"bookmark-sync-service/backend/internal/mobile"
+	"bookmark-sync-service/backend/pkg/database"
 )
 
 // CommunityService interface for dependency injection
@@ -242,6 +245,10 @@ func (h *Handler) GetFeed(c *gin.Context) {
 		return
 	}
 
+	if mobile.Requested(c) {
+		trimFeedForMobile(feed)
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"feed":        feed,
 		"total":       len(feed),
@@ -249,6 +256,21 @@ func (h *Handler) GetFeed(c *gin.Context) {
 	})
 }
 
+// trimFeedForMobile swaps the bookmarks attached to feed entries for their
+// mobile form and drops attachments that are not bookmarks
+func trimFeedForMobile(feed []UserFeedResponse) {
+	for i := range feed {
+		switch bookmark := feed[i].BookmarkData.(type) {
+		case *database.Bookmark:
+			feed[i].BookmarkData = mobile.FromBookmark(bookmark)
+		case database.Bookmark:
+			feed[i].BookmarkData = mobile.FromBookmark(&bookmark)
+		default:
+			feed[i].BookmarkData = nil
+		}
+	}
+}
+
 // GetSocialMetrics handles GET /api/v1/community/metrics/:bookmark_id
 func (h *Handler) GetSocialMetrics(c *gin.Context) {
 	bookmarkIDStr := c.Param("bookmark_id")
@@ -0,0 +1,112 @@
+package community
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// languageBoost is added to the score of recommendations in a preferred
+// language. It reorders close candidates without hiding other languages
+const languageBoost = 0.2
+
+// LanguagePreferences looks up preferred content languages and bookmark languages
+type LanguagePreferences interface {
+	PreferredLanguages(userID string) ([]string, error)
+	BookmarkLanguages(bookmarkIDs []uint) (map[uint]string, error)
+}
+
+// gormLanguagePreferences reads languages from the users and bookmarks tables
+type gormLanguagePreferences struct {
+	db *gorm.DB
+}
+
+// NewGormLanguagePreferences creates a LanguagePreferences backed by the main database
+func NewGormLanguagePreferences(db *gorm.DB) LanguagePreferences {
+	return &gormLanguagePreferences{db: db}
+}
+
+// PreferredLanguages returns the contentLanguages stored in the user's preferences
+func (g *gormLanguagePreferences) PreferredLanguages(userID string) ([]string, error) {
+	var raw []string
+	if err := g.db.Table("users").Where("id = ?", userID).Pluck("preferences", &raw).Error; err != nil {
+		return nil, fmt.Errorf("failed to get user preferences: %w", err)
+	}
+	if len(raw) == 0 || raw[0] == "" {
+		return nil, nil
+	}
+
+	var preferences struct {
+		ContentLanguages []string `json:"contentLanguages"`
+	}
+	if err := json.Unmarshal([]byte(raw[0]), &preferences); err != nil {
+		return nil, fmt.Errorf("failed to parse user preferences: %w", err)
+	}
+
+	return preferences.ContentLanguages, nil
+}
+
+// BookmarkLanguages returns the detected language of each bookmark that has one
+func (g *gormLanguagePreferences) BookmarkLanguages(bookmarkIDs []uint) (map[uint]string, error) {
+	var rows []struct {
+		ID       uint
+		Language string
+	}
+	if err := g.db.Table("bookmarks").Select("id, language").
+		Where("id IN ? AND language <> ''", bookmarkIDs).Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to get bookmark languages: %w", err)
+	}
+
+	languages := make(map[uint]string, len(rows))
+	for _, row := range rows {
+		languages[row.ID] = row.Language
+	}
+	return languages, nil
+}
+
+// SetLanguagePreferences enables boosting recommendations in the user's preferred languages
+func (s *RecommendationService) SetLanguagePreferences(languages LanguagePreferences) {
+	s.languages = languages
+}
+
+// boostPreferredLanguages raises the score of recommendations whose bookmark
+// matches one of the user's preferred content languages and re-sorts them.
+// Lookup failures leave the recommendations unchanged
+func (s *RecommendationService) boostPreferredLanguages(userID string, recommendations []RecommendationResponse) {
+	if s.languages == nil || len(recommendations) == 0 {
+		return
+	}
+
+	preferred, err := s.languages.PreferredLanguages(userID)
+	if err != nil || len(preferred) == 0 {
+		return
+	}
+
+	bookmarkIDs := make([]uint, len(recommendations))
+	for i, rec := range recommendations {
+		bookmarkIDs[i] = rec.BookmarkID
+	}
+	bookmarkLanguages, err := s.languages.BookmarkLanguages(bookmarkIDs)
+	if err != nil {
+		s.logger.Warn("Failed to get bookmark languages", zap.Error(err))
+		return
+	}
+
+	wanted := make(map[string]bool, len(preferred))
+	for _, code := range preferred {
+		wanted[code] = true
+	}
+
+	for i := range recommendations {
+		if wanted[bookmarkLanguages[recommendations[i].BookmarkID]] {
+			recommendations[i].Score += languageBoost
+		}
+	}
+
+	sort.SliceStable(recommendations, func(i, j int) bool {
+		return recommendations[i].Score > recommendations[j].Score
+	})
+}
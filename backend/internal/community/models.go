@@ -6,6 +6,19 @@ import (
 	"gorm.io/gorm"
 )
 
+// AutoMigrate creates the community tables. They are migrated separately
+// from pkg/database, which this package depends on
+func AutoMigrate(db *gorm.DB) error {
+	return db.AutoMigrate(
+		&UserBehavior{},
+		&UserFollow{},
+		&BookmarkRecommendation{},
+		&TrendingBookmark{},
+		&UserFeed{},
+		&SocialMetrics{},
+	)
+}
+
 // UserBehavior tracks user interactions for recommendation engine
 type UserBehavior struct {
 	ID         uint           `json:"id" gorm:"primaryKey"`
@@ -65,6 +78,9 @@ type TrendingBookmark struct {
 	TrendingScore float64        `json:"trending_score" gorm:"default:0"`
 	TimeWindow    string         `json:"time_window" gorm:"not null"` // hourly, daily, weekly
 	CalculatedAt  time.Time      `json:"calculated_at"`
+	// Participants is how many distinct users the counts come from. It is
+	// kept private, as it would undo the noise added to small counts
+	Participants int `json:"-" gorm:"default:0"`
 }
 
 // UserFeed represents personalized feed items
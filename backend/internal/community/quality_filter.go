@@ -0,0 +1,77 @@
+package community
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+// QualityFilter reports which bookmarks are suppressed from public areas
+// for low quality, e.g. the quality service
+type QualityFilter interface {
+	SuppressedBookmarks(ctx context.Context, ids []uint) (map[uint]bool, error)
+}
+
+// SetQualityFilter leaves suppressed bookmarks out of trending
+func (s *TrendingService) SetQualityFilter(filter QualityFilter) {
+	s.quality = filter
+}
+
+// SetQualityFilter leaves suppressed bookmarks out of recommendations
+func (s *RecommendationService) SetQualityFilter(filter QualityFilter) {
+	s.quality = filter
+}
+
+// suppressedAmong looks up which of the bookmarks are suppressed. A failed
+// lookup suppresses none, so trending and recommendations keep working
+func suppressedAmong(ctx context.Context, filter QualityFilter, ids []uint, logger *zap.Logger) map[uint]bool {
+	if filter == nil || len(ids) == 0 {
+		return nil
+	}
+	suppressed, err := filter.SuppressedBookmarks(ctx, ids)
+	if err != nil {
+		logger.Warn("Failed to get suppressed bookmarks", zap.Error(err))
+		return nil
+	}
+	return suppressed
+}
+
+// dropSuppressedTrending removes suppressed bookmarks from trending ones
+func (s *TrendingService) dropSuppressedTrending(ctx context.Context, trending []TrendingBookmark) []TrendingBookmark {
+	ids := make([]uint, len(trending))
+	for i, tb := range trending {
+		ids[i] = tb.BookmarkID
+	}
+	suppressed := suppressedAmong(ctx, s.quality, ids, s.logger)
+	if len(suppressed) == 0 {
+		return trending
+	}
+
+	kept := trending[:0]
+	for _, tb := range trending {
+		if !suppressed[tb.BookmarkID] {
+			kept = append(kept, tb)
+		}
+	}
+	return kept
+}
+
+// dropSuppressedRecommendations removes suppressed bookmarks from recommendations
+func (s *RecommendationService) dropSuppressedRecommendations(ctx context.Context, recommendations []RecommendationResponse) []RecommendationResponse {
+	ids := make([]uint, len(recommendations))
+	for i, rec := range recommendations {
+		ids[i] = rec.BookmarkID
+	}
+	suppressed := suppressedAmong(ctx, s.quality, ids, s.logger)
+	if len(suppressed) == 0 {
+		return recommendations
+	}
+
+	kept := recommendations[:0]
+	for _, rec := range recommendations {
+		if !suppressed[rec.BookmarkID] {
+			kept = append(kept, rec)
+		}
+	}
+	return kept
+}
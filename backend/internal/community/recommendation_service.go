@@ -15,6 +15,9 @@ type RecommendationService struct {
 	redis      RedisClient
 	jsonHelper *JSONHelper
 	logger     *zap.Logger
+	languages  LanguagePreferences
+	quality    QualityFilter
+	privacy    TrendingPrivacy
 }
 
 // NewRecommendationService creates a new recommendation service
@@ -54,6 +57,8 @@ func (s *RecommendationService) GetRecommendations(ctx context.Context, req *Rec
 
 	// Convert to response format
 	recommendations = s.convertToResponseFormat(dbRecommendations)
+	recommendations = s.dropSuppressedRecommendations(ctx, recommendations)
+	s.boostPreferredLanguages(req.UserID, recommendations)
 
 	// Cache results
 	cacheHelper.Set(ctx, cacheKey, recommendations, 15*time.Minute)
@@ -209,7 +214,11 @@ func (s *RecommendationService) generateContentBasedRecommendations(userID strin
 // generateTrendingRecommendations generates trending-based recommendations
 func (s *RecommendationService) generateTrendingRecommendations(userID string) []BookmarkRecommendation {
 	var trending []TrendingBookmark
-	s.db.Where("time_window = ?", "daily").Order("trending_score DESC").Limit(10).Find(&trending)
+	query := s.db.Where("time_window = ?", "daily")
+	if s.privacy.MinParticipants > 0 {
+		query = query.Where("participants >= ?", s.privacy.MinParticipants)
+	}
+	query.Order("trending_score DESC").Limit(10).Find(&trending)
 
 	var recommendations []BookmarkRecommendation
 	for _, t := range trending {
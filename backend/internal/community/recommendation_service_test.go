@@ -279,3 +279,42 @@ func TestRecommendationService_GenerateReasonText(t *testing.T) {
 		assert.Equal(t, tt.expected, result, "Failed for reason type: %s", tt.reasonType)
 	}
 }
+
+// stubLanguagePreferences returns fixed languages for boost tests
+type stubLanguagePreferences struct {
+	preferred []string
+	bookmarks map[uint]string
+}
+
+func (s *stubLanguagePreferences) PreferredLanguages(userID string) ([]string, error) {
+	return s.preferred, nil
+}
+
+func (s *stubLanguagePreferences) BookmarkLanguages(bookmarkIDs []uint) (map[uint]string, error) {
+	return s.bookmarks, nil
+}
+
+func TestRecommendationService_BoostPreferredLanguages(t *testing.T) {
+	service := NewRecommendationService(&MockDB{}, &MockRedisClient{}, NewJSONHelper(), zap.NewNop())
+	recommendations := []RecommendationResponse{
+		{BookmarkID: 1, Score: 0.9},
+		{BookmarkID: 2, Score: 0.8},
+		{BookmarkID: 3, Score: 0.3},
+	}
+
+	// Without language preferences the order is untouched
+	service.boostPreferredLanguages("user-123", recommendations)
+	assert.Equal(t, uint(1), recommendations[0].BookmarkID)
+
+	service.SetLanguagePreferences(&stubLanguagePreferences{
+		preferred: []string{"fr"},
+		bookmarks: map[uint]string{1: "en", 2: "fr", 3: "fr"},
+	})
+	service.boostPreferredLanguages("user-123", recommendations)
+
+	assert.Equal(t, uint(2), recommendations[0].BookmarkID)
+	assert.InDelta(t, 1.0, recommendations[0].Score, 0.0001)
+	assert.Equal(t, uint(1), recommendations[1].BookmarkID)
+	// The boost is soft: a weak match still ranks below a strong non-match
+	assert.Equal(t, uint(3), recommendations[2].BookmarkID)
+}
@@ -0,0 +1,70 @@
+package community
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// RedisAdapter adapts the go-redis client to implement the RedisClient interface
+type RedisAdapter struct {
+	client *redis.Client
+}
+
+// NewRedisAdapter creates a new Redis adapter
+func NewRedisAdapter(client *redis.Client) RedisClient {
+	return &RedisAdapter{client: client}
+}
+
+func (r *RedisAdapter) Get(ctx context.Context, key string) (string, error) {
+	return r.client.Get(ctx, key).Result()
+}
+
+func (r *RedisAdapter) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	return r.client.Set(ctx, key, value, expiration).Err()
+}
+
+func (r *RedisAdapter) Del(ctx context.Context, keys ...string) error {
+	return r.client.Del(ctx, keys...).Err()
+}
+
+// ZAdd adds members given as score, member pairs to a sorted set
+func (r *RedisAdapter) ZAdd(ctx context.Context, key string, members ...interface{}) error {
+	if len(members)%2 != 0 {
+		return fmt.Errorf("sorted set members must be score, member pairs")
+	}
+
+	entries := make([]*redis.Z, 0, len(members)/2)
+	for i := 0; i < len(members); i += 2 {
+		score, err := sortedSetScore(members[i])
+		if err != nil {
+			return err
+		}
+		entries = append(entries, &redis.Z{Score: score, Member: members[i+1]})
+	}
+	return r.client.ZAdd(ctx, key, entries...).Err()
+}
+
+func (r *RedisAdapter) ZRevRange(ctx context.Context, key string, start, stop int64) ([]string, error) {
+	return r.client.ZRevRange(ctx, key, start, stop).Result()
+}
+
+// sortedSetScore converts a numeric score to the float Redis stores
+func sortedSetScore(value interface{}) (float64, error) {
+	switch score := value.(type) {
+	case float64:
+		return score, nil
+	case float32:
+		return float64(score), nil
+	case int:
+		return float64(score), nil
+	case int64:
+		return float64(score), nil
+	case uint:
+		return float64(score), nil
+	default:
+		return 0, fmt.Errorf("invalid sorted set score %v", value)
+	}
+}
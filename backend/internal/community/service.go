@@ -25,6 +25,13 @@ type Database interface {
 	Order(value interface{}) Database
 	Limit(limit int) Database
 	Offset(offset int) Database
+	// ForUpdate locks the rows the next read returns until the transaction ends
+	ForUpdate() Database
+	// Transaction runs fn as one unit of work, rolling back every write made
+	// through tx when fn returns an error
+	Transaction(ctx context.Context, fn func(tx Database) error) error
+	// AdjustBookmarkCounter adds delta to a bookmark's social counter column
+	AdjustBookmarkCounter(bookmarkID uint, column string, delta int) error
 }
 
 // Redis interface for caching
@@ -90,8 +97,8 @@ func (s *Service) TrackUserBehavior(ctx context.Context, req *BehaviorTrackingRe
 	}
 
 	// Save to database
-	if err := s.db.Create(behavior).Error; err != nil {
-		return fmt.Errorf("failed to track user behavior: %w", err)
+	if err := recordBehavior(ctx, s.db, behavior); err != nil {
+		return err
 	}
 
 	// Update social metrics asynchronously using worker queue
@@ -127,17 +134,6 @@ func (s *Service) FollowUser(ctx context.Context, followerID string, req *Follow
 		return ErrCannotFollowSelf
 	}
 
-	// Check if already following
-	var existing UserFollow
-	err := s.db.First(&existing, "follower_id = ? AND following_id = ?", followerID, req.FollowingID).Error
-	if err == nil {
-		return ErrAlreadyFollowing
-	}
-	if err != gorm.ErrRecordNotFound {
-		return fmt.Errorf("failed to check existing follow: %w", err)
-	}
-
-	// Create follow relationship
 	follow := &UserFollow{
 		FollowerID:  followerID,
 		FollowingID: req.FollowingID,
@@ -148,8 +144,25 @@ func (s *Service) FollowUser(ctx context.Context, followerID string, req *Follow
 		return err
 	}
 
-	if err := s.db.Create(follow).Error; err != nil {
-		return fmt.Errorf("failed to create follow relationship: %w", err)
+	// The duplicate check and the insert run together so concurrent
+	// requests cannot create the relationship twice
+	err := s.db.Transaction(ctx, func(tx Database) error {
+		var existing UserFollow
+		err := tx.First(&existing, "follower_id = ? AND following_id = ?", followerID, req.FollowingID).Error
+		if err == nil {
+			return ErrAlreadyFollowing
+		}
+		if err != gorm.ErrRecordNotFound {
+			return fmt.Errorf("failed to check existing follow: %w", err)
+		}
+
+		if err := tx.Create(follow).Error; err != nil {
+			return fmt.Errorf("failed to create follow relationship: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	// Clear cache
@@ -365,60 +378,64 @@ func (s *Service) UpdateSocialMetrics(ctx context.Context, bookmarkID uint, acti
 		return ErrInvalidBookmarkID
 	}
 
-	var metrics SocialMetrics
-	err := s.db.First(&metrics, "bookmark_id = ?", bookmarkID).Error
-	if err == gorm.ErrRecordNotFound {
-		// Create new metrics record
-		metrics = SocialMetrics{
-			BookmarkID:     bookmarkID,
-			LastCalculated: time.Now(),
+	// The counters are read locked and written back in one transaction so
+	// concurrent actions on the same bookmark do not lose increments
+	return s.db.Transaction(ctx, func(tx Database) error {
+		var metrics SocialMetrics
+		err := tx.ForUpdate().First(&metrics, "bookmark_id = ?", bookmarkID).Error
+		if err == gorm.ErrRecordNotFound {
+			// Create new metrics record
+			metrics = SocialMetrics{
+				BookmarkID:     bookmarkID,
+				LastCalculated: time.Now(),
+			}
+		} else if err != nil {
+			return fmt.Errorf("failed to get social metrics: %w", err)
 		}
-	} else if err != nil {
-		return fmt.Errorf("failed to get social metrics: %w", err)
-	}
 
-	// Update metrics based on action type
-	switch actionType {
-	case "view":
-		metrics.TotalViews++
-	case "click":
-		metrics.TotalClicks++
-	case "save":
-		metrics.TotalSaves++
-	case "share":
-		metrics.TotalShares++
-	case "like":
-		metrics.TotalLikes++
-	}
+		// Update metrics based on action type
+		switch actionType {
+		case "view":
+			metrics.TotalViews++
+		case "click":
+			metrics.TotalClicks++
+		case "save":
+			metrics.TotalSaves++
+		case "share":
+			metrics.TotalShares++
+		case "like":
+			metrics.TotalLikes++
+		}
 
-	// Calculate engagement rate
-	if metrics.TotalViews > 0 {
-		totalEngagement := metrics.TotalClicks + metrics.TotalSaves + metrics.TotalShares + metrics.TotalLikes
-		metrics.EngagementRate = float64(totalEngagement) / float64(metrics.TotalViews)
-	}
+		// Calculate engagement rate
+		if metrics.TotalViews > 0 {
+			totalEngagement := metrics.TotalClicks + metrics.TotalSaves + metrics.TotalShares + metrics.TotalLikes
+			metrics.EngagementRate = float64(totalEngagement) / float64(metrics.TotalViews)
+		}
 
-	// Calculate virality score (simplified)
-	metrics.ViralityScore = float64(metrics.TotalShares)*2.0 + float64(metrics.TotalSaves)*1.5
+		// Calculate virality score (simplified)
+		metrics.ViralityScore = float64(metrics.TotalShares)*2.0 + float64(metrics.TotalSaves)*1.5
 
-	// Calculate quality score (simplified)
-	if metrics.TotalViews > 0 {
-		metrics.QualityScore = (metrics.EngagementRate * 0.6) + (float64(metrics.TotalLikes) / float64(metrics.TotalViews) * 0.4)
-	}
+		// Calculate quality score (simplified)
+		if metrics.TotalViews > 0 {
+			metrics.QualityScore = (metrics.EngagementRate * 0.6) + (float64(metrics.TotalLikes) / float64(metrics.TotalViews) * 0.4)
+		}
 
-	metrics.LastCalculated = time.Now()
+		metrics.LastCalculated = time.Now()
 
-	// Save or create metrics
-	if metrics.ID == 0 {
-		err = s.db.Create(&metrics).Error
-	} else {
-		err = s.db.Save(&metrics).Error
-	}
+		// Save or create metrics
+		if metrics.ID == 0 {
+			err = tx.Create(&metrics).Error
+		} else {
+			err = tx.Save(&metrics).Error
+		}
 
-	if err != nil {
-		return fmt.Errorf("failed to update social metrics: %w", err)
-	}
+		if err != nil {
+			return fmt.Errorf("failed to update social metrics: %w", err)
+		}
 
-	return nil
+		return nil
+	})
 }
 
 // GetUserStats returns user statistics and influence metrics
@@ -517,45 +534,51 @@ func (s *Service) CalculateTrendingScores(ctx context.Context, timeWindow string
 		}
 	}
 
-	// Calculate trending scores and save
-	for _, metric := range bookmarkMetrics {
-		// Calculate trending score using weighted formula
-		viewWeight := 1.0
-		clickWeight := 2.0
-		saveWeight := 3.0
-		shareWeight := 4.0
-		likeWeight := 2.5
-
-		rawScore := float64(metric.ViewCount)*viewWeight +
-			float64(metric.ClickCount)*clickWeight +
-			float64(metric.SaveCount)*saveWeight +
-			float64(metric.ShareCount)*shareWeight +
-			float64(metric.LikeCount)*likeWeight
-
-		// Apply time decay
-		hoursAgo := now.Sub(startTime).Hours()
-		timeDecay := math.Exp(-hoursAgo / 24.0) // Decay over 24 hours
-
-		metric.TrendingScore = rawScore * timeDecay
-
-		// Save or update trending bookmark
-		var existing TrendingBookmark
-		err := s.db.First(&existing, "bookmark_id = ? AND time_window = ?", metric.BookmarkID, timeWindow).Error
-		if err == gorm.ErrRecordNotFound {
-			s.db.Create(metric)
-		} else if err == nil {
-			existing.ViewCount = metric.ViewCount
-			existing.ClickCount = metric.ClickCount
-			existing.SaveCount = metric.SaveCount
-			existing.ShareCount = metric.ShareCount
-			existing.LikeCount = metric.LikeCount
-			existing.TrendingScore = metric.TrendingScore
-			existing.CalculatedAt = metric.CalculatedAt
-			s.db.Save(&existing)
+	// Calculate trending scores and save them together, so a failed run
+	// leaves the previous ranking in place rather than a mix of both
+	return s.db.Transaction(ctx, func(tx Database) error {
+		for _, metric := range bookmarkMetrics {
+			// Calculate trending score using weighted formula
+			viewWeight := 1.0
+			clickWeight := 2.0
+			saveWeight := 3.0
+			shareWeight := 4.0
+			likeWeight := 2.5
+
+			rawScore := float64(metric.ViewCount)*viewWeight +
+				float64(metric.ClickCount)*clickWeight +
+				float64(metric.SaveCount)*saveWeight +
+				float64(metric.ShareCount)*shareWeight +
+				float64(metric.LikeCount)*likeWeight
+
+			// Apply time decay
+			hoursAgo := now.Sub(startTime).Hours()
+			timeDecay := math.Exp(-hoursAgo / 24.0) // Decay over 24 hours
+
+			metric.TrendingScore = rawScore * timeDecay
+
+			// Save or update trending bookmark
+			var existing TrendingBookmark
+			err := tx.First(&existing, "bookmark_id = ? AND time_window = ?", metric.BookmarkID, timeWindow).Error
+			if err == gorm.ErrRecordNotFound {
+				err = tx.Create(metric).Error
+			} else if err == nil {
+				existing.ViewCount = metric.ViewCount
+				existing.ClickCount = metric.ClickCount
+				existing.SaveCount = metric.SaveCount
+				existing.ShareCount = metric.ShareCount
+				existing.LikeCount = metric.LikeCount
+				existing.TrendingScore = metric.TrendingScore
+				existing.CalculatedAt = metric.CalculatedAt
+				err = tx.Save(&existing).Error
+			}
+			if err != nil {
+				return fmt.Errorf("failed to save trending bookmark %d: %w", metric.BookmarkID, err)
+			}
 		}
-	}
 
-	return nil
+		return nil
+	})
 }
 
 // GenerateRecommendations generates bookmark recommendations for a user
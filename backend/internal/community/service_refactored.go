@@ -68,6 +68,25 @@ func (s *RefactoredService) UnfollowUser(ctx context.Context, followerID, follow
 	return s.userRelationship.UnfollowUser(ctx, followerID, followingID)
 }
 
+// SetLanguagePreferences enables boosting recommendations in preferred content languages
+func (s *RefactoredService) SetLanguagePreferences(languages LanguagePreferences) {
+	s.recommendations.SetLanguagePreferences(languages)
+}
+
+// SetQualityFilter leaves bookmarks suppressed for low quality out of
+// trending and recommendations
+func (s *RefactoredService) SetQualityFilter(filter QualityFilter) {
+	s.trending.SetQualityFilter(filter)
+	s.recommendations.SetQualityFilter(filter)
+}
+
+// SetTrendingPrivacy applies the participant threshold and noise of small
+// instances to trending and trending-based recommendations
+func (s *RefactoredService) SetTrendingPrivacy(privacy TrendingPrivacy) {
+	s.trending.SetPrivacy(privacy)
+	s.recommendations.SetTrendingPrivacy(privacy)
+}
+
 // GetRecommendations delegates to RecommendationService
 func (s *RefactoredService) GetRecommendations(ctx context.Context, req *RecommendationRequest) ([]RecommendationResponse, error) {
 	return s.recommendations.GetRecommendations(ctx, req)
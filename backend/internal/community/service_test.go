@@ -61,6 +61,21 @@ func (m *MockDB) Offset(offset int) Database {
 	return args.Get(0).(Database)
 }
 
+func (m *MockDB) ForUpdate() Database {
+	return m
+}
+
+// Transaction runs fn against the mock itself, so expectations set on the
+// mock apply inside the transaction too
+func (m *MockDB) Transaction(ctx context.Context, fn func(tx Database) error) error {
+	return fn(m)
+}
+
+func (m *MockDB) AdjustBookmarkCounter(bookmarkID uint, column string, delta int) error {
+	args := m.Called(bookmarkID, column, delta)
+	return args.Error(0)
+}
+
 type MockRedisClient struct {
 	mock.Mock
 }
@@ -62,38 +62,42 @@ func (s *SocialMetricsService) UpdateSocialMetrics(ctx context.Context, bookmark
 		return ErrInvalidBookmarkID
 	}
 
-	var metrics SocialMetrics
-	err := s.db.First(&metrics, "bookmark_id = ?", bookmarkID).Error
-	if err == gorm.ErrRecordNotFound {
-		// Create new metrics record
-		metrics = SocialMetrics{
-			BookmarkID:     bookmarkID,
-			LastCalculated: time.Now(),
+	// The counters are read locked and written back in one transaction so
+	// concurrent actions on the same bookmark do not lose increments
+	return s.db.Transaction(ctx, func(tx Database) error {
+		var metrics SocialMetrics
+		err := tx.ForUpdate().First(&metrics, "bookmark_id = ?", bookmarkID).Error
+		if err == gorm.ErrRecordNotFound {
+			// Create new metrics record
+			metrics = SocialMetrics{
+				BookmarkID:     bookmarkID,
+				LastCalculated: time.Now(),
+			}
+		} else if err != nil {
+			return fmt.Errorf("failed to get social metrics: %w", err)
 		}
-	} else if err != nil {
-		return fmt.Errorf("failed to get social metrics: %w", err)
-	}
 
-	// Update metrics based on action type
-	s.updateMetricsByAction(&metrics, actionType)
+		// Update metrics based on action type
+		s.updateMetricsByAction(&metrics, actionType)
 
-	// Calculate derived metrics
-	s.calculateDerivedMetrics(&metrics)
+		// Calculate derived metrics
+		s.calculateDerivedMetrics(&metrics)
 
-	metrics.LastCalculated = time.Now()
+		metrics.LastCalculated = time.Now()
 
-	// Save or create metrics
-	if metrics.ID == 0 {
-		err = s.db.Create(&metrics).Error
-	} else {
-		err = s.db.Save(&metrics).Error
-	}
+		// Save or create metrics
+		if metrics.ID == 0 {
+			err = tx.Create(&metrics).Error
+		} else {
+			err = tx.Save(&metrics).Error
+		}
 
-	if err != nil {
-		return fmt.Errorf("failed to update social metrics: %w", err)
-	}
+		if err != nil {
+			return fmt.Errorf("failed to update social metrics: %w", err)
+		}
 
-	return nil
+		return nil
+	})
 }
 
 // updateMetricsByAction updates metrics based on action type
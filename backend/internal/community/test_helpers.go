@@ -73,6 +73,21 @@ func (m *TestMockDB) Offset(offset int) Database {
 	return args.Get(0).(Database)
 }
 
+func (m *TestMockDB) ForUpdate() Database {
+	return m
+}
+
+// Transaction runs fn against the mock itself, so expectations set on the
+// mock apply inside the transaction too
+func (m *TestMockDB) Transaction(ctx context.Context, fn func(tx Database) error) error {
+	return fn(m)
+}
+
+func (m *TestMockDB) AdjustBookmarkCounter(bookmarkID uint, column string, delta int) error {
+	args := m.Called(bookmarkID, column, delta)
+	return args.Error(0)
+}
+
 // TestMockRedisClient provides a properly configured mock Redis client for testing
 type TestMockRedisClient struct {
 	mock.Mock
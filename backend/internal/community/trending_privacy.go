@@ -0,0 +1,49 @@
+package community
+
+import "math"
+
+// TrendingPrivacy keeps trending from revealing what individual users do,
+// which on a small instance a single view or save would otherwise show
+type TrendingPrivacy struct {
+	// MinParticipants leaves bookmarks out of trending until this many
+	// distinct users interacted with them in the time window; 0 disables it
+	MinParticipants int
+	// Noise is the scale of the Laplace noise added to each published
+	// count before scoring; 0 publishes exact counts
+	Noise float64
+}
+
+// SetPrivacy applies a participant threshold and noise to trending
+func (s *TrendingService) SetPrivacy(privacy TrendingPrivacy) {
+	s.privacy = privacy
+}
+
+// SetTrendingPrivacy leaves trending bookmarks below the participant
+// threshold out of trending-based recommendations
+func (s *RecommendationService) SetTrendingPrivacy(privacy TrendingPrivacy) {
+	s.privacy = privacy
+}
+
+// addNoise blurs the counts of each bookmark with Laplace noise. Counts
+// stay whole and never go negative
+func (s *TrendingService) addNoise(metrics map[uint]*TrendingBookmark) {
+	if s.privacy.Noise <= 0 {
+		return
+	}
+	for _, metric := range metrics {
+		for _, count := range []*int{&metric.ViewCount, &metric.ClickCount, &metric.SaveCount, &metric.ShareCount, &metric.LikeCount} {
+			*count = int(math.Max(0, math.Round(float64(*count)+s.laplace())))
+		}
+	}
+}
+
+// laplace draws from a Laplace distribution centered on 0 with the
+// configured noise scale
+func (s *TrendingService) laplace() float64 {
+	u := s.random() - 0.5
+	if u == -0.5 {
+		// 0 would be drawn as an infinite sample
+		return 0
+	}
+	return -s.privacy.Noise * math.Copysign(1, u) * math.Log(1-2*math.Abs(u))
+}
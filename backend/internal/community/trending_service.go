@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"math/rand"
 	"strconv"
 	"time"
 
@@ -17,6 +18,9 @@ type TrendingService struct {
 	redis      RedisClient
 	jsonHelper *JSONHelper
 	logger     *zap.Logger
+	quality    QualityFilter
+	privacy    TrendingPrivacy
+	random     func() float64 // uniform in [0, 1), for noise
 }
 
 // NewTrendingService creates a new trending service
@@ -26,6 +30,7 @@ func NewTrendingService(db Database, redis RedisClient, jsonHelper *JSONHelper,
 		redis:      redis,
 		jsonHelper: jsonHelper,
 		logger:     logger,
+		random:     rand.Float64,
 	}
 }
 
@@ -51,11 +56,15 @@ func (s *TrendingService) GetTrendingBookmarksInternal(ctx context.Context, req
 	if req.MinScore > 0 {
 		query = query.Where("trending_score >= ?", req.MinScore)
 	}
+	if s.privacy.MinParticipants > 0 {
+		query = query.Where("participants >= ?", s.privacy.MinParticipants)
+	}
 
 	err := query.Order("trending_score DESC").Limit(req.Limit).Find(&trendingBookmarks).Error
 	if err != nil {
 		return nil, fmt.Errorf("failed to get trending bookmarks: %w", err)
 	}
+	trendingBookmarks = s.dropSuppressedTrending(ctx, trendingBookmarks)
 
 	// Convert to response format
 	trending := make([]TrendingResponse, len(trendingBookmarks))
@@ -93,8 +102,9 @@ func (s *TrendingService) CalculateTrendingScores(ctx context.Context, timeWindo
 		return fmt.Errorf("failed to get user behaviors: %w", err)
 	}
 
-	// Aggregate metrics by bookmark
+	// Aggregate metrics by bookmark, blurring the counts when configured
 	bookmarkMetrics := s.aggregateMetrics(behaviors, timeWindow)
+	s.addNoise(bookmarkMetrics)
 
 	// Calculate trending scores and save
 	return s.saveTrendingMetrics(ctx, bookmarkMetrics, timeWindow)
@@ -121,6 +131,7 @@ func (s *TrendingService) calculateEngagementRate(tb TrendingBookmark) float64 {
 func (s *TrendingService) aggregateMetrics(behaviors []UserBehavior, timeWindow string) map[uint]*TrendingBookmark {
 	now := time.Now()
 	bookmarkMetrics := make(map[uint]*TrendingBookmark)
+	participants := make(map[uint]map[string]bool)
 
 	for _, behavior := range behaviors {
 		if bookmarkMetrics[behavior.BookmarkID] == nil {
@@ -129,10 +140,15 @@ func (s *TrendingService) aggregateMetrics(behaviors []UserBehavior, timeWindow
 				TimeWindow:   timeWindow,
 				CalculatedAt: now,
 			}
+			participants[behavior.BookmarkID] = make(map[string]bool)
 		}
 
 		metric := bookmarkMetrics[behavior.BookmarkID]
 		s.updateMetricCounts(metric, behavior.ActionType)
+		if !participants[behavior.BookmarkID][behavior.UserID] {
+			participants[behavior.BookmarkID][behavior.UserID] = true
+			metric.Participants++
+		}
 	}
 
 	return bookmarkMetrics
@@ -158,18 +174,22 @@ func (s *TrendingService) updateMetricCounts(metric *TrendingBookmark, actionTyp
 func (s *TrendingService) saveTrendingMetrics(ctx context.Context, bookmarkMetrics map[uint]*TrendingBookmark, timeWindow string) error {
 	now := time.Now()
 
-	for _, metric := range bookmarkMetrics {
-		// Calculate trending score using weighted formula
-		metric.TrendingScore = s.calculateTrendingScore(metric, now, timeWindow)
-
-		// Save or update trending bookmark
-		if err := s.saveOrUpdateTrendingBookmark(metric, timeWindow); err != nil {
-			s.logger.Error("Failed to save trending bookmark", zap.Error(err), zap.Uint("bookmark_id", metric.BookmarkID))
-			continue
+	// The window's scores are saved together, so a failed run leaves the
+	// previous ranking in place rather than a mix of both
+	return s.db.Transaction(ctx, func(tx Database) error {
+		for _, metric := range bookmarkMetrics {
+			// Calculate trending score using weighted formula
+			metric.TrendingScore = s.calculateTrendingScore(metric, now, timeWindow)
+
+			// Save or update trending bookmark
+			if err := s.saveOrUpdateTrendingBookmark(tx, metric, timeWindow); err != nil {
+				s.logger.Error("Failed to save trending bookmark", zap.Error(err), zap.Uint("bookmark_id", metric.BookmarkID))
+				return fmt.Errorf("failed to save trending bookmark %d: %w", metric.BookmarkID, err)
+			}
 		}
-	}
 
-	return nil
+		return nil
+	})
 }
 
 // calculateTrendingScore calculates trending score with time decay
@@ -195,12 +215,12 @@ func (s *TrendingService) calculateTrendingScore(metric *TrendingBookmark, now t
 }
 
 // saveOrUpdateTrendingBookmark saves or updates trending bookmark record
-func (s *TrendingService) saveOrUpdateTrendingBookmark(metric *TrendingBookmark, timeWindow string) error {
+func (s *TrendingService) saveOrUpdateTrendingBookmark(db Database, metric *TrendingBookmark, timeWindow string) error {
 	var existing TrendingBookmark
-	err := s.db.First(&existing, "bookmark_id = ? AND time_window = ?", metric.BookmarkID, timeWindow).Error
+	err := db.First(&existing, "bookmark_id = ? AND time_window = ?", metric.BookmarkID, timeWindow).Error
 
 	if err == gorm.ErrRecordNotFound {
-		return s.db.Create(metric).Error
+		return db.Create(metric).Error
 	} else if err == nil {
 		// Update existing record
 		existing.ViewCount = metric.ViewCount
@@ -210,7 +230,8 @@ func (s *TrendingService) saveOrUpdateTrendingBookmark(metric *TrendingBookmark,
 		existing.LikeCount = metric.LikeCount
 		existing.TrendingScore = metric.TrendingScore
 		existing.CalculatedAt = metric.CalculatedAt
-		return s.db.Save(&existing).Error
+		existing.Participants = metric.Participants
+		return db.Save(&existing).Error
 	}
 
 	return err
@@ -56,6 +56,87 @@ func (suite *TrendingServiceTestSuite) TestGetTrendingBookmarksInternal_Success(
 	assert.NotNil(suite.T(), trending)
 }
 
+// Test GetTrendingBookmarksInternal - Suppressed bookmarks are left out
+func (suite *TrendingServiceTestSuite) TestGetTrendingBookmarksInternal_DropsSuppressed() {
+	suite.service.SetQualityFilter(staticQualityFilter{2: true})
+
+	suite.mockDB.On("Where", "time_window = ?", mock.Anything).Return(suite.mockDB)
+	suite.mockDB.On("Order", "trending_score DESC").Return(suite.mockDB)
+	suite.mockDB.On("Limit", 20).Return(suite.mockDB)
+	suite.mockDB.On("Find", mock.AnythingOfType("*[]community.TrendingBookmark"), mock.Anything).Run(func(args mock.Arguments) {
+		*args.Get(0).(*[]TrendingBookmark) = []TrendingBookmark{{BookmarkID: 1}, {BookmarkID: 2}, {BookmarkID: 3}}
+	}).Return(&gorm.DB{Error: nil})
+
+	trending, err := suite.service.GetTrendingBookmarksInternal(suite.ctx, &TrendingRequest{TimeWindow: "daily"})
+
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), trending, 2)
+	assert.Equal(suite.T(), uint(1), trending[0].BookmarkID)
+	assert.Equal(suite.T(), uint(3), trending[1].BookmarkID)
+}
+
+// staticQualityFilter suppresses a fixed set of bookmarks
+type staticQualityFilter map[uint]bool
+
+func (f staticQualityFilter) SuppressedBookmarks(ctx context.Context, ids []uint) (map[uint]bool, error) {
+	suppressed := map[uint]bool{}
+	for _, id := range ids {
+		if f[id] {
+			suppressed[id] = true
+		}
+	}
+	return suppressed, nil
+}
+
+// Test GetTrendingBookmarksInternal - Bookmarks below the participant threshold are left out
+func (suite *TrendingServiceTestSuite) TestGetTrendingBookmarksInternal_MinParticipants() {
+	suite.service.SetPrivacy(TrendingPrivacy{MinParticipants: 3})
+
+	suite.mockDB.On("Where", "time_window = ?", mock.Anything).Return(suite.mockDB)
+	suite.mockDB.On("Where", "participants >= ?", mock.Anything).Return(suite.mockDB).Once()
+	suite.mockDB.On("Order", "trending_score DESC").Return(suite.mockDB)
+	suite.mockDB.On("Limit", 20).Return(suite.mockDB)
+	suite.mockDB.On("Find", mock.AnythingOfType("*[]community.TrendingBookmark"), mock.Anything).Return(&gorm.DB{Error: nil})
+
+	_, err := suite.service.GetTrendingBookmarksInternal(suite.ctx, &TrendingRequest{TimeWindow: "daily"})
+
+	assert.NoError(suite.T(), err)
+}
+
+// Test aggregateMetrics - Participants are distinct users
+func (suite *TrendingServiceTestSuite) TestAggregateMetrics_CountsParticipants() {
+	metrics := suite.service.aggregateMetrics([]UserBehavior{
+		{UserID: "1", BookmarkID: 7, ActionType: "view"},
+		{UserID: "1", BookmarkID: 7, ActionType: "save"},
+		{UserID: "2", BookmarkID: 7, ActionType: "view"},
+		{UserID: "1", BookmarkID: 8, ActionType: "view"},
+	}, "daily")
+
+	assert.Equal(suite.T(), 2, metrics[7].Participants)
+	assert.Equal(suite.T(), 2, metrics[7].ViewCount)
+	assert.Equal(suite.T(), 1, metrics[8].Participants)
+}
+
+// Test addNoise - Counts are blurred but stay whole and non-negative
+func (suite *TrendingServiceTestSuite) TestAddNoise() {
+	metrics := map[uint]*TrendingBookmark{1: {ViewCount: 10, SaveCount: 1}}
+	suite.service.addNoise(metrics)
+	assert.Equal(suite.T(), 10, metrics[1].ViewCount, "no noise unless configured")
+
+	draws := []float64{0.99, 0.01, 0.5, 0.5, 0.5}
+	suite.service.random = func() float64 {
+		u := draws[0]
+		draws = draws[1:]
+		return u
+	}
+	suite.service.SetPrivacy(TrendingPrivacy{Noise: 2})
+	suite.service.addNoise(metrics)
+
+	assert.Equal(suite.T(), 18, metrics[1].ViewCount) // 10 + 2*ln(50)
+	assert.Equal(suite.T(), 0, metrics[1].ClickCount) // 0 - 2*ln(50), clamped
+	assert.Equal(suite.T(), 1, metrics[1].SaveCount)  // the median draw adds nothing
+}
+
 // Test GetTrendingBookmarksInternal - Invalid time window
 func (suite *TrendingServiceTestSuite) TestGetTrendingBookmarksInternal_InvalidTimeWindow() {
 	request := &TrendingRequest{
@@ -39,14 +39,6 @@ func (s *UserRelationshipService) FollowUser(ctx context.Context, followerID str
 		return ErrCannotFollowSelf
 	}
 
-	// Check if already following
-	if exists, err := s.checkFollowExists(followerID, req.FollowingID); err != nil {
-		return err
-	} else if exists {
-		return ErrAlreadyFollowing
-	}
-
-	// Create follow relationship
 	follow := &UserFollow{
 		FollowerID:  followerID,
 		FollowingID: req.FollowingID,
@@ -57,8 +49,22 @@ func (s *UserRelationshipService) FollowUser(ctx context.Context, followerID str
 		return err
 	}
 
-	if err := s.db.Create(follow).Error; err != nil {
-		return fmt.Errorf("failed to create follow relationship: %w", err)
+	// The duplicate check and the insert run together so concurrent
+	// requests cannot create the relationship twice
+	err := s.db.Transaction(ctx, func(tx Database) error {
+		if exists, err := checkFollowExists(tx, followerID, req.FollowingID); err != nil {
+			return err
+		} else if exists {
+			return ErrAlreadyFollowing
+		}
+
+		if err := tx.Create(follow).Error; err != nil {
+			return fmt.Errorf("failed to create follow relationship: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	// Clear cache for both users
@@ -119,9 +125,9 @@ func (s *UserRelationshipService) GetUserStats(ctx context.Context, userID strin
 }
 
 // checkFollowExists checks if a follow relationship already exists
-func (s *UserRelationshipService) checkFollowExists(followerID, followingID string) (bool, error) {
+func checkFollowExists(db Database, followerID, followingID string) (bool, error) {
 	var existing UserFollow
-	err := s.db.First(&existing, "follower_id = ? AND following_id = ?", followerID, followingID).Error
+	err := db.First(&existing, "follower_id = ? AND following_id = ?", followerID, followingID).Error
 	if err == gorm.ErrRecordNotFound {
 		return false, nil
 	}
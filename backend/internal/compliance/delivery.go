@@ -0,0 +1,74 @@
+package compliance
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+// Headers of webhook deliveries
+const (
+	HeaderReportID  = "X-Compliance-Report"
+	HeaderSignature = "X-Compliance-Signature" // sha256=<hex HMAC of the body>, when the report has a secret
+)
+
+// deliver sends a rendered report to its destination and returns where it went
+func (s *Service) deliver(ctx context.Context, report *database.ComplianceReport, name string, body []byte, contentType string) (string, error) {
+	switch report.DestinationType {
+	case DestinationBucket:
+		if s.uploader == nil {
+			return "", ErrBucketDeliveryDisabled
+		}
+		if report.DestinationID == nil {
+			return "", fmt.Errorf("%w: no destination", ErrInvalidReport)
+		}
+		key := path.Join("compliance", name)
+		return s.uploader.UploadToDestination(ctx, strconv.FormatUint(uint64(report.UserID), 10), *report.DestinationID, key, bytes.NewReader(body), int64(len(body)))
+	case DestinationWebhook:
+		return report.WebhookURL, s.post(ctx, report, name, body, contentType)
+	default:
+		return "", fmt.Errorf("%w: unknown destination type %q", ErrInvalidReport, report.DestinationType)
+	}
+}
+
+// post delivers a report to its webhook, signed with the report's secret
+func (s *Service) post(ctx context.Context, report *database.ComplianceReport, name string, body []byte, contentType string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, report.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	req.Header.Set(HeaderReportID, strconv.FormatUint(uint64(report.ID), 10))
+	if report.WebhookSecret != "" {
+		req.Header.Set(HeaderSignature, sign(body, report.WebhookSecret))
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to deliver report: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// sign returns the signature header value of a webhook body
+func sign(body []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,230 @@
+package compliance
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"bookmark-sync-service/backend/pkg/utils"
+)
+
+// Handler serves workspace owners' compliance reports
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new compliance report handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes registers the compliance report routes
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	compliance := router.Group("/compliance")
+	compliance.GET("/templates", h.GetTemplates)
+	compliance.GET("/reports", h.ListReports)
+	compliance.POST("/reports", h.CreateReport)
+	compliance.GET("/reports/:id", h.GetReport)
+	compliance.DELETE("/reports/:id", h.DeleteReport)
+	compliance.GET("/reports/:id/preview", h.PreviewReport)
+	compliance.POST("/reports/:id/run", h.RunReport)
+	compliance.GET("/reports/:id/runs", h.ListRuns)
+}
+
+// GetTemplates lists the report templates and sections
+// @Summary List compliance report templates
+// @Tags compliance
+// @Produce json
+// @Success 200 {array} Template
+// @Router /compliance/templates [get]
+func (h *Handler) GetTemplates(c *gin.Context) {
+	utils.SuccessResponse(c, gin.H{"templates": Templates, "custom": TemplateCustom}, "Compliance report templates retrieved")
+}
+
+// ListReports lists the user's scheduled compliance reports
+// @Summary List compliance reports
+// @Tags compliance
+// @Produce json
+// @Success 200 {array} database.ComplianceReport
+// @Router /compliance/reports [get]
+func (h *Handler) ListReports(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	reports, err := h.service.ListReports(c.Request.Context(), userID)
+	if err != nil {
+		h.fail(c, err, "Failed to list compliance reports")
+		return
+	}
+	utils.SuccessResponse(c, gin.H{"reports": reports}, "Compliance reports retrieved")
+}
+
+// CreateReport schedules a compliance report of the user's workspace
+// @Summary Schedule a compliance report
+// @Description Reports cover the user's shares, shared collections and their accepted collaborators, and are delivered to a backup destination or a webhook
+// @Tags compliance
+// @Accept json
+// @Produce json
+// @Param request body ReportRequest true "Compliance report"
+// @Success 201 {object} database.ComplianceReport
+// @Failure 400 {object} utils.ErrorResponse
+// @Router /compliance/reports [post]
+func (h *Handler) CreateReport(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	var req ReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request format", nil)
+		return
+	}
+
+	report, err := h.service.CreateReport(c.Request.Context(), userID, req)
+	if err != nil {
+		h.fail(c, err, "Failed to create compliance report")
+		return
+	}
+	c.JSON(http.StatusCreated, utils.APIResponse{Success: true, Data: report, Message: "Compliance report scheduled"})
+}
+
+// GetReport returns one of the user's compliance reports
+// @Summary Get a compliance report
+// @Tags compliance
+// @Produce json
+// @Param id path int true "Report ID"
+// @Success 200 {object} database.ComplianceReport
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /compliance/reports/{id} [get]
+func (h *Handler) GetReport(c *gin.Context) {
+	userID, id, ok := h.reportParams(c)
+	if !ok {
+		return
+	}
+
+	report, err := h.service.GetReport(c.Request.Context(), userID, id)
+	if err != nil {
+		h.fail(c, err, "Failed to get compliance report")
+		return
+	}
+	utils.SuccessResponse(c, report, "Compliance report retrieved")
+}
+
+// DeleteReport stops and removes a compliance report
+// @Summary Delete a compliance report
+// @Tags compliance
+// @Param id path int true "Report ID"
+// @Success 200 {object} utils.Response
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /compliance/reports/{id} [delete]
+func (h *Handler) DeleteReport(c *gin.Context) {
+	userID, id, ok := h.reportParams(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteReport(c.Request.Context(), userID, id); err != nil {
+		h.fail(c, err, "Failed to delete compliance report")
+		return
+	}
+	utils.SuccessResponse(c, nil, "Compliance report deleted")
+}
+
+// PreviewReport generates the report's next period without delivering it
+// @Summary Preview a compliance report
+// @Tags compliance
+// @Produce json
+// @Param id path int true "Report ID"
+// @Success 200 {object} Document
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /compliance/reports/{id}/preview [get]
+func (h *Handler) PreviewReport(c *gin.Context) {
+	userID, id, ok := h.reportParams(c)
+	if !ok {
+		return
+	}
+
+	document, err := h.service.Preview(c.Request.Context(), userID, id)
+	if err != nil {
+		h.fail(c, err, "Failed to preview compliance report")
+		return
+	}
+	utils.SuccessResponse(c, document, "Compliance report generated")
+}
+
+// RunReport generates and delivers a report now
+// @Summary Run a compliance report now
+// @Description Covers the time since the last delivery; a failed delivery is recorded on the returned run
+// @Tags compliance
+// @Produce json
+// @Param id path int true "Report ID"
+// @Success 200 {object} database.ComplianceRun
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /compliance/reports/{id}/run [post]
+func (h *Handler) RunReport(c *gin.Context) {
+	userID, id, ok := h.reportParams(c)
+	if !ok {
+		return
+	}
+
+	run, err := h.service.RunReport(c.Request.Context(), userID, id)
+	if err != nil {
+		h.fail(c, err, "Failed to run compliance report")
+		return
+	}
+	utils.SuccessResponse(c, run, "Compliance report run")
+}
+
+// ListRuns lists a report's latest runs
+// @Summary List the runs of a compliance report
+// @Tags compliance
+// @Produce json
+// @Param id path int true "Report ID"
+// @Success 200 {array} database.ComplianceRun
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /compliance/reports/{id}/runs [get]
+func (h *Handler) ListRuns(c *gin.Context) {
+	userID, id, ok := h.reportParams(c)
+	if !ok {
+		return
+	}
+
+	runs, err := h.service.ListRuns(c.Request.Context(), userID, id)
+	if err != nil {
+		h.fail(c, err, "Failed to list compliance report runs")
+		return
+	}
+	utils.SuccessResponse(c, gin.H{"runs": runs}, "Compliance report runs retrieved")
+}
+
+func (h *Handler) reportParams(c *gin.Context) (uint, uint, bool) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return 0, 0, false
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_ID", "Invalid report ID", nil)
+		return 0, 0, false
+	}
+	return userID, uint(id), true
+}
+
+func (h *Handler) fail(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, ErrReportNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, "REPORT_NOT_FOUND", "Compliance report not found", nil)
+	case errors.Is(err, ErrInvalidReport):
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_REPORT", err.Error(), nil)
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", message, nil)
+	}
+}
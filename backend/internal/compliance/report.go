@@ -0,0 +1,327 @@
+package compliance
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"bookmark-sync-service/backend/internal/automation"
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+// Entry is one event in a report section
+type Entry struct {
+	Time      time.Time `json:"time"`
+	ActorID   *uint     `json:"actor_id,omitempty"` // empty for anonymous visitors
+	Action    string    `json:"action"`
+	Resource  string    `json:"resource"` // e.g. share/12 or app/3
+	IPAddress string    `json:"ip_address,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	Detail    string    `json:"detail,omitempty"` // space separated key=value pairs
+}
+
+// Document is a generated report
+type Document struct {
+	ReportID    uint               `json:"report_id"`
+	Name        string             `json:"name"`
+	Template    string             `json:"template"`
+	OwnerID     uint               `json:"owner_id"`
+	Members     []uint             `json:"members"` // the owner and accepted collaborators
+	PeriodStart time.Time          `json:"period_start"`
+	PeriodEnd   time.Time          `json:"period_end"`
+	GeneratedAt time.Time          `json:"generated_at"`
+	Sections    map[string][]Entry `json:"sections"`
+}
+
+// csvHeader are the columns of CSV reports, one row per entry
+var csvHeader = []string{"section", "time", "actor_id", "action", "resource", "ip_address", "user_agent", "detail"}
+
+// Entries returns how many entries the document has across its sections
+func (d *Document) Entries() int {
+	count := 0
+	for _, entries := range d.Sections {
+		count += len(entries)
+	}
+	return count
+}
+
+// Render encodes the document in a report format, returning its content type
+func (d *Document) Render(format string) ([]byte, string, error) {
+	if format == FormatJSON {
+		body, err := json.MarshalIndent(d, "", "  ")
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to encode report: %w", err)
+		}
+		return body, "application/json", nil
+	}
+
+	sections := make([]string, 0, len(d.Sections))
+	for section := range d.Sections {
+		sections = append(sections, section)
+	}
+	sort.Strings(sections)
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write(csvHeader)
+	for _, section := range sections {
+		for _, entry := range d.Sections[section] {
+			actor := ""
+			if entry.ActorID != nil {
+				actor = strconv.FormatUint(uint64(*entry.ActorID), 10)
+			}
+			w.Write([]string{section, entry.Time.UTC().Format(time.RFC3339), actor, entry.Action, entry.Resource, entry.IPAddress, entry.UserAgent, entry.Detail})
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, "", fmt.Errorf("failed to encode report: %w", err)
+	}
+	return buf.Bytes(), "text/csv", nil
+}
+
+// fileName names the file of a report's run ending at end
+func fileName(report *database.ComplianceReport, end time.Time) string {
+	return fmt.Sprintf("compliance-%d_%s.%s", report.ID, end.UTC().Format("20060102T150405Z"), report.Format)
+}
+
+// generate gathers the report's sections for the period [start, end)
+func (s *Service) generate(ctx context.Context, report *database.ComplianceReport, start, end time.Time) (*Document, error) {
+	members, err := s.members(ctx, report.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	document := &Document{
+		ReportID:    report.ID,
+		Name:        report.Name,
+		Template:    report.Template,
+		OwnerID:     report.UserID,
+		Members:     members,
+		PeriodStart: start,
+		PeriodEnd:   end,
+		GeneratedAt: s.now(),
+		Sections:    map[string][]Entry{},
+	}
+
+	for _, section := range report.Sections {
+		var entries []Entry
+		switch section {
+		case SectionShareAccess:
+			entries, err = s.shareAccess(ctx, report.UserID, start, end)
+		case SectionCollaboratorChanges:
+			entries, err = s.collaboratorChanges(ctx, report.UserID, start, end)
+		case SectionBulkDeletions:
+			entries, err = s.bulkDeletions(ctx, members, start, end)
+		case SectionTokenUsage:
+			entries, err = s.tokenUsage(ctx, members, start, end)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to gather %s: %w", section, err)
+		}
+		sort.SliceStable(entries, func(i, j int) bool { return entries[i].Time.Before(entries[j].Time) })
+		document.Sections[section] = append([]Entry{}, entries...)
+	}
+	return document, nil
+}
+
+// members returns the owner and the users who accepted an invitation to
+// one of the owner's collections
+func (s *Service) members(ctx context.Context, ownerID uint) ([]uint, error) {
+	var collaborators []uint
+	if err := s.db.WithContext(ctx).Model(&database.CollectionCollaborator{}).
+		Joins("JOIN collections ON collections.id = collection_collaborators.collection_id").
+		Where("collections.user_id = ? AND collection_collaborators.status = ?", ownerID, "accepted").
+		Distinct().Order("collection_collaborators.user_id").
+		Pluck("collection_collaborators.user_id", &collaborators).Error; err != nil {
+		return nil, fmt.Errorf("failed to find workspace members: %w", err)
+	}
+
+	members := []uint{ownerID}
+	for _, id := range collaborators {
+		if id != ownerID {
+			members = append(members, id)
+		}
+	}
+	return members, nil
+}
+
+// shareAccess lists the activity on the owner's shares, deleted ones included
+func (s *Service) shareAccess(ctx context.Context, ownerID uint, start, end time.Time) ([]Entry, error) {
+	var rows []struct {
+		database.ShareActivity
+		CollectionID uint
+	}
+	if err := s.db.WithContext(ctx).Table("share_activities").
+		Select("share_activities.*, collection_shares.collection_id").
+		Joins("JOIN collection_shares ON collection_shares.id = share_activities.share_id").
+		Where("collection_shares.user_id = ? AND share_activities.created_at >= ? AND share_activities.created_at < ?", ownerID, start, end).
+		Order("share_activities.created_at").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0, len(rows))
+	for _, row := range rows {
+		entries = append(entries, Entry{
+			Time:      row.CreatedAt,
+			ActorID:   row.UserID,
+			Action:    row.ActivityType,
+			Resource:  fmt.Sprintf("share/%d", row.ShareID),
+			IPAddress: row.IPAddress,
+			UserAgent: row.UserAgent,
+			Detail:    fmt.Sprintf("collection_id=%d", row.CollectionID),
+		})
+	}
+	return entries, nil
+}
+
+// collaboratorChanges lists invitations to the owner's collections and
+// their acceptance and removal
+func (s *Service) collaboratorChanges(ctx context.Context, ownerID uint, start, end time.Time) ([]Entry, error) {
+	var collaborators []database.CollectionCollaborator
+	if err := s.db.WithContext(ctx).Unscoped().
+		Joins("JOIN collections ON collections.id = collection_collaborators.collection_id").
+		Where("collections.user_id = ?", ownerID).
+		Where("(collection_collaborators.invited_at >= ? AND collection_collaborators.invited_at < ?) OR "+
+			"(collection_collaborators.accepted_at >= ? AND collection_collaborators.accepted_at < ?) OR "+
+			"(collection_collaborators.deleted_at >= ? AND collection_collaborators.deleted_at < ?)",
+			start, end, start, end, start, end).
+		Find(&collaborators).Error; err != nil {
+		return nil, err
+	}
+
+	within := func(t time.Time) bool { return !t.Before(start) && t.Before(end) }
+	var entries []Entry
+	for _, c := range collaborators {
+		resource := fmt.Sprintf("collection/%d", c.CollectionID)
+		detail := fmt.Sprintf("user_id=%d permission=%s", c.UserID, c.Permission)
+		if within(c.InvitedAt) {
+			inviter := c.InviterID
+			entries = append(entries, Entry{Time: c.InvitedAt, ActorID: &inviter, Action: "invited", Resource: resource, Detail: detail})
+		}
+		if c.AcceptedAt != nil && within(*c.AcceptedAt) {
+			user := c.UserID
+			entries = append(entries, Entry{Time: *c.AcceptedAt, ActorID: &user, Action: "accepted", Resource: resource, Detail: detail})
+		}
+		if c.DeletedAt.Valid && within(c.DeletedAt.Time) {
+			entries = append(entries, Entry{Time: c.DeletedAt.Time, Action: "removed", Resource: resource, Detail: detail})
+		}
+	}
+	return entries, nil
+}
+
+// bulkDeletions lists the bulk delete operations members started
+func (s *Service) bulkDeletions(ctx context.Context, members []uint, start, end time.Time) ([]Entry, error) {
+	userIDs := make([]string, 0, len(members))
+	for _, id := range members {
+		userIDs = append(userIDs, strconv.FormatUint(uint64(id), 10))
+	}
+
+	var operations []automation.BulkOperation
+	if err := s.db.WithContext(ctx).Unscoped().
+		Where("type = ? AND user_id IN ? AND created_at >= ? AND created_at < ?", "delete", userIDs, start, end).
+		Find(&operations).Error; err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0, len(operations))
+	for _, op := range operations {
+		actor, err := strconv.ParseUint(op.UserID, 10, 32)
+		if err != nil {
+			continue
+		}
+		actorID := uint(actor)
+		entries = append(entries, Entry{
+			Time:     op.CreatedAt,
+			ActorID:  &actorID,
+			Action:   "bulk_delete",
+			Resource: fmt.Sprintf("bulk_operation/%d", op.ID),
+			Detail:   fmt.Sprintf("status=%s items=%d processed=%d failed=%d", op.Status, op.TotalItems, op.ProcessedItems, op.FailedItems),
+		})
+	}
+	return entries, nil
+}
+
+// tokenUsage lists the apps members authorized or revoked, and per member
+// and app the access tokens used during the period
+func (s *Service) tokenUsage(ctx context.Context, members []uint, start, end time.Time) ([]Entry, error) {
+	var grants []database.OAuthGrant
+	if err := s.db.WithContext(ctx).Preload("App").
+		Where("user_id IN ?", members).
+		Where("(created_at >= ? AND created_at < ?) OR (revoked_at >= ? AND revoked_at < ?)", start, end, start, end).
+		Find(&grants).Error; err != nil {
+		return nil, err
+	}
+
+	var tokens []database.OAuthToken
+	if err := s.db.WithContext(ctx).Select("user_id", "app_id", "last_used_at").
+		Where("user_id IN ? AND last_used_at >= ? AND last_used_at < ?", members, start, end).
+		Order("user_id, app_id").
+		Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+
+	// Tokens are rotated on refresh, so usage is summed per member and app
+	type usageKey struct{ userID, appID uint }
+	var keys []usageKey
+	usage := map[usageKey]*Entry{}
+	counts := map[usageKey]int{}
+	for _, token := range tokens {
+		key := usageKey{token.UserID, token.AppID}
+		entry, ok := usage[key]
+		if !ok {
+			user := token.UserID
+			entry = &Entry{ActorID: &user, Action: "token_used", Resource: fmt.Sprintf("app/%d", token.AppID)}
+			usage[key] = entry
+			keys = append(keys, key)
+		}
+		if token.LastUsedAt.After(entry.Time) {
+			entry.Time = *token.LastUsedAt
+		}
+		counts[key]++
+	}
+
+	names := map[uint]string{}
+	if len(keys) > 0 {
+		appIDs := make([]uint, 0, len(keys))
+		for _, key := range keys {
+			appIDs = append(appIDs, key.appID)
+		}
+		var apps []database.OAuthApp
+		if err := s.db.WithContext(ctx).Unscoped().Where("id IN ?", appIDs).Find(&apps).Error; err != nil {
+			return nil, err
+		}
+		for _, app := range apps {
+			names[app.ID] = app.Name
+		}
+	}
+
+	within := func(t time.Time) bool { return !t.Before(start) && t.Before(end) }
+	var entries []Entry
+	for _, grant := range grants {
+		user := grant.UserID
+		resource := fmt.Sprintf("app/%d", grant.AppID)
+		detail := fmt.Sprintf("app=%q scopes=%s", grant.App.Name, strings.Join(grant.Scopes, ","))
+		if within(grant.CreatedAt) {
+			entries = append(entries, Entry{Time: grant.CreatedAt, ActorID: &user, Action: "app_authorized", Resource: resource, Detail: detail})
+		}
+		if grant.RevokedAt != nil && within(*grant.RevokedAt) {
+			entries = append(entries, Entry{Time: *grant.RevokedAt, ActorID: &user, Action: "app_revoked", Resource: resource, Detail: detail})
+		}
+	}
+	for _, key := range keys {
+		entry := usage[key]
+		entry.Detail = fmt.Sprintf("app=%q tokens=%d", names[key.appID], counts[key])
+		entries = append(entries, *entry)
+	}
+	return entries, nil
+}
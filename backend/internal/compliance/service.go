@@ -0,0 +1,408 @@
+package compliance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/internal/automation"
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+// Report sections
+const (
+	SectionShareAccess         = "share_access"         // visits to the owner's shares
+	SectionCollaboratorChanges = "collaborator_changes" // invitations, acceptances and removals of collaborators
+	SectionBulkDeletions       = "bulk_deletions"       // bulk delete operations of workspace members
+	SectionTokenUsage          = "token_usage"          // apps members authorized and the tokens they used
+)
+
+// Report formats
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// Report schedules
+const (
+	ScheduleDaily   = "daily"
+	ScheduleWeekly  = "weekly"
+	ScheduleMonthly = "monthly"
+)
+
+// Report destinations
+const (
+	DestinationBucket  = "bucket"  // one of the owner's backup destinations
+	DestinationWebhook = "webhook" // an HTTP endpoint the report is POSTed to
+)
+
+// Statuses of a report run
+const (
+	RunDelivered = "delivered"
+	RunFailed    = "failed"
+)
+
+// TemplateCustom is the template of reports picking their own sections
+const TemplateCustom = "custom"
+
+var (
+	// ErrReportNotFound is returned for reports of other users or unknown IDs
+	ErrReportNotFound = errors.New("compliance report not found")
+	// ErrInvalidReport wraps what is wrong with a report request
+	ErrInvalidReport = errors.New("invalid compliance report")
+	// ErrBucketDeliveryDisabled is returned when no uploader is configured
+	ErrBucketDeliveryDisabled = errors.New("bucket delivery is not configured")
+)
+
+// Template is a predefined selection of report sections
+type Template struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Sections    []string `json:"sections"`
+}
+
+// Templates are the report templates offered to workspace owners
+var Templates = []Template{
+	{
+		Name:        "access_review",
+		Description: "Who opened the workspace's shares and how collaborators' access changed",
+		Sections:    []string{SectionShareAccess, SectionCollaboratorChanges},
+	},
+	{
+		Name:        "data_deletion",
+		Description: "Bulk deletions run by workspace members",
+		Sections:    []string{SectionBulkDeletions},
+	},
+	{
+		Name:        "api_usage",
+		Description: "Apps workspace members authorized and the API tokens they used",
+		Sections:    []string{SectionTokenUsage},
+	},
+	{
+		Name:        "full",
+		Description: "Every section",
+		Sections:    []string{SectionShareAccess, SectionCollaboratorChanges, SectionBulkDeletions, SectionTokenUsage},
+	},
+}
+
+var sectionNames = map[string]bool{
+	SectionShareAccess:         true,
+	SectionCollaboratorChanges: true,
+	SectionBulkDeletions:       true,
+	SectionTokenUsage:          true,
+}
+
+// Uploader stores files in a user's bucket, e.g. the automation service
+// with its backup destinations
+type Uploader interface {
+	UploadToDestination(ctx context.Context, userID string, destinationID uint, name string, body io.Reader, size int64) (string, error)
+}
+
+// ReportRequest creates a scheduled compliance report
+type ReportRequest struct {
+	Name     string   `json:"name" binding:"required,max=100"`
+	Template string   `json:"template" binding:"required"`
+	Sections []string `json:"sections,omitempty"` // only for the custom template
+	Format   string   `json:"format,omitempty"`   // csv or json, json by default
+	Schedule string   `json:"schedule" binding:"required"`
+
+	DestinationType string `json:"destination_type" binding:"required"`
+	DestinationID   *uint  `json:"destination_id,omitempty"`
+	WebhookURL      string `json:"webhook_url,omitempty"`
+	WebhookSecret   string `json:"webhook_secret,omitempty"`
+}
+
+// Service generates workspace owners' compliance reports and delivers them
+type Service struct {
+	db         *gorm.DB
+	uploader   Uploader
+	httpClient *http.Client
+	logger     *zap.Logger
+	now        func() time.Time
+}
+
+// NewService creates a new compliance report service
+func NewService(cfg config.ComplianceConfig, db *gorm.DB, logger *zap.Logger) *Service {
+	return &Service{
+		db:         db,
+		httpClient: &http.Client{Timeout: time.Duration(cfg.WebhookTimeout) * time.Second},
+		logger:     logger,
+		now:        time.Now,
+	}
+}
+
+// SetUploader enables delivering reports to buckets
+func (s *Service) SetUploader(uploader Uploader) {
+	s.uploader = uploader
+}
+
+// CreateReport schedules a compliance report of the user's workspace. Its
+// first run is one schedule period from now
+func (s *Service) CreateReport(ctx context.Context, userID uint, req ReportRequest) (*database.ComplianceReport, error) {
+	sections, err := templateSections(req.Template, req.Sections)
+	if err != nil {
+		return nil, err
+	}
+	if req.Format == "" {
+		req.Format = FormatJSON
+	}
+	if req.Format != FormatCSV && req.Format != FormatJSON {
+		return nil, fmt.Errorf("%w: unknown format %q", ErrInvalidReport, req.Format)
+	}
+	if _, ok := schedulePeriods[req.Schedule]; !ok {
+		return nil, fmt.Errorf("%w: unknown schedule %q", ErrInvalidReport, req.Schedule)
+	}
+
+	report := &database.ComplianceReport{
+		UserID:          userID,
+		Name:            req.Name,
+		Template:        req.Template,
+		Sections:        sections,
+		Format:          req.Format,
+		Schedule:        req.Schedule,
+		DestinationType: req.DestinationType,
+		Active:          true,
+		NextRunAt:       nextRun(req.Schedule, s.now()),
+	}
+
+	switch req.DestinationType {
+	case DestinationBucket:
+		if req.DestinationID == nil {
+			return nil, fmt.Errorf("%w: destination_id is required", ErrInvalidReport)
+		}
+		var count int64
+		if err := s.db.WithContext(ctx).Model(&automation.BackupDestination{}).
+			Where("id = ? AND user_id = ?", *req.DestinationID, fmt.Sprint(userID)).Count(&count).Error; err != nil {
+			return nil, fmt.Errorf("failed to check destination: %w", err)
+		}
+		if count == 0 {
+			return nil, fmt.Errorf("%w: unknown destination", ErrInvalidReport)
+		}
+		report.DestinationID = req.DestinationID
+	case DestinationWebhook:
+		parsed, err := url.Parse(req.WebhookURL)
+		if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+			return nil, fmt.Errorf("%w: webhook_url must be an http(s) URL", ErrInvalidReport)
+		}
+		report.WebhookURL = req.WebhookURL
+		report.WebhookSecret = req.WebhookSecret
+	default:
+		return nil, fmt.Errorf("%w: unknown destination type %q", ErrInvalidReport, req.DestinationType)
+	}
+
+	if err := s.db.WithContext(ctx).Create(report).Error; err != nil {
+		return nil, fmt.Errorf("failed to create compliance report: %w", err)
+	}
+	return report, nil
+}
+
+// ListReports returns the user's compliance reports
+func (s *Service) ListReports(ctx context.Context, userID uint) ([]database.ComplianceReport, error) {
+	var reports []database.ComplianceReport
+	if err := s.db.WithContext(ctx).Where("user_id = ?", userID).Order("id").Find(&reports).Error; err != nil {
+		return nil, fmt.Errorf("failed to list compliance reports: %w", err)
+	}
+	return reports, nil
+}
+
+// GetReport returns one of the user's compliance reports
+func (s *Service) GetReport(ctx context.Context, userID, id uint) (*database.ComplianceReport, error) {
+	var report database.ComplianceReport
+	if err := s.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&report).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrReportNotFound
+		}
+		return nil, fmt.Errorf("failed to get compliance report: %w", err)
+	}
+	return &report, nil
+}
+
+// DeleteReport stops and removes a compliance report; its runs are kept
+func (s *Service) DeleteReport(ctx context.Context, userID, id uint) error {
+	result := s.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&database.ComplianceReport{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete compliance report: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrReportNotFound
+	}
+	return nil
+}
+
+// ListRuns returns the latest runs of one of the user's reports, newest first
+func (s *Service) ListRuns(ctx context.Context, userID, id uint) ([]database.ComplianceRun, error) {
+	if _, err := s.GetReport(ctx, userID, id); err != nil {
+		return nil, err
+	}
+	var runs []database.ComplianceRun
+	if err := s.db.WithContext(ctx).Where("report_id = ?", id).Order("id DESC").Limit(50).Find(&runs).Error; err != nil {
+		return nil, fmt.Errorf("failed to list compliance runs: %w", err)
+	}
+	return runs, nil
+}
+
+// Preview generates the report for the period its next run would cover,
+// without delivering it
+func (s *Service) Preview(ctx context.Context, userID, id uint) (*Document, error) {
+	report, err := s.GetReport(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	end := s.now()
+	start, err := s.periodStart(ctx, report, end)
+	if err != nil {
+		return nil, err
+	}
+	return s.generate(ctx, report, start, end)
+}
+
+// RunReport generates and delivers one of the user's reports now, covering
+// the time since its last delivery. The schedule is left as it was
+func (s *Service) RunReport(ctx context.Context, userID, id uint) (*database.ComplianceRun, error) {
+	report, err := s.GetReport(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.run(ctx, report)
+}
+
+// RunDue generates and delivers the reports whose next run has come and
+// schedules their following run. It returns how many were delivered
+func (s *Service) RunDue(ctx context.Context) (int, error) {
+	var reports []database.ComplianceReport
+	if err := s.db.WithContext(ctx).Where("active = ? AND next_run_at <= ?", true, s.now()).
+		Order("next_run_at").Find(&reports).Error; err != nil {
+		return 0, fmt.Errorf("failed to find due compliance reports: %w", err)
+	}
+
+	delivered := 0
+	for i := range reports {
+		report := &reports[i]
+		run, err := s.run(ctx, report)
+		if err != nil {
+			return delivered, err
+		}
+		if run.Status == RunDelivered {
+			delivered++
+		} else {
+			s.logger.Warn("Compliance report delivery failed",
+				zap.Uint("report_id", report.ID), zap.String("error", run.Error))
+		}
+
+		// A failed period is covered by the next run, which starts at the
+		// last delivered one
+		if err := s.db.WithContext(ctx).Model(report).
+			Update("next_run_at", nextRun(report.Schedule, s.now())).Error; err != nil {
+			return delivered, fmt.Errorf("failed to schedule compliance report: %w", err)
+		}
+	}
+	return delivered, nil
+}
+
+// run generates a report for the period since its last delivery, delivers
+// it and records the run. Generation and delivery failures are recorded on
+// the run; only failing to record it is returned
+func (s *Service) run(ctx context.Context, report *database.ComplianceReport) (*database.ComplianceRun, error) {
+	end := s.now()
+	start, err := s.periodStart(ctx, report, end)
+	if err != nil {
+		return nil, err
+	}
+	run := &database.ComplianceRun{ReportID: report.ID, PeriodStart: start, PeriodEnd: end}
+
+	location, entries, err := s.generateAndDeliver(ctx, report, run.PeriodStart, end)
+	run.Entries = entries
+	if err != nil {
+		run.Status = RunFailed
+		run.Error = err.Error()
+	} else {
+		run.Status = RunDelivered
+		run.Location = location
+	}
+
+	if err := s.db.WithContext(ctx).Create(run).Error; err != nil {
+		return nil, fmt.Errorf("failed to record compliance run: %w", err)
+	}
+	if run.Status == RunDelivered {
+		report.LastRunAt = &end
+		if err := s.db.WithContext(ctx).Model(report).Update("last_run_at", end).Error; err != nil {
+			return nil, fmt.Errorf("failed to update compliance report: %w", err)
+		}
+	}
+	return run, nil
+}
+
+func (s *Service) generateAndDeliver(ctx context.Context, report *database.ComplianceReport, start, end time.Time) (string, int, error) {
+	document, err := s.generate(ctx, report, start, end)
+	if err != nil {
+		return "", 0, err
+	}
+	body, contentType, err := document.Render(report.Format)
+	if err != nil {
+		return "", document.Entries(), err
+	}
+	location, err := s.deliver(ctx, report, fileName(report, end), body, contentType)
+	return location, document.Entries(), err
+}
+
+// templateSections returns the sections of a report template
+func templateSections(template string, custom []string) ([]string, error) {
+	if template == TemplateCustom {
+		if len(custom) == 0 {
+			return nil, fmt.Errorf("%w: a custom report needs sections", ErrInvalidReport)
+		}
+		for _, section := range custom {
+			if !sectionNames[section] {
+				return nil, fmt.Errorf("%w: unknown section %q", ErrInvalidReport, section)
+			}
+		}
+		return custom, nil
+	}
+	for _, t := range Templates {
+		if t.Name == template {
+			return t.Sections, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: unknown template %q", ErrInvalidReport, template)
+}
+
+// schedulePeriods is how far apart each schedule's runs are, in days and months
+var schedulePeriods = map[string][2]int{
+	ScheduleDaily:   {1, 0},
+	ScheduleWeekly:  {7, 0},
+	ScheduleMonthly: {0, 1},
+}
+
+// nextRun returns when a report on the schedule runs after from
+func nextRun(schedule string, from time.Time) time.Time {
+	period := schedulePeriods[schedule]
+	return from.AddDate(0, period[1], period[0])
+}
+
+// periodStart returns where a report's run ending at end starts: at the end
+// of its last delivered period, else where its first failed run started, so
+// nothing goes unreported, else one schedule period back
+func (s *Service) periodStart(ctx context.Context, report *database.ComplianceReport, end time.Time) (time.Time, error) {
+	if report.LastRunAt != nil {
+		return *report.LastRunAt, nil
+	}
+
+	var first []database.ComplianceRun
+	if err := s.db.WithContext(ctx).Where("report_id = ?", report.ID).Order("id").Limit(1).Find(&first).Error; err != nil {
+		return time.Time{}, fmt.Errorf("failed to find compliance runs: %w", err)
+	}
+	if len(first) > 0 {
+		return first[0].PeriodStart, nil
+	}
+
+	period := schedulePeriods[report.Schedule]
+	return end.AddDate(0, -period[1], -period[0]), nil
+}
@@ -0,0 +1,364 @@
+package compliance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/internal/automation"
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/testfactory"
+)
+
+// fakeUploader records the files uploaded to each destination
+type fakeUploader struct {
+	mu       sync.Mutex
+	uploaded map[string]string
+}
+
+func (f *fakeUploader) UploadToDestination(ctx context.Context, userID string, destinationID uint, name string, body io.Reader, size int64) (string, error) {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return "", err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	location := fmt.Sprintf("s3://%s-%d/%s", userID, destinationID, name)
+	f.uploaded[location] = string(data)
+	return location, nil
+}
+
+// webhookRecorder is a webhook receiving reports
+type webhookRecorder struct {
+	*httptest.Server
+	mu       sync.Mutex
+	status   int
+	bodies   []string
+	requests []*http.Request
+}
+
+func newWebhookRecorder(t *testing.T) *webhookRecorder {
+	recorder := &webhookRecorder{status: http.StatusOK}
+	recorder.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		recorder.mu.Lock()
+		defer recorder.mu.Unlock()
+		recorder.bodies = append(recorder.bodies, string(body))
+		recorder.requests = append(recorder.requests, r)
+		w.WriteHeader(recorder.status)
+	}))
+	t.Cleanup(recorder.Close)
+	return recorder
+}
+
+type workspace struct {
+	owner, member, invitee, outsider *database.User
+	collection                       *database.Collection
+	share                            *database.CollectionShare
+	app                              *database.OAuthApp
+}
+
+// seedWorkspace creates an owner's shared collection with one accepted and
+// one pending collaborator, and activity between from and from+12h
+func seedWorkspace(t *testing.T, db *gorm.DB, from time.Time) workspace {
+	factory := testfactory.New(t, db)
+	w := workspace{owner: factory.User(), member: factory.User(), invitee: factory.User(), outsider: factory.User()}
+	w.collection = factory.Collection(w.owner.ID)
+	w.share = factory.Share(w.collection)
+
+	accepted := from.Add(2 * time.Hour)
+	require.NoError(t, db.Create(&[]database.CollectionCollaborator{
+		{CollectionID: w.collection.ID, UserID: w.member.ID, InviterID: w.owner.ID, Permission: "edit", Status: "accepted", InvitedAt: from.Add(time.Hour), AcceptedAt: &accepted},
+		{CollectionID: w.collection.ID, UserID: w.invitee.ID, InviterID: w.owner.ID, Permission: "view", Status: "pending", InvitedAt: from.Add(3 * time.Hour)},
+	}).Error)
+
+	// Visits to the share, one before the period
+	for _, at := range []time.Time{from.Add(-time.Hour), from.Add(4 * time.Hour)} {
+		activity := database.ShareActivity{ShareID: w.share.ID, ActivityType: "view", IPAddress: "203.0.113.0", UserAgent: "curl/8"}
+		activity.CreatedAt = at
+		require.NoError(t, db.Create(&activity).Error)
+	}
+	outsiderShare := factory.Share(factory.Collection(w.outsider.ID))
+	require.NoError(t, db.Create(&database.ShareActivity{ShareID: outsiderShare.ID, ActivityType: "view"}).Error)
+
+	// Bulk operations: the member's deletion counts, an export and an outsider's deletion don't
+	for _, op := range []automation.BulkOperation{
+		{UserID: fmt.Sprint(w.member.ID), Type: "delete", Status: "completed", TotalItems: 40, ProcessedItems: 40, CreatedAt: from.Add(5 * time.Hour)},
+		{UserID: fmt.Sprint(w.owner.ID), Type: "export", Status: "completed", CreatedAt: from.Add(5 * time.Hour)},
+		{UserID: fmt.Sprint(w.outsider.ID), Type: "delete", Status: "completed", CreatedAt: from.Add(5 * time.Hour)},
+	} {
+		require.NoError(t, db.Create(&op).Error)
+	}
+
+	// The member authorizes an app whose rotated tokens are both used
+	w.app = &database.OAuthApp{OwnerID: w.outsider.ID, Name: "Reader", ClientID: "client", ClientSecretHash: "hash", WebhookSecret: "secret"}
+	require.NoError(t, db.Create(w.app).Error)
+	grant := database.OAuthGrant{UserID: w.member.ID, AppID: w.app.ID, Scopes: automation.StringSlice{"bookmarks:read"}, CreatedAt: from.Add(6 * time.Hour)}
+	require.NoError(t, db.Create(&grant).Error)
+	for i, used := range []time.Time{from.Add(7 * time.Hour), from.Add(8 * time.Hour)} {
+		used := used
+		require.NoError(t, db.Create(&database.OAuthToken{
+			AppID: w.app.ID, UserID: w.member.ID, GrantID: grant.ID,
+			AccessTokenHash: fmt.Sprintf("access-%d", i), RefreshTokenHash: fmt.Sprintf("refresh-%d", i),
+			ExpiresAt: used.Add(time.Hour), RefreshExpiresAt: used.Add(24 * time.Hour), LastUsedAt: &used,
+		}).Error)
+	}
+	return w
+}
+
+func setupService(t *testing.T) (*Service, *gorm.DB, *time.Time) {
+	db := testfactory.NewDB(t)
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	service := NewService(config.ComplianceConfig{WebhookTimeout: 5}, db, zap.NewNop())
+	service.now = func() time.Time { return now }
+	return service, db, &now
+}
+
+func TestService_RunDue(t *testing.T) {
+	service, db, now := setupService(t)
+	ctx := context.Background()
+	hook := newWebhookRecorder(t)
+
+	// Given: A daily CSV report of every section, created a day before the activity
+	w := seedWorkspace(t, db, now.AddDate(0, 0, -1))
+	*now = now.AddDate(0, 0, -1)
+	report, err := service.CreateReport(ctx, w.owner.ID, ReportRequest{
+		Name: "Daily audit", Template: "full", Format: FormatCSV, Schedule: ScheduleDaily,
+		DestinationType: DestinationWebhook, WebhookURL: hook.URL, WebhookSecret: "s3cret",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, now.AddDate(0, 0, 1), report.NextRunAt)
+
+	delivered, err := service.RunDue(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, delivered, "nothing is due before the first period ends")
+
+	// When: The worker runs once the day is over
+	*now = now.AddDate(0, 0, 1)
+	delivered, err = service.RunDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, delivered)
+
+	// Then: The webhook got a signed CSV of the workspace's activity only
+	require.Len(t, hook.bodies, 1)
+	body, request := hook.bodies[0], hook.requests[0]
+	assert.Equal(t, "text/csv", request.Header.Get("Content-Type"))
+	assert.Equal(t, fmt.Sprint(report.ID), request.Header.Get(HeaderReportID))
+	assert.Equal(t, sign([]byte(body), "s3cret"), request.Header.Get(HeaderSignature))
+
+	lines := strings.Split(strings.TrimSpace(body), "\n")
+	assert.Equal(t, strings.Join(csvHeader, ","), lines[0])
+	assert.Equal(t, []string{
+		fmt.Sprintf("bulk_deletions,2026-05-31T05:00:00Z,%d,bulk_delete,bulk_operation/1,,,status=completed items=40 processed=40 failed=0", w.member.ID),
+		fmt.Sprintf("collaborator_changes,2026-05-31T01:00:00Z,%d,invited,collection/%d,,,user_id=%d permission=edit", w.owner.ID, w.collection.ID, w.member.ID),
+		fmt.Sprintf("collaborator_changes,2026-05-31T02:00:00Z,%d,accepted,collection/%d,,,user_id=%d permission=edit", w.member.ID, w.collection.ID, w.member.ID),
+		fmt.Sprintf("collaborator_changes,2026-05-31T03:00:00Z,%d,invited,collection/%d,,,user_id=%d permission=view", w.owner.ID, w.collection.ID, w.invitee.ID),
+		fmt.Sprintf("share_access,2026-05-31T04:00:00Z,,view,share/%d,203.0.113.0,curl/8,collection_id=%d", w.share.ID, w.collection.ID),
+		fmt.Sprintf(`token_usage,2026-05-31T06:00:00Z,%d,app_authorized,app/%d,,,"app=""Reader"" scopes=bookmarks:read"`, w.member.ID, w.app.ID),
+		fmt.Sprintf(`token_usage,2026-05-31T08:00:00Z,%d,token_used,app/%d,,,"app=""Reader"" tokens=2"`, w.member.ID, w.app.ID),
+	}, lines[1:])
+
+	// And: The run is recorded and the next one scheduled
+	runs, err := service.ListRuns(ctx, w.owner.ID, report.ID)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, RunDelivered, runs[0].Status)
+	assert.Equal(t, 7, runs[0].Entries)
+	assert.Equal(t, hook.URL, runs[0].Location)
+	assert.True(t, runs[0].PeriodStart.Equal(now.AddDate(0, 0, -1)))
+
+	stored, err := service.GetReport(ctx, w.owner.ID, report.ID)
+	require.NoError(t, err)
+	assert.True(t, stored.NextRunAt.Equal(now.AddDate(0, 0, 1)))
+	require.NotNil(t, stored.LastRunAt)
+	assert.True(t, stored.LastRunAt.Equal(*now))
+
+	delivered, err = service.RunDue(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, delivered)
+}
+
+func TestService_FailedDeliveryIsCoveredByTheNextRun(t *testing.T) {
+	service, db, now := setupService(t)
+	ctx := context.Background()
+	hook := newWebhookRecorder(t)
+	hook.status = http.StatusBadGateway
+
+	w := seedWorkspace(t, db, *now)
+	report, err := service.CreateReport(ctx, w.owner.ID, ReportRequest{
+		Name: "Deletions", Template: "data_deletion", Schedule: ScheduleDaily,
+		DestinationType: DestinationWebhook, WebhookURL: hook.URL,
+	})
+	require.NoError(t, err)
+
+	// When: The first delivery fails
+	*now = now.AddDate(0, 0, 1)
+	delivered, err := service.RunDue(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, delivered)
+
+	runs, err := service.ListRuns(ctx, w.owner.ID, report.ID)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, RunFailed, runs[0].Status)
+	assert.Contains(t, runs[0].Error, "status 502")
+	assert.Empty(t, hook.requests[0].Header.Get(HeaderSignature), "reports without a secret aren't signed")
+
+	// Then: The next run reports both days as JSON
+	hook.status = http.StatusOK
+	*now = now.AddDate(0, 0, 1)
+	delivered, err = service.RunDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, delivered)
+
+	var document Document
+	require.NoError(t, json.Unmarshal([]byte(hook.bodies[1]), &document))
+	assert.True(t, document.PeriodStart.Equal(now.AddDate(0, 0, -2)))
+	assert.Equal(t, []uint{w.owner.ID, w.member.ID}, document.Members)
+	require.Len(t, document.Sections[SectionBulkDeletions], 1)
+	assert.NotContains(t, document.Sections, SectionShareAccess)
+}
+
+func TestService_BucketDelivery(t *testing.T) {
+	service, db, now := setupService(t)
+	ctx := context.Background()
+	w := seedWorkspace(t, db, *now)
+
+	destination := automation.BackupDestination{UserID: fmt.Sprint(w.owner.ID), Name: "Audit bucket", Type: "s3", Bucket: "audit", Active: true}
+	require.NoError(t, db.Create(&destination).Error)
+	report, err := service.CreateReport(ctx, w.owner.ID, ReportRequest{
+		Name: "Access", Template: TemplateCustom, Sections: []string{SectionShareAccess}, Format: FormatJSON,
+		Schedule: ScheduleWeekly, DestinationType: DestinationBucket, DestinationID: &destination.ID,
+	})
+	require.NoError(t, err)
+
+	// Without an uploader the run fails
+	run, err := service.RunReport(ctx, w.owner.ID, report.ID)
+	require.NoError(t, err)
+	assert.Equal(t, RunFailed, run.Status)
+	assert.Equal(t, ErrBucketDeliveryDisabled.Error(), run.Error)
+
+	uploader := &fakeUploader{uploaded: map[string]string{}}
+	service.SetUploader(uploader)
+	*now = now.Add(12 * time.Hour)
+	run, err = service.RunReport(ctx, w.owner.ID, report.ID)
+	require.NoError(t, err)
+	assert.Equal(t, RunDelivered, run.Status)
+	assert.Equal(t, 2, run.Entries, "both visits of the week since the failed run")
+
+	location := fmt.Sprintf("s3://%d-%d/compliance/compliance-%d_20260601T120000Z.json", w.owner.ID, destination.ID, report.ID)
+	assert.Equal(t, location, run.Location)
+	assert.Contains(t, uploader.uploaded[location], `"ip_address": "203.0.113.0"`)
+
+	stored, err := service.GetReport(ctx, w.owner.ID, report.ID)
+	require.NoError(t, err)
+	assert.True(t, stored.NextRunAt.Equal(report.NextRunAt), "manual runs keep the schedule")
+}
+
+func TestService_CreateReport_Validation(t *testing.T) {
+	service, db, _ := setupService(t)
+	owner := testfactory.New(t, db).User()
+	otherDestination := automation.BackupDestination{UserID: "999", Name: "Not mine", Type: "s3", Bucket: "b", Active: true}
+	require.NoError(t, db.Create(&otherDestination).Error)
+
+	valid := ReportRequest{Name: "Audit", Template: "full", Schedule: ScheduleMonthly, DestinationType: DestinationWebhook, WebhookURL: "https://siem.example.com/ingest"}
+	tests := []struct {
+		name   string
+		change func(*ReportRequest)
+	}{
+		{"unknown template", func(r *ReportRequest) { r.Template = "everything" }},
+		{"custom without sections", func(r *ReportRequest) { r.Template = TemplateCustom }},
+		{"unknown section", func(r *ReportRequest) { r.Template = TemplateCustom; r.Sections = []string{"logins"} }},
+		{"unknown format", func(r *ReportRequest) { r.Format = "xlsx" }},
+		{"unknown schedule", func(r *ReportRequest) { r.Schedule = "hourly" }},
+		{"webhook without URL", func(r *ReportRequest) { r.WebhookURL = "" }},
+		{"webhook to another scheme", func(r *ReportRequest) { r.WebhookURL = "ftp://siem.example.com" }},
+		{"bucket without destination", func(r *ReportRequest) { r.DestinationType = DestinationBucket }},
+		{"another user's bucket", func(r *ReportRequest) { r.DestinationType = DestinationBucket; r.DestinationID = &otherDestination.ID }},
+		{"unknown destination type", func(r *ReportRequest) { r.DestinationType = "email" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := valid
+			tt.change(&req)
+			_, err := service.CreateReport(context.Background(), owner.ID, req)
+			assert.ErrorIs(t, err, ErrInvalidReport)
+		})
+	}
+
+	report, err := service.CreateReport(context.Background(), owner.ID, valid)
+	require.NoError(t, err)
+	assert.Equal(t, FormatJSON, report.Format)
+	assert.Equal(t, automation.StringSlice{SectionShareAccess, SectionCollaboratorChanges, SectionBulkDeletions, SectionTokenUsage}, report.Sections)
+}
+
+func TestHandler_Reports(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service, db, now := setupService(t)
+	w := seedWorkspace(t, db, now.AddDate(0, 0, -1))
+
+	router := gin.New()
+	userID := fmt.Sprint(w.owner.ID)
+	router.Use(func(c *gin.Context) { c.Set("user_id", userID) })
+	NewHandler(service).RegisterRoutes(router.Group("/api/v1"))
+
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/v1"+path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := request(http.MethodPost, "/compliance/reports", `{"name":"Review","template":"access_review","schedule":"weekly","destination_type":"webhook","webhook_url":"https://siem.example.com","webhook_secret":"s3cret"}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "s3cret")
+	var created struct {
+		Data database.ComplianceReport `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+
+	rec = request(http.MethodPost, "/compliance/reports", `{"name":"Bad","template":"full","schedule":"hourly","destination_type":"webhook","webhook_url":"https://siem.example.com"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// The preview covers the week before the first run without delivering it
+	rec = request(http.MethodGet, fmt.Sprintf("/compliance/reports/%d/preview", created.Data.ID), "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var preview struct {
+		Data Document `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &preview))
+	assert.Len(t, preview.Data.Sections[SectionCollaboratorChanges], 3)
+	assert.Len(t, preview.Data.Sections[SectionShareAccess], 2)
+
+	rec = request(http.MethodGet, "/compliance/templates", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"access_review"`)
+
+	// Other users' reports are not found
+	userID = fmt.Sprint(w.member.ID)
+	rec = request(http.MethodGet, fmt.Sprintf("/compliance/reports/%d", created.Data.ID), "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	rec = request(http.MethodDelete, fmt.Sprintf("/compliance/reports/%d", created.Data.ID), "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	userID = fmt.Sprint(w.owner.ID)
+	rec = request(http.MethodDelete, fmt.Sprintf("/compliance/reports/%d", created.Data.ID), "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = request(http.MethodGet, "/compliance/reports", "")
+	assert.Contains(t, rec.Body.String(), `"reports":[]`)
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"strings"
 
 	"github.com/spf13/viper"
@@ -9,14 +10,54 @@ import (
 
 // Config holds all configuration for the application
 type Config struct {
-	Server   ServerConfig   `mapstructure:"server"`
-	Database DatabaseConfig `mapstructure:"database"`
-	Redis    RedisConfig    `mapstructure:"redis"`
-	Supabase SupabaseConfig `mapstructure:"supabase"`
-	Storage  StorageConfig  `mapstructure:"storage"`
-	Search   SearchConfig   `mapstructure:"search"`
-	JWT      JWTConfig      `mapstructure:"jwt"`
-	Logger   LoggerConfig   `mapstructure:"logger"`
+	Server      ServerConfig      `mapstructure:"server"`
+	Database    DatabaseConfig    `mapstructure:"database"`
+	Redis       RedisConfig       `mapstructure:"redis"`
+	Supabase    SupabaseConfig    `mapstructure:"supabase"`
+	Storage     StorageConfig     `mapstructure:"storage"`
+	Search      SearchConfig      `mapstructure:"search"`
+	JWT         JWTConfig         `mapstructure:"jwt"`
+	Auth        AuthConfig        `mapstructure:"auth"`
+	Logger      LoggerConfig      `mapstructure:"logger"`
+	Security    SecurityConfig    `mapstructure:"security"`
+	Maintenance MaintenanceConfig `mapstructure:"maintenance"`
+	Telemetry   TelemetryConfig   `mapstructure:"telemetry"`
+	SEO         SEOConfig         `mapstructure:"seo"`
+	Privacy     PrivacyConfig     `mapstructure:"privacy"`
+	Summarizer  SummarizerConfig  `mapstructure:"summarizer"`
+	Archive     ArchiveConfig     `mapstructure:"archive"`
+	WebSocket   WebSocketConfig   `mapstructure:"websocket"`
+	Abuse       AbuseConfig       `mapstructure:"abuse"`
+	Screenshot  ScreenshotConfig  `mapstructure:"screenshot"`
+	Favicon     FaviconConfig     `mapstructure:"favicon"`
+	Backfill    BackfillConfig    `mapstructure:"backfill"`
+	Migrations  MigrationsConfig  `mapstructure:"migrations"`
+	Counters    CountersConfig    `mapstructure:"counters"`
+	Worker      WorkerConfig      `mapstructure:"worker"`
+	Mail        MailConfig        `mapstructure:"mail"`
+	// Subscriptions are email subscriptions of visitors to public collections
+	Subscriptions SubscriptionsConfig `mapstructure:"subscriptions"`
+	// PublicProfile is the login-less API of a user's public bookmarks
+	PublicProfile PublicProfileConfig `mapstructure:"public_profile"`
+	Safety        SafetyConfig        `mapstructure:"safety"`
+	Retention     RetentionConfig     `mapstructure:"retention"`
+	StorageGC     StorageGCConfig     `mapstructure:"storage_gc"`
+	Compliance    ComplianceConfig    `mapstructure:"compliance"`
+	Calendar      CalendarConfig      `mapstructure:"calendar"`
+	Graph         GraphConfig         `mapstructure:"graph"`
+	Cleanup       CleanupConfig       `mapstructure:"cleanup"`
+	Quality       QualityConfig       `mapstructure:"quality"`
+	// Federation is the experimental ActivityPub support of public profiles
+	Federation FederationConfig `mapstructure:"federation"`
+	Onboarding OnboardingConfig `mapstructure:"onboarding"`
+	Demo       DemoConfig       `mapstructure:"demo"`
+	Merge      MergeConfig      `mapstructure:"merge"`
+	Webhooks   WebhooksConfig   `mapstructure:"webhooks"`
+	Automation AutomationConfig `mapstructure:"automation"`
+	Quota      QuotaConfig      `mapstructure:"quota"`
+	// Hooks are the external validators and enrichers called at hook
+	// points; being a list, they can only be set in the config file
+	Hooks []HookConfig `mapstructure:"hooks"`
 }
 
 type ServerConfig struct {
@@ -25,6 +66,15 @@ type ServerConfig struct {
 	ReadTimeout  int    `mapstructure:"read_timeout"`
 	WriteTimeout int    `mapstructure:"write_timeout"`
 	Environment  string `mapstructure:"environment"`
+	BaseURL      string `mapstructure:"base_url"`
+	// MaxBodySize caps request bodies in bytes; upload routes set their own
+	MaxBodySize int64 `mapstructure:"max_body_size"`
+	// TrustedProxies are the addresses or CIDRs whose forwarded headers name
+	// the client; requests from anywhere else are keyed on their own address
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
+	// MetricsToken is the bearer token Prometheus scrapes /metrics with;
+	// without one the endpoint refuses every request
+	MetricsToken string `mapstructure:"metrics_token"`
 }
 
 type DatabaseConfig struct {
@@ -59,12 +109,75 @@ type StorageConfig struct {
 	SecretAccessKey string `mapstructure:"secret_access_key"`
 	BucketName      string `mapstructure:"bucket_name"`
 	UseSSL          bool   `mapstructure:"use_ssl"`
+	// DownloadURLTTL is the lifetime in seconds of signed artifact download links
+	DownloadURLTTL int `mapstructure:"download_url_ttl"`
+	// DownloadSigningKey signs those links; the API and the worker must share
+	// it. When empty each process signs with a random key of its own
+	DownloadSigningKey string `mapstructure:"download_signing_key"`
+	// UploadEventToken authenticates bucket notifications of uploaded import files
+	UploadEventToken string `mapstructure:"upload_event_token"`
 }
 
 type SearchConfig struct {
-	Host   string `mapstructure:"host"`
-	Port   string `mapstructure:"port"`
-	APIKey string `mapstructure:"api_key"`
+	Host     string `mapstructure:"host"`
+	Port     string `mapstructure:"port"`
+	APIKey   string `mapstructure:"api_key"`
+	CacheTTL int    `mapstructure:"cache_ttl"` // seconds Typesense caches results, 0 disables it
+
+	// Warm-up primes caches with the common queries of recently active users
+	WarmupOnStart    bool `mapstructure:"warmup_on_start"`
+	WarmupUsers      int  `mapstructure:"warmup_users"`       // most recently active users warmed
+	WarmupQueries    int  `mapstructure:"warmup_queries"`     // most common queries per user
+	WarmupActiveDays int  `mapstructure:"warmup_active_days"` // how recently a user must have saved a bookmark
+}
+
+// Auth providers
+const (
+	AuthProviderSupabase = "supabase"
+	AuthProviderOIDC     = "oidc"
+	AuthProviderLocal    = "local"
+)
+
+// AuthConfig selects who verifies user credentials. The API issues its own
+// tokens whichever provider signed the user in
+type AuthConfig struct {
+	// Provider is supabase, oidc (Keycloak, Authentik, ...) or local accounts
+	// with passwords stored by the API
+	Provider string     `mapstructure:"provider"`
+	OIDC     OIDCConfig `mapstructure:"oidc"`
+}
+
+// OIDCConfig configures a generic OpenID Connect provider. Its tokens are
+// accepted as bearer tokens and can be exchanged for API tokens
+type OIDCConfig struct {
+	IssuerURL string `mapstructure:"issuer_url"`
+	ClientID  string `mapstructure:"client_id"` // required audience of tokens
+	// Claims mapped to the user's profile
+	EmailClaim    string `mapstructure:"email_claim"`
+	UsernameClaim string `mapstructure:"username_claim"`
+	NameClaim     string `mapstructure:"name_claim"`
+	AvatarClaim   string `mapstructure:"avatar_claim"`
+	// LinkByEmail signs a provider user into the existing account with the
+	// same verified email, e.g. after moving off Supabase
+	LinkByEmail bool `mapstructure:"link_by_email"`
+}
+
+// Validate rejects unknown providers and incomplete OIDC settings
+func (c AuthConfig) Validate() error {
+	switch c.Provider {
+	case AuthProviderSupabase, AuthProviderLocal:
+		return nil
+	case AuthProviderOIDC:
+		if c.OIDC.IssuerURL == "" || c.OIDC.ClientID == "" {
+			return fmt.Errorf("oidc provider needs an issuer url and a client id")
+		}
+		if _, err := url.ParseRequestURI(c.OIDC.IssuerURL); err != nil {
+			return fmt.Errorf("oidc issuer url %q is invalid", c.OIDC.IssuerURL)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown auth provider %q", c.Provider)
+	}
 }
 
 type JWTConfig struct {
@@ -72,6 +185,433 @@ type JWTConfig struct {
 	ExpiryHour int    `mapstructure:"expiry_hour"`
 }
 
+type SecurityConfig struct {
+	// EncryptionKey encrypts user supplied credentials stored in the database
+	EncryptionKey string `mapstructure:"encryption_key"`
+	// AdminEmails lists the accounts allowed to use the admin API
+	AdminEmails []string `mapstructure:"admin_emails"`
+	// AllowedOrigins lists the browser origins allowed to call the API: "*",
+	// an exact origin, or a subdomain wildcard such as https://*.example.com
+	AllowedOrigins []string `mapstructure:"allowed_origins"`
+	// HSTSMaxAge is the Strict-Transport-Security max-age in seconds sent on
+	// HTTPS requests; 0 disables the header
+	HSTSMaxAge int `mapstructure:"hsts_max_age"`
+	// PageCSP is the Content-Security-Policy of server-rendered pages such as
+	// collection embeds. API responses always use a deny-all policy
+	PageCSP string `mapstructure:"page_csp"`
+}
+
+// Validate rejects CORS allowlists and policies that would be silently
+// ignored or misread by browsers
+func (c SecurityConfig) Validate() error {
+	for _, origin := range c.AllowedOrigins {
+		if origin == "*" {
+			if len(c.AllowedOrigins) > 1 {
+				return fmt.Errorf("allowed origin \"*\" cannot be combined with other origins")
+			}
+			continue
+		}
+		if err := ValidateOrigin(origin); err != nil {
+			return err
+		}
+	}
+	if c.HSTSMaxAge < 0 {
+		return fmt.Errorf("hsts max age must not be negative")
+	}
+	if strings.ContainsAny(c.PageCSP, "\r\n") {
+		return fmt.Errorf("page csp must be a single line")
+	}
+	return nil
+}
+
+// originHostChars are the characters an origin's host[:port] may use
+const originHostChars = "abcdefghijklmnopqrstuvwxyz0123456789.-:[]*"
+
+// ValidateOrigin checks that an allowlist entry is a bare scheme://host[:port],
+// optionally with a leading "*." subdomain wildcard. Entries end up in
+// headers such as the CSP, so the host may only use hostname characters
+func ValidateOrigin(origin string) error {
+	parsed, err := url.Parse(origin)
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		return fmt.Errorf("allowed origin %q must look like https://example.com", origin)
+	}
+	if parsed.Path != "" || parsed.RawQuery != "" || parsed.Fragment != "" || parsed.User != nil {
+		return fmt.Errorf("allowed origin %q must not have a path, query or credentials", origin)
+	}
+	host := strings.TrimPrefix(parsed.Hostname(), "*.")
+	if host == "" || strings.Contains(host, "*") {
+		return fmt.Errorf("allowed origin %q may only use a wildcard as its first label", origin)
+	}
+	if strings.IndexFunc(strings.ToLower(parsed.Host), func(r rune) bool {
+		return !strings.ContainsRune(originHostChars, r)
+	}) >= 0 {
+		return fmt.Errorf("allowed origin %q has an invalid host", origin)
+	}
+	return nil
+}
+
+type MaintenanceConfig struct {
+	// Enabled forces maintenance mode regardless of the toggle stored in Redis
+	Enabled    bool   `mapstructure:"enabled"`
+	Message    string `mapstructure:"message"`
+	RetryAfter int    `mapstructure:"retry_after"` // seconds
+}
+
+type TelemetryConfig struct {
+	// Enabled opts the instance in to anonymous usage reporting. When false
+	// nothing is ever sent; the preview endpoint still shows the report
+	Enabled  bool   `mapstructure:"enabled"`
+	Endpoint string `mapstructure:"endpoint"`
+	Interval int    `mapstructure:"interval"` // hours
+}
+
+type SEOConfig struct {
+	// Enabled publishes sitemap.xml and lets crawlers index public shares.
+	// When false robots.txt asks crawlers to stay away from the instance
+	Enabled         bool     `mapstructure:"enabled"`
+	RefreshInterval int      `mapstructure:"refresh_interval"` // minutes
+	Disallow        []string `mapstructure:"disallow"`         // extra robots.txt disallowed paths
+	CrawlDelay      int      `mapstructure:"crawl_delay"`      // seconds, 0 omits the directive
+}
+
+type PrivacyConfig struct {
+	// ComplianceMode truncates share viewer IP addresses to their /24 (IPv4)
+	// or /48 (IPv6) network and keeps user agents only as aggregate counts
+	ComplianceMode bool `mapstructure:"compliance_mode"`
+	// ActivityRetentionDays is the former share activity retention setting.
+	// When set it overrides Retention.ShareActivities
+	ActivityRetentionDays int `mapstructure:"activity_retention_days"`
+	// TrendingMinParticipants keeps bookmarks out of community trending
+	// until that many distinct users interacted with them, so trending on a
+	// small instance does not show what one person did; 0 disables it
+	TrendingMinParticipants int `mapstructure:"trending_min_participants"`
+	// TrendingNoise is the scale of the Laplace noise added to trending
+	// counts; 0 publishes exact counts
+	TrendingNoise float64 `mapstructure:"trending_noise"`
+}
+
+type SummarizerConfig struct {
+	// Endpoint is the HTTP summarization provider. Bookmarks are posted to it
+	// as JSON and it answers with {"summary": "..."}; empty disables summaries
+	Endpoint string `mapstructure:"endpoint"`
+	APIKey   string `mapstructure:"api_key"` // sent as a bearer token when set
+	Timeout  int    `mapstructure:"timeout"` // seconds
+	// MaxLength caps stored summaries in characters and is passed to the
+	// provider as a hint
+	MaxLength int `mapstructure:"max_length"`
+	// OnExtract also summarizes pages during metadata extraction, not only
+	// when a summary is requested
+	OnExtract bool `mapstructure:"on_extract"`
+}
+
+type ArchiveConfig struct {
+	// RecaptureInterval re-captures archived bookmarks whose latest snapshot
+	// is older than this many hours. 0 disables periodic re-capture
+	RecaptureInterval int `mapstructure:"recapture_interval"`
+	// ChangeThreshold is the fraction of lines (0-1) that must differ between
+	// two snapshots before the owner is alerted to a content change
+	ChangeThreshold float64 `mapstructure:"change_threshold"`
+	MaxVersions     int     `mapstructure:"max_versions"` // snapshots kept per bookmark
+}
+
+type WebSocketConfig struct {
+	SendBuffer int `mapstructure:"send_buffer"` // messages queued per client
+	// OverflowPolicy is what happens when a client's queue is full:
+	// drop_oldest discards its oldest queued message, disconnect closes it
+	OverflowPolicy string `mapstructure:"overflow_policy"`
+	// CoalesceWindow holds sync events for this many milliseconds so rapid
+	// changes to one resource reach clients once. 0 sends every event
+	CoalesceWindow int `mapstructure:"coalesce_window"`
+	// LiveQueryLimit is how many searches or filters one connection may
+	// subscribe to for pushed matches
+	LiveQueryLimit int `mapstructure:"live_query_limit"`
+}
+
+type AbuseConfig struct {
+	// Enabled turns on request budgets for the login-less share, feed and
+	// embed endpoints
+	Enabled bool `mapstructure:"enabled"`
+	// RequestsPerMinute is the budget of one client IP across those
+	// endpoints. Past it requests are slowed down; past twice it the IP is
+	// blocked
+	RequestsPerMinute int `mapstructure:"requests_per_minute"`
+	TarpitDelay       int `mapstructure:"tarpit_delay"` // milliseconds
+	// InvalidTokensPerHour blocks an IP requesting this many unknown share
+	// tokens, which looks like token enumeration
+	InvalidTokensPerHour int `mapstructure:"invalid_tokens_per_hour"`
+	BlockDuration        int `mapstructure:"block_duration"` // minutes
+}
+
+type ScreenshotConfig struct {
+	Workers   int `mapstructure:"workers"`    // concurrent captures
+	QueueSize int `mapstructure:"queue_size"` // capture jobs waiting per priority
+	// DomainRefreshesPerMinute caps on-demand re-captures of one domain so
+	// users cannot point the capture browser at a site in bulk
+	DomainRefreshesPerMinute int `mapstructure:"domain_refreshes_per_minute"`
+}
+
+// BackfillConfig controls the admin-started backfill capturing screenshots
+// and archives for existing bookmarks. It only runs between WindowStart and
+// WindowEnd (hours in Timezone, wrapping past midnight), and its captures
+// count against a global and a per-domain limit per minute
+type BackfillConfig struct {
+	Interval        int    `mapstructure:"interval"` // minutes between batches, 0 disables backfills
+	BatchSize       int    `mapstructure:"batch_size"`
+	WindowStart     int    `mapstructure:"window_start"`
+	WindowEnd       int    `mapstructure:"window_end"` // equal to WindowStart to run around the clock
+	Timezone        string `mapstructure:"timezone"`
+	PerMinute       int    `mapstructure:"per_minute"`        // bookmarks captured per minute, 0 for no limit
+	DomainPerMinute int    `mapstructure:"domain_per_minute"` // bookmarks of one domain captured per minute, 0 for no limit
+	// ScreenshotBytes is the expected size of a screenshot, and ArchiveBytes
+	// of an archive until some exist, for projecting the storage a backfill
+	// needs; StorageCostPerGB prices it per month
+	ScreenshotBytes  int64   `mapstructure:"screenshot_bytes"`
+	ArchiveBytes     int64   `mapstructure:"archive_bytes"`
+	StorageCostPerGB float64 `mapstructure:"storage_cost_per_gb"`
+}
+
+// FaviconConfig controls the background job that re-validates bookmark
+// favicons and looks for replacements of broken ones
+type FaviconConfig struct {
+	RefreshInterval int `mapstructure:"refresh_interval"` // minutes between runs, 0 disables the job
+	MaxAge          int `mapstructure:"max_age"`          // hours before a favicon is checked again
+	BatchSize       int `mapstructure:"batch_size"`       // bookmarks checked per run
+	// DomainChecksPerMinute caps favicon fetches from one site, for the job
+	// and manual refreshes together
+	DomainChecksPerMinute int `mapstructure:"domain_checks_per_minute"`
+}
+
+// SafetyConfig selects where bookmarked URLs are checked for malware and
+// phishing. The instance blocklist admins maintain is always consulted
+type SafetyConfig struct {
+	Provider string `mapstructure:"provider"` // none or safe_browsing
+	// SafeBrowsingURL is the base URL of a Google Safe Browsing v4
+	// compatible lookup API
+	SafeBrowsingURL    string `mapstructure:"safe_browsing_url"`
+	SafeBrowsingAPIKey string `mapstructure:"safe_browsing_api_key"`
+	CacheTTL           int    `mapstructure:"cache_ttl"` // minutes verdicts of the provider are cached
+	Timeout            int    `mapstructure:"timeout"`   // seconds per lookup
+}
+
+// RetentionConfig sets how many days each class of data is kept before the
+// cleanup worker deletes it; 0 keeps a class forever
+type RetentionConfig struct {
+	Interval  int  `mapstructure:"interval"`   // minutes between enforcement runs, 0 disables them
+	DryRun    bool `mapstructure:"dry_run"`    // only report what would be deleted
+	BatchSize int  `mapstructure:"batch_size"` // rows deleted per statement
+
+	UserBehaviors     int `mapstructure:"user_behaviors"`
+	WebhookDeliveries int `mapstructure:"webhook_deliveries"`
+	ShareActivities   int `mapstructure:"share_activities"` // raw activity; daily aggregates are kept
+	SyncEvents        int `mapstructure:"sync_events"`
+}
+
+// StorageGCConfig controls the garbage collector that deletes stored objects,
+// such as screenshots and backups, whose rows were purged
+type StorageGCConfig struct {
+	Interval    int  `mapstructure:"interval"`     // minutes between runs, 0 disables them
+	DryRun      bool `mapstructure:"dry_run"`      // only report orphans, never mark or delete them
+	GracePeriod int  `mapstructure:"grace_period"` // hours an orphan stays marked before it is deleted
+	MaxDeletes  int  `mapstructure:"max_deletes"`  // objects deleted per run
+}
+
+// ComplianceConfig controls the worker generating workspace owners'
+// scheduled compliance reports
+type ComplianceConfig struct {
+	Interval       int `mapstructure:"interval"`        // minutes between checks for due reports, 0 disables them
+	WebhookTimeout int `mapstructure:"webhook_timeout"` // seconds per delivery to a webhook
+}
+
+// CalendarConfig controls the worker aggregating bookmarks saved and read
+// per day for the calendar heat map
+type CalendarConfig struct {
+	Interval  int `mapstructure:"interval"`   // minutes between aggregations, 0 disables them
+	BatchSize int `mapstructure:"batch_size"` // new bookmarks and behaviors per transaction
+}
+
+// GraphConfig controls the worker precomputing the relationships between a
+// user's bookmarks for the bookmark graph
+type GraphConfig struct {
+	Interval      int `mapstructure:"interval"`       // minutes between refreshes, 0 disables them
+	BatchSize     int `mapstructure:"batch_size"`     // changed bookmarks and behaviors per transaction
+	Neighbors     int `mapstructure:"neighbors"`      // same domain and co-collection edges kept per bookmark
+	MaxNodes      int `mapstructure:"max_nodes"`      // bookmarks returned in one graph
+	CoVisitWindow int `mapstructure:"covisit_window"` // minutes between two visits for them to count as together
+}
+
+// CleanupConfig controls when bookmarks become spring-cleaning candidates
+// and the worker sending the quarterly cleanup reminders
+type CleanupConfig struct {
+	ReminderInterval int `mapstructure:"reminder_interval"` // minutes between checks for due reminders, 0 disables them
+	UnvisitedDays    int `mapstructure:"unvisited_days"`    // days after saving that a never visited bookmark is stale
+	UnreadDays       int `mapstructure:"unread_days"`       // days a reading-list item may go unopened
+	MaxCandidates    int `mapstructure:"max_candidates"`    // candidates listed per reason
+}
+
+// QualityConfig controls the worker scoring public bookmarks for spam. The
+// thresholds are defaults that admins can change at runtime
+type QualityConfig struct {
+	Interval       int `mapstructure:"interval"`         // minutes between scoring runs, 0 disables them
+	BatchSize      int `mapstructure:"batch_size"`       // bookmarks scored per run
+	SuppressBelow  int `mapstructure:"suppress_below"`   // scores below are hidden from public areas
+	ReviewBelow    int `mapstructure:"review_below"`     // scores below are queued for admin review
+	BurstCount     int `mapstructure:"burst_count"`      // bookmarks one account creates within BurstWindow that count as a burst
+	BurstWindow    int `mapstructure:"burst_window"`     // minutes
+	SpreadAccounts int `mapstructure:"spread_accounts"`  // new accounts saving the same URL within SpreadWindow that count as spreading it
+	SpreadWindow   int `mapstructure:"spread_window"`    // hours
+	NewAccountDays int `mapstructure:"new_account_days"` // accounts younger than this count towards spreading
+}
+
+// FederationConfig controls the experimental ActivityPub support that lets
+// other instances, e.g. Mastodon, follow users' public bookmarks
+type FederationConfig struct {
+	Enabled         bool `mapstructure:"enabled"`
+	DeliveryTimeout int  `mapstructure:"delivery_timeout"` // seconds per request to another instance
+	OutboxPageSize  int  `mapstructure:"outbox_page_size"`
+}
+
+// OnboardingConfig controls what new accounts start with. The checklist
+// steps themselves are managed by admins at runtime
+type OnboardingConfig struct {
+	SampleContent bool `mapstructure:"sample_content"` // seed sample bookmarks and a collection on signup
+}
+
+// DemoConfig controls the guest mode of a hosted demo, where anonymous
+// visitors get a sandbox account with sample data that the worker deletes
+// once it expires
+type DemoConfig struct {
+	Enabled         bool   `mapstructure:"enabled"`
+	TTL             int    `mapstructure:"ttl"`               // minutes a sandbox lives
+	SessionsPerHour int    `mapstructure:"sessions_per_hour"` // sandboxes one client IP may start per hour
+	MaxSessions     int    `mapstructure:"max_sessions"`      // live sandboxes at once, 0 for no limit
+	Plan            string `mapstructure:"plan"`              // rate limit plan of sandboxes
+	CleanupInterval int    `mapstructure:"cleanup_interval"`  // minutes between worker cleanups, 0 disables them
+}
+
+// MergeConfig controls account merges. A merge a user starts from the
+// account to give up waits ConfirmTTL for them to confirm it from the
+// account to keep; the worker then runs it
+type MergeConfig struct {
+	Interval   int `mapstructure:"interval"`    // minutes between worker runs, 0 disables merges
+	ConfirmTTL int `mapstructure:"confirm_ttl"` // hours
+}
+
+// MigrationsConfig holds the rollout stage of each dual-write schema
+// migration: off, dual_write, shadow or cutover
+type MigrationsConfig struct {
+	TagsMode string `mapstructure:"tags_mode"` // JSON tags column to relational tags
+}
+
+// CountersConfig controls the job that recounts bookmark social counters
+type CountersConfig struct {
+	ReconcileInterval int  `mapstructure:"reconcile_interval"` // minutes
+	BatchSize         int  `mapstructure:"batch_size"`         // bookmarks per transaction
+	Fix               bool `mapstructure:"fix"`                // false only logs and reports drift
+}
+
+type WorkerConfig struct {
+	MetricsAddr string `mapstructure:"metrics_addr"` // Prometheus listener of the worker process, empty to disable
+}
+
+// MailConfig is the SMTP relay outgoing email is sent through. Without a
+// host emails are logged instead of sent
+type MailConfig struct {
+	Host     string `mapstructure:"host"`
+	Port     int    `mapstructure:"port"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	From     string `mapstructure:"from"`
+}
+
+type SubscriptionsConfig struct {
+	DigestInterval int `mapstructure:"digest_interval"` // minutes between update emails to a subscriber, 0 disables them
+	// ConfirmCooldown is how long, in minutes, a repeated subscribe request
+	// for the same address is ignored instead of sending another confirmation
+	ConfirmCooldown int `mapstructure:"confirm_cooldown"`
+	ConfirmTTL      int `mapstructure:"confirm_ttl"` // hours a confirmation link stays valid
+}
+
+// PublicProfileConfig controls the public bookmarks API personal websites
+// read without a token
+type PublicProfileConfig struct {
+	CacheTTL    int `mapstructure:"cache_ttl"`     // seconds a page of bookmarks is cached
+	MaxPageSize int `mapstructure:"max_page_size"` // bookmarks per page at most
+}
+
+// WebhooksConfig bounds outgoing webhook deliveries, so one large account
+// can't flood a slow receiver or use up the process's connections
+type WebhooksConfig struct {
+	MaxConcurrency      int `mapstructure:"max_concurrency"`      // deliveries in flight across all endpoints, 0 for no limit
+	EndpointConcurrency int `mapstructure:"endpoint_concurrency"` // deliveries in flight to one endpoint, 0 for no limit
+	// BreakerTimeouts is how many timeouts in a row open an endpoint's
+	// circuit; its deliveries are then skipped for BreakerCooldown seconds
+	BreakerTimeouts int `mapstructure:"breaker_timeouts"`
+	BreakerCooldown int `mapstructure:"breaker_cooldown"`
+	// ThresholdInterval is how often the worker checks bookmark views and
+	// visits against the thresholds of endpoints and rules, in minutes, 0 to
+	// disable the threshold events
+	ThresholdInterval int `mapstructure:"threshold_interval"`
+	// RuleScheduleInterval is how often the worker runs the scheduled
+	// automation rules that are due, in minutes, 0 to disable them
+	RuleScheduleInterval int `mapstructure:"rule_schedule_interval"`
+	// DisableAfterFailures disables an endpoint after this many failed
+	// deliveries in a row, and DisableAfterDays after failing that many days
+	// without a success; the owner is notified. 0 disables either check
+	DisableAfterFailures int `mapstructure:"disable_after_failures"`
+	DisableAfterDays     int `mapstructure:"disable_after_days"`
+	// HealthWindowDays is the period an endpoint's success rate covers
+	HealthWindowDays int `mapstructure:"health_window_days"`
+}
+
+// AutomationConfig configures bulk operations and integrations
+type AutomationConfig struct {
+	// ExportDir is where export operations write their files, the system
+	// temporary directory when empty
+	ExportDir string `mapstructure:"export_dir"`
+	// IntegrationAlertAfter and IntegrationPauseAfter are the failed syncs in
+	// a row after which an integration's owner is alerted and its scheduled
+	// syncs pause, 0 to never alert or pause
+	IntegrationAlertAfter int `mapstructure:"integration_alert_after"`
+	IntegrationPauseAfter int `mapstructure:"integration_pause_after"`
+}
+
+// QuotaConfig sets the soft rate limits of signed-in users: token buckets
+// whose burst and sustained rate depend on the user's plan. API requests
+// over budget are held for up to MaxDelay before a 429; automation work
+// (bulk operations, backups) waits for up to QueueDelay instead
+type QuotaConfig struct {
+	Enabled     bool                 `mapstructure:"enabled"`
+	DefaultPlan string               `mapstructure:"default_plan"` // plan of users without one
+	AdminPlan   string               `mapstructure:"admin_plan"`   // plan of the admin accounts, empty to treat them like everyone else
+	MaxDelay    int                  `mapstructure:"max_delay"`    // milliseconds
+	QueueDelay  int                  `mapstructure:"queue_delay"`  // seconds
+	Plans       map[string]PlanQuota `mapstructure:"plans"`
+}
+
+// PlanQuota is the budget of one plan. Rates are tokens per minute, a rate
+// of 0 means no limit
+type PlanQuota struct {
+	APIBurst        int `mapstructure:"api_burst"`
+	APIRate         int `mapstructure:"api_rate"`
+	AutomationBurst int `mapstructure:"automation_burst"`
+	AutomationRate  int `mapstructure:"automation_rate"`
+}
+
+// HookConfig is one hook: an HTTP endpoint or a WASM module called at a
+// hook point, e.g. before_bookmark_create. A hook that fails or times out
+// is skipped under the "ignore" failure policy and fails the operation
+// under "reject"
+type HookConfig struct {
+	Name          string `mapstructure:"name"`
+	Point         string `mapstructure:"point"`
+	Type          string `mapstructure:"type"`           // http or wasm
+	URL           string `mapstructure:"url"`            // endpoint of http hooks
+	Module        string `mapstructure:"module"`         // path of wasm modules
+	Secret        string `mapstructure:"secret"`         // signs http requests, optional
+	Timeout       int    `mapstructure:"timeout"`        // milliseconds
+	FailurePolicy string `mapstructure:"failure_policy"` // ignore or reject
+}
+
 type LoggerConfig struct {
 	Level      string `mapstructure:"level"`
 	Format     string `mapstructure:"format"`
@@ -105,6 +645,17 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("error unmarshaling config: %w", err)
 	}
 
+	if config.Privacy.ActivityRetentionDays > 0 {
+		config.Retention.ShareActivities = config.Privacy.ActivityRetentionDays
+	}
+
+	if err := config.Security.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid security config: %w", err)
+	}
+	if err := config.Auth.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid auth config: %w", err)
+	}
+
 	return &config, nil
 }
 
@@ -115,6 +666,10 @@ func setDefaults() {
 	viper.SetDefault("server.read_timeout", 30)
 	viper.SetDefault("server.write_timeout", 30)
 	viper.SetDefault("server.environment", "development")
+	viper.SetDefault("server.base_url", "http://localhost:3000")
+	viper.SetDefault("server.max_body_size", 2<<20)
+	viper.SetDefault("server.trusted_proxies", []string{})
+	viper.SetDefault("server.metrics_token", "")
 
 	// Database defaults
 	viper.SetDefault("database.host", "localhost")
@@ -145,18 +700,252 @@ func setDefaults() {
 	viper.SetDefault("storage.secret_access_key", "minioadmin")
 	viper.SetDefault("storage.bucket_name", "bookmarks")
 	viper.SetDefault("storage.use_ssl", false)
+	viper.SetDefault("storage.download_url_ttl", 900)
+	viper.SetDefault("storage.download_signing_key", "")
+	viper.SetDefault("storage.upload_event_token", "")
 
 	// Search defaults (Typesense)
 	viper.SetDefault("search.host", "localhost")
 	viper.SetDefault("search.port", "8108")
 	viper.SetDefault("search.api_key", "xyz")
+	viper.SetDefault("search.cache_ttl", 0)
+	viper.SetDefault("search.warmup_on_start", false)
+	viper.SetDefault("search.warmup_users", 200)
+	viper.SetDefault("search.warmup_queries", 5)
+	viper.SetDefault("search.warmup_active_days", 14)
 
 	// JWT defaults
 	viper.SetDefault("jwt.secret", "your-secret-key")
 	viper.SetDefault("jwt.expiry_hour", 24)
 
+	// Auth provider defaults
+	viper.SetDefault("auth.provider", AuthProviderSupabase)
+	viper.SetDefault("auth.oidc.issuer_url", "")
+	viper.SetDefault("auth.oidc.client_id", "")
+	viper.SetDefault("auth.oidc.email_claim", "email")
+	viper.SetDefault("auth.oidc.username_claim", "preferred_username")
+	viper.SetDefault("auth.oidc.name_claim", "name")
+	viper.SetDefault("auth.oidc.avatar_claim", "picture")
+	viper.SetDefault("auth.oidc.link_by_email", false)
+
 	// Logger defaults
 	viper.SetDefault("logger.level", "info")
 	viper.SetDefault("logger.format", "json")
 	viper.SetDefault("logger.output_path", "stdout")
+
+	// Security defaults
+	viper.SetDefault("security.encryption_key", "your-encryption-key")
+	viper.SetDefault("security.admin_emails", []string{})
+	// Any origin may call the API with bearer tokens; list origins to allow credentials
+	viper.SetDefault("security.allowed_origins", []string{"*"})
+	// One year, the minimum accepted by browser preload lists
+	viper.SetDefault("security.hsts_max_age", 31536000)
+	// Embeds need their inline stylesheet and favicons from other sites
+	viper.SetDefault("security.page_csp", "default-src 'none'; style-src 'unsafe-inline'; img-src https: data:; base-uri 'none'; form-action 'none'")
+
+	// Maintenance defaults
+	viper.SetDefault("maintenance.enabled", false)
+	viper.SetDefault("maintenance.message", "The service is undergoing maintenance")
+	viper.SetDefault("maintenance.retry_after", 300)
+
+	// Telemetry defaults (opt-in)
+	viper.SetDefault("telemetry.enabled", false)
+	viper.SetDefault("telemetry.endpoint", "")
+	viper.SetDefault("telemetry.interval", 24)
+
+	// SEO defaults (public instances opt in to indexing)
+	viper.SetDefault("seo.enabled", false)
+	viper.SetDefault("seo.refresh_interval", 60)
+	viper.SetDefault("seo.disallow", []string{})
+	viper.SetDefault("seo.crawl_delay", 0)
+
+	// Privacy defaults (raw activity is stored as received and kept)
+	viper.SetDefault("privacy.compliance_mode", false)
+	viper.SetDefault("privacy.activity_retention_days", 0)
+	viper.SetDefault("privacy.trending_min_participants", 3)
+	viper.SetDefault("privacy.trending_noise", 0)
+
+	// Summarizer defaults (no provider configured)
+	viper.SetDefault("summarizer.endpoint", "")
+	viper.SetDefault("summarizer.api_key", "")
+	viper.SetDefault("summarizer.timeout", 30)
+	viper.SetDefault("summarizer.max_length", 500)
+	viper.SetDefault("summarizer.on_extract", false)
+
+	// Archive defaults (daily re-capture, alert when a fifth of the page changed)
+	viper.SetDefault("archive.recapture_interval", 24)
+	viper.SetDefault("archive.change_threshold", 0.2)
+	viper.SetDefault("archive.max_versions", 10)
+
+	// WebSocket defaults (slow clients lose their oldest messages)
+	viper.SetDefault("websocket.send_buffer", 256)
+	viper.SetDefault("websocket.overflow_policy", "drop_oldest")
+	viper.SetDefault("websocket.coalesce_window", 100)
+	viper.SetDefault("websocket.live_query_limit", 10)
+
+	// Public endpoint abuse defaults (a feed reader polls far less than once a second)
+	viper.SetDefault("abuse.enabled", true)
+	viper.SetDefault("abuse.requests_per_minute", 60)
+	viper.SetDefault("abuse.tarpit_delay", 2000)
+	viper.SetDefault("abuse.invalid_tokens_per_hour", 20)
+	viper.SetDefault("abuse.block_duration", 60)
+
+	// Screenshot capture defaults (a capture keeps a headless browser busy)
+	viper.SetDefault("screenshot.workers", 2)
+	viper.SetDefault("screenshot.queue_size", 100)
+	viper.SetDefault("screenshot.domain_refreshes_per_minute", 10)
+
+	// Media backfill defaults (nightly, a few captures per site at a time)
+	viper.SetDefault("backfill.interval", 1)
+	viper.SetDefault("backfill.batch_size", 50)
+	viper.SetDefault("backfill.window_start", 1)
+	viper.SetDefault("backfill.window_end", 6)
+	viper.SetDefault("backfill.timezone", "UTC")
+	viper.SetDefault("backfill.per_minute", 20)
+	viper.SetDefault("backfill.domain_per_minute", 2)
+	viper.SetDefault("backfill.screenshot_bytes", 200000)
+	viper.SetDefault("backfill.archive_bytes", 20000)
+	viper.SetDefault("backfill.storage_cost_per_gb", 0.023)
+
+	// Favicon refresh defaults (weekly re-validation, gentle on each site)
+	viper.SetDefault("favicon.refresh_interval", 60)
+	viper.SetDefault("favicon.max_age", 168)
+	viper.SetDefault("favicon.batch_size", 100)
+	viper.SetDefault("favicon.domain_checks_per_minute", 5)
+
+	// URL safety defaults (blocklist only until a provider is configured)
+	viper.SetDefault("safety.provider", "none")
+	viper.SetDefault("safety.safe_browsing_url", "https://safebrowsing.googleapis.com")
+	viper.SetDefault("safety.safe_browsing_api_key", "")
+	viper.SetDefault("safety.cache_ttl", 30)
+	viper.SetDefault("safety.timeout", 5)
+
+	// Data retention defaults, per class in days
+	viper.SetDefault("retention.interval", 60)
+	viper.SetDefault("retention.dry_run", false)
+	viper.SetDefault("retention.batch_size", 1000)
+	viper.SetDefault("retention.user_behaviors", 180)
+	viper.SetDefault("retention.webhook_deliveries", 30)
+	viper.SetDefault("retention.share_activities", 90)
+	viper.SetDefault("retention.sync_events", 60)
+
+	// Storage garbage collection
+	viper.SetDefault("storage_gc.interval", 360)
+	viper.SetDefault("storage_gc.dry_run", false)
+	viper.SetDefault("storage_gc.grace_period", 72)
+	viper.SetDefault("storage_gc.max_deletes", 1000)
+
+	// Scheduled compliance reports
+	viper.SetDefault("compliance.interval", 15)
+	viper.SetDefault("compliance.webhook_timeout", 30)
+
+	// Calendar heat map aggregation
+	viper.SetDefault("calendar.interval", 10)
+	viper.SetDefault("calendar.batch_size", 1000)
+
+	// Bookmark relationship graph
+	viper.SetDefault("graph.interval", 10)
+	viper.SetDefault("graph.batch_size", 500)
+	viper.SetDefault("graph.neighbors", 20)
+	viper.SetDefault("graph.max_nodes", 200)
+	viper.SetDefault("graph.covisit_window", 30)
+
+	// Spring cleaning candidates and reminders
+	viper.SetDefault("cleanup.reminder_interval", 60)
+	viper.SetDefault("cleanup.unvisited_days", 180)
+	viper.SetDefault("cleanup.unread_days", 90)
+	viper.SetDefault("cleanup.max_candidates", 200)
+
+	// Bookmark quality scoring of public areas
+	viper.SetDefault("quality.interval", 30)
+	viper.SetDefault("quality.batch_size", 500)
+	viper.SetDefault("quality.suppress_below", 40)
+	viper.SetDefault("quality.review_below", 60)
+	viper.SetDefault("quality.burst_count", 50)
+	viper.SetDefault("quality.burst_window", 10)
+	viper.SetDefault("quality.spread_accounts", 5)
+	viper.SetDefault("quality.spread_window", 24)
+	viper.SetDefault("quality.new_account_days", 7)
+
+	// ActivityPub federation stays off until an operator opts in
+	viper.SetDefault("federation.enabled", false)
+	viper.SetDefault("federation.delivery_timeout", 10)
+	viper.SetDefault("federation.outbox_page_size", 20)
+
+	// Onboarding defaults
+	viper.SetDefault("onboarding.sample_content", false)
+
+	// Guest sandboxes are for hosted demos only
+	viper.SetDefault("demo.enabled", false)
+	viper.SetDefault("demo.ttl", 120)
+	viper.SetDefault("demo.sessions_per_hour", 3)
+	viper.SetDefault("demo.max_sessions", 500)
+	viper.SetDefault("demo.plan", "demo")
+	viper.SetDefault("demo.cleanup_interval", 5)
+
+	// Account merges
+	viper.SetDefault("merge.interval", 1)
+	viper.SetDefault("merge.confirm_ttl", 24)
+
+	// Dual-write schema migrations start off until their tables are deployed
+	viper.SetDefault("migrations.tags_mode", "off")
+
+	// Counter reconciliation defaults (a run reads every bookmark)
+	viper.SetDefault("counters.reconcile_interval", 360)
+	viper.SetDefault("counters.batch_size", 500)
+	viper.SetDefault("counters.fix", true)
+	viper.SetDefault("worker.metrics_addr", ":9091")
+
+	// Outgoing email; no host logs emails instead of sending them
+	viper.SetDefault("mail.host", "")
+	viper.SetDefault("mail.port", 587)
+	viper.SetDefault("mail.from", "Bookmark Sync <no-reply@localhost>")
+
+	// Collection email subscriptions
+	viper.SetDefault("subscriptions.digest_interval", 60)
+	viper.SetDefault("subscriptions.confirm_cooldown", 15)
+	viper.SetDefault("subscriptions.confirm_ttl", 48)
+
+	// Public bookmarks API
+	viper.SetDefault("public_profile.cache_ttl", 60)
+	viper.SetDefault("public_profile.max_page_size", 50)
+
+	// Outgoing webhook deliveries
+	viper.SetDefault("webhooks.max_concurrency", 50)
+	viper.SetDefault("webhooks.endpoint_concurrency", 4)
+	viper.SetDefault("webhooks.breaker_timeouts", 5)
+	viper.SetDefault("webhooks.breaker_cooldown", 300)
+	viper.SetDefault("webhooks.threshold_interval", 60)
+	viper.SetDefault("webhooks.rule_schedule_interval", 1)
+	viper.SetDefault("webhooks.disable_after_failures", 50)
+	viper.SetDefault("webhooks.disable_after_days", 7)
+	viper.SetDefault("webhooks.health_window_days", 7)
+
+	// Automation defaults
+	viper.SetDefault("automation.export_dir", "")
+	viper.SetDefault("automation.integration_alert_after", 3)
+	viper.SetDefault("automation.integration_pause_after", 5)
+
+	// Soft rate limits per plan
+	viper.SetDefault("quota.enabled", true)
+	viper.SetDefault("quota.default_plan", "free")
+	viper.SetDefault("quota.admin_plan", "admin")
+	viper.SetDefault("quota.max_delay", 2000)
+	viper.SetDefault("quota.queue_delay", 60)
+	viper.SetDefault("quota.plans.free.api_burst", 120)
+	viper.SetDefault("quota.plans.free.api_rate", 300)
+	viper.SetDefault("quota.plans.free.automation_burst", 10)
+	viper.SetDefault("quota.plans.free.automation_rate", 5)
+	viper.SetDefault("quota.plans.pro.api_burst", 600)
+	viper.SetDefault("quota.plans.pro.api_rate", 1200)
+	viper.SetDefault("quota.plans.pro.automation_burst", 50)
+	viper.SetDefault("quota.plans.pro.automation_rate", 30)
+	viper.SetDefault("quota.plans.admin.api_burst", 0)
+	viper.SetDefault("quota.plans.admin.api_rate", 0)
+	viper.SetDefault("quota.plans.admin.automation_burst", 0)
+	viper.SetDefault("quota.plans.admin.automation_rate", 0)
+	viper.SetDefault("quota.plans.demo.api_burst", 30)
+	viper.SetDefault("quota.plans.demo.api_rate", 60)
+	viper.SetDefault("quota.plans.demo.automation_burst", 1)
+	viper.SetDefault("quota.plans.demo.automation_rate", 1)
 }
@@ -26,6 +26,8 @@ func TestLoad(t *testing.T) {
 		assert.Equal(t, "development", config.Server.Environment)
 		assert.Equal(t, 30, config.Server.ReadTimeout)
 		assert.Equal(t, 30, config.Server.WriteTimeout)
+		assert.Equal(t, "http://localhost:3000", config.Server.BaseURL)
+		assert.Equal(t, int64(2<<20), config.Server.MaxBodySize)
 
 		assert.Equal(t, "localhost", config.Database.Host)
 		assert.Equal(t, "5432", config.Database.Port)
@@ -52,17 +54,103 @@ func TestLoad(t *testing.T) {
 		assert.Equal(t, "minioadmin", config.Storage.SecretAccessKey)
 		assert.Equal(t, "bookmarks", config.Storage.BucketName)
 		assert.False(t, config.Storage.UseSSL)
+		assert.Equal(t, 900, config.Storage.DownloadURLTTL)
 
 		assert.Equal(t, "localhost", config.Search.Host)
 		assert.Equal(t, "8108", config.Search.Port)
 		assert.Equal(t, "xyz", config.Search.APIKey)
+		assert.Equal(t, 0, config.Search.CacheTTL)
+		assert.False(t, config.Search.WarmupOnStart)
+		assert.Equal(t, 200, config.Search.WarmupUsers)
+		assert.Equal(t, 5, config.Search.WarmupQueries)
+		assert.Equal(t, 14, config.Search.WarmupActiveDays)
 
 		assert.Equal(t, "your-secret-key", config.JWT.Secret)
 		assert.Equal(t, 24, config.JWT.ExpiryHour)
+		assert.Equal(t, AuthProviderSupabase, config.Auth.Provider)
+		assert.Equal(t, "preferred_username", config.Auth.OIDC.UsernameClaim)
+		assert.False(t, config.Auth.OIDC.LinkByEmail)
 
 		assert.Equal(t, "info", config.Logger.Level)
 		assert.Equal(t, "json", config.Logger.Format)
 		assert.Equal(t, "stdout", config.Logger.OutputPath)
+		assert.Equal(t, "your-encryption-key", config.Security.EncryptionKey)
+		assert.Equal(t, []string{"*"}, config.Security.AllowedOrigins)
+		assert.Equal(t, 31536000, config.Security.HSTSMaxAge)
+		assert.Contains(t, config.Security.PageCSP, "default-src 'none'")
+		assert.False(t, config.Maintenance.Enabled)
+		assert.Equal(t, 300, config.Maintenance.RetryAfter)
+		assert.False(t, config.Telemetry.Enabled)
+		assert.Empty(t, config.Telemetry.Endpoint)
+		assert.Equal(t, 24, config.Telemetry.Interval)
+		assert.False(t, config.SEO.Enabled)
+		assert.Equal(t, 60, config.SEO.RefreshInterval)
+		assert.Empty(t, config.SEO.Disallow)
+		assert.Equal(t, 0, config.SEO.CrawlDelay)
+		assert.False(t, config.Privacy.ComplianceMode)
+		assert.Equal(t, 0, config.Privacy.ActivityRetentionDays)
+		assert.Empty(t, config.Summarizer.Endpoint)
+		assert.Equal(t, 30, config.Summarizer.Timeout)
+		assert.Equal(t, 500, config.Summarizer.MaxLength)
+		assert.False(t, config.Summarizer.OnExtract)
+		assert.Equal(t, 24, config.Archive.RecaptureInterval)
+		assert.Equal(t, 0.2, config.Archive.ChangeThreshold)
+		assert.Equal(t, 10, config.Archive.MaxVersions)
+		assert.Equal(t, 256, config.WebSocket.SendBuffer)
+		assert.Equal(t, "drop_oldest", config.WebSocket.OverflowPolicy)
+		assert.Equal(t, 100, config.WebSocket.CoalesceWindow)
+		assert.True(t, config.Abuse.Enabled)
+		assert.Equal(t, 60, config.Abuse.RequestsPerMinute)
+		assert.Equal(t, 2000, config.Abuse.TarpitDelay)
+		assert.Equal(t, 20, config.Abuse.InvalidTokensPerHour)
+		assert.Equal(t, 60, config.Abuse.BlockDuration)
+		assert.Equal(t, 2, config.Screenshot.Workers)
+		assert.Equal(t, 100, config.Screenshot.QueueSize)
+		assert.Equal(t, 10, config.Screenshot.DomainRefreshesPerMinute)
+		assert.Equal(t, 60, config.Favicon.RefreshInterval)
+		assert.Equal(t, 168, config.Favicon.MaxAge)
+		assert.Equal(t, 100, config.Favicon.BatchSize)
+		assert.Equal(t, 5, config.Favicon.DomainChecksPerMinute)
+		assert.Equal(t, "none", config.Safety.Provider)
+		assert.Equal(t, "https://safebrowsing.googleapis.com", config.Safety.SafeBrowsingURL)
+		assert.Equal(t, 30, config.Safety.CacheTTL)
+		assert.Equal(t, 5, config.Safety.Timeout)
+		assert.Equal(t, 60, config.Retention.Interval)
+		assert.False(t, config.Retention.DryRun)
+		assert.Equal(t, 180, config.Retention.UserBehaviors)
+		assert.Equal(t, 30, config.Retention.WebhookDeliveries)
+		assert.Equal(t, 90, config.Retention.ShareActivities)
+		assert.Equal(t, 60, config.Retention.SyncEvents)
+		assert.Equal(t, 360, config.StorageGC.Interval)
+		assert.False(t, config.StorageGC.DryRun)
+		assert.Equal(t, 72, config.StorageGC.GracePeriod)
+		assert.Equal(t, 1000, config.StorageGC.MaxDeletes)
+		assert.False(t, config.Federation.Enabled)
+		assert.Equal(t, 10, config.Federation.DeliveryTimeout)
+		assert.Equal(t, 20, config.Federation.OutboxPageSize)
+		assert.False(t, config.Onboarding.SampleContent)
+		assert.Equal(t, "off", config.Migrations.TagsMode)
+		assert.Equal(t, 360, config.Counters.ReconcileInterval)
+		assert.Equal(t, 500, config.Counters.BatchSize)
+		assert.True(t, config.Counters.Fix)
+		assert.Equal(t, ":9091", config.Worker.MetricsAddr)
+		assert.Equal(t, 587, config.Mail.Port)
+		assert.Empty(t, config.Mail.Host)
+		assert.Equal(t, 60, config.Subscriptions.DigestInterval)
+		assert.Equal(t, 15, config.Subscriptions.ConfirmCooldown)
+		assert.Equal(t, 48, config.Subscriptions.ConfirmTTL)
+		assert.Equal(t, 60, config.PublicProfile.CacheTTL)
+		assert.Equal(t, 50, config.PublicProfile.MaxPageSize)
+		assert.Equal(t, 50, config.Webhooks.MaxConcurrency)
+		assert.Equal(t, 4, config.Webhooks.EndpointConcurrency)
+		assert.Equal(t, 5, config.Webhooks.BreakerTimeouts)
+		assert.Equal(t, 300, config.Webhooks.BreakerCooldown)
+		assert.True(t, config.Quota.Enabled)
+		assert.Equal(t, "free", config.Quota.DefaultPlan)
+		assert.Equal(t, 2000, config.Quota.MaxDelay)
+		assert.Equal(t, 60, config.Quota.QueueDelay)
+		assert.Equal(t, PlanQuota{APIBurst: 120, APIRate: 300, AutomationBurst: 10, AutomationRate: 5}, config.Quota.Plans["free"])
+		assert.Zero(t, config.Quota.Plans["admin"].APIRate)
 	})
 
 	t.Run("Load with Environment Variables", func(t *testing.T) {
@@ -294,6 +382,53 @@ func TestLoggerConfig(t *testing.T) {
 	assert.Equal(t, "stdout", config.OutputPath)
 }
 
+func TestSecurityConfigValidate(t *testing.T) {
+	valid := [][]string{
+		{"*"},
+		{"https://app.example.com", "http://localhost:3000"},
+		{"https://*.example.com", "chrome-extension://abcdefghijklmnop"},
+	}
+	for _, origins := range valid {
+		assert.NoError(t, SecurityConfig{AllowedOrigins: origins}.Validate(), origins)
+	}
+
+	invalid := [][]string{
+		{"*", "https://app.example.com"},
+		{"app.example.com"},
+		{"https://app.example.com/"},
+		{"https://app.example.com?x=1"},
+		{"https://app.*.example.com"},
+		{"https://*"},
+		{"https://app.example.com;script-src"},
+	}
+	for _, origins := range invalid {
+		assert.Error(t, SecurityConfig{AllowedOrigins: origins}.Validate(), origins)
+	}
+
+	assert.Error(t, SecurityConfig{HSTSMaxAge: -1}.Validate())
+	assert.Error(t, SecurityConfig{PageCSP: "default-src 'none'\r\nSet-Cookie: x"}.Validate())
+
+	os.Setenv("SECURITY_ALLOWED_ORIGINS", "https://app.example.com,ftp//broken")
+	defer os.Unsetenv("SECURITY_ALLOWED_ORIGINS")
+	_, err := Load()
+	assert.Error(t, err, "invalid allowlists are rejected at startup")
+}
+
+func TestAuthConfigValidate(t *testing.T) {
+	assert.NoError(t, AuthConfig{Provider: AuthProviderSupabase}.Validate())
+	assert.NoError(t, AuthConfig{Provider: AuthProviderLocal}.Validate())
+	assert.NoError(t, AuthConfig{Provider: AuthProviderOIDC, OIDC: OIDCConfig{IssuerURL: "https://id.example.com/realms/main", ClientID: "bookmarks"}}.Validate())
+
+	assert.Error(t, AuthConfig{Provider: "ldap"}.Validate())
+	assert.Error(t, AuthConfig{Provider: AuthProviderOIDC, OIDC: OIDCConfig{ClientID: "bookmarks"}}.Validate())
+	assert.Error(t, AuthConfig{Provider: AuthProviderOIDC, OIDC: OIDCConfig{IssuerURL: "id.example.com", ClientID: "bookmarks"}}.Validate())
+
+	os.Setenv("AUTH_PROVIDER", AuthProviderOIDC)
+	defer os.Unsetenv("AUTH_PROVIDER")
+	_, err := Load()
+	assert.Error(t, err, "incomplete oidc settings are rejected at startup")
+}
+
 // clearEnvVars clears all environment variables used in tests
 // clearEnvVars 清除測試中使用的所有環境變量
 func clearEnvVars() {
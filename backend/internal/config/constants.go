@@ -2,6 +2,9 @@ package config
 
 import "time"
 
+// Version is the application version reported by health checks and telemetry
+const Version = "1.0.0"
+
 // Constants for magic numbers and default values
 const (
 	// Cache TTL constants
@@ -48,6 +51,77 @@ const (
 	DefaultWorkerPoolSize = 10
 	DefaultQueueSize      = 1000
 	WorkerShutdownTimeout = 30 * time.Second
+
+	// Bulk operation workers, shared by all API instances' requests
+	BulkOperationWorkers   = 4
+	BulkOperationQueueSize = 100
+
+	// Maintenance mode settings
+	MaintenanceCacheTTL     = 5 * time.Second
+	MaintenancePollInterval = 10 * time.Second
+
+	// Telemetry settings
+	TelemetryRequestTimeout = 10 * time.Second
+
+	// SingletonJobLockTTL is how long a singleton job's lock survives its
+	// replica crashing; running jobs renew it
+	SingletonJobLockTTL = 2 * time.Minute
+
+	// TrendingCalculationInterval is how often trending scores are recomputed
+	TrendingCalculationInterval = time.Hour
+
+	// Batched search indexing settings
+	SearchIndexBatchSize     = 100 // documents per bulk import request
+	SearchIndexFlushInterval = 2 * time.Second
+	SearchIndexMaxAttempts   = 3 // per document, across flushes
+	SearchIndexRetryBackoff  = 500 * time.Millisecond
+	SearchIndexWatchTimeout  = time.Hour // an import's index complete event is given up after this
+
+	// MeteringFlushInterval is how often recorded feature usage is added to
+	// the usage table
+	MeteringFlushInterval = 30 * time.Second
+
+	// Search result export settings
+	SearchExportPageSize   = 100   // the largest page a search accepts
+	SearchExportMaxResults = 10000 // results saved by one export
+
+	// OpenID Connect settings
+	OIDCRequestTimeout     = 10 * time.Second
+	OIDCKeyCacheTTL        = time.Hour        // signing keys are refetched at least this often
+	OIDCKeyRefreshInterval = 30 * time.Second // least time between refetches for unknown keys
+
+	// Search warm-up settings
+	SearchWarmupPageSize = 20 // matches the default search page so warmed results are reused
+	SearchWarmupLockTTL  = 2 * time.Minute
+
+	// Sitemap settings
+	SitemapMaxURLs      = 50000 // limit of a single sitemap file
+	SitemapProfileBatch = 500   // users read at a time when listing public profiles
+
+	// Event stream settings
+	EventStreamHeartbeat   = 25 * time.Second // below common proxy idle timeouts
+	EventStreamReplayLimit = 1000             // missed events replayed on resume
+
+	// Archive settings
+	ArchiveRecaptureTick  = 10 * time.Minute // how often stale snapshots are looked for
+	ArchiveRecaptureBatch = 50               // snapshots re-captured per run
+	MaxArchivePageSize    = 5 << 20          // bytes read from an archived page
+	MaxArchiveDiffLines   = 2000             // lines per side compared line by line
+
+	// Collection suggestions
+	CollectionSuggestionLimit   = 3    // suggestions returned when a bookmark is created
+	MaxCollectionSuggestions    = 10   // per request
+	CollectionSuggestionHistory = 5000 // most recent filed bookmarks scored against
+
+	// Collection history
+	CollectionRevisionLimit = 500 // revisions kept per collection
+
+	// Response compression
+	CompressionMinSize = 1024 // bytes below which responses are sent uncompressed
+
+	// Request bodies; server.max_body_size caps the routes not listed here
+	MaxImportUploadSize = 64 << 20 // bookmark files and trees posted to import routes
+	MaxAvatarUploadSize = 5 << 20
 )
 
 // Redis key prefixes
@@ -57,6 +131,17 @@ const (
 	OfflineStatusPrefix   = "offline:status"
 	OfflineStatsPrefix    = "offline:stats"
 	CacheStatsPrefix      = "cache:stats"
+	MaintenanceStateKey   = "maintenance:state"
+	TelemetryInstanceKey  = "telemetry:instance_id"
+	SitemapCacheKey       = "seo:sitemap"
+	AbuseKeyPrefix        = "abuse"
+	ScreenshotRatePrefix  = "screenshot:rate"
+	FaviconRatePrefix     = "favicon:rate"
+	BackfillRatePrefix    = "backfill:rate"
+	SafetyVerdictPrefix   = "safety:verdict"
+	PublicBookmarksPrefix = "public:bookmarks"
+	SearchWarmupKey       = "search:warmup"
+	QuotaBucketPrefix     = "quota"
 )
 
 // Error messages
@@ -10,6 +10,8 @@ import (
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
+
+	"bookmark-sync-service/backend/pkg/language"
 )
 
 // WebContentAnalyzer implements ContentAnalyzer for web content
@@ -84,12 +86,14 @@ func (a *WebContentAnalyzer) ExtractContent(urlStr string) (*ContentData, error)
 	// Extract main content
 	content.Content = a.extractMainContent(doc)
 	content.WordCount = len(strings.Fields(content.Content))
+	content.ReadingTime = EstimateReadingTime(content.Content)
 
-	// Extract language
-	content.Language = doc.Find("html").AttrOr("lang", "en")
-	if content.Language == "" {
-		content.Language = doc.Find("meta[http-equiv='content-language']").AttrOr("content", "en")
+	// Extract language, falling back to detection when the page does not declare one
+	declared := doc.Find("html").AttrOr("lang", "")
+	if declared == "" {
+		declared = doc.Find("meta[http-equiv='content-language']").AttrOr("content", "")
 	}
+	content.Language = language.Resolve(declared, content.Title+" "+content.Description+" "+content.Content)
 
 	// Extract author
 	content.Author = doc.Find("meta[name='author']").AttrOr("content", "")
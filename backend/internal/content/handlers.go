@@ -13,8 +13,9 @@ import (
 
 // Handler handles HTTP requests for content analysis
 type Handler struct {
-	service *Service
-	cfg     *config.Config
+	service   *Service
+	cfg       *config.Config
+	verifiers []middleware.TokenVerifier
 }
 
 // NewHandler creates a new content analysis handler
@@ -25,10 +26,15 @@ func NewHandler(service *Service, cfg *config.Config) *Handler {
 	}
 }
 
+// SetTokenVerifiers accepts tokens of external identity providers too
+func (h *Handler) SetTokenVerifiers(verifiers ...middleware.TokenVerifier) {
+	h.verifiers = verifiers
+}
+
 // RegisterRoutes registers the content analysis routes
 func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
 	content := router.Group("/content")
-	content.Use(middleware.AuthMiddleware(&h.cfg.JWT))
+	content.Use(middleware.AuthMiddleware(&h.cfg.JWT, h.verifiers...))
 	{
 		content.POST("/analyze", h.AnalyzeURL)
 		content.POST("/suggest-tags", h.SuggestTags)
@@ -246,27 +252,19 @@ func (h *Handler) AnalyzeBookmarkContent(c *gin.Context) {
 		return
 	}
 
-	// Convert string to uint
-	userID := uint(1) // Default fallback
-	if userIDStr != "" {
-		if id, err := strconv.ParseUint(userIDStr, 10, 32); err == nil {
-			userID = uint(id)
-		}
-	}
-
-	// TODO: Fetch bookmark URL from database
-	// For now, we'll expect the URL to be provided in the request body
-	var req struct {
-		URL string `json:"url" binding:"required"`
-	}
-	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.ErrorResponse(c, http.StatusBadRequest, "MISSING_URL", "URL is required", nil)
+	userID, err := strconv.ParseUint(userIDStr, 10, 32)
+	if err != nil {
+		utils.UnauthorizedResponse(c, "Invalid user ID")
 		return
 	}
 
-	// Perform analysis
-	result, err := h.service.AnalyzeURL(c.Request.Context(), req.URL, userID)
+	// Analyze the bookmarked page, keeping the detected language and summary
+	result, err := h.service.AnalyzeBookmark(c.Request.Context(), uint(bookmarkID), uint(userID))
 	if err != nil {
+		if err.Error() == "bookmark not found" {
+			utils.ErrorResponse(c, http.StatusNotFound, "BOOKMARK_NOT_FOUND", "Bookmark not found", nil)
+			return
+		}
 		utils.ErrorResponse(c, http.StatusInternalServerError, "BOOKMARK_ANALYSIS_FAILED", "Failed to analyze bookmark content", nil)
 		return
 	}
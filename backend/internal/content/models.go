@@ -15,6 +15,7 @@ type ContentData struct {
 	ImageURL    string     `json:"image_url,omitempty"`
 	Domain      string     `json:"domain"`
 	WordCount   int        `json:"word_count"`
+	ReadingTime int        `json:"reading_time"` // estimated minutes
 }
 
 // ContentAnalysis represents the analysis results of content
@@ -60,6 +61,7 @@ type AnalysisResult struct {
 	Summary       string            `json:"summary"`
 	Language      string            `json:"language"`
 	WordCount     int               `json:"word_count"`
+	ReadingTime   int               `json:"reading_time"` // estimated minutes
 	AnalyzedAt    time.Time         `json:"analyzed_at"`
 }
 
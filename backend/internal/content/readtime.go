@@ -0,0 +1,42 @@
+package content
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Reading speeds used for estimates. Scripts written without spaces are
+// counted per character rather than per word
+const (
+	wordsPerMinute = 230
+	charsPerMinute = 500
+)
+
+// EstimateReadingTime returns the minutes an average reader needs for a
+// text, rounded up; zero for empty text
+func EstimateReadingTime(text string) int {
+	words, chars := 0, 0
+	for _, field := range strings.Fields(text) {
+		spaced := false
+		for _, r := range field {
+			if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul, unicode.Thai) {
+				chars++
+			} else if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				spaced = true
+			}
+		}
+		if spaced {
+			words++
+		}
+	}
+	if words == 0 && chars == 0 {
+		return 0
+	}
+
+	seconds := words*60/wordsPerMinute + chars*60/charsPerMinute
+	minutes := (seconds + 59) / 60
+	if minutes < 1 {
+		minutes = 1
+	}
+	return minutes
+}
@@ -2,12 +2,32 @@ package content
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
+
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/summarize"
 )
 
+// maxSummaryContent caps the page text sent to the summarization provider
+const maxSummaryContent = 20000
+
+// errNoBookmarkStore is returned when bookmarks are analyzed without a store
+var errNoBookmarkStore = errors.New("bookmark analysis is not configured")
+
+// BookmarkStore loads a user's bookmarks and keeps what analysis finds on them
+type BookmarkStore interface {
+	GetByID(bookmarkID, userID uint) (*database.Bookmark, error)
+	SetDetectedLanguage(bookmarkID, userID uint, code string) (*database.Bookmark, error)
+	SetSummary(bookmarkID, userID uint, summary string) (*database.Bookmark, error)
+}
+
 // Service handles content analysis operations
 type Service struct {
-	analyzer ContentAnalyzer
+	analyzer   ContentAnalyzer
+	summarizer summarize.Summarizer
+	bookmarks  BookmarkStore
 }
 
 // NewService creates a new content analysis service
@@ -17,36 +37,84 @@ func NewService() *Service {
 	}
 }
 
+// SetSummarizer makes metadata extraction summarize pages with a provider
+func (s *Service) SetSummarizer(summarizer summarize.Summarizer) {
+	s.summarizer = summarizer
+}
+
+// SetBookmarkStore enables analyzing saved bookmarks and storing the results
+func (s *Service) SetBookmarkStore(bookmarks BookmarkStore) {
+	s.bookmarks = bookmarks
+}
+
+// summarize returns the provider's summary of the extracted page, or an
+// empty string when there is no provider or it fails
+func (s *Service) summarize(ctx context.Context, data *ContentData) string {
+	if s.summarizer == nil {
+		return ""
+	}
+
+	text := []rune(data.Content)
+	if len(text) > maxSummaryContent {
+		text = text[:maxSummaryContent]
+	}
+
+	summary, err := s.summarizer.Summarize(ctx, summarize.Request{
+		URL:         data.URL,
+		Title:       data.Title,
+		Description: data.Description,
+		Content:     string(text),
+		Language:    data.Language,
+	})
+	if err != nil {
+		return ""
+	}
+	return summary
+}
+
 // AnalyzeURL performs comprehensive analysis of a URL
 func (s *Service) AnalyzeURL(ctx context.Context, url string, userID uint) (*AnalysisResult, error) {
+	result, _, err := s.analyzeURL(ctx, url, userID)
+	return result, err
+}
+
+// analyzeURL analyzes a URL, also returning the provider's summary of the
+// page, empty when there is no provider or it failed
+func (s *Service) analyzeURL(ctx context.Context, url string, userID uint) (*AnalysisResult, string, error) {
 	// Extract content from URL
 	contentData, err := s.analyzer.ExtractContent(url)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	// Perform content analysis
 	analysis, err := s.analyzer.AnalyzeContent(contentData)
 	if err != nil {
-		return nil, err
+		return nil, "", err
+	}
+
+	// Prefer the provider's summary over the extractive one
+	generated := s.summarize(ctx, contentData)
+	if generated != "" {
+		analysis.Summary = generated
 	}
 
 	// Get tag suggestions
 	suggestedTags, err := s.analyzer.SuggestTags(analysis)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	// Categorize content
 	category, err := s.analyzer.CategorizeContent(analysis)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	// Detect duplicates
 	duplicates, err := s.analyzer.DetectDuplicates(contentData, userID)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	// Build result
@@ -64,9 +132,40 @@ func (s *Service) AnalyzeURL(ctx context.Context, url string, userID uint) (*Ana
 		Summary:       analysis.Summary,
 		Language:      contentData.Language,
 		WordCount:     contentData.WordCount,
+		ReadingTime:   contentData.ReadingTime,
 		AnalyzedAt:    time.Now(),
 	}
 
+	return result, generated, nil
+}
+
+// AnalyzeBookmark analyzes the page of one of the user's bookmarks and
+// stores the language detected in it and the provider's summary on the
+// bookmark, which indexes both for search
+func (s *Service) AnalyzeBookmark(ctx context.Context, bookmarkID, userID uint) (*AnalysisResult, error) {
+	if s.bookmarks == nil {
+		return nil, errNoBookmarkStore
+	}
+
+	bookmark, err := s.bookmarks.GetByID(bookmarkID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result, generated, err := s.analyzeURL(ctx, bookmark.URL, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.bookmarks.SetDetectedLanguage(bookmarkID, userID, result.Language); err != nil {
+		return nil, fmt.Errorf("failed to store analysis: %w", err)
+	}
+	if generated != "" {
+		if _, err := s.bookmarks.SetSummary(bookmarkID, userID, generated); err != nil {
+			return nil, fmt.Errorf("failed to store summary: %w", err)
+		}
+	}
+
 	return result, nil
 }
 
@@ -2,11 +2,16 @@ package content
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
+
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/summarize"
 )
 
 // MockContentAnalyzer is a mock implementation of ContentAnalyzer
@@ -39,6 +44,39 @@ func (m *MockContentAnalyzer) DetectDuplicates(content *ContentData, userID uint
 	return args.Get(0).([]*DuplicateMatch), args.Error(1)
 }
 
+// stubBookmarkStore serves one bookmark and records what analysis stores
+type stubBookmarkStore struct {
+	bookmark *database.Bookmark
+	language string
+	summary  string
+}
+
+func (s *stubBookmarkStore) GetByID(bookmarkID, userID uint) (*database.Bookmark, error) {
+	if s.bookmark == nil || s.bookmark.ID != bookmarkID || s.bookmark.UserID != userID {
+		return nil, errors.New("bookmark not found")
+	}
+	return s.bookmark, nil
+}
+
+func (s *stubBookmarkStore) SetDetectedLanguage(bookmarkID, userID uint, code string) (*database.Bookmark, error) {
+	s.language = code
+	return s.bookmark, nil
+}
+
+func (s *stubBookmarkStore) SetSummary(bookmarkID, userID uint, summary string) (*database.Bookmark, error) {
+	s.summary = summary
+	return s.bookmark, nil
+}
+
+// stubSummarizer returns a fixed summary
+type stubSummarizer struct {
+	summary string
+}
+
+func (s *stubSummarizer) Summarize(ctx context.Context, req summarize.Request) (string, error) {
+	return s.summary, nil
+}
+
 // ContentServiceTestSuite defines the test suite for content service
 type ContentServiceTestSuite struct {
 	suite.Suite
@@ -269,6 +307,43 @@ func (suite *ContentServiceTestSuite) TestCategorizeBookmark() {
 	suite.analyzer.AssertExpectations(suite.T())
 }
 
+func (suite *ContentServiceTestSuite) TestAnalyzeBookmark() {
+	ctx := context.Background()
+	url := "https://example.de/artikel"
+
+	_, err := suite.service.AnalyzeBookmark(ctx, 7, 1)
+	assert.Error(suite.T(), err)
+
+	store := &stubBookmarkStore{bookmark: &database.Bookmark{BaseModel: database.BaseModel{ID: 7}, UserID: 1, URL: url, Language: "en"}}
+	suite.service.SetBookmarkStore(store)
+
+	contentData := &ContentData{URL: url, Title: "Ein Artikel", Language: "de"}
+	analysis := &ContentAnalysis{ContentData: contentData, Summary: "Der erste Satz."}
+
+	suite.analyzer.On("ExtractContent", url).Return(contentData, nil)
+	suite.analyzer.On("AnalyzeContent", contentData).Return(analysis, nil)
+	suite.analyzer.On("SuggestTags", analysis).Return([]string{}, nil)
+	suite.analyzer.On("CategorizeContent", analysis).Return("News", nil)
+	suite.analyzer.On("DetectDuplicates", contentData, uint(1)).Return([]*DuplicateMatch{}, nil)
+
+	result, err := suite.service.AnalyzeBookmark(ctx, 7, 1)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "de", result.Language)
+	assert.Equal(suite.T(), "de", store.language)
+
+	// Only summaries made by the provider are stored
+	assert.Empty(suite.T(), store.summary)
+
+	suite.service.SetSummarizer(&stubSummarizer{summary: "Ein Artikel über Go."})
+	result, err = suite.service.AnalyzeBookmark(ctx, 7, 1)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "Ein Artikel über Go.", result.Summary)
+	assert.Equal(suite.T(), "Ein Artikel über Go.", store.summary)
+
+	_, err = suite.service.AnalyzeBookmark(ctx, 7, 2)
+	assert.EqualError(suite.T(), err, "bookmark not found")
+}
+
 func TestContentServiceTestSuite(t *testing.T) {
 	suite.Run(t, new(ContentServiceTestSuite))
 }
@@ -278,3 +353,11 @@ func TestNewService(t *testing.T) {
 	assert.NotNil(t, service)
 	assert.NotNil(t, service.analyzer)
 }
+
+func TestEstimateReadingTime(t *testing.T) {
+	assert.Equal(t, 0, EstimateReadingTime("  "))
+	assert.Equal(t, 1, EstimateReadingTime("A short note."))
+	assert.Equal(t, 5, EstimateReadingTime(strings.Repeat("word ", 1000)))
+	// Chinese is read per character, not per space separated run
+	assert.Equal(t, 2, EstimateReadingTime(strings.Repeat("書籤同步服務", 100)))
+}
@@ -0,0 +1,56 @@
+package counters
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// collector exports reconciliation results to Prometheus
+type collector struct {
+	reconciler  *Reconciler
+	drifted     *prometheus.Desc
+	drift       *prometheus.Desc
+	runs        *prometheus.Desc
+	lastRun     *prometheus.Desc
+	lastDrifted *prometheus.Desc
+}
+
+// NewCollector returns a Prometheus collector for the reconciler's drift
+func NewCollector(reconciler *Reconciler) prometheus.Collector {
+	return &collector{
+		reconciler: reconciler,
+		drifted: prometheus.NewDesc("bookmark_sync_counter_drifted_bookmarks_total",
+			"Bookmarks found with a counter that disagreed with its source rows, by counter.", []string{"counter"}, nil),
+		drift: prometheus.NewDesc("bookmark_sync_counter_drift_total",
+			"Sum of absolute differences between stored and recounted counters, by counter.", []string{"counter"}, nil),
+		runs: prometheus.NewDesc("bookmark_sync_counter_reconcile_runs_total",
+			"Completed counter reconciliation runs.", nil, nil),
+		lastRun: prometheus.NewDesc("bookmark_sync_counter_reconcile_last_run_timestamp_seconds",
+			"Start time of the last completed counter reconciliation.", nil, nil),
+		lastDrifted: prometheus.NewDesc("bookmark_sync_counter_reconcile_last_drifted_bookmarks",
+			"Bookmarks with drifted counters in the last completed reconciliation.", nil, nil),
+	}
+}
+
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.drifted
+	ch <- c.drift
+	ch <- c.runs
+	ch <- c.lastRun
+	ch <- c.lastDrifted
+}
+
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	r := c.reconciler
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, column := range counterColumns {
+		ch <- prometheus.MustNewConstMetric(c.drifted, prometheus.CounterValue, float64(r.drifted[column]), column)
+		ch <- prometheus.MustNewConstMetric(c.drift, prometheus.CounterValue, float64(r.driftSums[column]), column)
+	}
+	ch <- prometheus.MustNewConstMetric(c.runs, prometheus.CounterValue, float64(r.runs))
+	if r.last != nil {
+		ch <- prometheus.MustNewConstMetric(c.lastRun, prometheus.GaugeValue, float64(r.last.StartedAt.Unix()))
+		ch <- prometheus.MustNewConstMetric(c.lastDrifted, prometheus.GaugeValue, float64(r.last.Drifted))
+	}
+}
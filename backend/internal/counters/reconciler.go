@@ -0,0 +1,253 @@
+// Package counters keeps the denormalized social counters of bookmarks in
+// step with the rows they count
+package counters
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+// behaviorsTable holds tracked user actions, the source of save and like
+// counts. It belongs to the community feature and may not be migrated
+const behaviorsTable = "user_behaviors"
+
+// counterColumns lists the reconciled bookmark counters
+var counterColumns = []string{database.CounterSaves, database.CounterLikes, database.CounterComments}
+
+// Report summarizes one reconciliation run
+type Report struct {
+	StartedAt time.Time     `json:"started_at"`
+	Duration  time.Duration `json:"duration"`
+	Checked   int           `json:"checked"`
+	Drifted   int           `json:"drifted"` // bookmarks with at least one wrong counter
+	Fixed     bool          `json:"fixed"`
+	// Per counter, the bookmarks it was wrong on and the sum of the
+	// absolute differences
+	DriftedBookmarks map[string]int `json:"drifted_bookmarks"`
+	Drift            map[string]int `json:"drift"`
+}
+
+// Reconciler recomputes bookmark counters from their source tables
+type Reconciler struct {
+	db       *gorm.DB
+	cfg      config.CountersConfig
+	logger   *zap.Logger
+	progress func(checked int) // optional
+
+	mu        sync.Mutex
+	last      *Report
+	runs      uint64
+	drifted   map[string]uint64 // bookmarks found drifted, per counter
+	driftSums map[string]uint64
+}
+
+// NewReconciler creates a counter reconciler
+func NewReconciler(db *gorm.DB, cfg config.CountersConfig, logger *zap.Logger) *Reconciler {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 500
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &Reconciler{
+		db:        db,
+		cfg:       cfg,
+		logger:    logger,
+		drifted:   make(map[string]uint64),
+		driftSums: make(map[string]uint64),
+	}
+}
+
+// SetProgress reports the number of bookmarks checked after every batch,
+// e.g. for the admin CLI
+func (r *Reconciler) SetProgress(progress func(checked int)) {
+	r.progress = progress
+}
+
+// ReconcileCounters runs a reconciliation and logs its summary
+func (r *Reconciler) ReconcileCounters(ctx context.Context) error {
+	report, err := r.Reconcile(ctx)
+	if err != nil {
+		return err
+	}
+	r.logger.Info("Bookmark counters reconciled",
+		zap.Int("checked", report.Checked),
+		zap.Int("drifted", report.Drifted),
+		zap.Bool("fixed", report.Fixed),
+		zap.Duration("duration", report.Duration))
+	return nil
+}
+
+// Reconcile compares every bookmark's counters with its source rows in
+// batches and, unless configured to only report, corrects the ones that
+// drifted. Each batch locks its bookmarks, so concurrent counter updates
+// wait rather than being overwritten by stale counts
+func (r *Reconciler) Reconcile(ctx context.Context) (*Report, error) {
+	report := &Report{
+		StartedAt:        time.Now(),
+		Fixed:            r.cfg.Fix,
+		DriftedBookmarks: make(map[string]int),
+		Drift:            make(map[string]int),
+	}
+	behaviors := r.db.Migrator().HasTable(behaviorsTable)
+
+	var lastID uint
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		var batch int
+		err := database.WithTransaction(ctx, r.db, func(tx *gorm.DB) error {
+			var bookmarks []database.Bookmark
+			if err := database.ForUpdate(tx.Model(&database.Bookmark{})).
+				Select("id", database.CounterSaves, database.CounterLikes, database.CounterComments).
+				Where("id > ?", lastID).Order("id").Limit(r.cfg.BatchSize).
+				Find(&bookmarks).Error; err != nil {
+				return fmt.Errorf("failed to load bookmarks: %w", err)
+			}
+			batch = len(bookmarks)
+			if batch == 0 {
+				return nil
+			}
+			lastID = bookmarks[batch-1].ID
+
+			return r.reconcileBatch(tx, bookmarks, behaviors, report)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if batch == 0 {
+			break
+		}
+		report.Checked += batch
+		if r.progress != nil {
+			r.progress(report.Checked)
+		}
+	}
+
+	report.Duration = time.Since(report.StartedAt)
+	r.record(report)
+	return report, nil
+}
+
+// reconcileBatch checks one batch of bookmarks inside its transaction
+func (r *Reconciler) reconcileBatch(tx *gorm.DB, bookmarks []database.Bookmark, behaviors bool, report *Report) error {
+	ids := make([]uint, len(bookmarks))
+	for i, bookmark := range bookmarks {
+		ids[i] = bookmark.ID
+	}
+	actual, err := countSources(tx, ids, behaviors)
+	if err != nil {
+		return err
+	}
+
+	for _, bookmark := range bookmarks {
+		stored := map[string]int{
+			database.CounterSaves:    bookmark.SaveCount,
+			database.CounterLikes:    bookmark.LikeCount,
+			database.CounterComments: bookmark.CommentCount,
+		}
+		updates := make(map[string]interface{})
+		for _, column := range counterColumns {
+			want, known := actual[column]
+			if !known {
+				continue // No source table to count from
+			}
+			have, count := stored[column], want[bookmark.ID]
+			if have == count {
+				continue
+			}
+			updates[column] = count
+			report.DriftedBookmarks[column]++
+			report.Drift[column] += abs(have - count)
+			r.logger.Warn("Bookmark counter drifted",
+				zap.Uint("bookmark_id", bookmark.ID),
+				zap.String("counter", column),
+				zap.Int("stored", have),
+				zap.Int("actual", count))
+		}
+		if len(updates) == 0 {
+			continue
+		}
+
+		report.Drifted++
+		if !r.cfg.Fix {
+			continue
+		}
+		if err := tx.Model(&database.Bookmark{}).Where("id = ?", bookmark.ID).UpdateColumns(updates).Error; err != nil {
+			return fmt.Errorf("failed to fix counters of bookmark %d: %w", bookmark.ID, err)
+		}
+	}
+	return nil
+}
+
+// countSources counts the source rows of each counter for the bookmarks.
+// Counters without a source table are left out
+func countSources(tx *gorm.DB, ids []uint, behaviors bool) (map[string]map[uint]int, error) {
+	type row struct {
+		BookmarkID uint
+		ActionType string
+		Count      int
+	}
+	actual := map[string]map[uint]int{database.CounterComments: {}}
+
+	var comments []row
+	if err := tx.Model(&database.Comment{}).Select("bookmark_id, COUNT(*) AS count").
+		Where("bookmark_id IN ?", ids).Group("bookmark_id").Scan(&comments).Error; err != nil {
+		return nil, fmt.Errorf("failed to count comments: %w", err)
+	}
+	for _, c := range comments {
+		actual[database.CounterComments][c.BookmarkID] = c.Count
+	}
+
+	if !behaviors {
+		return actual, nil
+	}
+	actual[database.CounterSaves] = map[uint]int{}
+	actual[database.CounterLikes] = map[uint]int{}
+
+	var actions []row
+	if err := tx.Table(behaviorsTable).Select("bookmark_id, action_type, COUNT(*) AS count").
+		Where("bookmark_id IN ? AND action_type IN ? AND deleted_at IS NULL", ids, []string{"save", "like"}).
+		Group("bookmark_id, action_type").Scan(&actions).Error; err != nil {
+		return nil, fmt.Errorf("failed to count user actions: %w", err)
+	}
+	for _, a := range actions {
+		actual[database.CounterForAction(a.ActionType)][a.BookmarkID] = a.Count
+	}
+	return actual, nil
+}
+
+// LastReport returns the latest completed run, or nil before the first
+func (r *Reconciler) LastReport() *Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.last
+}
+
+func (r *Reconciler) record(report *Report) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.last = report
+	r.runs++
+	for column, drifted := range report.DriftedBookmarks {
+		r.drifted[column] += uint64(drifted)
+		r.driftSums[column] += uint64(report.Drift[column])
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
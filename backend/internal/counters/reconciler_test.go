@@ -0,0 +1,95 @@
+package counters
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/testfactory"
+)
+
+func setupTestDB(t *testing.T) (*gorm.DB, *testfactory.Factory) {
+	db := testfactory.NewDB(t)
+	require.NoError(t, db.AutoMigrate(&testfactory.Behavior{}))
+	return db, testfactory.New(t, db)
+}
+
+func createBookmark(f *testfactory.Factory, saves, likes, comments int) uint {
+	return f.Bookmark(1, func(b *database.Bookmark) {
+		b.SaveCount, b.LikeCount, b.CommentCount = saves, likes, comments
+	}).ID
+}
+
+func TestReconciler_FixesDrift(t *testing.T) {
+	db, f := setupTestDB(t)
+	accurate := createBookmark(f, 1, 0, 1)
+	drifted := createBookmark(f, 5, 2, 3)
+
+	f.Behavior("a", accurate, "save")
+	f.Behavior("a", drifted, "save")
+	f.Behavior("b", drifted, "like")
+	unliked := f.Behavior("c", drifted, "like")
+	require.NoError(t, db.Delete(unliked).Error)
+	f.Behavior("c", drifted, "view")
+	require.NoError(t, db.Create(&database.Comment{BookmarkID: accurate, UserID: 1, Content: "hi"}).Error)
+	require.NoError(t, db.Create(&database.Comment{BookmarkID: drifted, UserID: 1, Content: "hi"}).Error)
+
+	reconciler := NewReconciler(db, config.CountersConfig{BatchSize: 1, Fix: true}, nil)
+	report, err := reconciler.Reconcile(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Checked)
+	assert.Equal(t, 1, report.Drifted)
+	assert.Equal(t, map[string]int{"save_count": 4, "like_count": 1, "comment_count": 2}, report.Drift)
+
+	var fixed database.Bookmark
+	require.NoError(t, db.First(&fixed, drifted).Error)
+	assert.Equal(t, 1, fixed.SaveCount)
+	assert.Equal(t, 1, fixed.LikeCount)
+	assert.Equal(t, 1, fixed.CommentCount)
+
+	// A second run finds nothing to fix
+	report, err = reconciler.Reconcile(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, report.Drifted)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(NewCollector(reconciler))
+	expected := `
+# HELP bookmark_sync_counter_drifted_bookmarks_total Bookmarks found with a counter that disagreed with its source rows, by counter.
+# TYPE bookmark_sync_counter_drifted_bookmarks_total counter
+bookmark_sync_counter_drifted_bookmarks_total{counter="comment_count"} 1
+bookmark_sync_counter_drifted_bookmarks_total{counter="like_count"} 1
+bookmark_sync_counter_drifted_bookmarks_total{counter="save_count"} 1
+# HELP bookmark_sync_counter_reconcile_runs_total Completed counter reconciliation runs.
+# TYPE bookmark_sync_counter_reconcile_runs_total counter
+bookmark_sync_counter_reconcile_runs_total 2
+`
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected),
+		"bookmark_sync_counter_drifted_bookmarks_total", "bookmark_sync_counter_reconcile_runs_total"))
+}
+
+func TestReconciler_ReportOnly(t *testing.T) {
+	db, f := setupTestDB(t)
+	require.NoError(t, db.Migrator().DropTable("user_behaviors"))
+	id := createBookmark(f, 7, 3, 2)
+
+	report, err := NewReconciler(db, config.CountersConfig{Fix: false}, nil).Reconcile(context.Background())
+
+	// Without the behaviors table only comments can be checked, and the
+	// drift is reported but left in place
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"comment_count": 2}, report.Drift)
+	var bookmark database.Bookmark
+	require.NoError(t, db.First(&bookmark, id).Error)
+	assert.Equal(t, 7, bookmark.SaveCount)
+	assert.Equal(t, 2, bookmark.CommentCount)
+}
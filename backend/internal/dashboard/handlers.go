@@ -0,0 +1,122 @@
+package dashboard
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"bookmark-sync-service/backend/pkg/utils"
+)
+
+// Handler serves the dashboard and its layout
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new dashboard handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes registers the dashboard routes
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	dashboard := router.Group("/dashboard")
+	dashboard.GET("", h.GetDashboard)
+	dashboard.GET("/widgets", h.GetWidgetTypes)
+	dashboard.PUT("/layout", h.SaveLayout)
+	dashboard.DELETE("/layout", h.ResetLayout)
+}
+
+// GetDashboard returns the user's layout with the data of all its widgets
+// @Summary Get the dashboard
+// @Description Widgets that fail to load carry an error, the others are still returned
+// @Tags dashboard
+// @Produce json
+// @Success 200 {object} Dashboard
+// @Router /dashboard [get]
+func (h *Handler) GetDashboard(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	dashboard, err := h.service.GetDashboard(c.Request.Context(), userID)
+	if err != nil {
+		h.fail(c, err, "Failed to get dashboard")
+		return
+	}
+	utils.SuccessResponse(c, dashboard, "Dashboard retrieved")
+}
+
+// GetWidgetTypes lists the widget types a layout can use
+// @Summary List dashboard widget types
+// @Tags dashboard
+// @Produce json
+// @Success 200 {array} string
+// @Router /dashboard/widgets [get]
+func (h *Handler) GetWidgetTypes(c *gin.Context) {
+	utils.SuccessResponse(c, gin.H{"types": WidgetTypes, "max_widgets": MaxWidgets, "grid_columns": GridColumns}, "Widget types retrieved")
+}
+
+// SaveLayout replaces the user's dashboard layout
+// @Summary Save the dashboard layout
+// @Tags dashboard
+// @Accept json
+// @Produce json
+// @Param layout body Layout true "Dashboard layout"
+// @Success 200 {object} Layout
+// @Failure 400 {object} utils.ErrorResponse
+// @Router /dashboard/layout [put]
+func (h *Handler) SaveLayout(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	var layout Layout
+	if err := c.ShouldBindJSON(&layout); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request format", nil)
+		return
+	}
+
+	saved, err := h.service.SaveLayout(c.Request.Context(), userID, layout)
+	if err != nil {
+		h.fail(c, err, "Failed to save dashboard layout")
+		return
+	}
+	utils.SuccessResponse(c, saved, "Dashboard layout saved")
+}
+
+// ResetLayout goes back to the default layout
+// @Summary Reset the dashboard layout
+// @Tags dashboard
+// @Produce json
+// @Success 200 {object} Layout
+// @Router /dashboard/layout [delete]
+func (h *Handler) ResetLayout(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	if err := h.service.ResetLayout(c.Request.Context(), userID); err != nil {
+		h.fail(c, err, "Failed to reset dashboard layout")
+		return
+	}
+	utils.SuccessResponse(c, DefaultLayout(), "Dashboard layout reset")
+}
+
+func (h *Handler) fail(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, ErrInvalidLayout):
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_LAYOUT", err.Error(), nil)
+	case errors.Is(err, ErrUserNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, "USER_NOT_FOUND", "User not found", nil)
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", message, nil)
+	}
+}
@@ -0,0 +1,119 @@
+package dashboard
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Widget types a dashboard can be composed of
+const (
+	WidgetRecentBookmarks = "recent_bookmarks"
+	WidgetReadingList     = "reading_list"
+	WidgetTrendingNetwork = "trending_network"
+	WidgetBrokenLinks     = "broken_links"
+	WidgetStatsChart      = "stats_chart"
+)
+
+const (
+	// MaxWidgets caps the widgets of one dashboard
+	MaxWidgets = 20
+	// GridColumns is the width of the layout grid
+	GridColumns = 12
+
+	defaultLimit = 10
+	maxLimit     = 50
+	defaultDays  = 30
+	maxDays      = 365
+	maxIDLength  = 50
+)
+
+// ErrInvalidLayout is returned for layouts that can't be saved
+var ErrInvalidLayout = errors.New("invalid dashboard layout")
+
+// WidgetTypes lists the widget types in the order they are documented
+var WidgetTypes = []string{
+	WidgetRecentBookmarks,
+	WidgetReadingList,
+	WidgetTrendingNetwork,
+	WidgetBrokenLinks,
+	WidgetStatsChart,
+}
+
+// Widget is one tile of the dashboard: what it shows and where. Positions
+// are grid cells, X and W count columns of GridColumns
+type Widget struct {
+	ID   string `json:"id"` // chosen by the client, unique in the layout
+	Type string `json:"type"`
+	X    int    `json:"x"`
+	Y    int    `json:"y"`
+	W    int    `json:"w"`
+	H    int    `json:"h"`
+
+	Limit int `json:"limit,omitempty"` // items of list widgets
+	Days  int `json:"days,omitempty"`  // range of the stats chart
+}
+
+// Layout is the arrangement of a user's dashboard, saved in their preferences
+type Layout struct {
+	Widgets []Widget `json:"widgets"`
+}
+
+// DefaultLayout is shown until the user saves their own
+func DefaultLayout() Layout {
+	return Layout{Widgets: []Widget{
+		{ID: "recent", Type: WidgetRecentBookmarks, X: 0, Y: 0, W: 6, H: 4, Limit: defaultLimit},
+		{ID: "reading", Type: WidgetReadingList, X: 6, Y: 0, W: 6, H: 4, Limit: defaultLimit},
+		{ID: "stats", Type: WidgetStatsChart, X: 0, Y: 4, W: 12, H: 3, Days: defaultDays},
+		{ID: "trending", Type: WidgetTrendingNetwork, X: 0, Y: 7, W: 6, H: 4, Limit: defaultLimit},
+		{ID: "broken", Type: WidgetBrokenLinks, X: 6, Y: 7, W: 6, H: 4, Limit: defaultLimit},
+	}}
+}
+
+// normalize validates a layout and fills in the defaults of its widgets
+func (l *Layout) normalize() error {
+	if len(l.Widgets) > MaxWidgets {
+		return fmt.Errorf("%w: at most %d widgets", ErrInvalidLayout, MaxWidgets)
+	}
+
+	ids := make(map[string]bool, len(l.Widgets))
+	for i := range l.Widgets {
+		widget := &l.Widgets[i]
+		switch {
+		case widget.ID == "" || len(widget.ID) > maxIDLength:
+			return fmt.Errorf("%w: widget %d needs an id of 1 to %d characters", ErrInvalidLayout, i, maxIDLength)
+		case ids[widget.ID]:
+			return fmt.Errorf("%w: duplicate widget id %q", ErrInvalidLayout, widget.ID)
+		case !knownType(widget.Type):
+			return fmt.Errorf("%w: unknown widget type %q", ErrInvalidLayout, widget.Type)
+		case widget.X < 0 || widget.Y < 0 || widget.W < 1 || widget.H < 1 || widget.X+widget.W > GridColumns:
+			return fmt.Errorf("%w: widget %q is outside the %d-column grid", ErrInvalidLayout, widget.ID, GridColumns)
+		case widget.Limit < 0 || widget.Limit > maxLimit:
+			return fmt.Errorf("%w: limit of widget %q must be between 1 and %d", ErrInvalidLayout, widget.ID, maxLimit)
+		case widget.Days < 0 || widget.Days > maxDays:
+			return fmt.Errorf("%w: days of widget %q must be between 1 and %d", ErrInvalidLayout, widget.ID, maxDays)
+		}
+		ids[widget.ID] = true
+
+		if widget.Type == WidgetStatsChart {
+			widget.Limit = 0
+			if widget.Days == 0 {
+				widget.Days = defaultDays
+			}
+			continue
+		}
+		widget.Days = 0
+		if widget.Limit == 0 {
+			widget.Limit = defaultLimit
+		}
+	}
+	return nil
+}
+
+func knownType(widgetType string) bool {
+	for _, known := range WidgetTypes {
+		if widgetType == known {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,445 @@
+package dashboard
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/internal/community"
+	"bookmark-sync-service/backend/internal/user"
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/locale"
+)
+
+const (
+	// preferencesKey is where the layout is kept in the users.preferences JSON
+	preferencesKey = "dashboard"
+	// networkWindow is how far back saves count towards network trends
+	networkWindow = 7 * 24 * time.Hour
+)
+
+// ErrUserNotFound is returned for dashboards of unknown users
+var ErrUserNotFound = errors.New("user not found")
+
+// ReadingList lists the reading-list items a user hasn't finished
+type ReadingList interface {
+	Unfinished(ctx context.Context, userID uint, limit int) ([]database.Bookmark, error)
+}
+
+// Dashboard is a user's layout with the data of every widget
+type Dashboard struct {
+	Layout      Layout       `json:"layout"`
+	Customized  bool         `json:"customized"` // false while the default layout is shown
+	Widgets     []WidgetData `json:"widgets"`
+	GeneratedAt time.Time    `json:"generated_at"`
+}
+
+// WidgetData is what one widget shows. A widget that failed to load has an
+// error instead, the rest of the dashboard is still returned
+type WidgetData struct {
+	ID    string      `json:"id"`
+	Type  string      `json:"type"`
+	Data  interface{} `json:"data,omitempty"`
+	Error string      `json:"error,omitempty"`
+}
+
+// Item is a bookmark in a list widget
+type Item struct {
+	ID          uint      `json:"id"`
+	URL         string    `json:"url"`
+	Title       string    `json:"title"`
+	Favicon     string    `json:"favicon,omitempty"`
+	ReadingTime int       `json:"reading_time,omitempty"` // estimated minutes
+	SavedAt     time.Time `json:"saved_at"`
+}
+
+// NetworkTrend is a page several people the user follows saved recently
+type NetworkTrend struct {
+	URL         string    `json:"url"`
+	Title       string    `json:"title"`
+	Favicon     string    `json:"favicon,omitempty"`
+	Savers      int       `json:"savers"`
+	SavedBy     []string  `json:"saved_by"` // usernames
+	LastSavedAt time.Time `json:"last_saved_at"`
+	Saved       bool      `json:"saved"` // the user has it too
+}
+
+// BrokenLink is a bookmark whose latest link check failed
+type BrokenLink struct {
+	BookmarkID   uint                `json:"bookmark_id"`
+	URL          string              `json:"url"`
+	Title        string              `json:"title"`
+	Status       database.LinkStatus `json:"status"`
+	StatusCode   int                 `json:"status_code,omitempty"`
+	ErrorMessage string              `json:"error_message,omitempty"`
+	CheckedAt    time.Time           `json:"checked_at"`
+}
+
+// StatsChart is the number of bookmarks saved per day
+type StatsChart struct {
+	Timezone       string `json:"timezone"`
+	Days           []Day  `json:"days"`
+	Added          int    `json:"added"` // over the whole range
+	TotalBookmarks int64  `json:"total_bookmarks"`
+}
+
+// Day is one point of the stats chart, a date in the user's timezone
+type Day struct {
+	Date      string `json:"date"` // YYYY-MM-DD
+	Bookmarks int    `json:"bookmarks"`
+}
+
+// Service composes dashboards from the user's data
+type Service struct {
+	db      *gorm.DB
+	reading ReadingList
+	now     func() time.Time
+}
+
+// NewService creates a new dashboard service
+func NewService(db *gorm.DB) *Service {
+	return &Service{db: db, now: time.Now}
+}
+
+// SetReadingList configures where the reading list widget comes from
+func (s *Service) SetReadingList(reading ReadingList) {
+	s.reading = reading
+}
+
+// GetDashboard resolves every widget of the user's layout in one go.
+// Widgets asking for the same data share one query
+func (s *Service) GetDashboard(ctx context.Context, userID uint) (*Dashboard, error) {
+	layout, customized, err := s.GetLayout(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	dashboard := &Dashboard{
+		Layout:      layout,
+		Customized:  customized,
+		Widgets:     make([]WidgetData, 0, len(layout.Widgets)),
+		GeneratedAt: s.now().UTC(),
+	}
+	type result struct {
+		data interface{}
+		err  error
+	}
+	resolved := map[string]result{}
+	for _, widget := range layout.Widgets {
+		key := fmt.Sprintf("%s:%d:%d", widget.Type, widget.Limit, widget.Days)
+		r, ok := resolved[key]
+		if !ok {
+			r.data, r.err = s.resolve(ctx, userID, widget)
+			resolved[key] = r
+		}
+
+		data := WidgetData{ID: widget.ID, Type: widget.Type, Data: r.data}
+		if r.err != nil {
+			data.Data = nil
+			data.Error = fmt.Sprintf("failed to load %s", widget.Type)
+		}
+		dashboard.Widgets = append(dashboard.Widgets, data)
+	}
+	return dashboard, nil
+}
+
+// GetLayout returns the user's saved layout, or the default one and false
+func (s *Service) GetLayout(ctx context.Context, userID uint) (Layout, bool, error) {
+	prefs, err := s.preferences(ctx, userID)
+	if err != nil {
+		return Layout{}, false, err
+	}
+
+	raw, ok := prefs[preferencesKey]
+	if !ok {
+		return DefaultLayout(), false, nil
+	}
+	var layout Layout
+	if err := json.Unmarshal(raw, &layout); err != nil || layout.normalize() != nil {
+		// A layout that no longer validates is replaced rather than breaking the dashboard
+		return DefaultLayout(), false, nil
+	}
+	return layout, true, nil
+}
+
+// SaveLayout validates a layout and stores it in the user's preferences
+func (s *Service) SaveLayout(ctx context.Context, userID uint, layout Layout) (*Layout, error) {
+	if layout.Widgets == nil {
+		layout.Widgets = []Widget{}
+	}
+	if err := layout.normalize(); err != nil {
+		return nil, err
+	}
+
+	raw, err := json.Marshal(layout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode layout: %w", err)
+	}
+	if err := s.updatePreferences(ctx, userID, func(prefs map[string]json.RawMessage) {
+		prefs[preferencesKey] = raw
+	}); err != nil {
+		return nil, err
+	}
+	return &layout, nil
+}
+
+// ResetLayout drops the saved layout so the default one is shown again
+func (s *Service) ResetLayout(ctx context.Context, userID uint) error {
+	return s.updatePreferences(ctx, userID, func(prefs map[string]json.RawMessage) {
+		delete(prefs, preferencesKey)
+	})
+}
+
+func (s *Service) resolve(ctx context.Context, userID uint, widget Widget) (interface{}, error) {
+	switch widget.Type {
+	case WidgetRecentBookmarks:
+		return s.recentBookmarks(ctx, userID, widget.Limit)
+	case WidgetReadingList:
+		return s.readingList(ctx, userID, widget.Limit)
+	case WidgetTrendingNetwork:
+		return s.trendingNetwork(ctx, userID, widget.Limit)
+	case WidgetBrokenLinks:
+		return s.brokenLinks(ctx, userID, widget.Limit)
+	case WidgetStatsChart:
+		return s.statsChart(ctx, userID, widget.Days)
+	}
+	return nil, fmt.Errorf("unknown widget type %q", widget.Type)
+}
+
+func (s *Service) recentBookmarks(ctx context.Context, userID uint, limit int) ([]Item, error) {
+	var bookmarks []database.Bookmark
+	if err := s.db.WithContext(ctx).Where("user_id = ?", userID).
+		Order("created_at DESC, id DESC").Limit(limit).Find(&bookmarks).Error; err != nil {
+		return nil, fmt.Errorf("failed to get recent bookmarks: %w", err)
+	}
+	return items(bookmarks), nil
+}
+
+func (s *Service) readingList(ctx context.Context, userID uint, limit int) ([]Item, error) {
+	if s.reading == nil {
+		return nil, errors.New("reading list is not configured")
+	}
+	bookmarks, err := s.reading.Unfinished(ctx, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return items(bookmarks), nil
+}
+
+// trendingNetwork ranks the pages the people a user follows saved this week
+// by how many of them saved it. Only bookmarks they published, in public
+// collections of a public profile, are considered, leaving out those
+// suppressed for low quality
+func (s *Service) trendingNetwork(ctx context.Context, userID uint, limit int) ([]NetworkTrend, error) {
+	trends := []NetworkTrend{}
+
+	var following []string
+	if err := s.db.WithContext(ctx).Model(&community.UserFollow{}).
+		Where("follower_id = ? AND status = ?", strconv.FormatUint(uint64(userID), 10), "active").
+		Pluck("following_id", &following).Error; err != nil {
+		return nil, fmt.Errorf("failed to get followed users: %w", err)
+	}
+	ids := make([]uint, 0, len(following))
+	for _, id := range following {
+		if parsed, err := strconv.ParseUint(id, 10, 32); err == nil {
+			ids = append(ids, uint(parsed))
+		}
+	}
+	if len(ids) == 0 {
+		return trends, nil
+	}
+
+	var users []database.User
+	if err := s.db.WithContext(ctx).Select("id", "username", "preferences").
+		Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, fmt.Errorf("failed to get followed users: %w", err)
+	}
+	usernames := map[uint]string{}
+	public := make([]uint, 0, len(users))
+	for _, u := range users {
+		if user.ProfileIsPublic(u.Preferences) {
+			usernames[u.ID] = u.Username
+			public = append(public, u.ID)
+		}
+	}
+	if len(public) == 0 {
+		return trends, nil
+	}
+
+	published := s.db.Table("bookmark_collections").
+		Select("bookmark_collections.bookmark_id").
+		Joins("JOIN collections ON collections.id = bookmark_collections.collection_id AND collections.deleted_at IS NULL").
+		Where("collections.user_id IN ? AND collections.visibility = ?", public, "public")
+	var saves []database.Bookmark
+	if err := s.db.WithContext(ctx).Select("id", "user_id", "url", "title", "favicon", "created_at").
+		Where("user_id IN ? AND created_at >= ? AND id IN (?)", public, s.now().Add(-networkWindow), published).
+		Where("id NOT IN (?)", database.SuppressedBookmarks(s.db)).
+		Order("created_at DESC").Find(&saves).Error; err != nil {
+		return nil, fmt.Errorf("failed to get network bookmarks: %w", err)
+	}
+
+	byURL := map[string]*NetworkTrend{}
+	savers := map[string]map[uint]bool{}
+	for _, save := range saves {
+		trend, ok := byURL[save.URL]
+		if !ok {
+			// Saves are newest first, so the first one names the trend
+			trend = &NetworkTrend{URL: save.URL, Title: save.Title, Favicon: save.Favicon, SavedBy: []string{}, LastSavedAt: save.CreatedAt}
+			byURL[save.URL] = trend
+			savers[save.URL] = map[uint]bool{}
+		}
+		if savers[save.URL][save.UserID] {
+			continue
+		}
+		savers[save.URL][save.UserID] = true
+		trend.Savers++
+		trend.SavedBy = append(trend.SavedBy, usernames[save.UserID])
+	}
+	for _, trend := range byURL {
+		trends = append(trends, *trend)
+	}
+	sort.Slice(trends, func(i, j int) bool {
+		if trends[i].Savers != trends[j].Savers {
+			return trends[i].Savers > trends[j].Savers
+		}
+		return trends[i].LastSavedAt.After(trends[j].LastSavedAt)
+	})
+	if len(trends) > limit {
+		trends = trends[:limit]
+	}
+
+	urls := make([]string, 0, len(trends))
+	for _, trend := range trends {
+		urls = append(urls, trend.URL)
+	}
+	var own []string
+	if len(urls) > 0 {
+		if err := s.db.WithContext(ctx).Model(&database.Bookmark{}).
+			Where("user_id = ? AND url IN ?", userID, urls).Pluck("url", &own).Error; err != nil {
+			return nil, fmt.Errorf("failed to check saved bookmarks: %w", err)
+		}
+	}
+	saved := make(map[string]bool, len(own))
+	for _, url := range own {
+		saved[url] = true
+	}
+	for i := range trends {
+		trends[i].Saved = saved[trends[i].URL]
+	}
+	return trends, nil
+}
+
+// brokenLinks lists the bookmarks whose most recent link check failed,
+// most recently checked first
+func (s *Service) brokenLinks(ctx context.Context, userID uint, limit int) ([]BrokenLink, error) {
+	latest := s.db.Table("link_checks").
+		Select("link_checks.bookmark_id, MAX(link_checks.checked_at) AS checked_at").
+		Joins("JOIN bookmarks ON bookmarks.id = link_checks.bookmark_id").
+		Where("bookmarks.user_id = ? AND link_checks.deleted_at IS NULL", userID).
+		Group("link_checks.bookmark_id")
+
+	links := []BrokenLink{}
+	if err := s.db.WithContext(ctx).Table("link_checks").
+		Select("bookmarks.id AS bookmark_id, bookmarks.url, bookmarks.title, link_checks.status, link_checks.status_code, link_checks.error_message, link_checks.checked_at").
+		Joins("JOIN (?) latest ON latest.bookmark_id = link_checks.bookmark_id AND latest.checked_at = link_checks.checked_at", latest).
+		Joins("JOIN bookmarks ON bookmarks.id = link_checks.bookmark_id AND bookmarks.deleted_at IS NULL").
+		Where("bookmarks.user_id = ? AND link_checks.deleted_at IS NULL AND link_checks.status IN ?",
+			userID, []database.LinkStatus{database.LinkStatusBroken, database.LinkStatusTimeout}).
+		Order("link_checks.checked_at DESC").
+		Limit(limit).
+		Scan(&links).Error; err != nil {
+		return nil, fmt.Errorf("failed to get broken links: %w", err)
+	}
+	return links, nil
+}
+
+// statsChart counts the bookmarks saved on each of the last days, today
+// included, in the user's timezone
+func (s *Service) statsChart(ctx context.Context, userID uint, days int) (*StatsChart, error) {
+	settings := locale.ForUser(ctx, s.db, userID)
+	now := settings.In(s.now())
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	start := today.AddDate(0, 0, -(days - 1))
+
+	var bookmarks []database.Bookmark
+	if err := s.db.WithContext(ctx).Select("created_at").
+		Where("user_id = ? AND created_at >= ?", userID, start).
+		Find(&bookmarks).Error; err != nil {
+		return nil, fmt.Errorf("failed to get bookmark stats: %w", err)
+	}
+	counts := map[string]int{}
+	for _, bookmark := range bookmarks {
+		counts[settings.In(bookmark.CreatedAt).Format("2006-01-02")]++
+	}
+
+	chart := &StatsChart{Timezone: settings.Timezone(), Days: make([]Day, 0, days)}
+	for day := start; !day.After(today); day = day.AddDate(0, 0, 1) {
+		date := day.Format("2006-01-02")
+		chart.Days = append(chart.Days, Day{Date: date, Bookmarks: counts[date]})
+		chart.Added += counts[date]
+	}
+	if err := s.db.WithContext(ctx).Model(&database.Bookmark{}).
+		Where("user_id = ?", userID).Count(&chart.TotalBookmarks).Error; err != nil {
+		return nil, fmt.Errorf("failed to count bookmarks: %w", err)
+	}
+	return chart, nil
+}
+
+func items(bookmarks []database.Bookmark) []Item {
+	result := make([]Item, 0, len(bookmarks))
+	for _, bookmark := range bookmarks {
+		result = append(result, Item{
+			ID:          bookmark.ID,
+			URL:         bookmark.URL,
+			Title:       bookmark.Title,
+			Favicon:     bookmark.Favicon,
+			ReadingTime: bookmark.ReadingTime,
+			SavedAt:     bookmark.CreatedAt,
+		})
+	}
+	return result
+}
+
+// preferences reads the users.preferences JSON, keeping the keys this
+// package doesn't know about
+func (s *Service) preferences(ctx context.Context, userID uint) (map[string]json.RawMessage, error) {
+	var u database.User
+	if err := s.db.WithContext(ctx).Select("id", "preferences").First(&u, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get preferences: %w", err)
+	}
+
+	prefs := map[string]json.RawMessage{}
+	if u.Preferences != "" {
+		if err := json.Unmarshal([]byte(u.Preferences), &prefs); err != nil {
+			return nil, fmt.Errorf("failed to parse preferences: %w", err)
+		}
+	}
+	return prefs, nil
+}
+
+func (s *Service) updatePreferences(ctx context.Context, userID uint, update func(map[string]json.RawMessage)) error {
+	prefs, err := s.preferences(ctx, userID)
+	if err != nil {
+		return err
+	}
+	update(prefs)
+
+	raw, err := json.Marshal(prefs)
+	if err != nil {
+		return fmt.Errorf("failed to encode preferences: %w", err)
+	}
+	if err := s.db.WithContext(ctx).Model(&database.User{}).Where("id = ?", userID).
+		Update("preferences", string(raw)).Error; err != nil {
+		return fmt.Errorf("failed to save preferences: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package dashboard
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bookmark-sync-service/backend/internal/community"
+	"bookmark-sync-service/backend/internal/reading"
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/testfactory"
+)
+
+func TestLayout(t *testing.T) {
+	db := testfactory.NewDB(t)
+	factory := testfactory.New(t, db)
+	owner := factory.User(func(u *database.User) { u.Preferences = `{"theme":"dark","timezone":"Asia/Taipei"}` })
+	service := NewService(db)
+	ctx := context.Background()
+
+	layout, customized, err := service.GetLayout(ctx, owner.ID)
+	require.NoError(t, err)
+	assert.False(t, customized)
+	assert.Equal(t, DefaultLayout(), layout)
+
+	t.Run("rejects invalid layouts", func(t *testing.T) {
+		for name, widgets := range map[string][]Widget{
+			"unknown type": {{ID: "a", Type: "weather", W: 4, H: 2}},
+			"duplicate id": {{ID: "a", Type: WidgetRecentBookmarks, W: 4, H: 2}, {ID: "a", Type: WidgetBrokenLinks, W: 4, H: 2}},
+			"off the grid": {{ID: "a", Type: WidgetRecentBookmarks, X: 10, W: 4, H: 2}},
+			"limit":        {{ID: "a", Type: WidgetRecentBookmarks, W: 4, H: 2, Limit: 500}},
+		} {
+			_, err := service.SaveLayout(ctx, owner.ID, Layout{Widgets: widgets})
+			assert.ErrorIs(t, err, ErrInvalidLayout, name)
+		}
+	})
+
+	t.Run("saves into the preferences", func(t *testing.T) {
+		saved, err := service.SaveLayout(ctx, owner.ID, Layout{Widgets: []Widget{
+			{ID: "chart", Type: WidgetStatsChart, W: 12, H: 3, Limit: 5},
+			{ID: "recent", Type: WidgetRecentBookmarks, Y: 3, W: 6, H: 4},
+		}})
+		require.NoError(t, err)
+		assert.Equal(t, Widget{ID: "chart", Type: WidgetStatsChart, W: 12, H: 3, Days: defaultDays}, saved.Widgets[0])
+		assert.Equal(t, defaultLimit, saved.Widgets[1].Limit)
+
+		layout, customized, err := service.GetLayout(ctx, owner.ID)
+		require.NoError(t, err)
+		assert.True(t, customized)
+		assert.Equal(t, *saved, layout)
+
+		// Other preferences are kept
+		var stored database.User
+		require.NoError(t, db.First(&stored, owner.ID).Error)
+		var prefs map[string]json.RawMessage
+		require.NoError(t, json.Unmarshal([]byte(stored.Preferences), &prefs))
+		assert.JSONEq(t, `"dark"`, string(prefs["theme"]))
+		assert.JSONEq(t, `"Asia/Taipei"`, string(prefs["timezone"]))
+	})
+
+	t.Run("resets to the default", func(t *testing.T) {
+		require.NoError(t, service.ResetLayout(ctx, owner.ID))
+		layout, customized, err := service.GetLayout(ctx, owner.ID)
+		require.NoError(t, err)
+		assert.False(t, customized)
+		assert.Equal(t, DefaultLayout(), layout)
+	})
+
+	_, _, err = service.GetLayout(ctx, 9999)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+}
+
+func TestGetDashboard(t *testing.T) {
+	db := testfactory.NewDB(t)
+	require.NoError(t, community.AutoMigrate(db))
+	factory := testfactory.New(t, db)
+	ctx := context.Background()
+
+	// Wednesday noon in Taipei
+	now := time.Date(2026, 10, 14, 4, 0, 0, 0, time.UTC)
+	owner := factory.User(func(u *database.User) { u.Preferences = `{"timezone":"Asia/Taipei"}` })
+	service := NewService(db)
+	service.SetReadingList(reading.NewService(db))
+	service.now = func() time.Time { return now }
+
+	older := factory.Bookmark(owner.ID, func(b *database.Bookmark) {
+		b.Title = "Older"
+		b.CreatedAt = now.AddDate(0, 0, -2)
+	})
+	later := factory.Bookmark(owner.ID, func(b *database.Bookmark) {
+		b.Title = "Read later"
+		b.Tags = `["read-later"]`
+		b.CreatedAt = now.Add(-time.Hour)
+	})
+	shared := factory.Bookmark(owner.ID, func(b *database.Bookmark) {
+		b.URL = "https://example.com/popular"
+		b.CreatedAt = now.Add(-3 * time.Hour)
+	})
+
+	// Link checks: the older bookmark broke, the later one recovered
+	checks := []database.LinkCheck{
+		{BookmarkID: older.ID, URL: older.URL, Status: database.LinkStatusBroken, StatusCode: 404, CheckedAt: now.Add(-time.Hour)},
+		{BookmarkID: later.ID, URL: later.URL, Status: database.LinkStatusTimeout, CheckedAt: now.Add(-2 * time.Hour)},
+		{BookmarkID: later.ID, URL: later.URL, Status: database.LinkStatusActive, StatusCode: 200, CheckedAt: now.Add(-time.Minute)},
+	}
+	require.NoError(t, db.Create(&checks).Error)
+
+	// The owner follows two public profiles and a private one
+	public := `{"profileVisibility":"public"}`
+	alice := factory.User(func(u *database.User) { u.Username = "alice"; u.Preferences = public })
+	bob := factory.User(func(u *database.User) { u.Username = "bob"; u.Preferences = public })
+	hidden := factory.User(func(u *database.User) { u.Username = "hidden" })
+	for _, followed := range []*database.User{alice, bob, hidden} {
+		require.NoError(t, db.Create(&community.UserFollow{
+			FollowerID:  strconv.FormatUint(uint64(owner.ID), 10),
+			FollowingID: strconv.FormatUint(uint64(followed.ID), 10),
+			Status:      "active",
+		}).Error)
+	}
+	publish := func(u *database.User, url string, private bool, at time.Time) {
+		visibility := "public"
+		if private {
+			visibility = "private"
+		}
+		collection := factory.Collection(u.ID, func(c *database.Collection) { c.Visibility = visibility })
+		factory.AddToCollection(collection, factory.Bookmark(u.ID, func(b *database.Bookmark) {
+			b.URL = url
+			b.Title = "Page " + url
+			b.CreatedAt = at
+		}))
+	}
+	publish(alice, "https://example.com/popular", false, now.Add(-time.Hour))
+	publish(bob, "https://example.com/popular", false, now.Add(-2*time.Hour))
+	publish(bob, "https://example.com/niche", false, now.Add(-30*time.Minute))
+	publish(bob, "https://example.com/secret", true, now)
+	publish(alice, "https://example.com/old", false, now.AddDate(0, 0, -10))
+	publish(hidden, "https://example.com/hidden", false, now)
+
+	_, err := service.SaveLayout(ctx, owner.ID, Layout{Widgets: []Widget{
+		{ID: "recent", Type: WidgetRecentBookmarks, W: 6, H: 4, Limit: 2},
+		{ID: "recent-copy", Type: WidgetRecentBookmarks, X: 6, W: 6, H: 4, Limit: 2},
+		{ID: "reading", Type: WidgetReadingList, Y: 4, W: 6, H: 4},
+		{ID: "trending", Type: WidgetTrendingNetwork, X: 6, Y: 4, W: 6, H: 4},
+		{ID: "broken", Type: WidgetBrokenLinks, Y: 8, W: 6, H: 4},
+		{ID: "stats", Type: WidgetStatsChart, Y: 12, W: 12, H: 3, Days: 7},
+	}})
+	require.NoError(t, err)
+
+	dashboard, err := service.GetDashboard(ctx, owner.ID)
+	require.NoError(t, err)
+	assert.True(t, dashboard.Customized)
+	require.Len(t, dashboard.Widgets, 6)
+	for _, widget := range dashboard.Widgets {
+		assert.Empty(t, widget.Error, widget.ID)
+	}
+
+	recent := dashboard.Widgets[0].Data.([]Item)
+	require.Len(t, recent, 2)
+	assert.Equal(t, []uint{later.ID, shared.ID}, []uint{recent[0].ID, recent[1].ID})
+	assert.Equal(t, recent, dashboard.Widgets[1].Data)
+
+	readingList := dashboard.Widgets[2].Data.([]Item)
+	require.Len(t, readingList, 1)
+	assert.Equal(t, "Read later", readingList[0].Title)
+
+	trends := dashboard.Widgets[3].Data.([]NetworkTrend)
+	require.Len(t, trends, 2)
+	assert.Equal(t, "https://example.com/popular", trends[0].URL)
+	assert.Equal(t, 2, trends[0].Savers)
+	assert.Equal(t, []string{"alice", "bob"}, trends[0].SavedBy)
+	assert.True(t, trends[0].Saved)
+	assert.Equal(t, "https://example.com/niche", trends[1].URL)
+	assert.False(t, trends[1].Saved)
+
+	broken := dashboard.Widgets[4].Data.([]BrokenLink)
+	require.Len(t, broken, 1)
+	assert.Equal(t, older.ID, broken[0].BookmarkID)
+	assert.Equal(t, 404, broken[0].StatusCode)
+
+	chart := dashboard.Widgets[5].Data.(*StatsChart)
+	assert.Equal(t, "Asia/Taipei", chart.Timezone)
+	require.Len(t, chart.Days, 7)
+	assert.Equal(t, Day{Date: "2026-10-14", Bookmarks: 2}, chart.Days[6])
+	assert.Equal(t, Day{Date: "2026-10-12", Bookmarks: 1}, chart.Days[4])
+	assert.Equal(t, 3, chart.Added)
+	assert.Equal(t, int64(3), chart.TotalBookmarks)
+
+	t.Run("a failing widget doesn't fail the dashboard", func(t *testing.T) {
+		service.SetReadingList(nil)
+		dashboard, err := service.GetDashboard(ctx, owner.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "failed to load reading_list", dashboard.Widgets[2].Error)
+		assert.Nil(t, dashboard.Widgets[2].Data)
+		assert.NotNil(t, dashboard.Widgets[0].Data)
+	})
+}
@@ -0,0 +1,57 @@
+package delicious
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"bookmark-sync-service/backend/internal/oauth"
+)
+
+// realm is announced to clients that call without credentials, so they
+// prompt for a user name and password
+const realm = `Basic realm="del.icio.us API"`
+
+// credentials returns the token a legacy client sent and the user name it
+// sent with it. Clients put the token in the basic auth password, or pass
+// auth_token=user:TOKEN the way Pinboard clients do. A bearer header is
+// accepted too. The user name is informational: the token decides the user
+func credentials(c *gin.Context) (user, token string) {
+	if user, password, ok := c.Request.BasicAuth(); ok {
+		return user, password
+	}
+	if authToken := c.Query("auth_token"); authToken != "" {
+		if i := strings.LastIndexByte(authToken, ':'); i >= 0 {
+			return authToken[:i], authToken[i+1:]
+		}
+		return "", authToken
+	}
+	if bearer := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "); bearer != c.GetHeader("Authorization") {
+		return "", bearer
+	}
+	return "", ""
+}
+
+// authenticate hands the token on as a bearer token to the app token
+// middleware, requiring the given scopes, or to the user token middleware
+func (h *Handler) authenticate(scopes ...string) gin.HandlerFunc {
+	appAuth := h.appAuth(scopes...)
+	return func(c *gin.Context) {
+		user, token := credentials(c)
+		if token == "" {
+			c.Header("WWW-Authenticate", realm)
+			respond(c, http.StatusUnauthorized, result{Code: "access denied"})
+			c.Abort()
+			return
+		}
+		c.Request.Header.Set("Authorization", "Bearer "+token)
+		c.Set(contextUser, user)
+
+		if oauth.IsAccessToken(token) {
+			appAuth(c)
+		} else {
+			h.userAuth(c)
+		}
+	}
+}
@@ -0,0 +1,360 @@
+package delicious
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"bookmark-sync-service/backend/internal/bookmark"
+	"bookmark-sync-service/backend/internal/oauth"
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/tags"
+	"bookmark-sync-service/backend/pkg/utils"
+)
+
+// contextUser holds the user name the client authenticated with
+const contextUser = "delicious_user"
+
+// maxPosts caps the posts one posts/all call returns
+const maxPosts = 10000
+
+// timeFormat is the timestamp format of the Delicious API
+const timeFormat = "2006-01-02T15:04:05Z"
+
+// Handler serves the Delicious v1 API on top of the bookmark service, for
+// the bookmarking tools and mobile apps that still speak it
+type Handler struct {
+	bookmarks *bookmark.Service
+	userAuth  gin.HandlerFunc
+	appAuth   func(scopes ...string) gin.HandlerFunc
+}
+
+// NewHandler creates a Delicious API handler. Tokens are checked by
+// userAuth, the JWT middleware, or by appAuth for app access tokens
+func NewHandler(bookmarks *bookmark.Service, userAuth gin.HandlerFunc, appAuth func(scopes ...string) gin.HandlerFunc) *Handler {
+	return &Handler{bookmarks: bookmarks, userAuth: userAuth, appAuth: appAuth}
+}
+
+// RegisterRoutes registers the Delicious API routes. The handlers in after
+// run once the client is authenticated
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup, after ...gin.HandlerFunc) {
+	methods := []string{http.MethodGet, http.MethodPost}
+	route := func(path, scope string, handler gin.HandlerFunc) {
+		chain := append([]gin.HandlerFunc{h.authenticate(scope)}, after...)
+		router.Match(methods, path, append(chain, handler)...)
+	}
+
+	route("/posts/add", oauth.ScopeBookmarksWrite, h.AddPost)
+	route("/posts/delete", oauth.ScopeBookmarksWrite, h.DeletePost)
+	route("/posts/all", oauth.ScopeBookmarksRead, h.AllPosts)
+	route("/posts/update", oauth.ScopeBookmarksRead, h.LastUpdate)
+	route("/tags/get", oauth.ScopeBookmarksRead, h.GetTags)
+}
+
+// result is the outcome of a write call; "done" on success
+type result struct {
+	XMLName xml.Name `xml:"result" json:"-"`
+	Code    string   `xml:"code,attr" json:"result_code"`
+}
+
+// post is a bookmark as the Delicious API describes it
+type post struct {
+	Href        string `xml:"href,attr" json:"href"`
+	Description string `xml:"description,attr" json:"description"`
+	Extended    string `xml:"extended,attr" json:"extended"`
+	Hash        string `xml:"hash,attr" json:"hash"`
+	Tag         string `xml:"tag,attr" json:"tags"`
+	Time        string `xml:"time,attr" json:"time"`
+}
+
+// postList is the posts/all response; its JSON form is the bare list
+type postList struct {
+	XMLName xml.Name `xml:"posts"`
+	User    string   `xml:"user,attr"`
+	Tag     string   `xml:"tag,attr,omitempty"`
+	Posts   []post   `xml:"post"`
+}
+
+func (l postList) MarshalJSON() ([]byte, error) {
+	if l.Posts == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(l.Posts)
+}
+
+type tagCount struct {
+	Count int    `xml:"count,attr"`
+	Tag   string `xml:"tag,attr"`
+}
+
+// tagList is the tags/get response; its JSON form maps tags to counts
+type tagList struct {
+	XMLName xml.Name   `xml:"tags"`
+	Tags    []tagCount `xml:"tag"`
+}
+
+func (l tagList) MarshalJSON() ([]byte, error) {
+	counts := make(map[string]int, len(l.Tags))
+	for _, tag := range l.Tags {
+		counts[tag.Tag] = tag.Count
+	}
+	return json.Marshal(counts)
+}
+
+// update is the posts/update response
+type update struct {
+	XMLName xml.Name `xml:"update" json:"-"`
+	Time    string   `xml:"time,attr" json:"update_time"`
+}
+
+// AddPost saves a bookmark
+// @Summary Delicious posts/add
+// @Description Save a URL. description is the title, extended the description and tags are space separated. An existing bookmark of the URL is replaced unless replace=no
+// @Tags delicious
+// @Produce xml
+// @Param url query string true "URL"
+// @Param description query string false "Title"
+// @Param extended query string false "Description"
+// @Param tags query string false "Space separated tags"
+// @Param replace query string false "no to keep an existing bookmark"
+// @Success 200 {object} result
+// @Router /v1/posts/add [get]
+func (h *Handler) AddPost(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	rawURL := strings.TrimSpace(param(c, "url"))
+	if rawURL == "" {
+		respond(c, http.StatusOK, result{Code: "missing url"})
+		return
+	}
+	title := strings.TrimSpace(param(c, "description"))
+	if title == "" {
+		title = rawURL
+	}
+	tagList := splitTags(param(c, "tags"))
+
+	existing, err := h.bookmarks.GetByURL(userID, rawURL)
+	if err != nil && err.Error() != "bookmark not found" {
+		respond(c, http.StatusInternalServerError, result{Code: "something went wrong"})
+		return
+	}
+	if existing != nil {
+		if param(c, "replace") == "no" {
+			respond(c, http.StatusOK, result{Code: "item already exists"})
+			return
+		}
+		_, err = h.bookmarks.Update(bookmark.UpdateBookmarkRequest{
+			ID:          existing.ID,
+			UserID:      userID,
+			Title:       title,
+			Description: param(c, "extended"),
+			Tags:        tagList,
+		})
+	} else {
+		_, err = h.bookmarks.Create(bookmark.CreateBookmarkRequest{
+			UserID:      userID,
+			URL:         rawURL,
+			Title:       title,
+			Description: param(c, "extended"),
+			Tags:        tagList,
+			Source:      database.BookmarkSourceAPI,
+			SourceRef:   "delicious",
+		})
+	}
+	if err != nil {
+		respond(c, http.StatusOK, result{Code: err.Error()})
+		return
+	}
+	respond(c, http.StatusOK, result{Code: "done"})
+}
+
+// DeletePost deletes the bookmark of a URL
+// @Summary Delicious posts/delete
+// @Tags delicious
+// @Produce xml
+// @Param url query string true "URL"
+// @Success 200 {object} result
+// @Router /v1/posts/delete [get]
+func (h *Handler) DeletePost(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	existing, err := h.bookmarks.GetByURL(userID, strings.TrimSpace(param(c, "url")))
+	if err != nil {
+		if err.Error() == "bookmark not found" {
+			respond(c, http.StatusOK, result{Code: "item not found"})
+			return
+		}
+		respond(c, http.StatusInternalServerError, result{Code: "something went wrong"})
+		return
+	}
+	if err := h.bookmarks.Delete(existing.ID, userID); err != nil {
+		respond(c, http.StatusInternalServerError, result{Code: "something went wrong"})
+		return
+	}
+	respond(c, http.StatusOK, result{Code: "done"})
+}
+
+// AllPosts lists the user's bookmarks, newest first
+// @Summary Delicious posts/all
+// @Tags delicious
+// @Produce xml
+// @Param tag query string false "Space separated tags the posts must all carry"
+// @Param start query int false "Offset"
+// @Param results query int false "Number of posts"
+// @Success 200 {object} postList
+// @Router /v1/posts/all [get]
+func (h *Handler) AllPosts(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	start, _ := strconv.Atoi(param(c, "start"))
+	results, _ := strconv.Atoi(param(c, "results"))
+	if start < 0 {
+		start = 0
+	}
+	if results <= 0 || results > maxPosts {
+		results = maxPosts
+	}
+	wanted := splitTags(param(c, "tag"))
+
+	req := bookmark.ListBookmarksRequest{UserID: userID, Limit: results, Offset: start}
+	if len(wanted) > 0 {
+		// The bookmark filter matches any tag and its children, so the
+		// page is cut after keeping the posts that carry every tag
+		req = bookmark.ListBookmarksRequest{UserID: userID, Tags: strings.Join(wanted, ",")}
+	}
+	bookmarks, _, err := h.bookmarks.List(req)
+	if err != nil {
+		respond(c, http.StatusInternalServerError, result{Code: "something went wrong"})
+		return
+	}
+	if len(wanted) > 0 {
+		bookmarks = withTags(bookmarks, wanted)
+		bookmarks = bookmarks[min(start, len(bookmarks)):min(start+results, len(bookmarks))]
+	}
+
+	list := postList{User: c.GetString(contextUser), Tag: strings.Join(wanted, " "), Posts: make([]post, 0, len(bookmarks))}
+	for _, b := range bookmarks {
+		if list.User == "" {
+			list.User = b.User.Username
+		}
+		list.Posts = append(list.Posts, toPost(b))
+	}
+	respond(c, http.StatusOK, list)
+}
+
+// LastUpdate returns when the user's bookmarks last changed, so clients can
+// skip posts/all when nothing did
+// @Summary Delicious posts/update
+// @Tags delicious
+// @Produce xml
+// @Success 200 {object} update
+// @Router /v1/posts/update [get]
+func (h *Handler) LastUpdate(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	latest, _, err := h.bookmarks.List(bookmark.ListBookmarksRequest{UserID: userID, SortBy: "updated_at", Limit: 1})
+	if err != nil {
+		respond(c, http.StatusInternalServerError, result{Code: "something went wrong"})
+		return
+	}
+	var at time.Time
+	if len(latest) > 0 {
+		at = latest[0].UpdatedAt
+	}
+	respond(c, http.StatusOK, update{Time: at.UTC().Format(timeFormat)})
+}
+
+// GetTags lists the user's tags with their bookmark counts
+// @Summary Delicious tags/get
+// @Tags delicious
+// @Produce xml
+// @Success 200 {object} tagList
+// @Router /v1/tags/get [get]
+func (h *Handler) GetTags(c *gin.Context) {
+	counts, err := h.bookmarks.TagCounts(utils.GetUserIDFromContext(c))
+	if err != nil {
+		respond(c, http.StatusInternalServerError, result{Code: "something went wrong"})
+		return
+	}
+
+	list := tagList{Tags: make([]tagCount, 0, len(counts))}
+	for tag, count := range counts {
+		list.Tags = append(list.Tags, tagCount{Count: count, Tag: tag})
+	}
+	sort.Slice(list.Tags, func(i, j int) bool { return list.Tags[i].Tag < list.Tags[j].Tag })
+	respond(c, http.StatusOK, list)
+}
+
+// respond writes XML, or JSON when the client asks with format=json
+func respond(c *gin.Context, status int, body interface{}) {
+	if param(c, "format") == "json" {
+		c.JSON(status, body)
+		return
+	}
+	out, err := xml.Marshal(body)
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	c.Data(status, "text/xml; charset=utf-8", append([]byte(xml.Header), out...))
+}
+
+// param reads a query parameter, or a form field of a POST request
+func param(c *gin.Context, name string) string {
+	if value, ok := c.GetQuery(name); ok {
+		return value
+	}
+	return c.PostForm(name)
+}
+
+// splitTags splits a Delicious tag list, which is space separated; commas
+// are accepted too since some clients send them
+func splitTags(raw string) []string {
+	return tags.NormalizeAll(strings.FieldsFunc(raw, func(r rune) bool { return r == ' ' || r == ',' }))
+}
+
+func withTags(bookmarks []*database.Bookmark, wanted []string) []*database.Bookmark {
+	kept := bookmarks[:0]
+	for _, b := range bookmarks {
+		var have []string
+		_ = json.Unmarshal([]byte(b.Tags), &have)
+		all := true
+		for _, tag := range wanted {
+			if !contains(have, tag) {
+				all = false
+				break
+			}
+		}
+		if all {
+			kept = append(kept, b)
+		}
+	}
+	return kept
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+func toPost(b *database.Bookmark) post {
+	var tagList []string
+	_ = json.Unmarshal([]byte(b.Tags), &tagList)
+	hash := md5.Sum([]byte(b.URL))
+	return post{
+		Href:        b.URL,
+		Description: b.Title,
+		Extended:    b.Description,
+		Hash:        hex.EncodeToString(hash[:]),
+		Tag:         strings.Join(tagList, " "),
+		Time:        b.CreatedAt.UTC().Format(timeFormat),
+	}
+}
@@ -0,0 +1,135 @@
+package delicious
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bookmark-sync-service/backend/internal/bookmark"
+	"bookmark-sync-service/backend/internal/oauth"
+	"bookmark-sync-service/backend/pkg/testfactory"
+)
+
+func setupRouter(t *testing.T) (*gin.Engine, uint) {
+	gin.SetMode(gin.TestMode)
+	db := testfactory.NewDB(t)
+	owner := testfactory.New(t, db).User()
+	userID := strconv.FormatUint(uint64(owner.ID), 10)
+
+	// Stand-ins for the JWT and app token middleware
+	userAuth := func(c *gin.Context) {
+		if c.GetHeader("Authorization") != "Bearer user-token" {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		c.Set("user_id", userID)
+		c.Next()
+	}
+	appAuth := func(scopes ...string) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			if c.GetHeader("Authorization") != "Bearer bsa_read" || scopes[0] != oauth.ScopeBookmarksRead {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Set("user_id", userID)
+			c.Next()
+		}
+	}
+
+	router := gin.New()
+	NewHandler(bookmark.NewService(db), userAuth, appAuth).RegisterRoutes(router.Group("/v1"))
+	return router, owner.ID
+}
+
+func call(router *gin.Engine, path string, query url.Values, password string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path+"?"+query.Encode(), nil)
+	if password != "" {
+		req.SetBasicAuth("alice", password)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuthentication(t *testing.T) {
+	router, _ := setupRouter(t)
+
+	w := call(router, "/v1/tags/get", nil, "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, realm, w.Header().Get("WWW-Authenticate"))
+
+	assert.Equal(t, http.StatusUnauthorized, call(router, "/v1/tags/get", nil, "wrong").Code)
+	assert.Equal(t, http.StatusOK, call(router, "/v1/tags/get", nil, "user-token").Code)
+	assert.Equal(t, http.StatusOK, call(router, "/v1/tags/get", url.Values{"auth_token": {"alice:user-token"}}, "").Code)
+
+	// App tokens need the scope of the call
+	assert.Equal(t, http.StatusOK, call(router, "/v1/posts/all", nil, "bsa_read").Code)
+	assert.Equal(t, http.StatusForbidden, call(router, "/v1/posts/add", url.Values{"url": {"https://example.com"}}, "bsa_read").Code)
+}
+
+func TestPosts(t *testing.T) {
+	router, _ := setupRouter(t)
+	add := func(values url.Values) string {
+		w := call(router, "/v1/posts/add", values, "user-token")
+		require.Equal(t, http.StatusOK, w.Code)
+		var res result
+		require.NoError(t, xml.Unmarshal(w.Body.Bytes(), &res))
+		return res.Code
+	}
+
+	assert.Equal(t, "missing url", add(url.Values{}))
+	assert.Equal(t, "done", add(url.Values{"url": {"https://go.dev"}, "description": {"Go"}, "tags": {"dev go"}}))
+	assert.Equal(t, "done", add(url.Values{"url": {"https://example.com"}, "extended": {"An example"}, "tags": {"dev"}}))
+	assert.Equal(t, "item already exists", add(url.Values{"url": {"https://go.dev"}, "replace": {"no"}}))
+	assert.Equal(t, "done", add(url.Values{"url": {"https://go.dev"}, "description": {"The Go site"}, "tags": {"dev,golang"}}))
+
+	w := call(router, "/v1/posts/all", nil, "user-token")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, strings.HasPrefix(w.Body.String(), xml.Header))
+	var list postList
+	require.NoError(t, xml.Unmarshal(w.Body.Bytes(), &list))
+	assert.Equal(t, "alice", list.User)
+	require.Len(t, list.Posts, 2)
+	assert.Equal(t, "https://example.com", list.Posts[0].Href)
+	assert.Equal(t, "https://example.com", list.Posts[0].Description)
+	assert.Equal(t, "An example", list.Posts[0].Extended)
+	assert.Len(t, list.Posts[0].Hash, 32)
+	assert.Equal(t, "The Go site", list.Posts[1].Description)
+	assert.Equal(t, "dev golang", list.Posts[1].Tag)
+
+	// Posts must carry every tag asked for
+	w = call(router, "/v1/posts/all", url.Values{"tag": {"dev golang"}, "format": {"json"}}, "user-token")
+	var posts []post
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &posts))
+	require.Len(t, posts, 1)
+	assert.Equal(t, "https://go.dev", posts[0].Href)
+
+	w = call(router, "/v1/posts/all", url.Values{"start": {"1"}, "results": {"5"}, "format": {"json"}}, "user-token")
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &posts))
+	require.Len(t, posts, 1)
+	assert.Equal(t, "https://go.dev", posts[0].Href)
+
+	w = call(router, "/v1/tags/get", url.Values{"format": {"json"}}, "user-token")
+	var counts map[string]int
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &counts))
+	assert.Equal(t, map[string]int{"dev": 2, "golang": 1}, counts)
+
+	w = call(router, "/v1/posts/update", nil, "user-token")
+	var up update
+	require.NoError(t, xml.Unmarshal(w.Body.Bytes(), &up))
+	assert.NotEqual(t, "0001-01-01T00:00:00Z", up.Time)
+
+	w = call(router, "/v1/posts/delete", url.Values{"url": {"https://go.dev"}}, "user-token")
+	assert.Contains(t, w.Body.String(), `code="done"`)
+	w = call(router, "/v1/posts/delete", url.Values{"url": {"https://go.dev"}}, "user-token")
+	assert.Contains(t, w.Body.String(), `code="item not found"`)
+}
@@ -0,0 +1,51 @@
+package demo
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"bookmark-sync-service/backend/pkg/utils"
+)
+
+// Handler lets visitors start demo sandboxes
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new demo handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes registers the demo route on the public auth group
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	router.POST("/demo", h.Start)
+}
+
+// Start creates a sandbox account with sample data and signs the visitor in
+// @Summary Start a demo session
+// @Description Creates a sandbox account with sample bookmarks that is deleted with all its data at expires_at. Sandboxes cannot share, send webhooks or federate, and have their own rate limits
+// @Tags auth
+// @Produce json
+// @Success 200 {object} Session
+// @Failure 404 {object} utils.ErrorResponse "Demo mode is off"
+// @Failure 429 {object} utils.ErrorResponse
+// @Failure 503 {object} utils.ErrorResponse "Too many live sandboxes"
+// @Router /auth/demo [post]
+func (h *Handler) Start(c *gin.Context) {
+	session, err := h.service.Start(c.Request.Context(), c.ClientIP())
+	switch {
+	case errors.Is(err, ErrDisabled):
+		utils.ErrorResponse(c, http.StatusNotFound, "DEMO_DISABLED", err.Error(), nil)
+	case errors.Is(err, ErrRateLimited):
+		utils.ErrorResponse(c, http.StatusTooManyRequests, "TOO_MANY_REQUESTS", err.Error(), nil)
+	case errors.Is(err, ErrFull):
+		utils.ErrorResponse(c, http.StatusServiceUnavailable, "DEMO_FULL", err.Error(), nil)
+	case err != nil:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to start demo session", nil)
+	default:
+		utils.SuccessResponse(c, session, "Demo session started")
+	}
+}
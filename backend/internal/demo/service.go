@@ -0,0 +1,273 @@
+// Package demo runs the guest mode of a hosted demo: anonymous visitors get
+// a sandbox account with sample data that lives for a configured time, after
+// which the worker deletes it with everything it created
+package demo
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/internal/auth"
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/redis"
+)
+
+const (
+	// purgeBatchSize bounds the sandboxes deleted per cleanup run
+	purgeBatchSize = 100
+	// emailDomain is reserved, so sandbox addresses never reach anyone
+	emailDomain = "demo.invalid"
+)
+
+// ownerColumns reference the owner of a row in tables a sandbox can write to
+var ownerColumns = []string{"user_id", "owner_id", "inviter_id", "follower_id", "following_id"}
+
+var (
+	// ErrDisabled is returned when demo mode is off
+	ErrDisabled = errors.New("demo mode is disabled")
+	// ErrRateLimited is returned when a client IP started too many sandboxes
+	ErrRateLimited = errors.New("too many demo sessions from this address, try again later")
+	// ErrFull is returned when the live sandboxes reach the configured limit
+	ErrFull = errors.New("the demo is full, try again later")
+)
+
+// Seeder fills a new sandbox with sample data, e.g. the onboarding service
+type Seeder interface {
+	SeedSampleContent(ctx context.Context, userID uint) error
+}
+
+// TokenIssuer signs in a sandbox, e.g. the auth service
+type TokenIssuer interface {
+	IssueTokens(ctx context.Context, user *database.User) (*auth.AuthResponse, error)
+}
+
+// Session is a new sandbox with its tokens
+type Session struct {
+	*auth.AuthResponse
+	ExpiresAt time.Time `json:"expires_at"` // when the sandbox and its data are deleted
+}
+
+// Service starts and deletes demo sandboxes
+type Service struct {
+	cfg    config.DemoConfig
+	db     *gorm.DB
+	redis  *redis.Client
+	seeder Seeder
+	tokens TokenIssuer
+	logger *zap.Logger
+	now    func() time.Time
+}
+
+// NewService creates a demo service. The worker only deletes sandboxes, so
+// it passes no Redis client and sets no token issuer
+func NewService(cfg config.DemoConfig, db *gorm.DB, redisClient *redis.Client, logger *zap.Logger) *Service {
+	return &Service{cfg: cfg, db: db, redis: redisClient, logger: logger, now: time.Now}
+}
+
+// SetSeeder makes new sandboxes start with sample data
+func (s *Service) SetSeeder(seeder Seeder) {
+	s.seeder = seeder
+}
+
+// SetTokenIssuer sets who signs in new sandboxes
+func (s *Service) SetTokenIssuer(tokens TokenIssuer) {
+	s.tokens = tokens
+}
+
+// Start creates a sandbox for a visitor and signs them in
+func (s *Service) Start(ctx context.Context, ip string) (*Session, error) {
+	if !s.cfg.Enabled || s.tokens == nil {
+		return nil, ErrDisabled
+	}
+	if s.redis != nil && s.cfg.SessionsPerHour > 0 {
+		started, err := s.redis.IncrementWithExpiration(ctx, "demo:sessions:"+ip, time.Hour)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count demo sessions: %w", err)
+		}
+		if started > int64(s.cfg.SessionsPerHour) {
+			return nil, ErrRateLimited
+		}
+	}
+
+	now := s.now()
+	db := s.db.WithContext(ctx)
+	if s.cfg.MaxSessions > 0 {
+		var live int64
+		if err := db.Model(&database.User{}).Where("demo_expires_at > ?", now).Count(&live).Error; err != nil {
+			return nil, fmt.Errorf("failed to count demo sessions: %w", err)
+		}
+		if live >= int64(s.cfg.MaxSessions) {
+			return nil, ErrFull
+		}
+	}
+
+	suffix := make([]byte, 6)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, fmt.Errorf("failed to generate demo account name: %w", err)
+	}
+	name := "demo-" + hex.EncodeToString(suffix)
+	expiresAt := now.Add(time.Duration(s.cfg.TTL) * time.Minute)
+	user := &database.User{
+		Email:         name + "@" + emailDomain,
+		Username:      name,
+		DisplayName:   "Demo user",
+		SupabaseID:    "demo:" + name,
+		Preferences:   `{"theme": "light", "gridSize": "medium", "defaultView": "grid"}`,
+		Plan:          s.cfg.Plan,
+		DemoExpiresAt: &expiresAt,
+	}
+	if err := db.Create(user).Error; err != nil {
+		return nil, fmt.Errorf("failed to create demo account: %w", err)
+	}
+
+	if s.seeder != nil {
+		if err := s.seeder.SeedSampleContent(ctx, user.ID); err != nil {
+			s.logger.Warn("Failed to seed demo sample content", zap.Uint("user_id", user.ID), zap.Error(err))
+		}
+	}
+
+	tokens, err := s.tokens.IssueTokens(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info("Demo session started", zap.Uint("user_id", user.ID), zap.Time("expires_at", expiresAt))
+	return &Session{AuthResponse: tokens, ExpiresAt: expiresAt}, nil
+}
+
+// RunCleanup deletes expired sandboxes. It backs the worker's demo cleanup
+// job, which keeps running after demo mode is turned off so no sandbox is
+// left behind
+func (s *Service) RunCleanup(ctx context.Context) error {
+	purged, err := s.PurgeExpired(ctx)
+	if purged > 0 {
+		s.logger.Info("Expired demo sessions deleted", zap.Int("accounts", purged))
+	}
+	return err
+}
+
+// PurgeExpired deletes up to a batch of expired sandboxes for good, with
+// every row they own, and returns how many it deleted. Storage objects the
+// rows pointed to are left to storage garbage collection
+func (s *Service) PurgeExpired(ctx context.Context) (int, error) {
+	db := s.db.WithContext(ctx)
+	var userIDs []uint
+	if err := db.Unscoped().Model(&database.User{}).
+		Where("demo_expires_at IS NOT NULL AND demo_expires_at <= ?", s.now()).
+		Order("demo_expires_at").Limit(purgeBatchSize).
+		Pluck("id", &userIDs).Error; err != nil {
+		return 0, fmt.Errorf("failed to get expired demo accounts: %w", err)
+	}
+	if len(userIDs) == 0 {
+		return 0, nil
+	}
+
+	tables, err := s.userTables(db)
+	if err != nil {
+		return 0, err
+	}
+	for i, userID := range userIDs {
+		if err := database.WithTransaction(ctx, s.db, func(tx *gorm.DB) error {
+			return purge(tx, tables, userID)
+		}); err != nil {
+			return i, err
+		}
+	}
+	return len(userIDs), nil
+}
+
+// table is a table with rows that belong to a user or to their bookmarks
+// or collections
+type table struct {
+	name         string
+	userColumns  map[string]bool // column name to whether it holds IDs as text
+	byBookmark   bool
+	byCollection bool
+}
+
+// userTables finds the tables rows of a sandbox can be in, so new tables
+// are cleaned up without being listed here
+func (s *Service) userTables(db *gorm.DB) ([]table, error) {
+	names, err := db.Migrator().GetTables()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+
+	var tables []table
+	for _, name := range names {
+		if name == "users" {
+			continue
+		}
+		columns, err := db.Migrator().ColumnTypes(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list columns of %s: %w", name, err)
+		}
+		t := table{name: name, userColumns: make(map[string]bool)}
+		for _, column := range columns {
+			switch column.Name() {
+			case "bookmark_id":
+				t.byBookmark = name != "bookmarks"
+			case "collection_id":
+				t.byCollection = name != "collections"
+			}
+			for _, userColumn := range ownerColumns {
+				if column.Name() == userColumn {
+					typeName := strings.ToLower(column.DatabaseTypeName())
+					t.userColumns[userColumn] = strings.Contains(typeName, "char") || strings.Contains(typeName, "text")
+				}
+			}
+		}
+		if len(t.userColumns) > 0 || t.byBookmark || t.byCollection {
+			tables = append(tables, t)
+		}
+	}
+	return tables, nil
+}
+
+// purge deletes a sandbox: first the rows hanging off its bookmarks and
+// collections, then the rows it owns, then the account
+func purge(tx *gorm.DB, tables []table, userID uint) error {
+	var bookmarkIDs, collectionIDs []uint
+	if err := tx.Unscoped().Model(&database.Bookmark{}).Where("user_id = ?", userID).Pluck("id", &bookmarkIDs).Error; err != nil {
+		return fmt.Errorf("failed to get demo bookmarks: %w", err)
+	}
+	if err := tx.Unscoped().Model(&database.Collection{}).Where("user_id = ?", userID).Pluck("id", &collectionIDs).Error; err != nil {
+		return fmt.Errorf("failed to get demo collections: %w", err)
+	}
+
+	for _, t := range tables {
+		if t.byBookmark && len(bookmarkIDs) > 0 {
+			if err := tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE bookmark_id IN ?", t.name), bookmarkIDs).Error; err != nil {
+				return fmt.Errorf("failed to delete demo rows of %s: %w", t.name, err)
+			}
+		}
+		if t.byCollection && len(collectionIDs) > 0 {
+			if err := tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE collection_id IN ?", t.name), collectionIDs).Error; err != nil {
+				return fmt.Errorf("failed to delete demo rows of %s: %w", t.name, err)
+			}
+		}
+	}
+	for _, t := range tables {
+		for column, text := range t.userColumns {
+			var id interface{} = userID
+			if text {
+				id = fmt.Sprint(userID)
+			}
+			if err := tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE %s = ?", t.name, column), id).Error; err != nil {
+				return fmt.Errorf("failed to delete demo rows of %s: %w", t.name, err)
+			}
+		}
+	}
+	if err := tx.Exec("DELETE FROM users WHERE id = ?", userID).Error; err != nil {
+		return fmt.Errorf("failed to delete demo account: %w", err)
+	}
+	return nil
+}
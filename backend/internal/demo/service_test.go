@@ -0,0 +1,123 @@
+package demo
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/internal/auth"
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/internal/onboarding"
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/redis"
+	"bookmark-sync-service/backend/pkg/testfactory"
+)
+
+var testConfig = config.DemoConfig{
+	Enabled:         true,
+	TTL:             60,
+	SessionsPerHour: 2,
+	MaxSessions:     3,
+	Plan:            "demo",
+}
+
+var jwtConfig = &config.JWTConfig{Secret: "test-secret", ExpiryHour: 24}
+
+func setupService(t *testing.T, cfg config.DemoConfig) (*Service, *gorm.DB) {
+	mr := miniredis.RunT(t)
+	client, err := redis.NewClient(config.RedisConfig{Host: mr.Host(), Port: mr.Port(), PoolSize: 1})
+	require.NoError(t, err)
+	db := testfactory.NewDB(t)
+
+	service := NewService(cfg, db, client, zap.NewNop())
+	service.SetTokenIssuer(auth.NewService(db, client, nil, jwtConfig, zap.NewNop()))
+	service.SetSeeder(onboarding.NewService(config.OnboardingConfig{}, db, zap.NewNop()))
+	return service, db
+}
+
+func count(t *testing.T, db *gorm.DB, model interface{}) int64 {
+	t.Helper()
+	var n int64
+	require.NoError(t, db.Unscoped().Model(model).Count(&n).Error)
+	return n
+}
+
+func TestStart(t *testing.T) {
+	ctx := context.Background()
+
+	disabled, _ := setupService(t, config.DemoConfig{})
+	_, err := disabled.Start(ctx, "203.0.113.1")
+	assert.ErrorIs(t, err, ErrDisabled)
+
+	service, db := setupService(t, testConfig)
+	session, err := service.Start(ctx, "203.0.113.1")
+	require.NoError(t, err)
+
+	var user database.User
+	require.NoError(t, db.First(&user, session.User.ID).Error)
+	assert.Equal(t, "demo", user.Plan)
+	require.NotNil(t, user.DemoExpiresAt)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), session.ExpiresAt, time.Minute)
+	assert.LessOrEqual(t, session.ExpiresIn, 3600)
+	assert.Positive(t, count(t, db.Where("user_id = ?", user.ID), &database.Bookmark{}), "sandboxes get sample data")
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(session.AccessToken, claims, func(*jwt.Token) (interface{}, error) { return []byte(jwtConfig.Secret), nil })
+	require.NoError(t, err)
+	assert.Equal(t, true, claims["demo"])
+	assert.LessOrEqual(t, int64(claims["exp"].(float64)), session.ExpiresAt.Unix(), "tokens expire with the sandbox")
+
+	_, err = service.Start(ctx, "203.0.113.1")
+	require.NoError(t, err)
+	_, err = service.Start(ctx, "203.0.113.1")
+	assert.ErrorIs(t, err, ErrRateLimited)
+
+	_, err = service.Start(ctx, "203.0.113.2")
+	require.NoError(t, err)
+	_, err = service.Start(ctx, "203.0.113.3")
+	assert.ErrorIs(t, err, ErrFull)
+}
+
+func TestPurgeExpired(t *testing.T) {
+	ctx := context.Background()
+	service, db := setupService(t, testConfig)
+	factory := testfactory.New(t, db)
+
+	regular := factory.User()
+	kept := factory.Bookmark(regular.ID)
+	factory.AddToCollection(factory.Collection(regular.ID), kept)
+
+	session, err := service.Start(ctx, "203.0.113.1")
+	require.NoError(t, err)
+	sandboxID := session.User.ID
+	collection := factory.Collection(sandboxID)
+	factory.AddToCollection(collection, factory.Bookmark(sandboxID))
+	require.NoError(t, db.AutoMigrate(&testfactory.Behavior{}))
+	factory.Behavior(strconv.FormatUint(uint64(sandboxID), 10), kept.ID, "view")
+	factory.Behavior(strconv.FormatUint(uint64(regular.ID), 10), kept.ID, "view")
+
+	purged, err := service.PurgeExpired(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, purged, "live sandboxes are kept")
+
+	service.now = func() time.Time { return session.ExpiresAt.Add(time.Minute) }
+	purged, err = service.PurgeExpired(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, purged)
+
+	assert.Equal(t, int64(1), count(t, db, &database.User{}))
+	assert.Equal(t, int64(1), count(t, db, &database.Bookmark{}))
+	assert.Equal(t, int64(1), count(t, db, &database.Collection{}))
+	var links int64
+	require.NoError(t, db.Table("bookmark_collections").Count(&links).Error)
+	assert.Equal(t, int64(1), links, "only the regular user's collection links are left")
+	assert.Equal(t, int64(1), count(t, db, &testfactory.Behavior{}), "rows keyed by the user ID as text go too")
+}
@@ -0,0 +1,84 @@
+package events
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"bookmark-sync-service/backend/pkg/middleware"
+	"bookmark-sync-service/backend/pkg/utils"
+)
+
+// Handler handles the Server-Sent Events endpoint
+type Handler struct {
+	service *Service
+	logger  *zap.Logger
+}
+
+// NewHandler creates a new event stream handler
+func NewHandler(service *Service, logger *zap.Logger) *Handler {
+	return &Handler{service: service, logger: logger}
+}
+
+// RegisterRoutes registers the event stream routes
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/events/stream", h.Stream)
+}
+
+// Stream streams the user's change events as Server-Sent Events
+// @Summary Stream change events
+// @Description Stream bookmark and collection changes and notifications as Server-Sent Events. Reconnect with the Last-Event-ID header, or the last_event_id query parameter, to replay missed changes
+// @Tags events
+// @Produce text/event-stream
+// @Param Last-Event-ID header int false "ID of the last event received"
+// @Param last_event_id query int false "ID of the last event received, for clients that cannot set headers"
+// @Success 200 {string} string "Event stream"
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Router /api/v1/events/stream [get]
+func (h *Handler) Stream(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	if userID == "" {
+		utils.UnauthorizedResponse(c, "User not authenticated")
+		return
+	}
+
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+	var cursor uint64
+	if lastEventID != "" {
+		var err error
+		cursor, err = strconv.ParseUint(lastEventID, 10, 32)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid Last-Event-ID", nil)
+			return
+		}
+	}
+
+	// Streams outlive the server write timeout; failed heartbeats end them instead
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	// Stop nginx from buffering the stream
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	err := h.service.Stream(c.Request.Context(), userID, uint(cursor), func(event Event) error {
+		if _, err := event.WriteTo(c.Writer); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return nil
+	})
+	if err != nil && c.Request.Context().Err() == nil {
+		h.logger.Warn("Event stream closed", zap.String("user_id", userID), zap.Error(err))
+	}
+}
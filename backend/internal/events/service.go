@@ -0,0 +1,200 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+// Event names that are not sync event types
+const (
+	// EventNotification carries a user notification, such as a new share
+	EventNotification = "notification"
+	// EventResync tells the client it missed more events than can be
+	// replayed and should run a full sync before resuming from the event ID
+	EventResync = "resync"
+)
+
+// Subscriber opens Redis pub/sub subscriptions, e.g. the go-redis client
+type Subscriber interface {
+	Subscribe(ctx context.Context, channels ...string) *redis.PubSub
+}
+
+// Event is one Server-Sent Event. Events from the sync log carry their log ID
+// so clients can resume with Last-Event-ID; transient events have no ID
+type Event struct {
+	ID   uint
+	Name string
+	Data []byte
+}
+
+// WriteTo writes the event in text/event-stream format. An empty event is
+// written as a comment, which keeps idle connections open
+func (e Event) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+	if e.Name == "" && e.Data == nil {
+		b.WriteString(": ping\n\n")
+	} else {
+		if e.ID != 0 {
+			b.WriteString("id: " + strconv.FormatUint(uint64(e.ID), 10) + "\n")
+		}
+		b.WriteString("event: " + e.Name + "\n")
+		// Data lines cannot contain newlines; JSON payloads are single line
+		for _, line := range strings.Split(string(e.Data), "\n") {
+			b.WriteString("data: " + line + "\n")
+		}
+		b.WriteString("\n")
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// Service streams a user's change events: sync log entries and notifications
+type Service struct {
+	db         *gorm.DB
+	subscriber Subscriber
+	logger     *zap.Logger
+	heartbeat  time.Duration
+}
+
+// NewService creates a new event stream service
+func NewService(db *gorm.DB, subscriber Subscriber, logger *zap.Logger) *Service {
+	return &Service{
+		db:         db,
+		subscriber: subscriber,
+		logger:     logger,
+		heartbeat:  config.EventStreamHeartbeat,
+	}
+}
+
+// Stream sends the user's events to send until ctx is cancelled or send
+// fails. With lastEventID set, events logged after it are replayed first
+func (s *Service) Stream(ctx context.Context, userID string, lastEventID uint, send func(Event) error) error {
+	// Subscribe before replaying so nothing logged in between is lost;
+	// duplicates are dropped by ID below
+	pubsub := s.subscriber.Subscribe(ctx, syncChannel(userID), notificationChannel(userID))
+	defer pubsub.Close()
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return fmt.Errorf("failed to subscribe to events: %w", err)
+	}
+
+	cursor := lastEventID
+	if lastEventID > 0 {
+		replayed, err := s.replay(ctx, userID, lastEventID, send)
+		if err != nil {
+			return err
+		}
+		cursor = replayed
+	}
+
+	messages := pubsub.Channel()
+	ticker := time.NewTicker(s.heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := send(Event{}); err != nil {
+				return err
+			}
+		case msg, ok := <-messages:
+			if !ok {
+				return nil
+			}
+
+			event, ok := s.liveEvent(msg)
+			if !ok {
+				continue
+			}
+			if event.ID != 0 {
+				if event.ID <= cursor {
+					continue
+				}
+				cursor = event.ID
+			}
+			if err := send(event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// replay sends the logged events after lastEventID and returns the ID of the
+// last one sent
+func (s *Service) replay(ctx context.Context, userID string, lastEventID uint, send func(Event) error) (uint, error) {
+	var logged []database.SyncEvent
+	if err := s.db.WithContext(ctx).
+		Where("user_id = ? AND id > ?", userID, lastEventID).
+		Order("id ASC").
+		Limit(config.EventStreamReplayLimit + 1).
+		Find(&logged).Error; err != nil {
+		return 0, fmt.Errorf("failed to get sync events: %w", err)
+	}
+
+	if len(logged) > config.EventStreamReplayLimit {
+		// Too far behind to catch up event by event
+		var latest uint
+		if err := s.db.WithContext(ctx).Model(&database.SyncEvent{}).
+			Where("user_id = ?", userID).
+			Select("MAX(id)").Scan(&latest).Error; err != nil {
+			return 0, fmt.Errorf("failed to get latest sync event: %w", err)
+		}
+		data, _ := json.Marshal(map[string]uint{"latest_event_id": latest})
+		return latest, send(Event{ID: latest, Name: EventResync, Data: data})
+	}
+
+	cursor := lastEventID
+	for _, event := range logged {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return 0, fmt.Errorf("failed to encode sync event: %w", err)
+		}
+		if err := send(Event{ID: event.ID, Name: event.Type, Data: data}); err != nil {
+			return 0, err
+		}
+		cursor = event.ID
+	}
+	return cursor, nil
+}
+
+// liveEvent converts a pub/sub message into an event
+func (s *Service) liveEvent(msg *redis.Message) (Event, bool) {
+	data := []byte(msg.Payload)
+	if strings.HasPrefix(msg.Channel, "notifications:") {
+		return Event{Name: EventNotification, Data: data}, true
+	}
+
+	var header struct {
+		ID   uint   `json:"id"`
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil || header.Type == "" {
+		s.logger.Warn("Dropping malformed sync event", zap.String("channel", msg.Channel), zap.Error(err))
+		return Event{}, false
+	}
+	return Event{ID: header.ID, Name: header.Type, Data: data}, true
+}
+
+// The channels match those the Redis client publishes sync events and
+// notifications on
+func syncChannel(userID string) string {
+	return "sync:user:" + userID
+}
+
+func notificationChannel(userID string) string {
+	return "notifications:user:" + userID
+}
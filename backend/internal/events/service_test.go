@@ -0,0 +1,122 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+func setupTestService(t *testing.T) (*Service, *gorm.DB, *redis.Client) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&database.SyncEvent{}))
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return NewService(db, client, zap.NewNop()), db, client
+}
+
+func logEvent(t *testing.T, db *gorm.DB, userID, eventType string) database.SyncEvent {
+	event := database.SyncEvent{
+		Type:       eventType,
+		UserID:     userID,
+		ResourceID: "1",
+		Action:     "update",
+		DeviceID:   "device",
+		Timestamp:  time.Now(),
+	}
+	require.NoError(t, db.Create(&event).Error)
+	return event
+}
+
+// collect runs Stream in the background and returns its events on a channel
+func collect(ctx context.Context, service *Service, userID string, lastEventID uint) <-chan Event {
+	received := make(chan Event, 16)
+	go func() {
+		_ = service.Stream(ctx, userID, lastEventID, func(event Event) error {
+			received <- event
+			return nil
+		})
+	}()
+	return received
+}
+
+func next(t *testing.T, received <-chan Event) Event {
+	select {
+	case event := <-received:
+		return event
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for event")
+		return Event{}
+	}
+}
+
+func TestEventWriteTo(t *testing.T) {
+	var buf bytes.Buffer
+	_, err := Event{ID: 7, Name: "bookmark_created", Data: []byte(`{"id":7}`)}.WriteTo(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, "id: 7\nevent: bookmark_created\ndata: {\"id\":7}\n\n", buf.String())
+
+	buf.Reset()
+	_, err = Event{}.WriteTo(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, ": ping\n\n", buf.String())
+}
+
+func TestStreamReplaysAndDeliversLiveEvents(t *testing.T) {
+	service, db, client := setupTestService(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	first := logEvent(t, db, "1", "bookmark_created")
+	second := logEvent(t, db, "1", "bookmark_updated")
+	logEvent(t, db, "2", "bookmark_created")
+
+	// Resuming after the first event replays only the user's later ones
+	received := collect(ctx, service, "1", first.ID)
+	event := next(t, received)
+	assert.Equal(t, second.ID, event.ID)
+	assert.Equal(t, "bookmark_updated", event.Name)
+
+	// A live copy of an event already replayed is dropped
+	payload, _ := json.Marshal(second)
+	require.NoError(t, client.Publish(ctx, "sync:user:1", payload).Err())
+
+	third := logEvent(t, db, "1", "collection_deleted")
+	payload, _ = json.Marshal(third)
+	require.NoError(t, client.Publish(ctx, "sync:user:1", payload).Err())
+	event = next(t, received)
+	assert.Equal(t, third.ID, event.ID)
+	assert.Equal(t, "collection_deleted", event.Name)
+
+	// Notifications have no ID since they are not in the sync log
+	require.NoError(t, client.Publish(ctx, "notifications:user:1", `{"type":"share.received"}`).Err())
+	event = next(t, received)
+	assert.Equal(t, EventNotification, event.Name)
+	assert.Zero(t, event.ID)
+	assert.JSONEq(t, `{"type":"share.received"}`, string(event.Data))
+}
+
+func TestStreamHeartbeat(t *testing.T) {
+	service, _, _ := setupTestService(t)
+	service.heartbeat = 10 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	event := next(t, collect(ctx, service, "1", 0))
+	assert.Equal(t, Event{}, event)
+}
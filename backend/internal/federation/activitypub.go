@@ -0,0 +1,170 @@
+package federation
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+// ContentType is the media type of ActivityPub documents
+const ContentType = "application/activity+json"
+
+const (
+	activityStreamsContext = "https://www.w3.org/ns/activitystreams"
+	securityContext        = "https://w3id.org/security/v1"
+	// publicAudience addresses an activity to everyone
+	publicAudience = "https://www.w3.org/ns/activitystreams#Public"
+)
+
+// Actor is the ActivityPub Person of a public profile
+type Actor struct {
+	Context           []string  `json:"@context"`
+	ID                string    `json:"id"`
+	Type              string    `json:"type"`
+	PreferredUsername string    `json:"preferredUsername"`
+	Name              string    `json:"name,omitempty"`
+	Summary           string    `json:"summary,omitempty"`
+	Inbox             string    `json:"inbox"`
+	Outbox            string    `json:"outbox"`
+	Followers         string    `json:"followers"`
+	PublicKey         PublicKey `json:"publicKey"`
+}
+
+// PublicKey is the key other instances verify an actor's requests with
+type PublicKey struct {
+	ID           string `json:"id"`
+	Owner        string `json:"owner"`
+	PublicKeyPem string `json:"publicKeyPem"`
+}
+
+// Note is a published bookmark
+type Note struct {
+	ID           string    `json:"id"`
+	Type         string    `json:"type"`
+	AttributedTo string    `json:"attributedTo"`
+	Content      string    `json:"content"`
+	URL          string    `json:"url"`
+	Published    time.Time `json:"published"`
+	To           []string  `json:"to"`
+	Cc           []string  `json:"cc"`
+}
+
+// Activity is an ActivityPub activity. Object is kept raw because it is an
+// ID or an embedded object depending on the activity
+type Activity struct {
+	Context   string          `json:"@context,omitempty"`
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	Actor     string          `json:"actor"`
+	Object    json.RawMessage `json:"object"`
+	Published *time.Time      `json:"published,omitempty"`
+	To        []string        `json:"to,omitempty"`
+	Cc        []string        `json:"cc,omitempty"`
+}
+
+// OrderedCollection is an actor's outbox or followers collection
+type OrderedCollection struct {
+	Context    string `json:"@context"`
+	ID         string `json:"id"`
+	Type       string `json:"type"`
+	TotalItems int64  `json:"totalItems"`
+	First      string `json:"first,omitempty"`
+}
+
+// OrderedCollectionPage is one page of an outbox, newest first
+type OrderedCollectionPage struct {
+	Context      string            `json:"@context"`
+	ID           string            `json:"id"`
+	Type         string            `json:"type"`
+	PartOf       string            `json:"partOf"`
+	Next         string            `json:"next,omitempty"`
+	OrderedItems []json.RawMessage `json:"orderedItems"`
+}
+
+// WebFinger is the discovery document of an account
+type WebFinger struct {
+	Subject string          `json:"subject"`
+	Links   []WebFingerLink `json:"links"`
+}
+
+// WebFingerLink points at a representation of an account
+type WebFingerLink struct {
+	Rel  string `json:"rel"`
+	Type string `json:"type,omitempty"`
+	Href string `json:"href"`
+}
+
+// remoteActor is the part of another instance's actor document we use
+type remoteActor struct {
+	ID        string `json:"id"`
+	Inbox     string `json:"inbox"`
+	Endpoints struct {
+		SharedInbox string `json:"sharedInbox"`
+	} `json:"endpoints"`
+	PublicKey PublicKey `json:"publicKey"`
+}
+
+// objectRef is an activity object given as an ID or an embedded object
+type objectRef struct {
+	ID     string
+	Type   string
+	Actor  string
+	Object string
+}
+
+// parseObject reads an activity's object, whether an ID or an object
+func parseObject(raw json.RawMessage) objectRef {
+	var id string
+	if err := json.Unmarshal(raw, &id); err == nil {
+		return objectRef{ID: id}
+	}
+	var embedded struct {
+		ID     string          `json:"id"`
+		Type   string          `json:"type"`
+		Actor  string          `json:"actor"`
+		Object json.RawMessage `json:"object"`
+	}
+	if err := json.Unmarshal(raw, &embedded); err != nil {
+		return objectRef{}
+	}
+	ref := objectRef{ID: embedded.ID, Type: embedded.Type, Actor: embedded.Actor}
+	if len(embedded.Object) > 0 {
+		ref.Object = parseObject(embedded.Object).ID
+	}
+	return ref
+}
+
+// bookmarkNote renders a bookmark as the Note of a Create activity
+func bookmarkNote(actorID string, bookmark *database.Bookmark, tags []string, published time.Time) Note {
+	title := bookmark.Title
+	if title == "" {
+		title = bookmark.URL
+	}
+	content := fmt.Sprintf(`<p><a href="%s" rel="nofollow noopener" target="_blank">%s</a></p>`,
+		html.EscapeString(bookmark.URL), html.EscapeString(title))
+	if bookmark.Description != "" {
+		content += "<p>" + html.EscapeString(bookmark.Description) + "</p>"
+	}
+	if len(tags) > 0 {
+		hashtags := make([]string, 0, len(tags))
+		for _, tag := range tags {
+			hashtags = append(hashtags, "#"+html.EscapeString(strings.NewReplacer("/", "_", " ", "_", "-", "_").Replace(tag)))
+		}
+		content += "<p>" + strings.Join(hashtags, " ") + "</p>"
+	}
+
+	return Note{
+		ID:           fmt.Sprintf("%s/bookmarks/%d", actorID, bookmark.ID),
+		Type:         "Note",
+		AttributedTo: actorID,
+		Content:      content,
+		URL:          bookmark.URL,
+		Published:    published,
+		To:           []string{publicAudience},
+		Cc:           []string{actorID + "/followers"},
+	}
+}
@@ -0,0 +1,154 @@
+package federation
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"bookmark-sync-service/backend/pkg/utils"
+)
+
+// maxInboxSize caps activities posted to an inbox
+const maxInboxSize = 1 << 20
+
+// Handler serves the ActivityPub endpoints of public profiles
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new federation handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes registers WebFinger and the actor endpoints. They live
+// outside the API prefix, at the URLs other instances are given
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/.well-known/webfinger", h.WebFinger)
+	router.GET("/ap/users/:username", h.GetActor)
+	router.GET("/ap/users/:username/outbox", h.GetOutbox)
+	router.GET("/ap/users/:username/followers", h.GetFollowers)
+	router.POST("/ap/users/:username/inbox", h.PostInbox)
+}
+
+// WebFinger resolves acct:username@host to a profile's actor
+// @Summary WebFinger discovery
+// @Tags federation
+// @Produce json
+// @Param resource query string true "acct:username@host"
+// @Success 200 {object} WebFinger
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /.well-known/webfinger [get]
+func (h *Handler) WebFinger(c *gin.Context) {
+	finger, err := h.service.WebFinger(c.Request.Context(), c.Query("resource"))
+	if err != nil {
+		federationError(c, err)
+		return
+	}
+	render(c, http.StatusOK, "application/jrd+json", finger)
+}
+
+// GetActor returns the ActivityPub actor of a public profile
+// @Summary Get an ActivityPub actor
+// @Tags federation
+// @Produce json
+// @Param username path string true "Username"
+// @Success 200 {object} Actor
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /ap/users/{username} [get]
+func (h *Handler) GetActor(c *gin.Context) {
+	actor, err := h.service.Actor(c.Request.Context(), c.Param("username"))
+	if err != nil {
+		federationError(c, err)
+		return
+	}
+	render(c, http.StatusOK, ContentType, actor)
+}
+
+// GetOutbox returns the bookmarks a public profile published
+// @Summary Get an ActivityPub outbox
+// @Tags federation
+// @Produce json
+// @Param username path string true "Username"
+// @Param page query int false "Page, omitted for the collection itself"
+// @Success 200 {object} OrderedCollectionPage
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /ap/users/{username}/outbox [get]
+func (h *Handler) GetOutbox(c *gin.Context) {
+	page, _ := strconv.Atoi(c.Query("page"))
+	outbox, err := h.service.Outbox(c.Request.Context(), c.Param("username"), page)
+	if err != nil {
+		federationError(c, err)
+		return
+	}
+	render(c, http.StatusOK, ContentType, outbox)
+}
+
+// GetFollowers returns how many actors follow a public profile
+// @Summary Get an ActivityPub followers collection
+// @Tags federation
+// @Produce json
+// @Param username path string true "Username"
+// @Success 200 {object} OrderedCollection
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /ap/users/{username}/followers [get]
+func (h *Handler) GetFollowers(c *gin.Context) {
+	followers, err := h.service.Followers(c.Request.Context(), c.Param("username"))
+	if err != nil {
+		federationError(c, err)
+		return
+	}
+	render(c, http.StatusOK, ContentType, followers)
+}
+
+// PostInbox receives activities from other instances
+// @Summary Post to an ActivityPub inbox
+// @Description Accepts Follow and Undo Follow activities signed with HTTP signatures. Other activities are acknowledged and ignored
+// @Tags federation
+// @Accept json
+// @Param username path string true "Username"
+// @Success 202 "Accepted"
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /ap/users/{username}/inbox [post]
+func (h *Handler) PostInbox(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxInboxSize))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", "Failed to read activity", nil)
+		return
+	}
+
+	err = h.service.HandleInbox(c.Request.Context(), c.Param("username"), c.Request, body)
+	if err != nil && !errors.Is(err, ErrUnsupportedActivity) {
+		federationError(c, err)
+		return
+	}
+	c.Status(http.StatusAccepted)
+}
+
+// render writes an ActivityPub or WebFinger document
+func render(c *gin.Context, status int, contentType string, document interface{}) {
+	body, err := json.Marshal(document)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to encode document", nil)
+		return
+	}
+	c.Header("Access-Control-Allow-Origin", "*")
+	c.Data(status, contentType+"; charset=utf-8", body)
+}
+
+// federationError maps federation errors to HTTP responses
+func federationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrActorNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, "NOT_FOUND", err.Error(), nil)
+	case errors.Is(err, ErrMissingSignature), errors.Is(err, ErrInvalidSignature), errors.Is(err, ErrActorMismatch):
+		utils.ErrorResponse(c, http.StatusUnauthorized, "INVALID_SIGNATURE", err.Error(), nil)
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Federation request failed", nil)
+	}
+}
@@ -0,0 +1,513 @@
+package federation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/internal/user"
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+// maxActorSize caps the remote actor documents read while verifying requests
+const maxActorSize = 1 << 20
+
+var (
+	// ErrActorNotFound is returned for unknown users and private profiles
+	ErrActorNotFound = errors.New("actor not found")
+	// ErrUnsupportedActivity is returned for inbox activities that are not
+	// handled; they are acknowledged and dropped
+	ErrUnsupportedActivity = errors.New("unsupported activity")
+	// ErrActorMismatch is returned when an activity is signed by another actor
+	ErrActorMismatch = errors.New("activity actor does not match the signature")
+	// ErrPrivateAddress is returned for requests to loopback, private and
+	// link-local addresses, which remote actors must not make us reach
+	ErrPrivateAddress = errors.New("refusing to connect to a non-public address")
+)
+
+// Profiles finds the public profiles federated as actors
+type Profiles interface {
+	PublicProfile(ctx context.Context, username string) (*database.User, error)
+}
+
+// Service publishes public bookmarks to followers on other instances
+type Service struct {
+	cfg      config.FederationConfig
+	baseURL  string
+	host     string
+	db       *gorm.DB
+	profiles Profiles
+	client   *http.Client
+	logger   *zap.Logger
+	now      func() time.Time
+	// deliver runs outgoing deliveries; tests run them inline
+	deliver func(func())
+}
+
+// NewService creates a new federation service. baseURL is the public URL
+// actor IDs are built from
+func NewService(cfg config.FederationConfig, baseURL string, db *gorm.DB, profiles Profiles, logger *zap.Logger) *Service {
+	timeout := time.Duration(cfg.DeliveryTimeout) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	host := baseURL
+	if parsed, err := url.Parse(baseURL); err == nil {
+		host = parsed.Host
+	}
+	return &Service{
+		cfg:      cfg,
+		baseURL:  baseURL,
+		host:     host,
+		db:       db,
+		profiles: profiles,
+		client:   publicClient(timeout),
+		logger:   logger,
+		now:      time.Now,
+		deliver:  func(fn func()) { go fn() },
+	}
+}
+
+// publicClient returns an HTTP client that only connects to public
+// addresses. The check runs on the resolved address, so a remote host
+// can't point its name at our network between check and connect
+func publicClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+				ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() {
+				return fmt.Errorf("%w: %s", ErrPrivateAddress, host)
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: &http.Transport{DialContext: dialer.DialContext, TLSHandshakeTimeout: timeout},
+	}
+}
+
+// sameHost reports whether a URL is on the given host
+func sameHost(rawURL, host string) bool {
+	parsed, err := url.Parse(rawURL)
+	return err == nil && parsed.Host != "" && strings.EqualFold(parsed.Host, host)
+}
+
+// actorID returns the ActivityPub ID of a user's actor
+func (s *Service) actorID(username string) string {
+	return s.baseURL + "/ap/users/" + url.PathEscape(username)
+}
+
+// profile returns a public profile, mapping private and unknown ones to
+// ErrActorNotFound
+func (s *Service) profile(ctx context.Context, username string) (*database.User, error) {
+	profile, err := s.profiles.PublicProfile(ctx, username)
+	if errors.Is(err, user.ErrPublicProfileNotFound) {
+		return nil, ErrActorNotFound
+	}
+	return profile, err
+}
+
+// WebFinger resolves an acct: resource of this instance to its actor
+func (s *Service) WebFinger(ctx context.Context, resource string) (*WebFinger, error) {
+	account := strings.TrimPrefix(resource, "acct:")
+	username, host, ok := strings.Cut(account, "@")
+	if !ok || !strings.EqualFold(host, s.host) {
+		return nil, ErrActorNotFound
+	}
+	if _, err := s.profile(ctx, username); err != nil {
+		return nil, err
+	}
+	return &WebFinger{
+		Subject: "acct:" + username + "@" + s.host,
+		Links:   []WebFingerLink{{Rel: "self", Type: ContentType, Href: s.actorID(username)}},
+	}, nil
+}
+
+// Actor returns the actor of a public profile
+func (s *Service) Actor(ctx context.Context, username string) (*Actor, error) {
+	profile, err := s.profile(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	key, err := s.keyFor(ctx, profile.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	id := s.actorID(profile.Username)
+	return &Actor{
+		Context:           []string{activityStreamsContext, securityContext},
+		ID:                id,
+		Type:              "Person",
+		PreferredUsername: profile.Username,
+		Name:              profile.DisplayName,
+		Summary:           "Public bookmarks of " + profile.Username,
+		Inbox:             id + "/inbox",
+		Outbox:            id + "/outbox",
+		Followers:         id + "/followers",
+		PublicKey:         PublicKey{ID: id + "#main-key", Owner: id, PublicKeyPem: key.PublicKeyPEM},
+	}, nil
+}
+
+// Outbox returns the outbox of a public profile: the collection itself for
+// page 0, otherwise one page of it
+func (s *Service) Outbox(ctx context.Context, username string, page int) (interface{}, error) {
+	profile, err := s.profile(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	outbox := s.actorID(profile.Username) + "/outbox"
+	query := s.db.WithContext(ctx).Model(&database.FederationActivity{}).Where("user_id = ?", profile.ID)
+
+	if page <= 0 {
+		var total int64
+		if err := query.Count(&total).Error; err != nil {
+			return nil, fmt.Errorf("failed to count outbox: %w", err)
+		}
+		return &OrderedCollection{
+			Context:    activityStreamsContext,
+			ID:         outbox,
+			Type:       "OrderedCollection",
+			TotalItems: total,
+			First:      outbox + "?page=1",
+		}, nil
+	}
+
+	size := s.cfg.OutboxPageSize
+	if size <= 0 {
+		size = 20
+	}
+	var activities []database.FederationActivity
+	if err := query.Order("id DESC").Offset((page - 1) * size).Limit(size + 1).Find(&activities).Error; err != nil {
+		return nil, fmt.Errorf("failed to get outbox: %w", err)
+	}
+
+	result := &OrderedCollectionPage{
+		Context:      activityStreamsContext,
+		ID:           fmt.Sprintf("%s?page=%d", outbox, page),
+		Type:         "OrderedCollectionPage",
+		PartOf:       outbox,
+		OrderedItems: []json.RawMessage{},
+	}
+	if len(activities) > size {
+		activities = activities[:size]
+		result.Next = fmt.Sprintf("%s?page=%d", outbox, page+1)
+	}
+	for _, activity := range activities {
+		result.OrderedItems = append(result.OrderedItems, json.RawMessage(activity.Object))
+	}
+	return result, nil
+}
+
+// Followers returns the size of a profile's followers collection. Who
+// follows is not disclosed
+func (s *Service) Followers(ctx context.Context, username string) (*OrderedCollection, error) {
+	profile, err := s.profile(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	var total int64
+	if err := s.db.WithContext(ctx).Model(&database.FederationFollower{}).Where("user_id = ?", profile.ID).Count(&total).Error; err != nil {
+		return nil, fmt.Errorf("failed to count followers: %w", err)
+	}
+	return &OrderedCollection{
+		Context:    activityStreamsContext,
+		ID:         s.actorID(profile.Username) + "/followers",
+		Type:       "OrderedCollection",
+		TotalItems: total,
+	}, nil
+}
+
+// HandleInbox verifies and handles an activity posted to a profile's inbox.
+// Follow requests are accepted at once and Undo of a Follow removes the
+// follower; anything else returns ErrUnsupportedActivity
+func (s *Service) HandleInbox(ctx context.Context, username string, req *http.Request, body []byte) error {
+	profile, err := s.profile(ctx, username)
+	if err != nil {
+		return err
+	}
+
+	sig, err := parseSignature(req)
+	if err != nil {
+		return err
+	}
+	sender, err := s.fetchActor(ctx, strings.SplitN(sig.KeyID, "#", 2)[0])
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	key, err := parsePublicKey(sender.PublicKey.PublicKeyPem)
+	if err != nil || sender.PublicKey.ID != sig.KeyID {
+		return ErrInvalidSignature
+	}
+	if err := verifySignature(req, body, sig, key, s.now()); err != nil {
+		return err
+	}
+
+	var activity Activity
+	if err := json.Unmarshal(body, &activity); err != nil {
+		return fmt.Errorf("%w: %v", ErrUnsupportedActivity, err)
+	}
+	if activity.Actor != sender.ID {
+		return ErrActorMismatch
+	}
+
+	object := parseObject(activity.Object)
+	switch {
+	case activity.Type == "Follow" && object.ID == s.actorID(profile.Username):
+		return s.follow(ctx, profile, sender, &activity)
+	case activity.Type == "Undo" && object.Type == "Follow":
+		return s.db.WithContext(ctx).Unscoped().
+			Where("user_id = ? AND actor_uri = ?", profile.ID, sender.ID).
+			Delete(&database.FederationFollower{}).Error
+	default:
+		return ErrUnsupportedActivity
+	}
+}
+
+// follow records a follower and sends them an Accept
+func (s *Service) follow(ctx context.Context, profile *database.User, sender *remoteActor, follow *Activity) error {
+	if sender.Inbox == "" {
+		return fmt.Errorf("%w: follower has no inbox", ErrUnsupportedActivity)
+	}
+	// Deliveries go where the actor document says; it may only point at
+	// the actor's own instance
+	actorURL, err := url.Parse(sender.ID)
+	if err != nil {
+		return fmt.Errorf("%w: invalid actor id", ErrUnsupportedActivity)
+	}
+	if !sameHost(sender.Inbox, actorURL.Host) ||
+		(sender.Endpoints.SharedInbox != "" && !sameHost(sender.Endpoints.SharedInbox, actorURL.Host)) {
+		return fmt.Errorf("%w: follower inbox is not on the actor's host", ErrUnsupportedActivity)
+	}
+	follower := database.FederationFollower{UserID: profile.ID, ActorURI: sender.ID}
+	if err := s.db.WithContext(ctx).
+		Where(database.FederationFollower{UserID: profile.ID, ActorURI: sender.ID}).
+		Assign(database.FederationFollower{InboxURL: sender.Inbox, SharedInbox: sender.Endpoints.SharedInbox}).
+		FirstOrCreate(&follower).Error; err != nil {
+		return fmt.Errorf("failed to save follower: %w", err)
+	}
+
+	followJSON, err := json.Marshal(follow)
+	if err != nil {
+		return fmt.Errorf("failed to encode follow: %w", err)
+	}
+	actorID := s.actorID(profile.Username)
+	accept := Activity{
+		Context: activityStreamsContext,
+		ID:      fmt.Sprintf("%s#accepts/%d", actorID, follower.ID),
+		Type:    "Accept",
+		Actor:   actorID,
+		Object:  followJSON,
+	}
+	s.deliverAll(profile, []string{sender.Inbox}, &accept)
+	return nil
+}
+
+// PublishBookmark adds a bookmark filed in a public collection to its
+// owner's outbox and delivers it to their followers. Private profiles,
+// bookmarks already published and links flagged as unsafe are skipped
+func (s *Service) PublishBookmark(ctx context.Context, userID uint, bookmark *database.Bookmark) error {
+	if bookmark.SafetyThreat != "" {
+		return nil
+	}
+	var profile database.User
+	if err := s.db.WithContext(ctx).First(&profile, userID).Error; err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if !user.ProfileIsPublic(profile.Preferences) {
+		return nil
+	}
+	var published int64
+	if err := s.db.WithContext(ctx).Model(&database.FederationActivity{}).
+		Where("user_id = ? AND bookmark_id = ?", userID, bookmark.ID).Count(&published).Error; err != nil {
+		return fmt.Errorf("failed to check outbox: %w", err)
+	}
+	if published > 0 {
+		return nil
+	}
+
+	var tags []string
+	if bookmark.Tags != "" {
+		_ = json.Unmarshal([]byte(bookmark.Tags), &tags)
+	}
+	actorID := s.actorID(profile.Username)
+	now := s.now().UTC()
+	note := bookmarkNote(actorID, bookmark, tags, now)
+	noteJSON, err := json.Marshal(note)
+	if err != nil {
+		return fmt.Errorf("failed to encode note: %w", err)
+	}
+	create := Activity{
+		Context:   activityStreamsContext,
+		ID:        note.ID + "/activity",
+		Type:      "Create",
+		Actor:     actorID,
+		Object:    noteJSON,
+		Published: &now,
+		To:        note.To,
+		Cc:        note.Cc,
+	}
+	createJSON, err := json.Marshal(create)
+	if err != nil {
+		return fmt.Errorf("failed to encode activity: %w", err)
+	}
+	if err := s.db.WithContext(ctx).Create(&database.FederationActivity{
+		UserID:     userID,
+		BookmarkID: bookmark.ID,
+		Type:       create.Type,
+		Object:     string(createJSON),
+	}).Error; err != nil {
+		return fmt.Errorf("failed to add to outbox: %w", err)
+	}
+
+	var followers []database.FederationFollower
+	if err := s.db.WithContext(ctx).Where("user_id = ?", userID).Find(&followers).Error; err != nil {
+		return fmt.Errorf("failed to get followers: %w", err)
+	}
+	// Followers on the same instance share one delivery when it has a
+	// shared inbox
+	seen := map[string]bool{}
+	var inboxes []string
+	for _, follower := range followers {
+		inbox := follower.InboxURL
+		if follower.SharedInbox != "" {
+			inbox = follower.SharedInbox
+		}
+		if !seen[inbox] {
+			seen[inbox] = true
+			inboxes = append(inboxes, inbox)
+		}
+	}
+	s.deliverAll(&profile, inboxes, &create)
+	return nil
+}
+
+// deliverAll posts an activity to inboxes outside the caller's request.
+// Failed deliveries are logged and not retried
+func (s *Service) deliverAll(profile *database.User, inboxes []string, activity *Activity) {
+	if len(inboxes) == 0 {
+		return
+	}
+	s.deliver(func() {
+		ctx := context.Background()
+		for _, inbox := range inboxes {
+			if err := s.post(ctx, profile, inbox, activity); err != nil {
+				s.logger.Warn("ActivityPub delivery failed",
+					zap.String("inbox", inbox), zap.String("type", activity.Type), zap.Error(err))
+			}
+		}
+	})
+}
+
+// post delivers an activity to an inbox, signed with the profile's key
+func (s *Service) post(ctx context.Context, profile *database.User, inbox string, activity *Activity) error {
+	key, err := s.keyFor(ctx, profile.ID)
+	if err != nil {
+		return err
+	}
+	privateKey, err := parsePrivateKey(key.PrivateKeyPEM)
+	if err != nil {
+		return fmt.Errorf("failed to read actor key: %w", err)
+	}
+	body, err := json.Marshal(activity)
+	if err != nil {
+		return fmt.Errorf("failed to encode activity: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, inbox, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid inbox: %w", err)
+	}
+	req.Header.Set("Content-Type", ContentType)
+	if err := signRequest(req, body, s.actorID(profile.Username)+"#main-key", privateKey, s.now()); err != nil {
+		return err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("inbox answered HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// fetchActor reads the actor document of another instance
+func (s *Service) fetchActor(ctx context.Context, actorURL string) (*remoteActor, error) {
+	parsed, err := url.Parse(actorURL)
+	if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") {
+		return nil, errors.New("invalid actor URL")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, actorURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", ContentType)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("actor fetch answered HTTP %d", resp.StatusCode)
+	}
+
+	var actor remoteActor
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxActorSize)).Decode(&actor); err != nil {
+		return nil, fmt.Errorf("invalid actor document: %w", err)
+	}
+	if actor.ID == "" {
+		return nil, errors.New("actor document has no id")
+	}
+	if !sameHost(actor.ID, parsed.Host) {
+		return nil, errors.New("actor id is not on the host serving it")
+	}
+	return &actor, nil
+}
+
+// keyFor returns a user's key pair, creating it on first use
+func (s *Service) keyFor(ctx context.Context, userID uint) (*database.FederationKey, error) {
+	var key database.FederationKey
+	err := s.db.WithContext(ctx).Where("user_id = ?", userID).First(&key).Error
+	if err == nil {
+		return &key, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to get actor key: %w", err)
+	}
+
+	publicPEM, privatePEM, err := generateKey()
+	if err != nil {
+		return nil, err
+	}
+	key = database.FederationKey{UserID: userID, PublicKeyPEM: publicPEM, PrivateKeyPEM: privatePEM}
+	if err := s.db.WithContext(ctx).Create(&key).Error; err != nil {
+		// Another request created it first
+		if err := s.db.WithContext(ctx).Where("user_id = ?", userID).First(&key).Error; err != nil {
+			return nil, fmt.Errorf("failed to save actor key: %w", err)
+		}
+	}
+	return &key, nil
+}
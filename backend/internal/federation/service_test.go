@@ -0,0 +1,237 @@
+package federation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/internal/user"
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+type stubProfiles struct {
+	db *gorm.DB
+}
+
+func (p *stubProfiles) PublicProfile(ctx context.Context, username string) (*database.User, error) {
+	var profile database.User
+	if err := p.db.Where("username = ?", username).First(&profile).Error; err != nil || !user.ProfileIsPublic(profile.Preferences) {
+		return nil, user.ErrPublicProfileNotFound
+	}
+	return &profile, nil
+}
+
+// remoteInstance is another server with one actor, recording what is
+// delivered to its inbox
+type remoteInstance struct {
+	server    *httptest.Server
+	actorID   string
+	inbox     string // the inbox the actor document names, its own by default
+	sign      func(req *http.Request, body []byte)
+	mu        sync.Mutex
+	delivered []*http.Request
+	bodies    [][]byte
+}
+
+func newRemoteInstance(t *testing.T) *remoteInstance {
+	remote := &remoteInstance{}
+	publicPEM, privatePEM, err := generateKey()
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/users/mallory", func(w http.ResponseWriter, r *http.Request) {
+		inbox := remote.inbox
+		if inbox == "" {
+			inbox = remote.server.URL + "/users/mallory/inbox"
+		}
+		w.Header().Set("Content-Type", ContentType)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":        remote.actorID,
+			"type":      "Person",
+			"inbox":     inbox,
+			"endpoints": map[string]string{"sharedInbox": remote.server.URL + "/inbox"},
+			"publicKey": PublicKey{ID: remote.actorID + "#main-key", Owner: remote.actorID, PublicKeyPem: publicPEM},
+		})
+	})
+	record := func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		remote.mu.Lock()
+		remote.delivered = append(remote.delivered, r)
+		remote.bodies = append(remote.bodies, body)
+		remote.mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	}
+	mux.HandleFunc("/users/mallory/inbox", record)
+	mux.HandleFunc("/inbox", record)
+	remote.server = httptest.NewServer(mux)
+	t.Cleanup(remote.server.Close)
+	remote.actorID = remote.server.URL + "/users/mallory"
+
+	key, err := parsePrivateKey(privatePEM)
+	require.NoError(t, err)
+	remote.sign = func(req *http.Request, body []byte) {
+		require.NoError(t, signRequest(req, body, remote.actorID+"#main-key", key, time.Now()))
+	}
+	return remote
+}
+
+func TestFederation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, err := database.SetupTestDB()
+	require.NoError(t, err)
+	t.Cleanup(func() { database.CleanupTestDB(db) })
+
+	alice := database.User{Email: "alice@example.com", Username: "alice", SupabaseID: "alice", DisplayName: "Alice", Preferences: `{"profileVisibility":"public"}`}
+	bob := database.User{Email: "bob@example.com", Username: "bob", SupabaseID: "bob"}
+	require.NoError(t, db.Create(&alice).Error)
+	require.NoError(t, db.Create(&bob).Error)
+
+	service := NewService(config.FederationConfig{Enabled: true, OutboxPageSize: 20}, "https://bookmarks.example/", db, &stubProfiles{db: db}, zap.NewNop())
+	service.deliver = func(fn func()) { fn() }
+	router := gin.New()
+	NewHandler(service).RegisterRoutes(router.Group("/"))
+	ctx := context.Background()
+	remote := newRemoteInstance(t)
+	// The remote instance runs on loopback, which the service refuses
+	_, err = service.fetchActor(ctx, remote.actorID)
+	assert.ErrorIs(t, err, ErrPrivateAddress)
+	service.client = remote.server.Client()
+
+	t.Run("Public profiles are discoverable actors", func(t *testing.T) {
+		finger, err := service.WebFinger(ctx, "acct:alice@bookmarks.example")
+		require.NoError(t, err)
+		assert.Equal(t, "https://bookmarks.example/ap/users/alice", finger.Links[0].Href)
+		_, err = service.WebFinger(ctx, "acct:bob@bookmarks.example")
+		assert.ErrorIs(t, err, ErrActorNotFound)
+		_, err = service.WebFinger(ctx, "acct:alice@elsewhere.example")
+		assert.ErrorIs(t, err, ErrActorNotFound)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ap/users/alice", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Header().Get("Content-Type"), ContentType)
+		var actor Actor
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &actor))
+		assert.Equal(t, "https://bookmarks.example/ap/users/alice/inbox", actor.Inbox)
+		assert.Contains(t, actor.PublicKey.PublicKeyPem, "PUBLIC KEY")
+
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ap/users/bob", nil))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	postInbox := func(activity map[string]interface{}, signed bool) int {
+		body, _ := json.Marshal(activity)
+		req := httptest.NewRequest(http.MethodPost, "/ap/users/alice/inbox", bytes.NewReader(body))
+		req.Host = "bookmarks.example"
+		req.Header.Set("Content-Type", ContentType)
+		if signed {
+			remote.sign(req, body)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	follow := map[string]interface{}{
+		"@context": activityStreamsContext,
+		"id":       remote.actorID + "#follows/1",
+		"type":     "Follow",
+		"actor":    remote.actorID,
+		"object":   "https://bookmarks.example/ap/users/alice",
+	}
+
+	t.Run("Signed follows are accepted", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, postInbox(follow, false))
+		require.Equal(t, http.StatusAccepted, postInbox(follow, true))
+
+		var followers []database.FederationFollower
+		require.NoError(t, db.Where("user_id = ?", alice.ID).Find(&followers).Error)
+		require.Len(t, followers, 1)
+		assert.Equal(t, remote.actorID, followers[0].ActorURI)
+
+		require.Len(t, remote.bodies, 1)
+		var accept Activity
+		require.NoError(t, json.Unmarshal(remote.bodies[0], &accept))
+		assert.Equal(t, "Accept", accept.Type)
+		assert.Equal(t, "/users/mallory/inbox", remote.delivered[0].URL.Path)
+
+		// The Accept verifies with the key the actor publishes
+		actor, err := service.Actor(ctx, "alice")
+		require.NoError(t, err)
+		key, err := parsePublicKey(actor.PublicKey.PublicKeyPem)
+		require.NoError(t, err)
+		sig, err := parseSignature(remote.delivered[0])
+		require.NoError(t, err)
+		assert.NoError(t, verifySignature(remote.delivered[0], remote.bodies[0], sig, key, time.Now()))
+	})
+
+	t.Run("Public bookmarks are published to followers once", func(t *testing.T) {
+		bookmark := &database.Bookmark{UserID: alice.ID, URL: "https://go.dev/?a=1&b=2", Title: "Go <3", Tags: `["dev/go"]`}
+		require.NoError(t, db.Create(bookmark).Error)
+		require.NoError(t, service.PublishBookmark(ctx, alice.ID, bookmark))
+		require.NoError(t, service.PublishBookmark(ctx, alice.ID, bookmark))
+
+		flagged := &database.Bookmark{UserID: alice.ID, URL: "https://malware.example", Title: "Bad", SafetyThreat: "malware"}
+		require.NoError(t, db.Create(flagged).Error)
+		require.NoError(t, service.PublishBookmark(ctx, alice.ID, flagged))
+
+		require.Len(t, remote.bodies, 2)
+		assert.Equal(t, "/inbox", remote.delivered[1].URL.Path, "delivered to the shared inbox")
+		var create Activity
+		require.NoError(t, json.Unmarshal(remote.bodies[1], &create))
+		assert.Equal(t, "Create", create.Type)
+		var note Note
+		require.NoError(t, json.Unmarshal(create.Object, &note))
+		assert.Contains(t, note.Content, "Go &lt;3")
+		assert.Contains(t, note.Content, "#dev_go")
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ap/users/alice/outbox?page=1", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		var page OrderedCollectionPage
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+		assert.Len(t, page.OrderedItems, 1)
+		assert.Empty(t, page.Next)
+	})
+
+	t.Run("Undo removes the follower", func(t *testing.T) {
+		undo := map[string]interface{}{
+			"id":     remote.actorID + "#undo/1",
+			"type":   "Undo",
+			"actor":  remote.actorID,
+			"object": follow,
+		}
+		require.Equal(t, http.StatusAccepted, postInbox(undo, true))
+		followers, err := service.Followers(ctx, "alice")
+		require.NoError(t, err)
+		assert.Zero(t, followers.TotalItems)
+
+		like := map[string]interface{}{"id": remote.actorID + "#likes/1", "type": "Like", "actor": remote.actorID, "object": "x"}
+		assert.Equal(t, http.StatusAccepted, postInbox(like, true), "other activities are acknowledged")
+	})
+
+	t.Run("Inboxes on other hosts are refused", func(t *testing.T) {
+		delivered := len(remote.bodies)
+		remote.inbox = "http://169.254.169.254/latest/meta-data"
+		t.Cleanup(func() { remote.inbox = "" })
+
+		assert.Equal(t, http.StatusAccepted, postInbox(follow, true))
+		followers, err := service.Followers(ctx, "alice")
+		require.NoError(t, err)
+		assert.Zero(t, followers.TotalItems)
+		assert.Len(t, remote.bodies, delivered, "no Accept is sent")
+	})
+}
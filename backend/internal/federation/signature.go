@@ -0,0 +1,180 @@
+package federation
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// signedHeaders are the headers outgoing requests sign, in order
+var signedHeaders = []string{"(request-target)", "host", "date", "digest"}
+
+// maxClockSkew is how far the Date of a signed request may be from now
+const maxClockSkew = time.Hour
+
+var (
+	// ErrMissingSignature is returned for inbox requests without an HTTP signature
+	ErrMissingSignature = errors.New("request is not signed")
+	// ErrInvalidSignature is returned when a signature does not verify
+	ErrInvalidSignature = errors.New("invalid request signature")
+)
+
+// signature is a parsed Signature header
+type signature struct {
+	KeyID     string
+	Algorithm string
+	Headers   []string
+	Signature []byte
+}
+
+// generateKey creates an actor's key pair as PEM
+func generateKey() (publicPEM, privatePEM string, err error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate actor key: %w", err)
+	}
+	public, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to encode actor key: %w", err)
+	}
+	publicPEM = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: public}))
+	privatePEM = string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+	return publicPEM, privatePEM, nil
+}
+
+func parsePrivateKey(privatePEM string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(privatePEM))
+	if block == nil {
+		return nil, errors.New("invalid private key")
+	}
+	return x509.ParsePKCS1PrivateKey(block.Bytes)
+}
+
+func parsePublicKey(publicPEM string) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(publicPEM))
+	if block == nil {
+		return nil, errors.New("invalid public key")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("public key is not RSA")
+	}
+	return rsaKey, nil
+}
+
+// digest returns the Digest header value of a body
+func digest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return "SHA-256=" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// signRequest signs a request the way Mastodon expects, setting its Date,
+// Digest and Signature headers
+func signRequest(req *http.Request, body []byte, keyID string, key *rsa.PrivateKey, now time.Time) error {
+	req.Header.Set("Date", now.UTC().Format(http.TimeFormat))
+	req.Header.Set("Digest", digest(body))
+	if req.Host == "" {
+		req.Host = req.URL.Host
+	}
+
+	sum := sha256.Sum256([]byte(signingString(req, signedHeaders)))
+	signed, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	if err != nil {
+		return fmt.Errorf("failed to sign request: %w", err)
+	}
+	req.Header.Set("Signature", fmt.Sprintf(`keyId="%s",algorithm="rsa-sha256",headers="%s",signature="%s"`,
+		keyID, strings.Join(signedHeaders, " "), base64.StdEncoding.EncodeToString(signed)))
+	return nil
+}
+
+// parseSignature reads the Signature header of a request
+func parseSignature(req *http.Request) (*signature, error) {
+	header := req.Header.Get("Signature")
+	if header == "" {
+		return nil, ErrMissingSignature
+	}
+
+	sig := &signature{Headers: []string{"date"}}
+	for _, part := range strings.Split(header, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		value = strings.Trim(value, `"`)
+		switch name {
+		case "keyId":
+			sig.KeyID = value
+		case "algorithm":
+			sig.Algorithm = value
+		case "headers":
+			sig.Headers = strings.Fields(strings.ToLower(value))
+		case "signature":
+			decoded, err := base64.StdEncoding.DecodeString(value)
+			if err != nil {
+				return nil, ErrInvalidSignature
+			}
+			sig.Signature = decoded
+		}
+	}
+	if sig.KeyID == "" || len(sig.Signature) == 0 {
+		return nil, ErrInvalidSignature
+	}
+	return sig, nil
+}
+
+// verifySignature checks a parsed signature against a request and its body.
+// The signature must cover the body digest and a recent date
+func verifySignature(req *http.Request, body []byte, sig *signature, key *rsa.PublicKey, now time.Time) error {
+	if sig.Algorithm != "" && sig.Algorithm != "rsa-sha256" && sig.Algorithm != "hs2019" {
+		return ErrInvalidSignature
+	}
+	covers := map[string]bool{}
+	for _, h := range sig.Headers {
+		covers[h] = true
+	}
+	if !covers["date"] || !covers["digest"] || !covers["(request-target)"] {
+		return ErrInvalidSignature
+	}
+	if req.Header.Get("Digest") != digest(body) {
+		return ErrInvalidSignature
+	}
+	date, err := http.ParseTime(req.Header.Get("Date"))
+	if err != nil || date.Sub(now) > maxClockSkew || now.Sub(date) > maxClockSkew {
+		return ErrInvalidSignature
+	}
+
+	sum := sha256.Sum256([]byte(signingString(req, sig.Headers)))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, sum[:], sig.Signature); err != nil {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// signingString builds the string an HTTP signature covers
+func signingString(req *http.Request, headers []string) string {
+	lines := make([]string, 0, len(headers))
+	for _, h := range headers {
+		switch h {
+		case "(request-target)":
+			lines = append(lines, "(request-target): "+strings.ToLower(req.Method)+" "+req.URL.RequestURI())
+		case "host":
+			lines = append(lines, "host: "+req.Host)
+		default:
+			lines = append(lines, h+": "+req.Header.Get(h))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
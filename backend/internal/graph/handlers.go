@@ -0,0 +1,71 @@
+package graph
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"bookmark-sync-service/backend/pkg/utils"
+)
+
+// Handler serves the bookmark graph
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new graph handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes registers the graph routes
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	graph := router.Group("/graph")
+	graph.GET("/bookmarks", h.GetBookmarks)
+}
+
+// GetBookmarks returns the bookmarks related to a bookmark as a graph
+// @Summary Get the bookmark relationship graph
+// @Description Bookmarks up to depth hops from the center, related by domain, shared collections and visits together; refreshed by the worker every few minutes
+// @Tags graph
+// @Produce json
+// @Param center query int true "Bookmark ID at the center of the graph"
+// @Param depth query int false "Hops from the center, 2 by default and at most 3"
+// @Success 200 {object} Graph
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /graph/bookmarks [get]
+func (h *Handler) GetBookmarks(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	center, err := strconv.ParseUint(c.Query("center"), 10, 32)
+	if err != nil || center == 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_CENTER", "center must be a bookmark ID", nil)
+		return
+	}
+	depth, err := strconv.Atoi(c.DefaultQuery("depth", "2"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_DEPTH", ErrInvalidDepth.Error(), nil)
+		return
+	}
+
+	graph, err := h.service.Bookmarks(c.Request.Context(), userID, uint(center), depth)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidDepth):
+			utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_DEPTH", err.Error(), nil)
+		case errors.Is(err, ErrBookmarkNotFound):
+			utils.ErrorResponse(c, http.StatusNotFound, "BOOKMARK_NOT_FOUND", "Bookmark not found", nil)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to get bookmark graph", nil)
+		}
+		return
+	}
+	utils.SuccessResponse(c, graph, "Bookmark graph retrieved")
+}
@@ -0,0 +1,568 @@
+// Package graph serves the relationships between a user's bookmarks as a
+// graph for visualization: bookmarks on the same domain, filed in the same
+// collections and visited one after the other. The edges are precomputed
+// by the worker from the bookmarks, collections and behaviors changed since
+// its last run, so a graph is read from the edge table alone
+package graph
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+// Kinds of edges
+const (
+	KindSameDomain   = "same_domain"
+	KindCoCollection = "co_collection"
+	KindCoVisit      = "co_visit"
+)
+
+// Sources the graph is refreshed from, named by their table
+const (
+	sourceBookmarks = "bookmarks"
+	// sourceBehaviors holds the bookmarks users visit. It belongs to the
+	// community feature and may not be migrated
+	sourceBehaviors = "user_behaviors"
+)
+
+const (
+	defaultBatchSize     = 500
+	defaultNeighbors     = 20
+	defaultMaxNodes      = 200
+	defaultCoVisitWindow = 30 * time.Minute
+
+	// MaxDepth bounds how many hops from the center a graph reaches
+	MaxDepth = 3
+)
+
+// visitActions are the behaviors that count as visiting a bookmark
+var visitActions = []string{"view", "click"}
+
+var (
+	// ErrBookmarkNotFound is returned for a center the user doesn't own
+	ErrBookmarkNotFound = errors.New("bookmark not found")
+	// ErrInvalidDepth is returned for a depth outside 1 to MaxDepth
+	ErrInvalidDepth = fmt.Errorf("depth must be between 1 and %d", MaxDepth)
+)
+
+// Node is a bookmark of a graph
+type Node struct {
+	ID     uint   `json:"id"`
+	Title  string `json:"title"`
+	URL    string `json:"url"`
+	Domain string `json:"domain,omitempty"`
+	Depth  int    `json:"depth"` // hops from the center
+}
+
+// Edge relates two bookmarks of a graph. Edges are undirected; the source
+// is the lower ID
+type Edge struct {
+	Source uint   `json:"source"`
+	Target uint   `json:"target"`
+	Kind   string `json:"kind"`
+	Weight int    `json:"weight"` // shared collections or visits together; 1 for the same domain
+}
+
+// Graph is the neighbourhood of a bookmark
+type Graph struct {
+	Center    uint       `json:"center"`
+	Depth     int        `json:"depth"`
+	Nodes     []Node     `json:"nodes"`
+	Edges     []Edge     `json:"edges"`
+	Truncated bool       `json:"truncated"`       // more bookmarks were in reach than a graph holds
+	AsOf      *time.Time `json:"as_of,omitempty"` // last refresh; later changes are not in the graph yet
+}
+
+// Service precomputes and serves the bookmark graph
+type Service struct {
+	db  *gorm.DB
+	cfg config.GraphConfig
+}
+
+// NewService creates a new graph service
+func NewService(cfg config.GraphConfig, db *gorm.DB) *Service {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultBatchSize
+	}
+	if cfg.Neighbors <= 0 {
+		cfg.Neighbors = defaultNeighbors
+	}
+	if cfg.MaxNodes <= 0 {
+		cfg.MaxNodes = defaultMaxNodes
+	}
+	return &Service{db: db, cfg: cfg}
+}
+
+// coVisitWindow is how far apart two visits may be to count as together
+func (s *Service) coVisitWindow() time.Duration {
+	if s.cfg.CoVisitWindow <= 0 {
+		return defaultCoVisitWindow
+	}
+	return time.Duration(s.cfg.CoVisitWindow) * time.Minute
+}
+
+// edgeKey identifies an undirected edge
+type edgeKey struct {
+	source, target uint
+	kind           string
+}
+
+// Bookmarks returns the bookmarks up to depth hops from a user's center
+// bookmark and the edges between them, nearest first
+func (s *Service) Bookmarks(ctx context.Context, userID, center uint, depth int) (*Graph, error) {
+	if depth < 1 || depth > MaxDepth {
+		return nil, ErrInvalidDepth
+	}
+	db := s.db.WithContext(ctx)
+
+	var count int64
+	if err := db.Model(&database.Bookmark{}).Where("id = ? AND user_id = ?", center, userID).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to get bookmark: %w", err)
+	}
+	if count == 0 {
+		return nil, ErrBookmarkNotFound
+	}
+
+	graph := &Graph{Center: center, Depth: depth}
+	depths := map[uint]int{center: 0}
+	weights := map[edgeKey]int{}
+	frontier := []uint{center}
+	for hop := 1; hop <= depth && len(frontier) > 0; hop++ {
+		var edges []database.BookmarkEdge
+		if err := db.Where("user_id = ? AND (source_id IN ? OR target_id IN ?)", userID, frontier, frontier).
+			Order("weight DESC, id").Find(&edges).Error; err != nil {
+			return nil, fmt.Errorf("failed to load edges: %w", err)
+		}
+
+		var next []uint
+		for _, edge := range edges {
+			for _, id := range []uint{edge.SourceID, edge.TargetID} {
+				if _, ok := depths[id]; ok {
+					continue
+				}
+				if len(depths) >= s.cfg.MaxNodes {
+					graph.Truncated = true
+					continue
+				}
+				depths[id] = hop
+				next = append(next, id)
+			}
+			key := edgeKey{source: min(edge.SourceID, edge.TargetID), target: max(edge.SourceID, edge.TargetID), kind: edge.Kind}
+			weights[key] = max(weights[key], edge.Weight)
+		}
+		frontier = next
+	}
+
+	// Edges may outlive a bookmark until the next refresh, so only the
+	// user's current bookmarks are nodes
+	ids := make([]uint, 0, len(depths))
+	for id := range depths {
+		ids = append(ids, id)
+	}
+	var bookmarks []database.Bookmark
+	if err := db.Select("id", "title", "url").Where("id IN ? AND user_id = ?", ids, userID).
+		Find(&bookmarks).Error; err != nil {
+		return nil, fmt.Errorf("failed to load bookmarks: %w", err)
+	}
+	graph.Nodes = make([]Node, 0, len(bookmarks))
+	present := make(map[uint]bool, len(bookmarks))
+	for _, bookmark := range bookmarks {
+		present[bookmark.ID] = true
+		graph.Nodes = append(graph.Nodes, Node{
+			ID:     bookmark.ID,
+			Title:  bookmark.Title,
+			URL:    bookmark.URL,
+			Domain: domain(bookmark.URL),
+			Depth:  depths[bookmark.ID],
+		})
+	}
+	slices.SortFunc(graph.Nodes, func(a, b Node) int {
+		if a.Depth != b.Depth {
+			return cmp.Compare(a.Depth, b.Depth)
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	graph.Edges = []Edge{}
+	for key, weight := range weights {
+		if present[key.source] && present[key.target] {
+			graph.Edges = append(graph.Edges, Edge{Source: key.source, Target: key.target, Kind: key.kind, Weight: weight})
+		}
+	}
+	slices.SortFunc(graph.Edges, func(a, b Edge) int {
+		if a.Source != b.Source {
+			return cmp.Compare(a.Source, b.Source)
+		}
+		if a.Target != b.Target {
+			return cmp.Compare(a.Target, b.Target)
+		}
+		return strings.Compare(a.Kind, b.Kind)
+	})
+
+	cursor, err := s.cursor(db, sourceBookmarks)
+	if err != nil {
+		return nil, err
+	}
+	if !cursor.UpdatedAt.IsZero() {
+		graph.AsOf = &cursor.UpdatedAt
+	}
+	return graph, nil
+}
+
+// MarkCollection queues the bookmarks of a collection for their edges to be
+// rebuilt, along with bookmarks that just left it
+func (s *Service) MarkCollection(ctx context.Context, collectionID uint, bookmarkIDs ...uint) error {
+	db := s.db.WithContext(ctx)
+
+	var members []uint
+	if err := db.Table("bookmark_collections").Where("collection_id = ?", collectionID).
+		Pluck("bookmark_id", &members).Error; err != nil {
+		return fmt.Errorf("failed to load collection bookmarks: %w", err)
+	}
+	members = append(members, bookmarkIDs...)
+	if len(members) == 0 {
+		return nil
+	}
+
+	changes := make([]database.BookmarkGraphChange, 0, len(members))
+	for _, id := range members {
+		changes = append(changes, database.BookmarkGraphChange{BookmarkID: id})
+	}
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(changes, s.cfg.BatchSize).Error; err != nil {
+		return fmt.Errorf("failed to queue graph changes: %w", err)
+	}
+	return nil
+}
+
+// Refresh brings the graph up to date with the bookmarks changed, queued
+// and visited since the last run and returns how many bookmarks it rebuilt
+// the edges of
+func (s *Service) Refresh(ctx context.Context) (int, error) {
+	behaviors := s.db.Migrator().HasTable(sourceBehaviors)
+
+	rebuilt := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return rebuilt, err
+		}
+
+		var more bool
+		err := database.WithTransaction(ctx, s.db, func(tx *gorm.DB) error {
+			dirty := map[uint]bool{}
+
+			bookmarksCursor, full, err := s.changedBookmarks(tx, dirty)
+			if err != nil {
+				return err
+			}
+			more = full
+			cursors := []database.BookmarkGraphCursor{bookmarksCursor}
+
+			queued, err := s.queuedChanges(tx, dirty)
+			if err != nil {
+				return err
+			}
+			more = more || len(queued) == s.cfg.BatchSize
+
+			if behaviors {
+				behaviorsCursor, full, err := s.newVisits(tx)
+				if err != nil {
+					return err
+				}
+				more = more || full
+				cursors = append(cursors, behaviorsCursor)
+			}
+
+			for id := range dirty {
+				if err := s.rebuild(tx, id); err != nil {
+					return err
+				}
+			}
+			if len(queued) > 0 {
+				if err := tx.Where("bookmark_id IN ?", queued).Delete(&database.BookmarkGraphChange{}).Error; err != nil {
+					return fmt.Errorf("failed to clear graph changes: %w", err)
+				}
+			}
+			for _, cursor := range cursors {
+				if err := tx.Save(&cursor).Error; err != nil {
+					return fmt.Errorf("failed to save graph cursor: %w", err)
+				}
+			}
+			rebuilt += len(dirty)
+			return nil
+		})
+		if err != nil {
+			return rebuilt, err
+		}
+		if !more {
+			return rebuilt, nil
+		}
+	}
+}
+
+// cursor loads how far a source was read
+func (s *Service) cursor(tx *gorm.DB, source string) (database.BookmarkGraphCursor, error) {
+	var cursors []database.BookmarkGraphCursor
+	if err := tx.Where("source = ?", source).Limit(1).Find(&cursors).Error; err != nil {
+		return database.BookmarkGraphCursor{}, fmt.Errorf("failed to load graph cursor: %w", err)
+	}
+	if len(cursors) == 0 {
+		return database.BookmarkGraphCursor{Source: source}, nil
+	}
+	return cursors[0], nil
+}
+
+// changedBookmarks marks the bookmarks created or updated since the cursor
+// and reports whether the batch was full. Soft deletes leave updated_at
+// alone, so edges of deleted bookmarks stay until their neighbours are
+// rebuilt and graphs leave them out meanwhile
+func (s *Service) changedBookmarks(tx *gorm.DB, dirty map[uint]bool) (database.BookmarkGraphCursor, bool, error) {
+	cursor, err := s.cursor(tx, sourceBookmarks)
+	if err != nil {
+		return cursor, false, err
+	}
+
+	var bookmarks []database.Bookmark
+	if err := tx.Unscoped().Select("id", "updated_at").
+		Where("updated_at > ? OR (updated_at = ? AND id > ?)", cursor.LastAt, cursor.LastAt, cursor.LastID).
+		Order("updated_at, id").Limit(s.cfg.BatchSize).
+		Find(&bookmarks).Error; err != nil {
+		return cursor, false, fmt.Errorf("failed to load changed bookmarks: %w", err)
+	}
+	for _, bookmark := range bookmarks {
+		dirty[bookmark.ID] = true
+		cursor.LastID, cursor.LastAt = bookmark.ID, bookmark.UpdatedAt
+	}
+	return cursor, len(bookmarks) == s.cfg.BatchSize, nil
+}
+
+// queuedChanges marks a batch of the queued bookmarks and returns them
+func (s *Service) queuedChanges(tx *gorm.DB, dirty map[uint]bool) ([]uint, error) {
+	var queued []uint
+	if err := tx.Model(&database.BookmarkGraphChange{}).Order("bookmark_id").Limit(s.cfg.BatchSize).
+		Pluck("bookmark_id", &queued).Error; err != nil {
+		return nil, fmt.Errorf("failed to load graph changes: %w", err)
+	}
+	for _, id := range queued {
+		dirty[id] = true
+	}
+	return queued, nil
+}
+
+// visit is a bookmark a user visited
+type visit struct {
+	ID         uint
+	UserID     string
+	ActionType string
+	BookmarkID uint
+	CreatedAt  time.Time
+	DeletedAt  *time.Time
+}
+
+// newVisits counts the bookmarks visited since the cursor right after
+// another one of the user's, within the co-visit window, as visited
+// together. It reports whether the batch was full
+func (s *Service) newVisits(tx *gorm.DB) (database.BookmarkGraphCursor, bool, error) {
+	cursor, err := s.cursor(tx, sourceBehaviors)
+	if err != nil {
+		return cursor, false, err
+	}
+
+	var rows []visit
+	if err := tx.Table(sourceBehaviors).Select("id", "user_id", "action_type", "bookmark_id", "created_at", "deleted_at").
+		Where("id > ?", cursor.LastID).Order("id").Limit(s.cfg.BatchSize).
+		Scan(&rows).Error; err != nil {
+		return cursor, false, fmt.Errorf("failed to load new behaviors: %w", err)
+	}
+	if len(rows) == 0 {
+		return cursor, false, nil
+	}
+	cursor.LastID = rows[len(rows)-1].ID
+
+	var visits []visit
+	for _, row := range rows {
+		if row.DeletedAt == nil && slices.Contains(visitActions, row.ActionType) {
+			visits = append(visits, row)
+		}
+	}
+
+	last := map[string]*visit{}
+	for i := range visits {
+		current := &visits[i]
+		previous, ok := last[current.UserID]
+		if !ok {
+			if previous, err = s.previousVisit(tx, current); err != nil {
+				return cursor, false, err
+			}
+		}
+		last[current.UserID] = current
+
+		if previous == nil || previous.BookmarkID == current.BookmarkID ||
+			current.CreatedAt.Sub(previous.CreatedAt) > s.coVisitWindow() {
+			continue
+		}
+		if err := s.addCoVisit(tx, current.UserID, previous.BookmarkID, current.BookmarkID); err != nil {
+			return cursor, false, err
+		}
+	}
+	return cursor, len(rows) == s.cfg.BatchSize, nil
+}
+
+// previousVisit loads the user's visit before v, if any
+func (s *Service) previousVisit(tx *gorm.DB, v *visit) (*visit, error) {
+	var previous []visit
+	if err := tx.Table(sourceBehaviors).Select("id", "user_id", "bookmark_id", "created_at").
+		Where("user_id = ? AND id < ? AND action_type IN ? AND deleted_at IS NULL", v.UserID, v.ID, visitActions).
+		Order("id DESC").Limit(1).Scan(&previous).Error; err != nil {
+		return nil, fmt.Errorf("failed to load previous visit: %w", err)
+	}
+	if len(previous) == 0 {
+		return nil, nil
+	}
+	return &previous[0], nil
+}
+
+// addCoVisit counts one more visit of two bookmarks together. Visits of
+// bookmarks the user doesn't own, such as public ones of others, are left
+// out of the user's graph
+func (s *Service) addCoVisit(tx *gorm.DB, rawUserID string, a, b uint) error {
+	userID, err := strconv.ParseUint(rawUserID, 10, 32)
+	if err != nil {
+		return nil
+	}
+
+	var owned int64
+	if err := tx.Model(&database.Bookmark{}).Where("id IN ? AND user_id = ?", []uint{a, b}, userID).
+		Count(&owned).Error; err != nil {
+		return fmt.Errorf("failed to check visited bookmarks: %w", err)
+	}
+	if owned != 2 {
+		return nil
+	}
+
+	edge := database.BookmarkEdge{UserID: uint(userID), SourceID: min(a, b), TargetID: max(a, b), Kind: KindCoVisit, Weight: 1}
+	if err := tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "source_id"}, {Name: "target_id"}, {Name: "kind"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"weight":     gorm.Expr("bookmark_edges.weight + 1"),
+			"updated_at": time.Now(),
+		}),
+	}).Create(&edge).Error; err != nil {
+		return fmt.Errorf("failed to save co-visit edge: %w", err)
+	}
+	return nil
+}
+
+// rebuild replaces the same domain and co-collection edges of a bookmark,
+// and drops every edge of one that was deleted or changed hands
+func (s *Service) rebuild(tx *gorm.DB, id uint) error {
+	var bookmarks []database.Bookmark
+	if err := tx.Select("id", "user_id", "url").Where("id = ?", id).Limit(1).Find(&bookmarks).Error; err != nil {
+		return fmt.Errorf("failed to load bookmark: %w", err)
+	}
+	if len(bookmarks) == 0 {
+		if err := tx.Where("source_id = ? OR target_id = ?", id, id).Delete(&database.BookmarkEdge{}).Error; err != nil {
+			return fmt.Errorf("failed to delete bookmark edges: %w", err)
+		}
+		return nil
+	}
+	bookmark := bookmarks[0]
+
+	if err := tx.Where("(source_id = ? OR target_id = ?) AND user_id <> ?", id, id, bookmark.UserID).
+		Delete(&database.BookmarkEdge{}).Error; err != nil {
+		return fmt.Errorf("failed to delete bookmark edges: %w", err)
+	}
+	if err := tx.Where("source_id = ? AND kind IN ?", id, []string{KindSameDomain, KindCoCollection}).
+		Delete(&database.BookmarkEdge{}).Error; err != nil {
+		return fmt.Errorf("failed to delete bookmark edges: %w", err)
+	}
+
+	var edges []database.BookmarkEdge
+	sameDomain, err := s.sameDomain(tx, &bookmark)
+	if err != nil {
+		return err
+	}
+	for _, target := range sameDomain {
+		edges = append(edges, database.BookmarkEdge{UserID: bookmark.UserID, SourceID: id, TargetID: target, Kind: KindSameDomain, Weight: 1})
+	}
+
+	var coCollected []struct {
+		BookmarkID uint
+		Shared     int
+	}
+	if err := tx.Table("bookmark_collections AS mine").
+		Select("theirs.bookmark_id, COUNT(*) AS shared").
+		Joins("JOIN collections ON collections.id = mine.collection_id AND collections.deleted_at IS NULL").
+		Joins("JOIN bookmark_collections AS theirs ON theirs.collection_id = mine.collection_id AND theirs.bookmark_id <> mine.bookmark_id").
+		Joins("JOIN bookmarks ON bookmarks.id = theirs.bookmark_id AND bookmarks.user_id = ? AND bookmarks.deleted_at IS NULL", bookmark.UserID).
+		Where("mine.bookmark_id = ?", id).
+		Group("theirs.bookmark_id").Order("shared DESC, theirs.bookmark_id").Limit(s.cfg.Neighbors).
+		Scan(&coCollected).Error; err != nil {
+		return fmt.Errorf("failed to load co-collected bookmarks: %w", err)
+	}
+	for _, row := range coCollected {
+		edges = append(edges, database.BookmarkEdge{UserID: bookmark.UserID, SourceID: id, TargetID: row.BookmarkID, Kind: KindCoCollection, Weight: row.Shared})
+	}
+
+	if len(edges) == 0 {
+		return nil
+	}
+	if err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "source_id"}, {Name: "target_id"}, {Name: "kind"}},
+		DoUpdates: clause.AssignmentColumns([]string{"user_id", "weight", "updated_at"}),
+	}).Create(&edges).Error; err != nil {
+		return fmt.Errorf("failed to save bookmark edges: %w", err)
+	}
+	return nil
+}
+
+// sameDomain returns the user's most recent other bookmarks on the domain
+// of a bookmark
+func (s *Service) sameDomain(tx *gorm.DB, bookmark *database.Bookmark) ([]uint, error) {
+	host := domain(bookmark.URL)
+	if host == "" {
+		return nil, nil
+	}
+
+	// LIKE narrows the candidates down; the host decides
+	var candidates []database.Bookmark
+	if err := tx.Select("id", "url").
+		Where("user_id = ? AND id <> ?", bookmark.UserID, bookmark.ID).
+		Where("url LIKE ? OR url LIKE ?", "%://"+host+"%", "%://www."+host+"%").
+		Order("created_at DESC, id DESC").Limit(s.cfg.Neighbors * 4).
+		Find(&candidates).Error; err != nil {
+		return nil, fmt.Errorf("failed to load same domain bookmarks: %w", err)
+	}
+
+	var ids []uint
+	for _, candidate := range candidates {
+		if len(ids) == s.cfg.Neighbors {
+			break
+		}
+		if domain(candidate.URL) == host {
+			ids = append(ids, candidate.ID)
+		}
+	}
+	return ids, nil
+}
+
+// domain returns the host of a URL in lower case without www.
+func domain(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+}
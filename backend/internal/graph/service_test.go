@@ -0,0 +1,196 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bookmark-sync-service/backend/internal/community"
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+func nodeIDs(graph *Graph) []uint {
+	ids := make([]uint, 0, len(graph.Nodes))
+	for _, node := range graph.Nodes {
+		ids = append(ids, node.ID)
+	}
+	return ids
+}
+
+func TestBookmarkGraph(t *testing.T) {
+	db, err := database.SetupTestDB()
+	require.NoError(t, err)
+	t.Cleanup(func() { database.CleanupTestDB(db) })
+	require.NoError(t, community.AutoMigrate(db))
+
+	user := database.User{Email: "reader@example.com", Username: "reader", SupabaseID: "reader"}
+	other := database.User{Email: "other@example.com", Username: "other", SupabaseID: "other"}
+	require.NoError(t, db.Create(&user).Error)
+	require.NoError(t, db.Create(&other).Error)
+
+	// Small batches make a refresh take several transactions
+	service := NewService(config.GraphConfig{BatchSize: 2, CoVisitWindow: 30}, db)
+	ctx := context.Background()
+
+	save := func(owner database.User, url string) database.Bookmark {
+		bookmark := database.Bookmark{UserID: owner.ID, URL: url, Title: url}
+		require.NoError(t, db.Create(&bookmark).Error)
+		return bookmark
+	}
+	docs := save(user, "https://go.dev/doc")
+	blog := save(user, "https://www.go.dev/blog")
+	article := save(user, "https://example.com/article")
+	paper := save(user, "https://papers.example.org/paper")
+	foreign := save(other, "https://go.dev/tour")
+
+	reading := database.Collection{UserID: user.ID, Name: "Reading", Visibility: "private"}
+	require.NoError(t, db.Create(&reading).Error)
+	require.NoError(t, db.Model(&reading).Association("Bookmarks").Append(&article, &paper))
+
+	start := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	visit := func(owner database.User, bookmark database.Bookmark, at time.Time) {
+		require.NoError(t, db.Create(&community.UserBehavior{
+			UserID:     strconv.FormatUint(uint64(owner.ID), 10),
+			BookmarkID: bookmark.ID,
+			ActionType: "view",
+			CreatedAt:  at,
+		}).Error)
+	}
+	visit(user, docs, start)
+	visit(user, article, start.Add(5*time.Minute))
+	visit(user, foreign, start.Add(6*time.Minute)) // not the user's bookmark
+	visit(user, paper, start.Add(3*time.Hour))     // too long after
+	visit(user, article, start.Add(4*time.Hour))
+	visit(user, docs, start.Add(4*time.Hour+time.Minute))
+	visit(other, foreign, start.Add(4*time.Hour+2*time.Minute))
+
+	rebuilt, err := service.Refresh(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 5, rebuilt)
+
+	graph, err := service.Bookmarks(ctx, user.ID, docs.ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{docs.ID, blog.ID, article.ID}, nodeIDs(graph))
+	assert.Equal(t, "go.dev", graph.Nodes[1].Domain)
+	assert.Equal(t, []Edge{
+		{Source: docs.ID, Target: blog.ID, Kind: KindSameDomain, Weight: 1},
+		{Source: docs.ID, Target: article.ID, Kind: KindCoVisit, Weight: 2},
+	}, graph.Edges)
+	assert.NotNil(t, graph.AsOf)
+
+	graph, err = service.Bookmarks(ctx, user.ID, docs.ID, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{docs.ID, blog.ID, article.ID, paper.ID}, nodeIDs(graph))
+	assert.Equal(t, 2, graph.Nodes[3].Depth)
+	assert.Contains(t, graph.Edges, Edge{Source: article.ID, Target: paper.ID, Kind: KindCoCollection, Weight: 1})
+
+	// Leaving the collection is queued by the collection service
+	require.NoError(t, db.Model(&reading).Association("Bookmarks").Delete(&paper))
+	require.NoError(t, service.MarkCollection(ctx, reading.ID, paper.ID))
+	rebuilt, err = service.Refresh(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, rebuilt)
+
+	graph, err = service.Bookmarks(ctx, user.ID, docs.ID, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{docs.ID, blog.ID, article.ID}, nodeIDs(graph))
+
+	// Deleted bookmarks are left out before their edges are rebuilt
+	require.NoError(t, db.Delete(&blog).Error)
+	graph, err = service.Bookmarks(ctx, user.ID, docs.ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{docs.ID, article.ID}, nodeIDs(graph))
+	assert.Len(t, graph.Edges, 1)
+
+	// Nothing changed since the last refresh
+	rebuilt, err = service.Refresh(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, rebuilt)
+
+	_, err = service.Bookmarks(ctx, other.ID, docs.ID, 1)
+	assert.ErrorIs(t, err, ErrBookmarkNotFound)
+	_, err = service.Bookmarks(ctx, user.ID, docs.ID, MaxDepth+1)
+	assert.ErrorIs(t, err, ErrInvalidDepth)
+
+	graph, err = service.Bookmarks(ctx, other.ID, foreign.ID, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{foreign.ID}, nodeIDs(graph), "users never see each other's bookmarks")
+	assert.Empty(t, graph.Edges)
+}
+
+func TestBookmarkGraphTruncates(t *testing.T) {
+	db, err := database.SetupTestDB()
+	require.NoError(t, err)
+	t.Cleanup(func() { database.CleanupTestDB(db) })
+
+	user := database.User{Email: "reader@example.com", Username: "reader", SupabaseID: "reader"}
+	require.NoError(t, db.Create(&user).Error)
+
+	service := NewService(config.GraphConfig{MaxNodes: 3}, db)
+	var center database.Bookmark
+	for i := 0; i < 5; i++ {
+		bookmark := database.Bookmark{UserID: user.ID, URL: "https://go.dev/" + strconv.Itoa(i)}
+		require.NoError(t, db.Create(&bookmark).Error)
+		if i == 0 {
+			center = bookmark
+		}
+	}
+	_, err = service.Refresh(context.Background())
+	require.NoError(t, err)
+
+	graph, err := service.Bookmarks(context.Background(), user.ID, center.ID, 2)
+	require.NoError(t, err)
+	assert.Len(t, graph.Nodes, 3)
+	assert.True(t, graph.Truncated)
+	for _, edge := range graph.Edges {
+		assert.Contains(t, nodeIDs(graph), edge.Source)
+		assert.Contains(t, nodeIDs(graph), edge.Target)
+	}
+}
+
+func TestGetBookmarksHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, err := database.SetupTestDB()
+	require.NoError(t, err)
+	t.Cleanup(func() { database.CleanupTestDB(db) })
+
+	user := database.User{Email: "reader@example.com", Username: "reader", SupabaseID: "reader"}
+	require.NoError(t, db.Create(&user).Error)
+	bookmark := database.Bookmark{UserID: user.ID, URL: "https://go.dev/doc", Title: "Docs"}
+	require.NoError(t, db.Create(&bookmark).Error)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", strconv.FormatUint(uint64(user.ID), 10))
+		c.Next()
+	})
+	NewHandler(NewService(config.GraphConfig{}, db)).RegisterRoutes(router.Group("/api/v1"))
+
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/graph/bookmarks?"+query, nil))
+		return w
+	}
+
+	w := get("center=" + strconv.FormatUint(uint64(bookmark.ID), 10))
+	require.Equal(t, http.StatusOK, w.Code)
+	var body struct {
+		Data Graph `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, 2, body.Data.Depth)
+	assert.Equal(t, []uint{bookmark.ID}, nodeIDs(&body.Data))
+
+	assert.Equal(t, http.StatusBadRequest, get("").Code)
+	assert.Equal(t, http.StatusBadRequest, get("center=1&depth=9").Code)
+	assert.Equal(t, http.StatusNotFound, get("center=999").Code)
+}
@@ -0,0 +1,87 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"bookmark-sync-service/backend/internal/config"
+)
+
+// Headers of HTTP hook requests
+const (
+	HeaderPoint     = "X-Hook-Point"
+	HeaderSignature = "X-Hook-Signature" // sha256=<hex HMAC of the body>, when the hook has a secret
+)
+
+// maxResponseSize caps what is read of a hook's answer
+const maxResponseSize = 1 << 20
+
+// HTTPRunner calls hooks by POSTing the request as JSON. A 2xx answer with
+// an empty body lets the operation go on unchanged; any other status is a
+// failure
+type HTTPRunner struct {
+	client *http.Client
+}
+
+// NewHTTPRunner creates an HTTP runner; calls are bounded by the hook's
+// timeout, set on the context
+func NewHTTPRunner() *HTTPRunner {
+	return &HTTPRunner{client: &http.Client{}}
+}
+
+// Call posts a request to the hook's URL and decodes its answer
+func (r *HTTPRunner) Call(ctx context.Context, hook config.HookConfig, req *Request) (*Response, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode hook request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create hook request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("User-Agent", "BookmarkSync-Hook/1.0")
+	httpReq.Header.Set(HeaderPoint, string(req.Point))
+	if hook.Secret != "" {
+		httpReq.Header.Set(HeaderSignature, sign(body, hook.Secret))
+	}
+
+	resp, err := r.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call hook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseSize))
+		return nil, fmt.Errorf("hook responded with status %d", resp.StatusCode)
+	}
+
+	answer, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read hook response: %w", err)
+	}
+	var response Response
+	if len(bytes.TrimSpace(answer)) == 0 {
+		return &response, nil
+	}
+	if err := json.Unmarshal(answer, &response); err != nil {
+		return nil, fmt.Errorf("invalid hook response: %w", err)
+	}
+	return &response, nil
+}
+
+// sign returns the signature header value of a hook request body
+func sign(body []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,289 @@
+// Package hooks lets self-hosters validate or enrich data at fixed points of
+// the bookmark, share and import flows without forking: each configured hook
+// is an HTTP endpoint or a WASM module that sees the data and may reject or
+// patch it
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"bookmark-sync-service/backend/internal/config"
+)
+
+// Point is a place in a flow where hooks are called
+type Point string
+
+// Hook points. Hooks at before points run in order and may reject the
+// operation or patch its data; hooks at after points are told about what
+// happened in the background
+const (
+	BeforeBookmarkCreate Point = "before_bookmark_create"
+	BeforeShareCreate    Point = "before_share_create"
+	AfterImport          Point = "after_import"
+)
+
+// Hook types
+const (
+	TypeHTTP = "http"
+	TypeWASM = "wasm"
+)
+
+// Failure policies, applied when a hook errors or times out
+const (
+	FailureIgnore = "ignore"
+	FailureReject = "reject"
+)
+
+// DefaultTimeout bounds a hook call when its config sets no timeout
+const DefaultTimeout = 2 * time.Second
+
+var points = map[Point]bool{
+	BeforeBookmarkCreate: true,
+	BeforeShareCreate:    true,
+	AfterImport:          true,
+}
+
+// hiddenFields are never sent to hooks; they keep their value whatever a
+// hook returns
+var hiddenFields = []string{"password"}
+
+// protectedFields are sent to hooks but can't be patched by them
+var protectedFields = []string{"user_id"}
+
+var (
+	// ErrRejected is returned when a hook rejects an operation
+	ErrRejected = errors.New("rejected by hook")
+	// ErrFailed is returned when a hook with the reject failure policy
+	// errors or times out
+	ErrFailed = errors.New("hook failed")
+)
+
+// Request is what a hook is called with
+type Request struct {
+	Point  Point                  `json:"point"`
+	Hook   string                 `json:"hook"`
+	UserID uint                   `json:"user_id"`
+	Data   map[string]interface{} `json:"data"`
+}
+
+// Response is what a hook answers. Data holds the fields a before hook
+// changes; the others are left as they are
+type Response struct {
+	Reject bool                   `json:"reject"`
+	Reason string                 `json:"reason,omitempty"`
+	Data   map[string]interface{} `json:"data,omitempty"`
+}
+
+// Runner calls hooks of one type: HTTPRunner or WASMRunner
+type Runner interface {
+	Call(ctx context.Context, hook config.HookConfig, req *Request) (*Response, error)
+}
+
+// Service calls the hooks configured for each point
+type Service struct {
+	hooks   map[Point][]config.HookConfig
+	runners map[string]Runner
+	logger  *zap.Logger
+}
+
+// NewService checks the configured hooks and creates a service calling
+// them. The modules of WASM hooks are compiled here, so a missing or broken
+// module is a config error
+func NewService(hooks []config.HookConfig, logger *zap.Logger) (*Service, error) {
+	s := &Service{
+		hooks:   make(map[Point][]config.HookConfig),
+		runners: map[string]Runner{TypeHTTP: NewHTTPRunner()},
+		logger:  logger,
+	}
+
+	var modules []string
+	for i, hook := range hooks {
+		if hook.Name == "" {
+			hook.Name = fmt.Sprintf("hook-%d", i+1)
+		}
+		if !points[Point(hook.Point)] {
+			return nil, fmt.Errorf("hook %s: unknown point %q", hook.Name, hook.Point)
+		}
+		switch hook.Type {
+		case TypeHTTP:
+			if hook.URL == "" {
+				return nil, fmt.Errorf("hook %s: url is required", hook.Name)
+			}
+		case TypeWASM:
+			if hook.Module == "" {
+				return nil, fmt.Errorf("hook %s: module is required", hook.Name)
+			}
+			modules = append(modules, hook.Module)
+		default:
+			return nil, fmt.Errorf("hook %s: unknown type %q", hook.Name, hook.Type)
+		}
+		switch hook.FailurePolicy {
+		case "":
+			hook.FailurePolicy = FailureIgnore
+		case FailureIgnore, FailureReject:
+		default:
+			return nil, fmt.Errorf("hook %s: unknown failure policy %q", hook.Name, hook.FailurePolicy)
+		}
+		s.hooks[Point(hook.Point)] = append(s.hooks[Point(hook.Point)], hook)
+	}
+
+	if len(modules) > 0 {
+		runner, err := NewWASMRunner(context.Background(), modules)
+		if err != nil {
+			return nil, err
+		}
+		s.runners[TypeWASM] = runner
+	}
+	return s, nil
+}
+
+// Enabled reports whether any hook is configured for a point
+func (s *Service) Enabled(point Point) bool {
+	return len(s.hooks[point]) > 0
+}
+
+// Run calls the hooks of a point with the payload, a pointer to a struct
+// encoded as JSON. At a before point each hook may reject the operation,
+// returning ErrRejected, or patch the payload in place. At an after point
+// the hooks are called in the background and Run returns at once
+func (s *Service) Run(ctx context.Context, point Point, userID uint, payload interface{}) error {
+	hooks := s.hooks[point]
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	data, err := toMap(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode hook payload: %w", err)
+	}
+
+	if !strings.HasPrefix(string(point), "before_") {
+		ctx = context.WithoutCancel(ctx)
+		go func() {
+			for _, hook := range hooks {
+				if _, err := s.call(ctx, hook, point, userID, data); err != nil {
+					s.logger.Warn("Hook failed", zap.String("hook", hook.Name), zap.String("point", string(point)), zap.Error(err))
+				}
+			}
+		}()
+		return nil
+	}
+
+	patched := false
+	for _, hook := range hooks {
+		resp, err := s.call(ctx, hook, point, userID, data)
+		if err == nil && resp.Reject {
+			reason := resp.Reason
+			if reason == "" {
+				reason = hook.Name
+			}
+			return fmt.Errorf("%w: %s", ErrRejected, reason)
+		}
+		if err == nil && len(resp.Data) > 0 {
+			merged := patch(data, resp.Data)
+			if err = fits(merged, payload); err == nil {
+				data, patched = merged, true
+			}
+		}
+		if err != nil {
+			if hook.FailurePolicy == FailureReject {
+				return fmt.Errorf("%w: %s: %v", ErrFailed, hook.Name, err)
+			}
+			s.logger.Warn("Hook failed, ignoring it", zap.String("hook", hook.Name), zap.String("point", string(point)), zap.Error(err))
+		}
+	}
+
+	if patched {
+		body, err := json.Marshal(data)
+		if err != nil {
+			return fmt.Errorf("failed to encode hook payload: %w", err)
+		}
+		if err := json.Unmarshal(body, payload); err != nil {
+			return fmt.Errorf("failed to apply hook changes: %w", err)
+		}
+	}
+	return nil
+}
+
+// call calls one hook with the data it may see, within its timeout
+func (s *Service) call(ctx context.Context, hook config.HookConfig, point Point, userID uint, data map[string]interface{}) (*Response, error) {
+	runner := s.runners[hook.Type]
+
+	timeout := DefaultTimeout
+	if hook.Timeout > 0 {
+		timeout = time.Duration(hook.Timeout) * time.Millisecond
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	visible := make(map[string]interface{}, len(data))
+	for key, value := range data {
+		visible[key] = value
+	}
+	for _, field := range hiddenFields {
+		delete(visible, field)
+	}
+
+	resp, err := runner.Call(ctx, hook, &Request{Point: point, Hook: hook.Name, UserID: userID, Data: visible})
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil {
+		resp = &Response{}
+	}
+	return resp, nil
+}
+
+// patch returns the data with a hook's changes, leaving hidden and
+// protected fields alone
+func patch(data, changes map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(data))
+	for key, value := range data {
+		merged[key] = value
+	}
+	for key, value := range changes {
+		merged[key] = value
+	}
+	for _, field := range append(hiddenFields, protectedFields...) {
+		if value, ok := data[field]; ok {
+			merged[field] = value
+		} else {
+			delete(merged, field)
+		}
+	}
+	return merged
+}
+
+// fits checks that patched data still decodes into the payload's type, so
+// a hook returning a wrong type fails without touching the payload
+func fits(data map[string]interface{}, payload interface{}) error {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	fresh := reflect.New(reflect.TypeOf(payload).Elem()).Interface()
+	if err := json.Unmarshal(body, fresh); err != nil {
+		return fmt.Errorf("invalid changes: %w", err)
+	}
+	return nil
+}
+
+func toMap(payload interface{}) (map[string]interface{}, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	data := make(map[string]interface{})
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
@@ -0,0 +1,168 @@
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"bookmark-sync-service/backend/internal/config"
+)
+
+type testPayload struct {
+	UserID   uint     `json:"user_id"`
+	URL      string   `json:"url"`
+	Tags     []string `json:"tags"`
+	Password string   `json:"password"`
+	internal string
+}
+
+// hookServer answers hook calls with the handler's response, recording
+// what it was called with
+func hookServer(t *testing.T, handler func(req Request) (int, interface{})) (*httptest.Server, chan Request) {
+	calls := make(chan Request, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req Request
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		calls <- req
+		status, body := handler(req)
+		w.WriteHeader(status)
+		if body != nil {
+			json.NewEncoder(w).Encode(body)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, calls
+}
+
+func newService(t *testing.T, hooks ...config.HookConfig) *Service {
+	service, err := NewService(hooks, zap.NewNop())
+	require.NoError(t, err)
+	return service
+}
+
+func TestNewService(t *testing.T) {
+	for name, hook := range map[string]config.HookConfig{
+		"unknown point":  {Point: "before_everything", Type: TypeHTTP, URL: "http://hooks.test"},
+		"unknown type":   {Point: string(BeforeBookmarkCreate), Type: "lua"},
+		"no url":         {Point: string(BeforeBookmarkCreate), Type: TypeHTTP},
+		"no module":      {Point: string(BeforeBookmarkCreate), Type: TypeWASM},
+		"unknown policy": {Point: string(BeforeBookmarkCreate), Type: TypeHTTP, URL: "http://hooks.test", FailurePolicy: "retry"},
+	} {
+		_, err := NewService([]config.HookConfig{hook}, zap.NewNop())
+		assert.Error(t, err, name)
+	}
+
+	service := newService(t, config.HookConfig{Point: string(AfterImport), Type: TypeHTTP, URL: "http://hooks.test"})
+	assert.True(t, service.Enabled(AfterImport))
+	assert.False(t, service.Enabled(BeforeBookmarkCreate))
+}
+
+func TestRun_PatchesPayload(t *testing.T) {
+	server, calls := hookServer(t, func(req Request) (int, interface{}) {
+		return http.StatusOK, Response{Data: map[string]interface{}{
+			"tags":     []string{"go", "checked"},
+			"user_id":  99,
+			"password": "stolen",
+		}}
+	})
+	service := newService(t, config.HookConfig{Name: "tagger", Point: string(BeforeBookmarkCreate), Type: TypeHTTP, URL: server.URL})
+
+	payload := &testPayload{UserID: 7, URL: "https://go.dev", Tags: []string{"go"}, Password: "secret", internal: "kept"}
+	require.NoError(t, service.Run(context.Background(), BeforeBookmarkCreate, 7, payload))
+
+	call := <-calls
+	assert.Equal(t, BeforeBookmarkCreate, call.Point)
+	assert.Equal(t, "tagger", call.Hook)
+	assert.Equal(t, uint(7), call.UserID)
+	assert.Equal(t, "https://go.dev", call.Data["url"])
+	assert.NotContains(t, call.Data, "password", "hooks never see passwords")
+
+	assert.Equal(t, []string{"go", "checked"}, payload.Tags)
+	assert.Equal(t, uint(7), payload.UserID, "hooks can't change the owner")
+	assert.Equal(t, "secret", payload.Password)
+	assert.Equal(t, "kept", payload.internal)
+}
+
+func TestRun_Rejects(t *testing.T) {
+	server, _ := hookServer(t, func(req Request) (int, interface{}) {
+		return http.StatusOK, Response{Reject: true, Reason: "domain is blocked"}
+	})
+	service := newService(t, config.HookConfig{Point: string(BeforeShareCreate), Type: TypeHTTP, URL: server.URL})
+
+	err := service.Run(context.Background(), BeforeShareCreate, 7, &testPayload{})
+	assert.ErrorIs(t, err, ErrRejected)
+	assert.Contains(t, err.Error(), "domain is blocked")
+}
+
+func TestRun_FailurePolicies(t *testing.T) {
+	down, _ := hookServer(t, func(req Request) (int, interface{}) {
+		return http.StatusInternalServerError, nil
+	})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	t.Cleanup(slow.Close)
+	invalid, _ := hookServer(t, func(req Request) (int, interface{}) {
+		return http.StatusOK, Response{Data: map[string]interface{}{"tags": "not a list"}}
+	})
+	ctx := context.Background()
+
+	for name, hook := range map[string]config.HookConfig{
+		"error":          {URL: down.URL},
+		"timeout":        {URL: slow.URL, Timeout: 50},
+		"invalid change": {URL: invalid.URL},
+	} {
+		hook.Point, hook.Type = string(BeforeBookmarkCreate), TypeHTTP
+		payload := &testPayload{Tags: []string{"go"}}
+
+		ignored := newService(t, hook)
+		assert.NoError(t, ignored.Run(ctx, BeforeBookmarkCreate, 7, payload), name)
+		assert.Equal(t, []string{"go"}, payload.Tags, name)
+
+		hook.FailurePolicy = FailureReject
+		rejected := newService(t, hook)
+		assert.ErrorIs(t, rejected.Run(ctx, BeforeBookmarkCreate, 7, payload), ErrFailed, name)
+	}
+}
+
+func TestRun_AfterHooksInBackground(t *testing.T) {
+	server, calls := hookServer(t, func(req Request) (int, interface{}) {
+		return http.StatusInternalServerError, nil
+	})
+	service := newService(t, config.HookConfig{Point: string(AfterImport), Type: TypeHTTP, URL: server.URL, FailurePolicy: FailureReject})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	require.NoError(t, service.Run(ctx, AfterImport, 7, map[string]interface{}{"source": "chrome"}), "after hooks can't fail the operation")
+	cancel()
+
+	select {
+	case call := <-calls:
+		assert.Equal(t, "chrome", call.Data["source"])
+	case <-time.After(2 * time.Second):
+		t.Fatal("after hook was not called")
+	}
+}
+
+func TestHTTPRunner_Signs(t *testing.T) {
+	var signature string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signature = r.Header.Get(HeaderSignature)
+	}))
+	t.Cleanup(server.Close)
+
+	resp, err := NewHTTPRunner().Call(context.Background(), config.HookConfig{URL: server.URL, Secret: "s3cret"}, &Request{Point: AfterImport})
+	require.NoError(t, err)
+	assert.False(t, resp.Reject, "an empty answer allows the operation")
+	body, _ := json.Marshal(&Request{Point: AfterImport})
+	assert.Equal(t, sign(body, "s3cret"), signature)
+}
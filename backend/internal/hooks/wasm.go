@@ -0,0 +1,126 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+
+	"bookmark-sync-service/backend/internal/config"
+)
+
+// maxMemoryPages caps the memory of a WASM hook instance, in 64 KiB pages
+const maxMemoryPages = 512
+
+// errResponseTooLarge is returned when a WASM hook writes more than
+// maxResponseSize to stdout
+var errResponseTooLarge = errors.New("hook response too large")
+
+// WASMRunner runs hooks compiled to WebAssembly for WASI. Each call starts
+// a fresh instance of the module with the request as JSON on stdin and
+// reads its answer from stdout, like an HTTP hook's body. Modules see no
+// files, network or environment; one that exits non-zero or runs past the
+// hook's timeout fails
+type WASMRunner struct {
+	runtime wazero.Runtime
+	modules map[string]wazero.CompiledModule
+}
+
+// NewWASMRunner creates a runner for the WASM modules at the given paths,
+// compiling each once up front so a broken module stops the start
+func NewWASMRunner(ctx context.Context, paths []string) (*WASMRunner, error) {
+	runtime := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+		WithCloseOnContextDone(true).
+		WithMemoryLimitPages(maxMemoryPages))
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, runtime); err != nil {
+		runtime.Close(ctx)
+		return nil, fmt.Errorf("failed to set up WASI: %w", err)
+	}
+
+	r := &WASMRunner{runtime: runtime, modules: make(map[string]wazero.CompiledModule)}
+	for _, path := range paths {
+		if _, ok := r.modules[path]; ok {
+			continue
+		}
+		code, err := os.ReadFile(path)
+		if err != nil {
+			runtime.Close(ctx)
+			return nil, fmt.Errorf("failed to read wasm module: %w", err)
+		}
+		module, err := runtime.CompileModule(ctx, code)
+		if err != nil {
+			runtime.Close(ctx)
+			return nil, fmt.Errorf("failed to compile wasm module %s: %w", path, err)
+		}
+		r.modules[path] = module
+	}
+	return r, nil
+}
+
+// Call runs the hook's module with the request and decodes its answer
+func (r *WASMRunner) Call(ctx context.Context, hook config.HookConfig, req *Request) (*Response, error) {
+	module, ok := r.modules[hook.Module]
+	if !ok {
+		return nil, fmt.Errorf("wasm module %s is not loaded", hook.Module)
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode hook request: %w", err)
+	}
+
+	// Instances are anonymous so concurrent calls of one hook don't clash
+	stdout := &cappedBuffer{limit: maxResponseSize}
+	instance, err := r.runtime.InstantiateModule(ctx, module, wazero.NewModuleConfig().
+		WithName("").
+		WithStdin(bytes.NewReader(body)).
+		WithStdout(stdout).
+		WithStderr(io.Discard))
+	if instance != nil {
+		defer instance.Close(ctx)
+	}
+	if err != nil {
+		var exit *sys.ExitError
+		if !errors.As(err, &exit) || exit.ExitCode() != 0 {
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("hook timed out: %w", ctx.Err())
+			}
+			return nil, fmt.Errorf("failed to run wasm hook: %w", err)
+		}
+	}
+	if stdout.overflow {
+		return nil, errResponseTooLarge
+	}
+
+	var response Response
+	if len(bytes.TrimSpace(stdout.Bytes())) == 0 {
+		return &response, nil
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &response); err != nil {
+		return nil, fmt.Errorf("invalid hook response: %w", err)
+	}
+	return &response, nil
+}
+
+// cappedBuffer keeps what a module writes up to a limit and fails the
+// writes past it
+type cappedBuffer struct {
+	bytes.Buffer
+	limit    int
+	overflow bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.limit {
+		b.overflow = true
+		return 0, errResponseTooLarge
+	}
+	return b.Buffer.Write(p)
+}
@@ -0,0 +1,157 @@
+package hooks
+
+import (
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"bookmark-sync-service/backend/internal/config"
+)
+
+// wasmModule assembles a WASI command whose _start runs body. Each import
+// is a WASI function taking four i32s and returning an errno, like fd_read
+// and fd_write; data is copied to memory at its offset, each below 64
+func wasmModule(imports []string, body []byte, data map[byte][]byte) []byte {
+	section := func(id byte, content []byte) []byte {
+		return append(append([]byte{id}, uleb(len(content))...), content...)
+	}
+	name := func(s string) []byte {
+		return append(uleb(len(s)), s...)
+	}
+
+	module := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+	module = append(module, section(1, []byte{0x02,
+		0x60, 0x04, 0x7f, 0x7f, 0x7f, 0x7f, 0x01, 0x7f,
+		0x60, 0x00, 0x00,
+	})...)
+	if len(imports) > 0 {
+		content := uleb(len(imports))
+		for _, imported := range imports {
+			content = append(content, name("wasi_snapshot_preview1")...)
+			content = append(content, name(imported)...)
+			content = append(content, 0x00, 0x00)
+		}
+		module = append(module, section(2, content)...)
+	}
+	module = append(module, section(3, []byte{0x01, 0x01})...)
+	module = append(module, section(5, []byte{0x01, 0x00, 0x01})...)
+
+	exports := append([]byte{0x02}, name("memory")...)
+	exports = append(exports, 0x02, 0x00)
+	exports = append(exports, name("_start")...)
+	exports = append(exports, 0x00, byte(len(imports)))
+	module = append(module, section(7, exports)...)
+
+	code := append([]byte{0x00}, body...)
+	module = append(module, section(10, append(append([]byte{0x01}, uleb(len(code))...), code...))...)
+
+	segments := uleb(len(data))
+	for offset, bytes := range data {
+		segments = append(segments, 0x00, 0x41, offset, 0x0b)
+		segments = append(segments, uleb(len(bytes))...)
+		segments = append(segments, bytes...)
+	}
+	return append(module, section(11, segments)...)
+}
+
+func uleb(n int) []byte {
+	var out []byte
+	for {
+		b := byte(n & 0x7f)
+		n >>= 7
+		if n == 0 {
+			return append(out, b)
+		}
+		out = append(out, b|0x80)
+	}
+}
+
+// iovec returns a WASI iovec pointing at memory offset 16
+func iovec(length int) []byte {
+	iov := make([]byte, 8)
+	binary.LittleEndian.PutUint32(iov, 16)
+	binary.LittleEndian.PutUint32(iov[4:], uint32(length))
+	return iov
+}
+
+// answerModule writes the answer to stdout
+func answerModule(answer string) []byte {
+	return wasmModule([]string{"fd_write"}, []byte{
+		0x41, 0x01, 0x41, 0x00, 0x41, 0x01, 0x41, 0x08, 0x10, 0x00, 0x1a, // drop(fd_write(1, 0, 1, 8))
+		0x0b,
+	}, map[byte][]byte{0: iovec(len(answer)), 16: []byte(answer)})
+}
+
+// echoModule copies stdin to stdout
+func echoModule() []byte {
+	return wasmModule([]string{"fd_read", "fd_write"}, []byte{
+		0x41, 0x00, 0x41, 0x00, 0x41, 0x01, 0x41, 0x08, 0x10, 0x00, 0x1a, // drop(fd_read(0, 0, 1, 8))
+		0x41, 0x00, 0x41, 0x08, 0x28, 0x02, 0x00, 0x36, 0x02, 0x04, // iov.len = nread
+		0x41, 0x01, 0x41, 0x00, 0x41, 0x01, 0x41, 0x08, 0x10, 0x01, 0x1a, // drop(fd_write(1, 0, 1, 8))
+		0x0b,
+	}, map[byte][]byte{0: iovec(60000)})
+}
+
+// spinModule never returns
+func spinModule() []byte {
+	return wasmModule(nil, []byte{0x03, 0x40, 0x0c, 0x00, 0x0b, 0x0b}, nil)
+}
+
+func writeModule(t *testing.T, code []byte) string {
+	path := filepath.Join(t.TempDir(), "hook.wasm")
+	require.NoError(t, os.WriteFile(path, code, 0o644))
+	return path
+}
+
+func TestWASMRunner_Call(t *testing.T) {
+	ctx := context.Background()
+	echo := writeModule(t, echoModule())
+	runner, err := NewWASMRunner(ctx, []string{echo})
+	require.NoError(t, err)
+
+	resp, err := runner.Call(ctx, config.HookConfig{Module: echo}, &Request{
+		Point: BeforeBookmarkCreate,
+		Data:  map[string]interface{}{"url": "https://go.dev"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"url": "https://go.dev"}, resp.Data, "the module reads the request on stdin")
+}
+
+func TestRun_WASMHooks(t *testing.T) {
+	ctx := context.Background()
+
+	tagger := config.HookConfig{Point: string(BeforeBookmarkCreate), Type: TypeWASM,
+		Module: writeModule(t, answerModule(`{"data":{"tags":["go","wasm"]}}`))}
+	payload := &testPayload{URL: "https://go.dev", Tags: []string{"go"}}
+	require.NoError(t, newService(t, tagger).Run(ctx, BeforeBookmarkCreate, 7, payload))
+	assert.Equal(t, []string{"go", "wasm"}, payload.Tags)
+
+	blocker := config.HookConfig{Point: string(BeforeBookmarkCreate), Type: TypeWASM,
+		Module: writeModule(t, answerModule(`{"reject":true,"reason":"domain is blocked"}`))}
+	err := newService(t, blocker).Run(ctx, BeforeBookmarkCreate, 7, &testPayload{})
+	assert.ErrorIs(t, err, ErrRejected)
+	assert.Contains(t, err.Error(), "domain is blocked")
+
+	spinner := config.HookConfig{Point: string(BeforeBookmarkCreate), Type: TypeWASM,
+		Module: writeModule(t, spinModule()), Timeout: 50, FailurePolicy: FailureReject}
+	start := time.Now()
+	assert.ErrorIs(t, newService(t, spinner).Run(ctx, BeforeBookmarkCreate, 7, &testPayload{}), ErrFailed)
+	assert.Less(t, time.Since(start), 5*time.Second, "a module past its timeout is stopped")
+}
+
+func TestNewService_WASMModules(t *testing.T) {
+	for name, module := range map[string]string{
+		"missing": filepath.Join(t.TempDir(), "missing.wasm"),
+		"invalid": writeModule(t, []byte("not wasm")),
+	} {
+		_, err := NewService([]config.HookConfig{{Point: string(AfterImport), Type: TypeWASM, Module: module}}, zap.NewNop())
+		assert.Error(t, err, name)
+	}
+}
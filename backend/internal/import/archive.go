@@ -0,0 +1,221 @@
+package import_export
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/exporter"
+)
+
+// SourceArchive imports the zip written by the archive export format
+const SourceArchive = "archive"
+
+// Limits on what is read from an archive. Zip entries inflate far beyond
+// the bounded upload, so the manifest and screenshots are capped on their
+// own and together
+const (
+	maxArchiveScreenshotSize   = 10 << 20  // one screenshot
+	maxArchiveManifestSize     = 256 << 20 // the manifest with its archived pages
+	maxArchiveUncompressedSize = 512 << 20 // everything read from one archive
+	maxArchiveFiles            = 100000    // entries in one archive
+)
+
+// Errors returned by archive imports
+var (
+	ErrInvalidArchive     = errors.New("file is not a bookmark archive")
+	ErrUnsupportedArchive = errors.New("archive was written by a newer version")
+	ErrArchiveTooLarge    = errors.New("archive is too large to import")
+)
+
+// ScreenshotStore stores the screenshots of imported bookmarks, e.g. the
+// storage client
+type ScreenshotStore interface {
+	StoreScreenshot(ctx context.Context, bookmarkID string, data []byte) (string, error)
+}
+
+// SetScreenshotStore lets archive imports restore screenshots. Without it
+// the screenshots in an archive are dropped
+func (s *Service) SetScreenshotStore(store ScreenshotStore) {
+	s.screenshots = store
+}
+
+// ParseArchive reads an archive export into a folder tree that recreates
+// its collections. Bookmarks outside any collection of the archive are
+// imported at the top level; one in several collections is imported into
+// the first reached from the top, as later copies are duplicates
+func ParseArchive(r io.Reader) (*ImportFolder, error) {
+	// Zip readers need random access, and uploads are bounded
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	archive, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, ErrInvalidArchive
+	}
+	if len(archive.File) > maxArchiveFiles {
+		return nil, ErrArchiveTooLarge
+	}
+
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+	manifestFile, ok := files[exporter.ArchiveManifest]
+	if !ok {
+		return nil, ErrInvalidArchive
+	}
+	if manifestFile.UncompressedSize64 > maxArchiveManifestSize {
+		return nil, ErrArchiveTooLarge
+	}
+	var manifest exporter.Manifest
+	if err := readArchiveJSON(manifestFile, maxArchiveManifestSize, &manifest); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+
+	// Sizes in the zip directory are checked against what is inflated, so
+	// the screenshots are weighed before any is read
+	total := manifestFile.UncompressedSize64
+	for _, bookmark := range manifest.Bookmarks {
+		if file, ok := files[bookmark.ScreenshotFile]; ok && bookmark.ScreenshotFile != "" {
+			total += file.UncompressedSize64
+			if total > maxArchiveUncompressedSize {
+				return nil, ErrArchiveTooLarge
+			}
+		}
+	}
+	if manifest.Version > exporter.ArchiveVersion {
+		return nil, ErrUnsupportedArchive
+	}
+
+	bookmarks := make(map[uint]ImportBookmark, len(manifest.Bookmarks))
+	for _, bookmark := range manifest.Bookmarks {
+		createdAt := bookmark.CreatedAt
+		item := ImportBookmark{
+			URL:         bookmark.URL,
+			Title:       bookmark.Title,
+			Description: bookmark.Description,
+			Notes:       bookmark.Notes,
+			AddedAt:     &createdAt,
+			Tags:        bookmark.Tags,
+			Archive:     bookmark.Archive,
+		}
+		if file, ok := files[bookmark.ScreenshotFile]; ok && bookmark.ScreenshotFile != "" {
+			item.Screenshot, err = readArchiveFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+			}
+		}
+		bookmarks[bookmark.ID] = item
+	}
+
+	children := make(map[uint][]exporter.ManifestCollection)
+	known := make(map[uint]bool, len(manifest.Collections))
+	for _, collection := range manifest.Collections {
+		known[collection.ID] = true
+	}
+	var top []exporter.ManifestCollection
+	for _, collection := range manifest.Collections {
+		if collection.ParentID == nil || !known[*collection.ParentID] {
+			top = append(top, collection)
+			continue
+		}
+		children[*collection.ParentID] = append(children[*collection.ParentID], collection)
+	}
+
+	placed := make(map[uint]bool, len(bookmarks))
+	var build func(collection exporter.ManifestCollection) ImportFolder
+	build = func(collection exporter.ManifestCollection) ImportFolder {
+		createdAt := collection.CreatedAt
+		folder := ImportFolder{Name: collection.Name, AddedAt: &createdAt}
+		for _, id := range collection.BookmarkIDs {
+			if item, ok := bookmarks[id]; ok && !placed[id] {
+				placed[id] = true
+				folder.Bookmarks = append(folder.Bookmarks, item)
+			}
+		}
+		for _, child := range children[collection.ID] {
+			folder.Folders = append(folder.Folders, build(child))
+		}
+		return folder
+	}
+
+	// A collection is only reached from its parent, so parent cycles in a
+	// tampered manifest are never walked
+	root := &ImportFolder{}
+	for _, collection := range top {
+		root.Folders = append(root.Folders, build(collection))
+	}
+	for _, bookmark := range manifest.Bookmarks {
+		if !placed[bookmark.ID] {
+			placed[bookmark.ID] = true
+			root.Bookmarks = append(root.Bookmarks, bookmarks[bookmark.ID])
+		}
+	}
+	return root, nil
+}
+
+func readArchiveJSON(file *zip.File, limit int64, v interface{}) error {
+	reader, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	return json.NewDecoder(io.LimitReader(reader, limit)).Decode(v)
+}
+
+func readArchiveFile(file *zip.File) ([]byte, error) {
+	if file.UncompressedSize64 > maxArchiveScreenshotSize {
+		return nil, fmt.Errorf("%s is too large", file.Name)
+	}
+	reader, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(io.LimitReader(reader, maxArchiveScreenshotSize))
+}
+
+// restoreAttachments saves the archived copy and screenshot an archive
+// carried for a newly created bookmark. Failures are reported without
+// undoing the bookmark
+func (s *Service) restoreAttachments(ctx context.Context, bookmark *database.Bookmark, item ImportBookmark, result *ImportResult) {
+	if page := item.Archive; page != nil {
+		hash := sha256.Sum256([]byte(page.Content))
+		snapshot := &database.ArchiveSnapshot{
+			UserID:      bookmark.UserID,
+			BookmarkID:  bookmark.ID,
+			Version:     1,
+			URL:         page.URL,
+			Title:       page.Title,
+			Content:     page.Content,
+			ContentHash: hex.EncodeToString(hash[:]),
+			StatusCode:  page.StatusCode,
+			CapturedAt:  page.CapturedAt,
+			CheckedAt:   page.CapturedAt,
+		}
+		if err := s.db.WithContext(ctx).Create(snapshot).Error; err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("Failed to restore archived copy of %s: %v", item.URL, err))
+		}
+	}
+
+	if len(item.Screenshot) > 0 && s.screenshots != nil {
+		screenshotURL, err := s.screenshots.StoreScreenshot(ctx, strconv.FormatUint(uint64(bookmark.ID), 10), item.Screenshot)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("Failed to restore screenshot of %s: %v", item.URL, err))
+			return
+		}
+		if err := s.db.WithContext(ctx).Model(bookmark).Update("screenshot", screenshotURL).Error; err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("Failed to restore screenshot of %s: %v", item.URL, err))
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package import_export
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/exporter"
+)
+
+type stubScreenshotStore map[string][]byte
+
+func (s stubScreenshotStore) StoreScreenshot(_ context.Context, bookmarkID string, data []byte) (string, error) {
+	s[bookmarkID] = data
+	return "https://storage.example.com/screenshots/" + bookmarkID, nil
+}
+
+func writeArchiveFixture(t *testing.T) []byte {
+	parent := uint(10)
+	capturedAt := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	goDev := exporter.Bookmark{ID: 1, URL: "https://go.dev/", Title: "Go", Notes: "Start here", Tags: []string{"go"},
+		Archive: &exporter.ArchivedPage{Version: 2, URL: "https://go.dev/", Title: "Go", Content: "Build simple software", StatusCode: 200, CapturedAt: capturedAt}}
+	mdn := exporter.Bookmark{ID: 2, URL: "https://developer.mozilla.org/", Title: "MDN"}
+	data := &exporter.Data{
+		ExportedAt: capturedAt,
+		Bookmarks:  []exporter.Bookmark{goDev, mdn},
+		Collections: []exporter.Collection{
+			{ID: 11, Name: "Web", ParentID: &parent, Bookmarks: []exporter.Bookmark{goDev, mdn}},
+			{ID: 10, Name: "Reading", Bookmarks: []exporter.Bookmark{goDev}},
+		},
+	}
+	data, ok := data.Subtree(10, true)
+	require.True(t, ok)
+	data.AttachScreenshot(2, []byte("png"), "image/png")
+
+	format, err := exporter.Lookup("archive")
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, format.Exporter.Export(&buf, data))
+	return buf.Bytes()
+}
+
+func TestParseArchive(t *testing.T) {
+	root, err := ParseArchive(bytes.NewReader(writeArchiveFixture(t)))
+	require.NoError(t, err)
+
+	assert.Empty(t, root.Bookmarks)
+	require.Len(t, root.Folders, 1)
+	reading := root.Folders[0]
+	assert.Equal(t, "Reading", reading.Name)
+	require.Len(t, reading.Folders, 1)
+	assert.Equal(t, "Web", reading.Folders[0].Name)
+
+	// Bookmarks shared with a parent collection stay in the parent
+	require.Len(t, reading.Folders[0].Bookmarks, 1)
+	assert.Equal(t, []byte("png"), reading.Folders[0].Bookmarks[0].Screenshot)
+	require.Len(t, reading.Bookmarks, 1)
+	assert.Equal(t, "Build simple software", reading.Bookmarks[0].Archive.Content)
+
+	_, err = ParseArchive(bytes.NewReader([]byte("not a zip")))
+	assert.ErrorIs(t, err, ErrInvalidArchive)
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	file, err := archive.Create(exporter.ArchiveManifest)
+	require.NoError(t, err)
+	_, err = file.Write([]byte(`{"version": 99}`))
+	require.NoError(t, err)
+	require.NoError(t, archive.Close())
+	_, err = ParseArchive(&buf)
+	assert.ErrorIs(t, err, ErrUnsupportedArchive)
+}
+
+func TestParseArchive_Limits(t *testing.T) {
+	// A manifest claiming to inflate past the cap is not read
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	file, err := archive.CreateRaw(&zip.FileHeader{Name: exporter.ArchiveManifest, Method: zip.Store, CompressedSize64: 2, UncompressedSize64: maxArchiveManifestSize + 1})
+	require.NoError(t, err)
+	_, err = file.Write([]byte("{}"))
+	require.NoError(t, err)
+	require.NoError(t, archive.Close())
+	_, err = ParseArchive(&buf)
+	assert.ErrorIs(t, err, ErrArchiveTooLarge)
+
+	// So are screenshots together past the budget, each within its cap
+	buf.Reset()
+	archive = zip.NewWriter(&buf)
+	count := maxArchiveUncompressedSize/maxArchiveScreenshotSize + 1
+	bookmarks := make([]map[string]interface{}, count)
+	for n := range bookmarks {
+		name := strconv.Itoa(n) + ".png"
+		bookmarks[n] = map[string]interface{}{"id": n + 1, "url": "https://example.com/" + name, "screenshot_file": name}
+		file, err = archive.CreateRaw(&zip.FileHeader{Name: name, Method: zip.Store, CompressedSize64: 3, UncompressedSize64: maxArchiveScreenshotSize})
+		require.NoError(t, err)
+		_, err = file.Write([]byte("png"))
+		require.NoError(t, err)
+	}
+	manifest, err := archive.Create(exporter.ArchiveManifest)
+	require.NoError(t, err)
+	require.NoError(t, json.NewEncoder(manifest).Encode(map[string]interface{}{"version": 1, "bookmarks": bookmarks}))
+	require.NoError(t, archive.Close())
+	_, err = ParseArchive(&buf)
+	assert.ErrorIs(t, err, ErrArchiveTooLarge)
+}
+
+func TestService_CommitArchiveImport(t *testing.T) {
+	db, err := database.SetupTestDB()
+	require.NoError(t, err)
+	defer database.CleanupTestDB(db)
+
+	store := stubScreenshotStore{}
+	service := NewService(db)
+	service.SetScreenshotStore(store)
+	ctx := context.Background()
+	require.NoError(t, db.Create(&database.User{BaseModel: database.BaseModel{ID: 2}, Email: "other@example.com", Username: "other", SupabaseID: "other"}).Error)
+
+	root, err := ParseArchive(bytes.NewReader(writeArchiveFixture(t)))
+	require.NoError(t, err)
+	result, err := service.CommitImport(ctx, 2, SourceArchive, root)
+	require.NoError(t, err)
+	assert.Empty(t, result.Errors)
+	assert.Equal(t, 2, result.ImportedBookmarksCount)
+	assert.Equal(t, 2, result.ImportedCollectionsCount)
+
+	var web database.Collection
+	require.NoError(t, db.Preload("Bookmarks").Where("user_id = ? AND name = ?", 2, "Web").First(&web).Error)
+	require.NotNil(t, web.ParentID)
+	var reading database.Collection
+	require.NoError(t, db.First(&reading, *web.ParentID).Error)
+	assert.Equal(t, "Reading", reading.Name)
+	require.Len(t, web.Bookmarks, 1)
+	assert.Equal(t, "https://storage.example.com/screenshots/"+strconv.FormatUint(uint64(web.Bookmarks[0].ID), 10), web.Bookmarks[0].Screenshot)
+	assert.Len(t, store, 1)
+
+	var goDev database.Bookmark
+	require.NoError(t, db.Where("user_id = ? AND url = ?", 2, "https://go.dev/").First(&goDev).Error)
+	assert.Equal(t, "Start here", goDev.Notes)
+	var snapshot database.ArchiveSnapshot
+	require.NoError(t, db.Where("bookmark_id = ?", goDev.ID).First(&snapshot).Error)
+	assert.Equal(t, uint(2), snapshot.UserID)
+	assert.Equal(t, 1, snapshot.Version)
+	assert.Equal(t, "Build simple software", snapshot.Content)
+	assert.NotEmpty(t, snapshot.ContentHash)
+}
@@ -0,0 +1,534 @@
+package import_export
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/exporter"
+	"bookmark-sync-service/backend/pkg/tags"
+)
+
+// Browser import sources
+const (
+	SourceChrome  = "chrome"
+	SourceFirefox = "firefox"
+)
+
+// sourceNames are the display names used in imported descriptions
+var sourceNames = map[string]string{
+	SourceChrome:   "Chrome",
+	SourceFirefox:  "Firefox",
+	SourceShaarli:  "Shaarli",
+	SourceWallabag: "Wallabag",
+	SourceLinkding: "Linkding",
+	SourceText:     "a link list",
+	SourceArchive:  "a bookmark archive",
+}
+
+// maxPlacesSize caps an uploaded Firefox places.sqlite database
+const maxPlacesSize = 256 << 20
+
+// chromeEpochOffset is the number of seconds between 1601-01-01, the epoch
+// of Chrome timestamps, and the Unix epoch
+const chromeEpochOffset = 11644473600
+
+// Firefox places root folder GUIDs
+const (
+	placesRootGUID = "root________"
+	placesTagsGUID = "tags________"
+)
+
+// Errors returned by browser imports
+var (
+	ErrUnknownSource  = errors.New("unknown import source")
+	ErrInvalidPlaces  = errors.New("file is not a Firefox places database")
+	ErrPlacesTooLarge = errors.New("places database is too large")
+)
+
+// ImportFolder is a parsed browser bookmark folder. The root folder is
+// unnamed and holds the bookmarks that belong to no folder
+type ImportFolder struct {
+	Name      string           `json:"name"`
+	AddedAt   *time.Time       `json:"added_at,omitempty"`
+	Folders   []ImportFolder   `json:"folders,omitempty"`
+	Bookmarks []ImportBookmark `json:"bookmarks,omitempty"`
+
+	// Match is the existing collection the folder is merged into instead
+	// of a new one. Previews suggest matches, which the client may change
+	// or clear before committing
+	Match *FolderMatch `json:"match,omitempty"`
+}
+
+// ImportBookmark is a parsed browser bookmark. Read state and flags only
+// come from legacy bookmark managers
+type ImportBookmark struct {
+	URL         string     `json:"url"`
+	Title       string     `json:"title"`
+	Description string     `json:"description,omitempty"`
+	Notes       string     `json:"notes,omitempty"`
+	AddedAt     *time.Time `json:"added_at,omitempty"`
+	Tags        []string   `json:"tags,omitempty"` // browser tags and keywords
+	Unread      bool       `json:"unread,omitempty"`
+	Archived    bool       `json:"archived,omitempty"`
+	Starred     bool       `json:"starred,omitempty"`
+	Duplicate   bool       `json:"duplicate,omitempty"` // already saved; set by preview
+
+	// Only archive imports carry an archived copy and a screenshot
+	Archive    *exporter.ArchivedPage `json:"archive,omitempty"`
+	Screenshot []byte                 `json:"screenshot,omitempty"`
+}
+
+// ImportPreview describes what committing a parsed import would create
+type ImportPreview struct {
+	Source           string       `json:"source"`
+	BookmarksCount   int          `json:"bookmarks_count"`
+	CollectionsCount int          `json:"collections_count"` // folders without a match, created on commit
+	MatchedCount     int          `json:"matched_count"`     // folders merged into existing collections
+	DuplicatesCount  int          `json:"duplicates_count"`
+	Root             ImportFolder `json:"root"`
+}
+
+// CommitImportRequest commits a previewed import. The root may have been
+// edited by the client, e.g. to leave out folders or change their matches.
+// With a collection ID the root's bookmarks and folders go into that
+// existing collection
+type CommitImportRequest struct {
+	Source       string       `json:"source" binding:"required"`
+	Root         ImportFolder `json:"root"`
+	CollectionID uint         `json:"collection_id,omitempty"`
+}
+
+// ParseChromeBookmarks parses a Chrome Bookmarks JSON file. The contents of
+// the bookmark bar, other and mobile roots are merged into the root folder
+func ParseChromeBookmarks(reader io.Reader) (*ImportFolder, error) {
+	var chromeFile ChromeBookmarkFile
+	if err := json.NewDecoder(reader).Decode(&chromeFile); err != nil {
+		return nil, fmt.Errorf("failed to parse Chrome bookmarks: %w", err)
+	}
+
+	root := &ImportFolder{}
+	for _, chromeRoot := range []ChromeBookmark{chromeFile.Roots.BookmarkBar, chromeFile.Roots.Other, chromeFile.Roots.Synced} {
+		addChromeChildren(root, chromeRoot.Children)
+	}
+	return root, nil
+}
+
+// addChromeChildren adds Chrome bookmarks and folders to a parsed folder
+func addChromeChildren(folder *ImportFolder, children []ChromeBookmark) {
+	for _, child := range children {
+		switch child.Type {
+		case "url":
+			folder.Bookmarks = append(folder.Bookmarks, ImportBookmark{
+				URL:     child.URL,
+				Title:   child.Name,
+				AddedAt: chromeTime(child.DateAdded),
+			})
+		case "folder":
+			sub := ImportFolder{Name: child.Name, AddedAt: chromeTime(child.DateAdded)}
+			addChromeChildren(&sub, child.Children)
+			folder.Folders = append(folder.Folders, sub)
+		}
+	}
+}
+
+// chromeTime converts a Chrome timestamp, in microseconds since 1601
+func chromeTime(value string) *time.Time {
+	micros, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || micros <= chromeEpochOffset*1e6 {
+		return nil
+	}
+	t := time.UnixMicro(micros - chromeEpochOffset*1e6).UTC()
+	return &t
+}
+
+// placesRow is a moz_bookmarks entry joined with its moz_places URL
+type placesRow struct {
+	ID        int64
+	Type      int // 1 bookmark, 2 folder, 3 separator
+	Parent    int64
+	Position  int
+	Title     *string
+	DateAdded int64 // microseconds since the Unix epoch
+	GUID      string
+	URL       *string
+}
+
+// placesKeyword is a moz_keywords entry with its URL
+type placesKeyword struct {
+	Keyword string
+	URL     string
+}
+
+// ParseFirefoxPlaces parses an uploaded Firefox places.sqlite database.
+// Bookmarks in the menu, toolbar, other and mobile roots are merged into the
+// root folder; Firefox tags and keywords become tags
+func ParseFirefoxPlaces(reader io.Reader) (*ImportFolder, error) {
+	file, err := os.CreateTemp("", "places-*.sqlite")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(file.Name())
+
+	written, err := io.Copy(file, io.LimitReader(reader, maxPlacesSize+1))
+	file.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read places database: %w", err)
+	}
+	if written > maxPlacesSize {
+		return nil, ErrPlacesTooLarge
+	}
+
+	db, err := gorm.Open(sqlite.Open("file:"+file.Name()+"?mode=ro&immutable=1"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		return nil, ErrInvalidPlaces
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+
+	var rows []placesRow
+	if err := db.Raw(`SELECT b.id, b.type, b.parent, b.position, b.title, b.dateAdded AS date_added, b.guid, p.url
+		FROM moz_bookmarks b LEFT JOIN moz_places p ON p.id = b.fk`).Scan(&rows).Error; err != nil {
+		return nil, ErrInvalidPlaces
+	}
+
+	var keywords []placesKeyword
+	// Old profiles may predate moz_keywords, so keywords are best effort
+	db.Raw(`SELECT k.keyword, p.url FROM moz_keywords k JOIN moz_places p ON p.id = k.place_id`).Scan(&keywords)
+
+	return buildPlacesTree(rows, keywords), nil
+}
+
+// buildPlacesTree turns moz_bookmarks rows into a folder tree
+func buildPlacesTree(rows []placesRow, keywords []placesKeyword) *ImportFolder {
+	children := make(map[int64][]placesRow)
+	var rootID, tagsID int64 = -1, -1
+	for _, row := range rows {
+		children[row.Parent] = append(children[row.Parent], row)
+		switch row.GUID {
+		case placesRootGUID:
+			rootID = row.ID
+		case placesTagsGUID:
+			tagsID = row.ID
+		}
+	}
+	for parent := range children {
+		sort.Slice(children[parent], func(i, j int) bool {
+			return children[parent][i].Position < children[parent][j].Position
+		})
+	}
+
+	// Tags are folders under the tags root holding entries for each tagged URL
+	urlTags := make(map[string][]string)
+	for _, tagFolder := range children[tagsID] {
+		for _, entry := range children[tagFolder.ID] {
+			if entry.URL != nil && tagFolder.Title != nil {
+				urlTags[*entry.URL] = append(urlTags[*entry.URL], *tagFolder.Title)
+			}
+		}
+	}
+	for _, keyword := range keywords {
+		urlTags[keyword.URL] = append(urlTags[keyword.URL], keyword.Keyword)
+	}
+
+	var addChildren func(folder *ImportFolder, parent int64)
+	addChildren = func(folder *ImportFolder, parent int64) {
+		for _, row := range children[parent] {
+			switch row.Type {
+			case 1:
+				// place: URLs are saved searches, not pages
+				if row.URL == nil || strings.HasPrefix(*row.URL, "place:") {
+					continue
+				}
+				bookmark := ImportBookmark{URL: *row.URL, AddedAt: placesTime(row.DateAdded), Tags: urlTags[*row.URL]}
+				if row.Title != nil {
+					bookmark.Title = *row.Title
+				}
+				if bookmark.Title == "" {
+					bookmark.Title = bookmark.URL
+				}
+				folder.Bookmarks = append(folder.Bookmarks, bookmark)
+			case 2:
+				sub := ImportFolder{AddedAt: placesTime(row.DateAdded)}
+				if row.Title != nil {
+					sub.Name = *row.Title
+				}
+				addChildren(&sub, row.ID)
+				folder.Folders = append(folder.Folders, sub)
+			}
+		}
+	}
+
+	root := &ImportFolder{}
+	for _, top := range children[rootID] {
+		if top.ID != tagsID && top.Type == 2 {
+			addChildren(root, top.ID)
+		}
+	}
+	return root
+}
+
+// placesTime converts a Firefox timestamp, in microseconds since 1970
+func placesTime(micros int64) *time.Time {
+	if micros <= 0 {
+		return nil
+	}
+	t := time.UnixMicro(micros).UTC()
+	return &t
+}
+
+// PreviewImport reports what committing a parsed import would create,
+// flagging bookmarks that are already saved or repeated in the import
+func (s *Service) PreviewImport(ctx context.Context, userID uint, source string, root *ImportFolder) (*ImportPreview, error) {
+	if _, ok := sourceNames[source]; !ok {
+		return nil, ErrUnknownSource
+	}
+
+	root = cleanFolder(root, s.urlCleaner(ctx, userID))
+	if err := s.matchFolders(ctx, userID, source, root); err != nil {
+		return nil, err
+	}
+	preview := &ImportPreview{Source: source, Root: *root}
+	seen := make(map[string]bool)
+	if err := s.previewFolder(ctx, userID, &preview.Root, preview, seen); err != nil {
+		return nil, err
+	}
+	return preview, nil
+}
+
+// previewFolder counts a folder's contents and flags duplicate bookmarks
+func (s *Service) previewFolder(ctx context.Context, userID uint, folder *ImportFolder, preview *ImportPreview, seen map[string]bool) error {
+	// Copy so flagging duplicates doesn't write through to the caller's tree
+	folder.Bookmarks = append([]ImportBookmark(nil), folder.Bookmarks...)
+	folder.Folders = append([]ImportFolder(nil), folder.Folders...)
+
+	for n := range folder.Bookmarks {
+		bookmark := &folder.Bookmarks[n]
+		duplicate, err := s.DetectDuplicate(ctx, userID, bookmark.URL)
+		if err != nil {
+			return err
+		}
+		bookmark.Duplicate = duplicate || seen[bookmark.URL]
+		seen[bookmark.URL] = true
+
+		if bookmark.Duplicate {
+			preview.DuplicatesCount++
+		} else {
+			preview.BookmarksCount++
+		}
+	}
+
+	for n := range folder.Folders {
+		if folder.Folders[n].Match != nil {
+			preview.MatchedCount++
+		} else {
+			preview.CollectionsCount++
+		}
+		if err := s.previewFolder(ctx, userID, &folder.Folders[n], preview, seen); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CommitImport creates collections for the parsed folders, keeping their
+// hierarchy, and bookmarks with their original add dates and tags.
+// Folders matching the user's collections are merged into them, and
+// bookmarks that are already saved are skipped
+func (s *Service) CommitImport(ctx context.Context, userID uint, source string, root *ImportFolder) (*ImportResult, error) {
+	return s.commitImport(ctx, userID, source, root, 0, true)
+}
+
+// CommitImportInto commits an import into an existing collection of the
+// user, or outside any collection when collectionID is 0. Folders keep the
+// matches they carry, e.g. from a preview
+func (s *Service) CommitImportInto(ctx context.Context, userID uint, source string, root *ImportFolder, collectionID uint) (*ImportResult, error) {
+	return s.commitImport(ctx, userID, source, root, collectionID, false)
+}
+
+func (s *Service) commitImport(ctx context.Context, userID uint, source string, root *ImportFolder, collectionID uint, match bool) (*ImportResult, error) {
+	name, ok := sourceNames[source]
+	if !ok {
+		return nil, ErrUnknownSource
+	}
+	var parent *database.Collection
+	if collectionID != 0 {
+		collection, err := s.userCollection(ctx, userID, collectionID)
+		if err != nil {
+			return nil, err
+		}
+		parent = collection
+	}
+
+	root = cleanFolder(root, s.urlCleaner(ctx, userID))
+	if match {
+		if err := s.matchFolders(ctx, userID, source, root); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.checkMatches(ctx, userID, root); err != nil {
+		return nil, err
+	}
+
+	startTime := time.Now()
+	result := &ImportResult{
+		Errors:  make([]string, 0),
+		BatchID: uuid.NewString(),
+	}
+
+	description := fmt.Sprintf("Imported from %s on %s", name, startTime.Format("2006-01-02"))
+	s.commitFolder(ctx, userID, root, parent, description, result)
+
+	// Folder paths are only repeated from the top, so imports into a
+	// collection aren't remembered
+	if collectionID == 0 {
+		if err := s.rememberFolders(ctx, userID, source, root); err != nil {
+			s.logger.Warn("Failed to remember import folders", zap.Uint("user_id", userID), zap.String("source", source), zap.Error(err))
+		}
+	}
+
+	result.IndexJobID = s.indexImported(userID, startTime)
+
+	result.ProcessingTimeMs = time.Since(startTime).Milliseconds()
+	s.importDone(ctx, userID, source, collectionID, result)
+	return result, nil
+}
+
+// commitFolder creates a folder's bookmarks and subfolders under the parent
+// collection, or outside any collection for the root
+func (s *Service) commitFolder(ctx context.Context, userID uint, folder *ImportFolder, parent *database.Collection, description string, result *ImportResult) {
+	for _, item := range folder.Bookmarks {
+		isDuplicate, err := s.DetectDuplicate(ctx, userID, item.URL)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("Error checking duplicate for %s: %v", item.URL, err))
+			continue
+		}
+		if isDuplicate {
+			result.DuplicatesSkipped++
+			continue
+		}
+
+		tagsJSON := "[]"
+		if normalized := tags.NormalizeAll(item.Tags); len(normalized) > 0 {
+			data, _ := json.Marshal(normalized)
+			tagsJSON = string(data)
+		}
+
+		bookmark := &database.Bookmark{
+			UserID:      userID,
+			URL:         item.URL,
+			Title:       item.Title,
+			Description: description,
+			Notes:       item.Notes,
+			Metadata:    importFlags(item),
+			Tags:        tagsJSON,
+			Status:      "active",
+			Source:      database.BookmarkSourceImport,
+			SourceRef:   result.BatchID,
+		}
+		if item.Description != "" {
+			bookmark.Description = item.Description
+		}
+		if item.AddedAt != nil {
+			bookmark.CreatedAt = *item.AddedAt
+		}
+
+		if err := s.db.Create(bookmark).Error; err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("Failed to create bookmark %s: %v", item.Title, err))
+			continue
+		}
+		s.restoreAttachments(ctx, bookmark, item, result)
+
+		if parent != nil {
+			if err := s.db.Model(parent).Association("Bookmarks").Append(bookmark); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("Failed to associate bookmark with collection: %v", err))
+			}
+		}
+
+		result.ImportedBookmarksCount++
+	}
+
+	for n := range folder.Folders {
+		sub := &folder.Folders[n]
+		if sub.Match != nil {
+			collection, err := s.userCollection(ctx, userID, sub.Match.CollectionID)
+			if err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("Error processing folder %s: %v", sub.Name, err))
+				continue
+			}
+			result.MatchedCollectionsCount++
+
+			s.commitFolder(ctx, userID, sub, collection, description, result)
+			continue
+		}
+
+		// Share links are unique, so each collection needs its own even
+		// while it is private
+		collection := &database.Collection{
+			UserID:      userID,
+			Name:        sub.Name,
+			Description: description,
+			Visibility:  "private",
+			ShareLink:   strings.ReplaceAll(uuid.NewString(), "-", ""),
+		}
+		if parent != nil {
+			collection.ParentID = &parent.ID
+		}
+		if sub.AddedAt != nil {
+			collection.CreatedAt = *sub.AddedAt
+		}
+
+		if err := s.db.Create(collection).Error; err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("Error processing folder %s: failed to create collection: %v", sub.Name, err))
+			continue
+		}
+		result.ImportedCollectionsCount++
+		// Remembered for the next import from the source
+		sub.Match = &FolderMatch{CollectionID: collection.ID, CollectionName: collection.Name}
+
+		s.commitFolder(ctx, userID, sub, collection, description, result)
+	}
+}
+
+// importFlags stores the flags a field mapping left on an imported bookmark
+// in its JSON metadata, empty when there are none
+func importFlags(item ImportBookmark) string {
+	flags := make(map[string]bool)
+	for name, set := range map[string]bool{"unread": item.Unread, "archived": item.Archived, "starred": item.Starred} {
+		if set {
+			flags[name] = true
+		}
+	}
+	if len(flags) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(flags)
+	return string(data)
+}
+
+// ImportBookmarksFromFirefoxPlaces imports bookmarks from a Firefox
+// places.sqlite database
+func (s *Service) ImportBookmarksFromFirefoxPlaces(ctx context.Context, userID uint, reader io.Reader) (*ImportResult, error) {
+	root, err := ParseFirefoxPlaces(reader)
+	if err != nil {
+		return nil, err
+	}
+	return s.CommitImport(ctx, userID, SourceFirefox, root)
+}
@@ -0,0 +1,202 @@
+package import_export
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/urlnorm"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// writePlacesFixture creates a minimal Firefox places.sqlite database
+func writePlacesFixture(t *testing.T) []byte {
+	path := filepath.Join(t.TempDir(), "places.sqlite")
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{})
+	require.NoError(t, err)
+
+	statements := []string{
+		`CREATE TABLE moz_places (id INTEGER PRIMARY KEY, url TEXT, title TEXT)`,
+		`CREATE TABLE moz_bookmarks (id INTEGER PRIMARY KEY, type INTEGER, fk INTEGER, parent INTEGER, position INTEGER, title TEXT, dateAdded INTEGER, guid TEXT)`,
+		`CREATE TABLE moz_keywords (id INTEGER PRIMARY KEY, keyword TEXT, place_id INTEGER)`,
+		`INSERT INTO moz_places VALUES (1, 'https://go.dev/', 'Go'), (2, 'https://developer.mozilla.org/', 'MDN'), (3, 'place:sort=8', NULL)`,
+		`INSERT INTO moz_bookmarks VALUES
+			(1, 2, NULL, 0, 0, '', 0, 'root________'),
+			(2, 2, NULL, 1, 0, 'menu', 0, 'menu________'),
+			(3, 2, NULL, 1, 1, 'toolbar', 0, 'toolbar_____'),
+			(4, 2, NULL, 1, 2, 'tags', 0, 'tags________'),
+			(10, 1, 1, 3, 0, 'The Go Programming Language', 1600000000000000, 'bm-go'),
+			(11, 2, NULL, 2, 0, 'Reference', 1500000000000000, 'folder-ref'),
+			(12, 1, 2, 11, 0, 'MDN Web Docs', 1550000000000000, 'bm-mdn'),
+			(13, 1, 3, 2, 1, 'Recent', 0, 'bm-smart'),
+			(14, 3, NULL, 2, 2, NULL, 0, 'sep'),
+			(20, 2, NULL, 4, 0, 'golang', 0, 'tag-golang'),
+			(21, 1, 1, 20, 0, NULL, 0, 'tag-entry')`,
+		`INSERT INTO moz_keywords VALUES (1, 'mdn', 2)`,
+	}
+	for _, statement := range statements {
+		require.NoError(t, db.Exec(statement).Error)
+	}
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	require.NoError(t, sqlDB.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return data
+}
+
+func TestParseFirefoxPlaces(t *testing.T) {
+	root, err := ParseFirefoxPlaces(bytes.NewReader(writePlacesFixture(t)))
+	require.NoError(t, err)
+
+	require.Len(t, root.Bookmarks, 1)
+	assert.Equal(t, "https://go.dev/", root.Bookmarks[0].URL)
+	assert.Equal(t, []string{"golang"}, root.Bookmarks[0].Tags)
+	assert.Equal(t, time.Unix(1600000000, 0).UTC(), *root.Bookmarks[0].AddedAt)
+
+	require.Len(t, root.Folders, 1)
+	folder := root.Folders[0]
+	assert.Equal(t, "Reference", folder.Name)
+	require.Len(t, folder.Bookmarks, 1)
+	assert.Equal(t, "MDN Web Docs", folder.Bookmarks[0].Title)
+	assert.Equal(t, []string{"mdn"}, folder.Bookmarks[0].Tags)
+}
+
+func TestParseFirefoxPlaces_Invalid(t *testing.T) {
+	_, err := ParseFirefoxPlaces(strings.NewReader("not a database"))
+	assert.ErrorIs(t, err, ErrInvalidPlaces)
+}
+
+func TestParseChromeBookmarks_Dates(t *testing.T) {
+	root, err := ParseChromeBookmarks(strings.NewReader(`{"roots": {"other": {"type": "folder", "children": [
+		{"type": "url", "name": "Go", "url": "https://go.dev", "date_added": "13245000000000000"}
+	]}}}`))
+	require.NoError(t, err)
+	require.Len(t, root.Bookmarks, 1)
+	assert.Equal(t, time.Unix(13245000000-chromeEpochOffset, 0).UTC(), *root.Bookmarks[0].AddedAt)
+}
+
+func TestService_PreviewAndCommitImport(t *testing.T) {
+	db, err := database.SetupTestDB()
+	require.NoError(t, err)
+	defer database.CleanupTestDB(db)
+
+	service := NewService(db)
+	ctx := context.Background()
+	require.NoError(t, db.Create(&database.User{BaseModel: database.BaseModel{ID: 1}, Email: "test@example.com", Username: "testuser", SupabaseID: "test-supabase-id"}).Error)
+	require.NoError(t, db.Create(&database.Bookmark{UserID: 1, URL: "https://go.dev/", Title: "Go", Status: "active"}).Error)
+
+	root, err := ParseFirefoxPlaces(bytes.NewReader(writePlacesFixture(t)))
+	require.NoError(t, err)
+
+	preview, err := service.PreviewImport(ctx, 1, SourceFirefox, root)
+	require.NoError(t, err)
+	assert.Equal(t, 1, preview.BookmarksCount)
+	assert.Equal(t, 1, preview.DuplicatesCount)
+	assert.Equal(t, 1, preview.CollectionsCount)
+	assert.True(t, preview.Root.Bookmarks[0].Duplicate)
+	assert.False(t, root.Bookmarks[0].Duplicate, "preview must not modify the parsed tree")
+
+	var count int64
+	db.Model(&database.Collection{}).Count(&count)
+	assert.Zero(t, count, "preview must not import anything")
+
+	_, err = service.PreviewImport(ctx, 1, "opera", root)
+	assert.ErrorIs(t, err, ErrUnknownSource)
+
+	result, err := service.CommitImport(ctx, 1, SourceFirefox, root)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.ImportedBookmarksCount)
+	assert.Equal(t, 1, result.ImportedCollectionsCount)
+	assert.Equal(t, 1, result.DuplicatesSkipped)
+
+	var imported database.Bookmark
+	require.NoError(t, db.Preload("Collections").Where("url = ?", "https://developer.mozilla.org/").First(&imported).Error)
+	assert.Equal(t, `["mdn"]`, imported.Tags)
+	assert.Equal(t, time.Unix(1550000000, 0).UTC(), imported.CreatedAt.UTC())
+	require.Len(t, imported.Collections, 1)
+	assert.Equal(t, "Reference", imported.Collections[0].Name)
+}
+
+type stubURLRules []urlnorm.Rule
+
+func (r stubURLRules) Rules(context.Context, uint) ([]urlnorm.Rule, error) {
+	return r, nil
+}
+
+func TestService_CommitImportCleansURLs(t *testing.T) {
+	db, err := database.SetupTestDB()
+	require.NoError(t, err)
+	defer database.CleanupTestDB(db)
+
+	service := NewService(db)
+	service.SetURLRules(stubURLRules(urlnorm.Defaults))
+	ctx := context.Background()
+	require.NoError(t, db.Create(&database.User{BaseModel: database.BaseModel{ID: 1}, Email: "test@example.com", Username: "testuser", SupabaseID: "test-supabase-id"}).Error)
+	require.NoError(t, db.Create(&database.Bookmark{UserID: 1, URL: "https://go.dev/", Title: "Go", Status: "active"}).Error)
+
+	root := &ImportFolder{Bookmarks: []ImportBookmark{
+		{URL: "https://go.dev/?utm_source=newsletter", Title: "Go"},
+		{URL: "https://example.com/?fbclid=abc&page=2", Title: "Example"},
+	}}
+	preview, err := service.PreviewImport(ctx, 1, SourceChrome, root)
+	require.NoError(t, err)
+	assert.True(t, preview.Root.Bookmarks[0].Duplicate, "the cleaned URL is already saved")
+	assert.Equal(t, "https://go.dev/?utm_source=newsletter", root.Bookmarks[0].URL, "cleaning must not modify the parsed tree")
+
+	result, err := service.CommitImport(ctx, 1, SourceChrome, root)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.ImportedBookmarksCount)
+	assert.Equal(t, 1, result.DuplicatesSkipped)
+	var count int64
+	db.Model(&database.Bookmark{}).Where("url = ?", "https://example.com/?page=2").Count(&count)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestHandlers_ImportFromFirefoxPlacesPreview(t *testing.T) {
+	router, _ := setupTestRouter()
+
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	part, err := writer.CreateFormFile("file", "places.sqlite")
+	require.NoError(t, err)
+	_, err = part.Write(writePlacesFixture(t))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/import-export/import/firefox/places?preview=true", &buf)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Data ImportPreview `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, SourceFirefox, response.Data.Source)
+	assert.Equal(t, 2, response.Data.BookmarksCount)
+
+	body, err := json.Marshal(CommitImportRequest{Source: response.Data.Source, Root: response.Data.Root})
+	require.NoError(t, err)
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/import-export/import/commit", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"imported_bookmarks_count":2`)
+}
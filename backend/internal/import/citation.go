@@ -0,0 +1,122 @@
+package import_export
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/exporter"
+	"bookmark-sync-service/backend/pkg/notes"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrBookmarkNotFound   = errors.New("bookmark not found")
+	ErrCollectionNotFound = errors.New("collection not found")
+	ErrNotCitationFormat  = errors.New("not a citation format")
+)
+
+// PrintView is a bookmark laid out for printing: its reference, notes and
+// the text of its latest archived copy
+type PrintView struct {
+	Bookmark    exporter.Bookmark
+	Reference   exporter.Reference
+	Collections []string
+	Notes       template.HTML // sanitized
+	Archive     *database.ArchiveSnapshot
+	Paragraphs  []string // archived text, one block per entry
+	PrintedAt   time.Time
+}
+
+// PrintBookmark returns the print view of one of the user's bookmarks
+func (s *Service) PrintBookmark(ctx context.Context, userID, bookmarkID uint) (*PrintView, error) {
+	data, err := exporter.Load(ctx, s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	data = data.Only([]uint{bookmarkID})
+	if len(data.Bookmarks) == 0 {
+		return nil, ErrBookmarkNotFound
+	}
+
+	bookmark := data.Bookmarks[0]
+	view := &PrintView{
+		Bookmark:  bookmark,
+		Reference: exporter.ReferenceFor(bookmark, map[string]bool{}),
+		Notes:     template.HTML(notes.Render(bookmark.Notes)),
+		PrintedAt: time.Now().UTC(),
+	}
+	for _, collection := range data.Collections {
+		view.Collections = append(view.Collections, collection.Name)
+	}
+
+	var snapshot database.ArchiveSnapshot
+	err = s.db.WithContext(ctx).Where("user_id = ? AND bookmark_id = ?", userID, bookmarkID).
+		Order("version DESC").First(&snapshot).Error
+	switch {
+	case err == nil:
+		view.Archive = &snapshot
+		for _, line := range strings.Split(snapshot.Content, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				view.Paragraphs = append(view.Paragraphs, line)
+			}
+		}
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, fmt.Errorf("failed to get archived copy: %w", err)
+	}
+	return view, nil
+}
+
+// CiteBookmark writes the reference of one of the user's bookmarks in a
+// citation format
+func (s *Service) CiteBookmark(ctx context.Context, userID, bookmarkID uint, name string, w io.Writer) error {
+	format, err := citationFormat(name)
+	if err != nil {
+		return err
+	}
+	data, err := exporter.Load(ctx, s.db, userID)
+	if err != nil {
+		return err
+	}
+	data = data.Only([]uint{bookmarkID})
+	if len(data.Bookmarks) == 0 {
+		return ErrBookmarkNotFound
+	}
+	return format.Exporter.Export(w, data)
+}
+
+// CiteCollection writes the references of the bookmarks in one of the
+// user's collections in a citation format
+func (s *Service) CiteCollection(ctx context.Context, userID, collectionID uint, name string, w io.Writer) error {
+	format, err := citationFormat(name)
+	if err != nil {
+		return err
+	}
+	data, err := exporter.Load(ctx, s.db, userID)
+	if err != nil {
+		return err
+	}
+	collection, ok := data.InCollection(collectionID)
+	if !ok {
+		return ErrCollectionNotFound
+	}
+	return format.Exporter.Export(w, collection)
+}
+
+// citationFormat looks up a registered format that writes references
+func citationFormat(name string) (exporter.Format, error) {
+	format, err := exporter.Lookup(name)
+	if err != nil {
+		return exporter.Format{}, err
+	}
+	if !format.Citation {
+		return exporter.Format{}, fmt.Errorf("%w: %s", ErrNotCitationFormat, name)
+	}
+	return format, nil
+}
@@ -0,0 +1,146 @@
+package import_export
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"strconv"
+
+	"bookmark-sync-service/backend/pkg/exporter"
+	"bookmark-sync-service/backend/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+var printTemplate = template.Must(template.New("print").Parse(`<!DOCTYPE html>
+<html{{with .Bookmark.Language}} lang="{{.}}"{{end}}>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Reference.Title}}</title>
+<style>
+body{font-family:Georgia,"Times New Roman",serif;max-width:42em;margin:2em auto;padding:0 1em;color:#111;line-height:1.5}
+h1{font-size:1.6em;line-height:1.2;margin:0 0 .3em}
+.url{word-break:break-all}
+dl{display:grid;grid-template-columns:max-content 1fr;gap:.2em 1em;font-size:.9em;margin:1em 0}
+dt{color:#555}
+dd{margin:0}
+.citation{border-left:3px solid #999;padding-left:.8em;font-size:.9em}
+section{margin-top:2em}
+h2{font-size:1.1em;border-bottom:1px solid #ccc}
+footer{margin-top:3em;font-size:.8em;color:#555}
+@media print{body{margin:0;max-width:none}a{color:inherit;text-decoration:none}}
+</style>
+</head>
+<body>
+<article>
+<h1>{{.Reference.Title}}</h1>
+<p class="url"><a href="{{.Bookmark.URL}}">{{.Bookmark.URL}}</a></p>
+<dl>
+{{if .Reference.Authors}}<dt>Authors</dt><dd>{{range $n, $author := .Reference.Authors}}{{if $n}}; {{end}}{{$author}}{{end}}</dd>
+{{end}}<dt>Site</dt><dd>{{.Reference.Site}}</dd>
+{{with .Reference.Published}}<dt>Published</dt><dd>{{.Format "January 2, 2006"}}</dd>
+{{end}}<dt>Saved</dt><dd>{{.Bookmark.CreatedAt.Format "January 2, 2006"}}</dd>
+{{if .Bookmark.Tags}}<dt>Tags</dt><dd>{{range $n, $tag := .Bookmark.Tags}}{{if $n}}, {{end}}{{$tag}}{{end}}</dd>
+{{end}}{{if .Collections}}<dt>Collections</dt><dd>{{range $n, $name := .Collections}}{{if $n}}, {{end}}{{$name}}{{end}}</dd>
+{{end}}</dl>
+<p class="citation">{{.Reference}}</p>
+{{with .Bookmark.Description}}<p>{{.}}</p>
+{{end}}{{if .Notes}}<section>
+<h2>Notes</h2>
+{{.Notes}}
+</section>
+{{else if .Bookmark.NotesEncrypted}}<section>
+<h2>Notes</h2>
+<p><em>These notes are encrypted and can only be printed from your own device.</em></p>
+</section>
+{{end}}{{with .Archive}}<section>
+<h2>Archived copy, captured {{.CapturedAt.Format "January 2, 2006"}}</h2>
+{{range $.Paragraphs}}<p>{{.}}</p>
+{{end}}</section>
+{{end}}</article>
+<footer>Printed {{.PrintedAt.Format "January 2, 2006"}}</footer>
+</body>
+</html>
+`))
+
+// PrintBookmark renders a bookmark as a standalone page for printing
+func (h *Handlers) PrintBookmark(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+	bookmarkID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_ID", "Invalid bookmark ID", nil)
+		return
+	}
+
+	view, err := h.service.PrintBookmark(c.Request.Context(), userID, uint(bookmarkID))
+	if errors.Is(err, ErrBookmarkNotFound) {
+		utils.ErrorResponse(c, http.StatusNotFound, "BOOKMARK_NOT_FOUND", err.Error(), nil)
+		return
+	}
+	if err != nil {
+		utils.InternalErrorResponse(c, "Failed to render bookmark")
+		return
+	}
+
+	var body bytes.Buffer
+	if err := printTemplate.Execute(&body, view); err != nil {
+		utils.InternalErrorResponse(c, "Failed to render bookmark")
+		return
+	}
+	c.Data(http.StatusOK, "text/html; charset=utf-8", body.Bytes())
+}
+
+// CiteBookmark handles citation export of a single bookmark
+func (h *Handlers) CiteBookmark(c *gin.Context) {
+	h.cite(c, "bookmark", h.service.CiteBookmark)
+}
+
+// CiteCollection handles citation export of every bookmark in a collection
+func (h *Handlers) CiteCollection(c *gin.Context) {
+	h.cite(c, "collection", h.service.CiteCollection)
+}
+
+// cite writes the references of a bookmark or collection as a download.
+// They are rendered first so failures still get a JSON error
+func (h *Handlers) cite(c *gin.Context, kind string, write func(ctx context.Context, userID, id uint, format string, w io.Writer) error) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_ID", "Invalid "+kind+" ID", nil)
+		return
+	}
+
+	var body bytes.Buffer
+	err = write(c.Request.Context(), userID, uint(id), c.Param("format"), &body)
+	switch {
+	case errors.Is(err, exporter.ErrUnknownFormat), errors.Is(err, ErrNotCitationFormat):
+		utils.ErrorResponse(c, http.StatusBadRequest, "UNKNOWN_FORMAT", err.Error(), nil)
+		return
+	case errors.Is(err, ErrBookmarkNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, "BOOKMARK_NOT_FOUND", err.Error(), nil)
+		return
+	case errors.Is(err, ErrCollectionNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, "COLLECTION_NOT_FOUND", err.Error(), nil)
+		return
+	case err != nil:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "EXPORT_FAILED", "Failed to export citation", map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	format, _ := exporter.Lookup(c.Param("format"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s_%d.%s", kind, id, format.Extension))
+	c.Data(http.StatusOK, format.ContentType, body.Bytes())
+}
@@ -1,10 +1,15 @@
 package import_export
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
 
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/pkg/exporter"
+	"bookmark-sync-service/backend/pkg/middleware"
 	"bookmark-sync-service/backend/pkg/utils"
 
 	"github.com/gin-gonic/gin"
@@ -27,48 +32,78 @@ func NewHandlers(service *Service) *Handlers {
 func (h *Handlers) RegisterRoutes(router *gin.RouterGroup) {
 	importExport := router.Group("/import-export")
 	{
-		// Import endpoints
-		importExport.POST("/import/chrome", h.ImportFromChrome)
-		importExport.POST("/import/firefox", h.ImportFromFirefox)
-		importExport.POST("/import/safari", h.ImportFromSafari)
+		// Import endpoints. Uploads are streamed, so their limits only
+		// bound the work a request can cause
+		uploadLimit := middleware.BodyLimit(config.MaxImportUploadSize)
+		importExport.POST("/import/chrome", uploadLimit, h.ImportFromChrome)
+		importExport.POST("/import/firefox", uploadLimit, h.ImportFromFirefox)
+		importExport.POST("/import/safari", uploadLimit, h.ImportFromSafari)
+		importExport.POST("/import/firefox/places", middleware.BodyLimit(maxPlacesSize+middleware.MultipartOverhead), h.ImportFromFirefoxPlaces)
+		importExport.POST("/import/legacy/:source", uploadLimit, h.ImportFromLegacy)
+		// JSON escaping of newlines and quotes can double a pasted document
+		importExport.POST("/import/text", middleware.BodyLimit(2*maxTextImportSize), h.ImportFromText)
+		importExport.POST("/import/archive", uploadLimit, h.ImportFromArchive)
+		importExport.POST("/import/commit", uploadLimit, h.CommitImport)
 		importExport.GET("/import/progress/:jobId", h.GetImportProgress)
+		importExport.GET("/import/mappings/:source", h.GetFolderMappings)
+		importExport.DELETE("/import/mappings/:source", h.ForgetFolderMappings)
 
 		// Export endpoints
-		importExport.GET("/export/json", h.ExportToJSON)
-		importExport.GET("/export/html", h.ExportToHTML)
+		importExport.GET("/export/:format", h.ExportBookmarks)
+		importExport.GET("/bookmarks/:id/print", h.PrintBookmark)
+		importExport.GET("/bookmarks/:id/citation/:format", h.CiteBookmark)
+		importExport.GET("/collections/:id/citation/:format", h.CiteCollection)
 
 		// Utility endpoints
 		importExport.POST("/detect-duplicates", h.DetectDuplicates)
 	}
 }
 
-// ImportFromChrome handles Chrome bookmark import
+// ImportFromChrome handles Chrome bookmark import. With ?preview=true
+// nothing is imported and the parsed bookmarks are returned for review
 func (h *Handlers) ImportFromChrome(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
 		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
 		return
 	}
 
-	// Get file from form
-	file, header, err := c.Request.FormFile("file")
+	// Stream the file from the form
+	file, err := utils.StreamUpload(c, "file")
 	if err != nil {
+		if middleware.BodyTooLarge(c, err) {
+			return
+		}
 		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_FILE", "No file provided or invalid file", map[string]interface{}{"error": err.Error()})
 		return
 	}
 	defer file.Close()
 
 	// Validate file extension (more reliable than content type in multipart uploads)
-	if !strings.HasSuffix(header.Filename, ".json") {
+	if !strings.HasSuffix(file.Filename, ".json") {
 		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_FILE_TYPE", "File must be a JSON file", nil)
 		return
 	}
 
+	root, err := ParseChromeBookmarks(file)
+	if err != nil {
+		if middleware.BodyTooLarge(c, err) {
+			return
+		}
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_FILE", "Failed to parse Chrome bookmarks", map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	if isPreview(c) {
+		h.previewImport(c, userID, SourceChrome, root)
+		return
+	}
+
 	// Generate job ID for progress tracking
 	jobID := uuid.New().String()
 
 	// Start import process
-	result, err := h.service.ImportBookmarksFromChrome(c.Request.Context(), userID.(uint), file)
+	result, err := h.service.CommitImport(c.Request.Context(), userID, SourceChrome, root)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "IMPORT_FAILED", "Failed to import Chrome bookmarks", map[string]interface{}{"error": err.Error()})
 		return
@@ -85,22 +120,25 @@ func (h *Handlers) ImportFromChrome(c *gin.Context) {
 
 // ImportFromFirefox handles Firefox bookmark import
 func (h *Handlers) ImportFromFirefox(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
 		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
 		return
 	}
 
-	// Get file from form
-	file, header, err := c.Request.FormFile("file")
+	// Stream the file from the form
+	file, err := utils.StreamUpload(c, "file")
 	if err != nil {
+		if middleware.BodyTooLarge(c, err) {
+			return
+		}
 		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_FILE", "No file provided or invalid file", map[string]interface{}{"error": err.Error()})
 		return
 	}
 	defer file.Close()
 
 	// Validate file extension (more reliable than content type in multipart uploads)
-	if !isHTMLFile(header.Filename) {
+	if !isHTMLFile(file.Filename) {
 		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_FILE_TYPE", "File must be an HTML file", nil)
 		return
 	}
@@ -109,8 +147,11 @@ func (h *Handlers) ImportFromFirefox(c *gin.Context) {
 	jobID := uuid.New().String()
 
 	// Start import process
-	result, err := h.service.ImportBookmarksFromFirefox(c.Request.Context(), userID.(uint), file)
+	result, err := h.service.ImportBookmarksFromFirefox(c.Request.Context(), userID, file)
 	if err != nil {
+		if middleware.BodyTooLarge(c, err) {
+			return
+		}
 		utils.ErrorResponse(c, http.StatusInternalServerError, "IMPORT_FAILED", "Failed to import Firefox bookmarks", map[string]interface{}{"error": err.Error()})
 		return
 	}
@@ -126,22 +167,25 @@ func (h *Handlers) ImportFromFirefox(c *gin.Context) {
 
 // ImportFromSafari handles Safari bookmark import
 func (h *Handlers) ImportFromSafari(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
 		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
 		return
 	}
 
-	// Get file from form
-	file, header, err := c.Request.FormFile("file")
+	// Stream the file from the form
+	file, err := utils.StreamUpload(c, "file")
 	if err != nil {
+		if middleware.BodyTooLarge(c, err) {
+			return
+		}
 		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_FILE", "No file provided or invalid file", map[string]interface{}{"error": err.Error()})
 		return
 	}
 	defer file.Close()
 
 	// Validate file type
-	if !isPlistFile(header.Filename) {
+	if !isPlistFile(file.Filename) {
 		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_FILE_TYPE", "File must be a plist file", nil)
 		return
 	}
@@ -150,8 +194,11 @@ func (h *Handlers) ImportFromSafari(c *gin.Context) {
 	jobID := uuid.New().String()
 
 	// Start import process
-	result, err := h.service.ImportBookmarksFromSafari(c.Request.Context(), userID.(uint), file)
+	result, err := h.service.ImportBookmarksFromSafari(c.Request.Context(), userID, file)
 	if err != nil {
+		if middleware.BodyTooLarge(c, err) {
+			return
+		}
 		utils.ErrorResponse(c, http.StatusInternalServerError, "IMPORT_FAILED", "Failed to import Safari bookmarks", map[string]interface{}{"error": err.Error()})
 		return
 	}
@@ -165,10 +212,311 @@ func (h *Handlers) ImportFromSafari(c *gin.Context) {
 	utils.SuccessResponse(c, response, "Safari bookmarks imported successfully")
 }
 
+// ImportFromFirefoxPlaces handles Firefox places.sqlite import. With
+// ?preview=true nothing is imported and the parsed bookmarks are returned
+// for review, to be sent back to the commit endpoint
+func (h *Handlers) ImportFromFirefoxPlaces(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	// Stream the file from the form
+	file, err := utils.StreamUpload(c, "file")
+	if err != nil {
+		if middleware.BodyTooLarge(c, err) {
+			return
+		}
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_FILE", "No file provided or invalid file", map[string]interface{}{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	if !isSQLiteFile(file.Filename) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_FILE_TYPE", "File must be a places.sqlite file", nil)
+		return
+	}
+
+	root, err := ParseFirefoxPlaces(file)
+	if err != nil {
+		if middleware.BodyTooLarge(c, err) {
+			return
+		}
+		switch err {
+		case ErrInvalidPlaces:
+			utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_FILE", err.Error(), nil)
+		case ErrPlacesTooLarge:
+			utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, "FILE_TOO_LARGE", err.Error(), nil)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, "IMPORT_FAILED", "Failed to read Firefox places database", map[string]interface{}{"error": err.Error()})
+		}
+		return
+	}
+
+	if isPreview(c) {
+		h.previewImport(c, userID, SourceFirefox, root)
+		return
+	}
+
+	result, err := h.service.CommitImport(c.Request.Context(), userID, SourceFirefox, root)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "IMPORT_FAILED", "Failed to import Firefox bookmarks", map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	response := gin.H{
+		"job_id": uuid.New().String(),
+		"result": result,
+	}
+
+	utils.SuccessResponse(c, response, "Firefox bookmarks imported successfully")
+}
+
+// ImportFromLegacy handles imports from legacy bookmark managers: Shaarli,
+// Wallabag and Linkding. An optional mapping form field, sent before the
+// file, holds a JSON FieldMapping for read state, flags and tags. With ?preview=true nothing
+// is imported and the mapped bookmarks are returned for review
+func (h *Handlers) ImportFromLegacy(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	source := c.Param("source")
+	if _, ok := legacyParsers[source]; !ok {
+		utils.ErrorResponse(c, http.StatusNotFound, "UNKNOWN_SOURCE", ErrUnknownSource.Error(), map[string]interface{}{"source": source})
+		return
+	}
+
+	// Stream the file from the form
+	file, err := utils.StreamUpload(c, "file")
+	if err != nil {
+		if middleware.BodyTooLarge(c, err) {
+			return
+		}
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_FILE", "No file provided or invalid file", map[string]interface{}{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	if !strings.HasSuffix(file.Filename, ".json") && !isHTMLFile(file.Filename) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_FILE_TYPE", "File must be a JSON or HTML file", nil)
+		return
+	}
+
+	// The mapping is sent before the file, so it has been read by now
+	var mapping FieldMapping
+	if raw := file.Fields["mapping"]; raw != "" {
+		if err := json.Unmarshal([]byte(raw), &mapping); err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_MAPPING", "Field mapping must be a JSON object", map[string]interface{}{"error": err.Error()})
+			return
+		}
+	}
+
+	root, err := ParseLegacyImport(source, file, mapping)
+	if err != nil {
+		if middleware.BodyTooLarge(c, err) {
+			return
+		}
+		if errors.Is(err, ErrInvalidMapping) {
+			utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_MAPPING", err.Error(), nil)
+			return
+		}
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_FILE", fmt.Sprintf("Failed to parse %s export", sourceNames[source]), map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	if isPreview(c) {
+		h.previewImport(c, userID, source, root)
+		return
+	}
+
+	result, err := h.service.CommitImport(c.Request.Context(), userID, source, root)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "IMPORT_FAILED", fmt.Sprintf("Failed to import %s bookmarks", sourceNames[source]), map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	response := gin.H{
+		"job_id": uuid.New().String(),
+		"result": result,
+	}
+
+	utils.SuccessResponse(c, response, fmt.Sprintf("%s bookmarks imported successfully", sourceNames[source]))
+}
+
+// ImportFromText handles imports of the links found in pasted plain text
+// or Markdown, into an existing or new collection and with the given tags.
+// With ?preview=true nothing is imported and the detected links are
+// returned for review
+func (h *Handlers) ImportFromText(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	var req TextImportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		if middleware.BodyTooLarge(c, err) {
+			return
+		}
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request format", map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	root, err := h.service.ParseTextImport(c.Request.Context(), userID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrTextTooLarge):
+			utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, "TEXT_TOO_LARGE", err.Error(), nil)
+		case errors.Is(err, ErrNoLinksFound):
+			utils.ErrorResponse(c, http.StatusBadRequest, "NO_LINKS_FOUND", err.Error(), nil)
+		case errors.Is(err, ErrCollectionNotFound):
+			utils.ErrorResponse(c, http.StatusNotFound, "COLLECTION_NOT_FOUND", err.Error(), nil)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, "IMPORT_FAILED", "Failed to read link list", map[string]interface{}{"error": err.Error()})
+		}
+		return
+	}
+
+	if isPreview(c) {
+		h.previewImport(c, userID, SourceText, root)
+		return
+	}
+
+	result, err := h.service.CommitImportInto(c.Request.Context(), userID, SourceText, root, req.CollectionID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "IMPORT_FAILED", "Failed to import links", map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	response := gin.H{
+		"job_id": uuid.New().String(),
+		"result": result,
+	}
+
+	utils.SuccessResponse(c, response, "Links imported successfully")
+}
+
+// CommitImport imports bookmarks returned by an import preview
+func (h *Handlers) CommitImport(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	var req CommitImportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		if middleware.BodyTooLarge(c, err) {
+			return
+		}
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request format", map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	result, err := h.service.CommitImportInto(c.Request.Context(), userID, req.Source, &req.Root, req.CollectionID)
+	if err != nil {
+		if err == ErrUnknownSource {
+			utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", err.Error(), nil)
+			return
+		}
+		if errors.Is(err, ErrCollectionNotFound) {
+			utils.ErrorResponse(c, http.StatusNotFound, "COLLECTION_NOT_FOUND", err.Error(), nil)
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "IMPORT_FAILED", "Failed to import bookmarks", map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	response := gin.H{
+		"job_id": uuid.New().String(),
+		"result": result,
+	}
+
+	utils.SuccessResponse(c, response, "Bookmarks imported successfully")
+}
+
+// ImportFromArchive recreates the collections, bookmarks, archived copies
+// and screenshots of an archive export. With ?preview=true nothing is
+// imported and the parsed tree is returned for review
+func (h *Handlers) ImportFromArchive(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	// Stream the file from the form
+	file, err := utils.StreamUpload(c, "file")
+	if err != nil {
+		if middleware.BodyTooLarge(c, err) {
+			return
+		}
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_FILE", "No file provided or invalid file", map[string]interface{}{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	if !strings.HasSuffix(file.Filename, ".zip") {
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_FILE_TYPE", "File must be a zip file", nil)
+		return
+	}
+
+	root, err := ParseArchive(file)
+	if err != nil {
+		if middleware.BodyTooLarge(c, err) {
+			return
+		}
+		if errors.Is(err, ErrUnsupportedArchive) {
+			utils.ErrorResponse(c, http.StatusBadRequest, "UNSUPPORTED_ARCHIVE", err.Error(), nil)
+			return
+		}
+		if errors.Is(err, ErrArchiveTooLarge) {
+			utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, "ARCHIVE_TOO_LARGE", err.Error(), nil)
+			return
+		}
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_FILE", "Failed to parse bookmark archive", map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	if isPreview(c) {
+		h.previewImport(c, userID, SourceArchive, root)
+		return
+	}
+
+	result, err := h.service.CommitImport(c.Request.Context(), userID, SourceArchive, root)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "IMPORT_FAILED", "Failed to import bookmark archive", map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	response := gin.H{
+		"job_id": uuid.New().String(),
+		"result": result,
+	}
+
+	utils.SuccessResponse(c, response, "Bookmark archive imported successfully")
+}
+
+// previewImport responds with what importing the parsed bookmarks would do
+func (h *Handlers) previewImport(c *gin.Context, userID uint, source string, root *ImportFolder) {
+	preview, err := h.service.PreviewImport(c.Request.Context(), userID, source, root)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "PREVIEW_FAILED", "Failed to preview import", map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	utils.SuccessResponse(c, preview, "Import preview generated successfully")
+}
+
 // GetImportProgress handles import progress requests
 func (h *Handlers) GetImportProgress(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
 		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
 		return
 	}
@@ -179,7 +527,7 @@ func (h *Handlers) GetImportProgress(c *gin.Context) {
 		return
 	}
 
-	progress, err := h.service.GetImportProgress(c.Request.Context(), userID.(uint), jobID)
+	progress, err := h.service.GetImportProgress(c.Request.Context(), userID, jobID)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "PROGRESS_FAILED", "Failed to get import progress", map[string]interface{}{"error": err.Error()})
 		return
@@ -193,48 +541,78 @@ func (h *Handlers) GetImportProgress(c *gin.Context) {
 	utils.SuccessResponse(c, progress, "Import progress retrieved successfully")
 }
 
-// ExportToJSON handles JSON export
-func (h *Handlers) ExportToJSON(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+// GetFolderMappings lists the collections folders of an import source were
+// remembered in
+func (h *Handlers) GetFolderMappings(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
 		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
 		return
 	}
 
-	// Set response headers for file download
-	c.Header("Content-Type", "application/json")
-	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=bookmarks_%d.json", userID))
+	mappings, err := h.service.FolderMappings(c.Request.Context(), userID, c.Param("source"))
+	if err != nil {
+		if errors.Is(err, ErrUnknownSource) {
+			utils.ErrorResponse(c, http.StatusNotFound, "UNKNOWN_SOURCE", err.Error(), map[string]interface{}{"source": c.Param("source")})
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "MAPPINGS_FAILED", "Failed to get folder mappings", map[string]interface{}{"error": err.Error()})
+		return
+	}
 
-	// Export bookmarks
-	if err := h.service.ExportBookmarksToJSON(c.Request.Context(), userID.(uint), c.Writer); err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "EXPORT_FAILED", "Failed to export bookmarks to JSON", map[string]interface{}{"error": err.Error()})
+	utils.SuccessResponse(c, gin.H{"mappings": mappings}, "Folder mappings retrieved successfully")
+}
+
+// ForgetFolderMappings drops the remembered folder mappings of an import
+// source
+func (h *Handlers) ForgetFolderMappings(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
 		return
 	}
+
+	if err := h.service.ForgetFolderMappings(c.Request.Context(), userID, c.Param("source")); err != nil {
+		if errors.Is(err, ErrUnknownSource) {
+			utils.ErrorResponse(c, http.StatusNotFound, "UNKNOWN_SOURCE", err.Error(), map[string]interface{}{"source": c.Param("source")})
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "MAPPINGS_FAILED", "Failed to forget folder mappings", map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	utils.SuccessResponse(c, nil, "Folder mappings forgotten successfully")
 }
 
-// ExportToHTML handles HTML export
-func (h *Handlers) ExportToHTML(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+// ExportBookmarks handles export in any registered format, e.g. json or html
+func (h *Handlers) ExportBookmarks(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
 		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
 		return
 	}
 
+	format, err := exporter.Lookup(c.Param("format"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "UNKNOWN_FORMAT", err.Error(), nil)
+		return
+	}
+
 	// Set response headers for file download
-	c.Header("Content-Type", "text/html")
-	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=bookmarks_%d.html", userID))
+	c.Header("Content-Type", format.ContentType)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=bookmarks_%d.%s", userID, format.Extension))
 
 	// Export bookmarks
-	if err := h.service.ExportBookmarksToHTML(c.Request.Context(), userID.(uint), c.Writer); err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "EXPORT_FAILED", "Failed to export bookmarks to HTML", map[string]interface{}{"error": err.Error()})
+	if err := h.service.Export(c.Request.Context(), userID, format.Name, c.Writer); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "EXPORT_FAILED", "Failed to export bookmarks", map[string]interface{}{"error": err.Error(), "format": format.Name})
 		return
 	}
 }
 
 // DetectDuplicates handles duplicate detection requests
 func (h *Handlers) DetectDuplicates(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
 		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
 		return
 	}
@@ -261,7 +639,7 @@ func (h *Handlers) DetectDuplicates(c *gin.Context) {
 	// Check each URL for duplicates
 	duplicates := make(map[string]bool)
 	for _, url := range request.URLs {
-		isDuplicate, err := h.service.DetectDuplicate(c.Request.Context(), userID.(uint), url)
+		isDuplicate, err := h.service.DetectDuplicate(c.Request.Context(), userID, url)
 		if err != nil {
 			utils.ErrorResponse(c, http.StatusInternalServerError, "DUPLICATE_CHECK_FAILED", "Failed to check for duplicates", map[string]interface{}{"error": err.Error()})
 			return
@@ -293,6 +671,16 @@ func isHTMLFile(filename string) bool {
 	return len(filename) > 5 && (filename[len(filename)-5:] == ".html" || filename[len(filename)-4:] == ".htm")
 }
 
+// isSQLiteFile checks if the filename has SQLite extension
+func isSQLiteFile(filename string) bool {
+	return strings.HasSuffix(filename, ".sqlite")
+}
+
+// isPreview reports whether an upload should only be previewed
+func isPreview(c *gin.Context) bool {
+	return c.Query("preview") == "true"
+}
+
 // isPlistFile checks if the filename has plist extension
 func isPlistFile(filename string) bool {
 	return len(filename) > 6 && filename[len(filename)-6:] == ".plist"
@@ -3,10 +3,12 @@ package import_export
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"bookmark-sync-service/backend/pkg/database"
 
@@ -25,7 +27,7 @@ func setupTestRouter() (*gin.Engine, *Service) {
 	router := gin.New()
 	router.Use(func(c *gin.Context) {
 		// Mock user ID for testing
-		c.Set("user_id", uint(1))
+		c.Set("user_id", "1")
 		c.Next()
 	})
 
@@ -129,6 +131,59 @@ func TestHandlers_DetectDuplicates(t *testing.T) {
 	assert.Contains(t, response, "data")
 }
 
+func TestHandlers_PrintAndCite(t *testing.T) {
+	router, service := setupTestRouter()
+	db := service.db
+
+	bookmark := &database.Bookmark{
+		UserID: 1, URL: "https://example.com/engine", Title: "Analytical <Engine>",
+		Notes: "**Read twice**", Metadata: `{"author":"Ada Lovelace","published_at":"1843-09-01"}`,
+	}
+	require.NoError(t, db.Create(bookmark).Error)
+	other := &database.Bookmark{UserID: 2, URL: "https://example.com/private", Title: "Private"}
+	require.NoError(t, db.Create(other).Error)
+	collection := &database.Collection{UserID: 1, Name: "Reading"}
+	require.NoError(t, db.Create(collection).Error)
+	require.NoError(t, db.Model(collection).Association("Bookmarks").Append(bookmark))
+	for version, content := range []string{"Old text", "First block\n\nSecond block"} {
+		require.NoError(t, db.Create(&database.ArchiveSnapshot{
+			UserID: 1, BookmarkID: bookmark.ID, Version: version + 1, URL: bookmark.URL,
+			Content: content, ContentHash: content, CapturedAt: time.Now(),
+		}).Error)
+	}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/import-export"+path, nil))
+		return w
+	}
+
+	w := get(fmt.Sprintf("/bookmarks/%d/print", bookmark.ID))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+	page := w.Body.String()
+	assert.Contains(t, page, "<h1>Analytical &lt;Engine&gt;</h1>")
+	assert.Contains(t, page, "<dd>Ada Lovelace</dd>")
+	assert.Contains(t, page, "<strong>Read twice</strong>")
+	assert.Contains(t, page, "<p>First block</p>\n<p>Second block</p>")
+	assert.NotContains(t, page, "Old text")
+
+	w = get(fmt.Sprintf("/bookmarks/%d/citation/bibtex", bookmark.ID))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/x-bibtex", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), fmt.Sprintf("bookmark_%d.bib", bookmark.ID))
+	assert.Contains(t, w.Body.String(), "@online{lovelace1843analytical,")
+
+	w = get(fmt.Sprintf("/collections/%d/citation/ris", collection.ID))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "UR  - https://example.com/engine")
+
+	assert.Equal(t, http.StatusBadRequest, get(fmt.Sprintf("/bookmarks/%d/citation/csv", bookmark.ID)).Code)
+	assert.Equal(t, http.StatusNotFound, get(fmt.Sprintf("/bookmarks/%d/citation/ris", other.ID)).Code)
+	assert.Equal(t, http.StatusNotFound, get(fmt.Sprintf("/bookmarks/%d/print", other.ID)).Code)
+	assert.Equal(t, http.StatusNotFound, get("/collections/999/citation/csl-json").Code)
+}
+
 func TestHelperFunctions(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -0,0 +1,33 @@
+package import_export
+
+import (
+	"context"
+
+	"bookmark-sync-service/backend/internal/hooks"
+)
+
+// Hooks calls the configured hooks of a hook point
+type Hooks interface {
+	Run(ctx context.Context, point hooks.Point, userID uint, payload interface{}) error
+}
+
+// SetHooks makes imports call the after_import hooks once they are done
+func (s *Service) SetHooks(h Hooks) {
+	s.hooks = h
+}
+
+// importEvent is what after_import hooks are told about an import
+type importEvent struct {
+	Source       string `json:"source"`
+	CollectionID uint   `json:"collection_id,omitempty"` // collection imported into, if any
+	*ImportResult
+}
+
+// importDone tells the after_import hooks about a finished import. They run
+// in the background, so they can't slow down or fail the import
+func (s *Service) importDone(ctx context.Context, userID uint, source string, collectionID uint, result *ImportResult) {
+	if s.hooks == nil {
+		return
+	}
+	s.hooks.Run(ctx, hooks.AfterImport, userID, importEvent{Source: source, CollectionID: collectionID, ImportResult: result})
+}
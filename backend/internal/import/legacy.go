@@ -0,0 +1,438 @@
+package import_export
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Legacy bookmark manager import sources
+const (
+	SourceShaarli  = "shaarli"
+	SourceWallabag = "wallabag"
+	SourceLinkding = "linkding"
+)
+
+// legacyParsers parse the exports of the supported legacy bookmark managers
+var legacyParsers = map[string]func(io.Reader) (*ImportFolder, error){
+	SourceShaarli:  ParseShaarli,
+	SourceWallabag: ParseWallabag,
+	SourceLinkding: ParseLinkding,
+}
+
+// Field mapping targets. A target is one of these or a "tag:" or
+// "collection:" prefix followed by a name
+const (
+	MappingMetadata   = "metadata" // keep the flag in the bookmark metadata
+	MappingIgnore     = "ignore"   // drop the flag
+	mappingTag        = "tag:"
+	mappingCollection = "collection:"
+)
+
+// ErrInvalidMapping is returned for a field mapping with an unknown target
+var ErrInvalidMapping = errors.New("invalid field mapping")
+
+// FieldMapping says what happens to the read state and flags of bookmarks
+// imported from a legacy bookmark manager, and renames imported tags. An
+// empty target keeps the flag in the bookmark metadata
+type FieldMapping struct {
+	Unread   string            `json:"unread,omitempty"`
+	Archived string            `json:"archived,omitempty"`
+	Starred  string            `json:"starred,omitempty"`
+	Tags     map[string]string `json:"tags,omitempty"` // source tag to new name; an empty name drops the tag
+}
+
+// Validate checks that every target of the mapping is known
+func (m FieldMapping) Validate() error {
+	for field, target := range map[string]string{"unread": m.Unread, "archived": m.Archived, "starred": m.Starred} {
+		switch {
+		case target == "", target == MappingMetadata, target == MappingIgnore:
+		case strings.HasPrefix(target, mappingTag) && strings.TrimSpace(target[len(mappingTag):]) != "":
+		case strings.HasPrefix(target, mappingCollection) && strings.TrimSpace(target[len(mappingCollection):]) != "":
+		default:
+			return fmt.Errorf("%w: %s target %q", ErrInvalidMapping, field, target)
+		}
+	}
+	return nil
+}
+
+// ParseLegacyImport parses an export of a legacy bookmark manager and
+// applies the field mapping to it
+func ParseLegacyImport(source string, reader io.Reader, mapping FieldMapping) (*ImportFolder, error) {
+	parse, ok := legacyParsers[source]
+	if !ok {
+		return nil, ErrUnknownSource
+	}
+	if err := mapping.Validate(); err != nil {
+		return nil, err
+	}
+
+	root, err := parse(reader)
+	if err != nil {
+		return nil, err
+	}
+	ApplyFieldMapping(root, mapping)
+	return root, nil
+}
+
+// ApplyFieldMapping turns the flags of the parsed bookmarks into tags or
+// collections as the mapping says and renames tags. Bookmarks mapped to a
+// collection move to a top-level folder of that name; a bookmark matching
+// several collection targets goes to the first of archived, unread, starred
+func ApplyFieldMapping(root *ImportFolder, mapping FieldMapping) {
+	folders := make(map[string]int)
+	for n, folder := range root.Folders {
+		folders[folder.Name] = n
+	}
+
+	var moved []struct {
+		name     string
+		bookmark ImportBookmark
+	}
+
+	var walk func(folder *ImportFolder)
+	walk = func(folder *ImportFolder) {
+		kept := folder.Bookmarks[:0]
+		for _, bookmark := range folder.Bookmarks {
+			bookmark.Tags = renameTags(bookmark.Tags, mapping.Tags)
+
+			collection := ""
+			apply := func(set *bool, target string) {
+				if !*set || target == "" || target == MappingMetadata {
+					return
+				}
+				switch {
+				case strings.HasPrefix(target, mappingTag):
+					bookmark.Tags = append(bookmark.Tags, strings.TrimSpace(target[len(mappingTag):]))
+				case strings.HasPrefix(target, mappingCollection):
+					if collection == "" {
+						collection = strings.TrimSpace(target[len(mappingCollection):])
+					}
+				}
+				*set = false
+			}
+			apply(&bookmark.Archived, mapping.Archived)
+			apply(&bookmark.Unread, mapping.Unread)
+			apply(&bookmark.Starred, mapping.Starred)
+
+			if collection != "" {
+				moved = append(moved, struct {
+					name     string
+					bookmark ImportBookmark
+				}{collection, bookmark})
+				continue
+			}
+			kept = append(kept, bookmark)
+		}
+		folder.Bookmarks = kept
+
+		for n := range folder.Folders {
+			walk(&folder.Folders[n])
+		}
+	}
+	walk(root)
+
+	for _, item := range moved {
+		n, ok := folders[item.name]
+		if !ok {
+			n = len(root.Folders)
+			folders[item.name] = n
+			root.Folders = append(root.Folders, ImportFolder{Name: item.name})
+		}
+		root.Folders[n].Bookmarks = append(root.Folders[n].Bookmarks, item.bookmark)
+	}
+}
+
+// renameTags applies tag renames, dropping tags renamed to nothing
+func renameTags(tags []string, renames map[string]string) []string {
+	if len(renames) == 0 {
+		return tags
+	}
+	renamed := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if name, ok := renames[tag]; ok {
+			tag = name
+		}
+		if tag != "" {
+			renamed = append(renamed, tag)
+		}
+	}
+	return renamed
+}
+
+// shaarliLink is a link in a Shaarli API or JSON export
+type shaarliLink struct {
+	URL         string   `json:"url"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+	Created     string   `json:"created"`
+}
+
+// ParseShaarli parses a Shaarli export, either the JSON list of links
+// returned by its API or the Netscape HTML export
+func ParseShaarli(reader io.Reader) (*ImportFolder, error) {
+	buffered := bufio.NewReader(reader)
+	if !isJSONArray(buffered) {
+		return parseNetscapeExport(buffered)
+	}
+
+	var links []shaarliLink
+	if err := json.NewDecoder(buffered).Decode(&links); err != nil {
+		return nil, fmt.Errorf("failed to parse Shaarli links: %w", err)
+	}
+
+	root := &ImportFolder{}
+	for _, link := range links {
+		if link.URL == "" {
+			continue
+		}
+		root.Bookmarks = append(root.Bookmarks, ImportBookmark{
+			URL:         link.URL,
+			Title:       titleOrURL(link.Title, link.URL),
+			Description: link.Description,
+			AddedAt:     parseLegacyTime(link.Created),
+			Tags:        link.Tags,
+		})
+	}
+	return root, nil
+}
+
+// wallabagEntry is an entry in a Wallabag JSON export or API response
+type wallabagEntry struct {
+	URL        string       `json:"url"`
+	Title      string       `json:"title"`
+	IsArchived flexBool     `json:"is_archived"`
+	IsStarred  flexBool     `json:"is_starred"`
+	Tags       wallabagTags `json:"tags"`
+	CreatedAt  string       `json:"created_at"`
+}
+
+// ParseWallabag parses a Wallabag JSON export, or an entries API response.
+// Wallabag archives entries once read, so entries that are not archived are
+// imported as unread
+func ParseWallabag(reader io.Reader) (*ImportFolder, error) {
+	buffered := bufio.NewReader(reader)
+
+	var entries []wallabagEntry
+	if isJSONArray(buffered) {
+		if err := json.NewDecoder(buffered).Decode(&entries); err != nil {
+			return nil, fmt.Errorf("failed to parse Wallabag export: %w", err)
+		}
+	} else {
+		var page struct {
+			Embedded struct {
+				Items []wallabagEntry `json:"items"`
+			} `json:"_embedded"`
+		}
+		if err := json.NewDecoder(buffered).Decode(&page); err != nil {
+			return nil, fmt.Errorf("failed to parse Wallabag export: %w", err)
+		}
+		entries = page.Embedded.Items
+	}
+
+	root := &ImportFolder{}
+	for _, entry := range entries {
+		if entry.URL == "" {
+			continue
+		}
+		root.Bookmarks = append(root.Bookmarks, ImportBookmark{
+			URL:      entry.URL,
+			Title:    titleOrURL(entry.Title, entry.URL),
+			AddedAt:  parseLegacyTime(entry.CreatedAt),
+			Tags:     entry.Tags,
+			Unread:   !bool(entry.IsArchived),
+			Archived: bool(entry.IsArchived),
+			Starred:  bool(entry.IsStarred),
+		})
+	}
+	return root, nil
+}
+
+// linkdingBookmark is a bookmark in a Linkding API response
+type linkdingBookmark struct {
+	URL         string   `json:"url"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Notes       string   `json:"notes"`
+	TagNames    []string `json:"tag_names"`
+	IsArchived  bool     `json:"is_archived"`
+	Unread      bool     `json:"unread"`
+	DateAdded   string   `json:"date_added"`
+}
+
+// ParseLinkding parses Linkding bookmarks, either a bookmarks API response,
+// a JSON list of API bookmarks or the Netscape HTML export
+func ParseLinkding(reader io.Reader) (*ImportFolder, error) {
+	buffered := bufio.NewReader(reader)
+	if !isJSON(buffered) {
+		return parseNetscapeExport(buffered)
+	}
+
+	var bookmarks []linkdingBookmark
+	if isJSONArray(buffered) {
+		if err := json.NewDecoder(buffered).Decode(&bookmarks); err != nil {
+			return nil, fmt.Errorf("failed to parse Linkding bookmarks: %w", err)
+		}
+	} else {
+		var page struct {
+			Results []linkdingBookmark `json:"results"`
+		}
+		if err := json.NewDecoder(buffered).Decode(&page); err != nil {
+			return nil, fmt.Errorf("failed to parse Linkding bookmarks: %w", err)
+		}
+		bookmarks = page.Results
+	}
+
+	root := &ImportFolder{}
+	for _, bookmark := range bookmarks {
+		if bookmark.URL == "" {
+			continue
+		}
+		root.Bookmarks = append(root.Bookmarks, ImportBookmark{
+			URL:         bookmark.URL,
+			Title:       titleOrURL(bookmark.Title, bookmark.URL),
+			Description: bookmark.Description,
+			Notes:       bookmark.Notes,
+			AddedAt:     parseLegacyTime(bookmark.DateAdded),
+			Tags:        bookmark.TagNames,
+			Unread:      bookmark.Unread,
+			Archived:    bookmark.IsArchived,
+		})
+	}
+	return root, nil
+}
+
+// parseNetscapeExport parses the flat Netscape bookmark files exported by
+// Shaarli and Linkding. Tags come from the TAGS attribute, the read state
+// from TOREAD, and the description from the <DD> following each link
+func parseNetscapeExport(reader io.Reader) (*ImportFolder, error) {
+	doc, err := goquery.NewDocumentFromReader(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse bookmark file: %w", err)
+	}
+
+	root := &ImportFolder{}
+	doc.Find("a[href]").Each(func(_ int, link *goquery.Selection) {
+		href, _ := link.Attr("href")
+		if href == "" {
+			return
+		}
+
+		bookmark := ImportBookmark{
+			URL:         href,
+			Title:       titleOrURL(strings.TrimSpace(link.Text()), href),
+			Description: strings.TrimSpace(link.ParentFiltered("dt").Next().Filter("dd").Text()),
+			Unread:      link.AttrOr("toread", "") == "1",
+		}
+		if added, err := strconv.ParseInt(link.AttrOr("add_date", ""), 10, 64); err == nil && added > 0 {
+			t := time.Unix(added, 0).UTC()
+			bookmark.AddedAt = &t
+		}
+		for _, tag := range strings.Split(link.AttrOr("tags", ""), ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				bookmark.Tags = append(bookmark.Tags, tag)
+			}
+		}
+		root.Bookmarks = append(root.Bookmarks, bookmark)
+	})
+	return root, nil
+}
+
+// isJSON reports whether buffered input starts with a JSON object or array
+func isJSON(reader *bufio.Reader) bool {
+	first := firstByte(reader)
+	return first == '{' || first == '['
+}
+
+// isJSONArray reports whether buffered input starts with a JSON array
+func isJSONArray(reader *bufio.Reader) bool {
+	return firstByte(reader) == '['
+}
+
+// firstByte peeks at the first byte of buffered input after whitespace,
+// which stays unread
+func firstByte(reader *bufio.Reader) byte {
+	for size := 64; ; size *= 2 {
+		data, err := reader.Peek(size)
+		if trimmed := bytes.TrimLeft(data, " \t\r\n\ufeff"); len(trimmed) > 0 {
+			return trimmed[0]
+		}
+		if err != nil || size >= reader.Size() {
+			return 0
+		}
+	}
+}
+
+// legacyTimeLayouts are the timestamp formats used by legacy exports.
+// Wallabag leaves the colon out of the zone offset
+var legacyTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05-0700",
+	"2006-01-02 15:04:05",
+}
+
+// parseLegacyTime parses an exported timestamp, nil when it has no known format
+func parseLegacyTime(value string) *time.Time {
+	for _, layout := range legacyTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			t = t.UTC()
+			return &t
+		}
+	}
+	return nil
+}
+
+// titleOrURL falls back to the URL for untitled bookmarks
+func titleOrURL(title, url string) string {
+	if strings.TrimSpace(title) == "" {
+		return url
+	}
+	return title
+}
+
+// flexBool decodes the 0/1 integers Wallabag uses for flags as well as booleans
+type flexBool bool
+
+func (b *flexBool) UnmarshalJSON(data []byte) error {
+	switch strings.Trim(string(data), `"`) {
+	case "true", "1":
+		*b = true
+	case "false", "0", "null", "":
+		*b = false
+	default:
+		return fmt.Errorf("invalid flag value %s", data)
+	}
+	return nil
+}
+
+// wallabagTags decodes the tag labels of exports and the tag objects of the API
+type wallabagTags []string
+
+func (t *wallabagTags) UnmarshalJSON(data []byte) error {
+	var labels []string
+	if err := json.Unmarshal(data, &labels); err == nil {
+		*t = labels
+		return nil
+	}
+
+	var objects []struct {
+		Label string `json:"label"`
+	}
+	if err := json.Unmarshal(data, &objects); err != nil {
+		return err
+	}
+	*t = make(wallabagTags, 0, len(objects))
+	for _, object := range objects {
+		*t = append(*t, object.Label)
+	}
+	return nil
+}
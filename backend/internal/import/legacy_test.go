@@ -0,0 +1,216 @@
+package import_export
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"bookmark-sync-service/backend/pkg/database"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const shaarliJSON = `[
+	{"id": 1, "url": "https://go.dev/", "title": "Go", "description": "The Go site", "tags": ["golang", "dev"], "private": false, "created": "2020-03-01T10:00:00+01:00"},
+	{"id": 2, "url": "https://example.com/", "title": "", "tags": [], "created": "bad"}
+]`
+
+const netscapeExport = `<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=UTF-8">
+<TITLE>Bookmarks</TITLE>
+<H1>Bookmarks</H1>
+<DL><p>
+<DT><A HREF="https://go.dev/" ADD_DATE="1583053200" PRIVATE="0" TOREAD="1" TAGS="golang,dev">Go</A>
+<DD>The Go site
+<DT><A HREF="https://example.com/" ADD_DATE="1583053300" TAGS="">Example</A>
+</DL><p>`
+
+const wallabagExport = `[
+	{"id": 1, "title": "Read article", "url": "https://example.com/read", "is_archived": 1, "is_starred": 0, "tags": ["news"], "created_at": "2020-03-01T10:00:00+0100"},
+	{"id": 2, "title": "Later", "url": "https://example.com/later", "is_archived": 0, "is_starred": 1, "tags": []}
+]`
+
+const wallabagAPI = `{"page": 1, "_embedded": {"items": [
+	{"id": 1, "title": "Read article", "url": "https://example.com/read", "is_archived": true, "is_starred": false, "tags": [{"id": 3, "label": "news", "slug": "news"}]}
+]}}`
+
+const linkdingAPI = `{"count": 2, "next": null, "results": [
+	{"id": 1, "url": "https://go.dev/", "title": "Go", "description": "The Go site", "notes": "Read the tour", "tag_names": ["golang"], "is_archived": false, "unread": true, "date_added": "2020-03-01T09:00:00.123456Z"},
+	{"id": 2, "url": "https://example.com/", "title": "Example", "tag_names": [], "is_archived": true, "unread": false}
+]}`
+
+func TestParseShaarli(t *testing.T) {
+	root, err := ParseShaarli(strings.NewReader(shaarliJSON))
+	require.NoError(t, err)
+	require.Len(t, root.Bookmarks, 2)
+	assert.Equal(t, "Go", root.Bookmarks[0].Title)
+	assert.Equal(t, "The Go site", root.Bookmarks[0].Description)
+	assert.Equal(t, []string{"golang", "dev"}, root.Bookmarks[0].Tags)
+	require.NotNil(t, root.Bookmarks[0].AddedAt)
+	assert.Equal(t, time.Date(2020, 3, 1, 9, 0, 0, 0, time.UTC), *root.Bookmarks[0].AddedAt)
+	assert.Equal(t, "https://example.com/", root.Bookmarks[1].Title, "untitled links fall back to the URL")
+	assert.Nil(t, root.Bookmarks[1].AddedAt)
+
+	root, err = ParseShaarli(strings.NewReader(netscapeExport))
+	require.NoError(t, err)
+	require.Len(t, root.Bookmarks, 2)
+	assert.Equal(t, "https://go.dev/", root.Bookmarks[0].URL)
+	assert.Equal(t, "The Go site", root.Bookmarks[0].Description)
+	assert.Equal(t, []string{"golang", "dev"}, root.Bookmarks[0].Tags)
+	assert.True(t, root.Bookmarks[0].Unread)
+	assert.Equal(t, time.Unix(1583053200, 0).UTC(), *root.Bookmarks[0].AddedAt)
+	assert.Empty(t, root.Bookmarks[1].Description)
+	assert.Empty(t, root.Bookmarks[1].Tags)
+}
+
+func TestParseWallabag(t *testing.T) {
+	root, err := ParseWallabag(strings.NewReader(wallabagExport))
+	require.NoError(t, err)
+	require.Len(t, root.Bookmarks, 2)
+
+	read := root.Bookmarks[0]
+	assert.True(t, read.Archived)
+	assert.False(t, read.Unread)
+	assert.Equal(t, []string{"news"}, read.Tags)
+	require.NotNil(t, read.AddedAt)
+	assert.Equal(t, time.Date(2020, 3, 1, 9, 0, 0, 0, time.UTC), *read.AddedAt)
+
+	later := root.Bookmarks[1]
+	assert.True(t, later.Unread)
+	assert.True(t, later.Starred)
+
+	root, err = ParseWallabag(strings.NewReader(wallabagAPI))
+	require.NoError(t, err)
+	require.Len(t, root.Bookmarks, 1)
+	assert.True(t, root.Bookmarks[0].Archived)
+	assert.Equal(t, []string{"news"}, root.Bookmarks[0].Tags)
+
+	_, err = ParseWallabag(strings.NewReader(`[{"url": "https://example.com/", "is_archived": "maybe"}]`))
+	assert.Error(t, err)
+}
+
+func TestParseLinkding(t *testing.T) {
+	root, err := ParseLinkding(strings.NewReader(linkdingAPI))
+	require.NoError(t, err)
+	require.Len(t, root.Bookmarks, 2)
+	assert.True(t, root.Bookmarks[0].Unread)
+	assert.Equal(t, "Read the tour", root.Bookmarks[0].Notes)
+	assert.Equal(t, []string{"golang"}, root.Bookmarks[0].Tags)
+	require.NotNil(t, root.Bookmarks[0].AddedAt)
+	assert.True(t, root.Bookmarks[1].Archived)
+
+	root, err = ParseLinkding(strings.NewReader(netscapeExport))
+	require.NoError(t, err)
+	assert.Len(t, root.Bookmarks, 2)
+}
+
+func TestApplyFieldMapping(t *testing.T) {
+	root, err := ParseWallabag(strings.NewReader(wallabagExport))
+	require.NoError(t, err)
+
+	ApplyFieldMapping(root, FieldMapping{
+		Unread:   "tag:to-read",
+		Archived: "collection:Archive",
+		Starred:  MappingMetadata,
+		Tags:     map[string]string{"news": "press"},
+	})
+
+	require.Len(t, root.Bookmarks, 1)
+	later := root.Bookmarks[0]
+	assert.Equal(t, []string{"to-read"}, later.Tags)
+	assert.False(t, later.Unread, "mapped flags are cleared")
+	assert.True(t, later.Starred, "metadata flags are kept")
+
+	require.Len(t, root.Folders, 1)
+	assert.Equal(t, "Archive", root.Folders[0].Name)
+	require.Len(t, root.Folders[0].Bookmarks, 1)
+	assert.Equal(t, []string{"press"}, root.Folders[0].Bookmarks[0].Tags)
+	assert.False(t, root.Folders[0].Bookmarks[0].Archived)
+
+	assert.ErrorIs(t, FieldMapping{Unread: "folder:Later"}.Validate(), ErrInvalidMapping)
+	assert.ErrorIs(t, FieldMapping{Archived: "tag: "}.Validate(), ErrInvalidMapping)
+	assert.NoError(t, FieldMapping{Unread: MappingIgnore, Archived: "collection:Done"}.Validate())
+}
+
+func TestService_CommitLegacyImport(t *testing.T) {
+	db, err := database.SetupTestDB()
+	require.NoError(t, err)
+	defer database.CleanupTestDB(db)
+
+	service := NewService(db)
+	require.NoError(t, db.Create(&database.User{BaseModel: database.BaseModel{ID: 1}, Email: "test@example.com", Username: "testuser", SupabaseID: "test-supabase-id"}).Error)
+
+	root, err := ParseLegacyImport(SourceLinkding, strings.NewReader(linkdingAPI), FieldMapping{Archived: "collection:Archive"})
+	require.NoError(t, err)
+
+	result, err := service.CommitImport(context.Background(), 1, SourceLinkding, root)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.ImportedBookmarksCount)
+	assert.Equal(t, 1, result.ImportedCollectionsCount)
+
+	var unread database.Bookmark
+	require.NoError(t, db.Where("url = ?", "https://go.dev/").First(&unread).Error)
+	assert.Equal(t, "The Go site", unread.Description)
+	assert.Equal(t, "Read the tour", unread.Notes)
+	assert.JSONEq(t, `{"unread": true}`, unread.Metadata)
+
+	var archived database.Bookmark
+	require.NoError(t, db.Preload("Collections").Where("url = ?", "https://example.com/").First(&archived).Error)
+	assert.Contains(t, archived.Description, "Imported from Linkding")
+	assert.Empty(t, archived.Metadata)
+	require.Len(t, archived.Collections, 1)
+	assert.Equal(t, "Archive", archived.Collections[0].Name)
+}
+
+func TestHandlers_ImportFromLegacy(t *testing.T) {
+	router, _ := setupTestRouter()
+
+	upload := func(source, filename, content, mapping string) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		writer := multipart.NewWriter(&buf)
+		if mapping != "" {
+			require.NoError(t, writer.WriteField("mapping", mapping))
+		}
+		part, err := writer.CreateFormFile("file", filename)
+		require.NoError(t, err)
+		_, err = part.Write([]byte(content))
+		require.NoError(t, err)
+		require.NoError(t, writer.Close())
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/import-export/import/legacy/"+source+"?preview=true", &buf)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := upload(SourceWallabag, "wallabag.json", wallabagExport, `{"unread": "tag:to-read"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Data ImportPreview `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, SourceWallabag, response.Data.Source)
+	assert.Equal(t, 2, response.Data.BookmarksCount)
+	assert.Contains(t, response.Data.Root.Bookmarks[1].Tags, "to-read")
+
+	w = upload(SourceShaarli, "shaarli.html", netscapeExport, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = upload("delicious", "export.json", "[]", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = upload(SourceLinkding, "bookmarks.json", linkdingAPI, `{"unread": "shelf"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "INVALID_MAPPING")
+
+	w = upload(SourceLinkding, "bookmarks.txt", linkdingAPI, "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
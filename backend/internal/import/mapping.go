@@ -0,0 +1,266 @@
+package import_export
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm/clause"
+
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+// Reasons a folder was matched to an existing collection
+const (
+	MatchRemembered = "remembered" // the collection the folder went into on an earlier import from the source
+	MatchPath       = "path"       // a collection of the same name at the same place in the tree
+	MatchName       = "name"       // a collection of a similar name at the same place in the tree
+)
+
+// nameMatchThreshold is the lowest name similarity at which a folder is
+// matched to an existing collection
+const nameMatchThreshold = 0.8
+
+// FolderMatch is the existing collection an import folder is merged into
+type FolderMatch struct {
+	CollectionID   uint    `json:"collection_id"`
+	CollectionName string  `json:"collection_name,omitempty"`
+	Reason         string  `json:"reason,omitempty"`
+	Score          float64 `json:"score,omitempty"` // name similarity from 0 to 1
+}
+
+// FolderMappingView is a remembered folder mapping of an import source
+type FolderMappingView struct {
+	Path         []string  `json:"path"`
+	CollectionID uint      `json:"collection_id"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// folderPathKey is the stored form of a folder path, which keeps names
+// holding separators apart
+func folderPathKey(path []string) string {
+	data, _ := json.Marshal(path)
+	return string(data)
+}
+
+// normalizeFolderName lowercases a name and reduces it to its words, so
+// "Dev-Tools" and "dev tools" are the same
+func normalizeFolderName(name string) string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, " ")
+}
+
+// nameSimilarity is one less the edit distance between two names relative
+// to the longer one
+func nameSimilarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 0
+	}
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return 1 - float64(previous[len(rb)])/float64(longest)
+}
+
+// folderMatcher matches import folders against the user's collections
+type folderMatcher struct {
+	collections map[uint]database.Collection
+	children    map[uint][]database.Collection // by parent ID, 0 for top-level
+	remembered  map[string]uint                // collection IDs by folder path key
+}
+
+// matchFolders sets the match of each folder of an import at the top level
+// to the collection it was remembered in for the source or, under a parent
+// that exists already, the collection of the closest name. Folders without
+// a match are created
+func (s *Service) matchFolders(ctx context.Context, userID uint, source string, root *ImportFolder) error {
+	var collections []database.Collection
+	if err := s.db.WithContext(ctx).Select("id", "name", "parent_id").Where("user_id = ?", userID).Find(&collections).Error; err != nil {
+		return err
+	}
+	var mappings []database.ImportFolderMapping
+	if err := s.db.WithContext(ctx).Where("user_id = ? AND source = ?", userID, source).Find(&mappings).Error; err != nil {
+		return err
+	}
+
+	matcher := &folderMatcher{
+		collections: make(map[uint]database.Collection, len(collections)),
+		children:    make(map[uint][]database.Collection),
+		remembered:  make(map[string]uint, len(mappings)),
+	}
+	for _, collection := range collections {
+		matcher.collections[collection.ID] = collection
+		var parentID uint
+		if collection.ParentID != nil {
+			parentID = *collection.ParentID
+		}
+		matcher.children[parentID] = append(matcher.children[parentID], collection)
+	}
+	for _, mapping := range mappings {
+		matcher.remembered[mapping.Path] = mapping.CollectionID
+	}
+
+	matcher.match(root.Folders, nil, 0, true)
+	return nil
+}
+
+// match matches folders under a parent collection, which doesn't exist yet
+// when the parent folder is created by the import
+func (m *folderMatcher) match(folders []ImportFolder, path []string, parentID uint, parentExists bool) {
+	for n := range folders {
+		folder := &folders[n]
+		folderPath := append(path[:len(path):len(path)], folder.Name)
+		folder.Match = m.find(folderPath, parentID, parentExists)
+		if folder.Match != nil {
+			m.match(folder.Folders, folderPath, folder.Match.CollectionID, true)
+		} else {
+			m.match(folder.Folders, folderPath, 0, false)
+		}
+	}
+}
+
+func (m *folderMatcher) find(path []string, parentID uint, parentExists bool) *FolderMatch {
+	// Remembered collections may have been deleted since
+	if id, ok := m.remembered[folderPathKey(path)]; ok {
+		if collection, ok := m.collections[id]; ok {
+			return &FolderMatch{CollectionID: id, CollectionName: collection.Name, Reason: MatchRemembered, Score: 1}
+		}
+	}
+	if !parentExists {
+		return nil
+	}
+
+	name := normalizeFolderName(path[len(path)-1])
+	var best *FolderMatch
+	for _, collection := range m.children[parentID] {
+		score := nameSimilarity(name, normalizeFolderName(collection.Name))
+		if score < nameMatchThreshold || (best != nil && score <= best.Score) {
+			continue
+		}
+		reason := MatchName
+		if score == 1 {
+			reason = MatchPath
+		}
+		best = &FolderMatch{CollectionID: collection.ID, CollectionName: collection.Name, Reason: reason, Score: score}
+	}
+	return best
+}
+
+// checkMatches makes sure the collections folders are matched to, which
+// clients may have changed, are the user's
+func (s *Service) checkMatches(ctx context.Context, userID uint, root *ImportFolder) error {
+	ids := make(map[uint]bool)
+	var collect func(folders []ImportFolder)
+	collect = func(folders []ImportFolder) {
+		for _, folder := range folders {
+			if folder.Match != nil {
+				ids[folder.Match.CollectionID] = true
+			}
+			collect(folder.Folders)
+		}
+	}
+	collect(root.Folders)
+	if len(ids) == 0 {
+		return nil
+	}
+
+	list := make([]uint, 0, len(ids))
+	for id := range ids {
+		list = append(list, id)
+	}
+	var owned int64
+	if err := s.db.WithContext(ctx).Model(&database.Collection{}).Where("id IN ? AND user_id = ?", list, userID).Count(&owned).Error; err != nil {
+		return err
+	}
+	if int(owned) != len(list) {
+		return ErrCollectionNotFound
+	}
+	return nil
+}
+
+// rememberFolders saves the collection each folder of a committed import
+// went into for the next import from the source
+func (s *Service) rememberFolders(ctx context.Context, userID uint, source string, root *ImportFolder) error {
+	now := time.Now()
+	var mappings []database.ImportFolderMapping
+	var collect func(folders []ImportFolder, path []string)
+	collect = func(folders []ImportFolder, path []string) {
+		for _, folder := range folders {
+			folderPath := append(path[:len(path):len(path)], folder.Name)
+			if folder.Match != nil {
+				mappings = append(mappings, database.ImportFolderMapping{
+					UserID:       userID,
+					Source:       source,
+					Path:         folderPathKey(folderPath),
+					CollectionID: folder.Match.CollectionID,
+					UpdatedAt:    now,
+				})
+			}
+			collect(folder.Folders, folderPath)
+		}
+	}
+	collect(root.Folders, nil)
+	if len(mappings) == 0 {
+		return nil
+	}
+
+	return s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "source"}, {Name: "path"}},
+		DoUpdates: clause.AssignmentColumns([]string{"collection_id", "updated_at"}),
+	}).CreateInBatches(mappings, 100).Error
+}
+
+// FolderMappings lists the remembered folder mappings of an import source
+func (s *Service) FolderMappings(ctx context.Context, userID uint, source string) ([]FolderMappingView, error) {
+	if _, ok := sourceNames[source]; !ok {
+		return nil, ErrUnknownSource
+	}
+	var mappings []database.ImportFolderMapping
+	if err := s.db.WithContext(ctx).Where("user_id = ? AND source = ?", userID, source).Find(&mappings).Error; err != nil {
+		return nil, err
+	}
+
+	views := make([]FolderMappingView, 0, len(mappings))
+	for _, mapping := range mappings {
+		var path []string
+		if err := json.Unmarshal([]byte(mapping.Path), &path); err != nil {
+			return nil, fmt.Errorf("invalid folder path %q: %w", mapping.Path, err)
+		}
+		views = append(views, FolderMappingView{Path: path, CollectionID: mapping.CollectionID, UpdatedAt: mapping.UpdatedAt})
+	}
+	// Parents come before their children
+	slices.SortFunc(views, func(a, b FolderMappingView) int {
+		return slices.Compare(a.Path, b.Path)
+	})
+	return views, nil
+}
+
+// ForgetFolderMappings drops the remembered folder mappings of an import
+// source, so the next import matches folders by name again
+func (s *Service) ForgetFolderMappings(ctx context.Context, userID uint, source string) error {
+	if _, ok := sourceNames[source]; !ok {
+		return ErrUnknownSource
+	}
+	return s.db.WithContext(ctx).Where("user_id = ? AND source = ?", userID, source).Delete(&database.ImportFolderMapping{}).Error
+}
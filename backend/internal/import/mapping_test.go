@@ -0,0 +1,115 @@
+package import_export
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+func TestNameSimilarity(t *testing.T) {
+	assert.Equal(t, "dev tools", normalizeFolderName("  Dev-Tools!"))
+	assert.Equal(t, 1.0, nameSimilarity("dev tools", "dev tools"))
+	assert.InDelta(t, 0.89, nameSimilarity("dev tools", "dev tool"), 0.01)
+	assert.Less(t, nameSimilarity("recipes", "reading"), nameMatchThreshold)
+	assert.Zero(t, nameSimilarity("", ""))
+}
+
+func TestService_ImportFolderMatching(t *testing.T) {
+	db, err := database.SetupTestDB()
+	require.NoError(t, err)
+	defer database.CleanupTestDB(db)
+
+	service := NewService(db)
+	ctx := context.Background()
+	require.NoError(t, db.Create(&database.User{BaseModel: database.BaseModel{ID: 1}, Email: "test@example.com", Username: "testuser", SupabaseID: "test-supabase-id"}).Error)
+	require.NoError(t, db.Create(&database.User{BaseModel: database.BaseModel{ID: 2}, Email: "other@example.com", Username: "other", SupabaseID: "other"}).Error)
+
+	collection := func(userID uint, name string, parent *database.Collection) *database.Collection {
+		created := &database.Collection{UserID: userID, Name: name, Visibility: "private", ShareLink: name + "-link"}
+		if parent != nil {
+			created.ParentID = &parent.ID
+		}
+		require.NoError(t, db.Create(created).Error)
+		return created
+	}
+	dev := collection(1, "Dev Tools", nil)
+	golang := collection(1, "Golang", dev)
+	collection(1, "Go", nil) // same name, wrong place
+	foreign := collection(2, "Foreign", nil)
+
+	root := &ImportFolder{Folders: []ImportFolder{
+		{Name: "dev-tools", Folders: []ImportFolder{
+			{Name: "golang", Bookmarks: []ImportBookmark{{URL: "https://go.dev/", Title: "Go"}}},
+			{Name: "Rust"},
+		}},
+		{Name: "Recipes", Folders: []ImportFolder{{Name: "Go"}}},
+	}}
+
+	preview, err := service.PreviewImport(ctx, 1, SourceChrome, root)
+	require.NoError(t, err)
+	assert.Equal(t, 2, preview.MatchedCount)
+	assert.Equal(t, 3, preview.CollectionsCount)
+	assert.Nil(t, root.Folders[0].Match, "preview must not modify the parsed tree")
+
+	matched := preview.Root.Folders[0]
+	require.NotNil(t, matched.Match)
+	assert.Equal(t, dev.ID, matched.Match.CollectionID)
+	assert.Equal(t, MatchPath, matched.Match.Reason)
+	require.NotNil(t, matched.Folders[0].Match)
+	assert.Equal(t, golang.ID, matched.Folders[0].Match.CollectionID)
+	assert.Nil(t, matched.Folders[1].Match)
+	assert.Nil(t, preview.Root.Folders[1].Match)
+	assert.Nil(t, preview.Root.Folders[1].Folders[0].Match, "folders under new collections are created too")
+
+	// Collections of other users can't be chosen
+	tampered := preview.Root
+	tampered.Folders = append([]ImportFolder(nil), preview.Root.Folders...)
+	tampered.Folders[1].Match = &FolderMatch{CollectionID: foreign.ID}
+	_, err = service.CommitImportInto(ctx, 1, SourceChrome, &tampered, 0)
+	assert.ErrorIs(t, err, ErrCollectionNotFound)
+
+	// The client keeps Rust apart from the matched parent
+	root = &preview.Root
+	root.Folders[0].Folders[1].Match = nil
+	result, err := service.CommitImportInto(ctx, 1, SourceChrome, root, 0)
+	require.NoError(t, err)
+	assert.Empty(t, result.Errors)
+	assert.Equal(t, 2, result.MatchedCollectionsCount)
+	assert.Equal(t, 3, result.ImportedCollectionsCount)
+
+	var imported database.Bookmark
+	require.NoError(t, db.Preload("Collections").Where("url = ?", "https://go.dev/").First(&imported).Error)
+	require.Len(t, imported.Collections, 1)
+	assert.Equal(t, golang.ID, imported.Collections[0].ID)
+
+	// Repeat imports from the source follow the collections, even renamed
+	var recipes database.Collection
+	require.NoError(t, db.Where("user_id = ? AND name = ?", 1, "Recipes").First(&recipes).Error)
+	require.NoError(t, db.Model(&recipes).Update("name", "Cooking").Error)
+	preview, err = service.PreviewImport(ctx, 1, SourceChrome, &ImportFolder{Folders: []ImportFolder{{Name: "Recipes"}}})
+	require.NoError(t, err)
+	require.NotNil(t, preview.Root.Folders[0].Match)
+	assert.Equal(t, recipes.ID, preview.Root.Folders[0].Match.CollectionID)
+	assert.Equal(t, MatchRemembered, preview.Root.Folders[0].Match.Reason)
+
+	preview, err = service.PreviewImport(ctx, 1, SourceFirefox, &ImportFolder{Folders: []ImportFolder{{Name: "Recipes"}}})
+	require.NoError(t, err)
+	assert.Nil(t, preview.Root.Folders[0].Match, "mappings are remembered per source")
+
+	mappings, err := service.FolderMappings(ctx, 1, SourceChrome)
+	require.NoError(t, err)
+	assert.Len(t, mappings, 5)
+	assert.Equal(t, []string{"Recipes"}, mappings[0].Path)
+	assert.Equal(t, recipes.ID, mappings[0].CollectionID)
+
+	require.NoError(t, service.ForgetFolderMappings(ctx, 1, SourceChrome))
+	mappings, err = service.FolderMappings(ctx, 1, SourceChrome)
+	require.NoError(t, err)
+	assert.Empty(t, mappings)
+	_, err = service.FolderMappings(ctx, 1, "opera")
+	assert.ErrorIs(t, err, ErrUnknownSource)
+}
@@ -2,29 +2,59 @@ package import_export
 
 import (
 	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/exporter"
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
 	"net/url"
 	"strings"
 	"time"
 
+	"github.com/google/uuid"
+	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
 // Service provides import/export functionality
 type Service struct {
-	db *gorm.DB
+	db       *gorm.DB
+	indexer  SearchIndexer
+	watcher  IndexWatcher
+	urlRules URLRules
+	hooks    Hooks
+
+	screenshots ScreenshotStore
+
+	logger *zap.Logger
+}
+
+// SearchIndexer queues imported bookmarks and collections for batched
+// search indexing
+type SearchIndexer interface {
+	QueueBookmark(bookmark *database.Bookmark)
+	QueueCollection(collection *database.Collection)
+}
+
+// IndexWatcher tells the user once the bookmarks of an import are all
+// searchable, returning the ID its event will carry
+type IndexWatcher interface {
+	WatchImport(userID uint, ids []uint) string
 }
 
 // ImportResult represents the result of an import operation
 type ImportResult struct {
 	ImportedBookmarksCount   int      `json:"imported_bookmarks_count"`
 	ImportedCollectionsCount int      `json:"imported_collections_count"`
+	MatchedCollectionsCount  int      `json:"matched_collections_count"` // existing collections folders were merged into
 	DuplicatesSkipped        int      `json:"duplicates_skipped"`
 	Errors                   []string `json:"errors"`
 	ProcessingTimeMs         int64    `json:"processing_time_ms"`
+	// BatchID is the source ref of the imported bookmarks, so the batch
+	// can be listed with ?source=import and found again
+	BatchID string `json:"batch_id"`
+	// IndexJobID identifies the index complete event pushed once the
+	// imported bookmarks are searchable; empty when none will be
+	IndexJobID string `json:"index_job_id,omitempty"`
 }
 
 // ImportProgress represents the progress of an import operation
@@ -64,116 +94,31 @@ type ChromeBookmarkFile struct {
 // NewService creates a new import/export service
 func NewService(db *gorm.DB) *Service {
 	return &Service{
-		db: db,
+		db:     db,
+		logger: zap.NewNop(),
 	}
 }
 
-// ImportBookmarksFromChrome imports bookmarks from Chrome format
-func (s *Service) ImportBookmarksFromChrome(ctx context.Context, userID uint, reader io.Reader) (*ImportResult, error) {
-	startTime := time.Now()
-	result := &ImportResult{
-		Errors: make([]string, 0),
-	}
-
-	// Parse Chrome bookmarks JSON
-	var chromeFile ChromeBookmarkFile
-	decoder := json.NewDecoder(reader)
-	if err := decoder.Decode(&chromeFile); err != nil {
-		return nil, fmt.Errorf("failed to parse Chrome bookmarks: %w", err)
-	}
-
-	// Process bookmark bar
-	if err := s.processChromeFolderRecursive(ctx, userID, &chromeFile.Roots.BookmarkBar, nil, result); err != nil {
-		result.Errors = append(result.Errors, fmt.Sprintf("Error processing bookmark bar: %v", err))
-	}
-
-	// Process other bookmarks
-	if err := s.processChromeFolderRecursive(ctx, userID, &chromeFile.Roots.Other, nil, result); err != nil {
-		result.Errors = append(result.Errors, fmt.Sprintf("Error processing other bookmarks: %v", err))
-	}
-
-	result.ProcessingTimeMs = time.Since(startTime).Milliseconds()
-	return result, nil
+// SetLogger logs the parts of an import that fail without failing it
+func (s *Service) SetLogger(logger *zap.Logger) {
+	s.logger = logger
 }
 
-// processChromeFolderRecursive processes Chrome bookmark folders recursively
-func (s *Service) processChromeFolderRecursive(ctx context.Context, userID uint, folder *ChromeBookmark, parentCollection *database.Collection, result *ImportResult) error {
-	var currentCollection *database.Collection
-
-	// Create collection for folder (except root folders)
-	if folder.Type == "folder" && folder.Name != "Bookmarks bar" && folder.Name != "Other bookmarks" {
-		collection := &database.Collection{
-			UserID:      userID,
-			Name:        folder.Name,
-			Description: fmt.Sprintf("Imported from Chrome on %s", time.Now().Format("2006-01-02")),
-			Visibility:  "private",
-		}
-
-		if parentCollection != nil {
-			collection.ParentID = &parentCollection.ID
-		}
-
-		if err := s.db.Create(collection).Error; err != nil {
-			return fmt.Errorf("failed to create collection: %w", err)
-		}
-
-		currentCollection = collection
-		result.ImportedCollectionsCount++
-	}
-
-	// Process children
-	for _, child := range folder.Children {
-		if child.Type == "url" {
-			// Check for duplicates
-			isDuplicate, err := s.DetectDuplicate(ctx, userID, child.URL)
-			if err != nil {
-				result.Errors = append(result.Errors, fmt.Sprintf("Error checking duplicate for %s: %v", child.URL, err))
-				continue
-			}
-
-			if isDuplicate {
-				result.DuplicatesSkipped++
-				continue
-			}
-
-			// Create bookmark
-			bookmark := &database.Bookmark{
-				UserID:      userID,
-				URL:         child.URL,
-				Title:       child.Name,
-				Description: fmt.Sprintf("Imported from Chrome on %s", time.Now().Format("2006-01-02")),
-				Status:      "active",
-			}
-
-			if err := s.db.Create(bookmark).Error; err != nil {
-				result.Errors = append(result.Errors, fmt.Sprintf("Failed to create bookmark %s: %v", child.Name, err))
-				continue
-			}
-
-			// Associate with collection if exists
-			if currentCollection != nil {
-				if err := s.db.Model(currentCollection).Association("Bookmarks").Append(bookmark); err != nil {
-					result.Errors = append(result.Errors, fmt.Sprintf("Failed to associate bookmark with collection: %v", err))
-				}
-			}
-
-			result.ImportedBookmarksCount++
-		} else if child.Type == "folder" {
-			// Recursively process subfolder
-			if err := s.processChromeFolderRecursive(ctx, userID, &child, currentCollection, result); err != nil {
-				result.Errors = append(result.Errors, fmt.Sprintf("Error processing subfolder %s: %v", child.Name, err))
-			}
-		}
+// ImportBookmarksFromChrome imports bookmarks from Chrome format
+func (s *Service) ImportBookmarksFromChrome(ctx context.Context, userID uint, reader io.Reader) (*ImportResult, error) {
+	root, err := ParseChromeBookmarks(reader)
+	if err != nil {
+		return nil, err
 	}
-
-	return nil
+	return s.CommitImport(ctx, userID, SourceChrome, root)
 }
 
 // ImportBookmarksFromFirefox imports bookmarks from Firefox HTML format
 func (s *Service) ImportBookmarksFromFirefox(ctx context.Context, userID uint, reader io.Reader) (*ImportResult, error) {
 	startTime := time.Now()
 	result := &ImportResult{
-		Errors: make([]string, 0),
+		Errors:  make([]string, 0),
+		BatchID: uuid.NewString(),
 	}
 
 	// Read HTML content
@@ -189,7 +134,10 @@ func (s *Service) ImportBookmarksFromFirefox(ctx context.Context, userID uint, r
 		return nil, fmt.Errorf("failed to parse Firefox HTML: %w", err)
 	}
 
+	result.IndexJobID = s.indexImported(userID, startTime)
+
 	result.ProcessingTimeMs = time.Since(startTime).Milliseconds()
+	s.importDone(ctx, userID, SourceFirefox, 0, result)
 	return result, nil
 }
 
@@ -197,6 +145,7 @@ func (s *Service) ImportBookmarksFromFirefox(ctx context.Context, userID uint, r
 func (s *Service) parseFirefoxHTML(ctx context.Context, userID uint, htmlContent string, result *ImportResult) error {
 	lines := strings.Split(htmlContent, "\n")
 	var currentCollection *database.Collection
+	clean := s.urlCleaner(ctx, userID)
 
 	for _, line := range lines {
 		line = strings.TrimSpace(line)
@@ -230,7 +179,7 @@ func (s *Service) parseFirefoxHTML(ctx context.Context, userID uint, htmlContent
 
 		// Parse bookmark
 		if strings.Contains(line, "<DT><A HREF=") {
-			url := extractTextBetween(line, "HREF=\"", "\"")
+			url := clean(extractTextBetween(line, "HREF=\"", "\""))
 			title := extractTextBetween(line, "\">", "</A>")
 
 			if url != "" && title != "" {
@@ -253,6 +202,8 @@ func (s *Service) parseFirefoxHTML(ctx context.Context, userID uint, htmlContent
 					Title:       title,
 					Description: fmt.Sprintf("Imported from Firefox on %s", time.Now().Format("2006-01-02")),
 					Status:      "active",
+					Source:      database.BookmarkSourceImport,
+					SourceRef:   result.BatchID,
 				}
 
 				if err := s.db.Create(bookmark).Error; err != nil {
@@ -300,7 +251,8 @@ func extractTextBetween(text, start, end string) string {
 func (s *Service) ImportBookmarksFromSafari(ctx context.Context, userID uint, reader io.Reader) (*ImportResult, error) {
 	startTime := time.Now()
 	result := &ImportResult{
-		Errors: make([]string, 0),
+		Errors:  make([]string, 0),
+		BatchID: uuid.NewString(),
 	}
 
 	// Read plist content
@@ -316,7 +268,10 @@ func (s *Service) ImportBookmarksFromSafari(ctx context.Context, userID uint, re
 		return nil, fmt.Errorf("failed to parse Safari plist: %w", err)
 	}
 
+	result.IndexJobID = s.indexImported(userID, startTime)
+
 	result.ProcessingTimeMs = time.Since(startTime).Milliseconds()
+	s.importDone(ctx, userID, "safari", 0, result)
 	return result, nil
 }
 
@@ -325,6 +280,7 @@ func (s *Service) parseSafariPlist(ctx context.Context, userID uint, plistConten
 	lines := strings.Split(plistContent, "\n")
 	var currentCollection *database.Collection
 	var currentURL, currentTitle string
+	clean := s.urlCleaner(ctx, userID)
 
 	for i, line := range lines {
 		line = strings.TrimSpace(line)
@@ -357,7 +313,7 @@ func (s *Service) parseSafariPlist(ctx context.Context, userID uint, plistConten
 		if strings.Contains(line, "<key>URLString</key>") && i+1 < len(lines) {
 			nextLine := strings.TrimSpace(lines[i+1])
 			if strings.Contains(nextLine, "<string>") {
-				currentURL = extractTextBetween(nextLine, "<string>", "</string>")
+				currentURL = clean(extractTextBetween(nextLine, "<string>", "</string>"))
 			}
 		}
 
@@ -385,6 +341,8 @@ func (s *Service) parseSafariPlist(ctx context.Context, userID uint, plistConten
 					Title:       currentTitle,
 					Description: fmt.Sprintf("Imported from Safari on %s", time.Now().Format("2006-01-02")),
 					Status:      "active",
+					Source:      database.BookmarkSourceImport,
+					SourceRef:   result.BatchID,
 				}
 
 				if err := s.db.Create(bookmark).Error; err != nil {
@@ -411,112 +369,66 @@ func (s *Service) parseSafariPlist(ctx context.Context, userID uint, plistConten
 
 // ExportBookmarksToJSON exports bookmarks to JSON format
 func (s *Service) ExportBookmarksToJSON(ctx context.Context, userID uint, writer io.Writer) error {
-	// Get all bookmarks for user
-	var bookmarks []database.Bookmark
-	if err := s.db.Where("user_id = ?", userID).Preload("Collections").Find(&bookmarks).Error; err != nil {
-		return fmt.Errorf("failed to fetch bookmarks: %w", err)
-	}
-
-	// Get all collections for user
-	var collections []database.Collection
-	if err := s.db.Where("user_id = ?", userID).Preload("Bookmarks").Find(&collections).Error; err != nil {
-		return fmt.Errorf("failed to fetch collections: %w", err)
-	}
-
-	// Create export data structure
-	exportData := map[string]interface{}{
-		"version":     "1.0",
-		"exported_at": time.Now().UTC().Format(time.RFC3339),
-		"user_id":     userID,
-		"bookmarks":   bookmarks,
-		"collections": collections,
-		"metadata": map[string]interface{}{
-			"total_bookmarks":   len(bookmarks),
-			"total_collections": len(collections),
-			"export_format":     "json",
-		},
-	}
+	return s.Export(ctx, userID, "json", writer)
+}
 
-	// Encode to JSON
-	encoder := json.NewEncoder(writer)
-	encoder.SetIndent("", "  ")
-	if err := encoder.Encode(exportData); err != nil {
-		return fmt.Errorf("failed to encode JSON: %w", err)
-	}
+// Export writes a user's bookmarks in a registered export format
+func (s *Service) Export(ctx context.Context, userID uint, format string, writer io.Writer) error {
+	return exporter.Write(ctx, s.db, userID, format, writer)
+}
 
-	return nil
+// SetIndexer makes imports queue what they create for search indexing
+func (s *Service) SetIndexer(indexer SearchIndexer) {
+	s.indexer = indexer
 }
 
-// ExportBookmarksToHTML exports bookmarks to HTML format (Netscape format)
-func (s *Service) ExportBookmarksToHTML(ctx context.Context, userID uint, writer io.Writer) error {
-	// Get all bookmarks for user
-	var bookmarks []database.Bookmark
-	if err := s.db.Where("user_id = ?", userID).Find(&bookmarks).Error; err != nil {
-		return fmt.Errorf("failed to fetch bookmarks: %w", err)
-	}
+// SetIndexWatcher makes imports push an event once their bookmarks are
+// searchable
+func (s *Service) SetIndexWatcher(watcher IndexWatcher) {
+	s.watcher = watcher
+}
 
-	// Get all collections for user
-	var collections []database.Collection
-	if err := s.db.Where("user_id = ?", userID).Preload("Bookmarks").Find(&collections).Error; err != nil {
-		return fmt.Errorf("failed to fetch collections: %w", err)
+// indexImported queues the bookmarks and collections an import created.
+// They are queued once the import is done so collections are indexed with
+// their final bookmark counts. Imports may backdate created_at to the
+// browser's add date, so rows are found by updated_at. It returns the ID
+// of the index complete event, if one will be pushed
+func (s *Service) indexImported(userID uint, since time.Time) string {
+	if s.indexer == nil {
+		return ""
 	}
 
-	// Write HTML header
-	fmt.Fprintf(writer, `<!DOCTYPE NETSCAPE-Bookmark-file-1>
-<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=UTF-8">
-<TITLE>Bookmarks</TITLE>
-<H1>Bookmarks Menu</H1>
-<DL><p>
-`)
-
-	// Write collections with their bookmarks
-	for _, collection := range collections {
-		addDate := collection.CreatedAt.Unix()
-		lastModified := collection.UpdatedAt.Unix()
-
-		fmt.Fprintf(writer, `    <DT><H3 ADD_DATE="%d" LAST_MODIFIED="%d">%s</H3>
-    <DL><p>
-`, addDate, lastModified, escapeHTML(collection.Name))
-
-		for _, bookmark := range collection.Bookmarks {
-			bookmarkAddDate := bookmark.CreatedAt.Unix()
-			fmt.Fprintf(writer, `        <DT><A HREF="%s" ADD_DATE="%d">%s</A>
-`, escapeHTML(bookmark.URL), bookmarkAddDate, escapeHTML(bookmark.Title))
+	var jobID string
+	var bookmarks []database.Bookmark
+	if err := s.db.Where("user_id = ? AND updated_at >= ?", userID, since).Find(&bookmarks).Error; err == nil && len(bookmarks) > 0 {
+		ids := make([]uint, len(bookmarks))
+		for n := range bookmarks {
+			ids[n] = bookmarks[n].ID
+			bookmarks[n].IndexStatus = database.IndexStatusPending
 		}
-
-		fmt.Fprintf(writer, `    </DL><p>
-`)
-	}
-
-	// Write uncategorized bookmarks
-	uncategorizedBookmarks := make([]database.Bookmark, 0)
-	for _, bookmark := range bookmarks {
-		if len(bookmark.Collections) == 0 {
-			uncategorizedBookmarks = append(uncategorizedBookmarks, bookmark)
+		s.db.Model(&database.Bookmark{}).Where("user_id = ? AND updated_at >= ?", userID, since).
+			UpdateColumn("index_status", database.IndexStatusPending)
+		// The watch starts before queueing so no report can slip past it
+		if s.watcher != nil {
+			jobID = s.watcher.WatchImport(userID, ids)
+		}
+		for n := range bookmarks {
+			s.indexer.QueueBookmark(&bookmarks[n])
 		}
 	}
 
-	for _, bookmark := range uncategorizedBookmarks {
-		addDate := bookmark.CreatedAt.Unix()
-		fmt.Fprintf(writer, `    <DT><A HREF="%s" ADD_DATE="%d">%s</A>
-`, escapeHTML(bookmark.URL), addDate, escapeHTML(bookmark.Title))
+	var collections []database.Collection
+	if err := s.db.Where("user_id = ? AND updated_at >= ?", userID, since).Preload("Bookmarks").Find(&collections).Error; err == nil {
+		for n := range collections {
+			s.indexer.QueueCollection(&collections[n])
+		}
 	}
-
-	// Write HTML footer
-	fmt.Fprintf(writer, `</DL><p>
-`)
-
-	return nil
+	return jobID
 }
 
-// escapeHTML escapes HTML special characters
-func escapeHTML(s string) string {
-	s = strings.ReplaceAll(s, "&", "&amp;")
-	s = strings.ReplaceAll(s, "<", "&lt;")
-	s = strings.ReplaceAll(s, ">", "&gt;")
-	s = strings.ReplaceAll(s, "\"", "&quot;")
-	s = strings.ReplaceAll(s, "'", "&#39;")
-	return s
+// ExportBookmarksToHTML exports bookmarks to HTML format (Netscape format)
+func (s *Service) ExportBookmarksToHTML(ctx context.Context, userID uint, writer io.Writer) error {
+	return s.Export(ctx, userID, "html", writer)
 }
 
 // DetectDuplicate checks if a bookmark URL already exists for the user
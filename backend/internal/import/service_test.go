@@ -166,6 +166,49 @@ func TestService_ImportBookmarksFromFirefox(t *testing.T) {
 	assert.Equal(t, "Development", collections[0].Name)
 }
 
+// fakeIndexer records what an import queues and watches
+type fakeIndexer struct {
+	queued  []database.Bookmark
+	watched []uint
+}
+
+func (f *fakeIndexer) QueueBookmark(bookmark *database.Bookmark) {
+	f.queued = append(f.queued, *bookmark)
+}
+
+func (f *fakeIndexer) QueueCollection(collection *database.Collection) {}
+
+func (f *fakeIndexer) WatchImport(userID uint, ids []uint) string {
+	f.watched = ids
+	return "job-1"
+}
+
+func TestService_ImportWatchesIndexing(t *testing.T) {
+	db, err := database.SetupTestDB()
+	require.NoError(t, err)
+	defer database.CleanupTestDB(db)
+
+	service := NewService(db)
+	indexer := &fakeIndexer{}
+	service.SetIndexer(indexer)
+	service.SetIndexWatcher(indexer)
+	require.NoError(t, db.Create(&database.User{BaseModel: database.BaseModel{ID: 1}, Email: "test@example.com", Username: "testuser", SupabaseID: "test-supabase-id"}).Error)
+
+	result, err := service.ImportBookmarksFromFirefox(context.Background(), 1, strings.NewReader(`<DL><p>
+    <DT><A HREF="https://github.com" ADD_DATE="1640995200">GitHub</A>
+    <DT><A HREF="https://www.google.com" ADD_DATE="1640995100">Google</A>
+</DL><p>`))
+	require.NoError(t, err)
+	assert.Equal(t, "job-1", result.IndexJobID)
+	assert.Len(t, indexer.watched, 2)
+	require.Len(t, indexer.queued, 2)
+	assert.Equal(t, database.IndexStatusPending, indexer.queued[0].IndexStatus)
+
+	var pending int64
+	db.Model(&database.Bookmark{}).Where("index_status = ?", database.IndexStatusPending).Count(&pending)
+	assert.Equal(t, int64(2), pending)
+}
+
 func TestService_ImportBookmarksFromSafari(t *testing.T) {
 	db, err := database.SetupTestDB()
 	require.NoError(t, err)
@@ -356,6 +399,7 @@ func TestService_ExportBookmarksToHTML(t *testing.T) {
 			URL:         "https://github.com",
 			Title:       "GitHub",
 			Description: "Code repository",
+			Notes:       "Mirrors <b>everything</b>",
 		},
 	}
 
@@ -375,6 +419,7 @@ func TestService_ExportBookmarksToHTML(t *testing.T) {
 	assert.Contains(t, htmlContent, "GitHub")
 	assert.Contains(t, htmlContent, "https://www.google.com")
 	assert.Contains(t, htmlContent, "https://github.com")
+	assert.Contains(t, htmlContent, "<DD>Mirrors &lt;b&gt;everything&lt;/b&gt;")
 }
 
 func TestService_DetectDuplicates(t *testing.T) {
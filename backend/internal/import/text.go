@@ -0,0 +1,209 @@
+package import_export
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+// SourceText imports the links found in pasted plain text or Markdown
+const SourceText = "text"
+
+// maxTextImportSize caps the pasted document of a text import
+const maxTextImportSize = 5 << 20
+
+// Errors returned by text imports
+var (
+	ErrTextTooLarge = errors.New("text to import is too large")
+	ErrNoLinksFound = errors.New("no links found in the text")
+)
+
+var (
+	// markdownLink matches [title](url "optional title") and the image form,
+	// told apart by the leading !
+	markdownLink = regexp.MustCompile(`(!?)\[([^\]]*)\]\(\s*<?(https?://[^\s)>]+)>?(?:\s+"[^"]*")?\s*\)`)
+	// markdownReference matches [title][label] and the collapsed [title][]
+	markdownReference = regexp.MustCompile(`(!?)\[([^\]]+)\]\[([^\]]*)\]`)
+	// markdownDefinition matches a reference definition line: [label]: url
+	markdownDefinition = regexp.MustCompile(`^\s{0,3}\[([^\]]+)\]:\s*<?(https?://[^\s>]+)>?(?:\s+["'(](.*)["')])?\s*$`)
+	// bareURL matches URLs in running text and autolinks
+	bareURL = regexp.MustCompile(`https?://[^\s<>"'\x60]+`)
+	// listMarker matches the bullet or number starting a list item
+	listMarker = regexp.MustCompile(`^\s*(?:[-*+]|\d+[.)])\s+(?:\[[ xX]\]\s+)?`)
+)
+
+// TextImportOptions say where the links of a text import go. A collection
+// ID puts them in an existing collection, a name in a new one; neither
+// leaves them outside any collection. The tags are added to every link
+type TextImportOptions struct {
+	CollectionID uint     `json:"collection_id,omitempty"`
+	Collection   string   `json:"collection,omitempty" binding:"max=255"`
+	Tags         []string `json:"tags,omitempty"`
+}
+
+// TextImportRequest is a pasted document whose links are imported
+type TextImportRequest struct {
+	Content string `json:"content" binding:"required"`
+	TextImportOptions
+}
+
+// ParseTextLinks extracts the links of a plain text or Markdown document.
+// Markdown links and reference definitions keep their text as the title;
+// a bare URL takes the rest of its line, minus list markers and
+// separators, when there is any. Links repeated in the document are kept
+// once, and images are skipped
+func ParseTextLinks(content string) *ImportFolder {
+	root := &ImportFolder{}
+	index := make(map[string]int)
+	add := func(rawURL, title string) {
+		link, ok := cleanTextURL(rawURL)
+		if !ok {
+			return
+		}
+		title = strings.TrimSpace(title)
+		if n, seen := index[link]; seen {
+			if root.Bookmarks[n].Title == link && title != "" {
+				root.Bookmarks[n].Title = title
+			}
+			return
+		}
+		index[link] = len(root.Bookmarks)
+		root.Bookmarks = append(root.Bookmarks, ImportBookmark{URL: link, Title: titleOrURL(title, link)})
+	}
+
+	// Reference definitions are collected first so links may use them
+	// before they are defined
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	definitions := make(map[string]string)
+	for _, line := range lines {
+		if m := markdownDefinition.FindStringSubmatch(line); m != nil {
+			definitions[strings.ToLower(m[1])] = m[2]
+		}
+	}
+	referenced := make(map[string]bool)
+
+	for _, line := range lines {
+		if m := markdownDefinition.FindStringSubmatch(line); m != nil {
+			// Added where the definition is used, or at the end
+			continue
+		}
+
+		rest := line
+		for _, m := range markdownLink.FindAllStringSubmatch(line, -1) {
+			if m[1] == "" {
+				add(m[3], m[2])
+			}
+		}
+		rest = markdownLink.ReplaceAllString(rest, " ")
+
+		for _, m := range markdownReference.FindAllStringSubmatch(rest, -1) {
+			label := strings.ToLower(m[3])
+			if label == "" {
+				label = strings.ToLower(m[2])
+			}
+			if target, ok := definitions[label]; ok && m[1] == "" {
+				add(target, m[2])
+				referenced[label] = true
+			}
+		}
+		rest = markdownReference.ReplaceAllString(rest, " ")
+
+		urls := bareURL.FindAllStringIndex(rest, -1)
+		for _, loc := range urls {
+			title := ""
+			// A line holding one URL is titled by its text, e.g. "Go docs - https://go.dev"
+			if len(urls) == 1 {
+				title = textTitle(rest[:loc[0]] + " " + rest[loc[1]:])
+			}
+			add(rest[loc[0]:loc[1]], title)
+		}
+	}
+
+	for _, line := range lines {
+		if m := markdownDefinition.FindStringSubmatch(line); m != nil && !referenced[strings.ToLower(m[1])] {
+			title := m[3]
+			if title == "" {
+				title = m[1]
+			}
+			add(m[2], title)
+		}
+	}
+	return root
+}
+
+// cleanTextURL trims the punctuation that ends a sentence or wraps a URL
+// in running text, and accepts only absolute http(s) URLs
+func cleanTextURL(rawURL string) (string, bool) {
+	for {
+		trimmed := strings.TrimRight(rawURL, ".,;:!?*_'\"")
+		// Keep a closing parenthesis that belongs to the URL, as in Wikipedia links
+		if strings.HasSuffix(trimmed, ")") && strings.Count(trimmed, "(") < strings.Count(trimmed, ")") {
+			trimmed = trimmed[:len(trimmed)-1]
+		}
+		if strings.HasSuffix(trimmed, "]") && !strings.Contains(trimmed, "[") {
+			trimmed = trimmed[:len(trimmed)-1]
+		}
+		if trimmed == rawURL {
+			break
+		}
+		rawURL = trimmed
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", false
+	}
+	return rawURL, true
+}
+
+// textTitle turns the text around a URL into a title
+func textTitle(text string) string {
+	text = listMarker.ReplaceAllString(text, "")
+	text = strings.NewReplacer("<", " ", ">", " ").Replace(text)
+	text = strings.Join(strings.Fields(text), " ")
+	text = strings.Trim(text, " -–—:|•*#>()[]")
+	return strings.TrimSpace(text)
+}
+
+// ParseTextImport parses a pasted document and applies the import options,
+// checking the target collection belongs to the user
+func (s *Service) ParseTextImport(ctx context.Context, userID uint, req TextImportRequest) (*ImportFolder, error) {
+	if len(req.Content) > maxTextImportSize {
+		return nil, ErrTextTooLarge
+	}
+	root := ParseTextLinks(req.Content)
+	if len(root.Bookmarks) == 0 {
+		return nil, ErrNoLinksFound
+	}
+	if req.CollectionID != 0 {
+		if _, err := s.userCollection(ctx, userID, req.CollectionID); err != nil {
+			return nil, err
+		}
+	}
+
+	for n := range root.Bookmarks {
+		root.Bookmarks[n].Tags = append(root.Bookmarks[n].Tags, req.Tags...)
+	}
+	if name := strings.TrimSpace(req.Collection); name != "" && req.CollectionID == 0 {
+		root = &ImportFolder{Folders: []ImportFolder{{Name: name, Bookmarks: root.Bookmarks}}}
+	}
+	return root, nil
+}
+
+// userCollection loads a collection the user owns
+func (s *Service) userCollection(ctx context.Context, userID, collectionID uint) (*database.Collection, error) {
+	var collection database.Collection
+	if err := s.db.WithContext(ctx).Where("id = ? AND user_id = ?", collectionID, userID).First(&collection).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCollectionNotFound
+		}
+		return nil, err
+	}
+	return &collection, nil
+}
@@ -0,0 +1,145 @@
+package import_export
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"bookmark-sync-service/backend/pkg/database"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTextLinks(t *testing.T) {
+	content := strings.Join([]string{
+		"# Reading list",
+		"",
+		"- [The Go Blog](https://go.dev/blog/ \"Go\") and ![logo](https://go.dev/images/go-logo.svg)",
+		"- Effective Go - https://go.dev/doc/effective_go",
+		"* [ ] https://pkg.go.dev/net/http",
+		"See [the spec][spec] or https://go.dev/ref/mem, https://go.dev/blog/.",
+		"1. <https://en.wikipedia.org/wiki/Go_(programming_language)>",
+		"Not a link: ftp://example.com/file",
+		"",
+		"[spec]: https://go.dev/ref/spec",
+		"[faq]: https://go.dev/doc/faq \"Go FAQ\"",
+	}, "\r\n")
+
+	root := ParseTextLinks(content)
+	require.Len(t, root.Bookmarks, 7)
+
+	got := make(map[string]string)
+	for _, bookmark := range root.Bookmarks {
+		got[bookmark.URL] = bookmark.Title
+	}
+	assert.Equal(t, map[string]string{
+		"https://go.dev/blog/":                                    "The Go Blog",
+		"https://go.dev/doc/effective_go":                         "Effective Go",
+		"https://pkg.go.dev/net/http":                             "https://pkg.go.dev/net/http",
+		"https://go.dev/ref/spec":                                 "the spec",
+		"https://go.dev/ref/mem":                                  "https://go.dev/ref/mem",
+		"https://en.wikipedia.org/wiki/Go_(programming_language)": "https://en.wikipedia.org/wiki/Go_(programming_language)",
+		"https://go.dev/doc/faq":                                  "Go FAQ",
+	}, got)
+	assert.Equal(t, "https://go.dev/blog/", root.Bookmarks[0].URL, "links keep document order")
+
+	assert.Empty(t, ParseTextLinks("nothing to see here").Bookmarks)
+}
+
+func TestService_TextImport(t *testing.T) {
+	db, err := database.SetupTestDB()
+	require.NoError(t, err)
+	defer database.CleanupTestDB(db)
+
+	service := NewService(db)
+	ctx := context.Background()
+	require.NoError(t, db.Create(&database.User{BaseModel: database.BaseModel{ID: 1}, Email: "test@example.com", Username: "testuser", SupabaseID: "test-supabase-id"}).Error)
+	require.NoError(t, db.Create(&database.User{BaseModel: database.BaseModel{ID: 2}, Email: "other@example.com", Username: "other", SupabaseID: "other-supabase-id"}).Error)
+	require.NoError(t, db.Create(&database.Bookmark{UserID: 1, URL: "https://go.dev/", Title: "Go", Status: "active"}).Error)
+	reading := &database.Collection{UserID: 1, Name: "Reading", Visibility: "private", ShareLink: "reading"}
+	require.NoError(t, db.Create(reading).Error)
+	foreign := &database.Collection{UserID: 2, Name: "Theirs", Visibility: "private", ShareLink: "theirs"}
+	require.NoError(t, db.Create(foreign).Error)
+
+	req := TextImportRequest{
+		Content:           "https://go.dev/\n- Tour: https://go.dev/tour/",
+		TextImportOptions: TextImportOptions{CollectionID: reading.ID, Tags: []string{"golang"}},
+	}
+	root, err := service.ParseTextImport(ctx, 1, req)
+	require.NoError(t, err)
+
+	preview, err := service.PreviewImport(ctx, 1, SourceText, root)
+	require.NoError(t, err)
+	assert.Equal(t, 1, preview.DuplicatesCount)
+	assert.Equal(t, 1, preview.BookmarksCount)
+
+	result, err := service.CommitImportInto(ctx, 1, SourceText, root, reading.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.ImportedBookmarksCount)
+	assert.Equal(t, 1, result.DuplicatesSkipped)
+	assert.Zero(t, result.ImportedCollectionsCount)
+
+	var imported database.Bookmark
+	require.NoError(t, db.Preload("Collections").Where("url = ?", "https://go.dev/tour/").First(&imported).Error)
+	assert.Equal(t, "Tour", imported.Title)
+	assert.Equal(t, `["golang"]`, imported.Tags)
+	assert.Equal(t, database.BookmarkSourceImport, imported.Source)
+	assert.Equal(t, result.BatchID, imported.SourceRef)
+	assert.NotEmpty(t, result.BatchID)
+	require.Len(t, imported.Collections, 1)
+	assert.Equal(t, reading.ID, imported.Collections[0].ID)
+
+	req.TextImportOptions = TextImportOptions{Collection: "Links"}
+	root, err = service.ParseTextImport(ctx, 1, req)
+	require.NoError(t, err)
+	require.Len(t, root.Folders, 1)
+	assert.Equal(t, "Links", root.Folders[0].Name)
+
+	req.TextImportOptions = TextImportOptions{CollectionID: foreign.ID}
+	_, err = service.ParseTextImport(ctx, 1, req)
+	assert.ErrorIs(t, err, ErrCollectionNotFound)
+	_, err = service.CommitImportInto(ctx, 1, SourceText, root, foreign.ID)
+	assert.ErrorIs(t, err, ErrCollectionNotFound)
+
+	_, err = service.ParseTextImport(ctx, 1, TextImportRequest{Content: "no links"})
+	assert.ErrorIs(t, err, ErrNoLinksFound)
+	_, err = service.ParseTextImport(ctx, 1, TextImportRequest{Content: strings.Repeat("a", maxTextImportSize+1)})
+	assert.ErrorIs(t, err, ErrTextTooLarge)
+}
+
+func TestHandlers_ImportFromText(t *testing.T) {
+	router, _ := setupTestRouter()
+
+	post := func(path string, body interface{}) *httptest.ResponseRecorder {
+		data, err := json.Marshal(body)
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post("/api/v1/import-export/import/text?preview=true", TextImportRequest{Content: "[Go](https://go.dev/)\nhttps://go.dev/tour/"})
+	require.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Data ImportPreview `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, SourceText, response.Data.Source)
+	assert.Equal(t, 2, response.Data.BookmarksCount)
+
+	w = post("/api/v1/import-export/import/text", TextImportRequest{Content: "just words"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = post("/api/v1/import-export/import/text", TextImportRequest{Content: "https://go.dev/", TextImportOptions: TextImportOptions{CollectionID: 999}})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = post("/api/v1/import-export/import/commit", CommitImportRequest{Source: SourceText, Root: response.Data.Root, CollectionID: 999})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,45 @@
+package import_export
+
+import (
+	"context"
+
+	"bookmark-sync-service/backend/pkg/urlnorm"
+)
+
+// URLRules returns the URL cleanup rules of a user, built-in defaults included
+type URLRules interface {
+	Rules(ctx context.Context, userID uint) ([]urlnorm.Rule, error)
+}
+
+// SetURLRules makes imports clean up URLs before checking for duplicates
+func (s *Service) SetURLRules(rules URLRules) {
+	s.urlRules = rules
+}
+
+// urlCleaner loads the user's rules once for a whole import. Without rules,
+// or when they can't be loaded, URLs are imported as given
+func (s *Service) urlCleaner(ctx context.Context, userID uint) func(string) string {
+	if s.urlRules == nil {
+		return func(rawURL string) string { return rawURL }
+	}
+	rules, err := s.urlRules.Rules(ctx, userID)
+	if err != nil {
+		return func(rawURL string) string { return rawURL }
+	}
+	return func(rawURL string) string { return urlnorm.Clean(rawURL, rules) }
+}
+
+// cleanFolder returns a copy of a parsed import with the URLs cleaned up
+func cleanFolder(folder *ImportFolder, clean func(string) string) *ImportFolder {
+	cleaned := *folder
+	cleaned.Bookmarks = make([]ImportBookmark, len(folder.Bookmarks))
+	for n, bookmark := range folder.Bookmarks {
+		bookmark.URL = clean(bookmark.URL)
+		cleaned.Bookmarks[n] = bookmark
+	}
+	cleaned.Folders = make([]ImportFolder, len(folder.Folders))
+	for n := range folder.Folders {
+		cleaned.Folders[n] = *cleanFolder(&folder.Folders[n], clean)
+	}
+	return &cleaned
+}
@@ -0,0 +1,246 @@
+// Package loadtest drives HTTP endpoints at a steady request rate and checks
+// their latency against budgets, so query regressions fail a run instead of
+// reaching users
+package loadtest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Target is an endpoint under load
+type Target struct {
+	Name string
+	// Request builds the i-th request to the endpoint. It is called from
+	// concurrent workers
+	Request func(i uint64) (*http.Request, error)
+}
+
+// Options controls the load put on each target
+type Options struct {
+	// Rate is the number of requests per second sent to each target
+	Rate int
+	// Duration is how long each target is hit
+	Duration time.Duration
+	// Workers caps the requests in flight per target; requests due while
+	// all workers are busy are dropped and count as errors
+	Workers int
+	Client  *http.Client
+}
+
+// Budget is the latency and error rate an endpoint must stay within
+type Budget struct {
+	P95          time.Duration
+	MaxErrorRate float64
+}
+
+// Result summarizes the requests sent to a target
+type Result struct {
+	Name     string
+	Requests int
+	// Errors counts transport failures and responses with status 400 or above
+	Errors  int
+	Dropped int
+	P50     time.Duration
+	P95     time.Duration
+	P99     time.Duration
+	Max     time.Duration
+}
+
+// ErrorRate is the share of failed and dropped requests
+func (r *Result) ErrorRate() float64 {
+	total := r.Requests + r.Dropped
+	if total == 0 {
+		return 0
+	}
+	return float64(r.Errors+r.Dropped) / float64(total)
+}
+
+// Run hits all targets at the same time, as production traffic mixes them,
+// and returns a result per target in the order given. It stops early when
+// ctx ends
+func Run(ctx context.Context, opts Options, targets []Target) []*Result {
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+
+	results := make([]*Result, len(targets))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func(i int, target Target) {
+			defer wg.Done()
+			results[i] = attack(ctx, opts, target)
+		}(i, target)
+	}
+	wg.Wait()
+	return results
+}
+
+// attack sends requests on a fixed schedule. Latency is measured from send
+// until the body is read, so slow responses cannot hide behind a quick
+// status line
+func attack(ctx context.Context, opts Options, target Target) *Result {
+	result := &Result{Name: target.Name}
+	if opts.Rate <= 0 || opts.Duration <= 0 {
+		return result
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Duration)
+	defer cancel()
+	ticker := time.NewTicker(time.Second / time.Duration(opts.Rate))
+	defer ticker.Stop()
+
+	var (
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		latencies []time.Duration
+		seq       uint64
+	)
+	workers := make(chan struct{}, opts.Workers)
+	record := func(latency time.Duration, failed bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		result.Requests++
+		if failed {
+			result.Errors++
+		}
+		latencies = append(latencies, latency)
+	}
+
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-ticker.C:
+		}
+		select {
+		case workers <- struct{}{}:
+		default:
+			mu.Lock()
+			result.Dropped++
+			mu.Unlock()
+			continue
+		}
+
+		seq++
+		wg.Add(1)
+		go func(i uint64) {
+			defer wg.Done()
+			defer func() { <-workers }()
+
+			req, err := target.Request(i)
+			if err != nil {
+				record(0, true)
+				return
+			}
+			start := time.Now()
+			resp, err := opts.Client.Do(req)
+			if err != nil {
+				record(time.Since(start), true)
+				return
+			}
+			_, err = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			record(time.Since(start), err != nil || resp.StatusCode >= http.StatusBadRequest)
+		}(seq)
+	}
+	wg.Wait()
+
+	sort.Slice(latencies, func(a, b int) bool { return latencies[a] < latencies[b] })
+	result.P50 = Percentile(latencies, 50)
+	result.P95 = Percentile(latencies, 95)
+	result.P99 = Percentile(latencies, 99)
+	if len(latencies) > 0 {
+		result.Max = latencies[len(latencies)-1]
+	}
+	return result
+}
+
+// Percentile returns the p-th percentile, 0 to 100, of sorted latencies
+// using the nearest rank
+func Percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1]
+}
+
+// Check returns a description of every budget a result exceeds. Targets
+// without a budget always pass
+func Check(results []*Result, budgets map[string]Budget) []string {
+	var violations []string
+	for _, result := range results {
+		budget, ok := budgets[result.Name]
+		if !ok {
+			continue
+		}
+		if result.Requests == 0 {
+			violations = append(violations, fmt.Sprintf("%s: no requests completed", result.Name))
+			continue
+		}
+		if budget.P95 > 0 && result.P95 > budget.P95 {
+			violations = append(violations, fmt.Sprintf("%s: p95 %s exceeds budget %s", result.Name, result.P95, budget.P95))
+		}
+		if rate := result.ErrorRate(); rate > budget.MaxErrorRate {
+			violations = append(violations, fmt.Sprintf("%s: error rate %.2f%% exceeds budget %.2f%%", result.Name, rate*100, budget.MaxErrorRate*100))
+		}
+	}
+	return violations
+}
+
+// ParseBudgets reads p95 budgets written as name=duration pairs separated by
+// commas, e.g. "list=150ms,search=300ms". Every budget gets maxErrorRate
+func ParseBudgets(spec string, maxErrorRate float64) (map[string]Budget, error) {
+	budgets := make(map[string]Budget)
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, value, found := strings.Cut(pair, "=")
+		if !found || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid budget %q, want name=duration", pair)
+		}
+		p95, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid budget %q: %w", pair, err)
+		}
+		budgets[strings.TrimSpace(name)] = Budget{P95: p95, MaxErrorRate: maxErrorRate}
+	}
+	return budgets, nil
+}
+
+// WriteReport prints a table of results with their budgets
+func WriteReport(w io.Writer, results []*Result, budgets map[string]Budget) {
+	fmt.Fprintf(w, "%-10s %8s %7s %8s %10s %10s %10s %10s %10s\n",
+		"endpoint", "requests", "errors", "dropped", "p50", "p95", "p99", "max", "budget")
+	for _, r := range results {
+		budget := "-"
+		if b, ok := budgets[r.Name]; ok && b.P95 > 0 {
+			budget = b.P95.String()
+		}
+		fmt.Fprintf(w, "%-10s %8d %7d %8d %10s %10s %10s %10s %10s\n",
+			r.Name, r.Requests, r.Errors, r.Dropped,
+			r.P50.Round(time.Microsecond), r.P95.Round(time.Microsecond),
+			r.P99.Round(time.Microsecond), r.Max.Round(time.Microsecond), budget)
+	}
+}
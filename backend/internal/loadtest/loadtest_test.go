@@ -0,0 +1,104 @@
+package loadtest
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPercentile(t *testing.T) {
+	var latencies []time.Duration
+	for i := 1; i <= 100; i++ {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+
+	assert.Equal(t, 50*time.Millisecond, Percentile(latencies, 50))
+	assert.Equal(t, 95*time.Millisecond, Percentile(latencies, 95))
+	assert.Equal(t, 100*time.Millisecond, Percentile(latencies, 100))
+	assert.Equal(t, 1*time.Millisecond, Percentile(latencies, 0))
+	assert.Equal(t, time.Duration(0), Percentile(nil, 95))
+}
+
+func TestParseBudgets(t *testing.T) {
+	budgets, err := ParseBudgets("list=150ms, search=1s,", 0.01)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Budget{
+		"list":   {P95: 150 * time.Millisecond, MaxErrorRate: 0.01},
+		"search": {P95: time.Second, MaxErrorRate: 0.01},
+	}, budgets)
+
+	for _, invalid := range []string{"list", "=150ms", "list=fast"} {
+		_, err := ParseBudgets(invalid, 0)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestRunAndCheck(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/slow":
+			time.Sleep(30 * time.Millisecond)
+		case "/broken":
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	target := func(name, path string) Target {
+		return Target{Name: name, Request: func(uint64) (*http.Request, error) {
+			return http.NewRequest(http.MethodGet, server.URL+path, nil)
+		}}
+	}
+	results := Run(context.Background(), Options{Rate: 50, Duration: 300 * time.Millisecond, Workers: 10}, []Target{
+		target("fast", "/fast"), target("slow", "/slow"), target("broken", "/broken"),
+	})
+	require.Len(t, results, 3)
+
+	fast, slow, broken := results[0], results[1], results[2]
+	assert.Equal(t, "fast", fast.Name)
+	assert.Greater(t, fast.Requests, 5)
+	assert.Zero(t, fast.Errors)
+	assert.GreaterOrEqual(t, slow.P95, 30*time.Millisecond)
+	assert.Equal(t, broken.Requests, broken.Errors)
+
+	budgets := map[string]Budget{
+		"fast":   {P95: time.Second, MaxErrorRate: 0.1},
+		"slow":   {P95: 10 * time.Millisecond, MaxErrorRate: 0.1},
+		"broken": {P95: time.Second, MaxErrorRate: 0.1},
+	}
+	violations := Check(results, budgets)
+	require.Len(t, violations, 2)
+	assert.Contains(t, violations[0], "slow: p95")
+	assert.Contains(t, violations[1], "broken: error rate")
+
+	var report bytes.Buffer
+	WriteReport(&report, results, budgets)
+	assert.Contains(t, report.String(), "slow")
+	assert.Contains(t, report.String(), "10ms")
+}
+
+func TestRunDropsRequestsWhenWorkersAreBusy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	results := Run(context.Background(), Options{Rate: 100, Duration: 200 * time.Millisecond, Workers: 1}, []Target{{
+		Name: "busy",
+		Request: func(uint64) (*http.Request, error) {
+			return http.NewRequest(http.MethodGet, server.URL, nil)
+		},
+	}})
+
+	assert.Greater(t, results[0].Dropped, 0)
+	assert.Greater(t, results[0].ErrorRate(), 0.5)
+	assert.NotEmpty(t, Check(results, map[string]Budget{"busy": {MaxErrorRate: 0.01}}))
+}
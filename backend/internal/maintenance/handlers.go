@@ -0,0 +1,65 @@
+package maintenance
+
+import (
+	"errors"
+	"net/http"
+
+	"bookmark-sync-service/backend/pkg/middleware"
+	"bookmark-sync-service/backend/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler handles maintenance mode admin requests
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new maintenance handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes registers maintenance routes on an admin-only group
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/maintenance", h.GetStatus)
+	router.PUT("/maintenance", h.Update)
+}
+
+// GetStatus returns the current maintenance state
+// @Summary Get maintenance mode
+// @Tags admin
+// @Produce json
+// @Success 200 {object} State
+// @Router /admin/maintenance [get]
+func (h *Handler) GetStatus(c *gin.Context) {
+	utils.SuccessResponse(c, h.service.Current(c.Request.Context()), "Maintenance status retrieved")
+}
+
+// Update turns maintenance mode on or off
+// @Summary Toggle maintenance mode
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param request body UpdateRequest true "Maintenance settings"
+// @Success 200 {object} State
+// @Router /admin/maintenance [put]
+func (h *Handler) Update(c *gin.Context) {
+	var req UpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body", map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	state, err := h.service.Update(c.Request.Context(), req, middleware.GetUserEmail(c))
+	if errors.Is(err, ErrInvalidRetryAfter) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", err.Error(), nil)
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "MAINTENANCE_UPDATE_FAILED", err.Error(), nil)
+		return
+	}
+
+	utils.SuccessResponse(c, state, "Maintenance status updated")
+}
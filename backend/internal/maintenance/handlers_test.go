@@ -0,0 +1,47 @@
+package maintenance
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"bookmark-sync-service/backend/internal/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler_ToggleMaintenance(t *testing.T) {
+	service, _ := setupService(t, config.MaintenanceConfig{})
+	handler := NewHandler(service)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("email", "ops@example.com") })
+	handler.RegisterRoutes(router.Group("/admin"))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/admin/maintenance",
+		strings.NewReader(`{"enabled":true,"message":"moving storage","retry_after":600}`)))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/maintenance", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Data State `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.True(t, response.Data.Enabled)
+	assert.Equal(t, "moving storage", response.Data.Message)
+	assert.Equal(t, 600, response.Data.RetryAfter)
+	assert.Equal(t, "ops@example.com", response.Data.UpdatedBy)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/admin/maintenance", strings.NewReader(`{"enabled":true,"retry_after":-1}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,50 @@
+package maintenance
+
+import (
+	"net/http"
+	"strconv"
+
+	"bookmark-sync-service/backend/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// exemptPaths keep working during maintenance so users can sign in and
+// admins can turn maintenance mode off again
+var exemptPaths = map[string]bool{
+	"/api/v1/auth/login":        true,
+	"/api/v1/auth/refresh":      true,
+	"/api/v1/admin/maintenance": true,
+}
+
+// Middleware rejects writes with 503 and a Retry-After header while
+// maintenance mode is on. Reads continue to be served
+func (s *Service) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if isReadOnly(c.Request.Method) || exemptPaths[c.FullPath()] {
+			c.Next()
+			return
+		}
+
+		state := s.Current(c.Request.Context())
+		if !state.Enabled {
+			c.Next()
+			return
+		}
+
+		c.Header("Retry-After", strconv.Itoa(state.RetryAfter))
+		utils.ErrorResponse(c, http.StatusServiceUnavailable, "MAINTENANCE_MODE", state.Message, map[string]interface{}{
+			"retry_after": state.RetryAfter,
+		})
+		c.Abort()
+	}
+}
+
+func isReadOnly(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,142 @@
+package maintenance
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"bookmark-sync-service/backend/internal/config"
+	redispkg "bookmark-sync-service/backend/pkg/redis"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrInvalidRetryAfter is returned for a negative retry_after
+var ErrInvalidRetryAfter = errors.New("retry_after cannot be negative")
+
+// State describes the current maintenance mode
+type State struct {
+	Enabled    bool       `json:"enabled"`
+	Message    string     `json:"message,omitempty"`
+	RetryAfter int        `json:"retry_after"` // seconds
+	Source     string     `json:"source"`      // config or admin
+	UpdatedBy  string     `json:"updated_by,omitempty"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+}
+
+// UpdateRequest represents an admin request to toggle maintenance mode
+type UpdateRequest struct {
+	Enabled    bool   `json:"enabled"`
+	Message    string `json:"message"`
+	RetryAfter int    `json:"retry_after"`
+}
+
+// Service tracks the deployment wide maintenance flag. The flag lives in Redis
+// so every instance sees an admin toggle; the config flag forces it on
+type Service struct {
+	cfg   config.MaintenanceConfig
+	redis redispkg.RedisInterface
+
+	mu       sync.RWMutex
+	cached   State
+	cachedAt time.Time
+	cacheTTL time.Duration
+}
+
+// NewService creates a maintenance service
+func NewService(cfg config.MaintenanceConfig, redisClient redispkg.RedisInterface) *Service {
+	if cfg.RetryAfter <= 0 {
+		cfg.RetryAfter = 300
+	}
+	return &Service{
+		cfg:      cfg,
+		redis:    redisClient,
+		cacheTTL: config.MaintenanceCacheTTL,
+	}
+}
+
+// Current returns the maintenance state, reading Redis at most once per cache TTL.
+// If Redis is unavailable the last known state is kept
+func (s *Service) Current(ctx context.Context) State {
+	if s.cfg.Enabled {
+		return State{Enabled: true, Message: s.cfg.Message, RetryAfter: s.cfg.RetryAfter, Source: "config"}
+	}
+
+	s.mu.RLock()
+	if !s.cachedAt.IsZero() && time.Since(s.cachedAt) < s.cacheTTL {
+		state := s.cached
+		s.mu.RUnlock()
+		return state
+	}
+	s.mu.RUnlock()
+
+	state, err := s.load(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err == nil {
+		s.cached = state
+	}
+	s.cachedAt = time.Now()
+	return s.cached
+}
+
+// IsEnabled reports whether maintenance mode is on
+func (s *Service) IsEnabled(ctx context.Context) bool {
+	return s.Current(ctx).Enabled
+}
+
+// Update toggles maintenance mode for every instance
+func (s *Service) Update(ctx context.Context, req UpdateRequest, updatedBy string) (State, error) {
+	if req.RetryAfter < 0 {
+		return State{}, ErrInvalidRetryAfter
+	}
+
+	state := State{Enabled: false, Source: "admin", UpdatedBy: updatedBy, RetryAfter: s.cfg.RetryAfter}
+	if req.Enabled {
+		now := time.Now().UTC()
+		state.Enabled = true
+		state.StartedAt = &now
+		state.Message = req.Message
+		if state.Message == "" {
+			state.Message = s.cfg.Message
+		}
+		if req.RetryAfter > 0 {
+			state.RetryAfter = req.RetryAfter
+		}
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return State{}, fmt.Errorf("failed to encode maintenance state: %w", err)
+	}
+	if err := s.redis.Set(ctx, config.MaintenanceStateKey, data, 0); err != nil {
+		return State{}, fmt.Errorf("failed to store maintenance state: %w", err)
+	}
+
+	s.mu.Lock()
+	s.cached = state
+	s.cachedAt = time.Now()
+	s.mu.Unlock()
+
+	return s.Current(ctx), nil
+}
+
+func (s *Service) load(ctx context.Context) (State, error) {
+	raw, err := s.redis.Get(ctx, config.MaintenanceStateKey)
+	if errors.Is(err, redis.Nil) {
+		return State{RetryAfter: s.cfg.RetryAfter, Source: "admin"}, nil
+	}
+	if err != nil {
+		return State{}, err
+	}
+
+	var state State
+	if err := json.Unmarshal([]byte(raw), &state); err != nil {
+		return State{}, fmt.Errorf("failed to decode maintenance state: %w", err)
+	}
+	return state, nil
+}
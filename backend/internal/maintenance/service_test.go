@@ -0,0 +1,117 @@
+package maintenance
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/pkg/redis"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupService(t *testing.T, cfg config.MaintenanceConfig) (*Service, *miniredis.Miniredis) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+
+	client, err := redis.NewClient(config.RedisConfig{Host: mr.Host(), Port: mr.Port(), PoolSize: 1})
+	require.NoError(t, err)
+
+	service := NewService(cfg, client)
+	service.cacheTTL = 0
+	return service, mr
+}
+
+func setupRouter(service *Service) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(service.Middleware())
+
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/api/v1/bookmarks", ok)
+	router.POST("/api/v1/bookmarks", ok)
+	router.PUT("/api/v1/admin/maintenance", ok)
+	return router
+}
+
+func TestService_DefaultsToDisabled(t *testing.T) {
+	service, _ := setupService(t, config.MaintenanceConfig{})
+
+	state := service.Current(context.Background())
+	assert.False(t, state.Enabled)
+	assert.Equal(t, 300, state.RetryAfter)
+}
+
+func TestService_UpdateIsSharedThroughRedis(t *testing.T) {
+	service, mr := setupService(t, config.MaintenanceConfig{Message: "default message", RetryAfter: 120})
+
+	state, err := service.Update(context.Background(), UpdateRequest{Enabled: true}, "ops@example.com")
+	require.NoError(t, err)
+	assert.True(t, state.Enabled)
+	assert.Equal(t, "default message", state.Message)
+	assert.Equal(t, 120, state.RetryAfter)
+	assert.Equal(t, "ops@example.com", state.UpdatedBy)
+	assert.NotNil(t, state.StartedAt)
+
+	// Another instance sharing the same Redis sees the toggle
+	client, err := redis.NewClient(config.RedisConfig{Host: mr.Host(), Port: mr.Port(), PoolSize: 1})
+	require.NoError(t, err)
+	other := NewService(config.MaintenanceConfig{}, client)
+	assert.True(t, other.IsEnabled(context.Background()))
+
+	_, err = service.Update(context.Background(), UpdateRequest{Enabled: false}, "ops@example.com")
+	require.NoError(t, err)
+	assert.False(t, service.IsEnabled(context.Background()))
+}
+
+func TestService_ConfigForcesMaintenance(t *testing.T) {
+	service, _ := setupService(t, config.MaintenanceConfig{Enabled: true, Message: "migrating", RetryAfter: 60})
+
+	state := service.Current(context.Background())
+	assert.True(t, state.Enabled)
+	assert.Equal(t, "config", state.Source)
+}
+
+func TestService_KeepsLastStateWhenRedisFails(t *testing.T) {
+	service, mr := setupService(t, config.MaintenanceConfig{})
+
+	_, err := service.Update(context.Background(), UpdateRequest{Enabled: true}, "")
+	require.NoError(t, err)
+
+	mr.Close()
+	assert.True(t, service.IsEnabled(context.Background()))
+}
+
+func TestMiddleware(t *testing.T) {
+	service, _ := setupService(t, config.MaintenanceConfig{})
+	router := setupRouter(service)
+
+	request := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	// Writes pass while maintenance is off
+	assert.Equal(t, http.StatusOK, request(http.MethodPost, "/api/v1/bookmarks").Code)
+
+	_, err := service.Update(context.Background(), UpdateRequest{Enabled: true, RetryAfter: 90}, "")
+	require.NoError(t, err)
+
+	// Reads continue, writes are rejected with Retry-After
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, "/api/v1/bookmarks").Code)
+
+	w := request(http.MethodPost, "/api/v1/bookmarks")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "90", w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), "MAINTENANCE_MODE")
+
+	// Admins can still turn maintenance off
+	assert.Equal(t, http.StatusOK, request(http.MethodPut, "/api/v1/admin/maintenance").Code)
+}
@@ -0,0 +1,262 @@
+package merge
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"bookmark-sync-service/backend/pkg/middleware"
+	"bookmark-sync-service/backend/pkg/utils"
+)
+
+// Handler serves account merges to users and admins
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new account merge handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes registers the user routes on an authenticated group
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	router.POST("/account/merges", h.Start)
+	router.POST("/account/merges/confirm", h.Confirm)
+	router.GET("/account/merges", h.List)
+	router.GET("/account/merges/:id", h.Get)
+	router.DELETE("/account/merges/:id", h.Cancel)
+}
+
+// RegisterAdminRoutes registers the merge routes on an admin-only group
+func (h *Handler) RegisterAdminRoutes(router *gin.RouterGroup) {
+	router.POST("/merges", h.Queue)
+	router.GET("/merges/:id", h.AdminGet)
+}
+
+// StartRequest starts merging the signed-in account into another one
+type StartRequest struct {
+	TargetEmail string `json:"target_email" binding:"required,email"`
+}
+
+// ConfirmRequest confirms a merge into the signed-in account
+type ConfirmRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// QueueRequest queues a merge of two accounts
+type QueueRequest struct {
+	SourceUserID uint `json:"source_user_id" binding:"required"`
+	TargetUserID uint `json:"target_user_id" binding:"required"`
+}
+
+// Start starts merging the signed-in account into another one
+// @Summary Start an account merge
+// @Description Start moving the bookmarks, collections, shares, follows and preferences of the signed-in account to the account with the target email, which is then closed. Sign in to the target account and confirm with the returned token to run it
+// @Tags account
+// @Accept json
+// @Produce json
+// @Param request body StartRequest true "Account to merge into"
+// @Success 201 {object} Started
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse "A merge is in progress"
+// @Router /account/merges [post]
+func (h *Handler) Start(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+	var req StartRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid request data", nil)
+		return
+	}
+
+	started, err := h.service.Start(c.Request.Context(), userID, middleware.GetUserEmail(c), req.TargetEmail)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, utils.APIResponse{
+		Success: true,
+		Message: "Account merge started, confirm it from the target account",
+		Data:    started,
+	})
+}
+
+// Confirm queues a merge into the signed-in account
+// @Summary Confirm an account merge
+// @Description Queue the merge of another account into the signed-in one; the worker runs it and stores a report
+// @Tags account
+// @Accept json
+// @Produce json
+// @Param request body ConfirmRequest true "Confirmation token"
+// @Success 200 {object} database.AccountMerge
+// @Failure 400 {object} utils.ErrorResponse
+// @Router /account/merges/confirm [post]
+func (h *Handler) Confirm(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+	var req ConfirmRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid request data", nil)
+		return
+	}
+
+	merge, err := h.service.Confirm(c.Request.Context(), userID, req.Token)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, merge, "Account merge queued")
+}
+
+// List lists the merges of the signed-in account
+// @Summary List account merges
+// @Tags account
+// @Produce json
+// @Success 200 {array} database.AccountMerge
+// @Router /account/merges [get]
+func (h *Handler) List(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	merges, err := h.service.List(c.Request.Context(), userID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, merges, "Account merges retrieved")
+}
+
+// Get returns a merge of the signed-in account with its report
+// @Summary Get an account merge
+// @Description The merge and, once it ran, its report: what moved and how duplicate URLs, colliding collection names and other conflicts were resolved
+// @Tags account
+// @Produce json
+// @Param id path int true "Merge ID"
+// @Success 200 {object} Merge
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /account/merges/{id} [get]
+func (h *Handler) Get(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+	h.get(c, userID)
+}
+
+// Cancel cancels a merge that has not started
+// @Summary Cancel an account merge
+// @Tags account
+// @Produce json
+// @Param id path int true "Merge ID"
+// @Success 200 {object} database.AccountMerge
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse "The merge started"
+// @Router /account/merges/{id} [delete]
+func (h *Handler) Cancel(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_ID", "Invalid merge ID", nil)
+		return
+	}
+
+	merge, err := h.service.Cancel(c.Request.Context(), userID, uint(id))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, merge, "Account merge cancelled")
+}
+
+// Queue queues a merge of two accounts
+// @Summary Queue an account merge
+// @Description Merge the source account into the target account without confirmation from either
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param request body QueueRequest true "Accounts"
+// @Success 201 {object} database.AccountMerge
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse "A merge is in progress"
+// @Router /admin/merges [post]
+func (h *Handler) Queue(c *gin.Context) {
+	var req QueueRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid request data", nil)
+		return
+	}
+
+	merge, err := h.service.Queue(c.Request.Context(), req.SourceUserID, req.TargetUserID, middleware.GetUserEmail(c))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, utils.APIResponse{
+		Success: true,
+		Message: "Account merge queued",
+		Data:    merge,
+	})
+}
+
+// AdminGet returns any merge with its report
+// @Summary Get an account merge
+// @Tags admin
+// @Produce json
+// @Param id path int true "Merge ID"
+// @Success 200 {object} Merge
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /admin/merges/{id} [get]
+func (h *Handler) AdminGet(c *gin.Context) {
+	h.get(c, 0)
+}
+
+func (h *Handler) get(c *gin.Context, userID uint) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_ID", "Invalid merge ID", nil)
+		return
+	}
+
+	merge, err := h.service.Get(c.Request.Context(), userID, uint(id))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, merge, "Account merge retrieved")
+}
+
+func (h *Handler) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrSameAccount), errors.Is(err, ErrInvalidToken):
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error(), nil)
+	case errors.Is(err, ErrAccountNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, "ACCOUNT_NOT_FOUND", err.Error(), nil)
+	case errors.Is(err, ErrNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, "NOT_FOUND", err.Error(), nil)
+	case errors.Is(err, ErrInProgress):
+		utils.ErrorResponse(c, http.StatusConflict, "MERGE_IN_PROGRESS", err.Error(), nil)
+	case errors.Is(err, ErrNotCancellable):
+		utils.ErrorResponse(c, http.StatusConflict, "NOT_CANCELLABLE", err.Error(), nil)
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to process account merge", nil)
+	}
+}
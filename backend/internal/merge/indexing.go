@@ -0,0 +1,83 @@
+package merge
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+// SearchIndexer queues changes for batched search indexing
+type SearchIndexer interface {
+	QueueBookmark(bookmark *database.Bookmark)
+	QueueBookmarkDelete(id uint)
+	QueueCollection(collection *database.Collection)
+	QueueCollectionDelete(id uint)
+}
+
+// SetIndexer makes merges re-index what they moved, so it is found under
+// the target account and no longer under the closed source account
+func (s *Service) SetIndexer(indexer SearchIndexer) {
+	s.indexer = indexer
+}
+
+// sourceRows are the IDs of the source account's rows before a merge
+type sourceRows struct {
+	bookmarkIDs   []uint
+	collectionIDs []uint
+}
+
+// index queues the source account's former rows once the merge committed:
+// the moved ones under their new owner, and those folded into the target's
+// own rows for removal
+func (s *Service) index(ctx context.Context, rows *sourceRows) {
+	if s.indexer == nil {
+		return
+	}
+
+	var bookmarks []database.Bookmark
+	if len(rows.bookmarkIDs) > 0 {
+		if err := s.db.WithContext(ctx).Where("id IN ?", rows.bookmarkIDs).Find(&bookmarks).Error; err != nil {
+			s.logger.Warn("Failed to load merged bookmarks for indexing", zap.Error(err))
+			return
+		}
+	}
+	moved := make(map[uint]bool, len(bookmarks))
+	if len(bookmarks) > 0 {
+		ids := make([]uint, len(bookmarks))
+		for n := range bookmarks {
+			ids[n] = bookmarks[n].ID
+			bookmarks[n].IndexStatus = database.IndexStatusPending
+		}
+		s.db.WithContext(ctx).Model(&database.Bookmark{}).Where("id IN ?", ids).
+			UpdateColumn("index_status", database.IndexStatusPending)
+	}
+	for n := range bookmarks {
+		moved[bookmarks[n].ID] = true
+		s.indexer.QueueBookmark(&bookmarks[n])
+	}
+	for _, id := range rows.bookmarkIDs {
+		if !moved[id] {
+			s.indexer.QueueBookmarkDelete(id)
+		}
+	}
+
+	var collections []database.Collection
+	if len(rows.collectionIDs) > 0 {
+		if err := s.db.WithContext(ctx).Where("id IN ?", rows.collectionIDs).Preload("Bookmarks").Find(&collections).Error; err != nil {
+			s.logger.Warn("Failed to load merged collections for indexing", zap.Error(err))
+			return
+		}
+	}
+	moved = make(map[uint]bool, len(collections))
+	for n := range collections {
+		moved[collections[n].ID] = true
+		s.indexer.QueueCollection(&collections[n])
+	}
+	for _, id := range rows.collectionIDs {
+		if !moved[id] {
+			s.indexer.QueueCollectionDelete(id)
+		}
+	}
+}
@@ -0,0 +1,580 @@
+package merge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/urlnorm"
+)
+
+// Conflict types of a merge report
+const (
+	ConflictDuplicateURL   = "duplicate_url"   // both accounts saved the page
+	ConflictCollectionName = "collection_name" // both accounts have a top-level collection of that name
+	ConflictCollaboration  = "collaboration"   // both accounts collaborate on the collection, or the target owns it
+	ConflictDirectShare    = "direct_share"    // the resource was shared with both accounts, or between them
+	ConflictFollow         = "follow"          // both accounts follow the user, or each other
+)
+
+// Report is what a merge moved and how it resolved what both accounts had
+type Report struct {
+	BookmarksMoved      int        `json:"bookmarks_moved"`
+	BookmarksMerged     int        `json:"bookmarks_merged"` // duplicates folded into the target's bookmark of the page
+	CollectionsMoved    int        `json:"collections_moved"`
+	CollectionsRenamed  int        `json:"collections_renamed"`
+	TagsMoved           int        `json:"tags_moved"`
+	SharesMoved         int        `json:"shares_moved"`
+	CollaborationsMoved int        `json:"collaborations_moved"`
+	DirectSharesMoved   int        `json:"direct_shares_moved"`
+	FollowsMoved        int        `json:"follows_moved"`
+	PreferencesAdded    []string   `json:"preferences_added"` // preferences only the source account had set
+	Conflicts           []Conflict `json:"conflicts"`
+}
+
+// Conflict is something both accounts had and how the merge resolved it
+type Conflict struct {
+	Type       string `json:"type"`
+	SourceID   uint   `json:"source_id,omitempty"` // the source account's row
+	TargetID   uint   `json:"target_id,omitempty"` // the target account's row it met
+	Detail     string `json:"detail"`
+	Resolution string `json:"resolution"`
+}
+
+// Run merges the source account of a merge into its target in one
+// transaction, so a failure leaves both accounts as they were. The source
+// account is closed once everything moved
+func (s *Service) Run(ctx context.Context, merge *database.AccountMerge) (*Report, error) {
+	report := &Report{PreferencesAdded: []string{}, Conflicts: []Conflict{}}
+	rows := &sourceRows{}
+	err := database.WithTransaction(ctx, s.db, func(tx *gorm.DB) error {
+		var source, target database.User
+		if err := tx.First(&source, merge.SourceUserID).Error; err != nil {
+			return accountError(err)
+		}
+		if err := tx.First(&target, merge.TargetUserID).Error; err != nil {
+			return accountError(err)
+		}
+
+		// What the source had is re-indexed once the merge committed
+		if err := tx.Model(&database.Bookmark{}).Where("user_id = ?", source.ID).Pluck("id", &rows.bookmarkIDs).Error; err != nil {
+			return fmt.Errorf("failed to get source bookmarks: %w", err)
+		}
+		if err := tx.Model(&database.Collection{}).Where("user_id = ?", source.ID).Pluck("id", &rows.collectionIDs).Error; err != nil {
+			return fmt.Errorf("failed to get source collections: %w", err)
+		}
+
+		m := &merger{tx: tx, source: source.ID, target: target.ID, report: report}
+		for _, step := range []func() error{
+			m.collections,
+			m.tags,
+			m.bookmarks,
+			m.shares,
+			m.collaborations,
+			m.directShares,
+			m.follows,
+		} {
+			if err := step(); err != nil {
+				return err
+			}
+		}
+		if err := m.preferences(&source, &target); err != nil {
+			return err
+		}
+
+		if err := tx.Delete(&source).Error; err != nil {
+			return fmt.Errorf("failed to close source account: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.index(ctx, rows)
+	return report, nil
+}
+
+func accountError(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrAccountNotFound
+	}
+	return fmt.Errorf("failed to get account: %w", err)
+}
+
+// merger moves the rows of one merge
+type merger struct {
+	tx     *gorm.DB
+	source uint
+	target uint
+	report *Report
+}
+
+func (m *merger) conflict(conflict Conflict) {
+	m.report.Conflicts = append(m.report.Conflicts, conflict)
+}
+
+// collections moves the source's collections. A top-level collection named
+// like one of the target's is renamed; subcollections keep their names, as
+// they move with their parent
+func (m *merger) collections() error {
+	var targets []database.Collection
+	if err := m.tx.Unscoped().Where("user_id = ?", m.target).Find(&targets).Error; err != nil {
+		return fmt.Errorf("failed to get target collections: %w", err)
+	}
+	names := make(map[string]uint)
+	clientIDs := make(map[string]bool)
+	for _, collection := range targets {
+		if collection.ParentID == nil && !collection.DeletedAt.Valid {
+			names[strings.ToLower(collection.Name)] = collection.ID
+		}
+		if collection.ClientID != nil {
+			clientIDs[*collection.ClientID] = true
+		}
+	}
+
+	var sources []database.Collection
+	if err := m.tx.Where("user_id = ?", m.source).Order("id").Find(&sources).Error; err != nil {
+		return fmt.Errorf("failed to get source collections: %w", err)
+	}
+	for _, collection := range sources {
+		updates := map[string]interface{}{"user_id": m.target}
+		if collection.ClientID != nil && clientIDs[*collection.ClientID] {
+			updates["client_id"] = nil
+		}
+		if targetID, taken := names[strings.ToLower(collection.Name)]; taken && collection.ParentID == nil {
+			name := freeName(collection.Name, names)
+			updates["name"] = name
+			m.conflict(Conflict{
+				Type: ConflictCollectionName, SourceID: collection.ID, TargetID: targetID,
+				Detail: collection.Name, Resolution: fmt.Sprintf("renamed to %q", name),
+			})
+			m.report.CollectionsRenamed++
+			names[strings.ToLower(name)] = collection.ID
+		} else if collection.ParentID == nil {
+			names[strings.ToLower(collection.Name)] = collection.ID
+		}
+		if err := m.tx.Model(&database.Collection{}).Where("id = ?", collection.ID).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to move collection %d: %w", collection.ID, err)
+		}
+		m.report.CollectionsMoved++
+	}
+	return nil
+}
+
+// freeName returns the first "<name> (merged)", "<name> (merged 2)", ...
+// none of the names takes
+func freeName(name string, names map[string]uint) string {
+	candidate := name + " (merged)"
+	for n := 2; ; n++ {
+		if _, taken := names[strings.ToLower(candidate)]; !taken {
+			return candidate
+		}
+		candidate = fmt.Sprintf("%s (merged %d)", name, n)
+	}
+}
+
+// tags moves the source's relational tags. A tag the target has too is
+// folded into the target's, keeping the bookmarks tagged with it
+func (m *merger) tags() error {
+	var sources []database.Tag
+	if err := m.tx.Where("user_id = ?", m.source).Find(&sources).Error; err != nil {
+		return fmt.Errorf("failed to get source tags: %w", err)
+	}
+	for _, tag := range sources {
+		var existing database.Tag
+		err := m.tx.Where("user_id = ? AND name = ?", m.target, tag.Name).First(&existing).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if err := m.tx.Model(&tag).Update("user_id", m.target).Error; err != nil {
+				return fmt.Errorf("failed to move tag %q: %w", tag.Name, err)
+			}
+		case err != nil:
+			return fmt.Errorf("failed to get target tag: %w", err)
+		default:
+			if err := m.tx.Model(&database.BookmarkTag{}).Where("tag_id = ?", tag.ID).Update("tag_id", existing.ID).Error; err != nil {
+				return fmt.Errorf("failed to move tag %q: %w", tag.Name, err)
+			}
+			if err := m.tx.Delete(&tag).Error; err != nil {
+				return fmt.Errorf("failed to delete tag %q: %w", tag.Name, err)
+			}
+		}
+		m.report.TagsMoved++
+	}
+	return nil
+}
+
+// bookmarks moves the source's bookmarks. A page the target saved too, by
+// canonical URL, is folded into the target's bookmark: it gains the tags,
+// collections and empty fields of the source's, which is deleted
+func (m *merger) bookmarks() error {
+	var targets []database.Bookmark
+	if err := m.tx.Unscoped().Where("user_id = ?", m.target).Find(&targets).Error; err != nil {
+		return fmt.Errorf("failed to get target bookmarks: %w", err)
+	}
+	pages := make(map[string]*database.Bookmark)
+	clientIDs := make(map[string]bool)
+	urlKeys := make(map[string]bool)
+	for i := range targets {
+		bookmark := &targets[i]
+		if !bookmark.DeletedAt.Valid {
+			pages[canonical(bookmark.URL)] = bookmark
+		}
+		if bookmark.ClientID != nil {
+			clientIDs[*bookmark.ClientID] = true
+		}
+		if bookmark.URLKey != nil {
+			urlKeys[*bookmark.URLKey] = true
+		}
+	}
+
+	var sources []database.Bookmark
+	if err := m.tx.Where("user_id = ?", m.source).Order("id").Find(&sources).Error; err != nil {
+		return fmt.Errorf("failed to get source bookmarks: %w", err)
+	}
+	for i := range sources {
+		bookmark := &sources[i]
+		page := canonical(bookmark.URL)
+		if existing, ok := pages[page]; ok {
+			if err := m.fold(bookmark, existing); err != nil {
+				return err
+			}
+			m.conflict(Conflict{
+				Type: ConflictDuplicateURL, SourceID: bookmark.ID, TargetID: existing.ID,
+				Detail: bookmark.URL, Resolution: "merged into the target bookmark",
+			})
+			m.report.BookmarksMerged++
+			continue
+		}
+
+		updates := map[string]interface{}{"user_id": m.target}
+		if bookmark.ClientID != nil && clientIDs[*bookmark.ClientID] {
+			updates["client_id"] = nil
+		}
+		if bookmark.URLKey != nil && urlKeys[*bookmark.URLKey] {
+			updates["url_key"] = nil
+		}
+		if err := m.tx.Model(&database.Bookmark{}).Where("id = ?", bookmark.ID).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to move bookmark %d: %w", bookmark.ID, err)
+		}
+		bookmark.UserID = m.target
+		pages[page] = bookmark
+		m.report.BookmarksMoved++
+	}
+	return nil
+}
+
+// fold merges a source bookmark into the target's bookmark of the page
+func (m *merger) fold(bookmark, into *database.Bookmark) error {
+	updates := map[string]interface{}{}
+	if tags := unionTags(into.Tags, bookmark.Tags); tags != into.Tags {
+		updates["tags"] = tags
+		into.Tags = tags
+	}
+	if into.Description == "" && bookmark.Description != "" {
+		updates["description"] = bookmark.Description
+		into.Description = bookmark.Description
+	}
+	if into.Notes == "" && bookmark.Notes != "" {
+		updates["notes"] = bookmark.Notes
+		updates["notes_encrypted"] = bookmark.NotesEncrypted
+		updates["notes_key_hint"] = bookmark.NotesKeyHint
+		into.Notes = bookmark.Notes
+	}
+	if into.Favicon == "" && bookmark.Favicon != "" {
+		updates["favicon"] = bookmark.Favicon
+	}
+	if into.Screenshot == "" && bookmark.Screenshot != "" {
+		updates["screenshot"] = bookmark.Screenshot
+	}
+	if len(updates) > 0 {
+		if err := m.tx.Model(&database.Bookmark{}).Where("id = ?", into.ID).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to merge bookmark %d: %w", bookmark.ID, err)
+		}
+	}
+
+	// Collections and relational tags of the source bookmark the target's
+	// lacks move over; the rest go with the source bookmark
+	if err := m.tx.Exec(`UPDATE bookmark_collections SET bookmark_id = ? WHERE bookmark_id = ?
+		AND collection_id NOT IN (SELECT collection_id FROM bookmark_collections WHERE bookmark_id = ?)`,
+		into.ID, bookmark.ID, into.ID).Error; err != nil {
+		return fmt.Errorf("failed to merge collections of bookmark %d: %w", bookmark.ID, err)
+	}
+	if err := m.tx.Exec(`DELETE FROM bookmark_collections WHERE bookmark_id = ?`, bookmark.ID).Error; err != nil {
+		return fmt.Errorf("failed to merge collections of bookmark %d: %w", bookmark.ID, err)
+	}
+	if err := m.tx.Exec(`UPDATE bookmark_tags SET bookmark_id = ? WHERE bookmark_id = ?
+		AND tag_id NOT IN (SELECT tag_id FROM bookmark_tags WHERE bookmark_id = ?)`,
+		into.ID, bookmark.ID, into.ID).Error; err != nil {
+		return fmt.Errorf("failed to merge tags of bookmark %d: %w", bookmark.ID, err)
+	}
+	if err := m.tx.Where("bookmark_id = ?", bookmark.ID).Delete(&database.BookmarkTag{}).Error; err != nil {
+		return fmt.Errorf("failed to merge tags of bookmark %d: %w", bookmark.ID, err)
+	}
+
+	if err := m.tx.Delete(&database.Bookmark{}, bookmark.ID).Error; err != nil {
+		return fmt.Errorf("failed to delete merged bookmark %d: %w", bookmark.ID, err)
+	}
+	return nil
+}
+
+// shares moves the share links of the source's collections
+func (m *merger) shares() error {
+	result := m.tx.Model(&database.CollectionShare{}).Where("user_id = ?", m.source).Update("user_id", m.target)
+	if result.Error != nil {
+		return fmt.Errorf("failed to move shares: %w", result.Error)
+	}
+	m.report.SharesMoved = int(result.RowsAffected)
+	return nil
+}
+
+// collaborations moves the source's collaborations on others' collections.
+// Collaborating on a collection the target owns now, or collaborates on
+// already, is dropped
+func (m *merger) collaborations() error {
+	if err := m.tx.Model(&database.CollectionCollaborator{}).Where("inviter_id = ?", m.source).Update("inviter_id", m.target).Error; err != nil {
+		return fmt.Errorf("failed to move collaborator invitations: %w", err)
+	}
+
+	var collaborations []database.CollectionCollaborator
+	if err := m.tx.Where("user_id IN ?", []uint{m.source, m.target}).Order("id").Find(&collaborations).Error; err != nil {
+		return fmt.Errorf("failed to get collaborations: %w", err)
+	}
+	owned, err := m.ownedCollections(collaborations)
+	if err != nil {
+		return err
+	}
+	joined := make(map[uint]uint)
+	for _, collaboration := range collaborations {
+		if collaboration.UserID == m.target && !owned[collaboration.CollectionID] {
+			joined[collaboration.CollectionID] = collaboration.ID
+		}
+	}
+
+	for _, collaboration := range collaborations {
+		existing, both := joined[collaboration.CollectionID]
+		switch {
+		case owned[collaboration.CollectionID]:
+			if err := m.tx.Delete(&collaboration).Error; err != nil {
+				return fmt.Errorf("failed to delete collaboration: %w", err)
+			}
+			conflict := Conflict{
+				Type:       ConflictCollaboration,
+				Detail:     fmt.Sprintf("collection %d", collaboration.CollectionID),
+				Resolution: "removed, the target account owns the collection",
+			}
+			if collaboration.UserID == m.source {
+				conflict.SourceID = collaboration.ID
+			} else {
+				conflict.TargetID = collaboration.ID
+			}
+			m.conflict(conflict)
+		case collaboration.UserID == m.target:
+		case both:
+			if err := m.tx.Delete(&collaboration).Error; err != nil {
+				return fmt.Errorf("failed to delete collaboration: %w", err)
+			}
+			m.conflict(Conflict{
+				Type: ConflictCollaboration, SourceID: collaboration.ID, TargetID: existing,
+				Detail:     fmt.Sprintf("collection %d", collaboration.CollectionID),
+				Resolution: "kept the target account's collaboration",
+			})
+		default:
+			if err := m.tx.Model(&collaboration).Update("user_id", m.target).Error; err != nil {
+				return fmt.Errorf("failed to move collaboration: %w", err)
+			}
+			m.report.CollaborationsMoved++
+		}
+	}
+	return nil
+}
+
+// ownedCollections returns which of the collaborations' collections the
+// target owns, now that the source's collections are the target's
+func (m *merger) ownedCollections(collaborations []database.CollectionCollaborator) (map[uint]bool, error) {
+	ids := make([]uint, 0, len(collaborations))
+	for _, collaboration := range collaborations {
+		ids = append(ids, collaboration.CollectionID)
+	}
+	owned := make(map[uint]bool)
+	if len(ids) == 0 {
+		return owned, nil
+	}
+	var ownedIDs []uint
+	if err := m.tx.Model(&database.Collection{}).Where("id IN ? AND user_id = ?", ids, m.target).Pluck("id", &ownedIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to get collections: %w", err)
+	}
+	for _, id := range ownedIDs {
+		owned[id] = true
+	}
+	return owned, nil
+}
+
+// directShares moves what the source shared with others and what others
+// shared with the source. A share the target received too, or one between
+// the two accounts, is dropped
+func (m *merger) directShares() error {
+	var shares []database.DirectShare
+	if err := m.tx.Where("owner_id = ? OR recipient_id = ?", m.source, m.source).Order("id").Find(&shares).Error; err != nil {
+		return fmt.Errorf("failed to get direct shares: %w", err)
+	}
+
+	for _, share := range shares {
+		owner, recipient := share.OwnerID, share.RecipientID
+		if owner == m.source {
+			owner = m.target
+		}
+		if recipient == m.source {
+			recipient = m.target
+		}
+
+		var existing database.DirectShare
+		err := m.tx.Where("recipient_id = ? AND resource_type = ? AND resource_id = ?", recipient, share.ResourceType, share.ResourceID).
+			First(&existing).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("failed to get direct share: %w", err)
+		}
+		if owner == recipient || (err == nil && existing.ID != share.ID) {
+			if err := m.tx.Delete(&share).Error; err != nil {
+				return fmt.Errorf("failed to delete direct share: %w", err)
+			}
+			resolution := "removed, the target account received it too"
+			if owner == recipient {
+				resolution = "removed, it was shared between the merged accounts"
+			}
+			m.conflict(Conflict{
+				Type: ConflictDirectShare, SourceID: share.ID, TargetID: existing.ID,
+				Detail: fmt.Sprintf("%s %d", share.ResourceType, share.ResourceID), Resolution: resolution,
+			})
+			continue
+		}
+
+		if err := m.tx.Model(&share).Updates(map[string]interface{}{"owner_id": owner, "recipient_id": recipient}).Error; err != nil {
+			return fmt.Errorf("failed to move direct share: %w", err)
+		}
+		m.report.DirectSharesMoved++
+	}
+	return nil
+}
+
+// follows moves who the source follows and who follows the source, in the
+// profile follows and the community follows, which key users by their ID as
+// text. Following a user twice, or the target itself, is dropped
+func (m *merger) follows() error {
+	for _, table := range []struct {
+		name           string
+		source, target interface{}
+	}{
+		{"follows", m.source, m.target},
+		{"user_follows", fmt.Sprint(m.source), fmt.Sprint(m.target)},
+	} {
+		if !m.tx.Migrator().HasTable(table.name) {
+			continue
+		}
+		for _, side := range [][2]string{{"follower_id", "following_id"}, {"following_id", "follower_id"}} {
+			column, other := side[0], side[1]
+
+			var dropped []uint
+			if err := m.tx.Table(table.name).Where(column+" = ?", table.source).
+				Where(fmt.Sprintf("(%s = ? OR %s IN (SELECT %s FROM %s WHERE %s = ? AND deleted_at IS NULL))", other, other, other, table.name, column),
+					table.target, table.target).
+				Pluck("id", &dropped).Error; err != nil {
+				return fmt.Errorf("failed to get %s: %w", table.name, err)
+			}
+			if len(dropped) > 0 {
+				if err := m.tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE id IN ?", table.name), dropped).Error; err != nil {
+					return fmt.Errorf("failed to delete %s: %w", table.name, err)
+				}
+				for _, id := range dropped {
+					m.conflict(Conflict{
+						Type: ConflictFollow, SourceID: id, Detail: table.name,
+						Resolution: "removed, the target account already has it or it would follow itself",
+					})
+				}
+			}
+
+			result := m.tx.Table(table.name).Where(column+" = ?", table.source).Update(column, table.target)
+			if result.Error != nil {
+				return fmt.Errorf("failed to move %s: %w", table.name, result.Error)
+			}
+			m.report.FollowsMoved += int(result.RowsAffected)
+		}
+	}
+	return nil
+}
+
+// preferences adds the preferences only the source account set to the
+// target's; the target's own are kept
+func (m *merger) preferences(source, target *database.User) error {
+	sourcePrefs, targetPrefs := map[string]interface{}{}, map[string]interface{}{}
+	if source.Preferences != "" {
+		if err := json.Unmarshal([]byte(source.Preferences), &sourcePrefs); err != nil {
+			return nil // unreadable preferences are left behind
+		}
+	}
+	if target.Preferences != "" {
+		if err := json.Unmarshal([]byte(target.Preferences), &targetPrefs); err != nil {
+			return nil
+		}
+	}
+
+	for key, value := range sourcePrefs {
+		if _, set := targetPrefs[key]; !set {
+			targetPrefs[key] = value
+			m.report.PreferencesAdded = append(m.report.PreferencesAdded, key)
+		}
+	}
+	if len(m.report.PreferencesAdded) == 0 {
+		return nil
+	}
+	sort.Strings(m.report.PreferencesAdded)
+
+	encoded, err := json.Marshal(targetPrefs)
+	if err != nil {
+		return fmt.Errorf("failed to encode preferences: %w", err)
+	}
+	if err := m.tx.Model(target).Update("preferences", string(encoded)).Error; err != nil {
+		return fmt.Errorf("failed to merge preferences: %w", err)
+	}
+	return nil
+}
+
+// canonical is the form URLs are matched by; URLs it can't parse are
+// matched as they are
+func canonical(rawURL string) string {
+	if normalized, err := urlnorm.Canonical(rawURL); err == nil {
+		return normalized
+	}
+	return rawURL
+}
+
+// unionTags adds the tags of a JSON list the other lacks, keeping its order
+func unionTags(into, from string) string {
+	var list, extra []string
+	json.Unmarshal([]byte(into), &list)
+	json.Unmarshal([]byte(from), &extra)
+
+	seen := make(map[string]bool, len(list))
+	for _, tag := range list {
+		seen[tag] = true
+	}
+	added := false
+	for _, tag := range extra {
+		if !seen[tag] {
+			list = append(list, tag)
+			seen[tag] = true
+			added = true
+		}
+	}
+	if !added {
+		return into
+	}
+	encoded, _ := json.Marshal(list)
+	return string(encoded)
+}
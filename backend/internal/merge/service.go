@@ -0,0 +1,299 @@
+// Package merge folds a user's second account into their first: the
+// bookmarks, collections, shares, follows and preferences of the source
+// account move to the target account, duplicates and name collisions are
+// resolved, and the source account is closed. Users start a merge from the
+// account to give up and confirm it from the account to keep; admins queue
+// merges directly. The worker runs queued merges and stores a report
+package merge
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+// runBatchSize bounds the merges the worker runs at a time
+const runBatchSize = 10
+
+// openStatuses are the statuses of merges not over yet
+var openStatuses = []string{database.MergeStatusAwaitingConfirmation, database.MergeStatusQueued, database.MergeStatusRunning}
+
+var (
+	// ErrSameAccount is returned when merging an account into itself
+	ErrSameAccount = errors.New("an account cannot be merged into itself")
+	// ErrAccountNotFound is returned when either account does not exist
+	ErrAccountNotFound = errors.New("account not found")
+	// ErrInProgress is returned when either account is already part of a
+	// merge that is not over
+	ErrInProgress = errors.New("an account merge is already in progress for this account")
+	// ErrInvalidToken is returned for unknown or expired confirmation
+	// tokens, and for tokens of a merge into another account
+	ErrInvalidToken = errors.New("invalid or expired confirmation token")
+	// ErrNotFound is returned for merges that do not exist or do not
+	// involve the user
+	ErrNotFound = errors.New("account merge not found")
+	// ErrNotCancellable is returned when cancelling a merge that started
+	ErrNotCancellable = errors.New("only merges that have not started can be cancelled")
+)
+
+// Merge is an account merge with its report, once it ran
+type Merge struct {
+	database.AccountMerge
+	Report *Report `json:"report,omitempty"`
+}
+
+// Started is a merge started from the source account, with the token that
+// confirms it from the target account. The token is only shown once
+type Started struct {
+	database.AccountMerge
+	ConfirmationToken string `json:"confirmation_token"`
+}
+
+// Service starts, confirms and runs account merges
+type Service struct {
+	cfg     config.MergeConfig
+	db      *gorm.DB
+	logger  *zap.Logger
+	now     func() time.Time
+	indexer SearchIndexer
+}
+
+// NewService creates an account merge service
+func NewService(cfg config.MergeConfig, db *gorm.DB, logger *zap.Logger) *Service {
+	return &Service{cfg: cfg, db: db, logger: logger, now: time.Now}
+}
+
+// Start starts merging the signed-in account into the account with the
+// target email. It only runs once confirmed from that account, so having
+// one account's credentials is not enough to take over the other's data
+func (s *Service) Start(ctx context.Context, sourceUserID uint, requestedBy, targetEmail string) (*Started, error) {
+	var target database.User
+	if err := s.db.WithContext(ctx).Where("email = ?", strings.TrimSpace(targetEmail)).First(&target).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAccountNotFound
+		}
+		return nil, fmt.Errorf("failed to get target account: %w", err)
+	}
+	if err := s.checkAccounts(ctx, sourceUserID, target.ID); err != nil {
+		return nil, err
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	expiresAt := s.now().Add(time.Duration(s.cfg.ConfirmTTL) * time.Hour)
+	merge := database.AccountMerge{
+		SourceUserID: sourceUserID,
+		TargetUserID: target.ID,
+		RequestedBy:  requestedBy,
+		Status:       database.MergeStatusAwaitingConfirmation,
+		TokenHash:    hashToken(token),
+		ExpiresAt:    &expiresAt,
+	}
+	if err := s.db.WithContext(ctx).Create(&merge).Error; err != nil {
+		return nil, fmt.Errorf("failed to create account merge: %w", err)
+	}
+	return &Started{AccountMerge: merge, ConfirmationToken: token}, nil
+}
+
+// Confirm queues a merge into the signed-in account
+func (s *Service) Confirm(ctx context.Context, targetUserID uint, token string) (*database.AccountMerge, error) {
+	var merge database.AccountMerge
+	err := s.db.WithContext(ctx).
+		Where("token_hash = ? AND status = ? AND expires_at > ?", hashToken(token), database.MergeStatusAwaitingConfirmation, s.now()).
+		First(&merge).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && merge.TargetUserID != targetUserID) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account merge: %w", err)
+	}
+
+	merge.Status = database.MergeStatusQueued
+	merge.TokenHash = ""
+	if err := s.db.WithContext(ctx).Save(&merge).Error; err != nil {
+		return nil, fmt.Errorf("failed to confirm account merge: %w", err)
+	}
+	return &merge, nil
+}
+
+// Queue queues a merge an admin started, which needs no confirmation
+func (s *Service) Queue(ctx context.Context, sourceUserID, targetUserID uint, requestedBy string) (*database.AccountMerge, error) {
+	if err := s.checkAccounts(ctx, sourceUserID, targetUserID); err != nil {
+		return nil, err
+	}
+	merge := database.AccountMerge{
+		SourceUserID: sourceUserID,
+		TargetUserID: targetUserID,
+		RequestedBy:  requestedBy,
+		Status:       database.MergeStatusQueued,
+	}
+	if err := s.db.WithContext(ctx).Create(&merge).Error; err != nil {
+		return nil, fmt.Errorf("failed to create account merge: %w", err)
+	}
+	return &merge, nil
+}
+
+// List returns the merges the user's account is part of, newest first
+func (s *Service) List(ctx context.Context, userID uint) ([]database.AccountMerge, error) {
+	var merges []database.AccountMerge
+	if err := s.db.WithContext(ctx).Where("source_user_id = ? OR target_user_id = ?", userID, userID).
+		Order("created_at DESC").Find(&merges).Error; err != nil {
+		return nil, fmt.Errorf("failed to list account merges: %w", err)
+	}
+	return merges, nil
+}
+
+// Get returns a merge with its report. A user ID of 0 is an admin, who
+// sees every merge
+func (s *Service) Get(ctx context.Context, userID, id uint) (*Merge, error) {
+	merge, err := s.find(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	result := &Merge{AccountMerge: *merge}
+	if merge.Report != "" {
+		result.Report = &Report{}
+		if err := json.Unmarshal([]byte(merge.Report), result.Report); err != nil {
+			return nil, fmt.Errorf("failed to decode merge report: %w", err)
+		}
+	}
+	return result, nil
+}
+
+// Cancel cancels a merge of the user's account that has not started
+func (s *Service) Cancel(ctx context.Context, userID, id uint) (*database.AccountMerge, error) {
+	merge, err := s.find(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	result := s.db.WithContext(ctx).Model(merge).
+		Where("status IN ?", []string{database.MergeStatusAwaitingConfirmation, database.MergeStatusQueued}).
+		Updates(map[string]interface{}{"status": database.MergeStatusCancelled, "token_hash": ""})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to cancel account merge: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrNotCancellable
+	}
+	merge.Status = database.MergeStatusCancelled
+	return merge, nil
+}
+
+// RunQueued runs the queued merges, oldest first. It backs the worker's
+// account merge job; a failed merge is rolled back and keeps its error.
+// The job runs on one replica at a time, so a merge still running was cut
+// short by a crash, its transaction rolled back, and is queued again
+func (s *Service) RunQueued(ctx context.Context) error {
+	if err := s.db.WithContext(ctx).Model(&database.AccountMerge{}).Where("status = ?", database.MergeStatusRunning).
+		Update("status", database.MergeStatusQueued).Error; err != nil {
+		return fmt.Errorf("failed to requeue interrupted account merges: %w", err)
+	}
+
+	var merges []database.AccountMerge
+	if err := s.db.WithContext(ctx).Where("status = ?", database.MergeStatusQueued).
+		Order("created_at").Limit(runBatchSize).Find(&merges).Error; err != nil {
+		return fmt.Errorf("failed to get queued account merges: %w", err)
+	}
+
+	for i := range merges {
+		merge := &merges[i]
+		startedAt := s.now()
+		claimed := s.db.WithContext(ctx).Model(merge).Where("status = ?", database.MergeStatusQueued).
+			Updates(map[string]interface{}{"status": database.MergeStatusRunning, "started_at": startedAt})
+		if claimed.Error != nil {
+			return fmt.Errorf("failed to start account merge: %w", claimed.Error)
+		}
+		if claimed.RowsAffected == 0 {
+			continue // cancelled meanwhile
+		}
+
+		updates := map[string]interface{}{"completed_at": s.now()}
+		report, err := s.Run(ctx, merge)
+		if err != nil {
+			updates["status"] = database.MergeStatusFailed
+			updates["error"] = err.Error()
+			s.logger.Error("Account merge failed", zap.Uint("merge_id", merge.ID), zap.Error(err))
+		} else {
+			encoded, _ := json.Marshal(report)
+			updates["status"] = database.MergeStatusCompleted
+			updates["report"] = string(encoded)
+			s.logger.Info("Accounts merged", zap.Uint("merge_id", merge.ID),
+				zap.Uint("source_user_id", merge.SourceUserID), zap.Uint("target_user_id", merge.TargetUserID))
+		}
+		if err := s.db.WithContext(ctx).Model(merge).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to save account merge: %w", err)
+		}
+	}
+	return nil
+}
+
+// checkAccounts checks that two accounts exist and can be merged
+func (s *Service) checkAccounts(ctx context.Context, sourceUserID, targetUserID uint) error {
+	if sourceUserID == targetUserID {
+		return ErrSameAccount
+	}
+	db := s.db.WithContext(ctx)
+
+	var users int64
+	if err := db.Model(&database.User{}).Where("id IN ?", []uint{sourceUserID, targetUserID}).Count(&users).Error; err != nil {
+		return fmt.Errorf("failed to get accounts: %w", err)
+	}
+	if users != 2 {
+		return ErrAccountNotFound
+	}
+
+	var open int64
+	if err := db.Model(&database.AccountMerge{}).
+		Where("status IN ? AND (expires_at IS NULL OR expires_at > ?)", openStatuses, s.now()).
+		Where("source_user_id IN ? OR target_user_id IN ?", []uint{sourceUserID, targetUserID}, []uint{sourceUserID, targetUserID}).
+		Count(&open).Error; err != nil {
+		return fmt.Errorf("failed to get account merges: %w", err)
+	}
+	if open > 0 {
+		return ErrInProgress
+	}
+	return nil
+}
+
+// find returns a merge the user's account is part of, any merge for admins
+func (s *Service) find(ctx context.Context, userID, id uint) (*database.AccountMerge, error) {
+	query := s.db.WithContext(ctx).Where("id = ?", id)
+	if userID != 0 {
+		query = query.Where("source_user_id = ? OR target_user_id = ?", userID, userID)
+	}
+	var merge database.AccountMerge
+	if err := query.First(&merge).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get account merge: %w", err)
+	}
+	return &merge, nil
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate confirmation token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
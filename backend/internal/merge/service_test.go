@@ -0,0 +1,212 @@
+package merge
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/testfactory"
+)
+
+func setupService(t *testing.T) (*Service, *testfactory.Factory) {
+	db := testfactory.NewDB(t)
+	return NewService(config.MergeConfig{Interval: 1, ConfirmTTL: 24}, db, zap.NewNop()), testfactory.New(t, db)
+}
+
+func TestStartAndConfirm(t *testing.T) {
+	ctx := context.Background()
+	service, factory := setupService(t)
+	source, target, other := factory.User(), factory.User(), factory.User()
+
+	_, err := service.Start(ctx, source.ID, source.Email, source.Email)
+	assert.ErrorIs(t, err, ErrSameAccount)
+	_, err = service.Start(ctx, source.ID, source.Email, "nobody@example.com")
+	assert.ErrorIs(t, err, ErrAccountNotFound)
+
+	started, err := service.Start(ctx, source.ID, source.Email, target.Email)
+	require.NoError(t, err)
+	assert.Equal(t, database.MergeStatusAwaitingConfirmation, started.Status)
+	assert.NotEmpty(t, started.ConfirmationToken)
+
+	_, err = service.Start(ctx, source.ID, source.Email, other.Email)
+	assert.ErrorIs(t, err, ErrInProgress, "an account is in one merge at a time")
+
+	_, err = service.Confirm(ctx, other.ID, started.ConfirmationToken)
+	assert.ErrorIs(t, err, ErrInvalidToken, "only the target account confirms")
+
+	merge, err := service.Confirm(ctx, target.ID, started.ConfirmationToken)
+	require.NoError(t, err)
+	assert.Equal(t, database.MergeStatusQueued, merge.Status)
+	_, err = service.Confirm(ctx, target.ID, started.ConfirmationToken)
+	assert.ErrorIs(t, err, ErrInvalidToken, "tokens are used once")
+
+	_, err = service.Get(ctx, other.ID, merge.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+	cancelled, err := service.Cancel(ctx, source.ID, merge.ID)
+	require.NoError(t, err)
+	assert.Equal(t, database.MergeStatusCancelled, cancelled.Status)
+
+	expired, err := service.Start(ctx, source.ID, source.Email, target.Email)
+	require.NoError(t, err)
+	service.now = func() time.Time { return time.Now().Add(25 * time.Hour) }
+	_, err = service.Confirm(ctx, target.ID, expired.ConfirmationToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	_, err = service.Queue(ctx, source.ID, target.ID, "admin@example.com")
+	assert.NoError(t, err, "expired merges are not in progress")
+}
+
+func TestRunQueued(t *testing.T) {
+	ctx := context.Background()
+	service, factory := setupService(t)
+	db := factory.DB()
+	source := factory.User(func(u *database.User) { u.Preferences = `{"theme": "dark", "language": "fr"}` })
+	target := factory.User(func(u *database.User) { u.Preferences = `{"theme": "light"}` })
+	friend := factory.User()
+
+	// Given: Both accounts saved the same page, have a "Reading" collection
+	// and follow the same user
+	kept := factory.Bookmark(target.ID, func(b *database.Bookmark) { b.URL = "https://go.dev/blog/"; b.Tags = `["go"]` })
+	duplicate := factory.Bookmark(source.ID, func(b *database.Bookmark) {
+		b.URL = "http://www.go.dev/blog#latest"
+		b.Tags = `["go","news"]`
+		b.Description = "The Go blog"
+	})
+	moved := factory.Bookmark(source.ID)
+	factory.Collection(target.ID, func(c *database.Collection) { c.Name = "Reading" })
+	reading := factory.Collection(source.ID, func(c *database.Collection) { c.Name = "reading" })
+	factory.AddToCollection(reading, duplicate, moved)
+	share := factory.Share(reading)
+	for _, follow := range []database.Follow{
+		{FollowerID: source.ID, FollowingID: friend.ID},
+		{FollowerID: target.ID, FollowingID: friend.ID},
+		{FollowerID: source.ID, FollowingID: target.ID},
+		{FollowerID: friend.ID, FollowingID: source.ID},
+	} {
+		require.NoError(t, db.Create(&follow).Error)
+	}
+
+	// When: An admin merges the source account into the target
+	merge, err := service.Queue(ctx, source.ID, target.ID, "admin@example.com")
+	require.NoError(t, err)
+	require.NoError(t, service.RunQueued(ctx))
+
+	// Then: Everything moved, duplicates folded and the source closed
+	result, err := service.Get(ctx, 0, merge.ID)
+	require.NoError(t, err)
+	require.Equal(t, database.MergeStatusCompleted, result.Status, result.Error)
+	report := result.Report
+	require.NotNil(t, report)
+	assert.Equal(t, 1, report.BookmarksMoved)
+	assert.Equal(t, 1, report.BookmarksMerged)
+	assert.Equal(t, 1, report.CollectionsMoved)
+	assert.Equal(t, 1, report.CollectionsRenamed)
+	assert.Equal(t, 1, report.SharesMoved)
+	assert.Equal(t, 1, report.FollowsMoved, "the friend follows the target now")
+	assert.Equal(t, []string{"language"}, report.PreferencesAdded)
+	types := map[string]int{}
+	for _, conflict := range report.Conflicts {
+		types[conflict.Type]++
+	}
+	assert.Equal(t, map[string]int{ConflictDuplicateURL: 1, ConflictCollectionName: 1, ConflictFollow: 2}, types)
+
+	var merged database.Bookmark
+	require.NoError(t, db.First(&merged, kept.ID).Error)
+	assert.JSONEq(t, `["go","news"]`, merged.Tags)
+	assert.Equal(t, "The Go blog", merged.Description)
+	assert.ErrorIs(t, db.First(&database.Bookmark{}, duplicate.ID).Error, gorm.ErrRecordNotFound)
+
+	var renamed database.Collection
+	require.NoError(t, db.Preload("Bookmarks").First(&renamed, reading.ID).Error)
+	assert.Equal(t, target.ID, renamed.UserID)
+	assert.Equal(t, "reading (merged)", renamed.Name)
+	assert.ElementsMatch(t, []uint{kept.ID, moved.ID}, []uint{renamed.Bookmarks[0].ID, renamed.Bookmarks[1].ID})
+
+	var movedShare database.CollectionShare
+	require.NoError(t, db.First(&movedShare, share.ID).Error)
+	assert.Equal(t, target.ID, movedShare.UserID)
+
+	var follows []database.Follow
+	require.NoError(t, db.Order("id").Find(&follows).Error)
+	require.Len(t, follows, 2)
+	assert.Equal(t, [2]uint{target.ID, friend.ID}, [2]uint{follows[0].FollowerID, follows[0].FollowingID})
+	assert.Equal(t, [2]uint{friend.ID, target.ID}, [2]uint{follows[1].FollowerID, follows[1].FollowingID})
+
+	var user database.User
+	require.NoError(t, db.First(&user, target.ID).Error)
+	assert.JSONEq(t, `{"theme": "light", "language": "fr"}`, user.Preferences)
+	assert.ErrorIs(t, db.First(&database.User{}, source.ID).Error, gorm.ErrRecordNotFound, "the source account is closed")
+}
+
+func TestRunQueued_FailureRollsBack(t *testing.T) {
+	ctx := context.Background()
+	service, factory := setupService(t)
+	db := factory.DB()
+	source, target := factory.User(), factory.User()
+	bookmark := factory.Bookmark(source.ID)
+
+	merge, err := service.Queue(ctx, source.ID, target.ID, "admin@example.com")
+	require.NoError(t, err)
+	require.NoError(t, db.Migrator().DropTable(&database.DirectShare{}))
+	require.NoError(t, service.RunQueued(ctx))
+
+	result, err := service.Get(ctx, 0, merge.ID)
+	require.NoError(t, err)
+	assert.Equal(t, database.MergeStatusFailed, result.Status)
+	assert.NotEmpty(t, result.Error)
+	var unchanged database.Bookmark
+	require.NoError(t, db.First(&unchanged, bookmark.ID).Error)
+	assert.Equal(t, source.ID, unchanged.UserID, "a failed merge moves nothing")
+}
+
+// fakeIndexer records what a merge queued for indexing
+type fakeIndexer struct {
+	bookmarks          map[uint]uint // bookmark ID to the owner it was indexed under
+	deletedBookmarks   []uint
+	collections        map[uint]uint
+	deletedCollections []uint
+}
+
+func (f *fakeIndexer) QueueBookmark(bookmark *database.Bookmark) {
+	f.bookmarks[bookmark.ID] = bookmark.UserID
+}
+
+func (f *fakeIndexer) QueueBookmarkDelete(id uint) {
+	f.deletedBookmarks = append(f.deletedBookmarks, id)
+}
+
+func (f *fakeIndexer) QueueCollection(collection *database.Collection) {
+	f.collections[collection.ID] = collection.UserID
+}
+
+func (f *fakeIndexer) QueueCollectionDelete(id uint) {
+	f.deletedCollections = append(f.deletedCollections, id)
+}
+
+func TestRunQueued_ReindexesMovedRows(t *testing.T) {
+	ctx := context.Background()
+	service, factory := setupService(t)
+	indexer := &fakeIndexer{bookmarks: map[uint]uint{}, collections: map[uint]uint{}}
+	service.SetIndexer(indexer)
+	source, target := factory.User(), factory.User()
+
+	factory.Bookmark(target.ID, func(b *database.Bookmark) { b.URL = "https://go.dev/" })
+	duplicate := factory.Bookmark(source.ID, func(b *database.Bookmark) { b.URL = "https://go.dev/" })
+	moved := factory.Bookmark(source.ID)
+	collection := factory.Collection(source.ID)
+
+	_, err := service.Queue(ctx, source.ID, target.ID, "admin@example.com")
+	require.NoError(t, err)
+	require.NoError(t, service.RunQueued(ctx))
+
+	assert.Equal(t, map[uint]uint{moved.ID: target.ID}, indexer.bookmarks)
+	assert.Equal(t, []uint{duplicate.ID}, indexer.deletedBookmarks, "folded duplicates leave the index")
+	assert.Equal(t, map[uint]uint{collection.ID: target.ID}, indexer.collections)
+	assert.Empty(t, indexer.deletedCollections)
+}
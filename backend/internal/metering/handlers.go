@@ -0,0 +1,91 @@
+package metering
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"bookmark-sync-service/backend/pkg/utils"
+)
+
+// Handler serves users their metered feature usage
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new metering handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes registers the usage routes on an authenticated group
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/usage/features", h.GetUsage)
+	router.GET("/usage/features/history", h.GetHistory)
+}
+
+// GetUsage returns the user's feature usage in a period
+// @Summary Get my feature usage
+// @Description Bookmarks created, searches, webhook deliveries, uploaded bytes and API calls in a monthly period
+// @Tags usage
+// @Produce json
+// @Param period query string false "Month as YYYY-MM, the current one by default"
+// @Success 200 {object} PeriodUsage
+// @Failure 400 {object} utils.ErrorResponse
+// @Router /usage/features [get]
+func (h *Handler) GetUsage(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	period, err := h.service.ParsePeriod(c.Query("period"))
+	if err != nil {
+		h.fail(c, err, "Invalid period")
+		return
+	}
+	usage, err := h.service.Usage(c.Request.Context(), userID, period)
+	if err != nil {
+		h.fail(c, err, "Failed to get feature usage")
+		return
+	}
+	utils.SuccessResponse(c, usage, "Feature usage retrieved")
+}
+
+// GetHistory returns the user's feature usage in recent periods
+// @Summary Get my feature usage history
+// @Tags usage
+// @Produce json
+// @Param months query int false "Periods to return, 6 by default and at most 24"
+// @Success 200 {array} PeriodUsage
+// @Router /usage/features/history [get]
+func (h *Handler) GetHistory(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	months, err := strconv.Atoi(c.DefaultQuery("months", "6"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_PARAMETER", "Invalid months parameter", nil)
+		return
+	}
+	history, err := h.service.History(c.Request.Context(), userID, months)
+	if err != nil {
+		h.fail(c, err, "Failed to get feature usage")
+		return
+	}
+	utils.SuccessResponse(c, history, "Feature usage retrieved")
+}
+
+func (h *Handler) fail(c *gin.Context, err error, message string) {
+	if errors.Is(err, ErrInvalidPeriod) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_PERIOD", err.Error(), nil)
+		return
+	}
+	utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", message, nil)
+}
@@ -0,0 +1,18 @@
+package metering
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"bookmark-sync-service/backend/pkg/utils"
+)
+
+// Middleware counts authenticated API requests. It must run after
+// AuthMiddleware
+func (s *Service) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if userID := utils.GetUserIDFromContext(c); userID != 0 {
+			s.Record(userID, MetricAPICalls, 1)
+		}
+		c.Next()
+	}
+}
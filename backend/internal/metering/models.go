@@ -0,0 +1,24 @@
+package metering
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AutoMigrate creates the usage table. It is migrated separately from
+// pkg/database, whose models import the packages this one is used by
+func AutoMigrate(db *gorm.DB) error {
+	return db.AutoMigrate(&UsageRecord{})
+}
+
+// UsageRecord is how much of a metered feature a user used in one monthly
+// period, e.g. bookmarks created or API calls made in October
+type UsageRecord struct {
+	ID        uint      `gorm:"primaryKey" json:"-"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_usage_record_period" json:"user_id"`
+	Period    time.Time `gorm:"not null;uniqueIndex:idx_usage_record_period" json:"period"` // first day of the month, UTC
+	Metric    string    `gorm:"size:40;not null;uniqueIndex:idx_usage_record_period" json:"metric"`
+	Amount    int64     `gorm:"not null;default:0" json:"amount"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
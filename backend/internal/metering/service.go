@@ -0,0 +1,252 @@
+package metering
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"bookmark-sync-service/backend/internal/config"
+)
+
+// Metered features
+const (
+	MetricBookmarksCreated  = "bookmarks_created"
+	MetricSearches          = "searches"
+	MetricWebhookDeliveries = "webhook_deliveries"
+	MetricStorageBytes      = "storage_bytes" // bytes uploaded
+	MetricAPICalls          = "api_calls"
+)
+
+// Metrics lists the metered features in the order they are reported
+var Metrics = []string{MetricBookmarksCreated, MetricSearches, MetricWebhookDeliveries, MetricStorageBytes, MetricAPICalls}
+
+// maxHistory bounds how many periods a history covers
+const maxHistory = 24
+
+// ErrInvalidPeriod is returned for a period that isn't a YYYY-MM month
+var ErrInvalidPeriod = errors.New("period must be a month as YYYY-MM")
+
+// usageKey identifies one row of the usage table
+type usageKey struct {
+	userID uint
+	period time.Time
+	metric string
+}
+
+// Service records feature usage per user and monthly period. Usage is
+// added up in memory and written to the usage table in batches, so the
+// features it meters don't each pay for a write
+type Service struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	now    func() time.Time
+
+	flushInterval time.Duration
+
+	mu      sync.Mutex
+	pending map[usageKey]int64
+	flushMu sync.Mutex
+}
+
+// NewService creates a metering service
+func NewService(db *gorm.DB, logger *zap.Logger) *Service {
+	return &Service{
+		db:            db,
+		logger:        logger,
+		now:           time.Now,
+		flushInterval: config.MeteringFlushInterval,
+		pending:       make(map[usageKey]int64),
+	}
+}
+
+// Record adds amount to a user's usage of a metric in the current period.
+// It is written by the next flush
+func (s *Service) Record(userID uint, metric string, amount int64) {
+	if userID == 0 || amount == 0 {
+		return
+	}
+	key := usageKey{userID: userID, period: PeriodStart(s.now()), metric: metric}
+
+	s.mu.Lock()
+	s.pending[key] += amount
+	s.mu.Unlock()
+}
+
+// Flush writes the recorded usage. Usage that fails to be written is kept
+// for the next flush
+func (s *Service) Flush(ctx context.Context) error {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+
+	s.mu.Lock()
+	pending := s.pending
+	s.pending = make(map[usageKey]int64)
+	s.mu.Unlock()
+
+	var errs []error
+	for key, amount := range pending {
+		if err := s.add(ctx, key, amount); err != nil {
+			errs = append(errs, err)
+			s.mu.Lock()
+			s.pending[key] += amount
+			s.mu.Unlock()
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Run flushes recorded usage every flush interval until ctx is done, then
+// flushes once more
+func (s *Service) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), config.WorkerShutdownTimeout)
+			defer cancel()
+			if err := s.Flush(shutdownCtx); err != nil {
+				s.logger.Warn("Final usage flush failed", zap.Error(err))
+			}
+			return
+		case <-ticker.C:
+		}
+
+		if err := s.Flush(ctx); err != nil {
+			s.logger.Warn("Usage flush failed", zap.Error(err))
+		}
+	}
+}
+
+// PeriodUsage is a user's usage of every metered feature in one period
+type PeriodUsage struct {
+	Period   string           `json:"period"` // YYYY-MM
+	StartsAt time.Time        `json:"starts_at"`
+	EndsAt   time.Time        `json:"ends_at"`
+	Metrics  map[string]int64 `json:"metrics"`
+}
+
+// Usage returns a user's usage in the period starting at period, including
+// usage recorded but not flushed yet
+func (s *Service) Usage(ctx context.Context, userID uint, period time.Time) (*PeriodUsage, error) {
+	history, err := s.usage(ctx, userID, PeriodStart(period), PeriodStart(period))
+	if err != nil {
+		return nil, err
+	}
+	return &history[0], nil
+}
+
+// History returns a user's usage in the last months periods, the current
+// one first
+func (s *Service) History(ctx context.Context, userID uint, months int) ([]PeriodUsage, error) {
+	if months < 1 {
+		months = 1
+	}
+	if months > maxHistory {
+		months = maxHistory
+	}
+	current := PeriodStart(s.now())
+	history, err := s.usage(ctx, userID, current.AddDate(0, -(months-1), 0), current)
+	if err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(history)-1; i < j; i, j = i+1, j-1 {
+		history[i], history[j] = history[j], history[i]
+	}
+	return history, nil
+}
+
+// Total returns how much of a metric a user used in the current period,
+// for limits to compare against a plan's allowance
+func (s *Service) Total(ctx context.Context, userID uint, metric string) (int64, error) {
+	usage, err := s.Usage(ctx, userID, s.now())
+	if err != nil {
+		return 0, err
+	}
+	return usage.Metrics[metric], nil
+}
+
+// usage reads the periods from first to last, oldest first, with every
+// metric present
+func (s *Service) usage(ctx context.Context, userID uint, first, last time.Time) ([]PeriodUsage, error) {
+	var records []UsageRecord
+	if err := s.db.WithContext(ctx).
+		Where("user_id = ? AND period >= ? AND period <= ?", userID, first, last).
+		Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to load usage: %w", err)
+	}
+
+	var history []PeriodUsage
+	index := make(map[time.Time]int)
+	for period := first; !period.After(last); period = period.AddDate(0, 1, 0) {
+		usage := PeriodUsage{
+			Period:   period.Format("2006-01"),
+			StartsAt: period,
+			EndsAt:   period.AddDate(0, 1, 0),
+			Metrics:  make(map[string]int64, len(Metrics)),
+		}
+		for _, metric := range Metrics {
+			usage.Metrics[metric] = 0
+		}
+		index[period] = len(history)
+		history = append(history, usage)
+	}
+
+	add := func(period time.Time, metric string, amount int64) {
+		if i, ok := index[period.UTC()]; ok {
+			history[i].Metrics[metric] += amount
+		}
+	}
+	for _, record := range records {
+		add(record.Period, record.Metric, record.Amount)
+	}
+	s.mu.Lock()
+	for key, amount := range s.pending {
+		if key.userID == userID {
+			add(key.period, key.metric, amount)
+		}
+	}
+	s.mu.Unlock()
+	return history, nil
+}
+
+// add adds an amount to a row of the usage table, creating it if needed
+func (s *Service) add(ctx context.Context, key usageKey, amount int64) error {
+	record := &UsageRecord{UserID: key.userID, Period: key.period, Metric: key.metric, Amount: amount}
+	err := s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "period"}, {Name: "metric"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"amount":     gorm.Expr("amount + ?", amount),
+			"updated_at": s.now(),
+		}),
+	}).Create(record).Error
+	if err != nil {
+		return fmt.Errorf("failed to record %s usage: %w", key.metric, err)
+	}
+	return nil
+}
+
+// PeriodStart returns the start of the monthly period t falls in
+func PeriodStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// ParsePeriod parses a YYYY-MM period; empty is the current one
+func (s *Service) ParsePeriod(value string) (time.Time, error) {
+	if value == "" {
+		return PeriodStart(s.now()), nil
+	}
+	period, err := time.Parse("2006-01", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %s", ErrInvalidPeriod, value)
+	}
+	return period, nil
+}
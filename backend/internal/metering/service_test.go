@@ -0,0 +1,137 @@
+package metering
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type testEnv struct {
+	service *Service
+	now     time.Time
+}
+
+func setupService(t *testing.T) *testEnv {
+	// testfactory migrates models that import this package
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	require.NoError(t, AutoMigrate(db))
+
+	env := &testEnv{now: time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)}
+	env.service = NewService(db, zap.NewNop())
+	env.service.now = func() time.Time { return env.now }
+	return env
+}
+
+func TestRecordAndFlush(t *testing.T) {
+	env := setupService(t)
+	ctx := context.Background()
+
+	env.service.Record(1, MetricBookmarksCreated, 1)
+	env.service.Record(1, MetricBookmarksCreated, 1)
+	env.service.Record(1, MetricStorageBytes, 2048)
+	env.service.Record(2, MetricSearches, 1)
+	env.service.Record(0, MetricAPICalls, 1)
+
+	// Usage not flushed yet is already counted
+	usage, err := env.service.Usage(ctx, 1, env.now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), usage.Metrics[MetricBookmarksCreated])
+
+	require.NoError(t, env.service.Flush(ctx))
+	env.service.Record(1, MetricBookmarksCreated, 3)
+	require.NoError(t, env.service.Flush(ctx))
+
+	var records []UsageRecord
+	require.NoError(t, env.service.db.Order("user_id, metric").Find(&records).Error)
+	require.Len(t, records, 3)
+	assert.Equal(t, int64(5), records[0].Amount)
+
+	usage, err = env.service.Usage(ctx, 1, env.now)
+	require.NoError(t, err)
+	assert.Equal(t, "2026-10", usage.Period)
+	assert.Equal(t, time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC), usage.EndsAt)
+	assert.Equal(t, map[string]int64{
+		MetricBookmarksCreated:  5,
+		MetricSearches:          0,
+		MetricWebhookDeliveries: 0,
+		MetricStorageBytes:      2048,
+		MetricAPICalls:          0,
+	}, usage.Metrics)
+
+	total, err := env.service.Total(ctx, 2, MetricSearches)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+}
+
+func TestHistorySplitsPeriods(t *testing.T) {
+	env := setupService(t)
+	ctx := context.Background()
+
+	env.now = time.Date(2026, 8, 31, 23, 0, 0, 0, time.UTC)
+	env.service.Record(1, MetricAPICalls, 4)
+	env.now = time.Date(2026, 10, 1, 0, 30, 0, 0, time.UTC)
+	env.service.Record(1, MetricAPICalls, 1)
+	require.NoError(t, env.service.Flush(ctx))
+
+	history, err := env.service.History(ctx, 1, 3)
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	assert.Equal(t, []string{"2026-10", "2026-09", "2026-08"}, []string{history[0].Period, history[1].Period, history[2].Period})
+	assert.Equal(t, int64(1), history[0].Metrics[MetricAPICalls])
+	assert.Equal(t, int64(0), history[1].Metrics[MetricAPICalls])
+	assert.Equal(t, int64(4), history[2].Metrics[MetricAPICalls])
+}
+
+func TestParsePeriod(t *testing.T) {
+	env := setupService(t)
+
+	period, err := env.service.ParsePeriod("")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), period)
+
+	period, err = env.service.ParsePeriod("2026-03")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), period)
+
+	_, err = env.service.ParsePeriod("March")
+	assert.True(t, errors.Is(err, ErrInvalidPeriod))
+}
+
+func TestMiddlewareCountsAuthenticatedRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	env := setupService(t)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if user := c.GetHeader("X-User"); user != "" {
+			c.Set("user_id", user)
+		}
+	}, env.service.Middleware())
+	router.GET("/ping", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	for _, user := range []string{"7", "7", ""} {
+		req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+		req.Header.Set("X-User", user)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	total, err := env.service.Total(context.Background(), 7, MetricAPICalls)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+}
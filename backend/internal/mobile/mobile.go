@@ -0,0 +1,98 @@
+// Package mobile trims API responses for mobile clients that ask for the
+// mobile profile, cutting what they download on metered connections
+package mobile
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+
+	"bookmark-sync-service/backend/internal/screenshot"
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+const (
+	// Profile is the profile query value, e.g. GET /bookmarks?profile=mobile
+	Profile = "mobile"
+	// ProfileHeader selects the profile for every request of a client
+	ProfileHeader = "X-API-Profile"
+
+	// MaxDescriptionLength caps descriptions in mobile payloads, in runes
+	MaxDescriptionLength = 200
+	// MaxTitleLength caps titles in mobile payloads, in runes
+	MaxTitleLength = 120
+)
+
+// Requested reports whether the client asked for the mobile profile. A
+// request with the header varies by it, so caches keep both payloads apart
+func Requested(c *gin.Context) bool {
+	if c.Query("profile") == Profile {
+		return true
+	}
+	c.Writer.Header().Add("Vary", ProfileHeader)
+	return strings.EqualFold(c.GetHeader(ProfileHeader), Profile)
+}
+
+// Bookmark is a bookmark in mobile payloads: no nested user or collections,
+// no notes or metadata, a thumbnail instead of the full screenshot
+type Bookmark struct {
+	ID           uint      `json:"id"`
+	URL          string    `json:"url"`
+	Title        string    `json:"title"`
+	Description  string    `json:"description,omitempty"`
+	Favicon      string    `json:"favicon,omitempty"`
+	ThumbnailURL string    `json:"thumbnail_url,omitempty"`
+	Tags         []string  `json:"tags,omitempty"`
+	Language     string    `json:"language,omitempty"`
+	Status       string    `json:"status,omitempty"`
+	HasNotes     bool      `json:"has_notes,omitempty"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// FromBookmark trims a bookmark for the mobile profile
+func FromBookmark(bookmark *database.Bookmark) Bookmark {
+	var tags []string
+	if bookmark.Tags != "" {
+		_ = json.Unmarshal([]byte(bookmark.Tags), &tags) // malformed tags are left out
+	}
+	return Bookmark{
+		ID:           bookmark.ID,
+		URL:          bookmark.URL,
+		Title:        Truncate(bookmark.Title, MaxTitleLength),
+		Description:  Truncate(bookmark.Description, MaxDescriptionLength),
+		Favicon:      bookmark.Favicon,
+		ThumbnailURL: screenshot.ThumbnailURL(bookmark.Screenshot),
+		Tags:         tags,
+		Language:     bookmark.Language,
+		Status:       bookmark.Status,
+		HasNotes:     bookmark.Notes != "",
+		UpdatedAt:    bookmark.UpdatedAt,
+	}
+}
+
+// FromBookmarks trims a list of bookmarks for the mobile profile
+func FromBookmarks(bookmarks []*database.Bookmark) []Bookmark {
+	trimmed := make([]Bookmark, len(bookmarks))
+	for i, bookmark := range bookmarks {
+		trimmed[i] = FromBookmark(bookmark)
+	}
+	return trimmed
+}
+
+// Truncate shortens text to at most max runes, ending cut text with an
+// ellipsis on a word boundary where one is close
+func Truncate(text string, max int) string {
+	text = strings.TrimSpace(text)
+	if utf8.RuneCountInString(text) <= max {
+		return text
+	}
+	runes := []rune(text)
+	cut := string(runes[:max-1])
+	if space := strings.LastIndexAny(cut, " \t\n"); space > len(cut)*3/4 {
+		cut = cut[:space]
+	}
+	return strings.TrimRight(cut, " \t\n.,;:") + "…"
+}
@@ -0,0 +1,58 @@
+package mobile
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+func TestRequested(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	context := func(target, header string) (*gin.Context, *httptest.ResponseRecorder) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("GET", target, nil)
+		if header != "" {
+			c.Request.Header.Set(ProfileHeader, header)
+		}
+		return c, w
+	}
+
+	c, _ := context("/bookmarks?profile=mobile", "")
+	assert.True(t, Requested(c))
+	c, w := context("/bookmarks", "Mobile")
+	assert.True(t, Requested(c))
+	assert.Equal(t, ProfileHeader, w.Header().Get("Vary"))
+	c, _ = context("/bookmarks?profile=full", "")
+	assert.False(t, Requested(c))
+}
+
+func TestFromBookmark(t *testing.T) {
+	bookmark := &database.Bookmark{
+		URL:        "https://example.com",
+		Title:      "Example",
+		Screenshot: "https://cdn.example.com/screenshots/7.png?v=2",
+		Tags:       "not json",
+	}
+	bookmark.ID = 7
+
+	trimmed := FromBookmark(bookmark)
+	assert.Equal(t, uint(7), trimmed.ID)
+	assert.Equal(t, "https://cdn.example.com/screenshots/7_thumb.png?v=2", trimmed.ThumbnailURL)
+	assert.Nil(t, trimmed.Tags)
+	assert.False(t, trimmed.HasNotes)
+
+	assert.Empty(t, FromBookmark(&database.Bookmark{}).ThumbnailURL)
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "short", Truncate("  short ", 10))
+	assert.Equal(t, "the quick brown…", Truncate("the quick brown fox jumps", 20))
+	assert.Equal(t, "abcdefghi…", Truncate(strings.Repeat("abcdefghij", 3), 10))
+	assert.Equal(t, "書籤同步服…", Truncate("書籤同步服務很好用", 6))
+}
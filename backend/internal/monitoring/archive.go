@@ -0,0 +1,368 @@
+package monitoring
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/internal/config"
+	redispkg "bookmark-sync-service/backend/pkg/redis"
+)
+
+// ErrCaptureFailed is returned when an archived page cannot be fetched
+var ErrCaptureFailed = errors.New("failed to capture page")
+
+// ChangeTypeContent marks notifications about changed page content
+const ChangeTypeContent = "content_change"
+
+// archiveBlocks are the elements whose text becomes snapshot lines
+const archiveBlocks = "h1, h2, h3, h4, h5, h6, p, li, pre, blockquote, dt, dd, th, td, figcaption"
+
+// ArchiveCapture is the outcome of capturing a bookmark's page
+type ArchiveCapture struct {
+	Snapshot *ArchiveSnapshot `json:"snapshot"`
+	// Changed is false when the page matched the latest snapshot, in which
+	// case no new version was stored
+	Changed  bool         `json:"changed"`
+	Summary  *DiffSummary `json:"summary,omitempty"` // against the previous version
+	Notified bool         `json:"notified"`
+}
+
+// ArchiveDiff compares two snapshot versions of a bookmark
+type ArchiveDiff struct {
+	BookmarkID uint             `json:"bookmark_id"`
+	From       *ArchiveSnapshot `json:"from"`
+	To         *ArchiveSnapshot `json:"to"`
+	Summary    DiffSummary      `json:"summary"`
+	Changes    []DiffLine       `json:"changes"` // added and removed lines only
+}
+
+// SetArchiveConfig sets how archived bookmarks are re-captured and when a
+// change is worth an alert
+func (s *Service) SetArchiveConfig(cfg config.ArchiveConfig) {
+	s.archive = cfg
+}
+
+// EnableLeaderElection makes only one replica re-capture archived pages
+func (s *Service) EnableLeaderElection(client *redispkg.Client) {
+	s.leader = client.NewLeader("archive-recapture", 2*config.ArchiveRecaptureTick)
+}
+
+// CaptureSnapshot archives the current text of a bookmarked page. A page that
+// differs from the latest snapshot is stored as a new version, and the owner
+// is notified when the change reaches the configured threshold
+func (s *Service) CaptureSnapshot(ctx context.Context, userID, bookmarkID uint) (*ArchiveCapture, error) {
+	var bookmark struct {
+		ID  uint
+		URL string
+	}
+	if err := s.db.WithContext(ctx).
+		Table("bookmarks").
+		Select("id, url").
+		Where("id = ? AND user_id = ? AND deleted_at IS NULL", bookmarkID, userID).
+		First(&bookmark).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("bookmark not found")
+		}
+		return nil, fmt.Errorf("failed to verify bookmark: %w", err)
+	}
+
+	latest, err := s.latestSnapshot(ctx, bookmarkID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	page, err := s.fetchArchivePage(ctx, bookmark.URL)
+	if err != nil {
+		// Failed attempts still count as checks so they don't hold up the queue
+		if latest != nil {
+			s.db.WithContext(ctx).Model(latest).Update("checked_at", now)
+		}
+		return nil, err
+	}
+
+	if latest != nil && latest.ContentHash == page.ContentHash {
+		if err := s.db.WithContext(ctx).Model(latest).Update("checked_at", now).Error; err != nil {
+			return nil, fmt.Errorf("failed to update snapshot: %w", err)
+		}
+		return &ArchiveCapture{Snapshot: latest}, nil
+	}
+
+	page.UserID = userID
+	page.BookmarkID = bookmarkID
+	page.Version = 1
+	page.CapturedAt = now
+	page.CheckedAt = now
+	if latest != nil {
+		page.Version = latest.Version + 1
+	}
+	if err := s.db.WithContext(ctx).Create(page).Error; err != nil {
+		return nil, fmt.Errorf("failed to save snapshot: %w", err)
+	}
+
+	capture := &ArchiveCapture{Snapshot: page, Changed: true}
+	if latest != nil {
+		summary := summarizeDiff(diffLines(snapshotLines(latest), snapshotLines(page)))
+		capture.Summary = &summary
+
+		if summary.ChangeRatio > 0 && summary.ChangeRatio >= s.archive.ChangeThreshold {
+			notification := &LinkChangeNotification{
+				UserID:     userID,
+				BookmarkID: bookmarkID,
+				ChangeType: ChangeTypeContent,
+				OldValue:   fmt.Sprintf("v%d", latest.Version),
+				NewValue:   fmt.Sprintf("v%d", page.Version),
+				Message:    contentChangeMessage(page.URL, summary),
+			}
+			if err := s.db.WithContext(ctx).Create(notification).Error; err != nil {
+				return nil, fmt.Errorf("failed to create notification: %w", err)
+			}
+			capture.Notified = true
+		}
+	}
+
+	if err := s.pruneSnapshots(ctx, bookmarkID, page.Version); err != nil {
+		return nil, err
+	}
+
+	return capture, nil
+}
+
+// ListSnapshots lists a bookmark's snapshots, newest first, without content
+func (s *Service) ListSnapshots(ctx context.Context, userID, bookmarkID uint) ([]*ArchiveSnapshot, error) {
+	var snapshots []*ArchiveSnapshot
+	if err := s.db.WithContext(ctx).
+		Omit("content").
+		Where("bookmark_id = ? AND user_id = ?", bookmarkID, userID).
+		Order("version DESC").
+		Find(&snapshots).Error; err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+	return snapshots, nil
+}
+
+// DiffSnapshots compares two versions of a bookmark's archive
+func (s *Service) DiffSnapshots(ctx context.Context, userID, bookmarkID uint, fromVersion, toVersion int) (*ArchiveDiff, error) {
+	from, err := s.getSnapshot(ctx, userID, bookmarkID, fromVersion)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.getSnapshot(ctx, userID, bookmarkID, toVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := diffLines(snapshotLines(from), snapshotLines(to))
+	from.Content, to.Content = "", ""
+
+	return &ArchiveDiff{
+		BookmarkID: bookmarkID,
+		From:       from,
+		To:         to,
+		Summary:    summarizeDiff(diff),
+		Changes:    changedLines(diff),
+	}, nil
+}
+
+// RecaptureArchives re-captures archived bookmarks not checked within the
+// configured interval, oldest first, and returns how many were captured
+func (s *Service) RecaptureArchives(ctx context.Context) (int, error) {
+	cutoff := time.Now().UTC().Add(-time.Duration(s.archive.RecaptureInterval) * time.Hour)
+
+	var due []struct {
+		BookmarkID uint
+		UserID     uint
+	}
+	if err := s.db.WithContext(ctx).
+		Table("archive_snapshots AS s").
+		Select("s.bookmark_id, s.user_id").
+		Joins("JOIN bookmarks ON bookmarks.id = s.bookmark_id AND bookmarks.deleted_at IS NULL").
+		Where("s.deleted_at IS NULL AND s.checked_at < ?", cutoff).
+		Where("s.version = (SELECT MAX(version) FROM archive_snapshots WHERE bookmark_id = s.bookmark_id AND deleted_at IS NULL)").
+		Order("s.checked_at").
+		Limit(config.ArchiveRecaptureBatch).
+		Scan(&due).Error; err != nil {
+		return 0, fmt.Errorf("failed to find snapshots to re-capture: %w", err)
+	}
+
+	captured := 0
+	for _, item := range due {
+		if ctx.Err() != nil {
+			break
+		}
+		if _, err := s.CaptureSnapshot(ctx, item.UserID, item.BookmarkID); err == nil {
+			captured++
+		}
+	}
+	return captured, nil
+}
+
+// RunArchiveRecapture re-captures stale snapshots until the context is
+// cancelled. It returns immediately when re-capture is disabled
+func (s *Service) RunArchiveRecapture(ctx context.Context) {
+	if s.archive.RecaptureInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(config.ArchiveRecaptureTick)
+	defer ticker.Stop()
+
+	for {
+		if s.leadsRecapture(ctx) {
+			if _, err := s.RecaptureArchives(ctx); err != nil {
+				s.logger.Error("Archive re-capture failed", zap.Error(err))
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// leadsRecapture reports whether this replica should re-capture. Without a
+// leader every replica does, which fetches pages more often than needed
+func (s *Service) leadsRecapture(ctx context.Context) bool {
+	if s.leader == nil {
+		return true
+	}
+	leading, _ := s.leader.Campaign(ctx)
+	return leading
+}
+
+// fetchArchivePage downloads a page and extracts its title and text
+func (s *Service) fetchArchivePage(ctx context.Context, url string) (*ArchiveSnapshot, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCaptureFailed, err)
+	}
+	req.Header.Set("User-Agent", "bookmark-sync-service/"+config.Version)
+
+	resp, err := s.archiveClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCaptureFailed, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%w: HTTP %d", ErrCaptureFailed, resp.StatusCode)
+	}
+
+	doc, err := goquery.NewDocumentFromReader(io.LimitReader(resp.Body, config.MaxArchivePageSize))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCaptureFailed, err)
+	}
+
+	content := extractArchiveText(doc)
+	hash := sha256.Sum256([]byte(content))
+
+	return &ArchiveSnapshot{
+		URL:         resp.Request.URL.String(),
+		Title:       strings.TrimSpace(doc.Find("title").First().Text()),
+		Content:     content,
+		ContentHash: hex.EncodeToString(hash[:]),
+		StatusCode:  resp.StatusCode,
+	}, nil
+}
+
+// extractArchiveText returns the readable text of a page, one block per
+// line, so diffs line up with paragraphs rather than markup
+func extractArchiveText(doc *goquery.Document) string {
+	doc.Find("script, style, noscript, template, svg, iframe").Remove()
+
+	root := doc.Find("main, article, [role='main']").First()
+	if root.Length() == 0 {
+		root = doc.Find("body")
+	}
+
+	var lines []string
+	root.Find(archiveBlocks).Each(func(_ int, block *goquery.Selection) {
+		// Nested blocks contribute their own lines
+		if block.Find(archiveBlocks).Length() > 0 {
+			return
+		}
+		if text := strings.Join(strings.Fields(block.Text()), " "); text != "" {
+			lines = append(lines, text)
+		}
+	})
+
+	if len(lines) == 0 {
+		for _, line := range strings.Split(root.Text(), "\n") {
+			if text := strings.Join(strings.Fields(line), " "); text != "" {
+				lines = append(lines, text)
+			}
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// snapshotLines splits a snapshot's content into lines
+func snapshotLines(snapshot *ArchiveSnapshot) []string {
+	if snapshot.Content == "" {
+		return nil
+	}
+	return strings.Split(snapshot.Content, "\n")
+}
+
+// contentChangeMessage describes a content change for a notification
+func contentChangeMessage(url string, summary DiffSummary) string {
+	return fmt.Sprintf("Content changed (%d lines added, %d removed, %.0f%% of the page): %s",
+		summary.Added, summary.Removed, summary.ChangeRatio*100, url)
+}
+
+// latestSnapshot returns the newest snapshot of a bookmark, or nil
+func (s *Service) latestSnapshot(ctx context.Context, bookmarkID uint) (*ArchiveSnapshot, error) {
+	var snapshots []*ArchiveSnapshot
+	if err := s.db.WithContext(ctx).
+		Where("bookmark_id = ?", bookmarkID).
+		Order("version DESC").
+		Limit(1).
+		Find(&snapshots).Error; err != nil {
+		return nil, fmt.Errorf("failed to get latest snapshot: %w", err)
+	}
+	if len(snapshots) == 0 {
+		return nil, nil
+	}
+	return snapshots[0], nil
+}
+
+// getSnapshot returns one version of a bookmark's archive
+func (s *Service) getSnapshot(ctx context.Context, userID, bookmarkID uint, version int) (*ArchiveSnapshot, error) {
+	var snapshot ArchiveSnapshot
+	if err := s.db.WithContext(ctx).
+		Where("bookmark_id = ? AND user_id = ? AND version = ?", bookmarkID, userID, version).
+		First(&snapshot).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("snapshot not found")
+		}
+		return nil, fmt.Errorf("failed to get snapshot: %w", err)
+	}
+	return &snapshot, nil
+}
+
+// pruneSnapshots deletes versions beyond the configured number kept
+func (s *Service) pruneSnapshots(ctx context.Context, bookmarkID uint, latestVersion int) error {
+	if s.archive.MaxVersions <= 0 {
+		return nil
+	}
+	if err := s.db.WithContext(ctx).Unscoped().
+		Where("bookmark_id = ? AND version <= ?", bookmarkID, latestVersion-s.archive.MaxVersions).
+		Delete(&ArchiveSnapshot{}).Error; err != nil {
+		return fmt.Errorf("failed to prune snapshots: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,284 @@
+package monitoring
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/internal/config"
+)
+
+// pageServer serves an HTML page whose paragraphs can be swapped
+type pageServer struct {
+	*httptest.Server
+	mu         sync.Mutex
+	paragraphs []string
+}
+
+func newPageServer(paragraphs ...string) *pageServer {
+	p := &pageServer{paragraphs: paragraphs}
+	p.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		fmt.Fprint(w, "<html><head><title>Article</title><script>var x = 1;</script></head><body><nav>Home</nav><main>")
+		for _, text := range p.paragraphs {
+			fmt.Fprintf(w, "<p>%s</p>", text)
+		}
+		fmt.Fprint(w, "</main></body></html>")
+	}))
+	return p
+}
+
+func (p *pageServer) set(paragraphs ...string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.paragraphs = paragraphs
+}
+
+func setupArchiveService(t *testing.T) (*Service, *gorm.DB) {
+	service, db := setupTestService(t)
+	require.NoError(t, db.AutoMigrate(&ArchiveSnapshot{}))
+	service.SetArchiveConfig(config.ArchiveConfig{RecaptureInterval: 24, ChangeThreshold: 0.2, MaxVersions: 3})
+	return service, db
+}
+
+func contentNotifications(t *testing.T, db *gorm.DB) []LinkChangeNotification {
+	var notifications []LinkChangeNotification
+	require.NoError(t, db.Where("change_type = ?", ChangeTypeContent).Find(&notifications).Error)
+	return notifications
+}
+
+func TestDiffLines(t *testing.T) {
+	diff := diffLines(
+		[]string{"intro", "old one", "shared", "old two", "outro"},
+		[]string{"intro", "shared", "new", "outro", "extra"},
+	)
+
+	assert.Equal(t, []DiffLine{
+		{Op: DiffDelete, Text: "old one", OldLine: 2},
+		{Op: DiffDelete, Text: "old two", OldLine: 4},
+		{Op: DiffInsert, Text: "new", NewLine: 3},
+		{Op: DiffInsert, Text: "extra", NewLine: 5},
+	}, changedLines(diff))
+
+	summary := summarizeDiff(diff)
+	assert.Equal(t, 2, summary.Added)
+	assert.Equal(t, 2, summary.Removed)
+	assert.Equal(t, 3, summary.Unchanged)
+	assert.InDelta(t, 0.4, summary.ChangeRatio, 0.001)
+
+	assert.Equal(t, 0.0, summarizeDiff(diffLines([]string{"same"}, []string{"same"})).ChangeRatio)
+	assert.Equal(t, 1.0, summarizeDiff(diffLines([]string{"a"}, []string{"b"})).ChangeRatio)
+}
+
+func TestDiffLines_TooLongToAlign(t *testing.T) {
+	oldLines := make([]string, config.MaxArchiveDiffLines+1)
+	newLines := make([]string, config.MaxArchiveDiffLines+1)
+	for i := range oldLines {
+		oldLines[i] = fmt.Sprintf("old %d", i)
+		newLines[i] = fmt.Sprintf("new %d", i)
+	}
+
+	summary := summarizeDiff(diffLines(oldLines, newLines))
+	assert.Equal(t, len(newLines), summary.Added)
+	assert.Equal(t, len(oldLines), summary.Removed)
+}
+
+func TestService_CaptureSnapshot_Versions(t *testing.T) {
+	service, db := setupArchiveService(t)
+	ctx := context.Background()
+	page := newPageServer("First paragraph.", "Second paragraph.", "Third paragraph.", "Fourth paragraph.", "Fifth paragraph.")
+	defer page.Close()
+	bookmarkID := createTestBookmark(t, db, 1, page.URL)
+
+	// First capture stores version 1 without a notification
+	first, err := service.CaptureSnapshot(ctx, 1, bookmarkID)
+	require.NoError(t, err)
+	assert.True(t, first.Changed)
+	assert.Equal(t, 1, first.Snapshot.Version)
+	assert.Equal(t, "Article", first.Snapshot.Title)
+	assert.Equal(t, "First paragraph.\nSecond paragraph.\nThird paragraph.\nFourth paragraph.\nFifth paragraph.", first.Snapshot.Content)
+	assert.Empty(t, contentNotifications(t, db))
+
+	// An unchanged page stores nothing new
+	same, err := service.CaptureSnapshot(ctx, 1, bookmarkID)
+	require.NoError(t, err)
+	assert.False(t, same.Changed)
+	assert.Equal(t, 1, same.Snapshot.Version)
+
+	// A small edit is stored but stays below the alert threshold
+	page.set("First paragraph.", "Second paragraph.", "Third paragraph.", "Fourth paragraph.", "Fifth paragraph.", "Sixth.")
+	small, err := service.CaptureSnapshot(ctx, 1, bookmarkID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, small.Snapshot.Version)
+	assert.False(t, small.Notified)
+	assert.Equal(t, 1, small.Summary.Added)
+	assert.Empty(t, contentNotifications(t, db))
+
+	// A rewrite crosses it and alerts the owner
+	page.set("Entirely new text.", "Second paragraph.")
+	big, err := service.CaptureSnapshot(ctx, 1, bookmarkID)
+	require.NoError(t, err)
+	assert.Equal(t, 3, big.Snapshot.Version)
+	assert.True(t, big.Notified)
+
+	notifications := contentNotifications(t, db)
+	require.Len(t, notifications, 1)
+	assert.Equal(t, bookmarkID, notifications[0].BookmarkID)
+	assert.Equal(t, "v2", notifications[0].OldValue)
+	assert.Equal(t, "v3", notifications[0].NewValue)
+	assert.Contains(t, notifications[0].Message, "1 lines added, 5 removed")
+
+	// Only the newest MaxVersions are kept
+	page.set("Yet another version.")
+	_, err = service.CaptureSnapshot(ctx, 1, bookmarkID)
+	require.NoError(t, err)
+	snapshots, err := service.ListSnapshots(ctx, 1, bookmarkID)
+	require.NoError(t, err)
+	require.Len(t, snapshots, 3)
+	assert.Equal(t, 4, snapshots[0].Version)
+	assert.Equal(t, 2, snapshots[2].Version)
+	assert.Empty(t, snapshots[0].Content)
+}
+
+func TestService_CaptureSnapshot_Errors(t *testing.T) {
+	service, db := setupArchiveService(t)
+	ctx := context.Background()
+
+	_, err := service.CaptureSnapshot(ctx, 1, 999)
+	assert.EqualError(t, err, "bookmark not found")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	bookmarkID := createTestBookmark(t, db, 1, server.URL)
+
+	_, err = service.CaptureSnapshot(ctx, 1, bookmarkID)
+	assert.ErrorIs(t, err, ErrCaptureFailed)
+}
+
+func TestService_DiffSnapshots(t *testing.T) {
+	service, db := setupArchiveService(t)
+	ctx := context.Background()
+	page := newPageServer("Kept.", "Removed.")
+	defer page.Close()
+	bookmarkID := createTestBookmark(t, db, 1, page.URL)
+
+	_, err := service.CaptureSnapshot(ctx, 1, bookmarkID)
+	require.NoError(t, err)
+	page.set("Kept.", "Added.")
+	_, err = service.CaptureSnapshot(ctx, 1, bookmarkID)
+	require.NoError(t, err)
+
+	diff, err := service.DiffSnapshots(ctx, 1, bookmarkID, 1, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 1, diff.From.Version)
+	assert.Equal(t, 2, diff.To.Version)
+	assert.Empty(t, diff.From.Content)
+	assert.Equal(t, DiffSummary{Added: 1, Removed: 1, Unchanged: 1, ChangeRatio: 0.5}, diff.Summary)
+	assert.Equal(t, []DiffLine{
+		{Op: DiffDelete, Text: "Removed.", OldLine: 2},
+		{Op: DiffInsert, Text: "Added.", NewLine: 2},
+	}, diff.Changes)
+
+	// Another user's bookmark is not visible
+	_, err = service.DiffSnapshots(ctx, 2, bookmarkID, 1, 2)
+	assert.EqualError(t, err, "snapshot not found")
+}
+
+func TestService_RecaptureArchives(t *testing.T) {
+	service, db := setupArchiveService(t)
+	ctx := context.Background()
+	page := newPageServer("Original.")
+	defer page.Close()
+	staleID := createTestBookmark(t, db, 1, page.URL)
+	freshID := createTestBookmark(t, db, 1, page.URL)
+
+	_, err := service.CaptureSnapshot(ctx, 1, staleID)
+	require.NoError(t, err)
+	_, err = service.CaptureSnapshot(ctx, 1, freshID)
+	require.NoError(t, err)
+	require.NoError(t, db.Model(&ArchiveSnapshot{}).Where("bookmark_id = ?", staleID).
+		Update("checked_at", time.Now().UTC().Add(-48*time.Hour)).Error)
+
+	// Only the snapshot past the interval is re-captured
+	page.set("Changed.")
+	captured, err := service.RecaptureArchives(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, captured)
+
+	stale, err := service.ListSnapshots(ctx, 1, staleID)
+	require.NoError(t, err)
+	assert.Len(t, stale, 2)
+	fresh, err := service.ListSnapshots(ctx, 1, freshID)
+	require.NoError(t, err)
+	assert.Len(t, fresh, 1)
+	assert.Len(t, contentNotifications(t, db), 1)
+
+	// Nothing is due afterwards
+	captured, err = service.RecaptureArchives(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, captured)
+}
+
+func TestHandler_GetArchiveDiff(t *testing.T) {
+	router, handler, db := setupTestRouter(t)
+	require.NoError(t, db.AutoMigrate(&ArchiveSnapshot{}))
+	handler.RegisterArchiveRoutes(router.Group("/api/v1"))
+
+	page := newPageServer("Before.")
+	defer page.Close()
+	bookmarkID := createTestBookmarkForHandler(t, db, 1, page.URL)
+
+	// Capture two versions through the API
+	for _, text := range []string{"Before.", "After."} {
+		page.set(text)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/bookmarks/%d/archive", bookmarkID), nil)
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/bookmarks/%d/archive-diff/1/2", bookmarkID), nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var diff ArchiveDiff
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &diff))
+	assert.Equal(t, 1, diff.Summary.Added)
+	assert.Equal(t, 1, diff.Summary.Removed)
+
+	for path, status := range map[string]int{
+		fmt.Sprintf("/api/v1/bookmarks/%d/archive-diff/1/9", bookmarkID): http.StatusNotFound,
+		fmt.Sprintf("/api/v1/bookmarks/%d/archive-diff/a/2", bookmarkID): http.StatusBadRequest,
+	} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, status, w.Code, path)
+	}
+}
+
+func TestExtractArchiveText(t *testing.T) {
+	page := newPageServer("Hello   <b>bold</b>\n world", "<ul><li>item</li></ul>")
+	defer page.Close()
+
+	service, _ := setupArchiveService(t)
+	snapshot, err := service.fetchArchivePage(context.Background(), page.URL)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Hello bold world\nitem", snapshot.Content)
+	assert.False(t, strings.Contains(snapshot.Content, "Home"))
+}
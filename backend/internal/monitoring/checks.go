@@ -0,0 +1,159 @@
+package monitoring
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/pkg/utils"
+)
+
+// Errors of the link check query API
+var (
+	ErrInvalidLinkStatus = errors.New("status must be one of active, broken, redirect, timeout or unknown")
+	ErrInvalidSince      = errors.New("since must be an RFC 3339 time or a Unix timestamp")
+)
+
+// LinkCheckFilter narrows the link checks of a user's bookmarks
+type LinkCheckFilter struct {
+	Status     LinkStatus
+	Since      *time.Time // checks at or after this time
+	BookmarkID uint
+	// Latest keeps only the most recent check of each bookmark, so a
+	// dashboard sees the current state of every link
+	Latest bool
+}
+
+// ListLinkChecks returns the link checks of all the user's bookmarks,
+// newest first, for external dashboards
+func (s *Service) ListLinkChecks(ctx context.Context, userID uint, filter LinkCheckFilter, page, pageSize int) ([]*LinkCheck, int64, error) {
+	query := s.db.WithContext(ctx).Model(&LinkCheck{}).
+		Joins("JOIN bookmarks ON bookmarks.id = link_checks.bookmark_id AND bookmarks.deleted_at IS NULL").
+		Where("bookmarks.user_id = ?", userID)
+	if filter.Latest {
+		query = query.Where("link_checks.id IN (?)",
+			s.db.Model(&LinkCheck{}).Select("MAX(id)").Group("bookmark_id"))
+	}
+	if filter.Status != "" {
+		query = query.Where("link_checks.status = ?", filter.Status)
+	}
+	if filter.Since != nil {
+		query = query.Where("link_checks.checked_at >= ?", *filter.Since)
+	}
+	if filter.BookmarkID != 0 {
+		query = query.Where("link_checks.bookmark_id = ?", filter.BookmarkID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count link checks: %w", err)
+	}
+
+	var checks []*LinkCheck
+	if err := query.Select("link_checks.*").
+		Order("link_checks.checked_at DESC, link_checks.id DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&checks).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list link checks: %w", err)
+	}
+
+	return checks, total, nil
+}
+
+// latestLinkCheck returns the most recent check of a bookmark, nil if it
+// was never checked
+func (s *Service) latestLinkCheck(ctx context.Context, bookmarkID uint) (*LinkCheck, error) {
+	var check LinkCheck
+	if err := s.db.WithContext(ctx).
+		Where("bookmark_id = ?", bookmarkID).
+		Order("checked_at DESC, id DESC").
+		First(&check).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get previous link check: %w", err)
+	}
+	return &check, nil
+}
+
+// parseLinkCheckFilter reads the filter from the query string
+func parseLinkCheckFilter(c *gin.Context) (LinkCheckFilter, error) {
+	var filter LinkCheckFilter
+	switch status := LinkStatus(c.Query("status")); status {
+	case "":
+	case LinkStatusActive, LinkStatusBroken, LinkStatusRedirect, LinkStatusTimeout, LinkStatusUnknown:
+		filter.Status = status
+	default:
+		return filter, ErrInvalidLinkStatus
+	}
+
+	if since := c.Query("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			unix, unixErr := strconv.ParseInt(since, 10, 64)
+			if unixErr != nil {
+				return filter, ErrInvalidSince
+			}
+			t = time.Unix(unix, 0)
+		}
+		filter.Since = &t
+	}
+
+	if id, err := strconv.ParseUint(c.Query("bookmark_id"), 10, 32); err == nil {
+		filter.BookmarkID = uint(id)
+	}
+	filter.Latest = c.Query("latest") == "true"
+	return filter, nil
+}
+
+// ListLinkChecks handles link check queries across all bookmarks
+// @Summary Query link checks
+// @Description List link check results of the current user's bookmarks, newest first, for external monitors such as Grafana or Uptime Kuma
+// @Tags monitoring
+// @Produce json
+// @Param status query string false "Check status" Enums(active, broken, redirect, timeout, unknown)
+// @Param since query string false "Only checks at or after this RFC 3339 time or Unix timestamp"
+// @Param bookmark_id query int false "Only checks of this bookmark"
+// @Param latest query bool false "Only the latest check of each bookmark"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(20)
+// @Success 200 {object} ListResponse
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/monitoring/checks [get]
+func (h *Handler) ListLinkChecks(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	filter, err := parseLinkCheckFilter(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_FILTER", err.Error(), nil)
+		return
+	}
+	page, pageSize := utils.GetPaginationParams(c)
+
+	checks, total, err := h.service.ListLinkChecks(c.Request.Context(), userID, filter, page, pageSize)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "FETCH_FAILED", "Failed to get link checks", map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, ListResponse{
+		Items:      checks,
+		Total:      total,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: int((total + int64(pageSize) - 1) / int64(pageSize)),
+	})
+}
@@ -0,0 +1,86 @@
+package monitoring
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_ListLinkChecks(t *testing.T) {
+	service, db := setupTestService(t)
+	ctx := context.Background()
+
+	now := time.Now()
+	first := createTestBookmark(t, db, 1, "https://example.com/a")
+	second := createTestBookmark(t, db, 1, "https://example.com/b")
+	other := createTestBookmark(t, db, 2, "https://example.com/c")
+	for _, check := range []*LinkCheck{
+		{BookmarkID: first, URL: "https://example.com/a", Status: LinkStatusBroken, CheckedAt: now.Add(-48 * time.Hour)},
+		{BookmarkID: first, URL: "https://example.com/a", Status: LinkStatusActive, CheckedAt: now.Add(-time.Hour)},
+		{BookmarkID: second, URL: "https://example.com/b", Status: LinkStatusBroken, CheckedAt: now.Add(-2 * time.Hour)},
+		{BookmarkID: other, URL: "https://example.com/c", Status: LinkStatusBroken, CheckedAt: now},
+	} {
+		require.NoError(t, db.Create(check).Error)
+	}
+
+	since := now.Add(-24 * time.Hour)
+	tests := []struct {
+		name   string
+		filter LinkCheckFilter
+		total  int64
+	}{
+		{name: "all of the user's checks", filter: LinkCheckFilter{}, total: 3},
+		{name: "broken", filter: LinkCheckFilter{Status: LinkStatusBroken}, total: 2},
+		{name: "broken since yesterday", filter: LinkCheckFilter{Status: LinkStatusBroken, Since: &since}, total: 1},
+		{name: "currently broken", filter: LinkCheckFilter{Status: LinkStatusBroken, Latest: true}, total: 1},
+		{name: "one bookmark", filter: LinkCheckFilter{BookmarkID: first}, total: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checks, total, err := service.ListLinkChecks(ctx, 1, tt.filter, 1, 20)
+			require.NoError(t, err)
+			assert.Equal(t, tt.total, total)
+			assert.Len(t, checks, int(tt.total))
+		})
+	}
+
+	checks, _, err := service.ListLinkChecks(ctx, 1, LinkCheckFilter{}, 1, 20)
+	require.NoError(t, err)
+	assert.Equal(t, LinkStatusActive, checks[0].Status) // newest first
+}
+
+func TestHandler_ListLinkChecks(t *testing.T) {
+	router, _, db := setupTestRouter(t)
+
+	bookmarkID := createTestBookmarkForHandler(t, db, 1, "https://example.com")
+	require.NoError(t, db.Create(&LinkCheck{BookmarkID: bookmarkID, URL: "https://example.com", Status: LinkStatusBroken, CheckedAt: time.Now()}).Error)
+
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/api/v1/monitoring/checks"+query, nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("?status=broken&since=" + time.Now().Add(-time.Hour).UTC().Format(time.RFC3339))
+	require.Equal(t, http.StatusOK, w.Code)
+	var response ListResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, int64(1), response.Total)
+
+	w = get(fmt.Sprintf("?since=%d", time.Now().Add(time.Hour).Unix()))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, int64(0), response.Total)
+
+	assert.Equal(t, http.StatusBadRequest, get("?status=down").Code)
+	assert.Equal(t, http.StatusBadRequest, get("?since=yesterday").Code)
+}
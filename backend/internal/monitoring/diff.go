@@ -0,0 +1,144 @@
+package monitoring
+
+import (
+	"bookmark-sync-service/backend/internal/config"
+)
+
+// DiffOp is the kind of change a diff line records
+type DiffOp string
+
+const (
+	DiffEqual  DiffOp = "equal"
+	DiffInsert DiffOp = "insert"
+	DiffDelete DiffOp = "delete"
+)
+
+// DiffLine is one line of a snapshot diff. OldLine and NewLine are 1-based
+// positions in the older and newer snapshot
+type DiffLine struct {
+	Op      DiffOp `json:"op"`
+	Text    string `json:"text"`
+	OldLine int    `json:"old_line,omitempty"`
+	NewLine int    `json:"new_line,omitempty"`
+}
+
+// DiffSummary counts the lines a diff added, removed and kept
+type DiffSummary struct {
+	Added     int `json:"added"`
+	Removed   int `json:"removed"`
+	Unchanged int `json:"unchanged"`
+	// ChangeRatio is the share of all lines on both sides that changed, from
+	// 0 for identical snapshots to 1 for nothing in common
+	ChangeRatio float64 `json:"change_ratio"`
+}
+
+// diffLines computes a line diff between two snapshots. Common leading and
+// trailing lines are matched first; what remains is aligned by longest common
+// subsequence, or replaced wholesale when it is too long to align
+func diffLines(oldLines, newLines []string) []DiffLine {
+	prefix := 0
+	for prefix < len(oldLines) && prefix < len(newLines) && oldLines[prefix] == newLines[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(oldLines)-prefix && suffix < len(newLines)-prefix &&
+		oldLines[len(oldLines)-1-suffix] == newLines[len(newLines)-1-suffix] {
+		suffix++
+	}
+
+	diff := make([]DiffLine, 0, len(oldLines)+len(newLines)-prefix-suffix)
+	for i := 0; i < prefix; i++ {
+		diff = append(diff, DiffLine{Op: DiffEqual, Text: oldLines[i], OldLine: i + 1, NewLine: i + 1})
+	}
+
+	a := oldLines[prefix : len(oldLines)-suffix]
+	b := newLines[prefix : len(newLines)-suffix]
+	if len(a) > config.MaxArchiveDiffLines || len(b) > config.MaxArchiveDiffLines {
+		for i, line := range a {
+			diff = append(diff, DiffLine{Op: DiffDelete, Text: line, OldLine: prefix + i + 1})
+		}
+		for j, line := range b {
+			diff = append(diff, DiffLine{Op: DiffInsert, Text: line, NewLine: prefix + j + 1})
+		}
+	} else {
+		diff = append(diff, alignLines(a, b, prefix)...)
+	}
+
+	for k := 0; k < suffix; k++ {
+		i, j := len(oldLines)-suffix+k, len(newLines)-suffix+k
+		diff = append(diff, DiffLine{Op: DiffEqual, Text: oldLines[i], OldLine: i + 1, NewLine: j + 1})
+	}
+
+	return diff
+}
+
+// alignLines diffs a and b by longest common subsequence. offset is the
+// number of lines before them in both snapshots
+func alignLines(a, b []string, offset int) []DiffLine {
+	// lcs[i][j] is the LCS length of a[i:] and b[j:]; lines are capped well
+	// below the uint16 range
+	width := len(b) + 1
+	lcs := make([]uint16, (len(a)+1)*width)
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				lcs[i*width+j] = lcs[(i+1)*width+j+1] + 1
+			case lcs[(i+1)*width+j] >= lcs[i*width+j+1]:
+				lcs[i*width+j] = lcs[(i+1)*width+j]
+			default:
+				lcs[i*width+j] = lcs[i*width+j+1]
+			}
+		}
+	}
+
+	diff := make([]DiffLine, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			diff = append(diff, DiffLine{Op: DiffEqual, Text: a[i], OldLine: offset + i + 1, NewLine: offset + j + 1})
+			i++
+			j++
+		case j < len(b) && (i == len(a) || lcs[i*width+j+1] > lcs[(i+1)*width+j]):
+			diff = append(diff, DiffLine{Op: DiffInsert, Text: b[j], NewLine: offset + j + 1})
+			j++
+		default:
+			diff = append(diff, DiffLine{Op: DiffDelete, Text: a[i], OldLine: offset + i + 1})
+			i++
+		}
+	}
+	return diff
+}
+
+// summarizeDiff counts a diff's changes
+func summarizeDiff(diff []DiffLine) DiffSummary {
+	var summary DiffSummary
+	for _, line := range diff {
+		switch line.Op {
+		case DiffInsert:
+			summary.Added++
+		case DiffDelete:
+			summary.Removed++
+		default:
+			summary.Unchanged++
+		}
+	}
+
+	// Unchanged lines appear on both sides
+	if total := summary.Added + summary.Removed + 2*summary.Unchanged; total > 0 {
+		summary.ChangeRatio = float64(summary.Added+summary.Removed) / float64(total)
+	}
+	return summary
+}
+
+// changedLines drops the unchanged lines from a diff
+func changedLines(diff []DiffLine) []DiffLine {
+	changes := make([]DiffLine, 0)
+	for _, line := range diff {
+		if line.Op != DiffEqual {
+			changes = append(changes, line)
+		}
+	}
+	return changes
+}
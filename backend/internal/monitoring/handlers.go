@@ -1,6 +1,7 @@
 package monitoring
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -27,6 +28,7 @@ func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
 	{
 		// Link checking endpoints
 		monitoring.POST("/check-link", h.CheckLink)
+		monitoring.GET("/checks", h.ListLinkChecks)
 		monitoring.GET("/bookmarks/:bookmark_id/checks", h.GetLinkChecks)
 
 		// Monitoring job endpoints
@@ -45,6 +47,14 @@ func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
 	}
 }
 
+// RegisterArchiveRoutes registers the bookmark archive routes, which live
+// under /bookmarks alongside the bookmark API
+func (h *Handler) RegisterArchiveRoutes(router *gin.RouterGroup) {
+	router.POST("/bookmarks/:id/archive", h.CaptureArchive)
+	router.GET("/bookmarks/:id/archives", h.ListArchives)
+	router.GET("/bookmarks/:id/archive-diff/:v1/:v2", h.GetArchiveDiff)
+}
+
 // CheckLink handles link checking requests
 func (h *Handler) CheckLink(c *gin.Context) {
 	userID := utils.GetUserIDFromContext(c)
@@ -371,3 +381,90 @@ func (h *Handler) MarkNotificationAsRead(c *gin.Context) {
 		"message": "Notification marked as read successfully",
 	})
 }
+
+// CaptureArchive handles requests to archive a bookmarked page now
+func (h *Handler) CaptureArchive(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	bookmarkID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_BOOKMARK_ID", "Invalid bookmark ID", nil)
+		return
+	}
+
+	capture, err := h.service.CaptureSnapshot(c.Request.Context(), userID, uint(bookmarkID))
+	if err != nil {
+		switch {
+		case err.Error() == "bookmark not found":
+			utils.ErrorResponse(c, http.StatusNotFound, "BOOKMARK_NOT_FOUND", "Bookmark not found", nil)
+		case errors.Is(err, ErrCaptureFailed):
+			utils.ErrorResponse(c, http.StatusBadGateway, "CAPTURE_FAILED", "Failed to capture page", map[string]interface{}{"error": err.Error()})
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, "CAPTURE_FAILED", "Failed to archive bookmark", map[string]interface{}{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, capture)
+}
+
+// ListArchives handles requests to list a bookmark's archived versions
+func (h *Handler) ListArchives(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	bookmarkID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_BOOKMARK_ID", "Invalid bookmark ID", nil)
+		return
+	}
+
+	snapshots, err := h.service.ListSnapshots(c.Request.Context(), userID, uint(bookmarkID))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "FETCH_FAILED", "Failed to list archives", map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"snapshots": snapshots})
+}
+
+// GetArchiveDiff handles requests to compare two archived versions
+func (h *Handler) GetArchiveDiff(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	bookmarkID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_BOOKMARK_ID", "Invalid bookmark ID", nil)
+		return
+	}
+
+	fromVersion, errFrom := strconv.Atoi(c.Param("v1"))
+	toVersion, errTo := strconv.Atoi(c.Param("v2"))
+	if errFrom != nil || errTo != nil || fromVersion < 1 || toVersion < 1 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_VERSION", "Invalid archive version", nil)
+		return
+	}
+
+	diff, err := h.service.DiffSnapshots(c.Request.Context(), userID, uint(bookmarkID), fromVersion, toVersion)
+	if err != nil {
+		if err.Error() == "snapshot not found" {
+			utils.ErrorResponse(c, http.StatusNotFound, "SNAPSHOT_NOT_FOUND", "Archive version not found", nil)
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "DIFF_FAILED", "Failed to compare archives", map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, diff)
+}
@@ -40,6 +40,8 @@ func setupTestRouter(t *testing.T) (*gin.Engine, *Handler, *gorm.DB) {
 			user_id INTEGER NOT NULL,
 			url TEXT NOT NULL,
 			title TEXT,
+			status TEXT DEFAULT 'active',
+			last_checked_at DATETIME,
 			created_at DATETIME,
 			updated_at DATETIME,
 			deleted_at DATETIME
@@ -27,6 +27,7 @@ type LinkCheck struct {
 	ResponseTime int64          `json:"response_time"` // in milliseconds
 	RedirectURL  string         `json:"redirect_url,omitempty"`
 	ErrorMessage string         `json:"error_message,omitempty"`
+	SafetyThreat string         `json:"safety_threat,omitempty"` // set when the URL is on a threat list
 	CheckedAt    time.Time      `json:"checked_at" gorm:"not null"`
 	CreatedAt    time.Time      `json:"created_at"`
 	UpdatedAt    time.Time      `json:"updated_at"`
@@ -60,6 +61,7 @@ type LinkMaintenanceReport struct {
 	ActiveLinks   int            `json:"active_links"`
 	Suggestions   string         `json:"suggestions" gorm:"type:text"` // Store as JSON string
 	GeneratedAt   time.Time      `json:"generated_at" gorm:"not null"`
+	Timezone      string         `json:"timezone"` // the owner's timezone GeneratedAt is shown in
 	CreatedAt     time.Time      `json:"created_at"`
 	UpdatedAt     time.Time      `json:"updated_at"`
 	DeletedAt     gorm.DeletedAt `json:"-" gorm:"index"`
@@ -70,7 +72,7 @@ type LinkChangeNotification struct {
 	ID         uint           `json:"id" gorm:"primaryKey"`
 	UserID     uint           `json:"user_id" gorm:"not null;index"`
 	BookmarkID uint           `json:"bookmark_id" gorm:"not null;index"`
-	ChangeType string         `json:"change_type" gorm:"not null"` // "broken", "redirect", "content_change"
+	ChangeType string         `json:"change_type" gorm:"not null"` // "broken", "redirect", "content_change", "unsafe"
 	OldValue   string         `json:"old_value"`
 	NewValue   string         `json:"new_value"`
 	Message    string         `json:"message" gorm:"not null"`
@@ -80,6 +82,25 @@ type LinkChangeNotification struct {
 	DeletedAt  gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
+// ArchiveSnapshot is a captured copy of a bookmarked page's text, kept in
+// numbered versions so changes between captures can be compared
+type ArchiveSnapshot struct {
+	ID          uint           `json:"id" gorm:"primaryKey"`
+	UserID      uint           `json:"user_id" gorm:"not null;index"`
+	BookmarkID  uint           `json:"bookmark_id" gorm:"not null;index:idx_archive_bookmark_version"`
+	Version     int            `json:"version" gorm:"not null;index:idx_archive_bookmark_version"`
+	URL         string         `json:"url" gorm:"not null"`
+	Title       string         `json:"title"`
+	Content     string         `json:"content,omitempty" gorm:"type:text"` // extracted text, one block per line
+	ContentHash string         `json:"content_hash" gorm:"not null"`
+	StatusCode  int            `json:"status_code"`
+	CapturedAt  time.Time      `json:"captured_at" gorm:"not null"`
+	CheckedAt   time.Time      `json:"checked_at" gorm:"index"` // last re-capture, changed or not
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
 // CreateLinkCheckRequest represents the request to create a link check
 type CreateLinkCheckRequest struct {
 	BookmarkID uint   `json:"bookmark_id" binding:"required"`
@@ -0,0 +1,46 @@
+package monitoring
+
+import (
+	"context"
+	"time"
+
+	"bookmark-sync-service/backend/internal/safety"
+)
+
+// ChangeTypeUnsafe notifies that a bookmarked URL was found on a threat list
+const ChangeTypeUnsafe = "unsafe"
+
+// SafetyChecker reports URLs that point at known-malicious sites
+type SafetyChecker interface {
+	CheckURL(ctx context.Context, rawURL string) (*safety.Verdict, error)
+}
+
+// SetSafetyChecker makes link checks also look the URL up on threat lists
+func (s *Service) SetSafetyChecker(checker SafetyChecker) {
+	s.safety = checker
+}
+
+// checkSafety looks up a checked URL and returns its threat, if any. When the
+// URL is the bookmark's own, the bookmark is flagged or cleared to match, and
+// flagged reports whether it was not flagged before. Lookup failures change
+// nothing
+func (s *Service) checkSafety(ctx context.Context, bookmarkID uint, bookmarkURL, rawURL string) (threat string, flagged bool) {
+	verdict, err := s.safety.CheckURL(ctx, rawURL)
+	if err != nil {
+		return "", false
+	}
+	if verdict != nil {
+		threat = verdict.Threat
+	}
+	if rawURL != bookmarkURL {
+		return threat, false
+	}
+
+	var previousThreat string
+	s.db.WithContext(ctx).Table("bookmarks").Where("id = ?", bookmarkID).Select("safety_threat").Scan(&previousThreat)
+	s.db.WithContext(ctx).Table("bookmarks").Where("id = ?", bookmarkID).UpdateColumns(map[string]interface{}{
+		"safety_threat":     threat,
+		"safety_checked_at": time.Now(),
+	})
+	return threat, threat != "" && previousThreat == ""
+}
@@ -0,0 +1,64 @@
+package monitoring
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bookmark-sync-service/backend/internal/safety"
+)
+
+type stubSafetyChecker struct {
+	threat string
+}
+
+func (s *stubSafetyChecker) CheckURL(_ context.Context, rawURL string) (*safety.Verdict, error) {
+	if s.threat == "" {
+		return nil, nil
+	}
+	return &safety.Verdict{URL: rawURL, Threat: s.threat, Source: safety.SourceBlocklist}, nil
+}
+
+func TestService_CheckLink_FlagsUnsafeLinks(t *testing.T) {
+	service, db := setupTestService(t)
+	require.NoError(t, db.Exec(`ALTER TABLE bookmarks ADD COLUMN safety_threat TEXT DEFAULT ''`).Error)
+	require.NoError(t, db.Exec(`ALTER TABLE bookmarks ADD COLUMN safety_checked_at DATETIME`).Error)
+	checker := &stubSafetyChecker{threat: safety.ThreatMalware}
+	service.SetSafetyChecker(checker)
+	ctx := context.Background()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	bookmarkID := createTestBookmark(t, db, 1, server.URL)
+
+	countUnsafe := func() int64 {
+		var count int64
+		db.Model(&LinkChangeNotification{}).Where("bookmark_id = ? AND change_type = ?", bookmarkID, ChangeTypeUnsafe).Count(&count)
+		return count
+	}
+	storedThreat := func() string {
+		var threat string
+		db.Table("bookmarks").Where("id = ?", bookmarkID).Select("safety_threat").Scan(&threat)
+		return threat
+	}
+
+	for i := 0; i < 2; i++ {
+		check, err := service.CheckLink(ctx, 1, &CreateLinkCheckRequest{BookmarkID: bookmarkID, URL: server.URL})
+		require.NoError(t, err)
+		assert.Equal(t, safety.ThreatMalware, check.SafetyThreat)
+	}
+	assert.Equal(t, safety.ThreatMalware, storedThreat())
+	assert.Equal(t, int64(1), countUnsafe(), "the owner is notified once")
+
+	checker.threat = ""
+	check, err := service.CheckLink(ctx, 1, &CreateLinkCheckRequest{BookmarkID: bookmarkID, URL: server.URL})
+	require.NoError(t, err)
+	assert.Empty(t, check.SafetyThreat)
+	assert.Empty(t, storedThreat(), "a link no longer listed is cleared")
+}
@@ -8,13 +8,26 @@ import (
 	"strings"
 	"time"
 
+	"go.uber.org/zap"
 	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/pkg/locale"
+	redispkg "bookmark-sync-service/backend/pkg/redis"
 )
 
 // Service handles link monitoring and maintenance operations
 type Service struct {
 	db         *gorm.DB
 	httpClient *http.Client
+
+	// Page archives follow redirects, unlike link checks
+	archiveClient *http.Client
+	archive       config.ArchiveConfig
+	leader        *redispkg.Leader
+	webhooks      WebhookTrigger
+	safety        SafetyChecker
+	logger        *zap.Logger
 }
 
 // NewService creates a new monitoring service
@@ -28,9 +41,16 @@ func NewService(db *gorm.DB) *Service {
 				return http.ErrUseLastResponse
 			},
 		},
+		archiveClient: &http.Client{Timeout: 30 * time.Second},
+		logger:        zap.NewNop(),
 	}
 }
 
+// SetLogger logs failed archive re-captures and webhook deliveries
+func (s *Service) SetLogger(logger *zap.Logger) {
+	s.logger = logger
+}
+
 // CheckLink performs a link check and returns the result
 func (s *Service) CheckLink(ctx context.Context, userID uint, req *CreateLinkCheckRequest) (*LinkCheck, error) {
 	// Verify bookmark belongs to user
@@ -86,10 +106,34 @@ func (s *Service) CheckLink(ctx context.Context, userID uint, req *CreateLinkChe
 		}
 	}
 
-	// Save the link check result
-	if err := s.db.WithContext(ctx).Create(linkCheck).Error; err != nil {
-		return nil, fmt.Errorf("failed to save link check: %w", err)
+	flagged := false
+	if s.safety != nil {
+		linkCheck.SafetyThreat, flagged = s.checkSafety(ctx, bookmark.ID, bookmark.URL, req.URL)
+	}
+
+	// The previous result tells whether the link just broke or recovered
+	previous, err := s.latestLinkCheck(ctx, req.BookmarkID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Save the link check result, and keep the bookmark's copy of it current
+	// so bookmark lists can show link health without a lookup per bookmark
+	if err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(linkCheck).Error; err != nil {
+			return fmt.Errorf("failed to save link check: %w", err)
+		}
+		if err := tx.Table("bookmarks").Where("id = ?", req.BookmarkID).Updates(map[string]interface{}{
+			"status":          string(linkCheck.Status),
+			"last_checked_at": linkCheck.CheckedAt,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to update bookmark link status: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
 	}
+	s.triggerLinkWebhook(userID, previous, linkCheck)
 
 	// Create notification if link is broken or redirected
 	if linkCheck.Status == LinkStatusBroken || linkCheck.Status == LinkStatusRedirect {
@@ -101,6 +145,15 @@ func (s *Service) CheckLink(ctx context.Context, userID uint, req *CreateLinkChe
 		}
 		s.db.WithContext(ctx).Create(notification)
 	}
+	if flagged {
+		s.db.WithContext(ctx).Create(&LinkChangeNotification{
+			UserID:     userID,
+			BookmarkID: req.BookmarkID,
+			ChangeType: ChangeTypeUnsafe,
+			NewValue:   linkCheck.SafetyThreat,
+			Message:    fmt.Sprintf("Link points at a site flagged for %s: %s", strings.ReplaceAll(linkCheck.SafetyThreat, "_", " "), linkCheck.URL),
+		})
+	}
 
 	return linkCheck, nil
 }
@@ -217,11 +270,13 @@ func (s *Service) ListMonitoringJobs(ctx context.Context, userID uint, page, pag
 
 // GenerateMaintenanceReport generates a maintenance report for user's bookmarks
 func (s *Service) GenerateMaintenanceReport(ctx context.Context, userID uint, collectionID *uint) (*LinkMaintenanceReport, error) {
+	settings := locale.ForUser(ctx, s.db, userID)
 	report := &LinkMaintenanceReport{
 		UserID:       userID,
 		CollectionID: collectionID,
 		ReportType:   "comprehensive",
-		GeneratedAt:  time.Now(),
+		GeneratedAt:  settings.In(time.Now()),
+		Timezone:     settings.Timezone(),
 	}
 
 	// Build query based on collection filter
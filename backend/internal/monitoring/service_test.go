@@ -33,6 +33,8 @@ func setupTestDB(t *testing.T) *gorm.DB {
 			user_id INTEGER NOT NULL,
 			url TEXT NOT NULL,
 			title TEXT,
+			status TEXT DEFAULT 'active',
+			last_checked_at DATETIME,
 			created_at DATETIME,
 			updated_at DATETIME,
 			deleted_at DATETIME
@@ -135,6 +137,16 @@ func TestService_CheckLink_BrokenLink(t *testing.T) {
 	err = db.Where("user_id = ? AND bookmark_id = ?", userID, bookmarkID).First(&notification).Error
 	require.NoError(t, err)
 	assert.Equal(t, "broken", notification.ChangeType)
+
+	// The bookmark carries the result for list responses
+	var bookmark struct {
+		Status        string
+		LastCheckedAt *time.Time
+	}
+	require.NoError(t, db.Table("bookmarks").Where("id = ?", bookmarkID).Take(&bookmark).Error)
+	assert.Equal(t, string(LinkStatusBroken), bookmark.Status)
+	require.NotNil(t, bookmark.LastCheckedAt)
+	assert.WithinDuration(t, result.CheckedAt, *bookmark.LastCheckedAt, time.Second)
 }
 
 func TestService_CheckLink_Redirect(t *testing.T) {
@@ -379,6 +391,20 @@ func TestService_GenerateMaintenanceReport_Success(t *testing.T) {
 	assert.Equal(t, 1, report.BrokenLinks)
 	assert.Equal(t, 0, report.RedirectLinks)
 	assert.NotEmpty(t, report.Suggestions)
+	assert.Equal(t, "UTC", report.Timezone, "users without preferences get UTC")
+}
+
+func TestService_GenerateMaintenanceReport_UsesUserTimezone(t *testing.T) {
+	service, db := setupTestService(t)
+	require.NoError(t, db.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, preferences TEXT)").Error)
+	require.NoError(t, db.Exec(`INSERT INTO users (id, preferences) VALUES (1, '{"timezone":"Asia/Tokyo"}')`).Error)
+	createTestBookmark(t, db, 1, "https://example.com/1")
+
+	report, err := service.GenerateMaintenanceReport(context.Background(), 1, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Asia/Tokyo", report.Timezone)
+	_, offset := report.GeneratedAt.Zone()
+	assert.Equal(t, 9*3600, offset)
 }
 
 func TestService_GetLinkChecks_Success(t *testing.T) {
@@ -0,0 +1,66 @@
+package monitoring
+
+import (
+	"context"
+	"strconv"
+
+	"go.uber.org/zap"
+
+	"bookmark-sync-service/backend/internal/automation"
+)
+
+// WebhookTrigger delivers events to a user's webhook endpoints
+type WebhookTrigger interface {
+	TriggerWebhook(ctx context.Context, event automation.WebhookEvent, userID string, data interface{}) error
+}
+
+// SetWebhooks makes the service report links that break or recover to the
+// owner's webhooks
+func (s *Service) SetWebhooks(webhooks WebhookTrigger) {
+	s.webhooks = webhooks
+}
+
+// isDown reports whether a check found the link unusable
+func isDown(status LinkStatus) bool {
+	return status == LinkStatusBroken || status == LinkStatusTimeout
+}
+
+// linkTransition returns the webhook event a check causes after the
+// previous one, if any. A first check only reports a broken link
+func linkTransition(previous *LinkCheck, check *LinkCheck) (automation.WebhookEvent, bool) {
+	switch {
+	case isDown(check.Status) && (previous == nil || !isDown(previous.Status)):
+		return automation.WebhookEventLinkBroken, true
+	case previous != nil && isDown(previous.Status) && (check.Status == LinkStatusActive || check.Status == LinkStatusRedirect):
+		return automation.WebhookEventLinkRecovered, true
+	}
+	return "", false
+}
+
+// triggerLinkWebhook reports a link that changed state. Deliveries outlive
+// the request, and their failures never fail the check itself
+func (s *Service) triggerLinkWebhook(userID uint, previous *LinkCheck, check *LinkCheck) {
+	if s.webhooks == nil {
+		return
+	}
+	event, changed := linkTransition(previous, check)
+	if !changed {
+		return
+	}
+
+	data := map[string]interface{}{
+		"bookmark_id":   check.BookmarkID,
+		"check_id":      check.ID,
+		"url":           check.URL,
+		"status":        check.Status,
+		"status_code":   check.StatusCode,
+		"error_message": check.ErrorMessage,
+		"checked_at":    check.CheckedAt,
+	}
+	if previous != nil {
+		data["previous_status"] = previous.Status
+	}
+	if err := s.webhooks.TriggerWebhook(context.Background(), event, strconv.FormatUint(uint64(userID), 10), data); err != nil {
+		s.logger.Warn("Failed to trigger link webhook", zap.String("event", string(event)), zap.Uint("bookmark_id", check.BookmarkID), zap.Error(err))
+	}
+}
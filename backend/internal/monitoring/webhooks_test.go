@@ -0,0 +1,95 @@
+package monitoring
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bookmark-sync-service/backend/internal/automation"
+)
+
+type recordedEvent struct {
+	event  automation.WebhookEvent
+	userID string
+	data   map[string]interface{}
+}
+
+type fakeWebhooks struct {
+	events []recordedEvent
+}
+
+func (f *fakeWebhooks) TriggerWebhook(ctx context.Context, event automation.WebhookEvent, userID string, data interface{}) error {
+	f.events = append(f.events, recordedEvent{event: event, userID: userID, data: data.(map[string]interface{})})
+	return nil
+}
+
+func TestService_CheckLink_TriggersTransitionWebhooks(t *testing.T) {
+	service, db := setupTestService(t)
+	webhooks := &fakeWebhooks{}
+	service.SetWebhooks(webhooks)
+	ctx := context.Background()
+
+	status := http.StatusNotFound
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	bookmarkID := createTestBookmark(t, db, 1, server.URL)
+	check := func() {
+		_, err := service.CheckLink(ctx, 1, &CreateLinkCheckRequest{BookmarkID: bookmarkID, URL: server.URL})
+		require.NoError(t, err)
+	}
+
+	// A link found broken is reported once, not on every failing check
+	check()
+	check()
+	require.Len(t, webhooks.events, 1)
+	assert.Equal(t, automation.WebhookEventLinkBroken, webhooks.events[0].event)
+	assert.Equal(t, "1", webhooks.events[0].userID)
+	assert.Equal(t, bookmarkID, webhooks.events[0].data["bookmark_id"])
+	assert.NotContains(t, webhooks.events[0].data, "previous_status")
+
+	// Working again
+	status = http.StatusOK
+	check()
+	check()
+	require.Len(t, webhooks.events, 2)
+	assert.Equal(t, automation.WebhookEventLinkRecovered, webhooks.events[1].event)
+	assert.Equal(t, LinkStatusBroken, webhooks.events[1].data["previous_status"])
+}
+
+func TestLinkTransition(t *testing.T) {
+	active := &LinkCheck{Status: LinkStatusActive}
+	broken := &LinkCheck{Status: LinkStatusBroken}
+	timeout := &LinkCheck{Status: LinkStatusTimeout}
+	redirect := &LinkCheck{Status: LinkStatusRedirect}
+
+	tests := []struct {
+		name     string
+		previous *LinkCheck
+		check    *LinkCheck
+		event    automation.WebhookEvent
+		changed  bool
+	}{
+		{name: "first check working", previous: nil, check: active},
+		{name: "first check broken", previous: nil, check: broken, event: automation.WebhookEventLinkBroken, changed: true},
+		{name: "breaks", previous: active, check: timeout, event: automation.WebhookEventLinkBroken, changed: true},
+		{name: "still broken", previous: broken, check: timeout},
+		{name: "recovers", previous: timeout, check: active, event: automation.WebhookEventLinkRecovered, changed: true},
+		{name: "recovers to a redirect", previous: broken, check: redirect, event: automation.WebhookEventLinkRecovered, changed: true},
+		{name: "starts redirecting", previous: active, check: redirect},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, changed := linkTransition(tt.previous, tt.check)
+			assert.Equal(t, tt.changed, changed)
+			assert.Equal(t, tt.event, event)
+		})
+	}
+}
@@ -0,0 +1,661 @@
+package oauth
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"bookmark-sync-service/backend/pkg/utils"
+)
+
+// Context keys set by RequireAppToken
+const (
+	contextTokenInfo = "oauth_token"
+)
+
+// Handler handles HTTP requests for the OAuth app platform
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new OAuth handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes registers the routes used by signed-in users: developers
+// managing their apps, and users granting or revoking access
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	oauth := router.Group("/oauth")
+	{
+		apps := oauth.Group("/apps")
+		{
+			apps.POST("", h.CreateApp)
+			apps.GET("", h.ListApps)
+			apps.GET("/:id", h.GetApp)
+			apps.PUT("/:id", h.UpdateApp)
+			apps.DELETE("/:id", h.DeleteApp)
+			apps.POST("/:id/secret", h.RotateSecrets)
+		}
+
+		// Consent screen
+		oauth.GET("/authorize", h.GetConsent)
+		oauth.POST("/authorize", h.Authorize)
+
+		oauth.GET("/authorizations", h.ListAuthorizations)
+		oauth.DELETE("/authorizations/:id", h.RevokeAuthorization)
+	}
+}
+
+// RegisterTokenRoutes registers the token endpoints apps call with their
+// client credentials
+func (h *Handler) RegisterTokenRoutes(router *gin.RouterGroup) {
+	router.POST("/oauth/token", h.Token)
+	router.POST("/oauth/revoke", h.Revoke)
+}
+
+// RegisterAppRoutes registers the API apps call with access tokens
+func (h *Handler) RegisterAppRoutes(router *gin.RouterGroup) {
+	app := router.Group("/app")
+	{
+		app.GET("/me", h.RequireAppToken(ScopeProfileRead), h.GetProfile)
+
+		webhooks := app.Group("/webhooks", h.RequireAppToken(ScopeWebhooks))
+		{
+			webhooks.GET("", h.ListSubscriptions)
+			webhooks.POST("", h.CreateSubscription)
+			webhooks.DELETE("/:id", h.DeleteSubscription)
+		}
+	}
+}
+
+// AppResourceHandlers serve one kind of the user's data to apps. They read
+// the user from user_id, which RequireAppToken sets
+type AppResourceHandlers struct {
+	List   gin.HandlerFunc
+	Get    gin.HandlerFunc
+	Create gin.HandlerFunc
+	Update gin.HandlerFunc
+	Delete gin.HandlerFunc
+}
+
+// RegisterAppResource registers /app/<name> routes apps call with access
+// tokens: reads need readScope, changes writeScope
+func (h *Handler) RegisterAppResource(router *gin.RouterGroup, name, readScope, writeScope string, handlers AppResourceHandlers) {
+	read := h.RequireAppToken(readScope)
+	write := h.RequireAppToken(writeScope)
+
+	resource := router.Group("/app/" + name)
+	{
+		resource.GET("", read, handlers.List)
+		resource.GET("/:id", read, handlers.Get)
+		resource.POST("", write, handlers.Create)
+		resource.PUT("/:id", write, handlers.Update)
+		resource.DELETE("/:id", write, handlers.Delete)
+	}
+}
+
+// RequireAppToken authenticates an app access token, checks it carries the
+// given scopes and counts the request against the app's rate limit. The
+// user the token acts for is set as user_id like the JWT middleware does
+func (h *Handler) RequireAppToken(scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !IsAccessToken(token) {
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			utils.UnauthorizedResponse(c, "App access token is required")
+			c.Abort()
+			return
+		}
+
+		info, err := h.service.Authenticate(token)
+		if err != nil {
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			utils.UnauthorizedResponse(c, "Invalid or expired access token")
+			c.Abort()
+			return
+		}
+
+		if !hasScopes(info.Scopes, scopes...) {
+			c.Header("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+strings.Join(scopes, " ")+`"`)
+			utils.ErrorResponse(c, http.StatusForbidden, "insufficient_scope", "Token does not grant the required scope", gin.H{"required": scopes})
+			c.Abort()
+			return
+		}
+
+		remaining, err := h.service.CheckRateLimit(c.Request.Context(), info)
+		if info.RateLimit > 0 && remaining >= 0 {
+			c.Header("X-RateLimit-Limit", strconv.Itoa(info.RateLimit))
+			c.Header("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		}
+		if errors.Is(err, ErrRateLimited) {
+			reset := time.Until(time.Now().Truncate(rateLimitWindow).Add(rateLimitWindow))
+			c.Header("Retry-After", strconv.Itoa(int(reset.Seconds())+1))
+			utils.ErrorResponse(c, http.StatusTooManyRequests, "rate_limited", err.Error(), nil)
+			c.Abort()
+			return
+		}
+
+		c.Set("user_id", strconv.FormatUint(uint64(info.UserID), 10))
+		c.Set(contextTokenInfo, info)
+		c.Next()
+	}
+}
+
+// CreateApp registers a new app
+// @Summary Register an OAuth app
+// @Description Register a third-party app. The client secret and webhook secret are only returned once
+// @Tags oauth
+// @Accept json
+// @Produce json
+// @Param request body AppRequest true "App details"
+// @Success 201 {object} AppCredentials
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/oauth/apps [post]
+func (h *Handler) CreateApp(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	var req AppRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "invalid_request", err.Error(), nil)
+		return
+	}
+
+	creds, err := h.service.RegisterApp(userID, req)
+	if err != nil {
+		oauthErrorResponse(c, err, "failed to register app")
+		return
+	}
+
+	c.JSON(http.StatusCreated, utils.APIResponse{
+		Success: true,
+		Message: "App registered successfully",
+		Data:    creds,
+	})
+}
+
+// ListApps lists the developer's apps
+// @Summary List OAuth apps
+// @Description List the apps registered by the current user
+// @Tags oauth
+// @Produce json
+// @Success 200 {array} database.OAuthApp
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/oauth/apps [get]
+func (h *Handler) ListApps(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	apps, err := h.service.ListApps(userID)
+	if err != nil {
+		oauthErrorResponse(c, err, "failed to list apps")
+		return
+	}
+
+	utils.SuccessResponse(c, apps, "Apps retrieved successfully")
+}
+
+// GetApp returns one of the developer's apps
+// @Summary Get an OAuth app
+// @Tags oauth
+// @Produce json
+// @Param id path int true "App ID"
+// @Success 200 {object} database.OAuthApp
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /api/v1/oauth/apps/{id} [get]
+func (h *Handler) GetApp(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	app, err := h.service.GetApp(userID, id)
+	if err != nil {
+		oauthErrorResponse(c, err, "failed to get app")
+		return
+	}
+
+	utils.SuccessResponse(c, app, "App retrieved successfully")
+}
+
+// UpdateApp updates one of the developer's apps
+// @Summary Update an OAuth app
+// @Tags oauth
+// @Accept json
+// @Produce json
+// @Param id path int true "App ID"
+// @Param request body AppRequest true "App details"
+// @Success 200 {object} database.OAuthApp
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /api/v1/oauth/apps/{id} [put]
+func (h *Handler) UpdateApp(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	var req AppRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "invalid_request", err.Error(), nil)
+		return
+	}
+
+	app, err := h.service.UpdateApp(userID, id, req)
+	if err != nil {
+		oauthErrorResponse(c, err, "failed to update app")
+		return
+	}
+
+	utils.SuccessResponse(c, app, "App updated successfully")
+}
+
+// DeleteApp deletes one of the developer's apps
+// @Summary Delete an OAuth app
+// @Description Delete an app, revoking every authorization, token and webhook subscription it holds
+// @Tags oauth
+// @Produce json
+// @Param id path int true "App ID"
+// @Success 200 {object} utils.SuccessResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /api/v1/oauth/apps/{id} [delete]
+func (h *Handler) DeleteApp(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteApp(userID, id); err != nil {
+		oauthErrorResponse(c, err, "failed to delete app")
+		return
+	}
+
+	utils.SuccessResponse(c, nil, "App deleted successfully")
+}
+
+// RotateSecrets issues new app secrets
+// @Summary Rotate OAuth app secrets
+// @Description Replace the client secret and webhook signing secret. The new secrets are only returned once
+// @Tags oauth
+// @Produce json
+// @Param id path int true "App ID"
+// @Success 200 {object} AppCredentials
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /api/v1/oauth/apps/{id}/secret [post]
+func (h *Handler) RotateSecrets(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	creds, err := h.service.RotateSecrets(userID, id)
+	if err != nil {
+		oauthErrorResponse(c, err, "failed to rotate secrets")
+		return
+	}
+
+	utils.SuccessResponse(c, creds, "App secrets rotated successfully")
+}
+
+// GetConsent describes an authorization request for the consent screen
+// @Summary Get OAuth consent details
+// @Description Validate an authorization request and describe the app and scopes the user is asked to approve
+// @Tags oauth
+// @Produce json
+// @Param client_id query string true "Client ID"
+// @Param redirect_uri query string true "Redirect URI"
+// @Param scope query string true "Space separated scopes"
+// @Success 200 {object} Consent
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Router /api/v1/oauth/authorize [get]
+func (h *Handler) GetConsent(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	var req AuthorizeRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "invalid_request", err.Error(), nil)
+		return
+	}
+
+	consent, err := h.service.GetConsent(userID, req)
+	if err != nil {
+		oauthErrorResponse(c, err, "failed to get consent")
+		return
+	}
+
+	utils.SuccessResponse(c, consent, "Consent details retrieved successfully")
+}
+
+// Authorize records the user's consent decision
+// @Summary Approve or deny an OAuth app
+// @Description Record the user's decision and return the URL to redirect the user back to the app
+// @Tags oauth
+// @Accept json
+// @Produce json
+// @Param request body AuthorizeRequest true "Authorization request and decision"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Router /api/v1/oauth/authorize [post]
+func (h *Handler) Authorize(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	var req AuthorizeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "invalid_request", err.Error(), nil)
+		return
+	}
+
+	redirect, err := h.service.Authorize(userID, req)
+	if err != nil {
+		oauthErrorResponse(c, err, "failed to authorize app")
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{"redirect_uri": redirect}, "Authorization recorded")
+}
+
+// ListAuthorizations lists the apps the user has authorized
+// @Summary List authorized apps
+// @Tags oauth
+// @Produce json
+// @Success 200 {array} database.OAuthGrant
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/oauth/authorizations [get]
+func (h *Handler) ListAuthorizations(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	grants, err := h.service.ListGrants(userID)
+	if err != nil {
+		oauthErrorResponse(c, err, "failed to list authorizations")
+		return
+	}
+
+	utils.SuccessResponse(c, grants, "Authorizations retrieved successfully")
+}
+
+// RevokeAuthorization revokes an app's access to the user's account
+// @Summary Revoke an authorized app
+// @Description Revoke the app's tokens and remove its webhook subscriptions for the user
+// @Tags oauth
+// @Produce json
+// @Param id path int true "Authorization ID"
+// @Success 200 {object} utils.SuccessResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /api/v1/oauth/authorizations/{id} [delete]
+func (h *Handler) RevokeAuthorization(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.RevokeGrant(userID, id); err != nil {
+		oauthErrorResponse(c, err, "failed to revoke authorization")
+		return
+	}
+
+	utils.SuccessResponse(c, nil, "Authorization revoked successfully")
+}
+
+// Token is the OAuth 2.0 token endpoint. Responses follow RFC 6749 rather
+// than the API envelope so standard OAuth client libraries work
+// @Summary OAuth token endpoint
+// @Description Exchange an authorization code or refresh token for an access token
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Success 200 {object} TokenResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /api/v1/oauth/token [post]
+func (h *Handler) Token(c *gin.Context) {
+	var req TokenRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "error_description": err.Error()})
+		return
+	}
+	if req.ClientSecret == "" {
+		// Clients may authenticate with HTTP Basic instead of the form
+		if id, secret, ok := c.Request.BasicAuth(); ok && id == req.ClientID {
+			req.ClientSecret = secret
+		}
+	}
+
+	token, err := h.service.Exchange(req)
+	c.Header("Cache-Control", "no-store")
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, token)
+	case errors.Is(err, ErrInvalidClient):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_client"})
+	case errors.Is(err, ErrInvalidGrant), errors.Is(err, ErrUnsupportedChallenge):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_grant"})
+	case errors.Is(err, ErrUnsupportedGrantType):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported_grant_type"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+	}
+}
+
+// Revoke is the OAuth 2.0 token revocation endpoint
+// @Summary Revoke an OAuth token
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Success 200
+// @Failure 401 {object} map[string]string
+// @Router /api/v1/oauth/revoke [post]
+func (h *Handler) Revoke(c *gin.Context) {
+	clientID := c.PostForm("client_id")
+	clientSecret := c.PostForm("client_secret")
+	if id, secret, ok := c.Request.BasicAuth(); ok {
+		clientID, clientSecret = id, secret
+	}
+
+	err := h.service.RevokeToken(clientID, clientSecret, c.PostForm("token"))
+	switch {
+	case err == nil:
+		c.Status(http.StatusOK)
+	case errors.Is(err, ErrInvalidClient):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_client"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+	}
+}
+
+// GetProfile returns the user the app acts for
+// @Summary Get the authorizing user
+// @Description Requires the profile:read scope
+// @Tags app-api
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 429 {object} utils.ErrorResponse
+// @Router /api/v1/app/me [get]
+func (h *Handler) GetProfile(c *gin.Context) {
+	info := tokenInfo(c)
+
+	user, err := h.service.GetProfile(info.UserID)
+	if err != nil {
+		oauthErrorResponse(c, err, "failed to get profile")
+		return
+	}
+
+	// Apps only see the public parts of the profile
+	utils.SuccessResponse(c, gin.H{
+		"id":           user.ID,
+		"username":     user.Username,
+		"display_name": user.DisplayName,
+		"avatar":       user.Avatar,
+	}, "Profile retrieved successfully")
+}
+
+// ListSubscriptions lists the app's webhook subscriptions for the user
+// @Summary List app webhook subscriptions
+// @Description Requires the webhooks scope
+// @Tags app-api
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} automation.WebhookEndpoint
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Router /api/v1/app/webhooks [get]
+func (h *Handler) ListSubscriptions(c *gin.Context) {
+	endpoints, err := h.service.ListSubscriptions(tokenInfo(c))
+	if err != nil {
+		oauthErrorResponse(c, err, "failed to list subscriptions")
+		return
+	}
+
+	utils.SuccessResponse(c, endpoints, "Subscriptions retrieved successfully")
+}
+
+// CreateSubscription subscribes the app to the user's events
+// @Summary Create an app webhook subscription
+// @Description Requires the webhooks scope. Deliveries are signed with the app's webhook secret
+// @Tags app-api
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body SubscriptionRequest true "Subscription"
+// @Success 201 {object} automation.WebhookEndpoint
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Router /api/v1/app/webhooks [post]
+func (h *Handler) CreateSubscription(c *gin.Context) {
+	var req SubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "invalid_request", err.Error(), nil)
+		return
+	}
+
+	endpoint, err := h.service.CreateSubscription(tokenInfo(c), req)
+	if err != nil {
+		oauthErrorResponse(c, err, "failed to create subscription")
+		return
+	}
+
+	c.JSON(http.StatusCreated, utils.APIResponse{
+		Success: true,
+		Message: "Subscription created successfully",
+		Data:    endpoint,
+	})
+}
+
+// DeleteSubscription removes one of the app's webhook subscriptions
+// @Summary Delete an app webhook subscription
+// @Tags app-api
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Subscription ID"
+// @Success 200 {object} utils.SuccessResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /api/v1/app/webhooks/{id} [delete]
+func (h *Handler) DeleteSubscription(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteSubscription(tokenInfo(c), id); err != nil {
+		oauthErrorResponse(c, err, "failed to delete subscription")
+		return
+	}
+
+	utils.SuccessResponse(c, nil, "Subscription deleted successfully")
+}
+
+func tokenInfo(c *gin.Context) *TokenInfo {
+	info, _ := c.MustGet(contextTokenInfo).(*TokenInfo)
+	return info
+}
+
+func requireUserID(c *gin.Context) (uint, bool) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "unauthorized", "user not authenticated", nil)
+		return 0, false
+	}
+	return userID, true
+}
+
+func parseID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "invalid_id", "invalid ID", nil)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// oauthErrorResponse maps OAuth errors to HTTP responses
+func oauthErrorResponse(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, ErrAppNotFound), errors.Is(err, ErrGrantNotFound), errors.Is(err, ErrSubscriptionNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, "not_found", err.Error(), nil)
+	case errors.Is(err, ErrInvalidClient):
+		utils.ErrorResponse(c, http.StatusBadRequest, "invalid_client", "unknown or inactive client", nil)
+	case errors.Is(err, ErrInvalidScope):
+		utils.ErrorResponse(c, http.StatusBadRequest, "invalid_scope", "unknown scope or scope not allowed for this app", nil)
+	case errors.Is(err, ErrInsufficientScope):
+		utils.ErrorResponse(c, http.StatusForbidden, "insufficient_scope", err.Error(), nil)
+	case errors.Is(err, ErrInvalidToken):
+		utils.ErrorResponse(c, http.StatusUnauthorized, "invalid_token", err.Error(), nil)
+	case errors.Is(err, ErrInvalidRedirectURI), errors.Is(err, ErrRedirectURIsRequired),
+		errors.Is(err, ErrUnsupportedChallenge), errors.Is(err, ErrInvalidEvent):
+		utils.ErrorResponse(c, http.StatusBadRequest, "invalid_request", err.Error(), nil)
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal_error", message, nil)
+	}
+}
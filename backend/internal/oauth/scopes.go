@@ -0,0 +1,72 @@
+package oauth
+
+import (
+	"sort"
+	"strings"
+)
+
+// Scopes an app can request. Each maps to a line on the consent screen
+const (
+	ScopeProfileRead      = "profile:read"
+	ScopeBookmarksRead    = "bookmarks:read"
+	ScopeBookmarksWrite   = "bookmarks:write"
+	ScopeCollectionsRead  = "collections:read"
+	ScopeCollectionsWrite = "collections:write"
+	ScopeWebhooks         = "webhooks"
+)
+
+// ScopeDescriptions explains each scope to the user granting it
+var ScopeDescriptions = map[string]string{
+	ScopeProfileRead:      "Read your profile",
+	ScopeBookmarksRead:    "Read your bookmarks",
+	ScopeBookmarksWrite:   "Create, edit and delete your bookmarks",
+	ScopeCollectionsRead:  "Read your collections",
+	ScopeCollectionsWrite: "Create, edit and delete your collections",
+	ScopeWebhooks:         "Receive notifications when your bookmarks and collections change",
+}
+
+// ParseScopes splits a space separated OAuth scope string, dropping
+// duplicates. It reports false when a scope is unknown
+func ParseScopes(scope string) ([]string, bool) {
+	return normalizeScopes(strings.Fields(scope))
+}
+
+func normalizeScopes(scopes []string) ([]string, bool) {
+	seen := make(map[string]bool, len(scopes))
+	result := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if _, ok := ScopeDescriptions[scope]; !ok {
+			return nil, false
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			result = append(result, scope)
+		}
+	}
+	sort.Strings(result)
+	return result, true
+}
+
+// hasScopes reports whether granted includes every required scope. A write
+// scope implies the matching read scope
+func hasScopes(granted []string, required ...string) bool {
+	set := make(map[string]bool, len(granted)*2)
+	for _, scope := range granted {
+		set[scope] = true
+		if strings.HasSuffix(scope, ":write") {
+			set[strings.TrimSuffix(scope, ":write")+":read"] = true
+		}
+	}
+	for _, scope := range required {
+		if !set[scope] {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeScopes returns the sorted union of two scope lists
+func mergeScopes(a, b []string) []string {
+	merged, _ := normalizeScopes(append(append([]string{}, a...), b...))
+	return merged
+}
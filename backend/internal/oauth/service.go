@@ -0,0 +1,832 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/internal/automation"
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+const (
+	codeTTL         = 10 * time.Minute
+	accessTokenTTL  = time.Hour
+	refreshTokenTTL = 30 * 24 * time.Hour
+
+	// Token prefixes let the API tell app tokens apart from user JWTs
+	accessTokenPrefix  = "bsa_"
+	refreshTokenPrefix = "bsr_"
+
+	// defaultRateLimit is the hourly request budget of a new app
+	defaultRateLimit = 1000
+	// rateLimitWindow is the period app rate limits are counted over
+	rateLimitWindow = time.Hour
+)
+
+// OAuth errors. Token endpoint errors use the RFC 6749 error codes
+var (
+	ErrAppNotFound          = errors.New("app not found")
+	ErrGrantNotFound        = errors.New("authorization not found")
+	ErrSubscriptionNotFound = errors.New("webhook subscription not found")
+	ErrInvalidClient        = errors.New("invalid_client")
+	ErrInvalidGrant         = errors.New("invalid_grant")
+	ErrInvalidScope         = errors.New("invalid_scope")
+	ErrInvalidRedirectURI   = errors.New("invalid redirect URI")
+	ErrUnsupportedGrantType = errors.New("unsupported_grant_type")
+	ErrInvalidToken         = errors.New("invalid or expired access token")
+	ErrInsufficientScope    = errors.New("insufficient_scope")
+	ErrRateLimited          = errors.New("app rate limit exceeded")
+	ErrInvalidEvent         = errors.New("unsupported webhook event")
+	ErrUnsupportedChallenge = errors.New("unsupported code challenge method")
+	ErrRedirectURIsRequired = errors.New("at least one redirect URI is required")
+)
+
+// subscribableEvents are the user events apps may subscribe to, with the
+// scope an app needs to read what the event carries
+var subscribableEvents = map[automation.WebhookEvent]string{
+	automation.WebhookEventBookmarkCreated:   ScopeBookmarksRead,
+	automation.WebhookEventBookmarkUpdated:   ScopeBookmarksRead,
+	automation.WebhookEventBookmarkDeleted:   ScopeBookmarksRead,
+	automation.WebhookEventCollectionCreated: ScopeCollectionsRead,
+	automation.WebhookEventCollectionUpdated: ScopeCollectionsRead,
+	automation.WebhookEventCollectionDeleted: ScopeCollectionsRead,
+}
+
+// RateCounter counts requests in a window, e.g. the Redis client
+type RateCounter interface {
+	IncrementWithExpiration(ctx context.Context, key string, expiration time.Duration) (int64, error)
+}
+
+// Service implements app registration, the authorization code flow, app
+// token authentication and per-app webhook subscriptions
+type Service struct {
+	db      *gorm.DB
+	counter RateCounter
+	now     func() time.Time
+}
+
+// NewService creates a new OAuth service. counter may be nil to disable app
+// rate limits
+func NewService(db *gorm.DB, counter RateCounter) *Service {
+	return &Service{db: db, counter: counter, now: time.Now}
+}
+
+// AppRequest registers or updates an app
+type AppRequest struct {
+	Name         string   `json:"name" binding:"required,max=100"`
+	Description  string   `json:"description" binding:"max=1000"`
+	HomepageURL  string   `json:"homepage_url" binding:"omitempty,url"`
+	RedirectURIs []string `json:"redirect_uris" binding:"required,max=10"`
+	Scopes       []string `json:"scopes" binding:"required"`
+}
+
+// AppCredentials is returned when an app is created or its secret rotated.
+// The secrets are only shown once
+type AppCredentials struct {
+	App           *database.OAuthApp `json:"app"`
+	ClientSecret  string             `json:"client_secret"`
+	WebhookSecret string             `json:"webhook_secret"`
+}
+
+// AuthorizeRequest carries the authorization request parameters
+type AuthorizeRequest struct {
+	ClientID            string `json:"client_id" form:"client_id" binding:"required"`
+	RedirectURI         string `json:"redirect_uri" form:"redirect_uri" binding:"required"`
+	Scope               string `json:"scope" form:"scope" binding:"required"`
+	State               string `json:"state" form:"state"`
+	CodeChallenge       string `json:"code_challenge" form:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method" form:"code_challenge_method"`
+	// Approve is the user's answer on the consent screen
+	Approve bool `json:"approve"`
+}
+
+// ScopeInfo describes a scope on the consent screen
+type ScopeInfo struct {
+	Scope       string `json:"scope"`
+	Description string `json:"description"`
+}
+
+// Consent is what the consent screen shows before the user approves
+type Consent struct {
+	App            ConsentApp  `json:"app"`
+	Scopes         []ScopeInfo `json:"scopes"`
+	AlreadyGranted bool        `json:"already_granted"`
+}
+
+// ConsentApp is the public description of an app
+type ConsentApp struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	HomepageURL string `json:"homepage_url"`
+}
+
+// TokenRequest is the token endpoint form
+type TokenRequest struct {
+	GrantType    string `form:"grant_type" binding:"required"`
+	ClientID     string `form:"client_id" binding:"required"`
+	ClientSecret string `form:"client_secret"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier"`
+	RefreshToken string `form:"refresh_token"`
+}
+
+// TokenResponse is the RFC 6749 token response
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	Scope        string `json:"scope"`
+}
+
+// TokenInfo identifies the app and user behind an access token
+type TokenInfo struct {
+	TokenID   uint
+	AppID     uint
+	UserID    uint
+	Scopes    []string
+	RateLimit int
+}
+
+// SubscriptionRequest subscribes an app to a user's events
+type SubscriptionRequest struct {
+	URL    string   `json:"url" binding:"required,url"`
+	Events []string `json:"events" binding:"required,min=1"`
+}
+
+// RegisterApp creates an app owned by a developer
+func (s *Service) RegisterApp(ownerID uint, req AppRequest) (*AppCredentials, error) {
+	scopes, redirectURIs, err := validateApp(req)
+	if err != nil {
+		return nil, err
+	}
+
+	clientID, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	clientSecret, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	webhookSecret, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	app := &database.OAuthApp{
+		OwnerID:          ownerID,
+		Name:             req.Name,
+		Description:      req.Description,
+		HomepageURL:      req.HomepageURL,
+		RedirectURIs:     redirectURIs,
+		Scopes:           scopes,
+		ClientID:         clientID,
+		ClientSecretHash: hashToken(clientSecret),
+		WebhookSecret:    webhookSecret,
+		RateLimit:        defaultRateLimit,
+		Active:           true,
+	}
+	if err := s.db.Create(app).Error; err != nil {
+		return nil, fmt.Errorf("failed to create app: %w", err)
+	}
+
+	return &AppCredentials{App: app, ClientSecret: clientSecret, WebhookSecret: webhookSecret}, nil
+}
+
+// ListApps returns the apps owned by a developer
+func (s *Service) ListApps(ownerID uint) ([]database.OAuthApp, error) {
+	var apps []database.OAuthApp
+	if err := s.db.Where("owner_id = ?", ownerID).Order("created_at DESC").Find(&apps).Error; err != nil {
+		return nil, fmt.Errorf("failed to list apps: %w", err)
+	}
+	return apps, nil
+}
+
+// GetApp returns an app owned by a developer
+func (s *Service) GetApp(ownerID, id uint) (*database.OAuthApp, error) {
+	var app database.OAuthApp
+	if err := s.db.Where("id = ? AND owner_id = ?", id, ownerID).First(&app).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAppNotFound
+		}
+		return nil, fmt.Errorf("failed to get app: %w", err)
+	}
+	return &app, nil
+}
+
+// UpdateApp changes an app's details. Narrowing its scopes does not change
+// grants users already made
+func (s *Service) UpdateApp(ownerID, id uint, req AppRequest) (*database.OAuthApp, error) {
+	app, err := s.GetApp(ownerID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes, redirectURIs, err := validateApp(req)
+	if err != nil {
+		return nil, err
+	}
+
+	app.Name = req.Name
+	app.Description = req.Description
+	app.HomepageURL = req.HomepageURL
+	app.RedirectURIs = redirectURIs
+	app.Scopes = scopes
+	if err := s.db.Save(app).Error; err != nil {
+		return nil, fmt.Errorf("failed to update app: %w", err)
+	}
+	return app, nil
+}
+
+// RotateSecrets replaces the client and webhook secrets of an app
+func (s *Service) RotateSecrets(ownerID, id uint) (*AppCredentials, error) {
+	app, err := s.GetApp(ownerID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	clientSecret, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	webhookSecret, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(app).Updates(map[string]interface{}{
+			"client_secret_hash": hashToken(clientSecret),
+			"webhook_secret":     webhookSecret,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to rotate secrets: %w", err)
+		}
+		// Existing subscriptions sign with the new secret from now on
+		if err := tx.Model(&automation.WebhookEndpoint{}).Where("oauth_app_id = ?", app.ID).
+			Update("secret", webhookSecret).Error; err != nil {
+			return fmt.Errorf("failed to update subscription secrets: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &AppCredentials{App: app, ClientSecret: clientSecret, WebhookSecret: webhookSecret}, nil
+}
+
+// DeleteApp removes an app and revokes everything users granted it
+func (s *Service) DeleteApp(ownerID, id uint) error {
+	app, err := s.GetApp(ownerID, id)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		now := s.now()
+		if err := tx.Model(&database.OAuthGrant{}).Where("app_id = ? AND revoked_at IS NULL", app.ID).
+			Update("revoked_at", now).Error; err != nil {
+			return fmt.Errorf("failed to revoke grants: %w", err)
+		}
+		if err := tx.Model(&database.OAuthToken{}).Where("app_id = ? AND revoked_at IS NULL", app.ID).
+			Update("revoked_at", now).Error; err != nil {
+			return fmt.Errorf("failed to revoke tokens: %w", err)
+		}
+		if err := tx.Where("oauth_app_id = ?", app.ID).Delete(&automation.WebhookEndpoint{}).Error; err != nil {
+			return fmt.Errorf("failed to delete subscriptions: %w", err)
+		}
+		if err := tx.Delete(app).Error; err != nil {
+			return fmt.Errorf("failed to delete app: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetConsent validates an authorization request and describes what the user
+// is asked to approve
+func (s *Service) GetConsent(userID uint, req AuthorizeRequest) (*Consent, error) {
+	app, scopes, err := s.validateAuthorize(req)
+	if err != nil {
+		return nil, err
+	}
+
+	consent := &Consent{
+		App: ConsentApp{
+			Name:        app.Name,
+			Description: app.Description,
+			HomepageURL: app.HomepageURL,
+		},
+		Scopes: make([]ScopeInfo, 0, len(scopes)),
+	}
+	for _, scope := range scopes {
+		consent.Scopes = append(consent.Scopes, ScopeInfo{Scope: scope, Description: ScopeDescriptions[scope]})
+	}
+
+	var grant database.OAuthGrant
+	if err := s.db.Where("user_id = ? AND app_id = ? AND revoked_at IS NULL", userID, app.ID).First(&grant).Error; err == nil {
+		consent.AlreadyGranted = hasScopes(grant.Scopes, scopes...)
+	}
+
+	return consent, nil
+}
+
+// Authorize records the user's answer and returns the URL to send the user
+// back to, carrying either an authorization code or an access_denied error
+func (s *Service) Authorize(userID uint, req AuthorizeRequest) (string, error) {
+	app, scopes, err := s.validateAuthorize(req)
+	if err != nil {
+		return "", err
+	}
+
+	redirect, _ := url.Parse(req.RedirectURI)
+	query := redirect.Query()
+	if req.State != "" {
+		query.Set("state", req.State)
+	}
+
+	if !req.Approve {
+		query.Set("error", "access_denied")
+		redirect.RawQuery = query.Encode()
+		return redirect.String(), nil
+	}
+
+	code, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var grant database.OAuthGrant
+		err := tx.Where("user_id = ? AND app_id = ?", userID, app.ID).First(&grant).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			grant = database.OAuthGrant{UserID: userID, AppID: app.ID, Scopes: scopes}
+			if err := tx.Create(&grant).Error; err != nil {
+				return fmt.Errorf("failed to create grant: %w", err)
+			}
+		case err != nil:
+			return fmt.Errorf("failed to get grant: %w", err)
+		default:
+			// Re-consenting extends an active grant, or restores a revoked one
+			if grant.RevokedAt != nil {
+				grant.Scopes = scopes
+			} else {
+				grant.Scopes = mergeScopes(grant.Scopes, scopes)
+			}
+			grant.RevokedAt = nil
+			if err := tx.Save(&grant).Error; err != nil {
+				return fmt.Errorf("failed to update grant: %w", err)
+			}
+		}
+
+		return tx.Create(&database.OAuthCode{
+			CodeHash:            hashToken(code),
+			AppID:               app.ID,
+			UserID:              userID,
+			GrantID:             grant.ID,
+			RedirectURI:         req.RedirectURI,
+			Scopes:              scopes,
+			CodeChallenge:       req.CodeChallenge,
+			CodeChallengeMethod: req.CodeChallengeMethod,
+			ExpiresAt:           s.now().Add(codeTTL),
+		}).Error
+	})
+	if err != nil {
+		return "", err
+	}
+
+	query.Set("code", code)
+	redirect.RawQuery = query.Encode()
+	return redirect.String(), nil
+}
+
+// Exchange implements the token endpoint for the authorization_code and
+// refresh_token grants
+func (s *Service) Exchange(req TokenRequest) (*TokenResponse, error) {
+	app, err := s.authenticateClient(req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	switch req.GrantType {
+	case "authorization_code":
+		return s.exchangeCode(app, req)
+	case "refresh_token":
+		return s.refresh(app, req.RefreshToken)
+	default:
+		return nil, ErrUnsupportedGrantType
+	}
+}
+
+// RevokeToken revokes an access or refresh token held by the app (RFC 7009).
+// Unknown tokens are ignored
+func (s *Service) RevokeToken(clientID, clientSecret, token string) error {
+	app, err := s.authenticateClient(clientID, clientSecret)
+	if err != nil {
+		return err
+	}
+
+	hash := hashToken(token)
+	return s.db.Model(&database.OAuthToken{}).
+		Where("app_id = ? AND (access_token_hash = ? OR refresh_token_hash = ?) AND revoked_at IS NULL", app.ID, hash, hash).
+		Update("revoked_at", s.now()).Error
+}
+
+// IsAccessToken reports whether a bearer token is an app access token rather
+// than a user JWT
+func IsAccessToken(token string) bool {
+	return strings.HasPrefix(token, accessTokenPrefix)
+}
+
+// Authenticate resolves an access token to the app and user it acts for
+func (s *Service) Authenticate(token string) (*TokenInfo, error) {
+	var record database.OAuthToken
+	if err := s.db.Where("access_token_hash = ?", hashToken(token)).First(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, fmt.Errorf("failed to get token: %w", err)
+	}
+	now := s.now()
+	if record.RevokedAt != nil || now.After(record.ExpiresAt) {
+		return nil, ErrInvalidToken
+	}
+
+	var app database.OAuthApp
+	if err := s.db.First(&app, record.AppID).Error; err != nil || !app.Active {
+		return nil, ErrInvalidToken
+	}
+
+	s.db.Model(&record).Update("last_used_at", now)
+
+	return &TokenInfo{
+		TokenID:   record.ID,
+		AppID:     record.AppID,
+		UserID:    record.UserID,
+		Scopes:    record.Scopes,
+		RateLimit: app.RateLimit,
+	}, nil
+}
+
+// CheckRateLimit counts a request against the app's hourly budget across
+// all of its users and returns how many requests remain
+func (s *Service) CheckRateLimit(ctx context.Context, info *TokenInfo) (int, error) {
+	if s.counter == nil || info.RateLimit <= 0 {
+		return -1, nil
+	}
+
+	window := s.now().Truncate(rateLimitWindow).Unix()
+	key := fmt.Sprintf("oauth:ratelimit:%d:%d", info.AppID, window)
+	count, err := s.counter.IncrementWithExpiration(ctx, key, rateLimitWindow)
+	if err != nil {
+		// Fail open: a Redis outage should not take every integration down
+		return -1, nil
+	}
+	if count > int64(info.RateLimit) {
+		return 0, ErrRateLimited
+	}
+	return info.RateLimit - int(count), nil
+}
+
+// GetProfile returns the user an app token acts for
+func (s *Service) GetProfile(userID uint) (*database.User, error) {
+	var user database.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return &user, nil
+}
+
+// ListGrants returns the apps a user has authorized
+func (s *Service) ListGrants(userID uint) ([]database.OAuthGrant, error) {
+	var grants []database.OAuthGrant
+	if err := s.db.Preload("App").
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Order("updated_at DESC").
+		Find(&grants).Error; err != nil {
+		return nil, fmt.Errorf("failed to list authorizations: %w", err)
+	}
+	return grants, nil
+}
+
+// RevokeGrant withdraws a user's consent: the app's tokens stop working and
+// its webhook subscriptions for the user are removed
+func (s *Service) RevokeGrant(userID, grantID uint) error {
+	var grant database.OAuthGrant
+	if err := s.db.Where("id = ? AND user_id = ? AND revoked_at IS NULL", grantID, userID).First(&grant).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrGrantNotFound
+		}
+		return fmt.Errorf("failed to get authorization: %w", err)
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		now := s.now()
+		if err := tx.Model(&grant).Update("revoked_at", now).Error; err != nil {
+			return fmt.Errorf("failed to revoke authorization: %w", err)
+		}
+		if err := tx.Model(&database.OAuthToken{}).Where("grant_id = ? AND revoked_at IS NULL", grant.ID).
+			Update("revoked_at", now).Error; err != nil {
+			return fmt.Errorf("failed to revoke tokens: %w", err)
+		}
+		if err := tx.Where("user_id = ? AND oauth_app_id = ?", strconv.FormatUint(uint64(userID), 10), grant.AppID).
+			Delete(&automation.WebhookEndpoint{}).Error; err != nil {
+			return fmt.Errorf("failed to delete subscriptions: %w", err)
+		}
+		return nil
+	})
+}
+
+// CreateSubscription subscribes the app to the user's events. Deliveries go
+// through the user's webhook pipeline and are signed with the app's secret.
+// Besides webhooks, the token must grant reading what each event carries
+func (s *Service) CreateSubscription(info *TokenInfo, req SubscriptionRequest) (*automation.WebhookEndpoint, error) {
+	if !hasScopes(info.Scopes, ScopeWebhooks) {
+		return nil, ErrInsufficientScope
+	}
+	for _, event := range req.Events {
+		scope, ok := subscribableEvents[automation.WebhookEvent(event)]
+		if !ok {
+			return nil, ErrInvalidEvent
+		}
+		if !hasScopes(info.Scopes, scope) {
+			return nil, ErrInsufficientScope
+		}
+	}
+
+	var app database.OAuthApp
+	if err := s.db.First(&app, info.AppID).Error; err != nil {
+		return nil, ErrAppNotFound
+	}
+
+	appID := app.ID
+	endpoint := &automation.WebhookEndpoint{
+		UserID:     strconv.FormatUint(uint64(info.UserID), 10),
+		Name:       app.Name,
+		URL:        req.URL,
+		Secret:     app.WebhookSecret,
+		Events:     automation.StringSlice(req.Events),
+		Active:     true,
+		RetryCount: 3,
+		Timeout:    30,
+		OAuthAppID: &appID,
+	}
+	if err := s.db.Create(endpoint).Error; err != nil {
+		return nil, fmt.Errorf("failed to create subscription: %w", err)
+	}
+	return endpoint, nil
+}
+
+// ListSubscriptions returns the app's subscriptions for the user
+func (s *Service) ListSubscriptions(info *TokenInfo) ([]automation.WebhookEndpoint, error) {
+	var endpoints []automation.WebhookEndpoint
+	if err := s.db.Where("user_id = ? AND oauth_app_id = ?", strconv.FormatUint(uint64(info.UserID), 10), info.AppID).
+		Find(&endpoints).Error; err != nil {
+		return nil, fmt.Errorf("failed to list subscriptions: %w", err)
+	}
+	return endpoints, nil
+}
+
+// DeleteSubscription removes one of the app's subscriptions for the user
+func (s *Service) DeleteSubscription(info *TokenInfo, id uint) error {
+	result := s.db.Where("id = ? AND user_id = ? AND oauth_app_id = ?", id, strconv.FormatUint(uint64(info.UserID), 10), info.AppID).
+		Delete(&automation.WebhookEndpoint{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete subscription: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrSubscriptionNotFound
+	}
+	return nil
+}
+
+func (s *Service) validateAuthorize(req AuthorizeRequest) (*database.OAuthApp, []string, error) {
+	var app database.OAuthApp
+	if err := s.db.Where("client_id = ? AND active = ?", req.ClientID, true).First(&app).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrInvalidClient
+		}
+		return nil, nil, fmt.Errorf("failed to get app: %w", err)
+	}
+
+	// The redirect URI must match exactly so codes cannot be sent elsewhere
+	registered := false
+	for _, uri := range app.RedirectURIs {
+		if uri == req.RedirectURI {
+			registered = true
+			break
+		}
+	}
+	if !registered {
+		return nil, nil, ErrInvalidRedirectURI
+	}
+
+	scopes, ok := ParseScopes(req.Scope)
+	if !ok || len(scopes) == 0 || !hasScopes(app.Scopes, scopes...) {
+		return nil, nil, ErrInvalidScope
+	}
+
+	switch req.CodeChallengeMethod {
+	case "", "S256", "plain":
+	default:
+		return nil, nil, ErrUnsupportedChallenge
+	}
+
+	return &app, scopes, nil
+}
+
+func (s *Service) authenticateClient(clientID, clientSecret string) (*database.OAuthApp, error) {
+	var app database.OAuthApp
+	if err := s.db.Where("client_id = ? AND active = ?", clientID, true).First(&app).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidClient
+		}
+		return nil, fmt.Errorf("failed to get app: %w", err)
+	}
+	if subtle.ConstantTimeCompare([]byte(hashToken(clientSecret)), []byte(app.ClientSecretHash)) != 1 {
+		return nil, ErrInvalidClient
+	}
+	return &app, nil
+}
+
+func (s *Service) exchangeCode(app *database.OAuthApp, req TokenRequest) (*TokenResponse, error) {
+	var response *TokenResponse
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var code database.OAuthCode
+		if err := tx.Where("code_hash = ? AND app_id = ?", hashToken(req.Code), app.ID).First(&code).Error; err != nil {
+			return ErrInvalidGrant
+		}
+		if code.UsedAt != nil || s.now().After(code.ExpiresAt) || code.RedirectURI != req.RedirectURI {
+			return ErrInvalidGrant
+		}
+		if err := verifyCodeChallenge(code.CodeChallenge, code.CodeChallengeMethod, req.CodeVerifier); err != nil {
+			return err
+		}
+
+		// Codes are single use; the conditional update wins any race
+		result := tx.Model(&database.OAuthCode{}).Where("id = ? AND used_at IS NULL", code.ID).Update("used_at", s.now())
+		if result.Error != nil {
+			return fmt.Errorf("failed to use authorization code: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidGrant
+		}
+
+		var err error
+		response, err = s.issueToken(tx, app.ID, code.UserID, code.GrantID, code.Scopes)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (s *Service) refresh(app *database.OAuthApp, refreshToken string) (*TokenResponse, error) {
+	var response *TokenResponse
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var record database.OAuthToken
+		if err := tx.Where("refresh_token_hash = ? AND app_id = ?", hashToken(refreshToken), app.ID).First(&record).Error; err != nil {
+			return ErrInvalidGrant
+		}
+		if record.RevokedAt != nil || s.now().After(record.RefreshExpiresAt) {
+			return ErrInvalidGrant
+		}
+
+		var grant database.OAuthGrant
+		if err := tx.First(&grant, record.GrantID).Error; err != nil || grant.RevokedAt != nil {
+			return ErrInvalidGrant
+		}
+
+		// Refresh tokens rotate: the old pair stops working
+		result := tx.Model(&database.OAuthToken{}).Where("id = ? AND revoked_at IS NULL", record.ID).Update("revoked_at", s.now())
+		if result.Error != nil {
+			return fmt.Errorf("failed to rotate token: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidGrant
+		}
+
+		var err error
+		response, err = s.issueToken(tx, app.ID, record.UserID, record.GrantID, record.Scopes)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (s *Service) issueToken(tx *gorm.DB, appID, userID, grantID uint, scopes []string) (*TokenResponse, error) {
+	access, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	access = accessTokenPrefix + access
+	refresh = refreshTokenPrefix + refresh
+
+	now := s.now()
+	if err := tx.Create(&database.OAuthToken{
+		AppID:            appID,
+		UserID:           userID,
+		GrantID:          grantID,
+		AccessTokenHash:  hashToken(access),
+		RefreshTokenHash: hashToken(refresh),
+		Scopes:           scopes,
+		ExpiresAt:        now.Add(accessTokenTTL),
+		RefreshExpiresAt: now.Add(refreshTokenTTL),
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to issue token: %w", err)
+	}
+
+	return &TokenResponse{
+		AccessToken:  access,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(accessTokenTTL.Seconds()),
+		RefreshToken: refresh,
+		Scope:        strings.Join(scopes, " "),
+	}, nil
+}
+
+// verifyCodeChallenge checks the PKCE verifier when the code was requested
+// with a challenge
+func verifyCodeChallenge(challenge, method, verifier string) error {
+	if challenge == "" {
+		return nil
+	}
+
+	var computed string
+	switch method {
+	case "plain":
+		computed = verifier
+	case "", "S256":
+		sum := sha256.Sum256([]byte(verifier))
+		computed = base64.RawURLEncoding.EncodeToString(sum[:])
+	default:
+		return ErrUnsupportedChallenge
+	}
+
+	if subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) != 1 {
+		return ErrInvalidGrant
+	}
+	return nil
+}
+
+func validateApp(req AppRequest) ([]string, []string, error) {
+	scopes, ok := normalizeScopes(req.Scopes)
+	if !ok || len(scopes) == 0 {
+		return nil, nil, ErrInvalidScope
+	}
+
+	if len(req.RedirectURIs) == 0 {
+		return nil, nil, ErrRedirectURIsRequired
+	}
+	for _, uri := range req.RedirectURIs {
+		parsed, err := url.Parse(uri)
+		if err != nil || parsed.Host == "" || parsed.Fragment != "" ||
+			(parsed.Scheme != "https" && !(parsed.Scheme == "http" && isLoopback(parsed.Hostname()))) {
+			return nil, nil, ErrInvalidRedirectURI
+		}
+	}
+
+	return scopes, req.RedirectURIs, nil
+}
+
+// isLoopback allows plain HTTP redirects for local development only
+func isLoopback(host string) bool {
+	return host == "localhost" || host == "127.0.0.1" || host == "::1"
+}
+
+func randomToken(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
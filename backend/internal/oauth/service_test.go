@@ -0,0 +1,312 @@
+package oauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/internal/automation"
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+const (
+	developerID uint = 1
+	testUserID  uint = 2
+	redirectURI      = "https://app.example.com/callback"
+)
+
+type memoryCounter map[string]int64
+
+func (m memoryCounter) IncrementWithExpiration(ctx context.Context, key string, expiration time.Duration) (int64, error) {
+	m[key]++
+	return m[key], nil
+}
+
+func setupTestService(t *testing.T) *Service {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(
+		&database.User{},
+		&database.OAuthApp{},
+		&database.OAuthGrant{},
+		&database.OAuthCode{},
+		&database.OAuthToken{},
+		&automation.WebhookEndpoint{},
+	))
+	return NewService(db, memoryCounter{})
+}
+
+func registerApp(t *testing.T, service *Service, scopes ...string) *AppCredentials {
+	creds, err := service.RegisterApp(developerID, AppRequest{
+		Name:         "Reader",
+		RedirectURIs: []string{redirectURI},
+		Scopes:       scopes,
+	})
+	require.NoError(t, err)
+	return creds
+}
+
+// authorize approves the app for the test user and returns the issued code
+func authorize(t *testing.T, service *Service, clientID, scope string) string {
+	redirect, err := service.Authorize(testUserID, AuthorizeRequest{
+		ClientID:    clientID,
+		RedirectURI: redirectURI,
+		Scope:       scope,
+		State:       "xyz",
+		Approve:     true,
+	})
+	require.NoError(t, err)
+
+	parsed, err := url.Parse(redirect)
+	require.NoError(t, err)
+	assert.Equal(t, "xyz", parsed.Query().Get("state"))
+	return parsed.Query().Get("code")
+}
+
+func TestParseScopes(t *testing.T) {
+	scopes, ok := ParseScopes("bookmarks:read profile:read bookmarks:read")
+	assert.True(t, ok)
+	assert.Equal(t, []string{ScopeBookmarksRead, ScopeProfileRead}, scopes)
+
+	_, ok = ParseScopes("bookmarks:read admin")
+	assert.False(t, ok)
+
+	assert.True(t, hasScopes([]string{ScopeBookmarksWrite}, ScopeBookmarksRead))
+	assert.False(t, hasScopes([]string{ScopeBookmarksRead}, ScopeBookmarksWrite))
+}
+
+func TestRegisterAppValidation(t *testing.T) {
+	service := setupTestService(t)
+
+	_, err := service.RegisterApp(developerID, AppRequest{Name: "x", RedirectURIs: []string{"http://evil.example.com/cb"}, Scopes: []string{ScopeProfileRead}})
+	assert.ErrorIs(t, err, ErrInvalidRedirectURI)
+
+	_, err = service.RegisterApp(developerID, AppRequest{Name: "x", RedirectURIs: []string{"http://localhost:8080/cb"}, Scopes: []string{"admin"}})
+	assert.ErrorIs(t, err, ErrInvalidScope)
+
+	creds := registerApp(t, service, ScopeProfileRead)
+	assert.NotEmpty(t, creds.ClientSecret)
+	assert.NotEqual(t, creds.ClientSecret, creds.App.ClientSecretHash)
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	service := setupTestService(t)
+	creds := registerApp(t, service, ScopeProfileRead, ScopeBookmarksRead)
+
+	// Apps cannot ask for more than they registered for
+	_, err := service.GetConsent(testUserID, AuthorizeRequest{ClientID: creds.App.ClientID, RedirectURI: redirectURI, Scope: ScopeBookmarksWrite})
+	assert.ErrorIs(t, err, ErrInvalidScope)
+	_, err = service.GetConsent(testUserID, AuthorizeRequest{ClientID: creds.App.ClientID, RedirectURI: "https://other.example.com", Scope: ScopeProfileRead})
+	assert.ErrorIs(t, err, ErrInvalidRedirectURI)
+
+	consent, err := service.GetConsent(testUserID, AuthorizeRequest{ClientID: creds.App.ClientID, RedirectURI: redirectURI, Scope: ScopeProfileRead})
+	require.NoError(t, err)
+	assert.False(t, consent.AlreadyGranted)
+	require.Len(t, consent.Scopes, 1)
+
+	code := authorize(t, service, creds.App.ClientID, ScopeProfileRead)
+	require.NotEmpty(t, code)
+
+	_, err = service.Exchange(TokenRequest{GrantType: "authorization_code", ClientID: creds.App.ClientID, ClientSecret: "wrong", Code: code, RedirectURI: redirectURI})
+	assert.ErrorIs(t, err, ErrInvalidClient)
+
+	token, err := service.Exchange(TokenRequest{GrantType: "authorization_code", ClientID: creds.App.ClientID, ClientSecret: creds.ClientSecret, Code: code, RedirectURI: redirectURI})
+	require.NoError(t, err)
+	assert.Equal(t, ScopeProfileRead, token.Scope)
+
+	// Codes are single use
+	_, err = service.Exchange(TokenRequest{GrantType: "authorization_code", ClientID: creds.App.ClientID, ClientSecret: creds.ClientSecret, Code: code, RedirectURI: redirectURI})
+	assert.ErrorIs(t, err, ErrInvalidGrant)
+
+	info, err := service.Authenticate(token.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, testUserID, info.UserID)
+	assert.Equal(t, []string{ScopeProfileRead}, info.Scopes)
+
+	consent, err = service.GetConsent(testUserID, AuthorizeRequest{ClientID: creds.App.ClientID, RedirectURI: redirectURI, Scope: ScopeProfileRead})
+	require.NoError(t, err)
+	assert.True(t, consent.AlreadyGranted)
+}
+
+func TestAuthorizeDenied(t *testing.T) {
+	service := setupTestService(t)
+	creds := registerApp(t, service, ScopeProfileRead)
+
+	redirect, err := service.Authorize(testUserID, AuthorizeRequest{ClientID: creds.App.ClientID, RedirectURI: redirectURI, Scope: ScopeProfileRead})
+	require.NoError(t, err)
+
+	parsed, err := url.Parse(redirect)
+	require.NoError(t, err)
+	assert.Equal(t, "access_denied", parsed.Query().Get("error"))
+	assert.Empty(t, parsed.Query().Get("code"))
+}
+
+func TestPKCE(t *testing.T) {
+	service := setupTestService(t)
+	creds := registerApp(t, service, ScopeProfileRead)
+
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	sum := sha256.Sum256([]byte(verifier))
+	redirect, err := service.Authorize(testUserID, AuthorizeRequest{
+		ClientID:            creds.App.ClientID,
+		RedirectURI:         redirectURI,
+		Scope:               ScopeProfileRead,
+		CodeChallenge:       base64.RawURLEncoding.EncodeToString(sum[:]),
+		CodeChallengeMethod: "S256",
+		Approve:             true,
+	})
+	require.NoError(t, err)
+	parsed, _ := url.Parse(redirect)
+	code := parsed.Query().Get("code")
+
+	req := TokenRequest{GrantType: "authorization_code", ClientID: creds.App.ClientID, ClientSecret: creds.ClientSecret, Code: code, RedirectURI: redirectURI, CodeVerifier: "wrong"}
+	_, err = service.Exchange(req)
+	assert.ErrorIs(t, err, ErrInvalidGrant)
+
+	req.CodeVerifier = verifier
+	_, err = service.Exchange(req)
+	assert.NoError(t, err)
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	service := setupTestService(t)
+	creds := registerApp(t, service, ScopeProfileRead)
+	code := authorize(t, service, creds.App.ClientID, ScopeProfileRead)
+
+	first, err := service.Exchange(TokenRequest{GrantType: "authorization_code", ClientID: creds.App.ClientID, ClientSecret: creds.ClientSecret, Code: code, RedirectURI: redirectURI})
+	require.NoError(t, err)
+
+	second, err := service.Exchange(TokenRequest{GrantType: "refresh_token", ClientID: creds.App.ClientID, ClientSecret: creds.ClientSecret, RefreshToken: first.RefreshToken})
+	require.NoError(t, err)
+	assert.NotEqual(t, first.AccessToken, second.AccessToken)
+
+	// The old pair is revoked by the rotation
+	_, err = service.Authenticate(first.AccessToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	_, err = service.Exchange(TokenRequest{GrantType: "refresh_token", ClientID: creds.App.ClientID, ClientSecret: creds.ClientSecret, RefreshToken: first.RefreshToken})
+	assert.ErrorIs(t, err, ErrInvalidGrant)
+
+	_, err = service.Authenticate(second.AccessToken)
+	assert.NoError(t, err)
+
+	require.NoError(t, service.RevokeToken(creds.App.ClientID, creds.ClientSecret, second.AccessToken))
+	_, err = service.Authenticate(second.AccessToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestRevokeGrantRemovesSubscriptions(t *testing.T) {
+	service := setupTestService(t)
+	creds := registerApp(t, service, ScopeBookmarksRead, ScopeWebhooks)
+	code := authorize(t, service, creds.App.ClientID, "bookmarks:read webhooks")
+
+	token, err := service.Exchange(TokenRequest{GrantType: "authorization_code", ClientID: creds.App.ClientID, ClientSecret: creds.ClientSecret, Code: code, RedirectURI: redirectURI})
+	require.NoError(t, err)
+	info, err := service.Authenticate(token.AccessToken)
+	require.NoError(t, err)
+
+	_, err = service.CreateSubscription(info, SubscriptionRequest{URL: "https://app.example.com/hooks", Events: []string{"user.login"}})
+	assert.ErrorIs(t, err, ErrInvalidEvent)
+	_, err = service.CreateSubscription(info, SubscriptionRequest{URL: "https://app.example.com/hooks", Events: []string{"bookmark.created", "collection.created"}})
+	assert.ErrorIs(t, err, ErrInsufficientScope, "collection events need collections:read")
+
+	endpoint, err := service.CreateSubscription(info, SubscriptionRequest{URL: "https://app.example.com/hooks", Events: []string{"bookmark.created"}})
+	require.NoError(t, err)
+	assert.Equal(t, "2", endpoint.UserID)
+	assert.Equal(t, creds.WebhookSecret, endpoint.Secret)
+
+	grants, err := service.ListGrants(testUserID)
+	require.NoError(t, err)
+	require.Len(t, grants, 1)
+	assert.Equal(t, "Reader", grants[0].App.Name)
+
+	require.NoError(t, service.RevokeGrant(testUserID, grants[0].ID))
+
+	_, err = service.Authenticate(token.AccessToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	subscriptions, err := service.ListSubscriptions(info)
+	require.NoError(t, err)
+	assert.Empty(t, subscriptions)
+
+	// Refresh tokens of a revoked grant are dead too
+	_, err = service.Exchange(TokenRequest{GrantType: "refresh_token", ClientID: creds.App.ClientID, ClientSecret: creds.ClientSecret, RefreshToken: token.RefreshToken})
+	assert.ErrorIs(t, err, ErrInvalidGrant)
+}
+
+func TestSubscriptionRequiresWebhooksScope(t *testing.T) {
+	service := setupTestService(t)
+
+	_, err := service.CreateSubscription(&TokenInfo{AppID: 1, UserID: testUserID, Scopes: []string{ScopeBookmarksRead}},
+		SubscriptionRequest{URL: "https://app.example.com/hooks", Events: []string{"bookmark.created"}})
+	assert.ErrorIs(t, err, ErrInsufficientScope)
+}
+
+func TestSubscriptionRequiresReadScopeOfEvents(t *testing.T) {
+	service := setupTestService(t)
+	subscribe := func(scopes []string, event string) error {
+		_, err := service.CreateSubscription(&TokenInfo{AppID: 1, UserID: testUserID, Scopes: scopes},
+			SubscriptionRequest{URL: "https://app.example.com/hooks", Events: []string{event}})
+		return err
+	}
+
+	assert.ErrorIs(t, subscribe([]string{ScopeWebhooks}, "bookmark.deleted"), ErrInsufficientScope)
+	assert.ErrorIs(t, subscribe([]string{ScopeWebhooks, ScopeCollectionsRead}, "bookmark.updated"), ErrInsufficientScope)
+	assert.ErrorIs(t, subscribe([]string{ScopeWebhooks, ScopeBookmarksRead}, "collection.updated"), ErrInsufficientScope)
+	// Subscribing needs the app to exist once the scopes check out
+	assert.ErrorIs(t, subscribe([]string{ScopeWebhooks, ScopeCollectionsWrite}, "collection.deleted"), ErrAppNotFound)
+}
+
+func TestCheckRateLimit(t *testing.T) {
+	service := setupTestService(t)
+	info := &TokenInfo{AppID: 1, RateLimit: 2}
+
+	remaining, err := service.CheckRateLimit(context.Background(), info)
+	require.NoError(t, err)
+	assert.Equal(t, 1, remaining)
+	_, err = service.CheckRateLimit(context.Background(), info)
+	require.NoError(t, err)
+	_, err = service.CheckRateLimit(context.Background(), info)
+	assert.ErrorIs(t, err, ErrRateLimited)
+
+	// The budget is per app
+	_, err = service.CheckRateLimit(context.Background(), &TokenInfo{AppID: 2, RateLimit: 2})
+	assert.NoError(t, err)
+}
+
+func TestAppResourceRoutesCheckScopes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service := setupTestService(t)
+	creds := registerApp(t, service, ScopeBookmarksWrite)
+	code := authorize(t, service, creds.App.ClientID, "bookmarks:read")
+	token, err := service.Exchange(TokenRequest{GrantType: "authorization_code", ClientID: creds.App.ClientID, ClientSecret: creds.ClientSecret, Code: code, RedirectURI: redirectURI})
+	require.NoError(t, err)
+
+	ok := func(c *gin.Context) { c.String(http.StatusOK, c.GetString("user_id")) }
+	router := gin.New()
+	NewHandler(service).RegisterAppResource(router.Group("/api/v1"), "bookmarks", ScopeBookmarksRead, ScopeBookmarksWrite,
+		AppResourceHandlers{List: ok, Get: ok, Create: ok, Update: ok, Delete: ok})
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/v1/app/bookmarks"+path, nil)
+		req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve(http.MethodGet, "/7")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Body.String(), "handlers act for the user who granted access")
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "").Code)
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodPost, "").Code)
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodDelete, "/7").Code)
+}
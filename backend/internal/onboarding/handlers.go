@@ -0,0 +1,177 @@
+package onboarding
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"bookmark-sync-service/backend/pkg/utils"
+)
+
+// Handler serves onboarding progress and the admin checklist
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new onboarding handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes registers the onboarding routes of users
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	onboarding := router.Group("/onboarding")
+	onboarding.GET("", h.GetProgress)
+	onboarding.POST("/steps/:key/complete", h.CompleteStep)
+}
+
+// RegisterAdminRoutes registers the checklist management routes. The
+// router must already require an admin
+func (h *Handler) RegisterAdminRoutes(router *gin.RouterGroup) {
+	steps := router.Group("/onboarding/steps")
+	steps.GET("", h.ListSteps)
+	steps.POST("", h.CreateStep)
+	steps.PUT("/:key", h.UpdateStep)
+	steps.DELETE("/:key", h.DeleteStep)
+}
+
+// GetProgress returns the user's onboarding checklist. The web UI polls it
+// until every step is done
+// @Summary Get onboarding progress
+// @Tags onboarding
+// @Produce json
+// @Success 200 {object} Progress
+// @Router /onboarding [get]
+func (h *Handler) GetProgress(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	progress, err := h.service.Progress(c.Request.Context(), userID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to get onboarding progress", nil)
+		return
+	}
+	utils.SuccessResponse(c, progress, "Onboarding progress retrieved")
+}
+
+// CompleteStep checks off a step the server cannot observe, e.g. installing
+// the extension
+// @Summary Complete an onboarding step
+// @Tags onboarding
+// @Produce json
+// @Param key path string true "Step key"
+// @Success 200 {object} Progress
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /onboarding/steps/{key}/complete [post]
+func (h *Handler) CompleteStep(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	progress, err := h.service.Complete(c.Request.Context(), userID, c.Param("key"))
+	if err != nil {
+		h.stepError(c, err, "Failed to complete onboarding step")
+		return
+	}
+	utils.SuccessResponse(c, progress, "Onboarding step completed")
+}
+
+// ListSteps returns every checklist step, disabled ones included
+// @Summary List onboarding steps
+// @Tags admin
+// @Produce json
+// @Success 200 {array} database.OnboardingStep
+// @Router /admin/onboarding/steps [get]
+func (h *Handler) ListSteps(c *gin.Context) {
+	steps, err := h.service.Steps(c.Request.Context())
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to list onboarding steps", nil)
+		return
+	}
+	utils.SuccessResponse(c, steps, "Onboarding steps retrieved")
+}
+
+// CreateStep adds a step to the checklist
+// @Summary Create an onboarding step
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param request body StepRequest true "Step"
+// @Success 201 {object} database.OnboardingStep
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Router /admin/onboarding/steps [post]
+func (h *Handler) CreateStep(c *gin.Context) {
+	var req StepRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request format", nil)
+		return
+	}
+
+	step, err := h.service.CreateStep(c.Request.Context(), req)
+	if err != nil {
+		h.stepError(c, err, "Failed to create onboarding step")
+		return
+	}
+	c.JSON(http.StatusCreated, utils.APIResponse{Success: true, Data: step, Message: "Onboarding step created"})
+}
+
+// UpdateStep changes a checklist step
+// @Summary Update an onboarding step
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param key path string true "Step key"
+// @Param request body StepRequest true "Fields to change"
+// @Success 200 {object} database.OnboardingStep
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /admin/onboarding/steps/{key} [put]
+func (h *Handler) UpdateStep(c *gin.Context) {
+	var req StepRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request format", nil)
+		return
+	}
+
+	step, err := h.service.UpdateStep(c.Request.Context(), c.Param("key"), req)
+	if err != nil {
+		h.stepError(c, err, "Failed to update onboarding step")
+		return
+	}
+	utils.SuccessResponse(c, step, "Onboarding step updated")
+}
+
+// DeleteStep removes a step from the checklist
+// @Summary Delete an onboarding step
+// @Tags admin
+// @Produce json
+// @Param key path string true "Step key"
+// @Success 200 {object} utils.APIResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /admin/onboarding/steps/{key} [delete]
+func (h *Handler) DeleteStep(c *gin.Context) {
+	if err := h.service.DeleteStep(c.Request.Context(), c.Param("key")); err != nil {
+		h.stepError(c, err, "Failed to delete onboarding step")
+		return
+	}
+	utils.SuccessResponse(c, nil, "Onboarding step deleted")
+}
+
+func (h *Handler) stepError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, ErrStepNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, "STEP_NOT_FOUND", "Onboarding step not found", nil)
+	case errors.Is(err, ErrStepExists):
+		utils.ErrorResponse(c, http.StatusConflict, "STEP_EXISTS", err.Error(), nil)
+	case errors.Is(err, ErrInvalidStep):
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_STEP", err.Error(), nil)
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", message, nil)
+	}
+}
@@ -0,0 +1,397 @@
+package onboarding
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+// Events that complete onboarding steps, reported by the services where
+// the user does them
+const (
+	EventBookmarkCreated    = "bookmark_created"
+	EventCollectionCreated  = "collection_created"
+	EventThemeSet           = "theme_set"
+	EventExtensionInstalled = "extension_installed"
+)
+
+// NotificationStepCompleted is the type of the notification sent when a
+// user completes a step
+const NotificationStepCompleted = "onboarding_step_completed"
+
+var (
+	// ErrStepNotFound is returned for unknown or disabled step keys
+	ErrStepNotFound = errors.New("onboarding step not found")
+	// ErrStepExists is returned when creating a step with a taken key
+	ErrStepExists = errors.New("onboarding step already exists")
+	// ErrInvalidStep is returned for steps without a valid key or title
+	ErrInvalidStep = errors.New("onboarding step needs a key of lowercase letters, digits and underscores, and a title")
+)
+
+var stepKeyPattern = regexp.MustCompile(`^[a-z0-9_]{1,50}$`)
+
+// sampleBookmarks are seeded for new users when sample content is enabled
+var sampleBookmarks = []database.Bookmark{
+	{URL: "https://www.mozilla.org/firefox/", Title: "Firefox", Description: "A browser the extension supports", Tags: `["browsers","sample"]`},
+	{URL: "https://go.dev/doc/", Title: "Go documentation", Description: "Tutorials, references and articles about Go", Tags: `["programming","sample"]`},
+	{URL: "https://en.wikipedia.org/wiki/Bookmark_(digital)", Title: "Bookmark (digital)", Description: "What bookmarks are and where they came from", Tags: `["reading","sample"]`},
+}
+
+// sampleMetadata marks seeded content so clients can offer to remove it
+const sampleMetadata = `{"sample":true}`
+
+// defaultSteps is the checklist of a new instance, until an admin changes it
+var defaultSteps = []database.OnboardingStep{
+	{Key: "install_extension", Title: "Install the browser extension", Description: "Save pages and sync bookmarks straight from your browser.", Event: EventExtensionInstalled, Position: 1},
+	{Key: "save_first_bookmark", Title: "Save your first bookmark", Event: EventBookmarkCreated, Position: 2},
+	{Key: "create_collection", Title: "Create a collection", Description: "Group related bookmarks and share them.", Event: EventCollectionCreated, Position: 3},
+	{Key: "set_theme", Title: "Pick a theme", Event: EventThemeSet, Position: 4},
+}
+
+// NotificationPublisher publishes a notification on a user's channel
+type NotificationPublisher interface {
+	PublishNotification(ctx context.Context, userID string, notification interface{}) error
+}
+
+// StepCompletedNotification tells the user's clients a step was completed
+type StepCompletedNotification struct {
+	Type      string `json:"type"`
+	Step      string `json:"step"`
+	Title     string `json:"title"`
+	Completed int    `json:"completed"`
+	Total     int    `json:"total"`
+	Done      bool   `json:"done"`
+}
+
+// StepProgress is a checklist step and whether the user completed it
+type StepProgress struct {
+	Key         string     `json:"key"`
+	Title       string     `json:"title"`
+	Description string     `json:"description,omitempty"`
+	Completed   bool       `json:"completed"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// Progress is a user's way through the onboarding checklist
+type Progress struct {
+	Steps     []StepProgress `json:"steps"`
+	Completed int            `json:"completed"`
+	Total     int            `json:"total"`
+	Done      bool           `json:"done"`
+}
+
+// StepRequest creates or updates a checklist step. On update, only the
+// fields that are set change
+type StepRequest struct {
+	Key         string  `json:"key"`
+	Title       string  `json:"title"`
+	Description *string `json:"description"`
+	Event       *string `json:"event"`
+	Position    *int    `json:"position"`
+	Enabled     *bool   `json:"enabled"`
+}
+
+// Service tracks users through the onboarding checklist
+type Service struct {
+	cfg           config.OnboardingConfig
+	db            *gorm.DB
+	notifications NotificationPublisher
+	logger        *zap.Logger
+}
+
+// NewService creates a new onboarding service
+func NewService(cfg config.OnboardingConfig, db *gorm.DB, logger *zap.Logger) *Service {
+	return &Service{cfg: cfg, db: db, logger: logger}
+}
+
+// SetNotifier makes the service notify users as they complete steps
+func (s *Service) SetNotifier(notifications NotificationPublisher) {
+	s.notifications = notifications
+}
+
+// SeedDefaultSteps creates the default checklist on an instance that never
+// had one. Steps an admin deleted are not brought back
+func (s *Service) SeedDefaultSteps(ctx context.Context) error {
+	var count int64
+	if err := s.db.WithContext(ctx).Unscoped().Model(&database.OnboardingStep{}).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to count onboarding steps: %w", err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	steps := make([]database.OnboardingStep, len(defaultSteps))
+	copy(steps, defaultSteps)
+	for i := range steps {
+		steps[i].Enabled = true
+	}
+	if err := s.db.WithContext(ctx).Create(&steps).Error; err != nil {
+		return fmt.Errorf("failed to seed onboarding steps: %w", err)
+	}
+	return nil
+}
+
+// UserRegistered seeds sample bookmarks in a sample collection for a new
+// account, when enabled. Failures are logged so they never fail a signup
+func (s *Service) UserRegistered(ctx context.Context, userID uint) {
+	if !s.cfg.SampleContent {
+		return
+	}
+	if err := s.SeedSampleContent(ctx, userID); err != nil {
+		s.logger.Warn("Failed to seed sample content", zap.Uint("user_id", userID), zap.Error(err))
+	}
+}
+
+// SeedSampleContent creates the sample bookmarks and their collection, also
+// for demo sandboxes whatever the onboarding setting
+func (s *Service) SeedSampleContent(ctx context.Context, userID uint) error {
+	shareLink := make([]byte, 16)
+	if _, err := rand.Read(shareLink); err != nil {
+		return fmt.Errorf("failed to generate share link: %w", err)
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		collection := database.Collection{
+			UserID:      userID,
+			Name:        "Getting started",
+			Description: "Sample bookmarks to try things out. Delete them whenever you like.",
+			Visibility:  "private",
+			ShareLink:   hex.EncodeToString(shareLink),
+			Metadata:    sampleMetadata,
+		}
+		if err := tx.Create(&collection).Error; err != nil {
+			return fmt.Errorf("failed to create sample collection: %w", err)
+		}
+
+		bookmarks := make([]database.Bookmark, len(sampleBookmarks))
+		copy(bookmarks, sampleBookmarks)
+		for i := range bookmarks {
+			bookmarks[i].UserID = userID
+			bookmarks[i].Metadata = sampleMetadata
+		}
+		if err := tx.Create(&bookmarks).Error; err != nil {
+			return fmt.Errorf("failed to create sample bookmarks: %w", err)
+		}
+		if err := tx.Model(&collection).Association("Bookmarks").Append(&bookmarks); err != nil {
+			return fmt.Errorf("failed to add sample bookmarks to collection: %w", err)
+		}
+		return nil
+	})
+}
+
+// Steps returns every checklist step, disabled ones included, in order
+func (s *Service) Steps(ctx context.Context) ([]database.OnboardingStep, error) {
+	var steps []database.OnboardingStep
+	if err := s.db.WithContext(ctx).Order("position, id").Find(&steps).Error; err != nil {
+		return nil, fmt.Errorf("failed to list onboarding steps: %w", err)
+	}
+	return steps, nil
+}
+
+// CreateStep adds a step to the checklist, enabled unless stated otherwise
+func (s *Service) CreateStep(ctx context.Context, req StepRequest) (*database.OnboardingStep, error) {
+	step := database.OnboardingStep{Key: strings.TrimSpace(req.Key), Enabled: true}
+	applyStep(&step, req)
+	if !stepKeyPattern.MatchString(step.Key) || step.Title == "" {
+		return nil, ErrInvalidStep
+	}
+
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&database.OnboardingStep{}).Where("key = ?", step.Key).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to check onboarding step: %w", err)
+	}
+	if count > 0 {
+		return nil, ErrStepExists
+	}
+	// A deleted step with the same key would block the unique index
+	if err := s.db.WithContext(ctx).Unscoped().Where("key = ?", step.Key).Delete(&database.OnboardingStep{}).Error; err != nil {
+		return nil, fmt.Errorf("failed to replace onboarding step: %w", err)
+	}
+
+	// GORM writes the column default in place of a false Enabled, so a
+	// disabled step is created enabled and then switched off
+	enabled := step.Enabled
+	if err := s.db.WithContext(ctx).Create(&step).Error; err != nil {
+		return nil, fmt.Errorf("failed to create onboarding step: %w", err)
+	}
+	if !enabled {
+		if err := s.db.WithContext(ctx).Model(&step).Update("enabled", false).Error; err != nil {
+			return nil, fmt.Errorf("failed to disable onboarding step: %w", err)
+		}
+	}
+	return &step, nil
+}
+
+// UpdateStep changes a checklist step
+func (s *Service) UpdateStep(ctx context.Context, key string, req StepRequest) (*database.OnboardingStep, error) {
+	step, err := s.step(ctx, key, false)
+	if err != nil {
+		return nil, err
+	}
+	applyStep(step, req)
+	if step.Title == "" {
+		return nil, ErrInvalidStep
+	}
+	if err := s.db.WithContext(ctx).Save(step).Error; err != nil {
+		return nil, fmt.Errorf("failed to update onboarding step: %w", err)
+	}
+	return step, nil
+}
+
+// DeleteStep removes a step from the checklist. Users who completed it
+// keep their progress, which counts again if the step is recreated
+func (s *Service) DeleteStep(ctx context.Context, key string) error {
+	result := s.db.WithContext(ctx).Where("key = ?", key).Delete(&database.OnboardingStep{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete onboarding step: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrStepNotFound
+	}
+	return nil
+}
+
+// Progress returns the enabled steps and which ones the user completed
+func (s *Service) Progress(ctx context.Context, userID uint) (*Progress, error) {
+	var steps []database.OnboardingStep
+	if err := s.db.WithContext(ctx).Where("enabled = ?", true).Order("position, id").Find(&steps).Error; err != nil {
+		return nil, fmt.Errorf("failed to list onboarding steps: %w", err)
+	}
+	var completed []database.OnboardingProgress
+	if err := s.db.WithContext(ctx).Where("user_id = ?", userID).Find(&completed).Error; err != nil {
+		return nil, fmt.Errorf("failed to load onboarding progress: %w", err)
+	}
+	completedAt := make(map[string]time.Time, len(completed))
+	for _, entry := range completed {
+		completedAt[entry.StepKey] = entry.CompletedAt
+	}
+
+	progress := &Progress{Steps: make([]StepProgress, 0, len(steps)), Total: len(steps)}
+	for _, step := range steps {
+		entry := StepProgress{Key: step.Key, Title: step.Title, Description: step.Description}
+		if at, ok := completedAt[step.Key]; ok {
+			entry.Completed = true
+			entry.CompletedAt = &at
+			progress.Completed++
+		}
+		progress.Steps = append(progress.Steps, entry)
+	}
+	progress.Done = progress.Completed == progress.Total
+	return progress, nil
+}
+
+// Complete checks off a step for the user, e.g. when the client saw the
+// extension connect. Completing a step twice is not an error
+func (s *Service) Complete(ctx context.Context, userID uint, key string) (*Progress, error) {
+	step, err := s.step(ctx, key, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.complete(ctx, userID, []database.OnboardingStep{*step}); err != nil {
+		return nil, err
+	}
+	return s.Progress(ctx, userID)
+}
+
+// Track completes the enabled steps waiting for an event. Failures are
+// logged, never returned, so they cannot fail the action that triggered them
+func (s *Service) Track(ctx context.Context, userID uint, event string) {
+	var steps []database.OnboardingStep
+	err := s.db.WithContext(ctx).
+		Where("event = ? AND enabled = ?", event, true).
+		Where("key NOT IN (?)", s.db.Model(&database.OnboardingProgress{}).Select("step_key").Where("user_id = ?", userID)).
+		Find(&steps).Error
+	if err == nil {
+		err = s.complete(ctx, userID, steps)
+	}
+	if err != nil {
+		s.logger.Warn("Failed to track onboarding event",
+			zap.Uint("user_id", userID), zap.String("event", event), zap.Error(err))
+	}
+}
+
+// complete records steps as completed and notifies the user of each one
+// that was not already
+func (s *Service) complete(ctx context.Context, userID uint, steps []database.OnboardingStep) error {
+	var newlyCompleted []database.OnboardingStep
+	for _, step := range steps {
+		entry := database.OnboardingProgress{UserID: userID, StepKey: step.Key, CompletedAt: time.Now()}
+		result := s.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&entry)
+		if result.Error != nil {
+			return fmt.Errorf("failed to record onboarding progress: %w", result.Error)
+		}
+		if result.RowsAffected > 0 {
+			newlyCompleted = append(newlyCompleted, step)
+		}
+	}
+	if len(newlyCompleted) == 0 || s.notifications == nil {
+		return nil
+	}
+
+	progress, err := s.Progress(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, step := range newlyCompleted {
+		notification := &StepCompletedNotification{
+			Type:      NotificationStepCompleted,
+			Step:      step.Key,
+			Title:     step.Title,
+			Completed: progress.Completed,
+			Total:     progress.Total,
+			Done:      progress.Done,
+		}
+		if err := s.notifications.PublishNotification(ctx, strconv.FormatUint(uint64(userID), 10), notification); err != nil {
+			s.logger.Warn("Failed to publish onboarding notification", zap.Uint("user_id", userID), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// step loads a step by key, optionally only when enabled
+func (s *Service) step(ctx context.Context, key string, enabledOnly bool) (*database.OnboardingStep, error) {
+	query := s.db.WithContext(ctx).Where("key = ?", key)
+	if enabledOnly {
+		query = query.Where("enabled = ?", true)
+	}
+	var step database.OnboardingStep
+	if err := query.First(&step).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrStepNotFound
+		}
+		return nil, fmt.Errorf("failed to get onboarding step: %w", err)
+	}
+	return &step, nil
+}
+
+// applyStep copies the set fields of a request onto a step
+func applyStep(step *database.OnboardingStep, req StepRequest) {
+	if title := strings.TrimSpace(req.Title); title != "" {
+		step.Title = title
+	}
+	if req.Description != nil {
+		step.Description = strings.TrimSpace(*req.Description)
+	}
+	if req.Event != nil {
+		step.Event = strings.TrimSpace(*req.Event)
+	}
+	if req.Position != nil {
+		step.Position = *req.Position
+	}
+	if req.Enabled != nil {
+		step.Enabled = *req.Enabled
+	}
+}
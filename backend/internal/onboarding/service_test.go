@@ -0,0 +1,135 @@
+package onboarding
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+type recordingNotifier struct {
+	notifications []*StepCompletedNotification
+}
+
+func (n *recordingNotifier) PublishNotification(ctx context.Context, userID string, notification interface{}) error {
+	n.notifications = append(n.notifications, notification.(*StepCompletedNotification))
+	return nil
+}
+
+func TestOnboarding(t *testing.T) {
+	db, err := database.SetupTestDB()
+	require.NoError(t, err)
+	t.Cleanup(func() { database.CleanupTestDB(db) })
+
+	user := database.User{Email: "new@example.com", Username: "new", SupabaseID: "new"}
+	require.NoError(t, db.Create(&user).Error)
+
+	service := NewService(config.OnboardingConfig{}, db, zap.NewNop())
+	notifier := &recordingNotifier{}
+	service.SetNotifier(notifier)
+	ctx := context.Background()
+
+	require.NoError(t, service.SeedDefaultSteps(ctx))
+	require.NoError(t, service.SeedDefaultSteps(ctx))
+	progress, err := service.Progress(ctx, user.ID)
+	require.NoError(t, err)
+	require.Equal(t, 4, progress.Total)
+	assert.Equal(t, "install_extension", progress.Steps[0].Key)
+	assert.Zero(t, progress.Completed)
+	assert.False(t, progress.Done)
+
+	// Events complete their steps once
+	service.Track(ctx, user.ID, EventBookmarkCreated)
+	service.Track(ctx, user.ID, EventBookmarkCreated)
+	service.Track(ctx, user.ID, "unknown_event")
+	require.Len(t, notifier.notifications, 1)
+	assert.Equal(t, StepCompletedNotification{
+		Type: NotificationStepCompleted, Step: "save_first_bookmark", Title: "Save your first bookmark", Completed: 1, Total: 4,
+	}, *notifier.notifications[0])
+
+	// Steps the server cannot observe are completed by the client
+	progress, err = service.Complete(ctx, user.ID, "install_extension")
+	require.NoError(t, err)
+	assert.Equal(t, 2, progress.Completed)
+	assert.True(t, progress.Steps[0].Completed)
+	assert.NotNil(t, progress.Steps[0].CompletedAt)
+	_, err = service.Complete(ctx, user.ID, "missing")
+	assert.ErrorIs(t, err, ErrStepNotFound)
+
+	// Admins shape the checklist
+	disabled := false
+	_, err = service.CreateStep(ctx, StepRequest{Key: "invite_friend", Title: "Invite a friend", Enabled: &disabled})
+	require.NoError(t, err)
+	_, err = service.CreateStep(ctx, StepRequest{Key: "invite_friend", Title: "Again"})
+	assert.ErrorIs(t, err, ErrStepExists)
+	_, err = service.CreateStep(ctx, StepRequest{Key: "Bad Key", Title: "Bad"})
+	assert.ErrorIs(t, err, ErrInvalidStep)
+	steps, err := service.Steps(ctx)
+	require.NoError(t, err)
+	assert.Len(t, steps, 5)
+
+	_, err = service.Complete(ctx, user.ID, "invite_friend")
+	assert.ErrorIs(t, err, ErrStepNotFound, "disabled steps cannot be completed")
+
+	require.NoError(t, service.DeleteStep(ctx, "set_theme"))
+	assert.ErrorIs(t, service.DeleteStep(ctx, "set_theme"), ErrStepNotFound)
+	position := 0
+	step, err := service.UpdateStep(ctx, "create_collection", StepRequest{Title: "Make a collection", Position: &position})
+	require.NoError(t, err)
+	assert.Equal(t, "Make a collection", step.Title)
+
+	service.Track(ctx, user.ID, EventCollectionCreated)
+	progress, err = service.Progress(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "create_collection", progress.Steps[0].Key)
+	assert.Equal(t, 3, progress.Total)
+	assert.True(t, progress.Done)
+	assert.True(t, notifier.notifications[len(notifier.notifications)-1].Done)
+
+	// A deleted default step is not seeded again
+	require.NoError(t, service.SeedDefaultSteps(ctx))
+	steps, err = service.Steps(ctx)
+	require.NoError(t, err)
+	assert.Len(t, steps, 4)
+}
+
+func TestUserRegisteredSeedsSampleContent(t *testing.T) {
+	db, err := database.SetupTestDB()
+	require.NoError(t, err)
+	t.Cleanup(func() { database.CleanupTestDB(db) })
+
+	first := database.User{Email: "first@example.com", Username: "first", SupabaseID: "first"}
+	second := database.User{Email: "second@example.com", Username: "second", SupabaseID: "second"}
+	require.NoError(t, db.Create(&first).Error)
+	require.NoError(t, db.Create(&second).Error)
+	ctx := context.Background()
+
+	NewService(config.OnboardingConfig{}, db, zap.NewNop()).UserRegistered(ctx, first.ID)
+	var count int64
+	require.NoError(t, db.Model(&database.Bookmark{}).Where("user_id = ?", first.ID).Count(&count).Error)
+	assert.Zero(t, count)
+
+	service := NewService(config.OnboardingConfig{SampleContent: true}, db, zap.NewNop())
+	service.UserRegistered(ctx, first.ID)
+	service.UserRegistered(ctx, second.ID)
+
+	var collection database.Collection
+	require.NoError(t, db.Preload("Bookmarks").Where("user_id = ?", second.ID).First(&collection).Error)
+	assert.Equal(t, "private", collection.Visibility)
+	assert.JSONEq(t, sampleMetadata, collection.Metadata)
+	require.Len(t, collection.Bookmarks, len(sampleBookmarks))
+	for _, bookmark := range collection.Bookmarks {
+		assert.Equal(t, second.ID, bookmark.UserID)
+		assert.JSONEq(t, sampleMetadata, bookmark.Metadata)
+	}
+
+	// Seeded content does not complete onboarding steps
+	var progress int64
+	require.NoError(t, db.Model(&database.OnboardingProgress{}).Count(&progress).Error)
+	assert.Zero(t, progress)
+}
@@ -0,0 +1,223 @@
+package quality
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"bookmark-sync-service/backend/pkg/middleware"
+	"bookmark-sync-service/backend/pkg/utils"
+)
+
+// Handler serves bookmark quality scores to owners and admins
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new quality handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes registers the owner routes on an authenticated group
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/bookmarks/:id/quality", h.GetQuality)
+	router.POST("/bookmarks/:id/quality/appeal", h.Appeal)
+}
+
+// RegisterAdminRoutes registers the threshold and review routes on an
+// admin-only group
+func (h *Handler) RegisterAdminRoutes(router *gin.RouterGroup) {
+	router.GET("/quality/thresholds", h.GetThresholds)
+	router.PUT("/quality/thresholds", h.UpdateThresholds)
+	router.GET("/quality/reviews", h.ListReviews)
+	router.PUT("/quality/reviews/:bookmark_id", h.Review)
+}
+
+// AppealRequest asks for a suppressed bookmark to be reviewed
+type AppealRequest struct {
+	Reason string `json:"reason" binding:"max=1000"`
+}
+
+// ThresholdsRequest sets the score thresholds
+type ThresholdsRequest struct {
+	SuppressBelow *int `json:"suppress_below" binding:"required"`
+	ReviewBelow   *int `json:"review_below" binding:"required"`
+}
+
+// ReviewRequest decides a review
+type ReviewRequest struct {
+	Decision string `json:"decision" binding:"required"` // approve or reject
+}
+
+// GetQuality returns the quality score of one of the user's bookmarks
+// @Summary Get bookmark quality score
+// @Description The spam score of a bookmark in a public collection, from 0 to 100, the signals that lowered it and whether it is left out of trending, discovery and recommendations
+// @Tags bookmarks
+// @Produce json
+// @Param id path int true "Bookmark ID"
+// @Success 200 {object} database.BookmarkQuality
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /bookmarks/{id}/quality [get]
+func (h *Handler) GetQuality(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+	bookmarkID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_ID", "Invalid bookmark ID", nil)
+		return
+	}
+
+	quality, err := h.service.GetQuality(c.Request.Context(), userID, uint(bookmarkID))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, quality, "Quality score retrieved")
+}
+
+// Appeal asks admins to review a suppressed bookmark
+// @Summary Appeal a bookmark quality score
+// @Description Queue a suppressed bookmark for admin review; an approved bookmark is shown whatever its score
+// @Tags bookmarks
+// @Accept json
+// @Produce json
+// @Param id path int true "Bookmark ID"
+// @Param request body AppealRequest false "Why the bookmark should be shown"
+// @Success 200 {object} database.BookmarkQuality
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse "Not suppressed, or already reviewed"
+// @Router /bookmarks/{id}/quality/appeal [post]
+func (h *Handler) Appeal(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+	bookmarkID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_ID", "Invalid bookmark ID", nil)
+		return
+	}
+	var req AppealRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid request data", nil)
+			return
+		}
+	}
+
+	quality, err := h.service.Appeal(c.Request.Context(), userID, uint(bookmarkID), req.Reason)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, quality, "Appeal submitted")
+}
+
+// GetThresholds returns the score thresholds
+// @Summary Get quality thresholds
+// @Tags admin
+// @Produce json
+// @Success 200 {object} database.QualityThresholds
+// @Router /admin/quality/thresholds [get]
+func (h *Handler) GetThresholds(c *gin.Context) {
+	thresholds, err := h.service.Thresholds(c.Request.Context())
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, thresholds, "Quality thresholds retrieved")
+}
+
+// UpdateThresholds sets the score thresholds
+// @Summary Update quality thresholds
+// @Description Bookmarks scored below suppress_below are hidden from public areas, effective immediately; those below review_below are queued for review
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param request body ThresholdsRequest true "Thresholds from 0 to 100"
+// @Success 200 {object} database.QualityThresholds
+// @Failure 400 {object} utils.ErrorResponse
+// @Router /admin/quality/thresholds [put]
+func (h *Handler) UpdateThresholds(c *gin.Context) {
+	var req ThresholdsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid request data", nil)
+		return
+	}
+
+	thresholds, err := h.service.UpdateThresholds(c.Request.Context(), *req.SuppressBelow, *req.ReviewBelow, middleware.GetUserEmail(c))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, thresholds, "Quality thresholds updated")
+}
+
+// ListReviews lists a review queue
+// @Summary List quality reviews
+// @Tags admin
+// @Produce json
+// @Param status query string false "appealed (default), low, approved or rejected"
+// @Success 200 {array} database.BookmarkQuality
+// @Failure 400 {object} utils.ErrorResponse
+// @Router /admin/quality/reviews [get]
+func (h *Handler) ListReviews(c *gin.Context) {
+	reviews, err := h.service.ListReviews(c.Request.Context(), c.Query("status"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, reviews, "Quality reviews retrieved")
+}
+
+// Review approves or rejects a bookmark
+// @Summary Review a bookmark quality score
+// @Description Approving shows the bookmark whatever its score, rejecting hides it from public areas for good
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param bookmark_id path int true "Bookmark ID"
+// @Param request body ReviewRequest true "Decision"
+// @Success 200 {object} database.BookmarkQuality
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /admin/quality/reviews/{bookmark_id} [put]
+func (h *Handler) Review(c *gin.Context) {
+	bookmarkID, err := strconv.ParseUint(c.Param("bookmark_id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_ID", "Invalid bookmark ID", nil)
+		return
+	}
+	var req ReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid request data", nil)
+		return
+	}
+
+	quality, err := h.service.Review(c.Request.Context(), uint(bookmarkID), req.Decision, middleware.GetUserEmail(c))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, quality, "Quality review saved")
+}
+
+func (h *Handler) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrNotScored):
+		utils.ErrorResponse(c, http.StatusNotFound, "NOT_SCORED", err.Error(), nil)
+	case errors.Is(err, ErrNotAppealable):
+		utils.ErrorResponse(c, http.StatusConflict, "NOT_APPEALABLE", err.Error(), nil)
+	case errors.Is(err, ErrInvalidThresholds), errors.Is(err, ErrInvalidDecision), errors.Is(err, ErrInvalidQueue):
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error(), nil)
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to process quality request", nil)
+	}
+}
@@ -0,0 +1,429 @@
+// Package quality scores bookmarks in public collections for spam, so
+// trending, discovery and recommendations can leave low-quality ones out.
+// The worker scores new and changed bookmarks; admins set the thresholds
+// and review what owners appeal
+package quality
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+// Signals that lower a bookmark's score
+const (
+	SignalUnsafe           = "unsafe"            // flagged by URL safety checks
+	SignalDomainReputation = "domain_reputation" // most scored bookmarks on the domain are suppressed
+	SignalLinkShortener    = "link_shortener"    // hides where the link goes
+	SignalDuplicateSpread  = "duplicate_spread"  // new accounts saving the same URL at once
+	SignalBurst            = "burst_creation"    // the account saved many bookmarks at once
+)
+
+// penalties are what each signal takes off a score of 100
+var penalties = map[string]int{
+	SignalUnsafe:           100,
+	SignalDomainReputation: 35,
+	SignalLinkShortener:    30,
+	SignalDuplicateSpread:  40,
+	SignalBurst:            25,
+}
+
+// shorteners are link shortener hosts
+var shorteners = map[string]bool{
+	"bit.ly": true, "bitly.com": true, "tinyurl.com": true, "t.co": true, "goo.gl": true,
+	"ow.ly": true, "is.gd": true, "buff.ly": true, "rebrand.ly": true, "cutt.ly": true,
+	"shorturl.at": true, "tiny.cc": true, "rb.gy": true, "t.ly": true, "s.id": true,
+	"v.gd": true, "bl.ink": true, "lnkd.in": true, "shorte.st": true, "adf.ly": true,
+}
+
+const (
+	// domainSamples is how many other scored bookmarks a domain needs
+	// before its reputation counts
+	domainSamples = 5
+	// rescoreAfter is how long a score is kept before the signals, which
+	// depend on other bookmarks, are checked again
+	rescoreAfter = 7 * 24 * time.Hour
+	// reviewListLimit bounds the admin review queue
+	reviewListLimit = 100
+)
+
+// Review decisions
+const (
+	DecisionApprove = "approve"
+	DecisionReject  = "reject"
+)
+
+// Review queues
+const (
+	QueueAppealed = "appealed"
+	QueueLow      = "low" // scored below the review threshold, not reviewed yet
+	QueueApproved = "approved"
+	QueueRejected = "rejected"
+)
+
+var (
+	// ErrNotScored is returned for bookmarks without a quality score
+	ErrNotScored = errors.New("bookmark has no quality score")
+	// ErrNotAppealable is returned when appealing a bookmark that is shown,
+	// or whose appeal is pending or was decided
+	ErrNotAppealable = errors.New("only suppressed bookmarks that were not reviewed can be appealed")
+	// ErrInvalidThresholds is returned for thresholds outside 0 to 100 or
+	// a review threshold below the suppression one
+	ErrInvalidThresholds = errors.New("thresholds must be between 0 and 100, with review_below at least suppress_below")
+	// ErrInvalidDecision is returned for an unknown review decision
+	ErrInvalidDecision = errors.New("decision must be approve or reject")
+	// ErrInvalidQueue is returned for an unknown review queue
+	ErrInvalidQueue = errors.New("status must be appealed, low, approved or rejected")
+)
+
+// Service scores public bookmarks and keeps their reviews
+type Service struct {
+	cfg    config.QualityConfig
+	db     *gorm.DB
+	logger *zap.Logger
+	now    func() time.Time
+}
+
+// NewService creates a bookmark quality service
+func NewService(cfg config.QualityConfig, db *gorm.DB, logger *zap.Logger) *Service {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 500
+	}
+	return &Service{cfg: cfg, db: db, logger: logger, now: time.Now}
+}
+
+// Thresholds returns the thresholds admins set, or the configured ones
+func (s *Service) Thresholds(ctx context.Context) (*database.QualityThresholds, error) {
+	var rows []database.QualityThresholds
+	if err := s.db.WithContext(ctx).Limit(1).Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to get quality thresholds: %w", err)
+	}
+	if len(rows) == 0 {
+		return &database.QualityThresholds{SuppressBelow: s.cfg.SuppressBelow, ReviewBelow: s.cfg.ReviewBelow}, nil
+	}
+	return &rows[0], nil
+}
+
+// UpdateThresholds changes the thresholds and applies the new suppression
+// threshold to every scored bookmark at once
+func (s *Service) UpdateThresholds(ctx context.Context, suppressBelow, reviewBelow int, updatedBy string) (*database.QualityThresholds, error) {
+	if suppressBelow < 0 || reviewBelow > 100 || reviewBelow < suppressBelow {
+		return nil, ErrInvalidThresholds
+	}
+
+	thresholds := &database.QualityThresholds{ID: 1, SuppressBelow: suppressBelow, ReviewBelow: reviewBelow, UpdatedBy: updatedBy}
+	err := database.WithTransaction(ctx, s.db, func(tx *gorm.DB) error {
+		if err := tx.Save(thresholds).Error; err != nil {
+			return fmt.Errorf("failed to save quality thresholds: %w", err)
+		}
+		// Reviews override the score either way
+		return tx.Model(&database.BookmarkQuality{}).Where("1 = 1").UpdateColumn("suppressed", gorm.Expr(
+			"(COALESCE(review, '') = ? OR (score < ? AND COALESCE(review, '') <> ?))",
+			database.QualityReviewRejected, suppressBelow, database.QualityReviewApproved)).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return thresholds, nil
+}
+
+// suppressed is whether a bookmark is hidden, its review overriding its score
+func suppressed(score int, review string, suppressBelow int) bool {
+	switch review {
+	case database.QualityReviewApproved:
+		return false
+	case database.QualityReviewRejected:
+		return true
+	}
+	return score < suppressBelow
+}
+
+// RunScoring scores a batch of public bookmarks. It backs the worker's
+// quality scoring job
+func (s *Service) RunScoring(ctx context.Context) error {
+	scored, suppressedCount, err := s.ScoreBookmarks(ctx)
+	if scored > 0 {
+		s.logger.Info("Bookmark quality scored", zap.Int("scored", scored), zap.Int("suppressed", suppressedCount))
+	}
+	return err
+}
+
+// ScoreBookmarks scores the bookmarks in public collections that have no
+// score yet, changed since they were scored or were scored a while ago,
+// oldest first and at most a batch per call. It returns how many it scored
+// and how many of those are suppressed
+func (s *Service) ScoreBookmarks(ctx context.Context) (int, int, error) {
+	thresholds, err := s.Thresholds(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	now := s.now()
+	db := s.db.WithContext(ctx)
+
+	public := db.Table("bookmark_collections").
+		Select("bookmark_collections.bookmark_id").
+		Joins("JOIN collections ON collections.id = bookmark_collections.collection_id AND collections.deleted_at IS NULL").
+		Where("collections.visibility = ?", "public")
+	var bookmarks []database.Bookmark
+	if err := db.Select("bookmarks.*").
+		Joins("LEFT JOIN bookmark_qualities ON bookmark_qualities.bookmark_id = bookmarks.id AND bookmark_qualities.deleted_at IS NULL").
+		Where("bookmarks.id IN (?)", public).
+		Where("bookmark_qualities.id IS NULL OR bookmarks.updated_at > bookmark_qualities.scored_at OR bookmark_qualities.scored_at < ?", now.Add(-rescoreAfter)).
+		Order("bookmarks.id").Limit(s.cfg.BatchSize).
+		Find(&bookmarks).Error; err != nil {
+		return 0, 0, fmt.Errorf("failed to get bookmarks to score: %w", err)
+	}
+
+	suppressedCount := 0
+	for i := range bookmarks {
+		if err := ctx.Err(); err != nil {
+			return i, suppressedCount, err
+		}
+		quality, err := s.score(ctx, &bookmarks[i], thresholds.SuppressBelow, now)
+		if err != nil {
+			return i, suppressedCount, err
+		}
+		if quality.Suppressed {
+			suppressedCount++
+		}
+	}
+	return len(bookmarks), suppressedCount, nil
+}
+
+// score computes and stores the score of one bookmark, keeping its review
+func (s *Service) score(ctx context.Context, bookmark *database.Bookmark, suppressBelow int, now time.Time) (*database.BookmarkQuality, error) {
+	signals, err := s.signals(ctx, bookmark)
+	if err != nil {
+		return nil, err
+	}
+	score := 100
+	for _, signal := range signals {
+		score -= penalties[signal]
+	}
+	if score < 0 {
+		score = 0
+	}
+
+	var rows []database.BookmarkQuality
+	if err := s.db.WithContext(ctx).Where("bookmark_id = ?", bookmark.ID).Limit(1).Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to get quality score: %w", err)
+	}
+	quality := &database.BookmarkQuality{BookmarkID: bookmark.ID}
+	if len(rows) > 0 {
+		quality = &rows[0]
+	}
+	quality.UserID = bookmark.UserID
+	quality.Domain = hostOf(bookmark.URL)
+	quality.Score = score
+	quality.Signals = signals
+	quality.Suppressed = suppressed(score, quality.Review, suppressBelow)
+	quality.ScoredAt = now
+	if err := s.db.WithContext(ctx).Save(quality).Error; err != nil {
+		return nil, fmt.Errorf("failed to save quality score: %w", err)
+	}
+	return quality, nil
+}
+
+// signals returns what lowers a bookmark's score
+func (s *Service) signals(ctx context.Context, bookmark *database.Bookmark) ([]string, error) {
+	db := s.db.WithContext(ctx)
+	signals := []string{}
+	domain := hostOf(bookmark.URL)
+
+	if bookmark.SafetyThreat != "" {
+		signals = append(signals, SignalUnsafe)
+	}
+
+	if domain != "" {
+		var counts struct {
+			Total      int64
+			Suppressed int64
+		}
+		if err := db.Model(&database.BookmarkQuality{}).
+			Select("COUNT(*) AS total, COALESCE(SUM(CASE WHEN suppressed THEN 1 ELSE 0 END), 0) AS suppressed").
+			Where("domain = ? AND bookmark_id <> ?", domain, bookmark.ID).
+			Scan(&counts).Error; err != nil {
+			return nil, fmt.Errorf("failed to get domain reputation: %w", err)
+		}
+		if counts.Total >= domainSamples && counts.Suppressed*2 > counts.Total {
+			signals = append(signals, SignalDomainReputation)
+		}
+	}
+
+	if shorteners[domain] {
+		signals = append(signals, SignalLinkShortener)
+	}
+
+	if s.cfg.SpreadAccounts > 0 {
+		window := time.Duration(s.cfg.SpreadWindow) * time.Hour
+		var accounts int64
+		if err := db.Model(&database.Bookmark{}).
+			Joins("JOIN users ON users.id = bookmarks.user_id").
+			Where("bookmarks.url = ? AND bookmarks.created_at BETWEEN ? AND ?", bookmark.URL,
+				bookmark.CreatedAt.Add(-window), bookmark.CreatedAt.Add(window)).
+			Where("users.created_at >= ?", bookmark.CreatedAt.AddDate(0, 0, -s.cfg.NewAccountDays)).
+			Distinct("bookmarks.user_id").Count(&accounts).Error; err != nil {
+			return nil, fmt.Errorf("failed to count accounts spreading the URL: %w", err)
+		}
+		if accounts >= int64(s.cfg.SpreadAccounts) {
+			signals = append(signals, SignalDuplicateSpread)
+		}
+	}
+
+	if s.cfg.BurstCount > 0 {
+		// Imports legitimately create many bookmarks at once
+		window := time.Duration(s.cfg.BurstWindow) * time.Minute
+		var created int64
+		if err := db.Model(&database.Bookmark{}).
+			Where("user_id = ? AND created_at BETWEEN ? AND ?", bookmark.UserID,
+				bookmark.CreatedAt.Add(-window), bookmark.CreatedAt.Add(window)).
+			Where("COALESCE(source, '') <> ?", database.BookmarkSourceImport).
+			Count(&created).Error; err != nil {
+			return nil, fmt.Errorf("failed to count bookmarks created together: %w", err)
+		}
+		if created >= int64(s.cfg.BurstCount) {
+			signals = append(signals, SignalBurst)
+		}
+	}
+
+	return signals, nil
+}
+
+// hostOf returns the host of a URL without a leading www.
+func hostOf(rawURL string) string {
+	parsed, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.TrimSuffix(strings.ToLower(parsed.Hostname()), "."), "www.")
+}
+
+// SuppressedBookmarks returns which of the given bookmarks are hidden from
+// public areas. It lets services that rank bookmarks in memory filter them
+func (s *Service) SuppressedBookmarks(ctx context.Context, ids []uint) (map[uint]bool, error) {
+	hidden := make(map[uint]bool)
+	if len(ids) == 0 {
+		return hidden, nil
+	}
+	var suppressedIDs []uint
+	if err := database.SuppressedBookmarks(s.db.WithContext(ctx)).
+		Where("bookmark_id IN ?", ids).Pluck("bookmark_id", &suppressedIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to get suppressed bookmarks: %w", err)
+	}
+	for _, id := range suppressedIDs {
+		hidden[id] = true
+	}
+	return hidden, nil
+}
+
+// GetQuality returns the score of one of the user's bookmarks
+func (s *Service) GetQuality(ctx context.Context, userID, bookmarkID uint) (*database.BookmarkQuality, error) {
+	var rows []database.BookmarkQuality
+	if err := s.db.WithContext(ctx).Where("bookmark_id = ? AND user_id = ?", bookmarkID, userID).
+		Limit(1).Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to get quality score: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, ErrNotScored
+	}
+	return &rows[0], nil
+}
+
+// Appeal asks admins to review one of the user's suppressed bookmarks
+func (s *Service) Appeal(ctx context.Context, userID, bookmarkID uint, reason string) (*database.BookmarkQuality, error) {
+	quality, err := s.GetQuality(ctx, userID, bookmarkID)
+	if err != nil {
+		return nil, err
+	}
+	if !quality.Suppressed || quality.Review != "" {
+		return nil, ErrNotAppealable
+	}
+
+	now := s.now()
+	quality.Review = database.QualityReviewAppealed
+	quality.AppealReason = strings.TrimSpace(reason)
+	quality.AppealedAt = &now
+	if err := s.db.WithContext(ctx).Model(quality).Updates(map[string]interface{}{
+		"review":        quality.Review,
+		"appeal_reason": quality.AppealReason,
+		"appealed_at":   quality.AppealedAt,
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to appeal quality score: %w", err)
+	}
+	return quality, nil
+}
+
+// ListReviews returns a review queue with the bookmarks in it: appeals
+// oldest first, and the others lowest score first
+func (s *Service) ListReviews(ctx context.Context, queue string) ([]database.BookmarkQuality, error) {
+	query := s.db.WithContext(ctx).Preload("Bookmark")
+	switch queue {
+	case "", QueueAppealed:
+		query = query.Where("review = ?", database.QualityReviewAppealed).Order("appealed_at")
+	case QueueLow:
+		thresholds, err := s.Thresholds(ctx)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("COALESCE(review, '') = '' AND score < ?", thresholds.ReviewBelow).Order("score, scored_at")
+	case QueueApproved:
+		query = query.Where("review = ?", database.QualityReviewApproved).Order("reviewed_at DESC")
+	case QueueRejected:
+		query = query.Where("review = ?", database.QualityReviewRejected).Order("reviewed_at DESC")
+	default:
+		return nil, ErrInvalidQueue
+	}
+
+	reviews := []database.BookmarkQuality{}
+	if err := query.Limit(reviewListLimit).Find(&reviews).Error; err != nil {
+		return nil, fmt.Errorf("failed to list quality reviews: %w", err)
+	}
+	return reviews, nil
+}
+
+// Review approves a bookmark, showing it whatever its score, or rejects it,
+// hiding it for good
+func (s *Service) Review(ctx context.Context, bookmarkID uint, decision, reviewedBy string) (*database.BookmarkQuality, error) {
+	var review string
+	switch decision {
+	case DecisionApprove:
+		review = database.QualityReviewApproved
+	case DecisionReject:
+		review = database.QualityReviewRejected
+	default:
+		return nil, ErrInvalidDecision
+	}
+
+	var rows []database.BookmarkQuality
+	if err := s.db.WithContext(ctx).Where("bookmark_id = ?", bookmarkID).Limit(1).Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to get quality score: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, ErrNotScored
+	}
+	quality := &rows[0]
+
+	now := s.now()
+	quality.Review = review
+	quality.Suppressed = review == database.QualityReviewRejected
+	quality.ReviewedBy = reviewedBy
+	quality.ReviewedAt = &now
+	if err := s.db.WithContext(ctx).Model(quality).Updates(map[string]interface{}{
+		"review":      quality.Review,
+		"suppressed":  quality.Suppressed,
+		"reviewed_by": quality.ReviewedBy,
+		"reviewed_at": quality.ReviewedAt,
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to review quality score: %w", err)
+	}
+	return quality, nil
+}
@@ -0,0 +1,213 @@
+package quality
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/testfactory"
+)
+
+var testConfig = config.QualityConfig{
+	BatchSize:      100,
+	SuppressBelow:  65,
+	ReviewBelow:    80,
+	BurstCount:     4,
+	BurstWindow:    10,
+	SpreadAccounts: 3,
+	SpreadWindow:   24,
+	NewAccountDays: 7,
+}
+
+type testEnv struct {
+	service *Service
+	factory *testfactory.Factory
+	public  *database.Collection
+}
+
+func setupService(t *testing.T) *testEnv {
+	db := testfactory.NewDB(t)
+	factory := testfactory.New(t, db)
+	owner := factory.User()
+	return &testEnv{
+		service: NewService(testConfig, db, zap.NewNop()),
+		factory: factory,
+		public:  factory.Collection(owner.ID, func(c *database.Collection) { c.Visibility = "public" }),
+	}
+}
+
+// publicBookmark creates a bookmark of the user in the public collection
+func (e *testEnv) publicBookmark(userID uint, url string, overrides ...func(*database.Bookmark)) *database.Bookmark {
+	overrides = append(overrides, func(b *database.Bookmark) { b.URL = url })
+	bookmark := e.factory.Bookmark(userID, overrides...)
+	e.factory.AddToCollection(e.public, bookmark)
+	return bookmark
+}
+
+func (e *testEnv) quality(t *testing.T, bookmark *database.Bookmark) *database.BookmarkQuality {
+	t.Helper()
+	quality, err := e.service.GetQuality(context.Background(), bookmark.UserID, bookmark.ID)
+	require.NoError(t, err)
+	return quality
+}
+
+func TestScoreBookmarks(t *testing.T) {
+	env := setupService(t)
+	ctx := context.Background()
+
+	clean := env.publicBookmark(env.factory.User().ID, "https://www.example.org/guide")
+	shortened := env.publicBookmark(env.factory.User().ID, "https://bit.ly/guide")
+	unsafe := env.publicBookmark(env.factory.User().ID, "https://malware.example/", func(b *database.Bookmark) {
+		b.SafetyThreat = "MALWARE"
+	})
+	var spread []*database.Bookmark
+	for i := 0; i < 3; i++ {
+		spread = append(spread, env.publicBookmark(env.factory.User().ID, "https://offer.example/deal"))
+	}
+	burster := env.factory.User()
+	var burst []*database.Bookmark
+	for i := 0; i < 4; i++ {
+		burst = append(burst, env.publicBookmark(burster.ID, "https://blog.example/post"+string(rune('a'+i))))
+	}
+	importer := env.factory.User()
+	var imported []*database.Bookmark
+	for i := 0; i < 4; i++ {
+		imported = append(imported, env.publicBookmark(importer.ID, "https://docs.example/page"+string(rune('a'+i)), func(b *database.Bookmark) {
+			b.Source = database.BookmarkSourceImport
+		}))
+	}
+	private := env.factory.Bookmark(env.factory.User().ID)
+
+	scored, suppressedCount, err := env.service.ScoreBookmarks(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 14, scored)
+	assert.Equal(t, 4, suppressedCount)
+
+	assert.Equal(t, 100, env.quality(t, clean).Score)
+	assert.Equal(t, "example.org", env.quality(t, clean).Domain)
+	assert.Equal(t, []string{SignalLinkShortener}, []string(env.quality(t, shortened).Signals))
+	assert.Equal(t, 70, env.quality(t, shortened).Score)
+	assert.Equal(t, 0, env.quality(t, unsafe).Score)
+	assert.True(t, env.quality(t, unsafe).Suppressed)
+	for _, bookmark := range spread {
+		assert.Equal(t, []string{SignalDuplicateSpread}, []string(env.quality(t, bookmark).Signals))
+		assert.True(t, env.quality(t, bookmark).Suppressed)
+	}
+	for _, bookmark := range burst {
+		assert.Equal(t, []string{SignalBurst}, []string(env.quality(t, bookmark).Signals))
+		assert.False(t, env.quality(t, bookmark).Suppressed)
+	}
+	for _, bookmark := range imported {
+		assert.Equal(t, 100, env.quality(t, bookmark).Score, "imports are not bursts")
+	}
+	_, err = env.service.GetQuality(ctx, private.UserID, private.ID)
+	assert.ErrorIs(t, err, ErrNotScored, "only public bookmarks are scored")
+
+	// Nothing changed, so nothing is scored again
+	scored, _, err = env.service.ScoreBookmarks(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, scored)
+
+	hidden, err := env.service.SuppressedBookmarks(ctx, []uint{clean.ID, unsafe.ID, spread[0].ID})
+	require.NoError(t, err)
+	assert.Equal(t, map[uint]bool{unsafe.ID: true, spread[0].ID: true}, hidden)
+
+	low, err := env.service.ListReviews(ctx, QueueLow)
+	require.NoError(t, err)
+	require.Len(t, low, 9)
+	assert.Equal(t, unsafe.ID, low[0].BookmarkID, "lowest score first")
+	require.NotNil(t, low[0].Bookmark)
+}
+
+func TestUpdateThresholds(t *testing.T) {
+	env := setupService(t)
+	ctx := context.Background()
+	shortened := env.publicBookmark(env.factory.User().ID, "https://bit.ly/guide")
+	_, _, err := env.service.ScoreBookmarks(ctx)
+	require.NoError(t, err)
+	require.False(t, env.quality(t, shortened).Suppressed)
+
+	_, err = env.service.UpdateThresholds(ctx, 60, 50, "admin@example.com")
+	assert.ErrorIs(t, err, ErrInvalidThresholds)
+
+	thresholds, err := env.service.UpdateThresholds(ctx, 75, 90, "admin@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "admin@example.com", thresholds.UpdatedBy)
+	assert.True(t, env.quality(t, shortened).Suppressed, "the new threshold applies at once")
+
+	current, err := env.service.Thresholds(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 75, current.SuppressBelow)
+	assert.Equal(t, 90, current.ReviewBelow)
+}
+
+func TestAppealAndReview(t *testing.T) {
+	env := setupService(t)
+	ctx := context.Background()
+	owner := env.factory.User()
+	shown := env.publicBookmark(owner.ID, "https://example.org/guide")
+	var spread []*database.Bookmark
+	spread = append(spread, env.publicBookmark(owner.ID, "https://bit.ly/deal"))
+	for i := 0; i < 2; i++ {
+		spread = append(spread, env.publicBookmark(env.factory.User().ID, "https://bit.ly/deal"))
+	}
+	_, _, err := env.service.ScoreBookmarks(ctx)
+	require.NoError(t, err)
+	appealed := spread[0]
+
+	_, err = env.service.Appeal(ctx, owner.ID, shown.ID, "")
+	assert.ErrorIs(t, err, ErrNotAppealable, "shown bookmarks cannot be appealed")
+	_, err = env.service.Appeal(ctx, spread[1].UserID+100, appealed.ID, "")
+	assert.ErrorIs(t, err, ErrNotScored, "only the owner can appeal")
+
+	quality, err := env.service.Appeal(ctx, owner.ID, appealed.ID, "  A real deal  ")
+	require.NoError(t, err)
+	assert.Equal(t, database.QualityReviewAppealed, quality.Review)
+	assert.Equal(t, "A real deal", quality.AppealReason)
+	_, err = env.service.Appeal(ctx, owner.ID, appealed.ID, "")
+	assert.ErrorIs(t, err, ErrNotAppealable, "an appeal is pending")
+
+	queue, err := env.service.ListReviews(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, queue, 1)
+	assert.Equal(t, appealed.ID, queue[0].BookmarkID)
+	_, err = env.service.ListReviews(ctx, "pending")
+	assert.ErrorIs(t, err, ErrInvalidQueue)
+
+	_, err = env.service.Review(ctx, appealed.ID, "maybe", "admin@example.com")
+	assert.ErrorIs(t, err, ErrInvalidDecision)
+	quality, err = env.service.Review(ctx, appealed.ID, DecisionApprove, "admin@example.com")
+	require.NoError(t, err)
+	assert.False(t, quality.Suppressed)
+	assert.Equal(t, "admin@example.com", quality.ReviewedBy)
+
+	quality, err = env.service.Review(ctx, spread[1].ID, DecisionReject, "admin@example.com")
+	require.NoError(t, err)
+	assert.True(t, quality.Suppressed)
+
+	// Reviews outlast threshold changes and rescoring
+	_, err = env.service.UpdateThresholds(ctx, 0, 0, "admin@example.com")
+	require.NoError(t, err)
+	assert.False(t, env.quality(t, appealed).Suppressed)
+	assert.True(t, env.quality(t, spread[1]).Suppressed)
+
+	env.service.now = func() time.Time { return time.Now().Add(rescoreAfter + time.Hour) }
+	_, err = env.service.UpdateThresholds(ctx, 100, 100, "admin@example.com")
+	require.NoError(t, err)
+	scored, _, err := env.service.ScoreBookmarks(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 4, scored)
+	assert.False(t, env.quality(t, appealed).Suppressed)
+	assert.Equal(t, database.QualityReviewApproved, env.quality(t, appealed).Review)
+
+	approved, err := env.service.ListReviews(ctx, QueueApproved)
+	require.NoError(t, err)
+	require.Len(t, approved, 1)
+	assert.Equal(t, appealed.ID, approved[0].BookmarkID)
+}
@@ -0,0 +1,104 @@
+package quota
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"bookmark-sync-service/backend/pkg/utils"
+)
+
+// Handler serves rate limit usage to users and plan changes to admins
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new quota handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes registers the usage route on an authenticated group
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/usage/rate-limits", h.GetUsage)
+}
+
+// RegisterAdminRoutes registers plan assignment on an admin-only group
+func (h *Handler) RegisterAdminRoutes(router *gin.RouterGroup) {
+	router.PUT("/users/:id/plan", h.SetPlan)
+}
+
+// GetUsage returns what is left of the user's rate limits
+// @Summary Get my rate limit usage
+// @Description Remaining tokens per bucket and how many requests fit in the next minute and hour
+// @Tags usage
+// @Produce json
+// @Success 200 {object} Usage
+// @Router /usage/rate-limits [get]
+func (h *Handler) GetUsage(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	usage, err := h.service.Usage(c.Request.Context(), userID)
+	if err != nil {
+		h.fail(c, err, "Failed to get rate limit usage")
+		return
+	}
+	utils.SuccessResponse(c, usage, "Rate limit usage retrieved")
+}
+
+// SetPlanRequest assigns a plan; empty goes back to the default plan
+type SetPlanRequest struct {
+	Plan string `json:"plan"`
+}
+
+// SetPlan assigns a rate limit plan to a user
+// @Summary Set a user's plan
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param request body SetPlanRequest true "Plan"
+// @Success 200 {object} Usage
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /admin/users/{id}/plan [put]
+func (h *Handler) SetPlan(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_ID", "Invalid user ID", nil)
+		return
+	}
+	var req SetPlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request format", nil)
+		return
+	}
+
+	if err := h.service.SetPlan(c.Request.Context(), uint(id), req.Plan); err != nil {
+		h.fail(c, err, "Failed to set plan")
+		return
+	}
+	usage, err := h.service.Usage(c.Request.Context(), uint(id))
+	if err != nil {
+		h.fail(c, err, "Failed to get rate limit usage")
+		return
+	}
+	utils.SuccessResponse(c, usage, "Plan updated")
+}
+
+func (h *Handler) fail(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, ErrUnknownPlan):
+		utils.ErrorResponse(c, http.StatusBadRequest, "UNKNOWN_PLAN", err.Error(), nil)
+	case errors.Is(err, ErrUserNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, "USER_NOT_FOUND", "User not found", nil)
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", message, nil)
+	}
+}
@@ -0,0 +1,46 @@
+package quota
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"bookmark-sync-service/backend/pkg/utils"
+)
+
+// Middleware meters authenticated API requests. A request past the burst is
+// held until its token refills, for up to the configured delay; beyond that
+// it gets a 429. It must run after AuthMiddleware
+func (s *Service) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := utils.GetUserIDFromContext(c)
+		if !s.cfg.Enabled || userID == 0 {
+			c.Next()
+			return
+		}
+
+		decision := s.Take(c.Request.Context(), userID, BucketAPI, time.Duration(s.cfg.MaxDelay)*time.Millisecond)
+		if !decision.Unlimited {
+			c.Header("X-RateLimit-Limit", strconv.Itoa(decision.Limit))
+			c.Header("X-RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+		}
+		if !decision.Allowed {
+			retryAfter := int(math.Ceil(decision.RetryAfter.Seconds()))
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			utils.ErrorResponse(c, http.StatusTooManyRequests, "TOO_MANY_REQUESTS", "Rate limit exceeded", map[string]interface{}{
+				"retry_after": retryAfter,
+			})
+			c.Abort()
+			return
+		}
+		if decision.Delay > 0 {
+			c.Header("X-RateLimit-Delay", strconv.FormatInt(decision.Delay.Milliseconds(), 10))
+			s.sleep(c.Request.Context(), decision.Delay)
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,311 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/internal/metering"
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/redis"
+)
+
+// Buckets a user's budget is split into
+const (
+	BucketAPI        = "api"
+	BucketAutomation = "automation"
+)
+
+// planCacheTTL is how long a user's plan is remembered between requests
+const planCacheTTL = time.Minute
+
+var (
+	// ErrOverBudget is returned to automation work that can't start within
+	// the queue delay
+	ErrOverBudget = errors.New("rate limit exceeded")
+	// ErrUnknownPlan is returned when assigning a plan that isn't configured
+	ErrUnknownPlan = errors.New("unknown plan")
+	// ErrUserNotFound is returned for users that don't exist
+	ErrUserNotFound = errors.New("user not found")
+)
+
+// Meter reads a user's feature usage in a monthly period
+type Meter interface {
+	Usage(ctx context.Context, userID uint, period time.Time) (*metering.PeriodUsage, error)
+}
+
+// Store is where the token buckets live; Redis, so every replica spends from
+// the same budget
+type Store interface {
+	TakeTokens(ctx context.Context, key string, bucket redis.TokenBucket, cost float64, now time.Time) (float64, bool, error)
+}
+
+// Decision is the outcome of spending a token
+type Decision struct {
+	Allowed    bool
+	Unlimited  bool
+	Delay      time.Duration // to wait before going ahead, for work allowed into debt
+	RetryAfter time.Duration // for denied work
+	Limit      int           // burst of the bucket
+	Remaining  int
+}
+
+type cachedPlan struct {
+	plan    string
+	expires time.Time
+}
+
+// Service meters signed-in users against the token buckets of their plan
+type Service struct {
+	cfg    config.QuotaConfig
+	db     *gorm.DB
+	store  Store
+	meter  Meter
+	admins map[string]bool
+	logger *zap.Logger
+	now    func() time.Time
+	sleep  func(ctx context.Context, d time.Duration)
+
+	mu    sync.Mutex
+	plans map[uint]cachedPlan
+}
+
+// NewService creates a quota service. Admin accounts get cfg.AdminPlan
+func NewService(cfg config.QuotaConfig, adminEmails []string, db *gorm.DB, store Store, logger *zap.Logger) *Service {
+	admins := make(map[string]bool, len(adminEmails))
+	for _, email := range adminEmails {
+		if email = strings.ToLower(strings.TrimSpace(email)); email != "" {
+			admins[email] = true
+		}
+	}
+	return &Service{
+		cfg:    cfg,
+		db:     db,
+		store:  store,
+		admins: admins,
+		logger: logger,
+		now:    time.Now,
+		sleep:  sleepContext,
+		plans:  make(map[uint]cachedPlan),
+	}
+}
+
+// SetMeter reports the feature usage of the current period next to the
+// rate limits, the usage future plan allowances are checked against
+func (s *Service) SetMeter(meter Meter) {
+	s.meter = meter
+}
+
+// Take spends a token of the user's bucket. Up to maxDelay worth of tokens
+// may be borrowed, the caller then waits out Decision.Delay. Redis and
+// database errors let the work through rather than failing it
+func (s *Service) Take(ctx context.Context, userID uint, bucket string, maxDelay time.Duration) Decision {
+	if !s.cfg.Enabled {
+		return Decision{Allowed: true, Unlimited: true}
+	}
+	plan, err := s.planOf(ctx, userID)
+	if err != nil {
+		s.logger.Warn("Failed to look up user plan", zap.Uint("user_id", userID), zap.Error(err))
+		return Decision{Allowed: true, Unlimited: true}
+	}
+	limit, ok := s.bucket(plan, bucket)
+	if !ok {
+		return Decision{Allowed: true, Unlimited: true}
+	}
+	limit.MaxDebt = limit.PerSecond * maxDelay.Seconds()
+
+	tokens, allowed, err := s.store.TakeTokens(ctx, s.key(bucket, userID), limit, 1, s.now())
+	if err != nil {
+		s.logger.Warn("Failed to take rate limit token", zap.String("bucket", bucket), zap.Error(err))
+		return Decision{Allowed: true, Unlimited: true}
+	}
+
+	decision := Decision{Allowed: allowed, Limit: int(limit.Capacity), Remaining: int(math.Max(tokens, 0))}
+	switch {
+	case !allowed:
+		decision.RetryAfter = seconds((1 - limit.MaxDebt - tokens) / limit.PerSecond)
+	case tokens < 0:
+		decision.Delay = seconds(-tokens / limit.PerSecond)
+	}
+	return decision
+}
+
+// Wait holds automation work of a user until their budget allows it, for up
+// to the configured queue delay. Work that would have to wait longer gets
+// ErrOverBudget
+func (s *Service) Wait(ctx context.Context, userID string) error {
+	id, err := strconv.ParseUint(userID, 10, 64)
+	if err != nil {
+		return nil
+	}
+	decision := s.Take(ctx, uint(id), BucketAutomation, time.Duration(s.cfg.QueueDelay)*time.Second)
+	if !decision.Allowed {
+		return fmt.Errorf("%w: try again in %s", ErrOverBudget, decision.RetryAfter.Round(time.Second))
+	}
+	if decision.Delay > 0 {
+		s.sleep(ctx, decision.Delay)
+	}
+	return ctx.Err()
+}
+
+// Usage is what is left of a user's budget, with what they used of metered
+// features this period
+type Usage struct {
+	Plan    string                `json:"plan"`
+	Buckets []BucketUsage         `json:"buckets"`
+	Metered *metering.PeriodUsage `json:"metered,omitempty"`
+}
+
+// BucketUsage describes one bucket. NextMinute and NextHour count the
+// requests that fit in that window: what is left now plus the refill
+type BucketUsage struct {
+	Bucket     string     `json:"bucket"`
+	Unlimited  bool       `json:"unlimited"`
+	Burst      int        `json:"burst,omitempty"`
+	PerMinute  int        `json:"per_minute,omitempty"`
+	Remaining  int        `json:"remaining"`
+	NextMinute int        `json:"next_minute"`
+	NextHour   int        `json:"next_hour"`
+	FullAt     *time.Time `json:"full_at,omitempty"` // when the bucket is back to its burst
+}
+
+// Usage reads the user's buckets without spending from them
+func (s *Service) Usage(ctx context.Context, userID uint) (*Usage, error) {
+	plan, err := s.planOf(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &Usage{Plan: plan}
+	for _, name := range []string{BucketAPI, BucketAutomation} {
+		limit, ok := s.bucket(plan, name)
+		if !s.cfg.Enabled || !ok {
+			usage.Buckets = append(usage.Buckets, BucketUsage{Bucket: name, Unlimited: true})
+			continue
+		}
+
+		now := s.now()
+		tokens, _, err := s.store.TakeTokens(ctx, s.key(name, userID), limit, 0, now)
+		if err != nil {
+			return nil, err
+		}
+		perMinute := limit.PerSecond * 60
+		bucket := BucketUsage{
+			Bucket:     name,
+			Burst:      int(limit.Capacity),
+			PerMinute:  int(math.Round(perMinute)),
+			Remaining:  int(math.Max(tokens, 0)),
+			NextMinute: int(math.Max(tokens+perMinute, 0)),
+			NextHour:   int(math.Max(tokens+perMinute*60, 0)),
+		}
+		if tokens < limit.Capacity {
+			full := now.Add(seconds((limit.Capacity - tokens) / limit.PerSecond)).UTC()
+			bucket.FullAt = &full
+		}
+		usage.Buckets = append(usage.Buckets, bucket)
+	}
+
+	if s.meter != nil {
+		metered, err := s.meter.Usage(ctx, userID, s.now())
+		if err != nil {
+			return nil, err
+		}
+		usage.Metered = metered
+	}
+	return usage, nil
+}
+
+// SetPlan assigns a configured plan to a user; an empty plan goes back to
+// the default
+func (s *Service) SetPlan(ctx context.Context, userID uint, plan string) error {
+	if _, ok := s.cfg.Plans[plan]; plan != "" && !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownPlan, plan)
+	}
+	result := s.db.WithContext(ctx).Model(&database.User{}).Where("id = ?", userID).Update("plan", plan)
+	if result.Error != nil {
+		return fmt.Errorf("failed to set plan: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrUserNotFound
+	}
+
+	s.mu.Lock()
+	delete(s.plans, userID)
+	s.mu.Unlock()
+	return nil
+}
+
+// planOf resolves the plan whose limits apply to a user: the admin plan for
+// admins, then their own plan if it is still configured, then the default
+func (s *Service) planOf(ctx context.Context, userID uint) (string, error) {
+	now := s.now()
+	s.mu.Lock()
+	cached, ok := s.plans[userID]
+	s.mu.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.plan, nil
+	}
+
+	var user database.User
+	if err := s.db.WithContext(ctx).Select("email", "plan").First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", ErrUserNotFound
+		}
+		return "", fmt.Errorf("failed to load user plan: %w", err)
+	}
+
+	plan := s.cfg.DefaultPlan
+	if _, ok := s.cfg.Plans[user.Plan]; ok {
+		plan = user.Plan
+	}
+	if s.cfg.AdminPlan != "" && s.admins[strings.ToLower(user.Email)] {
+		plan = s.cfg.AdminPlan
+	}
+
+	s.mu.Lock()
+	s.plans[userID] = cachedPlan{plan: plan, expires: now.Add(planCacheTTL)}
+	s.mu.Unlock()
+	return plan, nil
+}
+
+// bucket is the shape of a plan's bucket; false when it is unlimited
+func (s *Service) bucket(plan, name string) (redis.TokenBucket, bool) {
+	quota := s.cfg.Plans[plan]
+	burst, rate := quota.APIBurst, quota.APIRate
+	if name == BucketAutomation {
+		burst, rate = quota.AutomationBurst, quota.AutomationRate
+	}
+	if rate <= 0 {
+		return redis.TokenBucket{}, false
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return redis.TokenBucket{Capacity: float64(burst), PerSecond: float64(rate) / 60}, true
+}
+
+func (s *Service) key(bucket string, userID uint) string {
+	return fmt.Sprintf("%s:%s:%d", config.QuotaBucketPrefix, bucket, userID)
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Ceil(s * float64(time.Second)))
+}
+
+func sleepContext(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
@@ -0,0 +1,156 @@
+package quota
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/redis"
+	"bookmark-sync-service/backend/pkg/testfactory"
+)
+
+var testConfig = config.QuotaConfig{
+	Enabled:     true,
+	DefaultPlan: "free",
+	AdminPlan:   "admin",
+	MaxDelay:    1000,
+	QueueDelay:  10,
+	Plans: map[string]config.PlanQuota{
+		"free":  {APIBurst: 2, APIRate: 60, AutomationBurst: 1, AutomationRate: 6},
+		"pro":   {APIBurst: 10, APIRate: 600, AutomationBurst: 5, AutomationRate: 60},
+		"admin": {},
+	},
+}
+
+type testEnv struct {
+	service *Service
+	factory *testfactory.Factory
+	now     time.Time
+	slept   []time.Duration
+}
+
+func setupService(t *testing.T) *testEnv {
+	mr := miniredis.RunT(t)
+	client, err := redis.NewClient(config.RedisConfig{Host: mr.Host(), Port: mr.Port(), PoolSize: 1})
+	require.NoError(t, err)
+	db := testfactory.NewDB(t)
+
+	env := &testEnv{factory: testfactory.New(t, db), now: time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)}
+	env.service = NewService(testConfig, []string{"Root@example.com"}, db, client, zap.NewNop())
+	env.service.now = func() time.Time { return env.now }
+	env.service.sleep = func(_ context.Context, d time.Duration) { env.slept = append(env.slept, d) }
+	return env
+}
+
+func TestMiddleware(t *testing.T) {
+	env := setupService(t)
+	user := env.factory.User()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/bookmarks", func(c *gin.Context) {
+		c.Set("user_id", strconv.FormatUint(uint64(user.ID), 10))
+	}, env.service.Middleware(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	get := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/bookmarks", nil))
+		return w
+	}
+
+	// The burst goes through straight away
+	for remaining := 1; remaining >= 0; remaining-- {
+		w := get()
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "2", w.Header().Get("X-RateLimit-Limit"))
+		assert.Equal(t, strconv.Itoa(remaining), w.Header().Get("X-RateLimit-Remaining"))
+	}
+	assert.Empty(t, env.slept)
+
+	// The next request is held until its token refills
+	w := get()
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1000", w.Header().Get("X-RateLimit-Delay"))
+	assert.Equal(t, []time.Duration{time.Second}, env.slept)
+
+	// Past the delay budget it is turned away
+	w = get()
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+
+	env.now = env.now.Add(time.Second)
+	assert.Equal(t, http.StatusOK, get().Code)
+}
+
+func TestWait(t *testing.T) {
+	env := setupService(t)
+	user := env.factory.User()
+	id := strconv.FormatUint(uint64(user.ID), 10)
+	ctx := context.Background()
+
+	require.NoError(t, env.service.Wait(ctx, id))
+	assert.Empty(t, env.slept)
+
+	// Work past the burst is queued for its token, 10s at 6 a minute
+	require.NoError(t, env.service.Wait(ctx, id))
+	assert.Equal(t, []time.Duration{10 * time.Second}, env.slept)
+
+	err := env.service.Wait(ctx, id)
+	assert.ErrorIs(t, err, ErrOverBudget)
+	assert.Contains(t, err.Error(), "try again in 10s")
+
+	// API requests have a budget of their own
+	assert.True(t, env.service.Take(ctx, user.ID, BucketAPI, 0).Allowed)
+}
+
+func TestPlans(t *testing.T) {
+	env := setupService(t)
+	ctx := context.Background()
+	user := env.factory.User()
+	admin := env.factory.User(func(u *database.User) { u.Email = "root@example.com" })
+
+	usage, err := env.service.Usage(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "free", usage.Plan)
+	assert.Equal(t, BucketUsage{Bucket: BucketAPI, Burst: 2, PerMinute: 60, Remaining: 2, NextMinute: 62, NextHour: 3602}, usage.Buckets[0])
+
+	env.service.Take(ctx, user.ID, BucketAutomation, 0)
+	usage, err = env.service.Usage(ctx, user.ID)
+	require.NoError(t, err)
+	automation := usage.Buckets[1]
+	assert.Equal(t, 0, automation.Remaining)
+	assert.Equal(t, 6, automation.NextMinute)
+	require.NotNil(t, automation.FullAt)
+	assert.Equal(t, env.now.Add(10*time.Second), *automation.FullAt)
+
+	t.Run("admins are unlimited", func(t *testing.T) {
+		usage, err := env.service.Usage(ctx, admin.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "admin", usage.Plan)
+		assert.True(t, usage.Buckets[0].Unlimited)
+		assert.True(t, env.service.Take(ctx, admin.ID, BucketAPI, 0).Unlimited)
+	})
+
+	t.Run("assigning a plan", func(t *testing.T) {
+		assert.ErrorIs(t, env.service.SetPlan(ctx, user.ID, "platinum"), ErrUnknownPlan)
+		assert.ErrorIs(t, env.service.SetPlan(ctx, 9999, "pro"), ErrUserNotFound)
+
+		require.NoError(t, env.service.SetPlan(ctx, user.ID, "pro"))
+		usage, err := env.service.Usage(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "pro", usage.Plan)
+		assert.Equal(t, 10, usage.Buckets[0].Burst)
+	})
+}
@@ -0,0 +1,150 @@
+package reading
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"bookmark-sync-service/backend/pkg/utils"
+)
+
+// Handler serves reading sessions and statistics
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new reading handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes registers the reading routes
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	reading := router.Group("/reading")
+	reading.POST("/sessions", h.RecordSession)
+	reading.GET("/stats", h.GetStats)
+	reading.GET("/stats/weekly", h.GetWeekly)
+	reading.GET("/stats/longest", h.GetLongest)
+	reading.GET("/stats/completion", h.GetCompletion)
+}
+
+// RecordSessionRequest reports time spent reading a bookmark
+type RecordSessionRequest struct {
+	BookmarkID uint `json:"bookmark_id" binding:"required"`
+	Duration   int  `json:"duration" binding:"required"` // seconds
+}
+
+// RecordSession records time spent reading a bookmark
+// @Summary Record a reading session
+// @Tags reading
+// @Accept json
+// @Produce json
+// @Param request body RecordSessionRequest true "Reading session"
+// @Success 201 {object} utils.APIResponse
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /reading/sessions [post]
+func (h *Handler) RecordSession(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	var req RecordSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request format", nil)
+		return
+	}
+	if req.Duration <= 0 || req.Duration > MaxSessionSeconds {
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", "duration must be between 1 and "+strconv.Itoa(MaxSessionSeconds)+" seconds", nil)
+		return
+	}
+
+	if err := h.service.RecordSession(c.Request.Context(), userID, req.BookmarkID, req.Duration); err != nil {
+		if errors.Is(err, ErrBookmarkNotFound) {
+			utils.ErrorResponse(c, http.StatusNotFound, "BOOKMARK_NOT_FOUND", "Bookmark not found", nil)
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to record reading session", nil)
+		return
+	}
+
+	c.JSON(http.StatusCreated, utils.APIResponse{Success: true, Message: "Reading session recorded"})
+}
+
+// GetStats returns the reading summary for the analytics dashboard
+// @Summary Get reading statistics
+// @Tags reading
+// @Produce json
+// @Param weeks query int false "Weeks to cover, default 12"
+// @Success 200 {object} Stats
+// @Router /reading/stats [get]
+func (h *Handler) GetStats(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	stats, err := h.service.Stats(c.Request.Context(), userID, queryInt(c, "weeks"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to get reading statistics", nil)
+		return
+	}
+	utils.SuccessResponse(c, stats, "Reading statistics retrieved")
+}
+
+// GetWeekly returns the time read per week
+// @Summary Get reading time per week
+// @Tags reading
+// @Produce json
+// @Param weeks query int false "Weeks to cover, default 12"
+// @Success 200 {array} Week
+// @Router /reading/stats/weekly [get]
+func (h *Handler) GetWeekly(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	weeks, err := h.service.Weekly(c.Request.Context(), userID, queryInt(c, "weeks"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to get weekly reading time", nil)
+		return
+	}
+	utils.SuccessResponse(c, weeks, "Weekly reading time retrieved")
+}
+
+// GetLongest returns the bookmarks read the longest
+// @Summary Get the longest reads
+// @Tags reading
+// @Produce json
+// @Param weeks query int false "Weeks to cover, default 12"
+// @Param limit query int false "Maximum reads, default 10"
+// @Success 200 {array} Read
+// @Router /reading/stats/longest [get]
+func (h *Handler) GetLongest(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	reads, err := h.service.Longest(c.Request.Context(), userID, queryInt(c, "weeks"), queryInt(c, "limit"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to get longest reads", nil)
+		return
+	}
+	utils.SuccessResponse(c, reads, "Longest reads retrieved")
+}
+
+// GetCompletion returns the completion rate of the reading list
+// @Summary Get reading list completion
+// @Description Reading-list items are bookmarks tagged read-later or imported as unread
+// @Tags reading
+// @Produce json
+// @Success 200 {object} Completion
+// @Router /reading/stats/completion [get]
+func (h *Handler) GetCompletion(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	completion, err := h.service.Completion(c.Request.Context(), userID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to get reading list completion", nil)
+		return
+	}
+	utils.SuccessResponse(c, completion, "Reading list completion retrieved")
+}
+
+func queryInt(c *gin.Context, name string) int {
+	value, _ := strconv.Atoi(c.Query(name))
+	return value
+}
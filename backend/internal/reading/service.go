@@ -0,0 +1,361 @@
+package reading
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/internal/community"
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+// Reading sessions are stored as view behaviors with a duration, so the
+// recommendation engine sees them too
+const (
+	sessionAction  = "view"
+	sessionContext = "reading"
+)
+
+const (
+	// ListTag marks a bookmark as on the reading list. Bookmarks imported
+	// with the unread flag are on it as well
+	ListTag = "read-later"
+	// completionRatio is the share of the estimated reading time a reader
+	// must spend on a reading-list item for it to count as completed
+	completionRatio = 0.8
+
+	// MaxSessionSeconds caps one recorded reading session
+	MaxSessionSeconds = 4 * 60 * 60
+
+	defaultWeeks   = 12
+	maxWeeks       = 104
+	defaultLongest = 10
+	maxLongest     = 50
+)
+
+// ErrBookmarkNotFound is returned when recording a session for a bookmark
+// the user does not own
+var ErrBookmarkNotFound = errors.New("bookmark not found")
+
+// Week is the reading time of one week, starting Monday 00:00 UTC
+type Week struct {
+	Start     time.Time `json:"start"`
+	Seconds   int       `json:"seconds"`
+	Bookmarks int       `json:"bookmarks"` // distinct bookmarks read
+}
+
+// Read is a bookmark with the total time spent reading it
+type Read struct {
+	BookmarkID  uint      `json:"bookmark_id"`
+	Title       string    `json:"title"`
+	URL         string    `json:"url"`
+	Seconds     int       `json:"seconds"`
+	ReadingTime int       `json:"reading_time,omitempty"` // estimated minutes
+	LastReadAt  time.Time `json:"last_read_at"`
+}
+
+// Completion is the progress through the reading list
+type Completion struct {
+	Items     int     `json:"items"`
+	Completed int     `json:"completed"`
+	Rate      float64 `json:"rate"` // 0.0 to 1.0
+}
+
+// Stats is the reading summary of the personal analytics dashboard
+type Stats struct {
+	TotalSeconds int        `json:"total_seconds"`
+	Sessions     int        `json:"sessions"`
+	Weekly       []Week     `json:"weekly"`
+	Longest      []Read     `json:"longest"`
+	Completion   Completion `json:"completion"`
+}
+
+// Service computes reading statistics from tracked behavior
+type Service struct {
+	db  *gorm.DB
+	now func() time.Time
+}
+
+// NewService creates a new reading service
+func NewService(db *gorm.DB) *Service {
+	return &Service{db: db, now: time.Now}
+}
+
+// RecordSession stores time a user spent reading a bookmark
+func (s *Service) RecordSession(ctx context.Context, userID, bookmarkID uint, seconds int) error {
+	if seconds <= 0 || seconds > MaxSessionSeconds {
+		return fmt.Errorf("duration must be between 1 and %d seconds", MaxSessionSeconds)
+	}
+
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&database.Bookmark{}).
+		Where("id = ? AND user_id = ?", bookmarkID, userID).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to check bookmark: %w", err)
+	}
+	if count == 0 {
+		return ErrBookmarkNotFound
+	}
+
+	behavior := &community.UserBehavior{
+		UserID:     strconv.FormatUint(uint64(userID), 10),
+		BookmarkID: bookmarkID,
+		ActionType: sessionAction,
+		Duration:   seconds,
+		Context:    sessionContext,
+	}
+	if err := s.db.WithContext(ctx).Create(behavior).Error; err != nil {
+		return fmt.Errorf("failed to record reading session: %w", err)
+	}
+	return nil
+}
+
+// Stats returns the reading summary over the last weeks
+func (s *Service) Stats(ctx context.Context, userID uint, weeks int) (*Stats, error) {
+	sessions, err := s.sessions(ctx, userID, s.weeksStart(weeks))
+	if err != nil {
+		return nil, err
+	}
+	stats := &Stats{Sessions: len(sessions), Weekly: s.weekly(sessions, weeks)}
+	for _, session := range sessions {
+		stats.TotalSeconds += session.Duration
+	}
+
+	if stats.Longest, err = s.longest(ctx, userID, sessions, defaultLongest); err != nil {
+		return nil, err
+	}
+	if stats.Completion, err = s.Completion(ctx, userID); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// Weekly returns the time read per week, oldest first, including weeks
+// without reading
+func (s *Service) Weekly(ctx context.Context, userID uint, weeks int) ([]Week, error) {
+	sessions, err := s.sessions(ctx, userID, s.weeksStart(weeks))
+	if err != nil {
+		return nil, err
+	}
+	return s.weekly(sessions, weeks), nil
+}
+
+// Longest returns the bookmarks read the longest in total over the last
+// weeks
+func (s *Service) Longest(ctx context.Context, userID uint, weeks, limit int) ([]Read, error) {
+	sessions, err := s.sessions(ctx, userID, s.weeksStart(weeks))
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 || limit > maxLongest {
+		limit = defaultLongest
+	}
+	return s.longest(ctx, userID, sessions, limit)
+}
+
+// Completion returns how much of the reading list was read. An item is
+// completed once the time spent on it reaches most of its estimated
+// reading time, or after any reading when there is no estimate
+func (s *Service) Completion(ctx context.Context, userID uint) (Completion, error) {
+	items, err := s.list(ctx, s.db.Select("id", "reading_time"), userID)
+	if err != nil {
+		return Completion{}, err
+	}
+	completion := Completion{Items: len(items)}
+	if len(items) == 0 {
+		return completion, nil
+	}
+
+	read, err := s.readSeconds(ctx, userID, items)
+	if err != nil {
+		return Completion{}, err
+	}
+	for _, item := range items {
+		if seconds, ok := read[item.ID]; ok && completed(item, seconds) {
+			completion.Completed++
+		}
+	}
+	completion.Rate = float64(completion.Completed) / float64(completion.Items)
+	return completion, nil
+}
+
+// Unfinished returns the reading-list items not completed yet, most
+// recently saved first
+func (s *Service) Unfinished(ctx context.Context, userID uint, limit int) ([]database.Bookmark, error) {
+	items, err := s.list(ctx, s.db.Order("created_at DESC, id DESC"), userID)
+	if err != nil {
+		return nil, err
+	}
+	read, err := s.readSeconds(ctx, userID, items)
+	if err != nil {
+		return nil, err
+	}
+
+	unfinished := make([]database.Bookmark, 0, limit)
+	for _, item := range items {
+		if seconds, ok := read[item.ID]; ok && completed(item, seconds) {
+			continue
+		}
+		if unfinished = append(unfinished, item); len(unfinished) == limit {
+			break
+		}
+	}
+	return unfinished, nil
+}
+
+// list loads the user's reading-list items
+func (s *Service) list(ctx context.Context, query *gorm.DB, userID uint) ([]database.Bookmark, error) {
+	var items []database.Bookmark
+	if err := query.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Where("tags LIKE ? OR metadata LIKE ?", `%"`+ListTag+`"%`, `%"unread":true%`).
+		Find(&items).Error; err != nil {
+		return nil, fmt.Errorf("failed to load reading list: %w", err)
+	}
+	return items, nil
+}
+
+// readSeconds sums the time spent reading each of the bookmarks
+func (s *Service) readSeconds(ctx context.Context, userID uint, bookmarks []database.Bookmark) (map[uint]int, error) {
+	if len(bookmarks) == 0 {
+		return map[uint]int{}, nil
+	}
+	ids := make([]uint, 0, len(bookmarks))
+	for _, bookmark := range bookmarks {
+		ids = append(ids, bookmark.ID)
+	}
+
+	var totals []struct {
+		BookmarkID uint
+		Seconds    int
+	}
+	if err := s.db.WithContext(ctx).Model(&community.UserBehavior{}).
+		Select("bookmark_id, SUM(duration) AS seconds").
+		Where("user_id = ? AND action_type = ? AND duration > 0 AND bookmark_id IN ?",
+			strconv.FormatUint(uint64(userID), 10), sessionAction, ids).
+		Group("bookmark_id").Scan(&totals).Error; err != nil {
+		return nil, fmt.Errorf("failed to load reading sessions: %w", err)
+	}
+	read := make(map[uint]int, len(totals))
+	for _, total := range totals {
+		read[total.BookmarkID] = total.Seconds
+	}
+	return read, nil
+}
+
+// completed reports whether the time read covers a reading-list item
+func completed(item database.Bookmark, seconds int) bool {
+	return item.ReadingTime == 0 || float64(seconds) >= float64(item.ReadingTime*60)*completionRatio
+}
+
+// sessions loads the reading sessions of a user since a time
+func (s *Service) sessions(ctx context.Context, userID uint, since time.Time) ([]community.UserBehavior, error) {
+	var sessions []community.UserBehavior
+	if err := s.db.WithContext(ctx).
+		Select("bookmark_id", "duration", "created_at").
+		Where("user_id = ? AND action_type = ? AND duration > 0 AND created_at >= ?",
+			strconv.FormatUint(uint64(userID), 10), sessionAction, since).
+		Order("created_at").
+		Find(&sessions).Error; err != nil {
+		return nil, fmt.Errorf("failed to load reading sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+func (s *Service) weekly(sessions []community.UserBehavior, weeks int) []Week {
+	weeks = clampWeeks(weeks)
+	start := s.weeksStart(weeks)
+	result := make([]Week, weeks)
+	read := make([]map[uint]bool, weeks)
+	for i := range result {
+		result[i].Start = start.AddDate(0, 0, 7*i)
+		read[i] = map[uint]bool{}
+	}
+	for _, session := range sessions {
+		i := int(session.CreatedAt.UTC().Sub(start) / (7 * 24 * time.Hour))
+		if i < 0 || i >= weeks {
+			continue
+		}
+		result[i].Seconds += session.Duration
+		read[i][session.BookmarkID] = true
+	}
+	for i := range result {
+		result[i].Bookmarks = len(read[i])
+	}
+	return result
+}
+
+func (s *Service) longest(ctx context.Context, userID uint, sessions []community.UserBehavior, limit int) ([]Read, error) {
+	totals := map[uint]*Read{}
+	for _, session := range sessions {
+		read, ok := totals[session.BookmarkID]
+		if !ok {
+			read = &Read{BookmarkID: session.BookmarkID}
+			totals[session.BookmarkID] = read
+		}
+		read.Seconds += session.Duration
+		if session.CreatedAt.After(read.LastReadAt) {
+			read.LastReadAt = session.CreatedAt
+		}
+	}
+
+	reads := make([]Read, 0, len(totals))
+	for _, read := range totals {
+		reads = append(reads, *read)
+	}
+	sort.Slice(reads, func(i, j int) bool {
+		if reads[i].Seconds != reads[j].Seconds {
+			return reads[i].Seconds > reads[j].Seconds
+		}
+		return reads[i].BookmarkID < reads[j].BookmarkID
+	})
+	if len(reads) > limit {
+		reads = reads[:limit]
+	}
+	if len(reads) == 0 {
+		return reads, nil
+	}
+
+	ids := make([]uint, 0, len(reads))
+	for _, read := range reads {
+		ids = append(ids, read.BookmarkID)
+	}
+	var bookmarks []database.Bookmark
+	if err := s.db.WithContext(ctx).Select("id", "title", "url", "reading_time").
+		Where("id IN ? AND user_id = ?", ids, userID).Find(&bookmarks).Error; err != nil {
+		return nil, fmt.Errorf("failed to load read bookmarks: %w", err)
+	}
+	byID := make(map[uint]database.Bookmark, len(bookmarks))
+	for _, bookmark := range bookmarks {
+		byID[bookmark.ID] = bookmark
+	}
+	for i := range reads {
+		bookmark := byID[reads[i].BookmarkID]
+		reads[i].Title = bookmark.Title
+		reads[i].URL = bookmark.URL
+		reads[i].ReadingTime = bookmark.ReadingTime
+	}
+	return reads, nil
+}
+
+// weeksStart returns the Monday that starts the oldest of the last weeks
+func (s *Service) weeksStart(weeks int) time.Time {
+	now := s.now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	monday := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+	return monday.AddDate(0, 0, -7*(clampWeeks(weeks)-1))
+}
+
+func clampWeeks(weeks int) int {
+	if weeks <= 0 {
+		return defaultWeeks
+	}
+	if weeks > maxWeeks {
+		return maxWeeks
+	}
+	return weeks
+}
@@ -0,0 +1,92 @@
+package reading
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bookmark-sync-service/backend/internal/community"
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+func TestReadingStats(t *testing.T) {
+	db, err := database.SetupTestDB()
+	require.NoError(t, err)
+	t.Cleanup(func() { database.CleanupTestDB(db) })
+	require.NoError(t, community.AutoMigrate(db))
+
+	user := database.User{Email: "reader@example.com", Username: "reader", SupabaseID: "reader"}
+	other := database.User{Email: "other@example.com", Username: "other", SupabaseID: "other"}
+	require.NoError(t, db.Create(&user).Error)
+	require.NoError(t, db.Create(&other).Error)
+
+	essay := database.Bookmark{UserID: user.ID, URL: "https://example.com/essay", Title: "Essay", ReadingTime: 10, Tags: `["read-later"]`}
+	paper := database.Bookmark{UserID: user.ID, URL: "https://example.com/paper", Title: "Paper", ReadingTime: 30, Tags: `["read-later","research"]`}
+	imported := database.Bookmark{UserID: user.ID, URL: "https://example.com/imported", Title: "Imported", Metadata: `{"unread":true}`}
+	news := database.Bookmark{UserID: user.ID, URL: "https://example.com/news", Title: "News", ReadingTime: 2}
+	for _, bookmark := range []*database.Bookmark{&essay, &paper, &imported, &news} {
+		require.NoError(t, db.Create(bookmark).Error)
+	}
+
+	// Wednesday; the current week starts Monday 2026-10-12
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	service := NewService(db)
+	service.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	record := func(bookmark database.Bookmark, seconds int, at time.Time) {
+		require.NoError(t, service.RecordSession(ctx, user.ID, bookmark.ID, seconds))
+		require.NoError(t, db.Model(&community.UserBehavior{}).Where("id = (SELECT MAX(id) FROM user_behaviors)").
+			Update("created_at", at).Error)
+	}
+	record(essay, 300, now.Add(-time.Hour))
+	record(essay, 300, now.AddDate(0, 0, -7)) // previous week
+	record(paper, 600, now.Add(-2*time.Hour))
+	record(news, 90, now.Add(-3*time.Hour))
+	record(news, 60, now.AddDate(0, 0, -100)) // outside the last 12 weeks
+
+	assert.ErrorIs(t, service.RecordSession(ctx, other.ID, essay.ID, 60), ErrBookmarkNotFound)
+	assert.Error(t, service.RecordSession(ctx, user.ID, essay.ID, 0))
+
+	stats, err := service.Stats(ctx, user.ID, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 4, stats.Sessions)
+	assert.Equal(t, 1290, stats.TotalSeconds)
+
+	require.Len(t, stats.Weekly, 12)
+	assert.Equal(t, time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC), stats.Weekly[11].Start)
+	assert.Equal(t, Week{Start: stats.Weekly[11].Start, Seconds: 990, Bookmarks: 3}, stats.Weekly[11])
+	assert.Equal(t, 300, stats.Weekly[10].Seconds)
+
+	require.Len(t, stats.Longest, 3)
+	assert.Equal(t, []string{"Essay", "Paper", "News"}, []string{stats.Longest[0].Title, stats.Longest[1].Title, stats.Longest[2].Title})
+	assert.Equal(t, 600, stats.Longest[0].Seconds)
+	assert.Equal(t, 30, stats.Longest[1].ReadingTime)
+
+	// The essay reached its estimate, the paper did not and the imported
+	// bookmark was never opened
+	assert.Equal(t, Completion{Items: 3, Completed: 1, Rate: 1.0 / 3}, stats.Completion)
+
+	unfinished, err := service.Unfinished(ctx, user.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, unfinished, 2)
+	assert.Equal(t, []uint{imported.ID, paper.ID}, []uint{unfinished[0].ID, unfinished[1].ID})
+	unfinished, err = service.Unfinished(ctx, user.ID, 1)
+	require.NoError(t, err)
+	assert.Len(t, unfinished, 1)
+
+	longest, err := service.Longest(ctx, user.ID, 52, 1)
+	require.NoError(t, err)
+	require.Len(t, longest, 1)
+	assert.Equal(t, essay.ID, longest[0].BookmarkID)
+	assert.Equal(t, 600, longest[0].Seconds)
+
+	otherStats, err := service.Stats(ctx, other.ID, 4)
+	require.NoError(t, err)
+	assert.Zero(t, otherStats.TotalSeconds)
+	assert.Len(t, otherStats.Weekly, 4)
+	assert.Empty(t, otherStats.Longest)
+}
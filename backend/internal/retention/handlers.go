@@ -0,0 +1,56 @@
+package retention
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"bookmark-sync-service/backend/pkg/utils"
+)
+
+// Handler serves the retention policy to admins
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new retention handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterAdminRoutes registers retention routes on an admin-only group
+func (h *Handler) RegisterAdminRoutes(router *gin.RouterGroup) {
+	router.GET("/retention", h.GetPolicy)
+	router.POST("/retention/dry-run", h.DryRun)
+}
+
+// GetPolicy returns the retention policy and its last enforcement
+// @Summary Get the data retention policy
+// @Tags admin
+// @Produce json
+// @Success 200 {object} Report
+// @Router /admin/retention [get]
+func (h *Handler) GetPolicy(c *gin.Context) {
+	report, err := h.service.Report(c.Request.Context())
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error(), nil)
+		return
+	}
+	utils.SuccessResponse(c, report, "Retention policy retrieved")
+}
+
+// DryRun reports what enforcing the policy now would delete
+// @Summary Dry-run the data retention policy
+// @Description Counts the rows of each data class past its retention period without deleting them
+// @Tags admin
+// @Produce json
+// @Success 200 {object} Run
+// @Router /admin/retention/dry-run [post]
+func (h *Handler) DryRun(c *gin.Context) {
+	run, err := h.service.Enforce(c.Request.Context(), true)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error(), nil)
+		return
+	}
+	utils.SuccessResponse(c, run, "Retention dry run completed")
+}
@@ -0,0 +1,206 @@
+package retention
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+// Data classes with a retention period
+const (
+	ClassUserBehaviors     = "user_behaviors"
+	ClassWebhookDeliveries = "webhook_deliveries"
+	ClassShareActivities   = "share_activities"
+	ClassSyncEvents        = "sync_events"
+)
+
+// Policy is how long one class of data is kept
+type Policy struct {
+	Class string `json:"class"`
+	Table string `json:"table"`
+	Days  int    `json:"days"` // 0 keeps the class forever
+}
+
+// ClassResult is what a run did, or would do, to one data class
+type ClassResult struct {
+	Class   string     `json:"class"`
+	Days    int        `json:"days"`
+	Cutoff  *time.Time `json:"cutoff,omitempty"`
+	Expired int64      `json:"expired"` // rows past the cutoff
+	Deleted int64      `json:"deleted"` // always 0 in a dry run
+	Skipped string     `json:"skipped,omitempty"`
+	Error   string     `json:"error,omitempty"`
+}
+
+// Run is one enforcement of the retention policy
+type Run struct {
+	database.RetentionRun
+	Results []ClassResult `json:"results"`
+}
+
+// Report is the current policy and its last enforcement
+type Report struct {
+	Policies   []Policy `json:"policies"`
+	Interval   int      `json:"interval"` // minutes between runs, 0 when disabled
+	DryRun     bool     `json:"dry_run"`
+	LastRun    *Run     `json:"last_run"`
+	LastDryRun *Run     `json:"last_dry_run"`
+}
+
+// Service deletes data past its retention period
+type Service struct {
+	cfg    config.RetentionConfig
+	db     *gorm.DB
+	logger *zap.Logger
+	now    func() time.Time
+}
+
+// NewService creates a new retention service
+func NewService(cfg config.RetentionConfig, db *gorm.DB, logger *zap.Logger) *Service {
+	return &Service{cfg: cfg, db: db, logger: logger, now: time.Now}
+}
+
+// Policies returns the retention period of each data class
+func (s *Service) Policies() []Policy {
+	return []Policy{
+		{Class: ClassUserBehaviors, Table: "user_behaviors", Days: s.cfg.UserBehaviors},
+		{Class: ClassWebhookDeliveries, Table: "webhook_deliveries", Days: s.cfg.WebhookDeliveries},
+		{Class: ClassShareActivities, Table: "share_activities", Days: s.cfg.ShareActivities},
+		{Class: ClassSyncEvents, Table: "sync_events", Days: s.cfg.SyncEvents},
+	}
+}
+
+// RunCleanup enforces the policy, as a dry run when configured so. It lets
+// the service back the worker's cleanup job
+func (s *Service) RunCleanup(ctx context.Context) error {
+	run, err := s.Enforce(ctx, s.cfg.DryRun)
+	if err != nil {
+		return err
+	}
+	for _, result := range run.Results {
+		if result.Deleted > 0 || (run.DryRun && result.Expired > 0) {
+			s.logger.Info("Data retention enforced",
+				zap.String("class", result.Class),
+				zap.Int64("expired", result.Expired),
+				zap.Int64("deleted", result.Deleted),
+				zap.Bool("dry_run", run.DryRun))
+		}
+	}
+	if run.Error != "" {
+		return errors.New(run.Error)
+	}
+	return nil
+}
+
+// Enforce deletes the rows of each class older than its retention period,
+// or only counts them in a dry run, and records the run. A class that fails
+// is reported without stopping the others
+func (s *Service) Enforce(ctx context.Context, dryRun bool) (*Run, error) {
+	run := &Run{RetentionRun: database.RetentionRun{DryRun: dryRun, StartedAt: s.now()}}
+
+	var failed []string
+	for _, policy := range s.Policies() {
+		result := s.enforce(ctx, policy, dryRun)
+		if result.Error != "" {
+			failed = append(failed, result.Class)
+		}
+		run.Results = append(run.Results, result)
+	}
+	if len(failed) > 0 {
+		run.Error = fmt.Sprintf("retention failed for %v", failed)
+	}
+	run.FinishedAt = s.now()
+
+	results, err := json.Marshal(run.Results)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode retention results: %w", err)
+	}
+	run.RetentionRun.Results = string(results)
+	if err := s.db.WithContext(ctx).Create(&run.RetentionRun).Error; err != nil {
+		return nil, fmt.Errorf("failed to record retention run: %w", err)
+	}
+	return run, nil
+}
+
+// enforce applies one policy
+func (s *Service) enforce(ctx context.Context, policy Policy, dryRun bool) ClassResult {
+	result := ClassResult{Class: policy.Class, Days: policy.Days}
+	if policy.Days <= 0 {
+		result.Skipped = "kept forever"
+		return result
+	}
+	db := s.db.WithContext(ctx)
+	if !db.Migrator().HasTable(policy.Table) {
+		result.Skipped = "table not found"
+		return result
+	}
+
+	cutoff := s.now().AddDate(0, 0, -policy.Days)
+	result.Cutoff = &cutoff
+	if err := db.Table(policy.Table).Where("created_at < ?", cutoff).Count(&result.Expired).Error; err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if dryRun || result.Expired == 0 {
+		return result
+	}
+
+	batchSize := s.cfg.BatchSize
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+	// Deleting in batches keeps each statement's locks short
+	query := fmt.Sprintf("DELETE FROM %[1]s WHERE id IN (SELECT id FROM %[1]s WHERE created_at < ? LIMIT ?)", policy.Table)
+	for {
+		deleted := db.Exec(query, cutoff, batchSize)
+		if deleted.Error != nil {
+			result.Error = deleted.Error.Error()
+			return result
+		}
+		result.Deleted += deleted.RowsAffected
+		if deleted.RowsAffected < int64(batchSize) || ctx.Err() != nil {
+			return result
+		}
+	}
+}
+
+// LastRun returns the latest run of the given kind, or nil before the first
+func (s *Service) LastRun(ctx context.Context, dryRun bool) (*Run, error) {
+	var last database.RetentionRun
+	err := s.db.WithContext(ctx).Where("dry_run = ?", dryRun).Order("started_at DESC, id DESC").First(&last).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last retention run: %w", err)
+	}
+
+	run := &Run{RetentionRun: last}
+	if last.Results != "" {
+		if err := json.Unmarshal([]byte(last.Results), &run.Results); err != nil {
+			return nil, fmt.Errorf("failed to decode retention results: %w", err)
+		}
+	}
+	return run, nil
+}
+
+// Report returns the current policy and its last enforcement
+func (s *Service) Report(ctx context.Context) (*Report, error) {
+	report := &Report{Policies: s.Policies(), Interval: s.cfg.Interval, DryRun: s.cfg.DryRun}
+	var err error
+	if report.LastRun, err = s.LastRun(ctx, false); err != nil {
+		return nil, err
+	}
+	if report.LastDryRun, err = s.LastRun(ctx, true); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
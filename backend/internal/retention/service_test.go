@@ -0,0 +1,124 @@
+package retention
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/internal/automation"
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+var testConfig = config.RetentionConfig{
+	Interval:          60,
+	BatchSize:         2,
+	UserBehaviors:     180,
+	WebhookDeliveries: 30,
+	ShareActivities:   90,
+	SyncEvents:        0,
+}
+
+func setupService(t *testing.T) (*Service, *gorm.DB, time.Time) {
+	db, err := database.SetupTestDB()
+	require.NoError(t, err)
+	t.Cleanup(func() { database.CleanupTestDB(db) })
+
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	service := NewService(testConfig, db, zap.NewNop())
+	service.now = func() time.Time { return now }
+	return service, db, now
+}
+
+func seed(t *testing.T, db *gorm.DB, now time.Time) {
+	for _, age := range []int{200, 120, 100, 95, 10} {
+		activity := database.ShareActivity{ShareID: 1, ActivityType: "view"}
+		activity.CreatedAt = now.AddDate(0, 0, -age)
+		require.NoError(t, db.Create(&activity).Error)
+	}
+	for _, age := range []int{45, 5} {
+		delivery := automation.WebhookDelivery{EndpointID: 1, Event: automation.WebhookEventBookmarkCreated, Status: "success", CreatedAt: now.AddDate(0, 0, -age)}
+		require.NoError(t, db.Create(&delivery).Error)
+	}
+}
+
+func TestService_Enforce(t *testing.T) {
+	service, db, now := setupService(t)
+	seed(t, db, now)
+	ctx := context.Background()
+
+	results := func(run *Run) map[string]ClassResult {
+		byClass := map[string]ClassResult{}
+		for _, result := range run.Results {
+			byClass[result.Class] = result
+		}
+		return byClass
+	}
+
+	dry, err := service.Enforce(ctx, true)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), results(dry)[ClassShareActivities].Expired)
+	assert.Zero(t, results(dry)[ClassShareActivities].Deleted)
+	var remaining int64
+	db.Unscoped().Model(&database.ShareActivity{}).Count(&remaining)
+	assert.Equal(t, int64(5), remaining, "a dry run deletes nothing")
+
+	run, err := service.Enforce(ctx, false)
+	require.NoError(t, err)
+	byClass := results(run)
+	assert.Equal(t, int64(4), byClass[ClassShareActivities].Deleted, "deleted across batches")
+	assert.Equal(t, int64(1), byClass[ClassWebhookDeliveries].Deleted)
+	assert.Equal(t, "table not found", byClass[ClassUserBehaviors].Skipped)
+	assert.Equal(t, "kept forever", byClass[ClassSyncEvents].Skipped)
+	assert.Empty(t, run.Error)
+
+	db.Unscoped().Model(&database.ShareActivity{}).Count(&remaining)
+	assert.Equal(t, int64(1), remaining)
+
+	report, err := service.Report(ctx)
+	require.NoError(t, err)
+	assert.Len(t, report.Policies, 4)
+	require.NotNil(t, report.LastRun)
+	assert.Equal(t, run.ID, report.LastRun.ID)
+	assert.Equal(t, int64(4), results(report.LastRun)[ClassShareActivities].Deleted)
+	require.NotNil(t, report.LastDryRun)
+	assert.Equal(t, dry.ID, report.LastDryRun.ID)
+}
+
+func TestHandler_Admin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service, db, now := setupService(t)
+	seed(t, db, now)
+
+	router := gin.New()
+	NewHandler(service).RegisterAdminRoutes(router.Group("/admin"))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/retention", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var policy struct {
+		Data Report `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &policy))
+	assert.Nil(t, policy.Data.LastRun)
+	assert.Equal(t, 60, policy.Data.Interval)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/retention/dry-run", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var dry struct {
+		Data Run `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &dry))
+	assert.True(t, dry.Data.DryRun)
+	assert.Len(t, dry.Data.Results, 4)
+}
@@ -0,0 +1,102 @@
+package safety
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"bookmark-sync-service/backend/pkg/middleware"
+	"bookmark-sync-service/backend/pkg/utils"
+)
+
+// Handler serves the instance blocklist to admins
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new URL safety handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterAdminRoutes registers blocklist routes on an admin-only group
+func (h *Handler) RegisterAdminRoutes(router *gin.RouterGroup) {
+	router.GET("/safety/blocklist", h.ListBlockedDomains)
+	router.POST("/safety/blocklist", h.BlockDomain)
+	router.DELETE("/safety/blocklist/:domain", h.UnblockDomain)
+}
+
+// BlockDomainRequest adds a domain to the blocklist
+type BlockDomainRequest struct {
+	Domain string `json:"domain" binding:"required"`
+	Threat string `json:"threat"` // defaults to blocklisted
+	Reason string `json:"reason"`
+}
+
+// ListBlockedDomains lists the instance blocklist
+// @Summary List blocked domains
+// @Tags admin
+// @Produce json
+// @Success 200 {array} database.BlockedDomain
+// @Router /admin/safety/blocklist [get]
+func (h *Handler) ListBlockedDomains(c *gin.Context) {
+	blocked, err := h.service.ListBlockedDomains(c.Request.Context())
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "LIST_FAILED", err.Error(), nil)
+		return
+	}
+	utils.SuccessResponse(c, blocked, "Blocked domains retrieved")
+}
+
+// BlockDomain adds a domain to the instance blocklist
+// @Summary Block a domain
+// @Description Flags bookmarks on the domain and its subdomains, now and when saved later
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param request body BlockDomainRequest true "Domain to block"
+// @Success 201 {object} BlockResult
+// @Router /admin/safety/blocklist [post]
+func (h *Handler) BlockDomain(c *gin.Context) {
+	var req BlockDomainRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid request data", nil)
+		return
+	}
+
+	result, err := h.service.BlockDomain(c.Request.Context(), req.Domain, req.Threat, req.Reason, middleware.GetUserEmail(c))
+	switch {
+	case errors.Is(err, ErrInvalidDomain), errors.Is(err, ErrInvalidThreat):
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error(), nil)
+	case errors.Is(err, ErrDomainAlreadyBlocked):
+		utils.ErrorResponse(c, http.StatusConflict, "ALREADY_BLOCKED", err.Error(), nil)
+	case err != nil:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "BLOCK_FAILED", err.Error(), nil)
+	default:
+		c.JSON(http.StatusCreated, utils.APIResponse{
+			Success: true,
+			Message: "Domain blocked",
+			Data:    result,
+		})
+	}
+}
+
+// UnblockDomain removes a domain from the instance blocklist
+// @Summary Unblock a domain
+// @Tags admin
+// @Produce json
+// @Param domain path string true "Blocked domain"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/safety/blocklist/{domain} [delete]
+func (h *Handler) UnblockDomain(c *gin.Context) {
+	err := h.service.UnblockDomain(c.Request.Context(), c.Param("domain"))
+	switch {
+	case errors.Is(err, ErrDomainNotBlocked):
+		utils.ErrorResponse(c, http.StatusNotFound, "NOT_BLOCKED", err.Error(), nil)
+	case err != nil:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "UNBLOCK_FAILED", err.Error(), nil)
+	default:
+		utils.SuccessResponse(c, gin.H{"domain": c.Param("domain")}, "Domain unblocked")
+	}
+}
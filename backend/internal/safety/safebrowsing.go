@@ -0,0 +1,114 @@
+package safety
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"bookmark-sync-service/backend/internal/config"
+)
+
+// safeBrowsingThreats maps Safe Browsing threat types to ours
+var safeBrowsingThreats = map[string]string{
+	"MALWARE":                         ThreatMalware,
+	"SOCIAL_ENGINEERING":              ThreatSocialEngineering,
+	"UNWANTED_SOFTWARE":               ThreatUnwantedSoftware,
+	"POTENTIALLY_HARMFUL_APPLICATION": ThreatPotentiallyHarmful,
+}
+
+// SafeBrowsingProvider looks URLs up with the Google Safe Browsing v4
+// Lookup API, or any service speaking the same protocol
+type SafeBrowsingProvider struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// NewSafeBrowsingProvider creates a Safe Browsing provider
+func NewSafeBrowsingProvider(cfg config.SafetyConfig) *SafeBrowsingProvider {
+	timeout := time.Duration(cfg.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	return &SafeBrowsingProvider{
+		baseURL:    strings.TrimSuffix(cfg.SafeBrowsingURL, "/"),
+		apiKey:     cfg.SafeBrowsingAPIKey,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+type safeBrowsingEntry struct {
+	URL string `json:"url"`
+}
+
+type safeBrowsingRequest struct {
+	Client struct {
+		ClientID      string `json:"clientId"`
+		ClientVersion string `json:"clientVersion"`
+	} `json:"client"`
+	ThreatInfo struct {
+		ThreatTypes      []string            `json:"threatTypes"`
+		PlatformTypes    []string            `json:"platformTypes"`
+		ThreatEntryTypes []string            `json:"threatEntryTypes"`
+		ThreatEntries    []safeBrowsingEntry `json:"threatEntries"`
+	} `json:"threatInfo"`
+}
+
+type safeBrowsingResponse struct {
+	Matches []struct {
+		ThreatType string            `json:"threatType"`
+		Threat     safeBrowsingEntry `json:"threat"`
+	} `json:"matches"`
+}
+
+// Lookup implements Provider
+func (p *SafeBrowsingProvider) Lookup(ctx context.Context, urls []string) (map[string]string, error) {
+	var body safeBrowsingRequest
+	body.Client.ClientID = "bookmark-sync-service"
+	body.Client.ClientVersion = config.Version
+	for threatType := range safeBrowsingThreats {
+		body.ThreatInfo.ThreatTypes = append(body.ThreatInfo.ThreatTypes, threatType)
+	}
+	body.ThreatInfo.PlatformTypes = []string{"ANY_PLATFORM"}
+	body.ThreatInfo.ThreatEntryTypes = []string{"URL"}
+	for _, u := range urls {
+		body.ThreatInfo.ThreatEntries = append(body.ThreatInfo.ThreatEntries, safeBrowsingEntry{URL: u})
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode safe browsing request: %w", err)
+	}
+	endpoint := p.baseURL + "/v4/threatMatches:find?key=" + url.QueryEscape(p.apiKey)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create safe browsing request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("safe browsing lookup failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("safe browsing lookup failed: HTTP %d", resp.StatusCode)
+	}
+
+	var result safeBrowsingResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode safe browsing response: %w", err)
+	}
+	threats := make(map[string]string, len(result.Matches))
+	for _, match := range result.Matches {
+		if threat, ok := safeBrowsingThreats[match.ThreatType]; ok {
+			threats[match.Threat.URL] = threat
+		}
+	}
+	return threats, nil
+}
@@ -0,0 +1,349 @@
+package safety
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+// Threats a URL can be flagged for
+const (
+	ThreatMalware            = "malware"
+	ThreatSocialEngineering  = "social_engineering"
+	ThreatUnwantedSoftware   = "unwanted_software"
+	ThreatPotentiallyHarmful = "potentially_harmful_application"
+	ThreatBlocklisted        = "blocklisted"
+)
+
+// Verdict sources
+const (
+	SourceBlocklist = "blocklist"
+	SourceProvider  = "provider"
+)
+
+// safeVerdict is cached for URLs the provider did not report
+const safeVerdict = "safe"
+
+var (
+	// ErrInvalidDomain is returned when blocking something that is not a domain name
+	ErrInvalidDomain = errors.New("invalid domain")
+	// ErrInvalidThreat is returned for an unknown threat type
+	ErrInvalidThreat = errors.New("threat must be malware, social_engineering, unwanted_software, potentially_harmful_application or blocklisted")
+	// ErrDomainAlreadyBlocked is returned when blocking a domain twice
+	ErrDomainAlreadyBlocked = errors.New("domain is already blocked")
+	// ErrDomainNotBlocked is returned when unblocking a domain not on the blocklist
+	ErrDomainNotBlocked = errors.New("domain is not blocked")
+)
+
+var validThreats = map[string]bool{
+	ThreatMalware:            true,
+	ThreatSocialEngineering:  true,
+	ThreatUnwantedSoftware:   true,
+	ThreatPotentiallyHarmful: true,
+	ThreatBlocklisted:        true,
+}
+
+// Verdict reports why a URL is unsafe
+type Verdict struct {
+	URL    string `json:"url"`
+	Threat string `json:"threat"`
+	Source string `json:"source"`           // blocklist or provider
+	Domain string `json:"domain,omitempty"` // the blocked domain that matched
+}
+
+// Provider looks URLs up in an external threat list, e.g. Google Safe
+// Browsing. It returns the threat of each unsafe URL and omits safe ones
+type Provider interface {
+	Lookup(ctx context.Context, urls []string) (map[string]string, error)
+}
+
+// Cache keeps provider verdicts between lookups, e.g. the Redis client
+type Cache interface {
+	Get(ctx context.Context, key string) (string, error)
+	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error
+}
+
+// BlockResult is a domain added to the blocklist and how many bookmarks it
+// flagged
+type BlockResult struct {
+	Domain  *database.BlockedDomain `json:"domain"`
+	Flagged int                     `json:"flagged"`
+}
+
+// Service checks URLs against the instance blocklist and an optional
+// provider, and keeps the blocklist
+type Service struct {
+	cfg      config.SafetyConfig
+	db       *gorm.DB
+	provider Provider
+	cache    Cache
+	logger   *zap.Logger
+	now      func() time.Time
+}
+
+// NewService creates a URL safety service with the provider the
+// configuration names
+func NewService(cfg config.SafetyConfig, db *gorm.DB, cache Cache, logger *zap.Logger) *Service {
+	s := &Service{
+		cfg:    cfg,
+		db:     db,
+		cache:  cache,
+		logger: logger,
+		now:    time.Now,
+	}
+	if cfg.Provider == "safe_browsing" {
+		s.provider = NewSafeBrowsingProvider(cfg)
+	}
+	return s
+}
+
+// SetProvider replaces the threat list provider; nil checks the blocklist only
+func (s *Service) SetProvider(provider Provider) {
+	s.provider = provider
+}
+
+// CheckURL returns why a URL is unsafe, or nil when neither the blocklist nor
+// the provider reported it. Provider failures are returned so callers can
+// leave the URL unchecked rather than call it safe
+func (s *Service) CheckURL(ctx context.Context, rawURL string) (*Verdict, error) {
+	host := hostOf(rawURL)
+	if host == "" {
+		return nil, nil
+	}
+
+	blocked, err := s.blockedDomain(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	if blocked != nil {
+		return &Verdict{URL: rawURL, Threat: blocked.Threat, Source: SourceBlocklist, Domain: blocked.Domain}, nil
+	}
+
+	if s.provider == nil {
+		return nil, nil
+	}
+	threat, err := s.lookup(ctx, rawURL)
+	if err != nil || threat == "" {
+		return nil, err
+	}
+	return &Verdict{URL: rawURL, Threat: threat, Source: SourceProvider}, nil
+}
+
+// lookup asks the provider about a URL, through the cache
+func (s *Service) lookup(ctx context.Context, rawURL string) (string, error) {
+	sum := sha256.Sum256([]byte(rawURL))
+	key := config.SafetyVerdictPrefix + ":" + hex.EncodeToString(sum[:16])
+	if s.cache != nil {
+		if cached, err := s.cache.Get(ctx, key); err == nil && cached != "" {
+			if cached == safeVerdict {
+				return "", nil
+			}
+			return cached, nil
+		}
+	}
+
+	threats, err := s.provider.Lookup(ctx, []string{rawURL})
+	if err != nil {
+		return "", err
+	}
+	threat := threats[rawURL]
+
+	if s.cache != nil && s.cfg.CacheTTL > 0 {
+		value := threat
+		if value == "" {
+			value = safeVerdict
+		}
+		if err := s.cache.Set(ctx, key, value, time.Duration(s.cfg.CacheTTL)*time.Minute); err != nil {
+			s.logger.Warn("Failed to cache URL safety verdict", zap.Error(err))
+		}
+	}
+	return threat, nil
+}
+
+// blockedDomain returns the blocklist entry matching a host or one of its
+// parent domains, the most specific first
+func (s *Service) blockedDomain(ctx context.Context, host string) (*database.BlockedDomain, error) {
+	var blocked []database.BlockedDomain
+	if err := s.db.WithContext(ctx).Where("domain IN ?", parentDomains(host)).Find(&blocked).Error; err != nil {
+		return nil, fmt.Errorf("failed to check blocklist: %w", err)
+	}
+	var match *database.BlockedDomain
+	for i := range blocked {
+		if match == nil || len(blocked[i].Domain) > len(match.Domain) {
+			match = &blocked[i]
+		}
+	}
+	return match, nil
+}
+
+// ListBlockedDomains returns the instance blocklist
+func (s *Service) ListBlockedDomains(ctx context.Context) ([]database.BlockedDomain, error) {
+	var blocked []database.BlockedDomain
+	if err := s.db.WithContext(ctx).Order("domain").Find(&blocked).Error; err != nil {
+		return nil, fmt.Errorf("failed to list blocked domains: %w", err)
+	}
+	return blocked, nil
+}
+
+// BlockDomain adds a domain to the instance blocklist and flags the
+// bookmarks already saved on it or its subdomains
+func (s *Service) BlockDomain(ctx context.Context, domain, threat, reason, addedBy string) (*BlockResult, error) {
+	domain = normalizeDomain(domain)
+	if domain == "" {
+		return nil, ErrInvalidDomain
+	}
+	if threat == "" {
+		threat = ThreatBlocklisted
+	}
+	if !validThreats[threat] {
+		return nil, ErrInvalidThreat
+	}
+
+	blocked := &database.BlockedDomain{Domain: domain, Threat: threat, Reason: reason, AddedBy: addedBy}
+	result := &BlockResult{Domain: blocked}
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing int64
+		if err := tx.Model(&database.BlockedDomain{}).Where("domain = ?", domain).Count(&existing).Error; err != nil {
+			return fmt.Errorf("failed to check blocklist: %w", err)
+		}
+		if existing > 0 {
+			return ErrDomainAlreadyBlocked
+		}
+		if err := tx.Create(blocked).Error; err != nil {
+			return fmt.Errorf("failed to block domain: %w", err)
+		}
+
+		ids, err := bookmarksOnDomain(tx, domain)
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+		now := s.now()
+		if err := tx.Model(&database.Bookmark{}).Where("id IN ?", ids).UpdateColumns(map[string]interface{}{
+			"safety_threat":     threat,
+			"safety_checked_at": &now,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to flag bookmarks: %w", err)
+		}
+		result.Flagged = len(ids)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// UnblockDomain removes a domain from the blocklist. Its flagged bookmarks
+// are cleared and left unchecked, so the next link check looks again
+func (s *Service) UnblockDomain(ctx context.Context, domain string) error {
+	domain = normalizeDomain(domain)
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().Where("domain = ?", domain).Delete(&database.BlockedDomain{})
+		if result.Error != nil {
+			return fmt.Errorf("failed to unblock domain: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrDomainNotBlocked
+		}
+
+		ids, err := bookmarksOnDomain(tx, domain)
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+		return tx.Model(&database.Bookmark{}).Where("id IN ? AND safety_threat <> ''", ids).UpdateColumns(map[string]interface{}{
+			"safety_threat":     "",
+			"safety_checked_at": nil,
+		}).Error
+	})
+}
+
+// bookmarksOnDomain returns the IDs of bookmarks whose host is the domain or
+// one of its subdomains
+func bookmarksOnDomain(tx *gorm.DB, domain string) ([]uint, error) {
+	var candidates []struct {
+		ID  uint
+		URL string
+	}
+	if err := tx.Model(&database.Bookmark{}).Select("id, url").
+		Where("LOWER(url) LIKE ?", "%"+domain+"%").
+		Find(&candidates).Error; err != nil {
+		return nil, fmt.Errorf("failed to find bookmarks on domain: %w", err)
+	}
+
+	var ids []uint
+	for _, candidate := range candidates {
+		if host := hostOf(candidate.URL); host == domain || strings.HasSuffix(host, "."+domain) {
+			ids = append(ids, candidate.ID)
+		}
+	}
+	return ids, nil
+}
+
+// hostOf returns the lower-cased host of a URL
+func hostOf(rawURL string) string {
+	parsed, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(strings.ToLower(parsed.Hostname()), ".")
+}
+
+// parentDomains returns a host and the domains above it that still have a
+// dot, e.g. a.b.example.com, b.example.com and example.com
+func parentDomains(host string) []string {
+	domains := []string{host}
+	for {
+		i := strings.Index(host, ".")
+		if i < 0 {
+			break
+		}
+		host = host[i+1:]
+		if !strings.Contains(host, ".") {
+			break
+		}
+		domains = append(domains, host)
+	}
+	return domains
+}
+
+// normalizeDomain reduces admin input such as "https://Evil.example/path" to
+// "evil.example", or "" when it is not a domain name
+func normalizeDomain(domain string) string {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	if strings.Contains(domain, "://") {
+		domain = hostOf(domain)
+	}
+	domain = strings.TrimSuffix(strings.SplitN(domain, "/", 2)[0], ".")
+	if !strings.Contains(domain, ".") || strings.ContainsAny(domain, " :@?#*") || len(domain) > 255 {
+		return ""
+	}
+	return domain
+}
+
+// Warning returns the notice shown next to a flagged link in shared views,
+// or "" when the threat is empty
+func Warning(threat string) string {
+	switch threat {
+	case "":
+		return ""
+	case ThreatMalware:
+		return "This site may install malware"
+	case ThreatSocialEngineering:
+		return "This site may be deceptive"
+	case ThreatUnwantedSoftware, ThreatPotentiallyHarmful:
+		return "This site may install harmful software"
+	default:
+		return "This site was flagged as unsafe"
+	}
+}
@@ -0,0 +1,148 @@
+package safety
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/redis"
+)
+
+type stubProvider struct {
+	threats map[string]string
+	err     error
+	calls   int
+}
+
+func (p *stubProvider) Lookup(_ context.Context, urls []string) (map[string]string, error) {
+	p.calls++
+	if p.err != nil {
+		return nil, p.err
+	}
+	found := map[string]string{}
+	for _, u := range urls {
+		if threat, ok := p.threats[u]; ok {
+			found[u] = threat
+		}
+	}
+	return found, nil
+}
+
+func setupService(t *testing.T) (*Service, *gorm.DB) {
+	mr := miniredis.RunT(t)
+	client, err := redis.NewClient(config.RedisConfig{Host: mr.Host(), Port: mr.Port(), PoolSize: 1})
+	require.NoError(t, err)
+
+	db, err := database.SetupTestDB()
+	require.NoError(t, err)
+	t.Cleanup(func() { database.CleanupTestDB(db) })
+
+	return NewService(config.SafetyConfig{Provider: "none", CacheTTL: 30}, db, client, zap.NewNop()), db
+}
+
+func TestService_Blocklist(t *testing.T) {
+	service, db := setupService(t)
+	ctx := context.Background()
+
+	user := database.User{Email: "user@example.com", Username: "user", SupabaseID: "user"}
+	require.NoError(t, db.Create(&user).Error)
+	for _, u := range []string{"https://login.evil.example/account", "https://evil.example", "https://notevil.example"} {
+		require.NoError(t, db.Create(&database.Bookmark{UserID: user.ID, URL: u, Title: u, Status: "active"}).Error)
+	}
+
+	result, err := service.BlockDomain(ctx, "https://Evil.Example/path", ThreatSocialEngineering, "phishing kit", "admin@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "evil.example", result.Domain.Domain)
+	assert.Equal(t, 2, result.Flagged, "the domain and its subdomains, not lookalikes")
+
+	var flagged int64
+	db.Model(&database.Bookmark{}).Where("safety_threat = ?", ThreatSocialEngineering).Count(&flagged)
+	assert.Equal(t, int64(2), flagged)
+
+	_, err = service.BlockDomain(ctx, "evil.example", "", "", "admin@example.com")
+	assert.ErrorIs(t, err, ErrDomainAlreadyBlocked)
+	_, err = service.BlockDomain(ctx, "localhost", "", "", "admin@example.com")
+	assert.ErrorIs(t, err, ErrInvalidDomain)
+	_, err = service.BlockDomain(ctx, "spam.example", "spam", "", "admin@example.com")
+	assert.ErrorIs(t, err, ErrInvalidThreat)
+
+	verdict, err := service.CheckURL(ctx, "https://a.b.evil.example/x")
+	require.NoError(t, err)
+	require.NotNil(t, verdict)
+	assert.Equal(t, ThreatSocialEngineering, verdict.Threat)
+	assert.Equal(t, SourceBlocklist, verdict.Source)
+	assert.Equal(t, "evil.example", verdict.Domain)
+
+	verdict, err = service.CheckURL(ctx, "https://notevil.example")
+	require.NoError(t, err)
+	assert.Nil(t, verdict)
+
+	require.NoError(t, service.UnblockDomain(ctx, "evil.example"))
+	assert.ErrorIs(t, service.UnblockDomain(ctx, "evil.example"), ErrDomainNotBlocked)
+	db.Model(&database.Bookmark{}).Where("safety_threat <> ''").Count(&flagged)
+	assert.Equal(t, int64(0), flagged)
+
+	_, err = service.BlockDomain(ctx, "evil.example", "", "", "admin@example.com")
+	assert.NoError(t, err, "an unblocked domain can be blocked again")
+}
+
+func TestService_ProviderVerdictsAreCached(t *testing.T) {
+	service, _ := setupService(t)
+	ctx := context.Background()
+	provider := &stubProvider{threats: map[string]string{"https://malware.example/payload": ThreatMalware}}
+	service.SetProvider(provider)
+
+	for i := 0; i < 2; i++ {
+		verdict, err := service.CheckURL(ctx, "https://malware.example/payload")
+		require.NoError(t, err)
+		require.NotNil(t, verdict)
+		assert.Equal(t, ThreatMalware, verdict.Threat)
+		assert.Equal(t, SourceProvider, verdict.Source)
+
+		verdict, err = service.CheckURL(ctx, "https://safe.example")
+		require.NoError(t, err)
+		assert.Nil(t, verdict)
+	}
+	assert.Equal(t, 2, provider.calls, "safe and unsafe verdicts are both cached")
+
+	provider.err = errors.New("unavailable")
+	_, err := service.CheckURL(ctx, "https://other.example")
+	assert.Error(t, err)
+}
+
+func TestSafeBrowsingProvider_Lookup(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v4/threatMatches:find", r.URL.Path)
+		assert.Equal(t, "test-key", r.URL.Query().Get("key"))
+
+		var req safeBrowsingRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Len(t, req.ThreatInfo.ThreatEntries, 2)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"matches":[{"threatType":"SOCIAL_ENGINEERING","platformType":"ANY_PLATFORM","threat":{"url":"https://phish.example/"},"cacheDuration":"300s"}]}`))
+	}))
+	defer server.Close()
+
+	provider := NewSafeBrowsingProvider(config.SafetyConfig{SafeBrowsingURL: server.URL + "/", SafeBrowsingAPIKey: "test-key"})
+	threats, err := provider.Lookup(context.Background(), []string{"https://phish.example/", "https://safe.example/"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"https://phish.example/": ThreatSocialEngineering}, threats)
+}
+
+func TestWarning(t *testing.T) {
+	assert.Empty(t, Warning(""))
+	assert.Equal(t, "This site may be deceptive", Warning(ThreatSocialEngineering))
+	assert.Equal(t, "This site was flagged as unsafe", Warning(ThreatBlocklisted))
+}
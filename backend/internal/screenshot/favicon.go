@@ -0,0 +1,227 @@
+package screenshot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/pkg/database"
+	redispkg "bookmark-sync-service/backend/pkg/redis"
+	"bookmark-sync-service/backend/pkg/worker"
+)
+
+// ErrFaviconNotFound is returned when neither the current favicon nor any
+// common favicon location of the site serves an image
+var ErrFaviconNotFound = errors.New("no working favicon found")
+
+// FaviconFetcher validates favicon URLs and finds replacements, e.g. the
+// screenshot service
+type FaviconFetcher interface {
+	CheckFavicon(ctx context.Context, faviconURL string) error
+	FindFavicon(ctx context.Context, pageURL string) (string, error)
+}
+
+// FaviconResult is the outcome of re-validating a bookmark's favicon
+type FaviconResult struct {
+	BookmarkID uint      `json:"bookmark_id"`
+	Favicon    string    `json:"favicon"`
+	Changed    bool      `json:"changed"` // a replacement was stored
+	CheckedAt  time.Time `json:"checked_at"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// FaviconRefresher keeps bookmark favicons working. A periodic job
+// re-validates the favicons checked longest ago, and users can refresh one
+// bookmark on demand; both count against the same per-domain limit
+type FaviconRefresher struct {
+	db      *gorm.DB
+	fetcher FaviconFetcher
+	queue   JobQueue
+	counter RateCounter
+	cfg     config.FaviconConfig
+	leader  *redispkg.Leader
+	logger  *zap.Logger
+	now     func() time.Time
+}
+
+// NewFaviconRefresher creates a favicon refresher
+func NewFaviconRefresher(db *gorm.DB, fetcher FaviconFetcher, queue JobQueue, counter RateCounter, cfg config.FaviconConfig, logger *zap.Logger) *FaviconRefresher {
+	return &FaviconRefresher{
+		db:      db,
+		fetcher: fetcher,
+		queue:   queue,
+		counter: counter,
+		cfg:     cfg,
+		logger:  logger,
+		now:     time.Now,
+	}
+}
+
+// EnableLeaderElection makes only one replica queue the periodic refresh
+func (r *FaviconRefresher) EnableLeaderElection(client *redispkg.Client) {
+	r.leader = client.NewLeader("favicon-refresh", 2*time.Duration(r.cfg.RefreshInterval)*time.Minute)
+}
+
+// Run queues a refresh of stale favicons on the configured interval until
+// the context is cancelled. It returns immediately when the job is disabled
+func (r *FaviconRefresher) Run(ctx context.Context) {
+	if r.cfg.RefreshInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(r.cfg.RefreshInterval) * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		if r.leads(ctx) {
+			if err := r.queue.Submit(worker.NewFaviconRefreshJob(r, r.logger)); err != nil {
+				r.logger.Warn("Failed to queue favicon refresh", zap.Error(err))
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// leads reports whether this replica should queue the refresh. Without a
+// leader every replica does
+func (r *FaviconRefresher) leads(ctx context.Context) bool {
+	if r.leader == nil {
+		return true
+	}
+	leading, _ := r.leader.Campaign(ctx)
+	return leading
+}
+
+// RefreshStaleFavicons re-validates the favicons of active bookmarks not
+// checked within the configured age, oldest first. Domains over their limit
+// are left for a later run. It returns how many bookmarks were checked
+func (r *FaviconRefresher) RefreshStaleFavicons(ctx context.Context) (int, error) {
+	cutoff := r.now().Add(-time.Duration(r.cfg.MaxAge) * time.Hour)
+	batch := r.cfg.BatchSize
+	if batch <= 0 {
+		batch = 100
+	}
+
+	var bookmarks []database.Bookmark
+	if err := r.db.WithContext(ctx).
+		Where("status = ?", "active").
+		Where("favicon_checked_at IS NULL OR favicon_checked_at < ?", cutoff).
+		Order("favicon_checked_at IS NOT NULL, favicon_checked_at, id").
+		Limit(batch).
+		Find(&bookmarks).Error; err != nil {
+		return 0, fmt.Errorf("failed to find stale favicons: %w", err)
+	}
+
+	checked := 0
+	throttled := map[string]bool{}
+	for i := range bookmarks {
+		if ctx.Err() != nil {
+			return checked, ctx.Err()
+		}
+
+		domain := domainOf(bookmarks[i].URL)
+		if throttled[domain] {
+			continue
+		}
+		if err := r.checkDomainRate(ctx, domain); err != nil {
+			throttled[domain] = true
+			continue
+		}
+
+		if _, err := r.refresh(ctx, &bookmarks[i]); err != nil && !errors.Is(err, ErrFaviconNotFound) {
+			return checked, err
+		}
+		checked++
+	}
+	return checked, nil
+}
+
+// RefreshFavicon re-validates one of the user's bookmark favicons now
+func (r *FaviconRefresher) RefreshFavicon(ctx context.Context, userID, bookmarkID uint) (*FaviconResult, error) {
+	var bookmark database.Bookmark
+	if err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", bookmarkID, userID).First(&bookmark).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBookmarkNotFound
+		}
+		return nil, fmt.Errorf("failed to get bookmark: %w", err)
+	}
+
+	if err := r.checkDomainRate(ctx, domainOf(bookmark.URL)); err != nil {
+		return nil, err
+	}
+	return r.refresh(ctx, &bookmark)
+}
+
+// refresh keeps a favicon that still serves an image, or stores the one
+// found at the site's common favicon locations. When neither works the
+// current favicon is kept and the failure recorded on the bookmark
+func (r *FaviconRefresher) refresh(ctx context.Context, bookmark *database.Bookmark) (*FaviconResult, error) {
+	now := r.now()
+	result := &FaviconResult{BookmarkID: bookmark.ID, Favicon: bookmark.Favicon, CheckedAt: now}
+
+	var findErr error
+	if bookmark.Favicon == "" || r.fetcher.CheckFavicon(ctx, bookmark.Favicon) != nil {
+		var found string
+		found, findErr = r.fetcher.FindFavicon(ctx, bookmark.URL)
+		if findErr == nil && found != bookmark.Favicon {
+			result.Favicon = found
+			result.Changed = true
+		}
+	}
+	if findErr != nil {
+		result.Error = findErr.Error()
+	}
+
+	updates := map[string]interface{}{
+		"favicon":            result.Favicon,
+		"favicon_checked_at": &now,
+		"favicon_error":      result.Error,
+	}
+	query := r.db.WithContext(ctx).Model(bookmark)
+	var err error
+	if result.Changed {
+		// A new favicon is a change clients should sync
+		err = query.Updates(updates).Error
+	} else {
+		err = query.UpdateColumns(updates).Error
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to save favicon check: %w", err)
+	}
+
+	if findErr != nil {
+		return result, fmt.Errorf("%w: %v", ErrFaviconNotFound, findErr)
+	}
+	return result, nil
+}
+
+// checkDomainRate counts a favicon fetch against its domain. Redis errors
+// let the fetch through
+func (r *FaviconRefresher) checkDomainRate(ctx context.Context, domain string) error {
+	if r.counter == nil || r.cfg.DomainChecksPerMinute <= 0 {
+		return nil
+	}
+
+	now := r.now()
+	window := now.Truncate(time.Minute)
+	key := fmt.Sprintf("%s:%s:%d", config.FaviconRatePrefix, domain, window.Unix())
+	count, err := r.counter.IncrementWithExpiration(ctx, key, time.Minute)
+	if err != nil {
+		r.logger.Warn("Failed to count favicon check", zap.Error(err))
+		return nil
+	}
+	if count > int64(r.cfg.DomainChecksPerMinute) {
+		return &RateLimitError{Domain: domain, RetryAfter: window.Add(time.Minute).Sub(now)}
+	}
+	return nil
+}
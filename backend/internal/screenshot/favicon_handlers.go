@@ -0,0 +1,73 @@
+package screenshot
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"bookmark-sync-service/backend/pkg/utils"
+)
+
+// FaviconHandler handles on-demand favicon refreshes
+type FaviconHandler struct {
+	refresher *FaviconRefresher
+}
+
+// NewFaviconHandler creates a new favicon refresh handler
+func NewFaviconHandler(refresher *FaviconRefresher) *FaviconHandler {
+	return &FaviconHandler{refresher: refresher}
+}
+
+// RegisterRoutes registers the favicon refresh route
+func (h *FaviconHandler) RegisterRoutes(router *gin.RouterGroup) {
+	router.POST("/bookmarks/:id/refresh-favicon", h.RefreshFavicon)
+}
+
+// RefreshFavicon re-validates a bookmark's favicon now
+// @Summary Refresh a bookmark favicon
+// @Description Check that the bookmark's favicon still serves an image and look for a replacement at the site's common favicon locations when it does not
+// @Tags screenshots
+// @Produce json
+// @Param id path int true "Bookmark ID"
+// @Success 200 {object} FaviconResult
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 422 {object} utils.ErrorResponse "No working favicon found; the failure is recorded on the bookmark"
+// @Failure 429 {object} utils.ErrorResponse
+// @Router /api/v1/bookmarks/{id}/refresh-favicon [post]
+func (h *FaviconHandler) RefreshFavicon(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid bookmark ID", nil)
+		return
+	}
+
+	result, err := h.refresher.RefreshFavicon(c.Request.Context(), userID, uint(id))
+	if err != nil {
+		var rateErr *RateLimitError
+		switch {
+		case errors.As(err, &rateErr):
+			rateLimited(c, rateErr)
+		case errors.Is(err, ErrBookmarkNotFound):
+			utils.ErrorResponse(c, http.StatusNotFound, "NOT_FOUND", err.Error(), nil)
+		case errors.Is(err, ErrFaviconNotFound):
+			utils.ErrorResponse(c, http.StatusUnprocessableEntity, "FAVICON_NOT_FOUND", ErrFaviconNotFound.Error(), map[string]interface{}{
+				"result": result,
+			})
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to refresh favicon", nil)
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, result, "Favicon refreshed successfully")
+}
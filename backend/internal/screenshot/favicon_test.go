@@ -0,0 +1,223 @@
+package screenshot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/redis"
+	"bookmark-sync-service/backend/pkg/worker"
+)
+
+// stubFavicons serves the favicons in working and finds replacements in found
+type stubFavicons struct {
+	working map[string]bool
+	found   map[string]string // page URL to favicon URL
+	checks  int
+	finds   int
+}
+
+func (f *stubFavicons) CheckFavicon(ctx context.Context, faviconURL string) error {
+	f.checks++
+	if f.working[faviconURL] {
+		return nil
+	}
+	return errors.New("favicon returned HTTP 404")
+}
+
+func (f *stubFavicons) FindFavicon(ctx context.Context, pageURL string) (string, error) {
+	f.finds++
+	if found, ok := f.found[pageURL]; ok {
+		return found, nil
+	}
+	return "", errors.New("favicon not found for " + pageURL)
+}
+
+type faviconEnv struct {
+	refresher *FaviconRefresher
+	db        *gorm.DB
+	fetcher   *stubFavicons
+	queue     *recordingQueue
+	now       time.Time
+}
+
+func setupFaviconRefresher(t *testing.T, perMinute int) *faviconEnv {
+	mr := miniredis.RunT(t)
+	client, err := redis.NewClient(config.RedisConfig{Host: mr.Host(), Port: mr.Port(), PoolSize: 1})
+	require.NoError(t, err)
+
+	db, err := database.SetupTestDB()
+	require.NoError(t, err)
+	t.Cleanup(func() { database.CleanupTestDB(db) })
+
+	env := &faviconEnv{
+		db:      db,
+		fetcher: &stubFavicons{working: map[string]bool{}, found: map[string]string{}},
+		queue:   &recordingQueue{},
+		now:     time.Date(2026, 3, 1, 12, 0, 45, 0, time.UTC),
+	}
+	cfg := config.FaviconConfig{RefreshInterval: 60, MaxAge: 24, BatchSize: 10, DomainChecksPerMinute: perMinute}
+	env.refresher = NewFaviconRefresher(db, env.fetcher, env.queue, client, cfg, zap.NewNop())
+	env.refresher.now = func() time.Time { return env.now }
+	return env
+}
+
+func createFaviconBookmark(t *testing.T, db *gorm.DB, url, favicon string) *database.Bookmark {
+	bookmark := &database.Bookmark{UserID: 1, URL: url, Title: url, Favicon: favicon}
+	require.NoError(t, db.Create(bookmark).Error)
+	return bookmark
+}
+
+func TestFaviconRefresher_RefreshFavicon(t *testing.T) {
+	env := setupFaviconRefresher(t, 10)
+	ctx := context.Background()
+
+	t.Run("Working favicons are kept", func(t *testing.T) {
+		bookmark := createFaviconBookmark(t, env.db, "https://example.com/a", "https://example.com/icon.png")
+		env.fetcher.working["https://example.com/icon.png"] = true
+
+		result, err := env.refresher.RefreshFavicon(ctx, 1, bookmark.ID)
+		require.NoError(t, err)
+		assert.False(t, result.Changed)
+		assert.Equal(t, "https://example.com/icon.png", result.Favicon)
+		assert.Zero(t, env.fetcher.finds)
+
+		var stored database.Bookmark
+		require.NoError(t, env.db.First(&stored, bookmark.ID).Error)
+		require.NotNil(t, stored.FaviconCheckedAt)
+		assert.True(t, stored.FaviconCheckedAt.Equal(env.now))
+	})
+
+	t.Run("Broken favicons are replaced", func(t *testing.T) {
+		bookmark := createFaviconBookmark(t, env.db, "https://go.dev/doc", "https://go.dev/old.ico")
+		env.fetcher.found["https://go.dev/doc"] = "https://go.dev/favicon.ico"
+
+		result, err := env.refresher.RefreshFavicon(ctx, 1, bookmark.ID)
+		require.NoError(t, err)
+		assert.True(t, result.Changed)
+
+		var stored database.Bookmark
+		require.NoError(t, env.db.First(&stored, bookmark.ID).Error)
+		assert.Equal(t, "https://go.dev/favicon.ico", stored.Favicon)
+		assert.Empty(t, stored.FaviconError)
+	})
+
+	t.Run("Failures are recorded and the favicon kept", func(t *testing.T) {
+		bookmark := createFaviconBookmark(t, env.db, "https://gone.example/", "https://gone.example/favicon.ico")
+
+		result, err := env.refresher.RefreshFavicon(ctx, 1, bookmark.ID)
+		assert.ErrorIs(t, err, ErrFaviconNotFound)
+		require.NotNil(t, result)
+		assert.NotEmpty(t, result.Error)
+
+		var stored database.Bookmark
+		require.NoError(t, env.db.First(&stored, bookmark.ID).Error)
+		assert.Equal(t, "https://gone.example/favicon.ico", stored.Favicon)
+		assert.Contains(t, stored.FaviconError, "favicon not found")
+	})
+
+	t.Run("Other users' bookmarks are not found", func(t *testing.T) {
+		bookmark := createFaviconBookmark(t, env.db, "https://example.com/b", "")
+		_, err := env.refresher.RefreshFavicon(ctx, 2, bookmark.ID)
+		assert.ErrorIs(t, err, ErrBookmarkNotFound)
+	})
+}
+
+func TestFaviconRefresher_RefreshStaleFavicons(t *testing.T) {
+	env := setupFaviconRefresher(t, 2)
+	ctx := context.Background()
+
+	fresh := createFaviconBookmark(t, env.db, "https://fresh.example/", "https://fresh.example/favicon.ico")
+	checkedAt := env.now.Add(-time.Hour)
+	require.NoError(t, env.db.Model(fresh).UpdateColumn("favicon_checked_at", &checkedAt).Error)
+	broken := createFaviconBookmark(t, env.db, "https://broken.example/", "")
+	require.NoError(t, env.db.Model(broken).UpdateColumn("status", "broken").Error)
+	for _, url := range []string{"https://example.com/1", "https://example.com/2", "https://example.com/3"} {
+		createFaviconBookmark(t, env.db, url, "")
+		env.fetcher.found[url] = "https://example.com/favicon.ico"
+	}
+
+	checked, err := env.refresher.RefreshStaleFavicons(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, checked, "the third example.com bookmark waits for the domain's limit")
+	assert.Equal(t, 2, env.fetcher.finds)
+
+	var pending int64
+	env.db.Model(&database.Bookmark{}).Where("favicon_checked_at IS NULL AND status = ?", "active").Count(&pending)
+	assert.Equal(t, int64(1), pending)
+
+	// The next minute picks up the rest; fresh and broken bookmarks are skipped
+	env.now = env.now.Add(time.Minute)
+	checked, err = env.refresher.RefreshStaleFavicons(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, checked)
+}
+
+func TestFaviconRefresher_RunQueuesJob(t *testing.T) {
+	env := setupFaviconRefresher(t, 10)
+	createFaviconBookmark(t, env.db, "https://example.com/", "")
+	env.fetcher.found["https://example.com/"] = "https://example.com/favicon.ico"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	env.refresher.Run(ctx)
+
+	require.Len(t, env.queue.jobs, 1)
+	job, ok := env.queue.jobs[0].(*worker.FaviconRefreshJob)
+	require.True(t, ok)
+	assert.Equal(t, "favicon_refresh", job.LockName())
+	require.NoError(t, job.Execute(context.Background()))
+	assert.Equal(t, 1, env.fetcher.finds)
+}
+
+func TestFaviconHandler_Routes(t *testing.T) {
+	env := setupFaviconRefresher(t, 1)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", "1")
+		c.Next()
+	})
+	NewFaviconHandler(env.refresher).RegisterRoutes(router.Group("/api/v1"))
+
+	working := createFaviconBookmark(t, env.db, "https://example.com/a", "https://example.com/favicon.ico")
+	env.fetcher.working["https://example.com/favicon.ico"] = true
+	limited := createFaviconBookmark(t, env.db, "https://example.com/b", "")
+	missing := createFaviconBookmark(t, env.db, "https://gone.example/", "")
+
+	post := func(id uint) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/bookmarks/"+strconv.FormatUint(uint64(id), 10)+"/refresh-favicon", nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post(working.ID)
+	require.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Data FaviconResult `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "https://example.com/favicon.ico", response.Data.Favicon)
+
+	w = post(limited.ID)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "15", w.Header().Get("Retry-After"))
+
+	assert.Equal(t, http.StatusUnprocessableEntity, post(missing.ID).Code)
+	assert.Equal(t, http.StatusNotFound, post(9999).Code)
+}
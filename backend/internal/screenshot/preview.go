@@ -0,0 +1,208 @@
+package screenshot
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/jpeg" // screenshots may be stored as JPEG
+	"image/png"
+	"io"
+	"net/http"
+)
+
+// Preview image modes. Blur and crop keep page content out of link previews
+const (
+	PreviewFull = "full"
+	PreviewBlur = "blur"
+	PreviewCrop = "crop"
+)
+
+// Link preview size, the 1.91:1 ratio social sites crop og:image to
+const (
+	PreviewWidth  = 1200
+	PreviewHeight = 630
+)
+
+const (
+	// previewBlurFactor is how much a preview is shrunk before being scaled
+	// back up; text and faces are not recognizable at 1/24 size
+	previewBlurFactor = 24
+	// previewCropHeight is the part of a cropped preview left showing,
+	// usually the page header with the site's name and logo
+	previewCropHeight = PreviewHeight / 4
+	// maxScreenshotSize bounds the screenshots downloaded for previews and
+	// exports
+	maxScreenshotSize = 20 << 20
+)
+
+// previewBackground fills the part of a cropped preview that is left out
+var previewBackground = color.RGBA{R: 0xe5, G: 0xe7, B: 0xeb, A: 0xff}
+
+// RenderPreview renders the screenshot at screenshotURL as a link preview
+// PNG. Blurred previews are scaled down and up again, cropped ones only show
+// the top of the page
+func (s *Service) RenderPreview(ctx context.Context, screenshotURL, mode string) ([]byte, error) {
+	if mode != PreviewFull && mode != PreviewBlur && mode != PreviewCrop {
+		return nil, fmt.Errorf("unknown preview mode %q", mode)
+	}
+
+	data, _, err := s.FetchScreenshot(ctx, screenshotURL)
+	if err != nil {
+		return nil, err
+	}
+	source, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode screenshot: %w", err)
+	}
+
+	preview := ProcessPreview(source, mode)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, preview); err != nil {
+		return nil, fmt.Errorf("failed to encode preview: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// FetchScreenshot downloads a stored screenshot and returns it with its
+// content type, e.g. for exports that include screenshots
+func (s *Service) FetchScreenshot(ctx context.Context, screenshotURL string) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, screenshotURL, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid screenshot URL: %w", err)
+	}
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to download screenshot: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("failed to download screenshot: status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxScreenshotSize+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to download screenshot: %w", err)
+	}
+	if len(data) > maxScreenshotSize {
+		return nil, "", fmt.Errorf("screenshot is larger than %d bytes", maxScreenshotSize)
+	}
+	return data, http.DetectContentType(data), nil
+}
+
+// ProcessPreview fits a screenshot to the preview size, keeping its top
+// since that is what a page shows first, and applies the mode
+func ProcessPreview(source image.Image, mode string) *image.RGBA {
+	preview := cover(source, PreviewWidth, PreviewHeight)
+
+	switch mode {
+	case PreviewBlur:
+		small := resize(preview, PreviewWidth/previewBlurFactor, PreviewHeight/previewBlurFactor)
+		preview = resize(small, PreviewWidth, PreviewHeight)
+	case PreviewCrop:
+		hidden := image.Rect(0, previewCropHeight, PreviewWidth, PreviewHeight)
+		draw.Draw(preview, hidden, image.NewUniform(previewBackground), image.Point{}, draw.Src)
+	}
+	return preview
+}
+
+// cover scales source to fill width x height and cuts off what is left
+// over, from the bottom and both sides
+func cover(source image.Image, width, height int) *image.RGBA {
+	bounds := source.Bounds()
+	if bounds.Empty() {
+		return image.NewRGBA(image.Rect(0, 0, width, height))
+	}
+
+	// The part of the source with the target's aspect ratio
+	part := bounds
+	if bounds.Dx()*height > bounds.Dy()*width {
+		w := bounds.Dy() * width / height
+		part.Min.X += (bounds.Dx() - w) / 2
+		part.Max.X = part.Min.X + w
+	} else {
+		part.Max.Y = part.Min.Y + bounds.Dx()*height/width
+	}
+	if part.Empty() {
+		part = bounds
+	}
+
+	return resizeRect(source, part, width, height)
+}
+
+func resize(source image.Image, width, height int) *image.RGBA {
+	return resizeRect(source, source.Bounds(), width, height)
+}
+
+// resizeRect scales a part of source to width x height, averaging the source
+// pixels covered by each target pixel when shrinking and interpolating
+// between them when enlarging
+func resizeRect(source image.Image, part image.Rectangle, width, height int) *image.RGBA {
+	target := image.NewRGBA(image.Rect(0, 0, width, height))
+	scaleX := float64(part.Dx()) / float64(width)
+	scaleY := float64(part.Dy()) / float64(height)
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var c color.RGBA
+			if scaleX > 1 || scaleY > 1 {
+				c = average(source, image.Rect(
+					part.Min.X+int(float64(x)*scaleX),
+					part.Min.Y+int(float64(y)*scaleY),
+					part.Min.X+max(int(float64(x+1)*scaleX), int(float64(x)*scaleX)+1),
+					part.Min.Y+max(int(float64(y+1)*scaleY), int(float64(y)*scaleY)+1),
+				).Intersect(part))
+			} else {
+				c = bilinear(source, part, (float64(x)+0.5)*scaleX-0.5, (float64(y)+0.5)*scaleY-0.5)
+			}
+			target.SetRGBA(x, y, c)
+		}
+	}
+	return target
+}
+
+// average returns the mean color of the pixels in r
+func average(source image.Image, r image.Rectangle) color.RGBA {
+	var red, green, blue, alpha, n uint64
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			cr, cg, cb, ca := source.At(x, y).RGBA()
+			red, green, blue, alpha = red+uint64(cr), green+uint64(cg), blue+uint64(cb), alpha+uint64(ca)
+			n++
+		}
+	}
+	if n == 0 {
+		return color.RGBA{}
+	}
+	return color.RGBA{
+		R: uint8(red / n >> 8),
+		G: uint8(green / n >> 8),
+		B: uint8(blue / n >> 8),
+		A: uint8(alpha / n >> 8),
+	}
+}
+
+// bilinear samples source at a fractional position relative to part
+func bilinear(source image.Image, part image.Rectangle, fx, fy float64) color.RGBA {
+	fx = min(max(fx, 0), float64(part.Dx()-1))
+	fy = min(max(fy, 0), float64(part.Dy()-1))
+	x0, y0 := int(fx), int(fy)
+	x1, y1 := min(x0+1, part.Dx()-1), min(y0+1, part.Dy()-1)
+	dx, dy := fx-float64(x0), fy-float64(y0)
+
+	at := func(x, y int) [4]float64 {
+		r, g, b, a := source.At(part.Min.X+x, part.Min.Y+y).RGBA()
+		return [4]float64{float64(r), float64(g), float64(b), float64(a)}
+	}
+	c00, c10, c01, c11 := at(x0, y0), at(x1, y0), at(x0, y1), at(x1, y1)
+
+	var out [4]uint8
+	for i := range out {
+		top := c00[i]*(1-dx) + c10[i]*dx
+		bottom := c01[i]*(1-dx) + c11[i]*dx
+		out[i] = uint8(uint32(top*(1-dy)+bottom*dy) >> 8)
+	}
+	return color.RGBA{R: out[0], G: out[1], B: out[2], A: out[3]}
+}
@@ -0,0 +1,93 @@
+package screenshot
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stripedPage is a page screenshot with a dark header and alternating black
+// and white lines of "text" below it
+func stripedPage(width, height int) *image.RGBA {
+	page := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := color.RGBA{A: 0xff}
+			switch {
+			case y < height/10:
+				c = color.RGBA{R: 0x20, G: 0x40, B: 0x80, A: 0xff}
+			case y%4 < 2:
+				c = color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+			}
+			page.SetRGBA(x, y, c)
+		}
+	}
+	return page
+}
+
+func TestProcessPreview(t *testing.T) {
+	page := stripedPage(1600, 2400)
+
+	t.Run("full keeps the top of the page", func(t *testing.T) {
+		preview := ProcessPreview(page, PreviewFull)
+		assert.Equal(t, image.Rect(0, 0, PreviewWidth, PreviewHeight), preview.Bounds())
+		assert.Equal(t, color.RGBA{R: 0x20, G: 0x40, B: 0x80, A: 0xff}, preview.RGBAAt(600, 5))
+	})
+
+	t.Run("blur averages the text away", func(t *testing.T) {
+		preview := ProcessPreview(page, PreviewBlur)
+		assert.Equal(t, image.Rect(0, 0, PreviewWidth, PreviewHeight), preview.Bounds())
+		// Neighbouring lines of text come out the same shade of grey
+		a, b := preview.RGBAAt(600, 400), preview.RGBAAt(600, 401)
+		assert.InDelta(t, int(a.R), int(b.R), 2)
+		assert.InDelta(t, 0x80, int(a.R), 0x40, "neither black nor white")
+	})
+
+	t.Run("crop hides everything below the header", func(t *testing.T) {
+		preview := ProcessPreview(page, PreviewCrop)
+		assert.Equal(t, color.RGBA{R: 0x20, G: 0x40, B: 0x80, A: 0xff}, preview.RGBAAt(600, 5))
+		for _, y := range []int{previewCropHeight, 400, PreviewHeight - 1} {
+			assert.Equal(t, previewBackground, preview.RGBAAt(600, y))
+		}
+	})
+
+	t.Run("tiny images are scaled up", func(t *testing.T) {
+		preview := ProcessPreview(stripedPage(1, 1), PreviewFull)
+		assert.Equal(t, image.Rect(0, 0, PreviewWidth, PreviewHeight), preview.Bounds())
+	})
+}
+
+func TestRenderPreview(t *testing.T) {
+	var screenshot bytes.Buffer
+	require.NoError(t, png.Encode(&screenshot, stripedPage(400, 300)))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/page.png" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(screenshot.Bytes())
+	}))
+	defer server.Close()
+
+	service := NewService(nil)
+
+	data, err := service.RenderPreview(context.Background(), server.URL+"/page.png", PreviewBlur)
+	require.NoError(t, err)
+	preview, err := png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, PreviewWidth, PreviewHeight), preview.Bounds())
+
+	_, err = service.RenderPreview(context.Background(), server.URL+"/missing.png", PreviewBlur)
+	assert.ErrorContains(t, err, "status 404")
+
+	_, err = service.RenderPreview(context.Background(), server.URL+"/page.png", "sepia")
+	assert.ErrorContains(t, err, "unknown preview mode")
+}
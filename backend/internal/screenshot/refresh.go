@@ -0,0 +1,247 @@
+package screenshot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/worker"
+)
+
+// Screenshot job statuses
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobCompleted = "completed"
+	JobFailed    = "failed"
+)
+
+// maxRefreshAttempts is the first capture plus the worker's retries
+const maxRefreshAttempts = 3
+
+var (
+	// ErrBookmarkNotFound is returned when refreshing a bookmark the user does not own
+	ErrBookmarkNotFound = errors.New("bookmark not found")
+	// ErrJobNotFound is returned for a job none of the user's bookmarks wait on
+	ErrJobNotFound = errors.New("screenshot job not found")
+	// ErrQueueUnavailable is returned when the capture queue does not take the job
+	ErrQueueUnavailable = errors.New("screenshot queue is unavailable")
+)
+
+// RateLimitError is returned when a domain had too many refreshes this minute
+type RateLimitError struct {
+	Domain     string
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("too many refreshes for %s", e.Domain)
+}
+
+// JobQueue runs capture jobs, e.g. the worker pool
+type JobQueue interface {
+	Submit(job worker.Job) error
+}
+
+// RateCounter counts refreshes per domain across replicas, e.g. the Redis client
+type RateCounter interface {
+	IncrementWithExpiration(ctx context.Context, key string, expiration time.Duration) (int64, error)
+}
+
+// Capturer takes a screenshot of a page and stores it
+type Capturer interface {
+	UpdateBookmarkScreenshot(ctx context.Context, bookmarkID, pageURL string) (*CaptureResult, error)
+}
+
+// RefreshResult is the job a refresh request was queued on
+type RefreshResult struct {
+	Job       *database.ScreenshotJob `json:"job"`
+	Coalesced bool                    `json:"coalesced"` // joined a job already pending for the URL
+	StatusURL string                  `json:"status_url"`
+}
+
+// Refresher queues on-demand screenshot re-captures. Requests for a URL that
+// already has a pending job share it, whoever's bookmark they come from
+type Refresher struct {
+	db       *gorm.DB
+	capturer Capturer
+	queue    JobQueue
+	counter  RateCounter
+	cfg      config.ScreenshotConfig
+	logger   *zap.Logger
+	now      func() time.Time
+}
+
+// NewRefresher creates a screenshot refresher
+func NewRefresher(db *gorm.DB, capturer Capturer, queue JobQueue, counter RateCounter, cfg config.ScreenshotConfig, logger *zap.Logger) *Refresher {
+	return &Refresher{
+		db:       db,
+		capturer: capturer,
+		queue:    queue,
+		counter:  counter,
+		cfg:      cfg,
+		logger:   logger,
+		now:      time.Now,
+	}
+}
+
+// RequestRefresh queues a re-capture of a bookmark's page, or joins the job
+// already pending for its URL. New jobs count against the domain's limit
+func (r *Refresher) RequestRefresh(ctx context.Context, userID, bookmarkID uint) (*RefreshResult, error) {
+	var bookmark database.Bookmark
+	if err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", bookmarkID, userID).First(&bookmark).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBookmarkNotFound
+		}
+		return nil, fmt.Errorf("failed to get bookmark: %w", err)
+	}
+
+	result := &RefreshResult{}
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var pending database.ScreenshotJob
+		err := tx.Where("url = ? AND status IN ?", bookmark.URL, []string{JobQueued, JobRunning}).
+			Order("id").First(&pending).Error
+		if err == nil {
+			result.Job = &pending
+			result.Coalesced = true
+			return tx.Model(&pending).Association("Bookmarks").Append(&bookmark)
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("failed to find pending screenshot job: %w", err)
+		}
+
+		domain := domainOf(bookmark.URL)
+		if err := r.checkDomainRate(ctx, domain); err != nil {
+			return err
+		}
+
+		job := &database.ScreenshotJob{URL: bookmark.URL, Domain: domain, Status: JobQueued}
+		if err := tx.Create(job).Error; err != nil {
+			return fmt.Errorf("failed to create screenshot job: %w", err)
+		}
+		result.Job = job
+		return tx.Model(job).Association("Bookmarks").Append(&bookmark)
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.StatusURL = fmt.Sprintf("/api/v1/screenshots/jobs/%d", result.Job.ID)
+
+	if result.Coalesced {
+		return result, nil
+	}
+	if err := r.queue.Submit(worker.NewScreenshotRefreshJob(result.Job.ID, r, r.logger)); err != nil {
+		r.logger.Warn("Failed to queue screenshot refresh", zap.Uint("screenshot_job_id", result.Job.ID), zap.Error(err))
+		r.db.Model(result.Job).Updates(map[string]interface{}{"status": JobFailed, "error": err.Error()})
+		return nil, ErrQueueUnavailable
+	}
+	return result, nil
+}
+
+// checkDomainRate counts a new job against its domain. Redis errors let the
+// refresh through
+func (r *Refresher) checkDomainRate(ctx context.Context, domain string) error {
+	if r.counter == nil || r.cfg.DomainRefreshesPerMinute <= 0 {
+		return nil
+	}
+
+	now := r.now()
+	window := now.Truncate(time.Minute)
+	key := fmt.Sprintf("%s:%s:%d", config.ScreenshotRatePrefix, domain, window.Unix())
+	count, err := r.counter.IncrementWithExpiration(ctx, key, time.Minute)
+	if err != nil {
+		r.logger.Warn("Failed to count screenshot refresh", zap.Error(err))
+		return nil
+	}
+	if count > int64(r.cfg.DomainRefreshesPerMinute) {
+		return &RateLimitError{Domain: domain, RetryAfter: window.Add(time.Minute).Sub(now)}
+	}
+	return nil
+}
+
+// RunRefresh captures the page of a screenshot job and points every bookmark
+// waiting on it at the new screenshot. Failed captures go back to queued
+// while the worker still retries them
+func (r *Refresher) RunRefresh(ctx context.Context, jobID uint) error {
+	var job database.ScreenshotJob
+	if err := r.db.WithContext(ctx).First(&job, jobID).Error; err != nil {
+		return fmt.Errorf("failed to get screenshot job: %w", err)
+	}
+	if job.Status == JobCompleted || job.Status == JobFailed {
+		return nil
+	}
+
+	job.Attempts++
+	if err := r.db.WithContext(ctx).Model(&job).Updates(map[string]interface{}{"status": JobRunning, "attempts": job.Attempts}).Error; err != nil {
+		return fmt.Errorf("failed to start screenshot job: %w", err)
+	}
+
+	capture, err := r.capturer.UpdateBookmarkScreenshot(ctx, fmt.Sprintf("refresh-%d", job.ID), job.URL)
+	if err != nil {
+		status := JobQueued
+		if job.Attempts >= maxRefreshAttempts {
+			status = JobFailed
+		}
+		r.db.Model(&job).Updates(map[string]interface{}{"status": status, "error": err.Error()})
+		return err
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := r.now()
+		if err := tx.Model(&job).Updates(map[string]interface{}{
+			"status":         JobCompleted,
+			"screenshot_url": capture.URL,
+			"error":          "",
+			"completed_at":   &now,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to complete screenshot job: %w", err)
+		}
+
+		var bookmarkIDs []uint
+		if err := tx.Table("screenshot_job_bookmarks").Where("screenshot_job_id = ?", job.ID).
+			Pluck("bookmark_id", &bookmarkIDs).Error; err != nil {
+			return fmt.Errorf("failed to find waiting bookmarks: %w", err)
+		}
+		if len(bookmarkIDs) == 0 {
+			return nil
+		}
+		if err := tx.Model(&database.Bookmark{}).Where("id IN ?", bookmarkIDs).Update("screenshot", capture.URL).Error; err != nil {
+			return fmt.Errorf("failed to update bookmark screenshots: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetJob returns a screenshot job one of the user's bookmarks waits on
+func (r *Refresher) GetJob(ctx context.Context, userID, jobID uint) (*database.ScreenshotJob, error) {
+	var job database.ScreenshotJob
+	err := r.db.WithContext(ctx).
+		Joins("JOIN screenshot_job_bookmarks ON screenshot_job_bookmarks.screenshot_job_id = screenshot_jobs.id").
+		Joins("JOIN bookmarks ON bookmarks.id = screenshot_job_bookmarks.bookmark_id").
+		Where("screenshot_jobs.id = ? AND bookmarks.user_id = ?", jobID, userID).
+		First(&job).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrJobNotFound
+		}
+		return nil, fmt.Errorf("failed to get screenshot job: %w", err)
+	}
+	return &job, nil
+}
+
+// domainOf returns the lower-cased host of a URL without a leading www.
+func domainOf(pageURL string) string {
+	parsed, err := url.Parse(pageURL)
+	if err != nil || parsed.Hostname() == "" {
+		return pageURL
+	}
+	return strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+}
@@ -0,0 +1,125 @@
+package screenshot
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"bookmark-sync-service/backend/pkg/utils"
+)
+
+// RefreshHandler handles on-demand screenshot refreshes
+type RefreshHandler struct {
+	refresher *Refresher
+}
+
+// NewRefreshHandler creates a new screenshot refresh handler
+func NewRefreshHandler(refresher *Refresher) *RefreshHandler {
+	return &RefreshHandler{refresher: refresher}
+}
+
+// RegisterRoutes registers the refresh and job status routes
+func (h *RefreshHandler) RegisterRoutes(router *gin.RouterGroup) {
+	router.POST("/bookmarks/:id/refresh-screenshot", h.RefreshScreenshot)
+	router.GET("/screenshots/jobs/:id", h.GetJob)
+}
+
+// RefreshScreenshot queues a re-capture of a bookmark's page
+// @Summary Refresh a bookmark screenshot
+// @Description Queue a re-capture of the bookmarked page. Requests for a URL with a pending capture join it
+// @Tags screenshots
+// @Produce json
+// @Param id path int true "Bookmark ID"
+// @Success 202 {object} RefreshResult
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 429 {object} utils.ErrorResponse
+// @Failure 503 {object} utils.ErrorResponse
+// @Router /api/v1/bookmarks/{id}/refresh-screenshot [post]
+func (h *RefreshHandler) RefreshScreenshot(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid bookmark ID", nil)
+		return
+	}
+
+	result, err := h.refresher.RequestRefresh(c.Request.Context(), userID, uint(id))
+	if err != nil {
+		var rateErr *RateLimitError
+		switch {
+		case errors.As(err, &rateErr):
+			rateLimited(c, rateErr)
+		case errors.Is(err, ErrBookmarkNotFound):
+			utils.ErrorResponse(c, http.StatusNotFound, "NOT_FOUND", err.Error(), nil)
+		case errors.Is(err, ErrQueueUnavailable):
+			utils.ErrorResponse(c, http.StatusServiceUnavailable, "QUEUE_UNAVAILABLE", err.Error(), nil)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to refresh screenshot", nil)
+		}
+		return
+	}
+
+	c.Header("Location", result.StatusURL)
+	c.JSON(http.StatusAccepted, utils.APIResponse{
+		Success:   true,
+		Message:   "Screenshot refresh queued",
+		Data:      result,
+		RequestID: c.GetString("request_id"),
+	})
+}
+
+// GetJob reports the status of a screenshot job
+// @Summary Get screenshot job status
+// @Description Get the status of a screenshot job one of the user's bookmarks waits on
+// @Tags screenshots
+// @Produce json
+// @Param id path int true "Job ID"
+// @Success 200 {object} database.ScreenshotJob
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /api/v1/screenshots/jobs/{id} [get]
+func (h *RefreshHandler) GetJob(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid job ID", nil)
+		return
+	}
+
+	job, err := h.refresher.GetJob(c.Request.Context(), userID, uint(id))
+	if err != nil {
+		if errors.Is(err, ErrJobNotFound) {
+			utils.ErrorResponse(c, http.StatusNotFound, "NOT_FOUND", err.Error(), nil)
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to get screenshot job", nil)
+		return
+	}
+
+	utils.SuccessResponse(c, job, "Screenshot job retrieved successfully")
+}
+
+// rateLimited responds 429 with when the domain's limit resets
+func rateLimited(c *gin.Context, rateErr *RateLimitError) {
+	retryAfter := int(math.Ceil(rateErr.RetryAfter.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	utils.ErrorResponse(c, http.StatusTooManyRequests, "RATE_LIMITED", rateErr.Error(), map[string]interface{}{
+		"retry_after": retryAfter,
+	})
+}
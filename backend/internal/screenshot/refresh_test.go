@@ -0,0 +1,232 @@
+package screenshot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/redis"
+	"bookmark-sync-service/backend/pkg/worker"
+)
+
+// recordingQueue keeps submitted jobs instead of running them
+type recordingQueue struct {
+	jobs []worker.Job
+	err  error
+}
+
+func (q *recordingQueue) Submit(job worker.Job) error {
+	if q.err != nil {
+		return q.err
+	}
+	q.jobs = append(q.jobs, job)
+	return nil
+}
+
+type stubCapturer struct {
+	calls int
+	err   error
+}
+
+func (c *stubCapturer) UpdateBookmarkScreenshot(ctx context.Context, bookmarkID, pageURL string) (*CaptureResult, error) {
+	c.calls++
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &CaptureResult{URL: "https://cdn.example.com/" + bookmarkID + ".jpg"}, nil
+}
+
+type refreshEnv struct {
+	refresher *Refresher
+	db        *gorm.DB
+	queue     *recordingQueue
+	capturer  *stubCapturer
+}
+
+func setupRefresher(t *testing.T, perMinute int) *refreshEnv {
+	mr := miniredis.RunT(t)
+	client, err := redis.NewClient(config.RedisConfig{Host: mr.Host(), Port: mr.Port(), PoolSize: 1})
+	require.NoError(t, err)
+
+	db, err := database.SetupTestDB()
+	require.NoError(t, err)
+	t.Cleanup(func() { database.CleanupTestDB(db) })
+
+	env := &refreshEnv{db: db, queue: &recordingQueue{}, capturer: &stubCapturer{}}
+	env.refresher = NewRefresher(db, env.capturer, env.queue, client, config.ScreenshotConfig{DomainRefreshesPerMinute: perMinute}, zap.NewNop())
+	now := time.Date(2026, 3, 1, 12, 0, 45, 0, time.UTC)
+	env.refresher.now = func() time.Time { return now }
+	return env
+}
+
+func createBookmark(t *testing.T, db *gorm.DB, userID uint, url string) *database.Bookmark {
+	bookmark := &database.Bookmark{UserID: userID, URL: url, Title: url}
+	require.NoError(t, db.Create(bookmark).Error)
+	return bookmark
+}
+
+func TestRefresher_CoalescesRequestsForOneURL(t *testing.T) {
+	env := setupRefresher(t, 10)
+	ctx := context.Background()
+
+	mine := createBookmark(t, env.db, 1, "https://example.com/page")
+	theirs := createBookmark(t, env.db, 2, "https://example.com/page")
+
+	first, err := env.refresher.RequestRefresh(ctx, 1, mine.ID)
+	require.NoError(t, err)
+	assert.False(t, first.Coalesced)
+	assert.Equal(t, "example.com", first.Job.Domain)
+	assert.Equal(t, JobQueued, first.Job.Status)
+	assert.Equal(t, "/api/v1/screenshots/jobs/1", first.StatusURL)
+
+	second, err := env.refresher.RequestRefresh(ctx, 2, theirs.ID)
+	require.NoError(t, err)
+	assert.True(t, second.Coalesced)
+	assert.Equal(t, first.Job.ID, second.Job.ID)
+	require.Len(t, env.queue.jobs, 1, "one capture for both users")
+
+	// Running the job updates every bookmark that waited on it
+	require.NoError(t, env.queue.jobs[0].Execute(ctx))
+	assert.Equal(t, 1, env.capturer.calls)
+	for _, id := range []uint{mine.ID, theirs.ID} {
+		var bookmark database.Bookmark
+		require.NoError(t, env.db.First(&bookmark, id).Error)
+		assert.Equal(t, "https://cdn.example.com/refresh-1.jpg", bookmark.Screenshot)
+	}
+
+	job, err := env.refresher.GetJob(ctx, 2, first.Job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, JobCompleted, job.Status)
+	assert.NotNil(t, job.CompletedAt)
+	_, err = env.refresher.GetJob(ctx, 3, first.Job.ID)
+	assert.ErrorIs(t, err, ErrJobNotFound)
+
+	// Once done, the next request starts a new capture
+	third, err := env.refresher.RequestRefresh(ctx, 1, mine.ID)
+	require.NoError(t, err)
+	assert.False(t, third.Coalesced)
+	assert.Len(t, env.queue.jobs, 2)
+
+	_, err = env.refresher.RequestRefresh(ctx, 2, mine.ID)
+	assert.ErrorIs(t, err, ErrBookmarkNotFound)
+}
+
+func TestRefresher_LimitsRefreshesPerDomain(t *testing.T) {
+	env := setupRefresher(t, 2)
+	ctx := context.Background()
+
+	for _, url := range []string{"https://www.example.com/a", "https://example.com/b"} {
+		bookmark := createBookmark(t, env.db, 1, url)
+		_, err := env.refresher.RequestRefresh(ctx, 1, bookmark.ID)
+		require.NoError(t, err)
+	}
+
+	limited := createBookmark(t, env.db, 1, "https://example.com/c")
+	_, err := env.refresher.RequestRefresh(ctx, 1, limited.ID)
+	var rateErr *RateLimitError
+	require.ErrorAs(t, err, &rateErr)
+	assert.Equal(t, "example.com", rateErr.Domain)
+	assert.Equal(t, 15*time.Second, rateErr.RetryAfter)
+
+	// Joining a pending job does not count, and other domains have their own budget
+	again := createBookmark(t, env.db, 2, "https://www.example.com/a")
+	_, err = env.refresher.RequestRefresh(ctx, 2, again.ID)
+	require.NoError(t, err)
+	other := createBookmark(t, env.db, 1, "https://go.dev/")
+	_, err = env.refresher.RequestRefresh(ctx, 1, other.ID)
+	require.NoError(t, err)
+}
+
+func TestRefresher_RetriesThenFails(t *testing.T) {
+	env := setupRefresher(t, 10)
+	ctx := context.Background()
+	env.capturer.err = errors.New("browser crashed")
+
+	bookmark := createBookmark(t, env.db, 1, "https://example.com/")
+	result, err := env.refresher.RequestRefresh(ctx, 1, bookmark.ID)
+	require.NoError(t, err)
+
+	job := env.queue.jobs[0]
+	for attempt := 1; attempt <= maxRefreshAttempts; attempt++ {
+		assert.Error(t, job.Execute(ctx))
+	}
+
+	var stored database.ScreenshotJob
+	require.NoError(t, env.db.First(&stored, result.Job.ID).Error)
+	assert.Equal(t, JobFailed, stored.Status)
+	assert.Equal(t, "browser crashed", stored.Error)
+	assert.Equal(t, maxRefreshAttempts, stored.Attempts)
+}
+
+func TestRefresher_QueueUnavailable(t *testing.T) {
+	env := setupRefresher(t, 10)
+	env.queue.err = errors.New("job queue is full")
+
+	bookmark := createBookmark(t, env.db, 1, "https://example.com/")
+	_, err := env.refresher.RequestRefresh(context.Background(), 1, bookmark.ID)
+	assert.ErrorIs(t, err, ErrQueueUnavailable)
+
+	var job database.ScreenshotJob
+	require.NoError(t, env.db.First(&job).Error)
+	assert.Equal(t, JobFailed, job.Status, "a later request is not coalesced onto a job that never runs")
+}
+
+func TestRefreshHandler_Routes(t *testing.T) {
+	env := setupRefresher(t, 1)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", "1")
+		c.Next()
+	})
+	NewRefreshHandler(env.refresher).RegisterRoutes(router.Group("/api/v1"))
+
+	first := createBookmark(t, env.db, 1, "https://example.com/a")
+	createBookmark(t, env.db, 1, "https://example.com/b")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/bookmarks/1/refresh-screenshot", nil))
+	require.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, "/api/v1/screenshots/jobs/1", w.Header().Get("Location"))
+	var response struct {
+		Data RefreshResult `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, first.URL, response.Data.Job.URL)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, response.Data.StatusURL, nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"status":"queued"`)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/bookmarks/2/refresh-screenshot", nil))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "15", w.Header().Get("Retry-After"))
+
+	for _, tc := range []struct {
+		method, url string
+		code        int
+	}{
+		{http.MethodPost, "/api/v1/bookmarks/99/refresh-screenshot", http.StatusNotFound},
+		{http.MethodPost, "/api/v1/bookmarks/abc/refresh-screenshot", http.StatusBadRequest},
+		{http.MethodGet, "/api/v1/screenshots/jobs/99", http.StatusNotFound},
+	} {
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(tc.method, tc.url, nil))
+		assert.Equal(t, tc.code, w.Code, tc.url)
+	}
+}
@@ -6,9 +6,14 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"path"
+	"strings"
 	"time"
 )
 
+// ThumbnailSuffix is appended to a bookmark's screenshot name for its thumbnail
+const ThumbnailSuffix = "_thumb"
+
 // StorageService defines the interface for storage operations
 type StorageService interface {
 	StoreScreenshot(ctx context.Context, bookmarkID string, data []byte) (string, error)
@@ -86,7 +91,7 @@ func (s *Service) CaptureScreenshot(ctx context.Context, bookmarkID, pageURL str
 	if opts.Thumbnail {
 		thumbnailData, err := s.generateThumbnail(screenshotData, 300, 200)
 		if err == nil {
-			thumbnailURL, err := s.storageService.StoreScreenshot(ctx, bookmarkID+"_thumb", thumbnailData)
+			thumbnailURL, err := s.storageService.StoreScreenshot(ctx, bookmarkID+ThumbnailSuffix, thumbnailData)
 			if err == nil {
 				result.ThumbnailURL = thumbnailURL
 			}
@@ -96,6 +101,25 @@ func (s *Service) CaptureScreenshot(ctx context.Context, bookmarkID, pageURL str
 	return result, nil
 }
 
+// ThumbnailURL returns the URL of the thumbnail stored next to a bookmark
+// screenshot, or "" when there is no screenshot
+func ThumbnailURL(screenshotURL string) string {
+	if screenshotURL == "" {
+		return ""
+	}
+	parsed, err := url.Parse(screenshotURL)
+	if err != nil || parsed.Path == "" || strings.HasSuffix(parsed.Path, "/") {
+		return ""
+	}
+	ext := path.Ext(parsed.Path)
+	name := strings.TrimSuffix(parsed.Path, ext)
+	if strings.HasSuffix(name, ThumbnailSuffix) {
+		return screenshotURL
+	}
+	parsed.Path = name + ThumbnailSuffix + ext
+	return parsed.String()
+}
+
 // generatePlaceholderScreenshot generates a placeholder screenshot
 // In production, this would be replaced with actual browser automation
 func (s *Service) generatePlaceholderScreenshot(pageURL string, opts CaptureOptions) ([]byte, error) {
@@ -180,6 +204,58 @@ func (s *Service) GetFavicon(ctx context.Context, pageURL string) ([]byte, error
 	return nil, fmt.Errorf("favicon not found for %s", pageURL)
 }
 
+// CheckFavicon reports whether a favicon URL still serves an image. Inline
+// data: favicons need no fetch
+func (s *Service) CheckFavicon(ctx context.Context, faviconURL string) error {
+	if strings.HasPrefix(faviconURL, "data:image/") {
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, faviconURL, nil)
+	if err != nil {
+		return fmt.Errorf("invalid favicon URL: %w", err)
+	}
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch favicon: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("favicon returned HTTP %d", resp.StatusCode)
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if contentType != "" && !strings.HasPrefix(contentType, "image/") {
+		return fmt.Errorf("favicon is %s, not an image", contentType)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil || len(data) == 0 {
+		return fmt.Errorf("favicon is empty")
+	}
+	return nil
+}
+
+// FindFavicon returns the first common favicon location of a page that
+// serves an image
+func (s *Service) FindFavicon(ctx context.Context, pageURL string) (string, error) {
+	parsedURL, err := url.Parse(pageURL)
+	if err != nil || parsedURL.Host == "" {
+		return "", fmt.Errorf("invalid URL: %s", pageURL)
+	}
+	if parsedURL.Scheme == "" {
+		parsedURL.Scheme = "https"
+	}
+
+	var lastErr error
+	for _, name := range []string{"favicon.ico", "favicon.png", "apple-touch-icon.png"} {
+		faviconURL := fmt.Sprintf("%s://%s/%s", parsedURL.Scheme, parsedURL.Host, name)
+		if lastErr = s.CheckFavicon(ctx, faviconURL); lastErr == nil {
+			return faviconURL, nil
+		}
+	}
+	return "", fmt.Errorf("favicon not found for %s: %w", pageURL, lastErr)
+}
+
 // UpdateBookmarkScreenshot updates the screenshot for an existing bookmark
 func (s *Service) UpdateBookmarkScreenshot(ctx context.Context, bookmarkID, pageURL string) (*CaptureResult, error) {
 	opts := CaptureOptions{
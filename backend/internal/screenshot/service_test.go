@@ -2,6 +2,8 @@ package screenshot
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -219,3 +221,35 @@ func TestNewService(t *testing.T) {
 	assert.NotNil(t, service.httpClient)
 	assert.Equal(t, 30*time.Second, service.httpClient.Timeout)
 }
+
+// TestFindFavicon tests favicon validation and lookup
+func TestFindFavicon(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/favicon.ico":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html>not found</html>"))
+		case "/favicon.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte{0x89, 0x50, 0x4E, 0x47})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	service := NewService(new(MockStorageService))
+	ctx := context.Background()
+
+	assert.NoError(t, service.CheckFavicon(ctx, server.URL+"/favicon.png"))
+	assert.NoError(t, service.CheckFavicon(ctx, "data:image/png;base64,iVBORw0KGgo="))
+	assert.Error(t, service.CheckFavicon(ctx, server.URL+"/favicon.ico"), "HTML error pages are not favicons")
+	assert.Error(t, service.CheckFavicon(ctx, server.URL+"/missing.ico"))
+
+	found, err := service.FindFavicon(ctx, server.URL+"/some/page")
+	assert.NoError(t, err)
+	assert.Equal(t, server.URL+"/favicon.png", found)
+
+	_, err = service.FindFavicon(ctx, "not a url")
+	assert.Error(t, err)
+}
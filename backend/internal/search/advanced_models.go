@@ -126,10 +126,14 @@ func (p *FacetedSearchParams) Validate() error {
 
 	// Validate facet fields
 	validFacetFields := map[string]bool{
-		"tags":       true,
-		"created_at": true,
-		"updated_at": true,
-		"domain":     true,
+		"tags":         true,
+		"created_at":   true,
+		"updated_at":   true,
+		"domain":       true,
+		"content_type": true,
+		"year":         true,
+		"language":     true,
+		"source":       true,
 	}
 
 	for _, field := range p.FacetBy {
@@ -10,6 +10,7 @@ import (
 
 	"bookmark-sync-service/backend/pkg/database"
 	"bookmark-sync-service/backend/pkg/redis"
+	"bookmark-sync-service/backend/pkg/search"
 
 	"github.com/typesense/typesense-go/typesense/api"
 	"gorm.io/gorm"
@@ -56,13 +57,12 @@ func (s *AdvancedService) FacetedSearch(ctx context.Context, params FacetedSearc
 		return nil, fmt.Errorf("invalid parameters: %w", err)
 	}
 
-	// Build filter
-	filterBy := fmt.Sprintf("user_id:%s", params.UserID)
-
-	// Add custom filters
+	// Build filter; the search client scopes it to the user
+	var filters []string
 	for field, value := range params.Filters {
-		filterBy += fmt.Sprintf(" && %s:%s", field, value)
+		filters = append(filters, fmt.Sprintf("%s:%s", field, value))
 	}
+	filterBy := strings.Join(filters, " && ")
 
 	// Build facet by string
 	facetBy := strings.Join(params.FacetBy, ",")
@@ -93,7 +93,7 @@ func (s *AdvancedService) FacetedSearch(ctx context.Context, params FacetedSearc
 	}
 
 	// Perform search
-	result, err := s.client.Search(ctx, "bookmarks", searchParams)
+	result, err := s.client.Search(search.WithUser(ctx, params.UserID), "bookmarks", searchParams)
 	if err != nil {
 		return nil, fmt.Errorf("faceted search failed: %w", err)
 	}
@@ -112,31 +112,7 @@ func (s *AdvancedService) FacetedSearch(ctx context.Context, params FacetedSearc
 	}
 
 	// Convert facets
-	facets := make(map[string][]FacetValue)
-	if result.FacetCounts != nil {
-		for _, facetCount := range *result.FacetCounts {
-			if facetCount.FieldName != nil && facetCount.Counts != nil {
-				fieldName := *facetCount.FieldName
-				facetValues := make([]FacetValue, 0, len(*facetCount.Counts))
-
-				for _, count := range *facetCount.Counts {
-					if count.Value != nil && count.Count != nil {
-						facetValues = append(facetValues, FacetValue{
-							Value: *count.Value,
-							Count: *count.Count,
-						})
-					}
-				}
-
-				// Sort facet values by count (descending)
-				sort.Slice(facetValues, func(i, j int) bool {
-					return facetValues[i].Count > facetValues[j].Count
-				})
-
-				facets[fieldName] = facetValues
-			}
-		}
-	}
+	facets := convertFacetCounts(result.FacetCounts)
 
 	total := 0
 	if result.Found != nil {
@@ -162,10 +138,7 @@ func (s *AdvancedService) SemanticSearch(ctx context.Context, params SemanticSea
 	// Enhance query with semantic understanding
 	enhancedQuery := s.enhanceQuerySemantics(params.Query, params.Intent, params.Context)
 
-	// Build advanced search parameters
-	filterBy := fmt.Sprintf("user_id:%s", params.UserID)
-
-	// Use semantic ranking
+	// Use semantic ranking; the search client scopes it to the user
 	sortBy := "_text_match:desc,save_count:desc"
 	queryByWeights := "5,4,3,2,1" // Higher weight for semantic matching
 	highlightFields := "title,description"
@@ -178,7 +151,6 @@ func (s *AdvancedService) SemanticSearch(ctx context.Context, params SemanticSea
 		Q:                enhancedQuery,
 		QueryBy:          "title,description,url,tags,content",
 		QueryByWeights:   &queryByWeights,
-		FilterBy:         &filterBy,
 		SortBy:           &sortBy,
 		Page:             &params.Page,
 		PerPage:          &params.Limit,
@@ -190,7 +162,7 @@ func (s *AdvancedService) SemanticSearch(ctx context.Context, params SemanticSea
 	}
 
 	// Perform search
-	result, err := s.client.Search(ctx, "bookmarks", searchParams)
+	result, err := s.client.Search(search.WithUser(ctx, params.UserID), "bookmarks", searchParams)
 	if err != nil {
 		return nil, fmt.Errorf("semantic search failed: %w", err)
 	}
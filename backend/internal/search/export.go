@@ -0,0 +1,231 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"bookmark-sync-service/backend/internal/automation"
+	"bookmark-sync-service/backend/internal/collection"
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/exporter"
+)
+
+// Search export targets
+const (
+	ExportTargetCollection = "collection"
+	ExportTargetFile       = "file"
+)
+
+// defaultExportFileFormat is used when a file export names no format
+const defaultExportFileFormat = "csv"
+
+var (
+	// ErrInvalidExport is returned for export requests that cannot be run
+	ErrInvalidExport = errors.New("invalid search export")
+	// ErrNoExportResults is returned when the search matches no bookmarks
+	ErrNoExportResults = errors.New("no bookmarks match the search")
+)
+
+// BookmarkSearcher runs bookmark searches, e.g. the search service
+type BookmarkSearcher interface {
+	SearchBookmarksAdvanced(ctx context.Context, params SearchParams) (*SearchResult, error)
+}
+
+// CollectionMaker creates a collection holding given bookmarks, e.g. the
+// collection service
+type CollectionMaker interface {
+	CreateWithBookmarks(userID uint, req collection.CreateCollectionRequest, bookmarkIDs []uint) (*database.Collection, int, error)
+}
+
+// ExportQueue runs file exports in the background, e.g. the automation
+// bulk operations
+type ExportQueue interface {
+	CreateBulkOperation(userID string, req automation.BulkOperationRequest) (*automation.BulkOperation, error)
+}
+
+// ExportRequest is a search whose results are saved to a collection or a file
+type ExportRequest struct {
+	Query       string     `json:"query"`
+	Tags        []string   `json:"tags,omitempty"`
+	Collections []string   `json:"collections,omitempty"`
+	Languages   []string   `json:"lang,omitempty"`
+	DateFrom    *time.Time `json:"date_from,omitempty"`
+	DateTo      *time.Time `json:"date_to,omitempty"`
+	SortBy      string     `json:"sort_by,omitempty"`
+	SortDesc    bool       `json:"sort_desc,omitempty"`
+
+	Target string `json:"target" binding:"required,oneof=collection file"`
+	// Format is the export format of file exports, CSV by default
+	Format string `json:"format,omitempty"`
+	// Name, Description and Visibility describe the new collection. The
+	// name defaults to the query and the visibility to private
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	Visibility  string `json:"visibility,omitempty" binding:"omitempty,oneof=private public shared"`
+}
+
+// ExportResult is where the results of an export went
+type ExportResult struct {
+	Target    string `json:"target"`
+	Matched   int    `json:"matched"`   // bookmarks the search found
+	Exported  int    `json:"exported"`  // bookmarks saved
+	Truncated bool   `json:"truncated"` // more matched than one export saves
+
+	Collection *database.Collection      `json:"collection,omitempty"`
+	Operation  *automation.BulkOperation `json:"operation,omitempty"`
+	// StatusURL is where file exports report their progress
+	StatusURL string `json:"status_url,omitempty"`
+}
+
+// ResultExporter saves the full result set of a search, for research
+// workflows that outgrow paging through results
+type ResultExporter struct {
+	searcher    BookmarkSearcher
+	collections CollectionMaker
+	exports     ExportQueue
+	maxResults  int
+}
+
+// NewResultExporter creates a search result exporter
+func NewResultExporter(searcher BookmarkSearcher, collections CollectionMaker, exports ExportQueue) *ResultExporter {
+	return &ResultExporter{
+		searcher:    searcher,
+		collections: collections,
+		exports:     exports,
+		maxResults:  config.SearchExportMaxResults,
+	}
+}
+
+// Export runs the search and materializes its results into a new static
+// collection, or queues a file export of them
+func (e *ResultExporter) Export(ctx context.Context, userID string, req ExportRequest) (*ExportResult, error) {
+	switch req.Target {
+	case ExportTargetCollection:
+	case ExportTargetFile:
+		if req.Format == "" {
+			req.Format = defaultExportFileFormat
+		}
+		if _, err := exporter.Lookup(req.Format); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidExport, err)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown target %q", ErrInvalidExport, req.Target)
+	}
+
+	params := req.searchParams(userID)
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidExport, err)
+	}
+
+	ids, matched, err := e.collectResults(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, ErrNoExportResults
+	}
+
+	result := &ExportResult{
+		Target:    req.Target,
+		Matched:   matched,
+		Truncated: len(ids) == e.maxResults && matched > len(ids),
+	}
+
+	if req.Target == ExportTargetFile {
+		operation, err := e.exports.CreateBulkOperation(userID, automation.BulkOperationRequest{
+			Type: "export",
+			Parameters: map[string]interface{}{
+				"format":       req.Format,
+				"bookmark_ids": ids,
+				"query":        req.Query,
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to queue export: %w", err)
+		}
+		result.Operation = operation
+		result.Exported = len(ids)
+		result.StatusURL = fmt.Sprintf("/api/v1/automation/bulk/%d", operation.ID)
+		return result, nil
+	}
+
+	owner, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid user ID %q", ErrInvalidExport, userID)
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = "Search results"
+		if query := strings.TrimSpace(req.Query); query != "" {
+			name = "Search: " + query
+		}
+	}
+	visibility := req.Visibility
+	if visibility == "" {
+		visibility = "private"
+	}
+
+	created, added, err := e.collections.CreateWithBookmarks(uint(owner), collection.CreateCollectionRequest{
+		Name:        name,
+		Description: req.Description,
+		Visibility:  visibility,
+	}, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create collection: %w", err)
+	}
+	result.Collection = created
+	result.Exported = added
+	return result, nil
+}
+
+// searchParams returns the first page of the export's search
+func (req *ExportRequest) searchParams(userID string) SearchParams {
+	return SearchParams{
+		Query:       req.Query,
+		UserID:      userID,
+		Tags:        req.Tags,
+		Collections: req.Collections,
+		Languages:   req.Languages,
+		DateFrom:    req.DateFrom,
+		DateTo:      req.DateTo,
+		SortBy:      req.SortBy,
+		SortDesc:    req.SortDesc,
+		Page:        1,
+		Limit:       config.SearchExportPageSize,
+	}
+}
+
+// collectResults pages through the search, in rank order, until it has
+// every result or the export limit. It also returns how many matched
+func (e *ResultExporter) collectResults(ctx context.Context, params SearchParams) ([]uint, int, error) {
+	var ids []uint
+	matched := 0
+	for ; len(ids) < e.maxResults; params.Page++ {
+		page, err := e.searcher.SearchBookmarksAdvanced(ctx, params)
+		if err != nil {
+			return nil, 0, fmt.Errorf("search failed on page %d: %w", params.Page, err)
+		}
+		matched = page.Total
+
+		for _, bookmark := range page.Bookmarks {
+			id, err := strconv.ParseUint(bookmark.ID, 10, 32)
+			if err != nil {
+				continue // Skip documents that are not bookmark rows
+			}
+			ids = append(ids, uint(id))
+			if len(ids) == e.maxResults {
+				break
+			}
+		}
+
+		if len(page.Bookmarks) < params.Limit || params.Page*params.Limit >= page.Total {
+			break
+		}
+	}
+	return ids, matched, nil
+}
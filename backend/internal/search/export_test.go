@@ -0,0 +1,157 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/internal/automation"
+	"bookmark-sync-service/backend/internal/collection"
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+// pagedSearcher serves the given bookmark IDs as ranked search results
+type pagedSearcher struct {
+	ids   []string
+	pages []int
+	err   error
+}
+
+func (s *pagedSearcher) SearchBookmarksAdvanced(ctx context.Context, params SearchParams) (*SearchResult, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	s.pages = append(s.pages, params.Page)
+
+	result := &SearchResult{Total: len(s.ids), Page: params.Page, Limit: params.Limit, Bookmarks: []BookmarkSearchResult{}}
+	for i := (params.Page - 1) * params.Limit; i < len(s.ids) && i < params.Page*params.Limit; i++ {
+		result.Bookmarks = append(result.Bookmarks, BookmarkSearchResult{ID: s.ids[i]})
+	}
+	return result, nil
+}
+
+func setupResultExporter(t *testing.T, ids ...string) (*ResultExporter, *pagedSearcher, *gorm.DB) {
+	db, err := database.SetupTestDB()
+	require.NoError(t, err)
+	t.Cleanup(func() { database.CleanupTestDB(db) })
+
+	searcher := &pagedSearcher{ids: ids}
+	return NewResultExporter(searcher, collection.NewService(db), automation.NewServiceForTesting(db)), searcher, db
+}
+
+func createExportBookmarks(t *testing.T, db *gorm.DB, userID uint, count int) []string {
+	ids := make([]string, count)
+	for i := range ids {
+		bookmark := &database.Bookmark{UserID: userID, URL: "https://example.com/" + strconv.Itoa(i), Title: "Result"}
+		require.NoError(t, db.Create(bookmark).Error)
+		ids[i] = strconv.Itoa(int(bookmark.ID))
+	}
+	return ids
+}
+
+func TestResultExporter_SavesAllPagesAsCollection(t *testing.T) {
+	exporter, searcher, db := setupResultExporter(t)
+	searcher.ids = append(createExportBookmarks(t, db, 1, 120), "not-a-row")
+	searcher.ids = append(searcher.ids, createExportBookmarks(t, db, 2, 1)...)
+
+	result, err := exporter.Export(context.Background(), "1", ExportRequest{Query: "go tutorials", Target: ExportTargetCollection})
+	require.NoError(t, err)
+
+	assert.Equal(t, []int{1, 2}, searcher.pages)
+	assert.Equal(t, 122, result.Matched)
+	assert.Equal(t, 120, result.Exported, "another user's bookmark is not added")
+	assert.False(t, result.Truncated)
+	require.NotNil(t, result.Collection)
+	assert.Equal(t, "Search: go tutorials", result.Collection.Name)
+	assert.Equal(t, "private", result.Collection.Visibility)
+
+	var linked int64
+	require.NoError(t, db.Table("bookmark_collections").Where("collection_id = ?", result.Collection.ID).Count(&linked).Error)
+	assert.Equal(t, int64(120), linked)
+}
+
+func TestResultExporter_QueuesFileExport(t *testing.T) {
+	exporter, searcher, db := setupResultExporter(t)
+	searcher.ids = createExportBookmarks(t, db, 1, 5)
+	exporter.maxResults = 3
+
+	result, err := exporter.Export(context.Background(), "1", ExportRequest{Query: "rust", Target: ExportTargetFile})
+	require.NoError(t, err)
+
+	assert.True(t, result.Truncated)
+	assert.Equal(t, 3, result.Exported)
+	require.NotNil(t, result.Operation)
+	assert.Equal(t, "export", result.Operation.Type)
+	assert.Equal(t, "csv", result.Operation.Parameters["format"])
+	assert.Len(t, result.Operation.Parameters["bookmark_ids"], 3)
+	assert.Equal(t, "/api/v1/automation/bulk/"+strconv.Itoa(int(result.Operation.ID)), result.StatusURL)
+}
+
+func TestResultExporter_Errors(t *testing.T) {
+	exporter, searcher, _ := setupResultExporter(t)
+	ctx := context.Background()
+
+	_, err := exporter.Export(ctx, "1", ExportRequest{Target: ExportTargetFile, Format: "pdf"})
+	assert.ErrorIs(t, err, ErrInvalidExport)
+	_, err = exporter.Export(ctx, "1", ExportRequest{Target: ExportTargetCollection, SortBy: "color"})
+	assert.ErrorIs(t, err, ErrInvalidExport)
+	_, err = exporter.Export(ctx, "1", ExportRequest{Target: ExportTargetCollection})
+	assert.ErrorIs(t, err, ErrNoExportResults)
+	assert.Empty(t, searcher.pages[1:], "an empty first page ends the search")
+
+	searcher.err = errors.New("typesense unavailable")
+	_, err = exporter.Export(ctx, "1", ExportRequest{Target: ExportTargetCollection})
+	assert.ErrorContains(t, err, "typesense unavailable")
+}
+
+func TestHandlers_ExportResults(t *testing.T) {
+	exporter, searcher, db := setupResultExporter(t)
+	searcher.ids = createExportBookmarks(t, db, 1, 2)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", "1")
+		c.Next()
+	})
+	handlers := NewHandlers(nil)
+	handlers.SetResultExporter(exporter)
+	handlers.RegisterRoutes(router.Group("/api/v1"))
+
+	post := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/search/export", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post(`{"query":"go","target":"collection","name":"Reading list"}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var response struct {
+		Data ExportResult `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "Reading list", response.Data.Collection.Name)
+	assert.Equal(t, 2, response.Data.Exported)
+
+	w = post(`{"query":"go","target":"file","format":"json"}`)
+	require.Equal(t, http.StatusAccepted, w.Code)
+	assert.Contains(t, w.Header().Get("Location"), "/api/v1/automation/bulk/")
+
+	assert.Equal(t, http.StatusBadRequest, post(`{"query":"go","target":"smart"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, post(`{"query":"go","target":"file","format":"pdf"}`).Code)
+
+	searcher.ids = nil
+	assert.Equal(t, http.StatusNotFound, post(`{"query":"nothing","target":"collection"}`).Code)
+}
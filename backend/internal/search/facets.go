@@ -0,0 +1,185 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/search"
+
+	"github.com/typesense/typesense-go/typesense/api"
+)
+
+// Facet fields exposed by the facets API
+var bookmarkFacetFields = []string{"tags", "domain", "content_type", "year", "language", "source"}
+
+// FacetsParams represents parameters for facet aggregation
+type FacetsParams struct {
+	Query     string   `json:"query"`
+	UserID    string   `json:"user_id"`
+	Languages []string `json:"lang,omitempty"`
+	Sources   []string `json:"source,omitempty"`
+	MaxValues int      `json:"max_values,omitempty"`
+}
+
+// FacetsResult represents facet counts for the current query
+type FacetsResult struct {
+	Query  string                  `json:"query"`
+	Total  int                     `json:"total"`
+	Facets map[string][]FacetValue `json:"facets"`
+}
+
+// Validate validates facet parameters
+func (p *FacetsParams) Validate() error {
+	if p.UserID == "" {
+		return fmt.Errorf("user_id is required")
+	}
+
+	if p.MaxValues <= 0 {
+		p.MaxValues = 10
+	}
+
+	if p.MaxValues > 50 {
+		return fmt.Errorf("max_values cannot exceed 50")
+	}
+
+	return nil
+}
+
+// GetFacets returns tag, domain, content type, year, language and source counts for the
+// user's bookmarks matching the query, without returning the hits themselves
+func (s *Service) GetFacets(ctx context.Context, params FacetsParams) (*FacetsResult, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid facet parameters: %w", err)
+	}
+
+	query := strings.TrimSpace(params.Query)
+	if query == "" {
+		query = "*"
+	}
+
+	// The search client scopes the filter to the user
+	var filters []string
+	if languageFilter := buildLanguageFilter(params.Languages); languageFilter != "" {
+		filters = append(filters, languageFilter)
+	}
+	if sourceFilter := buildSourceFilter(params.Sources); sourceFilter != "" {
+		filters = append(filters, sourceFilter)
+	}
+	filterBy := strings.Join(filters, " && ")
+	facetBy := strings.Join(bookmarkFacetFields, ",")
+	maxFacetValues := params.MaxValues
+	page := 1
+	perPage := 0
+
+	searchParams := &api.SearchCollectionParams{
+		Q:              query,
+		QueryBy:        "title,description,url,tags",
+		FilterBy:       &filterBy,
+		FacetBy:        &facetBy,
+		MaxFacetValues: &maxFacetValues,
+		Page:           &page,
+		PerPage:        &perPage,
+	}
+
+	result, err := s.client.Search(search.WithUser(ctx, params.UserID), "bookmarks", searchParams)
+	if err != nil {
+		return nil, fmt.Errorf("facet search failed: %w", err)
+	}
+
+	facets := convertFacetCounts(result.FacetCounts)
+	for _, field := range bookmarkFacetFields {
+		if _, ok := facets[field]; !ok {
+			facets[field] = []FacetValue{}
+		}
+	}
+
+	total := 0
+	if result.Found != nil {
+		total = *result.Found
+	}
+
+	return &FacetsResult{
+		Query:  params.Query,
+		Total:  total,
+		Facets: facets,
+	}, nil
+}
+
+// convertFacetCounts converts Typesense facet counts into facet values sorted by count
+func convertFacetCounts(facetCounts *[]api.FacetCounts) map[string][]FacetValue {
+	facets := make(map[string][]FacetValue)
+	if facetCounts == nil {
+		return facets
+	}
+
+	for _, facetCount := range *facetCounts {
+		if facetCount.FieldName == nil || facetCount.Counts == nil {
+			continue
+		}
+
+		facetValues := make([]FacetValue, 0, len(*facetCount.Counts))
+		for _, count := range *facetCount.Counts {
+			if count.Value != nil && count.Count != nil {
+				facetValues = append(facetValues, FacetValue{
+					Value: *count.Value,
+					Count: *count.Count,
+				})
+			}
+		}
+
+		sort.SliceStable(facetValues, func(i, j int) bool {
+			return facetValues[i].Count > facetValues[j].Count
+		})
+
+		facets[*facetCount.FieldName] = facetValues
+	}
+
+	return facets
+}
+
+// bookmarkDomain returns the lower-cased host of a bookmark URL without a leading "www."
+func bookmarkDomain(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Hostname() == "" {
+		return ""
+	}
+
+	return strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+}
+
+// bookmarkContentType returns the content type stored in the bookmark metadata,
+// falling back to a guess based on the URL path extension
+func bookmarkContentType(bookmark *database.Bookmark) string {
+	if bookmark.Metadata != "" {
+		var metadata map[string]interface{}
+		if err := json.Unmarshal([]byte(bookmark.Metadata), &metadata); err == nil {
+			if contentType, ok := metadata["content_type"].(string); ok && contentType != "" {
+				return strings.ToLower(contentType)
+			}
+		}
+	}
+
+	parsed, err := url.Parse(bookmark.URL)
+	if err != nil {
+		return "webpage"
+	}
+
+	switch strings.ToLower(path.Ext(parsed.Path)) {
+	case ".pdf":
+		return "pdf"
+	case ".png", ".jpg", ".jpeg", ".gif", ".webp", ".svg":
+		return "image"
+	case ".mp4", ".webm", ".mov":
+		return "video"
+	case ".mp3", ".wav", ".ogg":
+		return "audio"
+	default:
+		return "webpage"
+	}
+}
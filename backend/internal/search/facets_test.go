@@ -0,0 +1,239 @@
+package search
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"bookmark-sync-service/backend/pkg/database"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/typesense/typesense-go/typesense/api"
+)
+
+func TestFacetsParams_Validate(t *testing.T) {
+	tests := []struct {
+		name          string
+		params        FacetsParams
+		expectedMax   int
+		expectedError string
+	}{
+		{
+			name:        "defaults max values",
+			params:      FacetsParams{Query: "go", UserID: "user123"},
+			expectedMax: 10,
+		},
+		{
+			name:          "missing user ID",
+			params:        FacetsParams{Query: "go"},
+			expectedError: "user_id is required",
+		},
+		{
+			name:          "max values too high",
+			params:        FacetsParams{UserID: "user123", MaxValues: 51},
+			expectedError: "max_values cannot exceed 50",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.params.Validate()
+
+			if tt.expectedError != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectedError)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedMax, tt.params.MaxValues)
+		})
+	}
+}
+
+func TestBookmarkDocument_FacetFields(t *testing.T) {
+	bookmark := &database.Bookmark{
+		BaseModel: database.BaseModel{
+			ID:        7,
+			CreatedAt: time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC),
+			UpdatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		UserID: 3,
+		URL:    "https://www.Example.com/papers/report.pdf",
+		Title:  "Report",
+		Tags:   `["research", "pdf"]`,
+	}
+
+	doc := bookmarkDocument(bookmark)
+
+	assert.Equal(t, "7", doc["id"])
+	assert.Equal(t, "3", doc["user_id"])
+	assert.Equal(t, []string{"research", "pdf"}, doc["tags"])
+	assert.Equal(t, "example.com", doc["domain"])
+	assert.Equal(t, "pdf", doc["content_type"])
+	assert.Equal(t, 2023, doc["year"])
+	assert.NotContains(t, doc, "language")
+
+	bookmark.Language = "fr"
+	assert.Equal(t, "fr", bookmarkDocument(bookmark)["language"])
+
+	assert.Equal(t, "unknown", doc["source"])
+	bookmark.Source = database.BookmarkSourceExtension
+	assert.Equal(t, "extension", bookmarkDocument(bookmark)["source"])
+}
+
+func TestBuildLanguageFilter(t *testing.T) {
+	assert.Equal(t, "language:[en,fr]", buildLanguageFilter([]string{"en-US", "fr", "en"}))
+	assert.Equal(t, "", buildLanguageFilter([]string{"not a language"}))
+	assert.Equal(t, "", buildLanguageFilter(nil))
+}
+
+func TestBuildSourceFilter(t *testing.T) {
+	assert.Equal(t, "source:[import,unknown]", buildSourceFilter(database.ParseBookmarkSources("import, Unknown,import,fax")))
+	assert.Equal(t, "", buildSourceFilter(nil))
+}
+
+func TestBookmarkContentType(t *testing.T) {
+	tests := []struct {
+		name     string
+		bookmark database.Bookmark
+		expected string
+	}{
+		{
+			name:     "metadata wins",
+			bookmark: database.Bookmark{URL: "https://example.com/a.pdf", Metadata: `{"content_type":"Video"}`},
+			expected: "video",
+		},
+		{
+			name:     "image extension",
+			bookmark: database.Bookmark{URL: "https://example.com/cat.PNG"},
+			expected: "image",
+		},
+		{
+			name:     "plain page",
+			bookmark: database.Bookmark{URL: "https://example.com/blog/post"},
+			expected: "webpage",
+		},
+		{
+			name:     "invalid metadata falls back",
+			bookmark: database.Bookmark{URL: "https://example.com/talk.mp3", Metadata: "not-json"},
+			expected: "audio",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, bookmarkContentType(&tt.bookmark))
+		})
+	}
+}
+
+func TestConvertFacetCounts(t *testing.T) {
+	field := "domain"
+	low, high := 1, 5
+	a, b := "a.com", "b.com"
+
+	facets := convertFacetCounts(&[]api.FacetCounts{
+		{
+			FieldName: &field,
+			Counts: &[]struct {
+				Count       *int    `json:"count,omitempty"`
+				Highlighted *string `json:"highlighted,omitempty"`
+				Value       *string `json:"value,omitempty"`
+			}{
+				{Count: &low, Value: &a},
+				{Count: &high, Value: &b},
+			},
+		},
+	})
+
+	assert.Equal(t, []FacetValue{{Value: "b.com", Count: 5}, {Value: "a.com", Count: 1}}, facets["domain"])
+	assert.Empty(t, convertFacetCounts(nil))
+}
+
+func TestHandlers_GetFacets(t *testing.T) {
+	router, _ := setupTestRouterFixed()
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+	}{
+		{
+			name:           "invalid max values",
+			query:          "?q=test&max_values=0",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "max values too high",
+			query:          "?q=test&max_values=51",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/search/facets"+tt.query, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
+
+func TestBuildTagFilter(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		want     string
+	}{
+		{nil, ""},
+		{[]string{"dev"}, "tags:=`dev` || tag_ancestors:=`dev`"},
+		{[]string{"dev/*", "news", " "}, "tag_ancestors:=`dev` || tags:=`news` || tag_ancestors:=`news`"},
+	}
+	for _, tt := range tests {
+		filter, err := buildTagFilter(tt.patterns)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.want, filter)
+	}
+
+	// A backtick would end the quoted tag and let the rest rewrite the filter
+	_, err := buildTagFilter([]string{"dev", "x` || user_id:=`2"})
+	assert.Error(t, err)
+}
+
+func TestBookmarkDocument_TagAncestors(t *testing.T) {
+	doc := bookmarkDocument(&database.Bookmark{
+		URL:  "https://example.com",
+		Tags: `["dev/go/testing", " news "]`,
+	})
+
+	assert.Equal(t, []string{"dev/go/testing", "news"}, doc["tags"])
+	assert.Equal(t, []string{"dev", "dev/go"}, doc["tag_ancestors"])
+}
+
+func TestBookmarkDocument_Notes(t *testing.T) {
+	doc := bookmarkDocument(&database.Bookmark{
+		URL:   "https://example.com",
+		Notes: "Read the **second** chapter",
+	})
+	assert.Equal(t, "Read the second chapter", doc["notes"])
+
+	doc = bookmarkDocument(&database.Bookmark{URL: "https://example.com"})
+	assert.NotContains(t, doc, "notes")
+
+	doc = bookmarkDocument(&database.Bookmark{URL: "https://example.com", Notes: "c2VjcmV0", NotesEncrypted: true})
+	assert.NotContains(t, doc, "notes")
+}
+
+func TestBookmarkDocument_Summary(t *testing.T) {
+	doc := bookmarkDocument(&database.Bookmark{
+		URL:      "https://example.com",
+		Metadata: `{"content_type":"article","summary":"A short overview"}`,
+	})
+	assert.Equal(t, "A short overview", doc["summary"])
+
+	doc = bookmarkDocument(&database.Bookmark{URL: "https://example.com", Metadata: `{"content_type":"article"}`})
+	assert.NotContains(t, doc, "summary")
+}
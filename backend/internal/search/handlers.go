@@ -1,11 +1,17 @@
 package search
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
+	"bookmark-sync-service/backend/internal/metering"
+	"bookmark-sync-service/backend/internal/mobile"
 	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/language"
 	"bookmark-sync-service/backend/pkg/utils"
 
 	"github.com/gin-gonic/gin"
@@ -13,7 +19,22 @@ import (
 
 // Handlers provides HTTP handlers for search functionality
 type Handlers struct {
-	service *Service
+	service  *Service
+	exporter *ResultExporter     // optional; serves search exports
+	history  QueryRecorder       // optional; remembers queries for warm-ups
+	warmer   *Warmer             // optional; serves the admin warm-up endpoints
+	status   *IndexStatusTracker // optional; serves the index status summary
+	usage    UsageMeter          // optional; counts searches towards usage
+}
+
+// QueryRecorder remembers the queries a user searches for
+type QueryRecorder interface {
+	RecordSearchHistory(ctx context.Context, userID, query string) error
+}
+
+// UsageMeter counts a user's use of metered features
+type UsageMeter interface {
+	Record(userID uint, metric string, amount int64)
 }
 
 // NewHandlers creates new search handlers
@@ -23,6 +44,39 @@ func NewHandlers(service *Service) *Handlers {
 	}
 }
 
+// SetResultExporter enables saving search results as collections and files
+func (h *Handlers) SetResultExporter(exporter *ResultExporter) {
+	h.exporter = exporter
+}
+
+// SetHistory records the queries of basic searches
+func (h *Handlers) SetHistory(history QueryRecorder) {
+	h.history = history
+}
+
+// SetWarmer enables the admin endpoints that warm search caches
+func (h *Handlers) SetWarmer(warmer *Warmer) {
+	h.warmer = warmer
+}
+
+// SetIndexStatus enables the summary of which bookmarks are searchable yet
+func (h *Handlers) SetIndexStatus(status *IndexStatusTracker) {
+	h.status = status
+}
+
+// SetUsageMeter makes bookmark and collection searches count towards the
+// user's usage
+func (h *Handlers) SetUsageMeter(meter UsageMeter) {
+	h.usage = meter
+}
+
+// meterSearch counts a search that was answered
+func (h *Handlers) meterSearch(c *gin.Context) {
+	if h.usage != nil {
+		h.usage.Record(utils.GetUserIDFromContext(c), metering.MetricSearches, 1)
+	}
+}
+
 // RegisterRoutes registers search routes
 func (h *Handlers) RegisterRoutes(router *gin.RouterGroup) {
 	search := router.Group("/search")
@@ -32,6 +86,9 @@ func (h *Handlers) RegisterRoutes(router *gin.RouterGroup) {
 		search.POST("/bookmarks/advanced", h.SearchBookmarksAdvanced)
 		search.GET("/collections", h.SearchCollections)
 		search.GET("/suggestions", h.GetSuggestions)
+		search.GET("/facets", h.GetFacets)
+		search.POST("/export", h.ExportResults)
+		search.GET("/index-status", h.GetIndexStatus)
 
 		// Index management endpoints
 		search.POST("/index/bookmark", h.IndexBookmark)
@@ -75,13 +132,31 @@ func (h *Handlers) SearchBookmarksBasic(c *gin.Context) {
 		return
 	}
 
-	result, err := h.service.SearchBookmarksBasic(c.Request.Context(), query, userID.(string), page, limit)
+	params := SearchParams{
+		Query:     query,
+		UserID:    userID.(string),
+		Languages: language.ParseList(c.Query("lang")),
+		Sources:   database.ParseBookmarkSources(c.Query("source")),
+		Page:      page,
+		Limit:     limit,
+	}
+
+	result, err := h.service.SearchBookmarksAdvanced(c.Request.Context(), params)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "SEARCH_FAILED", "Search failed", map[string]interface{}{"error": err.Error()})
 		return
 	}
+	h.meterSearch(c)
+	if h.history != nil && strings.TrimSpace(query) != "" {
+		// History only feeds warm-ups, the search succeeded without it
+		_ = h.history.RecordSearchHistory(c.Request.Context(), params.UserID, query)
+	}
 
-	utils.SuccessResponse(c, result, "Search completed successfully")
+	if mobile.Requested(c) {
+		utils.SparseResponse(c, toMobile(result), "Search completed successfully", "bookmarks")
+		return
+	}
+	utils.SparseResponse(c, result, "Search completed successfully", "bookmarks")
 }
 
 // SearchBookmarksAdvanced handles advanced bookmark search
@@ -114,8 +189,59 @@ func (h *Handlers) SearchBookmarksAdvanced(c *gin.Context) {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "SEARCH_FAILED", "Advanced search failed", map[string]interface{}{"error": err.Error()})
 		return
 	}
+	h.meterSearch(c)
+
+	if mobile.Requested(c) {
+		utils.SparseResponse(c, toMobile(result), "Advanced search completed successfully", "bookmarks")
+		return
+	}
+	utils.SparseResponse(c, result, "Advanced search completed successfully", "bookmarks")
+}
+
+// ExportResults saves every result of a search as a new collection, or
+// queues a CSV/JSON export of them
+func (h *Handlers) ExportResults(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+	if h.exporter == nil {
+		utils.ErrorResponse(c, http.StatusServiceUnavailable, "SERVICE_UNAVAILABLE", "Search export is not available", nil)
+		return
+	}
+
+	var req ExportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body", map[string]interface{}{"error": err.Error()})
+		return
+	}
 
-	utils.SuccessResponse(c, result, "Advanced search completed successfully")
+	result, err := h.exporter.Export(c.Request.Context(), userID.(string), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidExport):
+			utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", err.Error(), nil)
+		case errors.Is(err, ErrNoExportResults):
+			utils.ErrorResponse(c, http.StatusNotFound, "NO_RESULTS", err.Error(), nil)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, "EXPORT_FAILED", "Search export failed", map[string]interface{}{"error": err.Error()})
+		}
+		return
+	}
+
+	// File exports finish in the background; collections exist already
+	status, message := http.StatusCreated, "Search results saved as a collection"
+	if result.Target == ExportTargetFile {
+		status, message = http.StatusAccepted, "Search results export queued"
+		c.Header("Location", result.StatusURL)
+	}
+	c.JSON(status, utils.APIResponse{
+		Success:   true,
+		Message:   message,
+		Data:      result,
+		RequestID: c.GetString("request_id"),
+	})
 }
 
 // SearchCollections handles collection search
@@ -147,8 +273,9 @@ func (h *Handlers) SearchCollections(c *gin.Context) {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "SEARCH_FAILED", "Collection search failed", map[string]interface{}{"error": err.Error()})
 		return
 	}
+	h.meterSearch(c)
 
-	utils.SuccessResponse(c, result, "Collection search completed successfully")
+	utils.SparseResponse(c, result, "Collection search completed successfully", "collections")
 }
 
 // GetSuggestions handles search suggestions
@@ -180,6 +307,66 @@ func (h *Handlers) GetSuggestions(c *gin.Context) {
 	utils.SuccessResponse(c, result, "Suggestions retrieved successfully")
 }
 
+// GetFacets handles facet aggregation for the current query
+func (h *Handlers) GetFacets(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	params := FacetsParams{
+		Query:     c.Query("q"),
+		UserID:    userID.(string),
+		Languages: language.ParseList(c.Query("lang")),
+		Sources:   database.ParseBookmarkSources(c.Query("source")),
+	}
+
+	if maxValuesStr := c.Query("max_values"); maxValuesStr != "" {
+		maxValues, err := strconv.Atoi(maxValuesStr)
+		if err != nil || maxValues <= 0 || maxValues > 50 {
+			utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_PARAMETER", "Invalid max_values parameter (must be 1-50)", nil)
+			return
+		}
+		params.MaxValues = maxValues
+	}
+
+	result, err := h.service.GetFacets(c.Request.Context(), params)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "FACETS_FAILED", "Failed to get facets", map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	utils.SuccessResponse(c, result, "Facets retrieved successfully")
+}
+
+// GetIndexStatus counts the user's bookmarks that are searchable, still
+// waiting to be indexed or failed to be
+// @Summary Get the search index status of the user's bookmarks
+// @Tags search
+// @Produce json
+// @Success 200 {object} IndexStatusSummary
+// @Failure 503 {object} utils.ErrorResponse
+// @Router /search/index-status [get]
+func (h *Handlers) GetIndexStatus(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+	if h.status == nil {
+		utils.ErrorResponse(c, http.StatusServiceUnavailable, "INDEX_STATUS_UNAVAILABLE", "Index status is not tracked", nil)
+		return
+	}
+
+	summary, err := h.status.Summary(c.Request.Context(), userID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error(), nil)
+		return
+	}
+	utils.SuccessResponse(c, summary, "Index status retrieved successfully")
+}
+
 // IndexBookmark handles bookmark indexing
 func (h *Handlers) IndexBookmark(c *gin.Context) {
 	var bookmark database.Bookmark
@@ -327,3 +514,40 @@ func (h *Handlers) InitializeCollections(c *gin.Context) {
 
 	utils.SuccessResponse(c, gin.H{"message": "Search collections initialized successfully"}, "Search collections initialized successfully")
 }
+
+// RegisterAdminRoutes registers the warm-up endpoints on an admin-only group
+func (h *Handlers) RegisterAdminRoutes(router *gin.RouterGroup) {
+	if h.warmer == nil {
+		return
+	}
+	router.POST("/search/warmup", h.StartWarmup)
+	router.GET("/search/warmup", h.GetWarmup)
+}
+
+// StartWarmup primes the search caches in the background
+// @Summary Warm search caches
+// @Description Run the most common searches of recently active users so the first queries after a deploy or reindex are served from cache
+// @Tags admin
+// @Produce json
+// @Success 202 {object} WarmupProgress
+// @Failure 409 {object} utils.ErrorResponse "A warm-up is already running"
+// @Router /admin/search/warmup [post]
+func (h *Handlers) StartWarmup(c *gin.Context) {
+	progress, err := h.warmer.Start(c.Request.Context(), WarmupTriggerAdmin)
+	if errors.Is(err, ErrWarmupRunning) {
+		utils.ErrorResponse(c, http.StatusConflict, "WARMUP_RUNNING", err.Error(), map[string]interface{}{"progress": progress})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, utils.APIResponse{Success: true, Data: progress, Message: "Search warm-up started"})
+}
+
+// GetWarmup reports the progress of the latest warm-up
+// @Summary Get search warm-up progress
+// @Tags admin
+// @Produce json
+// @Success 200 {object} WarmupProgress
+// @Router /admin/search/warmup [get]
+func (h *Handlers) GetWarmup(c *gin.Context) {
+	utils.SuccessResponse(c, h.warmer.Progress(c.Request.Context()), "Search warm-up progress retrieved")
+}
@@ -0,0 +1,192 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/websocket"
+)
+
+// IndexCompleteMessageType is the WebSocket message pushed once every
+// bookmark of an import has been indexed or given up on
+const IndexCompleteMessageType = "index_complete"
+
+// indexStatusDeleted reports bookmarks removed from the index. It settles
+// them for import watches but is never stored
+const indexStatusDeleted = "deleted"
+
+// Hub delivers messages to a user's connected WebSocket clients
+type Hub interface {
+	BroadcastToUser(userID string, message *websocket.Message)
+}
+
+// IndexStatusSummary counts a user's bookmarks by index status
+type IndexStatusSummary struct {
+	Pending   int64 `json:"pending"`
+	Indexed   int64 `json:"indexed"`
+	Failed    int64 `json:"failed"`
+	Untracked int64 `json:"untracked"` // last written while search was off
+	Total     int64 `json:"total"`
+	Complete  bool  `json:"complete"` // nothing is pending
+}
+
+// IndexComplete is the payload of an index complete event
+type IndexComplete struct {
+	JobID     string `json:"job_id"`
+	Total     int    `json:"total"`
+	Indexed   int    `json:"indexed"`
+	Failed    int    `json:"failed"`
+	Deleted   int    `json:"deleted,omitempty"` // removed before they were indexed
+	FailedIDs []uint `json:"failed_ids,omitempty"`
+}
+
+// indexWatch is an import waiting for its bookmarks to be indexed
+type indexWatch struct {
+	userID  uint
+	waiting map[uint]bool
+	result  IndexComplete
+	expires time.Time
+}
+
+// IndexStatusTracker stores the index status the indexer reports on each
+// bookmark, and tells users when the bookmarks of an import are searchable
+type IndexStatusTracker struct {
+	db     *gorm.DB
+	hub    Hub // optional; without it imports are not watched
+	logger *zap.Logger
+	now    func() time.Time
+
+	mu      sync.Mutex
+	watches map[string]*indexWatch
+}
+
+// NewIndexStatusTracker creates a tracker storing statuses in db
+func NewIndexStatusTracker(db *gorm.DB, logger *zap.Logger) *IndexStatusTracker {
+	return &IndexStatusTracker{
+		db:      db,
+		logger:  logger,
+		now:     time.Now,
+		watches: make(map[string]*indexWatch),
+	}
+}
+
+// SetHub makes the tracker push index complete events for imports
+func (t *IndexStatusTracker) SetHub(hub Hub) {
+	t.hub = hub
+}
+
+// RecordIndexStatus stores the outcome of indexing bookmarks and settles
+// the imports waiting for them
+func (t *IndexStatusTracker) RecordIndexStatus(status string, ids []uint) {
+	if status != indexStatusDeleted {
+		if err := t.db.Model(&database.Bookmark{}).Where("id IN ?", ids).
+			UpdateColumn("index_status", status).Error; err != nil {
+			t.logger.Warn("Failed to record search index status", zap.String("status", status), zap.Error(err))
+		}
+	}
+
+	var complete []*indexWatch
+	t.mu.Lock()
+	now := t.now()
+	for jobID, watch := range t.watches {
+		if now.After(watch.expires) {
+			delete(t.watches, jobID)
+			continue
+		}
+		for _, id := range ids {
+			if !watch.waiting[id] {
+				continue
+			}
+			delete(watch.waiting, id)
+			switch status {
+			case database.IndexStatusIndexed:
+				watch.result.Indexed++
+			case database.IndexStatusFailed:
+				watch.result.Failed++
+				watch.result.FailedIDs = append(watch.result.FailedIDs, id)
+			default:
+				watch.result.Deleted++
+			}
+		}
+		if len(watch.waiting) == 0 {
+			delete(t.watches, jobID)
+			complete = append(complete, watch)
+		}
+	}
+	t.mu.Unlock()
+
+	for _, watch := range complete {
+		t.hub.BroadcastToUser(strconv.FormatUint(uint64(watch.userID), 10), &websocket.Message{
+			Type:      IndexCompleteMessageType,
+			Data:      watch.result,
+			Timestamp: now,
+		})
+	}
+}
+
+// WatchImport waits for the bookmarks of an import to be indexed, then
+// pushes an index complete event to the user. Call it before queueing the
+// bookmarks. It returns the job ID the event carries, or an empty string
+// when there is nothing to watch
+func (t *IndexStatusTracker) WatchImport(userID uint, ids []uint) string {
+	if t.hub == nil || len(ids) == 0 {
+		return ""
+	}
+
+	watch := &indexWatch{
+		userID:  userID,
+		waiting: make(map[uint]bool, len(ids)),
+		result:  IndexComplete{JobID: uuid.NewString()},
+		expires: t.now().Add(config.SearchIndexWatchTimeout),
+	}
+	for _, id := range ids {
+		watch.waiting[id] = true
+	}
+	watch.result.Total = len(watch.waiting)
+
+	t.mu.Lock()
+	t.watches[watch.result.JobID] = watch
+	t.mu.Unlock()
+	return watch.result.JobID
+}
+
+// Summary counts the user's bookmarks by index status
+func (t *IndexStatusTracker) Summary(ctx context.Context, userID uint) (*IndexStatusSummary, error) {
+	var rows []struct {
+		IndexStatus string
+		Count       int64
+	}
+	if err := t.db.WithContext(ctx).Model(&database.Bookmark{}).
+		Select("index_status, COUNT(*) AS count").
+		Where("user_id = ?", userID).
+		Group("index_status").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to count index statuses: %w", err)
+	}
+
+	summary := &IndexStatusSummary{}
+	for _, row := range rows {
+		switch row.IndexStatus {
+		case database.IndexStatusPending:
+			summary.Pending += row.Count
+		case database.IndexStatusIndexed:
+			summary.Indexed += row.Count
+		case database.IndexStatusFailed:
+			summary.Failed += row.Count
+		default:
+			summary.Untracked += row.Count
+		}
+		summary.Total += row.Count
+	}
+	summary.Complete = summary.Pending == 0
+	return summary, nil
+}
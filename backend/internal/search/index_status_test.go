@@ -0,0 +1,107 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/testfactory"
+	"bookmark-sync-service/backend/pkg/websocket"
+)
+
+// fakeHub records the messages pushed to each user
+type fakeHub struct {
+	mu       sync.Mutex
+	messages map[string][]*websocket.Message
+}
+
+func (h *fakeHub) BroadcastToUser(userID string, message *websocket.Message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.messages == nil {
+		h.messages = make(map[string][]*websocket.Message)
+	}
+	h.messages[userID] = append(h.messages[userID], message)
+}
+
+func TestIndexStatusTracker(t *testing.T) {
+	db := testfactory.NewDB(t)
+	factory := testfactory.New(t, db)
+	owner := factory.User()
+	var ids []uint
+	for n := 0; n < 3; n++ {
+		ids = append(ids, factory.Bookmark(owner.ID, func(b *database.Bookmark) { b.IndexStatus = database.IndexStatusPending }).ID)
+	}
+	factory.Bookmark(owner.ID)
+
+	hub := &fakeHub{}
+	tracker := NewIndexStatusTracker(db, zap.NewNop())
+	tracker.SetHub(hub)
+	jobID := tracker.WatchImport(owner.ID, ids)
+	require.NotEmpty(t, jobID)
+
+	// The indexer drives the tracker from a real batch
+	writer := &fakeBulkWriter{rejectIDs: map[string]bool{fmt.Sprint(ids[1]): true}}
+	indexer := newTestIndexer(writer)
+	indexer.maxAttempts = 1
+	indexer.SetStatusRecorder(tracker)
+	for _, id := range ids[:2] {
+		indexer.QueueBookmark(&database.Bookmark{BaseModel: database.BaseModel{ID: id}})
+	}
+	require.NoError(t, indexer.Flush(context.Background()))
+
+	summary, err := tracker.Summary(context.Background(), owner.ID)
+	require.NoError(t, err)
+	assert.Equal(t, IndexStatusSummary{Pending: 1, Indexed: 1, Failed: 1, Untracked: 1, Total: 4}, *summary)
+	assert.Empty(t, hub.messages, "the import still has a pending bookmark")
+
+	indexer.QueueBookmarkDelete(ids[2])
+	require.NoError(t, indexer.Flush(context.Background()))
+
+	messages := hub.messages[fmt.Sprint(owner.ID)]
+	require.Len(t, messages, 1)
+	assert.Equal(t, IndexCompleteMessageType, messages[0].Type)
+	assert.Equal(t, IndexComplete{JobID: jobID, Total: 3, Indexed: 1, Failed: 1, Deleted: 1, FailedIDs: []uint{ids[1]}}, messages[0].Data)
+
+	assert.Empty(t, NewIndexStatusTracker(db, zap.NewNop()).WatchImport(owner.ID, ids), "nothing is watched without a hub")
+}
+
+func TestHandlers_GetIndexStatus(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testfactory.NewDB(t)
+	factory := testfactory.New(t, db)
+	owner := factory.User()
+	factory.Bookmark(owner.ID, func(b *database.Bookmark) { b.IndexStatus = database.IndexStatusIndexed })
+
+	handlers := NewHandlers(nil)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", fmt.Sprint(owner.ID))
+		c.Next()
+	})
+	handlers.RegisterRoutes(router.Group("/api/v1"))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/search/index-status", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	handlers.SetIndexStatus(NewIndexStatusTracker(db, zap.NewNop()))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/search/index-status", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Data IndexStatusSummary `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, IndexStatusSummary{Indexed: 1, Total: 1, Complete: true}, response.Data)
+}
@@ -0,0 +1,350 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/typesense/typesense-go/typesense"
+	"github.com/typesense/typesense-go/typesense/api"
+	"go.uber.org/zap"
+
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+// BulkWriter writes batches of documents to the search engine
+type BulkWriter interface {
+	ImportDocuments(ctx context.Context, collection string, documents []interface{}) ([]*api.ImportDocumentResponse, error)
+	DeleteDocuments(ctx context.Context, collection string, ids []string) (int, error)
+}
+
+// StatusRecorder is told which bookmarks the indexer has written, deleted
+// or given up on
+type StatusRecorder interface {
+	RecordIndexStatus(status string, ids []uint)
+}
+
+// indexOp is a pending change to one search document
+type indexOp struct {
+	collection string
+	id         string
+	document   map[string]interface{} // nil deletes the document
+	attempts   int
+}
+
+func (op indexOp) key() string {
+	return op.collection + "/" + op.id
+}
+
+// IndexerStats counts the documents an indexer has written since it started
+type IndexerStats struct {
+	Pending int   `json:"pending"`
+	Indexed int64 `json:"indexed"`
+	Deleted int64 `json:"deleted"`
+	Retried int64 `json:"retried"`
+	Failed  int64 `json:"failed"` // dropped after the last attempt
+}
+
+// Indexer accumulates bookmark and collection changes and writes them to
+// Typesense in bulk. Changes to the same document are coalesced, so only
+// the latest version is sent. Failed documents are retried on later
+// flushes until they run out of attempts
+type Indexer struct {
+	writer BulkWriter
+	status StatusRecorder // optional
+	logger *zap.Logger
+
+	batchSize     int
+	flushInterval time.Duration
+	maxAttempts   int
+	backoff       time.Duration
+
+	mu      sync.Mutex
+	pending map[string]indexOp
+	stats   IndexerStats
+	flushMu sync.Mutex
+	wake    chan struct{}
+}
+
+// NewIndexer creates a batched indexer writing through writer
+func NewIndexer(writer BulkWriter, logger *zap.Logger) *Indexer {
+	return &Indexer{
+		writer:        writer,
+		logger:        logger,
+		batchSize:     config.SearchIndexBatchSize,
+		flushInterval: config.SearchIndexFlushInterval,
+		maxAttempts:   config.SearchIndexMaxAttempts,
+		backoff:       config.SearchIndexRetryBackoff,
+		pending:       make(map[string]indexOp),
+		wake:          make(chan struct{}, 1),
+	}
+}
+
+// NewIndexer creates a batched indexer writing to this service's Typesense
+func (s *Service) NewIndexer(logger *zap.Logger) *Indexer {
+	return NewIndexer(s.client, logger)
+}
+
+// SetStatusRecorder reports the outcome of every bookmark write to recorder
+func (i *Indexer) SetStatusRecorder(recorder StatusRecorder) {
+	i.status = recorder
+}
+
+// QueueBookmark queues a bookmark to be indexed
+func (i *Indexer) QueueBookmark(bookmark *database.Bookmark) {
+	i.queue(indexOp{collection: "bookmarks", id: fmt.Sprintf("%d", bookmark.ID), document: bookmarkDocument(bookmark)})
+}
+
+// QueueBookmarkDelete queues a bookmark to be removed from the index
+func (i *Indexer) QueueBookmarkDelete(id uint) {
+	i.queue(indexOp{collection: "bookmarks", id: fmt.Sprintf("%d", id)})
+}
+
+// QueueCollection queues a collection to be indexed
+func (i *Indexer) QueueCollection(collection *database.Collection) {
+	i.queue(indexOp{collection: "collections", id: fmt.Sprintf("%d", collection.ID), document: collectionDocument(collection)})
+}
+
+// QueueCollectionDelete queues a collection to be removed from the index
+func (i *Indexer) QueueCollectionDelete(id uint) {
+	i.queue(indexOp{collection: "collections", id: fmt.Sprintf("%d", id)})
+}
+
+// queue replaces any pending change to the same document and wakes the
+// flusher once a full batch is waiting
+func (i *Indexer) queue(op indexOp) {
+	i.mu.Lock()
+	i.pending[op.key()] = op
+	full := len(i.pending) >= i.batchSize
+	i.mu.Unlock()
+
+	if full {
+		select {
+		case i.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// requeue puts a failed change back unless a newer one was queued
+// meanwhile. It reports whether the change was dropped for good
+func (i *Indexer) requeue(op indexOp, reason string) bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if _, newer := i.pending[op.key()]; newer {
+		return false
+	}
+	op.attempts++
+	if op.attempts >= i.maxAttempts {
+		i.stats.Failed++
+		i.logger.Warn("Dropping search document after repeated failures",
+			zap.String("collection", op.collection), zap.String("id", op.id), zap.String("error", reason))
+		return true
+	}
+	i.stats.Retried++
+	i.pending[op.key()] = op
+	return false
+}
+
+// record reports the outcome of bookmark changes to the status recorder.
+// A bookmark changed again since is still pending, so its earlier version
+// being indexed is not reported
+func (i *Indexer) record(status string, ops []indexOp) {
+	if i.status == nil || len(ops) == 0 || ops[0].collection != "bookmarks" {
+		return
+	}
+
+	ids := make([]uint, 0, len(ops))
+	i.mu.Lock()
+	for _, op := range ops {
+		if _, newer := i.pending[op.key()]; newer && status == database.IndexStatusIndexed {
+			continue
+		}
+		if id, err := strconv.ParseUint(op.id, 10, 64); err == nil {
+			ids = append(ids, uint(id))
+		}
+	}
+	i.mu.Unlock()
+
+	if len(ids) > 0 {
+		i.status.RecordIndexStatus(status, ids)
+	}
+}
+
+// Stats returns the indexer counters
+func (i *Indexer) Stats() IndexerStats {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	stats := i.stats
+	stats.Pending = len(i.pending)
+	return stats
+}
+
+// Flush writes every pending change. Changes that fail are queued again
+// for the next flush rather than retried in this one
+func (i *Indexer) Flush(ctx context.Context) error {
+	i.flushMu.Lock()
+	defer i.flushMu.Unlock()
+
+	i.mu.Lock()
+	ops := i.pending
+	i.pending = make(map[string]indexOp)
+	i.mu.Unlock()
+
+	// Group by collection and by upsert or delete, each sent in batches
+	groups := make(map[string][]indexOp)
+	for _, op := range ops {
+		kind := "upsert"
+		if op.document == nil {
+			kind = "delete"
+		}
+		groups[op.collection+"/"+kind] = append(groups[op.collection+"/"+kind], op)
+	}
+
+	var errs []error
+	for _, group := range groups {
+		for start := 0; start < len(group); start += i.batchSize {
+			end := start + i.batchSize
+			if end > len(group) {
+				end = len(group)
+			}
+			batch := group[start:end]
+			if batch[0].document == nil {
+				errs = append(errs, i.deleteBatch(ctx, batch))
+			} else {
+				errs = append(errs, i.importBatch(ctx, batch))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// importBatch upserts a batch and requeues the documents Typesense rejected
+func (i *Indexer) importBatch(ctx context.Context, batch []indexOp) error {
+	documents := make([]interface{}, len(batch))
+	for n, op := range batch {
+		documents[n] = op.document
+	}
+
+	var results []*api.ImportDocumentResponse
+	err := i.withRetry(ctx, func() error {
+		var err error
+		results, err = i.writer.ImportDocuments(ctx, batch[0].collection, documents)
+		return err
+	})
+	if err != nil {
+		var dropped []indexOp
+		for _, op := range batch {
+			if i.requeue(op, err.Error()) {
+				dropped = append(dropped, op)
+			}
+		}
+		i.record(database.IndexStatusFailed, dropped)
+		return fmt.Errorf("failed to import %d %s: %w", len(batch), batch[0].collection, err)
+	}
+
+	var indexed, dropped []indexOp
+	for n, op := range batch {
+		if n < len(results) && results[n] != nil && !results[n].Success {
+			if i.requeue(op, results[n].Error) {
+				dropped = append(dropped, op)
+			}
+			continue
+		}
+		indexed = append(indexed, op)
+	}
+
+	i.mu.Lock()
+	i.stats.Indexed += int64(len(indexed))
+	i.mu.Unlock()
+	i.record(database.IndexStatusIndexed, indexed)
+	i.record(database.IndexStatusFailed, dropped)
+	return nil
+}
+
+// deleteBatch removes a batch of documents with a single filter
+func (i *Indexer) deleteBatch(ctx context.Context, batch []indexOp) error {
+	ids := make([]string, len(batch))
+	for n, op := range batch {
+		ids[n] = op.id
+	}
+
+	err := i.withRetry(ctx, func() error {
+		_, err := i.writer.DeleteDocuments(ctx, batch[0].collection, ids)
+		return err
+	})
+	if err != nil {
+		for _, op := range batch {
+			i.requeue(op, err.Error())
+		}
+		return fmt.Errorf("failed to delete %d %s: %w", len(batch), batch[0].collection, err)
+	}
+
+	i.mu.Lock()
+	i.stats.Deleted += int64(len(batch))
+	i.mu.Unlock()
+	i.record(indexStatusDeleted, batch)
+	return nil
+}
+
+// withRetry retries a request that failed because Typesense was
+// unreachable, overloaded or rate limiting, backing off exponentially
+func (i *Indexer) withRetry(ctx context.Context, fn func() error) error {
+	delay := i.backoff
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = fn(); err == nil || !retryable(err) || attempt >= i.maxAttempts {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// retryable reports whether a failed request may succeed if sent again.
+// Client errors other than rate limiting would fail the same way
+func retryable(err error) bool {
+	var httpErr *typesense.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.Status == http.StatusTooManyRequests || httpErr.Status >= http.StatusInternalServerError
+	}
+	return true
+}
+
+// Run flushes on the configured interval, and as soon as a full batch is
+// waiting, until the context is cancelled. Pending changes are flushed
+// once more on the way out
+func (i *Indexer) Run(ctx context.Context) {
+	ticker := time.NewTicker(i.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), config.WorkerShutdownTimeout)
+			defer cancel()
+			if err := i.Flush(shutdownCtx); err != nil {
+				i.logger.Warn("Final search index flush failed", zap.Error(err))
+			}
+			return
+		case <-ticker.C:
+		case <-i.wake:
+		}
+
+		if err := i.Flush(ctx); err != nil {
+			i.logger.Warn("Search index flush failed", zap.Error(err))
+		}
+	}
+}
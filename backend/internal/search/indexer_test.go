@@ -0,0 +1,137 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/typesense/typesense-go/typesense"
+	"github.com/typesense/typesense-go/typesense/api"
+	"go.uber.org/zap"
+
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+// fakeBulkWriter records batches and fails the documents or requests it is told to
+type fakeBulkWriter struct {
+	mu         sync.Mutex
+	imports    [][]interface{}
+	deletes    [][]string
+	rejectIDs  map[string]bool
+	failNext   []error
+	importCall int
+}
+
+func (w *fakeBulkWriter) ImportDocuments(ctx context.Context, collection string, documents []interface{}) ([]*api.ImportDocumentResponse, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.importCall++
+	if len(w.failNext) > 0 {
+		err := w.failNext[0]
+		w.failNext = w.failNext[1:]
+		return nil, err
+	}
+
+	w.imports = append(w.imports, documents)
+	results := make([]*api.ImportDocumentResponse, len(documents))
+	for n, doc := range documents {
+		id := doc.(map[string]interface{})["id"].(string)
+		results[n] = &api.ImportDocumentResponse{Success: !w.rejectIDs[id]}
+		if w.rejectIDs[id] {
+			results[n].Error = "field `title` must be a string"
+		}
+	}
+	return results, nil
+}
+
+func (w *fakeBulkWriter) DeleteDocuments(ctx context.Context, collection string, ids []string) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.deletes = append(w.deletes, ids)
+	return len(ids), nil
+}
+
+func newTestIndexer(writer BulkWriter) *Indexer {
+	indexer := NewIndexer(writer, zap.NewNop())
+	indexer.batchSize = 2
+	indexer.backoff = time.Millisecond
+	return indexer
+}
+
+func TestIndexer_BatchesAndCoalesces(t *testing.T) {
+	writer := &fakeBulkWriter{}
+	indexer := newTestIndexer(writer)
+
+	indexer.QueueBookmark(&database.Bookmark{BaseModel: database.BaseModel{ID: 1}, Title: "old"})
+	indexer.QueueBookmark(&database.Bookmark{BaseModel: database.BaseModel{ID: 1}, Title: "new"})
+	indexer.QueueBookmark(&database.Bookmark{BaseModel: database.BaseModel{ID: 2}})
+	indexer.QueueBookmark(&database.Bookmark{BaseModel: database.BaseModel{ID: 3}})
+	indexer.QueueBookmarkDelete(4)
+	indexer.QueueCollection(&database.Collection{BaseModel: database.BaseModel{ID: 5}})
+
+	require.NoError(t, indexer.Flush(context.Background()))
+
+	// Three bookmarks in batches of two, one collection, one delete
+	assert.Len(t, writer.imports, 3)
+	assert.Equal(t, [][]string{{"4"}}, writer.deletes)
+	for _, batch := range writer.imports {
+		for _, doc := range batch {
+			if doc.(map[string]interface{})["id"] == "1" {
+				assert.Equal(t, "new", doc.(map[string]interface{})["title"])
+			}
+		}
+	}
+
+	stats := indexer.Stats()
+	assert.Equal(t, int64(4), stats.Indexed)
+	assert.Equal(t, int64(1), stats.Deleted)
+	assert.Zero(t, stats.Pending)
+}
+
+func TestIndexer_PartialFailure(t *testing.T) {
+	writer := &fakeBulkWriter{rejectIDs: map[string]bool{"2": true}}
+	indexer := newTestIndexer(writer)
+
+	indexer.QueueBookmark(&database.Bookmark{BaseModel: database.BaseModel{ID: 1}})
+	indexer.QueueBookmark(&database.Bookmark{BaseModel: database.BaseModel{ID: 2}})
+
+	// The rejected document is retried on later flushes, then dropped
+	for n := 0; n < indexer.maxAttempts; n++ {
+		require.NoError(t, indexer.Flush(context.Background()))
+	}
+
+	stats := indexer.Stats()
+	assert.Equal(t, int64(1), stats.Indexed)
+	assert.Equal(t, int64(indexer.maxAttempts-1), stats.Retried)
+	assert.Equal(t, int64(1), stats.Failed)
+	assert.Zero(t, stats.Pending)
+}
+
+func TestIndexer_RetriesTransientErrors(t *testing.T) {
+	writer := &fakeBulkWriter{failNext: []error{
+		&typesense.HTTPError{Status: 503},
+		errors.New("connection reset"),
+	}}
+	indexer := newTestIndexer(writer)
+
+	indexer.QueueBookmark(&database.Bookmark{BaseModel: database.BaseModel{ID: 1}})
+	require.NoError(t, indexer.Flush(context.Background()))
+
+	assert.Equal(t, 3, writer.importCall)
+	assert.Equal(t, int64(1), indexer.Stats().Indexed)
+}
+
+func TestIndexer_DoesNotRetryClientErrors(t *testing.T) {
+	writer := &fakeBulkWriter{failNext: []error{&typesense.HTTPError{Status: 400}}}
+	indexer := newTestIndexer(writer)
+
+	indexer.QueueBookmark(&database.Bookmark{BaseModel: database.BaseModel{ID: 1}})
+	assert.Error(t, indexer.Flush(context.Background()))
+
+	assert.Equal(t, 1, writer.importCall)
+	assert.Equal(t, 1, indexer.Stats().Pending)
+}
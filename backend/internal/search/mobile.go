@@ -0,0 +1,60 @@
+package search
+
+import (
+	"time"
+
+	"bookmark-sync-service/backend/internal/mobile"
+)
+
+// MobileSearchResult is a search result page in the mobile profile
+type MobileSearchResult struct {
+	Bookmarks []MobileBookmarkResult `json:"bookmarks"`
+	Total     int                    `json:"total"`
+	Page      int                    `json:"page"`
+	Limit     int                    `json:"limit"`
+	Query     string                 `json:"query"`
+}
+
+// MobileBookmarkResult is a search hit without notes, summary or owner,
+// and with one highlight per field
+type MobileBookmarkResult struct {
+	ID          string            `json:"id"`
+	URL         string            `json:"url"`
+	Title       string            `json:"title"`
+	Description string            `json:"description,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+	Highlights  map[string]string `json:"highlights,omitempty"`
+}
+
+// toMobile trims a search result page for the mobile profile
+func toMobile(result *SearchResult) *MobileSearchResult {
+	trimmed := &MobileSearchResult{
+		Bookmarks: make([]MobileBookmarkResult, len(result.Bookmarks)),
+		Total:     result.Total,
+		Page:      result.Page,
+		Limit:     result.Limit,
+		Query:     result.Query,
+	}
+	for i, hit := range result.Bookmarks {
+		bookmark := MobileBookmarkResult{
+			ID:          hit.ID,
+			URL:         hit.URL,
+			Title:       mobile.Truncate(hit.Title, mobile.MaxTitleLength),
+			Description: mobile.Truncate(hit.Description, mobile.MaxDescriptionLength),
+			Tags:        hit.Tags,
+			UpdatedAt:   hit.UpdatedAt,
+		}
+		for field, snippets := range hit.Highlights {
+			if len(snippets) == 0 {
+				continue
+			}
+			if bookmark.Highlights == nil {
+				bookmark.Highlights = make(map[string]string)
+			}
+			bookmark.Highlights[field] = snippets[0]
+		}
+		trimmed.Bookmarks[i] = bookmark
+	}
+	return trimmed
+}
@@ -0,0 +1,34 @@
+package search
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToMobile(t *testing.T) {
+	result := &SearchResult{
+		Bookmarks: []BookmarkSearchResult{{
+			ID:          "1",
+			UserID:      "42",
+			URL:         "https://go.dev",
+			Title:       "Go",
+			Description: strings.Repeat("word ", 100),
+			Notes:       "notes",
+			Summary:     "summary",
+			Highlights:  map[string][]string{"title": {"<mark>Go</mark>", "more"}, "notes": {}},
+		}},
+		Total: 1, Page: 1, Limit: 20, Query: "go",
+	}
+
+	trimmed := toMobile(result)
+
+	require.Len(t, trimmed.Bookmarks, 1)
+	hit := trimmed.Bookmarks[0]
+	assert.Equal(t, map[string]string{"title": "<mark>Go</mark>"}, hit.Highlights)
+	assert.LessOrEqual(t, len([]rune(hit.Description)), 200)
+	assert.Equal(t, 1, trimmed.Total)
+	assert.Equal(t, "go", trimmed.Query)
+}
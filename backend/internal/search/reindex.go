@@ -0,0 +1,87 @@
+package search
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+// ReindexCounts is how many documents a rebuild of the index covers
+type ReindexCounts struct {
+	Bookmarks   int64 `json:"bookmarks"`
+	Collections int64 `json:"collections"`
+}
+
+// CountReindex returns how many documents Reindex would write, for a dry run
+func CountReindex(ctx context.Context, db *gorm.DB) (*ReindexCounts, error) {
+	counts := &ReindexCounts{}
+	if err := db.WithContext(ctx).Model(&database.Bookmark{}).Count(&counts.Bookmarks).Error; err != nil {
+		return nil, fmt.Errorf("failed to count bookmarks: %w", err)
+	}
+	if err := db.WithContext(ctx).Model(&database.Collection{}).Count(&counts.Collections).Error; err != nil {
+		return nil, fmt.Errorf("failed to count collections: %w", err)
+	}
+	return counts, nil
+}
+
+// Reindex rebuilds the index from the database, writing every bookmark and
+// collection through the indexer one batch at a time. Documents Typesense
+// rejects are left queued for the indexer's own retries. progress, when
+// set, is told the number of documents written so far
+func (i *Indexer) Reindex(ctx context.Context, db *gorm.DB, progress func(done int)) (int, error) {
+	done := 0
+	flush := func(n int) error {
+		if err := i.Flush(ctx); err != nil {
+			return err
+		}
+		done += n
+		if progress != nil {
+			progress(done)
+		}
+		return nil
+	}
+
+	var lastID uint
+	for {
+		var bookmarks []database.Bookmark
+		if err := db.WithContext(ctx).Where("id > ?", lastID).Order("id").Limit(i.batchSize).Find(&bookmarks).Error; err != nil {
+			return done, fmt.Errorf("failed to load bookmarks: %w", err)
+		}
+		if len(bookmarks) == 0 {
+			break
+		}
+		for n := range bookmarks {
+			i.QueueBookmark(&bookmarks[n])
+		}
+		lastID = bookmarks[len(bookmarks)-1].ID
+		if err := flush(len(bookmarks)); err != nil {
+			return done, err
+		}
+	}
+
+	lastID = 0
+	for {
+		// The document counts the bookmarks of each collection
+		var collections []database.Collection
+		if err := db.WithContext(ctx).Preload("Bookmarks", func(tx *gorm.DB) *gorm.DB {
+			return tx.Select("bookmarks.id")
+		}).Where("id > ?", lastID).Order("id").Limit(i.batchSize).Find(&collections).Error; err != nil {
+			return done, fmt.Errorf("failed to load collections: %w", err)
+		}
+		if len(collections) == 0 {
+			break
+		}
+		for n := range collections {
+			i.QueueCollection(&collections[n])
+		}
+		lastID = collections[len(collections)-1].ID
+		if err := flush(len(collections)); err != nil {
+			return done, err
+		}
+	}
+
+	return done, nil
+}
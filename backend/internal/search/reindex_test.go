@@ -0,0 +1,43 @@
+package search
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+func TestIndexer_Reindex(t *testing.T) {
+	db, err := database.SetupTestDB()
+	require.NoError(t, err)
+	t.Cleanup(func() { database.CleanupTestDB(db) })
+
+	var bookmarks []database.Bookmark
+	for _, url := range []string{"https://a.example", "https://b.example", "https://c.example"} {
+		bookmark := database.Bookmark{UserID: 1, URL: url, Title: url}
+		require.NoError(t, db.Create(&bookmark).Error)
+		bookmarks = append(bookmarks, bookmark)
+	}
+	collection := &database.Collection{UserID: 1, Name: "Reading", ShareLink: "reindex-reading"}
+	require.NoError(t, db.Create(collection).Error)
+	require.NoError(t, db.Model(collection).Association("Bookmarks").Append(&bookmarks[0], &bookmarks[1]))
+
+	counts, err := CountReindex(context.Background(), db)
+	require.NoError(t, err)
+	assert.Equal(t, &ReindexCounts{Bookmarks: 3, Collections: 1}, counts)
+
+	writer := &fakeBulkWriter{}
+	indexer := newTestIndexer(writer)
+	var progress []int
+	done, err := indexer.Reindex(context.Background(), db, func(done int) { progress = append(progress, done) })
+	require.NoError(t, err)
+
+	assert.Equal(t, 4, done)
+	assert.Equal(t, []int{2, 3, 4}, progress)
+	require.Len(t, writer.imports, 3)
+	assert.Equal(t, 2, writer.imports[2][0].(map[string]interface{})["bookmark_count"])
+	assert.Zero(t, indexer.Stats().Pending)
+}
@@ -2,20 +2,29 @@ package search
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
 	"bookmark-sync-service/backend/internal/config"
 	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/language"
+	"bookmark-sync-service/backend/pkg/notes"
 	"bookmark-sync-service/backend/pkg/search"
+	"bookmark-sync-service/backend/pkg/tags"
 
 	"github.com/typesense/typesense-go/typesense/api"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 // Service provides search functionality using Typesense
 type Service struct {
-	client *search.Client
+	client   *search.Client
+	db       *gorm.DB // optional; used to find followed users
+	cacheTTL int      // seconds Typesense caches results, 0 when it doesn't
 }
 
 // SearchParams represents advanced search parameters
@@ -24,6 +33,8 @@ type SearchParams struct {
 	UserID      string     `json:"user_id"`
 	Tags        []string   `json:"tags,omitempty"`
 	Collections []string   `json:"collections,omitempty"`
+	Languages   []string   `json:"lang,omitempty"`
+	Sources     []string   `json:"source,omitempty"`
 	DateFrom    *time.Time `json:"date_from,omitempty"`
 	DateTo      *time.Time `json:"date_to,omitempty"`
 	SortBy      string     `json:"sort_by,omitempty"`
@@ -48,19 +59,28 @@ type BookmarkSearchResult struct {
 	URL         string              `json:"url"`
 	Title       string              `json:"title"`
 	Description string              `json:"description"`
+	Notes       string              `json:"notes,omitempty"`   // plain text of the Markdown notes
+	Summary     string              `json:"summary,omitempty"` // generated by the summarization provider
 	Tags        []string            `json:"tags"`
 	CreatedAt   time.Time           `json:"created_at"`
 	UpdatedAt   time.Time           `json:"updated_at"`
 	Highlights  map[string][]string `json:"highlights,omitempty"`
 	Score       float64             `json:"score,omitempty"`
+	// Status and LastCheckedAt are the bookmark's latest link check, read
+	// from the database since checks don't reindex the bookmark
+	Status        string     `json:"status,omitempty"`
+	LastCheckedAt *time.Time `json:"last_checked_at,omitempty"`
 }
 
 // CollectionSearchResult represents a collection in search results
 type CollectionSearchResult struct {
 	ID            string              `json:"id"`
 	UserID        string              `json:"user_id"`
+	ParentID      string              `json:"parent_id,omitempty"`
 	Name          string              `json:"name"`
 	Description   string              `json:"description"`
+	Path          []string            `json:"path"` // ancestor names, root first
+	Visibility    string              `json:"visibility"`
 	BookmarkCount int                 `json:"bookmark_count"`
 	CreatedAt     time.Time           `json:"created_at"`
 	UpdatedAt     time.Time           `json:"updated_at"`
@@ -68,6 +88,15 @@ type CollectionSearchResult struct {
 	Score         float64             `json:"score,omitempty"`
 }
 
+// CollectionSearchResults represents collection search results
+type CollectionSearchResults struct {
+	Collections []CollectionSearchResult `json:"collections"`
+	Total       int                      `json:"total"`
+	Page        int                      `json:"page"`
+	Limit       int                      `json:"limit"`
+	Query       string                   `json:"query"`
+}
+
 // SuggestionResult represents search suggestions
 type SuggestionResult struct {
 	Suggestions []string `json:"suggestions"`
@@ -82,11 +111,24 @@ func NewService(cfg config.SearchConfig) (*Service, error) {
 	}
 
 	return &Service{
-		client: client,
+		client:   client,
+		cacheTTL: cfg.CacheTTL,
 	}, nil
 }
 
-// InitializeCollections creates the necessary search collections
+// SetDB lets collection search include the shared collections of followed
+// users, and bookmark results carry their link status
+func (s *Service) SetDB(db *gorm.DB) {
+	s.db = db
+}
+
+// SetLogger logs the searches the client denies for leaving out their user
+func (s *Service) SetLogger(logger *zap.Logger) {
+	s.client.SetLogger(logger)
+}
+
+// InitializeCollections creates the necessary search collections and
+// migrates those that already exist to the current schema
 func (s *Service) InitializeCollections(ctx context.Context) error {
 	// Create bookmarks collection
 	if err := s.client.CreateBookmarkCollection(ctx); err != nil {
@@ -104,57 +146,80 @@ func (s *Service) InitializeCollections(ctx context.Context) error {
 		}
 	}
 
-	return nil
+	return s.client.MigrateSchemas(ctx)
 }
 
 // IndexBookmark indexes a bookmark in the search engine
 func (s *Service) IndexBookmark(ctx context.Context, bookmark *database.Bookmark) error {
-	// Parse tags from JSON string
-	var tags []string
+	return s.client.IndexBookmark(ctx, bookmarkDocument(bookmark))
+}
+
+// UpdateBookmark updates a bookmark in the search engine
+func (s *Service) UpdateBookmark(ctx context.Context, bookmark *database.Bookmark) error {
+	return s.client.UpdateDocument(ctx, "bookmarks", fmt.Sprintf("%d", bookmark.ID), bookmarkDocument(bookmark))
+}
+
+// bookmarkDocument builds the Typesense document for a bookmark, including
+// the derived facet fields (domain, content type, year, language and source) and the
+// ancestors of namespaced tags so that filtering by "dev" also finds "dev/go"
+func bookmarkDocument(bookmark *database.Bookmark) map[string]interface{} {
+	tagList := []string{}
 	if bookmark.Tags != "" {
-		// For now, we'll handle tags as a simple array
-		// In a real implementation, you'd parse the JSON
-		tags = []string{} // TODO: Parse JSON tags
+		if err := json.Unmarshal([]byte(bookmark.Tags), &tagList); err != nil {
+			tagList = []string{}
+		}
 	}
+	tagList = tags.NormalizeAll(tagList)
 
 	doc := map[string]interface{}{
-		"id":          fmt.Sprintf("%d", bookmark.ID),
-		"user_id":     fmt.Sprintf("%d", bookmark.UserID),
-		"url":         bookmark.URL,
-		"title":       bookmark.Title,
-		"description": bookmark.Description,
-		"tags":        tags,
-		"created_at":  bookmark.CreatedAt.Unix(),
-		"updated_at":  bookmark.UpdatedAt.Unix(),
-		"save_count":  bookmark.SaveCount,
+		"id":            fmt.Sprintf("%d", bookmark.ID),
+		"user_id":       fmt.Sprintf("%d", bookmark.UserID),
+		"url":           bookmark.URL,
+		"title":         bookmark.Title,
+		"description":   bookmark.Description,
+		"tags":          tagList,
+		"tag_ancestors": tags.AllAncestors(tagList),
+		"created_at":    bookmark.CreatedAt.Unix(),
+		"updated_at":    bookmark.UpdatedAt.Unix(),
+		"save_count":    bookmark.SaveCount,
+		"domain":        bookmarkDomain(bookmark.URL),
+		"content_type":  bookmarkContentType(bookmark),
+		"year":          bookmark.CreatedAt.Year(),
+		"source":        bookmarkSource(bookmark),
+	}
+
+	// Language is optional in the schema, so unknown languages are left out
+	if bookmark.Language != "" {
+		doc["language"] = bookmark.Language
+	}
+
+	// Notes are indexed as plain text so Markdown syntax doesn't match
+	// queries; encrypted notes are ciphertext and stay out of the index
+	if !bookmark.NotesEncrypted {
+		if text := notes.PlainText(bookmark.Notes); text != "" {
+			doc["notes"] = text
+		}
+	}
+
+	if summary := bookmarkSummary(bookmark); summary != "" {
+		doc["summary"] = summary
 	}
 
-	return s.client.IndexBookmark(ctx, doc)
+	return doc
 }
 
-// UpdateBookmark updates a bookmark in the search engine
-func (s *Service) UpdateBookmark(ctx context.Context, bookmark *database.Bookmark) error {
-	// Parse tags from JSON string
-	var tags []string
-	if bookmark.Tags != "" {
-		// For now, we'll handle tags as a simple array
-		// In a real implementation, you'd parse the JSON
-		tags = []string{} // TODO: Parse JSON tags
+// bookmarkSummary returns the generated summary stored in the bookmark metadata
+func bookmarkSummary(bookmark *database.Bookmark) string {
+	if bookmark.Metadata == "" {
+		return ""
 	}
 
-	doc := map[string]interface{}{
-		"id":          fmt.Sprintf("%d", bookmark.ID),
-		"user_id":     fmt.Sprintf("%d", bookmark.UserID),
-		"url":         bookmark.URL,
-		"title":       bookmark.Title,
-		"description": bookmark.Description,
-		"tags":        tags,
-		"created_at":  bookmark.CreatedAt.Unix(),
-		"updated_at":  bookmark.UpdatedAt.Unix(),
-		"save_count":  bookmark.SaveCount,
+	var metadata map[string]interface{}
+	if err := json.Unmarshal([]byte(bookmark.Metadata), &metadata); err != nil {
+		return ""
 	}
-
-	return s.client.UpdateDocument(ctx, "bookmarks", fmt.Sprintf("%d", bookmark.ID), doc)
+	summary, _ := metadata["summary"].(string)
+	return summary
 }
 
 // DeleteBookmark removes a bookmark from the search engine
@@ -164,6 +229,16 @@ func (s *Service) DeleteBookmark(ctx context.Context, bookmarkID string) error {
 
 // IndexCollection indexes a collection in the search engine
 func (s *Service) IndexCollection(ctx context.Context, collection *database.Collection) error {
+	return s.client.IndexCollection(ctx, collectionDocument(collection))
+}
+
+// collectionDocument builds the Typesense document for a collection. The
+// ancestor path is indexed too, so "work go" finds Go under Work
+func collectionDocument(collection *database.Collection) map[string]interface{} {
+	path := collection.Path
+	if path == nil {
+		path = []string{}
+	}
 	doc := map[string]interface{}{
 		"id":             fmt.Sprintf("%d", collection.ID),
 		"user_id":        fmt.Sprintf("%d", collection.UserID),
@@ -173,23 +248,17 @@ func (s *Service) IndexCollection(ctx context.Context, collection *database.Coll
 		"created_at":     collection.CreatedAt.Unix(),
 		"updated_at":     collection.UpdatedAt.Unix(),
 		"bookmark_count": len(collection.Bookmarks),
+		"path":           path,
 	}
-
-	return s.client.IndexCollection(ctx, doc)
+	if collection.ParentID != nil {
+		doc["parent_id"] = fmt.Sprintf("%d", *collection.ParentID)
+	}
+	return doc
 }
 
 // UpdateCollection updates a collection in the search engine
 func (s *Service) UpdateCollection(ctx context.Context, collection *database.Collection) error {
-	doc := map[string]interface{}{
-		"id":             fmt.Sprintf("%d", collection.ID),
-		"user_id":        fmt.Sprintf("%d", collection.UserID),
-		"name":           collection.Name,
-		"description":    collection.Description,
-		"visibility":     collection.Visibility,
-		"created_at":     collection.CreatedAt.Unix(),
-		"updated_at":     collection.UpdatedAt.Unix(),
-		"bookmark_count": len(collection.Bookmarks),
-	}
+	doc := collectionDocument(collection)
 
 	return s.client.UpdateDocument(ctx, "collections", fmt.Sprintf("%d", collection.ID), doc)
 }
@@ -217,25 +286,53 @@ func (s *Service) SearchBookmarksAdvanced(ctx context.Context, params SearchPara
 		return nil, fmt.Errorf("invalid search parameters: %w", err)
 	}
 
-	// Build filter
-	filterBy := fmt.Sprintf("user_id:%s", params.UserID)
+	// Build filter; the search client scopes it to the user
+	var filters []string
 
-	// Add tag filters
-	if len(params.Tags) > 0 {
-		tagFilters := make([]string, len(params.Tags))
-		for i, tag := range params.Tags {
-			tagFilters[i] = fmt.Sprintf("tags:%s", tag)
+	// note:keyword and tag:pattern terms become filters on their fields
+	query, noteTerms := notes.SplitQuery(params.Query)
+	query, tagPatterns := tags.SplitQuery(query)
+	if query == "" {
+		query = "*"
+	}
+	for _, term := range noteTerms {
+		filters = append(filters, fmt.Sprintf("notes:`%s`", strings.ReplaceAll(term, "`", "")))
+	}
+	for _, pattern := range tagPatterns {
+		tagFilter, err := buildTagFilter([]string{pattern})
+		if err != nil {
+			return nil, fmt.Errorf("invalid search parameters: %w", err)
 		}
-		filterBy += " && (" + strings.Join(tagFilters, " || ") + ")"
+		filters = append(filters, "("+tagFilter+")")
+	}
+
+	// Add tag filters
+	tagFilter, err := buildTagFilter(params.Tags)
+	if err != nil {
+		return nil, fmt.Errorf("invalid search parameters: %w", err)
+	}
+	if tagFilter != "" {
+		filters = append(filters, "("+tagFilter+")")
+	}
+
+	// Add language filter
+	if languageFilter := buildLanguageFilter(params.Languages); languageFilter != "" {
+		filters = append(filters, languageFilter)
+	}
+
+	// Add source filter
+	if sourceFilter := buildSourceFilter(params.Sources); sourceFilter != "" {
+		filters = append(filters, sourceFilter)
 	}
 
 	// Add date filters
 	if params.DateFrom != nil {
-		filterBy += fmt.Sprintf(" && created_at:>=%d", params.DateFrom.Unix())
+		filters = append(filters, fmt.Sprintf("created_at:>=%d", params.DateFrom.Unix()))
 	}
 	if params.DateTo != nil {
-		filterBy += fmt.Sprintf(" && created_at:<=%d", params.DateTo.Unix())
+		filters = append(filters, fmt.Sprintf("created_at:<=%d", params.DateTo.Unix()))
 	}
+	filterBy := strings.Join(filters, " && ")
 
 	// Build sort
 	sortBy := "save_count:desc"
@@ -248,16 +345,16 @@ func (s *Service) SearchBookmarksAdvanced(ctx context.Context, params SearchPara
 	}
 
 	// Prepare search parameters
-	queryByWeights := "4,3,2,1"
-	highlightFields := "title,description"
+	queryByWeights := "4,3,2,2,2,1"
+	highlightFields := "title,description,summary,notes"
 	snippetThreshold := 30
 	numTypos := "2,1,0"
 	minLen1Typo := 4
 	minLen2Typo := 7
 
 	searchParams := &api.SearchCollectionParams{
-		Q:                params.Query,
-		QueryBy:          "title,description,url,tags",
+		Q:                query,
+		QueryBy:          "title,description,summary,notes,url,tags",
 		QueryByWeights:   &queryByWeights,
 		FilterBy:         &filterBy,
 		SortBy:           &sortBy,
@@ -271,7 +368,8 @@ func (s *Service) SearchBookmarksAdvanced(ctx context.Context, params SearchPara
 	}
 
 	// Perform search
-	result, err := s.client.Search(ctx, "bookmarks", searchParams)
+	s.useCache(searchParams)
+	result, err := s.client.Search(search.WithUser(ctx, params.UserID), "bookmarks", searchParams)
 	if err != nil {
 		return nil, fmt.Errorf("search failed: %w", err)
 	}
@@ -289,6 +387,10 @@ func (s *Service) SearchBookmarksAdvanced(ctx context.Context, params SearchPara
 		}
 	}
 
+	if err := s.addLinkHealth(ctx, params.UserID, bookmarks); err != nil {
+		return nil, err
+	}
+
 	total := 0
 	if result.Found != nil {
 		total = *result.Found
@@ -303,21 +405,177 @@ func (s *Service) SearchBookmarksAdvanced(ctx context.Context, params SearchPara
 	}, nil
 }
 
-// SearchCollections searches for collections
-func (s *Service) SearchCollections(ctx context.Context, query, userID string, page, limit int) (*api.SearchResult, error) {
-	filterBy := fmt.Sprintf("user_id:%s", userID)
-	sortBy := "bookmark_count:desc"
+// SearchCollections searches the user's own collections, public ones and
+// the shared collections of users they follow. Results carry the names of
+// their ancestors
+func (s *Service) SearchCollections(ctx context.Context, query, userID string, page, limit int) (*CollectionSearchResults, error) {
+	if strings.TrimSpace(query) == "" {
+		query = "*"
+	}
+
+	followed, err := s.followedUserIDs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	filterBy := collectionSearchFilter(userID, followed)
+	sortBy := "_text_match:desc,bookmark_count:desc"
 
 	searchParams := &api.SearchCollectionParams{
 		Q:        query,
-		QueryBy:  "name,description",
+		QueryBy:  "name,description,path",
 		FilterBy: &filterBy,
 		Page:     &page,
 		PerPage:  &limit,
 		SortBy:   &sortBy,
 	}
 
-	return s.client.Search(ctx, "collections", searchParams)
+	s.useCache(searchParams)
+	result, err := s.client.Search(ctx, "collections", searchParams)
+	if err != nil {
+		return nil, err
+	}
+
+	results := &CollectionSearchResults{
+		Collections: make([]CollectionSearchResult, 0),
+		Page:        page,
+		Limit:       limit,
+		Query:       query,
+	}
+	if result.Found != nil {
+		results.Total = *result.Found
+	}
+	if result.Hits != nil {
+		for _, hit := range *result.Hits {
+			if collection, ok := convertToCollectionResult(hit); ok {
+				results.Collections = append(results.Collections, collection)
+			}
+		}
+	}
+	return results, nil
+}
+
+// useCache lets Typesense answer repeated searches from its result cache
+// when one is configured
+func (s *Service) useCache(params *api.SearchCollectionParams) {
+	if s.cacheTTL <= 0 {
+		return
+	}
+	useCache := true
+	ttl := s.cacheTTL
+	params.UseCache = &useCache
+	params.CacheTtl = &ttl
+}
+
+// followedUserIDs returns the users a user follows, none without a database
+// or for IDs that aren't numeric
+func (s *Service) followedUserIDs(ctx context.Context, userID string) ([]uint, error) {
+	id, err := strconv.ParseUint(userID, 10, 64)
+	if s.db == nil || err != nil {
+		return nil, nil
+	}
+
+	var followed []uint
+	if err := s.db.WithContext(ctx).Model(&database.Follow{}).
+		Where("follower_id = ?", id).Pluck("following_id", &followed).Error; err != nil {
+		return nil, fmt.Errorf("failed to load followed users: %w", err)
+	}
+	return followed, nil
+}
+
+// addLinkHealth fills in the link check status of a page of results with
+// one query. Without a database results go without it
+func (s *Service) addLinkHealth(ctx context.Context, userID string, bookmarks []BookmarkSearchResult) error {
+	if s.db == nil || len(bookmarks) == 0 {
+		return nil
+	}
+	ids := make([]uint64, 0, len(bookmarks))
+	for _, bookmark := range bookmarks {
+		if id, err := strconv.ParseUint(bookmark.ID, 10, 64); err == nil {
+			ids = append(ids, id)
+		}
+	}
+
+	var rows []struct {
+		ID            uint64
+		Status        string
+		LastCheckedAt *time.Time
+	}
+	if err := s.db.WithContext(ctx).Model(&database.Bookmark{}).
+		Select("id, status, last_checked_at").
+		Where("id IN ? AND user_id = ?", ids, userID).Scan(&rows).Error; err != nil {
+		return fmt.Errorf("failed to load link status: %w", err)
+	}
+	byID := make(map[string]int, len(rows))
+	for i, row := range rows {
+		byID[strconv.FormatUint(row.ID, 10)] = i
+	}
+	for i := range bookmarks {
+		if n, ok := byID[bookmarks[i].ID]; ok {
+			bookmarks[i].Status = rows[n].Status
+			bookmarks[i].LastCheckedAt = rows[n].LastCheckedAt
+		}
+	}
+	return nil
+}
+
+// collectionSearchFilter limits collection search to what a user may see:
+// their own collections, public ones, and shared ones of followed users
+func collectionSearchFilter(userID string, followed []uint) string {
+	filter := fmt.Sprintf("user_id:=%s || visibility:=public", userID)
+	if len(followed) > 0 {
+		ids := make([]string, len(followed))
+		for n, id := range followed {
+			ids[n] = strconv.FormatUint(uint64(id), 10)
+		}
+		filter += fmt.Sprintf(" || (user_id:=[%s] && visibility:=shared)", strings.Join(ids, ","))
+	}
+	return filter
+}
+
+// convertToCollectionResult converts a search hit to a collection result
+func convertToCollectionResult(hit api.SearchResultHit) (CollectionSearchResult, bool) {
+	if hit.Document == nil {
+		return CollectionSearchResult{}, false
+	}
+	doc := *hit.Document
+
+	result := CollectionSearchResult{Path: []string{}}
+	result.ID, _ = doc["id"].(string)
+	result.UserID, _ = doc["user_id"].(string)
+	result.ParentID, _ = doc["parent_id"].(string)
+	result.Name, _ = doc["name"].(string)
+	result.Description, _ = doc["description"].(string)
+	result.Visibility, _ = doc["visibility"].(string)
+	if count, ok := doc["bookmark_count"].(float64); ok {
+		result.BookmarkCount = int(count)
+	}
+	if path, ok := doc["path"].([]interface{}); ok {
+		for _, name := range path {
+			if name, ok := name.(string); ok {
+				result.Path = append(result.Path, name)
+			}
+		}
+	}
+	if createdAt, ok := doc["created_at"].(float64); ok {
+		result.CreatedAt = time.Unix(int64(createdAt), 0)
+	}
+	if updatedAt, ok := doc["updated_at"].(float64); ok {
+		result.UpdatedAt = time.Unix(int64(updatedAt), 0)
+	}
+
+	if hit.Highlights != nil {
+		result.Highlights = make(map[string][]string)
+		for _, highlight := range *hit.Highlights {
+			if highlight.Field != nil && highlight.Snippets != nil && len(*highlight.Snippets) > 0 {
+				result.Highlights[*highlight.Field] = *highlight.Snippets
+			}
+		}
+	}
+	if hit.TextMatch != nil {
+		result.Score = float64(*hit.TextMatch)
+	}
+	return result, true
 }
 
 // GetSuggestions returns search suggestions based on partial query
@@ -326,20 +584,18 @@ func (s *Service) GetSuggestions(ctx context.Context, query, userID string, limi
 		limit = 5
 	}
 
-	filterBy := fmt.Sprintf("user_id:%s", userID)
 	page := 1
 	sortBy := "save_count:desc"
 
 	searchParams := &api.SearchCollectionParams{
-		Q:        query,
-		QueryBy:  "title,tags",
-		FilterBy: &filterBy,
-		Page:     &page,
-		PerPage:  &limit,
-		SortBy:   &sortBy,
+		Q:       query,
+		QueryBy: "title,tags",
+		Page:    &page,
+		PerPage: &limit,
+		SortBy:  &sortBy,
 	}
 
-	result, err := s.client.Search(ctx, "bookmarks", searchParams)
+	result, err := s.client.Search(search.WithUser(ctx, userID), "bookmarks", searchParams)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get suggestions: %w", err)
 	}
@@ -412,6 +668,12 @@ func (s *Service) convertToBookmarkResult(hit api.SearchResultHit) (BookmarkSear
 	if description, ok := doc["description"].(string); ok {
 		result.Description = description
 	}
+	if text, ok := doc["notes"].(string); ok {
+		result.Notes = text
+	}
+	if summary, ok := doc["summary"].(string); ok {
+		result.Summary = summary
+	}
 
 	// Extract tags
 	if tags, ok := doc["tags"].([]interface{}); ok {
@@ -449,6 +711,56 @@ func (s *Service) convertToBookmarkResult(hit api.SearchResultHit) (BookmarkSear
 	return result, nil
 }
 
+// buildTagFilter builds a Typesense filter matching any of the tag patterns.
+// "dev" matches the tag and its descendants, "dev/*" only its descendants.
+// Tags are quoted in backticks, which Typesense can't escape, so tags with
+// a backtick are refused rather than dropped, which would widen the search
+func buildTagFilter(patterns []string) (string, error) {
+	var filters []string
+	for _, pattern := range patterns {
+		base, descendantsOnly := tags.ParsePattern(pattern)
+		if base == "" {
+			continue
+		}
+		if strings.Contains(base, "`") {
+			return "", fmt.Errorf("tag %q must not contain backticks", base)
+		}
+		if descendantsOnly {
+			filters = append(filters, fmt.Sprintf("tag_ancestors:=`%s`", base))
+		} else {
+			filters = append(filters, fmt.Sprintf("tags:=`%s` || tag_ancestors:=`%s`", base, base))
+		}
+	}
+	return strings.Join(filters, " || "), nil
+}
+
+// buildLanguageFilter builds a Typesense filter matching any of the given language codes
+func buildLanguageFilter(codes []string) string {
+	codes = language.NormalizeAll(codes)
+	if len(codes) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("language:[%s]", strings.Join(codes, ","))
+}
+
+// bookmarkSource is the indexed source of a bookmark; bookmarks saved
+// before sources were recorded are indexed as unknown so they can be
+// filtered and counted too
+func bookmarkSource(bookmark *database.Bookmark) string {
+	if bookmark.Source == "" {
+		return database.BookmarkSourceUnknown
+	}
+	return bookmark.Source
+}
+
+// buildSourceFilter builds a Typesense filter matching any of the given bookmark sources
+func buildSourceFilter(sources []string) string {
+	if len(sources) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("source:[%s]", strings.Join(sources, ","))
+}
+
 // Validate validates search parameters
 func (p *SearchParams) Validate() error {
 	if p.UserID == "" {
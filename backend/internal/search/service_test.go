@@ -2,6 +2,7 @@ package search
 
 import (
 	"context"
+	"strconv"
 	"testing"
 	"time"
 
@@ -10,6 +11,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/typesense/typesense-go/typesense/api"
 )
 
 func TestNewService(t *testing.T) {
@@ -160,6 +162,109 @@ func TestService_SearchCollections(t *testing.T) {
 	assert.NotNil(t, results)
 }
 
+func TestCollectionSearchFilter(t *testing.T) {
+	assert.Equal(t, "user_id:=7 || visibility:=public", collectionSearchFilter("7", nil))
+	assert.Equal(t, "user_id:=7 || visibility:=public || (user_id:=[2,3] && visibility:=shared)",
+		collectionSearchFilter("7", []uint{2, 3}))
+}
+
+func TestService_FollowedUserIDs(t *testing.T) {
+	db, err := database.SetupTestDB()
+	require.NoError(t, err)
+	defer database.CleanupTestDB(db)
+	require.NoError(t, db.Create(&database.Follow{FollowerID: 1, FollowingID: 2}).Error)
+	require.NoError(t, db.Create(&database.Follow{FollowerID: 3, FollowingID: 1}).Error)
+
+	service := &Service{}
+	followed, err := service.followedUserIDs(context.Background(), "1")
+	require.NoError(t, err)
+	assert.Empty(t, followed, "no database means no followed users")
+
+	service.SetDB(db)
+	followed, err = service.followedUserIDs(context.Background(), "1")
+	require.NoError(t, err)
+	assert.Equal(t, []uint{2}, followed)
+
+	followed, err = service.followedUserIDs(context.Background(), "test-user-1")
+	require.NoError(t, err)
+	assert.Empty(t, followed)
+}
+
+func TestService_AddLinkHealth(t *testing.T) {
+	db, err := database.SetupTestDB()
+	require.NoError(t, err)
+	defer database.CleanupTestDB(db)
+	checked := time.Now().UTC().Truncate(time.Second)
+	broken := &database.Bookmark{UserID: 1, URL: "https://gone.example", Title: "Gone", Status: string(database.LinkStatusBroken), LastCheckedAt: &checked}
+	fresh := &database.Bookmark{UserID: 1, URL: "https://new.example", Title: "New"}
+	require.NoError(t, db.Create(broken).Error)
+	require.NoError(t, db.Create(fresh).Error)
+
+	results := []BookmarkSearchResult{
+		{ID: strconv.FormatUint(uint64(broken.ID), 10)},
+		{ID: strconv.FormatUint(uint64(fresh.ID), 10)},
+		{ID: "999"},
+	}
+	service := &Service{}
+	service.SetDB(db)
+	require.NoError(t, service.addLinkHealth(context.Background(), "1", results))
+
+	assert.Equal(t, "broken", results[0].Status)
+	require.NotNil(t, results[0].LastCheckedAt)
+	assert.True(t, checked.Equal(*results[0].LastCheckedAt))
+	assert.Equal(t, "active", results[1].Status)
+	assert.Nil(t, results[1].LastCheckedAt, "never checked")
+	assert.Empty(t, results[2].Status)
+
+	// Other users' bookmarks are never read
+	results = []BookmarkSearchResult{{ID: strconv.FormatUint(uint64(broken.ID), 10)}}
+	require.NoError(t, service.addLinkHealth(context.Background(), "2", results))
+	assert.Empty(t, results[0].Status)
+}
+
+func TestCollectionDocument_Path(t *testing.T) {
+	parentID := uint(4)
+	collection := &database.Collection{
+		BaseModel:  database.BaseModel{ID: 9},
+		UserID:     1,
+		Name:       "Go",
+		ParentID:   &parentID,
+		Visibility: "public",
+		Path:       []string{"Work", "Projects"},
+	}
+
+	doc := collectionDocument(collection)
+	assert.Equal(t, []string{"Work", "Projects"}, doc["path"])
+	assert.Equal(t, "4", doc["parent_id"])
+
+	doc = collectionDocument(&database.Collection{Name: "Root"})
+	assert.Equal(t, []string{}, doc["path"])
+	assert.NotContains(t, doc, "parent_id")
+}
+
+func TestConvertToCollectionResult(t *testing.T) {
+	doc := map[string]interface{}{
+		"id":             "9",
+		"user_id":        "1",
+		"parent_id":      "4",
+		"name":           "Go",
+		"visibility":     "public",
+		"bookmark_count": float64(3),
+		"path":           []interface{}{"Work", "Projects"},
+		"created_at":     float64(1700000000),
+	}
+	result, ok := convertToCollectionResult(api.SearchResultHit{Document: &doc})
+	require.True(t, ok)
+	assert.Equal(t, "9", result.ID)
+	assert.Equal(t, "4", result.ParentID)
+	assert.Equal(t, []string{"Work", "Projects"}, result.Path)
+	assert.Equal(t, 3, result.BookmarkCount)
+	assert.Equal(t, int64(1700000000), result.CreatedAt.Unix())
+
+	_, ok = convertToCollectionResult(api.SearchResultHit{})
+	assert.False(t, ok)
+}
+
 func TestService_GetSuggestions(t *testing.T) {
 	cfg := config.SearchConfig{
 		Host:   "localhost",
@@ -0,0 +1,346 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/pkg/database"
+	redispkg "bookmark-sync-service/backend/pkg/redis"
+)
+
+// Warm-up statuses
+const (
+	WarmupIdle      = "idle"
+	WarmupRunning   = "running"
+	WarmupCompleted = "completed"
+	WarmupFailed    = "failed"
+)
+
+// Warm-up triggers
+const (
+	WarmupTriggerAdmin   = "admin"
+	WarmupTriggerStartup = "startup"
+)
+
+// ErrWarmupRunning is returned when a warm-up is already in progress
+var ErrWarmupRunning = errors.New("search warm-up already running")
+
+// WarmupSearcher runs the searches a warm-up primes
+type WarmupSearcher interface {
+	SearchBookmarksAdvanced(ctx context.Context, params SearchParams) (*SearchResult, error)
+	SearchCollections(ctx context.Context, query, userID string, page, limit int) (*CollectionSearchResults, error)
+}
+
+// QueryHistory returns a user's recent searches, newest first
+type QueryHistory interface {
+	GetSearchHistory(ctx context.Context, userID string, limit int) (*SearchHistoryResult, error)
+}
+
+// EntityPrimer fills the read-through caches of a user's hot entities
+type EntityPrimer interface {
+	WarmPublicBookmarks(ctx context.Context, userID uint) error
+}
+
+// WarmupProgress reports on the latest warm-up
+type WarmupProgress struct {
+	Status     string     `json:"status"`
+	Trigger    string     `json:"trigger,omitempty"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	UpdatedAt  *time.Time `json:"updated_at,omitempty"`
+	Users      int        `json:"users"`      // users being warmed
+	UsersDone  int        `json:"users_done"` // users warmed so far
+	Queries    int        `json:"queries"`    // searches run
+	Failed     int        `json:"failed"`     // searches and cache fills that failed
+	Error      string     `json:"error,omitempty"`
+}
+
+// Warmer primes the Typesense result cache and the Redis read-through caches
+// after a deploy or reindex. For each recently active user it runs their
+// default searches and most common queries, with the parameters the search
+// endpoints use so the cached results are hit, and fills the caches of
+// their public pages. Only one warm-up runs at a time across replicas
+type Warmer struct {
+	searcher WarmupSearcher
+	db       *gorm.DB
+	cfg      config.SearchConfig
+	logger   *zap.Logger
+	history  QueryHistory            // optional; without it only default searches are warmed
+	primer   EntityPrimer            // optional
+	store    redispkg.RedisInterface // optional; shares progress between replicas
+	locker   redispkg.Locker         // optional
+	now      func() time.Time
+
+	mu       sync.Mutex
+	progress WarmupProgress
+}
+
+// NewWarmer creates a warmer running searches through searcher
+func NewWarmer(searcher WarmupSearcher, db *gorm.DB, cfg config.SearchConfig, logger *zap.Logger) *Warmer {
+	return &Warmer{
+		searcher: searcher,
+		db:       db,
+		cfg:      cfg,
+		logger:   logger,
+		now:      time.Now,
+		progress: WarmupProgress{Status: WarmupIdle},
+	}
+}
+
+// SetHistory makes the warmer run each user's most common queries
+func (w *Warmer) SetHistory(history QueryHistory) {
+	w.history = history
+}
+
+// SetPrimer makes the warmer fill the caches of users' public pages
+func (w *Warmer) SetPrimer(primer EntityPrimer) {
+	w.primer = primer
+}
+
+// SetStore shares progress through store and keeps replicas from warming
+// at the same time
+func (w *Warmer) SetStore(store redispkg.RedisInterface, locker redispkg.Locker) {
+	w.store = store
+	w.locker = locker
+}
+
+// Start begins a warm-up in the background. It returns ErrWarmupRunning
+// when one is already in progress on this or another replica
+func (w *Warmer) Start(ctx context.Context, trigger string) (WarmupProgress, error) {
+	if current := w.Progress(ctx); w.running(current) {
+		return current, ErrWarmupRunning
+	}
+
+	w.mu.Lock()
+	if w.progress.Status == WarmupRunning {
+		progress := w.progress
+		w.mu.Unlock()
+		return progress, ErrWarmupRunning
+	}
+	previous := w.progress
+	now := w.now().UTC()
+	w.progress = WarmupProgress{Status: WarmupRunning, Trigger: trigger, StartedAt: &now, UpdatedAt: &now}
+	progress := w.progress
+	w.mu.Unlock()
+
+	go func() {
+		ctx := context.Background()
+		var err error
+		if w.locker != nil {
+			err = w.locker.WithLock(ctx, "search-warmup", config.SearchWarmupLockTTL, w.Run)
+		} else {
+			err = w.Run(ctx)
+		}
+		if errors.Is(err, redispkg.ErrLockNotAcquired) {
+			w.logger.Info("Search warm-up already running on another replica")
+			w.mu.Lock()
+			w.progress = previous
+			w.mu.Unlock()
+		}
+	}()
+
+	return progress, nil
+}
+
+// Progress returns the state of the latest warm-up on any replica
+func (w *Warmer) Progress(ctx context.Context) WarmupProgress {
+	w.mu.Lock()
+	local := w.progress
+	w.mu.Unlock()
+
+	if w.store == nil || local.Status == WarmupRunning {
+		return local
+	}
+	raw, err := w.store.Get(ctx, config.SearchWarmupKey)
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			w.logger.Warn("Failed to load search warm-up progress", zap.Error(err))
+		}
+		return local
+	}
+	var shared WarmupProgress
+	if err := json.Unmarshal([]byte(raw), &shared); err != nil {
+		return local
+	}
+	return shared
+}
+
+// running reports whether a warm-up is in progress. A replica that stopped
+// reporting for longer than the lock lasts has crashed
+func (w *Warmer) running(progress WarmupProgress) bool {
+	return progress.Status == WarmupRunning && progress.UpdatedAt != nil &&
+		w.now().Sub(*progress.UpdatedAt) < config.SearchWarmupLockTTL
+}
+
+// Run warms the caches of the most recently active users, recording
+// progress as each user is done
+func (w *Warmer) Run(ctx context.Context) error {
+	w.update(ctx, func(progress *WarmupProgress) {
+		if progress.Status != WarmupRunning {
+			now := w.now().UTC()
+			*progress = WarmupProgress{Status: WarmupRunning, StartedAt: &now}
+		}
+	})
+
+	userIDs, err := w.activeUsers(ctx)
+	if err != nil {
+		w.finish(ctx, err)
+		return err
+	}
+	w.update(ctx, func(progress *WarmupProgress) { progress.Users = len(userIDs) })
+
+	for _, userID := range userIDs {
+		if err := ctx.Err(); err != nil {
+			w.finish(ctx, err)
+			return err
+		}
+		queries, failed := w.warmUser(ctx, userID)
+		w.update(ctx, func(progress *WarmupProgress) {
+			progress.UsersDone++
+			progress.Queries += queries
+			progress.Failed += failed
+		})
+	}
+
+	w.finish(ctx, nil)
+	w.mu.Lock()
+	progress := w.progress
+	w.mu.Unlock()
+	w.logger.Info("Search warm-up completed",
+		zap.Int("users", progress.Users),
+		zap.Int("queries", progress.Queries),
+		zap.Int("failed", progress.Failed),
+	)
+	return nil
+}
+
+// activeUsers returns the users who saved or changed bookmarks most
+// recently, most recent first
+func (w *Warmer) activeUsers(ctx context.Context) ([]uint, error) {
+	since := w.now().AddDate(0, 0, -w.cfg.WarmupActiveDays)
+	var userIDs []uint
+	if err := w.db.WithContext(ctx).Model(&database.Bookmark{}).
+		Where("updated_at >= ?", since).
+		Group("user_id").
+		Order("MAX(updated_at) DESC").
+		Limit(w.cfg.WarmupUsers).
+		Pluck("user_id", &userIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to find active users: %w", err)
+	}
+	return userIDs, nil
+}
+
+// warmUser runs a user's searches and fills their caches, returning the
+// searches run and the steps that failed
+func (w *Warmer) warmUser(ctx context.Context, userID uint) (queries, failed int) {
+	id := strconv.FormatUint(uint64(userID), 10)
+	logger := w.logger.With(zap.Uint("user_id", userID))
+
+	for _, query := range w.commonQueries(ctx, id) {
+		queries++
+		params := SearchParams{Query: query, UserID: id, Page: 1, Limit: config.SearchWarmupPageSize}
+		if _, err := w.searcher.SearchBookmarksAdvanced(ctx, params); err != nil {
+			failed++
+			logger.Debug("Search warm-up query failed", zap.String("query", query), zap.Error(err))
+		}
+	}
+
+	queries++
+	if _, err := w.searcher.SearchCollections(ctx, "", id, 1, config.SearchWarmupPageSize); err != nil {
+		failed++
+		logger.Debug("Search warm-up collection query failed", zap.Error(err))
+	}
+
+	if w.primer != nil {
+		if err := w.primer.WarmPublicBookmarks(ctx, userID); err != nil {
+			failed++
+			logger.Debug("Failed to warm public bookmarks", zap.Error(err))
+		}
+	}
+	return queries, failed
+}
+
+// commonQueries returns the empty query every search page opens with,
+// followed by the user's most frequent recent queries
+func (w *Warmer) commonQueries(ctx context.Context, userID string) []string {
+	queries := []string{""}
+	if w.history == nil || w.cfg.WarmupQueries <= 0 {
+		return queries
+	}
+
+	history, err := w.history.GetSearchHistory(ctx, userID, 100)
+	if err != nil {
+		w.logger.Debug("Failed to load search history", zap.String("user_id", userID), zap.Error(err))
+		return queries
+	}
+
+	// Ties keep the most recent query first
+	counts := make(map[string]int)
+	var order []string
+	for _, entry := range history.Entries {
+		query := strings.TrimSpace(entry.Query)
+		if query == "" {
+			continue
+		}
+		if counts[query] == 0 {
+			order = append(order, query)
+		}
+		counts[query]++
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return counts[order[i]] > counts[order[j]]
+	})
+	if len(order) > w.cfg.WarmupQueries {
+		order = order[:w.cfg.WarmupQueries]
+	}
+	return append(queries, order...)
+}
+
+// update changes the progress and shares it with other replicas
+func (w *Warmer) update(ctx context.Context, change func(progress *WarmupProgress)) {
+	w.mu.Lock()
+	change(&w.progress)
+	now := w.now().UTC()
+	w.progress.UpdatedAt = &now
+	progress := w.progress
+	w.mu.Unlock()
+
+	if w.store == nil {
+		return
+	}
+	data, err := json.Marshal(progress)
+	if err != nil {
+		return
+	}
+	if err := w.store.Set(context.WithoutCancel(ctx), config.SearchWarmupKey, data, 0); err != nil {
+		w.logger.Warn("Failed to store search warm-up progress", zap.Error(err))
+	}
+}
+
+// finish records the outcome of a warm-up
+func (w *Warmer) finish(ctx context.Context, err error) {
+	if err != nil {
+		w.logger.Error("Search warm-up failed", zap.Error(err))
+	}
+	w.update(ctx, func(progress *WarmupProgress) {
+		now := w.now().UTC()
+		progress.FinishedAt = &now
+		progress.Status = WarmupCompleted
+		if err != nil {
+			progress.Status = WarmupFailed
+			progress.Error = err.Error()
+		}
+	})
+}
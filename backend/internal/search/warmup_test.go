@@ -0,0 +1,131 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/redis"
+)
+
+// warmupRecorder records the searches and cache fills of a warm-up
+type warmupRecorder struct {
+	bookmarks   map[string][]string
+	collections []string
+	primed      []uint
+	failQuery   string
+}
+
+func (r *warmupRecorder) SearchBookmarksAdvanced(ctx context.Context, params SearchParams) (*SearchResult, error) {
+	if params.Page != 1 || params.Limit != config.SearchWarmupPageSize {
+		return nil, errors.New("unexpected page")
+	}
+	r.bookmarks[params.UserID] = append(r.bookmarks[params.UserID], params.Query)
+	if params.Query == r.failQuery {
+		return nil, errors.New("search unavailable")
+	}
+	return &SearchResult{}, nil
+}
+
+func (r *warmupRecorder) SearchCollections(ctx context.Context, query, userID string, page, limit int) (*CollectionSearchResults, error) {
+	r.collections = append(r.collections, userID)
+	return &CollectionSearchResults{}, nil
+}
+
+func (r *warmupRecorder) WarmPublicBookmarks(ctx context.Context, userID uint) error {
+	r.primed = append(r.primed, userID)
+	return nil
+}
+
+type staticHistory map[string][]string
+
+func (h staticHistory) GetSearchHistory(ctx context.Context, userID string, limit int) (*SearchHistoryResult, error) {
+	result := &SearchHistoryResult{}
+	for _, query := range h[userID] {
+		result.Entries = append(result.Entries, SearchHistoryEntry{UserID: userID, Query: query})
+	}
+	return result, nil
+}
+
+func TestWarmer_Run(t *testing.T) {
+	db, err := database.SetupTestDB()
+	require.NoError(t, err)
+	t.Cleanup(func() { database.CleanupTestDB(db) })
+
+	now := time.Now()
+	for userID, age := range map[uint]time.Duration{1: 2 * time.Hour, 2: time.Hour, 3: 30 * 24 * time.Hour} {
+		bookmark := &database.Bookmark{UserID: userID, URL: "https://example.com", Title: "Example"}
+		require.NoError(t, db.Create(bookmark).Error)
+		require.NoError(t, db.Model(bookmark).UpdateColumn("updated_at", now.Add(-age)).Error)
+	}
+
+	recorder := &warmupRecorder{bookmarks: map[string][]string{}, failQuery: "rust"}
+	warmer := NewWarmer(recorder, db, config.SearchConfig{WarmupUsers: 10, WarmupQueries: 2, WarmupActiveDays: 14}, zap.NewNop())
+	warmer.SetHistory(staticHistory{
+		"1": {"go", " rust ", "go", "docker", "rust", "go", ""},
+		"2": {"docker"},
+	})
+	warmer.SetPrimer(recorder)
+
+	require.NoError(t, warmer.Run(context.Background()))
+
+	// Inactive users are skipped, the most recently active user goes first
+	assert.Equal(t, []string{"2", "1"}, recorder.collections)
+	assert.Equal(t, []uint{2, 1}, recorder.primed)
+	assert.Equal(t, []string{"", "go", "rust"}, recorder.bookmarks["1"])
+	assert.Equal(t, []string{"", "docker"}, recorder.bookmarks["2"])
+
+	progress := warmer.Progress(context.Background())
+	assert.Equal(t, WarmupCompleted, progress.Status)
+	assert.Equal(t, 2, progress.Users)
+	assert.Equal(t, 2, progress.UsersDone)
+	assert.Equal(t, 7, progress.Queries)
+	assert.Equal(t, 1, progress.Failed)
+	assert.NotNil(t, progress.FinishedAt)
+}
+
+func TestWarmer_StartSharesProgress(t *testing.T) {
+	db, err := database.SetupTestDB()
+	require.NoError(t, err)
+	t.Cleanup(func() { database.CleanupTestDB(db) })
+
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+	client, err := redis.NewClient(config.RedisConfig{Host: mr.Host(), Port: mr.Port(), PoolSize: 1})
+	require.NoError(t, err)
+
+	newWarmer := func() *Warmer {
+		warmer := NewWarmer(&warmupRecorder{bookmarks: map[string][]string{}}, db, config.SearchConfig{WarmupUsers: 10, WarmupActiveDays: 14}, zap.NewNop())
+		warmer.SetStore(client, client)
+		return warmer
+	}
+	ctx := context.Background()
+
+	// Another replica is warming the caches
+	other := newWarmer()
+	other.update(ctx, func(progress *WarmupProgress) { progress.Status = WarmupRunning })
+	warmer := newWarmer()
+	_, err = warmer.Start(ctx, WarmupTriggerAdmin)
+	assert.ErrorIs(t, err, ErrWarmupRunning)
+
+	// A replica that stopped reporting no longer blocks a warm-up, and others
+	// see the new one finish
+	warmer.now = func() time.Time { return time.Now().Add(config.SearchWarmupLockTTL) }
+	progress, err := warmer.Start(ctx, WarmupTriggerAdmin)
+	require.NoError(t, err)
+	assert.Equal(t, WarmupRunning, progress.Status)
+	assert.Equal(t, WarmupTriggerAdmin, progress.Trigger)
+
+	assert.Eventually(t, func() bool {
+		return newWarmer().Progress(ctx).Status == WarmupCompleted
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,61 @@
+package seo
+
+import (
+	"net/http"
+
+	"bookmark-sync-service/backend/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// crawlerCacheControl lets CDNs serve robots.txt and the sitemap between refreshes
+const crawlerCacheControl = "public, max-age=3600"
+
+// Handler serves robots.txt and sitemap.xml
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new SEO handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes registers the crawler routes on the router root
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/robots.txt", h.GetRobots)
+	router.GET("/sitemap.xml", h.GetSitemap)
+}
+
+// GetRobots serves robots.txt
+// @Summary Get robots.txt
+// @Tags seo
+// @Produce plain
+// @Success 200 {string} string "robots.txt"
+// @Router /robots.txt [get]
+func (h *Handler) GetRobots(c *gin.Context) {
+	c.Header("Cache-Control", crawlerCacheControl)
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(h.service.RobotsTxt()))
+}
+
+// GetSitemap serves the precomputed sitemap of public shares
+// @Summary Get sitemap.xml
+// @Tags seo
+// @Produce xml
+// @Success 200 {string} string "Sitemap"
+// @Failure 404 {object} utils.ErrorResponse "Indexing disabled"
+// @Router /sitemap.xml [get]
+func (h *Handler) GetSitemap(c *gin.Context) {
+	sitemap, err := h.service.Sitemap(c.Request.Context())
+	if err != nil {
+		if err == ErrDisabled {
+			utils.ErrorResponse(c, http.StatusNotFound, "NOT_FOUND", err.Error(), nil)
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "SITEMAP_FAILED", err.Error(), nil)
+		return
+	}
+
+	c.Header("Cache-Control", crawlerCacheControl)
+	c.Data(http.StatusOK, "application/xml; charset=utf-8", sitemap)
+}
@@ -0,0 +1,257 @@
+package seo
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/internal/sharing"
+	"bookmark-sync-service/backend/internal/user"
+	"bookmark-sync-service/backend/pkg/database"
+	redispkg "bookmark-sync-service/backend/pkg/redis"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ErrDisabled is returned when the sitemap is requested while indexing is off
+var ErrDisabled = errors.New("public indexing is disabled")
+
+// sitemapNamespace is the XML namespace of the sitemaps.org protocol
+const sitemapNamespace = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+// URLSet is a sitemaps.org sitemap document
+type URLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	URLs    []SitemapURL `xml:"url"`
+}
+
+// SitemapURL is a single page listed in the sitemap
+type SitemapURL struct {
+	Loc        string `xml:"loc"`
+	LastMod    string `xml:"lastmod,omitempty"`
+	ChangeFreq string `xml:"changefreq,omitempty"`
+}
+
+// Service builds the sitemap and robots.txt of a public instance. The
+// worker precomputes the sitemap on an interval and shares it through Redis,
+// so crawlers never trigger database scans
+type Service struct {
+	cfg     config.SEOConfig
+	db      *gorm.DB
+	redis   redispkg.RedisInterface
+	baseURL string
+	logger  *zap.Logger
+	now     func() time.Time
+
+	mu      sync.RWMutex
+	sitemap []byte
+}
+
+// NewService creates an SEO service publishing URLs under baseURL
+func NewService(cfg config.SEOConfig, db *gorm.DB, redisClient redispkg.RedisInterface, baseURL string, logger *zap.Logger) *Service {
+	if cfg.RefreshInterval <= 0 {
+		cfg.RefreshInterval = 60
+	}
+
+	return &Service{
+		cfg:     cfg,
+		db:      db,
+		redis:   redisClient,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		logger:  logger,
+		now:     time.Now,
+	}
+}
+
+// Enabled reports whether public content is published for crawlers
+func (s *Service) Enabled() bool {
+	return s.cfg.Enabled && s.baseURL != ""
+}
+
+// Sitemap returns the sitemap the worker last shared, falling back to the
+// local copy and building one when neither exists
+func (s *Service) Sitemap(ctx context.Context) ([]byte, error) {
+	if !s.Enabled() {
+		return nil, ErrDisabled
+	}
+
+	if s.redis != nil {
+		if value, err := s.redis.Get(ctx, config.SitemapCacheKey); err == nil && value != "" {
+			s.store([]byte(value))
+			return []byte(value), nil
+		}
+	}
+
+	s.mu.RLock()
+	cached := s.sitemap
+	s.mu.RUnlock()
+	if cached != nil {
+		return cached, nil
+	}
+
+	if err := s.RefreshSitemap(ctx); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.sitemap, nil
+}
+
+// RefreshSitemap rebuilds the sitemap and shares it with other instances
+func (s *Service) RefreshSitemap(ctx context.Context) error {
+	if !s.Enabled() {
+		return ErrDisabled
+	}
+
+	sitemap, err := s.BuildSitemap(ctx)
+	if err != nil {
+		return err
+	}
+	s.store(sitemap)
+
+	if s.redis != nil {
+		// Outlive one missed refresh so a slow worker does not empty the cache
+		ttl := 2 * time.Duration(s.cfg.RefreshInterval) * time.Minute
+		if err := s.redis.Set(ctx, config.SitemapCacheKey, string(sitemap), ttl); err != nil {
+			s.logger.Warn("Failed to cache sitemap", zap.Error(err))
+		}
+	}
+
+	return nil
+}
+
+// BuildSitemap lists the instance root, every public, password-free share
+// and every public profile
+func (s *Service) BuildSitemap(ctx context.Context) ([]byte, error) {
+	var rows []struct {
+		ShareToken          string
+		UpdatedAt           time.Time
+		CollectionUpdatedAt time.Time
+	}
+	if err := s.db.WithContext(ctx).Model(&sharing.CollectionShare{}).
+		Select("collection_shares.share_token, collection_shares.updated_at, collections.updated_at AS collection_updated_at").
+		Joins("JOIN collections ON collections.id = collection_shares.collection_id AND collections.deleted_at IS NULL").
+		Where("collection_shares.share_type = ? AND collection_shares.is_active = ?", sharing.ShareTypePublic, true).
+		Where("collection_shares.password = '' OR collection_shares.password IS NULL").
+		Where("collection_shares.expires_at IS NULL OR collection_shares.expires_at > ?", s.now()).
+		Order("collection_shares.id").
+		Limit(config.SitemapMaxURLs - 1).
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to list public shares: %w", err)
+	}
+
+	set := URLSet{
+		Xmlns: sitemapNamespace,
+		URLs:  make([]SitemapURL, 0, len(rows)+1),
+	}
+	set.URLs = append(set.URLs, SitemapURL{Loc: s.baseURL + "/", ChangeFreq: "daily"})
+	for _, row := range rows {
+		lastMod := row.UpdatedAt
+		if row.CollectionUpdatedAt.After(lastMod) {
+			lastMod = row.CollectionUpdatedAt
+		}
+		set.URLs = append(set.URLs, SitemapURL{
+			Loc:        s.baseURL + "/shared/" + row.ShareToken,
+			LastMod:    lastMod.UTC().Format("2006-01-02"),
+			ChangeFreq: "weekly",
+		})
+	}
+
+	profiles, err := s.publicProfiles(ctx, config.SitemapMaxURLs-len(set.URLs))
+	if err != nil {
+		return nil, err
+	}
+	for _, profile := range profiles {
+		set.URLs = append(set.URLs, SitemapURL{
+			Loc:        s.baseURL + "/users/" + url.PathEscape(profile.Username),
+			LastMod:    profile.UpdatedAt.UTC().Format("2006-01-02"),
+			ChangeFreq: "weekly",
+		})
+	}
+
+	var body bytes.Buffer
+	body.WriteString(xml.Header)
+	encoder := xml.NewEncoder(&body)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(set); err != nil {
+		return nil, fmt.Errorf("failed to encode sitemap: %w", err)
+	}
+
+	return body.Bytes(), nil
+}
+
+// publicProfiles returns up to limit users who made their profile public.
+// Visibility is stored in the preferences document, so candidates are
+// narrowed in SQL and checked by parsing it
+func (s *Service) publicProfiles(ctx context.Context, limit int) ([]database.User, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+
+	var profiles []database.User
+	lastID := uint(0)
+	for len(profiles) < limit {
+		var batch []database.User
+		if err := s.db.WithContext(ctx).Select("id, username, preferences, updated_at").
+			Where("id > ? AND preferences LIKE ?", lastID, "%"+user.ProfileVisibilityPublic+"%").
+			Order("id").Limit(config.SitemapProfileBatch).
+			Find(&batch).Error; err != nil {
+			return nil, fmt.Errorf("failed to list public profiles: %w", err)
+		}
+		for _, candidate := range batch {
+			if user.ProfileIsPublic(candidate.Preferences) && len(profiles) < limit {
+				profiles = append(profiles, candidate)
+			}
+		}
+		if len(batch) < config.SitemapProfileBatch {
+			break
+		}
+		lastID = batch[len(batch)-1].ID
+	}
+	return profiles, nil
+}
+
+// RobotsTxt renders robots.txt from the configuration. Private areas are
+// always disallowed; disabled instances disallow everything
+func (s *Service) RobotsTxt() string {
+	var b strings.Builder
+	b.WriteString("User-agent: *\n")
+
+	if !s.Enabled() {
+		b.WriteString("Disallow: /\n")
+		return b.String()
+	}
+
+	b.WriteString("Allow: /shared/\n")
+	b.WriteString("Allow: /users/\n")
+	b.WriteString("Disallow: /api/\n")
+	b.WriteString("Disallow: /embed/\n")
+	for _, path := range s.cfg.Disallow {
+		if path = strings.TrimSpace(path); path != "" {
+			b.WriteString("Disallow: " + path + "\n")
+		}
+	}
+	if s.cfg.CrawlDelay > 0 {
+		b.WriteString("Crawl-delay: " + strconv.Itoa(s.cfg.CrawlDelay) + "\n")
+	}
+	b.WriteString("\nSitemap: " + s.baseURL + "/sitemap.xml\n")
+
+	return b.String()
+}
+
+func (s *Service) store(sitemap []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sitemap = sitemap
+}
@@ -0,0 +1,141 @@
+package seo
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/internal/sharing"
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/redis"
+	"bookmark-sync-service/backend/pkg/testfactory"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func setupService(t *testing.T, cfg config.SEOConfig) (*Service, *miniredis.Miniredis) {
+	db := testfactory.NewDB(t, &database.User{}, &database.Collection{}, &sharing.CollectionShare{})
+	f := testfactory.New(t, db)
+	user := f.User()
+	f.User(func(u *database.User) {
+		u.Username = "alice"
+		u.Preferences = `{"profileVisibility":"public"}`
+	})
+	f.User(func(u *database.User) {
+		u.Username = "bob"
+		u.Preferences = `{"profileVisibility":"private","theme":"public"}`
+	})
+
+	past := time.Now().Add(-time.Hour)
+	shares := []sharing.CollectionShare{
+		{ShareToken: "public", ShareType: sharing.ShareTypePublic},
+		{ShareToken: "private", ShareType: sharing.ShareTypePrivate},
+		{ShareToken: "protected", ShareType: sharing.ShareTypePublic, Password: "secret"},
+		{ShareToken: "expired", ShareType: sharing.ShareTypePublic, ExpiresAt: &past},
+	}
+	for i := range shares {
+		collection := f.Collection(user.ID)
+		shares[i].CollectionID = collection.ID
+		shares[i].UserID = user.ID
+		shares[i].Permission = sharing.PermissionView
+		shares[i].IsActive = true
+		require.NoError(t, db.Create(&shares[i]).Error)
+	}
+
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+	client, err := redis.NewClient(config.RedisConfig{Host: mr.Host(), Port: mr.Port(), PoolSize: 1})
+	require.NoError(t, err)
+
+	return NewService(cfg, db, client, "https://bookmarks.example.com/", zap.NewNop()), mr
+}
+
+func TestBuildSitemapListsOnlyPublicShares(t *testing.T) {
+	service, _ := setupService(t, config.SEOConfig{Enabled: true})
+
+	sitemap, err := service.BuildSitemap(context.Background())
+	require.NoError(t, err)
+
+	body := string(sitemap)
+	assert.Contains(t, body, `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
+	assert.Contains(t, body, "<loc>https://bookmarks.example.com/</loc>")
+	assert.Contains(t, body, "<loc>https://bookmarks.example.com/shared/public</loc>")
+	assert.NotContains(t, body, "/shared/private")
+	assert.NotContains(t, body, "/shared/protected")
+	assert.NotContains(t, body, "/shared/expired")
+	assert.Contains(t, body, "<loc>https://bookmarks.example.com/users/alice</loc>")
+	assert.NotContains(t, body, "/users/bob")
+}
+
+func TestSitemapIsCachedInRedis(t *testing.T) {
+	service, mr := setupService(t, config.SEOConfig{Enabled: true})
+
+	require.NoError(t, service.RefreshSitemap(context.Background()))
+
+	cached, err := mr.Get(config.SitemapCacheKey)
+	require.NoError(t, err)
+	assert.Contains(t, cached, "/shared/public")
+	assert.Equal(t, 2*time.Hour, mr.TTL(config.SitemapCacheKey))
+
+	sitemap, err := service.Sitemap(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, cached, string(sitemap))
+
+	// A newer sitemap shared by the worker replaces the local copy
+	require.NoError(t, mr.Set(config.SitemapCacheKey, "refreshed"))
+	sitemap, err = service.Sitemap(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "refreshed", string(sitemap))
+}
+
+func TestSitemapDisabled(t *testing.T) {
+	service, _ := setupService(t, config.SEOConfig{})
+
+	_, err := service.Sitemap(context.Background())
+	assert.ErrorIs(t, err, ErrDisabled)
+	assert.Equal(t, "User-agent: *\nDisallow: /\n", service.RobotsTxt())
+}
+
+func TestRobotsTxt(t *testing.T) {
+	service, _ := setupService(t, config.SEOConfig{
+		Enabled:    true,
+		Disallow:   []string{"/admin", " "},
+		CrawlDelay: 10,
+	})
+
+	robots := service.RobotsTxt()
+
+	assert.Contains(t, robots, "Allow: /shared/\n")
+	assert.Contains(t, robots, "Allow: /users/\n")
+	assert.Contains(t, robots, "Disallow: /api/\n")
+	assert.Contains(t, robots, "Disallow: /admin\n")
+	assert.NotContains(t, robots, "Disallow:  \n")
+	assert.Contains(t, robots, "Crawl-delay: 10\n")
+	assert.Contains(t, robots, "Sitemap: https://bookmarks.example.com/sitemap.xml\n")
+}
+
+func TestHandlerRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service, _ := setupService(t, config.SEOConfig{Enabled: true})
+	router := gin.New()
+	NewHandler(service).RegisterRoutes(router.Group("/"))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/sitemap.xml", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/xml; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), "/shared/public")
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/robots.txt", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Sitemap: ")
+}
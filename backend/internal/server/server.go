@@ -6,24 +6,67 @@ import (
 	"net/http"
 	"time"
 
+	"bookmark-sync-service/backend/internal/abuse"
+	"bookmark-sync-service/backend/internal/announcement"
+	"bookmark-sync-service/backend/internal/apimeta"
 	"bookmark-sync-service/backend/internal/auth"
+	"bookmark-sync-service/backend/internal/automation"
+	"bookmark-sync-service/backend/internal/backfill"
 	"bookmark-sync-service/backend/internal/bookmark"
+	"bookmark-sync-service/backend/internal/calendar"
+	"bookmark-sync-service/backend/internal/cleanup"
 	"bookmark-sync-service/backend/internal/collection"
+	"bookmark-sync-service/backend/internal/community"
+	"bookmark-sync-service/backend/internal/compliance"
 	"bookmark-sync-service/backend/internal/config"
 	"bookmark-sync-service/backend/internal/content"
+	"bookmark-sync-service/backend/internal/dashboard"
+	"bookmark-sync-service/backend/internal/delicious"
+	"bookmark-sync-service/backend/internal/demo"
+	"bookmark-sync-service/backend/internal/events"
+	"bookmark-sync-service/backend/internal/federation"
+	"bookmark-sync-service/backend/internal/graph"
+	"bookmark-sync-service/backend/internal/hooks"
 	import_export "bookmark-sync-service/backend/internal/import"
+	"bookmark-sync-service/backend/internal/maintenance"
+	"bookmark-sync-service/backend/internal/merge"
+	"bookmark-sync-service/backend/internal/metering"
 	"bookmark-sync-service/backend/internal/monitoring"
+	"bookmark-sync-service/backend/internal/oauth"
+	"bookmark-sync-service/backend/internal/onboarding"
+	"bookmark-sync-service/backend/internal/quality"
+	"bookmark-sync-service/backend/internal/quota"
+	"bookmark-sync-service/backend/internal/reading"
+	"bookmark-sync-service/backend/internal/retention"
+	"bookmark-sync-service/backend/internal/safety"
+	"bookmark-sync-service/backend/internal/screenshot"
 	"bookmark-sync-service/backend/internal/search"
+	"bookmark-sync-service/backend/internal/seo"
+	"bookmark-sync-service/backend/internal/sharing"
+	"bookmark-sync-service/backend/internal/speeddial"
+	"bookmark-sync-service/backend/internal/storagegc"
+	syncpkg "bookmark-sync-service/backend/internal/sync"
+	"bookmark-sync-service/backend/internal/telemetry"
+	"bookmark-sync-service/backend/internal/urlrules"
 	"bookmark-sync-service/backend/internal/user"
+	"bookmark-sync-service/backend/internal/vault"
+	"bookmark-sync-service/backend/pkg/dualwrite"
+	"bookmark-sync-service/backend/pkg/mail"
 	"bookmark-sync-service/backend/pkg/middleware"
 	"bookmark-sync-service/backend/pkg/redis"
 	searchpkg "bookmark-sync-service/backend/pkg/search"
+	"bookmark-sync-service/backend/pkg/secrets"
 	"bookmark-sync-service/backend/pkg/storage"
+	"bookmark-sync-service/backend/pkg/summarize"
 	"bookmark-sync-service/backend/pkg/supabase"
 	"bookmark-sync-service/backend/pkg/utils"
 	"bookmark-sync-service/backend/pkg/websocket"
+	"bookmark-sync-service/backend/pkg/worker"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
@@ -41,13 +84,71 @@ type Server struct {
 	httpServer          *http.Server
 	wsHub               *websocket.Hub
 	authHandler         *auth.Handler
+	tokenVerifiers      []middleware.TokenVerifier
 	userHandler         *user.Handler
+	publicUserHandler   *user.PublicHandler
 	bookmarkHandler     *bookmark.Handlers
 	collectionHandler   *collection.Handler
+	vaultHandler        *vault.Handler
+	oauthHandler        *oauth.Handler
+	eventsHandler       *events.Handler
 	searchHandler       *search.Handlers
+	searchService       *search.Service
+	searchIndexer       *search.Indexer
+	indexerCtx          context.Context
+	stopIndexer         context.CancelFunc
+	indexerDone         chan struct{}
+	searchWarmer        *search.Warmer
 	importExportHandler *import_export.Handlers
+	automationHandler   *automation.Handler
 	contentHandler      *content.Handler
+	monitoringService   *monitoring.Service
 	monitoringHandler   *monitoring.Handler
+	sharingService      *sharing.Service
+	sharingHandler      *sharing.Handler
+	abuseService        *abuse.Service
+	abuseHandler        *abuse.Handler
+	quotaService        *quota.Service
+	quotaHandler        *quota.Handler
+	meteringService     *metering.Service
+	meteringHandler     *metering.Handler
+	safetyHandler       *safety.Handler
+	retentionHandler    *retention.Handler
+	storageGCHandler    *storagegc.Handler
+	complianceHandler   *compliance.Handler
+	readingHandler      *reading.Handler
+	calendarHandler     *calendar.Handler
+	graphHandler        *graph.Handler
+	qualityHandler      *quality.Handler
+	communityHandler    *community.Handler
+	cleanupHandler      *cleanup.Handler
+	mergeHandler        *merge.Handler
+	speedDialHandler    *speeddial.Handler
+	urlRulesHandler     *urlrules.Handler
+	deliciousHandler    *delicious.Handler
+	dashboardHandler    *dashboard.Handler
+	onboardingHandler   *onboarding.Handler
+	demoHandler         *demo.Handler
+	announcementService *announcement.Service
+	announcementHandler *announcement.Handler
+	apiMetaService      *apimeta.Service
+	apiMetaHandler      *apimeta.Handler
+	federationHandler   *federation.Handler
+	maintenanceService  *maintenance.Service
+	maintenanceHandler  *maintenance.Handler
+	telemetryService    *telemetry.Service
+	telemetryHandler    *telemetry.Handler
+	seoService          *seo.Service
+	seoHandler          *seo.Handler
+	syncHandler         *syncpkg.Handler
+	screenshotPool      *worker.WorkerPool
+	bulkPool            *worker.WorkerPool
+	screenshotHandler   *screenshot.RefreshHandler
+	faviconRefresher    *screenshot.FaviconRefresher
+	faviconHandler      *screenshot.FaviconHandler
+	backfillHandler     *backfill.Handler
+	payloadMetrics      *middleware.PayloadMetrics
+	metricsRegistry     *prometheus.Registry
 }
 
 // NewServer creates a new server instance
@@ -59,23 +160,200 @@ func NewServer(cfg *config.Config, db *gorm.DB, redisClient *redis.Client, supab
 
 	// Create WebSocket hub
 	wsHub := websocket.NewHub(redisClient, logger)
+	wsHub.SetBackpressure(websocket.Backpressure{
+		SendBuffer:     cfg.WebSocket.SendBuffer,
+		Policy:         websocket.OverflowPolicy(cfg.WebSocket.OverflowPolicy),
+		CoalesceWindow: time.Duration(cfg.WebSocket.CoalesceWindow) * time.Millisecond,
+	})
+	wsHub.SetLiveQueryLimit(cfg.WebSocket.LiveQueryLimit)
+
+	// Prometheus metrics, registered per server so tests can create many
+	metricsRegistry := prometheus.NewRegistry()
+	metricsRegistry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		websocket.NewCollector(wsHub),
+	)
 
 	// Create auth service and handler
 	authService := auth.NewService(db, redisClient, supabaseClient, &cfg.JWT, logger)
 	authHandler := auth.NewHandler(authService, logger)
 
+	// Verify credentials with the configured provider; tokens of external
+	// identity providers are accepted wherever API tokens are
+	var tokenVerifiers []middleware.TokenVerifier
+	if provider, err := auth.NewProvider(cfg.Auth, db, logger); err != nil {
+		logger.Error("Failed to create auth provider, using Supabase", zap.Error(err))
+	} else {
+		authService.SetProvider(provider)
+		if _, ok := provider.(auth.TokenProvider); ok {
+			tokenVerifiers = append(tokenVerifiers, authService)
+		}
+	}
+
 	// Create user service and handler
 	userService := user.NewService(db, storageClient, logger)
+	userService.EnablePublicBookmarks(cfg.PublicProfile, redisClient)
 	userHandler := user.NewHandler(userService, logger)
+	publicUserHandler := user.NewPublicHandler(userService)
+
+	// Per-user URL cleanup rules, applied when bookmarks are saved or imported
+	urlRulesService := urlrules.NewService(db)
+	urlRulesHandler := urlrules.NewHandler(urlRulesService)
+
+	// Call the self-hoster's hooks when bookmarks and shares are created and
+	// imports finish; a broken hook config must not start a server skipping them
+	hookService, err := hooks.NewService(cfg.Hooks, logger)
+	if err != nil {
+		logger.Fatal("Invalid hook configuration", zap.Error(err))
+	}
 
 	// Create bookmark service and handler
 	bookmarkService := bookmark.NewService(db)
+	bookmarkService.SetLogger(logger)
+	bookmarkService.SetURLRules(urlRulesService)
+	bookmarkService.SetHooks(hookService)
 	bookmarkHandler := bookmark.NewHandlers(bookmarkService)
 
+	// Send bookmarks to the user's other devices; they acknowledge over the hub
+	bookmarkService.SetDeviceHub(wsHub)
+	bookmarkService.SetLiveQueries(wsHub)
+	wsHub.SetSendAcknowledger(bookmarkService)
+
+	// Roll out relational tags behind a flag, exporting divergence metrics
+	tagsMode, err := dualwrite.ParseMode(cfg.Migrations.TagsMode)
+	if err != nil {
+		logger.Error("Invalid relational tags mode, leaving it off", zap.Error(err))
+	}
+	tagMigration := dualwrite.New(bookmark.TagMigrationName, tagsMode, logger)
+	bookmarkService.SetTagMigration(tagMigration)
+	metricsRegistry.MustRegister(dualwrite.NewCollector(tagMigration))
+
+	// Flag bookmarks on blocklisted or known-malicious sites
+	safetyService := safety.NewService(cfg.Safety, db, redisClient, logger)
+	bookmarkService.SetSafetyChecker(safetyService)
+	safetyHandler := safety.NewHandler(safetyService)
+
+	// Soft rate limits of API requests and automation work, per plan
+	quotaService := quota.NewService(cfg.Quota, cfg.Security.AdminEmails, db, redisClient, logger)
+	quotaHandler := quota.NewHandler(quotaService)
+
+	// Feature usage per user and month, reported next to the rate limits
+	meteringService := metering.NewService(db, logger)
+	meteringHandler := metering.NewHandler(meteringService)
+	quotaService.SetMeter(meteringService)
+	bookmarkService.SetUsageMeter(meteringService)
+	userService.SetUsageMeter(meteringService)
+
+	// Webhook deliveries of all modules go through the automation service
+	webhookService := automation.NewService(db)
+	webhookService.SetWorkBudget(quotaService)
+	webhookService.SetUsageMeter(meteringService)
+	webhookService.SetWebhookDeliveryLimits(automation.WebhookDeliveryLimits{
+		MaxConcurrent:   cfg.Webhooks.MaxConcurrency,
+		PerEndpoint:     cfg.Webhooks.EndpointConcurrency,
+		BreakerTimeouts: cfg.Webhooks.BreakerTimeouts,
+		BreakerCooldown: time.Duration(cfg.Webhooks.BreakerCooldown) * time.Second,
+	})
+	// Failing endpoints are disabled by the configured policy and their
+	// owners notified on their notification channel
+	webhookService.SetWebhookHealthPolicy(automation.WebhookHealthPolicy{
+		MaxConsecutiveFailures: cfg.Webhooks.DisableAfterFailures,
+		MaxFailingDuration:     time.Duration(cfg.Webhooks.DisableAfterDays) * 24 * time.Hour,
+		Window:                 time.Duration(cfg.Webhooks.HealthWindowDays) * 24 * time.Hour,
+	})
+	webhookService.SetNotificationPublisher(redisClient)
+	// Bulk operations run on a bounded pool rather than a goroutine per request
+	bulkPool := worker.NewWorkerPool(config.BulkOperationWorkers, config.BulkOperationQueueSize, logger)
+	webhookService.SetBulkQueue(worker.NewBulkOperationQueue(bulkPool, webhookService, logger))
+	// Rows of import operations are saved as bookmarks, failures kept per row
+	webhookService.SetRowImporter(bookmarkService)
+	webhookService.SetBookmarkTagger(bookmarkService)
+	// Bookmark changes reach the user's webhooks and OAuth app subscriptions
+	bookmarkService.SetWebhooks(webhookService)
+	// Export and backup webhooks link to signed downloads on the public URL
+	if cfg.Storage.DownloadSigningKey != "" {
+		webhookService.SetDownloadSigner(automation.NewDownloadSigner(cfg.Server.BaseURL, cfg.Storage.DownloadSigningKey, time.Duration(cfg.Storage.DownloadURLTTL)*time.Second))
+	} else {
+		logger.Warn("No download signing key configured, download links only work on the process that issued them")
+	}
+	webhookService.SetExportDir(cfg.Automation.ExportDir)
+	// Backup destination secrets are encrypted with the configured key and
+	// uploads go through the S3 client
+	if cipher, err := secrets.NewCipher(cfg.Security.EncryptionKey); err != nil {
+		logger.Warn("Backup destinations are disabled without an encryption key", zap.Error(err))
+	} else {
+		webhookService.SetCredentialCipher(cipher)
+	}
+	webhookService.SetDestinationClientFactory(automation.NewS3DestinationClient)
+	// Integration types sync once their service clients are registered with
+	// SetIntegrationSyncer; until then their syncs are refused
+	webhookService.SetIntegrationHealthPolicy(automation.IntegrationHealthPolicy{
+		AlertAfter: cfg.Automation.IntegrationAlertAfter,
+		PauseAfter: cfg.Automation.IntegrationPauseAfter,
+	})
+	// Import files are uploaded straight to the bucket; storage notifies the
+	// upload-events route, which must present the configured token
+	webhookService.SetImportUploadStore(automation.NewImportUploadStore(cfg.Storage.Endpoint, cfg.Storage.AccessKeyID, cfg.Storage.SecretAccessKey, cfg.Storage.BucketName, cfg.Storage.UseSSL))
+	automationHandler := automation.NewHandler(webhookService)
+	automationHandler.SetUploadEventToken(cfg.Storage.UploadEventToken)
+
 	// Create collection service and handler
 	collectionService := collection.NewService(db)
+	collectionService.SetLogger(logger)
+	collectionService.SetWebhooks(webhookService)
+	if err := collectionService.SeedTemplates(); err != nil {
+		logger.Warn("Failed to seed collection templates", zap.Error(err))
+	}
 	collectionHandler := collection.NewHandler(collectionService)
 
+	// Track new users through the onboarding checklist
+	onboardingService := onboarding.NewService(cfg.Onboarding, db, logger)
+	onboardingService.SetNotifier(redisClient)
+	if err := onboardingService.SeedDefaultSteps(context.Background()); err != nil {
+		logger.Warn("Failed to seed onboarding steps", zap.Error(err))
+	}
+	authService.SetRegistrationHook(onboardingService)
+	userService.SetOnboarding(onboardingService)
+	bookmarkService.SetOnboarding(onboardingService)
+	collectionService.SetOnboarding(onboardingService)
+	onboardingHandler := onboarding.NewHandler(onboardingService)
+
+	// Guest sandboxes of a hosted demo, deleted by the worker once expired
+	demoService := demo.NewService(cfg.Demo, db, redisClient, logger)
+	demoService.SetTokenIssuer(authService)
+	demoService.SetSeeder(onboardingService)
+	demoHandler := demo.NewHandler(demoService)
+
+	// Show instance-wide announcement banners, pushed over the WebSocket hub
+	announcementService := announcement.NewService(db, cfg.Security.AdminEmails, logger)
+	announcementService.SetHub(wsHub)
+	announcementHandler := announcement.NewHandler(announcementService)
+
+	// Publish the API changelog and announce deprecated endpoints
+	apiMetaService := apimeta.NewService()
+	apiMetaHandler := apimeta.NewHandler(apiMetaService)
+
+	// Federate public profiles over ActivityPub when the operator opts in
+	var federationHandler *federation.Handler
+	if cfg.Federation.Enabled {
+		federationService := federation.NewService(cfg.Federation, cfg.Server.BaseURL, db, userService, logger)
+		collectionService.SetPublisher(federationService)
+		federationHandler = federation.NewHandler(federationService)
+	}
+
+	// Create end-to-end encrypted vault service and handler
+	vaultHandler := vault.NewHandler(vault.NewService(db))
+
+	// Create Server-Sent Events stream of user changes
+	eventsHandler := events.NewHandler(events.NewService(db, redisClient.Client, logger), logger)
+
+	// Create OAuth app platform for third-party integrations
+	oauthHandler := oauth.NewHandler(oauth.NewService(db, redisClient))
+
+	// Delicious v1 API for legacy clients, authenticated with API or app tokens
+	deliciousHandler := delicious.NewHandler(bookmarkService, middleware.AuthMiddleware(&cfg.JWT, tokenVerifiers...), oauthHandler.RequireAppToken)
+
 	// Create search service and handler
 	searchService, err := search.NewService(cfg.Search)
 	if err != nil {
@@ -84,22 +362,194 @@ func NewServer(cfg *config.Config, db *gorm.DB, redisClient *redis.Client, supab
 		searchService = nil
 	}
 	var searchHandler *search.Handlers
+	var searchIndexer *search.Indexer
+	var searchWarmer *search.Warmer
+	var indexStatus *search.IndexStatusTracker
 	if searchService != nil {
+		searchService.SetDB(db)
+		searchService.SetLogger(logger)
 		searchHandler = search.NewHandlers(searchService)
+		// Search results can be saved as collections or exported in the background
+		searchHandler.SetResultExporter(search.NewResultExporter(searchService, collectionService, webhookService))
+		searchHandler.SetUsageMeter(meteringService)
+		searchIndexer = searchService.NewIndexer(logger)
+
+		// Bookmarks carry their index status, and imports get an event over
+		// the WebSocket once they are searchable
+		indexStatus = search.NewIndexStatusTracker(db, logger)
+		indexStatus.SetHub(wsHub)
+		searchIndexer.SetStatusRecorder(indexStatus)
+		searchHandler.SetIndexStatus(indexStatus)
+
+		// Warm-ups replay the common queries of active users after a deploy
+		searchHistory := search.NewAdvancedService(searchService, db, redisClient)
+		searchHandler.SetHistory(searchHistory)
+		searchWarmer = search.NewWarmer(searchService, db, cfg.Search, logger)
+		searchWarmer.SetHistory(searchHistory)
+		searchWarmer.SetPrimer(userService)
+		searchWarmer.SetStore(redisClient, redisClient)
+		searchHandler.SetWarmer(searchWarmer)
 	}
 
 	// Create import/export service and handler
 	importExportService := import_export.NewService(db)
+	importExportService.SetLogger(logger)
+	importExportService.SetURLRules(urlRulesService)
+	importExportService.SetHooks(hookService)
+	importExportService.SetScreenshotStore(storageClient)
 	importExportHandler := import_export.NewHandlers(importExportService)
 
+	// Queue bookmark, collection and import changes for batched indexing
+	if searchIndexer != nil {
+		bookmarkService.SetIndexer(searchIndexer)
+		collectionService.SetIndexer(searchIndexer)
+		importExportService.SetIndexer(searchIndexer)
+		importExportService.SetIndexWatcher(indexStatus)
+	}
+
 	// Create content service and handler
 	contentService := content.NewService()
+	contentService.SetBookmarkStore(bookmarkService)
 	contentHandler := content.NewHandler(contentService, cfg)
+	contentHandler.SetTokenVerifiers(tokenVerifiers...)
+
+	// Summarize bookmarks on demand, and during extraction when enabled
+	if summarizer := summarize.NewHTTPProvider(cfg.Summarizer); summarizer != nil {
+		bookmarkService.SetSummarizer(summarizer)
+		if cfg.Summarizer.OnExtract {
+			contentService.SetSummarizer(summarizer)
+		}
+	}
 
 	// Create monitoring service and handler
 	monitoringService := monitoring.NewService(db)
+	monitoringService.SetLogger(logger)
+	monitoringService.SetArchiveConfig(cfg.Archive)
+	monitoringService.EnableLeaderElection(redisClient)
+	monitoringService.SetWebhooks(webhookService)
+	monitoringService.SetSafetyChecker(safetyService)
 	monitoringHandler := monitoring.NewHandler(monitoringService)
 
+	// Create sharing service and handler
+	sharingService := sharing.NewService(db, cfg.Server.BaseURL)
+	sharingService.SetLogger(logger)
+	sharingService.SetHooks(hookService)
+	sharingService.SetNotifier(sharing.NewPublisherNotifier(redisClient))
+	sharingService.SetQRCodeCache(storageClient, redisClient)
+	sharingService.SetPrivacy(cfg.Privacy)
+	sharingService.SetMailer(mail.NewSender(cfg.Mail, logger), cfg.Subscriptions)
+	sharingService.SetWebhooks(webhookService)
+	sharingService.SetRateCounter(redisClient)
+	sharingService.SetRenderCache(redisClient)
+	collectionService.SetShareRenders(sharingService)
+	bookmarkService.SetAccessChecker(sharingService)
+	collectionService.SetAccessChecker(sharingService)
+	sharingHandler := sharing.NewHandler(sharingService)
+
+	// Create abuse detection for the login-less share endpoints
+	abuseService := abuse.NewService(cfg.Abuse, db, redisClient, logger)
+	abuseHandler := abuse.NewHandler(abuseService)
+
+	// Show admins the retention policy the cleanup worker enforces
+	retentionHandler := retention.NewHandler(retention.NewService(cfg.Retention, db, logger))
+
+	// Show admins the orphaned objects the storage GC worker collects
+	storageGCHandler := storagegc.NewHandler(storagegc.NewService(cfg.StorageGC, db, storagegc.StoreOf(storageClient), logger))
+
+	// Workspace owners schedule compliance reports the worker delivers
+	complianceService := compliance.NewService(cfg.Compliance, db, logger)
+	complianceService.SetUploader(webhookService)
+	complianceHandler := compliance.NewHandler(complianceService)
+
+	readingService := reading.NewService(db)
+	readingHandler := reading.NewHandler(readingService)
+
+	// Heat map data of bookmarks saved and read, aggregated by the worker
+	calendarHandler := calendar.NewHandler(calendar.NewService(cfg.Calendar, db))
+
+	// Relationships between bookmarks, precomputed by the worker from
+	// changes the collection service queues
+	graphService := graph.NewService(cfg.Graph, db)
+	collectionService.SetGraph(graphService)
+	graphHandler := graph.NewHandler(graphService)
+
+	// Spam scores of public bookmarks, computed by the worker and reviewed by admins
+	qualityService := quality.NewService(cfg.Quality, db, logger)
+	qualityHandler := quality.NewHandler(qualityService)
+
+	// Follows, recommendations and feeds. Recommendations lean towards the
+	// content languages users prefer, matched against detected page languages
+	communityService := community.NewRefactoredService(community.NewGormAdapter(db), community.NewRedisAdapter(redisClient.Client), nil, logger)
+	communityService.SetLanguagePreferences(community.NewGormLanguagePreferences(db))
+	communityService.SetQualityFilter(qualityService)
+	communityService.SetTrendingPrivacy(community.TrendingPrivacy{
+		MinParticipants: cfg.Privacy.TrendingMinParticipants,
+		Noise:           cfg.Privacy.TrendingNoise,
+	})
+	communityHandler := community.NewHandler(communityService)
+
+	// Spring-cleaning candidates; the worker sends the quarterly reminders
+	cleanupService := cleanup.NewService(cfg.Cleanup, db, logger)
+	if searchIndexer != nil {
+		cleanupService.SetIndexer(searchIndexer)
+	}
+	cleanupHandler := cleanup.NewHandler(cleanupService)
+
+	// Merges of a user's two accounts, run by the worker
+	mergeHandler := merge.NewHandler(merge.NewService(cfg.Merge, db, logger))
+
+	// Compose the dashboard from bookmarks, reading list, network and link checks
+	dashboardService := dashboard.NewService(db)
+	dashboardService.SetReadingList(readingService)
+	dashboardHandler := dashboard.NewHandler(dashboardService)
+
+	// Create maintenance mode service and admin handler
+	maintenanceService := maintenance.NewService(cfg.Maintenance, redisClient)
+	maintenanceHandler := maintenance.NewHandler(maintenanceService)
+
+	// Create opt-in telemetry service and preview handler
+	telemetryService := telemetry.NewService(cfg.Telemetry, db, redisClient, enabledFeatures(cfg, searchService != nil), logger)
+	telemetryService.EnableLeaderElection(redisClient)
+	telemetryHandler := telemetry.NewHandler(telemetryService)
+
+	// Create sitemap and robots.txt service for public instances
+	seoService := seo.NewService(cfg.SEO, db, redisClient, cfg.Server.BaseURL, logger)
+	seoHandler := seo.NewHandler(seoService)
+
+	// Create delta sync service and handler
+	syncService := syncpkg.NewService(db, syncpkg.NewRedisClient(redisClient), logger)
+	syncService.SetLiveQueries(wsHub)
+	syncHandler := syncpkg.NewHandler(syncService, logger)
+
+	// Capture screenshots on worker goroutines, held back during maintenance
+	screenshotPool := worker.NewWorkerPool(cfg.Screenshot.Workers, cfg.Screenshot.QueueSize, logger)
+	screenshotPool.SetPauseCheck(maintenanceService.IsEnabled)
+	// Favicon refreshes are singleton jobs, run under their lock
+	screenshotPool.SetLocker(redisClient)
+	bulkPool.SetPauseCheck(maintenanceService.IsEnabled)
+	screenshotService := screenshot.NewService(storageClient)
+	sharingService.SetPreviewImages(screenshotService, storageClient, redisClient)
+	collectionService.SetScreenshots(screenshotService)
+	screenshotRefresher := screenshot.NewRefresher(db, screenshotService, screenshotPool, redisClient, cfg.Screenshot, logger)
+	screenshotHandler := screenshot.NewRefreshHandler(screenshotRefresher)
+
+	// Re-validate favicons on the same workers, one site at a time
+	faviconRefresher := screenshot.NewFaviconRefresher(db, screenshotService, screenshotPool, redisClient, cfg.Favicon, logger)
+	faviconRefresher.EnableLeaderElection(redisClient)
+	faviconHandler := screenshot.NewFaviconHandler(faviconRefresher)
+
+	// Admins project and start screenshot and archive backfills; the worker runs them
+	backfillHandler := backfill.NewHandler(backfill.NewService(cfg.Backfill, db, screenshotService, monitoringService, redisClient, logger))
+
+	// New-tab grid of pinned bookmarks, synced to devices as sync events
+	speedDialService := speeddial.NewService(db, logger)
+	speedDialService.SetSync(syncService)
+	speedDialService.SetThumbnailer(screenshotRefresher)
+	speedDialHandler := speeddial.NewHandler(speedDialService)
+
+	// The indexer runs until Shutdown cancels it, then writes what is queued
+	indexerCtx, stopIndexer := context.WithCancel(context.Background())
+
 	server := &Server{
 		config:              cfg,
 		db:                  db,
@@ -111,13 +561,71 @@ func NewServer(cfg *config.Config, db *gorm.DB, redisClient *redis.Client, supab
 		router:              gin.New(),
 		wsHub:               wsHub,
 		authHandler:         authHandler,
+		tokenVerifiers:      tokenVerifiers,
 		userHandler:         userHandler,
+		publicUserHandler:   publicUserHandler,
 		bookmarkHandler:     bookmarkHandler,
 		collectionHandler:   collectionHandler,
+		vaultHandler:        vaultHandler,
+		oauthHandler:        oauthHandler,
+		eventsHandler:       eventsHandler,
 		searchHandler:       searchHandler,
+		searchService:       searchService,
+		searchIndexer:       searchIndexer,
+		indexerCtx:          indexerCtx,
+		stopIndexer:         stopIndexer,
+		indexerDone:         make(chan struct{}),
+		searchWarmer:        searchWarmer,
 		importExportHandler: importExportHandler,
+		automationHandler:   automationHandler,
 		contentHandler:      contentHandler,
+		monitoringService:   monitoringService,
 		monitoringHandler:   monitoringHandler,
+		sharingService:      sharingService,
+		sharingHandler:      sharingHandler,
+		abuseService:        abuseService,
+		abuseHandler:        abuseHandler,
+		quotaService:        quotaService,
+		quotaHandler:        quotaHandler,
+		meteringService:     meteringService,
+		meteringHandler:     meteringHandler,
+		safetyHandler:       safetyHandler,
+		retentionHandler:    retentionHandler,
+		storageGCHandler:    storageGCHandler,
+		complianceHandler:   complianceHandler,
+		readingHandler:      readingHandler,
+		calendarHandler:     calendarHandler,
+		graphHandler:        graphHandler,
+		qualityHandler:      qualityHandler,
+		communityHandler:    communityHandler,
+		cleanupHandler:      cleanupHandler,
+		mergeHandler:        mergeHandler,
+		speedDialHandler:    speedDialHandler,
+		urlRulesHandler:     urlRulesHandler,
+		deliciousHandler:    deliciousHandler,
+		dashboardHandler:    dashboardHandler,
+		onboardingHandler:   onboardingHandler,
+		demoHandler:         demoHandler,
+		announcementService: announcementService,
+		announcementHandler: announcementHandler,
+		apiMetaService:      apiMetaService,
+		apiMetaHandler:      apiMetaHandler,
+		federationHandler:   federationHandler,
+		maintenanceService:  maintenanceService,
+		maintenanceHandler:  maintenanceHandler,
+		telemetryService:    telemetryService,
+		telemetryHandler:    telemetryHandler,
+		seoService:          seoService,
+		seoHandler:          seoHandler,
+		syncHandler:         syncHandler,
+		backfillHandler:     backfillHandler,
+		screenshotPool:      screenshotPool,
+		bulkPool:            bulkPool,
+		screenshotHandler:   screenshotHandler,
+		faviconRefresher:    faviconRefresher,
+		faviconHandler:      faviconHandler,
+		payloadMetrics:      middleware.NewPayloadMetrics(),
+		metricsRegistry:     metricsRegistry,
 	}
 
 	server.setupMiddleware()
@@ -126,19 +634,69 @@ func NewServer(cfg *config.Config, db *gorm.DB, redisClient *redis.Client, supab
 	return server
 }
 
+// enabledFeatures lists the optional subsystems reported by telemetry
+func enabledFeatures(cfg *config.Config, searchEnabled bool) []string {
+	features := []string{}
+	if searchEnabled {
+		features = append(features, "search")
+	}
+	if len(cfg.Security.AdminEmails) > 0 {
+		features = append(features, "admin")
+	}
+	if cfg.Maintenance.Enabled {
+		features = append(features, "maintenance")
+	}
+	if cfg.Demo.Enabled {
+		features = append(features, "demo")
+	}
+	if cfg.SEO.Enabled {
+		features = append(features, "seo")
+	}
+	if cfg.Summarizer.Endpoint != "" {
+		features = append(features, "summarizer")
+	}
+	if cfg.Security.EncryptionKey != "" && cfg.Security.EncryptionKey != "your-encryption-key" {
+		features = append(features, "credential_encryption")
+	}
+	return features
+}
+
 // setupMiddleware configures middleware for the server
 func (s *Server) setupMiddleware() {
+	// Client addresses, which rate and abuse limits key on, are only taken
+	// from forwarded headers set by the configured proxies
+	if err := s.router.SetTrustedProxies(s.config.Server.TrustedProxies); err != nil {
+		s.logger.Fatal("Invalid trusted proxies", zap.Error(err))
+	}
+
 	// Recovery middleware
 	s.router.Use(gin.Recovery())
 
 	// Tracing middleware (includes request ID and structured logging)
 	s.router.Use(utils.TracingMiddleware(s.logger))
 
-	// CORS middleware
-	s.router.Use(s.corsMiddleware())
+	// CORS allowlist and hardened response headers
+	s.router.Use(middleware.CORS(s.config.Security.AllowedOrigins))
+	s.router.Use(middleware.SecurityHeaders(s.config.Security.HSTSMaxAge))
+
+	// Request bodies past the limit are refused before handlers read them;
+	// upload routes raise it for themselves
+	if limit := s.config.Server.MaxBodySize; limit > 0 {
+		s.router.Use(middleware.BodyLimit(limit))
+	}
+
+	// Maintenance mode: reads continue, writes return 503
+	s.router.Use(s.maintenanceService.Middleware())
+
+	// Deprecation and Sunset headers on deprecated endpoints
+	s.router.Use(s.apiMetaService.Middleware())
 
 	// Rate limiting middleware (placeholder for now)
 	s.router.Use(s.rateLimitMiddleware())
+
+	// Response size per endpoint, then gzip/brotli for clients that accept it
+	s.router.Use(s.payloadMetrics.Middleware())
+	s.router.Use(middleware.Compression(config.CompressionMinSize))
 }
 
 // setupRoutes configures routes for the server
@@ -146,6 +704,25 @@ func (s *Server) setupRoutes() {
 	// Health check endpoint
 	s.router.GET("/health", s.healthCheck)
 
+	// Prometheus scrape endpoint, outside /api so the proxy does not expose
+	// it, and only served to scrapers with the metrics token
+	s.router.GET("/metrics", middleware.RequireBearerToken(s.config.Server.MetricsToken), gin.WrapH(promhttp.HandlerFor(s.metricsRegistry, promhttp.HandlerOpts{})))
+
+	// Public embed routes for shared collections (served outside the API prefix)
+	s.sharingHandler.RegisterEmbedRoutes(s.router.Group("/embed", s.abuseService.Middleware("embed"), middleware.ContentSecurityPolicy(s.config.Security.PageCSP)))
+	s.sharingHandler.RegisterFeedRoutes(s.router.Group("/feeds", s.abuseService.Middleware("feed")))
+
+	// Crawler routes: robots.txt and the precomputed sitemap
+	s.seoHandler.RegisterRoutes(s.router.Group("/"))
+
+	// ActivityPub actors and WebFinger, at the URLs other instances are given
+	if s.federationHandler != nil {
+		s.federationHandler.RegisterRoutes(s.router.Group("/"))
+	}
+
+	// Delicious v1 API, at the path legacy clients expect
+	s.deliciousHandler.RegisterRoutes(s.router.Group("/v1"), s.quotaService.Middleware(), s.meteringService.Middleware())
+
 	// API v1 routes
 	v1 := s.router.Group("/api/v1")
 	{
@@ -157,37 +734,145 @@ func (s *Server) setupRoutes() {
 			authGroup.POST("/refresh", s.authHandler.RefreshToken)
 			authGroup.POST("/reset", s.authHandler.ResetPassword)
 			authGroup.POST("/validate", s.authHandler.ValidateToken)
+			authGroup.GET("/provider", s.authHandler.GetProvider)
+			authGroup.POST("/token", s.authHandler.ExchangeToken)
+			s.demoHandler.RegisterRoutes(authGroup)
 		}
 
+		// OAuth token endpoints (authenticated with client credentials)
+		s.oauthHandler.RegisterTokenRoutes(v1)
+
+		// Third-party app API (authenticated with app access tokens)
+		s.oauthHandler.RegisterAppRoutes(v1)
+		s.oauthHandler.RegisterAppResource(v1, "bookmarks", oauth.ScopeBookmarksRead, oauth.ScopeBookmarksWrite, oauth.AppResourceHandlers{
+			List:   s.bookmarkHandler.ListBookmarksHandler,
+			Get:    s.bookmarkHandler.GetBookmark,
+			Create: s.bookmarkHandler.CreateBookmark,
+			Update: s.bookmarkHandler.UpdateBookmark,
+			Delete: s.bookmarkHandler.DeleteBookmark,
+		})
+		s.oauthHandler.RegisterAppResource(v1, "collections", oauth.ScopeCollectionsRead, oauth.ScopeCollectionsWrite, oauth.AppResourceHandlers{
+			List:   s.collectionHandler.ListCollections,
+			Get:    s.collectionHandler.GetCollection,
+			Create: s.collectionHandler.CreateCollection,
+			Update: s.collectionHandler.UpdateCollection,
+			Delete: s.collectionHandler.DeleteCollection,
+		})
+
+		// Public RSS feeds, signed downloads and storage upload notifications
+		s.automationHandler.RegisterPublicRoutes(v1)
+
 		// Protected routes (require authentication)
 		protected := v1.Group("/")
-		protected.Use(middleware.AuthMiddleware(&s.config.JWT))
+		protected.Use(middleware.AuthMiddleware(&s.config.JWT, s.tokenVerifiers...), s.quotaService.Middleware())
 		{
 			// Auth routes that require authentication
 			protected.POST("/auth/logout", s.authHandler.Logout)
 			protected.GET("/auth/profile", s.authHandler.GetProfile)
 
+			// Routes reaching other users or outside services, closed to demo sandboxes
+			outward := protected.Group("", middleware.DenyDemo())
+
 			// Register bookmark routes
 			s.bookmarkHandler.RegisterRoutes(protected)
 
 			// Register collection routes
 			s.collectionHandler.RegisterRoutes(protected)
 
+			// Register community routes: follows, recommendations and feeds
+			s.communityHandler.RegisterRoutes(outward)
+
+			// Register encrypted vault routes
+			s.vaultHandler.RegisterRoutes(protected)
+
+			// Register change event stream
+			s.eventsHandler.RegisterRoutes(protected)
+
+			// Register OAuth app management and consent routes
+			s.oauthHandler.RegisterRoutes(outward)
+
 			// Register import/export routes
 			s.importExportHandler.RegisterRoutes(protected)
 
+			// Register webhooks, RSS feeds, bulk operations, backups and integrations
+			s.automationHandler.RegisterRoutes(protected)
+
 			// Register content analysis routes
 			s.contentHandler.RegisterRoutes(protected)
 
 			// Register monitoring routes
 			s.monitoringHandler.RegisterRoutes(protected)
+			s.monitoringHandler.RegisterArchiveRoutes(protected)
+
+			// Register direct sharing routes
+			s.sharingHandler.RegisterDirectShareRoutes(outward)
+
+			// Register bookmark QR code route
+			s.sharingHandler.RegisterBookmarkQRCodeRoutes(protected)
+
+			// Register share analytics route
+			s.sharingHandler.RegisterAnalyticsRoutes(protected)
+
+			// Register share email subscriber management
+			s.sharingHandler.RegisterSubscriberRoutes(outward)
+
+			// Register collection-scoped webhooks
+			s.sharingHandler.RegisterCollectionWebhookRoutes(outward)
+
+			// Register shared collection discussions
+			s.sharingHandler.RegisterCommentRoutes(outward)
+
+			// Register abuse reports about the user's shares
+			s.abuseHandler.RegisterRoutes(protected)
+
+			// Register rate limit and feature usage
+			s.quotaHandler.RegisterRoutes(protected)
+			s.meteringHandler.RegisterRoutes(protected)
+
+			// Register on-demand screenshot and favicon refreshes
+			s.screenshotHandler.RegisterRoutes(protected)
+			s.faviconHandler.RegisterRoutes(protected)
+
+			// Register reading sessions and statistics
+			s.readingHandler.RegisterRoutes(protected)
+
+			// Register the calendar heat map
+			s.calendarHandler.RegisterRoutes(protected)
+
+			// Register the bookmark relationship graph
+			s.graphHandler.RegisterRoutes(protected)
+
+			// Register bookmark quality scores and appeals
+			s.qualityHandler.RegisterRoutes(protected)
+
+			// Register spring-cleaning candidates, bulk actions and reminders
+			s.cleanupHandler.RegisterRoutes(protected)
+
+			// Register account merges
+			s.mergeHandler.RegisterRoutes(outward)
+
+			// Register workspace compliance reports
+			s.complianceHandler.RegisterRoutes(outward)
+
+			// Register the widget dashboard
+			s.dashboardHandler.RegisterRoutes(protected)
+
+			// Register URL cleanup rules and rule packs
+			s.urlRulesHandler.RegisterRoutes(protected)
+
+			// Register onboarding progress
+			s.onboardingHandler.RegisterRoutes(protected)
+
+			// Register announcement dismissal
+			s.announcementHandler.RegisterRoutes(protected)
+
+			// Register the new-tab speed dial
+			s.speedDialHandler.RegisterRoutes(protected)
 
 			// Sync routes
 			sync := protected.Group("/sync")
 			{
-				sync.GET("/changes", s.placeholder)
-				sync.POST("/push", s.placeholder)
-				sync.GET("/status", s.placeholder)
+				s.syncHandler.RegisterRoutes(sync)
 				sync.POST("/devices", s.placeholder)
 				sync.GET("/devices", s.placeholder)
 			}
@@ -199,7 +884,7 @@ func (s *Server) setupRoutes() {
 				userGroup.PUT("/profile", s.userHandler.UpdateProfile)
 				userGroup.GET("/preferences", s.userHandler.GetPreferences)
 				userGroup.PUT("/preferences", s.userHandler.UpdatePreferences)
-				userGroup.POST("/avatar", s.userHandler.UploadAvatar)
+				userGroup.POST("/avatar", middleware.BodyLimit(config.MaxAvatarUploadSize+middleware.MultipartOverhead), s.userHandler.UploadAvatar)
 				userGroup.GET("/stats", s.userHandler.GetStats)
 				userGroup.POST("/export", s.userHandler.ExportData)
 				userGroup.DELETE("/account", s.userHandler.DeleteAccount)
@@ -214,23 +899,55 @@ func (s *Server) setupRoutes() {
 				storage.GET("/files/:id", s.placeholder)
 				storage.DELETE("/files/:id", s.placeholder)
 			}
+
+			// Admin routes
+			admin := protected.Group("/admin")
+			admin.Use(middleware.RequireAdmin(s.config.Security.AdminEmails))
+			{
+				s.maintenanceHandler.RegisterRoutes(admin)
+				s.telemetryHandler.RegisterRoutes(admin)
+				s.collectionHandler.RegisterAdminRoutes(admin)
+				s.abuseHandler.RegisterAdminRoutes(admin)
+				s.quotaHandler.RegisterAdminRoutes(admin)
+				s.safetyHandler.RegisterAdminRoutes(admin)
+				s.retentionHandler.RegisterAdminRoutes(admin)
+				s.storageGCHandler.RegisterAdminRoutes(admin)
+				s.qualityHandler.RegisterAdminRoutes(admin)
+				s.mergeHandler.RegisterAdminRoutes(admin)
+				s.backfillHandler.RegisterAdminRoutes(admin)
+				s.onboardingHandler.RegisterAdminRoutes(admin)
+				s.announcementHandler.RegisterAdminRoutes(admin)
+				if s.searchHandler != nil {
+					s.searchHandler.RegisterAdminRoutes(admin)
+				}
+				admin.GET("/metrics/payloads", s.payloadMetricsReport)
+			}
 		}
 
 		// Public routes (optional authentication)
 		public := v1.Group("/")
-		public.Use(middleware.OptionalAuthMiddleware(&s.config.JWT))
+		public.Use(middleware.OptionalAuthMiddleware(&s.config.JWT, s.tokenVerifiers...))
 		{
-			// Community routes
-			community := public.Group("/community")
-			{
-				community.GET("/trending", s.placeholder)
-				community.GET("/popular", s.placeholder)
-				community.GET("/collections/:id", s.placeholder)
-				community.GET("/users/:id", s.placeholder)
-				community.POST("/follow/:id", s.placeholder)
-				community.DELETE("/follow/:id", s.placeholder)
-				community.GET("/feed", s.placeholder)
-			}
+			// Active announcement banners, for visitors too
+			s.announcementHandler.RegisterPublicRoutes(public)
+
+			// API changelog and deprecations, for integration authors
+			s.apiMetaHandler.RegisterRoutes(public)
+
+			// Share link QR codes
+			s.sharingHandler.RegisterQRCodeRoutes(public.Group("", s.abuseService.Middleware("qrcode")))
+
+			// Link preview images of shares, with the screenshot blurred or cropped as configured
+			s.sharingHandler.RegisterPreviewRoutes(public.Group("", s.abuseService.Middleware("preview")))
+
+			// Data API of shares whose owner enabled it, limited per token
+			s.sharingHandler.RegisterShareAPIRoutes(public.Group("", s.abuseService.Middleware("share_api")))
+
+			// Email subscriptions to public shares
+			s.sharingHandler.RegisterSubscriptionRoutes(public.Group("", s.abuseService.Middleware("subscribe")))
+
+			// Public bookmarks of users who opted in, for personal websites
+			s.publicUserHandler.RegisterRoutes(public.Group("", s.abuseService.Middleware("public_bookmarks")))
 
 			// Search routes
 			if s.searchHandler != nil {
@@ -261,9 +978,52 @@ func (s *Server) Start() error {
 		WriteTimeout: time.Duration(s.config.Server.WriteTimeout) * time.Second,
 	}
 
+	// Start screenshot capture and bulk operation workers
+	s.screenshotPool.Start()
+	s.bulkPool.Start()
+
 	// Start WebSocket hub in a separate goroutine
 	go s.wsHub.Run(context.Background())
 
+	// Report anonymous usage statistics if the operator opted in
+	go s.telemetryService.Run(context.Background())
+
+	// Write metered feature usage in batches
+	go s.meteringService.Run(context.Background())
+
+	// Write queued search index changes in batches
+	if s.searchIndexer != nil {
+		go func() {
+			defer close(s.indexerDone)
+			s.searchIndexer.Run(s.indexerCtx)
+		}()
+	}
+
+	// Create the search collections, or bring those an earlier version
+	// created up to the current schema
+	if s.searchService != nil {
+		if err := s.searchService.InitializeCollections(context.Background()); err != nil {
+			s.logger.Warn("Failed to migrate search collections", zap.Error(err))
+		}
+	}
+
+	// Prime search caches once migrations have run, so the first queries
+	// after a deploy are fast
+	if s.searchWarmer != nil && s.config.Search.WarmupOnStart {
+		if _, err := s.searchWarmer.Start(context.Background(), search.WarmupTriggerStartup); err != nil {
+			s.logger.Info("Skipping search warm-up", zap.Error(err))
+		}
+	}
+
+	// Push scheduled announcements to connected clients as they start
+	go s.announcementService.Run(context.Background())
+
+	// Re-capture archived pages and alert owners to significant changes
+	go s.monitoringService.RunArchiveRecapture(context.Background())
+
+	// Re-validate stale favicons and replace broken ones
+	go s.faviconRefresher.Run(context.Background())
+
 	s.logger.Info("Server starting",
 		zap.String("address", s.httpServer.Addr),
 		zap.String("environment", s.config.Server.Environment),
@@ -275,7 +1035,21 @@ func (s *Server) Start() error {
 // Shutdown gracefully shuts down the server
 func (s *Server) Shutdown(ctx context.Context) error {
 	s.logger.Info("Server shutting down...")
-	return s.httpServer.Shutdown(ctx)
+	err := s.httpServer.Shutdown(ctx)
+	s.screenshotPool.Stop()
+	s.bulkPool.Stop()
+
+	// Queued index changes live in memory, so the indexer writes them
+	// before the process exits
+	if s.searchIndexer != nil {
+		s.stopIndexer()
+		select {
+		case <-s.indexerDone:
+		case <-ctx.Done():
+			s.logger.Warn("Search index changes were not written before shutdown", zap.Int("pending", s.searchIndexer.Stats().Pending))
+		}
+	}
+	return err
 }
 
 // healthCheck handles health check requests
@@ -283,7 +1057,7 @@ func (s *Server) healthCheck(c *gin.Context) {
 	healthData := gin.H{
 		"status":    "healthy",
 		"timestamp": time.Now().UTC(),
-		"version":   "1.0.0",
+		"version":   config.Version,
 		"services":  gin.H{},
 	}
 
@@ -339,6 +1113,11 @@ func (s *Server) healthCheck(c *gin.Context) {
 	utils.SuccessResponse(c, healthData, "System is healthy")
 }
 
+// payloadMetricsReport lists response sizes per endpoint, largest first
+func (s *Server) payloadMetricsReport(c *gin.Context) {
+	utils.SuccessResponse(c, gin.H{"endpoints": s.payloadMetrics.Snapshot()}, "Payload metrics retrieved successfully")
+}
+
 // placeholder handler for routes not yet implemented
 func (s *Server) placeholder(c *gin.Context) {
 	utils.ErrorResponse(c, http.StatusNotImplemented, "NOT_IMPLEMENTED",
@@ -353,21 +1132,3 @@ func (s *Server) rateLimitMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
-
-// corsMiddleware handles CORS headers
-func (s *Server) corsMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
-		c.Header("Access-Control-Expose-Headers", "Content-Length")
-		c.Header("Access-Control-Allow-Credentials", "true")
-
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(http.StatusNoContent)
-			return
-		}
-
-		c.Next()
-	}
-}
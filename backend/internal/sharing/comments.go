@@ -0,0 +1,574 @@
+package sharing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/utils"
+)
+
+// commentExcerptLength caps the comment text sent in notifications
+const commentExcerptLength = 140
+
+// commentAccess is what a user may do in a collection's discussion
+type commentAccess struct {
+	post     bool
+	moderate bool
+}
+
+// discussionAccess checks that a user can read a collection's discussion and
+// returns what else they may do there. The owner and admin collaborators
+// moderate; comment permission or higher allows posting
+func (s *Service) discussionAccess(ctx context.Context, userID, collectionID uint) (*commentAccess, error) {
+	levels := []SharePermission{PermissionAdmin, PermissionComment, PermissionView}
+	for _, level := range levels {
+		allowed, err := s.CanAccess(ctx, userID, ResourceTypeCollection, collectionID, level)
+		if err == ErrResourceNotFound {
+			return nil, ErrCollectionNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		if allowed {
+			return &commentAccess{post: level != PermissionView, moderate: level == PermissionAdmin}, nil
+		}
+	}
+	return nil, ErrUnauthorized
+}
+
+// GetCollectionComments lists a page of a collection's discussion threads.
+// Hidden comments keep their place, but only moderators and their authors
+// see what they said
+func (s *Service) GetCollectionComments(ctx context.Context, userID, collectionID uint, page, limit int) (*CommentThreadList, error) {
+	access, err := s.discussionAccess(ctx, userID, collectionID)
+	if err != nil {
+		return nil, err
+	}
+	if page < 1 {
+		page = 1
+	}
+	if limit <= 0 || limit > config.MaxPageSize {
+		limit = config.DefaultPageSize
+	}
+
+	db := s.db.WithContext(ctx)
+	list := &CommentThreadList{Threads: []CommentThread{}, Page: page, Limit: limit, CanPost: access.post, CanModerate: access.moderate}
+	threads := db.Model(&CollectionComment{}).Where("collection_id = ? AND thread_id IS NULL", collectionID)
+	if err := threads.Count(&list.Total).Error; err != nil {
+		return nil, fmt.Errorf("failed to count threads: %w", err)
+	}
+
+	var roots []CollectionComment
+	if err := threads.Order("updated_at DESC, id DESC").Offset((page - 1) * limit).Limit(limit).Find(&roots).Error; err != nil {
+		return nil, fmt.Errorf("failed to get threads: %w", err)
+	}
+	if len(roots) == 0 {
+		return list, nil
+	}
+
+	rootIDs := make([]uint, len(roots))
+	for n, root := range roots {
+		rootIDs[n] = root.ID
+	}
+	var replies []CollectionComment
+	if err := db.Where("thread_id IN ?", rootIDs).Order("created_at, id").Find(&replies).Error; err != nil {
+		return nil, fmt.Errorf("failed to get replies: %w", err)
+	}
+
+	usernames, err := s.usernames(ctx, append(append([]CollectionComment{}, roots...), replies...))
+	if err != nil {
+		return nil, err
+	}
+	present := func(comment CollectionComment) CollectionComment {
+		comment.Username = usernames[comment.UserID]
+		if comment.Hidden && !access.moderate && comment.UserID != userID {
+			comment.Content = ""
+		}
+		return comment
+	}
+
+	index := make(map[uint]int, len(roots))
+	for n, root := range roots {
+		index[root.ID] = n
+		list.Threads = append(list.Threads, CommentThread{CollectionComment: present(root), Replies: []CollectionComment{}})
+	}
+	for _, reply := range replies {
+		thread := &list.Threads[index[*reply.ThreadID]]
+		thread.Replies = append(thread.Replies, present(reply))
+	}
+	return list, nil
+}
+
+// PostCollectionComment starts a thread in a collection's discussion, or
+// replies to one. Replies to a reply join the same thread. Participants are
+// notified and the comment is logged on the collection's active shares
+func (s *Service) PostCollectionComment(ctx context.Context, userID, collectionID uint, request *CollectionCommentRequest) (*CollectionComment, error) {
+	content := strings.TrimSpace(request.Content)
+	if content == "" {
+		return nil, ErrEmptyComment
+	}
+	access, err := s.discussionAccess(ctx, userID, collectionID)
+	if err != nil {
+		return nil, err
+	}
+	if !access.post {
+		return nil, ErrInsufficientPermission
+	}
+
+	comment := &CollectionComment{CollectionID: collectionID, UserID: userID, Content: content}
+	if request.ThreadID != nil {
+		parent, err := s.getComment(ctx, *request.ThreadID)
+		if err != nil || parent.CollectionID != collectionID {
+			return nil, ErrCommentNotFound
+		}
+		threadID := parent.ID
+		if parent.ThreadID != nil {
+			threadID = *parent.ThreadID
+		}
+		comment.ThreadID = &threadID
+	}
+
+	err = database.WithTransaction(ctx, s.db, func(tx *gorm.DB) error {
+		if err := tx.Create(comment).Error; err != nil {
+			return fmt.Errorf("failed to create comment: %w", err)
+		}
+		// Threads are listed by their latest activity
+		if comment.ThreadID != nil {
+			if err := tx.Model(&CollectionComment{}).Where("id = ?", *comment.ThreadID).
+				UpdateColumn("updated_at", comment.CreatedAt).Error; err != nil {
+				return fmt.Errorf("failed to update thread: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logComment(ctx, comment, request)
+	s.notifyParticipants(ctx, comment)
+	return comment, nil
+}
+
+// UpdateCollectionComment edits a comment; only its author may
+func (s *Service) UpdateCollectionComment(ctx context.Context, userID, commentID uint, request *UpdateCollectionCommentRequest) (*CollectionComment, error) {
+	content := strings.TrimSpace(request.Content)
+	if content == "" {
+		return nil, ErrEmptyComment
+	}
+	comment, err := s.getComment(ctx, commentID)
+	if err != nil {
+		return nil, err
+	}
+	if comment.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+
+	now := time.Now()
+	comment.Content = content
+	comment.EditedAt = &now
+	if err := s.db.WithContext(ctx).Model(comment).Select("content", "edited_at").Updates(comment).Error; err != nil {
+		return nil, fmt.Errorf("failed to update comment: %w", err)
+	}
+	return comment, nil
+}
+
+// DeleteCollectionComment removes a comment. Authors delete their own,
+// moderators any; deleting the first comment of a thread removes its replies
+func (s *Service) DeleteCollectionComment(ctx context.Context, userID, commentID uint) error {
+	comment, err := s.getComment(ctx, commentID)
+	if err != nil {
+		return err
+	}
+	if comment.UserID != userID {
+		access, err := s.discussionAccess(ctx, userID, comment.CollectionID)
+		if err != nil {
+			return err
+		}
+		if !access.moderate {
+			return ErrUnauthorized
+		}
+	}
+
+	return database.WithTransaction(ctx, s.db, func(tx *gorm.DB) error {
+		if comment.ThreadID == nil {
+			if err := tx.Where("thread_id = ?", comment.ID).Delete(&CollectionComment{}).Error; err != nil {
+				return fmt.Errorf("failed to delete replies: %w", err)
+			}
+		}
+		if err := tx.Delete(comment).Error; err != nil {
+			return fmt.Errorf("failed to delete comment: %w", err)
+		}
+		return nil
+	})
+}
+
+// ModerateCollectionComment hides a comment from other participants, or
+// restores it
+func (s *Service) ModerateCollectionComment(ctx context.Context, userID, commentID uint, request *ModerateCollectionCommentRequest) (*CollectionComment, error) {
+	comment, err := s.getComment(ctx, commentID)
+	if err != nil {
+		return nil, err
+	}
+	access, err := s.discussionAccess(ctx, userID, comment.CollectionID)
+	if err != nil {
+		return nil, err
+	}
+	if !access.moderate {
+		return nil, ErrUnauthorized
+	}
+
+	comment.Hidden = request.Hidden
+	comment.HiddenBy = nil
+	if request.Hidden {
+		comment.HiddenBy = &userID
+	}
+	if err := s.db.WithContext(ctx).Model(comment).Select("hidden", "hidden_by").Updates(comment).Error; err != nil {
+		return nil, fmt.Errorf("failed to moderate comment: %w", err)
+	}
+	return comment, nil
+}
+
+func (s *Service) getComment(ctx context.Context, commentID uint) (*CollectionComment, error) {
+	var comment CollectionComment
+	if err := s.db.WithContext(ctx).First(&comment, commentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCommentNotFound
+		}
+		return nil, fmt.Errorf("failed to find comment: %w", err)
+	}
+	return &comment, nil
+}
+
+// usernames maps the authors of comments to their usernames
+func (s *Service) usernames(ctx context.Context, comments []CollectionComment) (map[uint]string, error) {
+	ids := make([]uint, 0, len(comments))
+	for _, comment := range comments {
+		ids = append(ids, comment.UserID)
+	}
+	var users []database.User
+	if err := s.db.WithContext(ctx).Select("id", "username").Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, fmt.Errorf("failed to get authors: %w", err)
+	}
+	usernames := make(map[uint]string, len(users))
+	for _, user := range users {
+		usernames[user.ID] = user.Username
+	}
+	return usernames, nil
+}
+
+// logComment records the comment in the activity log of every active share
+// of the collection
+func (s *Service) logComment(ctx context.Context, comment *CollectionComment, request *CollectionCommentRequest) {
+	var shares []CollectionShare
+	if err := s.db.WithContext(ctx).Where("collection_id = ? AND is_active = ?", comment.CollectionID, true).Find(&shares).Error; err != nil {
+		s.logger.Warn("Failed to find shares for comment activity", zap.Uint("collection_id", comment.CollectionID), zap.Error(err))
+		return
+	}
+
+	metadata := map[string]interface{}{"comment_id": comment.ID}
+	if comment.ThreadID != nil {
+		metadata["thread_id"] = *comment.ThreadID
+	}
+	for _, share := range shares {
+		if err := s.RecordActivity(ctx, share.ID, &comment.UserID, ActivityTypeComment, request.IPAddress, request.UserAgent, metadata); err != nil {
+			s.logger.Warn("Failed to record comment activity", zap.Uint("share_id", share.ID), zap.Error(err))
+		}
+	}
+}
+
+// notifyParticipants tells the owner and everyone who posted in the thread,
+// or for a new thread anywhere in the discussion, about a comment. People
+// who since lost access to the collection are skipped
+func (s *Service) notifyParticipants(ctx context.Context, comment *CollectionComment) {
+	if s.notifier == nil {
+		return
+	}
+
+	ownerID, err := s.resourceOwner(ResourceTypeCollection, comment.CollectionID)
+	if err != nil {
+		return
+	}
+	query := s.db.WithContext(ctx).Model(&CollectionComment{}).Where("collection_id = ?", comment.CollectionID)
+	if comment.ThreadID != nil {
+		query = query.Where("id = ? OR thread_id = ?", *comment.ThreadID, *comment.ThreadID)
+	}
+	var participants []uint
+	if err := query.Distinct().Pluck("user_id", &participants).Error; err != nil {
+		s.logger.Warn("Failed to find comment participants", zap.Uint("comment_id", comment.ID), zap.Error(err))
+		return
+	}
+
+	notification := &ShareNotification{
+		Type:         NotificationCommentPosted,
+		ResourceType: ResourceTypeCollection,
+		ResourceID:   comment.CollectionID,
+		FromUserID:   comment.UserID,
+		Message:      excerpt(comment.Content, commentExcerptLength),
+		CommentID:    comment.ID,
+		CreatedAt:    comment.CreatedAt,
+	}
+	if comment.ThreadID != nil {
+		notification.ThreadID = *comment.ThreadID
+	}
+
+	notified := map[uint]bool{comment.UserID: true}
+	for _, userID := range append([]uint{ownerID}, participants...) {
+		if notified[userID] {
+			continue
+		}
+		notified[userID] = true
+		if allowed, err := s.CanAccess(ctx, userID, ResourceTypeCollection, comment.CollectionID, PermissionView); err != nil || !allowed {
+			continue
+		}
+		s.notifier.Notify(ctx, userID, notification)
+	}
+}
+
+// excerpt shortens text to at most max runes
+func excerpt(text string, max int) string {
+	if utf8.RuneCountInString(text) <= max {
+		return text
+	}
+	return string([]rune(text)[:max-1]) + "…"
+}
+
+// RegisterCommentRoutes registers the collection discussion routes
+func (h *Handler) RegisterCommentRoutes(router *gin.RouterGroup) {
+	router.GET("/collections/:id/comments", h.GetCollectionComments)
+	router.POST("/collections/:id/comments", h.PostCollectionComment)
+	router.PUT("/collection-comments/:comment_id", h.UpdateCollectionComment)
+	router.DELETE("/collection-comments/:comment_id", h.DeleteCollectionComment)
+	router.POST("/collection-comments/:comment_id/moderate", h.ModerateCollectionComment)
+}
+
+// GetCollectionComments lists a collection's discussion
+// @Summary List collection comments
+// @Description List the discussion threads of a collection the user owns or has been shared, most recently active first
+// @Tags sharing
+// @Produce json
+// @Param id path int true "Collection ID"
+// @Param page query int false "Page number"
+// @Param limit query int false "Threads per page"
+// @Success 200 {object} CommentThreadList
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/collections/{id}/comments [get]
+func (h *Handler) GetCollectionComments(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+	collectionID, ok := collectionIDParam(c)
+	if !ok {
+		return
+	}
+	page, _ := strconv.Atoi(c.Query("page"))
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	list, err := h.service.GetCollectionComments(c.Request.Context(), userID, collectionID, page, limit)
+	if err != nil {
+		h.commentError(c, err, "failed to get comments")
+		return
+	}
+
+	utils.SuccessResponse(c, list, "comments retrieved successfully")
+}
+
+// PostCollectionComment adds a comment to a collection's discussion
+// @Summary Post a collection comment
+// @Description Start a discussion thread on a collection, or reply to one with thread_id. Requires comment permission
+// @Tags sharing
+// @Accept json
+// @Produce json
+// @Param id path int true "Collection ID"
+// @Param request body CollectionCommentRequest true "Comment"
+// @Success 201 {object} CollectionComment
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/collections/{id}/comments [post]
+func (h *Handler) PostCollectionComment(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+	collectionID, ok := collectionIDParam(c)
+	if !ok {
+		return
+	}
+
+	var request CollectionCommentRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "invalid_request", "invalid request body", map[string]interface{}{"error": err.Error()})
+		return
+	}
+	request.IPAddress = c.ClientIP()
+	request.UserAgent = c.GetHeader("User-Agent")
+
+	comment, err := h.service.PostCollectionComment(c.Request.Context(), userID, collectionID, &request)
+	if err != nil {
+		h.commentError(c, err, "failed to post comment")
+		return
+	}
+
+	c.JSON(http.StatusCreated, utils.APIResponse{
+		Success: true,
+		Data:    comment,
+		Message: "comment posted successfully",
+	})
+}
+
+// UpdateCollectionComment edits a comment
+// @Summary Edit a collection comment
+// @Description Edit one of your own comments
+// @Tags sharing
+// @Accept json
+// @Produce json
+// @Param comment_id path int true "Comment ID"
+// @Param request body UpdateCollectionCommentRequest true "Comment"
+// @Success 200 {object} CollectionComment
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/collection-comments/{comment_id} [put]
+func (h *Handler) UpdateCollectionComment(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+	commentID, ok := commentIDParam(c)
+	if !ok {
+		return
+	}
+
+	var request UpdateCollectionCommentRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "invalid_request", "invalid request body", map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	comment, err := h.service.UpdateCollectionComment(c.Request.Context(), userID, commentID, &request)
+	if err != nil {
+		h.commentError(c, err, "failed to update comment")
+		return
+	}
+
+	utils.SuccessResponse(c, comment, "comment updated successfully")
+}
+
+// DeleteCollectionComment removes a comment
+// @Summary Delete a collection comment
+// @Description Delete your own comment, or as the collection owner any comment. Deleting the first comment of a thread deletes the thread
+// @Tags sharing
+// @Produce json
+// @Param comment_id path int true "Comment ID"
+// @Success 200 {object} utils.APIResponse
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/collection-comments/{comment_id} [delete]
+func (h *Handler) DeleteCollectionComment(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+	commentID, ok := commentIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteCollectionComment(c.Request.Context(), userID, commentID); err != nil {
+		h.commentError(c, err, "failed to delete comment")
+		return
+	}
+
+	utils.SuccessResponse(c, nil, "comment deleted successfully")
+}
+
+// ModerateCollectionComment hides or restores a comment
+// @Summary Moderate a collection comment
+// @Description Hide a comment from other participants, or restore it. Only the collection owner and admin collaborators moderate
+// @Tags sharing
+// @Accept json
+// @Produce json
+// @Param comment_id path int true "Comment ID"
+// @Param request body ModerateCollectionCommentRequest true "Moderation"
+// @Success 200 {object} CollectionComment
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/collection-comments/{comment_id}/moderate [post]
+func (h *Handler) ModerateCollectionComment(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+	commentID, ok := commentIDParam(c)
+	if !ok {
+		return
+	}
+
+	var request ModerateCollectionCommentRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "invalid_request", "invalid request body", map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	comment, err := h.service.ModerateCollectionComment(c.Request.Context(), userID, commentID, &request)
+	if err != nil {
+		h.commentError(c, err, "failed to moderate comment")
+		return
+	}
+
+	utils.SuccessResponse(c, comment, "comment moderated successfully")
+}
+
+func commentIDParam(c *gin.Context) (uint, bool) {
+	commentID, err := strconv.ParseUint(c.Param("comment_id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "invalid_id", "invalid comment ID", nil)
+		return 0, false
+	}
+	return uint(commentID), true
+}
+
+func (h *Handler) commentError(c *gin.Context, err error, message string) {
+	switch err {
+	case ErrCollectionNotFound:
+		utils.ErrorResponse(c, http.StatusNotFound, "collection_not_found", "collection not found", nil)
+	case ErrCommentNotFound:
+		utils.ErrorResponse(c, http.StatusNotFound, "comment_not_found", "comment not found", nil)
+	case ErrEmptyComment:
+		utils.ErrorResponse(c, http.StatusBadRequest, "invalid_request", err.Error(), nil)
+	case ErrUnauthorized:
+		utils.ErrorResponse(c, http.StatusForbidden, "unauthorized", "unauthorized access", nil)
+	case ErrInsufficientPermission:
+		utils.ErrorResponse(c, http.StatusForbidden, "insufficient_permission", "comment permission is required to post", nil)
+	default:
+		respondError(c, err, message)
+	}
+}
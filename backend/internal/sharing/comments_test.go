@@ -0,0 +1,126 @@
+package sharing
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+// collaborate adds an accepted collaborator to a collection
+func (suite *SharingServiceTestSuite) collaborate(collection *database.Collection, user *database.User, permission SharePermission) {
+	suite.Require().NoError(suite.db.Create(&CollectionCollaborator{
+		CollectionID: collection.ID,
+		UserID:       user.ID,
+		InviterID:    collection.UserID,
+		Permission:   permission,
+		Status:       "accepted",
+		InvitedAt:    time.Now(),
+	}).Error)
+}
+
+func (suite *SharingServiceTestSuite) TestCollectionDiscussion() {
+	ctx := context.Background()
+	owner := suite.createUser("owner")
+	commenter := suite.createUser("commenter")
+	viewer := suite.createUser("viewer")
+	moderator := suite.createUser("moderator")
+	stranger := suite.createUser("stranger")
+	collection := suite.factory.Collection(owner.ID)
+	share := suite.factory.Share(collection)
+	suite.collaborate(collection, commenter, PermissionComment)
+	suite.collaborate(collection, moderator, PermissionAdmin)
+	_, err := suite.service.ShareWithUser(ctx, owner.ID, &DirectShareRequest{
+		ResourceType: ResourceTypeCollection, ResourceID: collection.ID, Recipient: "viewer", Permission: PermissionView,
+	})
+	suite.Require().NoError(err)
+
+	notifier := &recordingNotifier{sent: map[uint][]string{}}
+	suite.service.SetNotifier(notifier)
+	defer suite.service.SetNotifier(nil)
+
+	// Collaborators with comment permission start threads, the owner is told
+	thread, err := suite.service.PostCollectionComment(ctx, commenter.ID, collection.ID, &CollectionCommentRequest{Content: "  Should we add the Go tour?  "})
+	suite.Require().NoError(err)
+	suite.Equal("Should we add the Go tour?", thread.Content)
+	suite.Equal([]string{NotificationCommentPosted}, notifier.sent[owner.ID])
+	suite.Empty(notifier.sent[viewer.ID], "only participants are notified")
+
+	var activity ShareActivity
+	suite.Require().NoError(suite.db.Where("share_id = ? AND activity_type = ?", share.ID, ActivityTypeComment).First(&activity).Error)
+	suite.Equal(commenter.ID, *activity.UserID)
+	var metadata map[string]uint
+	suite.Require().NoError(json.Unmarshal([]byte(activity.Metadata), &metadata))
+	suite.Equal(thread.ID, metadata["comment_id"])
+
+	// Viewers read but don't post, strangers don't read
+	_, err = suite.service.PostCollectionComment(ctx, viewer.ID, collection.ID, &CollectionCommentRequest{Content: "Me too"})
+	suite.Equal(ErrInsufficientPermission, err)
+	_, err = suite.service.GetCollectionComments(ctx, stranger.ID, collection.ID, 1, 0)
+	suite.Equal(ErrUnauthorized, err)
+	_, err = suite.service.PostCollectionComment(ctx, commenter.ID, collection.ID, &CollectionCommentRequest{Content: " "})
+	suite.Equal(ErrEmptyComment, err)
+
+	// Replies to a reply join the thread, and its participants are told
+	reply, err := suite.service.PostCollectionComment(ctx, owner.ID, collection.ID, &CollectionCommentRequest{Content: "Yes", ThreadID: &thread.ID})
+	suite.Require().NoError(err)
+	nested, err := suite.service.PostCollectionComment(ctx, moderator.ID, collection.ID, &CollectionCommentRequest{Content: "Done", ThreadID: &reply.ID})
+	suite.Require().NoError(err)
+	suite.Equal(thread.ID, *nested.ThreadID)
+	suite.Equal([]string{NotificationCommentPosted, NotificationCommentPosted}, notifier.sent[commenter.ID])
+	suite.Len(notifier.sent[owner.ID], 2)
+
+	other := suite.factory.Collection(owner.ID)
+	_, err = suite.service.PostCollectionComment(ctx, owner.ID, other.ID, &CollectionCommentRequest{Content: "Elsewhere", ThreadID: &thread.ID})
+	suite.Equal(ErrCommentNotFound, err)
+
+	// Moderators hide comments; only they and the author still see the text
+	_, err = suite.service.ModerateCollectionComment(ctx, commenter.ID, reply.ID, &ModerateCollectionCommentRequest{Hidden: true})
+	suite.Equal(ErrUnauthorized, err)
+	hidden, err := suite.service.ModerateCollectionComment(ctx, moderator.ID, thread.ID, &ModerateCollectionCommentRequest{Hidden: true})
+	suite.Require().NoError(err)
+	suite.Equal(moderator.ID, *hidden.HiddenBy)
+
+	list, err := suite.service.GetCollectionComments(ctx, viewer.ID, collection.ID, 1, 0)
+	suite.Require().NoError(err)
+	suite.False(list.CanPost)
+	suite.Require().Len(list.Threads, 1)
+	suite.True(list.Threads[0].Hidden)
+	suite.Empty(list.Threads[0].Content)
+	suite.Equal("commenter", list.Threads[0].Username)
+	suite.Require().Len(list.Threads[0].Replies, 2)
+	suite.Equal("Yes", list.Threads[0].Replies[0].Content)
+
+	list, err = suite.service.GetCollectionComments(ctx, commenter.ID, collection.ID, 1, 0)
+	suite.Require().NoError(err)
+	suite.True(list.CanPost)
+	suite.False(list.CanModerate)
+	suite.Equal("Should we add the Go tour?", list.Threads[0].Content)
+
+	// Only authors edit
+	_, err = suite.service.UpdateCollectionComment(ctx, owner.ID, thread.ID, &UpdateCollectionCommentRequest{Content: "Edited"})
+	suite.Equal(ErrUnauthorized, err)
+	edited, err := suite.service.UpdateCollectionComment(ctx, commenter.ID, thread.ID, &UpdateCollectionCommentRequest{Content: "Should we add the tour?"})
+	suite.Require().NoError(err)
+	suite.NotNil(edited.EditedAt)
+
+	// Threads are listed by their latest activity
+	newer, err := suite.service.PostCollectionComment(ctx, commenter.ID, collection.ID, &CollectionCommentRequest{Content: "New topic"})
+	suite.Require().NoError(err)
+	suite.Require().NoError(suite.db.Model(&CollectionComment{}).Where("id = ?", newer.ID).
+		UpdateColumn("updated_at", time.Now().Add(-time.Hour)).Error)
+	list, err = suite.service.GetCollectionComments(ctx, owner.ID, collection.ID, 1, 0)
+	suite.Require().NoError(err)
+	suite.True(list.CanModerate)
+	suite.Equal(int64(2), list.Total)
+	suite.Equal(thread.ID, list.Threads[0].ID)
+
+	// Deleting a thread removes its replies; others can't delete the owner's
+	suite.Equal(ErrUnauthorized, suite.service.DeleteCollectionComment(ctx, commenter.ID, reply.ID))
+	suite.Require().NoError(suite.service.DeleteCollectionComment(ctx, owner.ID, thread.ID))
+	var left int64
+	suite.db.Model(&CollectionComment{}).Where("collection_id = ?", collection.ID).Count(&left)
+	suite.Equal(int64(1), left)
+	suite.Equal(ErrCommentNotFound, suite.service.DeleteCollectionComment(ctx, owner.ID, nested.ID))
+}
@@ -0,0 +1,385 @@
+package sharing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/internal/safety"
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/utils"
+)
+
+const (
+	// shareAPIDefaultRateLimit is the hourly request budget of a share's data
+	// API when its owner sets none
+	shareAPIDefaultRateLimit = 1000
+	// shareAPIRateWindow is the period share API budgets are counted over
+	shareAPIRateWindow = time.Hour
+
+	shareAPIDefaultLimit  = 20
+	shareAPIMaxLimit      = 100
+	shareAPIDefaultFacets = 10
+	shareAPIMaxFacets     = 50
+)
+
+// RateCounter counts requests in a window shared between replicas
+type RateCounter interface {
+	IncrementWithExpiration(ctx context.Context, key string, expiration time.Duration) (int64, error)
+}
+
+// SetRateCounter enables the per-share rate limit of the data API
+func (s *Service) SetRateCounter(counter RateCounter) {
+	s.counter = counter
+}
+
+// ShareAPIQuery selects a page of a shared collection. Tags must all match;
+// the other filters match one value
+type ShareAPIQuery struct {
+	Page      int
+	Limit     int
+	Tags      []string
+	Domain    string
+	Language  string
+	Sort      string // newest (default) or oldest
+	MaxFacets int
+}
+
+// ShareAPIBookmark is a bookmark as the data API publishes it
+type ShareAPIBookmark struct {
+	ID          uint      `json:"id"`
+	URL         string    `json:"url"`
+	Title       string    `json:"title"`
+	Description string    `json:"description,omitempty"`
+	Favicon     string    `json:"favicon,omitempty"`
+	Tags        []string  `json:"tags"`
+	Language    string    `json:"language,omitempty"`
+	SavedAt     time.Time `json:"saved_at"`
+	Warning     string    `json:"warning,omitempty"` // set when the link is flagged as unsafe
+}
+
+// ShareAPIFacet is a filter value and how many matching bookmarks have it
+type ShareAPIFacet struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// ShareAPIFacets count the bookmarks matching the filters by tag, domain
+// and language, most common first
+type ShareAPIFacets struct {
+	Tags      []ShareAPIFacet `json:"tags"`
+	Domains   []ShareAPIFacet `json:"domains"`
+	Languages []ShareAPIFacet `json:"languages"`
+}
+
+// ShareAPIPage is one page of a shared collection's bookmarks
+type ShareAPIPage struct {
+	Title       string             `json:"title"`
+	Description string             `json:"description"`
+	ShareURL    string             `json:"share_url"`
+	Bookmarks   []ShareAPIBookmark `json:"bookmarks"`
+	Facets      ShareAPIFacets     `json:"facets"`
+	Page        int                `json:"page"`
+	Limit       int                `json:"limit"`
+	Total       int                `json:"total"` // bookmarks matching the filters
+	HasMore     bool               `json:"has_more"`
+}
+
+// GetAPIShare returns the share behind a data API token. Password protected
+// shares can't be served since there is no way to prompt for the password
+func (s *Service) GetAPIShare(ctx context.Context, token string) (*CollectionShare, error) {
+	share, err := s.GetShareByToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if !share.ServesAPI() {
+		return nil, ErrShareAPIDisabled
+	}
+	return share, nil
+}
+
+// CheckAPIRateLimit counts a request against the share's hourly budget
+// across replicas and returns the budget and how many requests remain,
+// -1 when unlimited
+func (s *Service) CheckAPIRateLimit(ctx context.Context, share *CollectionShare) (int, int, error) {
+	limit := share.APIRateLimit
+	if limit <= 0 {
+		limit = shareAPIDefaultRateLimit
+	}
+	if s.counter == nil {
+		return limit, -1, nil
+	}
+
+	window := time.Now().Truncate(shareAPIRateWindow).Unix()
+	count, err := s.counter.IncrementWithExpiration(ctx, fmt.Sprintf("share:ratelimit:%d:%d", share.ID, window), shareAPIRateWindow)
+	if err != nil {
+		// Fail open: a Redis outage should not take the sites built on shares down
+		return limit, -1, nil
+	}
+	if count > int64(limit) {
+		return limit, 0, ErrShareRateLimited
+	}
+	return limit, limit - int(count), nil
+}
+
+// GetAPIBookmarks returns a page of the shared collection's bookmarks that
+// match the query, with facets over every match
+func (s *Service) GetAPIBookmarks(ctx context.Context, share *CollectionShare, query ShareAPIQuery) (*ShareAPIPage, error) {
+	query.normalize()
+
+	var collection database.Collection
+	if err := s.db.WithContext(ctx).First(&collection, share.CollectionID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrCollectionNotFound
+		}
+		return nil, fmt.Errorf("failed to find collection: %w", err)
+	}
+
+	order := "bookmarks.created_at DESC, bookmarks.id DESC"
+	if query.Sort == "oldest" {
+		order = "bookmarks.created_at ASC, bookmarks.id ASC"
+	}
+	var bookmarks []database.Bookmark
+	if err := s.db.WithContext(ctx).
+		Joins("JOIN bookmark_collections ON bookmarks.id = bookmark_collections.bookmark_id").
+		Where("bookmark_collections.collection_id = ?", collection.ID).
+		Order(order).
+		Find(&bookmarks).Error; err != nil {
+		return nil, fmt.Errorf("failed to get collection bookmarks: %w", err)
+	}
+
+	page := &ShareAPIPage{
+		Title:       share.Title,
+		Description: share.Description,
+		ShareURL:    s.baseURL + "/shared/" + share.ShareToken,
+		Bookmarks:   []ShareAPIBookmark{},
+		Page:        query.Page,
+		Limit:       query.Limit,
+	}
+	if page.Title == "" {
+		page.Title = collection.Name
+	}
+	if page.Description == "" {
+		page.Description = collection.Description
+	}
+
+	tags, domains, languages := map[string]int{}, map[string]int{}, map[string]int{}
+	offset := (query.Page - 1) * query.Limit
+	for _, bookmark := range bookmarks {
+		item := apiBookmark(bookmark)
+		domain := bookmarkDomain(bookmark.URL)
+		if !query.matches(item, domain) {
+			continue
+		}
+
+		for _, tag := range item.Tags {
+			tags[tag]++
+		}
+		if domain != "" {
+			domains[domain]++
+		}
+		if item.Language != "" {
+			languages[item.Language]++
+		}
+
+		if page.Total >= offset && len(page.Bookmarks) < query.Limit {
+			page.Bookmarks = append(page.Bookmarks, item)
+		}
+		page.Total++
+	}
+	page.HasMore = offset+len(page.Bookmarks) < page.Total
+	page.Facets = ShareAPIFacets{
+		Tags:      topFacets(tags, query.MaxFacets),
+		Domains:   topFacets(domains, query.MaxFacets),
+		Languages: topFacets(languages, query.MaxFacets),
+	}
+	return page, nil
+}
+
+// normalize applies the defaults and caps of the query
+func (q *ShareAPIQuery) normalize() {
+	if q.Page <= 0 {
+		q.Page = 1
+	}
+	if q.Limit <= 0 {
+		q.Limit = shareAPIDefaultLimit
+	}
+	if q.Limit > shareAPIMaxLimit {
+		q.Limit = shareAPIMaxLimit
+	}
+	if q.MaxFacets <= 0 {
+		q.MaxFacets = shareAPIDefaultFacets
+	}
+	if q.MaxFacets > shareAPIMaxFacets {
+		q.MaxFacets = shareAPIMaxFacets
+	}
+	for i, tag := range q.Tags {
+		q.Tags[i] = strings.ToLower(strings.TrimSpace(tag))
+	}
+	q.Domain = strings.TrimPrefix(strings.ToLower(q.Domain), "www.")
+	q.Language = strings.ToLower(q.Language)
+}
+
+// matches reports whether a bookmark passes the query's filters
+func (q *ShareAPIQuery) matches(bookmark ShareAPIBookmark, domain string) bool {
+	if q.Domain != "" && domain != q.Domain {
+		return false
+	}
+	if q.Language != "" && bookmark.Language != q.Language {
+		return false
+	}
+	for _, want := range q.Tags {
+		found := false
+		for _, tag := range bookmark.Tags {
+			if strings.ToLower(tag) == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// apiBookmark converts a bookmark to its published form
+func apiBookmark(bookmark database.Bookmark) ShareAPIBookmark {
+	tags := []string{}
+	if bookmark.Tags != "" {
+		if err := json.Unmarshal([]byte(bookmark.Tags), &tags); err != nil {
+			tags = []string{}
+		}
+	}
+	return ShareAPIBookmark{
+		ID:          bookmark.ID,
+		URL:         bookmark.URL,
+		Title:       bookmark.Title,
+		Description: bookmark.Description,
+		Favicon:     bookmark.Favicon,
+		Tags:        tags,
+		Language:    bookmark.Language,
+		SavedAt:     bookmark.CreatedAt,
+		Warning:     safety.Warning(bookmark.SafetyThreat),
+	}
+}
+
+// bookmarkDomain returns the host of a bookmark without www.
+func bookmarkDomain(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+// topFacets returns the most common values, ties in alphabetical order
+func topFacets(counts map[string]int, max int) []ShareAPIFacet {
+	facets := make([]ShareAPIFacet, 0, len(counts))
+	for value, count := range counts {
+		facets = append(facets, ShareAPIFacet{Value: value, Count: count})
+	}
+	sort.Slice(facets, func(i, j int) bool {
+		if facets[i].Count != facets[j].Count {
+			return facets[i].Count > facets[j].Count
+		}
+		return facets[i].Value < facets[j].Value
+	})
+	if len(facets) > max {
+		facets = facets[:max]
+	}
+	return facets
+}
+
+// RegisterShareAPIRoutes registers the login-less data API of shares. The
+// routes should be rate limited per client as well
+func (h *Handler) RegisterShareAPIRoutes(router *gin.RouterGroup) {
+	router.GET("/shares/:token/bookmarks", h.GetShareAPIBookmarks)
+}
+
+// GetShareAPIBookmarks lists the bookmarks of a shared collection as JSON
+// @Summary Get a shared collection's bookmarks
+// @Description List the bookmarks of a share whose owner enabled API access, with tag, domain and language facets. No user token is needed and any site may fetch it; requests count against the share's hourly rate limit
+// @Tags sharing
+// @Produce json
+// @Param token path string true "Share token"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Bookmarks per page (max 100)" default(20)
+// @Param tag query []string false "Only bookmarks with every tag" collectionFormat(multi)
+// @Param domain query string false "Only bookmarks of this domain"
+// @Param lang query string false "Only bookmarks in this language"
+// @Param sort query string false "Sort order" Enums(newest, oldest)
+// @Param facets query int false "Values per facet (max 50)" default(10)
+// @Param fields query string false "Comma-separated bookmark fields to return, dots for nested ones, e.g. url,title"
+// @Success 200 {object} ShareAPIPage
+// @Failure 403 {object} utils.ErrorResponse "API access disabled"
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 410 {object} utils.ErrorResponse "Share expired"
+// @Failure 429 {object} utils.ErrorResponse
+// @Router /api/v1/shares/{token}/bookmarks [get]
+func (h *Handler) GetShareAPIBookmarks(c *gin.Context) {
+	// Third-party sites call this from their own origin without credentials
+	c.Writer.Header().Del("Access-Control-Allow-Credentials")
+	c.Header("Access-Control-Allow-Origin", "*")
+
+	share, err := h.service.GetAPIShare(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		switch err {
+		case ErrShareNotFound:
+			utils.ErrorResponse(c, http.StatusNotFound, "share_not_found", "share not found", nil)
+		case ErrShareExpired:
+			utils.ErrorResponse(c, http.StatusGone, "share_expired", "share has expired", nil)
+		case ErrShareInactive:
+			utils.ErrorResponse(c, http.StatusGone, "share_inactive", "share is inactive", nil)
+		case ErrShareAPIDisabled:
+			utils.ErrorResponse(c, http.StatusForbidden, "share_api_disabled", err.Error(), nil)
+		default:
+			respondError(c, err, "failed to get share")
+		}
+		return
+	}
+
+	limit, remaining, err := h.service.CheckAPIRateLimit(c.Request.Context(), share)
+	if remaining >= 0 {
+		c.Header("X-RateLimit-Limit", strconv.Itoa(limit))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	}
+	if errors.Is(err, ErrShareRateLimited) {
+		reset := time.Until(time.Now().Truncate(shareAPIRateWindow).Add(shareAPIRateWindow))
+		c.Header("Retry-After", strconv.Itoa(int(reset.Seconds())+1))
+		utils.ErrorResponse(c, http.StatusTooManyRequests, "rate_limited", err.Error(), nil)
+		return
+	}
+
+	query := ShareAPIQuery{
+		Tags:     c.QueryArray("tag"),
+		Domain:   c.Query("domain"),
+		Language: c.Query("lang"),
+		Sort:     c.Query("sort"),
+	}
+	query.Page, _ = strconv.Atoi(c.Query("page"))
+	query.Limit, _ = strconv.Atoi(c.Query("limit"))
+	query.MaxFacets, _ = strconv.Atoi(c.Query("facets"))
+
+	page, err := h.service.GetAPIBookmarks(c.Request.Context(), share, query)
+	if err != nil {
+		if err == ErrCollectionNotFound {
+			utils.ErrorResponse(c, http.StatusNotFound, "share_not_found", "share not found", nil)
+			return
+		}
+		respondError(c, err, "failed to get bookmarks")
+		return
+	}
+
+	utils.SparseResponse(c, page, "Shared bookmarks retrieved successfully", "bookmarks")
+}
@@ -0,0 +1,134 @@
+package sharing
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+// fakeRateCounter counts in memory
+type fakeRateCounter map[string]int64
+
+func (f fakeRateCounter) IncrementWithExpiration(ctx context.Context, key string, expiration time.Duration) (int64, error) {
+	f[key]++
+	return f[key], nil
+}
+
+func (suite *SharingServiceTestSuite) createAPIShare() *CollectionShare {
+	owner := suite.createUser("owner")
+	collection := suite.factory.Collection(owner.ID, func(c *database.Collection) { c.Name = "Go Links" })
+	bookmarks := []struct {
+		url, tags, language string
+	}{
+		{"https://go.dev/doc", `["go","docs"]`, "en"},
+		{"https://www.go.dev/blog", `["go"]`, "en"},
+		{"https://example.de/go", `["go","docs"]`, "de"},
+	}
+	for n, b := range bookmarks {
+		bookmark := suite.factory.Bookmark(owner.ID, func(bm *database.Bookmark) {
+			bm.URL, bm.Tags, bm.Language = b.url, b.tags, b.language
+			bm.CreatedAt = time.Date(2026, 1, n+1, 0, 0, 0, 0, time.UTC)
+		})
+		suite.factory.AddToCollection(collection, bookmark)
+	}
+	// Bookmarks outside the collection are never published
+	suite.factory.Bookmark(owner.ID)
+
+	share := &CollectionShare{
+		CollectionID: collection.ID,
+		UserID:       owner.ID,
+		ShareType:    ShareTypePublic,
+		Permission:   PermissionView,
+		ShareToken:   "api-token",
+		IsActive:     true,
+		APIEnabled:   true,
+		APIRateLimit: 2,
+	}
+	suite.Require().NoError(suite.db.Create(share).Error)
+	return share
+}
+
+func (suite *SharingServiceTestSuite) TestGetAPIBookmarks() {
+	share := suite.createAPIShare()
+	ctx := context.Background()
+
+	// When: Reading the first page of two
+	page, err := suite.service.GetAPIBookmarks(ctx, share, ShareAPIQuery{Limit: 2})
+
+	// Then: The newest bookmarks come first with facets over the whole collection
+	suite.Require().NoError(err)
+	suite.Equal("Go Links", page.Title)
+	suite.Equal(3, page.Total)
+	suite.True(page.HasMore)
+	suite.Require().Len(page.Bookmarks, 2)
+	suite.Equal("https://example.de/go", page.Bookmarks[0].URL)
+	suite.Equal([]ShareAPIFacet{{"go", 3}, {"docs", 2}}, page.Facets.Tags)
+	suite.Equal([]ShareAPIFacet{{"go.dev", 2}, {"example.de", 1}}, page.Facets.Domains)
+
+	// And: Filters narrow both the page and the facets
+	page, err = suite.service.GetAPIBookmarks(ctx, share, ShareAPIQuery{Tags: []string{"Docs"}, Domain: "www.go.dev"})
+	suite.Require().NoError(err)
+	suite.Equal(1, page.Total)
+	suite.Equal("https://go.dev/doc", page.Bookmarks[0].URL)
+	suite.Equal([]ShareAPIFacet{{"en", 1}}, page.Facets.Languages)
+
+	page, err = suite.service.GetAPIBookmarks(ctx, share, ShareAPIQuery{Page: 2, Limit: 2, Sort: "oldest"})
+	suite.Require().NoError(err)
+	suite.False(page.HasMore)
+	suite.Require().Len(page.Bookmarks, 1)
+	suite.Equal("https://example.de/go", page.Bookmarks[0].URL)
+
+	// And: Password protected shares have no data API
+	suite.Require().NoError(suite.db.Model(share).Update("password", "secret").Error)
+	_, err = suite.service.GetAPIShare(ctx, "api-token")
+	suite.Equal(ErrShareAPIDisabled, err)
+}
+
+func (suite *SharingServiceTestSuite) TestGetShareAPIBookmarksHandler() {
+	suite.createAPIShare()
+	service := NewService(suite.db, "http://localhost:3000")
+	service.SetRateCounter(fakeRateCounter{})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	NewHandler(service).RegisterShareAPIRoutes(router.Group("/api/v1"))
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	w := get("/api/v1/shares/api-token/bookmarks?tag=go&tag=docs")
+	suite.Require().Equal(http.StatusOK, w.Code)
+	suite.Equal("*", w.Header().Get("Access-Control-Allow-Origin"))
+	suite.Equal("2", w.Header().Get("X-RateLimit-Limit"))
+	suite.Equal("1", w.Header().Get("X-RateLimit-Remaining"))
+	var response struct {
+		Data ShareAPIPage `json:"data"`
+	}
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &response))
+	suite.Equal(2, response.Data.Total)
+
+	suite.Equal(http.StatusOK, get("/api/v1/shares/api-token/bookmarks").Code)
+	w = get("/api/v1/shares/api-token/bookmarks")
+	suite.Equal(http.StatusTooManyRequests, w.Code)
+	suite.NotEmpty(w.Header().Get("Retry-After"))
+
+	suite.Equal(http.StatusNotFound, get("/api/v1/shares/unknown/bookmarks").Code)
+	suite.Require().NoError(suite.db.Model(&CollectionShare{}).Where("share_token = ?", "api-token").Update("api_enabled", false).Error)
+	suite.Equal(http.StatusForbidden, get("/api/v1/shares/api-token/bookmarks").Code)
+}
+
+func (suite *SharingServiceTestSuite) TestCollectionShareToResponseAPIURL() {
+	share := &CollectionShare{ShareToken: "abc", APIEnabled: true}
+	suite.Equal("http://localhost:3000/api/v1/shares/abc/bookmarks", share.ToResponse("http://localhost:3000").APIURL)
+
+	share.Password = "secret"
+	suite.Empty(share.ToResponse("http://localhost:3000").APIURL)
+}
@@ -0,0 +1,243 @@
+package sharing
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"bookmark-sync-service/backend/pkg/middleware"
+	"bookmark-sync-service/backend/pkg/utils"
+)
+
+// RegisterDirectShareRoutes registers the authenticated user-to-user sharing routes
+func (h *Handler) RegisterDirectShareRoutes(router *gin.RouterGroup) {
+	router.POST("/direct-shares", h.ShareWithUser)
+	router.GET("/direct-shares", h.GetDirectShares)
+	router.PUT("/direct-shares/:id", h.UpdateDirectShare)
+	router.DELETE("/direct-shares/:id", h.RevokeDirectShare)
+	router.GET("/shared-with-me", h.GetSharedWithMe)
+}
+
+// ShareWithUser shares a bookmark or collection with a named user
+// @Summary Share with a user
+// @Description Share a bookmark or collection directly with a user by email or username
+// @Tags sharing
+// @Accept json
+// @Produce json
+// @Param request body DirectShareRequest true "Direct share request"
+// @Success 201 {object} DirectShare
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/direct-shares [post]
+func (h *Handler) ShareWithUser(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	var request DirectShareRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "invalid_request", "invalid request format", map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	share, err := h.service.ShareWithUser(c.Request.Context(), userID, &request)
+	if err != nil {
+		switch err {
+		case ErrResourceNotFound:
+			utils.ErrorResponse(c, http.StatusNotFound, "resource_not_found", "resource not found", nil)
+		case ErrRecipientNotFound:
+			utils.ErrorResponse(c, http.StatusNotFound, "recipient_not_found", "recipient not found", nil)
+		case ErrUnauthorized:
+			utils.ErrorResponse(c, http.StatusForbidden, "unauthorized", "unauthorized access", nil)
+		case ErrDirectShareExists:
+			utils.ErrorResponse(c, http.StatusConflict, "share_exists", "resource already shared with this user", nil)
+		case ErrCannotShareWithSelf, ErrInvalidResourceType, ErrInvalidPermission:
+			utils.ErrorResponse(c, http.StatusBadRequest, "invalid_request", "invalid request parameters", map[string]interface{}{"error": err.Error()})
+		default:
+			respondError(c, err, "failed to share resource")
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, utils.APIResponse{
+		Success: true,
+		Message: "resource shared successfully",
+		Data:    share,
+	})
+}
+
+// GetDirectShares lists the direct shares the current user has granted
+// @Summary List direct shares
+// @Description List bookmarks and collections the current user has shared with other users
+// @Tags sharing
+// @Produce json
+// @Param resource_type query string false "Filter by resource type" Enums(bookmark, collection)
+// @Param resource_id query int false "Filter by resource ID"
+// @Success 200 {array} DirectShare
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/direct-shares [get]
+func (h *Handler) GetDirectShares(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	var resourceID uint64
+	if value := c.Query("resource_id"); value != "" {
+		parsed, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "invalid_resource_id", "invalid resource ID", nil)
+			return
+		}
+		resourceID = parsed
+	}
+
+	shares, err := h.service.GetDirectShares(c.Request.Context(), userID, c.Query("resource_type"), uint(resourceID))
+	if err != nil {
+		respondError(c, err, "failed to get direct shares")
+		return
+	}
+
+	c.JSON(http.StatusOK, shares)
+}
+
+// UpdateDirectShare changes the permission of a direct share
+// @Summary Update direct share
+// @Description Change the permission granted to a user
+// @Tags sharing
+// @Accept json
+// @Produce json
+// @Param id path int true "Direct share ID"
+// @Param request body UpdateDirectShareRequest true "Update request"
+// @Success 200 {object} DirectShare
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/direct-shares/{id} [put]
+func (h *Handler) UpdateDirectShare(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	shareID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "invalid_share_id", "invalid share ID", nil)
+		return
+	}
+
+	var request UpdateDirectShareRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "invalid_request", "invalid request format", map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	share, err := h.service.UpdateDirectShare(c.Request.Context(), userID, uint(shareID), &request)
+	if err != nil {
+		switch err {
+		case ErrShareNotFound:
+			utils.ErrorResponse(c, http.StatusNotFound, "share_not_found", "share not found", nil)
+		case ErrUnauthorized:
+			utils.ErrorResponse(c, http.StatusForbidden, "unauthorized", "unauthorized access", nil)
+		case ErrInvalidPermission:
+			utils.ErrorResponse(c, http.StatusBadRequest, "invalid_request", "invalid request parameters", map[string]interface{}{"error": err.Error()})
+		default:
+			respondError(c, err, "failed to update share")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, share)
+}
+
+// RevokeDirectShare revokes a direct share
+// @Summary Revoke direct share
+// @Description Revoke a user's access, or remove an item shared with the current user
+// @Tags sharing
+// @Param id path int true "Direct share ID"
+// @Success 204
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/direct-shares/{id} [delete]
+func (h *Handler) RevokeDirectShare(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	shareID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "invalid_share_id", "invalid share ID", nil)
+		return
+	}
+
+	if err := h.service.RevokeDirectShare(c.Request.Context(), userID, uint(shareID)); err != nil {
+		switch err {
+		case ErrShareNotFound:
+			utils.ErrorResponse(c, http.StatusNotFound, "share_not_found", "share not found", nil)
+		case ErrUnauthorized:
+			utils.ErrorResponse(c, http.StatusForbidden, "unauthorized", "unauthorized access", nil)
+		default:
+			respondError(c, err, "failed to revoke share")
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetSharedWithMe lists items other users have shared with the current user
+// @Summary Shared with me
+// @Description List bookmarks and collections shared directly with the current user
+// @Tags sharing
+// @Produce json
+// @Param resource_type query string false "Filter by resource type" Enums(bookmark, collection)
+// @Success 200 {array} SharedItem
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/shared-with-me [get]
+func (h *Handler) GetSharedWithMe(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	items, err := h.service.GetSharedWithMe(c.Request.Context(), userID, c.Query("resource_type"))
+	if err != nil {
+		respondError(c, err, "failed to get shared items")
+		return
+	}
+
+	c.JSON(http.StatusOK, items)
+}
+
+// requireUserID reads the authenticated user ID, writing an error response when missing
+func requireUserID(c *gin.Context) (uint, bool) {
+	userIDStr := middleware.GetUserID(c)
+	if userIDStr == "" {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "unauthorized", "user not authenticated", nil)
+		return 0, false
+	}
+
+	userID, err := strconv.ParseUint(userIDStr, 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "invalid_user_id", "invalid user ID", nil)
+		return 0, false
+	}
+
+	return uint(userID), true
+}
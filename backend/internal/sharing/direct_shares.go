@@ -0,0 +1,349 @@
+package sharing
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+// Notifier delivers share notifications to users
+type Notifier interface {
+	Notify(ctx context.Context, userID uint, notification *ShareNotification) error
+}
+
+// NotificationPublisher publishes a notification on a user's channel
+type NotificationPublisher interface {
+	PublishNotification(ctx context.Context, userID string, notification interface{}) error
+}
+
+// publisherNotifier adapts a NotificationPublisher, such as the Redis client, to Notifier
+type publisherNotifier struct {
+	publisher NotificationPublisher
+}
+
+// NewPublisherNotifier creates a Notifier that publishes on the recipient's notification channel
+func NewPublisherNotifier(publisher NotificationPublisher) Notifier {
+	return &publisherNotifier{publisher: publisher}
+}
+
+// Notify publishes the notification for the user
+func (n *publisherNotifier) Notify(ctx context.Context, userID uint, notification *ShareNotification) error {
+	return n.publisher.PublishNotification(ctx, strconv.FormatUint(uint64(userID), 10), notification)
+}
+
+// SetNotifier configures how share recipients are notified
+func (s *Service) SetNotifier(notifier Notifier) {
+	s.notifier = notifier
+}
+
+// ShareWithUser shares a bookmark or collection directly with another user
+func (s *Service) ShareWithUser(ctx context.Context, userID uint, request *DirectShareRequest) (*DirectShare, error) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+
+	ownerID, err := s.resourceOwner(request.ResourceType, request.ResourceID)
+	if err != nil {
+		return nil, err
+	}
+	if ownerID != userID {
+		return nil, ErrUnauthorized
+	}
+
+	recipient, err := s.findRecipient(request.Recipient)
+	if err != nil {
+		return nil, err
+	}
+	if recipient.ID == userID {
+		return nil, ErrCannotShareWithSelf
+	}
+
+	var existing DirectShare
+	if err := s.db.First(&existing, "recipient_id = ? AND resource_type = ? AND resource_id = ?",
+		recipient.ID, request.ResourceType, request.ResourceID).Error; err == nil {
+		return nil, ErrDirectShareExists
+	}
+
+	share := &DirectShare{
+		OwnerID:      userID,
+		RecipientID:  recipient.ID,
+		ResourceType: request.ResourceType,
+		ResourceID:   request.ResourceID,
+		Permission:   request.Permission,
+		Message:      request.Message,
+	}
+
+	if err := s.db.Create(share).Error; err != nil {
+		return nil, fmt.Errorf("failed to create direct share: %w", err)
+	}
+
+	s.notify(ctx, share.RecipientID, NotificationShareReceived, share)
+
+	return share, nil
+}
+
+// UpdateDirectShare changes the permission granted by a direct share
+func (s *Service) UpdateDirectShare(ctx context.Context, userID uint, shareID uint, request *UpdateDirectShareRequest) (*DirectShare, error) {
+	if !request.Permission.directShareable() {
+		return nil, ErrInvalidPermission
+	}
+
+	share, err := s.getDirectShare(shareID)
+	if err != nil {
+		return nil, err
+	}
+	if share.OwnerID != userID {
+		return nil, ErrUnauthorized
+	}
+
+	share.Permission = request.Permission
+	if err := s.db.Save(share).Error; err != nil {
+		return nil, fmt.Errorf("failed to update direct share: %w", err)
+	}
+
+	s.notify(ctx, share.RecipientID, NotificationShareUpdated, share)
+
+	return share, nil
+}
+
+// RevokeDirectShare removes a direct share. The owner revokes access; the
+// recipient may also remove an item from their own "Shared with me" list
+func (s *Service) RevokeDirectShare(ctx context.Context, userID uint, shareID uint) error {
+	share, err := s.getDirectShare(shareID)
+	if err != nil {
+		return err
+	}
+	if share.OwnerID != userID && share.RecipientID != userID {
+		return ErrUnauthorized
+	}
+
+	if err := s.db.Delete(share).Error; err != nil {
+		return fmt.Errorf("failed to revoke direct share: %w", err)
+	}
+
+	if share.OwnerID == userID {
+		s.notify(ctx, share.RecipientID, NotificationShareRevoked, share)
+	}
+
+	return nil
+}
+
+// GetDirectShares retrieves the direct shares a user has granted
+func (s *Service) GetDirectShares(ctx context.Context, userID uint, resourceType string, resourceID uint) ([]DirectShare, error) {
+	query := s.db.Where("owner_id = ?", userID)
+	if resourceType != "" {
+		query = query.Where("resource_type = ?", resourceType)
+	}
+	if resourceID != 0 {
+		query = query.Where("resource_id = ?", resourceID)
+	}
+
+	var shares []DirectShare
+	if err := query.Order("created_at DESC").Find(&shares).Error; err != nil {
+		return nil, fmt.Errorf("failed to get direct shares: %w", err)
+	}
+
+	return shares, nil
+}
+
+// GetSharedWithMe lists the bookmarks and collections shared directly with a user
+func (s *Service) GetSharedWithMe(ctx context.Context, userID uint, resourceType string) ([]SharedItem, error) {
+	query := s.db.Where("recipient_id = ?", userID)
+	if resourceType != "" {
+		query = query.Where("resource_type = ?", resourceType)
+	}
+
+	var shares []DirectShare
+	if err := query.Order("created_at DESC").Find(&shares).Error; err != nil {
+		return nil, fmt.Errorf("failed to get shared items: %w", err)
+	}
+
+	items := make([]SharedItem, 0, len(shares))
+	owners := make(map[uint]string)
+	for _, share := range shares {
+		item := SharedItem{
+			ShareID:      share.ID,
+			ResourceType: share.ResourceType,
+			ResourceID:   share.ResourceID,
+			Permission:   share.Permission,
+			OwnerID:      share.OwnerID,
+			Message:      share.Message,
+			SharedAt:     share.CreatedAt,
+		}
+
+		switch share.ResourceType {
+		case ResourceTypeBookmark:
+			var bookmark database.Bookmark
+			if err := s.db.First(&bookmark, share.ResourceID).Error; err != nil {
+				// The bookmark was deleted after it was shared
+				continue
+			}
+			item.Title = bookmark.Title
+			item.URL = bookmark.URL
+		case ResourceTypeCollection:
+			var collection database.Collection
+			if err := s.db.First(&collection, share.ResourceID).Error; err != nil {
+				continue
+			}
+			item.Title = collection.Name
+		}
+
+		if _, ok := owners[share.OwnerID]; !ok {
+			var owner database.User
+			if err := s.db.First(&owner, share.OwnerID).Error; err == nil {
+				owners[share.OwnerID] = owner.Username
+			}
+		}
+		item.OwnerUsername = owners[share.OwnerID]
+
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// CanAccess reports whether a user holds at least the required permission on
+// a bookmark or collection, either as owner, through a direct share, as an
+// accepted collaborator, or through a direct share of a containing collection
+func (s *Service) CanAccess(ctx context.Context, userID uint, resourceType string, resourceID uint, required SharePermission) (bool, error) {
+	ownerID, err := s.resourceOwner(resourceType, resourceID)
+	if err != nil {
+		return false, err
+	}
+	if ownerID == userID {
+		return true, nil
+	}
+
+	var share DirectShare
+	if err := s.db.First(&share, "recipient_id = ? AND resource_type = ? AND resource_id = ?",
+		userID, resourceType, resourceID).Error; err == nil && share.Permission.Allows(required) {
+		return true, nil
+	}
+
+	collectionIDs := []uint{resourceID}
+	if resourceType == ResourceTypeBookmark {
+		collectionIDs = nil
+		if err := s.db.Table("bookmark_collections").
+			Where("bookmark_id = ?", resourceID).
+			Pluck("collection_id", &collectionIDs).Error; err != nil {
+			return false, fmt.Errorf("failed to find bookmark collections: %w", err)
+		}
+
+		var shares []DirectShare
+		if len(collectionIDs) > 0 {
+			if err := s.db.Where("recipient_id = ? AND resource_type = ? AND resource_id IN ?",
+				userID, ResourceTypeCollection, collectionIDs).Find(&shares).Error; err != nil {
+				return false, fmt.Errorf("failed to find collection shares: %w", err)
+			}
+		}
+		for _, share := range shares {
+			if share.Permission.Allows(required) {
+				return true, nil
+			}
+		}
+	}
+
+	if len(collectionIDs) == 0 {
+		return false, nil
+	}
+
+	var collaborators []CollectionCollaborator
+	if err := s.db.Where("user_id = ? AND status = ? AND collection_id IN ?",
+		userID, "accepted", collectionIDs).Find(&collaborators).Error; err != nil {
+		return false, fmt.Errorf("failed to find collaborators: %w", err)
+	}
+	for _, collaborator := range collaborators {
+		if collaborator.Permission.Allows(required) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// HasAccess is CanAccess with the permission given by name, for services
+// that check grants without depending on this package
+func (s *Service) HasAccess(ctx context.Context, userID uint, resourceType string, resourceID uint, permission string) (bool, error) {
+	return s.CanAccess(ctx, userID, resourceType, resourceID, SharePermission(permission))
+}
+
+func (s *Service) getDirectShare(shareID uint) (*DirectShare, error) {
+	var share DirectShare
+	if err := s.db.First(&share, shareID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrShareNotFound
+		}
+		return nil, fmt.Errorf("failed to find direct share: %w", err)
+	}
+	return &share, nil
+}
+
+// resourceOwner returns the owner of a shareable resource
+func (s *Service) resourceOwner(resourceType string, resourceID uint) (uint, error) {
+	switch resourceType {
+	case ResourceTypeBookmark:
+		var bookmark database.Bookmark
+		if err := s.db.Select("id", "user_id").First(&bookmark, resourceID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return 0, ErrResourceNotFound
+			}
+			return 0, fmt.Errorf("failed to find bookmark: %w", err)
+		}
+		return bookmark.UserID, nil
+	case ResourceTypeCollection:
+		var collection database.Collection
+		if err := s.db.Select("id", "user_id").First(&collection, resourceID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return 0, ErrResourceNotFound
+			}
+			return 0, fmt.Errorf("failed to find collection: %w", err)
+		}
+		return collection.UserID, nil
+	default:
+		return 0, ErrInvalidResourceType
+	}
+}
+
+// findRecipient resolves a recipient by email or username
+func (s *Service) findRecipient(recipient string) (*database.User, error) {
+	recipient = strings.TrimSpace(recipient)
+
+	column := "username"
+	if strings.Contains(recipient, "@") {
+		column = "email"
+	}
+
+	var user database.User
+	if err := s.db.First(&user, column+" = ?", recipient).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecipientNotFound
+		}
+		return nil, fmt.Errorf("failed to find recipient: %w", err)
+	}
+
+	return &user, nil
+}
+
+// notify sends a share notification; delivery failures never fail the share itself
+func (s *Service) notify(ctx context.Context, userID uint, notificationType string, share *DirectShare) {
+	if s.notifier == nil {
+		return
+	}
+
+	s.notifier.Notify(ctx, userID, &ShareNotification{
+		Type:         notificationType,
+		ShareID:      share.ID,
+		ResourceType: share.ResourceType,
+		ResourceID:   share.ResourceID,
+		Permission:   share.Permission,
+		FromUserID:   share.OwnerID,
+		Message:      share.Message,
+		CreatedAt:    time.Now(),
+	})
+}
@@ -0,0 +1,166 @@
+package sharing
+
+import (
+	"context"
+
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+// recordingNotifier captures notifications sent by the service
+type recordingNotifier struct {
+	sent map[uint][]string
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, userID uint, notification *ShareNotification) error {
+	n.sent[userID] = append(n.sent[userID], notification.Type)
+	return nil
+}
+
+// createUser creates a user whose email is the username at example.com
+func (suite *SharingServiceTestSuite) createUser(username string) *database.User {
+	return suite.factory.User(func(u *database.User) {
+		u.Username = username
+		u.Email = username + "@example.com"
+	})
+}
+
+func (suite *SharingServiceTestSuite) TestShareWithUser() {
+	owner := suite.createUser("owner")
+	recipient := suite.createUser("recipient")
+	bookmark := suite.factory.Bookmark(owner.ID, func(b *database.Bookmark) {
+		b.URL = "https://example.com"
+		b.Title = "Example"
+	})
+
+	notifier := &recordingNotifier{sent: map[uint][]string{}}
+	suite.service.SetNotifier(notifier)
+	defer suite.service.SetNotifier(nil)
+
+	// When: Sharing a bookmark by username
+	share, err := suite.service.ShareWithUser(context.Background(), owner.ID, &DirectShareRequest{
+		ResourceType: ResourceTypeBookmark,
+		ResourceID:   bookmark.ID,
+		Recipient:    "recipient",
+		Permission:   PermissionView,
+		Message:      "Worth a read",
+	})
+
+	// Then: The recipient sees it in their listing and is notified
+	suite.Require().NoError(err)
+	suite.Equal(recipient.ID, share.RecipientID)
+	suite.Equal([]string{NotificationShareReceived}, notifier.sent[recipient.ID])
+
+	items, err := suite.service.GetSharedWithMe(context.Background(), recipient.ID, "")
+	suite.Require().NoError(err)
+	suite.Require().Len(items, 1)
+	suite.Equal("Example", items[0].Title)
+	suite.Equal("https://example.com", items[0].URL)
+	suite.Equal("owner", items[0].OwnerUsername)
+
+	// And: Sharing the same bookmark again is rejected
+	_, err = suite.service.ShareWithUser(context.Background(), owner.ID, &DirectShareRequest{
+		ResourceType: ResourceTypeBookmark,
+		ResourceID:   bookmark.ID,
+		Recipient:    "recipient@example.com",
+		Permission:   PermissionEdit,
+	})
+	suite.ErrorIs(err, ErrDirectShareExists)
+}
+
+func (suite *SharingServiceTestSuite) TestShareWithUser_Errors() {
+	owner := suite.createUser("owner")
+	other := suite.createUser("other")
+	collection := suite.factory.Collection(owner.ID)
+
+	request := func(recipient string) *DirectShareRequest {
+		return &DirectShareRequest{
+			ResourceType: ResourceTypeCollection,
+			ResourceID:   collection.ID,
+			Recipient:    recipient,
+			Permission:   PermissionView,
+		}
+	}
+
+	_, err := suite.service.ShareWithUser(context.Background(), other.ID, request("owner"))
+	suite.ErrorIs(err, ErrUnauthorized)
+
+	_, err = suite.service.ShareWithUser(context.Background(), owner.ID, request("nobody"))
+	suite.ErrorIs(err, ErrRecipientNotFound)
+
+	_, err = suite.service.ShareWithUser(context.Background(), owner.ID, request("owner@example.com"))
+	suite.ErrorIs(err, ErrCannotShareWithSelf)
+}
+
+func (suite *SharingServiceTestSuite) TestRevokeDirectShare() {
+	owner := suite.createUser("owner")
+	recipient := suite.createUser("recipient")
+	stranger := suite.createUser("stranger")
+	collection := suite.factory.Collection(owner.ID)
+
+	notifier := &recordingNotifier{sent: map[uint][]string{}}
+	suite.service.SetNotifier(notifier)
+	defer suite.service.SetNotifier(nil)
+
+	share, err := suite.service.ShareWithUser(context.Background(), owner.ID, &DirectShareRequest{
+		ResourceType: ResourceTypeCollection,
+		ResourceID:   collection.ID,
+		Recipient:    "recipient",
+		Permission:   PermissionEdit,
+	})
+	suite.Require().NoError(err)
+
+	// Only the owner or recipient may remove the share
+	suite.ErrorIs(suite.service.RevokeDirectShare(context.Background(), stranger.ID, share.ID), ErrUnauthorized)
+
+	// When: The owner revokes access
+	suite.NoError(suite.service.RevokeDirectShare(context.Background(), owner.ID, share.ID))
+
+	// Then: The recipient is notified and loses access
+	suite.Equal([]string{NotificationShareReceived, NotificationShareRevoked}, notifier.sent[recipient.ID])
+	allowed, err := suite.service.CanAccess(context.Background(), recipient.ID, ResourceTypeCollection, collection.ID, PermissionView)
+	suite.NoError(err)
+	suite.False(allowed)
+}
+
+func (suite *SharingServiceTestSuite) TestCanAccess() {
+	owner := suite.createUser("owner")
+	recipient := suite.createUser("recipient")
+	collaborator := suite.createUser("collaborator")
+
+	bookmark := suite.factory.Bookmark(owner.ID)
+	collection := suite.factory.Collection(owner.ID)
+	suite.factory.AddToCollection(collection, bookmark)
+
+	_, err := suite.service.ShareWithUser(context.Background(), owner.ID, &DirectShareRequest{
+		ResourceType: ResourceTypeCollection,
+		ResourceID:   collection.ID,
+		Recipient:    "recipient",
+		Permission:   PermissionView,
+	})
+	suite.Require().NoError(err)
+	suite.Require().NoError(suite.db.Create(&CollectionCollaborator{
+		CollectionID: collection.ID,
+		UserID:       collaborator.ID,
+		InviterID:    owner.ID,
+		Permission:   PermissionEdit,
+		Status:       "accepted",
+	}).Error)
+
+	cases := []struct {
+		userID   uint
+		required SharePermission
+		expected bool
+	}{
+		{owner.ID, PermissionAdmin, true},
+		{recipient.ID, PermissionView, true},
+		{recipient.ID, PermissionEdit, false},
+		{collaborator.ID, PermissionEdit, true},
+	}
+
+	for _, tc := range cases {
+		// Bookmarks inherit access from the collections that contain them
+		allowed, err := suite.service.CanAccess(context.Background(), tc.userID, ResourceTypeBookmark, bookmark.ID, tc.required)
+		suite.NoError(err)
+		suite.Equal(tc.expected, allowed, "user %d permission %s", tc.userID, tc.required)
+	}
+}
@@ -0,0 +1,130 @@
+package sharing
+
+import (
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"bookmark-sync-service/backend/pkg/middleware"
+	"bookmark-sync-service/backend/pkg/utils"
+)
+
+// embedCacheControl lets browsers and CDNs cache embeds briefly while still
+// revalidating through the ETag
+const embedCacheControl = "public, max-age=300, stale-while-revalidate=60"
+
+var embedTemplate = template.Must(template.New("embed").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+{{if .FeedURL}}<link rel="alternate" type="application/rss+xml" title="{{.Title}}" href="{{.FeedURL}}">
+{{end}}<style>
+body{font-family:-apple-system,BlinkMacSystemFont,"Segoe UI",sans-serif;margin:0;padding:12px;color:#1f2937}
+h1{font-size:16px;margin:0 0 4px}
+p{font-size:13px;color:#6b7280;margin:0 0 8px}
+ul{list-style:none;margin:0;padding:0}
+li{display:flex;align-items:center;padding:4px 0;font-size:14px}
+img{width:16px;height:16px;margin-right:8px}
+a{color:inherit;text-decoration:none}
+a:hover{text-decoration:underline}
+.warning{color:#b91c1c;font-size:12px;margin-left:8px}
+</style>
+</head>
+<body>
+<h1><a href="{{.ShareURL}}" target="_blank" rel="noopener">{{.Title}}</a></h1>
+{{if .Description}}<p>{{.Description}}</p>{{end}}
+<ul>
+{{range .Items}}<li>{{if .Favicon}}<img src="{{.Favicon}}" alt="" loading="lazy">{{end}}<a href="{{.URL}}" target="_blank" rel="noopener">{{if .Title}}{{.Title}}{{else}}{{.URL}}{{end}}</a>{{if .Warning}}<span class="warning">{{.Warning}}</span>{{end}}</li>
+{{end}}</ul>
+</body>
+</html>
+`))
+
+// RegisterEmbedRoutes registers the public embed routes
+func (h *Handler) RegisterEmbedRoutes(router *gin.RouterGroup) {
+	router.GET("/collections/:token", h.GetEmbed)
+	router.OPTIONS("/collections/:token", h.GetEmbed)
+}
+
+// GetEmbed renders a shared collection for embedding on third party sites
+// @Summary Get embeddable collection
+// @Description Render a shared collection as HTML (default) or JSON for embedding
+// @Tags sharing
+// @Produce html,json
+// @Param token path string true "Share token"
+// @Param format query string false "Response format" Enums(html, json)
+// @Success 200 {object} EmbedCollection
+// @Failure 403 {object} utils.ErrorResponse "Embedding disabled"
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 410 {object} utils.ErrorResponse "Share expired"
+// @Router /embed/collections/{token} [get]
+func (h *Handler) GetEmbed(c *gin.Context) {
+	rendered, cache, err := h.service.RenderShare(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		switch err {
+		case ErrShareNotFound, ErrCollectionNotFound:
+			utils.ErrorResponse(c, http.StatusNotFound, "share_not_found", "share not found", nil)
+		case ErrShareExpired:
+			utils.ErrorResponse(c, http.StatusGone, "share_expired", "share has expired", nil)
+		case ErrShareInactive:
+			utils.ErrorResponse(c, http.StatusGone, "share_inactive", "share is inactive", nil)
+		case ErrEmbedDisabled:
+			utils.ErrorResponse(c, http.StatusForbidden, "embed_disabled", "embedding is disabled for this share", nil)
+		default:
+			respondError(c, err, "failed to get embed")
+		}
+		return
+	}
+
+	share := &rendered.Share
+	setEmbedHeaders(c, share)
+	c.Header("X-Cache", cache)
+
+	if c.Request.Method == http.MethodOptions {
+		c.AbortWithStatus(http.StatusNoContent)
+		return
+	}
+
+	asJSON := c.Query("format") == "json" ||
+		(c.Query("format") == "" && strings.Contains(c.GetHeader("Accept"), "application/json"))
+
+	contentType, body, etag := "text/html; charset=utf-8", rendered.HTML, rendered.HTMLETag
+	if asJSON {
+		contentType, body, etag = "application/json; charset=utf-8", rendered.JSON, rendered.JSONETag
+	}
+	c.Header("ETag", etag)
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	if err := h.service.RecordActivity(c.Request.Context(), share.ID, nil, ActivityTypeEmbed,
+		c.ClientIP(), c.GetHeader("User-Agent"), map[string]interface{}{"referer": c.GetHeader("Referer")}); err != nil {
+		// Tracking failures must not break the embed
+		h.service.logger.Warn("Failed to record embed activity", zap.Uint("share_id", share.ID), zap.Error(err))
+	}
+
+	c.Data(http.StatusOK, contentType, body)
+}
+
+// setEmbedHeaders applies the per-share CORS, framing and caching policy
+func setEmbedHeaders(c *gin.Context, share *CollectionShare) {
+	c.Header("Cache-Control", embedCacheControl)
+	c.Header("Vary", "Origin, Accept")
+
+	// Override the API-wide CORS headers with the share's own allow list
+	c.Writer.Header().Del("Access-Control-Allow-Credentials")
+	if origin := c.GetHeader("Origin"); origin != "" && share.AllowsEmbedOrigin(origin) {
+		c.Header("Access-Control-Allow-Origin", origin)
+		c.Header("Access-Control-Allow-Methods", "GET, OPTIONS")
+	} else {
+		c.Writer.Header().Del("Access-Control-Allow-Origin")
+	}
+
+	middleware.SetFrameAncestors(c, share.FrameAncestors())
+}
@@ -0,0 +1,106 @@
+package sharing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollectionShareAllowsEmbedOrigin(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed string
+		origin  string
+		want    bool
+	}{
+		{name: "no origins configured", allowed: "", origin: "https://a.com", want: false},
+		{name: "exact match", allowed: "https://a.com, https://b.com", origin: "https://b.com", want: true},
+		{name: "wildcard", allowed: "*", origin: "https://any.com", want: true},
+		{name: "not listed", allowed: "https://a.com", origin: "https://evil.com", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			share := &CollectionShare{EmbedAllowedOrigins: tt.allowed}
+			assert.Equal(t, tt.want, share.AllowsEmbedOrigin(tt.origin))
+		})
+	}
+}
+
+func TestShareRequestEmbedOrigins(t *testing.T) {
+	valid := []string{"https://blog.example.com", "https://*.example.com", "'self'", "*"}
+	assert.NoError(t, (&CreateShareRequest{CollectionID: 1, ShareType: ShareTypePublic, Permission: PermissionView,
+		EmbedAllowedOrigins: valid, EmbedFrameAncestors: valid}).Validate())
+	assert.NoError(t, (&UpdateShareRequest{EmbedFrameAncestors: &valid}).Validate())
+
+	for _, origin := range []string{
+		"https://a.com; script-src *",
+		"https://a.com;script-src",
+		"https://a.com/path",
+		"'unsafe-inline'",
+		"blog.example.com",
+	} {
+		invalid := []string{"https://blog.example.com", origin}
+		assert.Equal(t, ErrInvalidEmbedOrigin, (&CreateShareRequest{CollectionID: 1, ShareType: ShareTypePublic, Permission: PermissionView,
+			EmbedFrameAncestors: invalid}).Validate(), origin)
+		assert.Equal(t, ErrInvalidEmbedOrigin, (&UpdateShareRequest{EmbedAllowedOrigins: &invalid}).Validate(), origin)
+		assert.Equal(t, ErrInvalidEmbedOrigin, (&UpdateShareRequest{EmbedFrameAncestors: &invalid}).Validate(), origin)
+	}
+}
+
+func TestSetEmbedHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("blocks framing by default", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/embed/collections/token", nil)
+		c.Request.Header.Set("Origin", "https://evil.com")
+
+		setEmbedHeaders(c, &CollectionShare{})
+
+		assert.Equal(t, "frame-ancestors 'none'", w.Header().Get("Content-Security-Policy"))
+		assert.Equal(t, "DENY", w.Header().Get("X-Frame-Options"))
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("applies configured origins", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/embed/collections/token", nil)
+		c.Request.Header.Set("Origin", "https://blog.example.com")
+
+		setEmbedHeaders(c, &CollectionShare{
+			EmbedAllowedOrigins: "https://blog.example.com",
+			EmbedFrameAncestors: "https://blog.example.com,https://docs.example.com",
+		})
+
+		assert.Equal(t, "frame-ancestors https://blog.example.com https://docs.example.com", w.Header().Get("Content-Security-Policy"))
+		assert.Empty(t, w.Header().Get("X-Frame-Options"))
+		assert.Equal(t, "https://blog.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, embedCacheControl, w.Header().Get("Cache-Control"))
+	})
+}
+
+func TestCollectionShareToResponseEmbedURL(t *testing.T) {
+	share := &CollectionShare{ShareToken: "abc", EmbedEnabled: true, EmbedFrameAncestors: "https://a.com"}
+
+	response := share.ToResponse("http://localhost:3000")
+
+	assert.Equal(t, "http://localhost:3000/embed/collections/abc", response.EmbedURL)
+	assert.Equal(t, []string{"https://a.com"}, response.EmbedFrameAncestors)
+}
+
+func TestCollectionShareToResponseFeedURL(t *testing.T) {
+	public := &CollectionShare{ShareToken: "abc", ShareType: ShareTypePublic}
+	assert.Equal(t, "http://localhost:3000/feeds/collections/abc", public.ToResponse("http://localhost:3000").FeedURL)
+
+	protected := &CollectionShare{ShareToken: "abc", ShareType: ShareTypePublic, Password: "secret"}
+	assert.Empty(t, protected.ToResponse("http://localhost:3000").FeedURL)
+
+	private := &CollectionShare{ShareToken: "abc", ShareType: ShareTypePrivate}
+	assert.Empty(t, private.ToResponse("http://localhost:3000").FeedURL)
+}
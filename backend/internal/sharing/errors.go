@@ -1,6 +1,11 @@
 package sharing
 
-import "errors"
+import (
+	"errors"
+	"net/http"
+
+	"bookmark-sync-service/backend/pkg/apperrors"
+)
 
 // Sharing service errors
 var (
@@ -20,4 +25,75 @@ var (
 	ErrCannotForkOwnCollection = errors.New("cannot fork own collection")
 	ErrForkNotAllowed          = errors.New("fork not allowed for this collection")
 	ErrInsufficientPermission  = errors.New("insufficient permission")
+	ErrEmbedDisabled           = errors.New("embedding is disabled for this share")
+	ErrFeedUnavailable         = errors.New("feeds are only available for public shares")
+	ErrInvalidResourceType     = errors.New("invalid resource type")
+	ErrResourceNotFound        = errors.New("resource not found")
+	ErrRecipientNotFound       = errors.New("recipient not found")
+	ErrCannotShareWithSelf     = errors.New("cannot share with yourself")
+	ErrDirectShareExists       = errors.New("resource already shared with this user")
+	ErrSubscriptionsDisabled   = errors.New("email subscriptions are not enabled for this share")
+	ErrSubscriberNotFound      = errors.New("subscriber not found")
+	ErrConfirmationExpired     = errors.New("confirmation link has expired")
+	ErrInvalidQRCodeOptions    = errors.New("QR code size must be 64-1024 and level one of L, M, Q, H")
+	ErrInvalidPreviewMode      = errors.New("preview mode must be one of full, blur, crop, none")
+	ErrInvalidEmbedOrigin      = errors.New("embed origins must be bare origins like https://example.com, 'self' or *")
+	ErrPreviewUnavailable      = errors.New("no preview image for this share")
+	ErrWebhookNotFound         = errors.New("webhook not found")
+	ErrInvalidWebhookEvent     = errors.New("collection webhooks support collection.bookmark_added, collection.bookmark_removed, collection.collaborator_joined and share.viewed")
+	ErrWebhooksUnavailable     = errors.New("collection webhooks are not available")
+	ErrCommentNotFound         = errors.New("comment not found")
+	ErrEmptyComment            = errors.New("comment must not be empty")
+	ErrShareAPIDisabled        = errors.New("API access is disabled for this share")
+	ErrShareRateLimited        = errors.New("share API rate limit exceeded")
 )
+
+// Error codes answered for sharing errors. Handlers answering an error with
+// a more specific code for their endpoint keep doing so
+func init() {
+	for _, entry := range []struct {
+		err    error
+		code   apperrors.Code
+		status int
+	}{
+		{ErrShareNotFound, "share_not_found", http.StatusNotFound},
+		{ErrCollectionNotFound, "collection_not_found", http.StatusNotFound},
+		{ErrInvalidShareToken, "invalid_share_token", http.StatusBadRequest},
+		{ErrShareExpired, "share_expired", http.StatusGone},
+		{ErrShareInactive, "share_inactive", http.StatusGone},
+		{ErrInvalidPassword, "invalid_password", http.StatusUnauthorized},
+		{ErrUnauthorized, "unauthorized", http.StatusForbidden},
+		{ErrInvalidCollectionID, "invalid_collection_id", http.StatusBadRequest},
+		{ErrInvalidShareType, apperrors.CodeInvalidRequest, http.StatusBadRequest},
+		{ErrInvalidPermission, apperrors.CodeInvalidRequest, http.StatusBadRequest},
+		{ErrInvalidEmail, "invalid_email", http.StatusBadRequest},
+		{ErrInvalidName, apperrors.CodeInvalidRequest, http.StatusBadRequest},
+		{ErrCollaboratorExists, "collaborator_exists", http.StatusConflict},
+		{ErrCannotForkOwnCollection, "cannot_fork_own", http.StatusBadRequest},
+		{ErrForkNotAllowed, "fork_not_allowed", http.StatusForbidden},
+		{ErrInsufficientPermission, "insufficient_permission", http.StatusForbidden},
+		{ErrEmbedDisabled, "embed_disabled", http.StatusForbidden},
+		{ErrFeedUnavailable, "feed_unavailable", http.StatusNotFound},
+		{ErrInvalidResourceType, apperrors.CodeInvalidRequest, http.StatusBadRequest},
+		{ErrResourceNotFound, "resource_not_found", http.StatusNotFound},
+		{ErrRecipientNotFound, "recipient_not_found", http.StatusNotFound},
+		{ErrCannotShareWithSelf, apperrors.CodeInvalidRequest, http.StatusBadRequest},
+		{ErrDirectShareExists, "share_exists", http.StatusConflict},
+		{ErrSubscriptionsDisabled, "subscriptions_disabled", http.StatusNotFound},
+		{ErrSubscriberNotFound, "subscriber_not_found", http.StatusNotFound},
+		{ErrConfirmationExpired, "confirmation_expired", http.StatusGone},
+		{ErrInvalidQRCodeOptions, apperrors.CodeInvalidRequest, http.StatusBadRequest},
+		{ErrInvalidPreviewMode, apperrors.CodeInvalidRequest, http.StatusBadRequest},
+		{ErrInvalidEmbedOrigin, apperrors.CodeInvalidRequest, http.StatusBadRequest},
+		{ErrPreviewUnavailable, "preview_unavailable", http.StatusNotFound},
+		{ErrWebhookNotFound, "webhook_not_found", http.StatusNotFound},
+		{ErrInvalidWebhookEvent, "invalid_event", http.StatusBadRequest},
+		{ErrWebhooksUnavailable, "webhooks_unavailable", http.StatusServiceUnavailable},
+		{ErrCommentNotFound, "comment_not_found", http.StatusNotFound},
+		{ErrEmptyComment, apperrors.CodeInvalidRequest, http.StatusBadRequest},
+		{ErrShareAPIDisabled, "share_api_disabled", http.StatusForbidden},
+		{ErrShareRateLimited, "rate_limited", http.StatusTooManyRequests},
+	} {
+		apperrors.Register(entry.err, entry.code, entry.status)
+	}
+}
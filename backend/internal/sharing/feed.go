@@ -0,0 +1,172 @@
+package sharing
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/locale"
+	"bookmark-sync-service/backend/pkg/utils"
+)
+
+// feedMaxItems caps the number of bookmarks published in a collection feed
+const feedMaxItems = 50
+
+// feedCacheControl lets feed readers poll without hitting the database each time
+const feedCacheControl = "public, max-age=900"
+
+// RSS is an RSS 2.0 document
+type RSS struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	Channel RSSChannel `xml:"channel"`
+}
+
+// RSSChannel describes a shared collection in an RSS feed
+type RSSChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	SelfLink      RSSLink   `xml:"atom:link"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []RSSItem `xml:"item"`
+}
+
+// RSSLink is the atom:link pointing a feed at its own URL
+type RSSLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+// RSSItem is a bookmark in an RSS feed
+type RSSItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	Description string `xml:"description,omitempty"`
+	GUID        string `xml:"guid"`
+	PubDate     string `xml:"pubDate"`
+}
+
+// GetCollectionFeed renders the newest bookmarks of a public share as RSS
+func (s *Service) GetCollectionFeed(ctx context.Context, token string) ([]byte, error) {
+	share, err := s.GetShareByToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if !share.IsPublic() {
+		return nil, ErrFeedUnavailable
+	}
+
+	var collection database.Collection
+	if err := s.db.WithContext(ctx).First(&collection, share.CollectionID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrCollectionNotFound
+		}
+		return nil, fmt.Errorf("failed to find collection: %w", err)
+	}
+
+	var bookmarks []database.Bookmark
+	if err := s.db.WithContext(ctx).
+		Joins("JOIN bookmark_collections ON bookmarks.id = bookmark_collections.bookmark_id").
+		Where("bookmark_collections.collection_id = ?", collection.ID).
+		Order("bookmarks.created_at DESC").
+		Limit(feedMaxItems).
+		Find(&bookmarks).Error; err != nil {
+		return nil, fmt.Errorf("failed to get collection bookmarks: %w", err)
+	}
+
+	title := share.Title
+	if title == "" {
+		title = collection.Name
+	}
+	description := share.Description
+	if description == "" {
+		description = collection.Description
+	}
+
+	shareURL := s.baseURL + "/shared/" + share.ShareToken
+	channel := RSSChannel{
+		Title:       title,
+		Link:        shareURL,
+		Description: description,
+		SelfLink: RSSLink{
+			Href: s.baseURL + "/feeds/collections/" + share.ShareToken,
+			Rel:  "self",
+			Type: "application/rss+xml",
+		},
+		Items: make([]RSSItem, 0, len(bookmarks)),
+	}
+	// Dates are written in the collection owner's timezone
+	settings := locale.ForUser(ctx, s.db, collection.UserID)
+	if len(bookmarks) > 0 {
+		channel.LastBuildDate = settings.FormatRFC1123Z(bookmarks[0].CreatedAt)
+	}
+
+	for _, bookmark := range bookmarks {
+		itemTitle := bookmark.Title
+		if itemTitle == "" {
+			itemTitle = bookmark.URL
+		}
+		channel.Items = append(channel.Items, RSSItem{
+			Title:       itemTitle,
+			Link:        bookmark.URL,
+			Description: bookmark.Description,
+			GUID:        fmt.Sprintf("%s#%d", shareURL, bookmark.ID),
+			PubDate:     settings.FormatRFC1123Z(bookmark.CreatedAt),
+		})
+	}
+
+	var body bytes.Buffer
+	body.WriteString(xml.Header)
+	encoder := xml.NewEncoder(&body)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(RSS{Version: "2.0", Atom: "http://www.w3.org/2005/Atom", Channel: channel}); err != nil {
+		return nil, fmt.Errorf("failed to encode feed: %w", err)
+	}
+
+	return body.Bytes(), nil
+}
+
+// RegisterFeedRoutes registers the public RSS feed routes
+func (h *Handler) RegisterFeedRoutes(router *gin.RouterGroup) {
+	router.GET("/collections/:token", h.GetFeed)
+}
+
+// GetFeed serves the RSS feed of a public shared collection
+// @Summary Get collection RSS feed
+// @Description Render the newest bookmarks of a public, password-free share as RSS 2.0
+// @Tags sharing
+// @Produce xml
+// @Param token path string true "Share token"
+// @Success 200 {string} string "RSS document"
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 410 {object} utils.ErrorResponse "Share expired"
+// @Router /feeds/collections/{token} [get]
+func (h *Handler) GetFeed(c *gin.Context) {
+	feed, err := h.service.GetCollectionFeed(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		switch err {
+		// Non-public shares answer like missing ones so feeds do not reveal them
+		case ErrShareNotFound, ErrCollectionNotFound, ErrFeedUnavailable:
+			utils.ErrorResponse(c, http.StatusNotFound, "share_not_found", "share not found", nil)
+		case ErrShareExpired:
+			utils.ErrorResponse(c, http.StatusGone, "share_expired", "share has expired", nil)
+		case ErrShareInactive:
+			utils.ErrorResponse(c, http.StatusGone, "share_inactive", "share is inactive", nil)
+		default:
+			respondError(c, err, "failed to get feed")
+		}
+		return
+	}
+
+	c.Header("Cache-Control", feedCacheControl)
+	c.Data(http.StatusOK, "application/rss+xml; charset=utf-8", feed)
+}
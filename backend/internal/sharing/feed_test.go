@@ -0,0 +1,54 @@
+package sharing
+
+import (
+	"context"
+	"time"
+
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+func (suite *SharingServiceTestSuite) TestGetCollectionFeed() {
+	owner := suite.createUser("owner")
+	suite.Require().NoError(suite.db.Model(owner).Update("preferences", `{"timezone":"UTC-5"}`).Error)
+	collection := suite.factory.Collection(owner.ID, func(c *database.Collection) {
+		c.Name = "Reading List"
+		c.Description = "Things to read"
+	})
+	bookmark := suite.factory.Bookmark(owner.ID, func(b *database.Bookmark) {
+		b.URL = "https://example.com/post"
+		b.Title = "A <great> post"
+		b.CreatedAt = time.Date(2026, 3, 2, 3, 0, 0, 0, time.UTC)
+	})
+	suite.factory.AddToCollection(collection, bookmark)
+
+	share := &CollectionShare{
+		CollectionID: collection.ID,
+		UserID:       owner.ID,
+		ShareType:    ShareTypePublic,
+		Permission:   PermissionView,
+		ShareToken:   "feed-token",
+		IsActive:     true,
+	}
+	suite.Require().NoError(suite.db.Create(share).Error)
+
+	// When: Rendering the feed of a public share
+	feed, err := suite.service.GetCollectionFeed(context.Background(), "feed-token")
+
+	// Then: The channel links back to the share and escapes bookmark titles
+	suite.Require().NoError(err)
+	body := string(feed)
+	suite.Contains(body, `<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom">`)
+	suite.Contains(body, "<title>Reading List</title>")
+	suite.Contains(body, "<link>http://localhost:3000/shared/feed-token</link>")
+	suite.Contains(body, `<atom:link href="http://localhost:3000/feeds/collections/feed-token" rel="self" type="application/rss+xml">`)
+	suite.Contains(body, "<title>A &lt;great&gt; post</title>")
+
+	// And: Dates are in the owner's timezone
+	suite.Contains(body, "<pubDate>Sun, 01 Mar 2026 22:00:00 -0500</pubDate>")
+	suite.Contains(body, "<lastBuildDate>Sun, 01 Mar 2026 22:00:00 -0500</lastBuildDate>")
+
+	// And: Password protected shares have no feed
+	suite.Require().NoError(suite.db.Model(share).Update("password", "secret").Error)
+	_, err = suite.service.GetCollectionFeed(context.Background(), "feed-token")
+	suite.Equal(ErrFeedUnavailable, err)
+}
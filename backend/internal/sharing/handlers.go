@@ -1,11 +1,15 @@
 package sharing
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 
+	"bookmark-sync-service/backend/internal/hooks"
+	"bookmark-sync-service/backend/pkg/apperrors"
 	"bookmark-sync-service/backend/pkg/middleware"
 	"bookmark-sync-service/backend/pkg/utils"
 )
@@ -56,15 +60,19 @@ func (h *Handler) CreateShare(c *gin.Context) {
 
 	share, err := h.service.CreateShare(c.Request.Context(), uint(userID), &request)
 	if err != nil {
-		switch err {
-		case ErrCollectionNotFound:
+		switch {
+		case err == ErrCollectionNotFound:
 			utils.ErrorResponse(c, http.StatusNotFound, "collection_not_found", "collection not found", nil)
-		case ErrUnauthorized:
+		case err == ErrUnauthorized:
 			utils.ErrorResponse(c, http.StatusForbidden, "unauthorized", "unauthorized access", nil)
-		case ErrInvalidCollectionID, ErrInvalidShareType, ErrInvalidPermission:
+		case err == ErrInvalidCollectionID, err == ErrInvalidShareType, err == ErrInvalidPermission, err == ErrInvalidPreviewMode, err == ErrInvalidEmbedOrigin:
 			utils.ErrorResponse(c, http.StatusBadRequest, "invalid_request", "invalid request parameters", map[string]interface{}{"error": err.Error()})
+		case errors.Is(err, hooks.ErrRejected):
+			utils.ErrorResponse(c, http.StatusUnprocessableEntity, "hook_rejected", err.Error(), nil)
+		case errors.Is(err, hooks.ErrFailed):
+			utils.ErrorResponse(c, http.StatusServiceUnavailable, "hook_unavailable", "share could not be checked, try again later", nil)
 		default:
-			utils.ErrorResponse(c, http.StatusInternalServerError, "internal_error", "failed to create share", map[string]interface{}{"error": err.Error()})
+			respondError(c, err, "failed to create share")
 		}
 		return
 	}
@@ -83,6 +91,7 @@ func (h *Handler) CreateShare(c *gin.Context) {
 // @Produce json
 // @Param token path string true "Share token"
 // @Param password query string false "Password for protected shares"
+// @Param fields query string false "Comma-separated fields to return, dots for nested ones, e.g. id,share_url"
 // @Success 200 {object} ShareResponse
 // @Failure 400 {object} utils.ErrorResponse
 // @Failure 404 {object} utils.ErrorResponse
@@ -106,7 +115,7 @@ func (h *Handler) GetShare(c *gin.Context) {
 		case ErrShareInactive:
 			utils.ErrorResponse(c, http.StatusGone, "share_inactive", "share is inactive", nil)
 		default:
-			utils.ErrorResponse(c, http.StatusInternalServerError, "internal_error", "failed to get share", map[string]interface{}{"error": err.Error()})
+			respondError(c, err, "failed to get share")
 		}
 		return
 	}
@@ -133,15 +142,11 @@ func (h *Handler) GetShare(c *gin.Context) {
 	if err := h.service.RecordActivity(c.Request.Context(), share.ID, userIDPtr, "view",
 		c.ClientIP(), c.GetHeader("User-Agent"), nil); err != nil {
 		// Log error but don't fail the request
-		// TODO: Use proper logging
+		h.service.logger.Warn("Failed to record share view", zap.Uint("share_id", share.ID), zap.Error(err))
 	}
 
 	response := share.ToResponse("http://localhost:3000") // TODO: Get base URL from config
-	c.JSON(http.StatusOK, utils.APIResponse{
-		Success: true,
-		Message: "share retrieved successfully",
-		Data:    response,
-	})
+	utils.SparseResponse(c, response, "share retrieved successfully", "")
 }
 
 // UpdateShare updates an existing share
@@ -191,19 +196,15 @@ func (h *Handler) UpdateShare(c *gin.Context) {
 			utils.ErrorResponse(c, http.StatusNotFound, "share_not_found", "share not found", nil)
 		case ErrUnauthorized:
 			utils.ErrorResponse(c, http.StatusForbidden, "unauthorized", "unauthorized access", nil)
-		case ErrInvalidShareType, ErrInvalidPermission:
+		case ErrInvalidShareType, ErrInvalidPermission, ErrInvalidPreviewMode, ErrInvalidEmbedOrigin:
 			utils.ErrorResponse(c, http.StatusBadRequest, "invalid_request", "invalid request parameters", map[string]interface{}{"error": err.Error()})
 		default:
-			utils.ErrorResponse(c, http.StatusInternalServerError, "internal_error", "failed to update share", map[string]interface{}{"error": err.Error()})
+			respondError(c, err, "failed to update share")
 		}
 		return
 	}
 
-	c.JSON(http.StatusOK, utils.APIResponse{
-		Success: true,
-		Message: "share updated successfully",
-		Data:    share,
-	})
+	utils.SparseResponse(c, share, "share updated successfully", "")
 }
 
 // DeleteShare deletes a share
@@ -244,7 +245,7 @@ func (h *Handler) DeleteShare(c *gin.Context) {
 		case ErrUnauthorized:
 			utils.ErrorResponse(c, http.StatusForbidden, "unauthorized", "unauthorized access", nil)
 		default:
-			utils.ErrorResponse(c, http.StatusInternalServerError, "internal_error", "failed to delete share", map[string]interface{}{"error": err.Error()})
+			respondError(c, err, "failed to delete share")
 		}
 		return
 	}
@@ -260,6 +261,7 @@ func (h *Handler) DeleteShare(c *gin.Context) {
 // @Description Retrieve all shares created by the authenticated user
 // @Tags sharing
 // @Produce json
+// @Param fields query string false "Comma-separated fields to return, dots for nested ones, e.g. id,share_url"
 // @Success 200 {array} ShareResponse
 // @Failure 401 {object} utils.ErrorResponse
 // @Failure 500 {object} utils.ErrorResponse
@@ -279,7 +281,7 @@ func (h *Handler) GetUserShares(c *gin.Context) {
 
 	shares, err := h.service.GetUserShares(c.Request.Context(), uint(userID))
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "internal_error", "failed to get user shares", map[string]interface{}{"error": err.Error()})
+		respondError(c, err, "failed to get user shares")
 		return
 	}
 
@@ -289,11 +291,7 @@ func (h *Handler) GetUserShares(c *gin.Context) {
 		responses[i] = *share.ToResponse("http://localhost:3000") // TODO: Get base URL from config
 	}
 
-	c.JSON(http.StatusOK, utils.APIResponse{
-		Success: true,
-		Message: "user shares retrieved successfully",
-		Data:    responses,
-	})
+	utils.SparseResponse(c, responses, "user shares retrieved successfully", "")
 }
 
 // GetCollectionShares retrieves all shares for a specific collection
@@ -302,6 +300,7 @@ func (h *Handler) GetUserShares(c *gin.Context) {
 // @Tags sharing
 // @Produce json
 // @Param id path int true "Collection ID"
+// @Param fields query string false "Comma-separated fields to return, dots for nested ones, e.g. id,share_url"
 // @Success 200 {array} ShareResponse
 // @Failure 400 {object} utils.ErrorResponse
 // @Failure 401 {object} utils.ErrorResponse
@@ -336,7 +335,7 @@ func (h *Handler) GetCollectionShares(c *gin.Context) {
 		case ErrUnauthorized:
 			utils.ErrorResponse(c, http.StatusForbidden, "unauthorized", "unauthorized access", nil)
 		default:
-			utils.ErrorResponse(c, http.StatusInternalServerError, "internal_error", "failed to get collection shares", map[string]interface{}{"error": err.Error()})
+			respondError(c, err, "failed to get collection shares")
 		}
 		return
 	}
@@ -347,11 +346,7 @@ func (h *Handler) GetCollectionShares(c *gin.Context) {
 		responses[i] = *share.ToResponse("http://localhost:3000") // TODO: Get base URL from config
 	}
 
-	c.JSON(http.StatusOK, utils.APIResponse{
-		Success: true,
-		Message: "collection shares retrieved successfully",
-		Data:    responses,
-	})
+	utils.SparseResponse(c, responses, "collection shares retrieved successfully", "")
 }
 
 // ForkCollection creates a fork of a shared collection
@@ -406,7 +401,7 @@ func (h *Handler) ForkCollection(c *gin.Context) {
 		case ErrInvalidName:
 			utils.ErrorResponse(c, http.StatusBadRequest, "invalid_request", "invalid request parameters", map[string]interface{}{"error": err.Error()})
 		default:
-			utils.ErrorResponse(c, http.StatusInternalServerError, "internal_error", "failed to fork collection", map[string]interface{}{"error": err.Error()})
+			respondError(c, err, "failed to fork collection")
 		}
 		return
 	}
@@ -471,7 +466,7 @@ func (h *Handler) AddCollaborator(c *gin.Context) {
 		case ErrInvalidEmail, ErrInvalidPermission:
 			utils.ErrorResponse(c, http.StatusBadRequest, "invalid_request", "invalid request parameters", map[string]interface{}{"error": err.Error()})
 		default:
-			utils.ErrorResponse(c, http.StatusInternalServerError, "internal_error", "failed to add collaborator", map[string]interface{}{"error": err.Error()})
+			respondError(c, err, "failed to add collaborator")
 		}
 		return
 	}
@@ -519,7 +514,7 @@ func (h *Handler) AcceptCollaboration(c *gin.Context) {
 		case ErrUnauthorized:
 			utils.ErrorResponse(c, http.StatusForbidden, "unauthorized", "unauthorized access", nil)
 		default:
-			utils.ErrorResponse(c, http.StatusInternalServerError, "internal_error", "failed to accept collaboration", map[string]interface{}{"error": err.Error()})
+			respondError(c, err, "failed to accept collaboration")
 		}
 		return
 	}
@@ -570,7 +565,7 @@ func (h *Handler) GetShareActivity(c *gin.Context) {
 		case ErrUnauthorized:
 			utils.ErrorResponse(c, http.StatusForbidden, "unauthorized", "unauthorized access", nil)
 		default:
-			utils.ErrorResponse(c, http.StatusInternalServerError, "internal_error", "failed to get share activity", map[string]interface{}{"error": err.Error()})
+			respondError(c, err, "failed to get share activity")
 		}
 		return
 	}
@@ -581,3 +576,13 @@ func (h *Handler) GetShareActivity(c *gin.Context) {
 		Data:    activities,
 	})
 }
+
+// respondError answers an error with its registered code and status, or as
+// an internal error with message when it has none
+func respondError(c *gin.Context, err error, message string) {
+	if coded, ok := apperrors.Lookup(err); ok {
+		utils.ErrorResponse(c, coded.Status, string(coded.Code), coded.Message, nil)
+		return
+	}
+	utils.ErrorResponse(c, http.StatusInternalServerError, string(apperrors.CodeInternal), message, map[string]interface{}{"error": err.Error()})
+}
@@ -0,0 +1,31 @@
+package sharing
+
+import (
+	"context"
+
+	"bookmark-sync-service/backend/internal/hooks"
+)
+
+// Hooks calls the configured hooks of a hook point, which may reject the
+// operation or change its request
+type Hooks interface {
+	Run(ctx context.Context, point hooks.Point, userID uint, payload interface{}) error
+}
+
+// SetHooks makes the service call the before_share_create hooks
+func (s *Service) SetHooks(h Hooks) {
+	s.hooks = h
+}
+
+// runCreateHooks lets the hooks check and enrich a share request. The
+// request is validated again, as a hook may have changed any field; its
+// password is never shown to them
+func (s *Service) runCreateHooks(ctx context.Context, userID uint, request *CreateShareRequest) error {
+	if s.hooks == nil {
+		return nil
+	}
+	if err := s.hooks.Run(ctx, hooks.BeforeShareCreate, userID, request); err != nil {
+		return err
+	}
+	return request.Validate()
+}
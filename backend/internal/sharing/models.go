@@ -1,9 +1,12 @@
 package sharing
 
 import (
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/internal/config"
 )
 
 // ShareType represents the type of sharing
@@ -26,30 +29,77 @@ const (
 	PermissionAdmin   SharePermission = "admin"
 )
 
+// Share activity types
+const (
+	ActivityTypeView    = "view"
+	ActivityTypeEmbed   = "embed"
+	ActivityTypeComment = "comment"
+)
+
+// Resource types that can be shared directly with users
+const (
+	ResourceTypeBookmark   = "bookmark"
+	ResourceTypeCollection = "collection"
+)
+
+// Notification types sent to share recipients
+const (
+	NotificationShareReceived = "share.received"
+	NotificationShareUpdated  = "share.updated"
+	NotificationShareRevoked  = "share.revoked"
+	NotificationCommentPosted = "collection.comment_posted"
+)
+
+// permissionRank orders permissions so that higher levels include lower ones
+var permissionRank = map[SharePermission]int{
+	PermissionView:    1,
+	PermissionComment: 2,
+	PermissionEdit:    3,
+	PermissionAdmin:   4,
+}
+
+// Allows reports whether the permission grants at least the required level
+func (p SharePermission) Allows(required SharePermission) bool {
+	rank, ok := permissionRank[p]
+	return ok && rank >= permissionRank[required]
+}
+
 // CollectionShare represents a shared collection
 type CollectionShare struct {
-	ID           uint            `json:"id" gorm:"primaryKey"`
-	CollectionID uint            `json:"collection_id" gorm:"not null;index"`
-	UserID       uint            `json:"user_id" gorm:"not null;index"`
-	ShareType    ShareType       `json:"share_type" gorm:"not null;default:'private'"`
-	Permission   SharePermission `json:"permission" gorm:"not null;default:'view'"`
-	ShareToken   string          `json:"share_token" gorm:"unique;not null;index"`
-	Title        string          `json:"title" gorm:"size:255"`
-	Description  string          `json:"description" gorm:"type:text"`
-	Password     string          `json:"-" gorm:"size:255"` // Optional password protection
-	ExpiresAt    *time.Time      `json:"expires_at"`
-	ViewCount    int64           `json:"view_count" gorm:"default:0"`
-	IsActive     bool            `json:"is_active" gorm:"default:true"`
-	CreatedAt    time.Time       `json:"created_at"`
-	UpdatedAt    time.Time       `json:"updated_at"`
-	DeletedAt    gorm.DeletedAt  `json:"-" gorm:"index"`
+	ID                  uint            `json:"id" gorm:"primaryKey"`
+	CollectionID        uint            `json:"collection_id" gorm:"not null;index"`
+	UserID              uint            `json:"user_id" gorm:"not null;index"`
+	ShareType           ShareType       `json:"share_type" gorm:"not null;default:'private'"`
+	Permission          SharePermission `json:"permission" gorm:"not null;default:'view'"`
+	ShareToken          string          `json:"share_token" gorm:"unique;not null;index"`
+	Title               string          `json:"title" gorm:"size:255"`
+	Description         string          `json:"description" gorm:"type:text"`
+	Password            string          `json:"-" gorm:"size:255"` // Optional password protection
+	ExpiresAt           *time.Time      `json:"expires_at"`
+	ViewCount           int64           `json:"view_count" gorm:"default:0"`
+	IsActive            bool            `json:"is_active" gorm:"default:true"`
+	EmbedEnabled        bool            `json:"embed_enabled" gorm:"default:false"`
+	EmbedAllowedOrigins string          `json:"embed_allowed_origins" gorm:"type:text"` // comma separated, "*" for any
+	EmbedFrameAncestors string          `json:"embed_frame_ancestors" gorm:"type:text"` // comma separated, empty blocks framing
+	// SubscriptionsEnabled lets visitors of a public share subscribe by email
+	SubscriptionsEnabled bool `json:"subscriptions_enabled" gorm:"default:false"`
+	// APIEnabled serves the shared bookmarks as JSON to anyone with the
+	// token, within APIRateLimit requests an hour (0 uses the default)
+	APIEnabled   bool `json:"api_enabled" gorm:"default:false"`
+	APIRateLimit int  `json:"api_rate_limit" gorm:"default:0"`
+	// PreviewMode is how the screenshot in the share's link preview image
+	// is shown: full, blur, crop or none. Empty means blur
+	PreviewMode string         `json:"preview_mode" gorm:"size:10"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
 // CollectionCollaborator represents a collaborator on a shared collection
 type CollectionCollaborator struct {
 	ID           uint            `json:"id" gorm:"primaryKey"`
-	CollectionID uint            `json:"collection_id" gorm:"not null;index"`
-	UserID       uint            `json:"user_id" gorm:"not null;index"`
+	CollectionID uint            `json:"collection_id" gorm:"not null;index;uniqueIndex:idx_collection_collaborator"`
+	UserID       uint            `json:"user_id" gorm:"not null;index;uniqueIndex:idx_collection_collaborator"`
 	InviterID    uint            `json:"inviter_id" gorm:"not null"`
 	Permission   SharePermission `json:"permission" gorm:"not null;default:'view'"`
 	Status       string          `json:"status" gorm:"not null;default:'pending'"` // pending, accepted, declined
@@ -60,6 +110,19 @@ type CollectionCollaborator struct {
 	DeletedAt    gorm.DeletedAt  `json:"-" gorm:"index"`
 }
 
+// DirectShare grants a specific user access to a bookmark or collection
+type DirectShare struct {
+	ID           uint            `json:"id" gorm:"primaryKey"`
+	OwnerID      uint            `json:"owner_id" gorm:"not null;index"`
+	RecipientID  uint            `json:"recipient_id" gorm:"not null;index;uniqueIndex:idx_direct_share_recipient"`
+	ResourceType string          `json:"resource_type" gorm:"not null;size:20;uniqueIndex:idx_direct_share_recipient"`
+	ResourceID   uint            `json:"resource_id" gorm:"not null;uniqueIndex:idx_direct_share_recipient"`
+	Permission   SharePermission `json:"permission" gorm:"not null;default:'view'"`
+	Message      string          `json:"message" gorm:"size:500"`
+	CreatedAt    time.Time       `json:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at"`
+}
+
 // CollectionFork represents a forked collection
 type CollectionFork struct {
 	ID                uint           `json:"id" gorm:"primaryKey"`
@@ -79,7 +142,7 @@ type ShareActivity struct {
 	ID           uint           `json:"id" gorm:"primaryKey"`
 	ShareID      uint           `json:"share_id" gorm:"not null;index"`
 	UserID       *uint          `json:"user_id" gorm:"index"`          // Nullable for anonymous views
-	ActivityType string         `json:"activity_type" gorm:"not null"` // view, comment, edit, fork
+	ActivityType string         `json:"activity_type" gorm:"not null"` // view, embed, comment, edit, fork
 	IPAddress    string         `json:"ip_address" gorm:"size:45"`
 	UserAgent    string         `json:"user_agent" gorm:"size:500"`
 	Metadata     string         `json:"metadata" gorm:"type:json"`
@@ -87,44 +150,181 @@ type ShareActivity struct {
 	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
+// CollectionComment is a post in a collection's discussion. A comment
+// without a thread starts one; replies point at the thread's first comment
+type CollectionComment struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	CollectionID uint       `json:"collection_id" gorm:"not null;index"`
+	UserID       uint       `json:"user_id" gorm:"not null;index"`
+	ThreadID     *uint      `json:"thread_id,omitempty" gorm:"index"`
+	Content      string     `json:"content" gorm:"type:text;not null"`
+	Hidden       bool       `json:"hidden" gorm:"default:false"` // hidden by a moderator
+	HiddenBy     *uint      `json:"hidden_by,omitempty"`
+	EditedAt     *time.Time `json:"edited_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	// Username is the author's, filled in for listings
+	Username  string         `json:"username" gorm:"-"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// CommentThread is the first comment of a thread and its replies, oldest first
+type CommentThread struct {
+	CollectionComment
+	Replies []CollectionComment `json:"replies"`
+}
+
+// CommentThreadList is a page of a collection's threads, most recently
+// active first
+type CommentThreadList struct {
+	Threads []CommentThread `json:"threads"`
+	Total   int64           `json:"total"`
+	Page    int             `json:"page"`
+	Limit   int             `json:"limit"`
+	// CanPost and CanModerate tell clients which controls to show
+	CanPost     bool `json:"can_post"`
+	CanModerate bool `json:"can_moderate"`
+}
+
+// CollectionCommentRequest posts a comment, in reply to a thread when
+// ThreadID is set
+type CollectionCommentRequest struct {
+	Content  string `json:"content" binding:"required,max=5000"`
+	ThreadID *uint  `json:"thread_id"`
+
+	// IPAddress and UserAgent are recorded in the share activity log
+	IPAddress string `json:"-"`
+	UserAgent string `json:"-"`
+}
+
+// UpdateCollectionCommentRequest edits a comment
+type UpdateCollectionCommentRequest struct {
+	Content string `json:"content" binding:"required,max=5000"`
+}
+
+// ModerateCollectionCommentRequest hides or restores a comment
+type ModerateCollectionCommentRequest struct {
+	Hidden bool `json:"hidden"`
+}
+
+// Email subscriber statuses
+const (
+	SubscriberPending      = "pending"
+	SubscriberConfirmed    = "confirmed"
+	SubscriberUnsubscribed = "unsubscribed"
+)
+
+// EmailSubscriber is a visitor, not necessarily a user, who receives emails
+// about bookmarks added to a public share. Addresses only receive updates
+// after confirming through the link sent to them
+type EmailSubscriber struct {
+	ID      uint   `json:"id" gorm:"primaryKey"`
+	ShareID uint   `json:"share_id" gorm:"not null;uniqueIndex:idx_email_subscriber"`
+	Email   string `json:"email" gorm:"size:255;not null;uniqueIndex:idx_email_subscriber"`
+	Status  string `json:"status" gorm:"size:20;not null;default:'pending';index"`
+	// Token authenticates the confirmation and unsubscribe links
+	Token              string     `json:"-" gorm:"size:64;not null;uniqueIndex"`
+	ConfirmationSentAt time.Time  `json:"-"`
+	ConfirmedAt        *time.Time `json:"confirmed_at"`
+	LastNotifiedAt     *time.Time `json:"last_notified_at"` // bookmarks added after it are in the next email
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+}
+
+// ShareActivityDaily counts a share's activity per day, activity type and
+// client class. It outlives the raw ShareActivity rows it is built from
+type ShareActivityDaily struct {
+	ID           uint      `json:"-" gorm:"primaryKey"`
+	ShareID      uint      `json:"share_id" gorm:"not null;uniqueIndex:idx_share_activity_daily"`
+	Day          time.Time `json:"day" gorm:"not null;uniqueIndex:idx_share_activity_daily"`
+	ActivityType string    `json:"activity_type" gorm:"not null;uniqueIndex:idx_share_activity_daily"`
+	Client       string    `json:"client" gorm:"size:20;not null;uniqueIndex:idx_share_activity_daily"` // desktop, mobile, bot, unknown
+	Count        int64     `json:"count" gorm:"not null;default:0"`
+}
+
 // CreateShareRequest represents a request to create a share
 type CreateShareRequest struct {
-	CollectionID uint            `json:"collection_id" binding:"required"`
-	ShareType    ShareType       `json:"share_type" binding:"required,oneof=public private shared collaborate"`
-	Permission   SharePermission `json:"permission" binding:"required,oneof=view comment edit admin"`
-	Title        string          `json:"title" binding:"max=255"`
-	Description  string          `json:"description" binding:"max=1000"`
-	Password     string          `json:"password" binding:"max=255"`
-	ExpiresAt    *time.Time      `json:"expires_at"`
+	CollectionID         uint            `json:"collection_id" binding:"required"`
+	ShareType            ShareType       `json:"share_type" binding:"required,oneof=public private shared collaborate"`
+	Permission           SharePermission `json:"permission" binding:"required,oneof=view comment edit admin"`
+	Title                string          `json:"title" binding:"max=255"`
+	Description          string          `json:"description" binding:"max=1000"`
+	Password             string          `json:"password" binding:"max=255"`
+	ExpiresAt            *time.Time      `json:"expires_at"`
+	EmbedEnabled         bool            `json:"embed_enabled"`
+	EmbedAllowedOrigins  []string        `json:"embed_allowed_origins"`
+	EmbedFrameAncestors  []string        `json:"embed_frame_ancestors"`
+	SubscriptionsEnabled bool            `json:"subscriptions_enabled"`
+	APIEnabled           bool            `json:"api_enabled"`
+	APIRateLimit         int             `json:"api_rate_limit" binding:"min=0,max=10000"`
+	PreviewMode          string          `json:"preview_mode" binding:"omitempty,oneof=full blur crop none"`
 }
 
 // UpdateShareRequest represents a request to update a share
 type UpdateShareRequest struct {
-	ShareType   *ShareType       `json:"share_type,omitempty" binding:"omitempty,oneof=public private shared collaborate"`
-	Permission  *SharePermission `json:"permission,omitempty" binding:"omitempty,oneof=view comment edit admin"`
-	Title       *string          `json:"title,omitempty" binding:"omitempty,max=255"`
-	Description *string          `json:"description,omitempty" binding:"omitempty,max=1000"`
-	Password    *string          `json:"password,omitempty" binding:"omitempty,max=255"`
-	ExpiresAt   *time.Time       `json:"expires_at,omitempty"`
-	IsActive    *bool            `json:"is_active,omitempty"`
+	ShareType            *ShareType       `json:"share_type,omitempty" binding:"omitempty,oneof=public private shared collaborate"`
+	Permission           *SharePermission `json:"permission,omitempty" binding:"omitempty,oneof=view comment edit admin"`
+	Title                *string          `json:"title,omitempty" binding:"omitempty,max=255"`
+	Description          *string          `json:"description,omitempty" binding:"omitempty,max=1000"`
+	Password             *string          `json:"password,omitempty" binding:"omitempty,max=255"`
+	ExpiresAt            *time.Time       `json:"expires_at,omitempty"`
+	IsActive             *bool            `json:"is_active,omitempty"`
+	EmbedEnabled         *bool            `json:"embed_enabled,omitempty"`
+	EmbedAllowedOrigins  *[]string        `json:"embed_allowed_origins,omitempty"`
+	EmbedFrameAncestors  *[]string        `json:"embed_frame_ancestors,omitempty"`
+	SubscriptionsEnabled *bool            `json:"subscriptions_enabled,omitempty"`
+	APIEnabled           *bool            `json:"api_enabled,omitempty"`
+	APIRateLimit         *int             `json:"api_rate_limit,omitempty" binding:"omitempty,min=0,max=10000"`
+	PreviewMode          *string          `json:"preview_mode,omitempty" binding:"omitempty,oneof=full blur crop none"`
 }
 
 // ShareResponse represents a share response
 type ShareResponse struct {
-	ID           uint            `json:"id"`
-	CollectionID uint            `json:"collection_id"`
-	ShareType    ShareType       `json:"share_type"`
-	Permission   SharePermission `json:"permission"`
-	ShareToken   string          `json:"share_token"`
-	ShareURL     string          `json:"share_url"`
-	Title        string          `json:"title"`
-	Description  string          `json:"description"`
-	HasPassword  bool            `json:"has_password"`
-	ExpiresAt    *time.Time      `json:"expires_at"`
-	ViewCount    int64           `json:"view_count"`
-	IsActive     bool            `json:"is_active"`
-	CreatedAt    time.Time       `json:"created_at"`
-	UpdatedAt    time.Time       `json:"updated_at"`
+	ID                   uint            `json:"id"`
+	CollectionID         uint            `json:"collection_id"`
+	ShareType            ShareType       `json:"share_type"`
+	Permission           SharePermission `json:"permission"`
+	ShareToken           string          `json:"share_token"`
+	ShareURL             string          `json:"share_url"`
+	Title                string          `json:"title"`
+	Description          string          `json:"description"`
+	HasPassword          bool            `json:"has_password"`
+	ExpiresAt            *time.Time      `json:"expires_at"`
+	ViewCount            int64           `json:"view_count"`
+	IsActive             bool            `json:"is_active"`
+	EmbedEnabled         bool            `json:"embed_enabled"`
+	EmbedURL             string          `json:"embed_url,omitempty"`
+	FeedURL              string          `json:"feed_url,omitempty"`
+	QRCodeURL            string          `json:"qrcode_url"`
+	EmbedAllowedOrigins  []string        `json:"embed_allowed_origins,omitempty"`
+	EmbedFrameAncestors  []string        `json:"embed_frame_ancestors,omitempty"`
+	SubscriptionsEnabled bool            `json:"subscriptions_enabled"`
+	SubscribeURL         string          `json:"subscribe_url,omitempty"`
+	APIEnabled           bool            `json:"api_enabled"`
+	APIURL               string          `json:"api_url,omitempty"`
+	APIRateLimit         int             `json:"api_rate_limit,omitempty"`
+	PreviewMode          string          `json:"preview_mode"`
+	PreviewURL           string          `json:"preview_url,omitempty"`
+	CreatedAt            time.Time       `json:"created_at"`
+	UpdatedAt            time.Time       `json:"updated_at"`
+}
+
+// EmbedCollection represents the public, cache-friendly payload of an embedded collection
+type EmbedCollection struct {
+	Title       string      `json:"title"`
+	Description string      `json:"description"`
+	ShareURL    string      `json:"share_url"`
+	FeedURL     string      `json:"feed_url,omitempty"`
+	Items       []EmbedItem `json:"items"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+}
+
+// EmbedItem represents a bookmark rendered in an embed
+type EmbedItem struct {
+	Title   string `json:"title"`
+	URL     string `json:"url"`
+	Favicon string `json:"favicon,omitempty"`
+	Warning string `json:"warning,omitempty"` // set when the link is flagged as unsafe
 }
 
 // CollaboratorRequest represents a request to add a collaborator
@@ -134,6 +334,49 @@ type CollaboratorRequest struct {
 	Message    string          `json:"message" binding:"max=500"`
 }
 
+// DirectShareRequest represents a request to share a bookmark or collection with a user
+type DirectShareRequest struct {
+	ResourceType string          `json:"resource_type" binding:"required,oneof=bookmark collection"`
+	ResourceID   uint            `json:"resource_id" binding:"required"`
+	Recipient    string          `json:"recipient" binding:"required"` // Email or username
+	Permission   SharePermission `json:"permission" binding:"required,oneof=view comment edit"`
+	Message      string          `json:"message" binding:"max=500"`
+}
+
+// UpdateDirectShareRequest represents a request to change a direct share's permission
+type UpdateDirectShareRequest struct {
+	Permission SharePermission `json:"permission" binding:"required,oneof=view comment edit"`
+}
+
+// SharedItem is an entry in the recipient's "Shared with me" listing
+type SharedItem struct {
+	ShareID       uint            `json:"share_id"`
+	ResourceType  string          `json:"resource_type"`
+	ResourceID    uint            `json:"resource_id"`
+	Title         string          `json:"title"`
+	URL           string          `json:"url,omitempty"`
+	Permission    SharePermission `json:"permission"`
+	OwnerID       uint            `json:"owner_id"`
+	OwnerUsername string          `json:"owner_username"`
+	Message       string          `json:"message,omitempty"`
+	SharedAt      time.Time       `json:"shared_at"`
+}
+
+// ShareNotification is delivered to a user when something is shared with
+// them, or someone posts in a discussion they take part in
+type ShareNotification struct {
+	Type         string          `json:"type"`
+	ShareID      uint            `json:"share_id"`
+	ResourceType string          `json:"resource_type"`
+	ResourceID   uint            `json:"resource_id"`
+	Permission   SharePermission `json:"permission,omitempty"`
+	FromUserID   uint            `json:"from_user_id"`
+	Message      string          `json:"message,omitempty"`
+	CommentID    uint            `json:"comment_id,omitempty"`
+	ThreadID     uint            `json:"thread_id,omitempty"`
+	CreatedAt    time.Time       `json:"created_at"`
+}
+
 // ForkRequest represents a request to fork a collection
 type ForkRequest struct {
 	Name              string `json:"name" binding:"required,max=255"`
@@ -157,6 +400,14 @@ func (r *CreateShareRequest) Validate() error {
 		return ErrInvalidPermission
 	}
 
+	if r.PreviewMode != "" && !validPreviewMode(r.PreviewMode) {
+		return ErrInvalidPreviewMode
+	}
+
+	if !validEmbedOrigins(r.EmbedAllowedOrigins) || !validEmbedOrigins(r.EmbedFrameAncestors) {
+		return ErrInvalidEmbedOrigin
+	}
+
 	return nil
 }
 
@@ -170,6 +421,15 @@ func (r *UpdateShareRequest) Validate() error {
 		return ErrInvalidPermission
 	}
 
+	if r.PreviewMode != nil && !validPreviewMode(*r.PreviewMode) {
+		return ErrInvalidPreviewMode
+	}
+
+	if (r.EmbedAllowedOrigins != nil && !validEmbedOrigins(*r.EmbedAllowedOrigins)) ||
+		(r.EmbedFrameAncestors != nil && !validEmbedOrigins(*r.EmbedFrameAncestors)) {
+		return ErrInvalidEmbedOrigin
+	}
+
 	return nil
 }
 
@@ -186,6 +446,29 @@ func (r *CollaboratorRequest) Validate() error {
 	return nil
 }
 
+// Validate validates the DirectShareRequest
+func (r *DirectShareRequest) Validate() error {
+	if r.ResourceType != ResourceTypeBookmark && r.ResourceType != ResourceTypeCollection {
+		return ErrInvalidResourceType
+	}
+
+	if strings.TrimSpace(r.Recipient) == "" {
+		return ErrRecipientNotFound
+	}
+
+	if !r.Permission.directShareable() {
+		return ErrInvalidPermission
+	}
+
+	return nil
+}
+
+// directShareable reports whether a permission can be granted by a direct
+// share; comment lets recipients take part in a collection's discussion
+func (p SharePermission) directShareable() bool {
+	return p == PermissionView || p == PermissionComment || p == PermissionEdit
+}
+
 // Validate validates the ForkRequest
 func (r *ForkRequest) Validate() error {
 	if r.Name == "" {
@@ -197,20 +480,113 @@ func (r *ForkRequest) Validate() error {
 
 // ToResponse converts CollectionShare to ShareResponse
 func (cs *CollectionShare) ToResponse(baseURL string) *ShareResponse {
-	return &ShareResponse{
-		ID:           cs.ID,
-		CollectionID: cs.CollectionID,
-		ShareType:    cs.ShareType,
-		Permission:   cs.Permission,
-		ShareToken:   cs.ShareToken,
-		ShareURL:     baseURL + "/shared/" + cs.ShareToken,
-		Title:        cs.Title,
-		Description:  cs.Description,
-		HasPassword:  cs.Password != "",
-		ExpiresAt:    cs.ExpiresAt,
-		ViewCount:    cs.ViewCount,
-		IsActive:     cs.IsActive,
-		CreatedAt:    cs.CreatedAt,
-		UpdatedAt:    cs.UpdatedAt,
+	response := &ShareResponse{
+		ID:                   cs.ID,
+		CollectionID:         cs.CollectionID,
+		ShareType:            cs.ShareType,
+		Permission:           cs.Permission,
+		ShareToken:           cs.ShareToken,
+		ShareURL:             baseURL + "/shared/" + cs.ShareToken,
+		QRCodeURL:            baseURL + "/api/v1/shares/" + cs.ShareToken + "/qrcode.png",
+		Title:                cs.Title,
+		Description:          cs.Description,
+		HasPassword:          cs.Password != "",
+		ExpiresAt:            cs.ExpiresAt,
+		ViewCount:            cs.ViewCount,
+		IsActive:             cs.IsActive,
+		EmbedEnabled:         cs.EmbedEnabled,
+		EmbedAllowedOrigins:  cs.AllowedEmbedOrigins(),
+		EmbedFrameAncestors:  cs.FrameAncestors(),
+		SubscriptionsEnabled: cs.SubscriptionsEnabled,
+		APIEnabled:           cs.APIEnabled,
+		APIRateLimit:         cs.APIRateLimit,
+		PreviewMode:          cs.EffectivePreviewMode(),
+		CreatedAt:            cs.CreatedAt,
+		UpdatedAt:            cs.UpdatedAt,
+	}
+
+	if cs.EmbedEnabled {
+		response.EmbedURL = baseURL + "/embed/collections/" + cs.ShareToken
+	}
+	if cs.IsPublic() {
+		response.FeedURL = baseURL + "/feeds/collections/" + cs.ShareToken
+	}
+	if cs.AcceptsSubscriptions() {
+		response.SubscribeURL = baseURL + "/api/v1/shares/" + cs.ShareToken + "/subscribe"
+	}
+	if cs.ServesAPI() {
+		response.APIURL = baseURL + "/api/v1/shares/" + cs.ShareToken + "/bookmarks"
+	}
+	if cs.EffectivePreviewMode() != PreviewNone {
+		response.PreviewURL = baseURL + "/api/v1/shares/" + cs.ShareToken + "/preview.png"
+	}
+
+	return response
+}
+
+// IsPublic reports whether anyone may read the share without a password, which
+// makes it eligible for RSS feeds and the sitemap
+func (cs *CollectionShare) IsPublic() bool {
+	return cs.ShareType == ShareTypePublic && cs.Password == ""
+}
+
+// AcceptsSubscriptions reports whether visitors may subscribe to the share
+// by email
+func (cs *CollectionShare) AcceptsSubscriptions() bool {
+	return cs.SubscriptionsEnabled && cs.IsPublic()
+}
+
+// ServesAPI reports whether the share's data API may be used. Like embeds,
+// it can't prompt for a password
+func (cs *CollectionShare) ServesAPI() bool {
+	return cs.APIEnabled && cs.Password == ""
+}
+
+// AllowedEmbedOrigins returns the origins allowed to fetch the embed
+func (cs *CollectionShare) AllowedEmbedOrigins() []string {
+	return splitList(cs.EmbedAllowedOrigins)
+}
+
+// FrameAncestors returns the origins allowed to frame the embed
+func (cs *CollectionShare) FrameAncestors() []string {
+	return splitList(cs.EmbedFrameAncestors)
+}
+
+// AllowsEmbedOrigin reports whether the given origin may fetch the embed
+func (cs *CollectionShare) AllowsEmbedOrigin(origin string) bool {
+	for _, allowed := range cs.AllowedEmbedOrigins() {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// validEmbedOrigins reports whether every entry of an embed origin list is
+// a bare origin, 'self' or *. The frame ancestors go into the embed's CSP
+// as they are, so anything else could smuggle in directives
+func validEmbedOrigins(values []string) bool {
+	for _, origin := range splitList(strings.Join(values, ",")) {
+		if origin == "*" || origin == "'self'" {
+			continue
+		}
+		if config.ValidateOrigin(origin) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func joinList(values []string) string {
+	return strings.Join(splitList(strings.Join(values, ",")), ",")
+}
+
+func splitList(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
 	}
+	return values
 }
@@ -0,0 +1,171 @@
+package sharing
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/utils"
+)
+
+// Link preview modes of a share's screenshot
+const (
+	PreviewFull = "full"
+	PreviewBlur = "blur"
+	PreviewCrop = "crop"
+	PreviewNone = "none"
+)
+
+// previewCacheTTL is how long the location of a rendered preview variant is
+// remembered
+const previewCacheTTL = 7 * 24 * time.Hour
+
+// previewCacheControl lets crawlers reuse a preview for a while; it changes
+// when the collection gets a newer screenshot or the owner changes the mode
+const previewCacheControl = "public, max-age=3600"
+
+func validPreviewMode(mode string) bool {
+	switch mode {
+	case PreviewFull, PreviewBlur, PreviewCrop, PreviewNone:
+		return true
+	}
+	return false
+}
+
+// EffectivePreviewMode returns how the share's link preview shows the
+// screenshot. Shares default to blurred previews, and password protected
+// ones never show the page in full
+func (cs *CollectionShare) EffectivePreviewMode() string {
+	mode := cs.PreviewMode
+	if mode == "" {
+		mode = PreviewBlur
+	}
+	if mode == PreviewFull && cs.Password != "" {
+		mode = PreviewBlur
+	}
+	return mode
+}
+
+// PreviewRenderer renders screenshots as link preview images through the
+// image pipeline, e.g. the screenshot service
+type PreviewRenderer interface {
+	RenderPreview(ctx context.Context, screenshotURL, mode string) ([]byte, error)
+}
+
+// SetPreviewImages configures rendering of share preview images and where
+// the rendered variants are cached. Without a renderer shares have no
+// preview image; without storage every request renders one
+func (s *Service) SetPreviewImages(renderer PreviewRenderer, uploader QRCodeUploader, index QRCodeIndex) {
+	s.previews = renderer
+	s.previewStorage = uploader
+	s.previewIndex = index
+}
+
+// SharePreview is a rendered preview image. When a cached variant is
+// available in storage, URL points at it and PNG is empty
+type SharePreview struct {
+	PNG []byte
+	URL string
+}
+
+// SharePreviewImage returns the link preview image of a share: the newest
+// screenshot in its collection, blurred or cropped as the share's owner
+// chose. Variants are cached per share token, mode and screenshot, so only
+// the processed image is ever uploaded
+func (s *Service) SharePreviewImage(ctx context.Context, token string) (*SharePreview, error) {
+	if s.previews == nil {
+		return nil, ErrPreviewUnavailable
+	}
+	share, err := s.GetShareByToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	mode := share.EffectivePreviewMode()
+	if mode == PreviewNone {
+		return nil, ErrPreviewUnavailable
+	}
+
+	var bookmarks []database.Bookmark
+	if err := s.db.WithContext(ctx).Select("bookmarks.id", "bookmarks.screenshot").
+		Joins("JOIN bookmark_collections ON bookmarks.id = bookmark_collections.bookmark_id").
+		Where("bookmark_collections.collection_id = ? AND bookmarks.screenshot <> ''", share.CollectionID).
+		Order("bookmarks.created_at DESC").
+		Limit(1).
+		Find(&bookmarks).Error; err != nil {
+		return nil, fmt.Errorf("failed to find screenshot: %w", err)
+	}
+	if len(bookmarks) == 0 {
+		return nil, ErrPreviewUnavailable
+	}
+	screenshotURL := bookmarks[0].Screenshot
+
+	sum := sha256.Sum256([]byte(screenshotURL))
+	key := fmt.Sprintf("previews/shares/%s-%s-%s.png", share.ShareToken, mode, hex.EncodeToString(sum[:8]))
+	if s.previewIndex != nil {
+		if url, err := s.previewIndex.GetString(ctx, "preview:"+key); err == nil && url != "" {
+			return &SharePreview{URL: url}, nil
+		}
+	}
+
+	png, err := s.previews.RenderPreview(ctx, screenshotURL, mode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render preview: %w", err)
+	}
+
+	// Caching is best effort, like for QR codes
+	if s.previewStorage != nil && s.previewIndex != nil {
+		if url, err := s.previewStorage.UploadFile(ctx, key, png, "image/png"); err == nil && url != "" {
+			_ = s.previewIndex.SetWithExpiration(ctx, "preview:"+key, url, previewCacheTTL)
+		}
+	}
+
+	return &SharePreview{PNG: png}, nil
+}
+
+// RegisterPreviewRoutes registers the public share preview image route
+func (h *Handler) RegisterPreviewRoutes(router *gin.RouterGroup) {
+	router.GET("/shares/:token/preview.png", h.GetSharePreview)
+}
+
+// GetSharePreview serves a share's link preview image, for og:image
+// @Summary Get share preview image
+// @Description Render the 1200x630 link preview image of a share from the newest screenshot in the collection, shown in full, blurred or cropped to the page header as set by the share's preview_mode
+// @Tags sharing
+// @Produce png
+// @Param token path string true "Share token"
+// @Success 200 {file} binary
+// @Success 302 "Redirect to the cached image"
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 410 {object} utils.ErrorResponse
+// @Router /api/v1/shares/{token}/preview.png [get]
+func (h *Handler) GetSharePreview(c *gin.Context) {
+	preview, err := h.service.SharePreviewImage(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		switch err {
+		case ErrShareNotFound:
+			utils.ErrorResponse(c, http.StatusNotFound, "share_not_found", "share not found", nil)
+		case ErrPreviewUnavailable:
+			utils.ErrorResponse(c, http.StatusNotFound, "preview_unavailable", err.Error(), nil)
+		case ErrShareExpired:
+			utils.ErrorResponse(c, http.StatusGone, "share_expired", "share has expired", nil)
+		case ErrShareInactive:
+			utils.ErrorResponse(c, http.StatusGone, "share_inactive", "share is inactive", nil)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, "internal_error", "failed to render preview", nil)
+		}
+		return
+	}
+
+	c.Header("Cache-Control", previewCacheControl)
+	if preview.URL != "" {
+		c.Redirect(http.StatusFound, preview.URL)
+		return
+	}
+	c.Data(http.StatusOK, "image/png", preview.PNG)
+}
@@ -0,0 +1,133 @@
+package sharing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+// previewRendererStub returns the mode and screenshot it was asked for
+// instead of an image
+type previewRendererStub struct {
+	calls int
+	err   error
+}
+
+func (r *previewRendererStub) RenderPreview(ctx context.Context, screenshotURL, mode string) ([]byte, error) {
+	r.calls++
+	if r.err != nil {
+		return nil, r.err
+	}
+	return []byte(mode + " " + screenshotURL), nil
+}
+
+func (suite *SharingServiceTestSuite) TestSharePreviewImage() {
+	owner := suite.createUser("owner")
+	collection := suite.factory.Collection(owner.ID)
+	older := suite.factory.Bookmark(owner.ID, func(b *database.Bookmark) { b.Screenshot = "https://cdn.example.com/older.png" })
+	newer := suite.factory.Bookmark(owner.ID, func(b *database.Bookmark) {
+		b.Screenshot = "https://cdn.example.com/newer.png"
+		b.CreatedAt = older.CreatedAt.Add(1)
+	})
+	suite.factory.AddToCollection(collection, older, newer)
+	share := suite.factory.Share(collection)
+	service := NewService(suite.db, "http://localhost:3000")
+
+	// Without a renderer there is no preview
+	_, err := service.SharePreviewImage(context.Background(), share.ShareToken)
+	suite.Equal(ErrPreviewUnavailable, err)
+
+	renderer := &previewRendererStub{}
+	store := &memoryQRCodeStore{uploads: map[string][]byte{}, index: map[string]string{}}
+	service.SetPreviewImages(renderer, store, store)
+
+	// When: Rendering the preview of a share without a preview mode
+	preview, err := service.SharePreviewImage(context.Background(), share.ShareToken)
+
+	// Then: The newest screenshot is blurred and the variant uploaded
+	suite.Require().NoError(err)
+	suite.Equal("blur https://cdn.example.com/newer.png", string(preview.PNG))
+	suite.Len(store.uploads, 1)
+	for key := range store.uploads {
+		suite.True(strings.HasPrefix(key, "previews/shares/"+share.ShareToken+"-blur-"))
+	}
+
+	// And: Later requests are served from storage
+	preview, err = service.SharePreviewImage(context.Background(), share.ShareToken)
+	suite.Require().NoError(err)
+	suite.Empty(preview.PNG)
+	suite.True(strings.HasPrefix(preview.URL, "https://cdn.example.com/previews/shares/"))
+	suite.Equal(1, renderer.calls)
+
+	// And: Changing the mode renders another variant
+	suite.Require().NoError(suite.db.Model(share).Update("preview_mode", PreviewCrop).Error)
+	preview, err = service.SharePreviewImage(context.Background(), share.ShareToken)
+	suite.Require().NoError(err)
+	suite.Equal("crop https://cdn.example.com/newer.png", string(preview.PNG))
+
+	// And: Password protected shares never show the page in full
+	suite.Require().NoError(suite.db.Model(share).Updates(map[string]interface{}{"preview_mode": PreviewFull, "password": "secret"}).Error)
+	preview, err = service.SharePreviewImage(context.Background(), share.ShareToken)
+	suite.Require().NoError(err)
+	suite.Empty(preview.PNG)
+	suite.Contains(preview.URL, "-blur-")
+
+	// And: Owners can turn the preview off
+	suite.Require().NoError(suite.db.Model(share).Update("preview_mode", PreviewNone).Error)
+	_, err = service.SharePreviewImage(context.Background(), share.ShareToken)
+	suite.Equal(ErrPreviewUnavailable, err)
+
+	// And: Collections without screenshots have no preview
+	empty := suite.factory.Share(suite.factory.Collection(owner.ID))
+	_, err = service.SharePreviewImage(context.Background(), empty.ShareToken)
+	suite.Equal(ErrPreviewUnavailable, err)
+
+	renderer.err = errors.New("screenshot not found")
+	suite.Require().NoError(suite.db.Model(share).Updates(map[string]interface{}{"preview_mode": PreviewFull, "password": ""}).Error)
+	_, err = service.SharePreviewImage(context.Background(), share.ShareToken)
+	suite.ErrorContains(err, "screenshot not found")
+}
+
+func (suite *SharingServiceTestSuite) TestSharePreviewMode_Validation() {
+	invalid := "pixelate"
+	suite.Equal(ErrInvalidPreviewMode, (&UpdateShareRequest{PreviewMode: &invalid}).Validate())
+	suite.Equal(ErrInvalidPreviewMode, (&CreateShareRequest{CollectionID: 1, ShareType: ShareTypePublic, Permission: PermissionView, PreviewMode: invalid}).Validate())
+
+	share := &CollectionShare{ShareToken: "token", IsActive: true}
+	response := share.ToResponse("https://app.example.com")
+	suite.Equal(PreviewBlur, response.PreviewMode)
+	suite.Equal("https://app.example.com/api/v1/shares/token/preview.png", response.PreviewURL)
+
+	share.PreviewMode = PreviewNone
+	suite.Empty(share.ToResponse("https://app.example.com").PreviewURL)
+}
+
+func (suite *SharingServiceTestSuite) TestGetSharePreview_Handler() {
+	owner := suite.createUser("owner")
+	collection := suite.factory.Collection(owner.ID)
+	bookmark := suite.factory.Bookmark(owner.ID, func(b *database.Bookmark) { b.Screenshot = "https://cdn.example.com/page.png" })
+	suite.factory.AddToCollection(collection, bookmark)
+	share := suite.factory.Share(collection)
+	service := NewService(suite.db, "http://localhost:3000")
+	service.SetPreviewImages(&previewRendererStub{}, nil, nil)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	NewHandler(service).RegisterPreviewRoutes(router.Group("/api/v1"))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/shares/"+share.ShareToken+"/preview.png", nil))
+	suite.Equal(http.StatusOK, w.Code)
+	suite.Equal("image/png", w.Header().Get("Content-Type"))
+	suite.Equal(previewCacheControl, w.Header().Get("Cache-Control"))
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/shares/missing/preview.png", nil))
+	suite.Equal(http.StatusNotFound, w.Code)
+}
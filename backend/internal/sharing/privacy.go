@@ -0,0 +1,147 @@
+package sharing
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/pkg/utils"
+)
+
+// Client classes share activity is aggregated by
+const (
+	ClientDesktop = "desktop"
+	ClientMobile  = "mobile"
+	ClientBot     = "bot"
+	ClientUnknown = "unknown"
+)
+
+// SetPrivacy configures how share activity is stored
+func (s *Service) SetPrivacy(cfg config.PrivacyConfig) {
+	s.privacy = cfg
+}
+
+// AnonymizeIP truncates an address to its /24 (IPv4) or /48 (IPv6) network.
+// Anything that is not an IP address is dropped
+func AnonymizeIP(address string) string {
+	ip := net.ParseIP(strings.TrimSpace(address))
+	if ip == nil {
+		return ""
+	}
+	if v4 := ip.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String()
+	}
+	return ip.Mask(net.CIDRMask(48, 128)).String()
+}
+
+// ClientClass reduces a user agent to the coarse class kept in aggregates
+func ClientClass(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	switch {
+	case ua == "":
+		return ClientUnknown
+	case strings.Contains(ua, "bot") || strings.Contains(ua, "crawler") || strings.Contains(ua, "spider"):
+		return ClientBot
+	case strings.Contains(ua, "mobile") || strings.Contains(ua, "android") || strings.Contains(ua, "iphone") || strings.Contains(ua, "ipad"):
+		return ClientMobile
+	default:
+		return ClientDesktop
+	}
+}
+
+// aggregateActivity adds one activity to the share's daily counts
+func (s *Service) aggregateActivity(ctx context.Context, shareID uint, activityType, userAgent string, at time.Time) error {
+	daily := &ShareActivityDaily{
+		ShareID:      shareID,
+		Day:          at.UTC().Truncate(24 * time.Hour),
+		ActivityType: activityType,
+		Client:       ClientClass(userAgent),
+		Count:        1,
+	}
+	return s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "share_id"}, {Name: "day"}, {Name: "activity_type"}, {Name: "client"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"count": gorm.Expr("count + 1")}),
+	}).Create(daily).Error
+}
+
+// PurgeActivity permanently deletes raw share activity recorded before the
+// cutoff. Daily aggregates are left alone. The retention policy purges it on
+// a schedule
+func (s *Service) PurgeActivity(ctx context.Context, before time.Time) (int64, error) {
+	result := s.db.WithContext(ctx).Unscoped().Where("created_at < ?", before).Delete(&ShareActivity{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to purge share activity: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// GetShareAnalytics returns the daily activity counts of a share owned by
+// the user, oldest first
+func (s *Service) GetShareAnalytics(ctx context.Context, userID uint, token string) ([]ShareActivityDaily, error) {
+	var share CollectionShare
+	if err := s.db.WithContext(ctx).Where("share_token = ?", token).First(&share).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrShareNotFound
+		}
+		return nil, fmt.Errorf("failed to find share: %w", err)
+	}
+
+	if share.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+
+	var daily []ShareActivityDaily
+	if err := s.db.WithContext(ctx).Where("share_id = ?", share.ID).
+		Order("day ASC, activity_type ASC, client ASC").Find(&daily).Error; err != nil {
+		return nil, fmt.Errorf("failed to get share analytics: %w", err)
+	}
+
+	return daily, nil
+}
+
+// RegisterAnalyticsRoutes registers the share owner's analytics route
+func (h *Handler) RegisterAnalyticsRoutes(router *gin.RouterGroup) {
+	router.GET("/shares/:token/analytics", h.GetShareAnalytics)
+}
+
+// GetShareAnalytics returns the daily activity counts of a share
+// @Summary Get share analytics
+// @Description Daily view and embed counts of a share by client class. Counts are kept after raw activity is purged by the retention setting
+// @Tags sharing
+// @Produce json
+// @Param token path string true "Share token"
+// @Success 200 {array} ShareActivityDaily
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/shares/{token}/analytics [get]
+func (h *Handler) GetShareAnalytics(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	daily, err := h.service.GetShareAnalytics(c.Request.Context(), userID, c.Param("token"))
+	if err != nil {
+		switch err {
+		case ErrShareNotFound:
+			utils.ErrorResponse(c, http.StatusNotFound, "share_not_found", "share not found", nil)
+		case ErrUnauthorized:
+			utils.ErrorResponse(c, http.StatusForbidden, "unauthorized", "unauthorized access", nil)
+		default:
+			respondError(c, err, "failed to get share analytics")
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, daily, "share analytics retrieved successfully")
+}
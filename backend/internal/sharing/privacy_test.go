@@ -0,0 +1,96 @@
+package sharing
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+func TestAnonymizeIP(t *testing.T) {
+	assert.Equal(t, "203.0.113.0", AnonymizeIP("203.0.113.42"))
+	assert.Equal(t, "2001:db8:abcd::", AnonymizeIP("2001:db8:abcd:12:34::1"))
+	assert.Equal(t, "192.0.2.0", AnonymizeIP("::ffff:192.0.2.7"))
+	assert.Empty(t, AnonymizeIP("not-an-ip"))
+}
+
+func TestClientClass(t *testing.T) {
+	assert.Equal(t, ClientDesktop, ClientClass("Mozilla/5.0 (Windows NT 10.0; Win64; x64)"))
+	assert.Equal(t, ClientMobile, ClientClass("Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X)"))
+	assert.Equal(t, ClientBot, ClientClass("Googlebot/2.1"))
+	assert.Equal(t, ClientUnknown, ClientClass(""))
+}
+
+func (suite *SharingServiceTestSuite) createActivityShare() (*database.User, *CollectionShare) {
+	owner := suite.createUser("owner")
+	collection := suite.factory.Collection(owner.ID, func(c *database.Collection) { c.Visibility = "public" })
+
+	share := &CollectionShare{
+		CollectionID: collection.ID,
+		UserID:       owner.ID,
+		ShareType:    ShareTypePublic,
+		Permission:   PermissionView,
+		ShareToken:   "analytics-token",
+		IsActive:     true,
+	}
+	suite.Require().NoError(suite.db.Create(share).Error)
+	return owner, share
+}
+
+func (suite *SharingServiceTestSuite) TestRecordActivityComplianceMode() {
+	ctx := context.Background()
+	owner, share := suite.createActivityShare()
+
+	suite.service.SetPrivacy(config.PrivacyConfig{ComplianceMode: true})
+	defer suite.service.SetPrivacy(config.PrivacyConfig{})
+
+	suite.Require().NoError(suite.service.RecordActivity(ctx, share.ID, nil, ActivityTypeView, "203.0.113.42", "Mozilla/5.0 (iPhone)", nil))
+	suite.Require().NoError(suite.service.RecordActivity(ctx, share.ID, nil, ActivityTypeView, "2001:db8:abcd:12::1", "Mozilla/5.0 (X11; Linux)", nil))
+	suite.Require().NoError(suite.service.RecordActivity(ctx, share.ID, nil, ActivityTypeView, "203.0.113.7", "Mozilla/5.0 (Android; Mobile)", nil))
+
+	var activities []ShareActivity
+	suite.Require().NoError(suite.db.Order("id").Find(&activities, "share_id = ?", share.ID).Error)
+	suite.Require().Len(activities, 3)
+	suite.Equal("203.0.113.0", activities[0].IPAddress)
+	suite.Equal("2001:db8:abcd::", activities[1].IPAddress)
+	for _, activity := range activities {
+		suite.Empty(activity.UserAgent)
+	}
+
+	daily, err := suite.service.GetShareAnalytics(ctx, owner.ID, share.ShareToken)
+	suite.Require().NoError(err)
+	suite.Require().Len(daily, 2)
+	suite.Equal(ClientDesktop, daily[0].Client)
+	suite.Equal(int64(1), daily[0].Count)
+	suite.Equal(ClientMobile, daily[1].Client)
+	suite.Equal(int64(2), daily[1].Count)
+
+	_, err = suite.service.GetShareAnalytics(ctx, owner.ID+1, share.ShareToken)
+	suite.Equal(ErrUnauthorized, err)
+}
+
+func (suite *SharingServiceTestSuite) TestPurgeActivityKeepsAggregates() {
+	ctx := context.Background()
+	owner, share := suite.createActivityShare()
+
+	suite.Require().NoError(suite.service.RecordActivity(ctx, share.ID, nil, ActivityTypeView, "198.51.100.1", "Mozilla/5.0", nil))
+	suite.Require().NoError(suite.service.RecordActivity(ctx, share.ID, nil, ActivityTypeEmbed, "198.51.100.2", "Mozilla/5.0", nil))
+	suite.Require().NoError(suite.db.Model(&ShareActivity{}).Where("activity_type = ?", ActivityTypeView).
+		Update("created_at", time.Now().AddDate(0, 0, -40)).Error)
+
+	purged, err := suite.service.PurgeActivity(ctx, time.Now().AddDate(0, 0, -30))
+	suite.Require().NoError(err)
+	suite.Equal(int64(1), purged)
+
+	var remaining int64
+	suite.db.Unscoped().Model(&ShareActivity{}).Where("share_id = ?", share.ID).Count(&remaining)
+	suite.Equal(int64(1), remaining)
+
+	daily, err := suite.service.GetShareAnalytics(ctx, owner.ID, share.ShareToken)
+	suite.Require().NoError(err)
+	suite.Len(daily, 2)
+}
@@ -0,0 +1,304 @@
+package sharing
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skip2/go-qrcode"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/utils"
+)
+
+// QR code bounds. Sizes are the PNG edge length in pixels
+const (
+	qrDefaultSize  = 256
+	qrMinSize      = 64
+	qrMaxSize      = 1024
+	qrDefaultLevel = "M"
+)
+
+// qrCacheTTL is how long the location of an uploaded share QR code is remembered
+const qrCacheTTL = 7 * 24 * time.Hour
+
+// qrCacheControl lets browsers and print pipelines reuse a QR code; the
+// encoded share URL never changes for a token
+const qrCacheControl = "public, max-age=86400"
+
+// qrLevels maps error-correction names to the encoder's recovery levels
+var qrLevels = map[string]qrcode.RecoveryLevel{
+	"L": qrcode.Low,
+	"M": qrcode.Medium,
+	"Q": qrcode.High,
+	"H": qrcode.Highest,
+}
+
+// QRCodeOptions controls how a QR code image is rendered
+type QRCodeOptions struct {
+	Size int `form:"size"`
+	// Level is the error-correction level: L, M, Q or H. Higher levels
+	// survive more damage, e.g. a logo printed over the code
+	Level string `form:"level"`
+	// NoBorder drops the quiet zone, for layouts that add their own margin
+	NoBorder bool `form:"no_border"`
+}
+
+// Normalize applies defaults and validates the options
+func (o *QRCodeOptions) Normalize() error {
+	if o.Size == 0 {
+		o.Size = qrDefaultSize
+	}
+	if o.Size < qrMinSize || o.Size > qrMaxSize {
+		return ErrInvalidQRCodeOptions
+	}
+	o.Level = strings.ToUpper(o.Level)
+	if o.Level == "" {
+		o.Level = qrDefaultLevel
+	}
+	if _, ok := qrLevels[o.Level]; !ok {
+		return ErrInvalidQRCodeOptions
+	}
+	return nil
+}
+
+// cacheKey names the stored image for these options
+func (o QRCodeOptions) cacheKey(prefix string) string {
+	return fmt.Sprintf("qrcodes/%s-%d-%s-%t.png", prefix, o.Size, o.Level, o.NoBorder)
+}
+
+// QRCode is a rendered QR code. When a cached copy is available in storage,
+// URL points at it and PNG may be empty
+type QRCode struct {
+	Content string `json:"content"`
+	PNG     []byte `json:"-"`
+	URL     string `json:"url,omitempty"`
+}
+
+// DataURI returns the image as a data: URI for inlining in HTML, such as
+// printable collection exports
+func (q *QRCode) DataURI() string {
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(q.PNG)
+}
+
+// QRCodeUploader stores generated QR codes, e.g. the object storage client
+type QRCodeUploader interface {
+	UploadFile(ctx context.Context, objectName string, data []byte, contentType string) (string, error)
+}
+
+// QRCodeIndex remembers where QR codes were uploaded, e.g. the Redis client
+type QRCodeIndex interface {
+	GetString(ctx context.Context, key string) (string, error)
+	SetWithExpiration(ctx context.Context, key string, value interface{}, expiration time.Duration) error
+}
+
+// SetQRCodeCache configures where share QR codes are cached. Without it
+// every request renders the image
+func (s *Service) SetQRCodeCache(uploader QRCodeUploader, index QRCodeIndex) {
+	s.qrUploader = uploader
+	s.qrIndex = index
+}
+
+// ShareQRCode renders a QR code for a share link. With cached set, a
+// previously uploaded copy is returned by URL instead of rendering again
+func (s *Service) ShareQRCode(ctx context.Context, token string, opts QRCodeOptions, cached bool) (*QRCode, error) {
+	if err := opts.Normalize(); err != nil {
+		return nil, err
+	}
+	share, err := s.GetShareByToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	content := s.baseURL + "/shared/" + share.ShareToken
+	key := opts.cacheKey("shares/" + share.ShareToken)
+	if cached && s.qrIndex != nil {
+		if url, err := s.qrIndex.GetString(ctx, "qrcode:"+key); err == nil && url != "" {
+			return &QRCode{Content: content, URL: url}, nil
+		}
+	}
+
+	png, err := renderQRCode(content, opts)
+	if err != nil {
+		return nil, err
+	}
+	code := &QRCode{Content: content, PNG: png}
+
+	// Caching is best effort; the rendered image is returned either way
+	if s.qrUploader != nil && s.qrIndex != nil {
+		if url, err := s.qrUploader.UploadFile(ctx, key, png, "image/png"); err == nil && url != "" {
+			_ = s.qrIndex.SetWithExpiration(ctx, "qrcode:"+key, url, qrCacheTTL)
+		}
+	}
+
+	return code, nil
+}
+
+// BookmarkQRCode renders a QR code for one of the user's bookmarks. These are
+// never cached in storage, since uploaded images are public and the bookmark
+// URL may not be
+func (s *Service) BookmarkQRCode(ctx context.Context, userID, bookmarkID uint, opts QRCodeOptions) (*QRCode, error) {
+	if err := opts.Normalize(); err != nil {
+		return nil, err
+	}
+
+	var bookmark database.Bookmark
+	if err := s.db.WithContext(ctx).Where("id = ? AND user_id = ?", bookmarkID, userID).First(&bookmark).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrResourceNotFound
+		}
+		return nil, fmt.Errorf("failed to find bookmark: %w", err)
+	}
+
+	png, err := renderQRCode(bookmark.URL, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &QRCode{Content: bookmark.URL, PNG: png}, nil
+}
+
+func renderQRCode(content string, opts QRCodeOptions) ([]byte, error) {
+	code, err := qrcode.New(content, qrLevels[opts.Level])
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode QR code: %w", err)
+	}
+	code.DisableBorder = opts.NoBorder
+
+	png, err := code.PNG(opts.Size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render QR code: %w", err)
+	}
+	return png, nil
+}
+
+// RegisterQRCodeRoutes registers the public share QR code route
+func (h *Handler) RegisterQRCodeRoutes(router *gin.RouterGroup) {
+	router.GET("/shares/:token/qrcode.png", h.GetShareQRCode)
+}
+
+// RegisterBookmarkQRCodeRoutes registers the authenticated bookmark QR code route
+func (h *Handler) RegisterBookmarkQRCodeRoutes(router *gin.RouterGroup) {
+	router.GET("/bookmarks/:id/qrcode.png", h.GetBookmarkQRCode)
+}
+
+// GetShareQRCode serves a QR code pointing at a share link
+// @Summary Get share QR code
+// @Description Render a QR code for a share link. Use format=data_uri to get a base64 data URI for embedding in printable exports
+// @Tags sharing
+// @Produce png
+// @Produce json
+// @Param token path string true "Share token"
+// @Param size query int false "Image size in pixels (64-1024)" default(256)
+// @Param level query string false "Error correction level: L, M, Q or H" default(M)
+// @Param no_border query bool false "Omit the quiet zone around the code"
+// @Param format query string false "png or data_uri" default(png)
+// @Success 200 {file} binary
+// @Success 302 "Redirect to the cached image"
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 410 {object} utils.ErrorResponse
+// @Router /api/v1/shares/{token}/qrcode.png [get]
+func (h *Handler) GetShareQRCode(c *gin.Context) {
+	var opts QRCodeOptions
+	if err := c.ShouldBindQuery(&opts); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "invalid_request", "invalid QR code options", nil)
+		return
+	}
+	dataURI := c.Query("format") == "data_uri"
+
+	code, err := h.service.ShareQRCode(c.Request.Context(), c.Param("token"), opts, !dataURI)
+	if err != nil {
+		switch err {
+		case ErrInvalidQRCodeOptions:
+			utils.ErrorResponse(c, http.StatusBadRequest, "invalid_request", err.Error(), nil)
+		case ErrShareNotFound:
+			utils.ErrorResponse(c, http.StatusNotFound, "share_not_found", "share not found", nil)
+		case ErrShareExpired:
+			utils.ErrorResponse(c, http.StatusGone, "share_expired", "share has expired", nil)
+		case ErrShareInactive:
+			utils.ErrorResponse(c, http.StatusGone, "share_inactive", "share is inactive", nil)
+		default:
+			respondError(c, err, "failed to generate QR code")
+		}
+		return
+	}
+
+	writeQRCode(c, code, dataURI)
+}
+
+// GetBookmarkQRCode serves a QR code for one of the user's bookmarks
+// @Summary Get bookmark QR code
+// @Description Render a QR code encoding the bookmark URL, for handing a link to another device or printing
+// @Tags sharing
+// @Produce png
+// @Produce json
+// @Param id path int true "Bookmark ID"
+// @Param size query int false "Image size in pixels (64-1024)" default(256)
+// @Param level query string false "Error correction level: L, M, Q or H" default(M)
+// @Param no_border query bool false "Omit the quiet zone around the code"
+// @Param format query string false "png or data_uri" default(png)
+// @Success 200 {file} binary
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /api/v1/bookmarks/{id}/qrcode.png [get]
+func (h *Handler) GetBookmarkQRCode(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	bookmarkID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "invalid_bookmark_id", "invalid bookmark ID", nil)
+		return
+	}
+
+	var opts QRCodeOptions
+	if err := c.ShouldBindQuery(&opts); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "invalid_request", "invalid QR code options", nil)
+		return
+	}
+
+	code, err := h.service.BookmarkQRCode(c.Request.Context(), userID, uint(bookmarkID), opts)
+	if err != nil {
+		switch err {
+		case ErrInvalidQRCodeOptions:
+			utils.ErrorResponse(c, http.StatusBadRequest, "invalid_request", err.Error(), nil)
+		case ErrResourceNotFound:
+			utils.ErrorResponse(c, http.StatusNotFound, "bookmark_not_found", "bookmark not found", nil)
+		default:
+			respondError(c, err, "failed to generate QR code")
+		}
+		return
+	}
+
+	// Bookmark URLs are private to the user, so the image is not publicly cacheable
+	c.Header("Cache-Control", "private, no-store")
+	writeQRCode(c, code, c.Query("format") == "data_uri")
+}
+
+func writeQRCode(c *gin.Context, code *QRCode, dataURI bool) {
+	if dataURI {
+		utils.SuccessResponse(c, gin.H{
+			"content":  code.Content,
+			"data_uri": code.DataURI(),
+		}, "QR code generated successfully")
+		return
+	}
+
+	if c.Writer.Header().Get("Cache-Control") == "" {
+		c.Header("Cache-Control", qrCacheControl)
+	}
+	if code.URL != "" {
+		c.Redirect(http.StatusFound, code.URL)
+		return
+	}
+	c.Data(http.StatusOK, "image/png", code.PNG)
+}
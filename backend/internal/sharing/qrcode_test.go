@@ -0,0 +1,91 @@
+package sharing
+
+import (
+	"bytes"
+	"context"
+	"image/png"
+	"strings"
+	"time"
+
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+type memoryQRCodeStore struct {
+	uploads map[string][]byte
+	index   map[string]string
+}
+
+func (m *memoryQRCodeStore) UploadFile(ctx context.Context, objectName string, data []byte, contentType string) (string, error) {
+	m.uploads[objectName] = data
+	return "https://cdn.example.com/" + objectName, nil
+}
+
+func (m *memoryQRCodeStore) GetString(ctx context.Context, key string) (string, error) {
+	return m.index[key], nil
+}
+
+func (m *memoryQRCodeStore) SetWithExpiration(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	m.index[key] = value.(string)
+	return nil
+}
+
+func (suite *SharingServiceTestSuite) TestShareQRCode() {
+	owner := suite.createUser("owner")
+	collection := suite.factory.Collection(owner.ID)
+	share := &CollectionShare{
+		CollectionID: collection.ID,
+		UserID:       owner.ID,
+		ShareType:    ShareTypePublic,
+		Permission:   PermissionView,
+		ShareToken:   "qr-token",
+		IsActive:     true,
+	}
+	suite.Require().NoError(suite.db.Create(share).Error)
+
+	store := &memoryQRCodeStore{uploads: map[string][]byte{}, index: map[string]string{}}
+	suite.service.SetQRCodeCache(store, store)
+
+	// When: Rendering a QR code for the first time
+	code, err := suite.service.ShareQRCode(context.Background(), "qr-token", QRCodeOptions{Size: 128, Level: "h"}, true)
+
+	// Then: A PNG of the requested size encoding the share URL is rendered and uploaded
+	suite.Require().NoError(err)
+	suite.Equal("http://localhost:3000/shared/qr-token", code.Content)
+	img, err := png.Decode(bytes.NewReader(code.PNG))
+	suite.Require().NoError(err)
+	suite.Equal(128, img.Bounds().Dx())
+	suite.Contains(store.uploads, "qrcodes/shares/qr-token-128-H-false.png")
+	suite.True(strings.HasPrefix(code.DataURI(), "data:image/png;base64,"))
+
+	// And: Later requests are served from storage
+	code, err = suite.service.ShareQRCode(context.Background(), "qr-token", QRCodeOptions{Size: 128, Level: "H"}, true)
+	suite.Require().NoError(err)
+	suite.Equal("https://cdn.example.com/qrcodes/shares/qr-token-128-H-false.png", code.URL)
+	suite.Empty(code.PNG)
+
+	// And: Invalid options and inactive shares are rejected
+	_, err = suite.service.ShareQRCode(context.Background(), "qr-token", QRCodeOptions{Size: 4096}, true)
+	suite.Equal(ErrInvalidQRCodeOptions, err)
+	_, err = suite.service.ShareQRCode(context.Background(), "qr-token", QRCodeOptions{Level: "X"}, true)
+	suite.Equal(ErrInvalidQRCodeOptions, err)
+	suite.Require().NoError(suite.db.Model(share).Update("is_active", false).Error)
+	_, err = suite.service.ShareQRCode(context.Background(), "qr-token", QRCodeOptions{}, true)
+	suite.Equal(ErrShareInactive, err)
+}
+
+func (suite *SharingServiceTestSuite) TestBookmarkQRCode() {
+	owner := suite.createUser("owner")
+	other := suite.createUser("other")
+	bookmark := suite.factory.Bookmark(owner.ID, func(b *database.Bookmark) { b.URL = "https://example.com/private" })
+
+	code, err := suite.service.BookmarkQRCode(context.Background(), owner.ID, bookmark.ID, QRCodeOptions{})
+	suite.Require().NoError(err)
+	suite.Equal("https://example.com/private", code.Content)
+	img, err := png.Decode(bytes.NewReader(code.PNG))
+	suite.Require().NoError(err)
+	suite.Equal(qrDefaultSize, img.Bounds().Dx())
+
+	// Only the owner can render a QR code for a bookmark
+	_, err = suite.service.BookmarkQRCode(context.Background(), other.ID, bookmark.ID, QRCodeOptions{})
+	suite.Equal(ErrResourceNotFound, err)
+}
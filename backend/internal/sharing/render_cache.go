@@ -0,0 +1,212 @@
+package sharing
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Render cache lifetimes. A rendered share is served as is while fresh, and
+// served while it is refreshed in the background until it is stale
+const (
+	renderFreshTTL = time.Minute
+	renderStaleTTL = time.Hour
+)
+
+// renderTimeout bounds a background refresh of a rendered share
+const renderTimeout = 10 * time.Second
+
+// Render cache states, reported in the X-Cache header
+const (
+	RenderCacheHit   = "HIT"
+	RenderCacheStale = "STALE"
+	RenderCacheMiss  = "MISS"
+)
+
+// ShareRenderStore keeps pre-rendered shares, e.g. the Redis client
+type ShareRenderStore interface {
+	GetString(ctx context.Context, key string) (string, error)
+	SetWithExpiration(ctx context.Context, key string, value interface{}, expiration time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// RenderedShare is a share page rendered as JSON and HTML, with the share
+// it was rendered for so its headers can be set without a lookup
+type RenderedShare struct {
+	Share      CollectionShare `json:"share"`
+	JSON       []byte          `json:"json"`
+	HTML       []byte          `json:"html"`
+	JSONETag   string          `json:"json_etag"`
+	HTMLETag   string          `json:"html_etag"`
+	RenderedAt time.Time       `json:"rendered_at"`
+}
+
+// shareRenders is the render cache of a service
+type shareRenders struct {
+	store ShareRenderStore
+	fresh time.Duration
+	stale time.Duration
+
+	// Tokens being refreshed, so a popular share is rendered once
+	refreshing sync.Map
+}
+
+// SetRenderCache makes public share pages served from pre-rendered copies.
+// Without it every view renders the share from the database
+func (s *Service) SetRenderCache(store ShareRenderStore) {
+	s.renders = &shareRenders{store: store, fresh: renderFreshTTL, stale: renderStaleTTL}
+}
+
+// RenderShare returns a share's rendered page and whether it came from the
+// cache fresh, stale or not at all. Stale copies are served while a fresh
+// one is rendered in the background
+func (s *Service) RenderShare(ctx context.Context, token string) (*RenderedShare, string, error) {
+	if s.renders == nil {
+		rendered, err := s.renderShare(ctx, token)
+		return rendered, RenderCacheMiss, err
+	}
+
+	if rendered, ok := s.cachedRender(ctx, token); ok {
+		if rendered.Share.ExpiresAt != nil && rendered.Share.ExpiresAt.Before(time.Now()) {
+			s.forgetRender(ctx, token)
+			return nil, RenderCacheMiss, ErrShareExpired
+		}
+		if time.Since(rendered.RenderedAt) < s.renders.fresh {
+			return rendered, RenderCacheHit, nil
+		}
+		s.refreshRenderAsync(token)
+		return rendered, RenderCacheStale, nil
+	}
+
+	rendered, err := s.refreshRender(ctx, token)
+	return rendered, RenderCacheMiss, err
+}
+
+// RefreshCollectionRenders renders the embeddable shares of a collection
+// again after it changed, so their next view is already up to date
+func (s *Service) RefreshCollectionRenders(ctx context.Context, collectionID uint) error {
+	if s.renders == nil {
+		return nil
+	}
+
+	var tokens []string
+	if err := s.db.WithContext(ctx).Model(&CollectionShare{}).
+		Where("collection_id = ? AND embed_enabled = ?", collectionID, true).
+		Pluck("share_token", &tokens).Error; err != nil {
+		return fmt.Errorf("failed to find collection shares: %w", err)
+	}
+
+	var errs []error
+	for _, token := range tokens {
+		if _, err := s.refreshRender(ctx, token); err != nil && !isShareUnavailable(err) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// refreshRender renders a share and stores it. A share that can no longer
+// be viewed is dropped from the cache
+func (s *Service) refreshRender(ctx context.Context, token string) (*RenderedShare, error) {
+	rendered, err := s.renderShare(ctx, token)
+	if err != nil {
+		if isShareUnavailable(err) {
+			s.forgetRender(ctx, token)
+		}
+		return nil, err
+	}
+
+	ttl := s.renders.fresh + s.renders.stale
+	if expires := rendered.Share.ExpiresAt; expires != nil && time.Until(*expires) < ttl {
+		ttl = time.Until(*expires)
+	}
+	if data, err := json.Marshal(rendered); err == nil && ttl > 0 {
+		// A failed write only costs the next view a render
+		_ = s.renders.store.SetWithExpiration(ctx, renderKey(token), string(data), ttl)
+	}
+	return rendered, nil
+}
+
+// refreshRenderAsync renders a stale share again in the background, once
+// at a time per share
+func (s *Service) refreshRenderAsync(token string) {
+	if _, busy := s.renders.refreshing.LoadOrStore(token, true); busy {
+		return
+	}
+	go func() {
+		defer s.renders.refreshing.Delete(token)
+		ctx, cancel := context.WithTimeout(context.Background(), renderTimeout)
+		defer cancel()
+		_, _ = s.refreshRender(ctx, token)
+	}()
+}
+
+// cachedRender reads a rendered share from the cache
+func (s *Service) cachedRender(ctx context.Context, token string) (*RenderedShare, bool) {
+	data, err := s.renders.store.GetString(ctx, renderKey(token))
+	if err != nil || data == "" {
+		return nil, false
+	}
+	var rendered RenderedShare
+	if err := json.Unmarshal([]byte(data), &rendered); err != nil {
+		return nil, false
+	}
+	return &rendered, true
+}
+
+// forgetRender drops a share from the render cache
+func (s *Service) forgetRender(ctx context.Context, token string) {
+	if s.renders != nil {
+		_ = s.renders.store.Delete(ctx, renderKey(token))
+	}
+}
+
+// renderShare renders a share's page from the database
+func (s *Service) renderShare(ctx context.Context, token string) (*RenderedShare, error) {
+	embed, share, err := s.GetEmbedCollection(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	rendered := &RenderedShare{Share: *share, RenderedAt: time.Now()}
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(embed); err != nil {
+		return nil, fmt.Errorf("failed to render share: %w", err)
+	}
+	rendered.JSON = append([]byte(nil), body.Bytes()...)
+
+	body.Reset()
+	if err := embedTemplate.Execute(&body, embed); err != nil {
+		return nil, fmt.Errorf("failed to render share: %w", err)
+	}
+	rendered.HTML = body.Bytes()
+
+	rendered.JSONETag = renderETag(rendered.JSON)
+	rendered.HTMLETag = renderETag(rendered.HTML)
+	return rendered, nil
+}
+
+// isShareUnavailable reports errors meaning the share cannot be viewed
+// rather than that rendering it failed
+func isShareUnavailable(err error) bool {
+	switch err {
+	case ErrShareNotFound, ErrCollectionNotFound, ErrShareExpired, ErrShareInactive, ErrEmbedDisabled:
+		return true
+	}
+	return false
+}
+
+func renderETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+func renderKey(token string) string {
+	return "share_render:" + token
+}
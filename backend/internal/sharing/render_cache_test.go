@@ -0,0 +1,109 @@
+package sharing
+
+import (
+	"context"
+	"time"
+
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+type memoryRenderStore map[string]string
+
+func (m memoryRenderStore) GetString(ctx context.Context, key string) (string, error) {
+	return m[key], nil
+}
+
+func (m memoryRenderStore) SetWithExpiration(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	m[key] = value.(string)
+	return nil
+}
+
+func (m memoryRenderStore) Delete(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		delete(m, key)
+	}
+	return nil
+}
+
+func (suite *SharingServiceTestSuite) TestRenderShare_Cache() {
+	owner := suite.createUser("owner")
+	collection := suite.factory.Collection(owner.ID, func(c *database.Collection) { c.Name = "Viral" })
+	bookmark := suite.factory.Bookmark(owner.ID, func(b *database.Bookmark) { b.Title = "First" })
+	suite.factory.AddToCollection(collection, bookmark)
+	share := &CollectionShare{
+		CollectionID: collection.ID,
+		UserID:       owner.ID,
+		ShareType:    ShareTypePublic,
+		Permission:   PermissionView,
+		ShareToken:   "render-token",
+		IsActive:     true,
+		EmbedEnabled: true,
+	}
+	suite.Require().NoError(suite.db.Create(share).Error)
+
+	store := memoryRenderStore{}
+	suite.service.SetRenderCache(store)
+	ctx := context.Background()
+
+	// When: The share is viewed twice
+	rendered, cache, err := suite.service.RenderShare(ctx, "render-token")
+	suite.Require().NoError(err)
+	suite.Equal(RenderCacheMiss, cache)
+	suite.Contains(string(rendered.HTML), "First")
+	suite.Contains(string(rendered.JSON), `"title":"Viral"`)
+
+	// Then: The second view is served from the cache, without the database
+	suite.Require().NoError(suite.db.Model(bookmark).Update("title", "Renamed").Error)
+	rendered, cache, err = suite.service.RenderShare(ctx, "render-token")
+	suite.Require().NoError(err)
+	suite.Equal(RenderCacheHit, cache)
+	suite.Contains(string(rendered.HTML), "First")
+
+	// And: A change to the collection renders it again
+	suite.Require().NoError(suite.service.RefreshCollectionRenders(ctx, collection.ID))
+	rendered, cache, err = suite.service.RenderShare(ctx, "render-token")
+	suite.Require().NoError(err)
+	suite.Equal(RenderCacheHit, cache)
+	suite.Contains(string(rendered.HTML), "Renamed")
+
+	// And: Updating the share drops its render, so disabling embeds applies at once
+	disabled := false
+	_, err = suite.service.UpdateShare(ctx, owner.ID, share.ID, &UpdateShareRequest{EmbedEnabled: &disabled})
+	suite.Require().NoError(err)
+	_, _, err = suite.service.RenderShare(ctx, "render-token")
+	suite.Equal(ErrEmbedDisabled, err)
+	suite.Empty(store)
+}
+
+func (suite *SharingServiceTestSuite) TestRenderShare_StaleWhileRevalidate() {
+	owner := suite.createUser("owner")
+	collection := suite.factory.Collection(owner.ID)
+	share := &CollectionShare{
+		CollectionID: collection.ID,
+		UserID:       owner.ID,
+		ShareType:    ShareTypePublic,
+		Permission:   PermissionView,
+		ShareToken:   "stale-token",
+		IsActive:     true,
+		EmbedEnabled: true,
+	}
+	suite.Require().NoError(suite.db.Create(share).Error)
+	suite.service.SetRenderCache(memoryRenderStore{})
+	ctx := context.Background()
+
+	_, _, err := suite.service.RenderShare(ctx, "stale-token")
+	suite.Require().NoError(err)
+
+	// A copy past its fresh lifetime is still served
+	suite.service.renders.fresh = 0
+	rendered, cache, err := suite.service.RenderShare(ctx, "stale-token")
+	suite.Require().NoError(err)
+	suite.Equal(RenderCacheStale, cache)
+	suite.Equal(share.ID, rendered.Share.ID)
+
+	// while a single refresh replaces it in the background
+	suite.Eventually(func() bool {
+		_, refreshing := suite.service.renders.refreshing.Load("stale-token")
+		return !refreshing
+	}, time.Second, 10*time.Millisecond)
+}
@@ -4,18 +4,49 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"time"
 
+	"go.uber.org/zap"
 	"gorm.io/gorm"
 
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/internal/safety"
 	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/mail"
 )
 
+// embedMaxItems caps the number of bookmarks rendered in an embed
+const embedMaxItems = 50
+
 // Service represents the sharing service
 type Service struct {
-	db      *gorm.DB
-	baseURL string
+	db       *gorm.DB
+	baseURL  string
+	notifier Notifier
+
+	qrUploader QRCodeUploader
+	qrIndex    QRCodeIndex
+
+	previews       PreviewRenderer
+	previewStorage QRCodeUploader
+	previewIndex   QRCodeIndex
+
+	privacy config.PrivacyConfig
+
+	mailer        mail.Sender
+	subscriptions config.SubscriptionsConfig
+
+	webhooks CollectionWebhooks
+
+	counter RateCounter // optional; counts data API requests per share
+
+	hooks Hooks
+
+	renders *shareRenders // optional; pre-rendered public share pages
+
+	logger *zap.Logger
 }
 
 // NewService creates a new sharing service
@@ -23,14 +54,24 @@ func NewService(db *gorm.DB, baseURL string) *Service {
 	return &Service{
 		db:      db,
 		baseURL: baseURL,
+		logger:  zap.NewNop(),
 	}
 }
 
+// SetLogger logs the failures that don't fail a request, such as activity
+// that could not be recorded
+func (s *Service) SetLogger(logger *zap.Logger) {
+	s.logger = logger
+}
+
 // CreateShare creates a new collection share
 func (s *Service) CreateShare(ctx context.Context, userID uint, request *CreateShareRequest) (*ShareResponse, error) {
 	if err := request.Validate(); err != nil {
 		return nil, err
 	}
+	if err := s.runCreateHooks(ctx, userID, request); err != nil {
+		return nil, err
+	}
 
 	// Check if collection exists and user has access
 	var collection database.Collection
@@ -55,16 +96,23 @@ func (s *Service) CreateShare(ctx context.Context, userID uint, request *CreateS
 
 	// Create share
 	share := &CollectionShare{
-		CollectionID: request.CollectionID,
-		UserID:       userID,
-		ShareType:    request.ShareType,
-		Permission:   request.Permission,
-		ShareToken:   shareToken,
-		Title:        request.Title,
-		Description:  request.Description,
-		Password:     request.Password, // TODO: Hash password
-		ExpiresAt:    request.ExpiresAt,
-		IsActive:     true,
+		CollectionID:         request.CollectionID,
+		UserID:               userID,
+		ShareType:            request.ShareType,
+		Permission:           request.Permission,
+		ShareToken:           shareToken,
+		Title:                request.Title,
+		Description:          request.Description,
+		Password:             request.Password, // TODO: Hash password
+		ExpiresAt:            request.ExpiresAt,
+		IsActive:             true,
+		EmbedEnabled:         request.EmbedEnabled,
+		EmbedAllowedOrigins:  joinList(request.EmbedAllowedOrigins),
+		EmbedFrameAncestors:  joinList(request.EmbedFrameAncestors),
+		SubscriptionsEnabled: request.SubscriptionsEnabled,
+		APIEnabled:           request.APIEnabled,
+		APIRateLimit:         request.APIRateLimit,
+		PreviewMode:          request.PreviewMode,
 	}
 
 	if err := s.db.Create(share).Error; err != nil {
@@ -139,10 +187,32 @@ func (s *Service) UpdateShare(ctx context.Context, userID uint, shareID uint, re
 	if request.IsActive != nil {
 		share.IsActive = *request.IsActive
 	}
+	if request.EmbedEnabled != nil {
+		share.EmbedEnabled = *request.EmbedEnabled
+	}
+	if request.EmbedAllowedOrigins != nil {
+		share.EmbedAllowedOrigins = joinList(*request.EmbedAllowedOrigins)
+	}
+	if request.EmbedFrameAncestors != nil {
+		share.EmbedFrameAncestors = joinList(*request.EmbedFrameAncestors)
+	}
+	if request.SubscriptionsEnabled != nil {
+		share.SubscriptionsEnabled = *request.SubscriptionsEnabled
+	}
+	if request.APIEnabled != nil {
+		share.APIEnabled = *request.APIEnabled
+	}
+	if request.APIRateLimit != nil {
+		share.APIRateLimit = *request.APIRateLimit
+	}
+	if request.PreviewMode != nil {
+		share.PreviewMode = *request.PreviewMode
+	}
 
 	if err := s.db.Save(&share).Error; err != nil {
 		return nil, fmt.Errorf("failed to update share: %w", err)
 	}
+	s.forgetRender(ctx, share.ShareToken)
 
 	return share.ToResponse(s.baseURL), nil
 }
@@ -166,6 +236,7 @@ func (s *Service) DeleteShare(ctx context.Context, userID uint, shareID uint) er
 	if err := s.db.Delete(&share, shareID).Error; err != nil {
 		return fmt.Errorf("failed to delete share: %w", err)
 	}
+	s.forgetRender(ctx, share.ShareToken)
 
 	return nil
 }
@@ -225,17 +296,24 @@ func (s *Service) ForkCollection(ctx context.Context, userID uint, originalColle
 
 	// TODO: Check if collection allows forking based on share settings
 
+	// Share links are unique, so the fork needs its own
+	shareLink, err := s.generateShareToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate share link: %w", err)
+	}
+
 	var forkedCollection *database.Collection
 	var fork *CollectionFork
 
-	// Use transaction to ensure consistency
-	err := s.db.Transaction(func(tx *gorm.DB) error {
+	// The collection, its bookmarks and the fork record are created together
+	err = database.WithTransaction(ctx, s.db, func(tx *gorm.DB) error {
 		// Create forked collection
 		forkedCollection = &database.Collection{
 			UserID:      userID,
 			Name:        request.Name,
 			Description: request.Description,
 			Visibility:  "private", // Forked collections are private by default
+			ShareLink:   shareLink,
 		}
 
 		if err := tx.Create(forkedCollection).Error; err != nil {
@@ -258,7 +336,7 @@ func (s *Service) ForkCollection(ctx context.Context, userID uint, originalColle
 				}
 
 				// Associate bookmark with forked collection
-				if err := tx.Exec("INSERT INTO collection_bookmarks (collection_id, bookmark_id) VALUES (?, ?)",
+				if err := tx.Exec("INSERT INTO bookmark_collections (collection_id, bookmark_id) VALUES (?, ?)",
 					forkedCollection.ID, newBookmark.ID).Error; err != nil {
 					return fmt.Errorf("failed to associate bookmark with forked collection: %w", err)
 				}
@@ -317,14 +395,6 @@ func (s *Service) AddCollaborator(ctx context.Context, userID uint, collectionID
 		return nil, fmt.Errorf("failed to find user: %w", err)
 	}
 
-	// Check if collaborator already exists
-	var existingCollaborator CollectionCollaborator
-	if err := s.db.First(&existingCollaborator, "collection_id = ? AND user_id = ?",
-		collectionID, collaboratorUser.ID).Error; err == nil {
-		return nil, ErrCollaboratorExists
-	}
-
-	// Create collaborator
 	collaborator := &CollectionCollaborator{
 		CollectionID: collectionID,
 		UserID:       collaboratorUser.ID,
@@ -334,7 +404,22 @@ func (s *Service) AddCollaborator(ctx context.Context, userID uint, collectionID
 		InvitedAt:    time.Now(),
 	}
 
-	if err := s.db.Create(collaborator).Error; err != nil {
+	// The check answers the common case; the unique index on collection and
+	// user rejects an invitation that raced past it
+	var existing int64
+	if err := s.db.WithContext(ctx).Model(&CollectionCollaborator{}).
+		Where("collection_id = ? AND user_id = ?", collectionID, collaboratorUser.ID).
+		Count(&existing).Error; err != nil {
+		return nil, fmt.Errorf("failed to check collaborator: %w", err)
+	}
+	if existing > 0 {
+		return nil, ErrCollaboratorExists
+	}
+
+	if err := s.db.WithContext(ctx).Create(collaborator).Error; err != nil {
+		if database.IsUniqueViolation(err) {
+			return nil, ErrCollaboratorExists
+		}
 		return nil, fmt.Errorf("failed to create collaborator: %w", err)
 	}
 
@@ -345,30 +430,39 @@ func (s *Service) AddCollaborator(ctx context.Context, userID uint, collectionID
 
 // AcceptCollaboration accepts a collaboration invitation
 func (s *Service) AcceptCollaboration(ctx context.Context, userID uint, collaboratorID uint) error {
+	// The invitation is locked while its status is checked and changed
 	var collaborator CollectionCollaborator
-	if err := s.db.First(&collaborator, collaboratorID).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return fmt.Errorf("collaboration not found")
+	err := database.WithTransaction(ctx, s.db, func(tx *gorm.DB) error {
+		if err := database.ForUpdate(tx).First(&collaborator, collaboratorID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return fmt.Errorf("collaboration not found")
+			}
+			return fmt.Errorf("failed to find collaboration: %w", err)
 		}
-		return fmt.Errorf("failed to find collaboration: %w", err)
-	}
 
-	if collaborator.UserID != userID {
-		return ErrUnauthorized
-	}
+		if collaborator.UserID != userID {
+			return ErrUnauthorized
+		}
 
-	if collaborator.Status != "pending" {
-		return fmt.Errorf("collaboration already %s", collaborator.Status)
-	}
+		if collaborator.Status != "pending" {
+			return fmt.Errorf("collaboration already %s", collaborator.Status)
+		}
 
-	now := time.Now()
-	collaborator.Status = "accepted"
-	collaborator.AcceptedAt = &now
+		now := time.Now()
+		collaborator.Status = "accepted"
+		collaborator.AcceptedAt = &now
+
+		if err := tx.Save(&collaborator).Error; err != nil {
+			return fmt.Errorf("failed to accept collaboration: %w", err)
+		}
 
-	if err := s.db.Save(&collaborator).Error; err != nil {
-		return fmt.Errorf("failed to accept collaboration: %w", err)
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
+	s.collaboratorJoined(ctx, &collaborator)
 	return nil
 }
 
@@ -382,18 +476,38 @@ func (s *Service) RecordActivity(ctx context.Context, shareID uint, userID *uint
 		UserAgent:    userAgent,
 	}
 
-	// TODO: Serialize metadata to JSON
+	// In compliance mode only the network is stored, and the user agent
+	// survives only as the client class in the daily aggregate
+	if s.privacy.ComplianceMode {
+		activity.IPAddress = AnonymizeIP(ipAddress)
+		activity.UserAgent = ""
+	}
+
+	if len(metadata) > 0 {
+		encoded, err := json.Marshal(metadata)
+		if err != nil {
+			return fmt.Errorf("failed to encode activity metadata: %w", err)
+		}
+		activity.Metadata = string(encoded)
+	}
 
 	if err := s.db.Create(activity).Error; err != nil {
 		return fmt.Errorf("failed to record activity: %w", err)
 	}
 
-	// Update view count if it's a view activity
-	if activityType == "view" {
+	if err := s.aggregateActivity(ctx, shareID, activityType, userAgent, activity.CreatedAt); err != nil {
+		// Log error but don't fail the request
+		s.logger.Warn("Failed to aggregate share activity", zap.Uint("share_id", shareID), zap.Error(err))
+	}
+
+	// Update view count for direct and embedded views
+	if activityType == ActivityTypeView || activityType == ActivityTypeEmbed {
 		if err := s.db.Model(&CollectionShare{}).Where("id = ?", shareID).
 			UpdateColumn("view_count", gorm.Expr("view_count + 1")).Error; err != nil {
 			// Log error but don't fail the request
-			fmt.Printf("failed to update view count: %v\n", err)
+			s.logger.Warn("Failed to update view count", zap.Uint("share_id", shareID), zap.Error(err))
+		} else {
+			s.shareViewed(ctx, shareID)
 		}
 	}
 
@@ -423,6 +537,74 @@ func (s *Service) GetShareActivity(ctx context.Context, userID uint, shareID uin
 	return activities, nil
 }
 
+// GetEmbedCollection returns the embeddable payload for a share token
+func (s *Service) GetEmbedCollection(ctx context.Context, token string) (*EmbedCollection, *CollectionShare, error) {
+	share, err := s.GetShareByToken(ctx, token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Password protected shares cannot be embedded since there is no way to prompt for it
+	if !share.EmbedEnabled || share.Password != "" {
+		return nil, nil, ErrEmbedDisabled
+	}
+
+	var collection database.Collection
+	if err := s.db.WithContext(ctx).First(&collection, share.CollectionID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil, ErrCollectionNotFound
+		}
+		return nil, nil, fmt.Errorf("failed to find collection: %w", err)
+	}
+
+	var bookmarks []database.Bookmark
+	if err := s.db.WithContext(ctx).
+		Joins("JOIN bookmark_collections ON bookmarks.id = bookmark_collections.bookmark_id").
+		Where("bookmark_collections.collection_id = ?", collection.ID).
+		Order("bookmarks.created_at DESC").
+		Limit(embedMaxItems).
+		Find(&bookmarks).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to get collection bookmarks: %w", err)
+	}
+
+	title := share.Title
+	if title == "" {
+		title = collection.Name
+	}
+	description := share.Description
+	if description == "" {
+		description = collection.Description
+	}
+
+	embed := &EmbedCollection{
+		Title:       title,
+		Description: description,
+		ShareURL:    s.baseURL + "/shared/" + share.ShareToken,
+		Items:       make([]EmbedItem, 0, len(bookmarks)),
+		UpdatedAt:   collection.UpdatedAt,
+	}
+
+	for _, bookmark := range bookmarks {
+		embed.Items = append(embed.Items, EmbedItem{
+			Title:   bookmark.Title,
+			URL:     bookmark.URL,
+			Favicon: bookmark.Favicon,
+			Warning: safety.Warning(bookmark.SafetyThreat),
+		})
+		if bookmark.UpdatedAt.After(embed.UpdatedAt) {
+			embed.UpdatedAt = bookmark.UpdatedAt
+		}
+	}
+	if share.UpdatedAt.After(embed.UpdatedAt) {
+		embed.UpdatedAt = share.UpdatedAt
+	}
+	if share.IsPublic() {
+		embed.FeedURL = s.baseURL + "/feeds/collections/" + share.ShareToken
+	}
+
+	return embed, share, nil
+}
+
 // generateShareToken generates a unique share token
 func (s *Service) generateShareToken() (string, error) {
 	bytes := make([]byte, 16)
@@ -2,6 +2,7 @@ package sharing
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -10,7 +11,9 @@ import (
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 
+	"bookmark-sync-service/backend/internal/automation"
 	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/testfactory"
 )
 
 // SharingServiceTestSuite defines the test suite for sharing service
@@ -18,6 +21,7 @@ type SharingServiceTestSuite struct {
 	suite.Suite
 	service *Service
 	db      *gorm.DB
+	factory *testfactory.Factory
 }
 
 func (suite *SharingServiceTestSuite) SetupSuite() {
@@ -34,6 +38,12 @@ func (suite *SharingServiceTestSuite) SetupSuite() {
 		&CollectionCollaborator{},
 		&CollectionFork{},
 		&ShareActivity{},
+		&ShareActivityDaily{},
+		&DirectShare{},
+		&EmailSubscriber{},
+		&CollectionComment{},
+		&automation.WebhookEndpoint{},
+		&automation.WebhookDelivery{},
 	)
 	suite.Require().NoError(err)
 
@@ -42,33 +52,29 @@ func (suite *SharingServiceTestSuite) SetupSuite() {
 }
 
 func (suite *SharingServiceTestSuite) SetupTest() {
+	suite.factory = testfactory.New(suite.T(), suite.db)
+
 	// Clean up data before each test
 	suite.db.Exec("DELETE FROM collection_shares")
 	suite.db.Exec("DELETE FROM collection_collaborators")
 	suite.db.Exec("DELETE FROM collection_forks")
 	suite.db.Exec("DELETE FROM share_activities")
+	suite.db.Exec("DELETE FROM share_activity_dailies")
+	suite.db.Exec("DELETE FROM direct_shares")
+	suite.db.Exec("DELETE FROM email_subscribers")
+	suite.db.Exec("DELETE FROM collection_comments")
+	suite.db.Exec("DELETE FROM webhook_endpoints")
+	suite.db.Exec("DELETE FROM webhook_deliveries")
+	suite.db.Exec("DELETE FROM bookmark_collections")
 	suite.db.Exec("DELETE FROM collections")
 	suite.db.Exec("DELETE FROM bookmarks")
 	suite.db.Exec("DELETE FROM users")
 }
 
 func (suite *SharingServiceTestSuite) TestCreateShare() {
-	// Create test user
-	user := &database.User{
-		Email:      "test@example.com",
-		Username:   "testuser",
-		SupabaseID: "test-supabase-id",
-	}
-	suite.Require().NoError(suite.db.Create(user).Error)
-
-	// Create test collection
-	collection := &database.Collection{
-		UserID:      user.ID,
-		Name:        "Test Collection",
-		Description: "Test Description",
-		Visibility:  "private",
-	}
-	suite.Require().NoError(suite.db.Create(collection).Error)
+	user := suite.factory.User()
+
+	collection := suite.factory.Collection(user.ID)
 
 	request := &CreateShareRequest{
 		CollectionID: collection.ID,
@@ -91,13 +97,7 @@ func (suite *SharingServiceTestSuite) TestCreateShare() {
 }
 
 func (suite *SharingServiceTestSuite) TestCreateShareCollectionNotFound() {
-	// Create test user
-	user := &database.User{
-		Email:      "test@example.com",
-		Username:   "testuser",
-		SupabaseID: "test-supabase-id",
-	}
-	suite.Require().NoError(suite.db.Create(user).Error)
+	user := suite.factory.User()
 
 	request := &CreateShareRequest{
 		CollectionID: 999, // Non-existent collection
@@ -113,22 +113,9 @@ func (suite *SharingServiceTestSuite) TestCreateShareCollectionNotFound() {
 }
 
 func (suite *SharingServiceTestSuite) TestGetShareByToken() {
-	// Create test user
-	user := &database.User{
-		Email:      "test@example.com",
-		Username:   "testuser",
-		SupabaseID: "test-supabase-id",
-	}
-	suite.Require().NoError(suite.db.Create(user).Error)
-
-	// Create test collection
-	collection := &database.Collection{
-		UserID:      user.ID,
-		Name:        "Test Collection",
-		Description: "Test Description",
-		Visibility:  "private",
-	}
-	suite.Require().NoError(suite.db.Create(collection).Error)
+	user := suite.factory.User()
+
+	collection := suite.factory.Collection(user.ID)
 
 	// Create test share
 	shareToken := "test-token-123"
@@ -161,22 +148,9 @@ func (suite *SharingServiceTestSuite) TestGetShareByTokenNotFound() {
 }
 
 func (suite *SharingServiceTestSuite) TestGetShareByTokenExpired() {
-	// Create test user
-	user := &database.User{
-		Email:      "test@example.com",
-		Username:   "testuser",
-		SupabaseID: "test-supabase-id",
-	}
-	suite.Require().NoError(suite.db.Create(user).Error)
-
-	// Create test collection
-	collection := &database.Collection{
-		UserID:      user.ID,
-		Name:        "Test Collection",
-		Description: "Test Description",
-		Visibility:  "private",
-	}
-	suite.Require().NoError(suite.db.Create(collection).Error)
+	user := suite.factory.User()
+
+	collection := suite.factory.Collection(user.ID)
 
 	// Create expired share
 	shareToken := "expired-token"
@@ -201,22 +175,9 @@ func (suite *SharingServiceTestSuite) TestGetShareByTokenExpired() {
 }
 
 func (suite *SharingServiceTestSuite) TestUpdateShare() {
-	// Create test user
-	user := &database.User{
-		Email:      "test@example.com",
-		Username:   "testuser",
-		SupabaseID: "test-supabase-id",
-	}
-	suite.Require().NoError(suite.db.Create(user).Error)
-
-	// Create test collection
-	collection := &database.Collection{
-		UserID:      user.ID,
-		Name:        "Test Collection",
-		Description: "Test Description",
-		Visibility:  "private",
-	}
-	suite.Require().NoError(suite.db.Create(collection).Error)
+	user := suite.factory.User()
+
+	collection := suite.factory.Collection(user.ID)
 
 	// Create test share
 	testShare := &CollectionShare{
@@ -246,22 +207,9 @@ func (suite *SharingServiceTestSuite) TestUpdateShare() {
 }
 
 func (suite *SharingServiceTestSuite) TestDeleteShare() {
-	// Create test user
-	user := &database.User{
-		Email:      "test@example.com",
-		Username:   "testuser",
-		SupabaseID: "test-supabase-id",
-	}
-	suite.Require().NoError(suite.db.Create(user).Error)
-
-	// Create test collection
-	collection := &database.Collection{
-		UserID:      user.ID,
-		Name:        "Test Collection",
-		Description: "Test Description",
-		Visibility:  "private",
-	}
-	suite.Require().NoError(suite.db.Create(collection).Error)
+	user := suite.factory.User()
+
+	collection := suite.factory.Collection(user.ID)
 
 	// Create test share
 	testShare := &CollectionShare{
@@ -285,30 +233,10 @@ func (suite *SharingServiceTestSuite) TestDeleteShare() {
 }
 
 func (suite *SharingServiceTestSuite) TestGetUserShares() {
-	// Create test user
-	user := &database.User{
-		Email:      "test@example.com",
-		Username:   "testuser",
-		SupabaseID: "test-supabase-id",
-	}
-	suite.Require().NoError(suite.db.Create(user).Error)
-
-	// Create test collections
-	collection1 := &database.Collection{
-		UserID:     user.ID,
-		Name:       "Collection 1",
-		Visibility: "private",
-		ShareLink:  "share-link-1",
-	}
-	suite.Require().NoError(suite.db.Create(collection1).Error)
+	user := suite.factory.User()
 
-	collection2 := &database.Collection{
-		UserID:     user.ID,
-		Name:       "Collection 2",
-		Visibility: "private",
-		ShareLink:  "share-link-2",
-	}
-	suite.Require().NoError(suite.db.Create(collection2).Error)
+	collection1 := suite.factory.Collection(user.ID)
+	collection2 := suite.factory.Collection(user.ID)
 
 	// Create test shares
 	share1 := &CollectionShare{
@@ -340,21 +268,9 @@ func (suite *SharingServiceTestSuite) TestGetUserShares() {
 }
 
 func (suite *SharingServiceTestSuite) TestRecordActivity() {
-	// Create test user
-	user := &database.User{
-		Email:      "test@example.com",
-		Username:   "testuser",
-		SupabaseID: "test-supabase-id",
-	}
-	suite.Require().NoError(suite.db.Create(user).Error)
+	user := suite.factory.User()
 
-	// Create test collection
-	collection := &database.Collection{
-		UserID:     user.ID,
-		Name:       "Test Collection",
-		Visibility: "private",
-	}
-	suite.Require().NoError(suite.db.Create(collection).Error)
+	collection := suite.factory.Collection(user.ID)
 
 	// Create test share
 	testShare := &CollectionShare{
@@ -380,6 +296,161 @@ func (suite *SharingServiceTestSuite) TestRecordActivity() {
 	suite.Equal("view", activity.ActivityType)
 }
 
+func (suite *SharingServiceTestSuite) TestGetEmbedCollection() {
+	user := suite.factory.User()
+	collection := suite.factory.Collection(user.ID, func(c *database.Collection) {
+		c.Name = "Reading List"
+		c.Visibility = "public"
+	})
+	bookmark := suite.factory.Bookmark(user.ID, func(b *database.Bookmark) {
+		b.URL = "https://example.com"
+		b.Title = "Example"
+		b.Favicon = "https://example.com/favicon.ico"
+	})
+	suite.factory.AddToCollection(collection, bookmark)
+
+	testShare := &CollectionShare{
+		CollectionID:        collection.ID,
+		UserID:              user.ID,
+		ShareType:           ShareTypePublic,
+		Permission:          PermissionView,
+		ShareToken:          "embed-token",
+		IsActive:            true,
+		EmbedEnabled:        true,
+		EmbedAllowedOrigins: "https://blog.example.com",
+	}
+	suite.Require().NoError(suite.db.Create(testShare).Error)
+
+	embed, share, err := suite.service.GetEmbedCollection(context.Background(), "embed-token")
+
+	suite.NoError(err)
+	suite.Equal(testShare.ID, share.ID)
+	suite.Equal("Reading List", embed.Title)
+	suite.Equal("http://localhost:3000/shared/embed-token", embed.ShareURL)
+	suite.Require().Len(embed.Items, 1)
+	suite.Equal("Example", embed.Items[0].Title)
+	suite.Equal("https://example.com/favicon.ico", embed.Items[0].Favicon)
+
+	// Disabled embeds are rejected
+	suite.Require().NoError(suite.db.Model(testShare).Update("embed_enabled", false).Error)
+	_, _, err = suite.service.GetEmbedCollection(context.Background(), "embed-token")
+	suite.Equal(ErrEmbedDisabled, err)
+}
+
+func (suite *SharingServiceTestSuite) TestRecordEmbedActivityCountsView() {
+	user := suite.factory.User()
+	collection := suite.factory.Collection(user.ID)
+
+	testShare := &CollectionShare{
+		CollectionID: collection.ID,
+		UserID:       user.ID,
+		ShareType:    ShareTypePublic,
+		Permission:   PermissionView,
+		ShareToken:   "test-token",
+		IsActive:     true,
+	}
+	suite.Require().NoError(suite.db.Create(testShare).Error)
+
+	err := suite.service.RecordActivity(context.Background(), testShare.ID, nil, ActivityTypeEmbed, "192.168.1.1", "Mozilla/5.0", nil)
+	suite.NoError(err)
+
+	var share CollectionShare
+	suite.Require().NoError(suite.db.First(&share, testShare.ID).Error)
+	suite.Equal(int64(1), share.ViewCount)
+
+	var activity ShareActivity
+	suite.Require().NoError(suite.db.First(&activity, "share_id = ?", testShare.ID).Error)
+	suite.Equal(ActivityTypeEmbed, activity.ActivityType)
+}
+
+// createForkSource creates an owner's collection holding two bookmarks and
+// the user who forks it
+func (suite *SharingServiceTestSuite) createForkSource() (*database.Collection, *database.User) {
+	owner := suite.createUser("owner")
+	forker := suite.createUser("forker")
+
+	collection := suite.factory.Collection(owner.ID, func(c *database.Collection) {
+		c.Name = "Original"
+		c.Visibility = "public"
+	})
+	suite.factory.AddToCollection(collection, suite.factory.Bookmark(owner.ID), suite.factory.Bookmark(owner.ID))
+	return collection, forker
+}
+
+func (suite *SharingServiceTestSuite) TestForkCollection() {
+	original, forker := suite.createForkSource()
+
+	fork, err := suite.service.ForkCollection(context.Background(), forker.ID, original.ID, &ForkRequest{
+		Name:              "My Fork",
+		PreserveBookmarks: true,
+	})
+	suite.Require().NoError(err)
+	suite.Equal(forker.ID, fork.UserID)
+	suite.Equal("private", fork.Visibility)
+
+	var copied []database.Bookmark
+	suite.Require().NoError(suite.db.Model(fork).Association("Bookmarks").Find(&copied))
+	suite.Len(copied, 2)
+
+	var record CollectionFork
+	suite.Require().NoError(suite.db.First(&record, "forked_id = ?", fork.ID).Error)
+	suite.Equal(original.ID, record.OriginalID)
+}
+
+func (suite *SharingServiceTestSuite) TestForkCollectionRollsBack() {
+	original, forker := suite.createForkSource()
+
+	// Fail the last write of the fork
+	const callback = "test:fail_fork_record"
+	suite.Require().NoError(suite.db.Callback().Create().Before("gorm:create").Register(callback, func(tx *gorm.DB) {
+		if tx.Statement.Table == "collection_forks" {
+			tx.AddError(errors.New("fork record rejected"))
+		}
+	}))
+	defer suite.db.Callback().Create().Remove(callback)
+
+	_, err := suite.service.ForkCollection(context.Background(), forker.ID, original.ID, &ForkRequest{
+		Name:              "My Fork",
+		PreserveBookmarks: true,
+	})
+	suite.Require().Error(err)
+
+	// Nothing the fork wrote before the failure survives
+	var collections, bookmarks, links int64
+	suite.db.Model(&database.Collection{}).Where("user_id = ?", forker.ID).Count(&collections)
+	suite.db.Model(&database.Bookmark{}).Where("user_id = ?", forker.ID).Count(&bookmarks)
+	suite.db.Table("bookmark_collections").Count(&links)
+	suite.Zero(collections)
+	suite.Zero(bookmarks)
+	suite.Equal(int64(2), links)
+}
+
+func (suite *SharingServiceTestSuite) TestAcceptCollaboration() {
+	ctx := context.Background()
+	owner := suite.createUser("owner")
+	invitee := suite.createUser("invitee")
+	collection := suite.factory.Collection(owner.ID)
+
+	request := &CollaboratorRequest{Email: invitee.Email, Permission: PermissionEdit}
+	collaborator, err := suite.service.AddCollaborator(ctx, owner.ID, collection.ID, request)
+	suite.Require().NoError(err)
+
+	_, err = suite.service.AddCollaborator(ctx, owner.ID, collection.ID, request)
+	suite.ErrorIs(err, ErrCollaboratorExists)
+	// The index refuses a second row even without the check
+	duplicate := &CollectionCollaborator{CollectionID: collection.ID, UserID: invitee.ID, InviterID: owner.ID, InvitedAt: time.Now()}
+	suite.True(database.IsUniqueViolation(suite.db.Create(duplicate).Error))
+
+	suite.ErrorIs(suite.service.AcceptCollaboration(ctx, owner.ID, collaborator.ID), ErrUnauthorized)
+	suite.NoError(suite.service.AcceptCollaboration(ctx, invitee.ID, collaborator.ID))
+	suite.EqualError(suite.service.AcceptCollaboration(ctx, invitee.ID, collaborator.ID), "collaboration already accepted")
+
+	var stored CollectionCollaborator
+	suite.Require().NoError(suite.db.First(&stored, collaborator.ID).Error)
+	suite.Equal("accepted", stored.Status)
+	suite.NotNil(stored.AcceptedAt)
+}
+
 // Run the test suite
 func TestSharingServiceTestSuite(t *testing.T) {
 	suite.Run(t, new(SharingServiceTestSuite))
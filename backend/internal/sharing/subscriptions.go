@@ -0,0 +1,539 @@
+package sharing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/mail"
+	"bookmark-sync-service/backend/pkg/utils"
+)
+
+// digestMaxItems caps the bookmarks listed in one subscription email
+const digestMaxItems = 20
+
+// SetMailer enables email subscriptions, sending confirmations and update
+// emails through the sender
+func (s *Service) SetMailer(sender mail.Sender, cfg config.SubscriptionsConfig) {
+	s.mailer = sender
+	s.subscriptions = cfg
+}
+
+// Subscribe starts a double opt-in subscription of an email address to a
+// public share by sending it a confirmation link. Repeated requests for an
+// address within the cooldown, and requests for confirmed addresses, succeed
+// without sending anything, so the endpoint cannot be used to flood an
+// inbox or to learn who is subscribed
+func (s *Service) Subscribe(ctx context.Context, shareToken, email string) error {
+	address, ok := mail.ValidAddress(email)
+	if !ok {
+		return ErrInvalidEmail
+	}
+
+	share, err := s.GetShareByToken(ctx, shareToken)
+	if err != nil {
+		return err
+	}
+	if !share.AcceptsSubscriptions() || s.mailer == nil {
+		return ErrSubscriptionsDisabled
+	}
+
+	var subscriber EmailSubscriber
+	err = s.db.WithContext(ctx).Where("share_id = ? AND email = ?", share.ID, address).First(&subscriber).Error
+	switch {
+	case err == gorm.ErrRecordNotFound:
+		subscriber = EmailSubscriber{ShareID: share.ID, Email: address}
+	case err != nil:
+		return fmt.Errorf("failed to find subscriber: %w", err)
+	case subscriber.Status == SubscriberConfirmed:
+		return nil
+	case subscriber.Status == SubscriberPending && time.Since(subscriber.ConfirmationSentAt) < s.confirmCooldown():
+		return nil
+	}
+
+	token, err := s.generateShareToken()
+	if err != nil {
+		return fmt.Errorf("failed to generate subscriber token: %w", err)
+	}
+	subscriber.Status = SubscriberPending
+	subscriber.Token = token
+	subscriber.ConfirmationSentAt = time.Now()
+	if err := s.db.WithContext(ctx).Save(&subscriber).Error; err != nil {
+		return fmt.Errorf("failed to save subscriber: %w", err)
+	}
+
+	title, err := s.shareTitle(ctx, share)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, &mail.Message{
+		To:      address,
+		Subject: "Confirm your subscription to " + title,
+		Body: fmt.Sprintf("Someone, hopefully you, asked to receive an email when bookmarks are added to %s.\n\n"+
+			"Confirm the subscription:\n%s\n\n"+
+			"If you did not ask for this, ignore this email and you will not hear from us again.\n",
+			title, s.subscriptionURL(token, "confirm")),
+	})
+}
+
+// ConfirmSubscription confirms the subscription the token was sent for.
+// Only bookmarks added after confirmation are emailed
+func (s *Service) ConfirmSubscription(ctx context.Context, token string) (*EmailSubscriber, error) {
+	subscriber, err := s.subscriberByToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	switch subscriber.Status {
+	case SubscriberConfirmed:
+		return subscriber, nil
+	case SubscriberUnsubscribed:
+		return nil, ErrSubscriberNotFound
+	}
+	if ttl := s.subscriptions.ConfirmTTL; ttl > 0 && time.Since(subscriber.ConfirmationSentAt) > time.Duration(ttl)*time.Hour {
+		return nil, ErrConfirmationExpired
+	}
+
+	now := time.Now()
+	subscriber.Status = SubscriberConfirmed
+	subscriber.ConfirmedAt = &now
+	subscriber.LastNotifiedAt = &now
+	if err := s.db.WithContext(ctx).Save(subscriber).Error; err != nil {
+		return nil, fmt.Errorf("failed to confirm subscriber: %w", err)
+	}
+	return subscriber, nil
+}
+
+// Unsubscribe stops emails for the subscription the token belongs to. The
+// link keeps working after use
+func (s *Service) Unsubscribe(ctx context.Context, token string) error {
+	subscriber, err := s.subscriberByToken(ctx, token)
+	if err != nil {
+		return err
+	}
+	if err := s.db.WithContext(ctx).Model(subscriber).Update("status", SubscriberUnsubscribed).Error; err != nil {
+		return fmt.Errorf("failed to unsubscribe: %w", err)
+	}
+	return nil
+}
+
+// GetSubscribers lists the email subscribers of a share owned by the user
+func (s *Service) GetSubscribers(ctx context.Context, userID uint, shareToken string) ([]EmailSubscriber, error) {
+	share, err := s.ownedShare(ctx, userID, shareToken)
+	if err != nil {
+		return nil, err
+	}
+
+	var subscribers []EmailSubscriber
+	if err := s.db.WithContext(ctx).Where("share_id = ?", share.ID).
+		Order("created_at DESC").Find(&subscribers).Error; err != nil {
+		return nil, fmt.Errorf("failed to get subscribers: %w", err)
+	}
+	return subscribers, nil
+}
+
+// RemoveSubscriber deletes a subscriber of a share owned by the user. The
+// address may subscribe again, confirming anew
+func (s *Service) RemoveSubscriber(ctx context.Context, userID uint, shareToken string, subscriberID uint) error {
+	share, err := s.ownedShare(ctx, userID, shareToken)
+	if err != nil {
+		return err
+	}
+
+	result := s.db.WithContext(ctx).Where("id = ? AND share_id = ?", subscriberID, share.ID).Delete(&EmailSubscriber{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to remove subscriber: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrSubscriberNotFound
+	}
+	return nil
+}
+
+// SendSubscriptionDigests emails confirmed subscribers the bookmarks added
+// to their collections since their last email, at most once per digest
+// interval. It returns the number of emails sent; a failed email is retried
+// on the next run
+func (s *Service) SendSubscriptionDigests(ctx context.Context) (int, error) {
+	if s.mailer == nil || s.subscriptions.DigestInterval <= 0 {
+		return 0, nil
+	}
+
+	now := time.Now()
+	var shares []CollectionShare
+	if err := s.db.WithContext(ctx).
+		Where("subscriptions_enabled = ? AND is_active = ? AND share_type = ?", true, true, ShareTypePublic).
+		Where("(password = '' OR password IS NULL) AND (expires_at IS NULL OR expires_at > ?)", now).
+		Find(&shares).Error; err != nil {
+		return 0, fmt.Errorf("failed to find subscribable shares: %w", err)
+	}
+
+	sent := 0
+	var failures []string
+	for i := range shares {
+		if err := ctx.Err(); err != nil {
+			return sent, err
+		}
+		count, err := s.sendShareDigests(ctx, &shares[i], now)
+		sent += count
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("share %d: %v", shares[i].ID, err))
+		}
+	}
+	if len(failures) > 0 {
+		return sent, fmt.Errorf("failed to send subscription digests: %s", strings.Join(failures, "; "))
+	}
+	return sent, nil
+}
+
+// sendShareDigests emails the due subscribers of one share. Bookmarks count
+// as added when they were created, as in the collection feed
+func (s *Service) sendShareDigests(ctx context.Context, share *CollectionShare, now time.Time) (int, error) {
+	due := now.Add(-time.Duration(s.subscriptions.DigestInterval) * time.Minute)
+	var subscribers []EmailSubscriber
+	if err := s.db.WithContext(ctx).
+		Where("share_id = ? AND status = ? AND last_notified_at <= ?", share.ID, SubscriberConfirmed, due).
+		Order("last_notified_at").Find(&subscribers).Error; err != nil {
+		return 0, fmt.Errorf("failed to find subscribers: %w", err)
+	}
+	if len(subscribers) == 0 {
+		return 0, nil
+	}
+
+	// Ordered by watermark, so the first subscriber needs the most bookmarks
+	var bookmarks []database.Bookmark
+	if err := s.db.WithContext(ctx).
+		Joins("JOIN bookmark_collections ON bookmarks.id = bookmark_collections.bookmark_id").
+		Where("bookmark_collections.collection_id = ?", share.CollectionID).
+		Where("bookmarks.created_at > ? AND bookmarks.created_at <= ?", *subscribers[0].LastNotifiedAt, now).
+		Order("bookmarks.created_at DESC").
+		Find(&bookmarks).Error; err != nil {
+		return 0, fmt.Errorf("failed to get new bookmarks: %w", err)
+	}
+	if len(bookmarks) == 0 {
+		return 0, nil
+	}
+
+	title, err := s.shareTitle(ctx, share)
+	if err != nil {
+		return 0, err
+	}
+
+	// A failed address does not hold back the other subscribers
+	sent, failed := 0, 0
+	var lastErr error
+	for i := range subscribers {
+		subscriber := &subscribers[i]
+		var added []database.Bookmark
+		for _, bookmark := range bookmarks {
+			if bookmark.CreatedAt.After(*subscriber.LastNotifiedAt) {
+				added = append(added, bookmark)
+			}
+		}
+		if len(added) == 0 {
+			continue
+		}
+
+		if err := s.mailer.Send(ctx, s.digestMessage(share, subscriber, title, added)); err != nil {
+			failed, lastErr = failed+1, err
+			continue
+		}
+		sent++
+		if err := s.db.WithContext(ctx).Model(subscriber).Update("last_notified_at", now).Error; err != nil {
+			return sent, fmt.Errorf("failed to update subscriber %d: %w", subscriber.ID, err)
+		}
+	}
+	if failed > 0 {
+		return sent, fmt.Errorf("%d of %d emails failed: %w", failed, failed+sent, lastErr)
+	}
+	return sent, nil
+}
+
+// digestMessage lists the newest of the added bookmarks with links back to
+// the share and to unsubscribe
+func (s *Service) digestMessage(share *CollectionShare, subscriber *EmailSubscriber, title string, added []database.Bookmark) *mail.Message {
+	var body strings.Builder
+	if len(added) == 1 {
+		fmt.Fprintf(&body, "A bookmark was added to %s:\n\n", title)
+	} else {
+		fmt.Fprintf(&body, "%d bookmarks were added to %s:\n\n", len(added), title)
+	}
+	for i, bookmark := range added {
+		if i == digestMaxItems {
+			fmt.Fprintf(&body, "…and %d more\n\n", len(added)-digestMaxItems)
+			break
+		}
+		itemTitle := bookmark.Title
+		if itemTitle == "" {
+			itemTitle = bookmark.URL
+		}
+		fmt.Fprintf(&body, "- %s\n  %s\n\n", itemTitle, bookmark.URL)
+	}
+
+	unsubscribeURL := s.subscriptionURL(subscriber.Token, "unsubscribe")
+	fmt.Fprintf(&body, "View the collection: %s/shared/%s\n\n", s.baseURL, share.ShareToken)
+	fmt.Fprintf(&body, "You receive this email because you subscribed to %s.\nUnsubscribe: %s\n", title, unsubscribeURL)
+
+	return &mail.Message{
+		To:      subscriber.Email,
+		Subject: "New in " + title,
+		Body:    body.String(),
+		Headers: map[string]string{
+			// One-click unsubscribe (RFC 8058) for mail clients
+			"List-Unsubscribe":      "<" + unsubscribeURL + ">",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		},
+	}
+}
+
+// subscriberByToken finds the subscriber a confirmation or unsubscribe link
+// was sent to
+func (s *Service) subscriberByToken(ctx context.Context, token string) (*EmailSubscriber, error) {
+	if token == "" {
+		return nil, ErrSubscriberNotFound
+	}
+	var subscriber EmailSubscriber
+	if err := s.db.WithContext(ctx).Where("token = ?", token).First(&subscriber).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrSubscriberNotFound
+		}
+		return nil, fmt.Errorf("failed to find subscriber: %w", err)
+	}
+	return &subscriber, nil
+}
+
+// ownedShare finds a share by token, requiring the user to own it
+func (s *Service) ownedShare(ctx context.Context, userID uint, token string) (*CollectionShare, error) {
+	var share CollectionShare
+	if err := s.db.WithContext(ctx).Where("share_token = ?", token).First(&share).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrShareNotFound
+		}
+		return nil, fmt.Errorf("failed to find share: %w", err)
+	}
+	if share.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+	return &share, nil
+}
+
+// shareTitle names the share in emails, falling back to its collection
+func (s *Service) shareTitle(ctx context.Context, share *CollectionShare) (string, error) {
+	if share.Title != "" {
+		return share.Title, nil
+	}
+	var collection database.Collection
+	if err := s.db.WithContext(ctx).Select("name").First(&collection, share.CollectionID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return "", ErrCollectionNotFound
+		}
+		return "", fmt.Errorf("failed to find collection: %w", err)
+	}
+	return collection.Name, nil
+}
+
+func (s *Service) subscriptionURL(token, action string) string {
+	return s.baseURL + "/api/v1/subscriptions/" + token + "/" + action
+}
+
+func (s *Service) confirmCooldown() time.Duration {
+	return time.Duration(s.subscriptions.ConfirmCooldown) * time.Minute
+}
+
+// SubscribeRequest is a visitor's request to subscribe to a public share
+type SubscribeRequest struct {
+	Email string `json:"email" binding:"required,max=255"`
+}
+
+// RegisterSubscriptionRoutes registers the public subscribe, confirm and
+// unsubscribe routes. They should be rate limited per client
+func (h *Handler) RegisterSubscriptionRoutes(router *gin.RouterGroup) {
+	router.POST("/shares/:token/subscribe", h.Subscribe)
+	router.GET("/subscriptions/:token/confirm", h.ConfirmSubscription)
+	router.GET("/subscriptions/:token/unsubscribe", h.Unsubscribe)
+	router.POST("/subscriptions/:token/unsubscribe", h.Unsubscribe)
+}
+
+// RegisterSubscriberRoutes registers the share owner's subscriber management routes
+func (h *Handler) RegisterSubscriberRoutes(router *gin.RouterGroup) {
+	router.GET("/shares/:token/subscribers", h.GetSubscribers)
+	router.DELETE("/shares/:token/subscribers/:id", h.RemoveSubscriber)
+}
+
+// Subscribe subscribes an email address to a public share
+// @Summary Subscribe to a shared collection
+// @Description Send a confirmation link to the address; once confirmed it receives an email when bookmarks are added. The response is the same whether or not an email was sent
+// @Tags sharing
+// @Accept json
+// @Produce json
+// @Param token path string true "Share token"
+// @Param request body SubscribeRequest true "Subscribe request"
+// @Success 202 {object} utils.APIResponse
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 410 {object} utils.ErrorResponse "Share expired"
+// @Failure 429 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/shares/{token}/subscribe [post]
+func (h *Handler) Subscribe(c *gin.Context) {
+	var request SubscribeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "invalid_request", "invalid request format", map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	if err := h.service.Subscribe(c.Request.Context(), c.Param("token"), request.Email); err != nil {
+		switch err {
+		case ErrInvalidEmail:
+			utils.ErrorResponse(c, http.StatusBadRequest, "invalid_email", "invalid email address", nil)
+		// Shares without subscriptions answer like missing ones, as feeds do
+		case ErrShareNotFound, ErrCollectionNotFound, ErrSubscriptionsDisabled:
+			utils.ErrorResponse(c, http.StatusNotFound, "share_not_found", "share not found", nil)
+		case ErrShareExpired:
+			utils.ErrorResponse(c, http.StatusGone, "share_expired", "share has expired", nil)
+		case ErrShareInactive:
+			utils.ErrorResponse(c, http.StatusGone, "share_inactive", "share is inactive", nil)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, "internal_error", "failed to subscribe", nil)
+		}
+		return
+	}
+
+	c.JSON(http.StatusAccepted, utils.APIResponse{
+		Success: true,
+		Message: "check your inbox to confirm the subscription",
+	})
+}
+
+// ConfirmSubscription confirms an email subscription
+// @Summary Confirm a subscription
+// @Description Confirm the subscription the emailed link was sent for
+// @Tags sharing
+// @Produce json
+// @Param token path string true "Subscriber token"
+// @Success 200 {object} utils.APIResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 410 {object} utils.ErrorResponse "Link expired"
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/subscriptions/{token}/confirm [get]
+func (h *Handler) ConfirmSubscription(c *gin.Context) {
+	if _, err := h.service.ConfirmSubscription(c.Request.Context(), c.Param("token")); err != nil {
+		switch err {
+		case ErrSubscriberNotFound:
+			utils.ErrorResponse(c, http.StatusNotFound, "subscription_not_found", "subscription not found", nil)
+		case ErrConfirmationExpired:
+			utils.ErrorResponse(c, http.StatusGone, "confirmation_expired", "confirmation link has expired, subscribe again", nil)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, "internal_error", "failed to confirm subscription", nil)
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, nil, "subscription confirmed")
+}
+
+// Unsubscribe ends an email subscription
+// @Summary Unsubscribe
+// @Description Stop emails for a subscription. POST serves one-click unsubscribe from mail clients
+// @Tags sharing
+// @Produce json
+// @Param token path string true "Subscriber token"
+// @Success 200 {object} utils.APIResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/subscriptions/{token}/unsubscribe [get]
+// @Router /api/v1/subscriptions/{token}/unsubscribe [post]
+func (h *Handler) Unsubscribe(c *gin.Context) {
+	if err := h.service.Unsubscribe(c.Request.Context(), c.Param("token")); err != nil {
+		if err == ErrSubscriberNotFound {
+			utils.ErrorResponse(c, http.StatusNotFound, "subscription_not_found", "subscription not found", nil)
+		} else {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "internal_error", "failed to unsubscribe", nil)
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, nil, "unsubscribed")
+}
+
+// GetSubscribers lists the email subscribers of a share
+// @Summary List share subscribers
+// @Description List the email subscribers of a share owned by the current user, pending and unsubscribed ones included
+// @Tags sharing
+// @Produce json
+// @Param token path string true "Share token"
+// @Success 200 {array} EmailSubscriber
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/shares/{token}/subscribers [get]
+func (h *Handler) GetSubscribers(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	subscribers, err := h.service.GetSubscribers(c.Request.Context(), userID, c.Param("token"))
+	if err != nil {
+		h.subscriberError(c, err, "failed to get subscribers")
+		return
+	}
+
+	utils.SuccessResponse(c, subscribers, "subscribers retrieved successfully")
+}
+
+// RemoveSubscriber removes an email subscriber from a share
+// @Summary Remove a share subscriber
+// @Description Delete a subscriber of a share owned by the current user
+// @Tags sharing
+// @Produce json
+// @Param token path string true "Share token"
+// @Param id path int true "Subscriber ID"
+// @Success 200 {object} utils.APIResponse
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/shares/{token}/subscribers/{id} [delete]
+func (h *Handler) RemoveSubscriber(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	subscriberID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "invalid_id", "invalid subscriber ID", nil)
+		return
+	}
+
+	if err := h.service.RemoveSubscriber(c.Request.Context(), userID, c.Param("token"), uint(subscriberID)); err != nil {
+		h.subscriberError(c, err, "failed to remove subscriber")
+		return
+	}
+
+	utils.SuccessResponse(c, nil, "subscriber removed successfully")
+}
+
+func (h *Handler) subscriberError(c *gin.Context, err error, message string) {
+	switch err {
+	case ErrShareNotFound:
+		utils.ErrorResponse(c, http.StatusNotFound, "share_not_found", "share not found", nil)
+	case ErrSubscriberNotFound:
+		utils.ErrorResponse(c, http.StatusNotFound, "subscriber_not_found", "subscriber not found", nil)
+	case ErrUnauthorized:
+		utils.ErrorResponse(c, http.StatusForbidden, "unauthorized", "unauthorized access", nil)
+	default:
+		respondError(c, err, message)
+	}
+}
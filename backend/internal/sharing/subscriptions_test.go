@@ -0,0 +1,193 @@
+package sharing
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"time"
+
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/mail"
+)
+
+// recordingMailer captures the emails sent by the service
+type recordingMailer struct {
+	sent []*mail.Message
+	fail string // recipient whose emails fail
+}
+
+func (m *recordingMailer) Send(ctx context.Context, message *mail.Message) error {
+	if message.To == m.fail {
+		return errors.New("mailbox unavailable")
+	}
+	m.sent = append(m.sent, message)
+	return nil
+}
+
+var subscriptionLink = regexp.MustCompile(`/api/v1/subscriptions/([0-9a-f]+)/(confirm|unsubscribe)`)
+
+// linkToken extracts the subscriber token from the link in an email
+func linkToken(message *mail.Message) string {
+	match := subscriptionLink.FindStringSubmatch(message.Body)
+	if match == nil {
+		return ""
+	}
+	return match[1]
+}
+
+func (suite *SharingServiceTestSuite) createSubscribableShare(owner *database.User) (*database.Collection, *CollectionShare) {
+	collection := suite.factory.Collection(owner.ID, func(c *database.Collection) { c.Name = "Go links" })
+	share := &CollectionShare{
+		CollectionID:         collection.ID,
+		UserID:               owner.ID,
+		ShareType:            ShareTypePublic,
+		Permission:           PermissionView,
+		ShareToken:           "subscribe-token",
+		IsActive:             true,
+		SubscriptionsEnabled: true,
+	}
+	suite.Require().NoError(suite.db.Create(share).Error)
+	return collection, share
+}
+
+func (suite *SharingServiceTestSuite) withMailer(cfg config.SubscriptionsConfig) *recordingMailer {
+	mailer := &recordingMailer{}
+	suite.service.SetMailer(mailer, cfg)
+	suite.T().Cleanup(func() { suite.service.SetMailer(nil, config.SubscriptionsConfig{}) })
+	return mailer
+}
+
+func (suite *SharingServiceTestSuite) TestSubscribeDoubleOptIn() {
+	ctx := context.Background()
+	owner := suite.createUser("owner")
+	_, share := suite.createSubscribableShare(owner)
+	mailer := suite.withMailer(config.SubscriptionsConfig{DigestInterval: 60, ConfirmCooldown: 15, ConfirmTTL: 48})
+
+	// When: A visitor subscribes
+	suite.Require().NoError(suite.service.Subscribe(ctx, "subscribe-token", " Reader@Example.com "))
+
+	// Then: A confirmation link is emailed and the subscriber waits for it
+	suite.Require().Len(mailer.sent, 1)
+	suite.Equal("reader@example.com", mailer.sent[0].To)
+	suite.Equal("Confirm your subscription to Go links", mailer.sent[0].Subject)
+	token := linkToken(mailer.sent[0])
+	suite.Require().NotEmpty(token)
+
+	subscribers, err := suite.service.GetSubscribers(ctx, owner.ID, "subscribe-token")
+	suite.Require().NoError(err)
+	suite.Require().Len(subscribers, 1)
+	suite.Equal(SubscriberPending, subscribers[0].Status)
+
+	// And: Asking again within the cooldown sends nothing more
+	suite.Require().NoError(suite.service.Subscribe(ctx, "subscribe-token", "reader@example.com"))
+	suite.Len(mailer.sent, 1)
+
+	// When: The link is followed
+	subscriber, err := suite.service.ConfirmSubscription(ctx, token)
+
+	// Then: The subscription is confirmed, and confirmed addresses are not mailed again
+	suite.Require().NoError(err)
+	suite.Equal(SubscriberConfirmed, subscriber.Status)
+	suite.NotNil(subscriber.ConfirmedAt)
+	suite.Require().NoError(suite.service.Subscribe(ctx, "subscribe-token", "reader@example.com"))
+	suite.Len(mailer.sent, 1)
+
+	// And: Only the owner sees the subscribers
+	_, err = suite.service.GetSubscribers(ctx, suite.createUser("other").ID, "subscribe-token")
+	suite.Equal(ErrUnauthorized, err)
+
+	// And: Invalid addresses and shares without subscriptions are rejected
+	suite.Equal(ErrInvalidEmail, suite.service.Subscribe(ctx, "subscribe-token", "not-an-email"))
+	suite.Require().NoError(suite.db.Model(share).Update("subscriptions_enabled", false).Error)
+	suite.Equal(ErrSubscriptionsDisabled, suite.service.Subscribe(ctx, "subscribe-token", "new@example.com"))
+}
+
+func (suite *SharingServiceTestSuite) TestConfirmSubscriptionExpired() {
+	ctx := context.Background()
+	_, share := suite.createSubscribableShare(suite.createUser("owner"))
+	suite.withMailer(config.SubscriptionsConfig{DigestInterval: 60, ConfirmTTL: 48})
+
+	subscriber := &EmailSubscriber{
+		ShareID:            share.ID,
+		Email:              "late@example.com",
+		Status:             SubscriberPending,
+		Token:              "expired-token",
+		ConfirmationSentAt: time.Now().Add(-72 * time.Hour),
+	}
+	suite.Require().NoError(suite.db.Create(subscriber).Error)
+
+	_, err := suite.service.ConfirmSubscription(ctx, "expired-token")
+	suite.Equal(ErrConfirmationExpired, err)
+	_, err = suite.service.ConfirmSubscription(ctx, "unknown-token")
+	suite.Equal(ErrSubscriberNotFound, err)
+}
+
+func (suite *SharingServiceTestSuite) TestSendSubscriptionDigests() {
+	ctx := context.Background()
+	owner := suite.createUser("owner")
+	collection, share := suite.createSubscribableShare(owner)
+	mailer := suite.withMailer(config.SubscriptionsConfig{DigestInterval: 60})
+
+	twoHoursAgo := time.Now().Add(-2 * time.Hour)
+	recent := time.Now().Add(-10 * time.Minute)
+	subscribe := func(email, token string, status string, notified time.Time) *EmailSubscriber {
+		subscriber := &EmailSubscriber{ShareID: share.ID, Email: email, Status: status, Token: token, LastNotifiedAt: &notified}
+		suite.Require().NoError(suite.db.Create(subscriber).Error)
+		return subscriber
+	}
+	reader := subscribe("reader@example.com", "aa11", SubscriberConfirmed, twoHoursAgo)
+	subscribe("recent@example.com", "bb22", SubscriberConfirmed, recent) // emailed within the interval
+	subscribe("pending@example.com", "cc33", SubscriberPending, twoHoursAgo)
+	subscribe("broken@example.com", "dd44", SubscriberConfirmed, twoHoursAgo)
+	mailer.fail = "broken@example.com"
+
+	old := suite.factory.Bookmark(owner.ID, func(b *database.Bookmark) {
+		b.URL = "https://example.com/old"
+		b.CreatedAt = time.Now().Add(-3 * time.Hour)
+	})
+	added := suite.factory.Bookmark(owner.ID, func(b *database.Bookmark) {
+		b.URL = "https://example.com/new"
+		b.Title = "New post"
+		b.CreatedAt = time.Now().Add(-time.Hour)
+	})
+	suite.factory.AddToCollection(collection, old, added)
+
+	// When: Digests are sent
+	sent, err := suite.service.SendSubscriptionDigests(ctx)
+
+	// Then: Only the due, confirmed subscriber is emailed the new bookmark,
+	// and the failed address is reported without blocking it
+	suite.Error(err)
+	suite.Equal(1, sent)
+	suite.Require().Len(mailer.sent, 1)
+	message := mailer.sent[0]
+	suite.Equal("reader@example.com", message.To)
+	suite.Equal("New in Go links", message.Subject)
+	suite.Contains(message.Body, "New post\n  https://example.com/new")
+	suite.NotContains(message.Body, "https://example.com/old")
+	suite.Equal("<http://localhost:3000/api/v1/subscriptions/aa11/unsubscribe>", message.Headers["List-Unsubscribe"])
+
+	// And: Nothing is sent twice
+	mailer.fail = ""
+	mailer.sent = nil
+	suite.Require().NoError(suite.db.Model(&EmailSubscriber{}).Where("email = ?", "broken@example.com").
+		Update("status", SubscriberUnsubscribed).Error)
+	sent, err = suite.service.SendSubscriptionDigests(ctx)
+	suite.Require().NoError(err)
+	suite.Zero(sent)
+
+	// When: The reader unsubscribes through the link
+	suite.Require().NoError(suite.service.Unsubscribe(ctx, linkToken(message)))
+
+	// Then: The owner sees it and may remove the subscriber
+	subscribers, err := suite.service.GetSubscribers(ctx, owner.ID, "subscribe-token")
+	suite.Require().NoError(err)
+	for _, subscriber := range subscribers {
+		if subscriber.ID == reader.ID {
+			suite.Equal(SubscriberUnsubscribed, subscriber.Status)
+		}
+	}
+	suite.Require().NoError(suite.service.RemoveSubscriber(ctx, owner.ID, "subscribe-token", reader.ID))
+	suite.Equal(ErrSubscriberNotFound, suite.service.RemoveSubscriber(ctx, owner.ID, "subscribe-token", reader.ID))
+}
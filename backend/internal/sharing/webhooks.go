@@ -0,0 +1,305 @@
+package sharing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/internal/automation"
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/utils"
+)
+
+// CollectionWebhooks stores webhook endpoints scoped to a collection and
+// delivers its events through the automation webhook pipeline
+type CollectionWebhooks interface {
+	CreateWebhookEndpoint(userID string, req automation.WebhookEndpointRequest) (*automation.WebhookEndpoint, error)
+	GetCollectionWebhookEndpoints(userID string, collectionID uint) ([]automation.WebhookEndpoint, error)
+	DeleteWebhookEndpoint(userID string, id uint) error
+	TriggerCollectionWebhook(ctx context.Context, event automation.WebhookEvent, ownerID string, collectionID uint, data interface{}) error
+	TriggerShareViewed(ctx context.Context, ownerID string, collectionID uint, viewCount int64, data interface{}) error
+}
+
+// SetWebhooks enables collection-scoped webhooks
+func (s *Service) SetWebhooks(webhooks CollectionWebhooks) {
+	s.webhooks = webhooks
+}
+
+// CollectionWebhookRequest configures a webhook endpoint for one collection
+type CollectionWebhookRequest struct {
+	Name    string            `json:"name" binding:"required,max=100"`
+	URL     string            `json:"url" binding:"required,url"`
+	Events  []string          `json:"events" binding:"required,min=1"`
+	Headers map[string]string `json:"headers"`
+	// ViewThreshold fires share.viewed every this many views
+	ViewThreshold int `json:"view_threshold" binding:"min=0"`
+}
+
+// CreateCollectionWebhook adds a webhook endpoint that only receives the
+// events of a collection the user owns
+func (s *Service) CreateCollectionWebhook(ctx context.Context, userID uint, collectionID uint, request *CollectionWebhookRequest) (*automation.WebhookEndpoint, error) {
+	if s.webhooks == nil {
+		return nil, ErrWebhooksUnavailable
+	}
+	for _, event := range request.Events {
+		if !automation.IsCollectionWebhookEvent(event) {
+			return nil, ErrInvalidWebhookEvent
+		}
+	}
+	if _, err := s.ownedCollection(ctx, userID, collectionID); err != nil {
+		return nil, err
+	}
+
+	return s.webhooks.CreateWebhookEndpoint(ownerKey(userID), automation.WebhookEndpointRequest{
+		Name:          request.Name,
+		URL:           request.URL,
+		Events:        request.Events,
+		Active:        true,
+		Headers:       request.Headers,
+		CollectionID:  &collectionID,
+		ViewThreshold: request.ViewThreshold,
+	})
+}
+
+// GetCollectionWebhooks lists the webhook endpoints scoped to a collection the user owns
+func (s *Service) GetCollectionWebhooks(ctx context.Context, userID uint, collectionID uint) ([]automation.WebhookEndpoint, error) {
+	if s.webhooks == nil {
+		return nil, ErrWebhooksUnavailable
+	}
+	if _, err := s.ownedCollection(ctx, userID, collectionID); err != nil {
+		return nil, err
+	}
+	return s.webhooks.GetCollectionWebhookEndpoints(ownerKey(userID), collectionID)
+}
+
+// DeleteCollectionWebhook removes a webhook endpoint of a collection the user owns
+func (s *Service) DeleteCollectionWebhook(ctx context.Context, userID uint, collectionID uint, webhookID uint) error {
+	endpoints, err := s.GetCollectionWebhooks(ctx, userID, collectionID)
+	if err != nil {
+		return err
+	}
+	for _, endpoint := range endpoints {
+		if endpoint.ID == webhookID {
+			return s.webhooks.DeleteWebhookEndpoint(ownerKey(userID), webhookID)
+		}
+	}
+	return ErrWebhookNotFound
+}
+
+// collaboratorJoined tells the collection's webhooks about an accepted
+// invitation. Deliveries run after the request ends, so they do not use its
+// context
+func (s *Service) collaboratorJoined(ctx context.Context, collaborator *CollectionCollaborator) {
+	if s.webhooks == nil {
+		return
+	}
+	var collection database.Collection
+	if err := s.db.WithContext(ctx).Select("id", "user_id").First(&collection, collaborator.CollectionID).Error; err != nil {
+		s.logger.Warn("Failed to find collection for webhook", zap.Uint("collection_id", collaborator.CollectionID), zap.Error(err))
+		return
+	}
+	err := s.webhooks.TriggerCollectionWebhook(context.Background(), automation.WebhookEventCollectionCollaboratorJoined, ownerKey(collection.UserID), collection.ID,
+		map[string]interface{}{
+			"collection_id":   collection.ID,
+			"collaborator_id": collaborator.ID,
+			"user_id":         collaborator.UserID,
+			"permission":      collaborator.Permission,
+		})
+	if err != nil {
+		s.logger.Warn("Failed to trigger collaborator webhook", zap.Uint("collection_id", collection.ID), zap.Error(err))
+	}
+}
+
+// shareViewed passes a share's new view count to the collection's webhooks,
+// which fire when it reaches their threshold. Like collaboratorJoined it
+// delivers outside the request's context
+func (s *Service) shareViewed(ctx context.Context, shareID uint) {
+	if s.webhooks == nil {
+		return
+	}
+	var share CollectionShare
+	if err := s.db.WithContext(ctx).Select("id", "collection_id", "user_id", "view_count").First(&share, shareID).Error; err != nil {
+		s.logger.Warn("Failed to find share for webhook", zap.Uint("share_id", shareID), zap.Error(err))
+		return
+	}
+	err := s.webhooks.TriggerShareViewed(context.Background(), ownerKey(share.UserID), share.CollectionID, share.ViewCount,
+		map[string]interface{}{
+			"collection_id": share.CollectionID,
+			"share_id":      share.ID,
+			"view_count":    share.ViewCount,
+		})
+	if err != nil {
+		s.logger.Warn("Failed to trigger share view webhook", zap.Uint("share_id", share.ID), zap.Error(err))
+	}
+}
+
+// ownedCollection finds a collection, requiring the user to own it
+func (s *Service) ownedCollection(ctx context.Context, userID uint, collectionID uint) (*database.Collection, error) {
+	var collection database.Collection
+	if err := s.db.WithContext(ctx).First(&collection, collectionID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrCollectionNotFound
+		}
+		return nil, fmt.Errorf("failed to find collection: %w", err)
+	}
+	if collection.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+	return &collection, nil
+}
+
+// ownerKey is a user's ID as the automation module stores it
+func ownerKey(userID uint) string {
+	return strconv.FormatUint(uint64(userID), 10)
+}
+
+// RegisterCollectionWebhookRoutes registers the collection owner's webhook routes
+func (h *Handler) RegisterCollectionWebhookRoutes(router *gin.RouterGroup) {
+	router.POST("/collections/:id/webhooks", h.CreateCollectionWebhook)
+	router.GET("/collections/:id/webhooks", h.GetCollectionWebhooks)
+	router.DELETE("/collections/:id/webhooks/:webhook_id", h.DeleteCollectionWebhook)
+}
+
+// CreateCollectionWebhook adds a webhook endpoint to a collection
+// @Summary Create a collection webhook
+// @Description Add a webhook endpoint that only receives events of one collection: collection.bookmark_added, collection.bookmark_removed, collection.collaborator_joined and share.viewed, fired every view_threshold views
+// @Tags sharing
+// @Accept json
+// @Produce json
+// @Param id path int true "Collection ID"
+// @Param request body CollectionWebhookRequest true "Webhook request"
+// @Success 201 {object} automation.WebhookEndpoint
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/collections/{id}/webhooks [post]
+func (h *Handler) CreateCollectionWebhook(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+	collectionID, ok := collectionIDParam(c)
+	if !ok {
+		return
+	}
+
+	var request CollectionWebhookRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "invalid_request", "invalid request body", map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	endpoint, err := h.service.CreateCollectionWebhook(c.Request.Context(), userID, collectionID, &request)
+	if err != nil {
+		h.webhookError(c, err, "failed to create webhook")
+		return
+	}
+
+	c.JSON(http.StatusCreated, utils.APIResponse{
+		Success: true,
+		Data:    endpoint,
+		Message: "webhook created successfully",
+	})
+}
+
+// GetCollectionWebhooks lists the webhook endpoints of a collection
+// @Summary List collection webhooks
+// @Description List the webhook endpoints scoped to a collection owned by the current user
+// @Tags sharing
+// @Produce json
+// @Param id path int true "Collection ID"
+// @Success 200 {array} automation.WebhookEndpoint
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/collections/{id}/webhooks [get]
+func (h *Handler) GetCollectionWebhooks(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+	collectionID, ok := collectionIDParam(c)
+	if !ok {
+		return
+	}
+
+	endpoints, err := h.service.GetCollectionWebhooks(c.Request.Context(), userID, collectionID)
+	if err != nil {
+		h.webhookError(c, err, "failed to get webhooks")
+		return
+	}
+
+	utils.SuccessResponse(c, endpoints, "webhooks retrieved successfully")
+}
+
+// DeleteCollectionWebhook removes a webhook endpoint from a collection
+// @Summary Delete a collection webhook
+// @Description Delete a webhook endpoint scoped to a collection owned by the current user
+// @Tags sharing
+// @Produce json
+// @Param id path int true "Collection ID"
+// @Param webhook_id path int true "Webhook ID"
+// @Success 200 {object} utils.APIResponse
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/collections/{id}/webhooks/{webhook_id} [delete]
+func (h *Handler) DeleteCollectionWebhook(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+	collectionID, ok := collectionIDParam(c)
+	if !ok {
+		return
+	}
+	webhookID, err := strconv.ParseUint(c.Param("webhook_id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "invalid_id", "invalid webhook ID", nil)
+		return
+	}
+
+	if err := h.service.DeleteCollectionWebhook(c.Request.Context(), userID, collectionID, uint(webhookID)); err != nil {
+		h.webhookError(c, err, "failed to delete webhook")
+		return
+	}
+
+	utils.SuccessResponse(c, nil, "webhook deleted successfully")
+}
+
+func collectionIDParam(c *gin.Context) (uint, bool) {
+	collectionID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "invalid_collection_id", "invalid collection ID", nil)
+		return 0, false
+	}
+	return uint(collectionID), true
+}
+
+func (h *Handler) webhookError(c *gin.Context, err error, message string) {
+	switch err {
+	case ErrCollectionNotFound:
+		utils.ErrorResponse(c, http.StatusNotFound, "collection_not_found", "collection not found", nil)
+	case ErrWebhookNotFound:
+		utils.ErrorResponse(c, http.StatusNotFound, "webhook_not_found", "webhook not found", nil)
+	case ErrInvalidWebhookEvent:
+		utils.ErrorResponse(c, http.StatusBadRequest, "invalid_event", err.Error(), nil)
+	case ErrUnauthorized:
+		utils.ErrorResponse(c, http.StatusForbidden, "unauthorized", "unauthorized access", nil)
+	case ErrWebhooksUnavailable:
+		utils.ErrorResponse(c, http.StatusServiceUnavailable, "webhooks_unavailable", err.Error(), nil)
+	default:
+		respondError(c, err, message)
+	}
+}
@@ -0,0 +1,102 @@
+package sharing
+
+import (
+	"context"
+	"fmt"
+
+	"bookmark-sync-service/backend/internal/automation"
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+func (suite *SharingServiceTestSuite) withWebhooks() *automation.Service {
+	webhooks := automation.NewServiceForTesting(suite.db)
+	suite.service.SetWebhooks(webhooks)
+	suite.T().Cleanup(func() { suite.service.SetWebhooks(nil) })
+	return webhooks
+}
+
+// deliveredEvents lists the events recorded for a webhook endpoint
+func (suite *SharingServiceTestSuite) deliveredEvents(webhooks *automation.Service, owner *database.User, endpointID uint) []automation.WebhookEvent {
+	deliveries, err := webhooks.GetWebhookDeliveries(fmt.Sprint(owner.ID), endpointID)
+	suite.Require().NoError(err)
+	events := make([]automation.WebhookEvent, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		events = append(events, delivery.Event)
+	}
+	return events
+}
+
+func (suite *SharingServiceTestSuite) TestCollectionWebhookLifecycle() {
+	ctx := context.Background()
+	owner := suite.createUser("owner")
+	stranger := suite.createUser("stranger")
+	collection := suite.factory.Collection(owner.ID)
+	suite.withWebhooks()
+
+	request := &CollectionWebhookRequest{
+		Name:          "Team channel",
+		URL:           "https://hooks.example.com/team",
+		Events:        []string{"collection.bookmark_added", "share.viewed"},
+		ViewThreshold: 10,
+	}
+	endpoint, err := suite.service.CreateCollectionWebhook(ctx, owner.ID, collection.ID, request)
+	suite.Require().NoError(err)
+	suite.Require().NotNil(endpoint.CollectionID)
+	suite.Equal(collection.ID, *endpoint.CollectionID)
+	suite.Equal(10, endpoint.ViewThreshold)
+
+	// Only the owner manages the collection's webhooks
+	_, err = suite.service.CreateCollectionWebhook(ctx, stranger.ID, collection.ID, request)
+	suite.Equal(ErrUnauthorized, err)
+	_, err = suite.service.GetCollectionWebhooks(ctx, stranger.ID, collection.ID)
+	suite.Equal(ErrUnauthorized, err)
+
+	// Account-wide events cannot be scoped to a collection
+	_, err = suite.service.CreateCollectionWebhook(ctx, owner.ID, collection.ID, &CollectionWebhookRequest{
+		Name: "Everything", URL: "https://hooks.example.com/all", Events: []string{"bookmark.created"},
+	})
+	suite.Equal(ErrInvalidWebhookEvent, err)
+
+	endpoints, err := suite.service.GetCollectionWebhooks(ctx, owner.ID, collection.ID)
+	suite.NoError(err)
+	suite.Len(endpoints, 1)
+
+	other := suite.factory.Collection(owner.ID)
+	suite.Equal(ErrWebhookNotFound, suite.service.DeleteCollectionWebhook(ctx, owner.ID, other.ID, endpoint.ID))
+	suite.NoError(suite.service.DeleteCollectionWebhook(ctx, owner.ID, collection.ID, endpoint.ID))
+	endpoints, err = suite.service.GetCollectionWebhooks(ctx, owner.ID, collection.ID)
+	suite.NoError(err)
+	suite.Empty(endpoints)
+}
+
+func (suite *SharingServiceTestSuite) TestCollectionWebhooksReceiveShareEvents() {
+	ctx := context.Background()
+	owner := suite.createUser("owner")
+	collaborator := suite.createUser("collaborator")
+	collection := suite.factory.Collection(owner.ID)
+	share := suite.factory.Share(collection)
+	webhooks := suite.withWebhooks()
+
+	endpoint, err := suite.service.CreateCollectionWebhook(ctx, owner.ID, collection.ID, &CollectionWebhookRequest{
+		Name:          "Team channel",
+		URL:           "https://hooks.example.com/team",
+		Events:        []string{"collection.collaborator_joined", "share.viewed"},
+		ViewThreshold: 3,
+	})
+	suite.Require().NoError(err)
+
+	// When: A collaborator joins and the share is viewed seven times
+	invitation, err := suite.service.AddCollaborator(ctx, owner.ID, collection.ID, &CollaboratorRequest{Email: collaborator.Email, Permission: PermissionView})
+	suite.Require().NoError(err)
+	suite.Require().NoError(suite.service.AcceptCollaboration(ctx, collaborator.ID, invitation.ID))
+	for i := 0; i < 7; i++ {
+		suite.Require().NoError(suite.service.RecordActivity(ctx, share.ID, nil, ActivityTypeView, "198.51.100.1", "Mozilla/5.0", nil))
+	}
+
+	// Then: The endpoint hears about the collaborator and every third view
+	suite.ElementsMatch([]automation.WebhookEvent{
+		automation.WebhookEventCollectionCollaboratorJoined,
+		automation.WebhookEventShareViewed,
+		automation.WebhookEventShareViewed,
+	}, suite.deliveredEvents(webhooks, owner, endpoint.ID))
+}
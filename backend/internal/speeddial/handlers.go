@@ -0,0 +1,370 @@
+package speeddial
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"bookmark-sync-service/backend/pkg/utils"
+)
+
+// Handler serves a user's speed dial
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new speed dial handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes registers the speed dial routes. Changes take the
+// device_id query parameter of the device making them, which the sync
+// event is not sent back to
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	dial := router.Group("/speed-dial")
+	dial.GET("", h.Get)
+	dial.PUT("/layout", h.Arrange)
+	dial.POST("/tiles", h.Pin)
+	dial.PUT("/tiles/:id", h.UpdateTile)
+	dial.DELETE("/tiles/:id", h.Unpin)
+	dial.POST("/groups", h.CreateGroup)
+	dial.PUT("/groups/:id", h.UpdateGroup)
+	dial.DELETE("/groups/:id", h.DeleteGroup)
+	dial.GET("/devices/:device_id", h.GetDeviceLayout)
+	dial.PUT("/devices/:device_id", h.SetDeviceLayout)
+}
+
+// DeviceLayoutRequest replaces the overrides of a device
+type DeviceLayoutRequest struct {
+	Overrides []OverrideRequest `json:"overrides" binding:"dive"`
+}
+
+// Get returns the user's speed dial
+// @Summary Get my speed dial
+// @Description Groups with their tiles in order, then the tiles in no group. With device_id, that device's overrides are applied
+// @Tags speed-dial
+// @Produce json
+// @Param device_id query string false "Device to lay the grid out for"
+// @Success 200 {object} Grid
+// @Router /speed-dial [get]
+func (h *Handler) Get(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	grid, err := h.service.Get(c.Request.Context(), userID, c.Query("device_id"))
+	if err != nil {
+		h.fail(c, err, "Failed to get speed dial")
+		return
+	}
+	utils.SuccessResponse(c, grid, "Speed dial retrieved")
+}
+
+// Arrange rearranges the speed dial
+// @Summary Rearrange my speed dial
+// @Description Order groups and move tiles between groups at once, e.g. after a drag and drop
+// @Tags speed-dial
+// @Accept json
+// @Produce json
+// @Param device_id query string false "Device making the change"
+// @Param request body ArrangeRequest true "New order"
+// @Success 200 {object} Grid
+// @Failure 400 {object} utils.ErrorResponse
+// @Router /speed-dial/layout [put]
+func (h *Handler) Arrange(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+	var req ArrangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid request data", nil)
+		return
+	}
+
+	grid, err := h.service.Arrange(c.Request.Context(), userID, c.Query("device_id"), req)
+	if err != nil {
+		h.fail(c, err, "Failed to rearrange speed dial")
+		return
+	}
+	utils.SuccessResponse(c, grid, "Speed dial rearranged")
+}
+
+// Pin pins a bookmark to the speed dial
+// @Summary Pin a bookmark to my speed dial
+// @Description Large tiles of bookmarks without a screenshot queue one
+// @Tags speed-dial
+// @Accept json
+// @Produce json
+// @Param device_id query string false "Device making the change"
+// @Param request body PinRequest true "Tile"
+// @Success 201 {object} database.SpeedDialTile
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse "Already pinned"
+// @Router /speed-dial/tiles [post]
+func (h *Handler) Pin(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+	var req PinRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid request data", nil)
+		return
+	}
+
+	tile, err := h.service.Pin(c.Request.Context(), userID, c.Query("device_id"), req)
+	if err != nil {
+		h.fail(c, err, "Failed to pin bookmark")
+		return
+	}
+	c.JSON(http.StatusCreated, utils.APIResponse{
+		Success: true,
+		Message: "Bookmark pinned",
+		Data:    tile,
+	})
+}
+
+// UpdateTile moves, resizes or renames a tile
+// @Summary Update a speed dial tile
+// @Tags speed-dial
+// @Accept json
+// @Produce json
+// @Param id path int true "Tile ID"
+// @Param device_id query string false "Device making the change"
+// @Param request body TileUpdate true "Changes"
+// @Success 200 {object} database.SpeedDialTile
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /speed-dial/tiles/{id} [put]
+func (h *Handler) UpdateTile(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_ID", "Invalid tile ID", nil)
+		return
+	}
+	var req TileUpdate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid request data", nil)
+		return
+	}
+
+	tile, err := h.service.UpdateTile(c.Request.Context(), userID, c.Query("device_id"), uint(id), req)
+	if err != nil {
+		h.fail(c, err, "Failed to update tile")
+		return
+	}
+	utils.SuccessResponse(c, tile, "Tile updated")
+}
+
+// Unpin removes a tile from the speed dial
+// @Summary Unpin a speed dial tile
+// @Tags speed-dial
+// @Param id path int true "Tile ID"
+// @Param device_id query string false "Device making the change"
+// @Success 200 {object} utils.SuccessResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /speed-dial/tiles/{id} [delete]
+func (h *Handler) Unpin(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_ID", "Invalid tile ID", nil)
+		return
+	}
+
+	if err := h.service.Unpin(c.Request.Context(), userID, c.Query("device_id"), uint(id)); err != nil {
+		h.fail(c, err, "Failed to unpin tile")
+		return
+	}
+	utils.SuccessResponse(c, nil, "Tile unpinned")
+}
+
+// CreateGroup adds a group to the speed dial
+// @Summary Create a speed dial group
+// @Tags speed-dial
+// @Accept json
+// @Produce json
+// @Param device_id query string false "Device making the change"
+// @Param request body GroupRequest true "Group"
+// @Success 201 {object} database.SpeedDialGroup
+// @Failure 400 {object} utils.ErrorResponse
+// @Router /speed-dial/groups [post]
+func (h *Handler) CreateGroup(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+	var req GroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid request data", nil)
+		return
+	}
+
+	group, err := h.service.CreateGroup(c.Request.Context(), userID, c.Query("device_id"), req)
+	if err != nil {
+		h.fail(c, err, "Failed to create group")
+		return
+	}
+	c.JSON(http.StatusCreated, utils.APIResponse{
+		Success: true,
+		Message: "Group created",
+		Data:    group,
+	})
+}
+
+// UpdateGroup renames or moves a group
+// @Summary Update a speed dial group
+// @Tags speed-dial
+// @Accept json
+// @Produce json
+// @Param id path int true "Group ID"
+// @Param device_id query string false "Device making the change"
+// @Param request body GroupRequest true "Changes"
+// @Success 200 {object} database.SpeedDialGroup
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /speed-dial/groups/{id} [put]
+func (h *Handler) UpdateGroup(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_ID", "Invalid group ID", nil)
+		return
+	}
+	var req GroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid request data", nil)
+		return
+	}
+
+	group, err := h.service.UpdateGroup(c.Request.Context(), userID, c.Query("device_id"), uint(id), req)
+	if err != nil {
+		h.fail(c, err, "Failed to update group")
+		return
+	}
+	utils.SuccessResponse(c, group, "Group updated")
+}
+
+// DeleteGroup removes a group, keeping its tiles
+// @Summary Delete a speed dial group
+// @Description The group's tiles move after the tiles in no group
+// @Tags speed-dial
+// @Param id path int true "Group ID"
+// @Param device_id query string false "Device making the change"
+// @Success 200 {object} utils.SuccessResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /speed-dial/groups/{id} [delete]
+func (h *Handler) DeleteGroup(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_ID", "Invalid group ID", nil)
+		return
+	}
+
+	if err := h.service.DeleteGroup(c.Request.Context(), userID, c.Query("device_id"), uint(id)); err != nil {
+		h.fail(c, err, "Failed to delete group")
+		return
+	}
+	utils.SuccessResponse(c, nil, "Group deleted")
+}
+
+// GetDeviceLayout returns the overrides of a device
+// @Summary Get a device's speed dial overrides
+// @Tags speed-dial
+// @Produce json
+// @Param device_id path string true "Device ID"
+// @Success 200 {array} database.SpeedDialOverride
+// @Router /speed-dial/devices/{device_id} [get]
+func (h *Handler) GetDeviceLayout(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	overrides, err := h.service.DeviceLayout(c.Request.Context(), userID, c.Param("device_id"))
+	if err != nil {
+		h.fail(c, err, "Failed to get device layout")
+		return
+	}
+	utils.SuccessResponse(c, overrides, "Device layout retrieved")
+}
+
+// SetDeviceLayout replaces the overrides of a device
+// @Summary Set a device's speed dial overrides
+// @Description Move, resize or hide tiles on one device only; an empty list clears its overrides
+// @Tags speed-dial
+// @Accept json
+// @Produce json
+// @Param device_id path string true "Device ID"
+// @Param request body DeviceLayoutRequest true "Overrides"
+// @Success 200 {array} database.SpeedDialOverride
+// @Failure 400 {object} utils.ErrorResponse
+// @Router /speed-dial/devices/{device_id} [put]
+func (h *Handler) SetDeviceLayout(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+	var req DeviceLayoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid request data", nil)
+		return
+	}
+
+	overrides, err := h.service.SetDeviceLayout(c.Request.Context(), userID, c.Param("device_id"), req.Overrides)
+	if err != nil {
+		h.fail(c, err, "Failed to save device layout")
+		return
+	}
+	utils.SuccessResponse(c, overrides, "Device layout saved")
+}
+
+func (h *Handler) fail(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, ErrInvalidSize), errors.Is(err, ErrInvalidName), errors.Is(err, ErrInvalidLayout),
+		errors.Is(err, ErrInvalidDevice):
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error(), nil)
+	case errors.Is(err, ErrTooManyTiles), errors.Is(err, ErrTooManyGroups):
+		utils.ErrorResponse(c, http.StatusBadRequest, "LIMIT_EXCEEDED", err.Error(), nil)
+	case errors.Is(err, ErrBookmarkNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, "BOOKMARK_NOT_FOUND", err.Error(), nil)
+	case errors.Is(err, ErrTileNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, "TILE_NOT_FOUND", err.Error(), nil)
+	case errors.Is(err, ErrGroupNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, "GROUP_NOT_FOUND", err.Error(), nil)
+	case errors.Is(err, ErrAlreadyPinned):
+		utils.ErrorResponse(c, http.StatusConflict, "ALREADY_PINNED", err.Error(), nil)
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", message, nil)
+	}
+}
@@ -0,0 +1,850 @@
+// Package speeddial keeps a user's new-tab grid: bookmarks pinned as tiles,
+// ordered within folder groups, with layout overrides for single devices.
+// Every change is published as a sync event carrying the whole layout, and
+// large tiles get a screenshot of their page from the screenshot pipeline
+package speeddial
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/internal/screenshot"
+	syncpkg "bookmark-sync-service/backend/internal/sync"
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+// Limits of a grid, so it stays one screen of tiles
+const (
+	MaxTiles  = 100
+	MaxGroups = 20
+)
+
+// SyncEventChanged is the sync event type of grid changes. The event's data
+// is the Layout, so devices apply it without fetching the grid
+const SyncEventChanged syncpkg.SyncEventType = "speed_dial_changed"
+
+const (
+	// syncResourceID is the resource of grid sync events; delta sync keeps
+	// only the latest event of a resource
+	syncResourceID = "speed_dial"
+	// serverDevice is the device of changes made without naming one
+	serverDevice = "server"
+)
+
+var (
+	ErrBookmarkNotFound = errors.New("bookmark not found")
+	ErrTileNotFound     = errors.New("tile not found")
+	ErrGroupNotFound    = errors.New("group not found")
+	ErrAlreadyPinned    = errors.New("bookmark is already pinned")
+	ErrTooManyTiles     = fmt.Errorf("a speed dial holds at most %d tiles", MaxTiles)
+	ErrTooManyGroups    = fmt.Errorf("a speed dial holds at most %d groups", MaxGroups)
+	ErrInvalidSize      = errors.New("size must be small, medium or large")
+	ErrInvalidName      = errors.New("group name is required and at most 100 characters")
+	ErrInvalidLayout    = errors.New("layout names tiles or groups that are not on the speed dial, or names them twice")
+	ErrInvalidDevice    = errors.New("device ID is required and at most 255 characters")
+)
+
+// SyncPublisher stores and broadcasts sync events, e.g. the sync service
+type SyncPublisher interface {
+	CreateSyncEvent(ctx context.Context, event *syncpkg.SyncEvent) error
+}
+
+// Thumbnailer captures the page of a bookmark, e.g. the screenshot refresher
+type Thumbnailer interface {
+	RequestRefresh(ctx context.Context, userID, bookmarkID uint) (*screenshot.RefreshResult, error)
+}
+
+// Tile is a pinned bookmark as the grid shows it
+type Tile struct {
+	ID         uint   `json:"id"`
+	BookmarkID uint   `json:"bookmark_id"`
+	GroupID    *uint  `json:"group_id"`
+	Position   int    `json:"position"`
+	Size       string `json:"size"`
+	Title      string `json:"title"`
+	URL        string `json:"url"`
+	Favicon    string `json:"favicon,omitempty"`
+	Thumbnail  string `json:"thumbnail,omitempty"` // screenshot of the page, for large tiles
+}
+
+// Group is a folder of tiles in order
+type Group struct {
+	database.SpeedDialGroup
+	Tiles []Tile `json:"tiles"`
+}
+
+// Grid is a speed dial as one device shows it
+type Grid struct {
+	Groups []Group `json:"groups"`
+	Tiles  []Tile  `json:"tiles"` // tiles in no group
+}
+
+// Layout is the shared grid with the overrides of every device, the data of
+// grid sync events
+type Layout struct {
+	Grid
+	Overrides []database.SpeedDialOverride `json:"overrides"`
+}
+
+// PinRequest pins a bookmark. Without a position the tile goes last
+type PinRequest struct {
+	BookmarkID uint   `json:"bookmark_id" binding:"required"`
+	GroupID    *uint  `json:"group_id"`
+	Position   *int   `json:"position"`
+	Size       string `json:"size"`
+	Title      string `json:"title" binding:"max=255"`
+}
+
+// TileUpdate changes the fields it sets. Group 0 takes the tile out of its
+// group
+type TileUpdate struct {
+	GroupID  *uint   `json:"group_id"`
+	Position *int    `json:"position"`
+	Size     *string `json:"size"`
+	Title    *string `json:"title" binding:"omitempty,max=255"`
+}
+
+// GroupRequest creates a group or, with the fields it sets, changes one
+type GroupRequest struct {
+	Name     string `json:"name"`
+	Position *int   `json:"position"`
+}
+
+// Placement puts a tile in a group, or in none
+type Placement struct {
+	ID      uint  `json:"id" binding:"required"`
+	GroupID *uint `json:"group_id"`
+}
+
+// ArrangeRequest rearranges the grid at once, e.g. after a drag and drop.
+// Groups and tiles take the order they are listed in; the ones left out
+// follow them in their current order
+type ArrangeRequest struct {
+	Groups []uint      `json:"groups"`
+	Tiles  []Placement `json:"tiles"`
+}
+
+// OverrideRequest changes a tile on one device
+type OverrideRequest struct {
+	TileID   uint   `json:"tile_id" binding:"required"`
+	Position *int   `json:"position"`
+	Size     string `json:"size"`
+	Hidden   bool   `json:"hidden"`
+}
+
+// Service manages speed dials
+type Service struct {
+	db         *gorm.DB
+	logger     *zap.Logger
+	sync       SyncPublisher
+	thumbnails Thumbnailer
+}
+
+// NewService creates a speed dial service
+func NewService(db *gorm.DB, logger *zap.Logger) *Service {
+	return &Service{db: db, logger: logger}
+}
+
+// SetSync publishes grid changes to the user's other devices
+func (s *Service) SetSync(publisher SyncPublisher) {
+	s.sync = publisher
+}
+
+// SetThumbnailer captures pages of large tiles that have no screenshot yet
+func (s *Service) SetThumbnailer(thumbnailer Thumbnailer) {
+	s.thumbnails = thumbnailer
+}
+
+// Get returns the user's grid with the overrides of the device, if any
+func (s *Service) Get(ctx context.Context, userID uint, deviceID string) (*Grid, error) {
+	groups, tiles, bookmarks, err := load(s.db.WithContext(ctx), userID)
+	if err != nil {
+		return nil, err
+	}
+	overrides := map[uint]database.SpeedDialOverride{}
+	if deviceID != "" {
+		var rows []database.SpeedDialOverride
+		if err := s.db.WithContext(ctx).Where("user_id = ? AND device_id = ?", userID, deviceID).Find(&rows).Error; err != nil {
+			return nil, fmt.Errorf("failed to get device layout: %w", err)
+		}
+		for _, row := range rows {
+			overrides[row.TileID] = row
+		}
+	}
+	return build(groups, tiles, bookmarks, overrides), nil
+}
+
+// Layout returns the user's shared grid with the overrides of all devices
+func (s *Service) Layout(ctx context.Context, userID uint) (*Layout, error) {
+	grid, err := s.Get(ctx, userID, "")
+	if err != nil {
+		return nil, err
+	}
+	layout := &Layout{Grid: *grid, Overrides: []database.SpeedDialOverride{}}
+	if err := s.db.WithContext(ctx).Where("user_id = ?", userID).Order("device_id, tile_id").
+		Find(&layout.Overrides).Error; err != nil {
+		return nil, fmt.Errorf("failed to get device layouts: %w", err)
+	}
+	return layout, nil
+}
+
+// Pin pins one of the user's bookmarks to their grid
+func (s *Service) Pin(ctx context.Context, userID uint, deviceID string, req PinRequest) (*database.SpeedDialTile, error) {
+	if req.Size == "" {
+		req.Size = database.SpeedDialMedium
+	}
+	if !validSize(req.Size) {
+		return nil, ErrInvalidSize
+	}
+	groupID := normalizeGroup(req.GroupID)
+
+	tile := &database.SpeedDialTile{
+		UserID:     userID,
+		BookmarkID: req.BookmarkID,
+		GroupID:    groupID,
+		Position:   MaxTiles,
+		Size:       req.Size,
+		Title:      strings.TrimSpace(req.Title),
+	}
+	err := database.WithTransaction(ctx, s.db, func(tx *gorm.DB) error {
+		var bookmarks int64
+		if err := tx.Model(&database.Bookmark{}).Where("id = ? AND user_id = ?", req.BookmarkID, userID).
+			Count(&bookmarks).Error; err != nil {
+			return fmt.Errorf("failed to get bookmark: %w", err)
+		}
+		if bookmarks == 0 {
+			return ErrBookmarkNotFound
+		}
+		var pinned []database.SpeedDialTile
+		if err := tx.Where("user_id = ?", userID).Find(&pinned).Error; err != nil {
+			return fmt.Errorf("failed to get tiles: %w", err)
+		}
+		if len(pinned) >= MaxTiles {
+			return ErrTooManyTiles
+		}
+		for _, existing := range pinned {
+			if existing.BookmarkID == req.BookmarkID {
+				return ErrAlreadyPinned
+			}
+		}
+		if err := checkGroup(tx, userID, groupID); err != nil {
+			return err
+		}
+
+		if err := tx.Create(tile).Error; err != nil {
+			return fmt.Errorf("failed to pin bookmark: %w", err)
+		}
+		return reorderTiles(tx, userID, groupID, tile.ID, req.Position)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if tile.Size == database.SpeedDialLarge {
+		s.thumbnail(ctx, userID, tile.BookmarkID)
+	}
+	s.changed(ctx, userID, deviceID)
+	return s.tile(ctx, userID, tile.ID)
+}
+
+// UpdateTile moves, resizes or renames a tile
+func (s *Service) UpdateTile(ctx context.Context, userID uint, deviceID string, id uint, req TileUpdate) (*database.SpeedDialTile, error) {
+	if req.Size != nil && !validSize(*req.Size) {
+		return nil, ErrInvalidSize
+	}
+
+	tile, err := s.tile(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	err = database.WithTransaction(ctx, s.db, func(tx *gorm.DB) error {
+		updates := map[string]interface{}{}
+		if req.Size != nil {
+			updates["size"] = *req.Size
+		}
+		if req.Title != nil {
+			updates["title"] = strings.TrimSpace(*req.Title)
+		}
+		from, to := tile.GroupID, tile.GroupID
+		if req.GroupID != nil {
+			to = normalizeGroup(req.GroupID)
+			if err := checkGroup(tx, userID, to); err != nil {
+				return err
+			}
+			updates["group_id"] = to
+		}
+		if len(updates) > 0 {
+			if err := tx.Model(tile).Updates(updates).Error; err != nil {
+				return fmt.Errorf("failed to update tile: %w", err)
+			}
+		}
+
+		if req.GroupID == nil && req.Position == nil {
+			return nil
+		}
+		if !sameGroup(from, to) {
+			if err := reorderTiles(tx, userID, from, 0, nil); err != nil {
+				return err
+			}
+		}
+		return reorderTiles(tx, userID, to, tile.ID, req.Position)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Size != nil && *req.Size == database.SpeedDialLarge {
+		s.thumbnail(ctx, userID, tile.BookmarkID)
+	}
+	s.changed(ctx, userID, deviceID)
+	return s.tile(ctx, userID, id)
+}
+
+// Unpin removes a tile and its device overrides
+func (s *Service) Unpin(ctx context.Context, userID uint, deviceID string, id uint) error {
+	tile, err := s.tile(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	err = database.WithTransaction(ctx, s.db, func(tx *gorm.DB) error {
+		if err := tx.Where("tile_id = ?", tile.ID).Delete(&database.SpeedDialOverride{}).Error; err != nil {
+			return fmt.Errorf("failed to delete tile overrides: %w", err)
+		}
+		if err := tx.Delete(tile).Error; err != nil {
+			return fmt.Errorf("failed to unpin tile: %w", err)
+		}
+		return reorderTiles(tx, userID, tile.GroupID, 0, nil)
+	})
+	if err != nil {
+		return err
+	}
+	s.changed(ctx, userID, deviceID)
+	return nil
+}
+
+// CreateGroup adds a group to the user's grid
+func (s *Service) CreateGroup(ctx context.Context, userID uint, deviceID string, req GroupRequest) (*database.SpeedDialGroup, error) {
+	name, err := groupName(req.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	group := &database.SpeedDialGroup{UserID: userID, Name: name, Position: MaxGroups}
+	err = database.WithTransaction(ctx, s.db, func(tx *gorm.DB) error {
+		var groups int64
+		if err := tx.Model(&database.SpeedDialGroup{}).Where("user_id = ?", userID).Count(&groups).Error; err != nil {
+			return fmt.Errorf("failed to count groups: %w", err)
+		}
+		if groups >= MaxGroups {
+			return ErrTooManyGroups
+		}
+		if err := tx.Create(group).Error; err != nil {
+			return fmt.Errorf("failed to create group: %w", err)
+		}
+		return reorderGroups(tx, userID, group.ID, req.Position)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.changed(ctx, userID, deviceID)
+	return s.group(ctx, userID, group.ID)
+}
+
+// UpdateGroup renames or moves a group
+func (s *Service) UpdateGroup(ctx context.Context, userID uint, deviceID string, id uint, req GroupRequest) (*database.SpeedDialGroup, error) {
+	group, err := s.group(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	err = database.WithTransaction(ctx, s.db, func(tx *gorm.DB) error {
+		if req.Name != "" {
+			name, err := groupName(req.Name)
+			if err != nil {
+				return err
+			}
+			if err := tx.Model(group).Update("name", name).Error; err != nil {
+				return fmt.Errorf("failed to rename group: %w", err)
+			}
+		}
+		if req.Position != nil {
+			return reorderGroups(tx, userID, group.ID, req.Position)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.changed(ctx, userID, deviceID)
+	return s.group(ctx, userID, id)
+}
+
+// DeleteGroup removes a group. Its tiles stay on the grid, after the tiles
+// in no group
+func (s *Service) DeleteGroup(ctx context.Context, userID uint, deviceID string, id uint) error {
+	group, err := s.group(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	err = database.WithTransaction(ctx, s.db, func(tx *gorm.DB) error {
+		if err := tx.Model(&database.SpeedDialTile{}).Where("group_id = ?", group.ID).
+			Updates(map[string]interface{}{"group_id": nil, "position": gorm.Expr("position + ?", MaxTiles)}).Error; err != nil {
+			return fmt.Errorf("failed to ungroup tiles: %w", err)
+		}
+		if err := tx.Delete(group).Error; err != nil {
+			return fmt.Errorf("failed to delete group: %w", err)
+		}
+		if err := reorderTiles(tx, userID, nil, 0, nil); err != nil {
+			return err
+		}
+		return reorderGroups(tx, userID, 0, nil)
+	})
+	if err != nil {
+		return err
+	}
+	s.changed(ctx, userID, deviceID)
+	return nil
+}
+
+// Arrange rearranges groups and tiles at once and returns the new grid as
+// the device shows it
+func (s *Service) Arrange(ctx context.Context, userID uint, deviceID string, req ArrangeRequest) (*Grid, error) {
+	err := database.WithTransaction(ctx, s.db, func(tx *gorm.DB) error {
+		var groups []database.SpeedDialGroup
+		if err := tx.Where("user_id = ?", userID).Order("position, id").Find(&groups).Error; err != nil {
+			return fmt.Errorf("failed to get groups: %w", err)
+		}
+		var tiles []database.SpeedDialTile
+		if err := tx.Where("user_id = ?", userID).Order("position, id").Find(&tiles).Error; err != nil {
+			return fmt.Errorf("failed to get tiles: %w", err)
+		}
+		groupIDs := make(map[uint]bool, len(groups))
+		for _, group := range groups {
+			groupIDs[group.ID] = true
+		}
+		tileIDs := make(map[uint]bool, len(tiles))
+		for _, tile := range tiles {
+			tileIDs[tile.ID] = true
+		}
+
+		listed := make(map[uint]bool)
+		for i, id := range req.Groups {
+			if !groupIDs[id] || listed[id] {
+				return ErrInvalidLayout
+			}
+			listed[id] = true
+			if err := tx.Model(&database.SpeedDialGroup{}).Where("id = ?", id).Update("position", i).Error; err != nil {
+				return fmt.Errorf("failed to move group: %w", err)
+			}
+		}
+		for _, group := range groups {
+			if !listed[group.ID] {
+				if err := tx.Model(&database.SpeedDialGroup{}).Where("id = ?", group.ID).
+					Update("position", MaxGroups+group.Position).Error; err != nil {
+					return fmt.Errorf("failed to move group: %w", err)
+				}
+			}
+		}
+
+		placed := make(map[uint]bool)
+		next := make(map[uint]int) // next position per group, 0 for no group
+		for _, placement := range req.Tiles {
+			groupID := normalizeGroup(placement.GroupID)
+			if !tileIDs[placement.ID] || placed[placement.ID] || (groupID != nil && !groupIDs[*groupID]) {
+				return ErrInvalidLayout
+			}
+			placed[placement.ID] = true
+			key := groupKey(groupID)
+			if err := tx.Model(&database.SpeedDialTile{}).Where("id = ?", placement.ID).
+				Updates(map[string]interface{}{"group_id": groupID, "position": next[key]}).Error; err != nil {
+				return fmt.Errorf("failed to move tile: %w", err)
+			}
+			next[key]++
+		}
+		for _, tile := range tiles {
+			if !placed[tile.ID] {
+				if err := tx.Model(&database.SpeedDialTile{}).Where("id = ?", tile.ID).
+					Update("position", MaxTiles+tile.Position).Error; err != nil {
+					return fmt.Errorf("failed to move tile: %w", err)
+				}
+			}
+		}
+
+		if err := reorderGroups(tx, userID, 0, nil); err != nil {
+			return err
+		}
+		if err := reorderTiles(tx, userID, nil, 0, nil); err != nil {
+			return err
+		}
+		for _, group := range groups {
+			id := group.ID
+			if err := reorderTiles(tx, userID, &id, 0, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.changed(ctx, userID, deviceID)
+	return s.Get(ctx, userID, deviceID)
+}
+
+// DeviceLayout returns the overrides of one of the user's devices
+func (s *Service) DeviceLayout(ctx context.Context, userID uint, deviceID string) ([]database.SpeedDialOverride, error) {
+	overrides := []database.SpeedDialOverride{}
+	if err := s.db.WithContext(ctx).Where("user_id = ? AND device_id = ?", userID, deviceID).
+		Order("tile_id").Find(&overrides).Error; err != nil {
+		return nil, fmt.Errorf("failed to get device layout: %w", err)
+	}
+	return overrides, nil
+}
+
+// SetDeviceLayout replaces the overrides of one of the user's devices
+func (s *Service) SetDeviceLayout(ctx context.Context, userID uint, deviceID string, overrides []OverrideRequest) ([]database.SpeedDialOverride, error) {
+	if deviceID == "" || len(deviceID) > 255 {
+		return nil, ErrInvalidDevice
+	}
+	for _, override := range overrides {
+		if override.Size != "" && !validSize(override.Size) {
+			return nil, ErrInvalidSize
+		}
+	}
+
+	rows := make([]database.SpeedDialOverride, 0, len(overrides))
+	var large []uint
+	err := database.WithTransaction(ctx, s.db, func(tx *gorm.DB) error {
+		var tiles []database.SpeedDialTile
+		if err := tx.Where("user_id = ?", userID).Find(&tiles).Error; err != nil {
+			return fmt.Errorf("failed to get tiles: %w", err)
+		}
+		bookmarks := make(map[uint]uint, len(tiles))
+		for _, tile := range tiles {
+			bookmarks[tile.ID] = tile.BookmarkID
+		}
+
+		seen := make(map[uint]bool, len(overrides))
+		for _, override := range overrides {
+			bookmarkID, ok := bookmarks[override.TileID]
+			if !ok || seen[override.TileID] {
+				return ErrInvalidLayout
+			}
+			seen[override.TileID] = true
+			rows = append(rows, database.SpeedDialOverride{
+				UserID:   userID,
+				DeviceID: deviceID,
+				TileID:   override.TileID,
+				Position: override.Position,
+				Size:     override.Size,
+				Hidden:   override.Hidden,
+			})
+			if override.Size == database.SpeedDialLarge && !override.Hidden {
+				large = append(large, bookmarkID)
+			}
+		}
+
+		if err := tx.Where("user_id = ? AND device_id = ?", userID, deviceID).Delete(&database.SpeedDialOverride{}).Error; err != nil {
+			return fmt.Errorf("failed to clear device layout: %w", err)
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		if err := tx.Create(&rows).Error; err != nil {
+			return fmt.Errorf("failed to save device layout: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, bookmarkID := range large {
+		s.thumbnail(ctx, userID, bookmarkID)
+	}
+	s.changed(ctx, userID, deviceID)
+	return rows, nil
+}
+
+// tile returns one of the user's tiles
+func (s *Service) tile(ctx context.Context, userID, id uint) (*database.SpeedDialTile, error) {
+	var tile database.SpeedDialTile
+	if err := s.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&tile).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTileNotFound
+		}
+		return nil, fmt.Errorf("failed to get tile: %w", err)
+	}
+	return &tile, nil
+}
+
+// group returns one of the user's groups
+func (s *Service) group(ctx context.Context, userID, id uint) (*database.SpeedDialGroup, error) {
+	var group database.SpeedDialGroup
+	if err := s.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&group).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrGroupNotFound
+		}
+		return nil, fmt.Errorf("failed to get group: %w", err)
+	}
+	return &group, nil
+}
+
+// thumbnail queues a screenshot of a bookmark's page for a large tile,
+// unless it has one. The tile shows its favicon until the capture is done
+func (s *Service) thumbnail(ctx context.Context, userID, bookmarkID uint) {
+	if s.thumbnails == nil {
+		return
+	}
+	var screenshots []string
+	if err := s.db.WithContext(ctx).Model(&database.Bookmark{}).Where("id = ?", bookmarkID).
+		Pluck("screenshot", &screenshots).Error; err != nil || len(screenshots) == 0 || screenshots[0] != "" {
+		return
+	}
+	if _, err := s.thumbnails.RequestRefresh(ctx, userID, bookmarkID); err != nil {
+		s.logger.Warn("Failed to queue speed dial thumbnail", zap.Uint("bookmark_id", bookmarkID), zap.Error(err))
+	}
+}
+
+// changed publishes the user's layout to their devices. The change is
+// saved either way; devices that miss the event get the grid when they
+// fetch it
+func (s *Service) changed(ctx context.Context, userID uint, deviceID string) {
+	if s.sync == nil {
+		return
+	}
+	layout, err := s.Layout(ctx, userID)
+	if err != nil {
+		s.logger.Warn("Failed to publish speed dial change", zap.Uint("user_id", userID), zap.Error(err))
+		return
+	}
+	data, err := json.Marshal(layout)
+	if err != nil {
+		s.logger.Warn("Failed to publish speed dial change", zap.Uint("user_id", userID), zap.Error(err))
+		return
+	}
+	if deviceID == "" {
+		deviceID = serverDevice
+	}
+	if err := s.sync.CreateSyncEvent(ctx, &syncpkg.SyncEvent{
+		Type:       SyncEventChanged,
+		UserID:     strconv.FormatUint(uint64(userID), 10),
+		ResourceID: syncResourceID,
+		Action:     "update",
+		Data:       string(data),
+		DeviceID:   deviceID,
+	}); err != nil {
+		s.logger.Warn("Failed to publish speed dial change", zap.Uint("user_id", userID), zap.Error(err))
+	}
+}
+
+// load returns a user's groups and tiles in order, and the bookmarks of the
+// tiles. Tiles of deleted bookmarks have none and are left off the grid
+func load(db *gorm.DB, userID uint) ([]database.SpeedDialGroup, []database.SpeedDialTile, map[uint]database.Bookmark, error) {
+	var groups []database.SpeedDialGroup
+	if err := db.Where("user_id = ?", userID).Order("position, id").Find(&groups).Error; err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to get groups: %w", err)
+	}
+	var tiles []database.SpeedDialTile
+	if err := db.Where("user_id = ?", userID).Order("position, id").Find(&tiles).Error; err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to get tiles: %w", err)
+	}
+
+	bookmarks := make(map[uint]database.Bookmark, len(tiles))
+	if len(tiles) == 0 {
+		return groups, tiles, bookmarks, nil
+	}
+	ids := make([]uint, len(tiles))
+	for i, tile := range tiles {
+		ids[i] = tile.BookmarkID
+	}
+	var rows []database.Bookmark
+	if err := db.Select("id", "url", "title", "favicon", "screenshot").
+		Where("id IN ? AND user_id = ?", ids, userID).Find(&rows).Error; err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to get pinned bookmarks: %w", err)
+	}
+	for _, bookmark := range rows {
+		bookmarks[bookmark.ID] = bookmark
+	}
+	return groups, tiles, bookmarks, nil
+}
+
+// build lays out tiles in their groups with a device's overrides applied:
+// hidden tiles are left out, and tiles the device moved are put at their
+// device position among the others, in order of that position
+func build(groups []database.SpeedDialGroup, tiles []database.SpeedDialTile, bookmarks map[uint]database.Bookmark, overrides map[uint]database.SpeedDialOverride) *Grid {
+	type moved struct {
+		tile     Tile
+		position int
+	}
+	buckets := make(map[uint][]Tile)
+	movedTiles := make(map[uint][]moved)
+	known := make(map[uint]bool, len(groups))
+	for _, group := range groups {
+		known[group.ID] = true
+	}
+
+	for _, tile := range tiles {
+		bookmark, ok := bookmarks[tile.BookmarkID]
+		if !ok {
+			continue
+		}
+		size, position := tile.Size, (*int)(nil)
+		if override, ok := overrides[tile.ID]; ok {
+			if override.Hidden {
+				continue
+			}
+			if override.Size != "" {
+				size = override.Size
+			}
+			position = override.Position
+		}
+
+		title := tile.Title
+		if title == "" {
+			title = bookmark.Title
+		}
+		view := Tile{
+			ID:         tile.ID,
+			BookmarkID: tile.BookmarkID,
+			GroupID:    tile.GroupID,
+			Size:       size,
+			Title:      title,
+			URL:        bookmark.URL,
+			Favicon:    bookmark.Favicon,
+		}
+		if size == database.SpeedDialLarge {
+			view.Thumbnail = bookmark.Screenshot
+		}
+		key := groupKey(tile.GroupID)
+		if !known[key] {
+			key, view.GroupID = 0, nil
+		}
+		if position != nil {
+			movedTiles[key] = append(movedTiles[key], moved{tile: view, position: *position})
+		} else {
+			buckets[key] = append(buckets[key], view)
+		}
+	}
+
+	ordered := func(key uint) []Tile {
+		result := buckets[key]
+		pending := movedTiles[key]
+		sort.SliceStable(pending, func(i, j int) bool { return pending[i].position < pending[j].position })
+		for _, m := range pending {
+			index := len(result)
+			if m.position >= 0 && m.position < index {
+				index = m.position
+			}
+			result = append(result[:index], append([]Tile{m.tile}, result[index:]...)...)
+		}
+		if result == nil {
+			result = []Tile{}
+		}
+		for i := range result {
+			result[i].Position = i
+		}
+		return result
+	}
+
+	grid := &Grid{Groups: make([]Group, len(groups)), Tiles: ordered(0)}
+	for i, group := range groups {
+		grid.Groups[i] = Group{SpeedDialGroup: group, Tiles: ordered(group.ID)}
+	}
+	return grid
+}
+
+// reorderTiles numbers the tiles of a group, or of no group, from 0 in
+// their current order, with the tile id moved to position or last
+func reorderTiles(tx *gorm.DB, userID uint, groupID *uint, id uint, position *int) error {
+	return reorder(tx, &database.SpeedDialTile{}, func(db *gorm.DB) *gorm.DB {
+		db = db.Where("user_id = ?", userID)
+		if groupID == nil {
+			return db.Where("group_id IS NULL")
+		}
+		return db.Where("group_id = ?", *groupID)
+	}, id, position)
+}
+
+// reorderGroups numbers the user's groups from 0 in their current order,
+// with the group id moved to position or last
+func reorderGroups(tx *gorm.DB, userID, id uint, position *int) error {
+	return reorder(tx, &database.SpeedDialGroup{}, func(db *gorm.DB) *gorm.DB {
+		return db.Where("user_id = ?", userID)
+	}, id, position)
+}
+
+func reorder(tx *gorm.DB, model interface{}, scope func(*gorm.DB) *gorm.DB, id uint, position *int) error {
+	var ids []uint
+	if err := scope(tx.Model(model)).Where("id <> ?", id).Order("position, id").Pluck("id", &ids).Error; err != nil {
+		return fmt.Errorf("failed to get positions: %w", err)
+	}
+	if id != 0 {
+		index := len(ids)
+		if position != nil && *position >= 0 && *position < index {
+			index = *position
+		}
+		ids = append(ids[:index], append([]uint{id}, ids[index:]...)...)
+	}
+	for i, rowID := range ids {
+		if err := tx.Model(model).Where("id = ?", rowID).Update("position", i).Error; err != nil {
+			return fmt.Errorf("failed to update positions: %w", err)
+		}
+	}
+	return nil
+}
+
+// checkGroup checks that a tile's group, if any, is one of the user's
+func checkGroup(tx *gorm.DB, userID uint, groupID *uint) error {
+	if groupID == nil {
+		return nil
+	}
+	var groups int64
+	if err := tx.Model(&database.SpeedDialGroup{}).Where("id = ? AND user_id = ?", *groupID, userID).
+		Count(&groups).Error; err != nil {
+		return fmt.Errorf("failed to get group: %w", err)
+	}
+	if groups == 0 {
+		return ErrGroupNotFound
+	}
+	return nil
+}
+
+func groupName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 100 {
+		return "", ErrInvalidName
+	}
+	return name, nil
+}
+
+func validSize(size string) bool {
+	return size == database.SpeedDialSmall || size == database.SpeedDialMedium || size == database.SpeedDialLarge
+}
+
+// normalizeGroup treats group 0 as no group
+func normalizeGroup(groupID *uint) *uint {
+	if groupID == nil || *groupID == 0 {
+		return nil
+	}
+	id := *groupID
+	return &id
+}
+
+func groupKey(groupID *uint) uint {
+	if groupID == nil {
+		return 0
+	}
+	return *groupID
+}
+
+func sameGroup(a, b *uint) bool {
+	return groupKey(a) == groupKey(b)
+}
@@ -0,0 +1,201 @@
+package speeddial
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"bookmark-sync-service/backend/internal/screenshot"
+	syncpkg "bookmark-sync-service/backend/internal/sync"
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/testfactory"
+)
+
+type recordingSync struct {
+	events []*syncpkg.SyncEvent
+}
+
+func (r *recordingSync) CreateSyncEvent(ctx context.Context, event *syncpkg.SyncEvent) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+type recordingThumbnailer struct {
+	bookmarkIDs []uint
+}
+
+func (r *recordingThumbnailer) RequestRefresh(ctx context.Context, userID, bookmarkID uint) (*screenshot.RefreshResult, error) {
+	r.bookmarkIDs = append(r.bookmarkIDs, bookmarkID)
+	return &screenshot.RefreshResult{}, nil
+}
+
+func setupService(t *testing.T) (*Service, *testfactory.Factory, *recordingSync, *recordingThumbnailer) {
+	db := testfactory.NewDB(t)
+	service := NewService(db, zap.NewNop())
+	sync, thumbnails := &recordingSync{}, &recordingThumbnailer{}
+	service.SetSync(sync)
+	service.SetThumbnailer(thumbnails)
+	return service, testfactory.New(t, db), sync, thumbnails
+}
+
+func tileIDs(tiles []Tile) []uint {
+	ids := make([]uint, len(tiles))
+	for i, tile := range tiles {
+		ids[i] = tile.ID
+	}
+	return ids
+}
+
+func intPtr(i int) *int { return &i }
+
+func TestPinAndGroups(t *testing.T) {
+	ctx := context.Background()
+	service, factory, _, _ := setupService(t)
+	user, other := factory.User(), factory.User()
+	docs := factory.Bookmark(user.ID, func(b *database.Bookmark) { b.Title = "Go docs" })
+	news, mail := factory.Bookmark(user.ID), factory.Bookmark(user.ID)
+
+	first, err := service.Pin(ctx, user.ID, "laptop", PinRequest{BookmarkID: docs.ID, Title: " Docs "})
+	require.NoError(t, err)
+	assert.Equal(t, database.SpeedDialMedium, first.Size)
+	second, err := service.Pin(ctx, user.ID, "laptop", PinRequest{BookmarkID: news.ID})
+	require.NoError(t, err)
+	third, err := service.Pin(ctx, user.ID, "laptop", PinRequest{BookmarkID: mail.ID, Position: intPtr(0)})
+	require.NoError(t, err)
+
+	_, err = service.Pin(ctx, user.ID, "laptop", PinRequest{BookmarkID: docs.ID})
+	assert.ErrorIs(t, err, ErrAlreadyPinned)
+	_, err = service.Pin(ctx, other.ID, "", PinRequest{BookmarkID: docs.ID})
+	assert.ErrorIs(t, err, ErrBookmarkNotFound)
+	_, err = service.Pin(ctx, user.ID, "", PinRequest{BookmarkID: docs.ID, Size: "huge"})
+	assert.ErrorIs(t, err, ErrInvalidSize)
+
+	grid, err := service.Get(ctx, user.ID, "")
+	require.NoError(t, err)
+	assert.Equal(t, []uint{third.ID, first.ID, second.ID}, tileIDs(grid.Tiles))
+	assert.Equal(t, "Docs", grid.Tiles[1].Title, "the tile title replaces the bookmark's")
+	assert.Equal(t, docs.URL, grid.Tiles[1].URL)
+
+	// Moving a tile into a group closes the gap it leaves
+	work, err := service.CreateGroup(ctx, user.ID, "", GroupRequest{Name: "Work"})
+	require.NoError(t, err)
+	_, err = service.UpdateTile(ctx, user.ID, "", first.ID, TileUpdate{GroupID: &work.ID})
+	require.NoError(t, err)
+	_, err = service.UpdateTile(ctx, other.ID, "", first.ID, TileUpdate{GroupID: &work.ID})
+	assert.ErrorIs(t, err, ErrTileNotFound)
+
+	grid, err = service.Get(ctx, user.ID, "")
+	require.NoError(t, err)
+	require.Len(t, grid.Groups, 1)
+	assert.Equal(t, []uint{first.ID}, tileIDs(grid.Groups[0].Tiles))
+	assert.Equal(t, []uint{third.ID, second.ID}, tileIDs(grid.Tiles))
+	assert.Equal(t, 1, grid.Tiles[1].Position)
+
+	// Deleting the group keeps its tiles, after the ungrouped ones
+	require.NoError(t, service.DeleteGroup(ctx, user.ID, "", work.ID))
+	grid, err = service.Get(ctx, user.ID, "")
+	require.NoError(t, err)
+	assert.Empty(t, grid.Groups)
+	assert.Equal(t, []uint{third.ID, second.ID, first.ID}, tileIDs(grid.Tiles))
+
+	// Tiles of deleted bookmarks drop off the grid
+	require.NoError(t, factory.DB().Delete(&database.Bookmark{}, news.ID).Error)
+	grid, err = service.Get(ctx, user.ID, "")
+	require.NoError(t, err)
+	assert.Equal(t, []uint{third.ID, first.ID}, tileIDs(grid.Tiles))
+}
+
+func TestArrange(t *testing.T) {
+	ctx := context.Background()
+	service, factory, _, _ := setupService(t)
+	user := factory.User()
+	var tiles []uint
+	for i := 0; i < 4; i++ {
+		tile, err := service.Pin(ctx, user.ID, "", PinRequest{BookmarkID: factory.Bookmark(user.ID).ID})
+		require.NoError(t, err)
+		tiles = append(tiles, tile.ID)
+	}
+	work, err := service.CreateGroup(ctx, user.ID, "", GroupRequest{Name: "Work"})
+	require.NoError(t, err)
+	home, err := service.CreateGroup(ctx, user.ID, "", GroupRequest{Name: "Home"})
+	require.NoError(t, err)
+
+	grid, err := service.Arrange(ctx, user.ID, "", ArrangeRequest{
+		Groups: []uint{home.ID},
+		Tiles: []Placement{
+			{ID: tiles[3], GroupID: &work.ID},
+			{ID: tiles[1], GroupID: &work.ID},
+			{ID: tiles[2]},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, grid.Groups, 2)
+	assert.Equal(t, home.ID, grid.Groups[0].ID, "listed groups come first")
+	assert.Equal(t, []uint{tiles[3], tiles[1]}, tileIDs(grid.Groups[1].Tiles))
+	assert.Equal(t, []uint{tiles[2], tiles[0]}, tileIDs(grid.Tiles), "unlisted tiles follow the listed ones")
+
+	_, err = service.Arrange(ctx, user.ID, "", ArrangeRequest{Tiles: []Placement{{ID: tiles[0]}, {ID: tiles[0]}}})
+	assert.ErrorIs(t, err, ErrInvalidLayout)
+	_, err = service.Arrange(ctx, user.ID, "", ArrangeRequest{Groups: []uint{999}})
+	assert.ErrorIs(t, err, ErrInvalidLayout)
+}
+
+func TestDeviceLayoutSyncAndThumbnails(t *testing.T) {
+	ctx := context.Background()
+	service, factory, sync, thumbnails := setupService(t)
+	user := factory.User()
+	captured := factory.Bookmark(user.ID, func(b *database.Bookmark) { b.Screenshot = "https://cdn.test/shot.png" })
+	uncaptured := factory.Bookmark(user.ID)
+	hidden := factory.Bookmark(user.ID)
+
+	big, err := service.Pin(ctx, user.ID, "laptop", PinRequest{BookmarkID: captured.ID, Size: database.SpeedDialLarge})
+	require.NoError(t, err)
+	small, err := service.Pin(ctx, user.ID, "laptop", PinRequest{BookmarkID: uncaptured.ID})
+	require.NoError(t, err)
+	gone, err := service.Pin(ctx, user.ID, "laptop", PinRequest{BookmarkID: hidden.ID})
+	require.NoError(t, err)
+	assert.Empty(t, thumbnails.bookmarkIDs, "bookmarks with a screenshot need no capture")
+
+	// The phone shows the small tile large and first, without the third
+	_, err = service.SetDeviceLayout(ctx, user.ID, "phone", []OverrideRequest{
+		{TileID: small.ID, Position: intPtr(0), Size: database.SpeedDialLarge},
+		{TileID: gone.ID, Hidden: true},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{uncaptured.ID}, thumbnails.bookmarkIDs, "large tiles without a screenshot get one")
+
+	phone, err := service.Get(ctx, user.ID, "phone")
+	require.NoError(t, err)
+	assert.Equal(t, []uint{small.ID, big.ID}, tileIDs(phone.Tiles))
+	assert.Equal(t, database.SpeedDialLarge, phone.Tiles[0].Size)
+	shared, err := service.Get(ctx, user.ID, "laptop")
+	require.NoError(t, err)
+	assert.Equal(t, []uint{big.ID, small.ID, gone.ID}, tileIDs(shared.Tiles), "other devices keep the shared layout")
+	assert.Equal(t, "https://cdn.test/shot.png", shared.Tiles[0].Thumbnail)
+	assert.Empty(t, shared.Tiles[1].Thumbnail)
+
+	_, err = service.SetDeviceLayout(ctx, user.ID, "phone", []OverrideRequest{{TileID: 999}})
+	assert.ErrorIs(t, err, ErrInvalidLayout)
+
+	// Every change reaches the other devices with the whole layout
+	require.Len(t, sync.events, 4)
+	last := sync.events[3]
+	assert.Equal(t, SyncEventChanged, last.Type)
+	assert.Equal(t, "phone", last.DeviceID)
+	assert.Equal(t, syncResourceID, last.ResourceID)
+	var layout Layout
+	require.NoError(t, json.Unmarshal([]byte(last.Data), &layout))
+	assert.Equal(t, []uint{big.ID, small.ID, gone.ID}, tileIDs(layout.Tiles))
+	require.Len(t, layout.Overrides, 2)
+	assert.Equal(t, "phone", layout.Overrides[0].DeviceID)
+
+	require.NoError(t, service.Unpin(ctx, user.ID, "", small.ID))
+	overrides, err := service.DeviceLayout(ctx, user.ID, "phone")
+	require.NoError(t, err)
+	assert.Len(t, overrides, 1, "unpinning removes the tile's overrides")
+	assert.Equal(t, serverDevice, sync.events[4].DeviceID)
+}
@@ -0,0 +1,63 @@
+package storagegc
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"bookmark-sync-service/backend/pkg/utils"
+)
+
+// Handler serves the storage garbage collector to admins
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new storage GC handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterAdminRoutes registers storage GC routes on an admin-only group
+func (h *Handler) RegisterAdminRoutes(router *gin.RouterGroup) {
+	router.GET("/storage-gc", h.GetReport)
+	router.POST("/storage-gc/dry-run", h.DryRun)
+}
+
+// GetReport returns the collector's settings, marked orphans and last runs
+// @Summary Get the storage garbage collection report
+// @Tags admin
+// @Produce json
+// @Success 200 {object} Report
+// @Router /admin/storage-gc [get]
+func (h *Handler) GetReport(c *gin.Context) {
+	report, err := h.service.Report(c.Request.Context())
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error(), nil)
+		return
+	}
+	utils.SuccessResponse(c, report, "Storage GC report retrieved")
+}
+
+// DryRun reports which stored objects are orphaned without marking or
+// deleting them
+// @Summary Dry-run the storage garbage collector
+// @Description Lists the managed prefixes and counts the objects no row references, and those past the grace period
+// @Tags admin
+// @Produce json
+// @Success 200 {object} Run
+// @Failure 503 {object} utils.ErrorResponse
+// @Router /admin/storage-gc/dry-run [post]
+func (h *Handler) DryRun(c *gin.Context) {
+	run, err := h.service.Collect(c.Request.Context(), true)
+	if err != nil {
+		if errors.Is(err, ErrNoObjectStore) {
+			utils.ErrorResponse(c, http.StatusServiceUnavailable, "STORAGE_GC_UNAVAILABLE", err.Error(), nil)
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error(), nil)
+		return
+	}
+	utils.SuccessResponse(c, run, "Storage GC dry run completed")
+}
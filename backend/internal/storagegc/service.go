@@ -0,0 +1,322 @@
+package storagegc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+// ErrNoObjectStore is returned when the storage client can't list objects
+var ErrNoObjectStore = errors.New("storage client does not support listing objects")
+
+// ObjectStore lists and deletes objects, e.g. the MinIO storage client
+type ObjectStore interface {
+	ListObjects(ctx context.Context, prefix string) ([]string, error)
+	DeleteFile(ctx context.Context, objectName string) error
+}
+
+// StoreOf returns the storage client as an ObjectStore, or nil when it
+// can't list and delete objects
+func StoreOf(client interface{}) ObjectStore {
+	store, _ := client.(ObjectStore)
+	return store
+}
+
+// Reference is a column that holds the keys or URLs of stored objects
+type Reference struct {
+	Table  string `json:"table"`
+	Column string `json:"column"`
+}
+
+// Prefix is a storage prefix the collector manages, with the columns that
+// reference its objects. Soft deleted rows still count, so an object is
+// only collected once its row is purged
+type Prefix struct {
+	Prefix     string      `json:"prefix"`
+	References []Reference `json:"references"`
+}
+
+// Prefixes are the managed prefixes. Objects elsewhere in the bucket are
+// never touched
+var Prefixes = []Prefix{
+	{Prefix: "screenshots/", References: []Reference{{"bookmarks", "screenshot"}, {"screenshot_jobs", "screenshot_url"}}},
+	{Prefix: "avatars/", References: []Reference{{"users", "avatar"}}},
+	{Prefix: "backups/", References: []Reference{{"backup_jobs", "file_path"}, {"backup_jobs", "remote_url"}}},
+}
+
+// PrefixResult is what a run did, or would do, under one prefix
+type PrefixResult struct {
+	Prefix     string `json:"prefix"`
+	Objects    int    `json:"objects"`
+	Referenced int    `json:"referenced"`
+	Missing    int    `json:"missing"`   // referenced objects not in storage
+	Orphans    int    `json:"orphans"`   // unreferenced objects
+	Marked     int    `json:"marked"`    // orphans seen for the first time
+	Expired    int    `json:"expired"`   // orphans marked longer than the grace period
+	Deleted    int    `json:"deleted"`   // always 0 in a dry run
+	Reclaimed  int    `json:"reclaimed"` // marked objects referenced again or already gone
+	Deferred   int    `json:"deferred"`  // expired orphans left for the next run
+	Skipped    string `json:"skipped,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Run is one run of the collector
+type Run struct {
+	database.StorageGCRun
+	Results []PrefixResult `json:"results"`
+}
+
+// Report is the collector's settings, the orphans it has marked and its
+// last runs
+type Report struct {
+	Enabled     bool     `json:"enabled"`
+	Prefixes    []Prefix `json:"prefixes"`
+	Interval    int      `json:"interval"`     // minutes between runs, 0 when disabled
+	GracePeriod int      `json:"grace_period"` // hours
+	DryRun      bool     `json:"dry_run"`
+	Marked      int64    `json:"marked"` // orphans waiting out the grace period
+	LastRun     *Run     `json:"last_run"`
+	LastDryRun  *Run     `json:"last_dry_run"`
+}
+
+// Service finds stored objects whose rows are gone, marks them and deletes
+// them once they stay unreferenced past the grace period
+type Service struct {
+	cfg    config.StorageGCConfig
+	db     *gorm.DB
+	store  ObjectStore
+	logger *zap.Logger
+	now    func() time.Time
+}
+
+// NewService creates a storage garbage collector. A nil store disables it
+func NewService(cfg config.StorageGCConfig, db *gorm.DB, store ObjectStore, logger *zap.Logger) *Service {
+	return &Service{cfg: cfg, db: db, store: store, logger: logger, now: time.Now}
+}
+
+// RunCollect collects orphans, as a dry run when configured so. It backs
+// the worker's storage GC job
+func (s *Service) RunCollect(ctx context.Context) error {
+	run, err := s.Collect(ctx, s.cfg.DryRun)
+	if err != nil {
+		return err
+	}
+	for _, result := range run.Results {
+		if result.Marked > 0 || result.Deleted > 0 || result.Expired > 0 {
+			s.logger.Info("Storage garbage collected",
+				zap.String("prefix", result.Prefix),
+				zap.Int("orphans", result.Orphans),
+				zap.Int("marked", result.Marked),
+				zap.Int("deleted", result.Deleted),
+				zap.Bool("dry_run", run.DryRun))
+		}
+	}
+	if run.Error != "" {
+		return errors.New(run.Error)
+	}
+	return nil
+}
+
+// Collect checks every managed prefix and records the run. A dry run only
+// counts; otherwise new orphans are marked and expired ones deleted. A
+// prefix that fails is reported without stopping the others
+func (s *Service) Collect(ctx context.Context, dryRun bool) (*Run, error) {
+	if s.store == nil {
+		return nil, ErrNoObjectStore
+	}
+	run := &Run{StorageGCRun: database.StorageGCRun{DryRun: dryRun, StartedAt: s.now()}}
+
+	budget := s.cfg.MaxDeletes
+	if budget <= 0 {
+		budget = 1000
+	}
+	var failed []string
+	for _, prefix := range Prefixes {
+		result := s.collect(ctx, prefix, dryRun, &budget)
+		if result.Error != "" {
+			failed = append(failed, result.Prefix)
+		}
+		run.Results = append(run.Results, result)
+	}
+	if len(failed) > 0 {
+		run.Error = fmt.Sprintf("storage GC failed for %v", failed)
+	}
+	run.FinishedAt = s.now()
+
+	results, err := json.Marshal(run.Results)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode storage GC results: %w", err)
+	}
+	run.StorageGCRun.Results = string(results)
+	if err := s.db.WithContext(ctx).Create(&run.StorageGCRun).Error; err != nil {
+		return nil, fmt.Errorf("failed to record storage GC run: %w", err)
+	}
+	return run, nil
+}
+
+// collect handles one prefix, deleting at most budget objects
+func (s *Service) collect(ctx context.Context, prefix Prefix, dryRun bool, budget *int) PrefixResult {
+	result := PrefixResult{Prefix: prefix.Prefix}
+	db := s.db.WithContext(ctx)
+
+	referenced := make(map[string]bool)
+	for _, ref := range prefix.References {
+		// Without every referencing table, referenced objects would look
+		// orphaned
+		if !db.Migrator().HasTable(ref.Table) {
+			result.Skipped = "table " + ref.Table + " not found"
+			return result
+		}
+		var values []string
+		if err := db.Table(ref.Table).Where(ref.Column+" LIKE ?", "%"+prefix.Prefix+"%").Pluck(ref.Column, &values).Error; err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		for _, value := range values {
+			referenced[objectKey(value, prefix.Prefix)] = true
+		}
+	}
+
+	keys, err := s.store.ListObjects(ctx, prefix.Prefix)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Objects = len(keys)
+
+	var marks []database.StorageOrphan
+	if err := db.Where("prefix = ?", prefix.Prefix).Find(&marks).Error; err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	markedAt := make(map[string]time.Time, len(marks))
+	for _, mark := range marks {
+		markedAt[mark.ObjectKey] = mark.MarkedAt
+	}
+
+	now := s.now()
+	cutoff := now.Add(-time.Duration(s.cfg.GracePeriod) * time.Hour)
+	listed := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		listed[key] = true
+		if referenced[key] {
+			result.Referenced++
+			continue
+		}
+		result.Orphans++
+
+		at, marked := markedAt[key]
+		switch {
+		case !marked:
+			result.Marked++
+			if !dryRun {
+				if err := db.Create(&database.StorageOrphan{ObjectKey: key, Prefix: prefix.Prefix, MarkedAt: now}).Error; err != nil {
+					result.Error = err.Error()
+					return result
+				}
+			}
+		case at.Before(cutoff):
+			result.Expired++
+			if dryRun {
+				continue
+			}
+			if *budget <= 0 {
+				result.Deferred++
+				continue
+			}
+			if err := s.store.DeleteFile(ctx, key); err != nil {
+				result.Error = err.Error()
+				return result
+			}
+			*budget--
+			result.Deleted++
+			if err := db.Where("object_key = ?", key).Delete(&database.StorageOrphan{}).Error; err != nil {
+				result.Error = err.Error()
+				return result
+			}
+		}
+	}
+
+	for key := range referenced {
+		if !listed[key] {
+			result.Missing++
+		}
+	}
+
+	// Marks of objects that are referenced again, or were deleted by
+	// someone else, are dropped so a later orphaning starts a new grace period
+	var reclaimed []uint
+	for _, mark := range marks {
+		if referenced[mark.ObjectKey] || !listed[mark.ObjectKey] {
+			reclaimed = append(reclaimed, mark.ID)
+		}
+	}
+	result.Reclaimed = len(reclaimed)
+	if len(reclaimed) > 0 && !dryRun {
+		if err := db.Delete(&database.StorageOrphan{}, reclaimed).Error; err != nil {
+			result.Error = err.Error()
+		}
+	}
+	return result
+}
+
+// objectKey returns the key a reference holds. URLs carry the key after the
+// bucket, and signed ones a query after it
+func objectKey(value, prefix string) string {
+	key := value[strings.Index(value, prefix):]
+	if i := strings.IndexByte(key, '?'); i >= 0 {
+		key = key[:i]
+	}
+	return key
+}
+
+// LastRun returns the latest run of the given kind, or nil before the first
+func (s *Service) LastRun(ctx context.Context, dryRun bool) (*Run, error) {
+	var last database.StorageGCRun
+	err := s.db.WithContext(ctx).Where("dry_run = ?", dryRun).Order("started_at DESC, id DESC").First(&last).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last storage GC run: %w", err)
+	}
+
+	run := &Run{StorageGCRun: last}
+	if last.Results != "" {
+		if err := json.Unmarshal([]byte(last.Results), &run.Results); err != nil {
+			return nil, fmt.Errorf("failed to decode storage GC results: %w", err)
+		}
+	}
+	return run, nil
+}
+
+// Report returns the collector's settings and last runs
+func (s *Service) Report(ctx context.Context) (*Report, error) {
+	report := &Report{
+		Enabled:     s.store != nil,
+		Prefixes:    Prefixes,
+		Interval:    s.cfg.Interval,
+		GracePeriod: s.cfg.GracePeriod,
+		DryRun:      s.cfg.DryRun,
+	}
+	if err := s.db.WithContext(ctx).Model(&database.StorageOrphan{}).Count(&report.Marked).Error; err != nil {
+		return nil, fmt.Errorf("failed to count storage orphans: %w", err)
+	}
+	var err error
+	if report.LastRun, err = s.LastRun(ctx, false); err != nil {
+		return nil, err
+	}
+	if report.LastDryRun, err = s.LastRun(ctx, true); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
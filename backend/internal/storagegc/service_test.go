@@ -0,0 +1,176 @@
+package storagegc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/internal/automation"
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/testfactory"
+)
+
+type fakeStore struct {
+	objects map[string]bool
+}
+
+func (f *fakeStore) ListObjects(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	for key := range f.objects {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (f *fakeStore) DeleteFile(ctx context.Context, objectName string) error {
+	delete(f.objects, objectName)
+	return nil
+}
+
+func setupService(t *testing.T) (*Service, *fakeStore, *gorm.DB, *time.Time) {
+	db := testfactory.NewDB(t)
+	factory := testfactory.New(t, db)
+	owner := factory.User(func(u *database.User) { u.Avatar = "https://cdn.example.com/bucket/avatars/user_1_1.png" })
+	factory.Bookmark(owner.ID, func(b *database.Bookmark) {
+		b.Screenshot = "https://cdn.example.com/bucket/screenshots/kept.png?X-Amz-Expires=60"
+	})
+	purged := factory.Bookmark(owner.ID, func(b *database.Bookmark) { b.Screenshot = "https://cdn.example.com/bucket/screenshots/trashed.png" })
+	require.NoError(t, db.Delete(purged).Error)
+	require.NoError(t, db.Create(&automation.BackupJob{UserID: "1", Type: "full", Status: "completed", FilePath: "/backups/1/full_1.tar.gz"}).Error)
+
+	store := &fakeStore{objects: map[string]bool{
+		"screenshots/kept.png":    true,
+		"screenshots/trashed.png": true,
+		"screenshots/orphan.png":  true,
+		"avatars/user_1_1.png":    true,
+		"avatars/user_1_0.png":    true,
+		"backups/1/full_1.tar.gz": true,
+		"exports/other.json":      true,
+	}}
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	service := NewService(config.StorageGCConfig{Interval: 360, GracePeriod: 72, MaxDeletes: 1}, db, store, zap.NewNop())
+	service.now = func() time.Time { return now }
+	return service, store, db, &now
+}
+
+func results(run *Run) map[string]PrefixResult {
+	byPrefix := map[string]PrefixResult{}
+	for _, result := range run.Results {
+		byPrefix[result.Prefix] = result
+	}
+	return byPrefix
+}
+
+func TestService_Collect(t *testing.T) {
+	service, store, db, now := setupService(t)
+	ctx := context.Background()
+
+	dry, err := service.Collect(ctx, true)
+	require.NoError(t, err)
+	assert.Equal(t, PrefixResult{Prefix: "screenshots/", Objects: 3, Referenced: 2, Orphans: 1, Marked: 1}, results(dry)["screenshots/"])
+	assert.Equal(t, 1, results(dry)["avatars/"].Orphans)
+	assert.Zero(t, results(dry)["backups/"].Orphans)
+	var marks int64
+	db.Model(&database.StorageOrphan{}).Count(&marks)
+	assert.Zero(t, marks, "a dry run marks nothing")
+
+	run, err := service.Collect(ctx, false)
+	require.NoError(t, err)
+	assert.Equal(t, 1, results(run)["screenshots/"].Marked)
+	assert.Equal(t, 1, results(run)["avatars/"].Marked)
+	assert.Len(t, store.objects, 7, "orphans are only marked")
+
+	// An orphan that is referenced again loses its mark
+	require.NoError(t, db.Model(&database.User{}).Where("1 = 1").Update("avatar", "avatars/user_1_0.png").Error)
+	*now = now.Add(73 * time.Hour)
+	run, err = service.Collect(ctx, false)
+	require.NoError(t, err)
+	assert.Equal(t, 1, results(run)["screenshots/"].Deleted)
+	assert.Equal(t, 1, results(run)["avatars/"].Reclaimed)
+	assert.Equal(t, 1, results(run)["avatars/"].Marked, "the previous avatar is now the orphan")
+	assert.False(t, store.objects["screenshots/orphan.png"])
+	assert.True(t, store.objects["screenshots/trashed.png"], "soft deleted rows still reference their objects")
+	assert.True(t, store.objects["exports/other.json"], "unmanaged prefixes are left alone")
+
+	report, err := service.Report(ctx)
+	require.NoError(t, err)
+	assert.True(t, report.Enabled)
+	assert.Equal(t, int64(1), report.Marked)
+	require.NotNil(t, report.LastRun)
+	assert.Equal(t, run.ID, report.LastRun.ID)
+	require.NotNil(t, report.LastDryRun)
+	assert.Equal(t, dry.ID, report.LastDryRun.ID)
+}
+
+func TestService_MaxDeletes(t *testing.T) {
+	service, store, db, now := setupService(t)
+	ctx := context.Background()
+	require.NoError(t, db.Unscoped().Where("1 = 1").Delete(&database.Bookmark{}).Error)
+
+	_, err := service.Collect(ctx, false)
+	require.NoError(t, err)
+	*now = now.Add(73 * time.Hour)
+	run, err := service.Collect(ctx, false)
+	require.NoError(t, err)
+	assert.Equal(t, 1, results(run)["screenshots/"].Deleted)
+	assert.Equal(t, 2, results(run)["screenshots/"].Deferred)
+	assert.Equal(t, 1, results(run)["avatars/"].Deferred)
+	assert.Len(t, store.objects, 6)
+
+	_, err = NewService(config.StorageGCConfig{}, db, nil, zap.NewNop()).Collect(ctx, true)
+	assert.ErrorIs(t, err, ErrNoObjectStore)
+}
+
+func TestService_Missing(t *testing.T) {
+	service, store, _, _ := setupService(t)
+	delete(store.objects, "screenshots/kept.png")
+
+	run, err := service.Collect(context.Background(), true)
+	require.NoError(t, err)
+	assert.Equal(t, 1, results(run)["screenshots/"].Missing)
+	assert.Zero(t, results(run)["avatars/"].Missing)
+}
+
+func TestHandler_Admin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service, _, _, _ := setupService(t)
+
+	router := gin.New()
+	NewHandler(service).RegisterAdminRoutes(router.Group("/admin"))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/storage-gc/dry-run", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var dry struct {
+		Data Run `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &dry))
+	assert.True(t, dry.Data.DryRun)
+	assert.Len(t, dry.Data.Results, len(Prefixes))
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/storage-gc", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var report struct {
+		Data Report `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, 72, report.Data.GracePeriod)
+	require.NotNil(t, report.Data.LastDryRun)
+	assert.Nil(t, report.Data.LastRun)
+}
@@ -0,0 +1,65 @@
+package sync
+
+import (
+	"context"
+	"strconv"
+
+	"go.uber.org/zap"
+
+	"bookmark-sync-service/backend/pkg/clientid"
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+// resolveClientID lets devices refer to bookmarks and collections created
+// offline by their client UUID. An event naming a resource by client ID is
+// mapped to the server ID once the resource exists, and an event naming it
+// by server ID gets its client ID, so every device can match the event to
+// its own copy. Lookups that fail leave the event as it is
+func (s *Service) resolveClientID(ctx context.Context, event *SyncEvent) {
+	var model interface{}
+	switch {
+	case isBookmarkEvent(event.Type):
+		model = &database.Bookmark{}
+	case isCollectionEvent(event.Type):
+		model = &database.Collection{}
+	default:
+		return
+	}
+	userID, err := strconv.ParseUint(event.UserID, 10, 32)
+	if err != nil {
+		return
+	}
+
+	if id, err := clientid.Normalize(event.ResourceID); err == nil && id != "" {
+		event.ClientID = id
+		var ids []uint
+		if err := s.db.WithContext(ctx).Model(model).Unscoped().
+			Where("user_id = ? AND client_id = ?", userID, id).Limit(1).Pluck("id", &ids).Error; err != nil {
+			s.logger.Warn("Failed to resolve client ID", zap.String("client_id", id), zap.Error(err))
+			return
+		}
+		if len(ids) > 0 {
+			event.ResourceID = strconv.FormatUint(uint64(ids[0]), 10)
+		}
+		return
+	}
+
+	if id, err := clientid.Normalize(event.ClientID); err == nil && id != "" {
+		event.ClientID = id
+		return
+	}
+	event.ClientID = ""
+	resourceID, err := strconv.ParseUint(event.ResourceID, 10, 32)
+	if err != nil {
+		return
+	}
+	var clientIDs []*string
+	if err := s.db.WithContext(ctx).Model(model).Unscoped().
+		Where("id = ? AND user_id = ?", resourceID, userID).Limit(1).Pluck("client_id", &clientIDs).Error; err != nil {
+		s.logger.Warn("Failed to look up client ID", zap.String("resource_id", event.ResourceID), zap.Error(err))
+		return
+	}
+	if len(clientIDs) > 0 && clientIDs[0] != nil {
+		event.ClientID = *clientIDs[0]
+	}
+}
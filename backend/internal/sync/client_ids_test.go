@@ -0,0 +1,53 @@
+package sync
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+func TestResolveClientID(t *testing.T) {
+	db, err := database.SetupTestDB()
+	require.NoError(t, err)
+	t.Cleanup(func() { database.CleanupTestDB(db) })
+	service := NewService(db, &MockRedisClient{}, zap.NewNop())
+	ctx := context.Background()
+
+	user := database.User{Email: "offline@example.com", Username: "offline", SupabaseID: "offline"}
+	require.NoError(t, db.Create(&user).Error)
+	userID := strconv.FormatUint(uint64(user.ID), 10)
+
+	clientID := "3f2504e0-4f89-41d3-9a0c-0305e82c3301"
+	bookmark := database.Bookmark{UserID: user.ID, ClientID: &clientID, URL: "https://example.com", Title: "Offline"}
+	require.NoError(t, db.Create(&bookmark).Error)
+	bookmarkID := strconv.FormatUint(uint64(bookmark.ID), 10)
+
+	// An event naming the bookmark by client ID gets its server ID
+	event := &SyncEvent{Type: SyncEventBookmarkUpdated, UserID: userID, ResourceID: "3F2504E0-4F89-41D3-9A0C-0305E82C3301", Action: "update", DeviceID: "laptop"}
+	require.NoError(t, service.QueueOfflineEvent(ctx, event))
+	assert.Equal(t, bookmarkID, event.ResourceID)
+	assert.Equal(t, clientID, event.ClientID)
+
+	// And one naming it by server ID gets its client ID
+	event = &SyncEvent{Type: SyncEventBookmarkDeleted, UserID: userID, ResourceID: bookmarkID}
+	service.resolveClientID(ctx, event)
+	assert.Equal(t, clientID, event.ClientID)
+
+	// Resources not created yet keep their client ID as resource ID
+	pending := "9b2e1c7a-0d4f-4a53-8f0e-6c1d2b3a4f5e"
+	event = &SyncEvent{Type: SyncEventCollectionCreated, UserID: userID, ResourceID: pending}
+	service.resolveClientID(ctx, event)
+	assert.Equal(t, pending, event.ResourceID)
+	assert.Equal(t, pending, event.ClientID)
+
+	// Other users' client IDs are never resolved
+	event = &SyncEvent{Type: SyncEventBookmarkUpdated, UserID: "999", ResourceID: clientID}
+	service.resolveClientID(ctx, event)
+	assert.Equal(t, clientID, event.ResourceID)
+}
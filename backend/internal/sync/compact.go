@@ -0,0 +1,126 @@
+package sync
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Sync response formats
+const (
+	FormatFull    = "full"
+	FormatCompact = "compact"
+)
+
+// Numeric wire codes of the compact format. Clients persist these, so a
+// code must never be reused or renumbered; new values get new codes
+var (
+	eventTypeCodes = map[SyncEventType]int{
+		SyncEventBookmarkCreated:   1,
+		SyncEventBookmarkUpdated:   2,
+		SyncEventBookmarkDeleted:   3,
+		SyncEventCollectionCreated: 4,
+		SyncEventCollectionUpdated: 5,
+		SyncEventCollectionDeleted: 6,
+	}
+	statusCodes = map[SyncStatus]int{
+		SyncStatusPending: 1,
+		SyncStatusSynced:  2,
+		SyncStatusFailed:  3,
+	}
+	actionCodes = map[string]int{
+		"create": 1,
+		"update": 2,
+		"delete": 3,
+	}
+)
+
+// compactFieldKeys maps the event fields accepted by fields= to their
+// compact keys
+var compactFieldKeys = map[string]string{
+	"id":          "i",
+	"type":        "t",
+	"resource_id": "r",
+	"action":      "a",
+	"data":        "d",
+	"device_id":   "v",
+	"status":      "s",
+	"timestamp":   "ts",
+}
+
+// CompactEvent is a sync event with short keys and numeric enums. Type,
+// action and status are sent as their numeric code, or as the plain string
+// when the value has no code yet, so older clients never lose an event
+type CompactEvent struct {
+	ID         uint            `json:"i"`
+	Type       interface{}     `json:"t"`
+	ResourceID string          `json:"r"`
+	Action     interface{}     `json:"a"`
+	Data       json.RawMessage `json:"d,omitempty"`
+	DeviceID   string          `json:"v,omitempty"`
+	Status     interface{}     `json:"s,omitempty"`
+	Timestamp  int64           `json:"ts"` // unix milliseconds
+}
+
+// CompactDelta is the compact form of a delta sync batch
+type CompactDelta struct {
+	Events    []*CompactEvent `json:"e"`
+	Timestamp int64           `json:"ts"` // unix milliseconds
+}
+
+// ToCompact converts a delta sync batch to the compact format
+func (d *DeltaSync) ToCompact() *CompactDelta {
+	events := make([]*CompactEvent, 0, len(d.Events))
+	for _, event := range d.Events {
+		events = append(events, event.ToCompact())
+	}
+	return &CompactDelta{Events: events, Timestamp: d.Timestamp.UnixMilli()}
+}
+
+// ToCompact converts an event to the compact format. Data is embedded as
+// JSON rather than as an escaped string
+func (e *SyncEvent) ToCompact() *CompactEvent {
+	compact := &CompactEvent{
+		ID:         e.ID,
+		Type:       enumCode(eventTypeCodes[e.Type], string(e.Type)),
+		ResourceID: e.ResourceID,
+		Action:     enumCode(actionCodes[e.Action], e.Action),
+		DeviceID:   e.DeviceID,
+		Timestamp:  e.Timestamp.UnixMilli(),
+	}
+	if e.Status != "" {
+		compact.Status = enumCode(statusCodes[e.Status], string(e.Status))
+	}
+	if e.Data != "" {
+		if json.Valid([]byte(e.Data)) {
+			compact.Data = json.RawMessage(e.Data)
+		} else {
+			compact.Data, _ = json.Marshal(e.Data)
+		}
+	}
+	return compact
+}
+
+func enumCode(code int, name string) interface{} {
+	if code == 0 {
+		return name
+	}
+	return code
+}
+
+// eventFieldKeys validates a fields= list against the event fields and
+// returns the keys to keep in the requested format
+func eventFieldKeys(fields []string, format string) ([]string, error) {
+	keys := make([]string, 0, len(fields))
+	for _, field := range fields {
+		key, ok := compactFieldKeys[field]
+		if !ok {
+			return nil, fmt.Errorf("unknown field: %s", field)
+		}
+		if format == FormatCompact {
+			keys = append(keys, key)
+		} else {
+			keys = append(keys, field)
+		}
+	}
+	return keys, nil
+}
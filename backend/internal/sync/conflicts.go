@@ -0,0 +1,420 @@
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"bookmark-sync-service/backend/pkg/database"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// SyncStatusConflict marks an event held back by a conflict. It is not
+// published or served in delta syncs
+const SyncStatusConflict SyncStatus = "conflict"
+
+// Conflict statuses and resolutions
+const (
+	ConflictUnresolved = "unresolved"
+	ConflictResolved   = "resolved"
+
+	ResolutionLocal  = "local"
+	ResolutionRemote = "remote"
+	ResolutionMerge  = "merge"
+)
+
+// Conflict errors
+var (
+	ErrConflictNotFound        = errors.New("sync conflict not found")
+	ErrConflictResolved        = errors.New("sync conflict is already resolved")
+	ErrInvalidResolution       = errors.New("resolution must be local, remote or merge")
+	ErrUnresolvedFields        = errors.New("a merge must pick a side or value for every conflicting field")
+	ErrDeleteConflictNotMerged = errors.New("conflicts with a deletion can't be merged; pick a side")
+)
+
+// ConflictError is returned for events held back by a conflict
+type ConflictError struct {
+	Conflict *database.SyncConflict
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("event conflicts with changes from another device (conflict %d)", e.Conflict.ID)
+}
+
+// FieldDiff is a field whose value differs between the two versions
+type FieldDiff struct {
+	Field       string      `json:"field"`
+	Local       interface{} `json:"local"`
+	Remote      interface{} `json:"remote"`
+	Conflicting bool        `json:"conflicting"` // changed on both sides
+}
+
+// ConflictDetail shows both versions of a conflict and how they differ
+type ConflictDetail struct {
+	Conflict *database.SyncConflict `json:"conflict"`
+	Local    *SyncEvent             `json:"local"`
+	Remote   *SyncEvent             `json:"remote"`
+	Diff     []FieldDiff            `json:"diff"`
+}
+
+// ConflictResolution settles a conflict. Resolution local or remote takes
+// that side's value for every conflicting field; merge takes Fields, which
+// names the side of each one, and Data, which overrides values outright.
+// Changes to other fields are kept from both sides either way
+type ConflictResolution struct {
+	Resolution string                 `json:"resolution" binding:"required"`
+	Fields     map[string]string      `json:"fields"`
+	Data       map[string]interface{} `json:"data"`
+	DeviceID   string                 `json:"device_id" binding:"required"`
+}
+
+// isChange reports whether an event changes or deletes an existing resource,
+// the kinds of events that can conflict
+func isChange(eventType SyncEventType) bool {
+	return strings.HasSuffix(string(eventType), "_updated") || isDeletion(eventType)
+}
+
+func isDeletion(eventType SyncEventType) bool {
+	return strings.HasSuffix(string(eventType), "_deleted")
+}
+
+// resourceKind is the part of an event type naming the resource, e.g. bookmark
+func resourceKind(eventType SyncEventType) string {
+	kind, _, _ := strings.Cut(string(eventType), "_")
+	return kind
+}
+
+// detectConflict looks for changes other devices made to the event's
+// resource since its device last synced, which the device could not have
+// seen. Updates of different fields merge cleanly and are not conflicts
+func (s *Service) detectConflict(ctx context.Context, tx *gorm.DB, event *SyncEvent) (*database.SyncConflict, error) {
+	if !isChange(event.Type) {
+		return nil, nil
+	}
+
+	var states []SyncState
+	if err := tx.WithContext(ctx).Where("user_id = ? AND device_id = ?", event.UserID, event.DeviceID).
+		Limit(1).Find(&states).Error; err != nil {
+		return nil, fmt.Errorf("failed to get sync state: %w", err)
+	}
+	if len(states) == 0 {
+		// The device never synced, so there is nothing to compare with
+		return nil, nil
+	}
+
+	kind := resourceKind(event.Type)
+	var remotes []*SyncEvent
+	if err := tx.WithContext(ctx).
+		Where("user_id = ? AND resource_id = ? AND device_id <> ? AND timestamp > ? AND status <> ?",
+			event.UserID, event.ResourceID, event.DeviceID, states[0].LastSyncTime, SyncStatusConflict).
+		Where("type IN ?", []SyncEventType{SyncEventType(kind + "_updated"), SyncEventType(kind + "_deleted")}).
+		Order("timestamp DESC").Limit(1).
+		Find(&remotes).Error; err != nil {
+		return nil, fmt.Errorf("failed to find concurrent changes: %w", err)
+	}
+	if len(remotes) == 0 {
+		return nil, nil
+	}
+	remote := remotes[0]
+
+	var fields []string
+	switch {
+	case isDeletion(event.Type) && isDeletion(remote.Type):
+		return nil, nil
+	case isDeletion(event.Type) || isDeletion(remote.Type):
+		// An edit of a resource the other side deleted
+	default:
+		for _, diff := range diffEventData(event, remote) {
+			if diff.Conflicting {
+				fields = append(fields, diff.Field)
+			}
+		}
+		if len(fields) == 0 {
+			return nil, nil
+		}
+	}
+
+	return &database.SyncConflict{
+		UserID:        event.UserID,
+		ResourceID:    event.ResourceID,
+		EventType:     string(event.Type),
+		RemoteEventID: remote.ID,
+		Fields:        fields,
+		Status:        ConflictUnresolved,
+	}, nil
+}
+
+// holdBack stores an event and, if it conflicts, the conflict with the
+// event marked as held back. Returns the conflict, if any
+func (s *Service) holdBack(ctx context.Context, event *SyncEvent, store func(tx *gorm.DB) error) (*database.SyncConflict, error) {
+	var conflict *database.SyncConflict
+	err := database.WithTransaction(ctx, s.db, func(tx *gorm.DB) error {
+		var err error
+		conflict, err = s.detectConflict(ctx, tx, event)
+		if err != nil {
+			return err
+		}
+		if conflict != nil {
+			event.Status = SyncStatusConflict
+		}
+		if err := store(tx); err != nil {
+			return err
+		}
+		if conflict == nil {
+			return nil
+		}
+		conflict.LocalEventID = event.ID
+		if err := tx.Create(conflict).Error; err != nil {
+			return fmt.Errorf("failed to record sync conflict: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if conflict != nil {
+		s.logger.Info("Sync conflict held back for review",
+			zap.Uint("conflict_id", conflict.ID),
+			zap.String("user_id", event.UserID),
+			zap.String("resource_id", event.ResourceID),
+			zap.String("device_id", event.DeviceID),
+		)
+	}
+	return conflict, nil
+}
+
+// ListConflicts returns a user's conflicts with the given status, or all
+// of them when status is empty, newest first
+func (s *Service) ListConflicts(ctx context.Context, userID, status string) ([]database.SyncConflict, error) {
+	query := s.db.WithContext(ctx).Where("user_id = ?", userID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var conflicts []database.SyncConflict
+	if err := query.Order("created_at DESC, id DESC").Find(&conflicts).Error; err != nil {
+		return nil, fmt.Errorf("failed to list sync conflicts: %w", err)
+	}
+	return conflicts, nil
+}
+
+// GetConflict returns a conflict with both versions and their differences
+func (s *Service) GetConflict(ctx context.Context, userID string, id uint) (*ConflictDetail, error) {
+	conflict, err := s.findConflict(ctx, s.db, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	local, remote, err := s.conflictEvents(ctx, s.db, conflict)
+	if err != nil {
+		return nil, err
+	}
+	return &ConflictDetail{Conflict: conflict, Local: local, Remote: remote, Diff: diffEventData(local, remote)}, nil
+}
+
+// ResolveSyncConflict settles a conflict and publishes the outcome as a new
+// sync event from the resolving device, so every device converges on it
+func (s *Service) ResolveSyncConflict(ctx context.Context, userID string, id uint, resolution *ConflictResolution) (*SyncEvent, error) {
+	switch resolution.Resolution {
+	case ResolutionLocal, ResolutionRemote, ResolutionMerge:
+	default:
+		return nil, ErrInvalidResolution
+	}
+
+	var resolved *SyncEvent
+	err := database.WithTransaction(ctx, s.db, func(tx *gorm.DB) error {
+		conflict, err := s.findConflict(ctx, tx, userID, id)
+		if err != nil {
+			return err
+		}
+		if conflict.Status == ConflictResolved {
+			return ErrConflictResolved
+		}
+		local, remote, err := s.conflictEvents(ctx, tx, conflict)
+		if err != nil {
+			return err
+		}
+
+		resolved, err = resolveEvents(local, remote, conflict.Fields, resolution)
+		if err != nil {
+			return err
+		}
+		resolved.DeviceID = resolution.DeviceID
+		resolved.Status = SyncStatusPending
+		resolved.Timestamp = time.Now()
+		if err := tx.Create(resolved).Error; err != nil {
+			return fmt.Errorf("failed to create resolution event: %w", err)
+		}
+
+		now := time.Now()
+		conflict.Status = ConflictResolved
+		conflict.Resolution = resolution.Resolution
+		conflict.ResolvedEventID = &resolved.ID
+		conflict.ResolvedAt = &now
+		if err := tx.Save(conflict).Error; err != nil {
+			return fmt.Errorf("failed to resolve sync conflict: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.redisClient.PublishSyncEvent(ctx, userID, resolved); err != nil {
+		s.logger.Error("Failed to publish conflict resolution", zap.Error(err))
+	}
+	return resolved, nil
+}
+
+func (s *Service) findConflict(ctx context.Context, tx *gorm.DB, userID string, id uint) (*database.SyncConflict, error) {
+	var conflicts []database.SyncConflict
+	if err := tx.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Limit(1).Find(&conflicts).Error; err != nil {
+		return nil, fmt.Errorf("failed to get sync conflict: %w", err)
+	}
+	if len(conflicts) == 0 {
+		return nil, ErrConflictNotFound
+	}
+	return &conflicts[0], nil
+}
+
+func (s *Service) conflictEvents(ctx context.Context, tx *gorm.DB, conflict *database.SyncConflict) (*SyncEvent, *SyncEvent, error) {
+	var events []*SyncEvent
+	if err := tx.WithContext(ctx).Where("id IN ?", []uint{conflict.LocalEventID, conflict.RemoteEventID}).
+		Find(&events).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to get conflicting events: %w", err)
+	}
+
+	var local, remote *SyncEvent
+	for _, event := range events {
+		switch event.ID {
+		case conflict.LocalEventID:
+			local = event
+		case conflict.RemoteEventID:
+			remote = event
+		}
+	}
+	if local == nil || remote == nil {
+		return nil, nil, ErrConflictNotFound
+	}
+	return local, remote, nil
+}
+
+// resolveEvents builds the event settling a conflict between two versions
+func resolveEvents(local, remote *SyncEvent, fields []string, resolution *ConflictResolution) (*SyncEvent, error) {
+	if isDeletion(local.Type) || isDeletion(remote.Type) {
+		var winner *SyncEvent
+		switch resolution.Resolution {
+		case ResolutionLocal:
+			winner = local
+		case ResolutionRemote:
+			winner = remote
+		default:
+			return nil, ErrDeleteConflictNotMerged
+		}
+		return &SyncEvent{
+			Type:       winner.Type,
+			UserID:     winner.UserID,
+			ResourceID: winner.ResourceID,
+			ClientID:   winner.ClientID,
+			Action:     winner.Action,
+			Data:       winner.Data,
+		}, nil
+	}
+
+	localData, remoteData := eventData(local), eventData(remote)
+
+	// Changes to fields only one side touched are kept
+	merged := make(map[string]interface{}, len(localData)+len(remoteData))
+	for field, value := range remoteData {
+		merged[field] = value
+	}
+	for field, value := range localData {
+		merged[field] = value
+	}
+
+	for _, field := range fields {
+		side := resolution.Resolution
+		if side == ResolutionMerge {
+			side = resolution.Fields[field]
+		}
+		if value, ok := resolution.Data[field]; ok {
+			merged[field] = value
+			continue
+		}
+		switch side {
+		case ResolutionLocal:
+			merged[field] = localData[field]
+		case ResolutionRemote:
+			merged[field] = remoteData[field]
+		default:
+			return nil, ErrUnresolvedFields
+		}
+	}
+	for field, value := range resolution.Data {
+		merged[field] = value
+	}
+
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal merged data: %w", err)
+	}
+	return &SyncEvent{
+		Type:       local.Type,
+		UserID:     local.UserID,
+		ResourceID: local.ResourceID,
+		ClientID:   local.ClientID,
+		Action:     local.Action,
+		Data:       string(data),
+	}, nil
+}
+
+// diffEventData lists the fields whose values differ between two events.
+// A field is conflicting when both events set it, to different values
+func diffEventData(local, remote *SyncEvent) []FieldDiff {
+	localData, remoteData := eventData(local), eventData(remote)
+
+	fields := make(map[string]bool, len(localData)+len(remoteData))
+	for field := range localData {
+		fields[field] = true
+	}
+	for field := range remoteData {
+		fields[field] = true
+	}
+
+	diffs := make([]FieldDiff, 0, len(fields))
+	for field := range fields {
+		localValue, inLocal := localData[field]
+		remoteValue, inRemote := remoteData[field]
+		if inLocal && inRemote && reflect.DeepEqual(localValue, remoteValue) {
+			continue
+		}
+		diffs = append(diffs, FieldDiff{
+			Field:       field,
+			Local:       localValue,
+			Remote:      remoteValue,
+			Conflicting: inLocal && inRemote,
+		})
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Field < diffs[j].Field })
+	return diffs
+}
+
+// eventData decodes the fields an event carries; events without an object
+// of fields carry none
+func eventData(event *SyncEvent) map[string]interface{} {
+	var data map[string]interface{}
+	if event.Data != "" {
+		_ = json.Unmarshal([]byte(event.Data), &data)
+	}
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	return data
+}
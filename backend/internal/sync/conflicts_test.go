@@ -0,0 +1,224 @@
+package sync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// updateEvent is a change of a bookmark's fields from a device
+func updateEvent(userID, resourceID, deviceID string, data map[string]interface{}) *SyncEvent {
+	encoded, _ := json.Marshal(data)
+	return &SyncEvent{
+		Type:       SyncEventBookmarkUpdated,
+		UserID:     userID,
+		ResourceID: resourceID,
+		Action:     "update",
+		Data:       string(encoded),
+		DeviceID:   deviceID,
+	}
+}
+
+func (suite *SyncServiceTestSuite) TestConflicts_DetectAndResolve() {
+	ctx := context.Background()
+	userID := "conflict-user"
+	suite.redisClient.On("PublishSyncEvent", mock.Anything, userID, mock.AnythingOfType("*sync.SyncEvent")).Return(nil)
+
+	// The laptop last synced before the phone renamed the bookmark
+	suite.Require().NoError(suite.service.UpdateSyncState(ctx, userID, "laptop", time.Now().Add(-time.Minute)))
+	phone := updateEvent(userID, "bookmark-1", "phone", map[string]interface{}{"title": "Phone title", "tags": []interface{}{"go"}})
+	suite.Require().NoError(suite.service.CreateSyncEvent(ctx, phone))
+
+	// Changing other fields merges cleanly
+	suite.Require().NoError(suite.service.CreateSyncEvent(ctx, updateEvent(userID, "bookmark-1", "laptop", map[string]interface{}{"notes": "read later"})))
+
+	// When: The laptop changes the same field
+	laptop := updateEvent(userID, "bookmark-1", "laptop", map[string]interface{}{"title": "Laptop title", "tags": []interface{}{"go"}, "description": "From the laptop"})
+	err := suite.service.CreateSyncEvent(ctx, laptop)
+
+	// Then: The change is held back as a conflict instead of being synced
+	var conflictErr *ConflictError
+	suite.Require().True(errors.As(err, &conflictErr))
+	conflict := conflictErr.Conflict
+	suite.Equal([]string{"title"}, []string(conflict.Fields))
+	suite.Equal(laptop.ID, conflict.LocalEventID)
+	suite.Equal(phone.ID, conflict.RemoteEventID)
+	suite.Equal(SyncStatusConflict, laptop.Status)
+
+	delta, err := suite.service.GetDeltaSync(ctx, userID, "phone", time.Now().Add(-time.Hour))
+	suite.Require().NoError(err)
+	for _, event := range delta.Events {
+		suite.NotEqual(laptop.ID, event.ID)
+	}
+
+	conflicts, err := suite.service.ListConflicts(ctx, userID, ConflictUnresolved)
+	suite.Require().NoError(err)
+	suite.Len(conflicts, 1)
+
+	// And: Both versions are shown with their differences
+	detail, err := suite.service.GetConflict(ctx, userID, conflict.ID)
+	suite.Require().NoError(err)
+	suite.Equal(laptop.ID, detail.Local.ID)
+	suite.Equal(phone.ID, detail.Remote.ID)
+	suite.Equal([]FieldDiff{
+		{Field: "description", Local: "From the laptop"},
+		{Field: "title", Local: "Laptop title", Remote: "Phone title", Conflicting: true},
+	}, detail.Diff)
+
+	_, err = suite.service.GetConflict(ctx, "someone-else", conflict.ID)
+	suite.Equal(ErrConflictNotFound, err)
+
+	// And: A merge has to settle every conflicting field
+	_, err = suite.service.ResolveSyncConflict(ctx, userID, conflict.ID, &ConflictResolution{Resolution: ResolutionMerge, DeviceID: "laptop"})
+	suite.Equal(ErrUnresolvedFields, err)
+	_, err = suite.service.ResolveSyncConflict(ctx, userID, conflict.ID, &ConflictResolution{Resolution: "newest", DeviceID: "laptop"})
+	suite.Equal(ErrInvalidResolution, err)
+
+	resolved, err := suite.service.ResolveSyncConflict(ctx, userID, conflict.ID, &ConflictResolution{
+		Resolution: ResolutionMerge,
+		Fields:     map[string]string{"title": ResolutionRemote},
+		DeviceID:   "laptop",
+	})
+	suite.Require().NoError(err)
+	suite.Equal(SyncEventBookmarkUpdated, resolved.Type)
+	suite.Equal("laptop", resolved.DeviceID)
+	suite.JSONEq(`{"title":"Phone title","tags":["go"],"description":"From the laptop"}`, resolved.Data)
+
+	// And: The resolution syncs to the other devices
+	delta, err = suite.service.GetDeltaSync(ctx, userID, "phone", time.Now().Add(-time.Hour))
+	suite.Require().NoError(err)
+	suite.Require().NotEmpty(delta.Events)
+	suite.Equal(resolved.ID, delta.Events[len(delta.Events)-1].ID)
+
+	conflicts, err = suite.service.ListConflicts(ctx, userID, ConflictResolved)
+	suite.Require().NoError(err)
+	suite.Require().Len(conflicts, 1)
+	suite.Equal(ResolutionMerge, conflicts[0].Resolution)
+	suite.Equal(&resolved.ID, conflicts[0].ResolvedEventID)
+
+	_, err = suite.service.ResolveSyncConflict(ctx, userID, conflict.ID, &ConflictResolution{Resolution: ResolutionLocal, DeviceID: "laptop"})
+	suite.Equal(ErrConflictResolved, err)
+}
+
+func (suite *SyncServiceTestSuite) TestConflicts_Deletion() {
+	ctx := context.Background()
+	userID := "delete-user"
+	suite.redisClient.On("PublishSyncEvent", mock.Anything, userID, mock.AnythingOfType("*sync.SyncEvent")).Return(nil)
+
+	suite.Require().NoError(suite.service.UpdateSyncState(ctx, userID, "laptop", time.Now().Add(-time.Minute)))
+	suite.Require().NoError(suite.service.CreateSyncEvent(ctx, &SyncEvent{
+		Type: SyncEventBookmarkDeleted, UserID: userID, ResourceID: "bookmark-2", Action: "delete", DeviceID: "phone",
+	}))
+
+	// When: The laptop edits the bookmark the phone deleted
+	err := suite.service.CreateSyncEvent(ctx, updateEvent(userID, "bookmark-2", "laptop", map[string]interface{}{"title": "Still here"}))
+
+	// Then: It is a conflict a side has to be picked for
+	var conflictErr *ConflictError
+	suite.Require().True(errors.As(err, &conflictErr))
+	suite.Empty(conflictErr.Conflict.Fields)
+
+	_, err = suite.service.ResolveSyncConflict(ctx, userID, conflictErr.Conflict.ID, &ConflictResolution{Resolution: ResolutionMerge, DeviceID: "laptop"})
+	suite.Equal(ErrDeleteConflictNotMerged, err)
+
+	resolved, err := suite.service.ResolveSyncConflict(ctx, userID, conflictErr.Conflict.ID, &ConflictResolution{Resolution: ResolutionRemote, DeviceID: "laptop"})
+	suite.Require().NoError(err)
+	suite.Equal(SyncEventBookmarkDeleted, resolved.Type)
+
+	// Deleting on both sides agrees
+	suite.NoError(suite.service.CreateSyncEvent(ctx, &SyncEvent{
+		Type: SyncEventBookmarkDeleted, UserID: userID, ResourceID: "bookmark-2", Action: "delete", DeviceID: "laptop",
+	}))
+}
+
+func (suite *SyncServiceTestSuite) TestConflicts_OfflineQueue() {
+	ctx := context.Background()
+	userID := "offline-user"
+	suite.redisClient.On("PublishSyncEvent", mock.Anything, userID, mock.AnythingOfType("*sync.SyncEvent")).Return(nil)
+
+	// The laptop made changes offline while the phone renamed the bookmark
+	suite.Require().NoError(suite.service.UpdateSyncState(ctx, userID, "laptop", time.Now().Add(-time.Minute)))
+	suite.Require().NoError(suite.service.QueueOfflineEvent(ctx, updateEvent(userID, "bookmark-3", "laptop", map[string]interface{}{"title": "Offline"})))
+	suite.Require().NoError(suite.service.QueueOfflineEvent(ctx, updateEvent(userID, "bookmark-4", "laptop", map[string]interface{}{"title": "Untouched"})))
+	suite.Require().NoError(suite.service.CreateSyncEvent(ctx, updateEvent(userID, "bookmark-3", "phone", map[string]interface{}{"title": "Online"})))
+
+	// When: The laptop's queue is processed
+	suite.Require().NoError(suite.service.ProcessOfflineQueue(ctx, userID, "laptop"))
+
+	// Then: Only the conflicting change is held back
+	conflicts, err := suite.service.ListConflicts(ctx, userID, "")
+	suite.Require().NoError(err)
+	suite.Require().Len(conflicts, 1)
+	suite.Equal("bookmark-3", conflicts[0].ResourceID)
+
+	var statuses []SyncStatus
+	suite.Require().NoError(suite.db.Model(&SyncEvent{}).Where("device_id = ?", "laptop").Order("resource_id").Pluck("status", &statuses).Error)
+	suite.Equal([]SyncStatus{SyncStatusConflict, SyncStatusSynced}, statuses)
+}
+
+func (suite *SyncHandlerTestSuite) TestConflictEndpoints() {
+	suite.handler.RegisterRoutes(suite.router.Group("/api/v1/conflict-api"))
+	suite.redisClient.On("PublishSyncEvent", mock.Anything, "test-user-123", mock.AnythingOfType("*sync.SyncEvent")).Return(nil)
+	ctx := context.Background()
+	suite.Require().NoError(suite.service.UpdateSyncState(ctx, "test-user-123", "laptop", time.Now().Add(-time.Minute)))
+	suite.Require().NoError(suite.service.CreateSyncEvent(ctx, updateEvent("test-user-123", "bookmark-1", "phone", map[string]interface{}{"title": "Phone"})))
+
+	send := func(method, path string, body interface{}) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			suite.Require().NoError(json.NewEncoder(&buf).Encode(body))
+		}
+		req := httptest.NewRequest(method, "/api/v1/conflict-api"+path, &buf)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		suite.router.ServeHTTP(w, req)
+		return w
+	}
+
+	// A conflicting change is answered with 409 and the conflict
+	w := send(http.MethodPost, "/events", map[string]interface{}{
+		"type": "bookmark_updated", "resource_id": "bookmark-1", "action": "update",
+		"data": map[string]interface{}{"title": "Laptop"}, "device_id": "laptop",
+	})
+	suite.Require().Equal(http.StatusConflict, w.Code)
+	var failure struct {
+		Error struct {
+			Code    string                 `json:"code"`
+			Details map[string]interface{} `json:"details"`
+		} `json:"error"`
+	}
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &failure))
+	suite.Equal("SYNC_CONFLICT", failure.Error.Code)
+	conflictID := uint(failure.Error.Details["conflict_id"].(float64))
+
+	w = send(http.MethodGet, "/conflicts", nil)
+	suite.Equal(http.StatusOK, w.Code)
+	suite.Contains(w.Body.String(), `"fields":["title"]`)
+
+	w = send(http.MethodGet, fmt.Sprintf("/conflicts/%d", conflictID), nil)
+	suite.Equal(http.StatusOK, w.Code)
+	suite.Contains(w.Body.String(), `"conflicting":true`)
+
+	w = send(http.MethodGet, "/conflicts?status=pending", nil)
+	suite.Equal(http.StatusBadRequest, w.Code)
+
+	w = send(http.MethodPost, fmt.Sprintf("/conflicts/%d/resolve", conflictID), map[string]interface{}{"resolution": "merge", "device_id": "laptop"})
+	suite.Equal(http.StatusBadRequest, w.Code)
+
+	w = send(http.MethodPost, fmt.Sprintf("/conflicts/%d/resolve", conflictID), map[string]interface{}{"resolution": "local", "device_id": "laptop"})
+	suite.Equal(http.StatusOK, w.Code)
+	suite.Contains(w.Body.String(), `Laptop`)
+
+	w = send(http.MethodPost, fmt.Sprintf("/conflicts/%d/resolve", conflictID), map[string]interface{}{"resolution": "local", "device_id": "laptop"})
+	suite.Equal(http.StatusConflict, w.Code)
+
+	w = send(http.MethodGet, "/conflicts/999", nil)
+	suite.Equal(http.StatusNotFound, w.Code)
+}
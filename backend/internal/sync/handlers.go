@@ -2,6 +2,7 @@ package sync
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -26,6 +27,23 @@ func NewHandler(service *Service, logger *zap.Logger) *Handler {
 	}
 }
 
+// RegisterRoutes registers the sync routes on a /sync group
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/state", h.GetSyncState)
+	router.PUT("/state", h.UpdateSyncState)
+	router.GET("/delta", h.GetDeltaSync)
+	router.POST("/events", h.CreateSyncEvent)
+	router.GET("/offline-queue", h.GetOfflineQueue)
+	router.POST("/offline-queue", h.QueueOfflineEvent)
+	router.POST("/offline-queue/process", h.ProcessOfflineQueue)
+	router.GET("/scope", h.GetSyncScope)
+	router.PUT("/scope", h.UpdateSyncScope)
+	router.DELETE("/scope", h.DeleteSyncScope)
+	router.GET("/conflicts", h.ListConflicts)
+	router.GET("/conflicts/:id", h.GetConflict)
+	router.POST("/conflicts/:id/resolve", h.ResolveConflict)
+}
+
 // GetSyncState handles GET /api/v1/sync/state
 func (h *Handler) GetSyncState(c *gin.Context) {
 	userID := c.GetString("user_id")
@@ -46,7 +64,8 @@ func (h *Handler) GetSyncState(c *gin.Context) {
 	utils.SuccessResponse(c, state, "Sync state retrieved successfully")
 }
 
-// GetDeltaSync handles GET /api/v1/sync/delta
+// GetDeltaSync handles GET /api/v1/sync/delta. format=compact returns short
+// keys and numeric enums, and fields= limits each event to the named fields
 func (h *Handler) GetDeltaSync(c *gin.Context) {
 	userID := c.GetString("user_id")
 	deviceID := c.Query("device_id")
@@ -70,6 +89,18 @@ func (h *Handler) GetDeltaSync(c *gin.Context) {
 		lastSyncTime = time.Now().Add(-24 * time.Hour)
 	}
 
+	format := c.DefaultQuery("format", FormatFull)
+	if format != FormatFull && format != FormatCompact {
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", "format must be full or compact", nil)
+		return
+	}
+
+	fields, err := eventFieldKeys(utils.ParseFields(c), format)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", err.Error(), nil)
+		return
+	}
+
 	delta, err := h.service.GetDeltaSync(c.Request.Context(), userID, deviceID, lastSyncTime)
 	if err != nil {
 		h.logger.Error("Failed to get delta sync", zap.Error(err))
@@ -77,16 +108,42 @@ func (h *Handler) GetDeltaSync(c *gin.Context) {
 		return
 	}
 
+	if format == FormatCompact {
+		compact := delta.ToCompact()
+		events, err := utils.FilterFields(compact.Events, fields)
+		if err != nil {
+			h.logger.Error("Failed to filter delta sync", zap.Error(err))
+			utils.ErrorResponse(c, http.StatusInternalServerError, "SYNC_ERROR", "Failed to get delta sync", nil)
+			return
+		}
+		utils.SuccessResponse(c, gin.H{"e": events, "ts": compact.Timestamp}, "Delta sync retrieved successfully")
+		return
+	}
+
+	if len(fields) > 0 {
+		events, err := utils.FilterFields(delta.Events, fields)
+		if err != nil {
+			h.logger.Error("Failed to filter delta sync", zap.Error(err))
+			utils.ErrorResponse(c, http.StatusInternalServerError, "SYNC_ERROR", "Failed to get delta sync", nil)
+			return
+		}
+		utils.SuccessResponse(c, gin.H{"events": events, "timestamp": delta.Timestamp}, "Delta sync retrieved successfully")
+		return
+	}
+
 	utils.SuccessResponse(c, delta, "Delta sync retrieved successfully")
 }
 
-// CreateSyncEvent handles POST /api/v1/sync/events
+// CreateSyncEvent handles POST /api/v1/sync/events. Changes conflicting
+// with what other devices did since the device last synced are held back
+// for review with 409
 func (h *Handler) CreateSyncEvent(c *gin.Context) {
 	userID := c.GetString("user_id")
 
 	var req struct {
 		Type       SyncEventType          `json:"type" binding:"required"`
-		ResourceID string                 `json:"resource_id" binding:"required"`
+		ResourceID string                 `json:"resource_id" binding:"required"` // server ID, or client ID of a resource created offline
+		ClientID   string                 `json:"client_id"`
 		Action     string                 `json:"action" binding:"required"`
 		Data       map[string]interface{} `json:"data"`
 		DeviceID   string                 `json:"device_id" binding:"required"`
@@ -108,6 +165,7 @@ func (h *Handler) CreateSyncEvent(c *gin.Context) {
 		Type:       req.Type,
 		UserID:     userID,
 		ResourceID: req.ResourceID,
+		ClientID:   req.ClientID,
 		Action:     req.Action,
 		Data:       string(dataJSON),
 		DeviceID:   req.DeviceID,
@@ -115,6 +173,15 @@ func (h *Handler) CreateSyncEvent(c *gin.Context) {
 	}
 
 	if err := h.service.CreateSyncEvent(c.Request.Context(), event); err != nil {
+		var conflict *ConflictError
+		if errors.As(err, &conflict) {
+			utils.ErrorResponse(c, http.StatusConflict, "SYNC_CONFLICT", "Event conflicts with changes from another device", map[string]interface{}{
+				"conflict_id": conflict.Conflict.ID,
+				"event_id":    event.ID,
+				"fields":      conflict.Conflict.Fields,
+			})
+			return
+		}
 		h.logger.Error("Failed to create sync event", zap.Error(err))
 		utils.ErrorResponse(c, http.StatusInternalServerError, "SYNC_ERROR", "Failed to create sync event", nil)
 		return
@@ -171,7 +238,8 @@ func (h *Handler) QueueOfflineEvent(c *gin.Context) {
 
 	var req struct {
 		Type       SyncEventType          `json:"type" binding:"required"`
-		ResourceID string                 `json:"resource_id" binding:"required"`
+		ResourceID string                 `json:"resource_id" binding:"required"` // server ID, or client ID of a resource created offline
+		ClientID   string                 `json:"client_id"`
 		Action     string                 `json:"action" binding:"required"`
 		Data       map[string]interface{} `json:"data"`
 		DeviceID   string                 `json:"device_id" binding:"required"`
@@ -193,6 +261,7 @@ func (h *Handler) QueueOfflineEvent(c *gin.Context) {
 		Type:       req.Type,
 		UserID:     userID,
 		ResourceID: req.ResourceID,
+		ClientID:   req.ClientID,
 		Action:     req.Action,
 		Data:       string(dataJSON),
 		DeviceID:   req.DeviceID,
@@ -231,3 +300,159 @@ func (h *Handler) UpdateSyncState(c *gin.Context) {
 
 	utils.SuccessResponse(c, gin.H{"message": "Sync state updated successfully"}, "Sync state updated successfully")
 }
+
+// GetSyncScope handles GET /api/v1/sync/scope
+func (h *Handler) GetSyncScope(c *gin.Context) {
+	userID := c.GetString("user_id")
+	deviceID := c.Query("device_id")
+
+	if deviceID == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", "device_id is required", nil)
+		return
+	}
+
+	scope, err := h.service.GetDeviceScope(c.Request.Context(), userID, deviceID)
+	if err != nil {
+		h.logger.Error("Failed to get sync scope", zap.Error(err))
+		utils.ErrorResponse(c, http.StatusInternalServerError, "SYNC_ERROR", "Failed to get sync scope", nil)
+		return
+	}
+
+	utils.SuccessResponse(c, scope, "Sync scope retrieved successfully")
+}
+
+// UpdateSyncScope handles PUT /api/v1/sync/scope. The device only syncs
+// the listed collections from then on; the response holds the backfill and
+// tombstone events for the change
+func (h *Handler) UpdateSyncScope(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req struct {
+		DeviceID      string `json:"device_id" binding:"required"`
+		CollectionIDs []uint `json:"collection_ids" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body", nil)
+		return
+	}
+
+	h.setScope(c, userID, req.DeviceID, req.CollectionIDs)
+}
+
+// DeleteSyncScope handles DELETE /api/v1/sync/scope, returning the device
+// to syncing every collection
+func (h *Handler) DeleteSyncScope(c *gin.Context) {
+	userID := c.GetString("user_id")
+	deviceID := c.Query("device_id")
+
+	if deviceID == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", "device_id is required", nil)
+		return
+	}
+
+	h.setScope(c, userID, deviceID, nil)
+}
+
+func (h *Handler) setScope(c *gin.Context, userID, deviceID string, collectionIDs []uint) {
+	change, err := h.service.SetDeviceScope(c.Request.Context(), userID, deviceID, collectionIDs)
+	if err != nil {
+		if errors.Is(err, ErrInvalidScope) {
+			utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_SCOPE", err.Error(), nil)
+			return
+		}
+		h.logger.Error("Failed to update sync scope", zap.Error(err))
+		utils.ErrorResponse(c, http.StatusInternalServerError, "SYNC_ERROR", "Failed to update sync scope", nil)
+		return
+	}
+
+	utils.SuccessResponse(c, change, "Sync scope updated successfully")
+}
+
+// ListConflicts handles GET /api/v1/sync/conflicts. status is unresolved
+// by default, or resolved or all
+func (h *Handler) ListConflicts(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	status := c.DefaultQuery("status", ConflictUnresolved)
+	switch status {
+	case ConflictUnresolved, ConflictResolved:
+	case "all":
+		status = ""
+	default:
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", "status must be unresolved, resolved or all", nil)
+		return
+	}
+
+	conflicts, err := h.service.ListConflicts(c.Request.Context(), userID, status)
+	if err != nil {
+		h.logger.Error("Failed to list sync conflicts", zap.Error(err))
+		utils.ErrorResponse(c, http.StatusInternalServerError, "SYNC_ERROR", "Failed to list sync conflicts", nil)
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{"conflicts": conflicts}, "Sync conflicts retrieved successfully")
+}
+
+// GetConflict handles GET /api/v1/sync/conflicts/:id, showing both
+// versions and the fields that differ
+func (h *Handler) GetConflict(c *gin.Context) {
+	id, ok := conflictID(c)
+	if !ok {
+		return
+	}
+
+	detail, err := h.service.GetConflict(c.Request.Context(), c.GetString("user_id"), id)
+	if err != nil {
+		h.conflictError(c, err, "Failed to get sync conflict")
+		return
+	}
+
+	utils.SuccessResponse(c, detail, "Sync conflict retrieved successfully")
+}
+
+// ResolveConflict handles POST /api/v1/sync/conflicts/:id/resolve. The
+// outcome is published as a new sync event and returned
+func (h *Handler) ResolveConflict(c *gin.Context) {
+	id, ok := conflictID(c)
+	if !ok {
+		return
+	}
+
+	var req ConflictResolution
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body", nil)
+		return
+	}
+
+	event, err := h.service.ResolveSyncConflict(c.Request.Context(), c.GetString("user_id"), id, &req)
+	if err != nil {
+		h.conflictError(c, err, "Failed to resolve sync conflict")
+		return
+	}
+
+	utils.SuccessResponse(c, event, "Sync conflict resolved successfully")
+}
+
+func conflictID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", "Invalid conflict ID", nil)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func (h *Handler) conflictError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, ErrConflictNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, "CONFLICT_NOT_FOUND", err.Error(), nil)
+	case errors.Is(err, ErrConflictResolved):
+		utils.ErrorResponse(c, http.StatusConflict, "CONFLICT_RESOLVED", err.Error(), nil)
+	case errors.Is(err, ErrInvalidResolution), errors.Is(err, ErrUnresolvedFields), errors.Is(err, ErrDeleteConflictNotMerged):
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_RESOLUTION", err.Error(), nil)
+	default:
+		h.logger.Error(message, zap.Error(err))
+		utils.ErrorResponse(c, http.StatusInternalServerError, "SYNC_ERROR", message, nil)
+	}
+}
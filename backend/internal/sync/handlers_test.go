@@ -166,6 +166,116 @@ func (suite *SyncHandlerTestSuite) TestGetDeltaSync() {
 	suite.Len(events, 1)
 }
 
+// Test GET /api/v1/sync/delta?format=compact
+func (suite *SyncHandlerTestSuite) TestGetDeltaSyncCompact() {
+	timestamp := time.Now().Add(-30 * time.Minute)
+	events := []*SyncEvent{
+		{
+			Type:       SyncEventBookmarkUpdated,
+			UserID:     "test-user-123",
+			ResourceID: "bookmark-123",
+			Action:     "update",
+			Data:       `{"title":"Updated"}`,
+			DeviceID:   "other-device",
+			Timestamp:  timestamp,
+		},
+		{
+			Type:       SyncEventType("bookmark_archived"),
+			UserID:     "test-user-123",
+			ResourceID: "bookmark-456",
+			Action:     "archive",
+			DeviceID:   "other-device",
+			Timestamp:  timestamp.Add(time.Minute),
+		},
+	}
+	for _, event := range events {
+		suite.NoError(suite.db.Create(event).Error)
+	}
+
+	lastSyncTime := time.Now().Add(-1 * time.Hour).Unix()
+	url := fmt.Sprintf("/api/v1/sync/delta?device_id=device-123&last_sync_time=%d&format=compact", lastSyncTime)
+	req, _ := http.NewRequest("GET", url, nil)
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+
+	suite.Equal(http.StatusOK, w.Code)
+
+	var response struct {
+		Data struct {
+			Events    []map[string]interface{} `json:"e"`
+			Timestamp int64                    `json:"ts"`
+		} `json:"data"`
+	}
+	suite.NoError(json.Unmarshal(w.Body.Bytes(), &response))
+	suite.Require().Len(response.Data.Events, 2)
+	suite.NotZero(response.Data.Timestamp)
+
+	// Known values become codes and data is embedded as JSON
+	first := response.Data.Events[0]
+	suite.Equal(float64(2), first["t"])
+	suite.Equal(float64(2), first["a"])
+	suite.Equal(float64(1), first["s"])
+	suite.Equal("bookmark-123", first["r"])
+	suite.Equal(map[string]interface{}{"title": "Updated"}, first["d"])
+	suite.Equal(float64(timestamp.UnixMilli()), first["ts"])
+
+	// Values without a code are sent as strings
+	second := response.Data.Events[1]
+	suite.Equal("bookmark_archived", second["t"])
+	suite.Equal("archive", second["a"])
+}
+
+// Test GET /api/v1/sync/delta?fields=
+func (suite *SyncHandlerTestSuite) TestGetDeltaSyncFields() {
+	event := &SyncEvent{
+		Type:       SyncEventBookmarkCreated,
+		UserID:     "test-user-123",
+		ResourceID: "bookmark-123",
+		Action:     "create",
+		Data:       `{"title":"New"}`,
+		DeviceID:   "other-device",
+		Timestamp:  time.Now().Add(-30 * time.Minute),
+	}
+	suite.NoError(suite.db.Create(event).Error)
+
+	lastSyncTime := time.Now().Add(-1 * time.Hour).Unix()
+	for format, expected := range map[string][]string{
+		"full":    {"resource_id", "type"},
+		"compact": {"r", "t"},
+	} {
+		url := fmt.Sprintf("/api/v1/sync/delta?device_id=device-123&last_sync_time=%d&format=%s&fields=resource_id,type", lastSyncTime, format)
+		req, _ := http.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		suite.router.ServeHTTP(w, req)
+		suite.Equal(http.StatusOK, w.Code, format)
+
+		var response map[string]interface{}
+		suite.NoError(json.Unmarshal(w.Body.Bytes(), &response))
+		data := response["data"].(map[string]interface{})
+		key := "events"
+		if format == "compact" {
+			key = "e"
+		}
+		events := data[key].([]interface{})
+		suite.Require().Len(events, 1)
+
+		keys := make([]string, 0)
+		for k := range events[0].(map[string]interface{}) {
+			keys = append(keys, k)
+		}
+		suite.ElementsMatch(expected, keys, format)
+	}
+
+	// Unknown fields and formats are rejected
+	for _, query := range []string{"fields=title", "format=xml"} {
+		url := fmt.Sprintf("/api/v1/sync/delta?device_id=device-123&%s", query)
+		req, _ := http.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		suite.router.ServeHTTP(w, req)
+		suite.Equal(http.StatusBadRequest, w.Code, query)
+	}
+}
+
 // Test POST /api/v1/sync/events
 func (suite *SyncHandlerTestSuite) TestCreateSyncEvent() {
 	suite.redisClient.On("PublishSyncEvent", mock.Anything, "test-user-123", mock.AnythingOfType("*sync.SyncEvent")).Return(nil)
@@ -0,0 +1,68 @@
+package sync
+
+import (
+	"encoding/json"
+	"strings"
+
+	"bookmark-sync-service/backend/pkg/websocket"
+)
+
+// LiveQueryPublisher pushes bookmarks to WebSocket clients subscribed to a
+// search or filter they match
+type LiveQueryPublisher interface {
+	PublishBookmark(userID, action string, item websocket.LiveItem) int
+}
+
+// SetLiveQueries makes synced bookmark changes reach live query subscribers
+func (s *Service) SetLiveQueries(live LiveQueryPublisher) {
+	s.liveQueries = live
+}
+
+// publishLive offers a bookmark created or updated by a device to the
+// user's live queries. Other events and undecodable data are ignored
+func (s *Service) publishLive(event *SyncEvent) {
+	if s.liveQueries == nil {
+		return
+	}
+	var action string
+	switch event.Type {
+	case SyncEventBookmarkCreated:
+		action = "created"
+	case SyncEventBookmarkUpdated:
+		action = "updated"
+	default:
+		return
+	}
+
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(event.Data), &data); err != nil {
+		return
+	}
+	if _, ok := data["id"]; !ok {
+		data["id"] = event.ResourceID
+	}
+	item := websocket.LiveItem{ID: event.ResourceID, Tags: eventTags(data["tags"]), Data: data}
+	item.URL, _ = data["url"].(string)
+	item.Title, _ = data["title"].(string)
+	item.Description, _ = data["description"].(string)
+	s.liveQueries.PublishBookmark(event.UserID, action, item)
+}
+
+// eventTags reads tags sent either as an array or as the JSON-encoded
+// string bookmarks store them in
+func eventTags(raw interface{}) []string {
+	var tags []string
+	switch value := raw.(type) {
+	case []interface{}:
+		for _, tag := range value {
+			if name, ok := tag.(string); ok {
+				tags = append(tags, name)
+			}
+		}
+	case string:
+		if strings.HasPrefix(strings.TrimSpace(value), "[") {
+			_ = json.Unmarshal([]byte(value), &tags)
+		}
+	}
+	return tags
+}
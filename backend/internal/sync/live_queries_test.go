@@ -0,0 +1,48 @@
+package sync
+
+import (
+	"context"
+
+	"bookmark-sync-service/backend/pkg/websocket"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type recordingLiveQueries struct {
+	userID, action string
+	items          []websocket.LiveItem
+}
+
+func (r *recordingLiveQueries) PublishBookmark(userID, action string, item websocket.LiveItem) int {
+	r.userID, r.action = userID, action
+	r.items = append(r.items, item)
+	return 1
+}
+
+func (suite *SyncServiceTestSuite) TestCreateSyncEvent_PublishesToLiveQueries() {
+	live := &recordingLiveQueries{}
+	suite.service.SetLiveQueries(live)
+	suite.redisClient.On("PublishSyncEvent", mock.Anything, "user-1", mock.Anything).Return(nil)
+
+	// Given: A device reports a created bookmark with tags stored as JSON
+	err := suite.service.CreateSyncEvent(context.Background(), &SyncEvent{
+		Type: SyncEventBookmarkCreated, UserID: "user-1", ResourceID: "42", Action: "create", DeviceID: "phone",
+		Data: `{"url":"https://go.dev","title":"Go","tags":"[\"go\",\"lang\"]"}`,
+	})
+	suite.Require().NoError(err)
+
+	// Then: It is offered to the user's live queries
+	suite.Require().Len(live.items, 1)
+	suite.Equal("user-1", live.userID)
+	suite.Equal("created", live.action)
+	suite.Equal("https://go.dev", live.items[0].URL)
+	suite.Equal([]string{"go", "lang"}, live.items[0].Tags)
+	suite.Equal("42", live.items[0].Data.(map[string]interface{})["id"])
+
+	// Collection events are not
+	err = suite.service.CreateSyncEvent(context.Background(), &SyncEvent{
+		Type: SyncEventCollectionCreated, UserID: "user-1", ResourceID: "7", Action: "create", DeviceID: "phone", Data: `{"name":"Go"}`,
+	})
+	suite.Require().NoError(err)
+	suite.Len(live.items, 1)
+}
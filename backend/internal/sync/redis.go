@@ -0,0 +1,25 @@
+package sync
+
+import (
+	"context"
+
+	redispkg "bookmark-sync-service/backend/pkg/redis"
+)
+
+// redisAdapter publishes sync events through the shared Redis client
+type redisAdapter struct {
+	client *redispkg.Client
+}
+
+// NewRedisClient adapts the shared Redis client to the sync RedisClient interface
+func NewRedisClient(client *redispkg.Client) RedisClient {
+	return &redisAdapter{client: client}
+}
+
+func (r *redisAdapter) PublishSyncEvent(ctx context.Context, userID string, event interface{}) error {
+	return r.client.PublishSyncEvent(ctx, userID, event)
+}
+
+func (r *redisAdapter) SubscribeToSyncEvents(ctx context.Context, userID string) interface{} {
+	return r.client.SubscribeToSyncEvents(ctx, userID)
+}
@@ -0,0 +1,373 @@
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"bookmark-sync-service/backend/pkg/database"
+
+	"go.uber.org/zap"
+)
+
+// Scope messages. A device sends sync_scope with the collection IDs it
+// wants and gets scope_change back, carrying the backfill and tombstone
+// events that bring its local copy in line with the new scope
+const (
+	SyncMessageScope       SyncMessageType = "sync_scope"
+	SyncMessageScopeChange SyncMessageType = "scope_change"
+)
+
+// ErrInvalidScope is returned when a scope names collections the user does
+// not own
+var ErrInvalidScope = errors.New("sync scope contains unknown collections")
+
+// DeviceScope is the set of collections a device syncs. All is true for
+// devices without a scope
+type DeviceScope struct {
+	DeviceID      string `json:"device_id"`
+	All           bool   `json:"all"`
+	CollectionIDs []uint `json:"collection_ids"`
+}
+
+// ScopeChange is the result of changing a device's scope. Backfill holds
+// create events for items that entered the scope and Tombstones delete
+// events for items that left it
+type ScopeChange struct {
+	Scope      *DeviceScope `json:"scope"`
+	Backfill   []*SyncEvent `json:"backfill"`
+	Tombstones []*SyncEvent `json:"tombstones"`
+}
+
+// GetDeviceScope returns the collections a device syncs
+func (s *Service) GetDeviceScope(ctx context.Context, userID, deviceID string) (*DeviceScope, error) {
+	collections, err := s.scopeCollections(ctx, userID, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	return newDeviceScope(deviceID, collections), nil
+}
+
+// SetDeviceScope limits a device to the given collections, or lifts its
+// scope when collectionIDs is nil. The returned change tells the device
+// what to add and remove locally
+func (s *Service) SetDeviceScope(ctx context.Context, userID, deviceID string, collectionIDs []uint) (*ScopeChange, error) {
+	ownerID, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	var next map[uint]bool
+	if collectionIDs != nil {
+		next = make(map[uint]bool, len(collectionIDs))
+		for _, id := range collectionIDs {
+			next[id] = true
+		}
+		var owned int64
+		if err := s.db.WithContext(ctx).Model(&database.Collection{}).
+			Where("id IN ? AND user_id = ?", setKeys(next), ownerID).
+			Count(&owned).Error; err != nil {
+			return nil, fmt.Errorf("failed to check scope collections: %w", err)
+		}
+		if int(owned) != len(next) {
+			return nil, ErrInvalidScope
+		}
+	}
+
+	previous, err := s.scopeCollections(ctx, userID, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	before, err := s.scopedBookmarks(ctx, uint(ownerID), previous)
+	if err != nil {
+		return nil, err
+	}
+	after, err := s.scopedBookmarks(ctx, uint(ownerID), next)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.saveScope(ctx, userID, deviceID, next); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	change := &ScopeChange{Scope: newDeviceScope(deviceID, next), Backfill: []*SyncEvent{}, Tombstones: []*SyncEvent{}}
+
+	added, removed, err := s.collectionDiff(ctx, uint(ownerID), previous, next)
+	if err != nil {
+		return nil, err
+	}
+	collections, err := s.loadCollections(ctx, added)
+	if err != nil {
+		return nil, err
+	}
+	for _, collection := range collections {
+		change.Backfill = append(change.Backfill, scopeEvent(SyncEventCollectionCreated, "create", collection.ID, collection, userID, now))
+	}
+	for _, id := range removed {
+		change.Tombstones = append(change.Tombstones, scopeEvent(SyncEventCollectionDeleted, "delete", id, nil, userID, now))
+	}
+
+	var entering, leaving []uint
+	for _, id := range setKeys(after) {
+		if !before[id] {
+			entering = append(entering, id)
+		}
+	}
+	for _, id := range setKeys(before) {
+		if !after[id] {
+			leaving = append(leaving, id)
+		}
+	}
+
+	if len(entering) > 0 {
+		var bookmarks []database.Bookmark
+		if err := s.db.WithContext(ctx).Where("id IN ?", entering).Order("id").Find(&bookmarks).Error; err != nil {
+			return nil, fmt.Errorf("failed to load backfill bookmarks: %w", err)
+		}
+		for _, bookmark := range bookmarks {
+			change.Backfill = append(change.Backfill, scopeEvent(SyncEventBookmarkCreated, "create", bookmark.ID, bookmark, userID, now))
+		}
+	}
+	for _, id := range leaving {
+		change.Tombstones = append(change.Tombstones, scopeEvent(SyncEventBookmarkDeleted, "delete", id, nil, userID, now))
+	}
+
+	s.logger.Info("Device sync scope changed",
+		zap.String("user_id", userID),
+		zap.String("device_id", deviceID),
+		zap.Int("backfill", len(change.Backfill)),
+		zap.Int("tombstones", len(change.Tombstones)),
+	)
+
+	return change, nil
+}
+
+// filterScope drops the events outside a device's scope. Bookmark deletions
+// always pass, since the device may hold the bookmark from before
+func (s *Service) filterScope(ctx context.Context, userID, deviceID string, events []*SyncEvent) ([]*SyncEvent, error) {
+	scope, err := s.scopeCollections(ctx, userID, deviceID)
+	if err != nil || scope == nil || len(events) == 0 {
+		return events, err
+	}
+
+	var bookmarkIDs []uint
+	for _, event := range events {
+		if isBookmarkEvent(event.Type) {
+			if id, err := strconv.ParseUint(event.ResourceID, 10, 32); err == nil {
+				bookmarkIDs = append(bookmarkIDs, uint(id))
+			}
+		}
+	}
+	inScope := map[string]bool{}
+	if len(bookmarkIDs) > 0 && len(scope) > 0 {
+		var linked []uint
+		if err := s.db.WithContext(ctx).Table("bookmark_collections").
+			Where("bookmark_id IN ? AND collection_id IN ?", bookmarkIDs, setKeys(scope)).
+			Distinct().Pluck("bookmark_id", &linked).Error; err != nil {
+			return nil, fmt.Errorf("failed to resolve sync scope: %w", err)
+		}
+		for _, id := range linked {
+			inScope[strconv.FormatUint(uint64(id), 10)] = true
+		}
+	}
+
+	filtered := make([]*SyncEvent, 0, len(events))
+	for _, event := range events {
+		switch {
+		case event.Type == SyncEventBookmarkDeleted:
+		case isBookmarkEvent(event.Type):
+			if !inScope[event.ResourceID] && !scope[eventCollectionID(event)] {
+				continue
+			}
+		case isCollectionEvent(event.Type) && event.Type != SyncEventCollectionDeleted:
+			id, _ := strconv.ParseUint(event.ResourceID, 10, 32)
+			if !scope[uint(id)] {
+				continue
+			}
+		}
+		filtered = append(filtered, event)
+	}
+	return filtered, nil
+}
+
+// scopeCollections returns the collections a device is limited to, or nil
+// when it syncs everything
+func (s *Service) scopeCollections(ctx context.Context, userID, deviceID string) (map[uint]bool, error) {
+	var scopes []database.SyncScope
+	if err := s.db.WithContext(ctx).Where("user_id = ? AND device_id = ?", userID, deviceID).
+		Limit(1).Find(&scopes).Error; err != nil {
+		return nil, fmt.Errorf("failed to get sync scope: %w", err)
+	}
+	if len(scopes) == 0 {
+		return nil, nil
+	}
+	scope := scopes[0]
+
+	var ids []uint
+	if err := json.Unmarshal([]byte(scope.CollectionIDs), &ids); err != nil {
+		return nil, fmt.Errorf("failed to decode sync scope: %w", err)
+	}
+	collections := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		collections[id] = true
+	}
+	return collections, nil
+}
+
+func (s *Service) saveScope(ctx context.Context, userID, deviceID string, collections map[uint]bool) error {
+	db := s.db.WithContext(ctx)
+	if collections == nil {
+		if err := db.Unscoped().Where("user_id = ? AND device_id = ?", userID, deviceID).
+			Delete(&database.SyncScope{}).Error; err != nil {
+			return fmt.Errorf("failed to clear sync scope: %w", err)
+		}
+		return nil
+	}
+
+	encoded, err := json.Marshal(setKeys(collections))
+	if err != nil {
+		return fmt.Errorf("failed to encode sync scope: %w", err)
+	}
+	scope := database.SyncScope{UserID: userID, DeviceID: deviceID}
+	if err := db.Where(scope).Assign(database.SyncScope{CollectionIDs: string(encoded)}).
+		FirstOrCreate(&scope).Error; err != nil {
+		return fmt.Errorf("failed to save sync scope: %w", err)
+	}
+	return nil
+}
+
+// scopedBookmarks returns the IDs of a user's bookmarks within a scope
+func (s *Service) scopedBookmarks(ctx context.Context, userID uint, collections map[uint]bool) (map[uint]bool, error) {
+	query := s.db.WithContext(ctx).Model(&database.Bookmark{}).Where("bookmarks.user_id = ?", userID)
+	if collections != nil {
+		if len(collections) == 0 {
+			return map[uint]bool{}, nil
+		}
+		query = query.Joins("JOIN bookmark_collections ON bookmark_collections.bookmark_id = bookmarks.id").
+			Where("bookmark_collections.collection_id IN ?", setKeys(collections))
+	}
+
+	var ids []uint
+	if err := query.Distinct().Pluck("bookmarks.id", &ids).Error; err != nil {
+		return nil, fmt.Errorf("failed to list scoped bookmarks: %w", err)
+	}
+	set := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set, nil
+}
+
+func (s *Service) loadCollections(ctx context.Context, ids []uint) ([]database.Collection, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	var collections []database.Collection
+	if err := s.db.WithContext(ctx).Where("id IN ?", ids).Order("id").Find(&collections).Error; err != nil {
+		return nil, fmt.Errorf("failed to load backfill collections: %w", err)
+	}
+	return collections, nil
+}
+
+// collectionDiff returns the collections entering and leaving the scope.
+// An unscoped side stands for all of the user's collections
+func (s *Service) collectionDiff(ctx context.Context, userID uint, previous, next map[uint]bool) (added, removed []uint, err error) {
+	if previous == nil || next == nil {
+		var ids []uint
+		if err := s.db.WithContext(ctx).Model(&database.Collection{}).
+			Where("user_id = ?", userID).Pluck("id", &ids).Error; err != nil {
+			return nil, nil, fmt.Errorf("failed to list collections: %w", err)
+		}
+		all := make(map[uint]bool, len(ids))
+		for _, id := range ids {
+			all[id] = true
+		}
+		if previous == nil {
+			previous = all
+		}
+		if next == nil {
+			next = all
+		}
+	}
+	for _, id := range setKeys(next) {
+		if !previous[id] {
+			added = append(added, id)
+		}
+	}
+	for _, id := range setKeys(previous) {
+		if !next[id] {
+			removed = append(removed, id)
+		}
+	}
+	return added, removed, nil
+}
+
+// scopeEvent builds a backfill or tombstone event. They are generated for
+// one device and never stored
+func scopeEvent(eventType SyncEventType, action string, resourceID uint, resource interface{}, userID string, now time.Time) *SyncEvent {
+	event := &SyncEvent{
+		Type:       eventType,
+		UserID:     userID,
+		ResourceID: strconv.FormatUint(uint64(resourceID), 10),
+		Action:     action,
+		Status:     SyncStatusSynced,
+		Timestamp:  now,
+	}
+	if resource != nil {
+		if data, err := json.Marshal(resource); err == nil {
+			event.Data = string(data)
+		}
+	}
+	switch resource := resource.(type) {
+	case database.Bookmark:
+		if resource.ClientID != nil {
+			event.ClientID = *resource.ClientID
+		}
+	case database.Collection:
+		if resource.ClientID != nil {
+			event.ClientID = *resource.ClientID
+		}
+	}
+	return event
+}
+
+func newDeviceScope(deviceID string, collections map[uint]bool) *DeviceScope {
+	if collections == nil {
+		return &DeviceScope{DeviceID: deviceID, All: true, CollectionIDs: []uint{}}
+	}
+	return &DeviceScope{DeviceID: deviceID, CollectionIDs: setKeys(collections)}
+}
+
+// eventCollectionID reads the collection_id some bookmark events carry
+func eventCollectionID(event *SyncEvent) uint {
+	var data struct {
+		CollectionID uint `json:"collection_id"`
+	}
+	if event.Data == "" || json.Unmarshal([]byte(event.Data), &data) != nil {
+		return 0
+	}
+	return data.CollectionID
+}
+
+func isBookmarkEvent(eventType SyncEventType) bool {
+	return eventType == SyncEventBookmarkCreated || eventType == SyncEventBookmarkUpdated || eventType == SyncEventBookmarkDeleted
+}
+
+func isCollectionEvent(eventType SyncEventType) bool {
+	return eventType == SyncEventCollectionCreated || eventType == SyncEventCollectionUpdated || eventType == SyncEventCollectionDeleted
+}
+
+func setKeys(set map[uint]bool) []uint {
+	keys := make([]uint, 0, len(set))
+	for id := range set {
+		keys = append(keys, id)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
@@ -0,0 +1,184 @@
+package sync
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/websocket"
+
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// SyncScopeTestSuite tests per-device selective sync
+type SyncScopeTestSuite struct {
+	suite.Suite
+	service  *Service
+	db       *gorm.DB
+	userID   string
+	work     database.Collection
+	personal database.Collection
+	report   database.Bookmark // in work
+	recipe   database.Bookmark // in personal
+	unfiled  database.Bookmark
+}
+
+func (suite *SyncScopeTestSuite) SetupTest() {
+	db, err := database.SetupTestDB()
+	suite.Require().NoError(err)
+	suite.db = db
+	suite.service = NewService(db, &MockRedisClient{}, zap.NewNop())
+
+	user := database.User{Email: "scope@example.com", Username: "scope", SupabaseID: "scope"}
+	suite.Require().NoError(db.Create(&user).Error)
+	suite.userID = strconv.FormatUint(uint64(user.ID), 10)
+
+	suite.work = database.Collection{UserID: user.ID, Name: "Work", ShareLink: "scope-work"}
+	suite.personal = database.Collection{UserID: user.ID, Name: "Personal", ShareLink: "scope-personal"}
+	suite.Require().NoError(db.Create(&suite.work).Error)
+	suite.Require().NoError(db.Create(&suite.personal).Error)
+
+	// Bookmark IDs are kept apart from collection IDs, as delta sync
+	// collapses events by resource ID
+	suite.report = database.Bookmark{BaseModel: database.BaseModel{ID: 101}, UserID: user.ID, URL: "https://work.example/report", Title: "Report"}
+	suite.recipe = database.Bookmark{BaseModel: database.BaseModel{ID: 102}, UserID: user.ID, URL: "https://food.example/recipe", Title: "Recipe"}
+	suite.unfiled = database.Bookmark{BaseModel: database.BaseModel{ID: 103}, UserID: user.ID, URL: "https://unfiled.example", Title: "Unfiled"}
+	for _, bookmark := range []*database.Bookmark{&suite.report, &suite.recipe, &suite.unfiled} {
+		suite.Require().NoError(db.Create(bookmark).Error)
+	}
+	suite.Require().NoError(db.Model(&suite.work).Association("Bookmarks").Append(&suite.report))
+	suite.Require().NoError(db.Model(&suite.personal).Association("Bookmarks").Append(&suite.recipe))
+}
+
+func (suite *SyncScopeTestSuite) TearDownTest() {
+	database.CleanupTestDB(suite.db)
+}
+
+func TestSyncScopeTestSuite(t *testing.T) {
+	suite.Run(t, new(SyncScopeTestSuite))
+}
+
+func resourceIDs(events []*SyncEvent) []string {
+	ids := make([]string, 0, len(events))
+	for _, event := range events {
+		ids = append(ids, string(event.Type)+":"+event.ResourceID)
+	}
+	return ids
+}
+
+func id(value uint) string {
+	return strconv.FormatUint(uint64(value), 10)
+}
+
+func (suite *SyncScopeTestSuite) TestScopeChangesBackfillAndTombstone() {
+	ctx := context.Background()
+
+	scope, err := suite.service.GetDeviceScope(ctx, suite.userID, "laptop")
+	suite.Require().NoError(err)
+	suite.True(scope.All)
+
+	// Narrowing from everything removes the other collection and its bookmarks
+	change, err := suite.service.SetDeviceScope(ctx, suite.userID, "laptop", []uint{suite.work.ID})
+	suite.Require().NoError(err)
+	suite.False(change.Scope.All)
+	suite.Equal([]uint{suite.work.ID}, change.Scope.CollectionIDs)
+	suite.Empty(change.Backfill)
+	suite.ElementsMatch([]string{
+		"collection_deleted:" + id(suite.personal.ID),
+		"bookmark_deleted:" + id(suite.recipe.ID),
+		"bookmark_deleted:" + id(suite.unfiled.ID),
+	}, resourceIDs(change.Tombstones))
+
+	// Switching collections backfills the new one with full records
+	change, err = suite.service.SetDeviceScope(ctx, suite.userID, "laptop", []uint{suite.personal.ID})
+	suite.Require().NoError(err)
+	suite.Equal([]string{
+		"collection_created:" + id(suite.personal.ID),
+		"bookmark_created:" + id(suite.recipe.ID),
+	}, resourceIDs(change.Backfill))
+	suite.Contains(change.Backfill[1].Data, "https://food.example/recipe")
+	suite.Equal([]string{
+		"collection_deleted:" + id(suite.work.ID),
+		"bookmark_deleted:" + id(suite.report.ID),
+	}, resourceIDs(change.Tombstones))
+
+	// Lifting the scope backfills everything the device was missing
+	change, err = suite.service.SetDeviceScope(ctx, suite.userID, "laptop", nil)
+	suite.Require().NoError(err)
+	suite.True(change.Scope.All)
+	suite.ElementsMatch([]string{
+		"collection_created:" + id(suite.work.ID),
+		"bookmark_created:" + id(suite.report.ID),
+		"bookmark_created:" + id(suite.unfiled.ID),
+	}, resourceIDs(change.Backfill))
+	suite.Empty(change.Tombstones)
+
+	var scopes int64
+	suite.db.Unscoped().Model(&database.SyncScope{}).Count(&scopes)
+	suite.Zero(scopes)
+}
+
+func (suite *SyncScopeTestSuite) TestScopeRejectsForeignCollections() {
+	other := database.Collection{UserID: 999, Name: "Not mine", ShareLink: "scope-other"}
+	suite.Require().NoError(suite.db.Create(&other).Error)
+
+	_, err := suite.service.SetDeviceScope(context.Background(), suite.userID, "laptop", []uint{suite.work.ID, other.ID})
+	suite.ErrorIs(err, ErrInvalidScope)
+}
+
+func (suite *SyncScopeTestSuite) TestDeltaSyncIsFiltered() {
+	ctx := context.Background()
+	_, err := suite.service.SetDeviceScope(ctx, suite.userID, "laptop", []uint{suite.work.ID})
+	suite.Require().NoError(err)
+
+	since := time.Now().Add(-time.Minute)
+	events := []*SyncEvent{
+		{Type: SyncEventBookmarkUpdated, ResourceID: id(suite.report.ID), Action: "update"},
+		{Type: SyncEventBookmarkUpdated, ResourceID: id(suite.recipe.ID), Action: "update"},
+		{Type: SyncEventBookmarkCreated, ResourceID: "12345", Action: "create", Data: `{"collection_id":` + id(suite.work.ID) + `}`},
+		{Type: SyncEventBookmarkDeleted, ResourceID: id(suite.unfiled.ID), Action: "delete"},
+		{Type: SyncEventCollectionUpdated, ResourceID: id(suite.work.ID), Action: "update"},
+		{Type: SyncEventCollectionUpdated, ResourceID: id(suite.personal.ID), Action: "update"},
+	}
+	for _, event := range events {
+		event.UserID = suite.userID
+		event.DeviceID = "phone"
+		event.Status = SyncStatusSynced
+		event.Timestamp = time.Now()
+		suite.Require().NoError(suite.db.Create(event).Error)
+	}
+
+	delta, err := suite.service.GetDeltaSync(ctx, suite.userID, "laptop", since)
+	suite.Require().NoError(err)
+	suite.ElementsMatch([]string{
+		"bookmark_updated:" + id(suite.report.ID),
+		"bookmark_created:12345",
+		"bookmark_deleted:" + id(suite.unfiled.ID),
+		"collection_updated:" + id(suite.work.ID),
+	}, resourceIDs(delta.Events))
+
+	// Unscoped devices still get everything
+	delta, err = suite.service.GetDeltaSync(ctx, suite.userID, "tablet", since)
+	suite.Require().NoError(err)
+	suite.Len(delta.Events, len(events))
+}
+
+func (suite *SyncScopeTestSuite) TestScopeMessage() {
+	response, err := suite.service.HandleSyncMessage(context.Background(), &websocket.SyncMessage{
+		Type:     "sync_scope",
+		UserID:   suite.userID,
+		DeviceID: "laptop",
+		Data:     map[string]interface{}{"collection_ids": []interface{}{float64(suite.work.ID)}},
+	})
+	suite.Require().NoError(err)
+	suite.Equal("scope_change", response.Type)
+	suite.Len(response.Data["tombstones"], 3)
+
+	scope, err := suite.service.GetDeviceScope(context.Background(), suite.userID, "laptop")
+	suite.Require().NoError(err)
+	suite.Equal([]uint{suite.work.ID}, scope.CollectionIDs)
+}
@@ -23,6 +23,7 @@ type Service struct {
 	db          *gorm.DB
 	redisClient RedisClient
 	logger      *zap.Logger
+	liveQueries LiveQueryPublisher
 }
 
 // SyncEventType represents the type of sync event
@@ -63,6 +64,7 @@ type SyncEvent struct {
 	Type       SyncEventType `json:"type" gorm:"not null"`
 	UserID     string        `json:"user_id" gorm:"not null;index"`
 	ResourceID string        `json:"resource_id" gorm:"not null;index"`
+	ClientID   string        `json:"client_id,omitempty" gorm:"size:36"` // client UUID of the resource, if any
 	Action     string        `json:"action" gorm:"not null"`
 	Data       string        `json:"data" gorm:"type:jsonb"`
 	DeviceID   string        `json:"device_id" gorm:"not null;index"`
@@ -99,6 +101,8 @@ func NewService(db *gorm.DB, redisClient RedisClient, logger *zap.Logger) *Servi
 
 // CreateSyncEvent creates and publishes a sync event
 func (s *Service) CreateSyncEvent(ctx context.Context, event *SyncEvent) error {
+	s.resolveClientID(ctx, event)
+
 	// Set timestamp if not provided
 	if event.Timestamp.IsZero() {
 		event.Timestamp = time.Now()
@@ -109,10 +113,20 @@ func (s *Service) CreateSyncEvent(ctx context.Context, event *SyncEvent) error {
 		event.Status = SyncStatusPending
 	}
 
-	// Store event in database
-	if err := s.db.WithContext(ctx).Create(event).Error; err != nil {
-		s.logger.Error("Failed to create sync event", zap.Error(err))
-		return fmt.Errorf("failed to create sync event: %w", err)
+	// Store event in database, held back if it conflicts with changes the
+	// device has not synced yet
+	conflict, err := s.holdBack(ctx, event, func(tx *gorm.DB) error {
+		if err := tx.Create(event).Error; err != nil {
+			s.logger.Error("Failed to create sync event", zap.Error(err))
+			return fmt.Errorf("failed to create sync event: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if conflict != nil {
+		return &ConflictError{Conflict: conflict}
 	}
 
 	// Publish event to Redis for real-time sync
@@ -120,6 +134,7 @@ func (s *Service) CreateSyncEvent(ctx context.Context, event *SyncEvent) error {
 		s.logger.Error("Failed to publish sync event", zap.Error(err))
 		// Don't return error here as the event is already stored
 	}
+	s.publishLive(event)
 
 	s.logger.Info("Sync event created",
 		zap.String("type", string(event.Type)),
@@ -214,7 +229,7 @@ func (s *Service) GetDeltaSync(ctx context.Context, userID, deviceID string, las
 	var events []*SyncEvent
 
 	err := s.db.WithContext(ctx).
-		Where("user_id = ? AND device_id != ? AND timestamp > ?", userID, deviceID, lastSyncTime).
+		Where("user_id = ? AND device_id != ? AND timestamp > ? AND status <> ?", userID, deviceID, lastSyncTime, SyncStatusConflict).
 		Order("timestamp ASC").
 		Find(&events).Error
 
@@ -222,6 +237,12 @@ func (s *Service) GetDeltaSync(ctx context.Context, userID, deviceID string, las
 		return nil, fmt.Errorf("failed to get delta sync: %w", err)
 	}
 
+	// Leave out what the device's sync scope excludes
+	events, err = s.filterScope(ctx, userID, deviceID, events)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get delta sync: %w", err)
+	}
+
 	// Optimize events to reduce bandwidth
 	optimizedEvents := s.OptimizeEvents(events)
 
@@ -233,6 +254,7 @@ func (s *Service) GetDeltaSync(ctx context.Context, userID, deviceID string, las
 
 // QueueOfflineEvent queues an event for offline processing
 func (s *Service) QueueOfflineEvent(ctx context.Context, event *SyncEvent) error {
+	s.resolveClientID(ctx, event)
 	event.Status = SyncStatusPending
 	event.Timestamp = time.Now()
 
@@ -273,7 +295,24 @@ func (s *Service) ProcessOfflineQueue(ctx context.Context, userID, deviceID stri
 		return err
 	}
 
+	held := 0
 	for _, event := range events {
+		// Changes made offline may conflict with what other devices did meanwhile
+		conflict, err := s.holdBack(ctx, event, func(tx *gorm.DB) error {
+			if event.Status != SyncStatusConflict {
+				return nil
+			}
+			return tx.Model(event).Update("status", SyncStatusConflict).Error
+		})
+		if err != nil {
+			s.logger.Error("Failed to check offline event for conflicts", zap.Error(err))
+			continue
+		}
+		if conflict != nil {
+			held++
+			continue
+		}
+
 		// Publish event to Redis
 		if err := s.redisClient.PublishSyncEvent(ctx, userID, event); err != nil {
 			s.logger.Error("Failed to publish offline event", zap.Error(err))
@@ -292,6 +331,7 @@ func (s *Service) ProcessOfflineQueue(ctx context.Context, userID, deviceID stri
 		zap.String("user_id", userID),
 		zap.String("device_id", deviceID),
 		zap.Int("events_processed", len(events)),
+		zap.Int("conflicts", held),
 	)
 
 	return nil
@@ -343,6 +383,41 @@ func (s *Service) HandleSyncMessage(ctx context.Context, msg *websocket.SyncMess
 			Timestamp: time.Now(),
 		}, nil
 
+	case string(SyncMessageScope):
+		// A missing or null collection_ids lifts the scope
+		var collectionIDs []uint
+		if ids, ok := msg.Data["collection_ids"].([]interface{}); ok {
+			collectionIDs = make([]uint, 0, len(ids))
+			for _, id := range ids {
+				number, ok := id.(float64)
+				if !ok || number < 1 {
+					return nil, fmt.Errorf("invalid collection ID: %v", id)
+				}
+				collectionIDs = append(collectionIDs, uint(number))
+			}
+		}
+
+		change, err := s.SetDeviceScope(ctx, msg.UserID, msg.DeviceID, collectionIDs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set sync scope: %w", err)
+		}
+
+		changeData, err := json.Marshal(change)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal scope change: %w", err)
+		}
+
+		var responseData map[string]interface{}
+		if err := json.Unmarshal(changeData, &responseData); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal scope change: %w", err)
+		}
+
+		return &websocket.SyncMessage{
+			Type:      string(SyncMessageScopeChange),
+			Data:      responseData,
+			Timestamp: time.Now(),
+		}, nil
+
 	default:
 		return nil, fmt.Errorf("unknown message type: %s", msg.Type)
 	}
@@ -0,0 +1,40 @@
+package telemetry
+
+import (
+	"net/http"
+
+	"bookmark-sync-service/backend/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler serves the telemetry preview
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new telemetry handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes registers telemetry routes on an admin-only group
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/telemetry/preview", h.GetPreview)
+}
+
+// GetPreview shows exactly what the instance reports, whether or not telemetry is enabled
+// @Summary Preview telemetry report
+// @Tags admin
+// @Produce json
+// @Success 200 {object} Preview
+// @Router /admin/telemetry/preview [get]
+func (h *Handler) GetPreview(c *gin.Context) {
+	preview, err := h.service.Preview(c.Request.Context())
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "TELEMETRY_PREVIEW_FAILED", err.Error(), nil)
+		return
+	}
+
+	utils.SuccessResponse(c, preview, "Telemetry preview generated")
+}
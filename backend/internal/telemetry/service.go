@@ -0,0 +1,252 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/pkg/database"
+	redispkg "bookmark-sync-service/backend/pkg/redis"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ErrDisabled is returned when a report is requested while telemetry is off
+var ErrDisabled = errors.New("telemetry is disabled")
+
+// Report is the anonymous payload sent to the telemetry endpoint. Counts are
+// reported as coarse buckets so no exact figures leave the instance
+type Report struct {
+	InstanceID        string    `json:"instance_id"`
+	Version           string    `json:"version"`
+	UsersBucket       string    `json:"users_bucket"`
+	ActiveUsersBucket string    `json:"daily_active_users_bucket"`
+	BookmarksBucket   string    `json:"bookmarks_bucket"`
+	Features          []string  `json:"features"`
+	GeneratedAt       time.Time `json:"generated_at"`
+}
+
+// Preview describes what the instance would report and whether it is sending
+type Preview struct {
+	Enabled  bool    `json:"enabled"`
+	Endpoint string  `json:"endpoint"`
+	Interval int     `json:"interval_hours"`
+	Report   *Report `json:"report"`
+}
+
+// Service collects aggregate instance statistics and reports them when the
+// operator has opted in
+type Service struct {
+	cfg        config.TelemetryConfig
+	db         *gorm.DB
+	redis      redispkg.RedisInterface
+	features   []string
+	httpClient *http.Client
+	logger     *zap.Logger
+	now        func() time.Time
+	leader     *redispkg.Leader
+
+	mu         sync.Mutex
+	instanceID string
+}
+
+// NewService creates a telemetry service. features lists the optional
+// subsystems enabled on this instance
+func NewService(cfg config.TelemetryConfig, db *gorm.DB, redisClient redispkg.RedisInterface, features []string, logger *zap.Logger) *Service {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 24
+	}
+
+	sorted := append([]string(nil), features...)
+	sort.Strings(sorted)
+
+	return &Service{
+		cfg:        cfg,
+		db:         db,
+		redis:      redisClient,
+		features:   sorted,
+		httpClient: &http.Client{Timeout: config.TelemetryRequestTimeout},
+		logger:     logger,
+		now:        time.Now,
+	}
+}
+
+// Enabled reports whether the instance sends telemetry
+func (s *Service) Enabled() bool {
+	return s.cfg.Enabled && s.cfg.Endpoint != ""
+}
+
+// Preview returns exactly the report that would be sent
+func (s *Service) Preview(ctx context.Context) (*Preview, error) {
+	report, err := s.Collect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Preview{
+		Enabled:  s.Enabled(),
+		Endpoint: s.cfg.Endpoint,
+		Interval: s.cfg.Interval,
+		Report:   report,
+	}, nil
+}
+
+// Collect builds the telemetry report from the database
+func (s *Service) Collect(ctx context.Context) (*Report, error) {
+	db := s.db.WithContext(ctx)
+	now := s.now()
+
+	var users, activeUsers, bookmarks int64
+	if err := db.Model(&database.User{}).Count(&users).Error; err != nil {
+		return nil, fmt.Errorf("failed to count users: %w", err)
+	}
+	if err := db.Model(&database.User{}).Where("last_active_at >= ?", now.Add(-24*time.Hour)).Count(&activeUsers).Error; err != nil {
+		return nil, fmt.Errorf("failed to count active users: %w", err)
+	}
+	if err := db.Model(&database.Bookmark{}).Count(&bookmarks).Error; err != nil {
+		return nil, fmt.Errorf("failed to count bookmarks: %w", err)
+	}
+
+	return &Report{
+		InstanceID:        s.instance(ctx),
+		Version:           config.Version,
+		UsersBucket:       Bucket(users),
+		ActiveUsersBucket: Bucket(activeUsers),
+		BookmarksBucket:   Bucket(bookmarks),
+		Features:          s.features,
+		GeneratedAt:       now.UTC().Truncate(time.Hour),
+	}, nil
+}
+
+// Send collects and posts a report to the configured endpoint
+func (s *Service) Send(ctx context.Context) error {
+	if !s.Enabled() {
+		return ErrDisabled
+	}
+
+	report, err := s.Collect(ctx)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "bookmark-sync-service/"+config.Version)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send report: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("telemetry endpoint returned HTTP %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// EnableLeaderElection makes only one replica send reports. The lease
+// outlives one interval so the leader keeps it between reports
+func (s *Service) EnableLeaderElection(client *redispkg.Client) {
+	s.leader = client.NewLeader("telemetry", 2*time.Duration(s.cfg.Interval)*time.Hour)
+}
+
+// Run reports on the configured interval until the context is cancelled. It
+// returns immediately when telemetry is disabled
+func (s *Service) Run(ctx context.Context) {
+	if !s.Enabled() {
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(s.cfg.Interval) * time.Hour)
+	defer ticker.Stop()
+
+	for {
+		if s.leads(ctx) {
+			if err := s.Send(ctx); err != nil {
+				s.logger.Debug("Telemetry report failed", zap.Error(err))
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// leads reports whether this replica should send the report. Without a
+// leader every replica reports
+func (s *Service) leads(ctx context.Context) bool {
+	if s.leader == nil {
+		return true
+	}
+	leading, err := s.leader.Campaign(ctx)
+	if err != nil {
+		s.logger.Debug("Telemetry leader election failed", zap.Error(err))
+	}
+	return leading
+}
+
+// instance returns a random identifier for this installation, shared by all
+// instances through Redis so reports can be de-duplicated
+func (s *Service) instance(ctx context.Context) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.instanceID != "" {
+		return s.instanceID
+	}
+
+	if s.redis != nil {
+		if id, err := s.redis.Get(ctx, config.TelemetryInstanceKey); err == nil && id != "" {
+			s.instanceID = id
+			return id
+		}
+	}
+
+	s.instanceID = uuid.NewString()
+	if s.redis != nil {
+		s.redis.Set(ctx, config.TelemetryInstanceKey, s.instanceID, 0)
+	}
+	return s.instanceID
+}
+
+// Bucket maps a count onto a coarse, order-of-magnitude range
+func Bucket(n int64) string {
+	switch {
+	case n <= 0:
+		return "0"
+	case n <= 10:
+		return "1-10"
+	case n <= 100:
+		return "11-100"
+	case n <= 1000:
+		return "101-1000"
+	case n <= 10000:
+		return "1001-10000"
+	case n <= 100000:
+		return "10001-100000"
+	default:
+		return "100000+"
+	}
+}
@@ -0,0 +1,129 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/redis"
+	"bookmark-sync-service/backend/pkg/testfactory"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func setupService(t *testing.T, cfg config.TelemetryConfig) *Service {
+	db := testfactory.NewDB(t, &database.User{}, &database.Bookmark{})
+	f := testfactory.New(t, db)
+
+	now := time.Now()
+	active := f.User(func(u *database.User) { u.LastActiveAt = &now })
+	f.User()
+	f.User()
+	for i := 0; i < 12; i++ {
+		f.Bookmark(active.ID)
+	}
+
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+	client, err := redis.NewClient(config.RedisConfig{Host: mr.Host(), Port: mr.Port(), PoolSize: 1})
+	require.NoError(t, err)
+
+	return NewService(cfg, db, client, []string{"search", "admin"}, zap.NewNop())
+}
+
+func TestBucket(t *testing.T) {
+	assert.Equal(t, "0", Bucket(0))
+	assert.Equal(t, "1-10", Bucket(10))
+	assert.Equal(t, "11-100", Bucket(11))
+	assert.Equal(t, "1001-10000", Bucket(5000))
+	assert.Equal(t, "100000+", Bucket(250000))
+}
+
+func TestCollect_ReportsBucketsOnly(t *testing.T) {
+	service := setupService(t, config.TelemetryConfig{})
+
+	report, err := service.Collect(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, "1-10", report.UsersBucket)
+	assert.Equal(t, "1-10", report.ActiveUsersBucket)
+	assert.Equal(t, "11-100", report.BookmarksBucket)
+	assert.Equal(t, config.Version, report.Version)
+	assert.Equal(t, []string{"admin", "search"}, report.Features)
+
+	// The instance identifier is stable across reports
+	again, err := service.Collect(context.Background())
+	require.NoError(t, err)
+	assert.NotEmpty(t, report.InstanceID)
+	assert.Equal(t, report.InstanceID, again.InstanceID)
+}
+
+func TestSend_DisabledSendsNothing(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	service := setupService(t, config.TelemetryConfig{Enabled: false, Endpoint: server.URL})
+
+	assert.ErrorIs(t, service.Send(context.Background()), ErrDisabled)
+	service.Run(context.Background())
+	assert.False(t, called)
+}
+
+func TestSend_PostsPreviewedReport(t *testing.T) {
+	var received Report
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&received)
+	}))
+	defer server.Close()
+
+	service := setupService(t, config.TelemetryConfig{Enabled: true, Endpoint: server.URL})
+
+	preview, err := service.Preview(context.Background())
+	require.NoError(t, err)
+	assert.True(t, preview.Enabled)
+
+	require.NoError(t, service.Send(context.Background()))
+	assert.Equal(t, *preview.Report, received)
+}
+
+func TestGetPreview(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	NewHandler(setupService(t, config.TelemetryConfig{})).RegisterRoutes(router.Group("/admin"))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/telemetry/preview", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"enabled":false`)
+	assert.Contains(t, w.Body.String(), `"users_bucket":"1-10"`)
+}
+
+func TestLeaderElectionLetsOneReplicaReport(t *testing.T) {
+	first := setupService(t, config.TelemetryConfig{Enabled: true, Endpoint: "http://example.com"})
+	client := first.redis.(*redis.Client)
+	second := NewService(first.cfg, first.db, client, nil, zap.NewNop())
+
+	// Without election every replica reports
+	assert.True(t, second.leads(context.Background()))
+
+	first.EnableLeaderElection(client)
+	second.EnableLeaderElection(client)
+
+	assert.True(t, first.leads(context.Background()))
+	assert.False(t, second.leads(context.Background()))
+	assert.True(t, first.leads(context.Background()))
+}
@@ -0,0 +1,263 @@
+package urlrules
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"bookmark-sync-service/backend/pkg/urlnorm"
+	"bookmark-sync-service/backend/pkg/utils"
+)
+
+// Handler serves a user's URL cleanup rules and shared rule packs
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new URL rules handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes registers the URL rules routes
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	rules := router.Group("/url-rules")
+	rules.GET("", h.GetRules)
+	rules.PUT("", h.SaveRules)
+	rules.POST("/test", h.TestRules)
+	rules.GET("/packs", h.ListPacks)
+	rules.POST("/packs", h.CreatePack)
+	rules.DELETE("/packs/:id", h.DeletePack)
+	rules.GET("/shared/:token", h.GetSharedPack)
+	rules.POST("/shared/:token/install", h.InstallPack)
+}
+
+// GetRules returns the user's rules and the built-in defaults
+// @Summary Get my URL cleanup rules
+// @Tags url-rules
+// @Produce json
+// @Success 200 {object} Settings
+// @Router /url-rules [get]
+func (h *Handler) GetRules(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	settings, err := h.service.GetSettings(c.Request.Context(), userID)
+	if err != nil {
+		h.fail(c, err, "Failed to get URL rules")
+		return
+	}
+	utils.SuccessResponse(c, gin.H{"settings": settings, "defaults": urlnorm.Defaults}, "URL rules retrieved")
+}
+
+// SaveRules replaces the user's rules
+// @Summary Save my URL cleanup rules
+// @Description Rules apply to bookmarks saved or imported from now on
+// @Tags url-rules
+// @Accept json
+// @Produce json
+// @Param settings body Settings true "Rules"
+// @Success 200 {object} Settings
+// @Failure 400 {object} utils.ErrorResponse
+// @Router /url-rules [put]
+func (h *Handler) SaveRules(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	var settings Settings
+	if err := c.ShouldBindJSON(&settings); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request format", nil)
+		return
+	}
+	saved, err := h.service.SaveSettings(c.Request.Context(), userID, settings)
+	if err != nil {
+		h.fail(c, err, "Failed to save URL rules")
+		return
+	}
+	utils.SuccessResponse(c, saved, "URL rules saved")
+}
+
+// TestRulesRequest is a URL to clean up, with draft rules to try instead of
+// the saved ones
+type TestRulesRequest struct {
+	URL      string    `json:"url" binding:"required"`
+	Settings *Settings `json:"settings,omitempty"`
+}
+
+// TestRules shows what the rules would do to a URL
+// @Summary Test URL cleanup rules
+// @Tags url-rules
+// @Accept json
+// @Produce json
+// @Param request body TestRulesRequest true "URL and optional draft rules"
+// @Success 200 {object} urlnorm.Result
+// @Failure 400 {object} utils.ErrorResponse
+// @Router /url-rules/test [post]
+func (h *Handler) TestRules(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	var req TestRulesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request format", nil)
+		return
+	}
+	result, err := h.service.Test(c.Request.Context(), userID, req.URL, req.Settings)
+	if err != nil {
+		h.fail(c, err, "Failed to test URL rules")
+		return
+	}
+	utils.SuccessResponse(c, result, "URL rules tested")
+}
+
+// CreatePackRequest names a pack shared from the user's current rules
+type CreatePackRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+}
+
+// CreatePack shares the user's rules as a pack
+// @Summary Share my URL rules as a pack
+// @Tags url-rules
+// @Accept json
+// @Produce json
+// @Param request body CreatePackRequest true "Pack"
+// @Success 201 {object} Pack
+// @Failure 400 {object} utils.ErrorResponse
+// @Router /url-rules/packs [post]
+func (h *Handler) CreatePack(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	var req CreatePackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request format", nil)
+		return
+	}
+	pack, err := h.service.CreatePack(c.Request.Context(), userID, req.Name, req.Description)
+	if err != nil {
+		h.fail(c, err, "Failed to create rule pack")
+		return
+	}
+	c.JSON(http.StatusCreated, utils.APIResponse{
+		Success: true,
+		Message: "Rule pack created",
+		Data:    pack,
+	})
+}
+
+// ListPacks lists the packs the user shared
+// @Summary List my URL rule packs
+// @Tags url-rules
+// @Produce json
+// @Success 200 {array} Pack
+// @Router /url-rules/packs [get]
+func (h *Handler) ListPacks(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	packs, err := h.service.ListPacks(c.Request.Context(), userID)
+	if err != nil {
+		h.fail(c, err, "Failed to list rule packs")
+		return
+	}
+	utils.SuccessResponse(c, gin.H{"packs": packs}, "Rule packs retrieved")
+}
+
+// DeletePack stops sharing a pack
+// @Summary Delete a URL rule pack
+// @Tags url-rules
+// @Param id path int true "Pack ID"
+// @Success 200 {object} utils.SuccessResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /url-rules/packs/{id} [delete]
+func (h *Handler) DeletePack(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_ID", "Invalid pack ID", nil)
+		return
+	}
+
+	if err := h.service.DeletePack(c.Request.Context(), userID, uint(id)); err != nil {
+		h.fail(c, err, "Failed to delete rule pack")
+		return
+	}
+	utils.SuccessResponse(c, nil, "Rule pack deleted")
+}
+
+// GetSharedPack shows a shared pack before installing it
+// @Summary Get a shared URL rule pack
+// @Tags url-rules
+// @Produce json
+// @Param token path string true "Share token"
+// @Success 200 {object} Pack
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /url-rules/shared/{token} [get]
+func (h *Handler) GetSharedPack(c *gin.Context) {
+	pack, err := h.service.GetSharedPack(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		h.fail(c, err, "Failed to get rule pack")
+		return
+	}
+	utils.SuccessResponse(c, pack, "Rule pack retrieved")
+}
+
+// InstallPack merges a shared pack into the user's rules
+// @Summary Install a shared URL rule pack
+// @Tags url-rules
+// @Produce json
+// @Param token path string true "Share token"
+// @Success 200 {object} Settings
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /url-rules/shared/{token}/install [post]
+func (h *Handler) InstallPack(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	settings, err := h.service.InstallPack(c.Request.Context(), userID, c.Param("token"))
+	if err != nil {
+		h.fail(c, err, "Failed to install rule pack")
+		return
+	}
+	utils.SuccessResponse(c, settings, "Rule pack installed")
+}
+
+func (h *Handler) fail(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, urlnorm.ErrInvalidRule):
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_URL_RULE", err.Error(), nil)
+	case errors.Is(err, ErrInvalidPack):
+		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_RULE_PACK", err.Error(), nil)
+	case errors.Is(err, ErrPackNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, "RULE_PACK_NOT_FOUND", "Rule pack not found", nil)
+	case errors.Is(err, ErrUserNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, "USER_NOT_FOUND", "User not found", nil)
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", message, nil)
+	}
+}
@@ -0,0 +1,282 @@
+package urlrules
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/urlnorm"
+)
+
+// preferencesKey is where a user's rules are kept in their preferences
+const preferencesKey = "urlRules"
+
+// maxPacks caps the rule packs one user can share
+const maxPacks = 20
+
+var (
+	// ErrUserNotFound is returned for users that don't exist
+	ErrUserNotFound = errors.New("user not found")
+	// ErrPackNotFound is returned for unknown packs and tokens
+	ErrPackNotFound = errors.New("rule pack not found")
+	// ErrInvalidPack is returned for packs that can't be shared
+	ErrInvalidPack = errors.New("invalid rule pack")
+)
+
+// Settings are a user's URL cleanup rules. The built-in defaults apply
+// ahead of them unless disabled
+type Settings struct {
+	Rules           []urlnorm.Rule `json:"rules"`
+	DisableDefaults bool           `json:"disable_defaults"`
+}
+
+// Pack is a shared rule pack with its rules decoded
+type Pack struct {
+	database.URLRulePack
+	Rules []urlnorm.Rule `json:"rules"`
+}
+
+// Service stores users' URL rules and the packs they share
+type Service struct {
+	db *gorm.DB
+}
+
+// NewService creates a URL rules service
+func NewService(db *gorm.DB) *Service {
+	return &Service{db: db}
+}
+
+// GetSettings returns the user's saved rules
+func (s *Service) GetSettings(ctx context.Context, userID uint) (*Settings, error) {
+	prefs, err := s.preferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	settings := &Settings{Rules: []urlnorm.Rule{}}
+	if raw, ok := prefs[preferencesKey]; ok {
+		if err := json.Unmarshal(raw, settings); err != nil || urlnorm.Validate(settings.Rules) != nil {
+			// Rules that no longer validate are dropped rather than breaking saves
+			return &Settings{Rules: []urlnorm.Rule{}}, nil
+		}
+	}
+	return settings, nil
+}
+
+// SaveSettings validates and stores the user's rules
+func (s *Service) SaveSettings(ctx context.Context, userID uint, settings Settings) (*Settings, error) {
+	if settings.Rules == nil {
+		settings.Rules = []urlnorm.Rule{}
+	}
+	if err := urlnorm.Validate(settings.Rules); err != nil {
+		return nil, err
+	}
+
+	raw, err := json.Marshal(settings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode URL rules: %w", err)
+	}
+	if err := s.updatePreferences(ctx, userID, func(prefs map[string]json.RawMessage) {
+		prefs[preferencesKey] = raw
+	}); err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// Rules returns the rules applied to the user's URLs, defaults included
+func (s *Service) Rules(ctx context.Context, userID uint) ([]urlnorm.Rule, error) {
+	settings, err := s.GetSettings(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return settings.effective(), nil
+}
+
+// Test shows what saving a URL would do to it, under the given settings or
+// the user's saved ones
+func (s *Service) Test(ctx context.Context, userID uint, rawURL string, settings *Settings) (*urlnorm.Result, error) {
+	if settings == nil {
+		saved, err := s.GetSettings(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		settings = saved
+	} else if err := urlnorm.Validate(settings.Rules); err != nil {
+		return nil, err
+	}
+
+	result, err := urlnorm.Normalize(rawURL, settings.effective())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", urlnorm.ErrInvalidRule, err)
+	}
+	return &result, nil
+}
+
+// CreatePack shares the user's current rules as a pack others can install
+func (s *Service) CreatePack(ctx context.Context, userID uint, name, description string) (*Pack, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 100 {
+		return nil, fmt.Errorf("%w: name must be 1 to 100 characters", ErrInvalidPack)
+	}
+	settings, err := s.GetSettings(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(settings.Rules) == 0 {
+		return nil, fmt.Errorf("%w: there are no rules to share", ErrInvalidPack)
+	}
+
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&database.URLRulePack{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to count rule packs: %w", err)
+	}
+	if count >= maxPacks {
+		return nil, fmt.Errorf("%w: at most %d packs", ErrInvalidPack, maxPacks)
+	}
+
+	rules, err := json.Marshal(settings.Rules)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode rule pack: %w", err)
+	}
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	pack := database.URLRulePack{
+		UserID:      userID,
+		Name:        name,
+		Description: strings.TrimSpace(description),
+		Rules:       string(rules),
+		ShareToken:  token,
+	}
+	if err := s.db.WithContext(ctx).Create(&pack).Error; err != nil {
+		return nil, fmt.Errorf("failed to create rule pack: %w", err)
+	}
+	return &Pack{URLRulePack: pack, Rules: settings.Rules}, nil
+}
+
+// ListPacks returns the packs the user shared, newest first
+func (s *Service) ListPacks(ctx context.Context, userID uint) ([]Pack, error) {
+	var rows []database.URLRulePack
+	if err := s.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC, id DESC").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to list rule packs: %w", err)
+	}
+	packs := make([]Pack, 0, len(rows))
+	for _, row := range rows {
+		packs = append(packs, decode(row))
+	}
+	return packs, nil
+}
+
+// DeletePack stops sharing a pack; installed copies are kept
+func (s *Service) DeletePack(ctx context.Context, userID, id uint) error {
+	result := s.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&database.URLRulePack{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete rule pack: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrPackNotFound
+	}
+	return nil
+}
+
+// GetSharedPack looks a pack up by its share token
+func (s *Service) GetSharedPack(ctx context.Context, token string) (*Pack, error) {
+	var row database.URLRulePack
+	if err := s.db.WithContext(ctx).Where("share_token = ?", token).First(&row).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPackNotFound
+		}
+		return nil, fmt.Errorf("failed to get rule pack: %w", err)
+	}
+	pack := decode(row)
+	return &pack, nil
+}
+
+// InstallPack merges a shared pack into the user's rules; the pack's rule
+// for a domain replaces the user's own
+func (s *Service) InstallPack(ctx context.Context, userID uint, token string) (*Settings, error) {
+	pack, err := s.GetSharedPack(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	settings, err := s.GetSettings(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	settings.Rules = urlnorm.Merge(settings.Rules, pack.Rules)
+	saved, err := s.SaveSettings(ctx, userID, *settings)
+	if err != nil {
+		return nil, err
+	}
+	if pack.UserID != userID {
+		s.db.WithContext(ctx).Model(&database.URLRulePack{}).Where("id = ?", pack.ID).
+			UpdateColumn("install_count", gorm.Expr("install_count + 1"))
+	}
+	return saved, nil
+}
+
+func (settings *Settings) effective() []urlnorm.Rule {
+	if settings.DisableDefaults {
+		return settings.Rules
+	}
+	return urlnorm.WithDefaults(settings.Rules)
+}
+
+func decode(row database.URLRulePack) Pack {
+	pack := Pack{URLRulePack: row, Rules: []urlnorm.Rule{}}
+	_ = json.Unmarshal([]byte(row.Rules), &pack.Rules)
+	return pack
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate share token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func (s *Service) preferences(ctx context.Context, userID uint) (map[string]json.RawMessage, error) {
+	var u database.User
+	if err := s.db.WithContext(ctx).Select("id", "preferences").First(&u, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get preferences: %w", err)
+	}
+
+	prefs := map[string]json.RawMessage{}
+	if u.Preferences != "" {
+		if err := json.Unmarshal([]byte(u.Preferences), &prefs); err != nil {
+			return nil, fmt.Errorf("failed to parse preferences: %w", err)
+		}
+	}
+	return prefs, nil
+}
+
+func (s *Service) updatePreferences(ctx context.Context, userID uint, update func(map[string]json.RawMessage)) error {
+	prefs, err := s.preferences(ctx, userID)
+	if err != nil {
+		return err
+	}
+	update(prefs)
+
+	raw, err := json.Marshal(prefs)
+	if err != nil {
+		return fmt.Errorf("failed to encode preferences: %w", err)
+	}
+	if err := s.db.WithContext(ctx).Model(&database.User{}).Where("id = ?", userID).
+		Update("preferences", string(raw)).Error; err != nil {
+		return fmt.Errorf("failed to save preferences: %w", err)
+	}
+	return nil
+}
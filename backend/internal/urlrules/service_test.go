@@ -0,0 +1,111 @@
+package urlrules
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/testfactory"
+	"bookmark-sync-service/backend/pkg/urlnorm"
+)
+
+func TestSettings(t *testing.T) {
+	db := testfactory.NewDB(t)
+	factory := testfactory.New(t, db)
+	owner := factory.User(func(u *database.User) { u.Preferences = `{"theme":"dark"}` })
+	service := NewService(db)
+	ctx := context.Background()
+
+	settings, err := service.GetSettings(ctx, owner.ID)
+	require.NoError(t, err)
+	assert.Empty(t, settings.Rules)
+	rules, err := service.Rules(ctx, owner.ID)
+	require.NoError(t, err)
+	assert.Equal(t, urlnorm.Defaults, rules)
+
+	_, err = service.SaveSettings(ctx, owner.ID, Settings{Rules: []urlnorm.Rule{{Domain: "https://example.com"}}})
+	assert.ErrorIs(t, err, urlnorm.ErrInvalidRule)
+
+	saved, err := service.SaveSettings(ctx, owner.ID, Settings{Rules: []urlnorm.Rule{{Domain: "Example.com", Strip: []string{"ref"}}}})
+	require.NoError(t, err)
+	assert.Equal(t, "example.com", saved.Rules[0].Domain)
+
+	// Other preferences are kept
+	var stored database.User
+	require.NoError(t, db.First(&stored, owner.ID).Error)
+	var prefs map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal([]byte(stored.Preferences), &prefs))
+	assert.JSONEq(t, `"dark"`, string(prefs["theme"]))
+
+	t.Run("testing rules", func(t *testing.T) {
+		result, err := service.Test(ctx, owner.ID, "https://example.com/a?ref=x&utm_source=y&id=1", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/a?id=1", result.URL)
+		assert.Equal(t, []string{"ref", "utm_source"}, result.Removed)
+
+		// Draft rules are tried without saving them
+		result, err = service.Test(ctx, owner.ID, "https://example.com/a?ref=x&utm_source=y", &Settings{DisableDefaults: true})
+		require.NoError(t, err)
+		assert.False(t, result.Changed)
+
+		_, err = service.Test(ctx, owner.ID, "not a url", nil)
+		assert.ErrorIs(t, err, urlnorm.ErrInvalidRule)
+	})
+
+	_, err = service.GetSettings(ctx, 9999)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+}
+
+func TestPacks(t *testing.T) {
+	db := testfactory.NewDB(t)
+	factory := testfactory.New(t, db)
+	author := factory.User()
+	installer := factory.User()
+	service := NewService(db)
+	ctx := context.Background()
+
+	_, err := service.CreatePack(ctx, author.ID, "Empty", "")
+	assert.ErrorIs(t, err, ErrInvalidPack)
+
+	_, err = service.SaveSettings(ctx, author.ID, Settings{Rules: []urlnorm.Rule{
+		{Domain: "example.com", Strip: []string{"ref"}},
+		{Domain: "news.org", ForceHTTPS: true},
+	}})
+	require.NoError(t, err)
+	pack, err := service.CreatePack(ctx, author.ID, " News sites ", "Cleaner news links")
+	require.NoError(t, err)
+	assert.Equal(t, "News sites", pack.Name)
+	assert.Len(t, pack.ShareToken, 32)
+	assert.Len(t, pack.Rules, 2)
+
+	_, err = service.SaveSettings(ctx, installer.ID, Settings{Rules: []urlnorm.Rule{
+		{Domain: "example.com", Keep: []string{"ref"}},
+		{Domain: "blog.dev", Strip: []string{"src"}},
+	}})
+	require.NoError(t, err)
+
+	settings, err := service.InstallPack(ctx, installer.ID, pack.ShareToken)
+	require.NoError(t, err)
+	assert.Equal(t, []urlnorm.Rule{
+		{Domain: "blog.dev", Strip: []string{"src"}},
+		{Domain: "example.com", Strip: []string{"ref"}},
+		{Domain: "news.org", ForceHTTPS: true},
+	}, settings.Rules)
+
+	packs, err := service.ListPacks(ctx, author.ID)
+	require.NoError(t, err)
+	require.Len(t, packs, 1)
+	assert.Equal(t, 1, packs[0].InstallCount)
+	assert.Equal(t, pack.Rules, packs[0].Rules)
+
+	_, err = service.InstallPack(ctx, installer.ID, "unknown")
+	assert.ErrorIs(t, err, ErrPackNotFound)
+	assert.ErrorIs(t, service.DeletePack(ctx, installer.ID, pack.ID), ErrPackNotFound)
+	require.NoError(t, service.DeletePack(ctx, author.ID, pack.ID))
+	_, err = service.GetSharedPack(ctx, pack.ShareToken)
+	assert.ErrorIs(t, err, ErrPackNotFound)
+}
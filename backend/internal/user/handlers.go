@@ -213,6 +213,9 @@ func (h *Handler) UploadAvatar(c *gin.Context) {
 	// Parse multipart form
 	file, header, err := c.Request.FormFile("avatar")
 	if err != nil {
+		if middleware.BodyTooLarge(c, err) {
+			return
+		}
 		utils.ErrorResponse(c, http.StatusBadRequest, "INVALID_FILE", "No file uploaded or invalid file", nil)
 		return
 	}
@@ -226,8 +229,8 @@ func (h *Handler) UploadAvatar(c *gin.Context) {
 	}
 
 	// Validate file size (max 5MB)
-	if header.Size > 5*1024*1024 {
-		utils.ErrorResponse(c, http.StatusBadRequest, "FILE_TOO_LARGE", "File size must be less than 5MB", nil)
+	if header.Size > config.MaxAvatarUploadSize {
+		utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, "FILE_TOO_LARGE", "File size must be less than 5MB", map[string]interface{}{"limit_bytes": config.MaxAvatarUploadSize})
 		return
 	}
 
@@ -0,0 +1,208 @@
+package user
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/internal/safety"
+	"bookmark-sync-service/backend/pkg/database"
+	redispkg "bookmark-sync-service/backend/pkg/redis"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Profile visibility values
+const (
+	ProfileVisibilityPrivate = "private"
+	ProfileVisibilityPublic  = "public"
+)
+
+// defaultPublicPageSize is the page size when a client asks for none
+const defaultPublicPageSize = 20
+
+// ErrPublicProfileNotFound is returned for unknown users and for users who
+// keep their profile private, so the API does not reveal which usernames exist
+var ErrPublicProfileNotFound = errors.New("public profile not found")
+
+// PublicBookmark is a bookmark as shown to anyone, without private fields
+// such as notes or metadata
+type PublicBookmark struct {
+	URL         string    `json:"url"`
+	Title       string    `json:"title"`
+	Description string    `json:"description,omitempty"`
+	Favicon     string    `json:"favicon,omitempty"`
+	Tags        []string  `json:"tags"`
+	SavedAt     time.Time `json:"saved_at"`
+	Warning     string    `json:"warning,omitempty"` // set when the link is flagged as unsafe
+}
+
+// PublicBookmarkPage is one page of a user's public bookmarks, newest first
+type PublicBookmarkPage struct {
+	Username    string           `json:"username"`
+	DisplayName string           `json:"display_name"`
+	Bookmarks   []PublicBookmark `json:"bookmarks"`
+	Page        int              `json:"page"`
+	Limit       int              `json:"limit"`
+	HasMore     bool             `json:"has_more"`
+}
+
+// EnablePublicBookmarks configures the public bookmarks API, caching pages in
+// cache when it is not nil
+func (s *Service) EnablePublicBookmarks(cfg config.PublicProfileConfig, cache redispkg.RedisInterface) {
+	s.publicCfg = cfg
+	s.cache = cache
+}
+
+// GetPublicBookmarks lists the bookmarks a user filed in their public
+// collections, if their profile is public. Pages are cached for a short
+// while, but the visibility is checked on every request so turning the
+// profile private takes effect at once
+func (s *Service) GetPublicBookmarks(ctx context.Context, username string, page, limit int) (*PublicBookmarkPage, error) {
+	user, err := s.PublicProfile(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+
+	page, limit = s.publicPage(page, limit)
+	key := fmt.Sprintf("%s:%d:%d:%d", config.PublicBookmarksPrefix, user.ID, page, limit)
+	caching := s.cache != nil && s.PublicCacheTTL() > 0
+	if caching {
+		if value, err := s.cache.Get(ctx, key); err == nil && value != "" {
+			var cached PublicBookmarkPage
+			if err := json.Unmarshal([]byte(value), &cached); err == nil {
+				return &cached, nil
+			}
+		}
+	}
+
+	var bookmarks []database.Bookmark
+	publicCollections := s.db.Table("bookmark_collections").
+		Select("bookmark_collections.bookmark_id").
+		Joins("JOIN collections ON collections.id = bookmark_collections.collection_id AND collections.deleted_at IS NULL").
+		Where("collections.user_id = ? AND collections.visibility = ?", user.ID, "public")
+	if err := s.db.WithContext(ctx).
+		Where("user_id = ? AND id IN (?)", user.ID, publicCollections).
+		Order("created_at DESC, id DESC").
+		Offset((page - 1) * limit).
+		Limit(limit + 1).
+		Find(&bookmarks).Error; err != nil {
+		return nil, fmt.Errorf("failed to get public bookmarks: %w", err)
+	}
+
+	result := &PublicBookmarkPage{
+		Username:    user.Username,
+		DisplayName: user.DisplayName,
+		Bookmarks:   make([]PublicBookmark, 0, len(bookmarks)),
+		Page:        page,
+		Limit:       limit,
+		HasMore:     len(bookmarks) > limit,
+	}
+	if result.HasMore {
+		bookmarks = bookmarks[:limit]
+	}
+	for _, bookmark := range bookmarks {
+		tags := []string{}
+		if bookmark.Tags != "" {
+			if err := json.Unmarshal([]byte(bookmark.Tags), &tags); err != nil {
+				tags = []string{}
+			}
+		}
+		result.Bookmarks = append(result.Bookmarks, PublicBookmark{
+			URL:         bookmark.URL,
+			Title:       bookmark.Title,
+			Description: bookmark.Description,
+			Favicon:     bookmark.Favicon,
+			Tags:        tags,
+			SavedAt:     bookmark.CreatedAt,
+			Warning:     safety.Warning(bookmark.SafetyThreat),
+		})
+	}
+
+	if caching {
+		if value, err := json.Marshal(result); err == nil {
+			if err := s.cache.Set(ctx, key, string(value), s.PublicCacheTTL()); err != nil {
+				s.logger.Warn("Failed to cache public bookmarks", zap.Error(err), zap.Uint("user_id", user.ID))
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// WarmPublicBookmarks caches the first page of a user's public bookmarks so
+// the first visitor after a deploy is served from the cache. Private
+// profiles, and deployments without the cache, are left alone
+func (s *Service) WarmPublicBookmarks(ctx context.Context, userID uint) error {
+	if s.cache == nil || s.PublicCacheTTL() <= 0 {
+		return nil
+	}
+
+	var user database.User
+	if err := s.db.WithContext(ctx).Select("username").First(&user, userID).Error; err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if _, err := s.GetPublicBookmarks(ctx, user.Username, 1, 0); err != nil && !errors.Is(err, ErrPublicProfileNotFound) {
+		return err
+	}
+	return nil
+}
+
+// PublicCacheTTL is how long pages of public bookmarks may be cached, zero
+// when they are not
+func (s *Service) PublicCacheTTL() time.Duration {
+	if s.publicCfg.CacheTTL <= 0 {
+		return 0
+	}
+	return time.Duration(s.publicCfg.CacheTTL) * time.Second
+}
+
+// publicPage clamps the requested page and page size
+func (s *Service) publicPage(page, limit int) (int, int) {
+	maxLimit := s.publicCfg.MaxPageSize
+	if maxLimit <= 0 {
+		maxLimit = defaultPublicPageSize
+	}
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = defaultPublicPageSize
+	}
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+	return page, limit
+}
+
+// PublicProfile returns the user with a username if their profile is public
+func (s *Service) PublicProfile(ctx context.Context, username string) (*database.User, error) {
+	var user database.User
+	if err := s.db.WithContext(ctx).Where("username = ?", username).First(&user).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrPublicProfileNotFound
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if !ProfileIsPublic(user.Preferences) {
+		return nil, ErrPublicProfileNotFound
+	}
+	return &user, nil
+}
+
+// ProfileIsPublic reads the profile visibility from stored preferences;
+// profiles are private unless the user opted in
+func ProfileIsPublic(preferences string) bool {
+	if preferences == "" {
+		return false
+	}
+	var prefs UserPreferences
+	if err := json.Unmarshal([]byte(preferences), &prefs); err != nil {
+		return false
+	}
+	return prefs.ProfileVisibility == ProfileVisibilityPublic
+}
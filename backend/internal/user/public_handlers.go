@@ -0,0 +1,87 @@
+package user
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"bookmark-sync-service/backend/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PublicBookmarksService serves the bookmarks users publish on their profile
+type PublicBookmarksService interface {
+	GetPublicBookmarks(ctx context.Context, username string, page, limit int) (*PublicBookmarkPage, error)
+	PublicCacheTTL() time.Duration
+}
+
+// PublicHandler serves the login-less public bookmarks API
+type PublicHandler struct {
+	service PublicBookmarksService
+}
+
+// NewPublicHandler creates a new public bookmarks handler
+func NewPublicHandler(service PublicBookmarksService) *PublicHandler {
+	return &PublicHandler{service: service}
+}
+
+// RegisterRoutes registers the public bookmarks routes
+func (h *PublicHandler) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/public/users/:username/bookmarks", h.GetPublicBookmarks)
+}
+
+// GetPublicBookmarks lists a user's public bookmarks for widgets on other sites
+// @Summary Get a user's public bookmarks
+// @Description List the bookmarks in a user's public collections, newest first, when the user made their profile public. No token is needed, any site may fetch it, and responses are cached briefly
+// @Tags users
+// @Produce json
+// @Param username path string true "Username"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Bookmarks per page" default(20)
+// @Success 200 {object} PublicBookmarkPage
+// @Success 304 "Not modified"
+// @Failure 404 {object} utils.ErrorResponse "Unknown user or private profile"
+// @Failure 429 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/public/users/{username}/bookmarks [get]
+func (h *PublicHandler) GetPublicBookmarks(c *gin.Context) {
+	// Personal websites call this from their own origin without credentials
+	c.Writer.Header().Del("Access-Control-Allow-Credentials")
+	c.Header("Access-Control-Allow-Origin", "*")
+
+	page, _ := strconv.Atoi(c.Query("page"))
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	result, err := h.service.GetPublicBookmarks(c.Request.Context(), c.Param("username"), page, limit)
+	if err != nil {
+		if err == ErrPublicProfileNotFound {
+			utils.ErrorResponse(c, http.StatusNotFound, "NOT_FOUND", "Public profile not found", nil)
+			return
+		}
+		utils.InternalErrorResponse(c, "Failed to get public bookmarks")
+		return
+	}
+
+	if ttl := h.service.PublicCacheTTL(); ttl > 0 {
+		c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(ttl.Seconds())))
+	} else {
+		c.Header("Cache-Control", "no-cache")
+	}
+	if body, err := json.Marshal(result); err == nil {
+		sum := sha256.Sum256(body)
+		etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+		c.Header("ETag", etag)
+		if c.GetHeader("If-None-Match") == etag {
+			c.Status(http.StatusNotModified)
+			return
+		}
+	}
+
+	utils.SuccessResponse(c, result, "Public bookmarks retrieved successfully")
+}
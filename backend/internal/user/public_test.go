@@ -0,0 +1,175 @@
+package user
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/redis"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// createPublicFixture gives the user a public and a private collection with
+// bookmarks in each
+func createPublicFixture(t *testing.T, db *gorm.DB, user *database.User, publicCount int) {
+	public := &database.Collection{UserID: user.ID, Name: "Links", Visibility: "public", ShareLink: fmt.Sprintf("public-%d", user.ID)}
+	private := &database.Collection{UserID: user.ID, Name: "Secret", Visibility: "private", ShareLink: fmt.Sprintf("private-%d", user.ID)}
+	require.NoError(t, db.Create(public).Error)
+	require.NoError(t, db.Create(private).Error)
+
+	created := time.Now().Add(-time.Hour)
+	for i := 0; i < publicCount; i++ {
+		bookmark := &database.Bookmark{UserID: user.ID, URL: fmt.Sprintf("https://example.com/%d", i), Title: fmt.Sprintf("Link %d", i),
+			Tags: `["go"]`, Notes: "private notes"}
+		bookmark.CreatedAt = created.Add(time.Duration(i) * time.Minute)
+		require.NoError(t, db.Create(bookmark).Error)
+		require.NoError(t, db.Exec("INSERT INTO bookmark_collections (bookmark_id, collection_id) VALUES (?, ?)", bookmark.ID, public.ID).Error)
+	}
+
+	hidden := &database.Bookmark{UserID: user.ID, URL: "https://example.com/hidden", Title: "Hidden"}
+	require.NoError(t, db.Create(hidden).Error)
+	require.NoError(t, db.Exec("INSERT INTO bookmark_collections (bookmark_id, collection_id) VALUES (?, ?)", hidden.ID, private.ID).Error)
+}
+
+func setProfileVisibility(t *testing.T, service *Service, userID uint, visibility string) {
+	_, err := service.UpdatePreferences(context.Background(), userID, &UpdatePreferencesRequest{ProfileVisibility: visibility})
+	require.NoError(t, err)
+}
+
+// TestGetPublicBookmarks tests the public bookmarks of a profile
+// TestGetPublicBookmarks 測試個人資料的公開書籤
+func TestGetPublicBookmarks(t *testing.T) {
+	service, db, _ := setupTestService(t)
+	service.EnablePublicBookmarks(config.PublicProfileConfig{MaxPageSize: 2}, nil)
+	ctx := context.Background()
+	user := createTestUser(t, db)
+	createPublicFixture(t, db, user, 3)
+
+	t.Run("Private profiles are not found", func(t *testing.T) {
+		_, err := service.GetPublicBookmarks(ctx, user.Username, 1, 0)
+		assert.ErrorIs(t, err, ErrPublicProfileNotFound)
+
+		_, err = service.GetPublicBookmarks(ctx, "nobody", 1, 0)
+		assert.ErrorIs(t, err, ErrPublicProfileNotFound)
+	})
+
+	t.Run("Public profiles list public collections newest first", func(t *testing.T) {
+		setProfileVisibility(t, service, user.ID, ProfileVisibilityPublic)
+
+		page, err := service.GetPublicBookmarks(ctx, user.Username, 1, 10)
+		require.NoError(t, err)
+		assert.Equal(t, 2, page.Limit) // clamped to the max page size
+		assert.True(t, page.HasMore)
+		require.Len(t, page.Bookmarks, 2)
+		assert.Equal(t, "https://example.com/2", page.Bookmarks[0].URL)
+		assert.Equal(t, []string{"go"}, page.Bookmarks[0].Tags)
+
+		page, err = service.GetPublicBookmarks(ctx, user.Username, 2, 10)
+		require.NoError(t, err)
+		assert.False(t, page.HasMore)
+		require.Len(t, page.Bookmarks, 1)
+		assert.Equal(t, "https://example.com/0", page.Bookmarks[0].URL)
+	})
+
+	t.Run("Turning the profile private hides it at once", func(t *testing.T) {
+		setProfileVisibility(t, service, user.ID, ProfileVisibilityPrivate)
+
+		_, err := service.GetPublicBookmarks(ctx, user.Username, 1, 10)
+		assert.ErrorIs(t, err, ErrPublicProfileNotFound)
+	})
+}
+
+// TestGetPublicBookmarksCache tests that pages are served from the cache
+// TestGetPublicBookmarksCache 測試頁面由快取提供
+func TestGetPublicBookmarksCache(t *testing.T) {
+	service, db, _ := setupTestService(t)
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+	client, err := redis.NewClient(config.RedisConfig{Host: mr.Host(), Port: mr.Port(), PoolSize: 1})
+	require.NoError(t, err)
+	service.EnablePublicBookmarks(config.PublicProfileConfig{CacheTTL: 60, MaxPageSize: 50}, client)
+
+	ctx := context.Background()
+	user := createTestUser(t, db)
+	createPublicFixture(t, db, user, 1)
+	setProfileVisibility(t, service, user.ID, ProfileVisibilityPublic)
+
+	// Warming caches the first page visitors get by default
+	require.NoError(t, service.WarmPublicBookmarks(ctx, user.ID))
+	assert.Equal(t, []string{fmt.Sprintf("%s:%d:1:20", config.PublicBookmarksPrefix, user.ID)}, mr.Keys())
+
+	page, err := service.GetPublicBookmarks(ctx, user.Username, 1, 20)
+	require.NoError(t, err)
+	require.Len(t, page.Bookmarks, 1)
+
+	// A change within the cache TTL shows up only once the page expires
+	require.NoError(t, db.Model(&database.Bookmark{}).Where("user_id = ?", user.ID).Update("title", "Renamed").Error)
+
+	page, err = service.GetPublicBookmarks(ctx, user.Username, 1, 20)
+	require.NoError(t, err)
+	assert.Equal(t, "Link 0", page.Bookmarks[0].Title)
+
+	mr.FastForward(time.Minute)
+	page, err = service.GetPublicBookmarks(ctx, user.Username, 1, 20)
+	require.NoError(t, err)
+	assert.Equal(t, "Renamed", page.Bookmarks[0].Title)
+}
+
+// TestGetPublicBookmarksHandler tests the public bookmarks endpoint
+// TestGetPublicBookmarksHandler 測試公開書籤端點
+func TestGetPublicBookmarksHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service, db, _ := setupTestService(t)
+	service.EnablePublicBookmarks(config.PublicProfileConfig{CacheTTL: 60, MaxPageSize: 50}, nil)
+	user := createTestUser(t, db)
+	createPublicFixture(t, db, user, 2)
+
+	router := gin.New()
+	NewPublicHandler(service).RegisterRoutes(router.Group("/api/v1"))
+	get := func(username, etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/public/users/"+username+"/bookmarks?limit=1", nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Private profile", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, get(user.Username, "").Code)
+	})
+
+	t.Run("Public profile", func(t *testing.T) {
+		setProfileVisibility(t, service, user.ID, ProfileVisibilityPublic)
+
+		w := get(user.Username, "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "public, max-age=60", w.Header().Get("Cache-Control"))
+		assert.NotContains(t, w.Body.String(), "private notes")
+
+		var response struct {
+			Data PublicBookmarkPage `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Len(t, response.Data.Bookmarks, 1)
+		assert.True(t, response.Data.HasMore)
+
+		etag := w.Header().Get("ETag")
+		require.NotEmpty(t, etag)
+		assert.Equal(t, http.StatusNotModified, get(user.Username, etag).Code)
+	})
+}
@@ -4,9 +4,16 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"time"
 
+	"bookmark-sync-service/backend/internal/config"
+	"bookmark-sync-service/backend/internal/metering"
+	"bookmark-sync-service/backend/internal/onboarding"
 	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/language"
+	"bookmark-sync-service/backend/pkg/locale"
+	redispkg "bookmark-sync-service/backend/pkg/redis"
 
 	"go.uber.org/zap"
 	"gorm.io/gorm"
@@ -23,6 +30,13 @@ type Service struct {
 	storageClient StorageClientInterface
 	logger        *zap.Logger
 	validator     *PreferenceValidator
+
+	// Public bookmarks API
+	publicCfg config.PublicProfileConfig
+	cache     redispkg.RedisInterface
+
+	onboarding OnboardingTracker
+	usage      UsageMeter
 }
 
 // NewService creates a new user service
@@ -42,6 +56,21 @@ type UserPreferences struct {
 	DefaultView string `json:"defaultView"` // grid, list
 	Language    string `json:"language"`    // en, zh-CN, zh-TW
 	Timezone    string `json:"timezone"`    // UTC offset or timezone name
+	DateFormat  string `json:"dateFormat"`  // YYYY-MM-DD, MM/DD/YYYY, DD/MM/YYYY
+	TimeFormat  string `json:"timeFormat"`  // 24h, 12h
+
+	// ContentLanguages are the languages whose bookmarks are boosted in recommendations
+	ContentLanguages []string `json:"contentLanguages"`
+
+	// ProfileVisibility publishes the bookmarks of public collections through
+	// the public bookmarks API when set to public
+	ProfileVisibility string `json:"profileVisibility"` // private, public
+
+	// Dashboard is the widget layout saved by the dashboard API
+	Dashboard json.RawMessage `json:"dashboard,omitempty"`
+
+	// URLRules are the URL cleanup rules saved by the URL rules API
+	URLRules json.RawMessage `json:"urlRules,omitempty"`
 }
 
 // UserQuotas represents user quotas and limits
@@ -72,6 +101,9 @@ type UserStats struct {
 	BookmarkCount   int `json:"bookmark_count"`
 	CollectionCount int `json:"collection_count"`
 	StorageUsed     int `json:"storage_used"` // in bytes
+	// BookmarksBySource counts bookmarks by the pathway that saved them,
+	// see the database.BookmarkSource values
+	BookmarksBySource map[string]int `json:"bookmarks_by_source,omitempty"`
 }
 
 // UpdateProfileRequest represents a profile update request
@@ -87,6 +119,13 @@ type UpdatePreferencesRequest struct {
 	DefaultView string `json:"defaultView,omitempty" binding:"omitempty,oneof=grid list"`
 	Language    string `json:"language,omitempty" binding:"omitempty,oneof=en zh-CN zh-TW"`
 	Timezone    string `json:"timezone,omitempty"`
+	DateFormat  string `json:"dateFormat,omitempty"`
+	TimeFormat  string `json:"timeFormat,omitempty" binding:"omitempty,oneof=24h 12h"`
+
+	// ContentLanguages replaces the preferred content languages when set; send [] to clear
+	ContentLanguages []string `json:"contentLanguages,omitempty"`
+
+	ProfileVisibility string `json:"profileVisibility,omitempty" binding:"omitempty,oneof=private public"`
 }
 
 // GetProfile retrieves a user's profile
@@ -105,7 +144,11 @@ func (s *Service) GetProfile(ctx context.Context, userID uint) (*UserProfile, er
 		GridSize:    "medium",
 		DefaultView: "grid",
 		Language:    "en",
-		Timezone:    "UTC",
+		Timezone:    locale.DefaultTimezone,
+		DateFormat:  locale.DateFormatISO,
+		TimeFormat:  locale.TimeFormat24h,
+
+		ProfileVisibility: ProfileVisibilityPrivate,
 	}
 	if user.Preferences != "" {
 		if err := json.Unmarshal([]byte(user.Preferences), &preferences); err != nil {
@@ -199,7 +242,11 @@ func (s *Service) UpdatePreferences(ctx context.Context, userID uint, req *Updat
 		GridSize:    "medium",
 		DefaultView: "grid",
 		Language:    "en",
-		Timezone:    "UTC",
+		Timezone:    locale.DefaultTimezone,
+		DateFormat:  locale.DateFormatISO,
+		TimeFormat:  locale.TimeFormat24h,
+
+		ProfileVisibility: ProfileVisibilityPrivate,
 	}
 	if user.Preferences != "" {
 		if err := json.Unmarshal([]byte(user.Preferences), &preferences); err != nil {
@@ -223,6 +270,18 @@ func (s *Service) UpdatePreferences(ctx context.Context, userID uint, req *Updat
 	if req.Timezone != "" {
 		preferences.Timezone = req.Timezone
 	}
+	if req.DateFormat != "" {
+		preferences.DateFormat = req.DateFormat
+	}
+	if req.TimeFormat != "" {
+		preferences.TimeFormat = req.TimeFormat
+	}
+	if req.ContentLanguages != nil {
+		preferences.ContentLanguages = language.NormalizeAll(req.ContentLanguages)
+	}
+	if req.ProfileVisibility != "" {
+		preferences.ProfileVisibility = req.ProfileVisibility
+	}
 
 	// Save preferences
 	preferencesJSON, err := json.Marshal(preferences)
@@ -236,6 +295,9 @@ func (s *Service) UpdatePreferences(ctx context.Context, userID uint, req *Updat
 	}
 
 	s.logger.Info("User preferences updated", zap.Uint("user_id", userID))
+	if req.Theme != "" && s.onboarding != nil {
+		s.onboarding.Track(ctx, userID, onboarding.EventThemeSet)
+	}
 
 	return s.GetProfile(ctx, userID)
 }
@@ -256,6 +318,9 @@ func (s *Service) UploadAvatar(ctx context.Context, userID uint, imageData []byt
 	if err != nil {
 		return nil, fmt.Errorf("failed to upload avatar: %w", err)
 	}
+	if s.usage != nil {
+		s.usage.Record(userID, metering.MetricStorageBytes, int64(len(imageData)))
+	}
 
 	// Update user avatar
 	user.Avatar = avatarURL
@@ -330,17 +395,54 @@ func (s *Service) DeleteUser(ctx context.Context, userID uint) error {
 		return fmt.Errorf("failed to delete user collections: %w", err)
 	}
 
-	// Delete user's comments
+	// Delete user's comments, keeping the comment counts of other users'
+	// bookmarks in step
+	var commentedIDs []uint
+	if err := tx.Model(&database.Comment{}).Where("user_id = ?", userID).
+		Distinct().Pluck("bookmark_id", &commentedIDs).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to load user comments: %w", err)
+	}
 	if err := tx.Where("user_id = ?", userID).Delete(&database.Comment{}).Error; err != nil {
 		tx.Rollback()
 		return fmt.Errorf("failed to delete user comments: %w", err)
 	}
+	if err := database.RecountBookmarkComments(tx, commentedIDs); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Where("user_id = ?", userID).Delete(&database.CollectionComment{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete user collection comments: %w", err)
+	}
 
 	// Delete user's sync events
 	if err := tx.Where("user_id = ?", userID).Delete(&database.SyncEvent{}).Error; err != nil {
 		tx.Rollback()
 		return fmt.Errorf("failed to delete user sync events: %w", err)
 	}
+	if err := tx.Unscoped().Where("user_id = ?", strconv.FormatUint(uint64(userID), 10)).Delete(&database.SyncScope{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete user sync scopes: %w", err)
+	}
+	if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&database.OnboardingProgress{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete user onboarding progress: %w", err)
+	}
+	if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&database.AnnouncementDismissal{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete user announcement dismissals: %w", err)
+	}
+	if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&database.UserIdentity{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete user identities: %w", err)
+	}
+	for _, model := range []interface{}{&database.SpeedDialOverride{}, &database.SpeedDialTile{}, &database.SpeedDialGroup{}} {
+		if err := tx.Where("user_id = ?", userID).Delete(model).Error; err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to delete user speed dial: %w", err)
+		}
+	}
 
 	// Delete user's follows
 	if err := tx.Where("follower_id = ? OR following_id = ?", userID, userID).Delete(&database.Follow{}).Error; err != nil {
@@ -364,6 +466,46 @@ func (s *Service) DeleteUser(ctx context.Context, userID uint) error {
 	return nil
 }
 
+// DeletionSummary counts what DeleteUser would remove, for a dry run
+type DeletionSummary struct {
+	Bookmarks   int64 `json:"bookmarks"`
+	Collections int64 `json:"collections"`
+	Comments    int64 `json:"comments"`
+	SyncEvents  int64 `json:"sync_events"`
+	Follows     int64 `json:"follows"`
+}
+
+// DeletionSummary returns what deleting a user would remove
+func (s *Service) DeletionSummary(ctx context.Context, userID uint) (*DeletionSummary, error) {
+	var user database.User
+	if err := s.db.WithContext(ctx).First(&user, userID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("user not found")
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	summary := &DeletionSummary{}
+	counts := []struct {
+		model interface{}
+		query string
+		args  []interface{}
+		count *int64
+	}{
+		{&database.Bookmark{}, "user_id = ?", []interface{}{userID}, &summary.Bookmarks},
+		{&database.Collection{}, "user_id = ?", []interface{}{userID}, &summary.Collections},
+		{&database.Comment{}, "user_id = ?", []interface{}{userID}, &summary.Comments},
+		{&database.SyncEvent{}, "user_id = ?", []interface{}{userID}, &summary.SyncEvents},
+		{&database.Follow{}, "follower_id = ? OR following_id = ?", []interface{}{userID, userID}, &summary.Follows},
+	}
+	for _, c := range counts {
+		if err := s.db.WithContext(ctx).Model(c.model).Where(c.query, c.args...).Count(c.count).Error; err != nil {
+			return nil, fmt.Errorf("failed to count user data: %w", err)
+		}
+	}
+	return summary, nil
+}
+
 // getUserStats calculates user statistics
 func (s *Service) getUserStats(ctx context.Context, userID uint) (*UserStats, error) {
 	var stats UserStats
@@ -375,6 +517,26 @@ func (s *Service) getUserStats(ctx context.Context, userID uint) (*UserStats, er
 	}
 	stats.BookmarkCount = int(bookmarkCount)
 
+	// Count bookmarks by source, older bookmarks have none
+	var bySource []struct {
+		Source string
+		Count  int
+	}
+	if err := s.db.Model(&database.Bookmark{}).Select("COALESCE(source, '') AS source, COUNT(*) AS count").
+		Where("user_id = ?", userID).Group("COALESCE(source, '')").Scan(&bySource).Error; err != nil {
+		return nil, fmt.Errorf("failed to count bookmarks by source: %w", err)
+	}
+	if len(bySource) > 0 {
+		stats.BookmarksBySource = make(map[string]int, len(bySource))
+		for _, row := range bySource {
+			source := row.Source
+			if source == "" {
+				source = database.BookmarkSourceUnknown
+			}
+			stats.BookmarksBySource[source] += row.Count
+		}
+	}
+
 	// Count collections
 	var collectionCount int64
 	if err := s.db.Model(&database.Collection{}).Where("user_id = ?", userID).Count(&collectionCount).Error; err != nil {
@@ -388,3 +550,23 @@ func (s *Service) getUserStats(ctx context.Context, userID uint) (*UserStats, er
 
 	return &stats, nil
 }
+
+// OnboardingTracker completes the onboarding steps waiting for an event
+type OnboardingTracker interface {
+	Track(ctx context.Context, userID uint, event string)
+}
+
+// SetOnboarding makes picking a theme count towards onboarding
+func (s *Service) SetOnboarding(tracker OnboardingTracker) {
+	s.onboarding = tracker
+}
+
+// UsageMeter counts a user's use of metered features
+type UsageMeter interface {
+	Record(userID uint, metric string, amount int64)
+}
+
+// SetUsageMeter makes uploaded avatars count towards the user's storage
+func (s *Service) SetUsageMeter(meter UsageMeter) {
+	s.usage = meter
+}
@@ -213,6 +213,12 @@ func TestDeleteUser(t *testing.T) {
 		err = db.Create(collection).Error
 		require.NoError(t, err)
 
+		// A dry run counts what would be removed
+		// 試運行統計將被刪除的數據
+		summary, err := service.DeletionSummary(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, &DeletionSummary{Bookmarks: 1, Collections: 1}, summary)
+
 		// Delete user
 		// 刪除用戶
 		err = service.DeleteUser(ctx, user.ID)
@@ -236,6 +242,9 @@ func TestDeleteUser(t *testing.T) {
 		err = db.Model(&database.Collection{}).Where("user_id = ?", user.ID).Count(&collectionCount).Error
 		require.NoError(t, err)
 		assert.Equal(t, int64(0), collectionCount)
+
+		_, err = service.DeletionSummary(ctx, user.ID)
+		assert.Error(t, err)
 	})
 }
 
@@ -259,6 +268,9 @@ func TestGetUserStats(t *testing.T) {
 				Title:  "Example",
 				Status: "active",
 			}
+			if i == 0 {
+				bookmark.Source = database.BookmarkSourceImport
+			}
 			err := db.Create(bookmark).Error
 			require.NoError(t, err)
 		}
@@ -282,6 +294,7 @@ func TestGetUserStats(t *testing.T) {
 		assert.Equal(t, 3, stats.BookmarkCount)
 		assert.Equal(t, 2, stats.CollectionCount)
 		assert.Equal(t, 0, stats.StorageUsed) // Default value
+		assert.Equal(t, map[string]int{"import": 1, "unknown": 2}, stats.BookmarksBySource)
 	})
 }
 
@@ -304,6 +317,7 @@ func TestUpdatePreferencesService(t *testing.T) {
 			DefaultView: "list",
 			Language:    "zh-CN",
 			Timezone:    "Asia/Shanghai",
+			DateFormat:  "DD/MM/YYYY",
 		}
 
 		profile, err := service.UpdatePreferences(ctx, user.ID, req)
@@ -317,6 +331,8 @@ func TestUpdatePreferencesService(t *testing.T) {
 		assert.Equal(t, "list", profile.Preferences.DefaultView)
 		assert.Equal(t, "zh-CN", profile.Preferences.Language)
 		assert.Equal(t, "Asia/Shanghai", profile.Preferences.Timezone)
+		assert.Equal(t, "DD/MM/YYYY", profile.Preferences.DateFormat)
+		assert.Equal(t, "24h", profile.Preferences.TimeFormat)
 	})
 
 	t.Run("Update Preferences with Invalid Theme", func(t *testing.T) {
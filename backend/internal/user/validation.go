@@ -3,9 +3,14 @@ package user
 import (
 	"fmt"
 	"strings"
-	"time"
+
+	"bookmark-sync-service/backend/pkg/language"
+	"bookmark-sync-service/backend/pkg/locale"
 )
 
+// maxContentLanguages caps the number of preferred content languages
+const maxContentLanguages = 10
+
 // PreferenceValidator handles validation of user preferences
 type PreferenceValidator struct{}
 
@@ -53,6 +58,32 @@ func (v *PreferenceValidator) ValidatePreferences(req *UpdatePreferencesRequest)
 		}
 	}
 
+	// Validate date and time formats
+	if req.DateFormat != "" {
+		if err := v.validateDateFormat(req.DateFormat); err != nil {
+			errors = append(errors, err.Error())
+		}
+	}
+	if req.TimeFormat != "" {
+		if err := v.validateTimeFormat(req.TimeFormat); err != nil {
+			errors = append(errors, err.Error())
+		}
+	}
+
+	// Validate content languages
+	if req.ContentLanguages != nil {
+		if err := v.validateContentLanguages(req.ContentLanguages); err != nil {
+			errors = append(errors, err.Error())
+		}
+	}
+
+	// Validate profile visibility
+	if req.ProfileVisibility != "" {
+		if err := v.validateProfileVisibility(req.ProfileVisibility); err != nil {
+			errors = append(errors, err.Error())
+		}
+	}
+
 	if len(errors) > 0 {
 		return fmt.Errorf("validation failed: %s", strings.Join(errors, "; "))
 	}
@@ -126,10 +157,49 @@ func (v *PreferenceValidator) validateTimezone(timezone string) error {
 		return nil // Empty strings are handled at request level
 	}
 
-	// Try to load the timezone to validate it
-	_, err := time.LoadLocation(timezone)
-	if err != nil {
-		return fmt.Errorf("invalid timezone '%s': %w", timezone, err)
+	// Accepts tz database names and UTC offsets such as "UTC+8"
+	if _, err := locale.LoadLocation(timezone); err != nil {
+		return fmt.Errorf("invalid timezone '%s', must be a timezone name such as 'Asia/Taipei' or a UTC offset such as 'UTC+8'", timezone)
+	}
+	return nil
+}
+
+// validateDateFormat validates the date format preference
+func (v *PreferenceValidator) validateDateFormat(dateFormat string) error {
+	if !locale.ValidDateFormat(dateFormat) {
+		return fmt.Errorf("invalid dateFormat '%s', must be one of: %s", dateFormat,
+			strings.Join([]string{locale.DateFormatISO, locale.DateFormatUS, locale.DateFormatEU}, ", "))
+	}
+	return nil
+}
+
+// validateTimeFormat validates the time format preference
+func (v *PreferenceValidator) validateTimeFormat(timeFormat string) error {
+	if !locale.ValidTimeFormat(timeFormat) {
+		return fmt.Errorf("invalid timeFormat '%s', must be one of: %s", timeFormat,
+			strings.Join([]string{locale.TimeFormat24h, locale.TimeFormat12h}, ", "))
+	}
+	return nil
+}
+
+// validateContentLanguages validates the preferred content language codes
+func (v *PreferenceValidator) validateContentLanguages(codes []string) error {
+	if len(codes) > maxContentLanguages {
+		return fmt.Errorf("too many contentLanguages, at most %d allowed", maxContentLanguages)
+	}
+
+	for _, code := range codes {
+		if language.Normalize(code) == "" {
+			return fmt.Errorf("invalid content language '%s', must be an ISO 639 code such as 'en'", code)
+		}
+	}
+	return nil
+}
+
+// validateProfileVisibility validates the profile visibility preference
+func (v *PreferenceValidator) validateProfileVisibility(visibility string) error {
+	if visibility != ProfileVisibilityPrivate && visibility != ProfileVisibilityPublic {
+		return fmt.Errorf("invalid profileVisibility '%s', must be one of: %s, %s", visibility, ProfileVisibilityPrivate, ProfileVisibilityPublic)
 	}
 	return nil
 }
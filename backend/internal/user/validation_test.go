@@ -256,6 +256,23 @@ func (suite *PreferenceValidatorTestSuite) TestValidateTimezone() {
 			expectError: true,
 			errorMsg:    "invalid timezone 'NotATimezone'",
 		},
+		{
+			name:        "Valid timezone - UTC offset",
+			timezone:    "UTC+8",
+			expectError: false,
+		},
+		{
+			name:        "Invalid timezone - server local time",
+			timezone:    "Local",
+			expectError: true,
+			errorMsg:    "invalid timezone 'Local'",
+		},
+		{
+			name:        "Invalid timezone - offset out of range",
+			timezone:    "UTC+15",
+			expectError: true,
+			errorMsg:    "invalid timezone 'UTC+15'",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -271,6 +288,73 @@ func (suite *PreferenceValidatorTestSuite) TestValidateTimezone() {
 	}
 }
 
+// TestValidateDateAndTimeFormat tests date and time format validation
+func (suite *PreferenceValidatorTestSuite) TestValidateDateAndTimeFormat() {
+	for _, format := range []string{"YYYY-MM-DD", "MM/DD/YYYY", "DD/MM/YYYY"} {
+		assert.NoError(suite.T(), suite.validator.validateDateFormat(format))
+	}
+	err := suite.validator.validateDateFormat("DD.MM.YYYY")
+	assert.ErrorContains(suite.T(), err, "invalid dateFormat 'DD.MM.YYYY'")
+
+	assert.NoError(suite.T(), suite.validator.validateTimeFormat("12h"))
+	err = suite.validator.ValidatePreferences(&UpdatePreferencesRequest{DateFormat: "MM/DD/YYYY", TimeFormat: "am/pm"})
+	assert.ErrorContains(suite.T(), err, "invalid timeFormat 'am/pm'")
+}
+
+// TestValidateContentLanguages tests preferred content language validation
+func (suite *PreferenceValidatorTestSuite) TestValidateContentLanguages() {
+	testCases := []struct {
+		name        string
+		codes       []string
+		expectError bool
+		errorMsg    string
+	}{
+		{
+			name:        "Valid content languages",
+			codes:       []string{"en", "zh-TW", "fr"},
+			expectError: false,
+		},
+		{
+			name:        "Empty list clears preference",
+			codes:       []string{},
+			expectError: false,
+		},
+		{
+			name:        "Invalid content language",
+			codes:       []string{"en", "english"},
+			expectError: true,
+			errorMsg:    "invalid content language 'english'",
+		},
+		{
+			name:        "Too many content languages",
+			codes:       []string{"en", "fr", "de", "es", "it", "pt", "nl", "ja", "ko", "zh", "ru"},
+			expectError: true,
+			errorMsg:    "too many contentLanguages",
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.name, func() {
+			err := suite.validator.validateContentLanguages(tc.codes)
+			if tc.expectError {
+				assert.Error(suite.T(), err)
+				assert.Contains(suite.T(), err.Error(), tc.errorMsg)
+			} else {
+				assert.NoError(suite.T(), err)
+			}
+		})
+	}
+}
+
+// TestValidateProfileVisibility tests profile visibility validation
+func (suite *PreferenceValidatorTestSuite) TestValidateProfileVisibility() {
+	assert.NoError(suite.T(), suite.validator.validateProfileVisibility("public"))
+	assert.NoError(suite.T(), suite.validator.validateProfileVisibility("private"))
+
+	err := suite.validator.ValidatePreferences(&UpdatePreferencesRequest{ProfileVisibility: "friends"})
+	assert.ErrorContains(suite.T(), err, "invalid profileVisibility 'friends'")
+}
+
 // TestValidatePreferences tests the complete preferences validation
 func (suite *PreferenceValidatorTestSuite) TestValidatePreferences() {
 	testCases := []struct {
@@ -0,0 +1,280 @@
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"bookmark-sync-service/backend/pkg/utils"
+)
+
+// Handler handles HTTP requests for the encrypted vault
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new vault handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes registers vault routes
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	vault := router.Group("/vault")
+	{
+		vault.GET("", h.GetStatus)
+		vault.PUT("/key", h.SetupKey)
+		vault.GET("/keys/:version", h.GetKey)
+		vault.DELETE("", h.DeleteVault)
+
+		// Blind sync of encrypted items
+		vault.GET("/items", h.PullItems)
+		vault.POST("/items", h.PushItems)
+
+		vault.GET("/export", h.ExportVault)
+	}
+}
+
+// GetStatus returns the vault status and wrapped key
+// @Summary Get vault status
+// @Description Get whether the encrypted vault is set up, its wrapped key and item count
+// @Tags vault
+// @Produce json
+// @Success 200 {object} Status
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/vault [get]
+func (h *Handler) GetStatus(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	status, err := h.service.GetStatus(userID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to get vault status", nil)
+		return
+	}
+
+	utils.SuccessResponse(c, status, "Vault status retrieved successfully")
+}
+
+// SetupKey stores or rotates the wrapped vault key
+// @Summary Set up or rotate the vault key
+// @Description Store the client-wrapped vault key. Rotations must use the next key version
+// @Tags vault
+// @Accept json
+// @Produce json
+// @Param request body SetupKeyRequest true "Wrapped key"
+// @Success 200 {object} database.VaultKey
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/vault/key [put]
+func (h *Handler) SetupKey(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	var req SetupKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid request data", nil)
+		return
+	}
+
+	key, err := h.service.SetupKey(userID, req)
+	if err != nil {
+		vaultErrorResponse(c, err, "Failed to set up vault key")
+		return
+	}
+
+	utils.SuccessResponse(c, key, "Vault key saved successfully")
+}
+
+// GetKey returns the wrapped key of a key version
+// @Summary Get a vault key version
+// @Description Get the wrapped key of the current or an earlier key version, to decrypt items not yet re-encrypted after a rotation
+// @Tags vault
+// @Produce json
+// @Param version path int true "Key version"
+// @Success 200 {object} database.VaultKeyVersion
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/vault/keys/{version} [get]
+func (h *Handler) GetKey(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil || version < 1 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid key version", nil)
+		return
+	}
+
+	key, err := h.service.GetKey(userID, version)
+	if err != nil {
+		vaultErrorResponse(c, err, "Failed to get vault key")
+		return
+	}
+
+	utils.SuccessResponse(c, key, "Vault key retrieved successfully")
+}
+
+// DeleteVault deletes the vault key and every encrypted item
+// @Summary Delete the vault
+// @Description Permanently delete the vault key and all encrypted items
+// @Tags vault
+// @Produce json
+// @Success 200 {object} utils.SuccessResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/vault [delete]
+func (h *Handler) DeleteVault(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	if err := h.service.Delete(userID); err != nil {
+		vaultErrorResponse(c, err, "Failed to delete vault")
+		return
+	}
+
+	utils.SuccessResponse(c, nil, "Vault deleted successfully")
+}
+
+// PullItems returns encrypted items changed after a revision
+// @Summary Pull vault items
+// @Description Get encrypted items and tombstones changed after the given revision
+// @Tags vault
+// @Produce json
+// @Param since query int false "Last revision seen by the client"
+// @Param limit query int false "Maximum items to return"
+// @Success 200 {object} PullResult
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/vault/items [get]
+func (h *Handler) PullItems(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	since, err := strconv.ParseInt(c.DefaultQuery("since", "0"), 10, 64)
+	if err != nil || since < 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid since revision", nil)
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "0"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid limit", nil)
+		return
+	}
+
+	result, err := h.service.Pull(userID, since, limit)
+	if err != nil {
+		vaultErrorResponse(c, err, "Failed to pull vault items")
+		return
+	}
+
+	utils.SuccessResponse(c, result, "Vault items retrieved successfully")
+}
+
+// PushItems uploads encrypted items
+// @Summary Push vault items
+// @Description Upload encrypted items. Items changed on the server since base_revision are returned as conflicts
+// @Tags vault
+// @Accept json
+// @Produce json
+// @Param request body PushRequest true "Encrypted items"
+// @Success 200 {object} PushResult
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/vault/items [post]
+func (h *Handler) PushItems(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	var req PushRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid request data", nil)
+		return
+	}
+
+	result, err := h.service.Push(userID, req)
+	if err != nil {
+		vaultErrorResponse(c, err, "Failed to push vault items")
+		return
+	}
+
+	utils.SuccessResponse(c, result, "Vault items pushed successfully")
+}
+
+// ExportVault downloads an encrypted backup of the vault
+// @Summary Export the vault
+// @Description Download the wrapped key and all encrypted items. The export stays encrypted
+// @Tags vault
+// @Produce json
+// @Success 200 {object} Export
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/v1/vault/export [get]
+func (h *Handler) ExportVault(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated", nil)
+		return
+	}
+
+	export, err := h.service.Export(userID)
+	if err != nil {
+		vaultErrorResponse(c, err, "Failed to export vault")
+		return
+	}
+
+	filename := fmt.Sprintf("vault-export-%s.json", export.ExportedAt.Format("20060102-150405"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.JSON(http.StatusOK, export)
+}
+
+// vaultErrorResponse maps vault errors to HTTP responses
+func vaultErrorResponse(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, ErrVaultNotSetUp):
+		utils.ErrorResponse(c, http.StatusNotFound, "VAULT_NOT_SET_UP", err.Error(), nil)
+	case errors.Is(err, ErrKeyVersionNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, "KEY_VERSION_NOT_FOUND", err.Error(), nil)
+	case errors.Is(err, ErrKeyVersionConflict), errors.Is(err, ErrStaleKeyVersion):
+		utils.ErrorResponse(c, http.StatusConflict, "KEY_VERSION_CONFLICT", err.Error(), nil)
+	case errors.Is(err, ErrItemTooLarge):
+		utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, "ITEM_TOO_LARGE", err.Error(), nil)
+	case errors.Is(err, ErrInvalidCiphertext), errors.Is(err, ErrTooManyItems):
+		utils.ErrorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error(), nil)
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", message, nil)
+	}
+}
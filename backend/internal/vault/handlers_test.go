@@ -0,0 +1,65 @@
+package vault
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupTestRouter serves the vault routes with the user ID set as a string,
+// the way the auth middleware sets it
+func setupTestRouter(service *Service, userID string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if userID != "" {
+			c.Set("user_id", userID)
+		}
+		c.Next()
+	})
+	NewHandler(service).RegisterRoutes(router.Group("/api/v1"))
+	return router
+}
+
+func TestHandlers_UserIDFromAuthMiddleware(t *testing.T) {
+	service := setupTestService(t)
+	router := setupTestRouter(service, "1")
+
+	body, err := json.Marshal(SetupKeyRequest{
+		Algorithm:  "aes-256-gcm",
+		KDF:        "argon2id",
+		KDFParams:  `{"memory":65536,"iterations":3}`,
+		Salt:       "c2FsdA==",
+		WrappedKey: "d3JhcHBlZA==",
+		KeyVersion: 1,
+	})
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/api/v1/vault/key", bytes.NewReader(body)))
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	for _, path := range []string{"/api/v1/vault", "/api/v1/vault/keys/1", "/api/v1/vault/items", "/api/v1/vault/export"} {
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusOK, w.Code, path)
+	}
+
+	status, err := service.GetStatus(testUserID)
+	require.NoError(t, err)
+	assert.True(t, status.Enabled)
+}
+
+func TestHandlers_Unauthenticated(t *testing.T) {
+	router := setupTestRouter(setupTestService(t), "")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/vault", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
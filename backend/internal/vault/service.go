@@ -0,0 +1,443 @@
+package vault
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+const (
+	// maxCiphertextSize caps a single encrypted item after base64 decoding
+	maxCiphertextSize = 64 * 1024
+	// maxPushItems caps the number of items accepted in one sync push
+	maxPushItems = 500
+	// defaultPullLimit and maxPullLimit bound a sync pull page
+	defaultPullLimit = 200
+	maxPullLimit     = 1000
+)
+
+// Vault errors
+var (
+	ErrVaultNotSetUp      = errors.New("vault is not set up")
+	ErrKeyVersionConflict = errors.New("key version must follow the current key version")
+	ErrInvalidCiphertext  = errors.New("ciphertext and nonce must be base64 encoded")
+	ErrItemTooLarge       = errors.New("encrypted item is too large")
+	ErrTooManyItems       = errors.New("too many items in one push")
+	ErrStaleKeyVersion    = errors.New("item is encrypted with an outdated key version")
+	ErrKeyVersionNotFound = errors.New("no wrapped key is kept for this key version")
+)
+
+// Service stores end-to-end encrypted bookmarks. It never sees plaintext:
+// clients encrypt titles, URLs and notes before upload and sync opaque blobs
+type Service struct {
+	db *gorm.DB
+}
+
+// NewService creates a new vault service
+func NewService(db *gorm.DB) *Service {
+	return &Service{db: db}
+}
+
+// SetupKeyRequest stores or rotates the wrapped vault key
+type SetupKeyRequest struct {
+	Algorithm  string `json:"algorithm" binding:"required,max=32"`
+	KDF        string `json:"kdf" binding:"required,max=32"`
+	KDFParams  string `json:"kdf_params" binding:"max=1024"`
+	Salt       string `json:"salt" binding:"required,max=128"`
+	WrappedKey string `json:"wrapped_key" binding:"required,max=4096"`
+	KeyVersion int    `json:"key_version" binding:"required,min=1"`
+}
+
+// PushItem is an encrypted item uploaded by a client
+type PushItem struct {
+	ClientID   string `json:"client_id" binding:"required,max=64"`
+	Ciphertext string `json:"ciphertext"`
+	Nonce      string `json:"nonce"`
+	KeyVersion int    `json:"key_version"`
+	Deleted    bool   `json:"deleted"`
+	// BaseRevision is the revision the client last saw, 0 for new items
+	BaseRevision int64 `json:"base_revision"`
+}
+
+// PushRequest uploads a batch of encrypted items
+type PushRequest struct {
+	Items []PushItem `json:"items" binding:"required,dive"`
+}
+
+// PushResult reports which items were stored and which conflicted with a
+// newer server copy. Clients resolve conflicts locally and push again
+type PushResult struct {
+	Applied   []database.VaultItem `json:"applied"`
+	Conflicts []database.VaultItem `json:"conflicts"`
+	Revision  int64                `json:"revision"`
+}
+
+// PullResult is a page of items changed after a revision
+type PullResult struct {
+	Items    []database.VaultItem `json:"items"`
+	Revision int64                `json:"revision"`
+	HasMore  bool                 `json:"has_more"`
+}
+
+// Status describes a user's vault. PreviousKeys are the wrapped keys of
+// earlier versions that items still use; StaleItemCount counts those items
+// left to re-encrypt with the current key
+type Status struct {
+	Enabled        bool                       `json:"enabled"`
+	Key            *database.VaultKey         `json:"key,omitempty"`
+	PreviousKeys   []database.VaultKeyVersion `json:"previous_keys,omitempty"`
+	ItemCount      int64                      `json:"item_count"`
+	StaleItemCount int64                      `json:"stale_item_count"`
+}
+
+// Export is the encrypted backup of a vault: the wrapped key and every live
+// item, still encrypted. It can only be read with the user's passphrase
+type Export struct {
+	Version      int                        `json:"version"`
+	ExportedAt   time.Time                  `json:"exported_at"`
+	Key          database.VaultKey          `json:"key"`
+	PreviousKeys []database.VaultKeyVersion `json:"previous_keys,omitempty"`
+	Items        []database.VaultItem       `json:"items"`
+}
+
+// GetStatus returns whether the vault is set up and how many items it holds
+func (s *Service) GetStatus(userID uint) (*Status, error) {
+	key, err := s.getKey(s.db, userID)
+	if errors.Is(err, ErrVaultNotSetUp) {
+		return &Status{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	status := &Status{Enabled: true, Key: key}
+	if err := s.db.Model(&database.VaultItem{}).
+		Where("user_id = ? AND deleted = ?", userID, false).
+		Count(&status.ItemCount).Error; err != nil {
+		return nil, fmt.Errorf("failed to count vault items: %w", err)
+	}
+	if err := s.db.Model(&database.VaultItem{}).
+		Where("user_id = ? AND deleted = ? AND key_version < ?", userID, false, key.KeyVersion).
+		Count(&status.StaleItemCount).Error; err != nil {
+		return nil, fmt.Errorf("failed to count vault items: %w", err)
+	}
+	if status.PreviousKeys, err = s.previousKeys(s.db, userID); err != nil {
+		return nil, err
+	}
+
+	return status, nil
+}
+
+// GetKey returns the wrapped key of a key version, so clients decrypt each
+// item with the key of its KeyVersion while re-encryption is under way
+func (s *Service) GetKey(userID uint, version int) (*database.VaultKeyVersion, error) {
+	key, err := s.getKey(s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	if version == key.KeyVersion {
+		return keyVersion(key), nil
+	}
+
+	var previous database.VaultKeyVersion
+	if err := s.db.Where("user_id = ? AND key_version = ?", userID, version).First(&previous).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrKeyVersionNotFound
+		}
+		return nil, fmt.Errorf("failed to get vault key version: %w", err)
+	}
+	return &previous, nil
+}
+
+// SetupKey stores the wrapped key for a new vault, or replaces it when the
+// client rotates to the next key version. The replaced key is kept until
+// every item encrypted with it has been pushed again with the new one
+func (s *Service) SetupKey(userID uint, req SetupKeyRequest) (*database.VaultKey, error) {
+	var key *database.VaultKey
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		current, err := s.lockKey(tx, userID)
+		if err != nil && !errors.Is(err, ErrVaultNotSetUp) {
+			return err
+		}
+
+		if current == nil {
+			if req.KeyVersion != 1 {
+				return ErrKeyVersionConflict
+			}
+			key = &database.VaultKey{UserID: userID}
+		} else if req.KeyVersion != current.KeyVersion+1 {
+			return ErrKeyVersionConflict
+		} else {
+			if err := tx.Create(keyVersion(current)).Error; err != nil {
+				return fmt.Errorf("failed to keep previous vault key: %w", err)
+			}
+			key = current
+		}
+
+		key.Algorithm = req.Algorithm
+		key.KDF = req.KDF
+		key.KDFParams = req.KDFParams
+		key.Salt = req.Salt
+		key.WrappedKey = req.WrappedKey
+		key.KeyVersion = req.KeyVersion
+		if current == nil {
+			err = tx.Create(key).Error
+		} else {
+			err = tx.Save(key).Error
+		}
+		if err != nil {
+			return fmt.Errorf("failed to save vault key: %w", err)
+		}
+
+		// A vault without items has nothing left to re-encrypt
+		return s.pruneKeyVersions(tx, userID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// Push stores encrypted items. An item is only overwritten when the client
+// pushed it on top of the server's latest revision; otherwise the server copy
+// is returned as a conflict
+func (s *Service) Push(userID uint, req PushRequest) (*PushResult, error) {
+	if len(req.Items) > maxPushItems {
+		return nil, ErrTooManyItems
+	}
+	for _, item := range req.Items {
+		if err := validateItem(item); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &PushResult{
+		Applied:   []database.VaultItem{},
+		Conflicts: []database.VaultItem{},
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		key, err := s.lockKey(tx, userID)
+		if err != nil {
+			return err
+		}
+
+		revision := key.Revision
+		for _, push := range req.Items {
+			if !push.Deleted && push.KeyVersion != key.KeyVersion {
+				return ErrStaleKeyVersion
+			}
+
+			var item database.VaultItem
+			err := tx.Where("user_id = ? AND client_id = ?", userID, push.ClientID).First(&item).Error
+			switch {
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				item = database.VaultItem{UserID: userID, ClientID: push.ClientID}
+			case err != nil:
+				return fmt.Errorf("failed to get vault item: %w", err)
+			case item.Revision != push.BaseRevision:
+				result.Conflicts = append(result.Conflicts, item)
+				continue
+			}
+
+			revision++
+			item.Revision = revision
+			item.Deleted = push.Deleted
+			item.KeyVersion = key.KeyVersion
+			if push.Deleted {
+				// Tombstones keep no ciphertext, only what sync needs
+				item.Ciphertext = ""
+				item.Nonce = ""
+			} else {
+				item.Ciphertext = push.Ciphertext
+				item.Nonce = push.Nonce
+			}
+			if err := tx.Save(&item).Error; err != nil {
+				return fmt.Errorf("failed to save vault item: %w", err)
+			}
+			result.Applied = append(result.Applied, item)
+		}
+
+		if revision != key.Revision {
+			if err := tx.Model(&database.VaultKey{}).Where("user_id = ?", userID).
+				Update("revision", revision).Error; err != nil {
+				return fmt.Errorf("failed to update vault revision: %w", err)
+			}
+			if err := s.pruneKeyVersions(tx, userID); err != nil {
+				return err
+			}
+		}
+		result.Revision = revision
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Pull returns the items, including tombstones, changed after a revision
+func (s *Service) Pull(userID uint, since int64, limit int) (*PullResult, error) {
+	key, err := s.getKey(s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 {
+		limit = defaultPullLimit
+	}
+	if limit > maxPullLimit {
+		limit = maxPullLimit
+	}
+
+	var items []database.VaultItem
+	if err := s.db.Where("user_id = ? AND revision > ?", userID, since).
+		Order("revision ASC").
+		Limit(limit + 1).
+		Find(&items).Error; err != nil {
+		return nil, fmt.Errorf("failed to get vault items: %w", err)
+	}
+
+	result := &PullResult{Items: items, Revision: key.Revision}
+	if len(items) > limit {
+		result.Items = items[:limit]
+		result.HasMore = true
+		// Resume the next page from the last returned item
+		result.Revision = result.Items[limit-1].Revision
+	}
+
+	return result, nil
+}
+
+// Export returns the wrapped key and every live item for an encrypted backup
+func (s *Service) Export(userID uint) (*Export, error) {
+	key, err := s.getKey(s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []database.VaultItem
+	if err := s.db.Where("user_id = ? AND deleted = ?", userID, false).
+		Order("revision ASC").
+		Find(&items).Error; err != nil {
+		return nil, fmt.Errorf("failed to get vault items: %w", err)
+	}
+
+	previous, err := s.previousKeys(s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Export{
+		Version:      1,
+		ExportedAt:   time.Now().UTC(),
+		Key:          *key,
+		PreviousKeys: previous,
+		Items:        items,
+	}, nil
+}
+
+// Delete removes the vault key and all items. Without the key the items
+// could never be decrypted again, so they are purged with it
+func (s *Service) Delete(userID uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("user_id = ?", userID).Delete(&database.VaultKey{})
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete vault key: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrVaultNotSetUp
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&database.VaultKeyVersion{}).Error; err != nil {
+			return fmt.Errorf("failed to delete previous vault keys: %w", err)
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&database.VaultItem{}).Error; err != nil {
+			return fmt.Errorf("failed to delete vault items: %w", err)
+		}
+		return nil
+	})
+}
+
+func (s *Service) getKey(db *gorm.DB, userID uint) (*database.VaultKey, error) {
+	var key database.VaultKey
+	if err := db.Where("user_id = ?", userID).First(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrVaultNotSetUp
+		}
+		return nil, fmt.Errorf("failed to get vault key: %w", err)
+	}
+	return &key, nil
+}
+
+// previousKeys returns the kept wrapped keys of earlier versions, oldest first
+func (s *Service) previousKeys(db *gorm.DB, userID uint) ([]database.VaultKeyVersion, error) {
+	var keys []database.VaultKeyVersion
+	if err := db.Where("user_id = ?", userID).Order("key_version ASC").Find(&keys).Error; err != nil {
+		return nil, fmt.Errorf("failed to get previous vault keys: %w", err)
+	}
+	return keys, nil
+}
+
+// pruneKeyVersions drops the kept keys of versions no live item uses any
+// more, once their items have been re-encrypted or deleted
+func (s *Service) pruneKeyVersions(tx *gorm.DB, userID uint) error {
+	inUse := tx.Model(&database.VaultItem{}).Select("key_version").
+		Where("user_id = ? AND deleted = ?", userID, false)
+	if err := tx.Where("user_id = ? AND key_version NOT IN (?)", userID, inUse).
+		Delete(&database.VaultKeyVersion{}).Error; err != nil {
+		return fmt.Errorf("failed to prune previous vault keys: %w", err)
+	}
+	return nil
+}
+
+// keyVersion copies the wrapped key of a vault key for its version
+func keyVersion(key *database.VaultKey) *database.VaultKeyVersion {
+	return &database.VaultKeyVersion{
+		UserID:     key.UserID,
+		KeyVersion: key.KeyVersion,
+		Algorithm:  key.Algorithm,
+		KDF:        key.KDF,
+		KDFParams:  key.KDFParams,
+		Salt:       key.Salt,
+		WrappedKey: key.WrappedKey,
+		CreatedAt:  key.UpdatedAt,
+	}
+}
+
+// lockKey loads the vault key inside a transaction after touching its row, so
+// concurrent pushes from several devices take revisions one after another
+func (s *Service) lockKey(tx *gorm.DB, userID uint) (*database.VaultKey, error) {
+	result := tx.Model(&database.VaultKey{}).Where("user_id = ?", userID).Update("updated_at", time.Now())
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to lock vault key: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrVaultNotSetUp
+	}
+	return s.getKey(tx, userID)
+}
+
+// validateItem checks the envelope of an item; its content is opaque
+func validateItem(item PushItem) error {
+	if item.Deleted {
+		return nil
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(item.Ciphertext)
+	if err != nil || len(ciphertext) == 0 {
+		return ErrInvalidCiphertext
+	}
+	if nonce, err := base64.StdEncoding.DecodeString(item.Nonce); err != nil || len(nonce) == 0 {
+		return ErrInvalidCiphertext
+	}
+	if len(ciphertext) > maxCiphertextSize {
+		return ErrItemTooLarge
+	}
+	return nil
+}
@@ -0,0 +1,246 @@
+package vault
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+const testUserID uint = 1
+
+func setupTestService(t *testing.T) *Service {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&database.VaultKey{}, &database.VaultKeyVersion{}, &database.VaultItem{}))
+	return NewService(db)
+}
+
+func setupKey(t *testing.T, service *Service, version int) {
+	_, err := service.SetupKey(testUserID, SetupKeyRequest{
+		Algorithm:  "aes-256-gcm",
+		KDF:        "argon2id",
+		KDFParams:  `{"memory":65536,"iterations":3}`,
+		Salt:       "c2FsdA==",
+		WrappedKey: "d3JhcHBlZA==",
+		KeyVersion: version,
+	})
+	require.NoError(t, err)
+}
+
+func encrypted(plaintext string) PushItem {
+	return PushItem{
+		Ciphertext: base64.StdEncoding.EncodeToString([]byte("enc:" + plaintext)),
+		Nonce:      base64.StdEncoding.EncodeToString([]byte("nonce")),
+		KeyVersion: 1,
+	}
+}
+
+func TestSetupKeyVersions(t *testing.T) {
+	service := setupTestService(t)
+
+	status, err := service.GetStatus(testUserID)
+	require.NoError(t, err)
+	assert.False(t, status.Enabled)
+
+	// A new vault starts at version 1
+	_, err = service.SetupKey(testUserID, SetupKeyRequest{Algorithm: "a", KDF: "k", Salt: "s", WrappedKey: "w", KeyVersion: 2})
+	assert.ErrorIs(t, err, ErrKeyVersionConflict)
+	setupKey(t, service, 1)
+
+	// Rotations must move to the next version
+	_, err = service.SetupKey(testUserID, SetupKeyRequest{Algorithm: "a", KDF: "k", Salt: "s", WrappedKey: "w", KeyVersion: 1})
+	assert.ErrorIs(t, err, ErrKeyVersionConflict)
+	setupKey(t, service, 2)
+
+	status, err = service.GetStatus(testUserID)
+	require.NoError(t, err)
+	assert.True(t, status.Enabled)
+	assert.Equal(t, 2, status.Key.KeyVersion)
+}
+
+func TestRotationKeepsPreviousKeys(t *testing.T) {
+	service := setupTestService(t)
+	setupKey(t, service, 1)
+
+	first := encrypted("first")
+	first.ClientID = "item-1"
+	second := encrypted("second")
+	second.ClientID = "item-2"
+	_, err := service.Push(testUserID, PushRequest{Items: []PushItem{first, second}})
+	require.NoError(t, err)
+
+	_, err = service.SetupKey(testUserID, SetupKeyRequest{Algorithm: "aes-256-gcm", KDF: "argon2id", Salt: "bmV3", WrappedKey: "bmV3IGtleQ==", KeyVersion: 2})
+	require.NoError(t, err)
+
+	// Items still on version 1 are decrypted with the kept key
+	status, err := service.GetStatus(testUserID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), status.StaleItemCount)
+	require.Len(t, status.PreviousKeys, 1)
+	assert.Equal(t, 1, status.PreviousKeys[0].KeyVersion)
+
+	key, err := service.GetKey(testUserID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "d3JhcHBlZA==", key.WrappedKey)
+	key, err = service.GetKey(testUserID, 2)
+	require.NoError(t, err)
+	assert.Equal(t, "bmV3IGtleQ==", key.WrappedKey)
+	_, err = service.GetKey(testUserID, 3)
+	assert.ErrorIs(t, err, ErrKeyVersionNotFound)
+
+	export, err := service.Export(testUserID)
+	require.NoError(t, err)
+	assert.Len(t, export.PreviousKeys, 1)
+
+	// Re-encrypting one item keeps the old key for the other
+	reencrypted := encrypted("first")
+	reencrypted.ClientID = "item-1"
+	reencrypted.KeyVersion = 2
+	reencrypted.BaseRevision = 1
+	_, err = service.Push(testUserID, PushRequest{Items: []PushItem{reencrypted}})
+	require.NoError(t, err)
+	_, err = service.GetKey(testUserID, 1)
+	require.NoError(t, err)
+
+	// Once no item uses version 1 its key is dropped
+	_, err = service.Push(testUserID, PushRequest{Items: []PushItem{{ClientID: "item-2", Deleted: true, BaseRevision: 2}}})
+	require.NoError(t, err)
+	_, err = service.GetKey(testUserID, 1)
+	assert.ErrorIs(t, err, ErrKeyVersionNotFound)
+
+	status, err = service.GetStatus(testUserID)
+	require.NoError(t, err)
+	assert.Zero(t, status.StaleItemCount)
+	assert.Empty(t, status.PreviousKeys)
+}
+
+func TestPushAndPull(t *testing.T) {
+	service := setupTestService(t)
+
+	_, err := service.Push(testUserID, PushRequest{Items: []PushItem{encrypted("a")}})
+	assert.ErrorIs(t, err, ErrVaultNotSetUp)
+
+	setupKey(t, service, 1)
+
+	first := encrypted("first")
+	first.ClientID = "item-1"
+	second := encrypted("second")
+	second.ClientID = "item-2"
+
+	pushed, err := service.Push(testUserID, PushRequest{Items: []PushItem{first, second}})
+	require.NoError(t, err)
+	assert.Len(t, pushed.Applied, 2)
+	assert.Equal(t, int64(2), pushed.Revision)
+
+	// A device that has seen nothing gets everything, paginated
+	page, err := service.Pull(testUserID, 0, 1)
+	require.NoError(t, err)
+	require.Len(t, page.Items, 1)
+	assert.True(t, page.HasMore)
+	assert.Equal(t, "item-1", page.Items[0].ClientID)
+
+	page, err = service.Pull(testUserID, page.Revision, 1)
+	require.NoError(t, err)
+	require.Len(t, page.Items, 1)
+	assert.False(t, page.HasMore)
+	assert.Equal(t, first.Nonce, page.Items[0].Nonce)
+	assert.Equal(t, second.Ciphertext, page.Items[0].Ciphertext)
+
+	// Deleting leaves a tombstone without ciphertext for other devices
+	deleted := PushItem{ClientID: "item-1", Deleted: true, BaseRevision: 1}
+	pushed, err = service.Push(testUserID, PushRequest{Items: []PushItem{deleted}})
+	require.NoError(t, err)
+	require.Len(t, pushed.Applied, 1)
+
+	page, err = service.Pull(testUserID, 2, 0)
+	require.NoError(t, err)
+	require.Len(t, page.Items, 1)
+	assert.True(t, page.Items[0].Deleted)
+	assert.Empty(t, page.Items[0].Ciphertext)
+
+	status, err := service.GetStatus(testUserID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), status.ItemCount)
+}
+
+func TestPushConflict(t *testing.T) {
+	service := setupTestService(t)
+	setupKey(t, service, 1)
+
+	item := encrypted("original")
+	item.ClientID = "item-1"
+	_, err := service.Push(testUserID, PushRequest{Items: []PushItem{item}})
+	require.NoError(t, err)
+
+	// Device A updates on top of revision 1
+	update := encrypted("from device A")
+	update.ClientID = "item-1"
+	update.BaseRevision = 1
+	result, err := service.Push(testUserID, PushRequest{Items: []PushItem{update}})
+	require.NoError(t, err)
+	require.Len(t, result.Applied, 1)
+
+	// Device B still thinks revision 1 is the latest
+	stale := encrypted("from device B")
+	stale.ClientID = "item-1"
+	stale.BaseRevision = 1
+	result, err = service.Push(testUserID, PushRequest{Items: []PushItem{stale}})
+	require.NoError(t, err)
+	assert.Empty(t, result.Applied)
+	require.Len(t, result.Conflicts, 1)
+	assert.Equal(t, update.Ciphertext, result.Conflicts[0].Ciphertext)
+	assert.Equal(t, int64(2), result.Revision)
+}
+
+func TestPushValidation(t *testing.T) {
+	service := setupTestService(t)
+	setupKey(t, service, 1)
+
+	invalid := PushItem{ClientID: "x", Ciphertext: "not base64!", Nonce: "bm9uY2U=", KeyVersion: 1}
+	_, err := service.Push(testUserID, PushRequest{Items: []PushItem{invalid}})
+	assert.ErrorIs(t, err, ErrInvalidCiphertext)
+
+	large := encrypted(strings.Repeat("x", maxCiphertextSize))
+	large.ClientID = "large"
+	_, err = service.Push(testUserID, PushRequest{Items: []PushItem{large}})
+	assert.ErrorIs(t, err, ErrItemTooLarge)
+
+	// After a rotation, items must be re-encrypted with the new key
+	setupKey(t, service, 2)
+	old := encrypted("old key")
+	old.ClientID = "old"
+	_, err = service.Push(testUserID, PushRequest{Items: []PushItem{old}})
+	assert.ErrorIs(t, err, ErrStaleKeyVersion)
+}
+
+func TestExportAndDelete(t *testing.T) {
+	service := setupTestService(t)
+	setupKey(t, service, 1)
+
+	live := encrypted("live")
+	live.ClientID = "live"
+	gone := encrypted("gone")
+	gone.ClientID = "gone"
+	_, err := service.Push(testUserID, PushRequest{Items: []PushItem{live, gone}})
+	require.NoError(t, err)
+	_, err = service.Push(testUserID, PushRequest{Items: []PushItem{{ClientID: "gone", Deleted: true, BaseRevision: 2}}})
+	require.NoError(t, err)
+
+	export, err := service.Export(testUserID)
+	require.NoError(t, err)
+	assert.Equal(t, "d3JhcHBlZA==", export.Key.WrappedKey)
+	require.Len(t, export.Items, 1)
+	assert.Equal(t, "live", export.Items[0].ClientID)
+
+	require.NoError(t, service.Delete(testUserID))
+	assert.ErrorIs(t, service.Delete(testUserID), ErrVaultNotSetUp)
+	_, err = service.Export(testUserID)
+	assert.ErrorIs(t, err, ErrVaultNotSetUp)
+}
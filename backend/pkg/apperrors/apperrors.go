@@ -0,0 +1,154 @@
+// Package apperrors is the registry of machine-readable API error codes.
+// Modules register their sentinel errors with a stable code and the HTTP
+// status it maps to, so handlers answer every error the same way instead
+// of keeping their own mapping or falling back to 500:
+//
+//	func init() {
+//		apperrors.Register(ErrShareNotFound, "share_not_found", http.StatusNotFound)
+//	}
+//
+// Codes are part of the API. Once published a code keeps its meaning and
+// status; modules keep the codes their clients already see
+package apperrors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// Code is a stable, machine-readable error code
+type Code string
+
+// Codes shared by all modules
+const (
+	CodeInternal       Code = "internal_error"
+	CodeNotFound       Code = "not_found"
+	CodeInvalidRequest Code = "invalid_request"
+)
+
+// Error is an error with the code and HTTP status it is answered with.
+// Err, when set, is the error it wraps
+type Error struct {
+	Code    Code
+	Status  int
+	Message string
+	Err     error
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	if e.Message == "" && e.Err != nil {
+		return e.Err.Error()
+	}
+	return e.Message
+}
+
+// Unwrap returns the wrapped error, so errors.Is sees through an Error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// New creates an error with a code and status
+func New(code Code, status int, message string) *Error {
+	return &Error{Code: code, Status: status, Message: message}
+}
+
+// Wrap gives err a code and status. The message defaults to err's own
+func Wrap(err error, code Code, status int, message string) *Error {
+	return &Error{Code: code, Status: status, Message: message, Err: err}
+}
+
+// Entry is a registered sentinel error
+type Entry struct {
+	Code    Code   `json:"code"`
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+	err     error
+}
+
+var (
+	mu      sync.RWMutex
+	entries []Entry
+	codes   = make(map[Code]int)
+)
+
+func init() {
+	Register(gorm.ErrRecordNotFound, CodeNotFound, http.StatusNotFound)
+}
+
+// Register maps a sentinel error, and every error wrapping it, to a code
+// and status. It panics if the error is already registered or the code is
+// registered with another status, as both are programming errors caught
+// at startup
+func Register(err error, code Code, status int) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if err == nil || code == "" || status < 400 || status > 599 {
+		panic(fmt.Sprintf("apperrors: Register %q needs an error, a code and an error status", code))
+	}
+	for _, entry := range entries {
+		if entry.err == err {
+			panic(fmt.Sprintf("apperrors: Register called twice for %q", err))
+		}
+	}
+	if registered, ok := codes[code]; ok && registered != status {
+		panic(fmt.Sprintf("apperrors: code %s registered with status %d and %d", code, registered, status))
+	}
+	codes[code] = status
+	entries = append(entries, Entry{Code: code, Status: status, Message: err.Error(), err: err})
+}
+
+// Lookup returns the code of an error: that of the outermost Error it
+// wraps, or else of the first registered sentinel it wraps. The message is
+// err's own, so details added by wrapping are kept
+func Lookup(err error) (*Error, bool) {
+	if err == nil {
+		return nil, false
+	}
+	var coded *Error
+	if errors.As(err, &coded) {
+		return &Error{Code: coded.Code, Status: coded.Status, Message: err.Error(), Err: err}, true
+	}
+
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, entry := range entries {
+		if errors.Is(err, entry.err) {
+			return &Error{Code: entry.Code, Status: entry.Status, Message: err.Error(), Err: err}, true
+		}
+	}
+	return nil, false
+}
+
+// Resolve is Lookup with unregistered errors answered as internal errors
+func Resolve(err error) *Error {
+	if coded, ok := Lookup(err); ok {
+		return coded
+	}
+	message := "internal error"
+	if err != nil {
+		message = err.Error()
+	}
+	return &Error{Code: CodeInternal, Status: http.StatusInternalServerError, Message: message, Err: err}
+}
+
+// Status returns the HTTP status an error is answered with
+func Status(err error) int {
+	return Resolve(err).Status
+}
+
+// Entries lists the registered errors by code, e.g. for API documentation
+func Entries() []Entry {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	list := append([]Entry(nil), entries...)
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Code < list[j].Code })
+	return list
+}
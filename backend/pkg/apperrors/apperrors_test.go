@@ -0,0 +1,69 @@
+package apperrors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+var errWidgetMissing = errors.New("widget missing")
+
+func init() {
+	Register(errWidgetMissing, "widget_not_found", http.StatusNotFound)
+}
+
+func TestLookupRegisteredSentinel(t *testing.T) {
+	coded, ok := Lookup(fmt.Errorf("loading widget 7: %w", errWidgetMissing))
+	require.True(t, ok)
+	assert.Equal(t, Code("widget_not_found"), coded.Code)
+	assert.Equal(t, http.StatusNotFound, coded.Status)
+	assert.Equal(t, "loading widget 7: widget missing", coded.Message)
+	assert.ErrorIs(t, coded, errWidgetMissing)
+}
+
+func TestLookupRecordNotFound(t *testing.T) {
+	coded, ok := Lookup(fmt.Errorf("failed to get widget: %w", gorm.ErrRecordNotFound))
+	require.True(t, ok)
+	assert.Equal(t, CodeNotFound, coded.Code)
+	assert.Equal(t, http.StatusNotFound, coded.Status)
+}
+
+func TestLookupPrefersWrappingError(t *testing.T) {
+	err := Wrap(errWidgetMissing, CodeInvalidRequest, http.StatusBadRequest, "widget ID is required")
+	coded, ok := Lookup(fmt.Errorf("create: %w", err))
+	require.True(t, ok)
+	assert.Equal(t, CodeInvalidRequest, coded.Code)
+	assert.Equal(t, http.StatusBadRequest, coded.Status)
+	assert.Equal(t, "create: widget ID is required", coded.Message)
+}
+
+func TestResolveUnregistered(t *testing.T) {
+	_, ok := Lookup(errors.New("disk on fire"))
+	assert.False(t, ok)
+
+	coded := Resolve(errors.New("disk on fire"))
+	assert.Equal(t, CodeInternal, coded.Code)
+	assert.Equal(t, http.StatusInternalServerError, coded.Status)
+	assert.Equal(t, "disk on fire", coded.Message)
+	assert.Equal(t, http.StatusNotFound, Status(errWidgetMissing))
+}
+
+func TestRegisterRejectsConflicts(t *testing.T) {
+	assert.Panics(t, func() { Register(errWidgetMissing, "widget_gone", http.StatusGone) })
+	assert.Panics(t, func() { Register(errors.New("other"), "widget_not_found", http.StatusGone) })
+	assert.Panics(t, func() { Register(errors.New("other"), "widget_ok", http.StatusOK) })
+	assert.Panics(t, func() { Register(nil, "widget_nil", http.StatusBadRequest) })
+}
+
+func TestEntriesSortedByCode(t *testing.T) {
+	entries := Entries()
+	require.NotEmpty(t, entries)
+	for i := 1; i < len(entries); i++ {
+		assert.LessOrEqual(t, entries[i-1].Code, entries[i].Code)
+	}
+}
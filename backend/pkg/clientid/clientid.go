@@ -0,0 +1,42 @@
+// Package clientid handles the UUIDs clients generate for bookmarks and
+// collections they create offline, so retries and other devices can refer
+// to a record before it has a server ID
+package clientid
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalid is returned for client IDs that are not UUIDs
+var ErrInvalid = errors.New("client_id must be a UUID")
+
+// Normalize returns a client ID in canonical lowercase form. An empty ID
+// stays empty
+func Normalize(id string) (string, error) {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return "", nil
+	}
+	parsed, err := uuid.Parse(id)
+	if err != nil || len(id) != 36 {
+		return "", ErrInvalid
+	}
+	return parsed.String(), nil
+}
+
+// Is reports whether a resource ID is a client ID rather than a server ID
+func Is(id string) bool {
+	normalized, err := Normalize(id)
+	return err == nil && normalized != ""
+}
+
+// Ptr returns a pointer to a client ID for nullable columns, nil when empty
+func Ptr(id string) *string {
+	if id == "" {
+		return nil
+	}
+	return &id
+}
@@ -0,0 +1,27 @@
+package clientid
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	id, err := Normalize(" 3F2504E0-4F89-41D3-9A0C-0305E82C3301 ")
+	assert.NoError(t, err)
+	assert.Equal(t, "3f2504e0-4f89-41d3-9a0c-0305e82c3301", id)
+
+	id, err = Normalize("")
+	assert.NoError(t, err)
+	assert.Empty(t, id)
+
+	for _, invalid := range []string{"42", "not-a-uuid", "{3f2504e0-4f89-41d3-9a0c-0305e82c3301}", "urn:uuid:3f2504e0-4f89-41d3-9a0c-0305e82c3301"} {
+		_, err = Normalize(invalid)
+		assert.ErrorIs(t, err, ErrInvalid, invalid)
+	}
+
+	assert.True(t, Is("3f2504e0-4f89-41d3-9a0c-0305e82c3301"))
+	assert.False(t, Is("42"))
+	assert.Nil(t, Ptr(""))
+	assert.Equal(t, "x", *Ptr("x"))
+}
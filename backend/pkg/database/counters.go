@@ -0,0 +1,58 @@
+package database
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// Bookmark social counter columns
+const (
+	CounterSaves    = "save_count"
+	CounterLikes    = "like_count"
+	CounterComments = "comment_count"
+)
+
+// CounterForAction returns the bookmark counter a tracked user action
+// increments, or "" for actions without one
+func CounterForAction(actionType string) string {
+	switch actionType {
+	case "save":
+		return CounterSaves
+	case "like":
+		return CounterLikes
+	default:
+		return ""
+	}
+}
+
+// AdjustBookmarkCounter adds delta to one of a bookmark's counters without
+// going below zero. Call it in the transaction that writes the source row
+// the counter is derived from, so the two cannot drift apart
+func AdjustBookmarkCounter(tx *gorm.DB, bookmarkID uint, column string, delta int) error {
+	switch column {
+	case CounterSaves, CounterLikes, CounterComments:
+	default:
+		return fmt.Errorf("unknown bookmark counter %q", column)
+	}
+
+	expr := gorm.Expr("CASE WHEN "+column+" + ? < 0 THEN 0 ELSE "+column+" + ? END", delta, delta)
+	if err := tx.Model(&Bookmark{}).Where("id = ?", bookmarkID).UpdateColumn(column, expr).Error; err != nil {
+		return fmt.Errorf("failed to update %s: %w", column, err)
+	}
+	return nil
+}
+
+// RecountBookmarkComments sets the comment counter of the bookmarks from
+// their comments, e.g. after comments were deleted in bulk
+func RecountBookmarkComments(tx *gorm.DB, bookmarkIDs []uint) error {
+	if len(bookmarkIDs) == 0 {
+		return nil
+	}
+	count := tx.Session(&gorm.Session{NewDB: true}).Model(&Comment{}).Select("COUNT(*)").Where("comments.bookmark_id = bookmarks.id")
+	if err := tx.Model(&Bookmark{}).Where("id IN ?", bookmarkIDs).
+		UpdateColumn(CounterComments, count).Error; err != nil {
+		return fmt.Errorf("failed to recount comments: %w", err)
+	}
+	return nil
+}
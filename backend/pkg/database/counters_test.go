@@ -0,0 +1,35 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBookmarkCounters tests counter adjustments and comment recounts
+// 測試書籤計數器的調整與重新計算
+func TestBookmarkCounters(t *testing.T) {
+	db := setupTestDB(t)
+	bookmark := &Bookmark{UserID: 1, URL: "https://example.com", Title: "Example"}
+	require.NoError(t, db.Create(bookmark).Error)
+
+	require.NoError(t, AdjustBookmarkCounter(db, bookmark.ID, CounterSaves, 2))
+	require.NoError(t, AdjustBookmarkCounter(db, bookmark.ID, CounterLikes, -1))
+	assert.Error(t, AdjustBookmarkCounter(db, bookmark.ID, "view_count", 1))
+
+	require.NoError(t, db.Create(&Comment{BookmarkID: bookmark.ID, UserID: 1, Content: "a"}).Error)
+	deleted := &Comment{BookmarkID: bookmark.ID, UserID: 2, Content: "b"}
+	require.NoError(t, db.Create(deleted).Error)
+	require.NoError(t, db.Delete(deleted).Error)
+	require.NoError(t, RecountBookmarkComments(db, []uint{bookmark.ID}))
+
+	var stored Bookmark
+	require.NoError(t, db.First(&stored, bookmark.ID).Error)
+	assert.Equal(t, 2, stored.SaveCount)
+	assert.Equal(t, 0, stored.LikeCount, "counters do not go below zero")
+	assert.Equal(t, 1, stored.CommentCount, "deleted comments are not counted")
+
+	assert.Equal(t, CounterLikes, CounterForAction("like"))
+	assert.Empty(t, CounterForAction("view"))
+}
@@ -2,6 +2,7 @@ package database
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"bookmark-sync-service/backend/internal/config"
@@ -56,3 +57,13 @@ func Close(db *gorm.DB) error {
 	}
 	return sqlDB.Close()
 }
+
+// likeEscaper escapes the wildcards and the escape character of LIKE
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// EscapeLike escapes value so it matches literally inside a LIKE pattern.
+// The condition must declare the escape character with ESCAPE '\', which
+// SQLite has no default for
+func EscapeLike(value string) string {
+	return likeEscaper.Replace(value)
+}
@@ -2,6 +2,7 @@ package database
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"gorm.io/driver/sqlite"
@@ -22,6 +23,52 @@ const (
 	LinkStatusUnknown  LinkStatus = "unknown"
 )
 
+// Search index statuses of a bookmark
+const (
+	IndexStatusPending = "pending" // queued, not searchable yet
+	IndexStatusIndexed = "indexed"
+	IndexStatusFailed  = "failed" // dropped by the indexer after its last attempt
+)
+
+// Bookmark sources: the pathway that created a bookmark
+const (
+	BookmarkSourceWeb         = "web"       // the web app
+	BookmarkSourceExtension   = "extension" // a browser extension
+	BookmarkSourceAPI         = "api"       // the REST or Delicious API, with a user or app token
+	BookmarkSourceImport      = "import"    // an import; the source ref is the batch
+	BookmarkSourceIntegration = "integration"
+	BookmarkSourceEmail       = "email" // email ingestion
+	// BookmarkSourceUnknown stands for bookmarks saved before sources
+	// were recorded, in filters and stats
+	BookmarkSourceUnknown = "unknown"
+)
+
+// bookmarkSources are the values ParseBookmarkSources accepts
+var bookmarkSources = map[string]bool{
+	BookmarkSourceWeb:         true,
+	BookmarkSourceExtension:   true,
+	BookmarkSourceAPI:         true,
+	BookmarkSourceImport:      true,
+	BookmarkSourceIntegration: true,
+	BookmarkSourceEmail:       true,
+	BookmarkSourceUnknown:     true,
+}
+
+// ParseBookmarkSources reads a comma separated list of bookmark sources,
+// dropping unknown values and repeats
+func ParseBookmarkSources(raw string) []string {
+	var sources []string
+	seen := make(map[string]bool)
+	for _, source := range strings.Split(raw, ",") {
+		source = strings.ToLower(strings.TrimSpace(source))
+		if bookmarkSources[source] && !seen[source] {
+			seen[source] = true
+			sources = append(sources, source)
+		}
+	}
+	return sources
+}
+
 // LinkCheck represents a link monitoring check result
 type LinkCheck struct {
 	ID           uint           `json:"id" gorm:"primaryKey"`
@@ -85,6 +132,45 @@ type LinkChangeNotification struct {
 	DeletedAt  gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
+// BookmarkSend is a bookmark sent from one of a user's devices to another,
+// to be opened or saved there. Sends to a device that is offline wait in the
+// notification center until the device picks them up
+type BookmarkSend struct {
+	ID             uint           `json:"id" gorm:"primaryKey"`
+	UserID         uint           `json:"user_id" gorm:"not null;index"`
+	BookmarkID     uint           `json:"bookmark_id" gorm:"not null;index"`
+	DeviceID       string         `json:"device_id" gorm:"not null;index"`
+	FromDevice     string         `json:"from_device"`
+	Action         string         `json:"action" gorm:"not null"`       // "open", "save"
+	Status         string         `json:"status" gorm:"not null;index"` // "delivered", "queued", "acknowledged"
+	NotificationID *uint          `json:"notification_id,omitempty"`
+	DeliveredAt    *time.Time     `json:"delivered_at,omitempty"`
+	AcknowledgedAt *time.Time     `json:"acknowledged_at,omitempty"`
+	Outcome        string         `json:"outcome,omitempty"` // the action the device took
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// ArchiveSnapshot is a captured copy of a bookmarked page's text, kept in
+// numbered versions so changes between captures can be compared
+type ArchiveSnapshot struct {
+	ID          uint           `json:"id" gorm:"primaryKey"`
+	UserID      uint           `json:"user_id" gorm:"not null;index"`
+	BookmarkID  uint           `json:"bookmark_id" gorm:"not null;index:idx_archive_bookmark_version"`
+	Version     int            `json:"version" gorm:"not null;index:idx_archive_bookmark_version"`
+	URL         string         `json:"url" gorm:"not null"`
+	Title       string         `json:"title"`
+	Content     string         `json:"content,omitempty" gorm:"type:text"` // extracted text, one block per line
+	ContentHash string         `json:"content_hash" gorm:"not null"`
+	StatusCode  int            `json:"status_code"`
+	CapturedAt  time.Time      `json:"captured_at" gorm:"not null"`
+	CheckedAt   time.Time      `json:"checked_at" gorm:"index"` // last re-capture, changed or not
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
 // BaseModel contains common fields for all models
 // 包含所有模型的通用字段，提供基本的 CRUD 時間戳和軟刪除功能
 type BaseModel struct {
@@ -111,6 +197,14 @@ type User struct {
 	// 用戶偏好設置（以 JSON 格式存儲）
 	Preferences string `gorm:"type:jsonb" json:"preferences,omitempty"` // 用戶偏好設置
 
+	// Plan picks the user's rate limits; empty means the default plan
+	// 方案決定用戶的速率限制；空值代表預設方案
+	Plan string `gorm:"size:50" json:"plan,omitempty"`
+
+	// DemoExpiresAt marks a guest sandbox of demo mode and when it is deleted
+	// DemoExpiresAt 標記示範模式的訪客沙盒及其刪除時間
+	DemoExpiresAt *time.Time `gorm:"index" json:"demo_expires_at,omitempty"`
+
 	// Relationships
 	// 關聯關係
 	Bookmarks   []Bookmark   `gorm:"foreignKey:UserID" json:"bookmarks,omitempty"`   // 用戶的書籤
@@ -124,12 +218,53 @@ type User struct {
 // Bookmark represents a bookmark in the system
 type Bookmark struct {
 	BaseModel
-	UserID      uint   `gorm:"not null;index" json:"user_id"`
-	URL         string `gorm:"not null" json:"url"`
-	Title       string `gorm:"not null" json:"title"`
-	Description string `json:"description,omitempty"`
-	Favicon     string `json:"favicon,omitempty"`
-	Screenshot  string `json:"screenshot,omitempty"`
+	UserID      uint    `gorm:"not null;index;uniqueIndex:idx_bookmarks_user_client;uniqueIndex:idx_bookmarks_user_url_key" json:"user_id"`
+	ClientID    *string `gorm:"size:36;uniqueIndex:idx_bookmarks_user_client" json:"client_id,omitempty"` // UUID generated by an offline client
+	URL         string  `gorm:"not null" json:"url"`
+	Title       string  `gorm:"not null" json:"title"`
+	Description string  `json:"description,omitempty"`
+	Favicon     string  `json:"favicon,omitempty"`
+	Screenshot  string  `json:"screenshot,omitempty"`
+
+	// URLKey is the hash of the canonical URL of a bookmark saved by URL, so
+	// concurrent saves of one page from several devices can't both create it
+	URLKey *string `gorm:"size:64;uniqueIndex:idx_bookmarks_user_url_key" json:"-"`
+
+	// FaviconCheckedAt is when the favicon was last re-validated, and
+	// FaviconError why that found no working favicon
+	FaviconCheckedAt *time.Time `gorm:"index" json:"favicon_checked_at,omitempty"`
+	FaviconError     string     `gorm:"type:text" json:"favicon_error,omitempty"`
+
+	// SafetyThreat flags a URL a safety provider or the instance blocklist
+	// reported, e.g. malware or social_engineering; empty when none did
+	SafetyThreat    string     `gorm:"size:50;index" json:"safety_threat,omitempty"`
+	SafetyCheckedAt *time.Time `json:"safety_checked_at,omitempty"`
+
+	// IndexStatus is whether the latest version of the bookmark has reached
+	// the search index. Empty for bookmarks written while search was off
+	IndexStatus string `gorm:"size:16;index" json:"index_status,omitempty"`
+
+	// Source is the pathway that created the bookmark, one of the
+	// BookmarkSource values, and SourceRef what within it: the import
+	// batch, the API client or the integration. Empty for older bookmarks
+	Source    string `gorm:"size:16;index" json:"source,omitempty"`
+	SourceRef string `gorm:"size:64" json:"source_ref,omitempty"`
+
+	// Language is the ISO 639-1 code of the page content, empty when unknown
+	Language string `gorm:"size:16;index" json:"language,omitempty"`
+
+	// ReadingTime is the estimated minutes to read the page, 0 when unknown
+	ReadingTime int `gorm:"default:0" json:"reading_time,omitempty"`
+
+	// Notes are the user's Markdown notes; NotesHTML is only filled in when a
+	// client asks for rendered notes
+	Notes     string `gorm:"type:text" json:"notes,omitempty"`
+	NotesHTML string `gorm:"-" json:"notes_html,omitempty"`
+	// NotesEncrypted marks Notes as base64 ciphertext encrypted by the
+	// client. The server cannot read it, so it is never indexed, searched,
+	// rendered or exported
+	NotesEncrypted bool   `gorm:"not null;default:false" json:"notes_encrypted"`
+	NotesKeyHint   string `gorm:"size:64" json:"notes_key_hint,omitempty"` // which key decrypts the notes
 
 	// Metadata stored as JSON
 	Metadata string `gorm:"type:jsonb" json:"metadata,omitempty"`
@@ -142,8 +277,9 @@ type Bookmark struct {
 	LikeCount    int `gorm:"default:0" json:"like_count"`
 	CommentCount int `gorm:"default:0" json:"comment_count"`
 
-	// Status
-	Status string `gorm:"default:'active'" json:"status"` // active, broken, redirected
+	// Status is the result of the latest link check: active, broken,
+	// redirect, timeout or unknown. Bookmarks never checked are active
+	Status string `gorm:"default:'active'" json:"status"`
 
 	// Relationships
 	User        User         `gorm:"foreignKey:UserID" json:"user,omitempty"`
@@ -152,20 +288,24 @@ type Bookmark struct {
 
 	// Timestamps
 	LastAccessedAt *time.Time `json:"last_accessed_at,omitempty"`
-	LastCheckedAt  *time.Time `json:"last_checked_at,omitempty"`
+	LastCheckedAt  *time.Time `json:"last_checked_at,omitempty"` // latest link check
 }
 
 // Collection represents a bookmark collection
 type Collection struct {
 	BaseModel
-	UserID      uint   `gorm:"not null;index" json:"user_id"`
-	Name        string `gorm:"not null" json:"name"`
-	Description string `json:"description,omitempty"`
-	Color       string `json:"color,omitempty"`
-	Icon        string `json:"icon,omitempty"`
+	UserID      uint    `gorm:"not null;index;uniqueIndex:idx_collections_user_client" json:"user_id"`
+	ClientID    *string `gorm:"size:36;uniqueIndex:idx_collections_user_client" json:"client_id,omitempty"` // UUID generated by an offline client
+	Name        string  `gorm:"not null" json:"name"`
+	Description string  `json:"description,omitempty"`
+	Color       string  `json:"color,omitempty"`
+	Icon        string  `json:"icon,omitempty"`
 
 	// Hierarchy support
 	ParentID *uint `gorm:"index" json:"parent_id,omitempty"`
+	// Path holds the names of the ancestors, root first. It is only filled
+	// in for search indexing
+	Path []string `gorm:"-" json:"path,omitempty"`
 
 	// Visibility and sharing
 	Visibility string `gorm:"default:'private'" json:"visibility"` // private, public, shared
@@ -174,6 +314,10 @@ type Collection struct {
 	// Metadata stored as JSON
 	Metadata string `gorm:"type:jsonb" json:"metadata,omitempty"`
 
+	// NoteTemplate is Markdown with placeholders such as {{url}}, rendered
+	// into the notes of bookmarks added to the collection without notes
+	NoteTemplate string `gorm:"type:text" json:"note_template,omitempty"`
+
 	// Relationships
 	User      User         `gorm:"foreignKey:UserID" json:"user,omitempty"`
 	Bookmarks []Bookmark   `gorm:"many2many:bookmark_collections;" json:"bookmarks,omitempty"`
@@ -207,6 +351,7 @@ type SyncEvent struct {
 	Type       string    `json:"type" gorm:"not null"`
 	UserID     string    `json:"user_id" gorm:"not null;index"`
 	ResourceID string    `json:"resource_id" gorm:"not null;index"`
+	ClientID   string    `json:"client_id,omitempty" gorm:"size:36"` // client UUID of the resource, if any
 	Action     string    `json:"action" gorm:"not null"`
 	Data       string    `json:"data" gorm:"type:jsonb"`
 	DeviceID   string    `json:"device_id" gorm:"not null;index"`
@@ -267,20 +412,83 @@ func AutoMigrate(db *gorm.DB) error {
 		&Follow{},
 		&CollectionShare{},
 		&CollectionCollaborator{},
+		&CollectionTransfer{},
 		&CollectionFork{},
 		&ShareActivity{},
+		&ShareActivityDaily{},
+		&AbuseReport{},
+		&DirectShare{},
+		&EmailSubscriber{},
+		&CollectionComment{},
+		&CollectionCluster{},
+		&CollectionClusterMember{},
+		&BookmarkQuality{},
+		&QualityThresholds{},
+		&CollectionSuggestionFeedback{},
+		&CollectionRevision{},
+		&CollectionTemplate{},
+		&CollectionTemplateRating{},
+		&BlockedDomain{},
+		&RetentionRun{},
+		&StorageOrphan{},
+		&StorageGCRun{},
+		&ComplianceReport{},
+		&ComplianceRun{},
+		&DailyStat{},
+		&DailyStatCursor{},
+		&BookmarkEdge{},
+		&BookmarkGraphCursor{},
+		&BookmarkGraphChange{},
+		&FederationKey{},
+		&FederationFollower{},
+		&FederationActivity{},
+		&SyncScope{},
+		&SyncConflict{},
+		&OnboardingStep{},
+		&OnboardingProgress{},
+		&Announcement{},
+		&AnnouncementDismissal{},
+		&UserIdentity{},
+		&ScreenshotJob{},
+		&Tag{},
+		&BookmarkTag{},
+		&VaultKey{},
+		&VaultKeyVersion{},
+		&VaultItem{},
+		&OAuthApp{},
+		&OAuthGrant{},
+		&OAuthCode{},
+		&OAuthToken{},
 		&LinkCheck{},
 		&LinkMonitoringJob{},
 		&LinkMaintenanceReport{},
 		&LinkChangeNotification{},
+		&BookmarkSend{},
+		&ArchiveSnapshot{},
+		&URLRulePack{},
+		&AccountMerge{},
+		&MediaBackfill{},
+		&SpeedDialGroup{},
+		&SpeedDialTile{},
+		&SpeedDialOverride{},
+		&ImportFolderMapping{},
+		&CleanupReminder{},
 		// Automation models
 		&WebhookEndpoint{},
 		&WebhookDelivery{},
 		&RSSFeed{},
 		&BulkOperation{},
 		&BackupJob{},
+		&BackupDestination{},
 		&APIIntegration{},
+		&SyncRun{},
+		&ImportUpload{},
 		&AutomationRule{},
+		&RuleRun{},
+		&TeamActivity{},
+		&SandboxCapture{},
+		&ThresholdFiring{},
+		&WebhookDeliveryStat{},
 	); err != nil {
 		return fmt.Errorf("failed to run auto migrations: %w", err)
 	}
@@ -320,13 +528,24 @@ type CollectionShare struct {
 	ExpiresAt    *time.Time `json:"expires_at"`
 	ViewCount    int64      `gorm:"default:0" json:"view_count"`
 	IsActive     bool       `gorm:"default:true" json:"is_active"`
+	// Embed settings
+	EmbedEnabled        bool   `gorm:"default:false" json:"embed_enabled"`
+	EmbedAllowedOrigins string `gorm:"type:text" json:"embed_allowed_origins"`
+	EmbedFrameAncestors string `gorm:"type:text" json:"embed_frame_ancestors"`
+	// Email subscriptions of visitors, public shares only
+	SubscriptionsEnabled bool `gorm:"default:false" json:"subscriptions_enabled"`
+	// Login-less JSON data API, limited to APIRateLimit requests an hour
+	APIEnabled   bool `gorm:"default:false" json:"api_enabled"`
+	APIRateLimit int  `gorm:"default:0" json:"api_rate_limit"`
+	// Screenshot shown in link previews: full, blur, crop or none
+	PreviewMode string `gorm:"size:10" json:"preview_mode"`
 }
 
 // CollectionCollaborator represents a collaborator on a shared collection
 type CollectionCollaborator struct {
 	BaseModel
-	CollectionID uint       `gorm:"not null;index" json:"collection_id"`
-	UserID       uint       `gorm:"not null;index" json:"user_id"`
+	CollectionID uint       `gorm:"not null;index;uniqueIndex:idx_collection_collaborator" json:"collection_id"`
+	UserID       uint       `gorm:"not null;index;uniqueIndex:idx_collection_collaborator" json:"user_id"`
 	InviterID    uint       `gorm:"not null" json:"inviter_id"`
 	Permission   string     `gorm:"not null;default:'view'" json:"permission"`
 	Status       string     `gorm:"not null;default:'pending'" json:"status"`
@@ -334,6 +553,183 @@ type CollectionCollaborator struct {
 	AcceptedAt   *time.Time `json:"accepted_at"`
 }
 
+// Collection transfer statuses
+const (
+	TransferStatusPending   = "pending"
+	TransferStatusAccepted  = "accepted"
+	TransferStatusDeclined  = "declined"
+	TransferStatusCancelled = "cancelled"
+)
+
+// CollectionTransfer hands a collection subtree to another user. Transfers to
+// a user wait until the recipient accepts; transfers into a team collection
+// the sender may edit are accepted at once
+type CollectionTransfer struct {
+	ID               uint       `gorm:"primaryKey" json:"id"`
+	CollectionID     uint       `gorm:"not null;index" json:"collection_id"`
+	CollectionName   string     `gorm:"size:255" json:"collection_name"`
+	FromUserID       uint       `gorm:"not null;index" json:"from_user_id"`
+	ToUserID         uint       `gorm:"not null;index" json:"to_user_id"`
+	TeamCollectionID *uint      `json:"team_collection_id,omitempty"` // the team collection the subtree moves into
+	Status           string     `gorm:"size:16;not null;default:'pending';index" json:"status"`
+	OperationID      *uint      `json:"operation_id,omitempty"` // the bulk operation that moved it, undoable by the sender
+	RespondedAt      *time.Time `json:"responded_at,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+// DirectShare grants a specific user access to a bookmark or collection
+type DirectShare struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	OwnerID      uint      `gorm:"not null;index" json:"owner_id"`
+	RecipientID  uint      `gorm:"not null;index;uniqueIndex:idx_direct_share_recipient" json:"recipient_id"`
+	ResourceType string    `gorm:"not null;size:20;uniqueIndex:idx_direct_share_recipient" json:"resource_type"`
+	ResourceID   uint      `gorm:"not null;uniqueIndex:idx_direct_share_recipient" json:"resource_id"`
+	Permission   string    `gorm:"not null;default:'view'" json:"permission"`
+	Message      string    `gorm:"size:500" json:"message"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// CollectionComment is a post in the discussion of a shared collection
+type CollectionComment struct {
+	ID           uint           `gorm:"primaryKey" json:"id"`
+	CollectionID uint           `gorm:"not null;index" json:"collection_id"`
+	UserID       uint           `gorm:"not null;index" json:"user_id"`
+	ThreadID     *uint          `gorm:"index" json:"thread_id,omitempty"`
+	Content      string         `gorm:"type:text;not null" json:"content"`
+	Hidden       bool           `gorm:"default:false" json:"hidden"`
+	HiddenBy     *uint          `json:"hidden_by,omitempty"`
+	EditedAt     *time.Time     `json:"edited_at,omitempty"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// EmailSubscriber is a visitor subscribed by email to a public share
+type EmailSubscriber struct {
+	ID                 uint       `gorm:"primaryKey" json:"id"`
+	ShareID            uint       `gorm:"not null;uniqueIndex:idx_email_subscriber" json:"share_id"`
+	Email              string     `gorm:"size:255;not null;uniqueIndex:idx_email_subscriber" json:"email"`
+	Status             string     `gorm:"size:20;not null;default:'pending';index" json:"status"` // pending, confirmed, unsubscribed
+	Token              string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	ConfirmationSentAt time.Time  `json:"-"`
+	ConfirmedAt        *time.Time `json:"confirmed_at"`
+	LastNotifiedAt     *time.Time `json:"last_notified_at"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+}
+
+// VaultKey holds a user's end-to-end encryption key, wrapped by a key only the
+// user's clients can derive. The server never sees the unwrapped key
+type VaultKey struct {
+	UserID     uint      `gorm:"primaryKey" json:"-"`
+	Algorithm  string    `gorm:"not null;size:32" json:"algorithm"` // cipher used for items, e.g. aes-256-gcm
+	KDF        string    `gorm:"not null;size:32" json:"kdf"`       // key derivation used to wrap the key, e.g. argon2id
+	KDFParams  string    `gorm:"type:text" json:"kdf_params"`       // opaque JSON for the client
+	Salt       string    `gorm:"not null;size:128" json:"salt"`
+	WrappedKey string    `gorm:"type:text;not null" json:"wrapped_key"`
+	KeyVersion int       `gorm:"not null;default:1" json:"key_version"`
+	Revision   int64     `gorm:"not null;default:0" json:"revision"` // last revision assigned to an item
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// VaultKeyVersion keeps the wrapped key of an earlier key version after a
+// rotation, while items encrypted with it wait to be re-encrypted, so clients
+// can still decrypt them. It is removed once no live item uses the version
+type VaultKeyVersion struct {
+	UserID     uint      `gorm:"primaryKey" json:"-"`
+	KeyVersion int       `gorm:"primaryKey" json:"key_version"`
+	Algorithm  string    `gorm:"not null;size:32" json:"algorithm"`
+	KDF        string    `gorm:"not null;size:32" json:"kdf"`
+	KDFParams  string    `gorm:"type:text" json:"kdf_params"`
+	Salt       string    `gorm:"not null;size:128" json:"salt"`
+	WrappedKey string    `gorm:"type:text;not null" json:"wrapped_key"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// VaultItem is an end-to-end encrypted bookmark stored as an opaque blob.
+// Vault items live outside the bookmarks table so they never reach search
+// indexing, social features or metadata extraction
+type VaultItem struct {
+	ID         uint      `gorm:"primaryKey" json:"-"`
+	UserID     uint      `gorm:"not null;uniqueIndex:idx_vault_item_client;index:idx_vault_item_revision" json:"-"`
+	ClientID   string    `gorm:"not null;size:64;uniqueIndex:idx_vault_item_client" json:"client_id"`
+	Ciphertext string    `gorm:"type:text" json:"ciphertext,omitempty"` // base64, empty once deleted
+	Nonce      string    `gorm:"size:64" json:"nonce,omitempty"`
+	KeyVersion int       `gorm:"not null;default:1" json:"key_version"`
+	Revision   int64     `gorm:"not null;index:idx_vault_item_revision" json:"revision"`
+	Deleted    bool      `gorm:"not null;default:false" json:"deleted"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// OAuthApp is a third-party application registered by a developer
+type OAuthApp struct {
+	ID               uint           `gorm:"primaryKey" json:"id"`
+	OwnerID          uint           `gorm:"not null;index" json:"owner_id"`
+	Name             string         `gorm:"not null;size:100" json:"name"`
+	Description      string         `gorm:"type:text" json:"description"`
+	HomepageURL      string         `gorm:"size:500" json:"homepage_url"`
+	RedirectURIs     StringSlice    `gorm:"type:text" json:"redirect_uris"`
+	Scopes           StringSlice    `gorm:"type:text" json:"scopes"` // the most an app may request
+	ClientID         string         `gorm:"not null;size:64;uniqueIndex" json:"client_id"`
+	ClientSecretHash string         `gorm:"not null;size:64" json:"-"`
+	WebhookSecret    string         `gorm:"not null;size:64" json:"-"`               // signs deliveries to the app's subscriptions
+	RateLimit        int            `gorm:"not null;default:1000" json:"rate_limit"` // requests per hour across all users
+	Active           bool           `gorm:"default:true" json:"active"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// OAuthGrant records the scopes a user consented to for an app
+type OAuthGrant struct {
+	ID        uint        `gorm:"primaryKey" json:"id"`
+	UserID    uint        `gorm:"not null;uniqueIndex:idx_oauth_grant_user_app" json:"user_id"`
+	AppID     uint        `gorm:"not null;uniqueIndex:idx_oauth_grant_user_app" json:"app_id"`
+	Scopes    StringSlice `gorm:"type:text" json:"scopes"`
+	RevokedAt *time.Time  `json:"revoked_at,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+
+	App OAuthApp `gorm:"foreignKey:AppID" json:"app"`
+}
+
+// OAuthCode is a short-lived authorization code issued after consent
+type OAuthCode struct {
+	ID                  uint        `gorm:"primaryKey"`
+	CodeHash            string      `gorm:"not null;size:64;uniqueIndex"`
+	AppID               uint        `gorm:"not null;index"`
+	UserID              uint        `gorm:"not null"`
+	GrantID             uint        `gorm:"not null"`
+	RedirectURI         string      `gorm:"not null;size:500"`
+	Scopes              StringSlice `gorm:"type:text"`
+	CodeChallenge       string      `gorm:"size:128"`
+	CodeChallengeMethod string      `gorm:"size:10"`
+	ExpiresAt           time.Time   `gorm:"not null"`
+	UsedAt              *time.Time
+	CreatedAt           time.Time
+}
+
+// OAuthToken is an access and refresh token pair held by an app. Only token
+// hashes are stored
+type OAuthToken struct {
+	ID               uint        `gorm:"primaryKey"`
+	AppID            uint        `gorm:"not null;index"`
+	UserID           uint        `gorm:"not null;index"`
+	GrantID          uint        `gorm:"not null;index"`
+	AccessTokenHash  string      `gorm:"not null;size:64;uniqueIndex"`
+	RefreshTokenHash string      `gorm:"not null;size:64;uniqueIndex"`
+	Scopes           StringSlice `gorm:"type:text"`
+	ExpiresAt        time.Time   `gorm:"not null"`
+	RefreshExpiresAt time.Time   `gorm:"not null"`
+	RevokedAt        *time.Time
+	LastUsedAt       *time.Time
+	CreatedAt        time.Time
+}
+
 // CollectionFork represents a forked collection
 type CollectionFork struct {
 	BaseModel
@@ -356,6 +752,541 @@ type ShareActivity struct {
 	Metadata     string `gorm:"type:json" json:"metadata"`
 }
 
+// ShareActivityDaily holds the daily share activity counts kept after raw
+// activity is purged
+type ShareActivityDaily struct {
+	ID           uint      `gorm:"primaryKey" json:"-"`
+	ShareID      uint      `gorm:"not null;uniqueIndex:idx_share_activity_daily" json:"share_id"`
+	Day          time.Time `gorm:"not null;uniqueIndex:idx_share_activity_daily" json:"day"`
+	ActivityType string    `gorm:"not null;uniqueIndex:idx_share_activity_daily" json:"activity_type"`
+	Client       string    `gorm:"size:20;not null;uniqueIndex:idx_share_activity_daily" json:"client"`
+	Count        int64     `gorm:"not null;default:0" json:"count"`
+}
+
+// AbuseReport records a client IP blocked from the public share endpoints.
+// Reports about a specific share are shown to its owner
+type AbuseReport struct {
+	BaseModel
+	IPAddress    string     `gorm:"size:45;not null;index" json:"ip_address"`
+	Reason       string     `gorm:"size:30;not null" json:"reason"` // rate_limit, token_enumeration
+	Endpoint     string     `gorm:"size:20" json:"endpoint"`        // feed, embed, qrcode, subscribe
+	ShareID      *uint      `gorm:"index" json:"share_id,omitempty"`
+	OwnerID      *uint      `gorm:"index" json:"owner_id,omitempty"`
+	RequestCount int64      `json:"request_count"` // requests or unknown tokens counted in the window
+	BlockedUntil time.Time  `gorm:"index" json:"blocked_until"`
+	ResolvedAt   *time.Time `json:"resolved_at,omitempty"` // set when an admin lifts the block
+}
+
+// CollectionRevision is a snapshot of a collection's metadata and bookmarks
+// taken after each change, used to restore it to an earlier point in time
+type CollectionRevision struct {
+	BaseModel
+	CollectionID uint   `gorm:"not null;index:idx_collection_revision" json:"collection_id"`
+	UserID       uint   `gorm:"not null;index" json:"user_id"`
+	Action       string `gorm:"size:30;not null" json:"action"` // create, update, add_bookmark, remove_bookmark, merge, split, transfer, undo, restore
+	Snapshot     string `gorm:"type:text" json:"-"`
+}
+
+// CollectionTemplate is a predefined collection structure users can start
+// from. Built-in templates have a BuiltinKey and no creator; users can
+// contribute their own and publish them to the gallery
+type CollectionTemplate struct {
+	BaseModel
+	BuiltinKey  *string `gorm:"size:50;uniqueIndex" json:"builtin_key,omitempty"`
+	CreatorID   *uint   `gorm:"index" json:"creator_id,omitempty"`
+	Name        string  `gorm:"size:100;not null" json:"name"`
+	Description string  `gorm:"type:text" json:"description,omitempty"`
+	Category    string  `gorm:"size:50;index" json:"category,omitempty"`
+	IsPublic    bool    `gorm:"default:false;index" json:"is_public"`
+	Structure   string  `gorm:"type:text;not null" json:"-"` // JSON tree of sub-collections and suggested tags
+	UseCount    int     `gorm:"default:0" json:"use_count"`
+	Rating      float64 `gorm:"default:0" json:"rating"`
+	RatingCount int     `gorm:"default:0" json:"rating_count"`
+}
+
+// CollectionTemplateRating is a user's 1 to 5 star rating of a template
+type CollectionTemplateRating struct {
+	BaseModel
+	TemplateID uint   `gorm:"not null;uniqueIndex:idx_template_rating_user" json:"template_id"`
+	UserID     uint   `gorm:"not null;uniqueIndex:idx_template_rating_user" json:"user_id"`
+	Rating     int    `gorm:"not null" json:"rating"`
+	Comment    string `gorm:"size:500" json:"comment,omitempty"`
+}
+
+// BlockedDomain is a domain admins consider unsafe on this instance.
+// Bookmarks of the domain and its subdomains are flagged
+type BlockedDomain struct {
+	BaseModel
+	Domain  string `gorm:"size:255;not null;uniqueIndex" json:"domain"`
+	Threat  string `gorm:"size:50;not null" json:"threat"`
+	Reason  string `gorm:"type:text" json:"reason,omitempty"`
+	AddedBy string `gorm:"size:255" json:"added_by,omitempty"` // admin email
+}
+
+// RetentionRun records one enforcement of the data retention policy
+type RetentionRun struct {
+	BaseModel
+	DryRun     bool      `gorm:"not null;default:false" json:"dry_run"`
+	StartedAt  time.Time `gorm:"not null;index" json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Results    string    `gorm:"type:text" json:"-"` // JSON per data class
+	Error      string    `gorm:"type:text" json:"error,omitempty"`
+}
+
+// StorageOrphan is a stored object no row references. The storage garbage
+// collector deletes it once it has stayed unreferenced for the grace period
+type StorageOrphan struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	ObjectKey string    `gorm:"size:1024;not null;uniqueIndex" json:"object_key"`
+	Prefix    string    `gorm:"size:100;not null;index" json:"prefix"`
+	MarkedAt  time.Time `gorm:"not null;index" json:"marked_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// StorageGCRun records one run of the storage garbage collector
+type StorageGCRun struct {
+	BaseModel
+	DryRun     bool      `gorm:"not null;default:false" json:"dry_run"`
+	StartedAt  time.Time `gorm:"not null;index" json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Results    string    `gorm:"type:text" json:"-"` // JSON per prefix
+	Error      string    `gorm:"type:text" json:"error,omitempty"`
+}
+
+// ComplianceReport is a compliance export a workspace owner scheduled over
+// their shares, shared collections and members. The worker generates it
+// each period and delivers it to a bucket or a webhook
+type ComplianceReport struct {
+	BaseModel
+	UserID   uint        `gorm:"not null;index" json:"user_id"` // the workspace owner
+	Name     string      `gorm:"size:100;not null" json:"name"`
+	Template string      `gorm:"size:32;not null" json:"template"`
+	Sections StringSlice `gorm:"type:text" json:"sections"`
+	Format   string      `gorm:"size:8;not null;default:'json'" json:"format"` // csv, json
+	Schedule string      `gorm:"size:16;not null" json:"schedule"`             // daily, weekly, monthly
+
+	DestinationType string `gorm:"size:16;not null" json:"destination_type"` // bucket, webhook
+	DestinationID   *uint  `json:"destination_id,omitempty"`                 // backup destination of a bucket delivery
+	WebhookURL      string `gorm:"size:500" json:"webhook_url,omitempty"`
+	WebhookSecret   string `gorm:"size:128" json:"-"` // signs webhook deliveries
+
+	Active    bool       `gorm:"default:true" json:"active"`
+	NextRunAt time.Time  `gorm:"not null;index" json:"next_run_at"`
+	LastRunAt *time.Time `json:"last_run_at,omitempty"` // end of the last reported period
+}
+
+// ComplianceRun is one generation and delivery of a compliance report
+type ComplianceRun struct {
+	BaseModel
+	ReportID    uint      `gorm:"not null;index" json:"report_id"`
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+	Status      string    `gorm:"size:16;not null" json:"status"` // delivered, failed
+	Entries     int       `json:"entries"`
+	Location    string    `gorm:"size:1024" json:"location,omitempty"`
+	Error       string    `gorm:"type:text" json:"error,omitempty"`
+}
+
+// DailyStat is how many bookmarks a user saved and read on one UTC day,
+// aggregated by the worker for the calendar heat map
+type DailyStat struct {
+	ID        uint      `gorm:"primaryKey" json:"-"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_daily_stat_user_day" json:"user_id"`
+	Day       time.Time `gorm:"not null;uniqueIndex:idx_daily_stat_user_day" json:"day"`
+	Saved     int       `gorm:"not null;default:0" json:"saved"`
+	Read      int       `gorm:"not null;default:0" json:"read"` // distinct bookmarks with a reading session
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// DailyStatCursor is the last row of a source table aggregated into daily
+// stats, so each run only reads rows added since the previous one
+type DailyStatCursor struct {
+	Source    string    `gorm:"primaryKey;size:50" json:"source"`
+	LastID    uint      `gorm:"not null;default:0" json:"last_id"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// BookmarkEdge relates two of a user's bookmarks in the bookmark graph,
+// precomputed by the worker. Same domain and co-collection edges are each
+// bookmark's nearest neighbours, so they point from the bookmark they were
+// computed for; co-visit edges count how often the user went from one
+// bookmark to the other and point from the lower ID
+type BookmarkEdge struct {
+	ID        uint      `gorm:"primaryKey" json:"-"`
+	UserID    uint      `gorm:"not null;index" json:"user_id"`
+	SourceID  uint      `gorm:"not null;uniqueIndex:idx_bookmark_edge" json:"source_id"`
+	TargetID  uint      `gorm:"not null;uniqueIndex:idx_bookmark_edge;index" json:"target_id"`
+	Kind      string    `gorm:"size:20;not null;uniqueIndex:idx_bookmark_edge" json:"kind"` // same_domain, co_collection, co_visit
+	Weight    int       `gorm:"not null;default:1" json:"weight"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// BookmarkGraphCursor is how far the bookmark graph has read a source
+// table, by ID or, for rows that change, by update time and then ID
+type BookmarkGraphCursor struct {
+	Source    string    `gorm:"primaryKey;size:50" json:"source"`
+	LastID    uint      `gorm:"not null;default:0" json:"last_id"`
+	LastAt    time.Time `json:"last_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// BookmarkGraphChange queues a bookmark whose edges are rebuilt by the next
+// graph refresh, for changes that leave the bookmark itself untouched such
+// as being added to a collection
+type BookmarkGraphChange struct {
+	BookmarkID uint      `gorm:"primaryKey;autoIncrement:false" json:"bookmark_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// FederationKey is the key pair a user's ActivityPub actor signs with
+type FederationKey struct {
+	BaseModel
+	UserID        uint   `gorm:"not null;uniqueIndex" json:"user_id"`
+	PublicKeyPEM  string `gorm:"type:text;not null" json:"public_key_pem"`
+	PrivateKeyPEM string `gorm:"type:text;not null" json:"-"`
+}
+
+// FederationFollower is an actor on another instance following a user's
+// public bookmarks
+type FederationFollower struct {
+	BaseModel
+	UserID      uint   `gorm:"not null;uniqueIndex:idx_federation_follower" json:"user_id"`
+	ActorURI    string `gorm:"size:500;not null;uniqueIndex:idx_federation_follower" json:"actor_uri"`
+	InboxURL    string `gorm:"size:500;not null" json:"inbox_url"`
+	SharedInbox string `gorm:"size:500" json:"shared_inbox,omitempty"`
+}
+
+// FederationActivity is an entry of a user's ActivityPub outbox
+type FederationActivity struct {
+	BaseModel
+	UserID     uint   `gorm:"not null;index" json:"user_id"`
+	BookmarkID uint   `gorm:"not null;index" json:"bookmark_id"`
+	Type       string `gorm:"size:20;not null" json:"type"` // Create
+	Object     string `gorm:"type:text;not null" json:"-"`  // the published ActivityStreams object
+}
+
+// SyncScope limits a device to the bookmarks of selected collections.
+// Devices without a scope sync everything
+type SyncScope struct {
+	BaseModel
+	UserID        string `gorm:"not null;uniqueIndex:idx_sync_scope_device" json:"user_id"`
+	DeviceID      string `gorm:"not null;uniqueIndex:idx_sync_scope_device" json:"device_id"`
+	CollectionIDs string `gorm:"type:text;not null" json:"-"` // JSON array of collection IDs
+}
+
+// SyncConflict is a change a device made to a resource another device had
+// changed since the first one last synced. The change is held back as
+// LocalEventID until the user picks a side or merges the fields, and the
+// outcome is published as ResolvedEventID
+type SyncConflict struct {
+	BaseModel
+	UserID        string      `gorm:"not null;index" json:"user_id"`
+	ResourceID    string      `gorm:"not null;index" json:"resource_id"`
+	EventType     string      `gorm:"size:50;not null" json:"event_type"`
+	LocalEventID  uint        `gorm:"not null" json:"local_event_id"`
+	RemoteEventID uint        `gorm:"not null" json:"remote_event_id"`
+	Fields        StringSlice `gorm:"type:text" json:"fields"` // changed on both sides; empty when one side deleted
+	Status        string      `gorm:"size:16;not null;default:'unresolved';index" json:"status"`
+	// Resolution is local, remote or merge
+	Resolution      string     `gorm:"size:16" json:"resolution,omitempty"`
+	ResolvedEventID *uint      `json:"resolved_event_id,omitempty"`
+	ResolvedAt      *time.Time `json:"resolved_at,omitempty"`
+}
+
+// OnboardingStep is an item of the onboarding checklist. Steps with an
+// Event complete themselves when the user does that; the rest are checked
+// off by the client
+type OnboardingStep struct {
+	BaseModel
+	Key         string `gorm:"size:50;not null;uniqueIndex" json:"key"`
+	Title       string `gorm:"size:200;not null" json:"title"`
+	Description string `gorm:"type:text" json:"description,omitempty"`
+	Event       string `gorm:"size:50;index" json:"event,omitempty"` // e.g. bookmark_created
+	Position    int    `gorm:"not null;default:0" json:"position"`
+	Enabled     bool   `gorm:"not null;default:true" json:"enabled"`
+}
+
+// OnboardingProgress records a user completing an onboarding step
+type OnboardingProgress struct {
+	BaseModel
+	UserID      uint      `gorm:"not null;uniqueIndex:idx_onboarding_progress" json:"user_id"`
+	StepKey     string    `gorm:"size:50;not null;uniqueIndex:idx_onboarding_progress" json:"step_key"`
+	CompletedAt time.Time `gorm:"not null" json:"completed_at"`
+}
+
+// Announcement is an instance-wide banner, shown from StartsAt until EndsAt
+// to the users in its audience
+type Announcement struct {
+	BaseModel
+	Message  string     `gorm:"type:text;not null" json:"message"`
+	Severity string     `gorm:"size:20;not null;default:'info'" json:"severity"` // info, warning, critical
+	StartsAt time.Time  `gorm:"not null;index" json:"starts_at"`
+	EndsAt   *time.Time `gorm:"index" json:"ends_at,omitempty"`                 // nil shows it until deleted
+	Audience string     `gorm:"size:20;not null;default:'all'" json:"audience"` // all, users, new_users, admins
+}
+
+// AnnouncementDismissal records a user dismissing an announcement
+type AnnouncementDismissal struct {
+	BaseModel
+	AnnouncementID uint `gorm:"not null;uniqueIndex:idx_announcement_dismissal" json:"announcement_id"`
+	UserID         uint `gorm:"not null;uniqueIndex:idx_announcement_dismissal;index" json:"user_id"`
+}
+
+// UserIdentity links a user to their account at an auth provider. Users
+// created before pluggable providers are linked to Supabase through their
+// SupabaseID
+type UserIdentity struct {
+	BaseModel
+	UserID       uint   `gorm:"not null;index" json:"user_id"`
+	Provider     string `gorm:"size:20;not null;uniqueIndex:idx_user_identity" json:"provider"` // supabase, oidc or local
+	Subject      string `gorm:"not null;uniqueIndex:idx_user_identity" json:"subject"`
+	Email        string `gorm:"index" json:"email"`
+	PasswordHash string `json:"-"` // local accounts only
+}
+
+// ScreenshotJob is a queued re-capture of a page. Refresh requests for the
+// same URL, from any user, join the pending job instead of queueing another
+type ScreenshotJob struct {
+	BaseModel
+	URL           string     `gorm:"not null;index" json:"url"`
+	Domain        string     `gorm:"size:255;index" json:"domain"`
+	Status        string     `gorm:"size:20;not null;default:'queued';index" json:"status"` // queued, running, completed, failed
+	Attempts      int        `gorm:"default:0" json:"attempts"`
+	ScreenshotURL string     `json:"screenshot_url,omitempty"`
+	Error         string     `gorm:"type:text" json:"error,omitempty"`
+	CompletedAt   *time.Time `json:"completed_at,omitempty"`
+	Bookmarks     []Bookmark `gorm:"many2many:screenshot_job_bookmarks;" json:"-"`
+}
+
+// Tag is a user's tag in the relational tag model replacing the JSON tags
+// column of bookmarks. Until cutover both are written, see pkg/dualwrite
+type Tag struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_tags_user_name" json:"user_id"`
+	Name      string    `gorm:"size:255;not null;uniqueIndex:idx_tags_user_name" json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BookmarkTag links a bookmark to one of its tags
+type BookmarkTag struct {
+	BookmarkID uint `gorm:"primaryKey" json:"bookmark_id"`
+	TagID      uint `gorm:"primaryKey;index" json:"tag_id"`
+	Position   int  `gorm:"not null;default:0" json:"position"` // keeps the bookmark's tag order
+}
+
+// CollectionSuggestionFeedback records whether a user took a suggested
+// collection for a bookmark, so later suggestions learn from it
+type CollectionSuggestionFeedback struct {
+	BaseModel
+	UserID       uint   `gorm:"not null;index:idx_suggestion_feedback" json:"user_id"`
+	CollectionID uint   `gorm:"not null;index:idx_suggestion_feedback" json:"collection_id"`
+	BookmarkID   uint   `gorm:"not null;index" json:"bookmark_id"`
+	Domain       string `gorm:"size:255" json:"domain"`
+	Accepted     bool   `json:"accepted"`
+}
+
+// CollectionCluster groups public collections whose bookmarks largely
+// overlap. Discovery shows only the representative of a cluster until an
+// admin dismisses it
+type CollectionCluster struct {
+	BaseModel
+	RepresentativeID uint       `gorm:"not null;index" json:"representative_id"`
+	Status           string     `gorm:"size:20;not null;default:'pending';index" json:"status"` // pending, confirmed, dismissed
+	Similarity       float64    `json:"similarity"`                                             // lowest pairwise overlap in the cluster
+	Fingerprint      string     `gorm:"size:64;index" json:"-"`                                 // hash of the sorted member IDs
+	ReviewedAt       *time.Time `json:"reviewed_at,omitempty"`
+
+	Members []CollectionClusterMember `gorm:"foreignKey:ClusterID" json:"members,omitempty"`
+}
+
+// CollectionClusterMember is a collection in a duplicate cluster
+type CollectionClusterMember struct {
+	ID           uint `gorm:"primaryKey" json:"-"`
+	ClusterID    uint `gorm:"not null;index" json:"cluster_id"`
+	CollectionID uint `gorm:"not null;index" json:"collection_id"`
+
+	Collection Collection `gorm:"foreignKey:CollectionID" json:"collection,omitempty"`
+}
+
+// Reviews of a bookmark's quality score
+const (
+	QualityReviewAppealed = "appealed" // the owner asked for a review
+	QualityReviewApproved = "approved" // shown whatever its score
+	QualityReviewRejected = "rejected" // hidden whatever its score
+)
+
+// BookmarkQuality is the spam score of a bookmark in a public collection,
+// from 0 for spam to 100. Suppressed bookmarks are left out of trending,
+// discovery and recommendations; an admin review overrides the score
+type BookmarkQuality struct {
+	BaseModel
+	BookmarkID   uint        `gorm:"not null;uniqueIndex" json:"bookmark_id"`
+	UserID       uint        `gorm:"not null;index" json:"user_id"`
+	Domain       string      `gorm:"size:255;index" json:"domain"`
+	Score        int         `gorm:"not null" json:"score"`
+	Signals      StringSlice `gorm:"type:text" json:"signals"` // what lowered the score
+	Suppressed   bool        `gorm:"not null;default:false;index" json:"suppressed"`
+	Review       string      `gorm:"size:20;index" json:"review,omitempty"` // appealed, approved or rejected
+	AppealReason string      `gorm:"type:text" json:"appeal_reason,omitempty"`
+	AppealedAt   *time.Time  `json:"appealed_at,omitempty"`
+	ReviewedBy   string      `gorm:"size:255" json:"reviewed_by,omitempty"` // admin email
+	ReviewedAt   *time.Time  `json:"reviewed_at,omitempty"`
+	ScoredAt     time.Time   `gorm:"not null;index" json:"scored_at"`
+
+	Bookmark *Bookmark `gorm:"foreignKey:BookmarkID" json:"bookmark,omitempty"`
+}
+
+// QualityThresholds are the score thresholds admins set at runtime. There
+// is at most one row; without it the configured defaults apply
+type QualityThresholds struct {
+	ID            uint      `gorm:"primaryKey" json:"-"`
+	SuppressBelow int       `gorm:"not null" json:"suppress_below"`
+	ReviewBelow   int       `gorm:"not null" json:"review_below"`
+	UpdatedBy     string    `gorm:"size:255" json:"updated_by,omitempty"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// SuppressedBookmarks selects the IDs of bookmarks hidden from public areas
+// for their quality, for use as a subquery
+func SuppressedBookmarks(db *gorm.DB) *gorm.DB {
+	return db.Model(&BookmarkQuality{}).Select("bookmark_id").Where("suppressed = ?", true)
+}
+
+// URLRulePack is a set of URL cleanup rules a user shared; others install it
+// into their own rules through its token
+type URLRulePack struct {
+	BaseModel
+	UserID       uint   `gorm:"not null;index" json:"user_id"`
+	Name         string `gorm:"size:100;not null" json:"name"`
+	Description  string `gorm:"type:text" json:"description,omitempty"`
+	Rules        string `gorm:"type:text;not null" json:"-"` // JSON list of urlnorm rules
+	ShareToken   string `gorm:"size:64;uniqueIndex;not null" json:"share_token"`
+	InstallCount int    `gorm:"default:0" json:"install_count"`
+}
+
+// Account merge statuses
+const (
+	MergeStatusAwaitingConfirmation = "awaiting_confirmation" // started from the source account, to be confirmed from the target
+	MergeStatusQueued               = "queued"
+	MergeStatusRunning              = "running"
+	MergeStatusCompleted            = "completed"
+	MergeStatusFailed               = "failed"
+	MergeStatusCancelled            = "cancelled"
+)
+
+// AccountMerge moves the bookmarks, collections, shares, follows and
+// preferences of a source account to a target account, then closes the
+// source. The worker runs queued merges; Report is the JSON merge report
+type AccountMerge struct {
+	BaseModel
+	SourceUserID uint       `gorm:"not null;index" json:"source_user_id"`
+	TargetUserID uint       `gorm:"not null;index" json:"target_user_id"`
+	RequestedBy  string     `gorm:"size:255" json:"requested_by"` // email of the user or admin who started it
+	Status       string     `gorm:"size:24;not null;index" json:"status"`
+	TokenHash    string     `gorm:"size:64;index" json:"-"` // confirms a merge started by a user
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`   // confirmation deadline
+	StartedAt    *time.Time `json:"started_at,omitempty"`
+	CompletedAt  *time.Time `json:"completed_at,omitempty"`
+	Report       string     `gorm:"type:text" json:"-"`
+	Error        string     `gorm:"type:text" json:"error,omitempty"`
+}
+
+// Media backfill statuses
+const (
+	BackfillStatusQueued    = "queued"
+	BackfillStatusRunning   = "running"
+	BackfillStatusCompleted = "completed"
+	BackfillStatusCancelled = "cancelled"
+)
+
+// MediaBackfill captures screenshots and archives for existing bookmarks
+// lacking them. An admin starts it and the worker runs a batch at a time
+// within the nightly window. Cursor is the checkpoint: every bookmark up to
+// it has been attempted, so a restart carries on after it
+type MediaBackfill struct {
+	BaseModel
+	RequestedBy         string     `gorm:"size:255" json:"requested_by"` // admin email
+	Screenshots         bool       `json:"screenshots"`
+	Archives            bool       `json:"archives"`
+	Status              string     `gorm:"size:16;not null;index" json:"status"`
+	Cursor              uint       `json:"cursor"`
+	Total               int64      `json:"total"` // bookmarks lacking media when it started
+	Processed           int        `json:"processed"`
+	ScreenshotsCaptured int        `json:"screenshots_captured"`
+	ArchivesCaptured    int        `json:"archives_captured"`
+	Failed              int        `json:"failed"`
+	StoredBytes         int64      `json:"stored_bytes"`
+	LastError           string     `gorm:"type:text" json:"last_error,omitempty"`
+	StartedAt           *time.Time `json:"started_at,omitempty"`
+	CompletedAt         *time.Time `json:"completed_at,omitempty"`
+}
+
+// Speed dial tile sizes
+const (
+	SpeedDialSmall  = "small"
+	SpeedDialMedium = "medium"
+	SpeedDialLarge  = "large" // shows a screenshot of the page
+)
+
+// SpeedDialGroup is a folder of tiles on a user's new-tab grid
+type SpeedDialGroup struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"not null;index" json:"user_id"`
+	Name      string    `gorm:"size:100;not null" json:"name"`
+	Position  int       `gorm:"not null;default:0" json:"position"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SpeedDialTile is a bookmark pinned to a user's new-tab grid, at a position
+// within its group or among the ungrouped tiles
+type SpeedDialTile struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	UserID     uint      `gorm:"not null;uniqueIndex:idx_speed_dial_tiles_bookmark" json:"user_id"`
+	BookmarkID uint      `gorm:"not null;uniqueIndex:idx_speed_dial_tiles_bookmark" json:"bookmark_id"`
+	GroupID    *uint     `gorm:"index" json:"group_id"`
+	Position   int       `gorm:"not null;default:0" json:"position"`
+	Size       string    `gorm:"size:10;not null;default:'medium'" json:"size"`
+	Title      string    `gorm:"size:255" json:"title,omitempty"` // replaces the bookmark's title on the tile
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// SpeedDialOverride changes a tile on one device only: another position in
+// its group, another size, or hidden
+type SpeedDialOverride struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"not null;index" json:"user_id"`
+	DeviceID  string    `gorm:"size:255;not null;uniqueIndex:idx_speed_dial_overrides_tile" json:"device_id"`
+	TileID    uint      `gorm:"not null;uniqueIndex:idx_speed_dial_overrides_tile" json:"tile_id"`
+	Position  *int      `json:"position,omitempty"`
+	Size      string    `gorm:"size:10" json:"size,omitempty"`
+	Hidden    bool      `gorm:"default:false" json:"hidden"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ImportFolderMapping remembers the collection a folder of an import source
+// went into, so repeat imports from the source fill the same collections
+type ImportFolderMapping struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	UserID       uint      `gorm:"not null;uniqueIndex:idx_import_folder_mapping" json:"user_id"`
+	Source       string    `gorm:"size:50;not null;uniqueIndex:idx_import_folder_mapping" json:"source"`
+	Path         string    `gorm:"size:1000;not null;uniqueIndex:idx_import_folder_mapping" json:"path"` // JSON list of folder names from the top
+	CollectionID uint      `gorm:"not null;index" json:"collection_id"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// CleanupReminder is a user's opt-in to the quarterly spring-cleaning
+// reminder
+type CleanupReminder struct {
+	UserID     uint       `gorm:"primaryKey;autoIncrement:false" json:"user_id"`
+	Enabled    bool       `gorm:"not null;default:false;index" json:"enabled"`
+	LastSentAt *time.Time `json:"last_sent_at,omitempty"` // also set on opting in, so the first reminder waits for the next quarter
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
 // createIndexes creates additional database indexes for performance
 func createIndexes(db *gorm.DB) error {
 	// Basic indexes that work on both PostgreSQL and SQLite
@@ -0,0 +1,43 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// WithTransaction runs fn as one unit of work: every write made through tx
+// is committed when fn returns nil and rolled back when it returns an error
+// or panics. The transaction is bound to ctx, so a cancelled request rolls
+// back too. Calling it with a db that is already in a transaction nests the
+// work in a savepoint, so helpers can be composed into larger units.
+//
+// fn must use tx for every query; the parent db is a different connection
+// and does not see uncommitted writes
+func WithTransaction(ctx context.Context, db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	return db.WithContext(ctx).Transaction(fn)
+}
+
+// ForUpdate locks the rows a query reads until the transaction ends, so a
+// read-check-write sequence cannot race a concurrent one. SQLite locks the
+// whole database on write and ignores the clause
+func ForUpdate(tx *gorm.DB) *gorm.DB {
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"})
+}
+
+// IsUniqueViolation reports whether err is a write refused by a unique
+// index, so callers can turn a lost insert race into their own error
+func IsUniqueViolation(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return true
+	}
+	message := err.Error()
+	return strings.Contains(message, "duplicate key value violates unique constraint") || // PostgreSQL
+		strings.Contains(message, "UNIQUE constraint failed") // SQLite
+}
@@ -0,0 +1,118 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// TestWithTransaction tests that a unit of work commits or rolls back as a whole
+// 測試工作單元整體提交或回滾
+func TestWithTransaction(t *testing.T) {
+	ctx := context.Background()
+
+	countUsers := func(db *gorm.DB) int64 {
+		var count int64
+		require.NoError(t, db.Model(&User{}).Count(&count).Error)
+		return count
+	}
+	newUser := func(name string) *User {
+		return &User{Email: name + "@example.com", Username: name, SupabaseID: name}
+	}
+
+	t.Run("Commit", func(t *testing.T) {
+		db := setupTestDB(t)
+		err := WithTransaction(ctx, db, func(tx *gorm.DB) error {
+			if err := tx.Create(newUser("first")).Error; err != nil {
+				return err
+			}
+			return tx.Create(newUser("second")).Error
+		})
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), countUsers(db))
+	})
+
+	t.Run("Rollback On Error", func(t *testing.T) {
+		db := setupTestDB(t)
+		err := WithTransaction(ctx, db, func(tx *gorm.DB) error {
+			require.NoError(t, tx.Create(newUser("first")).Error)
+			// The unique email fails the second write
+			return tx.Create(newUser("first")).Error
+		})
+		assert.Error(t, err)
+		assert.Zero(t, countUsers(db))
+	})
+
+	t.Run("Rollback On Panic", func(t *testing.T) {
+		db := setupTestDB(t)
+		assert.Panics(t, func() {
+			WithTransaction(ctx, db, func(tx *gorm.DB) error {
+				require.NoError(t, tx.Create(newUser("first")).Error)
+				panic("boom")
+			})
+		})
+		assert.Zero(t, countUsers(db))
+	})
+
+	t.Run("Nested Rollback Keeps Outer Work", func(t *testing.T) {
+		db := setupTestDB(t)
+		err := WithTransaction(ctx, db, func(tx *gorm.DB) error {
+			if err := tx.Create(newUser("outer")).Error; err != nil {
+				return err
+			}
+			nested := WithTransaction(ctx, tx, func(tx *gorm.DB) error {
+				require.NoError(t, tx.Create(newUser("inner")).Error)
+				return errors.New("inner failed")
+			})
+			assert.EqualError(t, nested, "inner failed")
+			return nil
+		})
+		require.NoError(t, err)
+
+		var users []User
+		require.NoError(t, db.Find(&users).Error)
+		require.Len(t, users, 1)
+		assert.Equal(t, "outer", users[0].Username)
+	})
+
+	t.Run("Cancelled Context", func(t *testing.T) {
+		db := setupTestDB(t)
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		called := false
+		err := WithTransaction(cancelled, db, func(tx *gorm.DB) error {
+			called = true
+			return nil
+		})
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.False(t, called)
+	})
+
+	t.Run("ForUpdate", func(t *testing.T) {
+		db := setupTestDB(t)
+		require.NoError(t, db.Create(newUser("locked")).Error)
+
+		// SQLite ignores the lock, but the query must still run
+		err := WithTransaction(ctx, db, func(tx *gorm.DB) error {
+			var user User
+			return ForUpdate(tx).First(&user, "username = ?", "locked").Error
+		})
+		assert.NoError(t, err)
+	})
+
+	t.Run("IsUniqueViolation", func(t *testing.T) {
+		db := setupTestDB(t)
+		require.NoError(t, db.Create(newUser("taken")).Error)
+
+		err := db.Create(newUser("taken")).Error
+		require.Error(t, err)
+		assert.True(t, IsUniqueViolation(err))
+		assert.False(t, IsUniqueViolation(errors.New("connection refused")))
+		assert.False(t, IsUniqueViolation(nil))
+	})
+}
@@ -0,0 +1,222 @@
+// Package dualwrite rolls out a change of data representation in stages:
+// writing both the old and new representation, shadow-reading the new one
+// to compare it with the old, then cutting reads over to the new one
+package dualwrite
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// Mode is the rollout stage of a migration, usually set by a feature flag
+type Mode string
+
+const (
+	// ModeOff writes and reads only the old representation
+	ModeOff Mode = "off"
+	// ModeDualWrite also writes the new representation
+	ModeDualWrite Mode = "dual_write"
+	// ModeShadow dual-writes and compares reads of the new representation
+	// with the old one in the background
+	ModeShadow Mode = "shadow"
+	// ModeCutover reads the new representation and still writes both, so
+	// the flag can be turned back
+	ModeCutover Mode = "cutover"
+)
+
+// ParseMode parses a mode flag. An empty flag is ModeOff
+func ParseMode(value string) (Mode, error) {
+	switch mode := Mode(value); mode {
+	case "":
+		return ModeOff, nil
+	case ModeOff, ModeDualWrite, ModeShadow, ModeCutover:
+		return mode, nil
+	default:
+		return ModeOff, fmt.Errorf("unknown dual-write mode %q", value)
+	}
+}
+
+// WritesNew reports whether writes go to the new representation too
+func (m Mode) WritesNew() bool {
+	return m == ModeDualWrite || m == ModeShadow || m == ModeCutover
+}
+
+// Stats counts the outcomes of a migration's writes and shadow reads
+type Stats struct {
+	Mode           Mode   `json:"mode"`
+	NewWrites      uint64 `json:"new_writes"`
+	NewWriteErrors uint64 `json:"new_write_errors"`
+	ShadowMatches  uint64 `json:"shadow_matches"`
+	ShadowDiverged uint64 `json:"shadow_diverged"`
+	ShadowErrors   uint64 `json:"shadow_errors"`
+}
+
+// Migration coordinates one change of representation, e.g. JSON tags to
+// relational tags. It is safe for concurrent use
+type Migration struct {
+	name   string
+	mode   atomic.Value // Mode
+	logger *zap.Logger
+
+	// pending tracks shadow comparisons still running in the background
+	pending sync.WaitGroup
+
+	newWrites      atomic.Uint64
+	newWriteErrors atomic.Uint64
+	shadowMatches  atomic.Uint64
+	shadowDiverged atomic.Uint64
+	shadowErrors   atomic.Uint64
+}
+
+// New creates a migration in the given mode
+func New(name string, mode Mode, logger *zap.Logger) *Migration {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	m := &Migration{
+		name:   name,
+		logger: logger.With(zap.String("migration", name)),
+	}
+	m.mode.Store(mode)
+	return m
+}
+
+// Name returns the migration's name
+func (m *Migration) Name() string {
+	return m.name
+}
+
+// Mode returns the current rollout stage
+func (m *Migration) Mode() Mode {
+	return m.mode.Load().(Mode)
+}
+
+// SetMode moves the migration to another stage, e.g. to cut over or back
+func (m *Migration) SetMode(mode Mode) {
+	m.mode.Store(mode)
+}
+
+// Stats returns the migration's counters
+func (m *Migration) Stats() Stats {
+	return Stats{
+		Mode:           m.Mode(),
+		NewWrites:      m.newWrites.Load(),
+		NewWriteErrors: m.newWriteErrors.Load(),
+		ShadowMatches:  m.shadowMatches.Load(),
+		ShadowDiverged: m.shadowDiverged.Load(),
+		ShadowErrors:   m.shadowErrors.Load(),
+	}
+}
+
+// Wait blocks until running shadow comparisons finish, e.g. on shutdown
+func (m *Migration) Wait() {
+	m.pending.Wait()
+}
+
+// Write writes the old representation and, once dual writes are on, the
+// new one. Until cutover the old representation is the source of truth, so
+// a failed new write is counted and logged but not returned
+func (m *Migration) Write(ctx context.Context, writeOld, writeNew func(ctx context.Context) error) error {
+	if err := writeOld(ctx); err != nil {
+		return err
+	}
+
+	mode := m.Mode()
+	if !mode.WritesNew() {
+		return nil
+	}
+	if err := writeNew(ctx); err != nil {
+		m.newWriteErrors.Add(1)
+		if mode == ModeCutover {
+			return err
+		}
+		m.logger.Warn("Dual write to new representation failed", zap.Error(err))
+		return nil
+	}
+	m.newWrites.Add(1)
+	return nil
+}
+
+// Read reads the representation the migration's stage serves from. In
+// shadow mode it also reads the new one in the background and records
+// whether the two agree
+func Read[T any](ctx context.Context, m *Migration, readOld, readNew func(ctx context.Context) (T, error), equal func(old, new T) bool) (T, error) {
+	switch m.Mode() {
+	case ModeCutover:
+		return readNew(ctx)
+	case ModeShadow:
+		old, err := readOld(ctx)
+		if err != nil {
+			return old, err
+		}
+		// The request may finish before the comparison does
+		shadowCtx := context.WithoutCancel(ctx)
+		m.pending.Add(1)
+		go func() {
+			defer m.pending.Done()
+			shadow, err := readNew(shadowCtx)
+			switch {
+			case err != nil:
+				m.shadowErrors.Add(1)
+				m.logger.Warn("Shadow read failed", zap.Error(err))
+			case !equal(old, shadow):
+				m.shadowDiverged.Add(1)
+				m.logger.Warn("Shadow read diverged from old representation")
+			default:
+				m.shadowMatches.Add(1)
+			}
+		}()
+		return old, nil
+	default:
+		return readOld(ctx)
+	}
+}
+
+// collector exports migration counters to Prometheus
+type collector struct {
+	migrations []*Migration
+	writes     *prometheus.Desc
+	shadow     *prometheus.Desc
+	cutover    *prometheus.Desc
+}
+
+// NewCollector returns a Prometheus collector for the migrations' writes,
+// shadow read divergence and cutover state
+func NewCollector(migrations ...*Migration) prometheus.Collector {
+	return &collector{
+		migrations: migrations,
+		writes: prometheus.NewDesc("bookmark_sync_dualwrite_new_writes_total",
+			"Writes to the new representation of a migration, by result.", []string{"migration", "result"}, nil),
+		shadow: prometheus.NewDesc("bookmark_sync_dualwrite_shadow_reads_total",
+			"Shadow reads of the new representation of a migration, by comparison result.", []string{"migration", "result"}, nil),
+		cutover: prometheus.NewDesc("bookmark_sync_dualwrite_cutover",
+			"Whether a migration serves reads from the new representation.", []string{"migration"}, nil),
+	}
+}
+
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.writes
+	ch <- c.shadow
+	ch <- c.cutover
+}
+
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	for _, m := range c.migrations {
+		stats := m.Stats()
+		ch <- prometheus.MustNewConstMetric(c.writes, prometheus.CounterValue, float64(stats.NewWrites), m.name, "ok")
+		ch <- prometheus.MustNewConstMetric(c.writes, prometheus.CounterValue, float64(stats.NewWriteErrors), m.name, "error")
+		ch <- prometheus.MustNewConstMetric(c.shadow, prometheus.CounterValue, float64(stats.ShadowMatches), m.name, "match")
+		ch <- prometheus.MustNewConstMetric(c.shadow, prometheus.CounterValue, float64(stats.ShadowDiverged), m.name, "diverged")
+		ch <- prometheus.MustNewConstMetric(c.shadow, prometheus.CounterValue, float64(stats.ShadowErrors), m.name, "error")
+		cutover := 0.0
+		if stats.Mode == ModeCutover {
+			cutover = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.cutover, prometheus.GaugeValue, cutover, m.name)
+	}
+}
@@ -0,0 +1,107 @@
+package dualwrite
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMode(t *testing.T) {
+	mode, err := ParseMode("")
+	require.NoError(t, err)
+	assert.Equal(t, ModeOff, mode)
+
+	mode, err = ParseMode("shadow")
+	require.NoError(t, err)
+	assert.Equal(t, ModeShadow, mode)
+
+	_, err = ParseMode("both")
+	assert.Error(t, err)
+}
+
+func TestMigration_Write(t *testing.T) {
+	ctx := context.Background()
+	var written []string
+	writeOld := func(context.Context) error { written = append(written, "old"); return nil }
+	writeNew := func(context.Context) error { written = append(written, "new"); return nil }
+	failNew := func(context.Context) error { return errors.New("table missing") }
+
+	m := New("tags", ModeOff, nil)
+	require.NoError(t, m.Write(ctx, writeOld, writeNew))
+	assert.Equal(t, []string{"old"}, written)
+
+	m.SetMode(ModeDualWrite)
+	require.NoError(t, m.Write(ctx, writeOld, writeNew))
+	assert.Equal(t, []string{"old", "old", "new"}, written)
+
+	// Before cutover a failed new write does not fail the request
+	require.NoError(t, m.Write(ctx, writeOld, failNew))
+	m.SetMode(ModeCutover)
+	assert.Error(t, m.Write(ctx, writeOld, failNew))
+
+	err := m.Write(ctx, func(context.Context) error { return errors.New("old failed") }, writeNew)
+	assert.EqualError(t, err, "old failed")
+
+	stats := m.Stats()
+	assert.Equal(t, uint64(1), stats.NewWrites)
+	assert.Equal(t, uint64(2), stats.NewWriteErrors)
+}
+
+func TestRead(t *testing.T) {
+	ctx := context.Background()
+	m := New("tags", ModeShadow, nil)
+	newValue := "b"
+	readOld := func(context.Context) (string, error) { return "a", nil }
+	readNew := func(context.Context) (string, error) { return newValue, nil }
+	equal := func(old, new string) bool { return old == new }
+
+	// Shadow mode serves the old value and compares in the background
+	value, err := Read(ctx, m, readOld, readNew, equal)
+	require.NoError(t, err)
+	assert.Equal(t, "a", value)
+	m.Wait()
+	newValue = "a"
+	_, err = Read(ctx, m, readOld, readNew, equal)
+	require.NoError(t, err)
+	m.Wait()
+	_, err = Read(ctx, m, readOld, func(context.Context) (string, error) { return "", errors.New("timeout") }, equal)
+	require.NoError(t, err)
+	m.Wait()
+
+	stats := m.Stats()
+	assert.Equal(t, uint64(1), stats.ShadowDiverged)
+	assert.Equal(t, uint64(1), stats.ShadowMatches)
+	assert.Equal(t, uint64(1), stats.ShadowErrors)
+
+	m.SetMode(ModeCutover)
+	newValue = "c"
+	value, err = Read(ctx, m, readOld, readNew, equal)
+	require.NoError(t, err)
+	assert.Equal(t, "c", value)
+}
+
+func TestCollector(t *testing.T) {
+	m := New("relational_tags", ModeCutover, nil)
+	m.Write(context.Background(), func(context.Context) error { return nil }, func(context.Context) error { return nil })
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(NewCollector(m))
+
+	expected := `
+# HELP bookmark_sync_dualwrite_cutover Whether a migration serves reads from the new representation.
+# TYPE bookmark_sync_dualwrite_cutover gauge
+bookmark_sync_dualwrite_cutover{migration="relational_tags"} 1
+# HELP bookmark_sync_dualwrite_new_writes_total Writes to the new representation of a migration, by result.
+# TYPE bookmark_sync_dualwrite_new_writes_total counter
+bookmark_sync_dualwrite_new_writes_total{migration="relational_tags",result="error"} 0
+bookmark_sync_dualwrite_new_writes_total{migration="relational_tags",result="ok"} 1
+`
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected),
+		"bookmark_sync_dualwrite_cutover", "bookmark_sync_dualwrite_new_writes_total"))
+}
@@ -0,0 +1,100 @@
+package exporter
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+func init() {
+	Register("archive", Format{
+		Description: "Self-contained zip of collections and their bookmarks with archived copies and screenshots, importable into another account",
+		Extension:   "zip",
+		ContentType: "application/zip",
+		Exporter:    Func(writeArchive),
+	})
+}
+
+const (
+	// ArchiveVersion is the version of the archive layout. Imports reject
+	// archives of later versions
+	ArchiveVersion = 1
+	// ArchiveManifest is the name of the manifest in an archive
+	ArchiveManifest = "manifest.json"
+)
+
+// Manifest describes the contents of an archive. Collections refer to their
+// parent and bookmarks by ID, so the tree can be rebuilt on import
+type Manifest struct {
+	Version     int                  `json:"version"`
+	ExportedAt  time.Time            `json:"exported_at"`
+	Root        uint                 `json:"root,omitempty"` // collection the archive was exported from
+	Collections []ManifestCollection `json:"collections"`
+	Bookmarks   []Bookmark           `json:"bookmarks"`
+}
+
+// ManifestCollection is a collection of an archive
+type ManifestCollection struct {
+	ID          uint      `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	ParentID    *uint     `json:"parent_id,omitempty"` // left out of the archive for top-level collections
+	CreatedAt   time.Time `json:"created_at"`
+	BookmarkIDs []uint    `json:"bookmark_ids"`
+}
+
+// writeArchive writes a zip of the manifest and the files of the data
+func writeArchive(w io.Writer, data *Data) error {
+	manifest := Manifest{
+		Version:     ArchiveVersion,
+		ExportedAt:  data.ExportedAt,
+		Root:        data.Root,
+		Collections: make([]ManifestCollection, 0, len(data.Collections)),
+		Bookmarks:   data.Bookmarks,
+	}
+	included := make(map[uint]bool, len(data.Collections))
+	for _, collection := range data.Collections {
+		included[collection.ID] = true
+	}
+	for _, collection := range data.Collections {
+		entry := ManifestCollection{
+			ID:          collection.ID,
+			Name:        collection.Name,
+			Description: collection.Description,
+			CreatedAt:   collection.CreatedAt,
+			BookmarkIDs: make([]uint, 0, len(collection.Bookmarks)),
+		}
+		if collection.ParentID != nil && included[*collection.ParentID] {
+			entry.ParentID = collection.ParentID
+		}
+		for _, bookmark := range collection.Bookmarks {
+			entry.BookmarkIDs = append(entry.BookmarkIDs, bookmark.ID)
+		}
+		manifest.Collections = append(manifest.Collections, entry)
+	}
+
+	archive := zip.NewWriter(w)
+	file, err := archive.CreateHeader(&zip.FileHeader{Name: ArchiveManifest, Method: zip.Deflate, Modified: data.ExportedAt})
+	if err != nil {
+		return fmt.Errorf("failed to add %s: %w", ArchiveManifest, err)
+	}
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(manifest); err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	for _, attached := range data.Files {
+		// Images are compressed already
+		file, err := archive.CreateHeader(&zip.FileHeader{Name: attached.Path, Method: zip.Store, Modified: data.ExportedAt})
+		if err != nil {
+			return fmt.Errorf("failed to add %s: %w", attached.Path, err)
+		}
+		if _, err := file.Write(attached.Data); err != nil {
+			return fmt.Errorf("failed to write %s: %w", attached.Path, err)
+		}
+	}
+	return archive.Close()
+}
@@ -0,0 +1,72 @@
+package exporter
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+func init() {
+	Register("bibtex", Format{
+		Description: "BibTeX references for LaTeX, one @online entry per bookmark",
+		Extension:   "bib",
+		ContentType: "application/x-bibtex",
+		Citation:    true,
+		Exporter:    Func(writeBibTeX),
+	})
+}
+
+// bibtexEscaper escapes characters LaTeX would otherwise interpret
+var bibtexEscaper = strings.NewReplacer(
+	`\`, `\textbackslash{}`, "{", `\{`, "}", `\}`, "&", `\&`, "%", `\%`,
+	"$", `\$`, "#", `\#`, "_", `\_`, "~", `\textasciitilde{}`, "^", `\textasciicircum{}`,
+)
+
+// writeBibTeX writes biblatex @online entries, which BibTeX styles read as
+// @misc. Titles are braced so styles keep their capitalization
+func writeBibTeX(w io.Writer, data *Data) error {
+	for n, ref := range references(data) {
+		if n > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "@online{%s,\n", ref.Key)
+		fmt.Fprintf(w, "  title = {{%s}},\n", bibtexValue(ref.Title))
+		if len(ref.Authors) > 0 {
+			authors := make([]string, len(ref.Authors))
+			for i, author := range ref.Authors {
+				authors[i] = bibtexValue(author)
+			}
+			fmt.Fprintf(w, "  author = {%s},\n", strings.Join(authors, " and "))
+		}
+		if ref.Site != "" {
+			fmt.Fprintf(w, "  organization = {%s},\n", bibtexValue(ref.Site))
+		}
+		if ref.Published != nil {
+			fmt.Fprintf(w, "  date = {%s},\n", ref.Published.Format("2006-01-02"))
+		}
+		fmt.Fprintf(w, "  year = {%d},\n", ref.Year())
+		// url is verbatim in biblatex, only braces would end it early
+		fmt.Fprintf(w, "  url = {%s},\n", strings.NewReplacer("{", "%7B", "}", "%7D").Replace(ref.URL))
+		if !ref.Accessed.IsZero() {
+			fmt.Fprintf(w, "  urldate = {%s},\n", ref.Accessed.Format("2006-01-02"))
+		}
+		if ref.Language != "" {
+			fmt.Fprintf(w, "  langid = {%s},\n", bibtexValue(ref.Language))
+		}
+		if len(ref.Keywords) > 0 {
+			fmt.Fprintf(w, "  keywords = {%s},\n", bibtexValue(strings.Join(ref.Keywords, ", ")))
+		}
+		if ref.Abstract != "" {
+			fmt.Fprintf(w, "  abstract = {%s},\n", bibtexValue(ref.Abstract))
+		}
+		if _, err := fmt.Fprintln(w, "}"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bibtexValue escapes a field value and folds it to one line
+func bibtexValue(s string) string {
+	return bibtexEscaper.Replace(strings.Join(strings.Fields(s), " "))
+}
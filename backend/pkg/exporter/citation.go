@@ -0,0 +1,172 @@
+package exporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Reference is the bibliographic data of a bookmark that citation formats
+// write. Authors and the publication date come from the page metadata
+// extracted when the bookmark was saved, and are empty when the page had none
+type Reference struct {
+	Key       string // citation key, unique within one export
+	Title     string // falls back to the URL
+	URL       string
+	Authors   []string   // as the page names them, e.g. "Ada Lovelace"
+	Site      string     // site name, or the host without www.
+	Published *time.Time // publication date
+	Accessed  time.Time  // when the bookmark was saved
+	Language  string
+	Abstract  string   // description or summary
+	Keywords  []string // the bookmark's tags
+}
+
+// referenceMetadata holds the metadata fields citations use
+type referenceMetadata struct {
+	Author      json.RawMessage `json:"author"`
+	Authors     []string        `json:"authors"`
+	SiteName    string          `json:"site_name"`
+	PublishedAt string          `json:"published_at"`
+	Summary     string          `json:"summary"`
+}
+
+// ReferenceFor returns the reference of a bookmark. Citation keys are made
+// unique with the keys already in used, which it records
+func ReferenceFor(bookmark Bookmark, used map[string]bool) Reference {
+	var metadata referenceMetadata
+	if len(bookmark.Metadata) > 0 {
+		json.Unmarshal(bookmark.Metadata, &metadata)
+	}
+
+	ref := Reference{
+		Title:    strings.TrimSpace(bookmark.Title),
+		URL:      bookmark.URL,
+		Authors:  metadata.Authors,
+		Site:     strings.TrimSpace(metadata.SiteName),
+		Accessed: bookmark.CreatedAt,
+		Language: bookmark.Language,
+		Abstract: strings.TrimSpace(bookmark.Description),
+		Keywords: bookmark.Tags,
+	}
+	if ref.Title == "" {
+		ref.Title = bookmark.URL
+	}
+	if ref.Abstract == "" {
+		ref.Abstract = strings.TrimSpace(metadata.Summary)
+	}
+
+	// author is a single name or a list, depending on the extractor
+	var author string
+	if json.Unmarshal(metadata.Author, &author) == nil && strings.TrimSpace(author) != "" {
+		ref.Authors = append([]string{strings.TrimSpace(author)}, ref.Authors...)
+	} else {
+		var authors []string
+		if json.Unmarshal(metadata.Author, &authors) == nil {
+			ref.Authors = append(authors, ref.Authors...)
+		}
+	}
+
+	if ref.Site == "" {
+		if parsed, err := url.Parse(bookmark.URL); err == nil {
+			ref.Site = strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+		}
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if published, err := time.Parse(layout, metadata.PublishedAt); err == nil {
+			ref.Published = &published
+			break
+		}
+	}
+
+	ref.Key = citationKey(ref, used)
+	return ref
+}
+
+// Year returns the publication year, or the year the bookmark was saved
+func (r Reference) Year() int {
+	if r.Published != nil {
+		return r.Published.Year()
+	}
+	return r.Accessed.Year()
+}
+
+// String formats the reference as a plain text citation, for print views
+func (r Reference) String() string {
+	var b strings.Builder
+	if len(r.Authors) > 0 {
+		fmt.Fprintf(&b, "%s. ", strings.Join(r.Authors, ", "))
+	}
+	if r.Published != nil {
+		fmt.Fprintf(&b, "(%s). ", r.Published.Format("2006, January 2"))
+	}
+	fmt.Fprintf(&b, "%s. %s. %s", strings.TrimSuffix(r.Title, "."), r.Site, r.URL)
+	if !r.Accessed.IsZero() {
+		fmt.Fprintf(&b, " (accessed %s)", r.Accessed.Format("2006-01-02"))
+	}
+	return b.String()
+}
+
+// citationKey builds a key like lovelace2023notes from the first author's
+// last name, or the site, the year and the first word of the title
+func citationKey(ref Reference, used map[string]bool) string {
+	name := ref.Site
+	if len(ref.Authors) > 0 {
+		fields := strings.Fields(ref.Authors[0])
+		if last, _, comma := strings.Cut(ref.Authors[0], ","); comma {
+			name = last
+		} else if len(fields) > 0 {
+			name = fields[len(fields)-1]
+		}
+	} else {
+		name, _, _ = strings.Cut(name, ".")
+	}
+
+	word := ""
+	for _, field := range strings.Fields(ref.Title) {
+		if field = keyPart(field); len(field) > 3 {
+			word = field
+			break
+		}
+	}
+
+	base := fmt.Sprintf("%s%d%s", keyPart(name), ref.Year(), word)
+	if base == "" || !unicode.IsLetter(rune(base[0])) {
+		base = "ref" + base
+	}
+	// Later references with the same key get a letter, like 2023b
+	key := base
+	for n := 1; used[key]; n++ {
+		if n < 26 {
+			key = base + string(rune('a'+n))
+		} else {
+			key = fmt.Sprintf("%s-%d", base, n)
+		}
+	}
+	used[key] = true
+	return key
+}
+
+// keyPart lowercases s and keeps its ASCII letters and digits
+func keyPart(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// references returns the references of every bookmark in the data
+func references(data *Data) []Reference {
+	used := make(map[string]bool, len(data.Bookmarks))
+	refs := make([]Reference, len(data.Bookmarks))
+	for n, bookmark := range data.Bookmarks {
+		refs[n] = ReferenceFor(bookmark, used)
+	}
+	return refs
+}
@@ -0,0 +1,96 @@
+package exporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+func init() {
+	Register("csl-json", Format{
+		Description: "CSL-JSON references for citation processors like Pandoc and citeproc",
+		Extension:   "json",
+		ContentType: "application/vnd.citationstyles.csl+json",
+		Citation:    true,
+		Exporter:    Func(writeCSLJSON),
+	})
+}
+
+// cslItem is a CSL-JSON item of type webpage
+type cslItem struct {
+	ID             string    `json:"id"`
+	Type           string    `json:"type"`
+	Title          string    `json:"title"`
+	Author         []cslName `json:"author,omitempty"`
+	ContainerTitle string    `json:"container-title,omitempty"`
+	Issued         *cslDate  `json:"issued,omitempty"`
+	Accessed       *cslDate  `json:"accessed,omitempty"`
+	URL            string    `json:"URL"`
+	Language       string    `json:"language,omitempty"`
+	Keyword        string    `json:"keyword,omitempty"`
+	Abstract       string    `json:"abstract,omitempty"`
+}
+
+// cslName is a person, or with Literal an organization or a name that
+// can't be split
+type cslName struct {
+	Family  string `json:"family,omitempty"`
+	Given   string `json:"given,omitempty"`
+	Literal string `json:"literal,omitempty"`
+}
+
+type cslDate struct {
+	DateParts [][]int `json:"date-parts"`
+}
+
+// writeCSLJSON writes an array of CSL-JSON items
+func writeCSLJSON(w io.Writer, data *Data) error {
+	items := make([]cslItem, 0, len(data.Bookmarks))
+	for _, ref := range references(data) {
+		item := cslItem{
+			ID:             ref.Key,
+			Type:           "webpage",
+			Title:          ref.Title,
+			ContainerTitle: ref.Site,
+			URL:            ref.URL,
+			Language:       ref.Language,
+			Keyword:        strings.Join(ref.Keywords, ", "),
+			Abstract:       ref.Abstract,
+		}
+		for _, author := range ref.Authors {
+			item.Author = append(item.Author, cslAuthor(author))
+		}
+		if ref.Published != nil {
+			item.Issued = newCSLDate(*ref.Published)
+		}
+		if !ref.Accessed.IsZero() {
+			item.Accessed = newCSLDate(ref.Accessed)
+		}
+		items = append(items, item)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(items); err != nil {
+		return fmt.Errorf("failed to encode CSL-JSON: %w", err)
+	}
+	return nil
+}
+
+// cslAuthor splits "Family, Given" and "Given Family" names
+func cslAuthor(name string) cslName {
+	if family, given, ok := strings.Cut(name, ","); ok {
+		return cslName{Family: strings.TrimSpace(family), Given: strings.TrimSpace(given)}
+	}
+	fields := strings.Fields(name)
+	if len(fields) < 2 {
+		return cslName{Literal: strings.TrimSpace(name)}
+	}
+	return cslName{Family: fields[len(fields)-1], Given: strings.Join(fields[:len(fields)-1], " ")}
+}
+
+func newCSLDate(t time.Time) *cslDate {
+	return &cslDate{DateParts: [][]int{{t.Year(), int(t.Month()), t.Day()}}}
+}
@@ -0,0 +1,56 @@
+package exporter
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+func init() {
+	Register("csv", Format{
+		Description: "Comma separated values, one row per bookmark",
+		Extension:   "csv",
+		ContentType: "text/csv",
+		Exporter:    Func(writeCSV),
+	})
+}
+
+// csvHeader names the columns of a CSV export
+var csvHeader = []string{"id", "url", "title", "description", "tags", "collections", "notes", "language", "status", "created_at"}
+
+// writeCSV writes one row per bookmark. Tags and collection names are
+// joined into single cells so spreadsheets can filter on them
+func writeCSV(w io.Writer, data *Data) error {
+	collections := make(map[uint][]string)
+	for _, collection := range data.Collections {
+		for _, bookmark := range collection.Bookmarks {
+			collections[bookmark.ID] = append(collections[bookmark.ID], collection.Name)
+		}
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+	for _, bookmark := range data.Bookmarks {
+		record := []string{
+			fmt.Sprint(bookmark.ID),
+			bookmark.URL,
+			bookmark.Title,
+			bookmark.Description,
+			strings.Join(bookmark.Tags, ", "),
+			strings.Join(collections[bookmark.ID], "; "),
+			bookmark.Notes,
+			bookmark.Language,
+			bookmark.Status,
+			bookmark.CreatedAt.UTC().Format(time.RFC3339),
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write CSV: %w", err)
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
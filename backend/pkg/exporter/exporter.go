@@ -0,0 +1,473 @@
+// Package exporter is the registry of bookmark export formats. Each format
+// registers itself by name, so new formats can be added without changing
+// the code that runs exports:
+//
+//	func init() {
+//		exporter.Register("csv", exporter.Format{
+//			Description: "Comma separated values",
+//			Extension:   "csv",
+//			ContentType: "text/csv",
+//			Exporter:    exporter.Func(writeCSV),
+//		})
+//	}
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrUnknownFormat is returned when no exporter is registered for a format
+var ErrUnknownFormat = errors.New("unknown export format")
+
+// Data is everything an exporter writes out for one user
+type Data struct {
+	UserID      uint
+	ExportedAt  time.Time
+	Bookmarks   []Bookmark
+	Collections []Collection
+
+	// Root is the collection the data was narrowed to, 0 for everything
+	Root uint
+	// Files are written next to the bookmarks by self-contained formats,
+	// e.g. the screenshots they refer to
+	Files []File
+}
+
+// File is a file of a self-contained export, at a path inside it
+type File struct {
+	Path string
+	Data []byte
+}
+
+// Bookmark is a bookmark as exporters see it. The package keeps its own
+// types rather than the database models so packages the models depend on
+// can run exports too
+type Bookmark struct {
+	ID          uint            `json:"id"`
+	URL         string          `json:"url"`
+	Title       string          `json:"title"`
+	Description string          `json:"description,omitempty"`
+	Favicon     string          `json:"favicon,omitempty"`
+	Screenshot  string          `json:"screenshot,omitempty"`
+	Language    string          `json:"language,omitempty"`
+	Notes       string          `json:"notes,omitempty"` // Markdown
+	Tags        []string        `json:"tags"`
+	Metadata    json.RawMessage `json:"metadata,omitempty"`
+	Status      string          `json:"status"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+
+	// NotesEncrypted marks notes left out because only the user's clients
+	// can decrypt them
+	NotesEncrypted bool `json:"notes_encrypted,omitempty"`
+
+	// Archive and ScreenshotFile are only loaded for exports that ask for
+	// them, see LoadArchives and AttachScreenshot
+	Archive        *ArchivedPage `json:"archive,omitempty"`
+	ScreenshotFile string        `json:"screenshot_file,omitempty"` // path of the screenshot in the export
+}
+
+// ArchivedPage is the latest captured copy of a bookmarked page
+type ArchivedPage struct {
+	Version    int       `json:"version"`
+	URL        string    `json:"url"`
+	Title      string    `json:"title,omitempty"`
+	Content    string    `json:"content"` // extracted text, one block per line
+	StatusCode int       `json:"status_code,omitempty"`
+	CapturedAt time.Time `json:"captured_at"`
+}
+
+// Collection is a collection and the bookmarks in it
+type Collection struct {
+	ID          uint       `json:"id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	ParentID    *uint      `json:"parent_id,omitempty"`
+	Visibility  string     `json:"visibility"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	Bookmarks   []Bookmark `json:"bookmarks"`
+}
+
+// Exporter writes a user's bookmarks in one format
+type Exporter interface {
+	Export(w io.Writer, data *Data) error
+}
+
+// Func adapts a function to the Exporter interface
+type Func func(w io.Writer, data *Data) error
+
+// Export calls f(w, data)
+func (f Func) Export(w io.Writer, data *Data) error {
+	return f(w, data)
+}
+
+// Format describes a registered export format
+type Format struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Extension   string   `json:"extension"` // file extension without the dot
+	ContentType string   `json:"content_type"`
+	Citation    bool     `json:"citation"` // one reference per bookmark, for reference managers
+	Exporter    Exporter `json:"-"`
+}
+
+var (
+	mu      sync.RWMutex
+	formats = make(map[string]Format)
+)
+
+// Register makes an export format available by name. It panics if the name
+// is already taken or the format has no exporter, as both are programming
+// errors caught at startup
+func Register(name string, format Format) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if format.Exporter == nil {
+		panic("exporter: Register " + name + " without an exporter")
+	}
+	if _, dup := formats[name]; dup {
+		panic("exporter: Register called twice for " + name)
+	}
+	format.Name = name
+	formats[name] = format
+}
+
+// Lookup returns the format registered under name
+func Lookup(name string) (Format, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	format, ok := formats[name]
+	if !ok {
+		return Format{}, fmt.Errorf("%w: %s", ErrUnknownFormat, name)
+	}
+	return format, nil
+}
+
+// Formats lists the registered formats by name
+func Formats() []Format {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	list := make([]Format, 0, len(formats))
+	for _, format := range formats {
+		list = append(list, format)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// bookmarkRow is a bookmarks table row
+type bookmarkRow struct {
+	ID          uint
+	URL         string
+	Title       string
+	Description string
+	Favicon     string
+	Screenshot  string
+	Language    string
+	Notes       string
+	Tags        string
+	Metadata    string
+	Status      string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+
+	NotesEncrypted bool
+}
+
+// collectionRow is a collections table row
+type collectionRow struct {
+	ID          uint
+	Name        string
+	Description string
+	ParentID    *uint
+	Visibility  string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// Load reads the bookmarks and collections of a user for export
+func Load(ctx context.Context, db *gorm.DB, userID uint) (*Data, error) {
+	data := &Data{UserID: userID, ExportedAt: time.Now().UTC()}
+
+	var rows []bookmarkRow
+	if err := db.WithContext(ctx).Table("bookmarks").
+		Where("user_id = ? AND deleted_at IS NULL", userID).Order("id").Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch bookmarks: %w", err)
+	}
+
+	byID := make(map[uint]Bookmark, len(rows))
+	data.Bookmarks = make([]Bookmark, 0, len(rows))
+	for _, row := range rows {
+		bookmark := Bookmark{
+			ID:          row.ID,
+			URL:         row.URL,
+			Title:       row.Title,
+			Description: row.Description,
+			Favicon:     row.Favicon,
+			Screenshot:  row.Screenshot,
+			Language:    row.Language,
+			Notes:       row.Notes,
+			Tags:        parseTags(row.Tags),
+			Status:      row.Status,
+			CreatedAt:   row.CreatedAt,
+			UpdatedAt:   row.UpdatedAt,
+		}
+		if row.NotesEncrypted {
+			bookmark.NotesEncrypted = true
+			bookmark.Notes = ""
+		}
+		if json.Valid([]byte(row.Metadata)) {
+			bookmark.Metadata = json.RawMessage(row.Metadata)
+		}
+		byID[row.ID] = bookmark
+		data.Bookmarks = append(data.Bookmarks, bookmark)
+	}
+
+	var collectionRows []collectionRow
+	if err := db.WithContext(ctx).Table("collections").
+		Where("user_id = ? AND deleted_at IS NULL", userID).Order("id").Scan(&collectionRows).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch collections: %w", err)
+	}
+	data.Collections = make([]Collection, len(collectionRows))
+	for n, row := range collectionRows {
+		data.Collections[n] = Collection{
+			ID:          row.ID,
+			Name:        row.Name,
+			Description: row.Description,
+			ParentID:    row.ParentID,
+			Visibility:  row.Visibility,
+			CreatedAt:   row.CreatedAt,
+			UpdatedAt:   row.UpdatedAt,
+		}
+	}
+
+	var links []struct {
+		CollectionID uint
+		BookmarkID   uint
+	}
+	if err := db.WithContext(ctx).Table("bookmark_collections").
+		Select("bookmark_collections.collection_id, bookmark_collections.bookmark_id").
+		Joins("JOIN collections ON collections.id = bookmark_collections.collection_id").
+		Where("collections.user_id = ?", userID).
+		Order("bookmark_collections.collection_id, bookmark_collections.bookmark_id").
+		Scan(&links).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch collection bookmarks: %w", err)
+	}
+
+	index := make(map[uint]int, len(data.Collections))
+	for n := range data.Collections {
+		data.Collections[n].Bookmarks = []Bookmark{}
+		index[data.Collections[n].ID] = n
+	}
+	for _, link := range links {
+		bookmark, ok := byID[link.BookmarkID]
+		if n, found := index[link.CollectionID]; ok && found {
+			data.Collections[n].Bookmarks = append(data.Collections[n].Bookmarks, bookmark)
+		}
+	}
+
+	return data, nil
+}
+
+// Write loads a user's data and writes it in the named format
+func Write(ctx context.Context, db *gorm.DB, userID uint, name string, w io.Writer) error {
+	format, err := Lookup(name)
+	if err != nil {
+		return err
+	}
+
+	data, err := Load(ctx, db, userID)
+	if err != nil {
+		return err
+	}
+
+	return format.Exporter.Export(w, data)
+}
+
+// Uncategorized returns the bookmarks that belong to no collection
+func (d *Data) Uncategorized() []Bookmark {
+	collected := make(map[uint]bool)
+	for _, collection := range d.Collections {
+		for _, bookmark := range collection.Bookmarks {
+			collected[bookmark.ID] = true
+		}
+	}
+
+	result := make([]Bookmark, 0)
+	for _, bookmark := range d.Bookmarks {
+		if !collected[bookmark.ID] {
+			result = append(result, bookmark)
+		}
+	}
+	return result
+}
+
+// Only returns a copy of the data holding just the given bookmarks, in the
+// given order, and the collections that contain any of them
+func (d *Data) Only(ids []uint) *Data {
+	byID := make(map[uint]Bookmark, len(d.Bookmarks))
+	for _, bookmark := range d.Bookmarks {
+		byID[bookmark.ID] = bookmark
+	}
+
+	selected := &Data{UserID: d.UserID, ExportedAt: d.ExportedAt, Bookmarks: []Bookmark{}, Collections: []Collection{}}
+	wanted := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		bookmark, ok := byID[id]
+		if !ok || wanted[id] {
+			continue
+		}
+		wanted[id] = true
+		selected.Bookmarks = append(selected.Bookmarks, bookmark)
+	}
+
+	for _, collection := range d.Collections {
+		bookmarks := []Bookmark{}
+		for _, bookmark := range collection.Bookmarks {
+			if wanted[bookmark.ID] {
+				bookmarks = append(bookmarks, bookmark)
+			}
+		}
+		if len(bookmarks) > 0 {
+			collection.Bookmarks = bookmarks
+			selected.Collections = append(selected.Collections, collection)
+		}
+	}
+	return selected
+}
+
+// InCollection returns a copy of the data holding just one collection and
+// its bookmarks, and false when the user has no such collection
+func (d *Data) InCollection(id uint) (*Data, bool) {
+	for _, collection := range d.Collections {
+		if collection.ID == id {
+			bookmarks := append([]Bookmark{}, collection.Bookmarks...)
+			return &Data{UserID: d.UserID, ExportedAt: d.ExportedAt, Bookmarks: bookmarks, Collections: []Collection{collection}}, true
+		}
+	}
+	return nil, false
+}
+
+// Subtree returns a copy of the data holding just one collection, its
+// descendants when asked for, and their bookmarks. It is false when the
+// user has no such collection
+func (d *Data) Subtree(id uint, descendants bool) (*Data, bool) {
+	included := map[uint]bool{id: true}
+	found := false
+	for _, collection := range d.Collections {
+		found = found || collection.ID == id
+	}
+	if !found {
+		return nil, false
+	}
+
+	// Parents may come after their children, so repeat until nothing is added
+	for added := descendants; added; {
+		added = false
+		for _, collection := range d.Collections {
+			if collection.ParentID != nil && included[*collection.ParentID] && !included[collection.ID] {
+				included[collection.ID] = true
+				added = true
+			}
+		}
+	}
+
+	subtree := &Data{UserID: d.UserID, ExportedAt: d.ExportedAt, Root: id, Bookmarks: []Bookmark{}, Collections: []Collection{}}
+	seen := make(map[uint]bool)
+	for _, collection := range d.Collections {
+		if !included[collection.ID] {
+			continue
+		}
+		subtree.Collections = append(subtree.Collections, collection)
+		for _, bookmark := range collection.Bookmarks {
+			if !seen[bookmark.ID] {
+				seen[bookmark.ID] = true
+				subtree.Bookmarks = append(subtree.Bookmarks, bookmark)
+			}
+		}
+	}
+	return subtree, true
+}
+
+// screenshotExtensions name screenshot files by their content type
+var screenshotExtensions = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/webp": ".webp",
+	"image/gif":  ".gif",
+}
+
+// AttachScreenshot adds the screenshot of a bookmark to the files of the
+// data and points the bookmark at it
+func (d *Data) AttachScreenshot(bookmarkID uint, data []byte, contentType string) {
+	extension, ok := screenshotExtensions[contentType]
+	if !ok {
+		extension = ".png"
+	}
+	path := fmt.Sprintf("screenshots/%d%s", bookmarkID, extension)
+
+	for n := range d.Bookmarks {
+		if d.Bookmarks[n].ID == bookmarkID {
+			d.Bookmarks[n].ScreenshotFile = path
+			d.Files = append(d.Files, File{Path: path, Data: data})
+			return
+		}
+	}
+}
+
+// LoadArchives adds the latest archived copy of each bookmark to the data
+func LoadArchives(ctx context.Context, db *gorm.DB, data *Data) error {
+	if len(data.Bookmarks) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(data.Bookmarks))
+	for n, bookmark := range data.Bookmarks {
+		ids[n] = bookmark.ID
+	}
+
+	var rows []struct {
+		BookmarkID uint
+		ArchivedPage
+	}
+	if err := db.WithContext(ctx).Table("archive_snapshots").
+		Select("bookmark_id, version, url, title, content, status_code, captured_at").
+		Where("user_id = ? AND bookmark_id IN ? AND deleted_at IS NULL", data.UserID, ids).
+		Order("bookmark_id, version DESC").Scan(&rows).Error; err != nil {
+		return fmt.Errorf("failed to fetch archived copies: %w", err)
+	}
+
+	latest := make(map[uint]*ArchivedPage, len(rows))
+	for n := range rows {
+		if _, ok := latest[rows[n].BookmarkID]; !ok {
+			latest[rows[n].BookmarkID] = &rows[n].ArchivedPage
+		}
+	}
+	for n := range data.Bookmarks {
+		data.Bookmarks[n].Archive = latest[data.Bookmarks[n].ID]
+	}
+	return nil
+}
+
+// parseTags decodes a bookmark's JSON tags
+func parseTags(raw string) []string {
+	list := []string{}
+	if raw != "" {
+		if err := json.Unmarshal([]byte(raw), &list); err != nil || list == nil {
+			return []string{}
+		}
+	}
+	return list
+}
@@ -0,0 +1,240 @@
+package exporter
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testData() *Data {
+	goDev := Bookmark{ID: 1, URL: "https://go.dev", Title: "Go [site]", Tags: []string{"dev/go", "go lang"}, Notes: "Start with the tour"}
+	mdn := Bookmark{ID: 2, URL: "https://developer.mozilla.org", Title: "MDN", Tags: []string{}}
+	return &Data{
+		UserID:      1,
+		ExportedAt:  time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		Bookmarks:   []Bookmark{goDev, mdn},
+		Collections: []Collection{{ID: 1, Name: "Languages", Bookmarks: []Bookmark{goDev}}},
+	}
+}
+
+func export(t *testing.T, name string, data *Data) string {
+	format, err := Lookup(name)
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, format.Exporter.Export(&buf, data))
+	return buf.String()
+}
+
+func TestRegistry(t *testing.T) {
+	names := []string{}
+	for _, format := range Formats() {
+		names = append(names, format.Name)
+	}
+	assert.Equal(t, []string{"archive", "bibtex", "csl-json", "csv", "html", "json", "markdown", "obsidian", "org", "ris"}, names)
+
+	_, err := Lookup("pdf")
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+
+	assert.Panics(t, func() { Register("json", Format{Exporter: Func(writeJSON)}) })
+	assert.Panics(t, func() { Register("empty", Format{}) })
+}
+
+func TestUncategorized(t *testing.T) {
+	uncategorized := testData().Uncategorized()
+	require.Len(t, uncategorized, 1)
+	assert.Equal(t, "MDN", uncategorized[0].Title)
+}
+
+func TestOnly(t *testing.T) {
+	only := testData().Only([]uint{2, 1, 2, 9})
+	require.Len(t, only.Bookmarks, 2)
+	assert.Equal(t, "MDN", only.Bookmarks[0].Title, "keeps the requested order")
+	require.Len(t, only.Collections, 1)
+
+	only = testData().Only([]uint{2})
+	assert.Len(t, only.Bookmarks, 1)
+	assert.Empty(t, only.Collections)
+}
+
+func TestInCollection(t *testing.T) {
+	collection, ok := testData().InCollection(1)
+	require.True(t, ok)
+	require.Len(t, collection.Bookmarks, 1)
+	assert.Equal(t, "Go [site]", collection.Bookmarks[0].Title)
+	assert.Len(t, collection.Collections, 1)
+
+	_, ok = testData().InCollection(2)
+	assert.False(t, ok)
+}
+
+func TestCSV(t *testing.T) {
+	out := export(t, "csv", testData())
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, "id,url,title,description,tags,collections,notes,language,status,created_at", lines[0])
+	assert.Equal(t, `1,https://go.dev,Go [site],,"dev/go, go lang",Languages,Start with the tour,,,0001-01-01T00:00:00Z`, lines[1])
+}
+
+func TestMarkdown(t *testing.T) {
+	out := export(t, "markdown", testData())
+	assert.Contains(t, out, "## Languages\n\n- [Go \\[site\\]](<https://go.dev>) `dev/go` `go lang`\n  > Start with the tour\n")
+	assert.Contains(t, out, "## Uncategorized\n\n- [MDN](<https://developer.mozilla.org>)\n")
+}
+
+func TestOrg(t *testing.T) {
+	out := export(t, "org", testData())
+	assert.Contains(t, out, "* Languages\n** [[https://go.dev][Go {site}]] :dev_go:go_lang:\n")
+	assert.Contains(t, out, "* Uncategorized\n** [[https://developer.mozilla.org][MDN]]\n")
+}
+
+func TestObsidian(t *testing.T) {
+	out := export(t, "obsidian", testData())
+	archive, err := zip.NewReader(strings.NewReader(out), int64(len(out)))
+	require.NoError(t, err)
+
+	files := map[string]string{}
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		files[file.Name] = string(content)
+	}
+
+	require.Contains(t, files, "Bookmarks/Languages/Go site.md")
+	assert.Contains(t, files["Bookmarks/Languages/Go site.md"], "url: \"https://go.dev\"\ntags:\n  - \"dev/go\"\n  - \"go-lang\"\n")
+	assert.Contains(t, files, "Bookmarks/Unsorted/MDN.md")
+}
+
+func TestUniqueName(t *testing.T) {
+	used := map[string]bool{}
+	assert.Equal(t, "a/Note.md", uniqueName(used, "a", "Note", ".md"))
+	assert.Equal(t, "a/note 2.md", uniqueName(used, "a", "note", ".md"))
+}
+
+// citationData has a bookmark with page metadata and two that share a key
+func citationData() *Data {
+	saved := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	return &Data{Bookmarks: []Bookmark{
+		{
+			ID: 1, URL: "https://example.com/notes?a=1&b={2}", Title: "Notes on the Analytical Engine",
+			Tags: []string{"history", "c_s"}, Language: "en", CreatedAt: saved,
+			Metadata: []byte(`{"author":"Ada Lovelace","authors":["Babbage, Charles"],"published_at":"1843-09-01","site_name":"Scientific Memoirs"}`),
+		},
+		{ID: 2, URL: "https://www.go.dev/doc", Title: "Go Documentation", Description: "50% of {the} docs", CreatedAt: saved},
+		{ID: 3, URL: "https://go.dev/tour", Title: "Go Documentation", CreatedAt: saved},
+	}}
+}
+
+func TestReferenceFor(t *testing.T) {
+	refs := references(citationData())
+	require.Len(t, refs, 3)
+	assert.Equal(t, "lovelace1843notes", refs[0].Key)
+	assert.Equal(t, []string{"Ada Lovelace", "Babbage, Charles"}, refs[0].Authors)
+	assert.Equal(t, "Scientific Memoirs", refs[0].Site)
+	assert.Equal(t, "go2024documentation", refs[1].Key)
+	assert.Equal(t, "go.dev", refs[1].Site)
+	assert.Equal(t, "go2024documentationb", refs[2].Key)
+	assert.Equal(t, "Ada Lovelace, Babbage, Charles. (1843, September 1). Notes on the Analytical Engine. Scientific Memoirs. https://example.com/notes?a=1&b={2} (accessed 2024-05-01)", refs[0].String())
+}
+
+func TestBibTeX(t *testing.T) {
+	out := export(t, "bibtex", citationData())
+	assert.Contains(t, out, `@online{lovelace1843notes,
+  title = {{Notes on the Analytical Engine}},
+  author = {Ada Lovelace and Babbage, Charles},
+  organization = {Scientific Memoirs},
+  date = {1843-09-01},
+  year = {1843},
+  url = {https://example.com/notes?a=1&b=%7B2%7D},
+  urldate = {2024-05-01},
+  langid = {en},
+  keywords = {history, c\_s},
+}
+`)
+	assert.Contains(t, out, `abstract = {50\% of \{the\} docs},`)
+	assert.Contains(t, out, "@online{go2024documentationb,")
+}
+
+func TestRIS(t *testing.T) {
+	out := export(t, "ris", citationData())
+	assert.True(t, strings.HasPrefix(out, "TY  - ELEC\r\nID  - lovelace1843notes\r\nTI  - Notes on the Analytical Engine\r\nAU  - Ada Lovelace\r\nAU  - Babbage, Charles\r\nPY  - 1843\r\nDA  - 1843/09/01\r\n"))
+	assert.Contains(t, out, "Y2  - 2024/05/01\r\nLA  - en\r\nKW  - history\r\nKW  - c_s\r\nER  - \r\n")
+	assert.Equal(t, 3, strings.Count(out, "ER  - "))
+}
+
+func TestCSLJSON(t *testing.T) {
+	var items []map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(export(t, "csl-json", citationData())), &items))
+	require.Len(t, items, 3)
+	assert.Equal(t, "lovelace1843notes", items[0]["id"])
+	assert.Equal(t, "webpage", items[0]["type"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"family": "Lovelace", "given": "Ada"},
+		map[string]interface{}{"family": "Babbage", "given": "Charles"},
+	}, items[0]["author"])
+	assert.Equal(t, map[string]interface{}{"date-parts": []interface{}{[]interface{}{1843.0, 9.0, 1.0}}}, items[0]["issued"])
+	assert.NotContains(t, items[1], "issued")
+	assert.Equal(t, "go.dev", items[1]["container-title"])
+}
+
+func TestSubtree(t *testing.T) {
+	parent := uint(1)
+	data := testData()
+	data.Collections = append(data.Collections,
+		Collection{ID: 3, Name: "Web", ParentID: &parent, Bookmarks: []Bookmark{data.Bookmarks[1]}},
+		Collection{ID: 4, Name: "Other"},
+	)
+
+	subtree, ok := data.Subtree(1, false)
+	require.True(t, ok)
+	assert.Equal(t, uint(1), subtree.Root)
+	assert.Len(t, subtree.Collections, 1)
+	assert.Len(t, subtree.Bookmarks, 1)
+
+	subtree, ok = data.Subtree(1, true)
+	require.True(t, ok)
+	assert.Len(t, subtree.Collections, 2)
+	assert.Len(t, subtree.Bookmarks, 2)
+
+	_, ok = data.Subtree(9, true)
+	assert.False(t, ok)
+}
+
+func TestArchive(t *testing.T) {
+	parent := uint(1)
+	data := testData()
+	data.Collections = append(data.Collections, Collection{ID: 3, Name: "Web", ParentID: &parent, Bookmarks: []Bookmark{data.Bookmarks[1]}})
+	data, ok := data.Subtree(1, true)
+	require.True(t, ok)
+	data.Bookmarks[0].Archive = &ArchivedPage{Version: 1, URL: "https://go.dev", Content: "The Go programming language"}
+	data.AttachScreenshot(2, []byte("jpeg"), "image/jpeg")
+
+	out := export(t, "archive", data)
+	archive, err := zip.NewReader(strings.NewReader(out), int64(len(out)))
+	require.NoError(t, err)
+	require.Len(t, archive.File, 2)
+	assert.Equal(t, ArchiveManifest, archive.File[0].Name)
+	assert.Equal(t, "screenshots/2.jpg", archive.File[1].Name)
+
+	file, err := archive.File[0].Open()
+	require.NoError(t, err)
+	defer file.Close()
+	var manifest Manifest
+	require.NoError(t, json.NewDecoder(file).Decode(&manifest))
+	assert.Equal(t, ArchiveVersion, manifest.Version)
+	assert.Equal(t, uint(1), manifest.Root)
+	require.Len(t, manifest.Collections, 2)
+	assert.Nil(t, manifest.Collections[0].ParentID, "the exported collection becomes top-level")
+	assert.Equal(t, &parent, manifest.Collections[1].ParentID)
+	assert.Equal(t, []uint{2}, manifest.Collections[1].BookmarkIDs)
+	assert.Equal(t, "The Go programming language", manifest.Bookmarks[0].Archive.Content)
+	assert.Equal(t, "screenshots/2.jpg", manifest.Bookmarks[1].ScreenshotFile)
+}
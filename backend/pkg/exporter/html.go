@@ -0,0 +1,70 @@
+package exporter
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+func init() {
+	Register("html", Format{
+		Description: "Netscape bookmark file, importable by every browser",
+		Extension:   "html",
+		ContentType: "text/html",
+		Exporter:    Func(writeHTML),
+	})
+}
+
+// writeHTML writes a Netscape bookmark file with one folder per collection
+func writeHTML(w io.Writer, data *Data) error {
+	fmt.Fprintf(w, `<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=UTF-8">
+<TITLE>Bookmarks</TITLE>
+<H1>Bookmarks Menu</H1>
+<DL><p>
+`)
+
+	for _, collection := range data.Collections {
+		fmt.Fprintf(w, `    <DT><H3 ADD_DATE="%d" LAST_MODIFIED="%d">%s</H3>
+    <DL><p>
+`, collection.CreatedAt.Unix(), collection.UpdatedAt.Unix(), escapeHTML(collection.Name))
+
+		for _, bookmark := range collection.Bookmarks {
+			fmt.Fprintf(w, `        <DT><A HREF="%s" ADD_DATE="%d">%s</A>
+`, escapeHTML(bookmark.URL), bookmark.CreatedAt.Unix(), escapeHTML(bookmark.Title))
+			writeNotes(w, "        ", bookmark.Notes)
+		}
+
+		fmt.Fprintf(w, `    </DL><p>
+`)
+	}
+
+	for _, bookmark := range data.Uncategorized() {
+		fmt.Fprintf(w, `    <DT><A HREF="%s" ADD_DATE="%d">%s</A>
+`, escapeHTML(bookmark.URL), bookmark.CreatedAt.Unix(), escapeHTML(bookmark.Title))
+		writeNotes(w, "    ", bookmark.Notes)
+	}
+
+	_, err := fmt.Fprintf(w, `</DL><p>
+`)
+	return err
+}
+
+// writeNotes writes a bookmark's notes as the <DD> description browsers
+// show for an entry
+func writeNotes(w io.Writer, indent, notes string) {
+	if notes == "" {
+		return
+	}
+	fmt.Fprintf(w, "%s<DD>%s\n", indent, escapeHTML(notes))
+}
+
+// escapeHTML escapes HTML special characters
+func escapeHTML(s string) string {
+	s = strings.ReplaceAll(s, "&", "&amp;")
+	s = strings.ReplaceAll(s, "<", "&lt;")
+	s = strings.ReplaceAll(s, ">", "&gt;")
+	s = strings.ReplaceAll(s, "\"", "&quot;")
+	s = strings.ReplaceAll(s, "'", "&#39;")
+	return s
+}
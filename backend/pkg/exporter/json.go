@@ -0,0 +1,40 @@
+package exporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+func init() {
+	Register("json", Format{
+		Description: "Full JSON export of bookmarks and collections",
+		Extension:   "json",
+		ContentType: "application/json",
+		Exporter:    Func(writeJSON),
+	})
+}
+
+// writeJSON writes every bookmark and collection with all their fields
+func writeJSON(w io.Writer, data *Data) error {
+	exportData := map[string]interface{}{
+		"version":     "1.0",
+		"exported_at": data.ExportedAt.Format(time.RFC3339),
+		"user_id":     data.UserID,
+		"bookmarks":   data.Bookmarks,
+		"collections": data.Collections,
+		"metadata": map[string]interface{}{
+			"total_bookmarks":   len(data.Bookmarks),
+			"total_collections": len(data.Collections),
+			"export_format":     "json",
+		},
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(exportData); err != nil {
+		return fmt.Errorf("failed to encode JSON: %w", err)
+	}
+	return nil
+}
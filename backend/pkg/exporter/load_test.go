@@ -0,0 +1,79 @@
+package exporter_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bookmark-sync-service/backend/pkg/database"
+	"bookmark-sync-service/backend/pkg/exporter"
+	"bookmark-sync-service/backend/pkg/testfactory"
+)
+
+// Load is tested from outside the package because the database package
+// depends, indirectly, on the exporter
+
+func TestLoad(t *testing.T) {
+	db := testfactory.NewDB(t)
+	f := testfactory.New(t, db)
+
+	user := f.User()
+	bookmark := f.Bookmark(user.ID, func(b *database.Bookmark) {
+		b.URL = "https://go.dev"
+		b.Tags = `["golang"]`
+		b.Metadata = `{"summary":"Go"}`
+	})
+	f.Bookmark(user.ID)
+	f.AddToCollection(f.Collection(user.ID), bookmark)
+
+	data, err := exporter.Load(context.Background(), db, user.ID)
+	require.NoError(t, err)
+	require.Len(t, data.Bookmarks, 2)
+	assert.Equal(t, []string{"golang"}, data.Bookmarks[0].Tags)
+	assert.JSONEq(t, `{"summary":"Go"}`, string(data.Bookmarks[0].Metadata))
+	require.Len(t, data.Collections, 1)
+	require.Len(t, data.Collections[0].Bookmarks, 1)
+	assert.Equal(t, "https://go.dev", data.Collections[0].Bookmarks[0].URL)
+
+	var buf bytes.Buffer
+	require.NoError(t, exporter.Write(context.Background(), db, user.ID, "json", &buf))
+	var exported map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &exported))
+	assert.Len(t, exported["bookmarks"], 2)
+}
+
+func TestLoad_EncryptedNotes(t *testing.T) {
+	db := testfactory.NewDB(t)
+	f := testfactory.New(t, db)
+
+	user := f.User()
+	f.Bookmark(user.ID, func(b *database.Bookmark) {
+		b.Notes = "c2VjcmV0"
+		b.NotesEncrypted = true
+		b.NotesKeyHint = "vault-v1"
+	})
+
+	data, err := exporter.Load(context.Background(), db, user.ID)
+	require.NoError(t, err)
+	require.Len(t, data.Bookmarks, 1)
+	assert.Empty(t, data.Bookmarks[0].Notes)
+	assert.True(t, data.Bookmarks[0].NotesEncrypted)
+}
+
+func BenchmarkLoad(b *testing.B) {
+	db := testfactory.NewDB(b)
+	dataset, err := testfactory.Seed(db, testfactory.Volume{Users: 1, BookmarksPerUser: 2000, CollectionsPerUser: 20, RandSeed: 1})
+	require.NoError(b, err)
+	userID := dataset.Users[0].ID
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := exporter.Load(context.Background(), db, userID); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package exporter
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+func init() {
+	Register("markdown", Format{
+		Description: "Markdown lists, one section per collection",
+		Extension:   "md",
+		ContentType: "text/markdown",
+		Exporter:    Func(writeMarkdown),
+	})
+}
+
+// markdownEscaper escapes characters that would end a link text early
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`)
+
+// writeMarkdown writes a list of links per collection, then the bookmarks
+// in no collection
+func writeMarkdown(w io.Writer, data *Data) error {
+	fmt.Fprintf(w, "# Bookmarks\n\nExported %s\n", data.ExportedAt.Format("2006-01-02"))
+
+	for _, collection := range data.Collections {
+		fmt.Fprintf(w, "\n## %s\n\n", strings.TrimSpace(collection.Name))
+		if collection.Description != "" {
+			fmt.Fprintf(w, "%s\n\n", collection.Description)
+		}
+		writeMarkdownList(w, collection.Bookmarks)
+	}
+
+	if uncategorized := data.Uncategorized(); len(uncategorized) > 0 {
+		fmt.Fprint(w, "\n## Uncategorized\n\n")
+		writeMarkdownList(w, uncategorized)
+	}
+	return nil
+}
+
+// writeMarkdownList writes bookmarks as list items, notes quoted below them
+func writeMarkdownList(w io.Writer, bookmarks []Bookmark) {
+	for _, bookmark := range bookmarks {
+		fmt.Fprintf(w, "- [%s](<%s>)", markdownEscaper.Replace(bookmark.Title), bookmark.URL)
+		for _, tag := range bookmark.Tags {
+			fmt.Fprintf(w, " `%s`", tag)
+		}
+		fmt.Fprintln(w)
+
+		if notes := strings.TrimSpace(bookmark.Notes); notes != "" {
+			for _, line := range strings.Split(notes, "\n") {
+				fmt.Fprintf(w, "  > %s\n", line)
+			}
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package exporter
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	Register("obsidian", Format{
+		Description: "Obsidian vault: a folder per collection and a note per bookmark, zipped",
+		Extension:   "zip",
+		ContentType: "application/zip",
+		Exporter:    Func(writeObsidian),
+	})
+}
+
+// obsidianNameInvalid lists characters Obsidian rejects in note and folder names
+var obsidianNameInvalid = strings.NewReplacer(
+	"/", " ", `\`, " ", ":", " ", "*", " ", "?", " ", `"`, " ",
+	"<", " ", ">", " ", "|", " ", "#", " ", "^", " ", "[", " ", "]", " ",
+)
+
+// maxObsidianName keeps note names well under file system limits
+const maxObsidianName = 100
+
+// writeObsidian writes a zipped vault. Bookmarks in several collections get a
+// note in each folder; bookmarks in none go to Unsorted
+func writeObsidian(w io.Writer, data *Data) error {
+	archive := zip.NewWriter(w)
+	used := make(map[string]bool)
+
+	for _, collection := range data.Collections {
+		folder := uniqueName(used, "Bookmarks", obsidianName(collection.Name, "Collection"), "")
+		for _, bookmark := range collection.Bookmarks {
+			if err := writeObsidianNote(archive, used, folder, bookmark); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, bookmark := range data.Uncategorized() {
+		if err := writeObsidianNote(archive, used, "Bookmarks/Unsorted", bookmark); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+// writeObsidianNote writes a bookmark note with its URL, tags and add date
+// as front matter
+func writeObsidianNote(archive *zip.Writer, used map[string]bool, folder string, bookmark Bookmark) error {
+	name := uniqueName(used, folder, obsidianName(bookmark.Title, "Untitled"), ".md")
+	file, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: bookmark.UpdatedAt})
+	if err != nil {
+		return fmt.Errorf("failed to add %s: %w", name, err)
+	}
+
+	var note strings.Builder
+	note.WriteString("---\n")
+	fmt.Fprintf(&note, "url: %s\n", strconv.Quote(bookmark.URL))
+	if tags := bookmark.Tags; len(tags) > 0 {
+		note.WriteString("tags:\n")
+		for _, tag := range tags {
+			fmt.Fprintf(&note, "  - %s\n", strconv.Quote(strings.ReplaceAll(tag, " ", "-")))
+		}
+	}
+	fmt.Fprintf(&note, "created: %s\n", bookmark.CreatedAt.UTC().Format(time.RFC3339))
+	note.WriteString("---\n\n")
+	fmt.Fprintf(&note, "# %s\n\n<%s>\n", bookmark.Title, bookmark.URL)
+	if bookmark.Description != "" {
+		fmt.Fprintf(&note, "\n%s\n", bookmark.Description)
+	}
+	if notes := strings.TrimSpace(bookmark.Notes); notes != "" {
+		fmt.Fprintf(&note, "\n%s\n", notes)
+	}
+
+	_, err = io.WriteString(file, note.String())
+	return err
+}
+
+// obsidianName turns a title into a valid note or folder name
+func obsidianName(title, fallback string) string {
+	name := strings.Join(strings.Fields(obsidianNameInvalid.Replace(title)), " ")
+	name = strings.Trim(name, ".")
+	if runes := []rune(name); len(runes) > maxObsidianName {
+		name = strings.TrimSpace(string(runes[:maxObsidianName]))
+	}
+	if name == "" {
+		return fallback
+	}
+	return name
+}
+
+// uniqueName joins a name into folder, numbering it when the path is taken
+func uniqueName(used map[string]bool, folder, name, extension string) string {
+	candidate := path.Join(folder, name) + extension
+	for n := 2; used[strings.ToLower(candidate)]; n++ {
+		candidate = path.Join(folder, fmt.Sprintf("%s %d", name, n)) + extension
+	}
+	used[strings.ToLower(candidate)] = true
+	return candidate
+}
@@ -0,0 +1,62 @@
+package exporter
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+func init() {
+	Register("org", Format{
+		Description: "Emacs Org-mode outline, one heading per collection",
+		Extension:   "org",
+		ContentType: "text/org",
+		Exporter:    Func(writeOrg),
+	})
+}
+
+// orgTagInvalid matches characters Org-mode doesn't allow in tags
+var orgTagInvalid = regexp.MustCompile(`[^\p{L}\p{N}_@#%]`)
+
+// orgLinkEscaper keeps titles from closing an Org link early
+var orgLinkEscaper = strings.NewReplacer("[", "{", "]", "}")
+
+// writeOrg writes a top-level heading per collection with a link heading
+// per bookmark, tagged and carrying the add date as a property
+func writeOrg(w io.Writer, data *Data) error {
+	fmt.Fprintf(w, "#+TITLE: Bookmarks\n#+DATE: [%s]\n", data.ExportedAt.Format("2006-01-02 Mon"))
+
+	for _, collection := range data.Collections {
+		fmt.Fprintf(w, "\n* %s\n", strings.TrimSpace(collection.Name))
+		if collection.Description != "" {
+			fmt.Fprintf(w, "%s\n", collection.Description)
+		}
+		writeOrgEntries(w, collection.Bookmarks)
+	}
+
+	if uncategorized := data.Uncategorized(); len(uncategorized) > 0 {
+		fmt.Fprint(w, "\n* Uncategorized\n")
+		writeOrgEntries(w, uncategorized)
+	}
+	return nil
+}
+
+// writeOrgEntries writes second-level headings for bookmarks
+func writeOrgEntries(w io.Writer, bookmarks []Bookmark) {
+	for _, bookmark := range bookmarks {
+		fmt.Fprintf(w, "** [[%s][%s]]", bookmark.URL, orgLinkEscaper.Replace(bookmark.Title))
+		if len(bookmark.Tags) > 0 {
+			tags := make([]string, len(bookmark.Tags))
+			for n, tag := range bookmark.Tags {
+				tags[n] = orgTagInvalid.ReplaceAllString(tag, "_")
+			}
+			fmt.Fprintf(w, " :%s:", strings.Join(tags, ":"))
+		}
+		fmt.Fprintf(w, "\n:PROPERTIES:\n:ADDED: [%s]\n:END:\n", bookmark.CreatedAt.Format("2006-01-02 Mon 15:04"))
+
+		if notes := strings.TrimSpace(bookmark.Notes); notes != "" {
+			fmt.Fprintf(w, "%s\n", notes)
+		}
+	}
+}
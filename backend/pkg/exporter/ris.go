@@ -0,0 +1,54 @@
+package exporter
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+func init() {
+	Register("ris", Format{
+		Description: "RIS references for Zotero, EndNote and Mendeley",
+		Extension:   "ris",
+		ContentType: "application/x-research-info-systems",
+		Citation:    true,
+		Exporter:    Func(writeRIS),
+	})
+}
+
+// writeRIS writes an ELEC record per bookmark. RIS is line based, so values
+// are folded to one line
+func writeRIS(w io.Writer, data *Data) error {
+	for _, ref := range references(data) {
+		tag := func(name, value string) {
+			if value = strings.Join(strings.Fields(value), " "); value != "" {
+				fmt.Fprintf(w, "%s  - %s\r\n", name, value)
+			}
+		}
+
+		tag("TY", "ELEC")
+		tag("ID", ref.Key)
+		tag("TI", ref.Title)
+		for _, author := range ref.Authors {
+			tag("AU", author)
+		}
+		tag("PY", fmt.Sprint(ref.Year()))
+		if ref.Published != nil {
+			tag("DA", ref.Published.Format("2006/01/02"))
+		}
+		tag("T2", ref.Site)
+		tag("UR", ref.URL)
+		if !ref.Accessed.IsZero() {
+			tag("Y2", ref.Accessed.Format("2006/01/02"))
+		}
+		tag("LA", ref.Language)
+		for _, keyword := range ref.Keywords {
+			tag("KW", keyword)
+		}
+		tag("AB", ref.Abstract)
+		if _, err := fmt.Fprint(w, "ER  - \r\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package language
+
+import (
+	"strings"
+	"unicode"
+)
+
+// minDetectWords is the fewest stopword hits needed to trust a Latin-script guess
+const minDetectWords = 2
+
+// scripts maps Unicode scripts used by a single language to its code
+var scripts = []struct {
+	table *unicode.RangeTable
+	code  string
+}{
+	{unicode.Hiragana, "ja"},
+	{unicode.Katakana, "ja"},
+	{unicode.Hangul, "ko"},
+	{unicode.Han, "zh"},
+	{unicode.Cyrillic, "ru"},
+	{unicode.Arabic, "ar"},
+	{unicode.Hebrew, "he"},
+	{unicode.Greek, "el"},
+	{unicode.Thai, "th"},
+	{unicode.Devanagari, "hi"},
+}
+
+// stopwords lists frequent function words of Latin-script languages
+var stopwords = map[string][]string{
+	"en": {"the", "and", "of", "to", "is", "in", "that", "for", "with", "this", "are", "you", "how", "what"},
+	"es": {"el", "los", "las", "del", "que", "y", "en", "por", "para", "una", "con", "es", "como", "pero"},
+	"fr": {"le", "les", "des", "et", "est", "une", "du", "dans", "pour", "que", "qui", "avec", "sur", "pas"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "mit", "ein", "eine", "für", "auf", "den", "zu", "wie"},
+	"it": {"il", "gli", "della", "che", "e", "di", "per", "una", "con", "non", "sono", "come", "nel", "anche"},
+	"pt": {"os", "as", "do", "da", "que", "e", "em", "para", "uma", "com", "não", "como", "mais", "por"},
+	"nl": {"de", "het", "een", "en", "van", "is", "dat", "niet", "op", "met", "voor", "zijn", "ook", "wat"},
+}
+
+var stopwordIndex = buildStopwordIndex()
+
+func buildStopwordIndex() map[string][]string {
+	index := make(map[string][]string)
+	for code, words := range stopwords {
+		for _, word := range words {
+			index[word] = append(index[word], code)
+		}
+	}
+	return index
+}
+
+// Normalize reduces a language tag such as "en-US" or "zh_TW" to its primary
+// ISO 639 subtag ("en", "zh"). Invalid tags return ""
+func Normalize(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		tag = tag[:i]
+	}
+	if len(tag) < 2 || len(tag) > 3 {
+		return ""
+	}
+	for _, r := range tag {
+		if r < 'a' || r > 'z' {
+			return ""
+		}
+	}
+	return tag
+}
+
+// NormalizeAll normalizes a list of tags, dropping invalid and duplicate entries
+func NormalizeAll(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	result := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if code := Normalize(tag); code != "" && !seen[code] {
+			seen[code] = true
+			result = append(result, code)
+		}
+	}
+	return result
+}
+
+// ParseList parses a comma separated list of language tags such as "en,fr"
+func ParseList(list string) []string {
+	if strings.TrimSpace(list) == "" {
+		return nil
+	}
+	return NormalizeAll(strings.Split(list, ","))
+}
+
+// Detect guesses the language of a text from its script, or from common
+// function words for Latin-script languages. It returns "" when unsure
+func Detect(text string) string {
+	counts := make(map[string]int)
+	letters := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		for _, script := range scripts {
+			if unicode.Is(script.table, r) {
+				counts[script.code]++
+				break
+			}
+		}
+	}
+	if letters == 0 {
+		return ""
+	}
+
+	// Japanese text mixes kana with Han characters, so any kana wins over "zh"
+	if counts["ja"] > 0 && counts["ja"]*10 >= counts["zh"] {
+		counts["ja"] += counts["zh"]
+		delete(counts, "zh")
+	}
+	if code, count := best(counts); count*2 >= letters {
+		return code
+	}
+
+	hits := make(map[string]int)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	}) {
+		for _, code := range stopwordIndex[word] {
+			hits[code]++
+		}
+	}
+	if code, count := best(hits); count >= minDetectWords {
+		return code
+	}
+
+	return ""
+}
+
+// Resolve prefers a declared language tag, such as an HTML lang attribute,
+// and falls back to detecting the language of the text
+func Resolve(declared, text string) string {
+	if code := Normalize(declared); code != "" {
+		return code
+	}
+	return Detect(text)
+}
+
+// best returns the key with the highest count, breaking ties alphabetically
+func best(counts map[string]int) (string, int) {
+	bestCode, bestCount := "", 0
+	for code, count := range counts {
+		if count > bestCount || (count == bestCount && code < bestCode) {
+			bestCode, bestCount = code, count
+		}
+	}
+	return bestCode, bestCount
+}
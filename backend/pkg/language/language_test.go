@@ -0,0 +1,45 @@
+package language
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	assert.Equal(t, "en", Normalize("en-US"))
+	assert.Equal(t, "zh", Normalize("zh_TW"))
+	assert.Equal(t, "fr", Normalize(" FR "))
+	assert.Equal(t, "", Normalize("english"))
+	assert.Equal(t, "", Normalize("e1"))
+	assert.Equal(t, "", Normalize(""))
+}
+
+func TestParseList(t *testing.T) {
+	assert.Equal(t, []string{"en", "fr"}, ParseList("en, fr-CA, en, bogus-value"))
+	assert.Nil(t, ParseList(" "))
+}
+
+func TestDetect(t *testing.T) {
+	tests := map[string]string{
+		"How to write a web server with Go and the standard library":         "en",
+		"Cómo escribir un servidor web con Go y la biblioteca para todos":    "es",
+		"Comment écrire un serveur web avec Go et la bibliothèque standard":  "fr",
+		"Wie man einen Webserver mit Go und der Standardbibliothek schreibt": "de",
+		"如何使用標準庫編寫網頁伺服器":                                                     "zh",
+		"標準ライブラリでウェブサーバーを書く方法":                                               "ja",
+		"표준 라이브러리로 웹 서버 작성하기":                                                "ko",
+		"Как написать веб-сервер на Go":                                      "ru",
+		"Go": "",
+		"":   "",
+	}
+
+	for text, expected := range tests {
+		assert.Equal(t, expected, Detect(text), text)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	assert.Equal(t, "de", Resolve("de-DE", "The quick brown fox and the dog"))
+	assert.Equal(t, "en", Resolve("", "The quick brown fox and the dog"))
+}
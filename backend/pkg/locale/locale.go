@@ -0,0 +1,197 @@
+// Package locale renders generated content (feeds, reports) in a user's
+// timezone and date format instead of the server's
+package locale
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DefaultTimezone is used for users without a valid timezone preference
+const DefaultTimezone = "UTC"
+
+// Date format preferences
+const (
+	DateFormatISO = "YYYY-MM-DD"
+	DateFormatUS  = "MM/DD/YYYY"
+	DateFormatEU  = "DD/MM/YYYY"
+)
+
+// Time format preferences
+const (
+	TimeFormat24h = "24h"
+	TimeFormat12h = "12h"
+)
+
+// maxOffsetHours is the largest UTC offset in use (Line Islands, UTC+14)
+const maxOffsetHours = 14
+
+// ErrInvalidTimezone is returned for timezones that are neither a tz
+// database name nor a UTC offset
+var ErrInvalidTimezone = errors.New("invalid timezone")
+
+var dateLayouts = map[string]string{
+	DateFormatISO: "2006-01-02",
+	DateFormatUS:  "01/02/2006",
+	DateFormatEU:  "02/01/2006",
+}
+
+var timeLayouts = map[string]string{
+	TimeFormat24h: "15:04",
+	TimeFormat12h: "3:04 PM",
+}
+
+// offsetPattern matches UTC offsets such as "+08:00", "UTC-5" or "GMT+05:30"
+var offsetPattern = regexp.MustCompile(`^(?i:UTC|GMT)?([+-])(\d{1,2})(?::?(\d{2}))?$`)
+
+// LoadLocation parses a timezone preference, either a tz database name such
+// as "Asia/Taipei" or a UTC offset such as "UTC+8". An empty name is UTC
+func LoadLocation(name string) (*time.Location, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return time.UTC, nil
+	}
+	// "Local" is whatever the server runs in, which is what users opt out of
+	if strings.EqualFold(name, "Local") {
+		return nil, fmt.Errorf("%w %q: the server timezone cannot be chosen", ErrInvalidTimezone, name)
+	}
+
+	if match := offsetPattern.FindStringSubmatch(name); match != nil {
+		hours, _ := strconv.Atoi(match[2])
+		minutes := 0
+		if match[3] != "" {
+			minutes, _ = strconv.Atoi(match[3])
+		}
+		if hours > maxOffsetHours || minutes >= 60 {
+			return nil, fmt.Errorf("%w %q: offset out of range", ErrInvalidTimezone, name)
+		}
+		offset := hours*3600 + minutes*60
+		if match[1] == "-" {
+			offset = -offset
+		}
+		return time.FixedZone(fmt.Sprintf("UTC%s%02d:%02d", match[1], hours, minutes), offset), nil
+	}
+
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w %q", ErrInvalidTimezone, name)
+	}
+	return location, nil
+}
+
+// ValidDateFormat reports whether format is a supported date format preference
+func ValidDateFormat(format string) bool {
+	_, ok := dateLayouts[format]
+	return ok
+}
+
+// ValidTimeFormat reports whether format is a supported time format preference
+func ValidTimeFormat(format string) bool {
+	_, ok := timeLayouts[format]
+	return ok
+}
+
+// Settings is how times are presented to one user
+type Settings struct {
+	Location   *time.Location
+	DateFormat string
+	TimeFormat string
+}
+
+// Default returns UTC with ISO dates and a 24-hour clock
+func Default() Settings {
+	return Settings{Location: time.UTC, DateFormat: DateFormatISO, TimeFormat: TimeFormat24h}
+}
+
+// New builds settings from stored preferences. Each invalid or missing
+// preference falls back to its default rather than failing the content
+func New(timezone, dateFormat, timeFormat string) Settings {
+	settings := Default()
+	if location, err := LoadLocation(timezone); err == nil {
+		settings.Location = location
+	}
+	if ValidDateFormat(dateFormat) {
+		settings.DateFormat = dateFormat
+	}
+	if ValidTimeFormat(timeFormat) {
+		settings.TimeFormat = timeFormat
+	}
+	return settings
+}
+
+// Timezone returns the name of the settings' timezone
+func (s Settings) Timezone() string {
+	return s.location().String()
+}
+
+// In returns t in the settings' timezone
+func (s Settings) In(t time.Time) time.Time {
+	return t.In(s.location())
+}
+
+// FormatDate formats the date of t, e.g. "03/01/2026"
+func (s Settings) FormatDate(t time.Time) string {
+	return s.In(t).Format(dateLayouts[s.dateFormat()])
+}
+
+// FormatDateTime formats t with the date and time format, e.g. "2026-03-01 14:30"
+func (s Settings) FormatDateTime(t time.Time) string {
+	layout, ok := timeLayouts[s.TimeFormat]
+	if !ok {
+		layout = timeLayouts[TimeFormat24h]
+	}
+	return s.In(t).Format(dateLayouts[s.dateFormat()] + " " + layout)
+}
+
+// FormatRFC1123Z formats t as an RSS date in the settings' timezone
+func (s Settings) FormatRFC1123Z(t time.Time) string {
+	return s.In(t).Format(time.RFC1123Z)
+}
+
+func (s Settings) location() *time.Location {
+	if s.Location == nil {
+		return time.UTC
+	}
+	return s.Location
+}
+
+func (s Settings) dateFormat() string {
+	if ValidDateFormat(s.DateFormat) {
+		return s.DateFormat
+	}
+	return DateFormatISO
+}
+
+// preferences mirrors the locale fields of the users.preferences JSON
+type preferences struct {
+	Timezone   string `json:"timezone"`
+	DateFormat string `json:"dateFormat"`
+	TimeFormat string `json:"timeFormat"`
+}
+
+// ForUser loads a user's locale settings, falling back to the defaults when
+// the user or their preferences cannot be read. It queries the users table
+// directly so packages the database models depend on can use it
+func ForUser(ctx context.Context, db *gorm.DB, userID uint) Settings {
+	var user struct {
+		Preferences string
+	}
+	if err := db.WithContext(ctx).Table("users").Select("preferences").
+		Where("id = ?", userID).Take(&user).Error; err != nil || user.Preferences == "" {
+		return Default()
+	}
+
+	var prefs preferences
+	if err := json.Unmarshal([]byte(user.Preferences), &prefs); err != nil {
+		return Default()
+	}
+	return New(prefs.Timezone, prefs.DateFormat, prefs.TimeFormat)
+}
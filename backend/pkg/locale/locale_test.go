@@ -0,0 +1,75 @@
+package locale
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestLoadLocation(t *testing.T) {
+	valid := map[string]string{
+		"":                 "UTC",
+		"Asia/Taipei":      "Asia/Taipei",
+		"America/New_York": "America/New_York",
+		"UTC+8":            "UTC+08:00",
+		"+05:30":           "UTC+05:30",
+		"gmt-0330":         "UTC-03:30",
+	}
+	for name, want := range valid {
+		location, err := LoadLocation(name)
+		require.NoError(t, err, name)
+		assert.Equal(t, want, location.String(), name)
+	}
+
+	location, _ := LoadLocation("UTC-5")
+	_, offset := time.Date(2026, 1, 1, 0, 0, 0, 0, location).Zone()
+	assert.Equal(t, -5*3600, offset)
+
+	for _, name := range []string{"Local", "Invalid/Timezone", "UTC+15", "+08:75", "8"} {
+		_, err := LoadLocation(name)
+		assert.ErrorIs(t, err, ErrInvalidTimezone, name)
+	}
+}
+
+func TestSettings_Format(t *testing.T) {
+	at := time.Date(2026, 3, 1, 22, 30, 0, 0, time.UTC)
+
+	settings := New("Asia/Taipei", DateFormatUS, TimeFormat12h)
+	assert.Equal(t, "03/02/2026", settings.FormatDate(at))
+	assert.Equal(t, "03/02/2026 6:30 AM", settings.FormatDateTime(at))
+	assert.Equal(t, "Mon, 02 Mar 2026 06:30:00 +0800", settings.FormatRFC1123Z(at))
+	assert.Equal(t, "Asia/Taipei", settings.Timezone())
+
+	// Invalid preferences fall back one by one
+	settings = New("Mars/Olympus", "D.M.YYYY", TimeFormat12h)
+	assert.Equal(t, "UTC", settings.Timezone())
+	assert.Equal(t, "2026-03-01 10:30 PM", settings.FormatDateTime(at))
+
+	assert.Equal(t, "01/03/2026 22:30", Settings{DateFormat: DateFormatEU}.FormatDateTime(at))
+}
+
+func TestForUser(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, preferences TEXT)").Error)
+	require.NoError(t, db.Exec(`INSERT INTO users (id, preferences) VALUES
+		(1, '{"timezone":"Europe/Berlin","dateFormat":"DD/MM/YYYY"}'),
+		(2, '{"timezone":"Nowhere/City"}'),
+		(3, 'not json'),
+		(4, '')`).Error)
+	ctx := context.Background()
+
+	settings := ForUser(ctx, db, 1)
+	assert.Equal(t, "Europe/Berlin", settings.Timezone())
+	assert.Equal(t, DateFormatEU, settings.DateFormat)
+	assert.Equal(t, TimeFormat24h, settings.TimeFormat)
+
+	for _, userID := range []uint{2, 3, 4, 99} {
+		assert.Equal(t, Default(), ForUser(ctx, db, userID), userID)
+	}
+}
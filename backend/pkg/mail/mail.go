@@ -0,0 +1,132 @@
+// Package mail sends plain text email through an SMTP relay
+package mail
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"bookmark-sync-service/backend/internal/config"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+	// Headers are added to the standard ones, e.g. List-Unsubscribe
+	Headers map[string]string
+}
+
+// Sender delivers email
+type Sender interface {
+	Send(ctx context.Context, message *Message) error
+}
+
+// NewSender returns an SMTP sender for the relay, or a sender that only
+// logs messages when no relay is configured
+func NewSender(cfg config.MailConfig, logger *zap.Logger) Sender {
+	if cfg.Host == "" {
+		return &logSender{logger: logger}
+	}
+	return &smtpSender{cfg: cfg}
+}
+
+// ValidAddress normalizes an email address, returning false for anything
+// that is not a single bare address
+func ValidAddress(address string) (string, bool) {
+	address = strings.ToLower(strings.TrimSpace(address))
+	parsed, err := mail.ParseAddress(address)
+	if err != nil || parsed.Address != address {
+		return "", false
+	}
+	return address, true
+}
+
+type smtpSender struct {
+	cfg config.MailConfig
+}
+
+func (s *smtpSender) Send(ctx context.Context, message *Message) error {
+	from, err := mail.ParseAddress(s.cfg.From)
+	if err != nil {
+		return fmt.Errorf("invalid sender address: %w", err)
+	}
+	if _, ok := ValidAddress(message.To); !ok {
+		return fmt.Errorf("invalid recipient address %q", message.To)
+	}
+
+	var auth smtp.Auth
+	if s.cfg.Username != "" {
+		auth = smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)
+	}
+	addr := net.JoinHostPort(s.cfg.Host, strconv.Itoa(s.cfg.Port))
+
+	// net/smtp takes no context, so the send runs aside and is abandoned
+	// when the context ends first
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(addr, auth, from.Address, []string{message.To}, Render(from.String(), message))
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("failed to send email: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type logSender struct {
+	logger *zap.Logger
+}
+
+func (s *logSender) Send(ctx context.Context, message *Message) error {
+	s.logger.Info("Email not sent, no mail host configured",
+		zap.String("to", message.To),
+		zap.String("subject", message.Subject))
+	return nil
+}
+
+// Render encodes the message as an RFC 5322 email
+func Render(from string, message *Message) []byte {
+	headers := map[string]string{
+		"From":                      from,
+		"To":                        message.To,
+		"Subject":                   mime.QEncoding.Encode("utf-8", message.Subject),
+		"Date":                      time.Now().Format(time.RFC1123Z),
+		"MIME-Version":              "1.0",
+		"Content-Type":              "text/plain; charset=utf-8",
+		"Content-Transfer-Encoding": "8bit",
+	}
+	for name, value := range message.Headers {
+		headers[name] = value
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	for _, name := range names {
+		// Header values never carry line breaks, which would inject headers
+		value := strings.NewReplacer("\r", "", "\n", "").Replace(headers[name])
+		fmt.Fprintf(&buf, "%s: %s\r\n", name, value)
+	}
+	buf.WriteString("\r\n")
+	buf.WriteString(strings.ReplaceAll(strings.ReplaceAll(message.Body, "\r\n", "\n"), "\n", "\r\n"))
+	return buf.Bytes()
+}
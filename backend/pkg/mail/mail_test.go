@@ -0,0 +1,39 @@
+package mail
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidAddress(t *testing.T) {
+	address, ok := ValidAddress("  Reader@Example.com ")
+	assert.True(t, ok)
+	assert.Equal(t, "reader@example.com", address)
+
+	for _, invalid := range []string{"", "reader", "Reader <reader@example.com>", "a@example.com, b@example.com"} {
+		_, ok := ValidAddress(invalid)
+		assert.False(t, ok, invalid)
+	}
+}
+
+func TestRender(t *testing.T) {
+	raw := string(Render("Bookmarks <no-reply@example.com>", &Message{
+		To:      "reader@example.com",
+		Subject: "New in Go links",
+		Body:    "First line\nSecond line",
+		Headers: map[string]string{
+			"List-Unsubscribe": "<https://example.com/unsubscribe>",
+			"X-Collection":     "Go links\r\nBcc: victim@example.com",
+		},
+	}))
+
+	headers, body, found := strings.Cut(raw, "\r\n\r\n")
+	assert.True(t, found)
+	assert.Contains(t, headers, "List-Unsubscribe: <https://example.com/unsubscribe>\r\n")
+	assert.Contains(t, headers, "To: reader@example.com")
+	// Line breaks in a header cannot add another header
+	assert.NotContains(t, headers, "\r\nBcc:")
+	assert.Equal(t, "First line\r\nSecond line", body)
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"bookmark-sync-service/backend/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireAdmin restricts a route group to the configured admin accounts.
+// It must run after AuthMiddleware, which sets the caller's email
+func RequireAdmin(adminEmails []string) gin.HandlerFunc {
+	admins := make(map[string]bool, len(adminEmails))
+	for _, email := range adminEmails {
+		if email = strings.ToLower(strings.TrimSpace(email)); email != "" {
+			admins[email] = true
+		}
+	}
+
+	return func(c *gin.Context) {
+		if !admins[strings.ToLower(GetUserEmail(c))] {
+			utils.ErrorResponse(c, http.StatusForbidden, "FORBIDDEN", "Admin access required", nil)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireBearerToken restricts a route to callers presenting the token, such
+// as a metrics scraper. Without a configured token every request is refused
+func RequireBearerToken(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		presented := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if token == "" || subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+			utils.ErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "Invalid bearer token", nil)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -1,7 +1,9 @@
 package middleware
 
 import (
+	"context"
 	"fmt"
+	"strconv"
 	"strings"
 
 	"bookmark-sync-service/backend/internal/config"
@@ -11,8 +13,38 @@ import (
 	"github.com/golang-jwt/jwt/v4"
 )
 
-// AuthMiddleware creates a JWT authentication middleware
-func AuthMiddleware(cfg *config.JWTConfig) gin.HandlerFunc {
+// Principal is the user a verified bearer token belongs to
+type Principal struct {
+	UserID  uint
+	Email   string
+	Subject string
+}
+
+// TokenVerifier authenticates bearer tokens that this API did not issue,
+// such as access tokens of an OpenID Connect provider
+type TokenVerifier interface {
+	VerifyBearer(ctx context.Context, token string) (*Principal, error)
+}
+
+// verifyExternal tries the verifiers on a token the API did not sign and
+// sets the user of the first one accepting it
+func verifyExternal(c *gin.Context, tokenString string, verifiers []TokenVerifier) bool {
+	for _, verifier := range verifiers {
+		principal, err := verifier.VerifyBearer(c.Request.Context(), tokenString)
+		if err != nil {
+			continue
+		}
+		c.Set("user_id", strconv.FormatUint(uint64(principal.UserID), 10))
+		c.Set("email", principal.Email)
+		c.Set("supabase_id", principal.Subject)
+		return true
+	}
+	return false
+}
+
+// AuthMiddleware creates a JWT authentication middleware. Tokens not signed
+// by the API are offered to the verifiers of external identity providers
+func AuthMiddleware(cfg *config.JWTConfig, verifiers ...TokenVerifier) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -39,6 +71,10 @@ func AuthMiddleware(cfg *config.JWTConfig) gin.HandlerFunc {
 		})
 
 		if err != nil {
+			if verifyExternal(c, tokenString, verifiers) {
+				c.Next()
+				return
+			}
 			utils.UnauthorizedResponse(c, "Invalid token")
 			c.Abort()
 			return
@@ -67,6 +103,9 @@ func AuthMiddleware(cfg *config.JWTConfig) gin.HandlerFunc {
 			if supabaseID, exists := claims["sub"]; exists {
 				c.Set("supabase_id", fmt.Sprintf("%v", supabaseID))
 			}
+			if demo, ok := claims["demo"].(bool); ok && demo {
+				c.Set("demo", true)
+			}
 		}
 
 		c.Next()
@@ -76,7 +115,7 @@ func AuthMiddleware(cfg *config.JWTConfig) gin.HandlerFunc {
 // OptionalAuthMiddleware creates an optional JWT authentication middleware
 // This middleware will extract user information if a valid token is provided,
 // but won't block the request if no token is provided
-func OptionalAuthMiddleware(cfg *config.JWTConfig) gin.HandlerFunc {
+func OptionalAuthMiddleware(cfg *config.JWTConfig, verifiers ...TokenVerifier) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -101,6 +140,7 @@ func OptionalAuthMiddleware(cfg *config.JWTConfig) gin.HandlerFunc {
 		})
 
 		if err != nil || !token.Valid {
+			verifyExternal(c, tokenString, verifiers)
 			c.Next()
 			return
 		}
@@ -122,6 +162,9 @@ func OptionalAuthMiddleware(cfg *config.JWTConfig) gin.HandlerFunc {
 			if supabaseID, exists := claims["sub"]; exists {
 				c.Set("supabase_id", fmt.Sprintf("%v", supabaseID))
 			}
+			if demo, ok := claims["demo"].(bool); ok && demo {
+				c.Set("demo", true)
+			}
 		}
 
 		c.Next()
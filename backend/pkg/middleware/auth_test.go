@@ -1,6 +1,8 @@
 package middleware
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -295,3 +297,48 @@ func TestHelperFunctions(t *testing.T) {
 		assert.False(t, RequireAuth(c))
 	})
 }
+
+type staticVerifier map[string]*Principal
+
+func (v staticVerifier) VerifyBearer(ctx context.Context, token string) (*Principal, error) {
+	if principal, ok := v[token]; ok {
+		return principal, nil
+	}
+	return nil, errors.New("unknown token")
+}
+
+// TestAuthMiddlewareTokenVerifiers tests tokens of external identity providers
+// TestAuthMiddlewareTokenVerifiers 測試外部身份提供者的令牌
+func TestAuthMiddlewareTokenVerifiers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	verifier := staticVerifier{"provider-token": {UserID: 42, Email: "kc@example.com", Subject: "oidc:abc"}}
+
+	for _, optional := range []bool{false, true} {
+		router := gin.New()
+		if optional {
+			router.Use(OptionalAuthMiddleware(&config.JWTConfig{Secret: "secret"}, verifier))
+		} else {
+			router.Use(AuthMiddleware(&config.JWTConfig{Secret: "secret"}, verifier))
+		}
+		router.GET("/test", func(c *gin.Context) {
+			c.String(http.StatusOK, GetUserID(c)+" "+GetUserEmail(c)+" "+GetSupabaseID(c))
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		req.Header.Set("Authorization", "Bearer provider-token")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "42 kc@example.com oidc:abc", w.Body.String())
+
+		req = httptest.NewRequest(http.MethodGet, "/test", nil)
+		req.Header.Set("Authorization", "Bearer forged-token")
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if optional {
+			assert.Equal(t, "  ", w.Body.String())
+		} else {
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+		}
+	}
+}
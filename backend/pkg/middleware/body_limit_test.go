@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupBodyLimitRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(BodyLimit(16))
+
+	read := func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			if BodyTooLarge(c, err) {
+				return
+			}
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"bytes": len(body), "limit": c.GetInt64(BodyLimitKey)})
+	}
+	router.POST("/default", read)
+	router.POST("/upload", BodyLimit(64), read)
+	router.POST("/tiny", BodyLimit(4), read)
+	return router
+}
+
+func TestBodyLimit(t *testing.T) {
+	router := setupBodyLimitRouter()
+
+	post := func(path string, body string, chunked bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		if chunked {
+			req.ContentLength = -1
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		size    int
+		chunked bool
+		status  int
+		limit   int64
+	}{
+		{"within the default", "/default", 16, false, http.StatusOK, 16},
+		{"declared past the default", "/default", 17, false, http.StatusRequestEntityTooLarge, 16},
+		{"read past the default", "/default", 17, true, http.StatusRequestEntityTooLarge, 16},
+		{"route raises the limit", "/upload", 64, false, http.StatusOK, 64},
+		{"past the raised limit", "/upload", 65, true, http.StatusRequestEntityTooLarge, 64},
+		{"route lowers the limit", "/tiny", 5, false, http.StatusRequestEntityTooLarge, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := post(tt.path, strings.Repeat("x", tt.size), tt.chunked)
+			require.Equal(t, tt.status, w.Code)
+
+			var response struct {
+				Error struct {
+					Code    string           `json:"code"`
+					Details map[string]int64 `json:"details"`
+				} `json:"error"`
+				Limit int64 `json:"limit"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			if tt.status == http.StatusOK {
+				assert.Equal(t, tt.limit, response.Limit)
+				return
+			}
+			assert.Equal(t, "REQUEST_TOO_LARGE", response.Error.Code)
+			assert.Equal(t, tt.limit, response.Error.Details["limit_bytes"])
+		})
+	}
+}
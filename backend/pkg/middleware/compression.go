@@ -0,0 +1,247 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+)
+
+// PayloadBytesKey is the context key holding a response's size before
+// compression
+const PayloadBytesKey = "payload_bytes"
+
+// Supported response encodings
+const (
+	EncodingBrotli = "br"
+	EncodingGzip   = "gzip"
+)
+
+// brotliLevel trades a little ratio for the speed API responses need
+const brotliLevel = 5
+
+var (
+	gzipWriters = sync.Pool{New: func() interface{} {
+		w, _ := gzip.NewWriterLevel(io.Discard, gzip.DefaultCompression)
+		return w
+	}}
+	brotliWriters = sync.Pool{New: func() interface{} {
+		return brotli.NewWriterLevel(io.Discard, brotliLevel)
+	}}
+)
+
+// Compression encodes responses with brotli or gzip, whichever the client
+// prefers. Responses smaller than minSize, partial content, and types that
+// are already compressed are sent as they are. WebSocket upgrades and event
+// streams are left alone so they are not buffered
+func Compression(minSize int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		encoding := NegotiateEncoding(c.GetHeader("Accept-Encoding"))
+		if encoding == "" || c.Request.Method == http.MethodHead ||
+			c.GetHeader("Upgrade") != "" || strings.Contains(c.GetHeader("Accept"), "text/event-stream") {
+			c.Next()
+			return
+		}
+
+		writer := &compressWriter{ResponseWriter: c.Writer, encoding: encoding, minSize: minSize}
+		c.Writer = writer
+
+		c.Next()
+
+		writer.finish()
+		c.Writer = writer.ResponseWriter
+		c.Set(PayloadBytesKey, writer.raw)
+	}
+}
+
+// NegotiateEncoding picks the response encoding from an Accept-Encoding
+// header: the supported coding with the highest quality, brotli on a tie, or
+// "" when the client accepts neither
+func NegotiateEncoding(header string) string {
+	best, bestQ := "", 0.0
+	wildcard := -1.0
+	for _, part := range strings.Split(header, ",") {
+		name, q := parseCoding(part)
+		switch name {
+		case EncodingBrotli, EncodingGzip:
+			if q > bestQ || (q == bestQ && q > 0 && name == EncodingBrotli) {
+				best, bestQ = name, q
+			}
+		case "*":
+			wildcard = q
+		}
+	}
+	if best == "" && wildcard > 0 {
+		return EncodingGzip
+	}
+	return best
+}
+
+// parseCoding splits "gzip;q=0.5" into its name and quality
+func parseCoding(part string) (string, float64) {
+	name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+	q := 1.0
+	for _, param := range strings.Split(params, ";") {
+		key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if ok && strings.EqualFold(key, "q") {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+	}
+	return strings.ToLower(strings.TrimSpace(name)), q
+}
+
+// compressWriter buffers the start of a response until it knows whether
+// the response is worth compressing
+type compressWriter struct {
+	gin.ResponseWriter
+	encoding string
+	minSize  int
+
+	buf     bytes.Buffer
+	decided bool
+	encoder io.WriteCloser
+	raw     int
+}
+
+func (w *compressWriter) Write(p []byte) (int, error) {
+	w.raw += len(p)
+	if w.decided {
+		return w.target().Write(p)
+	}
+
+	w.buf.Write(p)
+	if w.buf.Len() >= w.minSize {
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// WriteHeaderNow commits to an uncompressed response, as nothing has been
+// written yet to judge the size by
+func (w *compressWriter) WriteHeaderNow() {
+	if !w.decided {
+		w.decide(false)
+	}
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.decide(w.buf.Len() >= w.minSize)
+	}
+	if flusher, ok := w.encoder.(interface{ Flush() error }); ok {
+		flusher.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+func (w *compressWriter) target() io.Writer {
+	if w.encoder != nil {
+		return w.encoder
+	}
+	return w.ResponseWriter
+}
+
+// decide settles whether the response is compressed and writes out what
+// has been buffered
+func (w *compressWriter) decide(compress bool) error {
+	w.decided = true
+	header := w.Header()
+
+	if compressibleType(header.Get("Content-Type")) {
+		header.Add("Vary", "Accept-Encoding")
+		if compress && w.compressibleResponse() {
+			header.Set("Content-Encoding", w.encoding)
+			header.Del("Content-Length")
+			w.encoder = newEncoder(w.encoding, w.ResponseWriter)
+		}
+	}
+
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	_, err := w.target().Write(w.buf.Bytes())
+	w.buf.Reset()
+	return err
+}
+
+// compressibleResponse rules out responses whose status or headers forbid
+// re-encoding the body
+func (w *compressWriter) compressibleResponse() bool {
+	switch w.Status() {
+	case http.StatusNoContent, http.StatusNotModified, http.StatusPartialContent:
+		return false
+	}
+	header := w.Header()
+	return header.Get("Content-Encoding") == "" && header.Get("Content-Range") == ""
+}
+
+// finish writes out a response that never reached the minimum size and
+// closes the encoder
+func (w *compressWriter) finish() {
+	if !w.decided {
+		w.decide(false)
+	}
+	if w.encoder != nil {
+		w.encoder.Close()
+		releaseEncoder(w.encoder)
+		w.encoder = nil
+	}
+}
+
+func newEncoder(encoding string, dst io.Writer) io.WriteCloser {
+	if encoding == EncodingBrotli {
+		w := brotliWriters.Get().(*brotli.Writer)
+		w.Reset(dst)
+		return w
+	}
+	w := gzipWriters.Get().(*gzip.Writer)
+	w.Reset(dst)
+	return w
+}
+
+func releaseEncoder(encoder io.WriteCloser) {
+	switch w := encoder.(type) {
+	case *brotli.Writer:
+		brotliWriters.Put(w)
+	case *gzip.Writer:
+		gzipWriters.Put(w)
+	}
+}
+
+// compressibleType reports whether a content type benefits from compression.
+// Images, archives and media are already compressed
+func compressibleType(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+
+	switch {
+	case mediaType == "":
+		return false
+	case strings.HasPrefix(mediaType, "text/"):
+		return mediaType != "text/event-stream"
+	case strings.HasSuffix(mediaType, "+json"), strings.HasSuffix(mediaType, "+xml"):
+		return true
+	}
+
+	switch mediaType {
+	case "application/json", "application/javascript", "application/xml",
+		"application/x-ndjson", "application/rss+xml", "image/svg+xml":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupCompressionRouter(metrics *PayloadMetrics) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(metrics.Middleware())
+	router.Use(Compression(1024))
+
+	router.GET("/large", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"text": strings.Repeat("bookmark ", 500)})
+	})
+	router.GET("/small", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+	router.GET("/image", func(c *gin.Context) {
+		c.Data(http.StatusOK, "image/png", bytes.Repeat([]byte{0x89}, 4096))
+	})
+	router.GET("/empty", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	return router
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := map[string]string{
+		"":                         "",
+		"identity":                 "",
+		"gzip":                     EncodingGzip,
+		"gzip, deflate, br":        EncodingBrotli,
+		"br;q=0.5, gzip":           EncodingGzip,
+		"gzip;q=0.8, br;q=0.8":     EncodingBrotli,
+		"br;q=0, gzip;q=0":         "",
+		"*":                        EncodingGzip,
+		"*;q=0":                    "",
+		"GZIP;Q=0.3, deflate":      EncodingGzip,
+		"deflate, *;q=0.1, br;q=0": EncodingGzip,
+	}
+	for header, expected := range tests {
+		assert.Equal(t, expected, NegotiateEncoding(header), header)
+	}
+}
+
+func TestCompression(t *testing.T) {
+	router := setupCompressionRouter(NewPayloadMetrics())
+
+	t.Run("gzip", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/large", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, EncodingGzip, w.Header().Get("Content-Encoding"))
+		assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+
+		reader, err := gzip.NewReader(w.Body)
+		require.NoError(t, err)
+		body, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		assert.Contains(t, string(body), `"text":"bookmark bookmark`)
+	})
+
+	t.Run("brotli", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/large", nil)
+		req.Header.Set("Accept-Encoding", "gzip, br")
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, EncodingBrotli, w.Header().Get("Content-Encoding"))
+		body, err := io.ReadAll(brotli.NewReader(w.Body))
+		require.NoError(t, err)
+		assert.Contains(t, string(body), `"text":"bookmark bookmark`)
+	})
+
+	t.Run("not accepted", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/large", nil)
+		router.ServeHTTP(w, req)
+
+		assert.Empty(t, w.Header().Get("Content-Encoding"))
+		assert.Contains(t, w.Body.String(), `"text":"bookmark bookmark`)
+	})
+
+	// Small bodies, compressed media and empty responses are sent as they are
+	for _, path := range []string{"/small", "/image", "/empty"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept-Encoding", "br, gzip")
+		router.ServeHTTP(w, req)
+
+		assert.Empty(t, w.Header().Get("Content-Encoding"), path)
+	}
+}
+
+func TestPayloadMetrics(t *testing.T) {
+	metrics := NewPayloadMetrics()
+	router := setupCompressionRouter(metrics)
+
+	for _, encoding := range []string{"gzip", ""} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/large", nil)
+		req.Header.Set("Accept-Encoding", encoding)
+		router.ServeHTTP(w, req)
+	}
+	for _, path := range []string{"/small", "/missing"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		router.ServeHTTP(w, req)
+	}
+
+	// Unmatched routes are not recorded
+	snapshot := metrics.Snapshot()
+	require.Len(t, snapshot, 2)
+
+	large := snapshot[0]
+	assert.Equal(t, "/large", large.Path)
+	assert.Equal(t, http.MethodGet, large.Method)
+	assert.Equal(t, int64(2), large.Requests)
+	assert.Equal(t, large.Bytes/2, large.AvgBytes)
+	assert.Equal(t, large.AvgBytes, large.MaxBytes)
+	assert.Less(t, large.WireBytes, large.Bytes)
+	assert.Less(t, large.CompressionRatio, 1.0)
+
+	small := snapshot[1]
+	assert.Equal(t, "/small", small.Path)
+	assert.Equal(t, small.Bytes, small.WireBytes)
+	assert.Equal(t, 1.0, small.CompressionRatio)
+
+	metrics.Reset()
+	assert.Empty(t, metrics.Snapshot())
+}
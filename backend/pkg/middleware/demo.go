@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"net/http"
+
+	"bookmark-sync-service/backend/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IsDemo reports whether the caller is a guest sandbox of demo mode
+func IsDemo(c *gin.Context) bool {
+	return c.GetBool("demo")
+}
+
+// DenyDemo keeps demo sandboxes off routes that reach other users or the
+// outside world, such as sharing, webhooks and federation. It must run
+// after AuthMiddleware, which marks sandbox tokens
+func DenyDemo() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if IsDemo(c) {
+			utils.ErrorResponse(c, http.StatusForbidden, "DEMO_RESTRICTED", "Not available in demo mode", nil)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
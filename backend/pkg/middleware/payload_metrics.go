@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EndpointPayload is the response size record of one route
+type EndpointPayload struct {
+	Method    string `json:"method"`
+	Path      string `json:"path"` // route pattern, e.g. /api/v1/bookmarks/:id
+	Requests  int64  `json:"requests"`
+	Bytes     int64  `json:"bytes"`      // before compression
+	WireBytes int64  `json:"wire_bytes"` // as sent
+	AvgBytes  int64  `json:"avg_bytes"`
+	MaxBytes  int64  `json:"max_bytes"`
+	// CompressionRatio is wire bytes over payload bytes; 1 means nothing was saved
+	CompressionRatio float64 `json:"compression_ratio"`
+}
+
+// PayloadMetrics records response sizes per route so payload regressions
+// show up. It is safe for concurrent use
+type PayloadMetrics struct {
+	mu        sync.Mutex
+	endpoints map[string]*EndpointPayload
+}
+
+// NewPayloadMetrics creates an empty recorder
+func NewPayloadMetrics() *PayloadMetrics {
+	return &PayloadMetrics{endpoints: make(map[string]*EndpointPayload)}
+}
+
+// Middleware records the size of each response. It must run before
+// Compression so it sees the bytes actually sent. Requests that match no
+// route are not recorded, which keeps the number of entries bounded
+func (m *PayloadMetrics) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		path := c.FullPath()
+		if path == "" {
+			return
+		}
+
+		wire := int64(c.Writer.Size())
+		if wire < 0 {
+			wire = 0
+		}
+		payload := wire
+		if raw, ok := c.Get(PayloadBytesKey); ok {
+			payload = int64(raw.(int))
+		}
+
+		m.Record(c.Request.Method, path, payload, wire)
+	}
+}
+
+// Record adds one response to a route's totals
+func (m *PayloadMetrics) Record(method, path string, payload, wire int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := method + " " + path
+	entry, ok := m.endpoints[key]
+	if !ok {
+		entry = &EndpointPayload{Method: method, Path: path}
+		m.endpoints[key] = entry
+	}
+
+	entry.Requests++
+	entry.Bytes += payload
+	entry.WireBytes += wire
+	if payload > entry.MaxBytes {
+		entry.MaxBytes = payload
+	}
+}
+
+// Snapshot returns the recorded routes, largest total payload first
+func (m *PayloadMetrics) Snapshot() []EndpointPayload {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list := make([]EndpointPayload, 0, len(m.endpoints))
+	for _, entry := range m.endpoints {
+		item := *entry
+		item.AvgBytes = item.Bytes / item.Requests
+		item.CompressionRatio = 1
+		if item.Bytes > 0 {
+			item.CompressionRatio = float64(item.WireBytes) / float64(item.Bytes)
+		}
+		list = append(list, item)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Bytes != list[j].Bytes {
+			return list[i].Bytes > list[j].Bytes
+		}
+		return list[i].Method+list[i].Path < list[j].Method+list[j].Path
+	})
+	return list
+}
+
+// Reset clears the recorded totals
+func (m *PayloadMetrics) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.endpoints = make(map[string]*EndpointPayload)
+}
@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupSecurityRouter(origins []string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(CORS(origins))
+	router.Use(SecurityHeaders(3600))
+
+	router.GET("/api", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+	page := router.Group("/page", ContentSecurityPolicy("default-src 'none'; style-src 'unsafe-inline'"))
+	page.GET("/framed", func(c *gin.Context) {
+		SetFrameAncestors(c, []string{"https://blog.example.com"})
+		c.String(http.StatusOK, "<p>embed</p>")
+	})
+	page.GET("/private", func(c *gin.Context) {
+		SetFrameAncestors(c, nil)
+		c.String(http.StatusOK, "<p>embed</p>")
+	})
+	return router
+}
+
+func serveSecurity(router *gin.Engine, method, path string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestCORS_Allowlist(t *testing.T) {
+	router := setupSecurityRouter([]string{"https://app.example.com", "https://*.example.org", "chrome-extension://abcdef"})
+
+	tests := map[string]bool{
+		"https://app.example.com":   true,
+		"https://APP.example.com":   true,
+		"http://app.example.com":    false,
+		"https://evil.com":          false,
+		"https://docs.example.org":  true,
+		"https://a.b.example.org":   true,
+		"https://example.org":       false,
+		"https://evilexample.org":   false,
+		"chrome-extension://abcdef": true,
+		"chrome-extension://other":  false,
+	}
+	for origin, allowed := range tests {
+		w := serveSecurity(router, http.MethodGet, "/api", map[string]string{"Origin": origin})
+		assert.Equal(t, http.StatusOK, w.Code, origin)
+		assert.Contains(t, w.Header().Values("Vary"), "Origin", origin)
+		if allowed {
+			assert.Equal(t, origin, w.Header().Get("Access-Control-Allow-Origin"), origin)
+			assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"), origin)
+		} else {
+			assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"), origin)
+			assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"), origin)
+		}
+	}
+}
+
+func TestCORS_AnyOrigin(t *testing.T) {
+	router := setupSecurityRouter([]string{"*"})
+
+	w := serveSecurity(router, http.MethodOptions, "/api", map[string]string{"Origin": "https://anywhere.test"})
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"), "browsers reject credentials with a wildcard")
+	assert.Contains(t, w.Header().Get("Access-Control-Allow-Methods"), "PATCH")
+}
+
+func TestSecurityHeaders(t *testing.T) {
+	router := setupSecurityRouter([]string{"*"})
+
+	w := serveSecurity(router, http.MethodGet, "/api", nil)
+	assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, "DENY", w.Header().Get("X-Frame-Options"))
+	assert.Equal(t, APIContentSecurityPolicy, w.Header().Get("Content-Security-Policy"))
+	assert.Empty(t, w.Header().Get("Strict-Transport-Security"), "HSTS is ignored over plain HTTP")
+
+	w = serveSecurity(router, http.MethodGet, "/api", map[string]string{"X-Forwarded-Proto": "https"})
+	assert.Equal(t, "max-age=3600; includeSubDomains", w.Header().Get("Strict-Transport-Security"))
+}
+
+func TestSecurityHeaders_RouteOverrides(t *testing.T) {
+	router := setupSecurityRouter([]string{"*"})
+
+	w := serveSecurity(router, http.MethodGet, "/page/framed", nil)
+	assert.Equal(t, "default-src 'none'; style-src 'unsafe-inline'; frame-ancestors https://blog.example.com", w.Header().Get("Content-Security-Policy"))
+	assert.Empty(t, w.Header().Get("X-Frame-Options"))
+	assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+
+	w = serveSecurity(router, http.MethodGet, "/page/private", nil)
+	assert.Equal(t, "default-src 'none'; style-src 'unsafe-inline'; frame-ancestors 'none'", w.Header().Get("Content-Security-Policy"))
+	assert.Equal(t, "DENY", w.Header().Get("X-Frame-Options"))
+}
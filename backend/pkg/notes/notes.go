@@ -0,0 +1,95 @@
+// Package notes handles the Markdown notes users attach to bookmarks
+package notes
+
+import (
+	"bytes"
+	"encoding/base64"
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// MaxLength caps the size of a note in bytes
+const MaxLength = 100000
+
+// MaxKeyHintLength caps the hint stored with encrypted notes
+const MaxKeyHintLength = 64
+
+// markdown renders GitHub flavoured Markdown. Raw HTML in notes is escaped
+// by goldmark and anything left is removed by the sanitizer
+var markdown = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+// policy allows the formatting user generated content needs and adds
+// rel="nofollow noopener" to links
+var policy = func() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.RequireNoFollowOnLinks(true)
+	p.AddTargetBlankToFullyQualifiedLinks(true)
+	// GFM task lists render as disabled checkboxes
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+	return p
+}()
+
+// Render converts a note to sanitized HTML that is safe to insert in a page
+func Render(note string) string {
+	if note == "" {
+		return ""
+	}
+
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte(note), &buf); err != nil {
+		// Fall back to the escaped source rather than dropping the note
+		return "<p>" + policy.Sanitize(strings.ReplaceAll(note, "<", "&lt;")) + "</p>"
+	}
+	return policy.Sanitize(buf.String())
+}
+
+// PlainText strips Markdown and HTML from a note, for search indexing
+func PlainText(note string) string {
+	if note == "" {
+		return ""
+	}
+	text := bluemonday.StrictPolicy().Sanitize(Render(note))
+	return strings.Join(strings.Fields(unescape.Replace(text)), " ")
+}
+
+var unescape = strings.NewReplacer("&amp;", "&", "&lt;", "<", "&gt;", ">", "&#34;", `"`, "&#39;", "'")
+
+// queryTerm matches note:keyword and note:"a phrase" in a search query
+var queryTerm = regexp.MustCompile(`(?i)(?:^|\s)note:(?:"([^"]*)"|(\S+))`)
+
+// SplitQuery separates note: terms from the rest of a search query, so
+// "go note:concurrency" searches for "go" in bookmarks whose notes mention
+// "concurrency"
+func SplitQuery(query string) (string, []string) {
+	var terms []string
+	for _, match := range queryTerm.FindAllStringSubmatch(query, -1) {
+		term := match[1]
+		if term == "" {
+			term = match[2]
+		}
+		if term = strings.TrimSpace(term); term != "" {
+			terms = append(terms, term)
+		}
+	}
+	rest := queryTerm.ReplaceAllString(query, " ")
+	return strings.Join(strings.Fields(rest), " "), terms
+}
+
+// ValidEncrypted reports whether client-side encrypted notes can be stored:
+// the ciphertext must be standard base64 and come with a key hint, which
+// tells the user's clients which key decrypts it
+func ValidEncrypted(ciphertext, keyHint string) bool {
+	if keyHint == "" || len(keyHint) > MaxKeyHintLength {
+		return false
+	}
+	if ciphertext == "" {
+		return true
+	}
+	_, err := base64.StdEncoding.DecodeString(ciphertext)
+	return err == nil
+}
@@ -0,0 +1,46 @@
+package notes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRender(t *testing.T) {
+	html := Render("# Title\n\nSome **bold** text and a [link](https://example.com).\n\n- [x] done")
+	assert.Contains(t, html, "<h1")
+	assert.Contains(t, html, "<strong>bold</strong>")
+	assert.Contains(t, html, `href="https://example.com"`)
+	assert.Contains(t, html, `rel="nofollow noopener"`)
+	assert.Contains(t, html, `type="checkbox"`)
+
+	assert.Empty(t, Render(""))
+}
+
+func TestRenderSanitizes(t *testing.T) {
+	html := Render("<script>alert(1)</script>\n\n[x](javascript:alert(1))\n\n<img src=x onerror=alert(1)>")
+	assert.NotContains(t, html, "<script")
+	assert.NotContains(t, html, "javascript:")
+	assert.NotContains(t, html, "onerror")
+}
+
+func TestPlainText(t *testing.T) {
+	assert.Equal(t, "Title Some bold text & more", PlainText("# Title\n\nSome **bold** text & _more_"))
+}
+
+func TestSplitQuery(t *testing.T) {
+	rest, terms := SplitQuery(`golang note:concurrency tips NOTE:"worker pools"`)
+	assert.Equal(t, "golang tips", rest)
+	assert.Equal(t, []string{"concurrency", "worker pools"}, terms)
+
+	rest, terms = SplitQuery("no notes here")
+	assert.Equal(t, "no notes here", rest)
+	assert.Empty(t, terms)
+}
+
+func TestValidEncrypted(t *testing.T) {
+	assert.True(t, ValidEncrypted("c2VjcmV0IG5vdGU=", "vault-v1"))
+	assert.True(t, ValidEncrypted("", "vault-v1"))
+	assert.False(t, ValidEncrypted("# plain markdown", "vault-v1"))
+	assert.False(t, ValidEncrypted("c2VjcmV0IG5vdGU=", ""))
+}
@@ -0,0 +1,209 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+// lockKeyPrefix namespaces distributed lock keys
+const lockKeyPrefix = "lock:"
+
+var (
+	// ErrLockNotAcquired is returned when another replica holds the lock
+	ErrLockNotAcquired = errors.New("lock is held by another owner")
+	// ErrLockLost is returned when a lock expired or was taken over before it was renewed
+	ErrLockLost = errors.New("lock was lost")
+)
+
+// The scripts only touch the key while it still holds our token, so an owner
+// whose lock expired can never renew or delete a successor's lock
+var (
+	renewScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+
+	releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+)
+
+// Locker runs a function while holding a named distributed lock
+type Locker interface {
+	WithLock(ctx context.Context, name string, ttl time.Duration, fn func(ctx context.Context) error) error
+}
+
+// Ensure Client implements Locker
+var _ Locker = (*Client)(nil)
+
+// Lock is a distributed lock acquired with SET NX and identified by a random
+// token so that only its owner can renew or release it
+type Lock struct {
+	client *Client
+	key    string
+	token  string
+	ttl    time.Duration
+}
+
+// AcquireLock takes the named lock for ttl. It returns ErrLockNotAcquired
+// without waiting when another owner holds it
+func (c *Client) AcquireLock(ctx context.Context, name string, ttl time.Duration) (*Lock, error) {
+	lock := &Lock{
+		client: c,
+		key:    lockKeyPrefix + name,
+		token:  uuid.NewString(),
+		ttl:    ttl,
+	}
+
+	ok, err := c.Client.SetNX(ctx, lock.key, lock.token, ttl).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire lock %s: %w", name, err)
+	}
+	if !ok {
+		return nil, ErrLockNotAcquired
+	}
+
+	return lock, nil
+}
+
+// Refresh extends the lock by its ttl. It returns ErrLockLost when the lock
+// is no longer held by this owner
+func (l *Lock) Refresh(ctx context.Context) error {
+	renewed, err := renewScript.Run(ctx, l.client.Client, []string{l.key}, l.token, l.ttl.Milliseconds()).Int()
+	if err != nil {
+		return fmt.Errorf("failed to refresh lock: %w", err)
+	}
+	if renewed == 0 {
+		return ErrLockLost
+	}
+	return nil
+}
+
+// Release gives the lock up if it is still held by this owner
+func (l *Lock) Release(ctx context.Context) error {
+	if err := releaseScript.Run(ctx, l.client.Client, []string{l.key}, l.token).Err(); err != nil {
+		return fmt.Errorf("failed to release lock: %w", err)
+	}
+	return nil
+}
+
+// WithLock runs fn while holding the named lock, renewing it every third of
+// its ttl. fn's context is cancelled if the lock is lost. It returns
+// ErrLockNotAcquired when another owner is already running
+func (c *Client) WithLock(ctx context.Context, name string, ttl time.Duration, fn func(ctx context.Context) error) error {
+	lock, err := c.AcquireLock(ctx, name, ttl)
+	if err != nil {
+		return err
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// lost is only read after renewed is closed
+	lost := false
+	renewed := make(chan struct{})
+	go func() {
+		defer close(renewed)
+
+		ticker := time.NewTicker(ttl / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-runCtx.Done():
+				return
+			case <-ticker.C:
+				if err := lock.Refresh(runCtx); err != nil && runCtx.Err() == nil {
+					lost = true
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	err = fn(runCtx)
+	cancel()
+	<-renewed
+
+	// Release with the parent context, runCtx is already cancelled
+	lock.Release(context.WithoutCancel(ctx))
+
+	if lost && err == nil {
+		return ErrLockLost
+	}
+	return err
+}
+
+// Leader is a leadership lease shared by replicas. The replica holding the
+// lease renews it each time it campaigns; others take over once it expires
+type Leader struct {
+	client *Client
+	key    string
+	token  string
+	ttl    time.Duration
+
+	mu      sync.Mutex
+	leading bool
+}
+
+// NewLeader creates a leadership lease for name. ttl should exceed the
+// interval between campaigns so the leader keeps the lease between runs
+func (c *Client) NewLeader(name string, ttl time.Duration) *Leader {
+	return &Leader{
+		client: c,
+		key:    lockKeyPrefix + "leader:" + name,
+		token:  uuid.NewString(),
+		ttl:    ttl,
+	}
+}
+
+// Campaign renews the lease if this replica holds it, or takes it if it is
+// free, and reports whether this replica is the leader
+func (l *Leader) Campaign(ctx context.Context) (bool, error) {
+	renewed, err := renewScript.Run(ctx, l.client.Client, []string{l.key}, l.token, l.ttl.Milliseconds()).Int()
+	if err != nil {
+		return l.setLeading(false), fmt.Errorf("failed to renew leadership: %w", err)
+	}
+	if renewed == 1 {
+		return l.setLeading(true), nil
+	}
+
+	acquired, err := l.client.Client.SetNX(ctx, l.key, l.token, l.ttl).Result()
+	if err != nil {
+		return l.setLeading(false), fmt.Errorf("failed to acquire leadership: %w", err)
+	}
+	return l.setLeading(acquired), nil
+}
+
+// IsLeader reports the outcome of the last campaign
+func (l *Leader) IsLeader() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.leading
+}
+
+// Resign gives up the lease so another replica can take over immediately
+func (l *Leader) Resign(ctx context.Context) error {
+	l.setLeading(false)
+	if err := releaseScript.Run(ctx, l.client.Client, []string{l.key}, l.token).Err(); err != nil {
+		return fmt.Errorf("failed to resign leadership: %w", err)
+	}
+	return nil
+}
+
+func (l *Leader) setLeading(leading bool) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.leading = leading
+	return leading
+}
@@ -0,0 +1,105 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAcquireLock tests that a lock excludes other owners until released
+func TestAcquireLock(t *testing.T) {
+	client, mr := setupTestRedis(t)
+	defer mr.Close()
+	ctx := context.Background()
+
+	lock, err := client.AcquireLock(ctx, "cleanup", time.Minute)
+	require.NoError(t, err)
+
+	_, err = client.AcquireLock(ctx, "cleanup", time.Minute)
+	assert.ErrorIs(t, err, ErrLockNotAcquired)
+
+	require.NoError(t, lock.Release(ctx))
+	_, err = client.AcquireLock(ctx, "cleanup", time.Minute)
+	assert.NoError(t, err)
+}
+
+// TestLockOwnership tests that an expired owner cannot touch its successor's lock
+func TestLockOwnership(t *testing.T) {
+	client, mr := setupTestRedis(t)
+	defer mr.Close()
+	ctx := context.Background()
+
+	stale, err := client.AcquireLock(ctx, "cleanup", time.Second)
+	require.NoError(t, err)
+	mr.FastForward(2 * time.Second)
+
+	current, err := client.AcquireLock(ctx, "cleanup", time.Minute)
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, stale.Refresh(ctx), ErrLockLost)
+	require.NoError(t, stale.Release(ctx))
+	assert.True(t, mr.Exists("lock:cleanup"))
+
+	require.NoError(t, current.Refresh(ctx))
+	assert.Equal(t, time.Minute, mr.TTL("lock:cleanup"))
+}
+
+// TestWithLock tests that WithLock runs once per holder and releases afterwards
+func TestWithLock(t *testing.T) {
+	client, mr := setupTestRedis(t)
+	defer mr.Close()
+	ctx := context.Background()
+
+	jobErr := errors.New("job failed")
+	err := client.WithLock(ctx, "trending", time.Minute, func(ctx context.Context) error {
+		// A second replica is turned away while the first one runs
+		nested := client.WithLock(ctx, "trending", time.Minute, func(ctx context.Context) error {
+			t.Fatal("nested job should not run")
+			return nil
+		})
+		assert.ErrorIs(t, nested, ErrLockNotAcquired)
+		return jobErr
+	})
+
+	assert.ErrorIs(t, err, jobErr)
+	assert.False(t, mr.Exists("lock:trending"))
+}
+
+// TestLeaderCampaign tests that one replica leads until its lease lapses
+func TestLeaderCampaign(t *testing.T) {
+	client, mr := setupTestRedis(t)
+	defer mr.Close()
+	ctx := context.Background()
+
+	first := client.NewLeader("telemetry", time.Minute)
+	second := client.NewLeader("telemetry", time.Minute)
+
+	leading, err := first.Campaign(ctx)
+	require.NoError(t, err)
+	assert.True(t, leading)
+
+	leading, err = second.Campaign(ctx)
+	require.NoError(t, err)
+	assert.False(t, leading)
+
+	// The leader keeps renewing its lease
+	mr.FastForward(50 * time.Second)
+	leading, err = first.Campaign(ctx)
+	require.NoError(t, err)
+	assert.True(t, leading)
+	assert.Equal(t, time.Minute, mr.TTL("lock:leader:telemetry"))
+
+	// Another replica takes over once the leader stops campaigning
+	mr.FastForward(2 * time.Minute)
+	leading, err = second.Campaign(ctx)
+	require.NoError(t, err)
+	assert.True(t, leading)
+
+	require.NoError(t, second.Resign(ctx))
+	assert.False(t, second.IsLeader())
+	assert.False(t, mr.Exists("lock:leader:telemetry"))
+}
@@ -0,0 +1,82 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// takeTokensScript refills a bucket for the time since it was last touched,
+// then spends the cost if that leaves no more than maxDebt owed. The balance
+// comes back as a string since Lua numbers are truncated to integers on the
+// way out
+var takeTokensScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local cost = tonumber(ARGV[3])
+local debt = tonumber(ARGV[4])
+local now = tonumber(ARGV[5])
+
+local state = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(state[1]) or capacity
+local ts = tonumber(state[2]) or now
+if now > ts then
+	tokens = math.min(capacity, tokens + (now - ts) * rate)
+	ts = now
+end
+
+local allowed = 0
+if tokens - cost >= -debt then
+	tokens = tokens - cost
+	allowed = 1
+end
+
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "ts", ts)
+redis.call("PEXPIRE", KEYS[1], ARGV[6])
+return {allowed, tostring(tokens)}`)
+
+// TokenBucket is the shape of a rate limit: up to Capacity tokens, refilled
+// at PerSecond. MaxDebt lets the balance go that far below zero, for callers
+// that wait out the debt instead of failing
+type TokenBucket struct {
+	Capacity  float64
+	PerSecond float64
+	MaxDebt   float64
+}
+
+// TakeTokens spends cost tokens from the bucket stored at key and returns the
+// balance left. When that would exceed the allowed debt nothing is spent and
+// ok is false. A cost of 0 reads the balance
+func (c *Client) TakeTokens(ctx context.Context, key string, bucket TokenBucket, cost float64, now time.Time) (float64, bool, error) {
+	// Keep the key until a drained bucket is full again
+	ttl := time.Minute
+	if bucket.PerSecond > 0 {
+		ttl += time.Duration((bucket.Capacity + bucket.MaxDebt) / bucket.PerSecond * float64(time.Second))
+	}
+
+	result, err := takeTokensScript.Run(ctx, c.Client, []string{key},
+		bucket.Capacity,
+		bucket.PerSecond/1000,
+		cost,
+		bucket.MaxDebt,
+		now.UnixMilli(),
+		ttl.Milliseconds(),
+	).Slice()
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to take tokens from %s: %w", key, err)
+	}
+	if len(result) != 2 {
+		return 0, false, fmt.Errorf("unexpected token bucket reply %v", result)
+	}
+
+	allowed, _ := result[0].(int64)
+	balance, _ := result[1].(string)
+	tokens, err := strconv.ParseFloat(balance, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid token balance %q: %w", balance, err)
+	}
+	return tokens, allowed == 1, nil
+}
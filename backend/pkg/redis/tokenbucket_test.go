@@ -0,0 +1,50 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTakeTokens tests that a bucket drains, goes into debt and refills over time
+func TestTakeTokens(t *testing.T) {
+	client, mr := setupTestRedis(t)
+	defer mr.Close()
+	ctx := context.Background()
+
+	bucket := TokenBucket{Capacity: 3, PerSecond: 1, MaxDebt: 1}
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	for want := 2.0; want >= -1; want-- {
+		tokens, ok, err := client.TakeTokens(ctx, "quota:api:1", bucket, 1, now)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, want, tokens)
+	}
+
+	// Past the debt nothing is spent
+	tokens, ok, err := client.TakeTokens(ctx, "quota:api:1", bucket, 1, now)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, -1.0, tokens)
+
+	// Half a second refills half a token
+	tokens, ok, err = client.TakeTokens(ctx, "quota:api:1", bucket, 0, now.Add(500*time.Millisecond))
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, -0.5, tokens)
+
+	// A bucket never fills past its capacity
+	tokens, _, err = client.TakeTokens(ctx, "quota:api:1", bucket, 1, now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 2.0, tokens)
+	assert.Equal(t, time.Minute+4*time.Second, mr.TTL("quota:api:1"))
+
+	// Buckets are independent
+	tokens, _, err = client.TakeTokens(ctx, "quota:api:2", bucket, 1, now)
+	require.NoError(t, err)
+	assert.Equal(t, 2.0, tokens)
+}
@@ -0,0 +1,82 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// userScopedCollections hold documents that each belong to one user, such
+// as bookmarks with their notes and pages archived with the user's own
+// credentials. The client scopes every search of them to a user itself,
+// so no caller can leave the scope out or widen it
+var userScopedCollections = map[string]bool{
+	"bookmarks": true,
+	"archives":  true,
+}
+
+var (
+	// ErrMissingScope is returned for a search of user data without the
+	// user it is made for
+	ErrMissingScope = errors.New("search of user data without a user scope")
+	// ErrInvalidFilter is returned for a filter whose groups or quotes
+	// don't close, which could otherwise escape the user scope
+	ErrInvalidFilter = errors.New("search filter is not well formed")
+)
+
+type userScopeKey struct{}
+
+// WithUser scopes the searches made with ctx to the documents of a user
+func WithUser(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, userScopeKey{}, userID)
+}
+
+// UserFrom returns the user searches made with ctx are scoped to
+func UserFrom(ctx context.Context) (string, bool) {
+	userID, ok := ctx.Value(userScopeKey{}).(string)
+	return userID, ok && userID != ""
+}
+
+// ScopeFilter restricts a filter to a user's documents. The user clause
+// comes first and the filter is grouped after it, so nothing in the filter
+// can match documents of other users
+func ScopeFilter(userID, filter string) (string, error) {
+	id, err := strconv.ParseUint(userID, 10, 64)
+	if err != nil || id == 0 || strconv.FormatUint(id, 10) != userID {
+		return "", fmt.Errorf("%w: invalid user %q", ErrMissingScope, userID)
+	}
+	scope := "user_id:=" + userID
+
+	filter = strings.TrimSpace(filter)
+	if filter == "" {
+		return scope, nil
+	}
+	if !wellFormed(filter) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidFilter, filter)
+	}
+	return scope + " && (" + filter + ")", nil
+}
+
+// wellFormed reports whether every group and backtick quoted value of a
+// filter is closed, and no group closes before it opens
+func wellFormed(filter string) bool {
+	depth := 0
+	quoted := false
+	for _, r := range filter {
+		switch {
+		case r == '`':
+			quoted = !quoted
+		case quoted:
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+			if depth < 0 {
+				return false
+			}
+		}
+	}
+	return depth == 0 && !quoted
+}
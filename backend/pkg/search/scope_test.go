@@ -0,0 +1,162 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/typesense/typesense-go/typesense/api"
+
+	"bookmark-sync-service/backend/internal/config"
+)
+
+func TestScopeFilter(t *testing.T) {
+	tests := []struct {
+		userID, filter, want string
+		err                  error
+	}{
+		{"7", "", "user_id:=7", nil},
+		{"7", "  ", "user_id:=7", nil},
+		{"7", "language:[en]", "user_id:=7 && (language:[en])", nil},
+		{"7", "tags:=`go` || user_id:=8", "user_id:=7 && (tags:=`go` || user_id:=8)", nil},
+		{"7", "notes:`a ) b`", "user_id:=7 && (notes:`a ) b`)", nil},
+		{"", "language:[en]", "", ErrMissingScope},
+		{"0", "", "", ErrMissingScope},
+		{"07", "", "", ErrMissingScope},
+		{"7 || user_id:8", "", "", ErrMissingScope},
+		{"7", "x:1) || (user_id:8", "", ErrInvalidFilter},
+		{"7", "tags:=`go) || (user_id:8", "", ErrInvalidFilter},
+		{"7", "(x:1", "", ErrInvalidFilter},
+	}
+	for _, tt := range tests {
+		got, err := ScopeFilter(tt.userID, tt.filter)
+		if tt.err != nil {
+			assert.True(t, errors.Is(err, tt.err), "%q %q: %v", tt.userID, tt.filter, err)
+			continue
+		}
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, got)
+	}
+}
+
+// FuzzScopeFilter tries to reach other users' documents through the user
+// and the filter of a search. A scoped filter must keep its user clause at
+// the top level, with the rest grouped where it can only narrow it
+func FuzzScopeFilter(f *testing.F) {
+	for _, seed := range [][2]string{
+		{"7", ""},
+		{"7", "user_id:=8"},
+		{"7", ") || (user_id:=8"},
+		{"7", "x:1) || user_id:=8 || (y:1"},
+		{"7", "tags:=`a` || user_id:=8 || tags:=`b`"},
+		{"7", "notes:`) || (user_id:=8`"},
+		{"7", "notes:`x` ) || (`y`"},
+		{"7 || user_id:=8", ""},
+		{"7) || (user_id:=8", "x:1"},
+		{"*", ""},
+	} {
+		f.Add(seed[0], seed[1])
+	}
+
+	f.Fuzz(func(t *testing.T, userID, filter string) {
+		scoped, err := ScopeFilter(userID, filter)
+		if err != nil {
+			return
+		}
+
+		id, parseErr := strconv.ParseUint(userID, 10, 64)
+		if parseErr != nil || id == 0 || strconv.FormatUint(id, 10) != userID {
+			t.Fatalf("scoped to invalid user %q: %q", userID, scoped)
+		}
+		scope := "user_id:=" + userID
+		if scoped == scope {
+			return
+		}
+		rest, ok := strings.CutPrefix(scoped, scope+" && (")
+		if !ok || !strings.HasSuffix(rest, ")") {
+			t.Fatalf("scope is not the first clause: %q", scoped)
+		}
+
+		// The group opened after the scope only closes at the very end
+		depth, quoted := 1, false
+		for i, r := range rest {
+			switch {
+			case r == '`':
+				quoted = !quoted
+			case quoted:
+			case r == '(':
+				depth++
+			case r == ')':
+				depth--
+				if depth == 0 && i != len(rest)-1 {
+					t.Fatalf("filter escapes the user scope: %q", scoped)
+				}
+			}
+		}
+		if depth != 0 || quoted {
+			t.Fatalf("filter is left open: %q", scoped)
+		}
+	})
+}
+
+// newTestClient returns a client of a fake Typesense that records the
+// filters it is searched with
+func newTestClient(t *testing.T) (*Client, *[]string) {
+	var filters []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		filters = append(filters, r.URL.Query().Get("filter_by"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"found":0,"hits":[]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	address, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	host, port, err := net.SplitHostPort(address.Host)
+	require.NoError(t, err)
+	client, err := NewClient(config.SearchConfig{Host: host, Port: port, APIKey: "test"})
+	require.NoError(t, err)
+	return client, &filters
+}
+
+func TestSearchScopesUserData(t *testing.T) {
+	client, filters := newTestClient(t)
+	ctx := WithUser(context.Background(), "7")
+
+	filter := "language:[en]"
+	params := &api.SearchCollectionParams{Q: "*", QueryBy: "title", FilterBy: &filter}
+	_, err := client.Search(ctx, "bookmarks", params)
+	require.NoError(t, err)
+	_, err = client.Search(ctx, "archives", &api.SearchCollectionParams{Q: "*", QueryBy: "text"})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"user_id:=7 && (language:[en])", "user_id:=7"}, *filters)
+	assert.Equal(t, "language:[en]", *params.FilterBy, "the caller's parameters are left alone")
+}
+
+func TestSearchDeniesUnscopedUserData(t *testing.T) {
+	client, filters := newTestClient(t)
+
+	filter := "user_id:=8"
+	_, err := client.Search(context.Background(), "bookmarks", &api.SearchCollectionParams{Q: "*", QueryBy: "title", FilterBy: &filter})
+	assert.True(t, errors.Is(err, ErrMissingScope))
+
+	escape := "x:1) || (user_id:=8"
+	_, err = client.Search(WithUser(context.Background(), "7"), "bookmarks", &api.SearchCollectionParams{Q: "*", QueryBy: "title", FilterBy: &escape})
+	assert.True(t, errors.Is(err, ErrInvalidFilter))
+	assert.Empty(t, *filters, "denied searches never reach Typesense")
+
+	// Collections carry their own visibility filter
+	visible := "user_id:=7 || visibility:=public"
+	_, err = client.Search(context.Background(), "collections", &api.SearchCollectionParams{Q: "*", QueryBy: "name", FilterBy: &visible})
+	require.NoError(t, err)
+	assert.Equal(t, []string{visible}, *filters)
+}
@@ -3,17 +3,20 @@ package search
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"bookmark-sync-service/backend/internal/config"
 
 	"github.com/typesense/typesense-go/typesense"
 	"github.com/typesense/typesense-go/typesense/api"
+	"go.uber.org/zap"
 )
 
 // Client wraps the Typesense client with additional functionality
 type Client struct {
 	client *typesense.Client
 	config *config.SearchConfig
+	logger *zap.Logger
 }
 
 // NewClient creates a new Typesense client
@@ -27,9 +30,15 @@ func NewClient(cfg config.SearchConfig) (*Client, error) {
 	return &Client{
 		client: client,
 		config: &cfg,
+		logger: zap.NewNop(),
 	}, nil
 }
 
+// SetLogger logs searches denied for leaving out their user scope
+func (c *Client) SetLogger(logger *zap.Logger) {
+	c.logger = logger
+}
+
 // HealthCheck checks if Typesense is healthy
 func (c *Client) HealthCheck(ctx context.Context) error {
 	_, err := c.client.Health(1)
@@ -66,19 +75,67 @@ func (c *Client) DeleteDocument(ctx context.Context, collection string, id strin
 	return err
 }
 
-// Search searches for documents in Typesense
+// ImportDocuments upserts documents in one bulk import request. The
+// results are in the order of the documents; a failed document does not
+// fail the request
+func (c *Client) ImportDocuments(ctx context.Context, collection string, documents []interface{}) ([]*api.ImportDocumentResponse, error) {
+	action := "upsert"
+	return c.client.Collection(collection).Documents().Import(documents, &api.ImportDocumentsParams{Action: &action})
+}
+
+// DeleteDocuments deletes the documents with the given IDs in one request
+func (c *Client) DeleteDocuments(ctx context.Context, collection string, ids []string) (int, error) {
+	filter := "id:[" + strings.Join(ids, ",") + "]"
+	return c.client.Collection(collection).Documents().Delete(&api.DeleteDocumentsParams{FilterBy: &filter})
+}
+
+// Search searches for documents in Typesense. Searches of user data must
+// be made with a context from WithUser; they only match that user's
+// documents, whatever their filter says
 func (c *Client) Search(ctx context.Context, collection string, searchParams *api.SearchCollectionParams) (*api.SearchResult, error) {
+	if userScopedCollections[collection] {
+		scoped, err := c.scope(ctx, collection, searchParams)
+		if err != nil {
+			return nil, err
+		}
+		searchParams = scoped
+	}
 	return c.client.Collection(collection).Documents().Search(searchParams)
 }
 
+// scope returns a copy of the search parameters with the filter restricted
+// to the context's user. Searches that can't be scoped are denied
+func (c *Client) scope(ctx context.Context, collection string, searchParams *api.SearchCollectionParams) (*api.SearchCollectionParams, error) {
+	userID, _ := UserFrom(ctx)
+	filter := ""
+	if searchParams.FilterBy != nil {
+		filter = *searchParams.FilterBy
+	}
+
+	scoped, err := ScopeFilter(userID, filter)
+	if err != nil {
+		c.logger.Warn("Denied search without a user scope",
+			zap.String("collection", collection), zap.String("user_id", userID), zap.String("filter", filter), zap.Error(err))
+		return nil, err
+	}
+	params := *searchParams
+	params.FilterBy = &scoped
+	return &params, nil
+}
+
 // CreateBookmarkCollection creates the bookmarks collection with Chinese language support
 func (c *Client) CreateBookmarkCollection(ctx context.Context) error {
+	return c.CreateCollection(ctx, bookmarkSchema())
+}
+
+// bookmarkSchema is the current schema of the bookmarks collection
+func bookmarkSchema() *api.CollectionSchema {
 	truePtr := true
 	zhPtr := "zh"
 	enPtr := "en"
 	saveCountPtr := "save_count"
 
-	schema := &api.CollectionSchema{
+	return &api.CollectionSchema{
 		Name: "bookmarks",
 		Fields: []api.Field{
 			{
@@ -115,6 +172,12 @@ func (c *Client) CreateBookmarkCollection(ctx context.Context) error {
 				Index: &truePtr,
 				Facet: &truePtr,
 			},
+			{
+				Name:     "tag_ancestors",
+				Type:     "string[]",
+				Index:    &truePtr,
+				Optional: &truePtr,
+			},
 			{
 				Name:  "created_at",
 				Type:  "int64",
@@ -130,20 +193,72 @@ func (c *Client) CreateBookmarkCollection(ctx context.Context) error {
 				Type:  "int32",
 				Index: &truePtr,
 			},
+			{
+				Name:     "domain",
+				Type:     "string",
+				Index:    &truePtr,
+				Facet:    &truePtr,
+				Optional: &truePtr,
+			},
+			{
+				Name:     "content_type",
+				Type:     "string",
+				Index:    &truePtr,
+				Facet:    &truePtr,
+				Optional: &truePtr,
+			},
+			{
+				Name:     "year",
+				Type:     "int32",
+				Index:    &truePtr,
+				Facet:    &truePtr,
+				Optional: &truePtr,
+			},
+			{
+				Name:     "language",
+				Type:     "string",
+				Index:    &truePtr,
+				Facet:    &truePtr,
+				Optional: &truePtr,
+			},
+			{
+				Name:     "source",
+				Type:     "string",
+				Index:    &truePtr,
+				Facet:    &truePtr,
+				Optional: &truePtr,
+			},
+			{
+				Name:     "notes",
+				Type:     "string",
+				Index:    &truePtr,
+				Locale:   &zhPtr,
+				Optional: &truePtr,
+			},
+			{
+				Name:     "summary",
+				Type:     "string",
+				Index:    &truePtr,
+				Locale:   &zhPtr,
+				Optional: &truePtr,
+			},
 		},
 		DefaultSortingField: &saveCountPtr,
 	}
-
-	return c.CreateCollection(ctx, schema)
 }
 
 // CreateCollectionCollection creates the collections collection with Chinese language support
 func (c *Client) CreateCollectionCollection(ctx context.Context) error {
+	return c.CreateCollection(ctx, collectionSchema())
+}
+
+// collectionSchema is the current schema of the collections collection
+func collectionSchema() *api.CollectionSchema {
 	truePtr := true
 	zhPtr := "zh"
 	bookmarkCountPtr := "bookmark_count"
 
-	schema := &api.CollectionSchema{
+	return &api.CollectionSchema{
 		Name: "collections",
 		Fields: []api.Field{
 			{
@@ -189,11 +304,102 @@ func (c *Client) CreateCollectionCollection(ctx context.Context) error {
 				Type:  "int32",
 				Index: &truePtr,
 			},
+			{
+				Name:     "parent_id",
+				Type:     "string",
+				Index:    &truePtr,
+				Optional: &truePtr,
+			},
+			{
+				Name:     "path",
+				Type:     "string[]",
+				Index:    &truePtr,
+				Optional: &truePtr,
+				Locale:   &zhPtr,
+			},
 		},
 		DefaultSortingField: &bookmarkCountPtr,
 	}
+}
+
+// MigrateSchemas brings existing collections up to the current schemas.
+// Collections created by an earlier version lack the fields added since,
+// and documents written to them are rejected or lose those fields
+func (c *Client) MigrateSchemas(ctx context.Context) error {
+	for _, schema := range []*api.CollectionSchema{bookmarkSchema(), collectionSchema()} {
+		if err := c.migrateSchema(ctx, schema); err != nil {
+			return fmt.Errorf("failed to migrate %s collection: %w", schema.Name, err)
+		}
+	}
+	return nil
+}
+
+// migrateSchema patches a collection with the fields it is missing and
+// re-adds those whose definition changed, e.g. fields made facets
+func (c *Client) migrateSchema(ctx context.Context, schema *api.CollectionSchema) error {
+	existing, err := c.client.Collection(schema.Name).Retrieve()
+	if err != nil {
+		return err
+	}
 
-	return c.CreateCollection(ctx, schema)
+	changes := schemaChanges(existing.Fields, schema.Fields)
+	if len(changes) == 0 {
+		return nil
+	}
+	if _, err := c.client.Collection(schema.Name).Update(&api.CollectionUpdateSchema{Fields: changes}); err != nil {
+		return err
+	}
+	c.logger.Info("Migrated search collection schema", zap.String("collection", schema.Name), zap.Int("fields", len(changes)))
+	return nil
+}
+
+// schemaChanges lists the updates turning the current fields into the
+// wanted ones. A changed field is dropped and added in the same update, which
+// Typesense re-indexes from the stored documents
+func schemaChanges(current, wanted []api.Field) []api.Field {
+	byName := make(map[string]api.Field, len(current))
+	for _, field := range current {
+		byName[field.Name] = field
+	}
+
+	var changes []api.Field
+	dropPtr := true
+	for _, field := range wanted {
+		if field.Name == "id" {
+			continue
+		}
+		old, ok := byName[field.Name]
+		if ok && sameField(old, field) {
+			continue
+		}
+		if ok {
+			changes = append(changes, api.Field{Name: field.Name, Drop: &dropPtr})
+		}
+		changes = append(changes, field)
+	}
+	return changes
+}
+
+// sameField compares the settings the schemas set, reading unset ones as
+// the Typesense defaults
+func sameField(a, b api.Field) bool {
+	flag := func(value *bool, fallback bool) bool {
+		if value == nil {
+			return fallback
+		}
+		return *value
+	}
+	locale := func(value *string) string {
+		if value == nil {
+			return ""
+		}
+		return *value
+	}
+	return a.Type == b.Type &&
+		flag(a.Facet, false) == flag(b.Facet, false) &&
+		flag(a.Optional, false) == flag(b.Optional, false) &&
+		flag(a.Index, true) == flag(b.Index, true) &&
+		locale(a.Locale) == locale(b.Locale)
 }
 
 // IndexBookmark indexes a bookmark in Typesense
@@ -208,11 +414,11 @@ func (c *Client) IndexCollection(ctx context.Context, collection interface{}) er
 
 // SearchBookmarks searches for bookmarks in Typesense
 func (c *Client) SearchBookmarks(ctx context.Context, query string, userID string, filters map[string]string, page, limit int) (*api.SearchResult, error) {
-	filterBy := fmt.Sprintf("user_id:%s", userID)
-
+	var clauses []string
 	for key, value := range filters {
-		filterBy += fmt.Sprintf(" && %s:%s", key, value)
+		clauses = append(clauses, fmt.Sprintf("%s:%s", key, value))
 	}
+	filterBy := strings.Join(clauses, " && ")
 
 	searchParams := &api.SearchCollectionParams{
 		Q:        query,
@@ -222,7 +428,7 @@ func (c *Client) SearchBookmarks(ctx context.Context, query string, userID strin
 		PerPage:  &limit,
 	}
 
-	return c.Search(ctx, "bookmarks", searchParams)
+	return c.Search(WithUser(ctx, userID), "bookmarks", searchParams)
 }
 
 // SearchCollections searches for collections in Typesense
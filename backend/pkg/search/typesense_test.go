@@ -0,0 +1,50 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/typesense/typesense-go/typesense/api"
+)
+
+func TestSchemaChanges(t *testing.T) {
+	truePtr, falsePtr := true, false
+	zhPtr := "zh"
+
+	// A collection created before facets, tag ancestors, language, notes
+	// and summaries were added
+	current := []api.Field{
+		{Name: "id", Type: "string"},
+		{Name: "user_id", Type: "string", Index: &truePtr, Facet: &falsePtr},
+		{Name: "title", Type: "string", Locale: &zhPtr},
+		{Name: "description", Type: "string", Locale: &zhPtr},
+		{Name: "url", Type: "string", Locale: stringPtr("en")},
+		{Name: "tags", Type: "string[]", Facet: &truePtr},
+		{Name: "created_at", Type: "int64"},
+		{Name: "updated_at", Type: "int64"},
+		{Name: "save_count", Type: "int32"},
+		{Name: "domain", Type: "string", Optional: &truePtr},
+	}
+
+	changes := schemaChanges(current, bookmarkSchema().Fields)
+	names := make([]string, len(changes))
+	for n, field := range changes {
+		names[n] = field.Name
+	}
+	assert.Equal(t, []string{"tag_ancestors", "domain", "domain", "content_type", "year", "language", "source", "notes", "summary"}, names)
+
+	// A field made a facet is dropped and added back in the same update
+	require.NotNil(t, changes[1].Drop)
+	assert.True(t, *changes[1].Drop)
+	require.NotNil(t, changes[2].Facet)
+	assert.True(t, *changes[2].Facet)
+	assert.Nil(t, changes[2].Drop)
+
+	// An up-to-date collection needs no update
+	assert.Empty(t, schemaChanges(bookmarkSchema().Fields, bookmarkSchema().Fields))
+}
+
+func stringPtr(value string) *string {
+	return &value
+}
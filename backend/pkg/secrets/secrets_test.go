@@ -0,0 +1,40 @@
+package secrets
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCipher_RoundTrip(t *testing.T) {
+	c, err := NewCipher("passphrase")
+	require.NoError(t, err)
+
+	encrypted, err := c.Encrypt("s3-secret-key")
+	require.NoError(t, err)
+	assert.NotContains(t, encrypted, "s3-secret-key")
+
+	again, err := c.Encrypt("s3-secret-key")
+	require.NoError(t, err)
+	assert.NotEqual(t, encrypted, again, "nonces should differ")
+
+	decrypted, err := c.Decrypt(encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, "s3-secret-key", decrypted)
+}
+
+func TestCipher_Errors(t *testing.T) {
+	_, err := NewCipher("")
+	assert.Error(t, err)
+
+	c, _ := NewCipher("passphrase")
+	other, _ := NewCipher("other")
+
+	encrypted, _ := c.Encrypt("value")
+	_, err = other.Decrypt(encrypted)
+	assert.ErrorIs(t, err, ErrInvalidCiphertext)
+
+	_, err = c.Decrypt("not base64!")
+	assert.ErrorIs(t, err, ErrInvalidCiphertext)
+}
@@ -0,0 +1,124 @@
+// Package summarize produces short summaries of bookmarked pages through a
+// pluggable provider. The bundled provider posts to any HTTP endpoint, so
+// self-hosted instances can put whichever model they like behind it
+package summarize
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"bookmark-sync-service/backend/internal/config"
+)
+
+// ErrDisabled is returned when no summarization provider is configured
+var ErrDisabled = errors.New("summarization is not configured")
+
+// Request describes the page to summarize
+type Request struct {
+	URL         string `json:"url"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Content     string `json:"content,omitempty"` // extracted page text, when available
+	Language    string `json:"language,omitempty"`
+	MaxLength   int    `json:"max_length,omitempty"` // characters
+}
+
+// Summarizer turns a page into a short summary
+type Summarizer interface {
+	Summarize(ctx context.Context, req Request) (string, error)
+}
+
+// response is the body expected from an HTTP provider
+type response struct {
+	Summary string `json:"summary"`
+}
+
+// HTTPProvider posts requests as JSON to a configured endpoint
+type HTTPProvider struct {
+	endpoint   string
+	apiKey     string
+	maxLength  int
+	httpClient *http.Client
+}
+
+// NewHTTPProvider creates a provider for the configured endpoint. It returns
+// nil when no endpoint is configured
+func NewHTTPProvider(cfg config.SummarizerConfig) *HTTPProvider {
+	if cfg.Endpoint == "" {
+		return nil
+	}
+
+	return &HTTPProvider{
+		endpoint:   cfg.Endpoint,
+		apiKey:     cfg.APIKey,
+		maxLength:  cfg.MaxLength,
+		httpClient: &http.Client{Timeout: time.Duration(cfg.Timeout) * time.Second},
+	}
+}
+
+// Summarize asks the endpoint for a summary, truncated to the maximum length
+func (p *HTTPProvider) Summarize(ctx context.Context, req Request) (string, error) {
+	if req.MaxLength == 0 {
+		req.MaxLength = p.maxLength
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode summary request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("User-Agent", "bookmark-sync-service/"+config.Version)
+	if p.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return "", fmt.Errorf("failed to reach summarizer: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("summarizer returned HTTP %d", resp.StatusCode)
+	}
+
+	var result response
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode summary: %w", err)
+	}
+
+	summary := strings.TrimSpace(result.Summary)
+	if summary == "" {
+		return "", errors.New("summarizer returned an empty summary")
+	}
+
+	return Truncate(summary, req.MaxLength), nil
+}
+
+// Truncate shortens a summary to at most max characters, cutting at a word
+// boundary where possible. A max of 0 leaves it alone
+func Truncate(summary string, max int) string {
+	if max <= 0 || utf8.RuneCountInString(summary) <= max {
+		return summary
+	}
+
+	runes := []rune(summary)
+	cut := string(runes[:max-1])
+	if space := strings.LastIndex(cut, " "); space > len(cut)/2 {
+		cut = cut[:space]
+	}
+	return strings.TrimRight(cut, " ,.;:") + "…"
+}
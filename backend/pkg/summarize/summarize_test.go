@@ -0,0 +1,68 @@
+package summarize
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bookmark-sync-service/backend/internal/config"
+)
+
+func TestNewHTTPProvider_Disabled(t *testing.T) {
+	assert.Nil(t, NewHTTPProvider(config.SummarizerConfig{}))
+}
+
+func TestHTTPProvider_Summarize(t *testing.T) {
+	var received Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		json.NewEncoder(w).Encode(map[string]string{"summary": "  Go is a programming language.  "})
+	}))
+	defer server.Close()
+
+	provider := NewHTTPProvider(config.SummarizerConfig{Endpoint: server.URL, APIKey: "secret", Timeout: 5, MaxLength: 100})
+	summary, err := provider.Summarize(context.Background(), Request{URL: "https://go.dev", Title: "Go"})
+	require.NoError(t, err)
+	assert.Equal(t, "Go is a programming language.", summary)
+	assert.Equal(t, "https://go.dev", received.URL)
+	assert.Equal(t, 100, received.MaxLength)
+}
+
+func TestHTTPProvider_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr string
+	}{
+		{name: "server error", status: http.StatusInternalServerError, body: "", wantErr: "summarizer returned HTTP 500"},
+		{name: "empty summary", status: http.StatusOK, body: `{"summary":""}`, wantErr: "summarizer returned an empty summary"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			provider := NewHTTPProvider(config.SummarizerConfig{Endpoint: server.URL, Timeout: 5})
+			_, err := provider.Summarize(context.Background(), Request{URL: "https://go.dev"})
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "short", Truncate("short", 10))
+	assert.Equal(t, "unlimited text", Truncate("unlimited text", 0))
+	assert.Equal(t, "The quick brown…", Truncate("The quick brown fox jumps", 18))
+}
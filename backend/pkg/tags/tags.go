@@ -0,0 +1,190 @@
+package tags
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Separator separates namespace segments in hierarchical tags, e.g. "dev/go"
+const Separator = "/"
+
+// Wildcard suffix matching every descendant of a tag, e.g. "dev/*"
+const Wildcard = Separator + "*"
+
+// Node represents a tag in the tag tree
+type Node struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	// Count is the number of bookmarks tagged with exactly this path
+	Count int `json:"count"`
+	// TotalCount also includes bookmarks tagged with any descendant
+	TotalCount int     `json:"total_count"`
+	Children   []*Node `json:"children,omitempty"`
+}
+
+// Normalize trims whitespace around every segment and drops empty segments,
+// so " dev / go/ " becomes "dev/go"
+func Normalize(tag string) string {
+	segments := strings.Split(tag, Separator)
+	cleaned := make([]string, 0, len(segments))
+	for _, segment := range segments {
+		if segment = strings.TrimSpace(segment); segment != "" {
+			cleaned = append(cleaned, segment)
+		}
+	}
+	return strings.Join(cleaned, Separator)
+}
+
+// NormalizeAll normalizes a tag list, dropping empty and duplicate tags while
+// preserving order
+func NormalizeAll(list []string) []string {
+	seen := make(map[string]bool, len(list))
+	result := make([]string, 0, len(list))
+	for _, tag := range list {
+		tag = Normalize(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+	return result
+}
+
+// Ancestors returns the proper ancestors of a tag, closest to the root first:
+// "dev/go/testing" yields ["dev", "dev/go"]
+func Ancestors(tag string) []string {
+	segments := strings.Split(Normalize(tag), Separator)
+	ancestors := make([]string, 0, len(segments)-1)
+	for i := 1; i < len(segments); i++ {
+		ancestors = append(ancestors, strings.Join(segments[:i], Separator))
+	}
+	return ancestors
+}
+
+// AllAncestors returns the distinct proper ancestors of every tag in the list
+func AllAncestors(list []string) []string {
+	seen := make(map[string]bool)
+	result := []string{}
+	for _, tag := range list {
+		for _, ancestor := range Ancestors(tag) {
+			if !seen[ancestor] {
+				seen[ancestor] = true
+				result = append(result, ancestor)
+			}
+		}
+	}
+	return result
+}
+
+// ParsePattern splits a tag query into its base tag and whether it only
+// matches descendants ("dev/*") rather than the tag and its descendants ("dev")
+func ParsePattern(pattern string) (base string, descendantsOnly bool) {
+	pattern = strings.TrimSpace(pattern)
+	if strings.HasSuffix(pattern, Wildcard) {
+		return Normalize(strings.TrimSuffix(pattern, Wildcard)), true
+	}
+	return Normalize(pattern), false
+}
+
+// queryTerm matches tag:dev and tag:dev/* in a search query
+var queryTerm = regexp.MustCompile(`(?i)(?:^|\s)tag:(\S+)`)
+
+// SplitQuery separates tag: terms from the rest of a search query, so
+// "generics tag:dev/go" searches for "generics" in bookmarks tagged dev/go or
+// below it. Every term must match
+func SplitQuery(query string) (string, []string) {
+	var patterns []string
+	for _, match := range queryTerm.FindAllStringSubmatch(query, -1) {
+		if base, _ := ParsePattern(match[1]); base != "" {
+			patterns = append(patterns, strings.TrimSpace(match[1]))
+		}
+	}
+	rest := queryTerm.ReplaceAllString(query, " ")
+	return strings.Join(strings.Fields(rest), " "), patterns
+}
+
+// Matches reports whether a tag satisfies a pattern. A plain pattern matches
+// the tag itself and all of its children; a wildcard pattern only matches children
+func Matches(tag, pattern string) bool {
+	base, descendantsOnly := ParsePattern(pattern)
+	if base == "" {
+		return false
+	}
+
+	tag = Normalize(tag)
+	if tag == base {
+		return !descendantsOnly
+	}
+	return strings.HasPrefix(tag, base+Separator)
+}
+
+// Rename moves a tag, and any descendant, from one namespace to another.
+// It returns the renamed tag and whether the tag was affected
+func Rename(tag, from, to string) (string, bool) {
+	tag, from, to = Normalize(tag), Normalize(from), Normalize(to)
+	if from == "" || to == "" {
+		return tag, false
+	}
+
+	if tag == from {
+		return to, true
+	}
+	if strings.HasPrefix(tag, from+Separator) {
+		return to + strings.TrimPrefix(tag, from), true
+	}
+	return tag, false
+}
+
+// BuildTree builds a sorted tag tree from per-tag bookmark counts. Missing
+// intermediate namespaces are created with a zero count
+func BuildTree(counts map[string]int) []*Node {
+	root := &Node{}
+	index := map[string]*Node{"": root}
+
+	paths := make([]string, 0, len(counts))
+	for path := range counts {
+		if path = Normalize(path); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		parent := root
+		segments := strings.Split(path, Separator)
+		for i, segment := range segments {
+			current := strings.Join(segments[:i+1], Separator)
+			node, ok := index[current]
+			if !ok {
+				node = &Node{Name: segment, Path: current}
+				index[current] = node
+				parent.Children = append(parent.Children, node)
+			}
+			parent = node
+		}
+	}
+
+	for path, count := range counts {
+		path = Normalize(path)
+		if path == "" {
+			continue
+		}
+		index[path].Count += count
+		for _, ancestor := range append(Ancestors(path), path) {
+			index[ancestor].TotalCount += count
+		}
+	}
+
+	return root.Children
+}
+
+// Migrate converts a flat tag that uses a legacy separator (for example
+// "dev:go" with separator ":") into a namespaced tag ("dev/go")
+func Migrate(tag, separator string) string {
+	if separator == "" || separator == Separator {
+		return Normalize(tag)
+	}
+	return Normalize(strings.ReplaceAll(tag, separator, Separator))
+}
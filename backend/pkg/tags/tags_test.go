@@ -0,0 +1,97 @@
+package tags
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalize(t *testing.T) {
+	assert.Equal(t, "dev/go", Normalize(" dev / go/ "))
+	assert.Equal(t, "dev", Normalize("/dev//"))
+	assert.Equal(t, "", Normalize(" / "))
+	assert.Equal(t, []string{"dev/go", "news"}, NormalizeAll([]string{"dev/go", " news ", "dev / go", ""}))
+}
+
+func TestAncestors(t *testing.T) {
+	assert.Equal(t, []string{"dev", "dev/go"}, Ancestors("dev/go/testing"))
+	assert.Empty(t, Ancestors("dev"))
+	assert.Equal(t, []string{"dev", "dev/go"}, AllAncestors([]string{"dev/go/testing", "dev/rust"}))
+}
+
+func TestSplitQuery(t *testing.T) {
+	rest, patterns := SplitQuery("generics TAG:dev/go tips tag:news/* tag:/")
+	assert.Equal(t, "generics tips", rest)
+	assert.Equal(t, []string{"dev/go", "news/*"}, patterns)
+
+	rest, patterns = SplitQuery("no tags here")
+	assert.Equal(t, "no tags here", rest)
+	assert.Empty(t, patterns)
+}
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		tag     string
+		pattern string
+		want    bool
+	}{
+		{"dev", "dev", true},
+		{"dev/go", "dev", true},
+		{"dev/go/testing", "dev", true},
+		{"devops", "dev", false},
+		{"dev", "dev/*", false},
+		{"dev/go", "dev/*", true},
+		{"dev/go", "dev/go", true},
+		{"dev/rust", "dev/go", false},
+		{"dev", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tag+"~"+tt.pattern, func(t *testing.T) {
+			assert.Equal(t, tt.want, Matches(tt.tag, tt.pattern))
+		})
+	}
+}
+
+func TestRename(t *testing.T) {
+	renamed, ok := Rename("dev/go/testing", "dev", "programming")
+	assert.True(t, ok)
+	assert.Equal(t, "programming/go/testing", renamed)
+
+	renamed, ok = Rename("dev", "dev", "programming")
+	assert.True(t, ok)
+	assert.Equal(t, "programming", renamed)
+
+	renamed, ok = Rename("devops", "dev", "programming")
+	assert.False(t, ok)
+	assert.Equal(t, "devops", renamed)
+}
+
+func TestBuildTree(t *testing.T) {
+	tree := BuildTree(map[string]int{
+		"dev/go":   2,
+		"dev/rust": 1,
+		"news":     4,
+	})
+
+	require.Len(t, tree, 2)
+
+	dev := tree[0]
+	assert.Equal(t, "dev", dev.Path)
+	assert.Equal(t, 0, dev.Count)
+	assert.Equal(t, 3, dev.TotalCount)
+	require.Len(t, dev.Children, 2)
+	assert.Equal(t, "go", dev.Children[0].Name)
+	assert.Equal(t, "dev/go", dev.Children[0].Path)
+	assert.Equal(t, 2, dev.Children[0].Count)
+
+	assert.Equal(t, "news", tree[1].Path)
+	assert.Equal(t, 4, tree[1].TotalCount)
+}
+
+func TestMigrate(t *testing.T) {
+	assert.Equal(t, "dev/go", Migrate("dev:go", ":"))
+	assert.Equal(t, "dev/go", Migrate("dev/go", ""))
+	assert.Equal(t, "dev/go/testing", Migrate("dev.go.testing", "."))
+}
@@ -0,0 +1,238 @@
+// Package testfactory builds and stores models for tests: each builder
+// fills in valid, unique defaults that override functions can change, so a
+// test only spells out the fields it is about
+package testfactory
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"bookmark-sync-service/backend/internal/automation"
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+// Factory creates models in a test database
+type Factory struct {
+	t   testing.TB
+	db  *gorm.DB
+	seq uint64
+}
+
+// New returns a factory writing to db that fails t on errors
+func New(t testing.TB, db *gorm.DB) *Factory {
+	return &Factory{t: t, db: db}
+}
+
+// NewDB opens an in-memory SQLite database for a test and migrates the
+// models, or the full schema when none are given
+func NewDB(t testing.TB, models ...interface{}) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	// Every connection to :memory: is a new empty database
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if len(models) == 0 {
+		require.NoError(t, database.AutoMigrate(db))
+	} else {
+		require.NoError(t, db.AutoMigrate(models...))
+	}
+	return db
+}
+
+// DB returns the factory's database
+func (f *Factory) DB() *gorm.DB {
+	return f.db
+}
+
+// next returns a number unique within the factory, for unique columns
+func (f *Factory) next() uint64 {
+	return atomic.AddUint64(&f.seq, 1)
+}
+
+func (f *Factory) create(value interface{}) {
+	f.t.Helper()
+	require.NoError(f.t, f.db.Create(value).Error)
+}
+
+// keepFalse restores a flag set to false that Create replaced with the
+// column's default:true, as GORM skips zero values that have a default
+func (f *Factory) keepFalse(value interface{}, column string, flag bool) {
+	f.t.Helper()
+	if !flag {
+		require.NoError(f.t, f.db.Model(value).Update(column, false).Error)
+	}
+}
+
+// User creates a user with a unique email, username and Supabase ID
+func (f *Factory) User(overrides ...func(*database.User)) *database.User {
+	f.t.Helper()
+	n := f.next()
+	user := &database.User{
+		Email:       fmt.Sprintf("user%d@example.com", n),
+		Username:    fmt.Sprintf("user%d", n),
+		DisplayName: fmt.Sprintf("User %d", n),
+		SupabaseID:  fmt.Sprintf("supabase-user-%d", n),
+	}
+	for _, override := range overrides {
+		override(user)
+	}
+	f.create(user)
+	return user
+}
+
+// Bookmark creates an active bookmark of the user
+func (f *Factory) Bookmark(userID uint, overrides ...func(*database.Bookmark)) *database.Bookmark {
+	f.t.Helper()
+	n := f.next()
+	bookmark := &database.Bookmark{
+		UserID: userID,
+		URL:    fmt.Sprintf("https://example.com/articles/%d", n),
+		Title:  fmt.Sprintf("Article %d", n),
+		Tags:   "[]",
+		Status: "active",
+	}
+	for _, override := range overrides {
+		override(bookmark)
+	}
+	f.create(bookmark)
+	return bookmark
+}
+
+// Collection creates a private collection of the user. Collections get a
+// unique share link as the collection service gives them
+func (f *Factory) Collection(userID uint, overrides ...func(*database.Collection)) *database.Collection {
+	f.t.Helper()
+	n := f.next()
+	collection := &database.Collection{
+		UserID:     userID,
+		Name:       fmt.Sprintf("Collection %d", n),
+		Visibility: "private",
+		ShareLink:  fmt.Sprintf("collection-%d", n),
+	}
+	for _, override := range overrides {
+		override(collection)
+	}
+	f.create(collection)
+	return collection
+}
+
+// AddToCollection puts bookmarks in a collection
+func (f *Factory) AddToCollection(collection *database.Collection, bookmarks ...*database.Bookmark) {
+	f.t.Helper()
+	require.NoError(f.t, f.db.Model(collection).Association("Bookmarks").Append(bookmarks))
+}
+
+// Share creates an active, public view share of a collection owned by the
+// collection's owner
+func (f *Factory) Share(collection *database.Collection, overrides ...func(*database.CollectionShare)) *database.CollectionShare {
+	f.t.Helper()
+	n := f.next()
+	share := &database.CollectionShare{
+		CollectionID: collection.ID,
+		UserID:       collection.UserID,
+		ShareType:    "public",
+		Permission:   "view",
+		ShareToken:   fmt.Sprintf("share-token-%d", n),
+		IsActive:     true,
+	}
+	for _, override := range overrides {
+		override(share)
+	}
+	active := share.IsActive
+	f.create(share)
+	f.keepFalse(share, "is_active", active)
+	return share
+}
+
+// Behavior is a tracked user action on a bookmark, stored in the table of
+// the community package's UserBehavior. It holds the columns tests and
+// counters rely on
+type Behavior struct {
+	ID         uint `gorm:"primaryKey"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	DeletedAt  gorm.DeletedAt `gorm:"index"`
+	UserID     string         `gorm:"not null;index"`
+	BookmarkID uint           `gorm:"not null;index"`
+	ActionType string         `gorm:"not null"` // view, click, save, share, like
+	Duration   int
+	Context    string
+}
+
+// TableName maps Behavior onto the community behaviors table
+func (Behavior) TableName() string {
+	return "user_behaviors"
+}
+
+// Behavior records a user action on a bookmark. The behaviors table must
+// exist, e.g. by migrating &Behavior{}
+func (f *Factory) Behavior(userID string, bookmarkID uint, actionType string, overrides ...func(*Behavior)) *Behavior {
+	f.t.Helper()
+	behavior := &Behavior{
+		UserID:     userID,
+		BookmarkID: bookmarkID,
+		ActionType: actionType,
+		Context:    "search",
+	}
+	for _, override := range overrides {
+		override(behavior)
+	}
+	f.create(behavior)
+	return behavior
+}
+
+// WebhookEndpoint creates an active webhook endpoint subscribed to bookmark
+// creation
+func (f *Factory) WebhookEndpoint(userID string, overrides ...func(*automation.WebhookEndpoint)) *automation.WebhookEndpoint {
+	f.t.Helper()
+	n := f.next()
+	endpoint := &automation.WebhookEndpoint{
+		UserID:     userID,
+		Name:       fmt.Sprintf("Webhook %d", n),
+		URL:        fmt.Sprintf("https://hooks.example.com/%d", n),
+		Secret:     fmt.Sprintf("secret-%d", n),
+		Events:     automation.StringSlice{string(automation.WebhookEventBookmarkCreated)},
+		Active:     true,
+		RetryCount: 3,
+		Timeout:    30,
+	}
+	for _, override := range overrides {
+		override(endpoint)
+	}
+	active := endpoint.Active
+	f.create(endpoint)
+	f.keepFalse(endpoint, "active", active)
+	return endpoint
+}
+
+// AutomationRule creates an active rule tagging new bookmarks
+func (f *Factory) AutomationRule(userID string, overrides ...func(*automation.AutomationRule)) *automation.AutomationRule {
+	f.t.Helper()
+	n := f.next()
+	rule := &automation.AutomationRule{
+		UserID:     userID,
+		Name:       fmt.Sprintf("Rule %d", n),
+		Trigger:    "bookmark_added",
+		Conditions: automation.InterfaceMap{},
+		Actions:    automation.InterfaceMap{"add_tags": []interface{}{"inbox"}},
+		Active:     true,
+	}
+	for _, override := range overrides {
+		override(rule)
+	}
+	active := rule.Active
+	f.create(rule)
+	f.keepFalse(rule, "active", active)
+	return rule
+}
@@ -0,0 +1,68 @@
+package testfactory
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bookmark-sync-service/backend/internal/automation"
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+func TestFactory_Builders(t *testing.T) {
+	db := NewDB(t)
+	require.NoError(t, db.AutoMigrate(&Behavior{}))
+	f := New(t, db)
+
+	// Defaults are unique, so builders can be called repeatedly
+	alice := f.User(func(u *database.User) { u.Username = "alice" })
+	bob := f.User()
+	assert.Equal(t, "alice", alice.Username)
+	assert.NotEqual(t, alice.Email, bob.Email)
+
+	bookmark := f.Bookmark(alice.ID, func(b *database.Bookmark) { b.Title = "Go" })
+	first, second := f.Collection(alice.ID), f.Collection(alice.ID)
+	f.AddToCollection(first, bookmark, f.Bookmark(alice.ID))
+	share := f.Share(first)
+	f.Behavior("2", bookmark.ID, "save")
+	f.WebhookEndpoint("1")
+	f.AutomationRule("1", func(r *automation.AutomationRule) { r.Active = false })
+
+	assert.Equal(t, alice.ID, share.UserID)
+	assert.NotEqual(t, first.ShareLink, second.ShareLink)
+	assert.Equal(t, int64(2), db.Model(first).Association("Bookmarks").Count())
+
+	var saves int64
+	require.NoError(t, db.Table("user_behaviors").Where("action_type = ?", "save").Count(&saves).Error)
+	assert.Equal(t, int64(1), saves)
+
+	var rule automation.AutomationRule
+	require.NoError(t, db.First(&rule).Error)
+	assert.False(t, rule.Active)
+}
+
+func TestSeed(t *testing.T) {
+	db := NewDB(t)
+	volume := Volume{Users: 3, BookmarksPerUser: 40, CollectionsPerUser: 4, SharedCollections: 0.5, RandSeed: 7}
+
+	dataset, err := Seed(db, volume)
+	require.NoError(t, err)
+
+	var bookmarks, collections, links, shares int64
+	db.Model(&database.Bookmark{}).Count(&bookmarks)
+	db.Model(&database.Collection{}).Count(&collections)
+	db.Table("bookmark_collections").Count(&links)
+	db.Model(&database.CollectionShare{}).Count(&shares)
+	assert.Equal(t, int64(120), bookmarks)
+	assert.Equal(t, int64(12), collections)
+	assert.Equal(t, int64(len(dataset.Shares)), shares)
+	assert.Greater(t, links, int64(40), "most bookmarks are filed")
+	assert.Less(t, links, bookmarks)
+
+	// The same seed gives the same data, and seeding again does not collide
+	again, err := Seed(db, volume)
+	require.NoError(t, err)
+	assert.Equal(t, dataset.Bookmarks[0].Title, again.Bookmarks[0].Title)
+	assert.Len(t, again.Shares, len(dataset.Shares))
+}
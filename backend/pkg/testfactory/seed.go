@@ -0,0 +1,195 @@
+package testfactory
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"bookmark-sync-service/backend/pkg/database"
+)
+
+// seedBatchSize is the number of rows inserted per statement when seeding
+const seedBatchSize = 500
+
+// Volume sizes a seeded dataset
+type Volume struct {
+	Users              int
+	BookmarksPerUser   int
+	CollectionsPerUser int
+	// SharedCollections is the share of collections, 0 to 1, with a public share
+	SharedCollections float64
+	// Span spreads bookmark creation times over this long before now;
+	// a year when zero
+	Span time.Duration
+	// RandSeed makes datasets reproducible; the same seed gives the same data
+	RandSeed int64
+}
+
+// Dataset is what Seed stored
+type Dataset struct {
+	Users       []*database.User
+	Bookmarks   []*database.Bookmark
+	Collections []*database.Collection
+	Shares      []*database.CollectionShare
+}
+
+var (
+	seedDomains = []string{"github.com", "go.dev", "news.ycombinator.com", "medium.com", "dev.to",
+		"stackoverflow.com", "wikipedia.org", "arxiv.org", "youtube.com", "nytimes.com"}
+	seedWords = []string{"database", "performance", "guide", "release", "design", "testing", "search",
+		"sync", "browser", "security", "golang", "postgres", "redis", "cache", "index", "notes"}
+	seedTags = []string{"work", "reading", "go", "infra", "research", "todo", "video", "reference"}
+)
+
+// Seed stores a realistic dataset: bookmarks on a handful of popular
+// domains with tags and words from small vocabularies, spread over time,
+// filed into each user's collections. It takes no testing.TB so tools such
+// as load tests can seed a database too
+func Seed(db *gorm.DB, volume Volume) (*Dataset, error) {
+	random := rand.New(rand.NewSource(volume.RandSeed))
+	span := volume.Span
+	if span <= 0 {
+		span = 365 * 24 * time.Hour
+	}
+	now := time.Now()
+	// Keep unique columns unique across calls on the same database
+	prefix := fmt.Sprintf("seed%d-%d", volume.RandSeed, now.UnixNano())
+
+	dataset := &Dataset{}
+	for u := 0; u < volume.Users; u++ {
+		dataset.Users = append(dataset.Users, &database.User{
+			Email:       fmt.Sprintf("%s-user%d@example.com", prefix, u),
+			Username:    fmt.Sprintf("%s-user%d", prefix, u),
+			DisplayName: fmt.Sprintf("Seed User %d", u),
+			SupabaseID:  fmt.Sprintf("%s-supabase-%d", prefix, u),
+		})
+	}
+	if len(dataset.Users) == 0 {
+		return dataset, nil
+	}
+	if err := db.CreateInBatches(dataset.Users, seedBatchSize).Error; err != nil {
+		return nil, fmt.Errorf("failed to seed users: %w", err)
+	}
+
+	for _, user := range dataset.Users {
+		for c := 0; c < volume.CollectionsPerUser; c++ {
+			collection := &database.Collection{
+				UserID:     user.ID,
+				Name:       capitalize(pick(random, seedWords)) + " " + pick(random, seedWords),
+				Visibility: "private",
+				ShareLink:  fmt.Sprintf("%s-collection-%d-%d", prefix, user.ID, c),
+			}
+			if random.Float64() < volume.SharedCollections {
+				collection.Visibility = "public"
+			}
+			dataset.Collections = append(dataset.Collections, collection)
+		}
+		for b := 0; b < volume.BookmarksPerUser; b++ {
+			domain := pick(random, seedDomains)
+			title := capitalize(pick(random, seedWords)) + " " + pick(random, seedWords) + " " + pick(random, seedWords)
+			tags, _ := json.Marshal(pickTags(random))
+			bookmark := &database.Bookmark{
+				UserID:      user.ID,
+				URL:         fmt.Sprintf("https://%s/%s/%d", domain, strings.ReplaceAll(strings.ToLower(title), " ", "-"), b),
+				Title:       title,
+				Description: "Notes on " + strings.ToLower(title) + " from " + domain,
+				Tags:        string(tags),
+				Status:      "active",
+			}
+			bookmark.CreatedAt = now.Add(-time.Duration(random.Int63n(int64(span))))
+			bookmark.UpdatedAt = bookmark.CreatedAt
+			dataset.Bookmarks = append(dataset.Bookmarks, bookmark)
+		}
+	}
+	if len(dataset.Collections) > 0 {
+		if err := db.CreateInBatches(dataset.Collections, seedBatchSize).Error; err != nil {
+			return nil, fmt.Errorf("failed to seed collections: %w", err)
+		}
+	}
+	if len(dataset.Bookmarks) > 0 {
+		if err := db.CreateInBatches(dataset.Bookmarks, seedBatchSize).Error; err != nil {
+			return nil, fmt.Errorf("failed to seed bookmarks: %w", err)
+		}
+	}
+
+	if err := seedCollectionLinks(db, random, dataset); err != nil {
+		return nil, err
+	}
+
+	for _, collection := range dataset.Collections {
+		if collection.Visibility != "public" {
+			continue
+		}
+		dataset.Shares = append(dataset.Shares, &database.CollectionShare{
+			CollectionID: collection.ID,
+			UserID:       collection.UserID,
+			ShareType:    "public",
+			Permission:   "view",
+			ShareToken:   fmt.Sprintf("%s-share-%d", prefix, collection.ID),
+			IsActive:     true,
+		})
+	}
+	if len(dataset.Shares) > 0 {
+		if err := db.CreateInBatches(dataset.Shares, seedBatchSize).Error; err != nil {
+			return nil, fmt.Errorf("failed to seed shares: %w", err)
+		}
+	}
+
+	return dataset, nil
+}
+
+// Seed stores a realistic dataset, failing the test on errors
+func (f *Factory) Seed(volume Volume) *Dataset {
+	f.t.Helper()
+	dataset, err := Seed(f.db, volume)
+	require.NoError(f.t, err)
+	return dataset
+}
+
+// seedCollectionLinks files about two thirds of the bookmarks into one of
+// their owner's collections
+func seedCollectionLinks(db *gorm.DB, random *rand.Rand, dataset *Dataset) error {
+	byUser := make(map[uint][]*database.Collection)
+	for _, collection := range dataset.Collections {
+		byUser[collection.UserID] = append(byUser[collection.UserID], collection)
+	}
+
+	var links []map[string]interface{}
+	for _, bookmark := range dataset.Bookmarks {
+		collections := byUser[bookmark.UserID]
+		if len(collections) == 0 || random.Intn(3) == 0 {
+			continue
+		}
+		collection := collections[random.Intn(len(collections))]
+		links = append(links, map[string]interface{}{"bookmark_id": bookmark.ID, "collection_id": collection.ID})
+	}
+	if len(links) == 0 {
+		return nil
+	}
+	if err := db.Table("bookmark_collections").CreateInBatches(links, seedBatchSize).Error; err != nil {
+		return fmt.Errorf("failed to seed collection bookmarks: %w", err)
+	}
+	return nil
+}
+
+func capitalize(word string) string {
+	return strings.ToUpper(word[:1]) + word[1:]
+}
+
+func pick(random *rand.Rand, words []string) string {
+	return words[random.Intn(len(words))]
+}
+
+// pickTags returns zero to three distinct tags
+func pickTags(random *rand.Rand) []string {
+	tags := []string{}
+	for _, i := range random.Perm(len(seedTags))[:random.Intn(4)] {
+		tags = append(tags, seedTags[i])
+	}
+	return tags
+}
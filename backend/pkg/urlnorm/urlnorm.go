@@ -0,0 +1,230 @@
+package urlnorm
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Wildcard as a rule domain applies the rule to every host; as the last
+// character of a parameter pattern it matches a prefix, e.g. "utm_*"
+const Wildcard = "*"
+
+// Limits of a rule set, so rules stay cheap to apply on every save
+const (
+	MaxRules  = 100
+	MaxParams = 50
+)
+
+// ErrInvalidRule is returned for rules that can't be applied
+var ErrInvalidRule = errors.New("invalid URL rule")
+
+// Rule cleans up the URLs of one domain and its subdomains. A parameter
+// matching Keep is never stripped, whichever rule strips it
+type Rule struct {
+	Domain     string   `json:"domain"`
+	Strip      []string `json:"strip,omitempty"`
+	Keep       []string `json:"keep,omitempty"`
+	ForceHTTPS bool     `json:"force_https,omitempty"`
+}
+
+// Defaults are the built-in rules: tracking parameters nobody wants to keep,
+// and the few that carry meaning on sites that also add tracking
+var Defaults = []Rule{
+	{Domain: Wildcard, Strip: []string{"utm_*", "fbclid", "gclid", "dclid", "gbraid", "wbraid", "msclkid", "yclid", "mc_cid", "mc_eid", "igshid", "_hsenc", "_hsmi", "mkt_tok"}},
+	{Domain: "youtube.com", Strip: []string{"feature", "si", "pp", "ab_channel"}, Keep: []string{"v", "t", "list", "index"}, ForceHTTPS: true},
+	{Domain: "youtu.be", Strip: []string{"feature", "si"}, Keep: []string{"t"}, ForceHTTPS: true},
+	{Domain: "amazon.com", Strip: []string{"ref", "ref_", "pd_rd_*", "pf_rd_*", "content-id", "psc", "crid", "sprefix", "qid"}},
+	{Domain: "twitter.com", Strip: []string{"s", "t", "ref_src"}, ForceHTTPS: true},
+	{Domain: "x.com", Strip: []string{"s", "t", "ref_src"}, ForceHTTPS: true},
+	{Domain: "medium.com", Strip: []string{"source", "sk"}},
+	{Domain: "github.com", ForceHTTPS: true},
+}
+
+// WithDefaults puts the built-in rules ahead of a user's own
+func WithDefaults(rules []Rule) []Rule {
+	all := make([]Rule, 0, len(Defaults)+len(rules))
+	return append(append(all, Defaults...), rules...)
+}
+
+// Result describes what the rules did to a URL
+type Result struct {
+	URL      string   `json:"url"`
+	Changed  bool     `json:"changed"`
+	Removed  []string `json:"removed,omitempty"` // stripped parameters, in URL order
+	Upgraded bool     `json:"upgraded"`          // switched from http to https
+	Matched  []string `json:"matched,omitempty"` // domains of the rules that applied
+}
+
+// Normalize applies the rules matching the URL's host. The rest of the URL,
+// including the order and encoding of kept parameters, is left alone
+func Normalize(rawURL string, rules []Rule) (Result, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || u.Host == "" {
+		return Result{URL: rawURL}, fmt.Errorf("invalid URL %q", rawURL)
+	}
+
+	host := strings.ToLower(u.Hostname())
+	var strip, keep []string
+	result := Result{}
+	forceHTTPS := false
+	for _, rule := range rules {
+		if !rule.matches(host) {
+			continue
+		}
+		result.Matched = append(result.Matched, rule.Domain)
+		strip = append(strip, rule.Strip...)
+		keep = append(keep, rule.Keep...)
+		forceHTTPS = forceHTTPS || rule.ForceHTTPS
+	}
+
+	if forceHTTPS && u.Scheme == "http" {
+		u.Scheme = "https"
+		if u.Port() == "80" {
+			u.Host = u.Hostname()
+		}
+		result.Upgraded = true
+	}
+
+	if u.RawQuery != "" && len(strip) > 0 {
+		parts := strings.Split(u.RawQuery, "&")
+		kept := parts[:0]
+		for _, part := range parts {
+			key := part
+			if i := strings.IndexByte(part, '='); i >= 0 {
+				key = part[:i]
+			}
+			if unescaped, err := url.QueryUnescape(key); err == nil {
+				key = unescaped
+			}
+			if key != "" && matchAny(strip, key) && !matchAny(keep, key) {
+				result.Removed = append(result.Removed, key)
+				continue
+			}
+			kept = append(kept, part)
+		}
+		u.RawQuery = strings.Join(kept, "&")
+		u.ForceQuery = false
+	}
+
+	if !result.Upgraded && len(result.Removed) == 0 {
+		result.URL = rawURL
+		return result, nil
+	}
+	result.URL = u.String()
+	result.Changed = true
+	return result, nil
+}
+
+// Clean applies the rules and falls back to the URL as given when it can't
+// be parsed
+func Clean(rawURL string, rules []Rule) string {
+	result, err := Normalize(rawURL, rules)
+	if err != nil {
+		return rawURL
+	}
+	return result.URL
+}
+
+// Canonical returns the form of a URL that decides whether two saves are the
+// same page: http and https, a www. host, a default port, a trailing slash,
+// the fragment and the order of query parameters make no difference
+func Canonical(rawURL string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("invalid URL %q", rawURL)
+	}
+
+	scheme := strings.ToLower(u.Scheme)
+	if scheme == "http" {
+		scheme = "https"
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	if port := u.Port(); port != "" && port != "80" && port != "443" {
+		host += ":" + port
+	}
+	path := strings.TrimSuffix(u.EscapedPath(), "/")
+
+	canonical := scheme + "://" + host + path
+	if u.RawQuery != "" {
+		params := strings.Split(u.RawQuery, "&")
+		sort.Strings(params)
+		canonical += "?" + strings.Trim(strings.Join(params, "&"), "&")
+	}
+	return canonical, nil
+}
+
+// Validate checks a user's rule set and normalizes its domains and patterns
+func Validate(rules []Rule) error {
+	if len(rules) > MaxRules {
+		return fmt.Errorf("%w: at most %d rules", ErrInvalidRule, MaxRules)
+	}
+	for i := range rules {
+		rule := &rules[i]
+		rule.Domain = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(rule.Domain)), "www.")
+		if rule.Domain != Wildcard && !validDomain(rule.Domain) {
+			return fmt.Errorf("%w: %q is not a domain", ErrInvalidRule, rule.Domain)
+		}
+		if len(rule.Strip)+len(rule.Keep) > MaxParams {
+			return fmt.Errorf("%w: at most %d parameters for %s", ErrInvalidRule, MaxParams, rule.Domain)
+		}
+		if len(rule.Strip) == 0 && len(rule.Keep) == 0 && !rule.ForceHTTPS {
+			return fmt.Errorf("%w: the rule for %s does nothing", ErrInvalidRule, rule.Domain)
+		}
+		for _, patterns := range [][]string{rule.Strip, rule.Keep} {
+			for n, pattern := range patterns {
+				pattern = strings.TrimSpace(pattern)
+				if pattern == "" || strings.ContainsAny(pattern, "&=# ") || strings.Contains(strings.TrimSuffix(pattern, Wildcard), Wildcard) {
+					return fmt.Errorf("%w: bad parameter %q for %s", ErrInvalidRule, pattern, rule.Domain)
+				}
+				patterns[n] = pattern
+			}
+		}
+	}
+	return nil
+}
+
+// Merge adds rules to a set, replacing the rules of the domains it already has
+func Merge(rules, added []Rule) []Rule {
+	replaced := make(map[string]bool, len(added))
+	for _, rule := range added {
+		replaced[rule.Domain] = true
+	}
+	merged := make([]Rule, 0, len(rules)+len(added))
+	for _, rule := range rules {
+		if !replaced[rule.Domain] {
+			merged = append(merged, rule)
+		}
+	}
+	return append(merged, added...)
+}
+
+func (r Rule) matches(host string) bool {
+	return r.Domain == Wildcard || host == r.Domain || strings.HasSuffix(host, "."+r.Domain)
+}
+
+func matchAny(patterns []string, key string) bool {
+	key = strings.ToLower(key)
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if pattern == Wildcard || pattern == key ||
+			(strings.HasSuffix(pattern, Wildcard) && strings.HasPrefix(key, strings.TrimSuffix(pattern, Wildcard))) {
+			return true
+		}
+	}
+	return false
+}
+
+func validDomain(domain string) bool {
+	if domain == "" || len(domain) > 253 || strings.HasPrefix(domain, ".") || strings.HasSuffix(domain, ".") {
+		return false
+	}
+	for _, r := range domain {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '.') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,89 @@
+package urlnorm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name  string
+		url   string
+		rules []Rule
+		want  string
+	}{
+		{"tracking parameters", "https://example.com/post?id=3&utm_source=news&UTM_Medium=mail&fbclid=x", Defaults, "https://example.com/post?id=3"},
+		{"untouched", "https://example.com/a?b=c%20d#top", Defaults, "https://example.com/a?b=c%20d#top"},
+		{"fragment kept", "https://example.com/a?utm_source=x#top", Defaults, "https://example.com/a#top"},
+		{"youtube timestamp kept", "http://www.youtube.com/watch?v=abc&t=42&si=track&feature=share", Defaults, "https://www.youtube.com/watch?v=abc&t=42"},
+		{"user strips ref", "https://blog.example.com/p?ref=hn&page=2", []Rule{{Domain: "example.com", Strip: []string{"ref"}}}, "https://blog.example.com/p?page=2"},
+		{"keep wins", "https://example.com/p?utm_campaign=spring", WithDefaults([]Rule{{Domain: "example.com", Keep: []string{"utm_campaign"}}}), "https://example.com/p?utm_campaign=spring"},
+		{"strip everything", "http://example.com:80/p?a=1&b=2", []Rule{{Domain: "example.com", Strip: []string{"*"}, ForceHTTPS: true}}, "https://example.com/p"},
+		{"other domains", "https://notexample.com/p?ref=1", []Rule{{Domain: "example.com", Strip: []string{"ref"}}}, "https://notexample.com/p?ref=1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Normalize(tt.url, tt.rules)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, result.URL)
+			assert.Equal(t, tt.want != tt.url, result.Changed)
+		})
+	}
+
+	result, err := Normalize("http://youtu.be/abc?si=x&t=10", Defaults)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"si"}, result.Removed)
+	assert.True(t, result.Upgraded)
+	assert.Equal(t, []string{Wildcard, "youtu.be"}, result.Matched)
+
+	_, err = Normalize("not a url", Defaults)
+	assert.Error(t, err)
+	assert.Equal(t, "not a url", Clean("not a url", Defaults))
+}
+
+func TestCanonical(t *testing.T) {
+	same := []string{
+		"https://example.com/a?b=2&a=1",
+		"http://www.Example.com:80/a/?a=1&b=2#top",
+		"https://EXAMPLE.com:443/a?a=1&b=2&",
+	}
+	for _, raw := range same {
+		canonical, err := Canonical(raw)
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/a?a=1&b=2", canonical, raw)
+	}
+
+	canonical, err := Canonical("https://example.com:8080/")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com:8080", canonical)
+	canonical, err = Canonical("https://example.com/A")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/A", canonical, "paths are case sensitive")
+
+	_, err = Canonical("example")
+	assert.Error(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	rules := []Rule{{Domain: " WWW.Example.com ", Strip: []string{" ref "}}}
+	require.NoError(t, Validate(rules))
+	assert.Equal(t, Rule{Domain: "example.com", Strip: []string{"ref"}}, rules[0])
+
+	for name, rule := range map[string]Rule{
+		"scheme":        {Domain: "https://example.com", Strip: []string{"ref"}},
+		"no action":     {Domain: "example.com"},
+		"bad parameter": {Domain: "example.com", Strip: []string{"a=b"}},
+		"inner star":    {Domain: "example.com", Strip: []string{"a*b"}},
+	} {
+		assert.ErrorIs(t, Validate([]Rule{rule}), ErrInvalidRule, name)
+	}
+	assert.ErrorIs(t, Validate(make([]Rule, MaxRules+1)), ErrInvalidRule)
+}
+
+func TestMerge(t *testing.T) {
+	mine := []Rule{{Domain: "example.com", Strip: []string{"ref"}}, {Domain: "news.org", ForceHTTPS: true}}
+	pack := []Rule{{Domain: "example.com", Strip: []string{"src"}}}
+	assert.Equal(t, []Rule{mine[1], pack[0]}, Merge(mine, pack))
+}
@@ -0,0 +1,159 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ParseFields reads the comma-separated fields query parameter. It returns
+// nil when the parameter is absent, meaning every field is wanted
+func ParseFields(c *gin.Context) []string {
+	raw := c.Query("fields")
+	if raw == "" {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	fields := make([]string, 0)
+	for _, field := range strings.Split(raw, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" || seen[field] {
+			continue
+		}
+		seen[field] = true
+		fields = append(fields, field)
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return fields
+}
+
+// FilterFields keeps only the named JSON fields of value. A name with dots
+// selects within a nested object, e.g. user.username keeps only the
+// username of the user; a field named on its own is kept whole. Arrays are
+// filtered element by element; other values are returned unchanged. No
+// fields means no filtering
+func FilterFields(value interface{}, fields []string) (interface{}, error) {
+	if len(fields) == 0 {
+		return value, nil
+	}
+
+	tree, err := parseFieldTree(fields)
+	if err != nil {
+		return nil, err
+	}
+	decoded, err := decodeJSON(value)
+	if err != nil {
+		return nil, err
+	}
+	return filterDecoded(decoded, tree), nil
+}
+
+// SparseResponse sends a successful response limited to the fields the
+// request names with fields=. For list responses listKey names the array
+// of resources within data, so the fields apply to each resource and the
+// rest of data (totals, paging) is kept; an empty listKey applies them to
+// data itself
+func SparseResponse(c *gin.Context, data interface{}, message, listKey string) {
+	fields := ParseFields(c)
+	if fields == nil {
+		SuccessResponse(c, data, message)
+		return
+	}
+
+	tree, err := parseFieldTree(fields)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", err.Error(), nil)
+		return
+	}
+	decoded, err := decodeJSON(data)
+	if err != nil {
+		InternalErrorResponse(c, "")
+		return
+	}
+
+	if listKey == "" {
+		decoded = filterDecoded(decoded, tree)
+	} else if envelope, ok := decoded.(map[string]interface{}); ok {
+		envelope[listKey] = filterDecoded(envelope[listKey], tree)
+	}
+	SuccessResponse(c, decoded, message)
+}
+
+// fieldTree is a parsed fields= list. A nil subtree keeps the field whole
+type fieldTree map[string]fieldTree
+
+func parseFieldTree(fields []string) (fieldTree, error) {
+	tree := fieldTree{}
+	for _, field := range fields {
+		names := strings.Split(field, ".")
+		for _, name := range names {
+			if name == "" {
+				return nil, fmt.Errorf("invalid field: %s", field)
+			}
+		}
+
+		node := tree
+		for i, name := range names {
+			child, seen := node[name]
+			if seen && child == nil {
+				break // the whole field is already kept
+			}
+			if i == len(names)-1 {
+				node[name] = nil
+				break
+			}
+			if child == nil {
+				child = fieldTree{}
+				node[name] = child
+			}
+			node = child
+		}
+	}
+	return tree, nil
+}
+
+// decodeJSON round-trips value through JSON into maps and slices. Numbers
+// stay json.Number so large IDs survive
+func decodeJSON(value interface{}) (interface{}, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	var decoded interface{}
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, err
+	}
+	return decoded, nil
+}
+
+func filterDecoded(value interface{}, keep fieldTree) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			children, ok := keep[key]
+			if !ok {
+				delete(v, key)
+			} else if children != nil {
+				v[key] = filterDecoded(item, children)
+			}
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = filterDecoded(item, keep)
+		}
+		return v
+	default:
+		return value
+	}
+}
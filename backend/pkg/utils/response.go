@@ -82,6 +82,12 @@ func InternalErrorResponse(c *gin.Context, message string) {
 
 // GetUserIDFromContext extracts user ID from the request context
 func GetUserIDFromContext(c *gin.Context) uint {
+	// Tests and internal callers may set the ID itself rather than its string
+	if value, exists := c.Get("user_id"); exists {
+		if userID, ok := value.(uint); ok {
+			return userID
+		}
+	}
 	userIDStr := c.GetString("user_id")
 	if userIDStr == "" {
 		return 0
@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxUploadFieldSize caps each form field read before an uploaded file
+const maxUploadFieldSize = 64 << 10
+
+// ErrNoUpload is returned when a multipart request has no file in the
+// expected field
+var ErrNoUpload = errors.New("no file provided")
+
+// Upload is a file read straight from a multipart request body. Nothing is
+// buffered, so it can be read only once, before the response is written
+type Upload struct {
+	*multipart.Part
+	Filename string
+	// Fields are the form fields sent before the file
+	Fields map[string]string
+}
+
+// StreamUpload reads a multipart request up to the file in field, without
+// parsing the whole form into memory or temporary files as FormFile does.
+// Form fields must be sent before the file to be seen; they are collected
+// in the upload's Fields
+func StreamUpload(c *gin.Context, field string) (*Upload, error) {
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]string)
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil, ErrNoUpload
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if part.FormName() == field && part.FileName() != "" {
+			return &Upload{Part: part, Filename: part.FileName(), Fields: fields}, nil
+		}
+		if part.FileName() != "" {
+			continue // another file, skipped by NextPart
+		}
+
+		value, err := io.ReadAll(io.LimitReader(part, maxUploadFieldSize+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(value) > maxUploadFieldSize {
+			return nil, fmt.Errorf("form field %s is larger than %d bytes", part.FormName(), maxUploadFieldSize)
+		}
+		fields[part.FormName()] = string(value)
+	}
+}
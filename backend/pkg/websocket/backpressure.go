@@ -0,0 +1,192 @@
+package websocket
+
+import (
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+// OverflowPolicy decides what happens to a client whose send queue is full
+type OverflowPolicy string
+
+const (
+	// PolicyDropOldest discards the client's oldest queued message
+	PolicyDropOldest OverflowPolicy = "drop_oldest"
+	// PolicyDisconnect closes the client, which reconnects and resyncs
+	PolicyDisconnect OverflowPolicy = "disconnect"
+)
+
+// Backpressure limits how much a slow client can back up the hub
+type Backpressure struct {
+	SendBuffer     int            // messages queued per client
+	Policy         OverflowPolicy // applied when a client's queue is full
+	CoalesceWindow time.Duration  // sync events are held this long; 0 disables
+}
+
+// DefaultBackpressure returns the limits used unless configured otherwise
+func DefaultBackpressure() Backpressure {
+	return Backpressure{
+		SendBuffer:     256,
+		Policy:         PolicyDropOldest,
+		CoalesceWindow: 100 * time.Millisecond,
+	}
+}
+
+// SetBackpressure sets the send queue limits of clients connecting from now
+// on and the sync event coalesce window. Invalid values keep the defaults
+func (h *Hub) SetBackpressure(backpressure Backpressure) {
+	defaults := DefaultBackpressure()
+	if backpressure.SendBuffer <= 0 {
+		backpressure.SendBuffer = defaults.SendBuffer
+	}
+	if backpressure.Policy != PolicyDropOldest && backpressure.Policy != PolicyDisconnect {
+		backpressure.Policy = defaults.Policy
+	}
+	if backpressure.CoalesceWindow < 0 {
+		backpressure.CoalesceWindow = 0
+	}
+	h.backpressure = backpressure
+}
+
+// outbound is a queued message and when it was queued
+type outbound struct {
+	data   []byte
+	queued time.Time
+}
+
+// coalesceKey identifies the sync events that replace one another
+type coalesceKey struct {
+	userID     string
+	resourceID string
+}
+
+// newClient creates a client with a send queue sized by the hub's limits
+func (h *Hub) newClient(conn *websocket.Conn, userID, deviceID string) *Client {
+	return &Client{
+		conn:     conn,
+		send:     make(chan outbound, h.backpressure.SendBuffer),
+		userID:   userID,
+		deviceID: deviceID,
+		hub:      h,
+		logger:   h.logger.With(zap.String("user_id", userID), zap.String("device_id", deviceID)),
+	}
+}
+
+// close closes the client's send queue once, which makes its write pump
+// close the connection
+func (c *Client) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.closed {
+		c.closed = true
+		close(c.send)
+	}
+}
+
+// enqueue queues a message for a client without blocking. When the queue is
+// full the overflow policy applies; it reports false when the client has to
+// be disconnected
+func (h *Hub) enqueue(client *Client, data []byte) bool {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	if client.closed {
+		return true
+	}
+
+	message := outbound{data: data, queued: time.Now()}
+	select {
+	case client.send <- message:
+		return true
+	default:
+	}
+
+	if h.backpressure.Policy == PolicyDisconnect {
+		h.stats.dropped.Add(1)
+		return false
+	}
+
+	// The write pump may have freed a slot meanwhile, so dropping can fail
+	select {
+	case <-client.send:
+		h.stats.dropped.Add(1)
+	default:
+	}
+	select {
+	case client.send <- message:
+	default:
+		h.stats.dropped.Add(1)
+	}
+	return true
+}
+
+// deliver queues a message for every matching client and disconnects the
+// ones that cannot keep up. It returns how many clients it was queued for
+func (h *Hub) deliver(data []byte, match func(*Client) bool) int {
+	var slow []*Client
+	queued := 0
+
+	h.mutex.RLock()
+	for client := range h.clients {
+		if !match(client) {
+			continue
+		}
+		if h.enqueue(client, data) {
+			queued++
+		} else {
+			slow = append(slow, client)
+		}
+	}
+	h.mutex.RUnlock()
+
+	for _, client := range slow {
+		h.disconnectSlow(client)
+	}
+	return queued
+}
+
+// disconnectSlow removes a client whose send queue overflowed
+func (h *Hub) disconnectSlow(client *Client) {
+	h.mutex.Lock()
+	delete(h.clients, client)
+	h.mutex.Unlock()
+
+	h.stats.disconnects.Add(1)
+	client.logger.Warn("Disconnecting slow WebSocket client", zap.Int("send_buffer", cap(client.send)))
+	client.close()
+}
+
+// BroadcastSyncEvent sends a sync event about a resource to all clients of
+// a user. Events for the same resource within the coalesce window are
+// merged, so clients only receive the latest
+func (h *Hub) BroadcastSyncEvent(userID, resourceID string, message *Message) {
+	window := h.backpressure.CoalesceWindow
+	if window <= 0 || resourceID == "" {
+		h.BroadcastToUser(userID, message)
+		return
+	}
+
+	key := coalesceKey{userID: userID, resourceID: resourceID}
+	h.coalesceMu.Lock()
+	defer h.coalesceMu.Unlock()
+
+	if _, held := h.pending[key]; held {
+		h.stats.coalesced.Add(1)
+		h.pending[key] = message
+		return
+	}
+	h.pending[key] = message
+	time.AfterFunc(window, func() { h.flushSyncEvent(key) })
+}
+
+// flushSyncEvent sends the latest held sync event for a resource
+func (h *Hub) flushSyncEvent(key coalesceKey) {
+	h.coalesceMu.Lock()
+	message := h.pending[key]
+	delete(h.pending, key)
+	h.coalesceMu.Unlock()
+
+	if message != nil {
+		h.BroadcastToUser(key.userID, message)
+	}
+}
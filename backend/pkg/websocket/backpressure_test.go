@@ -0,0 +1,130 @@
+package websocket
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// addClient registers a client without a connection; tests read its queue
+func addClient(hub *Hub, userID string) *Client {
+	client := hub.newClient(nil, userID, "device")
+	hub.clients[client] = true
+	return client
+}
+
+// drain returns the messages queued for a client
+func drain(client *Client) []string {
+	var messages []string
+	for {
+		select {
+		case message, ok := <-client.send:
+			if !ok {
+				return messages
+			}
+			messages = append(messages, string(message.data))
+		default:
+			return messages
+		}
+	}
+}
+
+func TestHub_DropOldest(t *testing.T) {
+	hub := NewHub(nil, zap.NewNop())
+	hub.SetBackpressure(Backpressure{SendBuffer: 2, Policy: PolicyDropOldest})
+	client := addClient(hub, "user")
+
+	for _, message := range []string{"1", "2", "3"} {
+		hub.deliver([]byte(message), func(*Client) bool { return true })
+	}
+
+	assert.Equal(t, []string{"2", "3"}, drain(client))
+	stats := hub.Stats()
+	assert.Equal(t, 1, stats.Clients)
+	assert.Equal(t, uint64(1), stats.Dropped)
+	assert.Zero(t, stats.Disconnects)
+}
+
+func TestHub_DisconnectSlowClient(t *testing.T) {
+	hub := NewHub(nil, zap.NewNop())
+	hub.SetBackpressure(Backpressure{SendBuffer: 1, Policy: PolicyDisconnect})
+	slow := addClient(hub, "slow")
+	other := addClient(hub, "other")
+
+	hub.deliver([]byte("1"), func(*Client) bool { return true })
+	drain(other)
+	hub.deliver([]byte("2"), func(*Client) bool { return true })
+
+	assert.True(t, slow.closed)
+	assert.Equal(t, []string{"1"}, drain(slow), "queued messages are still written before closing")
+	assert.Equal(t, []string{"2"}, drain(other))
+
+	stats := hub.Stats()
+	assert.Equal(t, 1, stats.Clients)
+	assert.Equal(t, uint64(1), stats.Disconnects)
+
+	// Later broadcasts skip the closed client instead of panicking
+	hub.enqueue(slow, []byte("3"))
+}
+
+func TestHub_BroadcastSyncEventCoalesces(t *testing.T) {
+	hub := NewHub(nil, zap.NewNop())
+	hub.SetBackpressure(Backpressure{CoalesceWindow: 20 * time.Millisecond})
+	client := addClient(hub, "user")
+	addClient(hub, "someone-else")
+
+	hub.BroadcastSyncEvent("user", "bookmark:1", &Message{Type: "sync_event", Data: "first"})
+	hub.BroadcastSyncEvent("user", "bookmark:1", &Message{Type: "sync_event", Data: "second"})
+	hub.BroadcastSyncEvent("user", "bookmark:2", &Message{Type: "sync_event", Data: "other"})
+	assert.Empty(t, drain(client), "events are held for the window")
+
+	require.Eventually(t, func() bool { return len(client.send) == 2 }, time.Second, 5*time.Millisecond)
+	messages := strings.Join(drain(client), "\n")
+	assert.Contains(t, messages, `"second"`)
+	assert.Contains(t, messages, `"other"`)
+	assert.NotContains(t, messages, `"first"`)
+	assert.Equal(t, uint64(1), hub.Stats().Coalesced)
+}
+
+func TestHub_SetBackpressureDefaults(t *testing.T) {
+	hub := NewHub(nil, zap.NewNop())
+	hub.SetBackpressure(Backpressure{SendBuffer: -1, Policy: "block", CoalesceWindow: -time.Second})
+
+	assert.Equal(t, 256, hub.backpressure.SendBuffer)
+	assert.Equal(t, PolicyDropOldest, hub.backpressure.Policy)
+	assert.Zero(t, hub.backpressure.CoalesceWindow)
+}
+
+func TestCollector(t *testing.T) {
+	hub := NewHub(nil, zap.NewNop())
+	addClient(hub, "user")
+	hub.stats.observeSent([]outbound{{data: []byte("1"), queued: time.Now()}})
+	hub.stats.dropped.Add(2)
+
+	registry := prometheus.NewRegistry()
+	require.NoError(t, registry.Register(NewCollector(hub)))
+	families, err := registry.Gather()
+	require.NoError(t, err)
+
+	values := make(map[string]float64)
+	for _, family := range families {
+		metric := family.GetMetric()[0]
+		switch {
+		case metric.GetGauge() != nil:
+			values[family.GetName()] = metric.GetGauge().GetValue()
+		case metric.GetCounter() != nil:
+			values[family.GetName()] = metric.GetCounter().GetValue()
+		case metric.GetHistogram() != nil:
+			values[family.GetName()] = float64(metric.GetHistogram().GetSampleCount())
+		}
+	}
+	assert.Equal(t, 1.0, values["bookmark_sync_websocket_clients"])
+	assert.Equal(t, 1.0, values["bookmark_sync_websocket_messages_sent_total"])
+	assert.Equal(t, 2.0, values["bookmark_sync_websocket_messages_dropped_total"])
+	assert.Equal(t, 1.0, values["bookmark_sync_websocket_send_latency_seconds"])
+}
@@ -0,0 +1,250 @@
+package websocket
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Live query messages. A client sends query_subscribe with a query and gets
+// query_match whenever one of the user's bookmarks is created or updated to
+// match it, until it sends query_unsubscribe or disconnects
+const (
+	MessageQuerySubscribe    = "query_subscribe"
+	MessageQuerySubscribed   = "query_subscribed"
+	MessageQueryUnsubscribe  = "query_unsubscribe"
+	MessageQueryUnsubscribed = "query_unsubscribed"
+	MessageQueryMatch        = "query_match"
+)
+
+// DefaultLiveQueryLimit is how many live queries a connection may hold
+// unless configured otherwise
+const DefaultLiveQueryLimit = 10
+
+// maxLiveQueryTerms bounds the words and tags of one query, keeping each
+// match a handful of string comparisons
+const maxLiveQueryTerms = 10
+
+// Live query errors
+var (
+	ErrLiveQueryInvalid = errors.New("invalid live query")
+	ErrLiveQueryLimit   = errors.New("too many live queries on this connection")
+)
+
+// LiveQuery is a search or filter a client subscribed to. Every given part
+// has to match: all words of Query in the title, URL, description or tags,
+// all Tags, and the Domain or one of its subdomains
+type LiveQuery struct {
+	ID     string   `json:"id"`
+	Query  string   `json:"q,omitempty"`
+	Tags   []string `json:"tags,omitempty"`
+	Domain string   `json:"domain,omitempty"`
+}
+
+// LiveItem is a created or updated bookmark matched against live queries.
+// Data is pushed to the clients as it is
+type LiveItem struct {
+	ID          string
+	URL         string
+	Title       string
+	Description string
+	Tags        []string
+	Data        interface{}
+}
+
+// liveQuery is a subscribed query prepared for matching
+type liveQuery struct {
+	id     string
+	words  []string
+	tags   []string
+	domain string
+}
+
+// SetLiveQueryLimit sets how many live queries a connection may hold.
+// Values below one keep the default
+func (h *Hub) SetLiveQueryLimit(limit int) {
+	if limit < 1 {
+		limit = DefaultLiveQueryLimit
+	}
+	h.liveQueryLimit = limit
+}
+
+// PublishBookmark pushes a created or updated bookmark to the user's clients
+// with a live query it matches, once per client with the IDs of the queries
+// it matches. It returns how many clients it was queued for
+func (h *Hub) PublishBookmark(userID, action string, item LiveItem) int {
+	fields := newLiveFields(item)
+
+	h.mutex.RLock()
+	matches := make(map[*Client][]string)
+	for client := range h.clients {
+		if client.userID != userID {
+			continue
+		}
+		if ids := client.matchLiveQueries(fields); len(ids) > 0 {
+			matches[client] = ids
+		}
+	}
+	h.mutex.RUnlock()
+
+	for client, ids := range matches {
+		client.sendMessage(&Message{
+			Type: MessageQueryMatch,
+			Data: map[string]interface{}{
+				"query_ids": ids,
+				"action":    action,
+				"bookmark":  item.Data,
+			},
+			Timestamp: time.Now(),
+		})
+	}
+	return len(matches)
+}
+
+// subscribeQuery adds or replaces one of the client's live queries
+func (c *Client) subscribeQuery(query LiveQuery) error {
+	prepared, err := prepareLiveQuery(query)
+	if err != nil {
+		return err
+	}
+
+	c.queriesMu.Lock()
+	defer c.queriesMu.Unlock()
+	if c.queries == nil {
+		c.queries = make(map[string]*liveQuery)
+	}
+	if _, replacing := c.queries[prepared.id]; !replacing && len(c.queries) >= c.hub.liveQueryLimit {
+		return fmt.Errorf("%w: at most %d", ErrLiveQueryLimit, c.hub.liveQueryLimit)
+	}
+	c.queries[prepared.id] = prepared
+	return nil
+}
+
+// unsubscribeQuery removes one of the client's live queries and reports
+// whether it had one with that ID
+func (c *Client) unsubscribeQuery(id string) bool {
+	c.queriesMu.Lock()
+	defer c.queriesMu.Unlock()
+	if _, ok := c.queries[id]; !ok {
+		return false
+	}
+	delete(c.queries, id)
+	return true
+}
+
+// matchLiveQueries returns the IDs of the client's queries a bookmark
+// matches, in no particular order
+func (c *Client) matchLiveQueries(fields liveFields) []string {
+	c.queriesMu.Lock()
+	defer c.queriesMu.Unlock()
+
+	var ids []string
+	for id, query := range c.queries {
+		if query.matches(fields) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// handleLiveQuery answers query_subscribe and query_unsubscribe messages
+func (c *Client) handleLiveQuery(msg *Message) {
+	data, _ := msg.Data.(map[string]interface{})
+	var query LiveQuery
+	query.ID, _ = data["id"].(string)
+
+	var err error
+	switch msg.Type {
+	case MessageQuerySubscribe:
+		query.Query, _ = data["q"].(string)
+		query.Domain, _ = data["domain"].(string)
+		if tags, ok := data["tags"].([]interface{}); ok {
+			for _, tag := range tags {
+				if name, ok := tag.(string); ok {
+					query.Tags = append(query.Tags, name)
+				}
+			}
+		}
+		if err = c.subscribeQuery(query); err == nil {
+			c.sendMessage(&Message{Type: MessageQuerySubscribed, Data: map[string]interface{}{"id": query.ID}, Timestamp: time.Now()})
+		}
+	case MessageQueryUnsubscribe:
+		if !c.unsubscribeQuery(query.ID) {
+			err = fmt.Errorf("%w: no query %q", ErrLiveQueryInvalid, query.ID)
+		} else {
+			c.sendMessage(&Message{Type: MessageQueryUnsubscribed, Data: map[string]interface{}{"id": query.ID}, Timestamp: time.Now()})
+		}
+	}
+
+	if err != nil {
+		c.sendMessage(&Message{
+			Type:      "error",
+			Data:      map[string]interface{}{"error": err.Error(), "received_type": msg.Type, "id": query.ID},
+			Timestamp: time.Now(),
+		})
+	}
+}
+
+// prepareLiveQuery validates a query and lowercases its parts
+func prepareLiveQuery(query LiveQuery) (*liveQuery, error) {
+	id := strings.TrimSpace(query.ID)
+	if id == "" || len(id) > 64 {
+		return nil, fmt.Errorf("%w: an id of up to 64 characters is required", ErrLiveQueryInvalid)
+	}
+
+	prepared := &liveQuery{id: id, words: strings.Fields(strings.ToLower(query.Query))}
+	for _, tag := range query.Tags {
+		if tag = strings.ToLower(strings.TrimSpace(tag)); tag != "" {
+			prepared.tags = append(prepared.tags, tag)
+		}
+	}
+	prepared.domain = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(query.Domain)), "www.")
+
+	if len(prepared.words) == 0 && len(prepared.tags) == 0 && prepared.domain == "" {
+		return nil, fmt.Errorf("%w: q, tags or domain is required", ErrLiveQueryInvalid)
+	}
+	if len(prepared.words)+len(prepared.tags) > maxLiveQueryTerms {
+		return nil, fmt.Errorf("%w: at most %d words and tags", ErrLiveQueryInvalid, maxLiveQueryTerms)
+	}
+	return prepared, nil
+}
+
+// liveFields are the lowercased parts of a bookmark queries look at,
+// prepared once for all clients
+type liveFields struct {
+	text string
+	tags map[string]bool
+	host string
+}
+
+func newLiveFields(item LiveItem) liveFields {
+	fields := liveFields{tags: make(map[string]bool, len(item.Tags))}
+	for _, tag := range item.Tags {
+		fields.tags[strings.ToLower(tag)] = true
+	}
+	fields.text = strings.ToLower(strings.Join(append([]string{item.Title, item.URL, item.Description}, item.Tags...), " "))
+	if parsed, err := url.Parse(item.URL); err == nil {
+		fields.host = strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+	}
+	return fields
+}
+
+// matches reports whether a bookmark matches every part of the query
+func (q *liveQuery) matches(fields liveFields) bool {
+	if q.domain != "" && fields.host != q.domain && !strings.HasSuffix(fields.host, "."+q.domain) {
+		return false
+	}
+	for _, tag := range q.tags {
+		if !fields.tags[tag] {
+			return false
+		}
+	}
+	for _, word := range q.words {
+		if !strings.Contains(fields.text, word) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,96 @@
+package websocket
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestLiveQuery_Matches(t *testing.T) {
+	item := newLiveFields(LiveItem{
+		URL:         "https://blog.golang.org/generics",
+		Title:       "An Introduction To Generics",
+		Description: "Type parameters in Go",
+		Tags:        []string{"Go", "reading"},
+	})
+
+	cases := []struct {
+		name  string
+		query LiveQuery
+		want  bool
+	}{
+		{"words in any field", LiveQuery{ID: "q", Query: "generics type"}, true},
+		{"missing word", LiveQuery{ID: "q", Query: "generics rust"}, false},
+		{"all tags", LiveQuery{ID: "q", Tags: []string{"go", "Reading"}}, true},
+		{"missing tag", LiveQuery{ID: "q", Tags: []string{"go", "video"}}, false},
+		{"subdomain", LiveQuery{ID: "q", Domain: "golang.org"}, true},
+		{"other domain", LiveQuery{ID: "q", Domain: "lang.org"}, false},
+		{"every part", LiveQuery{ID: "q", Query: "intro", Tags: []string{"go"}, Domain: "www.blog.golang.org"}, true},
+	}
+	for _, tc := range cases {
+		prepared, err := prepareLiveQuery(tc.query)
+		require.NoError(t, err, tc.name)
+		assert.Equal(t, tc.want, prepared.matches(item), tc.name)
+	}
+
+	_, err := prepareLiveQuery(LiveQuery{ID: "q"})
+	assert.ErrorIs(t, err, ErrLiveQueryInvalid)
+	_, err = prepareLiveQuery(LiveQuery{Query: "go"})
+	assert.ErrorIs(t, err, ErrLiveQueryInvalid)
+}
+
+func TestHub_PublishBookmark(t *testing.T) {
+	hub := NewHub(nil, zap.NewNop())
+	phone := addClient(hub, "user")
+	laptop := addClient(hub, "user")
+	other := addClient(hub, "other")
+
+	phone.handleMessage(&Message{Type: MessageQuerySubscribe, Data: map[string]interface{}{"id": "go", "tags": []interface{}{"go"}}})
+	messages := drain(phone)
+	require.Len(t, messages, 1)
+	assert.Contains(t, messages[0], MessageQuerySubscribed)
+	require.NoError(t, other.subscribeQuery(LiveQuery{ID: "go", Tags: []string{"go"}}))
+
+	// Only the user's clients with a matching query get the bookmark
+	item := LiveItem{URL: "https://go.dev", Title: "Go", Tags: []string{"go"}, Data: map[string]interface{}{"id": 1}}
+	assert.Equal(t, 1, hub.PublishBookmark("user", "created", item))
+	messages = drain(phone)
+	require.Len(t, messages, 1)
+	assert.Contains(t, messages[0], `"query_ids":["go"]`)
+	assert.Contains(t, messages[0], `"action":"created"`)
+	assert.Empty(t, drain(laptop))
+	assert.Empty(t, drain(other))
+
+	assert.Zero(t, hub.PublishBookmark("user", "updated", LiveItem{URL: "https://rust-lang.org", Tags: []string{"rust"}}))
+
+	phone.handleMessage(&Message{Type: MessageQueryUnsubscribe, Data: map[string]interface{}{"id": "go"}})
+	assert.Len(t, drain(phone), 1)
+	assert.Zero(t, hub.PublishBookmark("user", "created", item))
+
+	phone.handleMessage(&Message{Type: MessageQueryUnsubscribe, Data: map[string]interface{}{"id": "go"}})
+	messages = drain(phone)
+	require.Len(t, messages, 1)
+	assert.Contains(t, messages[0], "invalid live query")
+}
+
+func TestClient_LiveQueryLimit(t *testing.T) {
+	hub := NewHub(nil, zap.NewNop())
+	hub.SetLiveQueryLimit(2)
+	client := addClient(hub, "user")
+
+	for n := 0; n < 2; n++ {
+		require.NoError(t, client.subscribeQuery(LiveQuery{ID: fmt.Sprint(n), Query: "go"}))
+	}
+	assert.ErrorIs(t, client.subscribeQuery(LiveQuery{ID: "2", Query: "go"}), ErrLiveQueryLimit)
+
+	// Replacing a query does not count against the limit
+	assert.NoError(t, client.subscribeQuery(LiveQuery{ID: "1", Query: "rust"}))
+
+	client.handleMessage(&Message{Type: MessageQuerySubscribe, Data: map[string]interface{}{"id": "3", "q": "go"}})
+	messages := drain(client)
+	require.Len(t, messages, 1)
+	assert.Contains(t, messages[0], "too many live queries")
+}
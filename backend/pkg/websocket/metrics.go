@@ -0,0 +1,109 @@
+package websocket
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// hubStats counts what happens to messages passing through a hub
+type hubStats struct {
+	sent        atomic.Uint64
+	dropped     atomic.Uint64
+	disconnects atomic.Uint64
+	coalesced   atomic.Uint64
+	latency     prometheus.Histogram // time from queueing to write
+}
+
+func newHubStats() *hubStats {
+	return &hubStats{
+		latency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: "bookmark_sync",
+			Subsystem: "websocket",
+			Name:      "send_latency_seconds",
+			Help:      "Time from queueing a WebSocket message to writing it to the client.",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 2, 12),
+		}),
+	}
+}
+
+// observeSent records a batch of messages written to a client
+func (s *hubStats) observeSent(batch []outbound) {
+	now := time.Now()
+	for _, message := range batch {
+		s.latency.Observe(now.Sub(message.queued).Seconds())
+	}
+	s.sent.Add(uint64(len(batch)))
+}
+
+// HubStats is a snapshot of a hub's counters
+type HubStats struct {
+	Clients     int    `json:"clients"`
+	Sent        uint64 `json:"sent"`
+	Dropped     uint64 `json:"dropped"`
+	Disconnects uint64 `json:"disconnects"`
+	Coalesced   uint64 `json:"coalesced"`
+}
+
+// Stats returns the hub's current counters
+func (h *Hub) Stats() HubStats {
+	h.mutex.RLock()
+	clients := len(h.clients)
+	h.mutex.RUnlock()
+
+	return HubStats{
+		Clients:     clients,
+		Sent:        h.stats.sent.Load(),
+		Dropped:     h.stats.dropped.Load(),
+		Disconnects: h.stats.disconnects.Load(),
+		Coalesced:   h.stats.coalesced.Load(),
+	}
+}
+
+// Collector exposes a hub's client count, message counters and send
+// latency to Prometheus
+type Collector struct {
+	hub         *Hub
+	clients     *prometheus.Desc
+	sent        *prometheus.Desc
+	dropped     *prometheus.Desc
+	disconnects *prometheus.Desc
+	coalesced   *prometheus.Desc
+}
+
+// NewCollector creates a Prometheus collector for a hub
+func NewCollector(hub *Hub) *Collector {
+	name := func(metric string) string {
+		return prometheus.BuildFQName("bookmark_sync", "websocket", metric)
+	}
+	return &Collector{
+		hub:         hub,
+		clients:     prometheus.NewDesc(name("clients"), "Connected WebSocket clients.", nil, nil),
+		sent:        prometheus.NewDesc(name("messages_sent_total"), "Messages written to WebSocket clients.", nil, nil),
+		dropped:     prometheus.NewDesc(name("messages_dropped_total"), "Messages dropped because a client's send queue was full.", nil, nil),
+		disconnects: prometheus.NewDesc(name("slow_client_disconnects_total"), "Clients disconnected for not keeping up.", nil, nil),
+		coalesced:   prometheus.NewDesc(name("events_coalesced_total"), "Sync events replaced by a later event for the same resource.", nil, nil),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.clients
+	ch <- c.sent
+	ch <- c.dropped
+	ch <- c.disconnects
+	ch <- c.coalesced
+	c.hub.stats.latency.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.hub.Stats()
+	ch <- prometheus.MustNewConstMetric(c.clients, prometheus.GaugeValue, float64(stats.Clients))
+	ch <- prometheus.MustNewConstMetric(c.sent, prometheus.CounterValue, float64(stats.Sent))
+	ch <- prometheus.MustNewConstMetric(c.dropped, prometheus.CounterValue, float64(stats.Dropped))
+	ch <- prometheus.MustNewConstMetric(c.disconnects, prometheus.CounterValue, float64(stats.Disconnects))
+	ch <- prometheus.MustNewConstMetric(c.coalesced, prometheus.CounterValue, float64(stats.Coalesced))
+	c.hub.stats.latency.Collect(ch)
+}
@@ -3,6 +3,7 @@ package websocket
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"sync"
 	"time"
@@ -35,11 +36,27 @@ type Hub struct {
 	// Sync service for handling sync messages
 	syncService SyncService
 
+	// Records devices acknowledging bookmarks sent to them
+	sendAcknowledger SendAcknowledger
+
 	// Logger
 	logger *zap.Logger
 
 	// Mutex for thread safety
 	mutex sync.RWMutex
+
+	// Send queue limits and sync event coalescing
+	backpressure Backpressure
+
+	// Sync events held for the coalesce window, latest per resource
+	pending    map[coalesceKey]*Message
+	coalesceMu sync.Mutex
+
+	// Counters and send latency exposed to Prometheus
+	stats *hubStats
+
+	// How many live queries one connection may subscribe to
+	liveQueryLimit int
 }
 
 // Client is a middleman between the websocket connection and the hub
@@ -48,7 +65,11 @@ type Client struct {
 	conn *websocket.Conn
 
 	// Buffered channel of outbound messages
-	send chan []byte
+	send chan outbound
+
+	// Guards closing send, so the hub never sends on a closed queue
+	mu     sync.Mutex
+	closed bool
 
 	// User ID for this client
 	userID string
@@ -59,6 +80,10 @@ type Client struct {
 	// Hub reference
 	hub *Hub
 
+	// Live queries the client subscribed to, by ID
+	queries   map[string]*liveQuery
+	queriesMu sync.Mutex
+
 	// Logger
 	logger *zap.Logger
 }
@@ -98,26 +123,25 @@ var upgrader = websocket.Upgrader{
 // NewHub creates a new WebSocket hub
 func NewHub(redisClient *redis.Client, logger *zap.Logger) *Hub {
 	return &Hub{
-		clients:     make(map[*Client]bool),
-		broadcast:   make(chan []byte),
-		register:    make(chan *Client),
-		unregister:  make(chan *Client),
-		redisClient: redisClient,
-		logger:      logger,
+		clients:      make(map[*Client]bool),
+		broadcast:    make(chan []byte),
+		register:     make(chan *Client),
+		unregister:   make(chan *Client),
+		redisClient:  redisClient,
+		logger:       logger,
+		backpressure: DefaultBackpressure(),
+		pending:      make(map[coalesceKey]*Message),
+		stats:        newHubStats(),
+
+		liveQueryLimit: DefaultLiveQueryLimit,
 	}
 }
 
 // NewHubWithSyncService creates a new WebSocket hub with sync service integration
 func NewHubWithSyncService(redisClient *redis.Client, syncService SyncService, logger *zap.Logger) *Hub {
-	return &Hub{
-		clients:     make(map[*Client]bool),
-		broadcast:   make(chan []byte),
-		register:    make(chan *Client),
-		unregister:  make(chan *Client),
-		redisClient: redisClient,
-		syncService: syncService,
-		logger:      logger,
-	}
+	hub := NewHub(redisClient, logger)
+	hub.syncService = syncService
+	return hub
 }
 
 // Run starts the hub
@@ -136,11 +160,9 @@ func (h *Hub) Run(ctx context.Context) {
 
 		case client := <-h.unregister:
 			h.mutex.Lock()
-			if _, ok := h.clients[client]; ok {
-				delete(h.clients, client)
-				close(client.send)
-			}
+			delete(h.clients, client)
 			h.mutex.Unlock()
+			client.close()
 
 			h.logger.Info("Client disconnected",
 				zap.String("user_id", client.userID),
@@ -148,16 +170,7 @@ func (h *Hub) Run(ctx context.Context) {
 			)
 
 		case message := <-h.broadcast:
-			h.mutex.RLock()
-			for client := range h.clients {
-				select {
-				case client.send <- message:
-				default:
-					close(client.send)
-					delete(h.clients, client)
-				}
-			}
-			h.mutex.RUnlock()
+			h.deliver(message, func(*Client) bool { return true })
 
 		case <-ctx.Done():
 			h.logger.Info("WebSocket hub shutting down")
@@ -174,19 +187,38 @@ func (h *Hub) BroadcastToUser(userID string, message *Message) {
 		return
 	}
 
+	h.deliver(messageBytes, func(client *Client) bool { return client.userID == userID })
+}
+
+// SendToDevice sends a message to the clients of one of a user's devices
+// and reports whether any of them is connected to this hub
+func (h *Hub) SendToDevice(userID, deviceID string, message *Message) bool {
+	messageBytes, err := json.Marshal(message)
+	if err != nil {
+		h.logger.Error("Failed to marshal message", zap.Error(err))
+		return false
+	}
+
+	return h.deliver(messageBytes, func(client *Client) bool {
+		return client.userID == userID && client.deviceID == deviceID
+	}) > 0
+}
+
+// ConnectedUsers returns the IDs of the users with a client connected to
+// this hub
+func (h *Hub) ConnectedUsers() []string {
 	h.mutex.RLock()
 	defer h.mutex.RUnlock()
 
+	seen := make(map[string]bool, len(h.clients))
+	users := make([]string, 0, len(h.clients))
 	for client := range h.clients {
-		if client.userID == userID {
-			select {
-			case client.send <- messageBytes:
-			default:
-				close(client.send)
-				delete(h.clients, client)
-			}
+		if !seen[client.userID] {
+			seen[client.userID] = true
+			users = append(users, client.userID)
 		}
 	}
+	return users
 }
 
 // HandleWebSocket handles WebSocket connections
@@ -206,14 +238,7 @@ func (h *Hub) HandleWebSocket(c *gin.Context) {
 		return
 	}
 
-	client := &Client{
-		conn:     conn,
-		send:     make(chan []byte, 256),
-		userID:   userID,
-		deviceID: deviceID,
-		hub:      h,
-		logger:   h.logger.With(zap.String("user_id", userID), zap.String("device_id", deviceID)),
-	}
+	client := h.newClient(conn, userID, deviceID)
 
 	client.hub.register <- client
 
@@ -283,18 +308,25 @@ func (c *Client) writePump() {
 			if err != nil {
 				return
 			}
-			w.Write(message)
+			w.Write(message.data)
+			batch := []outbound{message}
 
-			// Add queued chat messages to the current websocket message
+			// Add queued messages to the current websocket message
 			n := len(c.send)
 			for i := 0; i < n; i++ {
+				queued, ok := <-c.send
+				if !ok {
+					break
+				}
 				w.Write([]byte{'\n'})
-				w.Write(<-c.send)
+				w.Write(queued.data)
+				batch = append(batch, queued)
 			}
 
 			if err := w.Close(); err != nil {
 				return
 			}
+			c.hub.stats.observeSent(batch)
 
 		case <-ticker.C:
 			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
@@ -310,6 +342,16 @@ type SyncService interface {
 	HandleSyncMessage(ctx context.Context, msg *SyncMessage) (*SyncMessage, error)
 }
 
+// SendAcknowledger records a device acknowledging a bookmark sent to it
+type SendAcknowledger interface {
+	AcknowledgeDeviceSend(ctx context.Context, userID, deviceID string, sendID uint, action string) error
+}
+
+// SetSendAcknowledger handles bookmark_send_ack messages from clients
+func (h *Hub) SetSendAcknowledger(acknowledger SendAcknowledger) {
+	h.sendAcknowledger = acknowledger
+}
+
 // SyncMessage represents a sync message for WebSocket communication
 type SyncMessage struct {
 	Type      string                 `json:"type"`
@@ -332,8 +374,8 @@ func (c *Client) handleMessage(msg *Message) {
 		}
 		c.sendMessage(response)
 
-	case "sync_request":
-		// Handle sync request
+	case "sync_request", "sync_scope":
+		// Handle sync request or scope change
 		c.logger.Info("Sync request received", zap.String("type", msg.Type))
 
 		if c.hub.syncService != nil {
@@ -350,7 +392,7 @@ func (c *Client) handleMessage(msg *Message) {
 			}
 
 			syncMsg := &SyncMessage{
-				Type:      "sync_request",
+				Type:      msg.Type,
 				UserID:    c.userID,
 				DeviceID:  c.deviceID,
 				Data:      data,
@@ -372,7 +414,7 @@ func (c *Client) handleMessage(msg *Message) {
 
 			// Convert sync response back to WebSocket message
 			response := &Message{
-				Type:      "sync_response",
+				Type:      syncResponse.Type,
 				Data:      syncResponse.Data,
 				Timestamp: time.Now(),
 			}
@@ -404,6 +446,14 @@ func (c *Client) handleMessage(msg *Message) {
 		}
 		c.sendMessage(response)
 
+	case "bookmark_send_ack":
+		// The device opened or saved a bookmark sent to it
+		c.acknowledgeSend(ctx, msg)
+
+	case MessageQuerySubscribe, MessageQueryUnsubscribe:
+		// Push bookmarks matching a search or filter as they change
+		c.handleLiveQuery(msg)
+
 	default:
 		c.logger.Warn("Unknown message type", zap.String("type", msg.Type))
 
@@ -425,9 +475,33 @@ func (c *Client) sendMessage(msg *Message) {
 		return
 	}
 
-	select {
-	case c.send <- messageBytes:
+	if !c.hub.enqueue(c, messageBytes) {
+		c.hub.disconnectSlow(c)
+	}
+}
+
+// acknowledgeSend passes a bookmark_send_ack on to the acknowledger. Its data
+// carries the send_id and the action the device took
+func (c *Client) acknowledgeSend(ctx context.Context, msg *Message) {
+	data, _ := msg.Data.(map[string]interface{})
+	sendID, _ := data["send_id"].(float64)
+	action, _ := data["action"].(string)
+
+	var err error
+	switch {
+	case c.hub.sendAcknowledger == nil:
+		err = errors.New("send acknowledgements unavailable")
+	case sendID <= 0:
+		err = errors.New("send_id is required")
 	default:
-		close(c.send)
+		err = c.hub.sendAcknowledger.AcknowledgeDeviceSend(ctx, c.userID, c.deviceID, uint(sendID), action)
+	}
+
+	if err != nil {
+		c.sendMessage(&Message{
+			Type:      "error",
+			Data:      map[string]interface{}{"error": err.Error(), "received_type": msg.Type},
+			Timestamp: time.Now(),
+		})
 	}
 }
@@ -0,0 +1,73 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type stubAcknowledger struct {
+	userID, deviceID, action string
+	sendID                   uint
+	err                      error
+}
+
+func (a *stubAcknowledger) AcknowledgeDeviceSend(ctx context.Context, userID, deviceID string, sendID uint, action string) error {
+	a.userID, a.deviceID, a.sendID, a.action = userID, deviceID, sendID, action
+	return a.err
+}
+
+func TestHub_SendToDevice(t *testing.T) {
+	hub := NewHub(nil, zap.NewNop())
+	phone := hub.newClient(nil, "user", "phone")
+	laptop := hub.newClient(nil, "user", "laptop")
+	other := hub.newClient(nil, "other", "phone")
+	for _, client := range []*Client{phone, laptop, other} {
+		hub.clients[client] = true
+	}
+
+	assert.True(t, hub.SendToDevice("user", "phone", &Message{Type: "bookmark_sent"}))
+	assert.Len(t, drain(phone), 1)
+	assert.Empty(t, drain(laptop))
+	assert.Empty(t, drain(other))
+
+	assert.False(t, hub.SendToDevice("user", "tablet", &Message{Type: "bookmark_sent"}))
+}
+
+func TestClient_BookmarkSendAck(t *testing.T) {
+	hub := NewHub(nil, zap.NewNop())
+	client := hub.newClient(nil, "user", "phone")
+	hub.clients[client] = true
+
+	ack := &Message{Type: "bookmark_send_ack", Data: map[string]interface{}{"send_id": float64(7), "action": "save"}}
+
+	// Without an acknowledger the client is told so
+	client.handleMessage(ack)
+	messages := drain(client)
+	require.Len(t, messages, 1)
+	assert.Contains(t, messages[0], "send acknowledgements unavailable")
+
+	acknowledger := &stubAcknowledger{}
+	hub.SetSendAcknowledger(acknowledger)
+	client.handleMessage(ack)
+	assert.Empty(t, drain(client))
+	assert.Equal(t, "user", acknowledger.userID)
+	assert.Equal(t, "phone", acknowledger.deviceID)
+	assert.Equal(t, uint(7), acknowledger.sendID)
+	assert.Equal(t, "save", acknowledger.action)
+
+	acknowledger.err = errors.New("send not found")
+	client.handleMessage(ack)
+	messages = drain(client)
+	require.Len(t, messages, 1)
+	assert.Contains(t, messages[0], "send not found")
+
+	client.handleMessage(&Message{Type: "bookmark_send_ack", Data: map[string]interface{}{}})
+	messages = drain(client)
+	require.Len(t, messages, 1)
+	assert.Contains(t, messages[0], "send_id is required")
+}
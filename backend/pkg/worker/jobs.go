@@ -152,6 +152,11 @@ func NewLinkCheckerJob(bookmark *database.Bookmark, service LinkCheckerService,
 	}
 }
 
+// LockName keeps replicas from checking the same bookmark concurrently
+func (j *LinkCheckerJob) LockName() string {
+	return fmt.Sprintf("link_check:%d", j.Bookmark.ID)
+}
+
 func (j *LinkCheckerJob) Execute(ctx context.Context) error {
 	j.Logger.Debug("Executing link checker job",
 		zap.String("job_id", j.ID),
@@ -161,6 +166,88 @@ func (j *LinkCheckerJob) Execute(ctx context.Context) error {
 	return j.Service.CheckLink(ctx, j.Bookmark)
 }
 
+// ScreenshotRefreshJob re-captures the page of a queued screenshot job
+type ScreenshotRefreshJob struct {
+	BaseJob
+	ScreenshotJobID uint
+	Service         ScreenshotRefreshService
+	Logger          *zap.Logger
+}
+
+// ScreenshotRefreshService defines the interface for screenshot re-capture
+type ScreenshotRefreshService interface {
+	RunRefresh(ctx context.Context, screenshotJobID uint) error
+}
+
+// NewScreenshotRefreshJob creates a new screenshot refresh job
+func NewScreenshotRefreshJob(screenshotJobID uint, service ScreenshotRefreshService, logger *zap.Logger) *ScreenshotRefreshJob {
+	return &ScreenshotRefreshJob{
+		BaseJob: BaseJob{
+			ID:         fmt.Sprintf("screenshot-refresh-%d-%d", screenshotJobID, time.Now().UnixNano()),
+			Type:       "screenshot_refresh",
+			MaxRetries: 2,
+			CreatedAt:  time.Now(),
+		},
+		ScreenshotJobID: screenshotJobID,
+		Service:         service,
+		Logger:          logger,
+	}
+}
+
+func (j *ScreenshotRefreshJob) Execute(ctx context.Context) error {
+	j.Logger.Debug("Executing screenshot refresh job",
+		zap.String("job_id", j.ID),
+		zap.Uint("screenshot_job_id", j.ScreenshotJobID))
+
+	return j.Service.RunRefresh(ctx, j.ScreenshotJobID)
+}
+
+// IsHighPriority puts refreshes ahead of background work, a user asked for them
+func (j *ScreenshotRefreshJob) IsHighPriority() bool {
+	return true
+}
+
+// FaviconRefreshJob re-validates a batch of stale bookmark favicons
+type FaviconRefreshJob struct {
+	BaseJob
+	Service FaviconRefreshService
+	Logger  *zap.Logger
+}
+
+// FaviconRefreshService defines the interface for favicon re-validation
+type FaviconRefreshService interface {
+	RefreshStaleFavicons(ctx context.Context) (int, error)
+}
+
+// NewFaviconRefreshJob creates a new favicon refresh job
+func NewFaviconRefreshJob(service FaviconRefreshService, logger *zap.Logger) *FaviconRefreshJob {
+	return &FaviconRefreshJob{
+		BaseJob: BaseJob{
+			ID:         fmt.Sprintf("favicon-refresh-%d", time.Now().UnixNano()),
+			Type:       "favicon_refresh",
+			MaxRetries: 1,
+			CreatedAt:  time.Now(),
+		},
+		Service: service,
+		Logger:  logger,
+	}
+}
+
+func (j *FaviconRefreshJob) Execute(ctx context.Context) error {
+	j.Logger.Debug("Executing favicon refresh job", zap.String("job_id", j.ID))
+	refreshed, err := j.Service.RefreshStaleFavicons(ctx)
+	if err != nil {
+		return err
+	}
+	j.Logger.Info("Favicon refresh completed", zap.String("job_id", j.ID), zap.Int("checked", refreshed))
+	return nil
+}
+
+// LockName keeps replicas from re-validating the same batch concurrently
+func (j *FaviconRefreshJob) LockName() string {
+	return "favicon_refresh"
+}
+
 // CleanupJob handles cleanup operations
 type CleanupJob struct {
 	BaseJob
@@ -192,6 +279,88 @@ func (j *CleanupJob) Execute(ctx context.Context) error {
 	return j.Service.RunCleanup(ctx)
 }
 
+// LockName runs cleanup on a single replica
+func (j *CleanupJob) LockName() string {
+	return "cleanup"
+}
+
+// TrendingCalculationJob recomputes trending scores for a time window
+type TrendingCalculationJob struct {
+	BaseJob
+	TimeWindow string
+	Service    TrendingCalculationService
+	Logger     *zap.Logger
+}
+
+// TrendingCalculationService defines the interface for trending score calculation
+type TrendingCalculationService interface {
+	CalculateTrendingScores(ctx context.Context, timeWindow string) error
+}
+
+// NewTrendingCalculationJob creates a new trending calculation job
+func NewTrendingCalculationJob(timeWindow string, service TrendingCalculationService, logger *zap.Logger) *TrendingCalculationJob {
+	return &TrendingCalculationJob{
+		BaseJob: BaseJob{
+			ID:         fmt.Sprintf("trending-calculation-%s-%d", timeWindow, time.Now().UnixNano()),
+			Type:       "trending_calculation",
+			MaxRetries: 1,
+			CreatedAt:  time.Now(),
+		},
+		TimeWindow: timeWindow,
+		Service:    service,
+		Logger:     logger,
+	}
+}
+
+func (j *TrendingCalculationJob) Execute(ctx context.Context) error {
+	j.Logger.Debug("Executing trending calculation job",
+		zap.String("job_id", j.ID),
+		zap.String("time_window", j.TimeWindow))
+
+	return j.Service.CalculateTrendingScores(ctx, j.TimeWindow)
+}
+
+// LockName runs one calculation per time window across replicas
+func (j *TrendingCalculationJob) LockName() string {
+	return "trending_calculation:" + j.TimeWindow
+}
+
+// SitemapRefreshJob rebuilds the public sitemap
+type SitemapRefreshJob struct {
+	BaseJob
+	Service SitemapService
+	Logger  *zap.Logger
+}
+
+// SitemapService defines the interface for sitemap generation
+type SitemapService interface {
+	RefreshSitemap(ctx context.Context) error
+}
+
+// NewSitemapRefreshJob creates a new sitemap refresh job
+func NewSitemapRefreshJob(service SitemapService, logger *zap.Logger) *SitemapRefreshJob {
+	return &SitemapRefreshJob{
+		BaseJob: BaseJob{
+			ID:         fmt.Sprintf("sitemap-%d", time.Now().UnixNano()),
+			Type:       "sitemap_refresh",
+			MaxRetries: 2,
+			CreatedAt:  time.Now(),
+		},
+		Service: service,
+		Logger:  logger,
+	}
+}
+
+func (j *SitemapRefreshJob) Execute(ctx context.Context) error {
+	j.Logger.Debug("Executing sitemap refresh job", zap.String("job_id", j.ID))
+	return j.Service.RefreshSitemap(ctx)
+}
+
+// LockName runs sitemap generation on a single replica
+func (j *SitemapRefreshJob) LockName() string {
+	return "sitemap_refresh"
+}
+
 // EmailNotificationJob handles sending email notifications
 type EmailNotificationJob struct {
 	BaseJob
@@ -232,3 +401,79 @@ func (j *EmailNotificationJob) Execute(ctx context.Context) error {
 
 	return j.Service.SendEmail(ctx, j.To, j.Subject, j.Body)
 }
+
+// IsCritical keeps transactional emails flowing during maintenance mode
+func (j *EmailNotificationJob) IsCritical() bool {
+	return true
+}
+
+// BulkOperationJob runs a bulk operation submitted through the API
+type BulkOperationJob struct {
+	BaseJob
+	OperationID uint
+	Service     BulkOperationService
+	Logger      *zap.Logger
+}
+
+// BulkOperationService defines the interface for running bulk operations
+type BulkOperationService interface {
+	RunBulkOperation(ctx context.Context, operationID uint) error
+	FailBulkOperation(ctx context.Context, operationID uint, reason string) error
+}
+
+// NewBulkOperationJob creates a new bulk operation job
+func NewBulkOperationJob(operationID uint, service BulkOperationService, logger *zap.Logger) *BulkOperationJob {
+	return &BulkOperationJob{
+		BaseJob: BaseJob{
+			ID:         fmt.Sprintf("bulk-operation-%d-%d", operationID, time.Now().UnixNano()),
+			Type:       "bulk_operation",
+			MaxRetries: 2,
+			CreatedAt:  time.Now(),
+		},
+		OperationID: operationID,
+		Service:     service,
+		Logger:      logger,
+	}
+}
+
+func (j *BulkOperationJob) Execute(ctx context.Context) error {
+	j.Logger.Debug("Executing bulk operation job",
+		zap.String("job_id", j.ID),
+		zap.Uint("operation_id", j.OperationID))
+
+	return j.Service.RunBulkOperation(ctx, j.OperationID)
+}
+
+// Fail marks the operation failed when the pool gives up on the job, so its
+// client doesn't wait on a pending operation forever
+func (j *BulkOperationJob) Fail(ctx context.Context, err error) {
+	if err := j.Service.FailBulkOperation(ctx, j.OperationID, err.Error()); err != nil {
+		j.Logger.Error("Failed to mark bulk operation failed",
+			zap.Uint("operation_id", j.OperationID),
+			zap.Error(err))
+	}
+}
+
+// IsHighPriority puts bulk operations ahead of background work, their
+// client may be waiting on them
+func (j *BulkOperationJob) IsHighPriority() bool {
+	return true
+}
+
+// BulkOperationQueue submits bulk operations to a worker pool as jobs
+type BulkOperationQueue struct {
+	pool    *WorkerPool
+	service BulkOperationService
+	logger  *zap.Logger
+}
+
+// NewBulkOperationQueue creates a queue running a service's bulk operations
+// on the pool's workers
+func NewBulkOperationQueue(pool *WorkerPool, service BulkOperationService, logger *zap.Logger) *BulkOperationQueue {
+	return &BulkOperationQueue{pool: pool, service: service, logger: logger}
+}
+
+// SubmitBulkOperation queues a bulk operation
+func (q *BulkOperationQueue) SubmitBulkOperation(operationID uint) error {
+	return q.pool.Submit(NewBulkOperationJob(operationID, q.service, q.logger))
+}
@@ -2,11 +2,13 @@ package worker
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
 
 	"bookmark-sync-service/backend/internal/config"
+	redispkg "bookmark-sync-service/backend/pkg/redis"
 
 	"go.uber.org/zap"
 )
@@ -21,6 +23,31 @@ type Job interface {
 	GetMaxRetries() int
 }
 
+// CriticalJob is implemented by jobs that must keep running while the worker
+// pool is paused, e.g. during maintenance mode
+type CriticalJob interface {
+	IsCritical() bool
+}
+
+// PriorityJob is implemented by jobs a user is waiting on. High priority jobs
+// are picked up before any queued background work
+type PriorityJob interface {
+	IsHighPriority() bool
+}
+
+// SingletonJob is implemented by scheduled jobs that must not run on more than
+// one replica at a time. Jobs sharing a lock name exclude each other
+type SingletonJob interface {
+	LockName() string
+}
+
+// FailableJob is implemented by jobs whose state is recorded elsewhere, e.g.
+// a bulk operation row. Fail is called when the pool gives up on the job, so
+// the record doesn't stay pending
+type FailableJob interface {
+	Fail(ctx context.Context, err error)
+}
+
 // BaseJob provides common job functionality
 type BaseJob struct {
 	ID         string
@@ -52,15 +79,19 @@ func (j *BaseJob) GetMaxRetries() int {
 
 // WorkerPool manages a pool of workers to process jobs
 type WorkerPool struct {
-	workers  int
-	jobQueue chan Job
-	quit     chan bool
-	wg       sync.WaitGroup
-	logger   *zap.Logger
-	ctx      context.Context
-	cancel   context.CancelFunc
-	started  bool
-	mu       sync.RWMutex
+	workers       int
+	jobQueue      chan Job
+	priorityQueue chan Job
+	quit          chan bool
+	wg            sync.WaitGroup
+	logger        *zap.Logger
+	ctx           context.Context
+	cancel        context.CancelFunc
+	started       bool
+	mu            sync.RWMutex
+	paused        func(ctx context.Context) bool
+	holdFor       time.Duration
+	locker        redispkg.Locker
 }
 
 // NewWorkerPool creates a new worker pool
@@ -75,12 +106,14 @@ func NewWorkerPool(workers int, queueSize int, logger *zap.Logger) *WorkerPool {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	return &WorkerPool{
-		workers:  workers,
-		jobQueue: make(chan Job, queueSize),
-		quit:     make(chan bool),
-		logger:   logger,
-		ctx:      ctx,
-		cancel:   cancel,
+		workers:       workers,
+		jobQueue:      make(chan Job, queueSize),
+		priorityQueue: make(chan Job, queueSize),
+		quit:          make(chan bool),
+		logger:        logger,
+		ctx:           ctx,
+		cancel:        cancel,
+		holdFor:       config.MaintenancePollInterval,
 	}
 }
 
@@ -116,8 +149,9 @@ func (wp *WorkerPool) Stop() {
 
 	wp.logger.Info("Stopping worker pool")
 
-	// Close job queue to prevent new jobs
+	// Close job queues to prevent new jobs
 	close(wp.jobQueue)
+	close(wp.priorityQueue)
 
 	// Cancel context to signal workers to stop
 	wp.cancel()
@@ -149,7 +183,7 @@ func (wp *WorkerPool) Submit(job Job) error {
 	}
 
 	select {
-	case wp.jobQueue <- job:
+	case wp.queueFor(job) <- job:
 		wp.logger.Debug("Job submitted",
 			zap.String("job_id", job.GetID()),
 			zap.String("job_type", job.GetType()))
@@ -168,7 +202,26 @@ func (wp *WorkerPool) worker(id int) {
 	wp.logger.Debug("Worker started", zap.Int("worker_id", id))
 
 	for {
+		// Drain high priority jobs before looking at background work
 		select {
+		case job, ok := <-wp.priorityQueue:
+			if !ok {
+				wp.logger.Debug("Worker stopping - job queue closed", zap.Int("worker_id", id))
+				return
+			}
+			wp.processJob(id, job)
+			continue
+		default:
+		}
+
+		select {
+		case job, ok := <-wp.priorityQueue:
+			if !ok {
+				wp.logger.Debug("Worker stopping - job queue closed", zap.Int("worker_id", id))
+				return
+			}
+			wp.processJob(id, job)
+
 		case job, ok := <-wp.jobQueue:
 			if !ok {
 				wp.logger.Debug("Worker stopping - job queue closed", zap.Int("worker_id", id))
@@ -193,13 +246,22 @@ func (wp *WorkerPool) processJob(workerID int, job Job) {
 		zap.Int("retry_count", job.GetRetryCount()),
 	)
 
+	if wp.shouldHold(job) && !wp.holdJob(logger, job) {
+		return
+	}
+
 	logger.Debug("Processing job")
 
 	// Create job context with timeout
 	jobCtx, cancel := context.WithTimeout(wp.ctx, 30*time.Second)
 	defer cancel()
 
-	err := job.Execute(jobCtx)
+	err := wp.execute(jobCtx, job)
+	if errors.Is(err, redispkg.ErrLockNotAcquired) {
+		// Another replica is already running it, so this run is redundant
+		logger.Debug("Skipping singleton job held by another replica")
+		return
+	}
 	if err != nil {
 		logger.Error("Job execution failed", zap.Error(err))
 
@@ -225,27 +287,137 @@ func (wp *WorkerPool) processJob(workerID int, job Job) {
 			defer retryCancel()
 
 			select {
-			case wp.jobQueue <- job:
+			case wp.queueFor(job) <- job:
 				logger.Debug("Job resubmitted for retry")
 			case <-retryCtx.Done():
 				logger.Debug("Cannot retry job - worker pool shutting down or timeout")
+				wp.fail(job, err)
 				return
 			case <-wp.ctx.Done():
 				logger.Debug("Cannot retry job - worker pool shutting down")
+				wp.fail(job, err)
 				return
 			}
 		} else {
 			logger.Error("Job failed after max retries",
 				zap.Int("max_retries", job.GetMaxRetries()))
+			wp.fail(job, err)
 		}
 	} else {
 		logger.Debug("Job completed successfully")
 	}
 }
 
-// GetQueueSize returns the current number of jobs in the queue
+// SetPauseCheck registers a check consulted before each job. While it reports
+// true, non-critical jobs are held back and requeued instead of executed
+func (wp *WorkerPool) SetPauseCheck(paused func(ctx context.Context) bool) {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	wp.paused = paused
+}
+
+// shouldHold reports whether a job has to wait for the pool to be resumed
+func (wp *WorkerPool) shouldHold(job Job) bool {
+	wp.mu.RLock()
+	paused := wp.paused
+	wp.mu.RUnlock()
+
+	if paused == nil || !paused(wp.ctx) {
+		return false
+	}
+	if critical, ok := job.(CriticalJob); ok && critical.IsCritical() {
+		return false
+	}
+	return true
+}
+
+// holdJob waits one poll interval and puts the job back at the end of the
+// queue. While the queue is full the worker keeps the job, and it reports
+// true once the pool is resumed so the worker runs the job itself. A job
+// still held when the pool stops is failed rather than lost
+func (wp *WorkerPool) holdJob(logger *zap.Logger, job Job) bool {
+	logger.Debug("Worker pool paused, holding job")
+
+	for {
+		select {
+		case <-time.After(wp.holdFor):
+		case <-wp.ctx.Done():
+			logger.Warn("Worker pool stopped while holding job")
+			wp.fail(job, errors.New("worker pool stopped before the job ran"))
+			return false
+		}
+
+		if !wp.shouldHold(job) {
+			return true
+		}
+		if wp.requeue(job) {
+			return false
+		}
+		logger.Debug("Job queue is full, keeping held job")
+	}
+}
+
+// requeue puts a job back in its queue if there is room. Holding the read
+// lock keeps Stop from closing the queue meanwhile
+func (wp *WorkerPool) requeue(job Job) bool {
+	wp.mu.RLock()
+	defer wp.mu.RUnlock()
+
+	if !wp.started {
+		return false
+	}
+	select {
+	case wp.queueFor(job) <- job:
+		return true
+	default:
+		return false
+	}
+}
+
+// fail tells a job the pool gave up on it
+func (wp *WorkerPool) fail(job Job, err error) {
+	failable, ok := job.(FailableJob)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(wp.ctx), config.WorkerShutdownTimeout)
+	defer cancel()
+	failable.Fail(ctx, err)
+}
+
+// queueFor returns the queue a job waits in
+func (wp *WorkerPool) queueFor(job Job) chan Job {
+	if priority, ok := job.(PriorityJob); ok && priority.IsHighPriority() {
+		return wp.priorityQueue
+	}
+	return wp.jobQueue
+}
+
+// SetLocker registers the distributed lock used to run singleton jobs on one
+// replica at a time. Without a locker every replica runs them
+func (wp *WorkerPool) SetLocker(locker redispkg.Locker) {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	wp.locker = locker
+}
+
+// execute runs a job, under its distributed lock for singleton jobs
+func (wp *WorkerPool) execute(ctx context.Context, job Job) error {
+	wp.mu.RLock()
+	locker := wp.locker
+	wp.mu.RUnlock()
+
+	singleton, ok := job.(SingletonJob)
+	if !ok || locker == nil {
+		return job.Execute(ctx)
+	}
+
+	return locker.WithLock(ctx, "job:"+singleton.LockName(), config.SingletonJobLockTTL, job.Execute)
+}
+
+// GetQueueSize returns the current number of jobs in the queues
 func (wp *WorkerPool) GetQueueSize() int {
-	return len(wp.jobQueue)
+	return len(wp.jobQueue) + len(wp.priorityQueue)
 }
 
 // IsStarted returns whether the worker pool is started
@@ -8,6 +8,8 @@ import (
 	"testing"
 	"time"
 
+	redispkg "bookmark-sync-service/backend/pkg/redis"
+
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 	"go.uber.org/zap"
@@ -272,6 +274,185 @@ func (suite *WorkerPoolTestSuite) TestGracefulShutdown() {
 	assert.False(suite.T(), suite.pool.IsStarted())
 }
 
+// criticalTestJob is a TestJob that keeps running while the pool is paused
+type criticalTestJob struct {
+	*TestJob
+}
+
+func (j *criticalTestJob) IsCritical() bool {
+	return true
+}
+
+func (suite *WorkerPoolTestSuite) TestPauseHoldsNonCriticalJobs() {
+	var paused sync.Map
+	paused.Store("on", true)
+	suite.pool.holdFor = 10 * time.Millisecond
+	suite.pool.SetPauseCheck(func(ctx context.Context) bool {
+		on, _ := paused.Load("on")
+		return on.(bool)
+	})
+	suite.pool.Start()
+
+	regular := NewTestJob("regular", "test", 0, nil)
+	critical := &criticalTestJob{NewTestJob("critical", "test", 0, nil)}
+
+	assert.NoError(suite.T(), suite.pool.Submit(regular))
+	assert.NoError(suite.T(), suite.pool.Submit(critical))
+
+	// Critical jobs run while paused, regular jobs are held back
+	time.Sleep(50 * time.Millisecond)
+	assert.True(suite.T(), critical.IsExecuted())
+	assert.False(suite.T(), regular.IsExecuted())
+
+	// Held jobs run once the pool is resumed
+	paused.Store("on", false)
+	assert.Eventually(suite.T(), regular.IsExecuted, time.Second, 10*time.Millisecond)
+}
+
+func (suite *WorkerPoolTestSuite) TestHeldJobKeptWhenQueueFull() {
+	pool := NewWorkerPool(1, 1, suite.logger)
+	pool.holdFor = 10 * time.Millisecond
+	var paused sync.Map
+	paused.Store("on", true)
+	pool.SetPauseCheck(func(ctx context.Context) bool {
+		on, _ := paused.Load("on")
+		return on.(bool)
+	})
+	pool.Start()
+	defer pool.Stop()
+
+	held := NewTestJob("held", "test", 0, nil)
+	queued := NewTestJob("queued", "test", 0, nil)
+	assert.NoError(suite.T(), pool.Submit(held))
+	assert.Eventually(suite.T(), func() bool { return pool.GetQueueSize() == 0 }, time.Second, time.Millisecond)
+	assert.NoError(suite.T(), pool.Submit(queued))
+
+	// The held job can't go back into the full queue, so the worker keeps
+	// it and runs it once the pool is resumed
+	time.Sleep(50 * time.Millisecond)
+	assert.False(suite.T(), held.IsExecuted())
+	paused.Store("on", false)
+	assert.Eventually(suite.T(), held.IsExecuted, time.Second, 10*time.Millisecond)
+	assert.Eventually(suite.T(), queued.IsExecuted, time.Second, 10*time.Millisecond)
+}
+
+// failableTestJob is a TestJob recording why the pool gave up on it
+type failableTestJob struct {
+	*TestJob
+	failed chan error
+}
+
+func (j *failableTestJob) Fail(ctx context.Context, err error) {
+	j.failed <- err
+}
+
+func (suite *WorkerPoolTestSuite) TestHeldJobFailedOnStop() {
+	pool := NewWorkerPool(1, 1, suite.logger)
+	pool.holdFor = 10 * time.Millisecond
+	pool.SetPauseCheck(func(ctx context.Context) bool { return true })
+	pool.Start()
+
+	held := &failableTestJob{TestJob: NewTestJob("held", "test", 0, nil), failed: make(chan error, 1)}
+	assert.NoError(suite.T(), pool.Submit(held))
+	assert.Eventually(suite.T(), func() bool { return pool.GetQueueSize() == 0 }, time.Second, time.Millisecond)
+	pool.Stop()
+
+	select {
+	case err := <-held.failed:
+		assert.Error(suite.T(), err)
+	case <-time.After(time.Second):
+		suite.T().Fatal("held job was dropped without failing")
+	}
+	assert.False(suite.T(), held.IsExecuted())
+}
+
+// priorityTestJob is a TestJob a user is waiting on
+type priorityTestJob struct {
+	*TestJob
+}
+
+func (j *priorityTestJob) IsHighPriority() bool {
+	return true
+}
+
+func (suite *WorkerPoolTestSuite) TestPriorityJobsRunFirst() {
+	pool := NewWorkerPool(1, 10, suite.logger)
+
+	var mu sync.Mutex
+	var order []string
+	record := func(id string) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			mu.Lock()
+			order = append(order, id)
+			mu.Unlock()
+			return nil
+		}
+	}
+
+	pool.Start()
+	defer pool.Stop()
+
+	// Keep the only worker busy while background work and then a job a user
+	// is waiting on are queued
+	release := make(chan struct{})
+	assert.NoError(suite.T(), pool.Submit(NewTestJob("busy", "test", 0, func(ctx context.Context) error {
+		<-release
+		return nil
+	})))
+	assert.Eventually(suite.T(), func() bool { return pool.GetQueueSize() == 0 }, time.Second, 10*time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		id := fmt.Sprintf("background-%d", i)
+		assert.NoError(suite.T(), pool.Submit(NewTestJob(id, "test", 0, record(id))))
+	}
+	assert.NoError(suite.T(), pool.Submit(&priorityTestJob{NewTestJob("urgent", "test", 0, record("urgent"))}))
+	assert.Equal(suite.T(), 4, pool.GetQueueSize())
+	close(release)
+
+	assert.Eventually(suite.T(), func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(order) == 4
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(suite.T(), "urgent", order[0])
+}
+
+// singletonTestJob is a TestJob that runs under a distributed lock
+type singletonTestJob struct {
+	*TestJob
+}
+
+func (j *singletonTestJob) LockName() string {
+	return "test"
+}
+
+// heldLocker behaves as if another replica holds every lock
+type heldLocker struct {
+	calls []string
+}
+
+func (l *heldLocker) WithLock(ctx context.Context, name string, ttl time.Duration, fn func(ctx context.Context) error) error {
+	l.calls = append(l.calls, name)
+	return redispkg.ErrLockNotAcquired
+}
+
+func (suite *WorkerPoolTestSuite) TestSingletonJobsSkippedWhileLocked() {
+	locker := &heldLocker{}
+	suite.pool.SetLocker(locker)
+
+	singleton := &singletonTestJob{NewTestJob("singleton", "test", 3, nil)}
+	regular := NewTestJob("regular", "test", 0, nil)
+
+	suite.pool.processJob(0, singleton)
+	suite.pool.processJob(0, regular)
+
+	// The singleton is skipped without a retry, regular jobs ignore the locker
+	assert.False(suite.T(), singleton.IsExecuted())
+	assert.Equal(suite.T(), 0, singleton.GetRetryCount())
+	assert.Equal(suite.T(), []string{"job:test"}, locker.calls)
+	assert.True(suite.T(), regular.IsExecuted())
+}
+
 func TestWorkerPoolTestSuite(t *testing.T) {
 	suite.Run(t, new(WorkerPoolTestSuite))
 }
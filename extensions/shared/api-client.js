@@ -28,7 +28,9 @@ class ApiClient {
    */
   getHeaders() {
     const headers = {
-      'Content-Type': 'application/json'
+      'Content-Type': 'application/json',
+      // Attributes the bookmarks saved from here to the extension
+      'X-Client-Type': 'extension'
     };
 
     if (this.token) {